@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-demo/chat/pkg/chatclient"
+	"github.com/google/uuid"
+)
+
+// isPurgeJob picks the jobs purge runs by default: the ones that delete
+// expired or orphaned data. Which exist depends on the server's config.
+func isPurgeJob(name string) bool {
+	return strings.HasSuffix(name, "_purge") || name == "upload_gc" || name == "upload_sessions"
+}
+
+// tailStatsColumns are the stats tail prints without -all
+var tailStatsColumns = []string{
+	"total_clients",
+	"online_users",
+	"active_rooms",
+	"dropped_messages",
+	"slow_consumer_evictions",
+	"write_timeouts",
+	"write_latency_p99_us",
+}
+
+// parseFlags parses the flags of a subcommand that takes no other
+// arguments
+func (c *cli) parseFlags(name string, args []string, define func(fs *flag.FlagSet)) error {
+	fs := flag.NewFlagSet("chatctl "+name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	define(fs)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		return c.usageError(fmt.Sprintf("%s takes no arguments", name))
+	}
+	return nil
+}
+
+func (c *cli) createUser(ctx context.Context, args []string) error {
+	var user chatclient.NewUser
+	var admin, invite bool
+	if err := c.parseFlags("users create", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&user.Username, "username", "", "username")
+		fs.StringVar(&user.Email, "email", "", "email address")
+		fs.StringVar(&user.Password, "password", "", "initial password; empty generates one and requires -invite")
+		fs.BoolVar(&admin, "admin", false, "create an administrator")
+		fs.BoolVar(&invite, "invite", false, "email the user their credentials")
+	}); err != nil {
+		return err
+	}
+	if user.Username == "" || user.Email == "" {
+		return c.usageError("users create needs -username and -email")
+	}
+	if admin {
+		user.Role = "admin"
+	}
+
+	result, err := c.client.CreateUsers(ctx, []*chatclient.NewUser{&user}, invite)
+	if err != nil {
+		return err
+	}
+	if len(result.Results) != 1 {
+		return fmt.Errorf("unexpected response with %d results", len(result.Results))
+	}
+
+	r := result.Results[0]
+	if r.Status != "created" {
+		return fmt.Errorf("user %s %s: %s", r.Username, r.Status, r.Error)
+	}
+	fmt.Fprintf(c.out, "created %s (%s)\n", r.Username, r.UserID)
+	if r.Error != "" {
+		fmt.Fprintf(c.errOut, "warning: %s\n", r.Error)
+	} else if r.Invited {
+		fmt.Fprintf(c.out, "invitation sent to %s\n", r.Email)
+	}
+	return nil
+}
+
+func (c *cli) setAdmin(ctx context.Context, args []string, isAdmin bool) error {
+	name := "users promote"
+	if !isAdmin {
+		name = "users demote"
+	}
+	if len(args) != 1 {
+		return c.usageError(name + " takes one user ID or username")
+	}
+
+	userID, err := c.resolveUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if err := c.client.SetAdmin(ctx, userID, isAdmin); err != nil {
+		return err
+	}
+
+	if isAdmin {
+		fmt.Fprintf(c.out, "%s is now an admin\n", args[0])
+	} else {
+		fmt.Fprintf(c.out, "%s is no longer an admin\n", args[0])
+	}
+	return nil
+}
+
+// resolveUser returns the ID of a user given by ID or exact username
+func (c *cli) resolveUser(ctx context.Context, user string) (string, error) {
+	if _, err := uuid.Parse(user); err == nil {
+		return user, nil
+	}
+
+	users, _, err := c.client.SearchUsers(ctx, user, chatclient.Page{Limit: 100})
+	if err != nil {
+		return "", err
+	}
+	for _, u := range users {
+		if u.Username == user {
+			return u.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no user named %q", user)
+}
+
+func (c *cli) listRooms(ctx context.Context, args []string) error {
+	var roomType string
+	var page chatclient.Page
+	if err := c.parseFlags("rooms list", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&roomType, "type", "", "only rooms of this type: public, private or direct")
+		fs.IntVar(&page.Page, "page", 1, "page number")
+		fs.IntVar(&page.Limit, "limit", 50, "rooms per page")
+	}); err != nil {
+		return err
+	}
+
+	rooms, meta, err := c.client.ListAllRooms(ctx, roomType, page)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tMEMBERS\tOWNER\tCREATED\tARCHIVED")
+	for _, r := range rooms {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", r.ID, r.Name, r.Type, r.MemberCount, r.OwnerID, r.CreatedAt, r.ArchivedAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if meta != nil && meta.HasMore {
+		fmt.Fprintf(c.out, "more rooms: -page %d\n", page.Page+1)
+	}
+	return nil
+}
+
+func (c *cli) listJobs(ctx context.Context) error {
+	jobs, err := c.client.ListJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tINTERVAL\tRUNS\tFAILURES\tITEMS\tLAST RUN\tSTATUS")
+	for _, job := range jobs {
+		lastRun, status := "-", "-"
+		if job.LastRun != nil {
+			lastRun = job.LastRun.StartedAt.Format(time.RFC3339)
+			status = job.LastRun.Status
+		}
+		if job.Running {
+			status = "running"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", job.Name, job.Interval, job.Runs, job.Failures, job.Items, lastRun, status)
+	}
+	return w.Flush()
+}
+
+// runJobs runs jobs one after another; a failed run does not stop the rest
+// but makes the command fail
+func (c *cli) runJobs(ctx context.Context, names []string) error {
+	failed := 0
+	for _, name := range names {
+		run, err := c.client.RunJob(ctx, name)
+		if err != nil {
+			fmt.Fprintf(c.errOut, "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(c.out, "%s: %s, %d item(s) in %dms", name, run.Status, run.Items, run.DurationMS)
+		if run.Error != "" {
+			fmt.Fprintf(c.out, ": %s", run.Error)
+		}
+		fmt.Fprintln(c.out)
+		if run.Status != "succeeded" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d job(s) failed", failed, len(names))
+	}
+	return nil
+}
+
+func (c *cli) purge(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		jobs, err := c.client.ListJobs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if isPurgeJob(job.Name) {
+				names = append(names, job.Name)
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("the server has no purge jobs enabled")
+		}
+	}
+	return c.runJobs(ctx, names)
+}
+
+func (c *cli) rotateJWTKey(ctx context.Context) error {
+	key, err := c.client.RotateJWTKey(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "rotated JWT signing key %s, signing from %s\n", key.ID, key.ActivatesAt)
+	return nil
+}
+
+// tailStats polls the WebSocket stats until ctx is done or count samples
+// have been printed. Stats are per instance, so behind a load balancer
+// successive lines may come from different instances.
+func (c *cli) tailStats(ctx context.Context, args []string) error {
+	var interval time.Duration
+	var count int
+	var all bool
+	if err := c.parseFlags("stats tail", args, func(fs *flag.FlagSet) {
+		fs.DurationVar(&interval, "interval", 2*time.Second, "time between samples")
+		fs.IntVar(&count, "count", 0, "stop after this many samples; 0 runs until interrupted")
+		fs.BoolVar(&all, "all", false, "print every stat as key=value")
+	}); err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return c.usageError("stats tail needs a positive -interval")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	w := tabwriter.NewWriter(c.out, 8, 0, 2, ' ', 0)
+	if !all {
+		fmt.Fprintf(w, "TIME\t%s\n", strings.ToUpper(strings.Join(tailStatsColumns, "\t")))
+	}
+	for n := 0; count == 0 || n < count; n++ {
+		if n > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+
+		stats, err := c.client.WSStats(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		writeStats(w, time.Now(), stats, all)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeStats(w io.Writer, now time.Time, stats map[string]int, all bool) {
+	if all {
+		keys := make([]string, 0, len(stats))
+		for k := range stats {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = fmt.Sprintf("%s=%d", k, stats[k])
+		}
+		fmt.Fprintf(w, "%s %s\n", now.Format(time.TimeOnly), strings.Join(pairs, " "))
+		return
+	}
+
+	fmt.Fprint(w, now.Format(time.TimeOnly))
+	for _, k := range tailStatsColumns {
+		fmt.Fprintf(w, "\t%d", stats[k])
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,143 @@
+// Command chatctl administers a chat server through its admin API: it
+// creates users, grants and revokes the admin role, lists rooms, runs the
+// purge jobs, rotates the JWT signing key and tails the WebSocket stats.
+//
+//	chatctl -server https://chat.example.com -user admin users create -username alice -email alice@example.com -invite
+//
+// It signs in with -user and -password, or uses an access token from
+// -token; each flag also reads a CHATCTL_ environment variable.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-demo/chat/pkg/chatclient"
+)
+
+const usage = `usage: chatctl [flags] <command> [arguments]
+
+commands:
+  users create -username NAME -email EMAIL [-password PW] [-admin] [-invite]
+  users promote USER      grant the admin role (user ID or username)
+  users demote USER       revoke the admin role
+  rooms list [-type public|private|direct] [-page N] [-limit N]
+  jobs list               background jobs of the instance that answers
+  jobs run JOB...         run background jobs now and wait for them
+  purge [JOB...]          run the purge jobs, or only the ones named
+  jwt rotate              rotate the JWT signing key
+  stats tail [-interval D] [-count N] [-all]
+                          print the WebSocket stats every interval`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// errUsage reports a command line that does not parse; the usage has
+// already been printed
+var errUsage = errors.New("usage")
+
+// cli is one invocation of chatctl
+type cli struct {
+	client *chatclient.Client
+	out    io.Writer
+	errOut io.Writer
+}
+
+// run executes chatctl with args and returns the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chatctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "%s\n\nflags:\n", usage)
+		fs.PrintDefaults()
+	}
+	server := fs.String("server", envOr("CHATCTL_SERVER", "http://localhost:8080"), "server base URL (CHATCTL_SERVER)")
+	username := fs.String("user", os.Getenv("CHATCTL_USER"), "admin username to sign in as (CHATCTL_USER)")
+	password := fs.String("password", os.Getenv("CHATCTL_PASSWORD"), "admin password (CHATCTL_PASSWORD)")
+	token := fs.String("token", os.Getenv("CHATCTL_TOKEN"), "access token, instead of signing in (CHATCTL_TOKEN)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	c := &cli{client: chatclient.New(*server), out: stdout, errOut: stderr}
+	switch {
+	case *token != "":
+		c.client.SetToken(&chatclient.Token{AccessToken: *token})
+	case *username != "" && *password != "":
+		if _, err := c.client.Login(ctx, *username, *password); err != nil {
+			fmt.Fprintf(stderr, "chatctl: sign in failed: %v\n", err)
+			return 1
+		}
+	default:
+		fmt.Fprintln(stderr, "chatctl: set -token, or -user and -password")
+		return 2
+	}
+
+	if err := c.dispatch(ctx, fs.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "chatctl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// dispatch runs the command named by args[0] and args[1]
+func (c *cli) dispatch(ctx context.Context, args []string) error {
+	command := args[0]
+	if len(args) > 1 {
+		command += " " + args[1]
+	}
+
+	switch {
+	case command == "users create":
+		return c.createUser(ctx, args[2:])
+	case command == "users promote":
+		return c.setAdmin(ctx, args[2:], true)
+	case command == "users demote":
+		return c.setAdmin(ctx, args[2:], false)
+	case command == "rooms list":
+		return c.listRooms(ctx, args[2:])
+	case command == "jobs list":
+		return c.listJobs(ctx)
+	case command == "jobs run":
+		if len(args) < 3 {
+			return c.usageError("jobs run takes at least one job name")
+		}
+		return c.runJobs(ctx, args[2:])
+	case args[0] == "purge":
+		return c.purge(ctx, args[1:])
+	case command == "jwt rotate":
+		return c.rotateJWTKey(ctx)
+	case command == "stats tail":
+		return c.tailStats(ctx, args[2:])
+	default:
+		return c.usageError(fmt.Sprintf("unknown command %q", command))
+	}
+}
+
+func (c *cli) usageError(msg string) error {
+	fmt.Fprintf(c.errOut, "chatctl: %s\n\n%s\n", msg, usage)
+	return errUsage
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
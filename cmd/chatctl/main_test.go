@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": status < 400, "data": data})
+}
+
+// fakeAdminAPI answers the admin endpoints chatctl calls and records the
+// requests it received
+func fakeAdminAPI(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"user":  map[string]string{"id": "admin-id", "username": "root"},
+			"token": map[string]string{"access_token": "tok", "refresh_token": "ref"},
+		})
+	})
+	mux.HandleFunc("/api/v1/users/search", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []map[string]string{
+			{"id": "11111111-1111-1111-1111-111111111111", "username": "alice2"},
+			{"id": "22222222-2222-2222-2222-222222222222", "username": "alice"},
+		})
+	})
+	mux.HandleFunc("/api/v1/admin/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			writeJSON(w, http.StatusUnauthorized, nil)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.URL.Path {
+		case "/api/v1/admin/users/import":
+			var body struct {
+				Users []map[string]string `json:"users"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"total": 1, "created": 1,
+				"results": []map[string]string{{"username": body.Users[0]["username"], "status": "created", "user_id": "new-id"}},
+			})
+		case "/api/v1/admin/jobs":
+			writeJSON(w, http.StatusOK, []map[string]string{
+				{"name": "room_purge"}, {"name": "mute_expiry"}, {"name": "upload_gc"},
+			})
+		case "/api/v1/admin/jobs/room_purge/run":
+			writeJSON(w, http.StatusOK, map[string]interface{}{"job": "room_purge", "status": "succeeded", "items": 3})
+		case "/api/v1/admin/jobs/upload_gc/run":
+			writeJSON(w, http.StatusOK, map[string]interface{}{"job": "upload_gc", "status": "failed", "error": "storage down"})
+		default:
+			writeJSON(w, http.StatusOK, nil)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func runChatctl(t *testing.T, srv *httptest.Server, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-server", srv.URL, "-user", "root", "-password", "secret"}, args...)
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsersCreate(t *testing.T) {
+	srv, requests := fakeAdminAPI(t)
+
+	code, out, errOut := runChatctl(t, srv, "users", "create", "-username", "bob", "-email", "bob@example.com", "-password", "password123")
+	if code != 0 {
+		t.Fatalf("Expected success, got %d: %s", code, errOut)
+	}
+	if !strings.Contains(out, "created bob (new-id)") {
+		t.Errorf("Unexpected output %q", out)
+	}
+	if len(*requests) != 1 || (*requests)[0] != "POST /api/v1/admin/users/import" {
+		t.Errorf("Unexpected requests %v", *requests)
+	}
+
+	if code, _, _ := runChatctl(t, srv, "users", "create", "-username", "bob"); code != 2 {
+		t.Errorf("Expected a usage error without -email, got %d", code)
+	}
+}
+
+func TestUsersPromoteByUsername(t *testing.T) {
+	srv, requests := fakeAdminAPI(t)
+
+	code, _, errOut := runChatctl(t, srv, "users", "promote", "alice")
+	if code != 0 {
+		t.Fatalf("Expected success, got %d: %s", code, errOut)
+	}
+	if len(*requests) != 1 || (*requests)[0] != "PUT /api/v1/admin/users/22222222-2222-2222-2222-222222222222/admin" {
+		t.Errorf("Expected the exact username match to be promoted, got %v", *requests)
+	}
+
+	code, _, _ = runChatctl(t, srv, "users", "demote", "33333333-3333-3333-3333-333333333333")
+	if code != 0 || (*requests)[1] != "DELETE /api/v1/admin/users/33333333-3333-3333-3333-333333333333/admin" {
+		t.Errorf("Expected the ID to be used as is, got %d %v", code, *requests)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	srv, requests := fakeAdminAPI(t)
+
+	code, out, errOut := runChatctl(t, srv, "purge")
+	if code != 1 || !strings.Contains(errOut, "1 of 2 job(s) failed") {
+		t.Errorf("Expected the failed run to fail the command, got %d %q", code, errOut)
+	}
+	want := []string{
+		"GET /api/v1/admin/jobs",
+		"POST /api/v1/admin/jobs/room_purge/run",
+		"POST /api/v1/admin/jobs/upload_gc/run",
+	}
+	if strings.Join(*requests, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected only the purge jobs to run, got %v", *requests)
+	}
+	if !strings.Contains(out, "room_purge: succeeded, 3 item(s)") || !strings.Contains(out, "upload_gc: failed") {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	srv, _ := fakeAdminAPI(t)
+
+	code, _, errOut := runChatctl(t, srv, "rooms", "delete")
+	if code != 2 || !strings.Contains(errOut, `unknown command "rooms delete"`) {
+		t.Errorf("Expected a usage error, got %d %q", code, errOut)
+	}
+}
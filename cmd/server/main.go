@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,14 +11,32 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/anomaly"
+	"github.com/go-demo/chat/internal/antivirus"
+	"github.com/go-demo/chat/internal/cluster"
 	"github.com/go-demo/chat/internal/config"
+	"github.com/go-demo/chat/internal/events"
+	"github.com/go-demo/chat/internal/features"
 	"github.com/go-demo/chat/internal/handler"
+	"github.com/go-demo/chat/internal/ipfilter"
+	"github.com/go-demo/chat/internal/jobs"
+	"github.com/go-demo/chat/internal/mail"
+	"github.com/go-demo/chat/internal/mail/templates"
 	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/moderation"
 	"github.com/go-demo/chat/internal/pkg/cache"
 	"github.com/go-demo/chat/internal/pkg/database"
+	"github.com/go-demo/chat/internal/pkg/idgen"
+	"github.com/go-demo/chat/internal/pkg/metrics"
+	"github.com/go-demo/chat/internal/pkg/search"
+	"github.com/go-demo/chat/internal/pkg/unfurl"
 	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/probe"
 	"github.com/go-demo/chat/internal/repository"
 	"github.com/go-demo/chat/internal/service"
+	"github.com/go-demo/chat/internal/sso"
+	"github.com/go-demo/chat/internal/system"
 	"github.com/go-demo/chat/internal/ws"
 	"github.com/redis/go-redis/v9"
 	swaggerFiles "github.com/swaggo/files"
@@ -40,6 +59,9 @@ import (
 // @host      localhost:8080
 // @BasePath  /
 
+// version is set at build time via -ldflags "-X main.version=..."
+var version = "dev"
+
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
@@ -57,6 +79,13 @@ func main() {
 	logger := initLogger(cfg.Log.Level)
 	defer func() { _ = logger.Sync() }()
 
+	// `server migrate ...` manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		code := runMigrate(cfg, logger, os.Args[2:])
+		_ = logger.Sync()
+		os.Exit(code)
+	}
+
 	logger.Info("Starting chat server",
 		zap.String("mode", cfg.Server.Mode),
 		zap.Int("port", cfg.Server.Port),
@@ -72,6 +101,13 @@ func main() {
 	}
 	defer database.Close(db, logger)
 
+	// Bring the schema up to date before it is checked below
+	if cfg.Database.AutoMigrate {
+		if err := autoMigrate(db, logger); err != nil {
+			logger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+	}
+
 	// Initialize Redis
 	redisClient, err := cache.NewRedis(&cfg.Redis, logger)
 	if err != nil {
@@ -79,6 +115,35 @@ func main() {
 	}
 	defer cache.Close(redisClient, logger)
 
+	// Prometheus metrics; components register their own as they are built
+	var metricsRegistry *metrics.Registry
+	if cfg.Metrics.Enabled {
+		metricsRegistry = metrics.NewRegistry()
+		metrics.InstrumentDB(metricsRegistry, db.DB)
+		metrics.InstrumentRedis(metricsRegistry, redisClient)
+	}
+
+	// Verify schema level and dependency versions before serving traffic
+	checker := system.NewChecker(db, redisClient, version, cfg.Redis.RequiredModules)
+	checkCtx, checkCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	report := checker.Run(checkCtx)
+	checkCancel()
+
+	logger.Info("Startup report",
+		zap.String("version", report.Version),
+		zap.String("go_version", report.GoVersion),
+		zap.Any("database", report.Database),
+		zap.Any("redis", report.Redis),
+		zap.Any("dependencies", report.Dependencies),
+		zap.Any("checks", report.Checks),
+	)
+	if err := report.Err(); err != nil {
+		if cfg.Server.SelfCheck {
+			logger.Fatal("Startup self-check failed", zap.Error(err))
+		}
+		logger.Warn("Startup self-check failed, continuing because server.self_check is disabled", zap.Error(err))
+	}
+
 	// Initialize JWT manager
 	jwtManager := utils.NewJWTManager(
 		cfg.JWT.Secret,
@@ -86,33 +151,826 @@ func main() {
 		cfg.JWT.RefreshTokenTTL,
 		cfg.JWT.Issuer,
 	)
+	if len(cfg.JWT.SigningKeys) > 0 {
+		signingKeys, err := utils.ParseSigningKeys(cfg.JWT.SigningKeys)
+		if err != nil {
+			logger.Fatal("Invalid jwt signing keys", zap.Error(err))
+		}
+		jwtManager.SetStaticKeys(signingKeys)
+	}
+	if !cfg.JWT.HMAC {
+		jwtManager.DisableHMAC()
+		if !jwtManager.CanSign() {
+			logger.Fatal("jwt.hmac is disabled but no RSA or Ed25519 key in jwt.signing_keys is active")
+		}
+	}
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	roomRepo := repository.NewRoomRepository(db)
+	txManager := repository.NewTxManager(db)
 	messageRepo := repository.NewMessageRepository(db)
 	dmRepo := repository.NewDirectMessageRepository(db)
 	blockedRepo := repository.NewBlockedUserRepository(db)
 	friendshipRepo := repository.NewFriendshipRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	banRepo := repository.NewBanRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	legalHoldRepo := repository.NewLegalHoldRepository(db)
+	deviceRepo := repository.NewDeviceRepository(db)
+	searchRepo := repository.NewSearchRepository(db)
+	ipBanRepo := repository.NewIPBanRepository(db)
+
+	// Apply the configured search analyzer; rebuilds the index when it changed
+	analyzer, err := search.ParseAnalyzer(cfg.Search.Analyzer)
+	if err != nil {
+		logger.Fatal("Invalid search analyzer", zap.Error(err))
+	}
+	rebuilt, err := searchRepo.ApplyAnalyzer(context.Background(), analyzer)
+	if err != nil {
+		logger.Fatal("Failed to apply search analyzer", zap.Error(err))
+	}
+	if rebuilt {
+		logger.Info("Search index rebuilt", zap.String("analyzer", string(analyzer)))
+	}
+	messageRepo.SetSearchAnalyzer(analyzer)
+
+	// Time-ordered message IDs; existing messages keep theirs, cursors accept both
+	idStrategy, err := idgen.ParseStrategy(cfg.Room.MessageIDStrategy)
+	if err != nil {
+		logger.Fatal("Invalid message ID strategy", zap.Error(err))
+	}
+	messageRepo.SetIDStrategy(idStrategy)
+
+	// Feature flags; non-critical features are shed while dependencies are slow
+	var disabledFlags []features.Flag
+	for _, name := range cfg.Features.Disabled {
+		flag, err := features.ParseFlag(name)
+		if err != nil {
+			logger.Fatal("Invalid feature flag", zap.Error(err))
+		}
+		disabledFlags = append(disabledFlags, flag)
+	}
+	featureFlags := features.NewSet(disabledFlags...)
+	syncSearchIndexing(searchRepo, featureFlags.Enabled(features.FlagSearchIndexing), logger)
+	featureFlags.OnChange(features.FlagSearchIndexing, func(enabled bool) {
+		syncSearchIndexing(searchRepo, enabled, logger)
+	})
+
+	degrader := features.NewDegrader(featureFlags, cfg.Features.LatencyThreshold, logger)
+	degrader.SetHysteresis(cfg.Features.TripAfter, cfg.Features.RecoverAfter)
+	degrader.AddProbe("postgres", db.PingContext)
+	degrader.AddProbe("redis", func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	})
 
 	// Initialize services
 	authService := service.NewAuthService(userRepo, jwtManager, logger)
 	userService := service.NewUserService(userRepo, blockedRepo, friendshipRepo, logger)
 	roomService := service.NewRoomService(roomRepo, userRepo, messageRepo, logger)
+	roomService.SetTransactor(txManager)
 	messageService := service.NewMessageService(messageRepo, roomRepo, logger)
 	dmService := service.NewDirectMessageService(dmRepo, userRepo, blockedRepo, logger)
+	notificationService := service.NewNotificationService(notificationRepo, logger)
+	banService := service.NewBanService(banRepo, userRepo, logger)
+	auditService := service.NewAuditService(auditRepo, logger)
+	complianceService := service.NewComplianceService(legalHoldRepo, roomRepo, userRepo, messageRepo, dmRepo, logger)
+	ipBanService := service.NewIPBanService(ipBanRepo, logger)
+	accountService := service.NewAccountService(repository.NewAccountRepository(db), userRepo, legalHoldRepo, deviceRepo, complianceService, logger)
+
+	// Decide what happens to the messages of deleted accounts
+	messageRetention, err := service.ParseRetentionMode(cfg.Account.MessageRetention)
+	if err != nil {
+		logger.Fatal("Invalid account message retention", zap.Error(err))
+	}
+	dmRetention, err := service.ParseRetentionMode(cfg.Account.DMRetention)
+	if err != nil {
+		logger.Fatal("Invalid account direct message retention", zap.Error(err))
+	}
+	accountService.SetRetentionPolicy(service.RetentionPolicy{
+		Messages:       messageRetention,
+		DirectMessages: dmRetention,
+	})
+
+	// Broadcasts take sender names and avatars from an in-process cache;
+	// profile changes drop the entry on every instance through Redis
+	userCache := service.NewUserCache(cfg.Account.DisplayCacheTTL, cfg.Account.DisplayCacheSize, redisClient, logger)
+	userCacheCtx, stopUserCache := context.WithCancel(context.Background())
+	go userCache.Watch(userCacheCtx)
+	userService.SetCache(userCache)
+	authService.SetUserCache(userCache)
+	accountService.SetUserCache(userCache)
+
+	// Identity providers provision accounts and push groups over SCIM.
+	// Groups map to rooms through the same rules as single sign-on.
+	var scimService *service.SCIMService
+	if cfg.SCIM.Token != "" {
+		groupRules, err := sso.ParseGroupRules(cfg.SSO.GroupRooms)
+		if err != nil {
+			logger.Fatal("Invalid sso group rooms", zap.Error(err))
+		}
+		groupRooms := service.NewGroupRooms(groupRules, repository.NewSSORepository(db), roomService, logger)
+		scimService = service.NewSCIMService(repository.NewSCIMRepository(db), userRepo, authService, groupRooms, logger)
+		scimService.SetAuditor(auditService)
+	}
+
+	// Banned, suspended, deleted and deprovisioned accounts may not sign in
+	// or use old tokens
+	accountCheckers := service.AccountCheckers{banService, accountService}
+	if scimService != nil {
+		accountCheckers = append(accountCheckers, scimService)
+	}
+
+	// Tokens that passed the checks skip them for a short while; bans and
+	// deletions drop the user's cached tokens right away
+	var authCache *middleware.AuthCache
+	if cfg.JWT.ValidationCacheTTL > 0 {
+		authCache = middleware.NewAuthCache(cfg.JWT.ValidationCacheTTL, middleware.DefaultAuthCacheSize)
+		banService.SetCredentialCache(authCache)
+		accountService.SetCredentialCache(authCache)
+		if scimService != nil {
+			scimService.SetCredentialCache(authCache)
+		}
+	}
+
+	authService.SetAccountChecker(accountCheckers)
+	authService.SetAuditor(auditService)
+	authService.SetDeviceRepository(deviceRepo)
+	roomService.SetAuditor(auditService)
+	banService.SetAuditor(auditService)
+	complianceService.SetAuditor(auditService)
+	ipBanService.SetAuditor(auditService)
+	accountService.SetAuditor(auditService)
+	userService.SetAuditor(auditService)
+
+	// Keep the IP denylist in memory and in sync with the other instances
+	denylist := ipfilter.NewDenylist(ipBanRepo, redisClient, logger)
+	if err := denylist.Reload(context.Background()); err != nil {
+		logger.Error("Failed to load ip denylist", zap.Error(err))
+	}
+	denylistCtx, stopDenylist := context.WithCancel(context.Background())
+	go denylist.Watch(denylistCtx)
+	ipBanService.SetSyncer(denylist)
+
+	// Allow and deny rules per scope, from the config file and the admin API,
+	// optionally by country
+	var staticRules []*model.IPAccessRule
+	for _, r := range cfg.IPFilter.AccessRules {
+		rule, err := ipfilter.ParseRule(r)
+		if err != nil {
+			logger.Fatal("Invalid ip access rule", zap.Error(err))
+		}
+		staticRules = append(staticRules, rule)
+	}
+	ipAccessRuleRepo := repository.NewIPAccessRuleRepository(db)
+	accessList := ipfilter.NewAccessList(ipAccessRuleRepo, staticRules, redisClient, logger)
+	if cfg.IPFilter.GeoIPDatabase != "" {
+		geoDB, err := ipfilter.LoadGeoDB(cfg.IPFilter.GeoIPDatabase)
+		if err != nil {
+			logger.Fatal("Failed to load geoip database", zap.Error(err))
+		}
+		accessList.SetGeoDB(geoDB)
+		logger.Info("GeoIP database loaded", zap.Int("ranges", geoDB.Len()))
+	}
+	accessList.SetCountryHeader(cfg.IPFilter.CountryHeader)
+	for _, rule := range staticRules {
+		if rule.Country.Valid && !accessList.GeoEnabled() {
+			logger.Fatal("Country access rules need ipfilter.geoip_database or ipfilter.country_header")
+		}
+	}
+	if err := accessList.Reload(context.Background()); err != nil {
+		logger.Error("Failed to load ip access rules", zap.Error(err))
+	}
+	accessListCtx, stopAccessList := context.WithCancel(context.Background())
+	go accessList.Watch(accessListCtx)
+	ipAccessService := service.NewIPAccessService(ipAccessRuleRepo, accessList, logger)
+	ipAccessService.SetAuditor(auditService)
+
+	// Rotated JWT signing keys are stored encrypted, loaded on every instance
+	// and reloaded when one of them rotates
+	keyEncryptionKey := cfg.JWT.KeyEncryptionKey
+	if keyEncryptionKey == "" {
+		keyEncryptionKey = cfg.JWT.Secret
+	}
+	jwtKeyBox, err := utils.NewSecretBox(keyEncryptionKey)
+	if err != nil {
+		logger.Fatal("Invalid jwt key encryption key", zap.Error(err))
+	}
+	jwtKeyService := service.NewJWTKeyService(repository.NewJWTKeyRepository(db), jwtManager, jwtKeyBox, cfg.JWT.KeyActivationDelay, redisClient, logger)
+	if err := jwtKeyService.Reload(context.Background()); err != nil {
+		logger.Error("Failed to load jwt signing keys", zap.Error(err))
+	}
+	jwtKeysCtx, stopJWTKeys := context.WithCancel(context.Background())
+	go jwtKeyService.Watch(jwtKeysCtx)
+	jwtKeyService.SetAuditor(auditService)
+
+	roomService.SetNotifier(notificationService)
+	notificationService.SetBatchWindow(cfg.Notification.BatchWindow)
+	notificationService.SetPreferenceRepository(repository.NewNotificationPreferenceRepository(db))
+	messageService.SetNotifier(notificationService)
+	dmService.SetNotifier(notificationService)
+	dmService.SetEditWindow(cfg.DM.EditWindow)
+	dmService.SetExports(repository.NewDMExportRepository(db), cfg.DMExport.Dir, cfg.DMExport.Retention)
+
+	// End-to-end encrypted DMs: devices publish public keys, the server
+	// relays ciphertext it cannot read
+	deviceKeyRepo := repository.NewDeviceKeyRepository(db)
+	keyService := service.NewKeyService(deviceKeyRepo, deviceRepo, userRepo, blockedRepo, logger)
+	dmService.SetKeyRepository(deviceKeyRepo)
+	roomService.SetDeletionDelay(cfg.Room.DeletionDelay)
+	roomService.SetRetention(cfg.Room.DeletionRetention, handler.UploadDir, fmt.Sprintf("http://localhost:%d", cfg.Server.Port))
+	roomService.SetRateLimitBounds(service.RateLimitBounds{Min: cfg.Room.MinMessageRateLimit, Max: cfg.Room.MessageRateLimit})
+	messageService.SetRateLimiter(middleware.NewRedisRateLimiter(redisClient, cfg.Room.MessageRateLimit, service.RoomRateWindow), cfg.Room.MessageRateLimit)
+	roomService.SetJoinRequestRepository(repository.NewJoinRequestRepository(db))
+	roomService.SetMergeRepository(repository.NewRoomMergeRepository(db))
+	roomService.SetEventRepository(repository.NewRoomEventRepository(db))
+	roomPermissionRepo := repository.NewRoomPermissionRepository(db)
+	roomService.SetPermissionRepository(roomPermissionRepo)
+	messageService.SetPermissionRepository(roomPermissionRepo)
+	messageService.SetScheduledRepository(repository.NewScheduledMessageRepository(db))
+	webhookDispatcher := service.NewWebhookDispatcher(repository.NewRoomWebhookRepository(db),
+		cfg.Webhook.Timeout, cfg.Webhook.MaxAttempts, cfg.Webhook.AllowPrivateTargets, logger)
+	roomService.SetWebhooks(webhookDispatcher)
+	messageService.SetWebhooks(webhookDispatcher)
+	incomingWebhookRepo := repository.NewIncomingWebhookRepository(db)
+	roomService.SetIncomingWebhookRepository(incomingWebhookRepo)
+	messageService.SetIncomingWebhookRepository(incomingWebhookRepo)
+
+	instanceID := cfg.Cluster.InstanceID
+	if instanceID == "" {
+		instanceID = cluster.DefaultInstanceID()
+	}
+
+	// WebSocket text messages are broadcast right away and written to
+	// Postgres in batches from a Redis stream
+	var messageWriter *service.MessageWriter
+	if cfg.WriteBehind.Enabled {
+		messageWriter = service.NewMessageWriter(redisClient, messageRepo, service.WriteBehindConfig{
+			BatchSize:     cfg.WriteBehind.BatchSize,
+			FlushInterval: cfg.WriteBehind.FlushInterval,
+			AtLeastOnce:   cfg.WriteBehind.AtLeastOnce,
+			ClaimAfter:    cfg.WriteBehind.ClaimAfter,
+			Consumer:      instanceID,
+		}, logger)
+		if err := messageWriter.Start(context.Background()); err != nil {
+			logger.Fatal("Failed to start message writer", zap.Error(err))
+		}
+		messageService.SetWriteBehind(messageWriter, userService)
+	}
+
+	// Optional search engine; message changes reach it through the search
+	// outbox, and Postgres answers searches while it is disabled or failing
+	var searchIndexer *service.SearchIndexer
+	if cfg.Search.ElasticEnabled {
+		elasticIndex, err := search.NewElasticIndex(search.ElasticConfig{
+			URL:      cfg.Search.ElasticURL,
+			Index:    cfg.Search.ElasticIndex,
+			Username: cfg.Search.ElasticUsername,
+			Password: cfg.Search.ElasticPassword,
+			Analyzer: cfg.Search.ElasticAnalyzer,
+			Timeout:  cfg.Search.ElasticTimeout,
+		})
+		if err != nil {
+			logger.Fatal("Invalid search engine config", zap.Error(err))
+		}
+		if err := elasticIndex.EnsureIndex(context.Background()); err != nil {
+			logger.Fatal("Failed to create search index", zap.Error(err))
+		}
+		searchIndexer = service.NewSearchIndexer(searchRepo, messageRepo, elasticIndex, cfg.Search.SyncBatchSize, logger)
+		messageService.SetSearchIndex(elasticIndex)
+	}
+	queued, err := searchRepo.SetOutboxEnabled(context.Background(), cfg.Search.ElasticEnabled)
+	if err != nil {
+		logger.Fatal("Failed to update search outbox", zap.Error(err))
+	}
+	if queued > 0 {
+		logger.Info("Queued existing messages for the search engine", zap.Int64("messages", queued))
+	}
+
+	// Domain events go out on an external bus for analytics and indexing
+	eventDriver, err := events.ParseDriver(cfg.Events.Driver)
+	if err != nil {
+		logger.Fatal("Invalid event bus driver", zap.Error(err))
+	}
+	var eventPublisher *events.Publisher
+	if eventDriver != events.DriverNone {
+		var bus events.Bus
+		switch eventDriver {
+		case events.DriverRedis:
+			bus = events.NewRedisBus(redisClient, cfg.Events.StreamMaxLen)
+		case events.DriverNATS:
+			if bus, err = events.NewNATSBus(cfg.Events.URL, "chat-"+instanceID, logger); err != nil {
+				logger.Fatal("Invalid NATS URL", zap.Error(err))
+			}
+		}
+		eventPublisher = events.NewPublisher(bus, cfg.Events.Prefix, instanceID, cfg.Events.BufferSize, logger)
+		if metricsRegistry != nil {
+			eventPublisher.SetMetrics(metricsRegistry)
+		}
+		authService.SetEvents(eventPublisher)
+		roomService.SetEvents(eventPublisher)
+		messageService.SetEvents(eventPublisher)
+		logger.Info("Publishing domain events", zap.String("driver", string(eventDriver)))
+	}
+
+	// Links in messages get Open Graph previews, fetched in the background
+	// and cached in Redis
+	var linkPreviewer *service.LinkPreviewer
+	if cfg.LinkPreview.Enabled {
+		fetcher := unfurl.NewFetcher(unfurl.Config{
+			Timeout:      cfg.LinkPreview.Timeout,
+			MaxBodySize:  cfg.LinkPreview.MaxBodySize,
+			UserAgent:    cfg.LinkPreview.UserAgent,
+			AllowPrivate: cfg.LinkPreview.AllowPrivateTargets,
+		})
+		linkPreviewer = service.NewLinkPreviewer(fetcher, messageRepo, redisClient, service.LinkPreviewConfig{
+			Workers:       cfg.LinkPreview.Workers,
+			QueueSize:     cfg.LinkPreview.QueueSize,
+			MaxPerMessage: cfg.LinkPreview.MaxPerMessage,
+			CacheTTL:      cfg.LinkPreview.CacheTTL,
+		}, logger)
+		linkPreviewer.SetNotifier(notificationService)
+		linkPreviewer.SetFeatures(featureFlags)
+		messageService.SetLinkPreviewer(linkPreviewer)
+	}
+
+	// Parse mail templates up front so a broken override fails at startup
+	mailTemplates, err := templates.New(templates.Site{
+		Name: cfg.Mail.SiteName,
+		URL:  cfg.Mail.SiteURL,
+	}, cfg.Mail.DefaultLocale, cfg.Mail.TemplateDir)
+	if err != nil {
+		logger.Fatal("Failed to load mail templates", zap.Error(err))
+	}
+	var mailSender mail.Sender = mail.NewLogSender(logger)
+	if cfg.Mail.SMTPHost != "" {
+		mailSender = mail.NewSMTPSender(mail.SMTPConfig{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+			From:     cfg.Mail.From,
+		})
+	}
+
+	userImportService := service.NewUserImportService(userRepo, logger)
+	userImportService.SetMailer(mailTemplates, mailSender)
+	userImportService.SetAuditor(auditService)
+
+	digestService := service.NewDigestService(repository.NewDigestRepository(db), logger)
+	digestService.SetMailer(mailTemplates, mailSender)
+
+	readStateService := service.NewReadStateService(repository.NewReadStateRepository(db), logger)
+
+	// Resumable uploads keep partial files outside the public uploads directory
+	uploadSessionService := service.NewUploadSessionService(repository.NewUploadSessionRepository(db), cfg.Upload.PartialDir, logger)
+	uploadSessionService.SetSessionTTL(cfg.Upload.SessionTTL)
+
+	// Sizes and types admins save at runtime take precedence over the config
+	uploadSettingsService := service.NewUploadSettingsService(repository.NewUploadSettingsRepository(db), model.UploadLimits{
+		Image:  uploadLimit(cfg.Upload.Image),
+		File:   uploadLimit(cfg.Upload.File),
+		Avatar: uploadLimit(cfg.Upload.Avatar),
+	}, logger)
+	uploadSettingsService.SetAuditor(auditService)
+
+	// Curated sticker packs; sticker messages are checked against them
+	stickerRepo := repository.NewStickerRepository(db)
+	stickerService := service.NewStickerService(stickerRepo, logger)
+	stickerService.SetAuditor(auditService)
+	messageService.SetStickerRepository(stickerRepo)
+
+	// Uploaded images and files, attached to messages by ID
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	attachmentService := service.NewAttachmentService(attachmentRepo, cfg.Upload.OrphanTTL, logger)
+	messageService.SetAttachments(attachmentService)
+	dmService.SetAttachments(attachmentService)
+	storageQuotaService := service.NewStorageQuotaService(attachmentRepo, cfg.Upload.UserQuota, logger)
+
+	// Uploaded avatars replace the profile's avatar and the previous files
+	avatarService := service.NewAvatarService(userRepo, logger)
+	avatarService.SetCache(userCache)
+
+	// New uploads are scanned by clamd in the background; infected files
+	// are moved out of the uploads directory
+	var uploadScanner *service.UploadScanner
+	if cfg.Upload.Scan.Enabled {
+		uploadScanner = service.NewUploadScanner(
+			antivirus.NewClamAVScanner(cfg.Upload.Scan.ClamAVAddress, cfg.Upload.Scan.Timeout),
+			attachmentRepo,
+			service.UploadScanConfig{
+				Workers:       cfg.Upload.Scan.Workers,
+				QuarantineDir: cfg.Upload.Scan.QuarantineDir,
+			}, logger)
+		uploadScanner.SetNotifier(notificationService)
+		attachmentService.SetScanner(uploadScanner)
+	}
+
+	feedbackService := service.NewFeedbackService(repository.NewFeedbackRepository(db), logger)
+	if cfg.Feedback.WebhookURL != "" {
+		feedbackService.SetForwarder(service.NewWebhookFeedbackForwarder(
+			cfg.Feedback.WebhookURL, cfg.Feedback.WebhookSecret, cfg.Feedback.WebhookTimeout))
+	}
+
+	// Uploaded images are scored by the detection API when one is configured
+	var imageDetector moderation.Detector = &moderation.StubDetector{}
+	if cfg.Moderation.ImageAPIURL != "" {
+		imageDetector = moderation.NewHTTPDetector(
+			cfg.Moderation.ImageAPIURL, cfg.Moderation.ImageAPIKey, cfg.Moderation.ImageAPITimeout)
+	}
+	imageModerationService := service.NewImageModerationService(repository.NewImageModerationRepository(db), imageDetector, logger)
+	imageModerationService.SetAuditor(auditService)
+
+	// Message text is checked against the word list and, when configured,
+	// scored by the text moderation API
+	moderationWords := cfg.Moderation.WordList
+	if cfg.Moderation.WordListFile != "" {
+		fileWords, err := moderation.LoadWordList(cfg.Moderation.WordListFile)
+		if err != nil {
+			logger.Fatal("Failed to load moderation word list", zap.Error(err))
+		}
+		moderationWords = append(moderationWords, fileWords...)
+	}
+	wordFilter := moderation.NewWordFilter(moderationWords)
+	var textClassifier moderation.TextClassifier
+	if cfg.Moderation.TextAPIURL != "" {
+		textClassifier = moderation.NewHTTPTextClassifier(
+			cfg.Moderation.TextAPIURL, cfg.Moderation.TextAPIKey, cfg.Moderation.TextAPITimeout)
+	}
+	messageFlagRepo := repository.NewMessageFlagRepository(db)
+	if wordFilter.Len() > 0 || textClassifier != nil {
+		messageService.SetModeration(service.NewMessageModerator(wordFilter, textClassifier, service.MessageModerationThresholds{
+			Flag:        cfg.Moderation.FlagThreshold,
+			Block:       cfg.Moderation.BlockThreshold,
+			StrictFlag:  cfg.Moderation.StrictFlagThreshold,
+			StrictBlock: cfg.Moderation.StrictBlockThreshold,
+		}, logger), messageFlagRepo)
+	}
+	messageModerationService := service.NewMessageModerationService(messageFlagRepo, logger)
+	messageModerationService.SetAuditor(auditService)
+	reportService := service.NewReportService(repository.NewReportRepository(db), messageService, userRepo, logger)
+	reportService.SetNotifier(notificationService)
+	reportService.SetAuditor(auditService)
+
+	groupService := service.NewGroupConversationService(repository.NewGroupConversationRepository(db), userRepo, blockedRepo, logger)
+	groupService.SetNotifier(notificationService)
+	spamService := service.NewSpamSweepService(repository.NewSpamRepository(db), banService, service.SpamPolicy{
+		MinAccountAge:          cfg.Spam.MinAccountAge,
+		MaxAccountAge:          cfg.Spam.MaxAccountAge,
+		FriendRequestThreshold: cfg.Spam.FriendRequestThreshold,
+		DisposableDomains:      cfg.Spam.DisposableDomains,
+		FlagScore:              cfg.Spam.FlagScore,
+		SuspendScore:           cfg.Spam.SuspendScore,
+		SuspendDuration:        cfg.Spam.SuspendDuration,
+	}, logger)
+	spamService.SetAuditor(auditService)
 
 	// Initialize WebSocket hub
 	hub := ws.NewHub(roomService, messageService, dmService, userService, redisClient, logger)
+	slowConsumerPolicy, err := ws.ParseSlowConsumerPolicy(cfg.WS.SlowConsumerPolicy)
+	if err != nil {
+		logger.Fatal("Invalid WebSocket slow consumer policy", zap.Error(err))
+	}
+	hub.SetSendQueue(cfg.WS.SendBufferSize, slowConsumerPolicy)
+	hub.SetFanoutWorkers(cfg.WS.FanoutWorkers)
+	hub.SetWriteTimeout(cfg.WS.WriteTimeout)
+	if err := hub.SetHeartbeat(cfg.WS.PingInterval, cfg.WS.PongTimeout); err != nil {
+		logger.Fatal("Invalid WebSocket heartbeat settings", zap.Error(err))
+	}
+	hub.SetFeatures(featureFlags)
+	hub.SetTickets(ws.NewTicketStore(redisClient, cfg.WS.ReconnectTicketTTL))
+	hub.SetDeviceChecker(authService)
+	hub.SetReplay(ws.NewReplayBuffer(redisClient, cfg.WS.ReplaySize, cfg.WS.ReplayTTL))
+	hub.SetMaxMessageSize(cfg.WS.MaxMessageSize)
+	hub.SetContents(ws.NewContentStore(redisClient, cfg.WS.ContentRefTTL))
+	hub.SetFloodPolicy(ws.FloodPolicy{
+		MaxPerSecond:    cfg.WS.FloodMaxPerSecond,
+		DuplicateLimit:  cfg.WS.FloodDuplicateLimit,
+		DuplicateWindow: cfg.WS.FloodDuplicateWindow,
+		Warnings:        cfg.WS.FloodWarnings,
+		MuteDuration:    cfg.WS.FloodMuteDuration,
+	})
+	if metricsRegistry != nil {
+		hub.SetMetrics(metricsRegistry)
+	}
 	go hub.Run()
+	notificationService.SetPublisher(hub)
+	banService.SetDisconnector(hub)
+	authService.SetDeviceDisconnector(hub)
+	accountService.SetDisconnector(hub)
+	if scimService != nil {
+		scimService.SetDisconnector(hub)
+	}
+	messageService.SetPublisher(hub)
+	roomService.SetMessagePublisher(hub)
+
+	// Registration spikes, mass friend requests and message floods alert the
+	// admins through the notification center and the optional webhook
+	if cfg.Abuse.Enabled {
+		detector := anomaly.NewDetector(anomaly.NewRedisCounter(redisClient), []anomaly.Rule{
+			{Signal: anomaly.SignalRegistration, Threshold: cfg.Abuse.RegistrationThreshold, Window: cfg.Abuse.RegistrationWindow},
+			{Signal: anomaly.SignalFriendRequest, Threshold: cfg.Abuse.FriendRequestThreshold, Window: cfg.Abuse.FriendRequestWindow},
+			{Signal: anomaly.SignalMessage, Threshold: cfg.Abuse.MessageThreshold, Window: cfg.Abuse.MessageWindow},
+		}, logger)
+		abuseAlertService := service.NewAbuseAlertService(userRepo, notificationService, logger)
+		if cfg.Abuse.WebhookURL != "" {
+			abuseAlertService.SetWebhook(cfg.Abuse.WebhookURL, cfg.Abuse.WebhookSecret, cfg.Abuse.WebhookTimeout)
+		}
+		detector.SetAlerter(abuseAlertService)
+		if metricsRegistry != nil {
+			detector.SetMetrics(metricsRegistry)
+		}
+		authService.SetAnomalyDetector(detector)
+		userService.SetAnomalyDetector(detector)
+		messageService.SetAnomalyDetector(detector)
+	}
+
+	// Enterprise single sign-on: accounts are provisioned on first login and
+	// directory groups map to rooms
+	var ssoService *service.SSOService
+	if cfg.SSO.Mode != service.SSOModeLocal {
+		ssoCfg, err := ssoConfig(cfg.SSO)
+		if err != nil {
+			logger.Fatal("Invalid single sign-on config", zap.Error(err))
+		}
+		ssoService = service.NewSSOService(ssoCfg, repository.NewSSORepository(db), userRepo, authService, roomService, redisClient, logger)
+		ssoService.SetAuditor(auditService)
+		logger.Info("Single sign-on enabled", zap.String("mode", cfg.SSO.Mode), zap.Bool("local_login", cfg.SSO.LocalLogin))
+	}
+
+	// Initialize background jobs
+	scheduler := jobs.NewScheduler(logger)
+	if metricsRegistry != nil {
+		scheduler.SetMetrics(metricsRegistry)
+	}
+	scheduler.Register("room_deletion", cfg.Room.DeletionSweepInterval, func(ctx context.Context) error {
+		n, err := roomService.PurgeScheduledDeletions(ctx, 100)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	if cfg.Room.DeletionRetention > 0 {
+		scheduler.Register("room_purge", cfg.Room.PurgeInterval, func(ctx context.Context) error {
+			n, err := roomService.PurgeDeletedRooms(ctx, 20)
+			jobs.AddItems(ctx, n)
+			return err
+		})
+	}
+	scheduler.Register("mute_expiry", cfg.Room.MuteSweepInterval, func(ctx context.Context) error {
+		n, err := roomService.ExpireMutes(ctx, 500)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("scheduled_messages", cfg.Room.ScheduledSendInterval, func(ctx context.Context) error {
+		n, err := messageService.DeliverScheduledMessages(ctx, 100)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("room_event_reminders", cfg.Room.EventReminderInterval, func(ctx context.Context) error {
+		n, err := roomService.SendEventReminders(ctx, cfg.Room.EventReminderLead, 100)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("room_merges", cfg.Room.MergeInterval, func(ctx context.Context) error {
+		n, err := roomService.ProcessRoomMerges(ctx)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("room_counters", cfg.Room.CounterInterval, func(ctx context.Context) error {
+		n, err := roomService.ReconcileCounters(ctx, 500)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("room_activity_purge", time.Hour, func(ctx context.Context) error {
+		n, err := roomService.PurgeActivity(ctx)
+		jobs.AddItems(ctx, int(n))
+		return err
+	})
+	scheduler.Register("message_expiry", cfg.Room.ExpirySweepInterval, func(ctx context.Context) error {
+		n, err := messageService.ExpireMessages(ctx, 500)
+		jobs.AddItems(ctx, n)
+		if err != nil {
+			return err
+		}
+		n, err = dmService.ExpireMessages(ctx, 500)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("room_webhooks", cfg.Webhook.DeliverInterval, func(ctx context.Context) error {
+		n, err := webhookDispatcher.DeliverDue(ctx, 100)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("room_webhook_purge", time.Hour, func(ctx context.Context) error {
+		n, err := webhookDispatcher.PurgeDeliveries(ctx, cfg.Webhook.DeliveryRetention)
+		jobs.AddItems(ctx, int(n))
+		return err
+	})
+	scheduler.Register("ip_denylist", cfg.IPFilter.RefreshInterval, func(ctx context.Context) error {
+		n, err := ipBanService.PurgeExpired(ctx)
+		jobs.AddItems(ctx, int(n))
+		if err != nil {
+			return err
+		}
+		if err := denylist.Reload(ctx); err != nil {
+			return err
+		}
+		return accessList.Reload(ctx)
+	})
+	scheduler.Register("jwt_keys", cfg.JWT.KeyRefreshInterval, jwtKeyService.Reload)
+	scheduler.Register("dm_exports", cfg.DMExport.ProcessInterval, func(ctx context.Context) error {
+		n, err := dmService.ProcessExports(ctx, 5)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("dm_export_purge", time.Hour, func(ctx context.Context) error {
+		n, err := dmService.PurgeExports(ctx, 100)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	scheduler.Register("upload_sessions", cfg.Upload.SweepInterval, func(ctx context.Context) error {
+		n, err := uploadSessionService.PurgeExpired(ctx, 100)
+		jobs.AddItems(ctx, n)
+		return err
+	})
+	if cfg.Upload.OrphanTTL > 0 {
+		scheduler.Register("upload_gc", cfg.Upload.GCInterval, func(ctx context.Context) error {
+			n, err := attachmentService.CollectGarbage(ctx, 200)
+			jobs.AddItems(ctx, n)
+			return err
+		})
+	}
+	if cfg.Upload.Scan.Enabled {
+		scheduler.Register("upload_scan_retry", cfg.Upload.Scan.RetryInterval, func(ctx context.Context) error {
+			n, err := uploadScanner.RetryPending(ctx, 100)
+			jobs.AddItems(ctx, n)
+			return err
+		})
+	}
+	if cfg.Feedback.WebhookURL != "" {
+		scheduler.Register("feedback_forward", cfg.Feedback.ForwardInterval, func(ctx context.Context) error {
+			n, err := feedbackService.ForwardPending(ctx, 50)
+			jobs.AddItems(ctx, n)
+			return err
+		})
+	}
+	if cfg.Mail.DigestEnabled {
+		scheduler.Register("mail_digests", cfg.Mail.DigestInterval, func(ctx context.Context) error {
+			n, err := digestService.SendDue(ctx, cfg.Mail.DigestBatchSize)
+			jobs.AddItems(ctx, n)
+			return err
+		})
+	}
+	if cfg.Spam.Enabled {
+		scheduler.Register("spam_sweep", cfg.Spam.Interval, func(ctx context.Context) error {
+			run, err := spamService.Sweep(ctx, cfg.Spam.BatchSize)
+			if run != nil {
+				jobs.AddItems(ctx, run.Scanned)
+			}
+			return err
+		})
+	}
+	if searchIndexer != nil {
+		scheduler.Register("search_index", cfg.Search.SyncInterval, func(ctx context.Context) error {
+			// Changes stay queued while indexing is shed
+			if !featureFlags.Enabled(features.FlagSearchIndexing) {
+				return nil
+			}
+			n, err := searchIndexer.Sync(ctx)
+			jobs.AddItems(ctx, n)
+			return err
+		})
+	}
+	if cfg.Features.AutoDegrade {
+		scheduler.Register("degradation", cfg.Features.ProbeInterval, degrader.Check)
+	}
+
+	// Register this instance so operators can see and drain the fleet
+	var registry *cluster.Registry
+	if cfg.Cluster.Register {
+		advertiseAddr := cfg.Cluster.AdvertiseAddr
+		if advertiseAddr == "" {
+			advertiseAddr = cfg.Server.GetAddr()
+		}
+		registry = cluster.NewRegistry(redisClient, instanceID, advertiseAddr, version, cfg.Cluster.HeartbeatInterval, logger)
+		registry.SetConnectionCounter(hub.ConnectionCount)
+		registry.SetHealthCheck(func() bool { return !degrader.Degraded() })
+		registry.OnDrainChange(func(draining bool) {
+			if draining {
+				hub.Drain(cfg.Cluster.DrainWindow)
+			} else {
+				hub.Undrain()
+			}
+		})
+		if err := registry.Register(context.Background()); err != nil {
+			logger.Fatal("Failed to register instance", zap.Error(err))
+		}
+		scheduler.Register("instance_heartbeat", cfg.Cluster.HeartbeatInterval, registry.Heartbeat)
+		logger.Info("Instance registered",
+			zap.String("instance_id", instanceID),
+			zap.String("address", advertiseAddr),
+		)
+	}
+
+	// Probe end-to-end message delivery through the public API
+	var deliveryProber *probe.DeliveryProber
+	if cfg.Probe.Enabled {
+		baseURL := cfg.Probe.BaseURL
+		if baseURL == "" {
+			baseURL = fmt.Sprintf("http://127.0.0.1:%d", cfg.Server.Port)
+		}
+		deliveryProber = probe.NewDeliveryProber(probe.Config{
+			BaseURL:          baseURL,
+			Username:         cfg.Probe.Username,
+			Password:         cfg.Probe.Password,
+			RoomID:           cfg.Probe.RoomID,
+			Timeout:          cfg.Probe.Timeout,
+			LatencyThreshold: cfg.Probe.LatencyThreshold,
+			FailAfter:        cfg.Probe.FailAfter,
+		}, logger)
+		scheduler.Register("delivery_probe", cfg.Probe.Interval, deliveryProber.Run)
+	}
+	scheduler.Start(context.Background())
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
+	ssoHandler := handler.NewSSOHandler(ssoService, cfg.SSO.CallbackURL)
+	if ssoService != nil {
+		authHandler.SetSSO(ssoService)
+	}
+	var scimHandler *handler.SCIMHandler
+	if scimService != nil {
+		scimHandler = handler.NewSCIMHandler(scimService, cfg.Mail.SiteURL)
+	}
 	userHandler := handler.NewUserHandler(userService)
 	roomHandler := handler.NewRoomHandler(roomService)
+	roomHandler.SetFeedCache(cache.NewCache(redisClient, logger), cfg.Room.FeedCacheTTL)
+	roomHandler.SetSiteURL(cfg.Mail.SiteURL)
 	messageHandler := handler.NewMessageHandler(messageService, roomService, dmService)
+	groupHandler := handler.NewGroupConversationHandler(groupService)
+	messageHandler.SetPublisher(hub)
+	messageHandler.SetWaiter(hub)
 	uploadHandler := handler.NewUploadHandler(fmt.Sprintf("http://localhost:%d", cfg.Server.Port))
+	uploadHandler.SetUploadSettings(uploadSettingsService)
+	uploadHandler.SetSessionService(uploadSessionService)
+	uploadHandler.SetImageModeration(imageModerationService)
+	uploadHandler.SetAttachments(attachmentService)
+	uploadHandler.SetStorageQuota(storageQuotaService)
+	uploadHandler.SetAvatars(avatarService)
+	feedbackHandler := handler.NewFeedbackHandler(feedbackService, fmt.Sprintf("http://localhost:%d", cfg.Server.Port))
+	feedbackHandler.SetUploadSettings(uploadSettingsService)
+	metaHandler := handler.NewMetaHandler(uploadSettingsService)
 	wsHandler := ws.NewHandler(hub, jwtManager, logger)
+	wsHandler.SetAccountChecker(accountCheckers)
+	if err := wsHandler.SetCompression(cfg.WS.Compression, cfg.WS.CompressionLevel); err != nil {
+		logger.Fatal("Invalid WebSocket compression settings", zap.Error(err))
+	}
+	adminHandler := handler.NewAdminHandler(checker, logger)
+	adminHandler.SetDegrader(degrader)
+	adminHandler.SetScheduler(scheduler)
+	adminHandler.SetJWTKeyService(jwtKeyService)
+	if registry != nil {
+		adminHandler.SetRegistry(registry)
+	}
+	banHandler := handler.NewBanHandler(banService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	complianceHandler := handler.NewComplianceHandler(complianceService)
+	ipBanHandler := handler.NewIPBanHandler(ipBanService)
+	ipAccessHandler := handler.NewIPAccessHandler(ipAccessService)
+	mailHandler := handler.NewMailHandler(mailTemplates, userService)
+	userImportHandler := handler.NewUserImportHandler(userImportService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	imageModerationHandler := handler.NewImageModerationHandler(imageModerationService)
+	messageModerationHandler := handler.NewMessageModerationHandler(messageModerationService)
+	reportHandler := handler.NewReportHandler(reportService)
+	spamHandler := handler.NewSpamHandler(spamService)
+	keyHandler := handler.NewKeyHandler(keyService)
+	stickerHandler := handler.NewStickerHandler(stickerService)
+
+	// Per-user caps on endpoints that can keep the database busy
+	searchLimiter := middleware.NewConcurrencyLimiter("search", cfg.Concurrency.SearchPerUser, cfg.Concurrency.QueueSize, cfg.Concurrency.QueueTimeout)
+	exportLimiter := middleware.NewConcurrencyLimiter("export", cfg.Concurrency.ExportPerUser, cfg.Concurrency.QueueSize, cfg.Concurrency.QueueTimeout)
+	adminHandler.SetConcurrencyLimiters(searchLimiter, exportLimiter)
+	notificationSettingsHandler := handler.NewNotificationSettingsHandler(notificationService)
+	readStateHandler := handler.NewReadStateHandler(readStateService)
+
+	// Readiness fails while a dependency is down or the instance is draining,
+	// so load balancers stop sending it new traffic
+	readiness := system.NewReadiness(cfg.Server.ReadinessTimeout)
+	readiness.Add("postgres", db.PingContext)
+	readiness.Add("redis", func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	})
+	readiness.Add("websocket", func(ctx context.Context) error {
+		if hub.Draining() {
+			return errors.New("instance is draining")
+		}
+		return nil
+	})
+	healthHandler := handler.NewHealthHandler(readiness)
+	jwksHandler := handler.NewJWKSHandler(jwtManager)
+	if deliveryProber != nil {
+		healthHandler.SetDeliveryProber(deliveryProber)
+	}
 
 	// Setup router
 	router := setupRouter(
@@ -121,11 +979,43 @@ func main() {
 		jwtManager,
 		redisClient,
 		authHandler,
+		ssoHandler,
+		scimHandler,
 		userHandler,
 		roomHandler,
 		messageHandler,
+		groupHandler,
 		uploadHandler,
 		wsHandler,
+		adminHandler,
+		banHandler,
+		auditHandler,
+		complianceHandler,
+		ipBanHandler,
+		ipAccessHandler,
+		mailHandler,
+		userImportHandler,
+		feedbackHandler,
+		accountHandler,
+		imageModerationHandler,
+		messageModerationHandler,
+		reportHandler,
+		spamHandler,
+		keyHandler,
+		stickerHandler,
+		notificationSettingsHandler,
+		readStateHandler,
+		healthHandler,
+		jwksHandler,
+		metaHandler,
+		userService,
+		accountCheckers,
+		authCache,
+		denylist,
+		accessList,
+		searchLimiter,
+		exportLimiter,
+		metricsRegistry,
 	)
 
 	// Create server
@@ -161,9 +1051,90 @@ func main() {
 		logger.Error("Server forced to shutdown", zap.Error(err))
 	}
 
+	scheduler.Stop()
+	if messageWriter != nil {
+		messageWriter.Stop()
+	}
+	stopDenylist()
+	stopAccessList()
+	stopJWTKeys()
+	stopUserCache()
+	linkPreviewer.Close()
+	uploadScanner.Close()
+	notificationService.Flush()
+	eventPublisher.Close()
+	if deliveryProber != nil {
+		deliveryProber.Close()
+	}
+	if registry != nil {
+		if err := registry.Deregister(ctx); err != nil {
+			logger.Warn("Failed to deregister instance", zap.Error(err))
+		}
+	}
+
 	logger.Info("Server exited")
 }
 
+func uploadLimit(c config.UploadCategoryConfig) model.UploadLimit {
+	return model.UploadLimit{
+		MaxSize:      c.MaxSize,
+		AllowedTypes: c.AllowedTypes,
+		SubDir:       c.SubDir,
+	}
+}
+
+// ssoConfig builds the single sign-on settings for c.Mode
+func ssoConfig(c config.SSOConfig) (service.SSOConfig, error) {
+	rules, err := sso.ParseGroupRules(c.GroupRooms)
+	if err != nil {
+		return service.SSOConfig{}, err
+	}
+	out := service.SSOConfig{
+		Mode:        c.Mode,
+		LocalLogin:  c.LocalLogin,
+		LinkByEmail: c.LinkByEmail,
+		GroupRules:  rules,
+	}
+
+	switch c.Mode {
+	case service.SSOModeLDAP:
+		out.LDAP, err = sso.NewLDAPAuthenticator(sso.LDAPConfig{
+			URL:                  c.LDAPURL,
+			StartTLS:             c.LDAPStartTLS,
+			UserDNTemplate:       c.LDAPUserDNTemplate,
+			BindDN:               c.LDAPBindDN,
+			BindPassword:         c.LDAPBindPassword,
+			BaseDN:               c.LDAPBaseDN,
+			UserFilter:           c.LDAPUserFilter,
+			UsernameAttribute:    c.LDAPUsernameAttribute,
+			EmailAttribute:       c.LDAPEmailAttribute,
+			DisplayNameAttribute: c.LDAPDisplayNameAttribute,
+			GroupAttribute:       c.LDAPGroupAttribute,
+			Timeout:              c.LDAPTimeout,
+		})
+	case service.SSOModeSAML:
+		cert, certErr := sso.ParseCertificate(c.SAMLIdPCertificate)
+		if certErr != nil {
+			return service.SSOConfig{}, certErr
+		}
+		out.SAML, err = sso.NewSAMLServiceProvider(sso.SAMLConfig{
+			EntityID:             c.SAMLEntityID,
+			ACSURL:               c.SAMLACSURL,
+			IdPSSOURL:            c.SAMLIdPSSOURL,
+			IdPEntityID:          c.SAMLIdPEntityID,
+			IdPCertificate:       cert,
+			UsernameAttribute:    c.SAMLUsernameAttribute,
+			EmailAttribute:       c.SAMLEmailAttribute,
+			DisplayNameAttribute: c.SAMLDisplayNameAttribute,
+			GroupsAttribute:      c.SAMLGroupsAttribute,
+			ClockSkew:            c.SAMLClockSkew,
+		})
+	default:
+		err = fmt.Errorf("unknown sso mode %q", c.Mode)
+	}
+	return out, err
+}
+
 func initLogger(level string) *zap.Logger {
 	var zapLevel zapcore.Level
 	switch level {
@@ -199,33 +1170,133 @@ func initLogger(level string) *zap.Logger {
 	return logger
 }
 
+// syncSearchIndexing pauses or resumes the search index trigger to match the
+// search_indexing flag, backfilling messages written while it was paused
+func syncSearchIndexing(searchRepo *repository.SearchRepository, enabled bool, logger *zap.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if !enabled {
+		if err := searchRepo.PauseIndexing(ctx); err != nil {
+			logger.Error("Failed to pause search indexing", zap.Error(err))
+			return
+		}
+		logger.Info("Search indexing paused")
+		return
+	}
+
+	indexed, err := searchRepo.ResumeIndexing(ctx)
+	if err != nil {
+		logger.Error("Failed to resume search indexing", zap.Error(err))
+		return
+	}
+	if indexed > 0 {
+		logger.Info("Search indexing resumed", zap.Int64("backfilled", indexed))
+	}
+}
+
 func setupRouter(
 	cfg *config.Config,
 	logger *zap.Logger,
 	jwtManager *utils.JWTManager,
 	redisClient *redis.Client,
 	authHandler *handler.AuthHandler,
+	ssoHandler *handler.SSOHandler,
+	scimHandler *handler.SCIMHandler,
 	userHandler *handler.UserHandler,
 	roomHandler *handler.RoomHandler,
 	messageHandler *handler.MessageHandler,
+	groupHandler *handler.GroupConversationHandler,
 	uploadHandler *handler.UploadHandler,
 	wsHandler *ws.Handler,
+	adminHandler *handler.AdminHandler,
+	banHandler *handler.BanHandler,
+	auditHandler *handler.AuditHandler,
+	complianceHandler *handler.ComplianceHandler,
+	ipBanHandler *handler.IPBanHandler,
+	ipAccessHandler *handler.IPAccessHandler,
+	mailHandler *handler.MailHandler,
+	userImportHandler *handler.UserImportHandler,
+	feedbackHandler *handler.FeedbackHandler,
+	accountHandler *handler.AccountHandler,
+	imageModerationHandler *handler.ImageModerationHandler,
+	messageModerationHandler *handler.MessageModerationHandler,
+	reportHandler *handler.ReportHandler,
+	spamHandler *handler.SpamHandler,
+	keyHandler *handler.KeyHandler,
+	stickerHandler *handler.StickerHandler,
+	notificationSettingsHandler *handler.NotificationSettingsHandler,
+	readStateHandler *handler.ReadStateHandler,
+	healthHandler *handler.HealthHandler,
+	jwksHandler *handler.JWKSHandler,
+	metaHandler *handler.MetaHandler,
+	adminChecker middleware.AdminChecker,
+	accountChecker middleware.AccountChecker,
+	authCache *middleware.AuthCache,
+	ipBlocker middleware.IPBlocker,
+	ipAccess middleware.IPAccessChecker,
+	searchLimiter *middleware.ConcurrencyLimiter,
+	exportLimiter *middleware.ConcurrencyLimiter,
+	metricsRegistry *metrics.Registry,
 ) *gin.Engine {
 	router := gin.New()
+	if err := middleware.TrustProxies(router, cfg.Server.TrustedProxies, cfg.Server.ClientIPHeaders); err != nil {
+		logger.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
+
+	// Authentication also rejects banned, suspended and deleted accounts
+	requireAuth := middleware.AuthWithCache(jwtManager, authCache, accountChecker)
+	limitSearch := middleware.ConcurrencyLimit(searchLimiter)
+	limitExport := middleware.ConcurrencyLimit(exportLimiter)
 
 	// Global middleware
 	router.Use(middleware.RequestID())
 	router.Use(middleware.Recovery(logger))
+	if metricsRegistry != nil {
+		router.Use(middleware.Metrics(metricsRegistry))
+	}
 	router.Use(middleware.Logger(logger))
+	router.Use(middleware.IPFilter(ipBlocker, cfg.IPFilter.TarpitDelay))
+	router.Use(middleware.IPAccess(ipAccess, model.IPAccessScopeGlobal))
 	router.Use(middleware.CORS())
+	if cfg.Abuse.Enabled {
+		router.Use(middleware.ClientIPContext())
+	}
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"timestamp": time.Now().Format(time.RFC3339),
-		})
-	})
+	// Prometheus scrape endpoint
+	if metricsRegistry != nil {
+		router.GET(cfg.Metrics.Path, middleware.IPAccess(ipAccess, model.IPAccessScopeMetrics), middleware.MetricsToken(cfg.Metrics.Token), gin.WrapH(metricsRegistry.Handler()))
+	}
+
+	// SCIM provisioning for identity providers
+	if scimHandler != nil {
+		scimAPI := router.Group("/scim/v2", middleware.IPAccess(ipAccess, model.IPAccessScopeSCIM), middleware.SCIMToken(cfg.SCIM.Token))
+		{
+			scimAPI.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+			scimAPI.GET("/ResourceTypes", scimHandler.ResourceTypes)
+
+			scimAPI.GET("/Users", scimHandler.ListUsers)
+			scimAPI.POST("/Users", scimHandler.CreateUser)
+			scimAPI.GET("/Users/:id", scimHandler.GetUser)
+			scimAPI.PUT("/Users/:id", scimHandler.ReplaceUser)
+			scimAPI.PATCH("/Users/:id", scimHandler.PatchUser)
+			scimAPI.DELETE("/Users/:id", scimHandler.DeleteUser)
+
+			scimAPI.GET("/Groups", scimHandler.ListGroups)
+			scimAPI.POST("/Groups", scimHandler.CreateGroup)
+			scimAPI.GET("/Groups/:id", scimHandler.GetGroup)
+			scimAPI.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+			scimAPI.PATCH("/Groups/:id", scimHandler.PatchGroup)
+			scimAPI.DELETE("/Groups/:id", scimHandler.DeleteGroup)
+		}
+	}
+
+	// Liveness and readiness probes
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+
+	// Public keys for services verifying access tokens
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
 
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -239,34 +1310,54 @@ func setupRouter(
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
+		// Server limits (public)
+		v1.GET("/meta", metaHandler.GetMeta)
+
 		// Auth routes (public)
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.GET("/sso", ssoHandler.GetInfo)
+			auth.GET("/sso/saml/login", ssoHandler.SAMLLogin)
+			auth.GET("/sso/saml/metadata", ssoHandler.SAMLMetadata)
+			auth.POST("/sso/saml/acs", ssoHandler.SAMLACS)
+			auth.POST("/sso/exchange", ssoHandler.Exchange)
 		}
 
 		// Auth routes (protected)
 		authProtected := v1.Group("/auth")
-		authProtected.Use(middleware.Auth(jwtManager))
+		authProtected.Use(requireAuth)
 		{
 			authProtected.POST("/logout", authHandler.Logout)
 			authProtected.PUT("/password", authHandler.ChangePassword)
 			authProtected.GET("/me", authHandler.GetMe)
+			authProtected.DELETE("/me", accountHandler.DeleteAccount)
+			authProtected.GET("/me/export", limitExport, accountHandler.ExportAccount)
 			authProtected.PUT("/profile", authHandler.UpdateProfile)
+			authProtected.GET("/devices", authHandler.ListDevices)
+			authProtected.DELETE("/devices/:id", authHandler.RevokeDevice)
+			authProtected.GET("/sessions", authHandler.ListSessions)
+			authProtected.DELETE("/sessions/:id", authHandler.RevokeSession)
 		}
 
 		// User routes
 		users := v1.Group("/users")
-		users.Use(middleware.Auth(jwtManager))
+		users.Use(requireAuth)
 		{
-			users.GET("/search", userHandler.Search)
+			users.GET("/search", limitSearch, userHandler.Search)
 			users.GET("/online", userHandler.GetOnlineUsers)
 			users.GET("/blocked", userHandler.ListBlockedUsers)
 			users.GET("/friends", userHandler.ListFriends)
+			users.GET("/suggestions", userHandler.ListSuggestions)
+			users.POST("/discover", middleware.DiscoverRateLimit(redisClient), userHandler.DiscoverContacts)
 			users.GET("/friend-requests/pending", userHandler.ListPendingRequests)
 			users.GET("/friend-requests/sent", userHandler.ListSentRequests)
+			users.GET("/me/notification-settings", notificationSettingsHandler.GetSettings)
+			users.PUT("/me/notification-settings", notificationSettingsHandler.UpdateSetting)
+			users.GET("/me/storage", uploadHandler.GetStorageUsage)
+			users.GET("/me/read-state", readStateHandler.GetReadStates)
 			users.GET("/:id", userHandler.GetProfile)
 			users.POST("/:id/block", userHandler.BlockUser)
 			users.POST("/:id/unblock", userHandler.UnblockUser)
@@ -274,64 +1365,232 @@ func setupRouter(
 			users.POST("/:id/friend-request/accept", userHandler.AcceptFriendRequest)
 			users.POST("/:id/friend-request/reject", userHandler.RejectFriendRequest)
 			users.DELETE("/:id/friend", userHandler.RemoveFriend)
+			users.GET("/:id/keys", keyHandler.GetPrekeyBundles)
+		}
+
+		// Device keys for end-to-end encrypted DMs
+		keys := v1.Group("/keys")
+		keys.Use(requireAuth)
+		{
+			keys.PUT("", keyHandler.RegisterKeys)
+			keys.POST("/prekeys", keyHandler.UploadPrekeys)
+			keys.GET("/prekeys/count", keyHandler.CountPrekeys)
+		}
+
+		// Sticker packs for sticker messages
+		stickers := v1.Group("/stickers")
+		stickers.Use(requireAuth)
+		{
+			stickers.GET("/packs", stickerHandler.ListPacks)
+			stickers.GET("/packs/:id", stickerHandler.GetPack)
 		}
 
+		// Public room feeds, readable by feed readers without a token
+		v1.GET("/rooms/:id/feed", middleware.FeedRateLimit(redisClient, cfg.Room.FeedRateLimit), roomHandler.GetFeed)
+		// Room calendars need a token so that banned and disabled accounts
+		// lose access along with the rest of the API
+		v1.GET("/rooms/:id/events.ics", middleware.FeedRateLimit(redisClient, cfg.Room.FeedRateLimit), requireAuth, roomHandler.GetEventCalendar)
+
+		// Incoming webhooks, authorized by the token in the path
+		v1.POST("/webhooks/incoming/:token", middleware.IncomingWebhookRateLimit(redisClient), messageHandler.PostIncomingWebhook)
+
 		// Room routes
 		rooms := v1.Group("/rooms")
-		rooms.Use(middleware.Auth(jwtManager))
+		rooms.Use(requireAuth)
 		{
 			rooms.GET("", roomHandler.ListPublic)
 			rooms.POST("", roomHandler.Create)
 			rooms.GET("/me", roomHandler.ListMyRooms)
-			rooms.GET("/search", roomHandler.Search)
+			rooms.GET("/search", limitSearch, roomHandler.Search)
 			rooms.GET("/:id", roomHandler.GetByID)
 			rooms.PUT("/:id", roomHandler.Update)
 			rooms.DELETE("/:id", roomHandler.Delete)
+			rooms.POST("/:id/deletion/cancel", roomHandler.CancelDeletion)
+			rooms.POST("/:id/archive", roomHandler.Archive)
+			rooms.DELETE("/:id/archive", roomHandler.Unarchive)
 			rooms.POST("/:id/join", roomHandler.Join)
 			rooms.POST("/:id/leave", roomHandler.Leave)
 			rooms.POST("/:id/invite", roomHandler.InviteMember)
+			rooms.POST("/:id/join-requests", roomHandler.CreateJoinRequest)
+			rooms.GET("/:id/join-requests", roomHandler.ListJoinRequests)
+			rooms.POST("/:id/join-requests/:request_id/approve", roomHandler.ApproveJoinRequest)
+			rooms.POST("/:id/join-requests/:request_id/reject", roomHandler.RejectJoinRequest)
 			rooms.GET("/:id/members", roomHandler.ListMembers)
+			rooms.POST("/:id/events", roomHandler.CreateEvent)
+			rooms.GET("/:id/events", roomHandler.ListEvents)
+			rooms.GET("/:id/events/:event_id", roomHandler.GetEvent)
+			rooms.DELETE("/:id/events/:event_id", roomHandler.DeleteEvent)
+			rooms.PUT("/:id/events/:event_id/rsvp", roomHandler.RSVPEvent)
+			rooms.DELETE("/:id/events/:event_id/rsvp", roomHandler.CancelRSVP)
+			rooms.GET("/:id/activity-heatmap", roomHandler.GetActivityHeatmap)
+			rooms.GET("/:id/export", limitExport, messageHandler.ExportHistory)
+			rooms.GET("/:id/permissions", roomHandler.GetPermissions)
+			rooms.PUT("/:id/permissions", roomHandler.UpdatePermissions)
 			rooms.POST("/:id/members/:user_id/kick", roomHandler.KickMember)
 			rooms.POST("/:id/members/:user_id/promote", roomHandler.PromoteMember)
 			rooms.POST("/:id/members/:user_id/demote", roomHandler.DemoteMember)
+			rooms.POST("/:id/members/:user_id/mute", roomHandler.MuteMember)
+			rooms.DELETE("/:id/members/:user_id/mute", roomHandler.UnmuteMember)
+			rooms.POST("/:id/webhooks", roomHandler.CreateWebhook)
+			rooms.GET("/:id/webhooks", roomHandler.ListWebhooks)
+			rooms.DELETE("/:id/webhooks/:webhook_id", roomHandler.DeleteWebhook)
+			rooms.GET("/:id/webhooks/:webhook_id/deliveries", roomHandler.ListWebhookDeliveries)
+			rooms.POST("/:id/incoming-webhooks", roomHandler.CreateIncomingWebhook)
+			rooms.GET("/:id/incoming-webhooks", roomHandler.ListIncomingWebhooks)
+			rooms.PUT("/:id/incoming-webhooks/:webhook_id", roomHandler.UpdateIncomingWebhook)
+			rooms.DELETE("/:id/incoming-webhooks/:webhook_id", roomHandler.DeleteIncomingWebhook)
 
 			// Room messages
 			rooms.GET("/:room_id/messages", messageHandler.GetMessages)
 			rooms.POST("/:room_id/messages", messageHandler.SendMessage)
 			rooms.PUT("/:room_id/messages/:message_id", messageHandler.UpdateMessage)
 			rooms.DELETE("/:room_id/messages/:message_id", messageHandler.DeleteMessage)
-			rooms.GET("/:room_id/messages/search", messageHandler.SearchMessages)
+			rooms.POST("/:room_id/messages/:message_id/forward", messageHandler.ForwardMessage)
+			rooms.GET("/:room_id/messages/search", limitSearch, messageHandler.SearchMessages)
+			rooms.GET("/:room_id/messages/poll", messageHandler.PollMessages)
+			rooms.GET("/:room_id/messages/scheduled", messageHandler.ListScheduledMessages)
+			rooms.DELETE("/:room_id/messages/scheduled/:id", messageHandler.CancelScheduledMessage)
 			rooms.POST("/:room_id/messages/read", messageHandler.MarkAsRead)
 		}
 
 		// Direct message routes
 		dm := v1.Group("/dm")
-		dm.Use(middleware.Auth(jwtManager))
+		dm.Use(requireAuth)
 		{
 			dm.GET("", messageHandler.ListConversations)
 			dm.GET("/unread", messageHandler.GetUnreadCount)
+			dm.GET("/exports/:export_id", messageHandler.GetConversationExport)
+			dm.GET("/exports/:export_id/download", messageHandler.DownloadConversationExport)
 			dm.GET("/:user_id", messageHandler.GetConversation)
 			dm.POST("/:user_id", messageHandler.SendDirectMessage)
 			dm.POST("/:user_id/read", messageHandler.MarkDMAsRead)
+			dm.POST("/:user_id/export", messageHandler.RequestConversationExport)
+			dm.GET("/:user_id/settings", messageHandler.GetConversationSettings)
+			dm.PUT("/:user_id/settings", messageHandler.UpdateConversationSettings)
+			dm.POST("/:user_id/archive", messageHandler.ArchiveConversation)
+			dm.DELETE("/:user_id/archive", messageHandler.UnarchiveConversation)
+			dm.POST("/:user_id/hide", messageHandler.HideConversation)
+			dm.DELETE("/:user_id/hide", messageHandler.UnhideConversation)
+			dm.POST("/:user_id/pin", messageHandler.PinConversation)
+			dm.DELETE("/:user_id/pin", messageHandler.UnpinConversation)
+			dm.PUT("/:user_id/messages/:message_id", messageHandler.UpdateDirectMessage)
+			dm.POST("/:user_id/messages/:message_id/forward", messageHandler.ForwardDirectMessage)
+		}
+
+		// Group conversation routes
+		groupDMs := v1.Group("/group-dms")
+		groupDMs.Use(requireAuth)
+		{
+			groupDMs.POST("", groupHandler.CreateConversation)
+			groupDMs.GET("", groupHandler.ListConversations)
+			groupDMs.GET("/unread", groupHandler.GetUnreadCount)
+			groupDMs.GET("/:id", groupHandler.GetConversation)
+			groupDMs.POST("/:id/participants", groupHandler.AddParticipants)
+			groupDMs.DELETE("/:id/participants/:user_id", groupHandler.RemoveParticipant)
+			groupDMs.GET("/:id/messages", groupHandler.GetMessages)
+			groupDMs.POST("/:id/messages", groupHandler.SendMessage)
+			groupDMs.POST("/:id/read", groupHandler.MarkAsRead)
 		}
 
 		// Upload routes
 		upload := v1.Group("/upload")
-		upload.Use(middleware.Auth(jwtManager))
+		upload.Use(requireAuth)
 		{
 			upload.POST("/image", uploadHandler.UploadImage)
 			upload.POST("/file", uploadHandler.UploadFile)
 			upload.POST("/avatar", uploadHandler.UploadAvatar)
 		}
 
-		// WebSocket stats (admin)
+		// Resumable uploads
+		uploads := v1.Group("/uploads")
+		uploads.Use(requireAuth)
+		{
+			uploads.POST("", uploadHandler.CreateUploadSession)
+			uploads.GET("/pending", uploadHandler.ListPendingUploads)
+			uploads.GET("/:id", uploadHandler.GetUploadSession)
+			uploads.PATCH("/:id", uploadHandler.UploadChunk)
+			uploads.DELETE("/:id", uploadHandler.AbandonUpload)
+		}
+
+		// In-product feedback and bug reports
+		v1.POST("/feedback", requireAuth, middleware.FeedbackRateLimit(redisClient), feedbackHandler.SubmitFeedback)
+
+		// Reports about messages and users
+		v1.POST("/reports", requireAuth, middleware.ReportRateLimit(redisClient), reportHandler.SubmitReport)
+
+		// Server-Sent Events for clients that cannot use WebSocket; it
+		// authenticates itself since EventSource cannot send headers
+		v1.GET("/events", wsHandler.ServeSSE)
+
+		// WebSocket stats (admin) and message bodies too large for a frame
 		wsStats := v1.Group("/ws")
-		wsStats.Use(middleware.Auth(jwtManager))
+		wsStats.Use(requireAuth)
 		{
 			wsStats.GET("/stats", wsHandler.GetStats)
+			wsStats.POST("/content", wsHandler.UploadContent)
 			wsStats.GET("/online", wsHandler.GetOnlineUsers)
 			wsStats.GET("/online/:user_id", wsHandler.IsUserOnline)
 		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(middleware.IPAccess(ipAccess, model.IPAccessScopeAdmin), requireAuth, middleware.AdminOnly(adminChecker))
+		{
+			admin.GET("/system", adminHandler.GetSystem)
+			admin.GET("/features", adminHandler.GetFeatures)
+			admin.GET("/concurrency", adminHandler.GetConcurrency)
+			admin.GET("/jobs", adminHandler.ListJobs)
+			admin.POST("/jobs/:name/run", adminHandler.RunJob)
+			admin.POST("/jwt/rotate", adminHandler.RotateJWTKey)
+			admin.GET("/instances", adminHandler.ListInstances)
+			admin.POST("/instances/:id/drain", adminHandler.DrainInstance)
+			admin.DELETE("/instances/:id/drain", adminHandler.UndrainInstance)
+			admin.GET("/bans", banHandler.ListActiveBans)
+			admin.POST("/users/:id/ban", banHandler.BanUser)
+			admin.DELETE("/users/:id/ban", banHandler.UnbanUser)
+			admin.GET("/users/:id/bans", banHandler.ListUserBans)
+			admin.POST("/users/import", userImportHandler.ImportUsers)
+			admin.PUT("/users/:id/admin", userHandler.GrantAdmin)
+			admin.DELETE("/users/:id/admin", userHandler.RevokeAdmin)
+			admin.GET("/rooms", roomHandler.ListAllRooms)
+			admin.GET("/ip-bans", ipBanHandler.ListIPBans)
+			admin.POST("/ip-bans", ipBanHandler.CreateIPBan)
+			admin.DELETE("/ip-bans/:id", ipBanHandler.DeleteIPBan)
+			admin.GET("/ip-rules", ipAccessHandler.ListIPRules)
+			admin.POST("/ip-rules", ipAccessHandler.CreateIPRule)
+			admin.GET("/ip-rules/check", ipAccessHandler.CheckIPRules)
+			admin.DELETE("/ip-rules/:id", ipAccessHandler.DeleteIPRule)
+			admin.GET("/mail/templates", mailHandler.ListTemplates)
+			admin.GET("/mail/templates/:name/preview", mailHandler.PreviewTemplate)
+			admin.GET("/audit-logs", auditHandler.ListAuditLogs)
+			admin.GET("/legal-holds", complianceHandler.ListLegalHolds)
+			admin.POST("/legal-holds", complianceHandler.PlaceLegalHold)
+			admin.DELETE("/legal-holds/:id", complianceHandler.ReleaseLegalHold)
+			admin.GET("/rooms/:id/export", limitExport, complianceHandler.ExportRoom)
+			admin.POST("/rooms/:id/merge", roomHandler.MergeRoom)
+			admin.GET("/room-merges", roomHandler.ListRoomMerges)
+			admin.GET("/room-merges/:id", roomHandler.GetRoomMerge)
+			admin.GET("/users/:id/export", limitExport, complianceHandler.ExportUser)
+			admin.GET("/feedback", feedbackHandler.ListFeedback)
+			admin.GET("/feedback/:id", feedbackHandler.GetFeedback)
+			admin.PATCH("/feedback/:id", feedbackHandler.TriageFeedback)
+			admin.GET("/reports", reportHandler.ListReports)
+			admin.GET("/reports/:id", reportHandler.GetReport)
+			admin.PATCH("/reports/:id", reportHandler.UpdateReport)
+			admin.GET("/moderation/images/settings", imageModerationHandler.GetSettings)
+			admin.PATCH("/moderation/images/settings", imageModerationHandler.UpdateSettings)
+			admin.GET("/moderation/images", imageModerationHandler.ListQueue)
+			admin.PATCH("/moderation/images/:id", imageModerationHandler.ReviewImage)
+			admin.GET("/moderation/messages", messageModerationHandler.ListQueue)
+			admin.PATCH("/moderation/messages/:id", messageModerationHandler.ReviewMessage)
+			admin.GET("/moderation/spam", spamHandler.ListFlags)
+			admin.GET("/moderation/spam/stats", spamHandler.GetStats)
+			admin.GET("/uploads/settings", uploadHandler.GetUploadSettings)
+			admin.PATCH("/uploads/settings", uploadHandler.UpdateUploadSettings)
+			admin.GET("/stickers/packs", stickerHandler.ListAllPacks)
+			admin.POST("/stickers/packs", stickerHandler.CreatePack)
+			admin.PATCH("/stickers/packs/:id", stickerHandler.UpdatePack)
+		}
 	}
 
 	return router
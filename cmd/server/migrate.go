@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-demo/chat/internal/config"
+	"github.com/go-demo/chat/internal/pkg/database"
+	"github.com/go-demo/chat/migrations"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up        apply every pending migration
+  down [N]  revert the last N migrations (default 1), or "all"
+  status    show the applied and pending migrations`
+
+// runMigrate handles the migrate subcommand and returns the exit code
+func runMigrate(cfg *config.Config, logger *zap.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	steps := 1
+	switch args[0] {
+	case "up", "status":
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+	case "down":
+		if len(args) > 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		if len(args) == 2 && args[1] == "all" {
+			steps = math.MaxInt
+		} else if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "invalid number of migrations %q\n", args[1])
+				return 2
+			}
+			steps = n
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := database.NewPostgres(&cfg.Database, logger)
+	if err != nil {
+		logger.Error("Failed to connect to database", zap.Error(err))
+		return 1
+	}
+	defer database.Close(db, logger)
+
+	migrator, err := database.NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		logger.Error("Failed to load migrations", zap.Error(err))
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			logger.Error("Migration failed", zap.Int("applied", applied), zap.Error(err))
+			return 1
+		}
+		fmt.Printf("applied %d migration(s)\n", applied)
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			logger.Error("Migration failed", zap.Int("reverted", reverted), zap.Error(err))
+			return 1
+		}
+		fmt.Printf("reverted %d migration(s)\n", reverted)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			logger.Error("Failed to read migration status", zap.Error(err))
+			return 1
+		}
+		fmt.Printf("version: %d (latest %d)\n", status.Version, status.Latest)
+		if status.Dirty {
+			fmt.Println("dirty: the last migration failed half way, fix the database before migrating again")
+		}
+		for _, m := range status.Pending {
+			fmt.Printf("pending: %06d_%s\n", m.Version, m.Name)
+		}
+	}
+	return 0
+}
+
+// autoMigrate applies pending migrations at startup
+func autoMigrate(db *sqlx.DB, logger *zap.Logger) error {
+	migrator, err := database.NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		logger.Info("Database migrated", zap.Int("applied", applied))
+	}
+	return nil
+}
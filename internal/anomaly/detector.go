@@ -0,0 +1,213 @@
+// Package anomaly watches abuse-prone activity and raises an alert when a
+// source exceeds its configured rate
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Signal is a kind of activity the detector counts
+type Signal string
+
+const (
+	SignalRegistration  Signal = "registration"   // new accounts, across the whole site
+	SignalFriendRequest Signal = "friend_request" // friend requests, per sender
+	SignalMessage       Signal = "message"        // room messages, per client network
+)
+
+// Networks messages are grouped by. Without an ASN database the allocation
+// prefix of a single customer or hosting box stands in for it.
+const (
+	ipv4NetworkPrefix = 24
+	ipv6NetworkPrefix = 48
+)
+
+// alertTimeout bounds delivering one alert to the admins
+const alertTimeout = 30 * time.Second
+
+// Rule raises an alert when a single key records Threshold events of Signal
+// within one Window. A zero threshold disables the rule.
+type Rule struct {
+	Signal    Signal
+	Threshold int64
+	Window    time.Duration
+}
+
+// Alert describes a key that crossed its rule's threshold
+type Alert struct {
+	Signal     Signal
+	Key        string
+	Count      int64
+	Threshold  int64
+	Window     time.Duration
+	DetectedAt time.Time
+}
+
+// Alerter tells the admins about an alert
+type Alerter interface {
+	Alert(ctx context.Context, alert *Alert)
+}
+
+// Counter counts events in fixed windows shared by every instance
+type Counter interface {
+	// Incr adds one to key and returns the new count. The count starts over
+	// once window has passed.
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// Detector counts activity per signal and key, alerting once per window
+// when a key reaches its threshold. Its methods are safe on a nil Detector,
+// which records nothing.
+type Detector struct {
+	counter Counter
+	rules   map[Signal]Rule
+	alerter Alerter
+	logger  *zap.Logger
+
+	events *metrics.CounterVec
+	alerts *metrics.CounterVec
+}
+
+// NewDetector creates a detector enforcing rules; rules with a zero
+// threshold or window are ignored
+func NewDetector(counter Counter, rules []Rule, logger *zap.Logger) *Detector {
+	d := &Detector{
+		counter: counter,
+		rules:   make(map[Signal]Rule),
+		logger:  logger,
+	}
+	for _, rule := range rules {
+		if rule.Threshold > 0 && rule.Window > 0 {
+			d.rules[rule.Signal] = rule
+		}
+	}
+	return d
+}
+
+// SetAlerter sets where alerts are sent
+func (d *Detector) SetAlerter(alerter Alerter) {
+	d.alerter = alerter
+}
+
+// SetMetrics exports the event and alert totals by signal
+func (d *Detector) SetMetrics(registry *metrics.Registry) {
+	d.events = registry.NewCounterVec("abuse_events_total", "Abuse-prone events recorded by signal", "signal")
+	d.alerts = registry.NewCounterVec("abuse_alerts_total", "Anomaly alerts raised by signal", "signal")
+}
+
+// Record counts one event of signal from key. Counting failures are logged
+// and never fail the caller's request.
+func (d *Detector) Record(ctx context.Context, signal Signal, key string) {
+	if d == nil {
+		return
+	}
+	if d.events != nil {
+		d.events.WithLabelValues(string(signal)).Inc()
+	}
+
+	rule, ok := d.rules[signal]
+	if !ok || key == "" {
+		return
+	}
+
+	now := time.Now()
+	windowStart := now.Truncate(rule.Window)
+	counterKey := fmt.Sprintf("anomaly:%s:%s:%d", signal, key, windowStart.Unix())
+
+	count, err := d.counter.Incr(ctx, counterKey, rule.Window)
+	if err != nil {
+		d.logger.Warn("Failed to count anomaly signal",
+			zap.String("signal", string(signal)),
+			zap.Error(err),
+		)
+		return
+	}
+
+	// Only the event that reaches the threshold alerts, so each key raises
+	// at most one alert per window however far past it goes
+	if count != rule.Threshold {
+		return
+	}
+
+	alert := &Alert{
+		Signal:     signal,
+		Key:        key,
+		Count:      count,
+		Threshold:  rule.Threshold,
+		Window:     rule.Window,
+		DetectedAt: now,
+	}
+	d.logger.Warn("Anomaly detected",
+		zap.String("signal", string(signal)),
+		zap.String("key", key),
+		zap.Int64("threshold", rule.Threshold),
+		zap.Duration("window", rule.Window),
+	)
+	if d.alerts != nil {
+		d.alerts.WithLabelValues(string(signal)).Inc()
+	}
+
+	if d.alerter != nil {
+		// Delivered in the background so the triggering request is not held up
+		go func() {
+			alertCtx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+			defer cancel()
+			d.alerter.Alert(alertCtx, alert)
+		}()
+	}
+}
+
+// NetworkKey returns the network an IP address belongs to, as a CIDR
+// string, or "" when ip cannot be parsed
+func NetworkKey(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(ipv4NetworkPrefix, 32)), Mask: net.CIDRMask(ipv4NetworkPrefix, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(ipv6NetworkPrefix, 128)), Mask: net.CIDRMask(ipv6NetworkPrefix, 128)}).String()
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context carrying the address of the client the
+// work is done for
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the client address stored by WithClientIP, or ""
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// RedisCounter keeps window counts in Redis so every instance adds to the
+// same totals
+type RedisCounter struct {
+	client *redis.Client
+}
+
+func NewRedisCounter(client *redis.Client) *RedisCounter {
+	return &RedisCounter{client: client}
+}
+
+// Incr increments key, expiring it with the window
+func (c *RedisCounter) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := c.client.Pipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment %s: %w", key, err)
+	}
+	return incr.Val(), nil
+}
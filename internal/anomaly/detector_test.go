@@ -0,0 +1,159 @@
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+type memoryCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	err    error
+}
+
+func newMemoryCounter() *memoryCounter {
+	return &memoryCounter{counts: make(map[string]int64)}
+}
+
+func (c *memoryCounter) Incr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[key]++
+	return c.counts[key], nil
+}
+
+type recordingAlerter struct {
+	alerts chan *Alert
+}
+
+func (a *recordingAlerter) Alert(_ context.Context, alert *Alert) {
+	a.alerts <- alert
+}
+
+func TestDetector_AlertsOncePerWindow(t *testing.T) {
+	d := NewDetector(newMemoryCounter(), []Rule{
+		{Signal: SignalFriendRequest, Threshold: 3, Window: time.Hour},
+	}, zap.NewNop())
+	alerter := &recordingAlerter{alerts: make(chan *Alert, 10)}
+	d.SetAlerter(alerter)
+
+	for i := 0; i < 10; i++ {
+		d.Record(context.Background(), SignalFriendRequest, "user-1")
+	}
+	d.Record(context.Background(), SignalFriendRequest, "user-2")
+
+	select {
+	case alert := <-alerter.alerts:
+		if alert.Signal != SignalFriendRequest || alert.Key != "user-1" || alert.Count != 3 || alert.Threshold != 3 {
+			t.Errorf("Unexpected alert: %+v", alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an alert")
+	}
+
+	select {
+	case alert := <-alerter.alerts:
+		t.Errorf("Expected a single alert, got another: %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDetector_IgnoresUnconfiguredSignals(t *testing.T) {
+	counter := newMemoryCounter()
+	d := NewDetector(counter, []Rule{
+		{Signal: SignalRegistration, Threshold: 0, Window: time.Minute},
+		{Signal: SignalMessage, Threshold: 1, Window: time.Minute},
+	}, zap.NewNop())
+
+	d.Record(context.Background(), SignalRegistration, "site")
+	d.Record(context.Background(), SignalFriendRequest, "user-1")
+	d.Record(context.Background(), SignalMessage, "")
+
+	if len(counter.counts) != 0 {
+		t.Errorf("Expected nothing counted, got %v", counter.counts)
+	}
+}
+
+func TestDetector_CounterFailureIsNotFatal(t *testing.T) {
+	counter := newMemoryCounter()
+	counter.err = errors.New("redis down")
+	d := NewDetector(counter, []Rule{{Signal: SignalMessage, Threshold: 1, Window: time.Minute}}, zap.NewNop())
+	alerter := &recordingAlerter{alerts: make(chan *Alert, 1)}
+	d.SetAlerter(alerter)
+
+	d.Record(context.Background(), SignalMessage, "203.0.113.0/24")
+
+	select {
+	case alert := <-alerter.alerts:
+		t.Errorf("Expected no alert, got %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDetector_NilIsNoop(t *testing.T) {
+	var d *Detector
+	d.Record(context.Background(), SignalMessage, "203.0.113.0/24")
+}
+
+func TestDetector_Metrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	d := NewDetector(newMemoryCounter(), []Rule{{Signal: SignalMessage, Threshold: 2, Window: time.Minute}}, zap.NewNop())
+	d.SetMetrics(registry)
+
+	d.Record(context.Background(), SignalMessage, "203.0.113.0/24")
+	d.Record(context.Background(), SignalMessage, "203.0.113.0/24")
+	d.Record(context.Background(), SignalRegistration, "site")
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	for _, want := range []string{
+		`abuse_events_total{signal="message"} 2`,
+		`abuse_events_total{signal="registration"} 1`,
+		`abuse_alerts_total{signal="message"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestNetworkKey(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{"203.0.113.57", "203.0.113.0/24"},
+		{"::ffff:203.0.113.57", "203.0.113.0/24"},
+		{"2001:db8:abcd:12::1", "2001:db8:abcd::/48"},
+		{"not-an-ip", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NetworkKey(tt.ip); got != tt.expected {
+			t.Errorf("NetworkKey(%q) = %q, expected %q", tt.ip, got, tt.expected)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	if ip := ClientIP(context.Background()); ip != "" {
+		t.Errorf("Expected no client IP, got %q", ip)
+	}
+	ctx := WithClientIP(context.Background(), "203.0.113.57")
+	if ip := ClientIP(ctx); ip != "203.0.113.57" {
+		t.Errorf("Expected 203.0.113.57, got %q", ip)
+	}
+}
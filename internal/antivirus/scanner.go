@@ -0,0 +1,133 @@
+// Package antivirus scans uploaded files for malware. A Scanner only reports
+// what it found; quarantining infected files is left to the service layer.
+package antivirus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a single scan, including connecting to the scanner
+const DefaultTimeout = 60 * time.Second
+
+// clamdChunkSize is how much of the file goes into one INSTREAM chunk
+const clamdChunkSize = 64 << 10
+
+// Result is a scanner's verdict on a file
+type Result struct {
+	Infected bool
+	// Signature names the malware found, e.g. "Win.Test.EICAR_HDB-1"
+	Signature string
+}
+
+// Scanner scans file contents for malware
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// ClamAVScanner streams files to a clamd daemon with the INSTREAM command
+type ClamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the clamd daemon at address, either
+// tcp://host:port (or just host:port) or unix:///path/to/clamd.sock
+func NewClamAVScanner(address string, timeout time.Duration) *ClamAVScanner {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	network := "tcp"
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+	return &ClamAVScanner{
+		network: network,
+		address: address,
+		timeout: timeout,
+	}
+}
+
+// Scan implements Scanner
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// clamd stops reading once a stream exceeds its size limit and answers
+	// with an error, so a failed write is only reported when no answer is
+	// readable
+	writeErr := s.stream(conn, r)
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		if writeErr != nil {
+			return nil, fmt.Errorf("failed to stream file to clamd: %w", writeErr)
+		}
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// stream sends r as an INSTREAM command: length-prefixed chunks ended by a
+// zero length
+func (s *ClamAVScanner) stream(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamdReply reads "stream: OK", "stream: <signature> FOUND" or an
+// error such as "INSTREAM size limit exceeded. ERROR"
+func parseClamdReply(reply string) (*Result, error) {
+	reply = strings.TrimRight(reply, "\x00\n")
+	body := strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case body == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(body, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(body, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd returned %q", reply)
+	}
+}
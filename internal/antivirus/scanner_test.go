@@ -0,0 +1,102 @@
+package antivirus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeClamd answers INSTREAM commands on a local port, replying with
+// reply(contents) once the stream ends
+func fakeClamd(t *testing.T, reply func(contents []byte) string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on loopback: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+					return
+				}
+				var contents bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&contents, r, int64(size)); err != nil {
+						return
+					}
+				}
+				_, _ = conn.Write([]byte(reply(contents.Bytes()) + "\x00"))
+			}(conn)
+		}
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestClamAVScanner_Scan(t *testing.T) {
+	addr := fakeClamd(t, func(contents []byte) string {
+		if bytes.Contains(contents, []byte("EICAR")) {
+			return "stream: Win.Test.EICAR_HDB-1 FOUND"
+		}
+		return "stream: OK"
+	})
+	scanner := NewClamAVScanner(addr, 0)
+
+	result, err := scanner.Scan(context.Background(), strings.NewReader("hello"))
+	if err != nil || result.Infected {
+		t.Errorf("Expected a clean file, got %+v, %v", result, err)
+	}
+
+	// Larger than one chunk, with the signature in the second
+	infected := strings.Repeat("x", clamdChunkSize+10) + "EICAR"
+	result, err = scanner.Scan(context.Background(), strings.NewReader(infected))
+	if err != nil || !result.Infected || result.Signature != "Win.Test.EICAR_HDB-1" {
+		t.Errorf("Expected the EICAR signature, got %+v, %v", result, err)
+	}
+}
+
+func TestClamAVScanner_ScanError(t *testing.T) {
+	addr := fakeClamd(t, func(contents []byte) string {
+		return "INSTREAM size limit exceeded. ERROR"
+	})
+
+	if _, err := NewClamAVScanner(addr, 0).Scan(context.Background(), strings.NewReader("big")); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestNewClamAVScanner_Address(t *testing.T) {
+	tests := []struct {
+		address, network, want string
+	}{
+		{"tcp://clamav:3310", "tcp", "clamav:3310"},
+		{"clamav:3310", "tcp", "clamav:3310"},
+		{"unix:///run/clamav/clamd.ctl", "unix", "/run/clamav/clamd.ctl"},
+	}
+	for _, tt := range tests {
+		s := NewClamAVScanner(tt.address, 0)
+		if s.network != tt.network || s.address != tt.want {
+			t.Errorf("%s: got %s %s", tt.address, s.network, s.address)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	membersKey     = "instances"
+	instancePrefix = "instances:"
+	drainSuffix    = ":drain"
+
+	// An instance that misses this many heartbeats drops out of the fleet
+	missedHeartbeats = 3
+)
+
+// ErrInstanceNotFound is returned when an instance is not registered
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// Instance describes one running server in the fleet
+type Instance struct {
+	ID            string    `json:"id"`
+	Address       string    `json:"address"`
+	Version       string    `json:"version,omitempty"`
+	Connections   int       `json:"connections"`
+	Healthy       bool      `json:"healthy"`
+	Draining      bool      `json:"draining"`
+	StartedAt     time.Time `json:"started_at"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+// Registry registers this instance in Redis and lists the fleet
+type Registry struct {
+	redis     *redis.Client
+	self      Instance
+	interval  time.Duration
+	logger    *zap.Logger
+	draining  atomic.Bool
+	connCount func() int
+	healthy   func() bool
+	onDrain   func(draining bool)
+}
+
+// DefaultInstanceID returns the host name, which is unique per container
+func DefaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return fmt.Sprintf("instance-%d", os.Getpid())
+	}
+	return host
+}
+
+// NewRegistry creates a registry for this instance. interval is how often
+// Heartbeat runs; entries expire after several missed heartbeats.
+func NewRegistry(redisClient *redis.Client, id, address, version string, interval time.Duration, logger *zap.Logger) *Registry {
+	return &Registry{
+		redis:    redisClient,
+		interval: interval,
+		logger:   logger,
+		self: Instance{
+			ID:        id,
+			Address:   address,
+			Version:   version,
+			StartedAt: time.Now(),
+		},
+	}
+}
+
+// SetConnectionCounter sets the function reporting open WebSocket connections
+func (r *Registry) SetConnectionCounter(count func() int) {
+	r.connCount = count
+}
+
+// SetHealthCheck sets the function reporting whether this instance is healthy
+func (r *Registry) SetHealthCheck(healthy func() bool) {
+	r.healthy = healthy
+}
+
+// OnDrainChange sets the function called when this instance starts or
+// stops draining
+func (r *Registry) OnDrainChange(fn func(draining bool)) {
+	r.onDrain = fn
+}
+
+// ID returns this instance's ID
+func (r *Registry) ID() string {
+	return r.self.ID
+}
+
+// Draining reports whether an operator asked this instance to drain
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Register clears any drain left over from a previous run under the same ID
+// and publishes this instance
+func (r *Registry) Register(ctx context.Context) error {
+	if err := r.redis.Del(ctx, drainKey(r.self.ID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear drain marker: %w", err)
+	}
+	return r.Heartbeat(ctx)
+}
+
+// Heartbeat refreshes this instance's entry and picks up drain requests.
+// It is meant to run as a scheduler job.
+func (r *Registry) Heartbeat(ctx context.Context) error {
+	draining, err := r.redis.Exists(ctx, drainKey(r.self.ID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check drain marker: %w", err)
+	}
+	if r.draining.Swap(draining > 0) != (draining > 0) {
+		r.logger.Warn("Instance drain state changed",
+			zap.String("instance_id", r.self.ID),
+			zap.Bool("draining", draining > 0),
+		)
+		if r.onDrain != nil {
+			r.onDrain(draining > 0)
+		}
+	}
+
+	data, err := json.Marshal(r.Self())
+	if err != nil {
+		return fmt.Errorf("failed to marshal instance: %w", err)
+	}
+
+	pipe := r.redis.TxPipeline()
+	pipe.Set(ctx, instanceKey(r.self.ID), data, r.ttl())
+	pipe.SAdd(ctx, membersKey, r.self.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish instance: %w", err)
+	}
+	return nil
+}
+
+// Deregister removes this instance from the fleet on shutdown
+func (r *Registry) Deregister(ctx context.Context) error {
+	pipe := r.redis.TxPipeline()
+	pipe.Del(ctx, instanceKey(r.self.ID), drainKey(r.self.ID))
+	pipe.SRem(ctx, membersKey, r.self.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to deregister instance: %w", err)
+	}
+	return nil
+}
+
+// Self returns this instance's current state
+func (r *Registry) Self() *Instance {
+	self := r.self
+	self.Draining = r.draining.Load()
+	self.Healthy = r.healthy == nil || r.healthy()
+	if r.connCount != nil {
+		self.Connections = r.connCount()
+	}
+	self.LastHeartbeat = time.Now()
+	return &self
+}
+
+// List returns the live instances ordered by start time, pruning members
+// whose entries have expired
+func (r *Registry) List(ctx context.Context) ([]*Instance, error) {
+	ids, err := r.redis.SMembers(ctx, membersKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	if len(ids) == 0 {
+		return []*Instance{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = instanceKey(id)
+	}
+	values, err := r.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instances: %w", err)
+	}
+
+	instances := make([]*Instance, 0, len(ids))
+	var stale []interface{}
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var instance Instance
+		if err := json.Unmarshal([]byte(data), &instance); err != nil {
+			r.logger.Warn("Skipping malformed instance entry",
+				zap.String("instance_id", ids[i]),
+				zap.Error(err),
+			)
+			continue
+		}
+		instances = append(instances, &instance)
+	}
+
+	if len(stale) > 0 {
+		if err := r.redis.SRem(ctx, membersKey, stale...).Err(); err != nil {
+			r.logger.Warn("Failed to prune stale instances", zap.Error(err))
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].StartedAt.Before(instances[j].StartedAt)
+	})
+	return instances, nil
+}
+
+// SetDrain asks a registered instance to start or stop draining. The
+// instance picks the change up on its next heartbeat.
+func (r *Registry) SetDrain(ctx context.Context, id string, drain bool) error {
+	exists, err := r.redis.Exists(ctx, instanceKey(id)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check instance: %w", err)
+	}
+	if exists == 0 {
+		return ErrInstanceNotFound
+	}
+
+	if drain {
+		err = r.redis.Set(ctx, drainKey(id), time.Now().Format(time.RFC3339), 0).Err()
+	} else {
+		err = r.redis.Del(ctx, drainKey(id)).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update drain marker: %w", err)
+	}
+	return nil
+}
+
+func (r *Registry) ttl() time.Duration {
+	return r.interval * missedHeartbeats
+}
+
+func instanceKey(id string) string {
+	return instancePrefix + id
+}
+
+func drainKey(id string) string {
+	return instancePrefix + id + drainSuffix
+}
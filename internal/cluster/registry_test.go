@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func setupTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 15})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		t.Skipf("Skipping test, could not connect to test redis: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestRegistry_Self(t *testing.T) {
+	registry := NewRegistry(nil, "node-1", "10.0.0.1:8080", "v1", time.Second, zap.NewNop())
+	registry.SetConnectionCounter(func() int { return 42 })
+
+	self := registry.Self()
+	if self.ID != "node-1" || self.Address != "10.0.0.1:8080" || self.Version != "v1" {
+		t.Errorf("Unexpected identity: %+v", self)
+	}
+	if self.Connections != 42 {
+		t.Errorf("Expected 42 connections, got %d", self.Connections)
+	}
+	if !self.Healthy {
+		t.Error("Expected instance to be healthy without a health check")
+	}
+
+	registry.SetHealthCheck(func() bool { return false })
+	if registry.Self().Healthy {
+		t.Error("Expected health check to be reported")
+	}
+}
+
+func TestRegistry_RegisterListDrain(t *testing.T) {
+	client := setupTestRedis(t)
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	suffix := time.Now().Format("150405.000000")
+	a := NewRegistry(client, "test-a-"+suffix, "10.0.0.1:8080", "v1", time.Second, logger)
+	b := NewRegistry(client, "test-b-"+suffix, "10.0.0.2:8080", "v1", time.Second, logger)
+	defer func() {
+		_ = a.Deregister(ctx)
+		_ = b.Deregister(ctx)
+	}()
+
+	for _, r := range []*Registry{a, b} {
+		if err := r.Register(ctx); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+	}
+
+	instances, err := a.List(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list instances: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, i := range instances {
+		seen[i.ID] = true
+	}
+	if !seen[a.ID()] || !seen[b.ID()] {
+		t.Fatalf("Expected both instances to be listed, got %v", seen)
+	}
+
+	if err := a.SetDrain(ctx, b.ID(), true); err != nil {
+		t.Fatalf("Failed to drain instance: %v", err)
+	}
+	if err := b.Heartbeat(ctx); err != nil {
+		t.Fatalf("Failed to heartbeat: %v", err)
+	}
+	if !b.Draining() {
+		t.Error("Expected instance to pick up the drain request")
+	}
+	if a.Draining() {
+		t.Error("Expected other instances to keep serving")
+	}
+
+	if err := a.SetDrain(ctx, "missing-"+suffix, true); err != ErrInstanceNotFound {
+		t.Errorf("Expected ErrInstanceNotFound, got %v", err)
+	}
+
+	if err := b.Deregister(ctx); err != nil {
+		t.Fatalf("Failed to deregister: %v", err)
+	}
+	instances, err = a.List(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list instances: %v", err)
+	}
+	for _, i := range instances {
+		if i.ID == b.ID() {
+			t.Error("Expected deregistered instance to be gone")
+		}
+	}
+}
@@ -8,11 +8,36 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Log      LogConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	Redis        RedisConfig
+	JWT          JWTConfig
+	Log          LogConfig
+	Room         RoomConfig
+	Search       SearchConfig
+	WS           WSConfig
+	Notification NotificationConfig
+	Features     FeaturesConfig
+	Cluster      ClusterConfig
+	Probe        ProbeConfig
+	IPFilter     IPFilterConfig
+	Mail         MailConfig
+	Upload       UploadConfig
+	Feedback     FeedbackConfig
+	Account      AccountConfig
+	Moderation   ModerationConfig
+	Concurrency  ConcurrencyConfig
+	Webhook      WebhookConfig
+	Metrics      MetricsConfig
+	DM           DMConfig
+	DMExport     DMExportConfig
+	Abuse        AbuseConfig
+	Spam         SpamConfig
+	WriteBehind  WriteBehindConfig
+	Events       EventsConfig
+	LinkPreview  LinkPreviewConfig
+	SSO          SSOConfig
+	SCIM         SCIMConfig
 }
 
 type ServerConfig struct {
@@ -21,6 +46,12 @@ type ServerConfig struct {
 	Mode         string // debug, release, test
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	SelfCheck    bool // 啟動時檢查資料庫結構與相依服務版本，失敗則終止
+
+	ReadinessTimeout time.Duration // /readyz 連線各相依服務的逾時
+
+	TrustedProxies  []string // 可信任的反向代理 IP 或 CIDR，僅來自這些位址的請求會採用轉送標頭中的用戶端 IP；預設不信任任何代理
+	ClientIPHeaders []string // 可信任代理提供用戶端 IP 的標頭，預設為 X-Forwarded-For 與 X-Real-IP
 }
 
 type DatabaseConfig struct {
@@ -33,6 +64,7 @@ type DatabaseConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	AutoMigrate     bool // 啟動時自動套用尚未執行的遷移
 }
 
 type RedisConfig struct {
@@ -41,13 +73,21 @@ type RedisConfig struct {
 	Password string
 	DB       int
 	PoolSize int
+
+	RequiredModules []string // 啟動時必須載入的 Redis 模組
 }
 
 type JWTConfig struct {
-	Secret          string
-	AccessTokenTTL  time.Duration
-	RefreshTokenTTL time.Duration
-	Issuer          string
+	Secret             string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	Issuer             string
+	ValidationCacheTTL time.Duration // 已驗證 Token 的快取時間，0 表示停用
+	KeyActivationDelay time.Duration // 輪替後新金鑰開始簽發 Token 前的等待時間，讓所有實例先載入
+	KeyRefreshInterval time.Duration // 重新載入簽章金鑰的間隔，補上漏收的輪替通知
+	SigningKeys        []string      // 設定檔中的簽章金鑰，格式為「kid 金鑰檔路徑 [啟用時間]」，金鑰檔為 HMAC 密鑰或 RSA、Ed25519 私鑰，公開金鑰發布於 JWKS
+	KeyEncryptionKey   string        // 加密資料庫中輪替金鑰的密鑰，未設定時沿用 jwt.secret
+	HMAC               bool          // 是否以共用密鑰（jwt.secret 與 HMAC 金鑰）簽章及驗證，關閉後僅使用 signing_keys 中的 RSA、Ed25519 金鑰
 }
 
 type LogConfig struct {
@@ -56,6 +96,307 @@ type LogConfig struct {
 	OutputPath string
 }
 
+type RoomConfig struct {
+	DeletionDelay         time.Duration // 排定刪除到實際刪除的等待時間
+	DeletionSweepInterval time.Duration // 背景掃描到期刪除的間隔
+	DeletionRetention     time.Duration // 刪除後保留資料的時間，到期後永久清除聊天室、訊息與附件，0 表示永久保留
+	PurgeInterval         time.Duration // 背景永久清除逾保留期聊天室的間隔
+	MuteSweepInterval     time.Duration // 背景解除到期禁言的間隔
+	ScheduledSendInterval time.Duration // 背景送出到期排程訊息的間隔
+	ExpirySweepInterval   time.Duration // 背景刪除過期（閱後即焚）訊息的間隔
+	MergeInterval         time.Duration // 背景分批執行聊天室合併的間隔
+	CounterInterval       time.Duration // 校正聊天室成員數與訊息數計數器的間隔
+	FeedCacheTTL          time.Duration // 聊天室 RSS/Atom 訂閱內容的快取時間
+	FeedRateLimit         int           // 每個 IP 每分鐘可讀取訂閱的次數
+	MessageIDStrategy     string        // 訊息 ID 產生方式：uuid（資料庫隨機產生）或 uuidv7（依時間排序）
+	MessageRateLimit      int           // 全站預設每位成員在每個聊天室每分鐘的發言數，也是擁有者可設定的上限，0 表示不限制
+	MinMessageRateLimit   int           // 擁有者可為聊天室設定的最低每分鐘發言數
+	EventReminderLead     time.Duration // 聊天室活動開始前多久提醒回覆參加的成員
+	EventReminderInterval time.Duration // 背景送出活動提醒的間隔
+}
+
+type SearchConfig struct {
+	Analyzer string // ilike, simple, english, zhparser, jieba
+
+	// 外部搜尋引擎（Elasticsearch / OpenSearch）；停用時由 Postgres 搜尋
+	ElasticEnabled  bool
+	ElasticURL      string
+	ElasticIndex    string
+	ElasticUsername string
+	ElasticPassword string
+	ElasticAnalyzer string        // 訊息內容使用的分析器，例如 standard 或 smartcn，僅在建立索引時套用
+	ElasticTimeout  time.Duration // 單次請求期限
+	SyncInterval    time.Duration // 同步 outbox 至索引的間隔
+	SyncBatchSize   int           // 每次批次寫入索引的訊息數
+}
+
+type WSConfig struct {
+	SendBufferSize     int           // 每個連線的待送訊息緩衝數
+	SlowConsumerPolicy string        // 緩衝滿時的處理方式：drop_oldest 或 disconnect
+	FanoutWorkers      int           // 廣播工作者數量，0 表示依 CPU 數
+	WriteTimeout       time.Duration // 單次寫入期限，逾時即中斷連線
+	PingInterval       time.Duration // 向用戶端送出 ping 的間隔，須短於 PongTimeout
+	PongTimeout        time.Duration // 用戶端多久沒有回應 pong 或任何訊框即視為閒置並關閉
+	ReconnectTicketTTL time.Duration // 重連票證有效期限，票證僅可使用一次
+	MaxMessageSize     int           // 用戶端單一訊框的位元組上限，超過即中斷連線（至少 1024）
+	ContentRefTTL      time.Duration // 以 REST 上傳的大型訊息內容可被引用的期限
+	Compression        bool          // 與支援的用戶端協商 permessage-deflate 壓縮
+	CompressionLevel   int           // 壓縮等級 1（最快）至 9（最小），0 使用預設值
+	ReplaySize         int           // 每個聊天室保留供斷線續傳的最近訊息數
+	ReplayTTL          time.Duration // 聊天室沒有新訊息多久後清除續傳紀錄
+
+	FloodMaxPerSecond    int           // 每位用戶每秒可經 WebSocket 發送的訊息數，0 表示不限制
+	FloodDuplicateLimit  int           // 時間窗內可重複發送相同文字的次數，0 表示不檢查
+	FloodDuplicateWindow time.Duration // 重複內容的計算時間窗
+	FloodWarnings        int           // 洗版訊息被拒絕並警告幾次後暫時禁言
+	FloodMuteDuration    time.Duration // 暫時禁言的長度，0 表示只警告不禁言
+}
+
+type NotificationConfig struct {
+	BatchWindow time.Duration // 提及、回覆等通知的合併時間窗，0 表示不合併
+}
+
+type FeaturesConfig struct {
+	Disabled         []string      // 停用的功能旗標：link_previews, search_indexing, typing_broadcasts
+	AutoDegrade      bool          // 依賴服務延遲升高時自動停用非關鍵功能
+	ProbeInterval    time.Duration // 依賴服務探測間隔
+	LatencyThreshold time.Duration // 探測延遲超過此值視為不健康
+	TripAfter        int           // 連續幾次不健康後降級
+	RecoverAfter     int           // 連續幾次健康後恢復
+}
+
+type ClusterConfig struct {
+	Register          bool          // 是否將本實例登記至 Redis 供管理端與負載平衡器查詢
+	InstanceID        string        // 實例 ID，空值時使用主機名稱
+	AdvertiseAddr     string        // 對外公布的位址，空值時使用 host:port
+	HeartbeatInterval time.Duration // 心跳間隔，連續三次未更新即視為離線
+	DrainWindow       time.Duration // 排空時將既有連線的重連要求隨機分散於此時間內
+}
+
+type ProbeConfig struct {
+	Enabled          bool          // 啟用訊息送達 SLO 探測
+	BaseURL          string        // 探測呼叫的伺服器位址，空值時使用本機 host:port
+	Username         string        // 探測用帳號
+	Password         string        // 探測用帳號密碼
+	RoomID           string        // 探測專用聊天室，探測訊息會留存於此
+	Interval         time.Duration // 探測間隔
+	Timeout          time.Duration // 等待 WebSocket 事件的期限
+	LatencyThreshold time.Duration // 端到端延遲超過此值視為違反 SLO
+	FailAfter        int           // 連續幾次違反後於健康檢查中告警
+}
+
+type IPFilterConfig struct {
+	TarpitDelay     time.Duration // 標記拖延的封鎖 IP 在回應前等待的時間
+	RefreshInterval time.Duration // 重新載入封鎖清單並清除過期封鎖的間隔
+	AccessRules     []string      // 固定的存取規則，格式為「範圍 動作 對象」，例如 "admin allow 10.0.0.0/8"、"global deny KP"
+	GeoIPDatabase   string        // 國家對應 CSV 檔，每列為「網段,國碼」或「起始IP,結束IP,國碼」
+	CountryHeader   string        // 由 CDN 或反向代理提供國碼的標頭，例如 CF-IPCountry；僅在該標頭無法被用戶端偽造時設定
+}
+
+type MailConfig struct {
+	SiteName      string // 信件中顯示的站台名稱
+	SiteURL       string // 信件中連結使用的站台網址
+	DefaultLocale string // 找不到收件者語系時使用的範本語系
+	TemplateDir   string // 覆寫內建信件範本的目錄，空值時僅使用內建範本
+	SMTPHost      string // SMTP 伺服器，空值時僅將信件寫入日誌
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	From          string // 寄件者地址
+
+	DigestEnabled   bool          // 是否寄送未讀摘要信，用戶仍需自行選擇每日或每週
+	DigestInterval  time.Duration // 背景檢查到期摘要信的間隔
+	DigestBatchSize int           // 每次檢查最多處理的用戶數
+}
+
+type UploadConfig struct {
+	PartialDir    string               // 可續傳上傳尚未完成的內容存放目錄
+	SessionTTL    time.Duration        // 可續傳上傳閒置多久後捨棄，每收到一段即重新計時
+	SweepInterval time.Duration        // 背景清除過期上傳的間隔
+	Image         UploadCategoryConfig // 聊天圖片，回饋截圖也適用
+	File          UploadCategoryConfig // 一般檔案
+	Avatar        UploadCategoryConfig // 頭像
+
+	OrphanTTL  time.Duration // 圖片與檔案上傳後多久仍未附加於任何訊息即刪除，0 表示保留
+	GCInterval time.Duration // 背景清除未附加上傳的間隔
+	UserQuota  int64         // 每位用戶可儲存的圖片與檔案總量（位元組），0 表示不限制；頭像不計入
+
+	Scan UploadScanConfig // 圖片與檔案的病毒掃描
+}
+
+// UploadScanConfig 為上傳檔案的病毒掃描設定，掃描於背景進行，掃描完成前檔案的 scan_status 為 pending
+type UploadScanConfig struct {
+	Enabled       bool          // 是否掃描上傳的圖片與檔案
+	ClamAVAddress string        // clamd 位址，例如 tcp://localhost:3310 或 unix:///run/clamav/clamd.ctl
+	Timeout       time.Duration // 單一檔案的掃描逾時（含連線）
+	Workers       int           // 同時掃描的檔案數
+	QuarantineDir string        // 受感染檔案的隔離目錄，須位於 uploads 目錄之外；空值時直接刪除
+	RetryInterval time.Duration // 重新排入掃描失敗或遺失的檔案的間隔
+}
+
+// UploadCategoryConfig 為單一上傳類別的限制，大小與類型可由管理員於執行期間覆寫
+type UploadCategoryConfig struct {
+	MaxSize      int64    // 單檔大小上限（位元組）
+	AllowedTypes []string // 允許的 MIME 類型
+	SubDir       string   // uploads 目錄下的存放子目錄
+}
+
+type FeedbackConfig struct {
+	WebhookURL      string        // 轉送回饋的外部追蹤系統 webhook，空值時不轉送
+	WebhookSecret   string        // 簽署 webhook 內容的密鑰，空值時不簽署
+	WebhookTimeout  time.Duration // 單次轉送的逾時
+	ForwardInterval time.Duration // 背景轉送尚未送出回饋的間隔
+}
+
+type AccountConfig struct {
+	MessageRetention string // 刪除帳號時聊天室訊息的處理方式：keep（保留內容、作者匿名化）或 erase（清除內容）
+	DMRetention      string // 刪除帳號時私訊的處理方式：keep 或 erase（雙方的私訊一併刪除）
+
+	DisplayCacheTTL  time.Duration // 用戶顯示名稱與頭像的本機快取時間，資料變更時經 Redis 通知各節點清除，0 表示停用
+	DisplayCacheSize int           // 快取的用戶數上限，超過時淘汰最久未使用者
+}
+
+type ModerationConfig struct {
+	ImageAPIURL     string        // 外部圖片偵測 API，空值時使用不標記任何圖片的 stub；門檻由管理員於後台調整
+	ImageAPIKey     string        // 呼叫偵測 API 的 Bearer token
+	ImageAPITimeout time.Duration // 單次偵測的逾時
+
+	WordList     []string // 不當用語清單，一般聊天室以星號遮蔽、嚴格聊天室拒絕送出
+	WordListFile string   // 不當用語清單檔，每行一個詞，# 開頭為註解；與 WordList 合併
+
+	TextAPIURL     string        // 外部文字審查 API，空值時只比對用語清單
+	TextAPIKey     string        // 呼叫文字審查 API 的 Bearer token
+	TextAPITimeout time.Duration // 單次審查的逾時
+
+	FlagThreshold        float64 // 一般聊天室：分數達此值的訊息送交人工審核
+	BlockThreshold       float64 // 一般聊天室：分數達此值的訊息拒絕送出
+	StrictFlagThreshold  float64 // 嚴格聊天室的送審門檻
+	StrictBlockThreshold float64 // 嚴格聊天室的拒絕門檻
+}
+
+type ConcurrencyConfig struct {
+	SearchPerUser int           // 每位用戶同時進行的搜尋請求上限，0 表示不限制
+	ExportPerUser int           // 每位用戶同時進行的匯出請求上限，0 表示不限制
+	QueueSize     int           // 達上限後每位用戶可排隊等候的請求數，超過即直接拒絕
+	QueueTimeout  time.Duration // 排隊等候的最長時間，逾時即拒絕
+}
+
+type WebhookConfig struct {
+	DeliverInterval     time.Duration // 投遞到期 webhook 事件的間隔
+	Timeout             time.Duration // 單次投遞的逾時
+	MaxAttempts         int           // 投遞失敗後以指數退避重試，超過此次數即放棄
+	DeliveryRetention   time.Duration // 已完成或放棄的投遞紀錄保留時間
+	AllowPrivateTargets bool          // 允許 webhook 指向內部網路位址，僅供開發環境使用
+}
+
+type MetricsConfig struct {
+	Enabled bool   // 是否提供 Prometheus 指標
+	Path    string // 指標路徑
+	Token   string // 抓取時需帶的 Bearer Token，留空表示不驗證（請以網路限制存取）
+}
+
+type DMConfig struct {
+	EditWindow time.Duration // 私訊送出後寄件者可編輯的期間，0 表示不限制
+}
+
+type DMExportConfig struct {
+	Dir             string        // 私訊對話匯出檔的存放目錄
+	Retention       time.Duration // 匯出檔完成後可下載的期間，逾期即刪除
+	ProcessInterval time.Duration // 背景處理待匯出對話的間隔
+}
+
+type AbuseConfig struct {
+	Enabled                bool          // 是否偵測異常活動並通知管理員
+	RegistrationThreshold  int64         // 全站在一個時間窗內的註冊數上限，0 表示不偵測
+	RegistrationWindow     time.Duration // 註冊數的統計時間窗
+	FriendRequestThreshold int64         // 單一用戶在一個時間窗內送出的好友邀請上限，0 表示不偵測
+	FriendRequestWindow    time.Duration // 好友邀請的統計時間窗
+	MessageThreshold       int64         // 同一網段（IPv4 /24、IPv6 /48）在一個時間窗內的訊息數上限，0 表示不偵測
+	MessageWindow          time.Duration // 訊息數的統計時間窗
+	WebhookURL             string        // 同時轉送警示的外部 webhook，空值時只通知管理員
+	WebhookSecret          string        // 簽署 webhook 內容的密鑰，空值時不簽署
+	WebhookTimeout         time.Duration // 單次轉送的逾時
+}
+
+type SpamConfig struct {
+	Enabled                bool          // 是否定期掃描垃圾帳號
+	Interval               time.Duration // 掃描間隔
+	BatchSize              int           // 每次查詢的帳號數
+	MinAccountAge          time.Duration // 註冊未滿此時間的帳號不掃描，讓新用戶有時間開始使用
+	MaxAccountAge          time.Duration // 註冊超過此時間的帳號不掃描
+	FriendRequestThreshold int           // 未被回應的好友邀請達此數量視為大量邀請，0 表示不計
+	DisposableDomains      []string      // 拋棄式信箱網域，子網域一併比對
+	FlagScore              int           // 分數達此值的帳號標記給管理員審核
+	SuspendScore           int           // 分數達此值的帳號自動停權，0 表示只標記不停權
+	SuspendDuration        time.Duration // 自動停權的期間，0 表示永久
+}
+
+type WriteBehindConfig struct {
+	Enabled       bool          // WebSocket 文字訊息先寫入 Redis stream 並立即廣播，再由背景批次寫入資料庫
+	AtLeastOnce   bool          // 寫入資料庫成功才自 stream 移除，當機的實例未完成的批次由其他實例接手；關閉時讀出即移除
+	BatchSize     int           // 每次批次寫入的訊息數上限
+	FlushInterval time.Duration // 批次未滿時最多等待的時間
+	ClaimAfter    time.Duration // 已讀出但未確認的訊息超過此時間即由其他實例重新寫入
+}
+
+type EventsConfig struct {
+	Driver       string // 領域事件的發布目標：none（不發布）、redis（Redis stream）或 nats
+	URL          string // NATS 伺服器位址，例如 nats://token@localhost:4222
+	Prefix       string // 事件主題前綴，主題為前綴加上事件類型，例如 chat.events.message.created
+	BufferSize   int    // 等待發布的事件上限，佇列滿時新事件會被捨棄
+	StreamMaxLen int64  // 使用 Redis 時每個 stream 約略保留的事件數
+}
+
+type LinkPreviewConfig struct {
+	Enabled             bool          // 是否為訊息中的網址抓取 Open Graph 預覽
+	Workers             int           // 同時抓取網頁的工作數
+	QueueSize           int           // 等待抓取的訊息上限，佇列滿時新訊息不產生預覽
+	MaxPerMessage       int           // 每則訊息最多預覽的網址數
+	Timeout             time.Duration // 單一網頁的抓取逾時（含轉址）
+	MaxBodySize         int64         // 讀取的 HTML 上限（位元組）
+	CacheTTL            time.Duration // 預覽在 Redis 中的快取時間
+	UserAgent           string        // 抓取網頁時送出的 User-Agent
+	AllowPrivateTargets bool          // 允許抓取內部網路位址，僅供開發環境使用
+}
+
+type SSOConfig struct {
+	Mode        string   // 登入模式：local（本機帳號）、ldap 或 saml
+	LocalLogin  bool     // 啟用 SSO 時仍允許本機帳號以密碼登入，供緊急管理員使用
+	LinkByEmail bool     // 首次 SSO 登入時，將電子郵件相同的既有帳號連結至外部身分
+	GroupRooms  []string // 群組對應聊天室，格式為「群組=聊天室ID,聊天室ID」
+	CallbackURL string   // SAML 登入完成後導向的前端頁面，網址帶有 code 或 error
+
+	// LDAP
+	LDAPURL                  string // ldap:// 或 ldaps:// 位址
+	LDAPStartTLS             bool
+	LDAPUserDNTemplate       string // 直接綁定的使用者 DN 樣板，例如 uid=%s,ou=people,dc=example,dc=com；設定後不搜尋
+	LDAPBindDN               string // 搜尋使用者時的服務帳號，留空則匿名搜尋
+	LDAPBindPassword         string
+	LDAPBaseDN               string
+	LDAPUserFilter           string // 搜尋使用者的篩選條件，%s 代入使用者名稱
+	LDAPUsernameAttribute    string
+	LDAPEmailAttribute       string
+	LDAPDisplayNameAttribute string
+	LDAPGroupAttribute       string
+	LDAPTimeout              time.Duration
+
+	// SAML
+	SAMLEntityID             string // 本服務（SP）的 Entity ID
+	SAMLACSURL               string // 身分提供者回傳結果的網址，即 /api/v1/auth/sso/saml/acs 的完整位址
+	SAMLIdPSSOURL            string // 身分提供者的 HTTP-Redirect 登入位址
+	SAMLIdPEntityID          string
+	SAMLIdPCertificate       string // 身分提供者的簽章憑證（PEM 或 base64）
+	SAMLUsernameAttribute    string
+	SAMLEmailAttribute       string
+	SAMLDisplayNameAttribute string
+	SAMLGroupsAttribute      string
+	SAMLClockSkew            time.Duration // 驗證斷言有效期間時容許的時鐘誤差
+}
+
+type SCIMConfig struct {
+	Token string // 身分提供者呼叫 /scim/v2 時帶的 Bearer Token，留空表示不啟用 SCIM
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -87,6 +428,12 @@ func Load() (*Config, error) {
 			Mode:         viper.GetString("server.mode"),
 			ReadTimeout:  viper.GetDuration("server.read_timeout"),
 			WriteTimeout: viper.GetDuration("server.write_timeout"),
+			SelfCheck:    viper.GetBool("server.self_check"),
+
+			ReadinessTimeout: viper.GetDuration("server.readiness_timeout"),
+
+			TrustedProxies:  viper.GetStringSlice("server.trusted_proxies"),
+			ClientIPHeaders: viper.GetStringSlice("server.client_ip_headers"),
 		},
 		Database: DatabaseConfig{
 			Host:            viper.GetString("database.host"),
@@ -98,6 +445,7 @@ func Load() (*Config, error) {
 			MaxOpenConns:    viper.GetInt("database.max_open_conns"),
 			MaxIdleConns:    viper.GetInt("database.max_idle_conns"),
 			ConnMaxLifetime: viper.GetDuration("database.conn_max_lifetime"),
+			AutoMigrate:     viper.GetBool("database.auto_migrate"),
 		},
 		Redis: RedisConfig{
 			Host:     viper.GetString("redis.host"),
@@ -105,23 +453,298 @@ func Load() (*Config, error) {
 			Password: viper.GetString("redis.password"),
 			DB:       viper.GetInt("redis.db"),
 			PoolSize: viper.GetInt("redis.pool_size"),
+
+			RequiredModules: viper.GetStringSlice("redis.required_modules"),
 		},
 		JWT: JWTConfig{
-			Secret:          viper.GetString("jwt.secret"),
-			AccessTokenTTL:  viper.GetDuration("jwt.access_token_ttl"),
-			RefreshTokenTTL: viper.GetDuration("jwt.refresh_token_ttl"),
-			Issuer:          viper.GetString("jwt.issuer"),
+			Secret:             viper.GetString("jwt.secret"),
+			AccessTokenTTL:     viper.GetDuration("jwt.access_token_ttl"),
+			RefreshTokenTTL:    viper.GetDuration("jwt.refresh_token_ttl"),
+			Issuer:             viper.GetString("jwt.issuer"),
+			ValidationCacheTTL: viper.GetDuration("jwt.validation_cache_ttl"),
+			KeyActivationDelay: viper.GetDuration("jwt.key_activation_delay"),
+			KeyRefreshInterval: viper.GetDuration("jwt.key_refresh_interval"),
+			SigningKeys:        viper.GetStringSlice("jwt.signing_keys"),
+			KeyEncryptionKey:   viper.GetString("jwt.key_encryption_key"),
+			HMAC:               viper.GetBool("jwt.hmac"),
 		},
 		Log: LogConfig{
 			Level:      viper.GetString("log.level"),
 			Format:     viper.GetString("log.format"),
 			OutputPath: viper.GetString("log.output_path"),
 		},
+		Room: RoomConfig{
+			DeletionDelay:         viper.GetDuration("room.deletion_delay"),
+			DeletionSweepInterval: viper.GetDuration("room.deletion_sweep_interval"),
+			DeletionRetention:     viper.GetDuration("room.deletion_retention"),
+			PurgeInterval:         viper.GetDuration("room.purge_interval"),
+			MuteSweepInterval:     viper.GetDuration("room.mute_sweep_interval"),
+			ScheduledSendInterval: viper.GetDuration("room.scheduled_send_interval"),
+			ExpirySweepInterval:   viper.GetDuration("room.expiry_sweep_interval"),
+			MergeInterval:         viper.GetDuration("room.merge_interval"),
+			CounterInterval:       viper.GetDuration("room.counter_interval"),
+			FeedCacheTTL:          viper.GetDuration("room.feed_cache_ttl"),
+			FeedRateLimit:         viper.GetInt("room.feed_rate_limit"),
+			MessageIDStrategy:     viper.GetString("room.message_id_strategy"),
+			MessageRateLimit:      viper.GetInt("room.message_rate_limit"),
+			MinMessageRateLimit:   viper.GetInt("room.min_message_rate_limit"),
+			EventReminderLead:     viper.GetDuration("room.event_reminder_lead"),
+			EventReminderInterval: viper.GetDuration("room.event_reminder_interval"),
+		},
+		Search: SearchConfig{
+			Analyzer:        viper.GetString("search.analyzer"),
+			ElasticEnabled:  viper.GetBool("search.elastic.enabled"),
+			ElasticURL:      viper.GetString("search.elastic.url"),
+			ElasticIndex:    viper.GetString("search.elastic.index"),
+			ElasticUsername: viper.GetString("search.elastic.username"),
+			ElasticPassword: viper.GetString("search.elastic.password"),
+			ElasticAnalyzer: viper.GetString("search.elastic.analyzer"),
+			ElasticTimeout:  viper.GetDuration("search.elastic.timeout"),
+			SyncInterval:    viper.GetDuration("search.elastic.sync_interval"),
+			SyncBatchSize:   viper.GetInt("search.elastic.sync_batch_size"),
+		},
+		WS: WSConfig{
+			SendBufferSize:     viper.GetInt("ws.send_buffer_size"),
+			SlowConsumerPolicy: viper.GetString("ws.slow_consumer_policy"),
+			FanoutWorkers:      viper.GetInt("ws.fanout_workers"),
+			WriteTimeout:       viper.GetDuration("ws.write_timeout"),
+			PingInterval:       viper.GetDuration("ws.ping_interval"),
+			PongTimeout:        viper.GetDuration("ws.pong_timeout"),
+			ReconnectTicketTTL: viper.GetDuration("ws.reconnect_ticket_ttl"),
+			MaxMessageSize:     viper.GetInt("ws.max_message_size"),
+			ContentRefTTL:      viper.GetDuration("ws.content_ref_ttl"),
+			Compression:        viper.GetBool("ws.compression.enabled"),
+			CompressionLevel:   viper.GetInt("ws.compression.level"),
+			ReplaySize:         viper.GetInt("ws.replay.size"),
+			ReplayTTL:          viper.GetDuration("ws.replay.ttl"),
+
+			FloodMaxPerSecond:    viper.GetInt("ws.flood.max_per_second"),
+			FloodDuplicateLimit:  viper.GetInt("ws.flood.duplicate_limit"),
+			FloodDuplicateWindow: viper.GetDuration("ws.flood.duplicate_window"),
+			FloodWarnings:        viper.GetInt("ws.flood.warnings"),
+			FloodMuteDuration:    viper.GetDuration("ws.flood.mute_duration"),
+		},
+		Notification: NotificationConfig{
+			BatchWindow: viper.GetDuration("notification.batch_window"),
+		},
+		Features: FeaturesConfig{
+			Disabled:         viper.GetStringSlice("features.disabled"),
+			AutoDegrade:      viper.GetBool("features.auto_degrade"),
+			ProbeInterval:    viper.GetDuration("features.probe_interval"),
+			LatencyThreshold: viper.GetDuration("features.latency_threshold"),
+			TripAfter:        viper.GetInt("features.trip_after"),
+			RecoverAfter:     viper.GetInt("features.recover_after"),
+		},
+		Cluster: ClusterConfig{
+			Register:          viper.GetBool("cluster.register"),
+			InstanceID:        viper.GetString("cluster.instance_id"),
+			AdvertiseAddr:     viper.GetString("cluster.advertise_addr"),
+			HeartbeatInterval: viper.GetDuration("cluster.heartbeat_interval"),
+			DrainWindow:       viper.GetDuration("cluster.drain_window"),
+		},
+		Probe: ProbeConfig{
+			Enabled:          viper.GetBool("probe.enabled"),
+			BaseURL:          viper.GetString("probe.base_url"),
+			Username:         viper.GetString("probe.username"),
+			Password:         viper.GetString("probe.password"),
+			RoomID:           viper.GetString("probe.room_id"),
+			Interval:         viper.GetDuration("probe.interval"),
+			Timeout:          viper.GetDuration("probe.timeout"),
+			LatencyThreshold: viper.GetDuration("probe.latency_threshold"),
+			FailAfter:        viper.GetInt("probe.fail_after"),
+		},
+		IPFilter: IPFilterConfig{
+			TarpitDelay:     viper.GetDuration("ipfilter.tarpit_delay"),
+			RefreshInterval: viper.GetDuration("ipfilter.refresh_interval"),
+			AccessRules:     viper.GetStringSlice("ipfilter.access_rules"),
+			GeoIPDatabase:   viper.GetString("ipfilter.geoip_database"),
+			CountryHeader:   viper.GetString("ipfilter.country_header"),
+		},
+		Mail: MailConfig{
+			SiteName:      viper.GetString("mail.site_name"),
+			SiteURL:       viper.GetString("mail.site_url"),
+			DefaultLocale: viper.GetString("mail.default_locale"),
+			TemplateDir:   viper.GetString("mail.template_dir"),
+			SMTPHost:      viper.GetString("mail.smtp_host"),
+			SMTPPort:      viper.GetInt("mail.smtp_port"),
+			SMTPUsername:  viper.GetString("mail.smtp_username"),
+			SMTPPassword:  viper.GetString("mail.smtp_password"),
+			From:          viper.GetString("mail.from"),
+
+			DigestEnabled:   viper.GetBool("mail.digest_enabled"),
+			DigestInterval:  viper.GetDuration("mail.digest_interval"),
+			DigestBatchSize: viper.GetInt("mail.digest_batch_size"),
+		},
+		Upload: UploadConfig{
+			PartialDir:    viper.GetString("upload.partial_dir"),
+			SessionTTL:    viper.GetDuration("upload.session_ttl"),
+			SweepInterval: viper.GetDuration("upload.sweep_interval"),
+			Image:         uploadCategoryConfig("upload.image"),
+			File:          uploadCategoryConfig("upload.file"),
+			Avatar:        uploadCategoryConfig("upload.avatar"),
+			OrphanTTL:     viper.GetDuration("upload.orphan_ttl"),
+			GCInterval:    viper.GetDuration("upload.gc_interval"),
+			UserQuota:     viper.GetInt64("upload.user_quota"),
+			Scan: UploadScanConfig{
+				Enabled:       viper.GetBool("upload.scan.enabled"),
+				ClamAVAddress: viper.GetString("upload.scan.clamav_address"),
+				Timeout:       viper.GetDuration("upload.scan.timeout"),
+				Workers:       viper.GetInt("upload.scan.workers"),
+				QuarantineDir: viper.GetString("upload.scan.quarantine_dir"),
+				RetryInterval: viper.GetDuration("upload.scan.retry_interval"),
+			},
+		},
+		Feedback: FeedbackConfig{
+			WebhookURL:      viper.GetString("feedback.webhook_url"),
+			WebhookSecret:   viper.GetString("feedback.webhook_secret"),
+			WebhookTimeout:  viper.GetDuration("feedback.webhook_timeout"),
+			ForwardInterval: viper.GetDuration("feedback.forward_interval"),
+		},
+		Account: AccountConfig{
+			MessageRetention: viper.GetString("account.message_retention"),
+			DMRetention:      viper.GetString("account.dm_retention"),
+			DisplayCacheTTL:  viper.GetDuration("account.display_cache_ttl"),
+			DisplayCacheSize: viper.GetInt("account.display_cache_size"),
+		},
+		Moderation: ModerationConfig{
+			ImageAPIURL:     viper.GetString("moderation.image_api_url"),
+			ImageAPIKey:     viper.GetString("moderation.image_api_key"),
+			ImageAPITimeout: viper.GetDuration("moderation.image_api_timeout"),
+
+			WordList:     viper.GetStringSlice("moderation.word_list"),
+			WordListFile: viper.GetString("moderation.word_list_file"),
+
+			TextAPIURL:     viper.GetString("moderation.text_api_url"),
+			TextAPIKey:     viper.GetString("moderation.text_api_key"),
+			TextAPITimeout: viper.GetDuration("moderation.text_api_timeout"),
+
+			FlagThreshold:        viper.GetFloat64("moderation.flag_threshold"),
+			BlockThreshold:       viper.GetFloat64("moderation.block_threshold"),
+			StrictFlagThreshold:  viper.GetFloat64("moderation.strict_flag_threshold"),
+			StrictBlockThreshold: viper.GetFloat64("moderation.strict_block_threshold"),
+		},
+		Concurrency: ConcurrencyConfig{
+			SearchPerUser: viper.GetInt("concurrency.search_per_user"),
+			ExportPerUser: viper.GetInt("concurrency.export_per_user"),
+			QueueSize:     viper.GetInt("concurrency.queue_size"),
+			QueueTimeout:  viper.GetDuration("concurrency.queue_timeout"),
+		},
+		Webhook: WebhookConfig{
+			DeliverInterval:     viper.GetDuration("webhook.deliver_interval"),
+			Timeout:             viper.GetDuration("webhook.timeout"),
+			MaxAttempts:         viper.GetInt("webhook.max_attempts"),
+			DeliveryRetention:   viper.GetDuration("webhook.delivery_retention"),
+			AllowPrivateTargets: viper.GetBool("webhook.allow_private_targets"),
+		},
+		Metrics: MetricsConfig{
+			Enabled: viper.GetBool("metrics.enabled"),
+			Path:    viper.GetString("metrics.path"),
+			Token:   viper.GetString("metrics.token"),
+		},
+		DM: DMConfig{
+			EditWindow: viper.GetDuration("dm.edit_window"),
+		},
+		DMExport: DMExportConfig{
+			Dir:             viper.GetString("dm_export.dir"),
+			Retention:       viper.GetDuration("dm_export.retention"),
+			ProcessInterval: viper.GetDuration("dm_export.process_interval"),
+		},
+		Abuse: AbuseConfig{
+			Enabled:                viper.GetBool("abuse.enabled"),
+			RegistrationThreshold:  viper.GetInt64("abuse.registration_threshold"),
+			RegistrationWindow:     viper.GetDuration("abuse.registration_window"),
+			FriendRequestThreshold: viper.GetInt64("abuse.friend_request_threshold"),
+			FriendRequestWindow:    viper.GetDuration("abuse.friend_request_window"),
+			MessageThreshold:       viper.GetInt64("abuse.message_threshold"),
+			MessageWindow:          viper.GetDuration("abuse.message_window"),
+			WebhookURL:             viper.GetString("abuse.webhook_url"),
+			WebhookSecret:          viper.GetString("abuse.webhook_secret"),
+			WebhookTimeout:         viper.GetDuration("abuse.webhook_timeout"),
+		},
+		Spam: SpamConfig{
+			Enabled:                viper.GetBool("spam.enabled"),
+			Interval:               viper.GetDuration("spam.interval"),
+			BatchSize:              viper.GetInt("spam.batch_size"),
+			MinAccountAge:          viper.GetDuration("spam.min_account_age"),
+			MaxAccountAge:          viper.GetDuration("spam.max_account_age"),
+			FriendRequestThreshold: viper.GetInt("spam.friend_request_threshold"),
+			DisposableDomains:      viper.GetStringSlice("spam.disposable_domains"),
+			FlagScore:              viper.GetInt("spam.flag_score"),
+			SuspendScore:           viper.GetInt("spam.suspend_score"),
+			SuspendDuration:        viper.GetDuration("spam.suspend_duration"),
+		},
+		WriteBehind: WriteBehindConfig{
+			Enabled:       viper.GetBool("write_behind.enabled"),
+			AtLeastOnce:   viper.GetBool("write_behind.at_least_once"),
+			BatchSize:     viper.GetInt("write_behind.batch_size"),
+			FlushInterval: viper.GetDuration("write_behind.flush_interval"),
+			ClaimAfter:    viper.GetDuration("write_behind.claim_after"),
+		},
+		Events: EventsConfig{
+			Driver:       viper.GetString("events.driver"),
+			URL:          viper.GetString("events.url"),
+			Prefix:       viper.GetString("events.prefix"),
+			BufferSize:   viper.GetInt("events.buffer_size"),
+			StreamMaxLen: viper.GetInt64("events.stream_max_len"),
+		},
+		LinkPreview: LinkPreviewConfig{
+			Enabled:             viper.GetBool("link_preview.enabled"),
+			Workers:             viper.GetInt("link_preview.workers"),
+			QueueSize:           viper.GetInt("link_preview.queue_size"),
+			MaxPerMessage:       viper.GetInt("link_preview.max_per_message"),
+			Timeout:             viper.GetDuration("link_preview.timeout"),
+			MaxBodySize:         viper.GetInt64("link_preview.max_body_size"),
+			CacheTTL:            viper.GetDuration("link_preview.cache_ttl"),
+			UserAgent:           viper.GetString("link_preview.user_agent"),
+			AllowPrivateTargets: viper.GetBool("link_preview.allow_private_targets"),
+		},
+		SSO: SSOConfig{
+			Mode:        viper.GetString("sso.mode"),
+			LocalLogin:  viper.GetBool("sso.local_login"),
+			LinkByEmail: viper.GetBool("sso.link_by_email"),
+			GroupRooms:  viper.GetStringSlice("sso.group_rooms"),
+			CallbackURL: viper.GetString("sso.callback_url"),
+
+			LDAPURL:                  viper.GetString("sso.ldap.url"),
+			LDAPStartTLS:             viper.GetBool("sso.ldap.start_tls"),
+			LDAPUserDNTemplate:       viper.GetString("sso.ldap.user_dn_template"),
+			LDAPBindDN:               viper.GetString("sso.ldap.bind_dn"),
+			LDAPBindPassword:         viper.GetString("sso.ldap.bind_password"),
+			LDAPBaseDN:               viper.GetString("sso.ldap.base_dn"),
+			LDAPUserFilter:           viper.GetString("sso.ldap.user_filter"),
+			LDAPUsernameAttribute:    viper.GetString("sso.ldap.username_attribute"),
+			LDAPEmailAttribute:       viper.GetString("sso.ldap.email_attribute"),
+			LDAPDisplayNameAttribute: viper.GetString("sso.ldap.display_name_attribute"),
+			LDAPGroupAttribute:       viper.GetString("sso.ldap.group_attribute"),
+			LDAPTimeout:              viper.GetDuration("sso.ldap.timeout"),
+
+			SAMLEntityID:             viper.GetString("sso.saml.entity_id"),
+			SAMLACSURL:               viper.GetString("sso.saml.acs_url"),
+			SAMLIdPSSOURL:            viper.GetString("sso.saml.idp_sso_url"),
+			SAMLIdPEntityID:          viper.GetString("sso.saml.idp_entity_id"),
+			SAMLIdPCertificate:       viper.GetString("sso.saml.idp_certificate"),
+			SAMLUsernameAttribute:    viper.GetString("sso.saml.username_attribute"),
+			SAMLEmailAttribute:       viper.GetString("sso.saml.email_attribute"),
+			SAMLDisplayNameAttribute: viper.GetString("sso.saml.display_name_attribute"),
+			SAMLGroupsAttribute:      viper.GetString("sso.saml.groups_attribute"),
+			SAMLClockSkew:            viper.GetDuration("sso.saml.clock_skew"),
+		},
+		SCIM: SCIMConfig{
+			Token: viper.GetString("scim.token"),
+		},
 	}
 
 	return cfg, nil
 }
 
+func uploadCategoryConfig(key string) UploadCategoryConfig {
+	return UploadCategoryConfig{
+		MaxSize:      viper.GetInt64(key + ".max_size"),
+		AllowedTypes: viper.GetStringSlice(key + ".allowed_types"),
+		SubDir:       viper.GetString(key + ".sub_dir"),
+	}
+}
+
 func setDefaults() {
 	// Server defaults
 	viper.SetDefault("server.host", "0.0.0.0")
@@ -129,6 +752,10 @@ func setDefaults() {
 	viper.SetDefault("server.mode", "debug")
 	viper.SetDefault("server.read_timeout", "30s")
 	viper.SetDefault("server.write_timeout", "30s")
+	viper.SetDefault("server.self_check", true)
+	viper.SetDefault("server.readiness_timeout", "2s")
+	viper.SetDefault("server.trusted_proxies", []string{})
+	viper.SetDefault("server.client_ip_headers", []string{"X-Forwarded-For", "X-Real-IP"})
 
 	// Database defaults
 	viper.SetDefault("database.host", "localhost")
@@ -140,6 +767,7 @@ func setDefaults() {
 	viper.SetDefault("database.max_open_conns", 25)
 	viper.SetDefault("database.max_idle_conns", 5)
 	viper.SetDefault("database.conn_max_lifetime", "5m")
+	viper.SetDefault("database.auto_migrate", false)
 
 	// Redis defaults
 	viper.SetDefault("redis.host", "localhost")
@@ -147,17 +775,267 @@ func setDefaults() {
 	viper.SetDefault("redis.password", "")
 	viper.SetDefault("redis.db", 0)
 	viper.SetDefault("redis.pool_size", 10)
+	viper.SetDefault("redis.required_modules", []string{})
 
 	// JWT defaults
 	viper.SetDefault("jwt.secret", "your-secret-key-change-in-production")
 	viper.SetDefault("jwt.access_token_ttl", "15m")
 	viper.SetDefault("jwt.refresh_token_ttl", "168h") // 7 days
 	viper.SetDefault("jwt.issuer", "chat-service")
+	viper.SetDefault("jwt.validation_cache_ttl", "30s")
+	viper.SetDefault("jwt.key_activation_delay", "2m")
+	viper.SetDefault("jwt.key_refresh_interval", "1m")
+	viper.SetDefault("jwt.hmac", true)
 
 	// Log defaults
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.format", "json")
 	viper.SetDefault("log.output_path", "stdout")
+
+	// Room defaults
+	viper.SetDefault("room.deletion_delay", "24h")
+	viper.SetDefault("room.deletion_sweep_interval", "1m")
+	viper.SetDefault("room.deletion_retention", "720h")
+	viper.SetDefault("room.purge_interval", "1h")
+	viper.SetDefault("room.mute_sweep_interval", "30s")
+	viper.SetDefault("room.scheduled_send_interval", "5s")
+	viper.SetDefault("room.expiry_sweep_interval", "30s")
+	viper.SetDefault("room.merge_interval", "5s")
+	viper.SetDefault("room.counter_interval", "10m")
+	viper.SetDefault("room.feed_cache_ttl", "1m")
+	viper.SetDefault("room.feed_rate_limit", 30)
+	viper.SetDefault("room.message_id_strategy", "uuid")
+	viper.SetDefault("room.message_rate_limit", 60)
+	viper.SetDefault("room.min_message_rate_limit", 1)
+	viper.SetDefault("room.event_reminder_lead", "15m")
+	viper.SetDefault("room.event_reminder_interval", "1m")
+
+	// Search defaults
+	viper.SetDefault("search.analyzer", "ilike")
+	viper.SetDefault("search.elastic.enabled", false)
+	viper.SetDefault("search.elastic.url", "http://localhost:9200")
+	viper.SetDefault("search.elastic.index", "chat-messages")
+	viper.SetDefault("search.elastic.analyzer", "standard")
+	viper.SetDefault("search.elastic.timeout", "5s")
+	viper.SetDefault("search.elastic.sync_interval", "2s")
+	viper.SetDefault("search.elastic.sync_batch_size", 500)
+
+	// WebSocket defaults
+	viper.SetDefault("ws.send_buffer_size", 256)
+	viper.SetDefault("ws.slow_consumer_policy", "drop_oldest")
+	viper.SetDefault("ws.fanout_workers", 0)
+	viper.SetDefault("ws.write_timeout", "10s")
+	viper.SetDefault("ws.ping_interval", "54s")
+	viper.SetDefault("ws.pong_timeout", "60s")
+	viper.SetDefault("ws.reconnect_ticket_ttl", "60s")
+	viper.SetDefault("ws.max_message_size", 4096)
+	viper.SetDefault("ws.content_ref_ttl", "5m")
+	viper.SetDefault("ws.compression.enabled", true)
+	viper.SetDefault("ws.compression.level", 1)
+	viper.SetDefault("ws.replay.size", 200)
+	viper.SetDefault("ws.replay.ttl", "5m")
+	viper.SetDefault("ws.flood.max_per_second", 5)
+	viper.SetDefault("ws.flood.duplicate_limit", 3)
+	viper.SetDefault("ws.flood.duplicate_window", "30s")
+	viper.SetDefault("ws.flood.warnings", 2)
+	viper.SetDefault("ws.flood.mute_duration", "60s")
+
+	// Notification defaults
+	viper.SetDefault("notification.batch_window", "10s")
+
+	// Feature flag defaults
+	viper.SetDefault("features.disabled", []string{})
+	viper.SetDefault("features.auto_degrade", true)
+	viper.SetDefault("features.probe_interval", "5s")
+	viper.SetDefault("features.latency_threshold", "250ms")
+	viper.SetDefault("features.trip_after", 3)
+	viper.SetDefault("features.recover_after", 6)
+
+	// Cluster defaults
+	viper.SetDefault("cluster.register", false)
+	viper.SetDefault("cluster.instance_id", "")
+	viper.SetDefault("cluster.advertise_addr", "")
+	viper.SetDefault("cluster.heartbeat_interval", "10s")
+	viper.SetDefault("cluster.drain_window", "60s")
+
+	// Probe defaults
+	viper.SetDefault("probe.enabled", false)
+	viper.SetDefault("probe.base_url", "")
+	viper.SetDefault("probe.username", "")
+	viper.SetDefault("probe.password", "")
+	viper.SetDefault("probe.room_id", "")
+	viper.SetDefault("probe.interval", "30s")
+	viper.SetDefault("probe.timeout", "5s")
+	viper.SetDefault("probe.latency_threshold", "1s")
+	viper.SetDefault("probe.fail_after", 3)
+
+	// IP filter defaults
+	viper.SetDefault("ipfilter.tarpit_delay", "10s")
+	viper.SetDefault("ipfilter.refresh_interval", "1m")
+
+	// Mail defaults
+	viper.SetDefault("mail.site_name", "Go Chat")
+	viper.SetDefault("mail.site_url", "http://localhost:8080")
+	viper.SetDefault("mail.default_locale", "zh-TW")
+	viper.SetDefault("mail.template_dir", "")
+	viper.SetDefault("mail.smtp_host", "")
+	viper.SetDefault("mail.smtp_port", 587)
+	viper.SetDefault("mail.smtp_username", "")
+	viper.SetDefault("mail.smtp_password", "")
+	viper.SetDefault("mail.from", "no-reply@localhost")
+	viper.SetDefault("mail.digest_enabled", true)
+	viper.SetDefault("mail.digest_interval", "15m")
+	viper.SetDefault("mail.digest_batch_size", 100)
+
+	// Upload defaults
+	viper.SetDefault("upload.partial_dir", "./tmp/uploads")
+	viper.SetDefault("upload.session_ttl", "24h")
+	viper.SetDefault("upload.sweep_interval", "10m")
+	viper.SetDefault("upload.orphan_ttl", "24h")
+	viper.SetDefault("upload.gc_interval", "1h")
+	viper.SetDefault("upload.user_quota", 1<<30)
+	viper.SetDefault("upload.scan.enabled", false)
+	viper.SetDefault("upload.scan.clamav_address", "tcp://localhost:3310")
+	viper.SetDefault("upload.scan.timeout", "60s")
+	viper.SetDefault("upload.scan.workers", 2)
+	viper.SetDefault("upload.scan.quarantine_dir", "./quarantine")
+	viper.SetDefault("upload.scan.retry_interval", "5m")
+	imageTypes := []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+	viper.SetDefault("upload.image.max_size", 5<<20)
+	viper.SetDefault("upload.image.allowed_types", imageTypes)
+	viper.SetDefault("upload.image.sub_dir", "images")
+	viper.SetDefault("upload.file.max_size", 10<<20)
+	viper.SetDefault("upload.file.allowed_types", append([]string{
+		"application/pdf",
+		"application/msword",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.ms-excel",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"text/plain",
+		"application/zip",
+	}, imageTypes...))
+	viper.SetDefault("upload.file.sub_dir", "files")
+	viper.SetDefault("upload.avatar.max_size", 2<<20)
+	viper.SetDefault("upload.avatar.allowed_types", imageTypes)
+	viper.SetDefault("upload.avatar.sub_dir", "avatars")
+
+	// Feedback defaults
+	viper.SetDefault("feedback.webhook_url", "")
+	viper.SetDefault("feedback.webhook_secret", "")
+	viper.SetDefault("feedback.webhook_timeout", "10s")
+	viper.SetDefault("feedback.forward_interval", "30s")
+
+	// Account defaults
+	viper.SetDefault("account.message_retention", "keep")
+	viper.SetDefault("account.dm_retention", "erase")
+	viper.SetDefault("account.display_cache_ttl", "5m")
+	viper.SetDefault("account.display_cache_size", 10000)
+
+	// Moderation defaults
+	viper.SetDefault("moderation.image_api_url", "")
+	viper.SetDefault("moderation.image_api_key", "")
+	viper.SetDefault("moderation.image_api_timeout", "10s")
+	viper.SetDefault("moderation.word_list", []string{})
+	viper.SetDefault("moderation.word_list_file", "")
+	viper.SetDefault("moderation.text_api_url", "")
+	viper.SetDefault("moderation.text_api_key", "")
+	viper.SetDefault("moderation.text_api_timeout", "3s")
+	viper.SetDefault("moderation.flag_threshold", 0.8)
+	viper.SetDefault("moderation.block_threshold", 0.95)
+	viper.SetDefault("moderation.strict_flag_threshold", 0.5)
+	viper.SetDefault("moderation.strict_block_threshold", 0.8)
+
+	// Concurrency limit defaults
+	viper.SetDefault("concurrency.search_per_user", 3)
+	viper.SetDefault("concurrency.export_per_user", 1)
+	viper.SetDefault("concurrency.queue_size", 2)
+	viper.SetDefault("concurrency.queue_timeout", "5s")
+
+	// Room webhook defaults
+	viper.SetDefault("webhook.deliver_interval", "5s")
+	viper.SetDefault("webhook.timeout", "10s")
+	viper.SetDefault("webhook.max_attempts", 8)
+	viper.SetDefault("webhook.delivery_retention", "168h")
+	viper.SetDefault("webhook.allow_private_targets", false)
+
+	// Metrics defaults
+	viper.SetDefault("metrics.enabled", false)
+	viper.SetDefault("metrics.path", "/metrics")
+
+	// DM export defaults
+	viper.SetDefault("dm.edit_window", "15m")
+	viper.SetDefault("dm_export.dir", "./tmp/exports")
+	viper.SetDefault("dm_export.retention", "168h")
+	viper.SetDefault("dm_export.process_interval", "15s")
+
+	// Abuse detection defaults
+	viper.SetDefault("abuse.enabled", false)
+	viper.SetDefault("abuse.registration_threshold", 50)
+	viper.SetDefault("abuse.registration_window", "10m")
+	viper.SetDefault("abuse.friend_request_threshold", 30)
+	viper.SetDefault("abuse.friend_request_window", "10m")
+	viper.SetDefault("abuse.message_threshold", 600)
+	viper.SetDefault("abuse.message_window", "1m")
+	viper.SetDefault("abuse.webhook_url", "")
+	viper.SetDefault("abuse.webhook_secret", "")
+	viper.SetDefault("abuse.webhook_timeout", "10s")
+
+	// Spam sweep defaults
+	viper.SetDefault("spam.enabled", false)
+	viper.SetDefault("spam.interval", "1h")
+	viper.SetDefault("spam.batch_size", 500)
+	viper.SetDefault("spam.min_account_age", "24h")
+	viper.SetDefault("spam.max_account_age", "720h")
+	viper.SetDefault("spam.friend_request_threshold", 20)
+	viper.SetDefault("spam.disposable_domains", []string{
+		"mailinator.com", "guerrillamail.com", "10minutemail.com", "temp-mail.org",
+		"yopmail.com", "trashmail.com", "sharklasers.com", "getnada.com",
+	})
+	viper.SetDefault("spam.flag_score", 60)
+	viper.SetDefault("spam.suspend_score", 0)
+	viper.SetDefault("spam.suspend_duration", "720h")
+
+	// Write-behind message persistence defaults
+	viper.SetDefault("write_behind.enabled", false)
+	viper.SetDefault("write_behind.at_least_once", true)
+	viper.SetDefault("write_behind.batch_size", 200)
+	viper.SetDefault("write_behind.flush_interval", "100ms")
+	viper.SetDefault("write_behind.claim_after", "30s")
+
+	// Domain event bus defaults
+	viper.SetDefault("events.driver", "none")
+	viper.SetDefault("events.url", "nats://localhost:4222")
+	viper.SetDefault("events.prefix", "chat.events")
+	viper.SetDefault("events.buffer_size", 1024)
+	viper.SetDefault("events.stream_max_len", 100000)
+
+	// Link preview defaults
+	viper.SetDefault("link_preview.enabled", true)
+	viper.SetDefault("link_preview.workers", 4)
+	viper.SetDefault("link_preview.queue_size", 1024)
+	viper.SetDefault("link_preview.max_per_message", 3)
+	viper.SetDefault("link_preview.timeout", "5s")
+	viper.SetDefault("link_preview.max_body_size", 524288)
+	viper.SetDefault("link_preview.cache_ttl", "24h")
+	viper.SetDefault("link_preview.user_agent", "go-demo-chat-unfurl/1.0")
+	viper.SetDefault("link_preview.allow_private_targets", false)
+
+	// Single sign-on defaults
+	viper.SetDefault("sso.mode", "local")
+	viper.SetDefault("sso.local_login", false)
+	viper.SetDefault("sso.link_by_email", false)
+	viper.SetDefault("sso.callback_url", "/sso/callback")
+	viper.SetDefault("sso.ldap.user_filter", "(uid=%s)")
+	viper.SetDefault("sso.ldap.username_attribute", "uid")
+	viper.SetDefault("sso.ldap.email_attribute", "mail")
+	viper.SetDefault("sso.ldap.display_name_attribute", "displayName")
+	viper.SetDefault("sso.ldap.group_attribute", "memberOf")
+	viper.SetDefault("sso.ldap.timeout", "10s")
+	viper.SetDefault("sso.saml.username_attribute", "uid")
+	viper.SetDefault("sso.saml.email_attribute", "email")
+	viper.SetDefault("sso.saml.display_name_attribute", "displayName")
+	viper.SetDefault("sso.saml.groups_attribute", "groups")
+	viper.SetDefault("sso.saml.clock_skew", "2m")
 }
 
 func bindEnvVariables() {
@@ -165,6 +1043,9 @@ func bindEnvVariables() {
 	_ = viper.BindEnv("server.host", "SERVER_HOST")
 	_ = viper.BindEnv("server.port", "SERVER_PORT")
 	_ = viper.BindEnv("server.mode", "SERVER_MODE")
+	_ = viper.BindEnv("server.self_check", "SERVER_SELF_CHECK")
+	_ = viper.BindEnv("server.trusted_proxies", "SERVER_TRUSTED_PROXIES")
+	_ = viper.BindEnv("server.client_ip_headers", "SERVER_CLIENT_IP_HEADERS")
 
 	// Database
 	_ = viper.BindEnv("database.host", "DB_HOST")
@@ -181,9 +1062,121 @@ func bindEnvVariables() {
 
 	// JWT
 	_ = viper.BindEnv("jwt.secret", "JWT_SECRET")
+	_ = viper.BindEnv("jwt.key_activation_delay", "JWT_KEY_ACTIVATION_DELAY")
+	_ = viper.BindEnv("jwt.key_encryption_key", "JWT_KEY_ENCRYPTION_KEY")
+	_ = viper.BindEnv("jwt.hmac", "JWT_HMAC")
 
 	// Log
 	_ = viper.BindEnv("log.level", "LOG_LEVEL")
+
+	// Room
+	_ = viper.BindEnv("room.deletion_delay", "ROOM_DELETION_DELAY")
+	_ = viper.BindEnv("room.deletion_retention", "ROOM_DELETION_RETENTION")
+	_ = viper.BindEnv("room.message_id_strategy", "MESSAGE_ID_STRATEGY")
+	_ = viper.BindEnv("room.event_reminder_lead", "ROOM_EVENT_REMINDER_LEAD")
+	_ = viper.BindEnv("room.event_reminder_lead", "ROOM_EVENT_REMINDER_LEAD")
+
+	// Search
+	_ = viper.BindEnv("search.analyzer", "SEARCH_ANALYZER")
+	_ = viper.BindEnv("search.elastic.enabled", "SEARCH_ELASTIC_ENABLED")
+	_ = viper.BindEnv("search.elastic.url", "SEARCH_ELASTIC_URL")
+	_ = viper.BindEnv("search.elastic.username", "SEARCH_ELASTIC_USERNAME")
+	_ = viper.BindEnv("search.elastic.password", "SEARCH_ELASTIC_PASSWORD")
+
+	// WebSocket
+	_ = viper.BindEnv("ws.send_buffer_size", "WS_SEND_BUFFER_SIZE")
+	_ = viper.BindEnv("ws.slow_consumer_policy", "WS_SLOW_CONSUMER_POLICY")
+	_ = viper.BindEnv("ws.fanout_workers", "WS_FANOUT_WORKERS")
+	_ = viper.BindEnv("ws.write_timeout", "WS_WRITE_TIMEOUT")
+	_ = viper.BindEnv("ws.ping_interval", "WS_PING_INTERVAL")
+	_ = viper.BindEnv("ws.pong_timeout", "WS_PONG_TIMEOUT")
+	_ = viper.BindEnv("ws.reconnect_ticket_ttl", "WS_RECONNECT_TICKET_TTL")
+	_ = viper.BindEnv("ws.max_message_size", "WS_MAX_MESSAGE_SIZE")
+	_ = viper.BindEnv("ws.content_ref_ttl", "WS_CONTENT_REF_TTL")
+	_ = viper.BindEnv("ws.compression.enabled", "WS_COMPRESSION_ENABLED")
+	_ = viper.BindEnv("ws.compression.level", "WS_COMPRESSION_LEVEL")
+	_ = viper.BindEnv("ws.replay.size", "WS_REPLAY_SIZE")
+	_ = viper.BindEnv("ws.replay.ttl", "WS_REPLAY_TTL")
+	_ = viper.BindEnv("ws.flood.max_per_second", "WS_FLOOD_MAX_PER_SECOND")
+	_ = viper.BindEnv("ws.flood.duplicate_limit", "WS_FLOOD_DUPLICATE_LIMIT")
+	_ = viper.BindEnv("ws.flood.mute_duration", "WS_FLOOD_MUTE_DURATION")
+
+	// Notification
+	_ = viper.BindEnv("notification.batch_window", "NOTIFICATION_BATCH_WINDOW")
+
+	// Features
+	_ = viper.BindEnv("features.disabled", "FEATURES_DISABLED")
+	_ = viper.BindEnv("features.auto_degrade", "FEATURES_AUTO_DEGRADE")
+	_ = viper.BindEnv("features.latency_threshold", "FEATURES_LATENCY_THRESHOLD")
+	_ = viper.BindEnv("cluster.register", "CLUSTER_REGISTER")
+	_ = viper.BindEnv("cluster.instance_id", "INSTANCE_ID")
+	_ = viper.BindEnv("cluster.advertise_addr", "CLUSTER_ADVERTISE_ADDR")
+	_ = viper.BindEnv("cluster.drain_window", "CLUSTER_DRAIN_WINDOW")
+	_ = viper.BindEnv("probe.enabled", "PROBE_ENABLED")
+	_ = viper.BindEnv("probe.base_url", "PROBE_BASE_URL")
+	_ = viper.BindEnv("probe.username", "PROBE_USERNAME")
+	_ = viper.BindEnv("probe.password", "PROBE_PASSWORD")
+	_ = viper.BindEnv("probe.room_id", "PROBE_ROOM_ID")
+	_ = viper.BindEnv("ipfilter.tarpit_delay", "IPFILTER_TARPIT_DELAY")
+	_ = viper.BindEnv("ipfilter.geoip_database", "IPFILTER_GEOIP_DATABASE")
+	_ = viper.BindEnv("ipfilter.country_header", "IPFILTER_COUNTRY_HEADER")
+	_ = viper.BindEnv("mail.site_name", "MAIL_SITE_NAME")
+	_ = viper.BindEnv("mail.site_url", "MAIL_SITE_URL")
+	_ = viper.BindEnv("mail.template_dir", "MAIL_TEMPLATE_DIR")
+	_ = viper.BindEnv("mail.smtp_host", "MAIL_SMTP_HOST")
+	_ = viper.BindEnv("mail.smtp_port", "MAIL_SMTP_PORT")
+	_ = viper.BindEnv("mail.smtp_username", "MAIL_SMTP_USERNAME")
+	_ = viper.BindEnv("mail.smtp_password", "MAIL_SMTP_PASSWORD")
+	_ = viper.BindEnv("mail.from", "MAIL_FROM")
+	_ = viper.BindEnv("mail.digest_enabled", "MAIL_DIGEST_ENABLED")
+	_ = viper.BindEnv("mail.digest_interval", "MAIL_DIGEST_INTERVAL")
+	_ = viper.BindEnv("mail.digest_batch_size", "MAIL_DIGEST_BATCH_SIZE")
+	_ = viper.BindEnv("upload.partial_dir", "UPLOAD_PARTIAL_DIR")
+	_ = viper.BindEnv("upload.orphan_ttl", "UPLOAD_ORPHAN_TTL")
+	_ = viper.BindEnv("upload.user_quota", "UPLOAD_USER_QUOTA")
+	_ = viper.BindEnv("upload.scan.enabled", "UPLOAD_SCAN_ENABLED")
+	_ = viper.BindEnv("upload.scan.clamav_address", "UPLOAD_SCAN_CLAMAV_ADDRESS")
+	_ = viper.BindEnv("upload.scan.quarantine_dir", "UPLOAD_SCAN_QUARANTINE_DIR")
+	_ = viper.BindEnv("feedback.webhook_url", "FEEDBACK_WEBHOOK_URL")
+	_ = viper.BindEnv("feedback.webhook_secret", "FEEDBACK_WEBHOOK_SECRET")
+	_ = viper.BindEnv("account.message_retention", "ACCOUNT_MESSAGE_RETENTION")
+	_ = viper.BindEnv("account.dm_retention", "ACCOUNT_DM_RETENTION")
+	_ = viper.BindEnv("account.display_cache_ttl", "ACCOUNT_DISPLAY_CACHE_TTL")
+	_ = viper.BindEnv("moderation.image_api_url", "MODERATION_IMAGE_API_URL")
+	_ = viper.BindEnv("moderation.image_api_key", "MODERATION_IMAGE_API_KEY")
+	_ = viper.BindEnv("moderation.word_list_file", "MODERATION_WORD_LIST_FILE")
+	_ = viper.BindEnv("moderation.text_api_url", "MODERATION_TEXT_API_URL")
+	_ = viper.BindEnv("moderation.text_api_key", "MODERATION_TEXT_API_KEY")
+	_ = viper.BindEnv("concurrency.search_per_user", "CONCURRENCY_SEARCH_PER_USER")
+	_ = viper.BindEnv("concurrency.export_per_user", "CONCURRENCY_EXPORT_PER_USER")
+	_ = viper.BindEnv("webhook.allow_private_targets", "WEBHOOK_ALLOW_PRIVATE_TARGETS")
+	_ = viper.BindEnv("metrics.enabled", "METRICS_ENABLED")
+	_ = viper.BindEnv("metrics.token", "METRICS_TOKEN")
+	_ = viper.BindEnv("dm.edit_window", "DM_EDIT_WINDOW")
+	_ = viper.BindEnv("dm_export.dir", "DM_EXPORT_DIR")
+	_ = viper.BindEnv("abuse.enabled", "ABUSE_ENABLED")
+	_ = viper.BindEnv("abuse.webhook_url", "ABUSE_WEBHOOK_URL")
+	_ = viper.BindEnv("abuse.webhook_secret", "ABUSE_WEBHOOK_SECRET")
+	_ = viper.BindEnv("spam.enabled", "SPAM_SWEEP_ENABLED")
+	_ = viper.BindEnv("spam.suspend_score", "SPAM_SUSPEND_SCORE")
+	_ = viper.BindEnv("write_behind.enabled", "WRITE_BEHIND_ENABLED")
+	_ = viper.BindEnv("write_behind.at_least_once", "WRITE_BEHIND_AT_LEAST_ONCE")
+	_ = viper.BindEnv("events.driver", "EVENTS_DRIVER")
+	_ = viper.BindEnv("events.url", "EVENTS_URL")
+	_ = viper.BindEnv("link_preview.enabled", "LINK_PREVIEW_ENABLED")
+	_ = viper.BindEnv("link_preview.allow_private_targets", "LINK_PREVIEW_ALLOW_PRIVATE_TARGETS")
+	_ = viper.BindEnv("upload.image.max_size", "UPLOAD_IMAGE_MAX_SIZE")
+	_ = viper.BindEnv("upload.file.max_size", "UPLOAD_FILE_MAX_SIZE")
+	_ = viper.BindEnv("upload.avatar.max_size", "UPLOAD_AVATAR_MAX_SIZE")
+	_ = viper.BindEnv("database.auto_migrate", "DB_AUTO_MIGRATE")
+	_ = viper.BindEnv("sso.mode", "SSO_MODE")
+	_ = viper.BindEnv("sso.local_login", "SSO_LOCAL_LOGIN")
+	_ = viper.BindEnv("sso.callback_url", "SSO_CALLBACK_URL")
+	_ = viper.BindEnv("sso.ldap.url", "SSO_LDAP_URL")
+	_ = viper.BindEnv("sso.ldap.bind_dn", "SSO_LDAP_BIND_DN")
+	_ = viper.BindEnv("sso.ldap.bind_password", "SSO_LDAP_BIND_PASSWORD")
+	_ = viper.BindEnv("sso.saml.idp_certificate", "SSO_SAML_IDP_CERTIFICATE")
+	_ = viper.BindEnv("scim.token", "SCIM_TOKEN")
 }
 
 // GetDSN returns PostgreSQL connection string
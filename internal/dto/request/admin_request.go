@@ -0,0 +1,64 @@
+package request
+
+// BanUserRequest represents a ban or suspension request
+type BanUserRequest struct {
+	Reason   string `json:"reason" binding:"required,max=500"`
+	Duration string `json:"duration,omitempty"` // e.g. "72h"; empty bans permanently
+}
+
+// IPBanRequest represents an IP or CIDR range ban request
+type IPBanRequest struct {
+	CIDR     string `json:"cidr" binding:"required,max=64"` // e.g. "203.0.113.7" or "203.0.113.0/24"
+	Reason   string `json:"reason" binding:"required,max=500"`
+	Duration string `json:"duration,omitempty"` // e.g. "24h"; empty bans until removed
+	Tarpit   bool   `json:"tarpit,omitempty"`   // delay responses before refusing
+}
+
+// IPAccessRuleRequest represents an IP access rule
+type IPAccessRuleRequest struct {
+	Scope  string `json:"scope" binding:"required,oneof=global admin scim metrics"`
+	Action string `json:"action" binding:"required,oneof=allow deny"`
+	Target string `json:"target" binding:"required,max=64"` // e.g. "203.0.113.0/24" or "TW"
+	Note   string `json:"note" binding:"max=500"`
+}
+
+// IPAccessCheckQuery represents an IP access rule check
+type IPAccessCheckQuery struct {
+	IP      string `form:"ip" binding:"required,ip"`
+	Country string `form:"country" binding:"omitempty,len=2"` // defaults to the GeoIP lookup
+}
+
+// AuditLogQuery represents audit log filters
+type AuditLogQuery struct {
+	ActorID string `form:"actor_id" binding:"omitempty,uuid"`
+	Action  string `form:"action" binding:"max=50"`
+	Since   string `form:"since"` // RFC3339
+	Until   string `form:"until"` // RFC3339
+}
+
+// PlaceLegalHoldRequest represents a legal hold request
+type PlaceLegalHoldRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=room user"`
+	TargetID   string `json:"target_id" binding:"required,uuid"`
+	Reason     string `json:"reason" binding:"required,max=1000"`
+}
+
+// ImportUserRow is one account in a bulk import
+type ImportUserRow struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`     // user (default) or admin
+	Password string `json:"password,omitempty"` // empty generates one and requires send_invitations
+}
+
+// ImportUsersRequest represents a JSON bulk user import
+type ImportUsersRequest struct {
+	Users           []ImportUserRow `json:"users" binding:"required,min=1,max=1000"`
+	SendInvitations bool            `json:"send_invitations,omitempty"` // email each created user their credentials
+}
+
+// MergeRoomRequest represents a request to merge a room into another
+type MergeRoomRequest struct {
+	TargetRoomID   string `json:"target_room_id" binding:"required,uuid"`
+	IncludeHistory *bool  `json:"include_history,omitempty"` // append the message history to the target; defaults to true
+}
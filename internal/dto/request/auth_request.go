@@ -1,16 +1,21 @@
 package request
 
+import "github.com/go-demo/chat/internal/pkg/pagination"
+
 // RegisterRequest represents a registration request
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8,max=72"`
+	Username   string `json:"username" binding:"required,min=3,max=50"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,min=8,max=72"`
+	DeviceName string `json:"device_name" binding:"max=100"`
 }
 
 // LoginRequest represents a login request
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	DeviceID   string `json:"device_id" binding:"omitempty,uuid"`
+	DeviceName string `json:"device_name" binding:"max=100"`
 }
 
 // RefreshTokenRequest represents a token refresh request
@@ -18,15 +23,57 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// SSOExchangeRequest trades a single sign-on code for tokens
+type SSOExchangeRequest struct {
+	Code       string `json:"code" binding:"required"`
+	DeviceID   string `json:"device_id" binding:"omitempty,uuid"`
+	DeviceName string `json:"device_name" binding:"max=100"`
+}
+
 // ChangePasswordRequest represents a password change request
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
 }
 
+// DeleteAccountRequest confirms account deletion with the current password
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// FriendSuggestionsQuery pages through friend suggestions
+type FriendSuggestionsQuery struct {
+	Limit  int    `form:"limit,default=10" binding:"min=1,max=50"`
+	Cursor string `form:"cursor"`
+}
+
+// Offset returns where the page starts, from the start when Cursor is
+// empty or invalid
+func (q *FriendSuggestionsQuery) Offset() int {
+	offset, _ := pagination.DecodeCursor(q.Cursor)
+	return offset
+}
+
+// FetchLimit returns the row count to query, one beyond Limit to detect a next page
+func (q *FriendSuggestionsQuery) FetchLimit() int {
+	return pagination.FetchLimit(q.Limit)
+}
+
 // UpdateProfileRequest represents a profile update request
 type UpdateProfileRequest struct {
 	DisplayName *string `json:"display_name,omitempty" binding:"omitempty,max=100"`
 	AvatarURL   *string `json:"avatar_url,omitempty" binding:"omitempty,url,max=500"`
 	Bio         *string `json:"bio,omitempty" binding:"omitempty,max=500"`
+	// Who may see when the user was last online
+	LastSeenVisibility *string `json:"last_seen_visibility,omitempty" binding:"omitempty,oneof=everyone friends nobody"`
+	// Whether contacts discovery may find the user
+	Discoverable *bool `json:"discoverable,omitempty"`
+	// How often unread activity is summarized by email
+	DigestFrequency *string `json:"digest_frequency,omitempty" binding:"omitempty,oneof=off daily weekly"`
+}
+
+// DiscoverContactsRequest lists a client's contacts as lowercase hex
+// SHA-256 hashes of their trimmed, lowercased emails
+type DiscoverContactsRequest struct {
+	EmailHashes []string `json:"email_hashes" binding:"required,min=1,max=500,dive,sha256"`
 }
@@ -0,0 +1,22 @@
+package request
+
+// SubmitFeedbackRequest represents a bug report or suggestion. It is sent
+// as multipart/form-data when a screenshot is attached, JSON otherwise.
+type SubmitFeedbackRequest struct {
+	Category   string `json:"category" form:"category" binding:"required,oneof=bug suggestion other"`
+	Text       string `json:"text" form:"text" binding:"required,max=5000"`
+	AppVersion string `json:"app_version,omitempty" form:"app_version" binding:"max=50"`
+	Client     string `json:"client,omitempty" form:"client" binding:"max=100"` // e.g. "web", "ios", "android"
+}
+
+// FeedbackQuery represents feedback list filters
+type FeedbackQuery struct {
+	Status   string `form:"status" binding:"omitempty,oneof=open in_progress resolved dismissed"`
+	Category string `form:"category" binding:"omitempty,oneof=bug suggestion other"`
+}
+
+// TriageFeedbackRequest represents an admin's triage of feedback
+type TriageFeedbackRequest struct {
+	Status string `json:"status" binding:"required,oneof=open in_progress resolved dismissed"`
+	Note   string `json:"note,omitempty" binding:"max=2000"`
+}
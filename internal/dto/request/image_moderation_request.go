@@ -0,0 +1,15 @@
+package request
+
+// UpdateImageModerationRequest changes the image moderation settings;
+// omitted fields are left as they are. Scores are between 0 and 1.
+type UpdateImageModerationRequest struct {
+	Enabled        *bool    `json:"enabled,omitempty"`
+	NSFWThreshold  *float64 `json:"nsfw_threshold,omitempty" binding:"omitempty,gt=0,lte=1"`  // blurred and queued for review at or above
+	BlockThreshold *float64 `json:"block_threshold,omitempty" binding:"omitempty,gt=0,lte=1"` // rejected at or above
+}
+
+// ReviewImageRequest represents an admin's verdict on a queued image
+type ReviewImageRequest struct {
+	Verdict string `json:"verdict" binding:"required,oneof=clean nsfw blocked"`
+	Note    string `json:"note,omitempty" binding:"max=2000"`
+}
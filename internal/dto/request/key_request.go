@@ -0,0 +1,22 @@
+package request
+
+// OneTimePrekeyRequest is a one-time prekey; keys are standard Base64
+type OneTimePrekeyRequest struct {
+	KeyID     int    `json:"key_id" binding:"min=0"`
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+// RegisterKeysRequest publishes the calling device's keys for encrypted DMs
+type RegisterKeysRequest struct {
+	RegistrationID        int                     `json:"registration_id" binding:"min=0"`
+	IdentityKey           string                  `json:"identity_key" binding:"required"`
+	SignedPrekeyID        int                     `json:"signed_prekey_id" binding:"min=0"`
+	SignedPrekey          string                  `json:"signed_prekey" binding:"required"`
+	SignedPrekeySignature string                  `json:"signed_prekey_signature" binding:"required"`
+	OneTimePrekeys        []*OneTimePrekeyRequest `json:"one_time_prekeys,omitempty" binding:"omitempty,dive"`
+}
+
+// UploadPrekeysRequest tops up the calling device's one-time prekeys
+type UploadPrekeysRequest struct {
+	OneTimePrekeys []*OneTimePrekeyRequest `json:"one_time_prekeys" binding:"required,min=1,dive"`
+}
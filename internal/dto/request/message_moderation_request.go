@@ -0,0 +1,7 @@
+package request
+
+// ReviewMessageRequest represents an admin's decision on a flagged message
+type ReviewMessageRequest struct {
+	Action string `json:"action" binding:"required,oneof=approve remove"`
+	Note   string `json:"note,omitempty" binding:"max=2000"`
+}
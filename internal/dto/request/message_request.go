@@ -1,10 +1,34 @@
 package request
 
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/pagination"
+)
+
 // SendMessageRequest represents a message sending request
 type SendMessageRequest struct {
 	Content   string `json:"content" binding:"required,max=5000"`
-	Type      string `json:"type,omitempty" binding:"omitempty,oneof=text image file"` // default: text
+	Type      string `json:"type,omitempty" binding:"omitempty,oneof=text image file sticker"` // default: text; a sticker's content is the sticker ID
 	ReplyToID string `json:"reply_to_id,omitempty" binding:"omitempty,uuid"`
+
+	// AttachmentIDs are uploads, made by the sender, attached to the message
+	AttachmentIDs []string `json:"attachment_ids,omitempty" binding:"omitempty,max=10,dive,uuid"`
+
+	// ScheduledAt (RFC3339) holds the message back until then instead of sending it now
+	ScheduledAt string `json:"scheduled_at,omitempty"`
+}
+
+// ForwardMessageRequest names where a message is forwarded to: exactly one
+// of a room or a user
+type ForwardMessageRequest struct {
+	RoomID string `json:"room_id,omitempty" binding:"omitempty,uuid"`
+	UserID string `json:"user_id,omitempty" binding:"omitempty,uuid"`
+}
+
+// UpdateConversationSettingsRequest represents a DM conversation settings update
+type UpdateConversationSettingsRequest struct {
+	MessageTTL *int `json:"message_ttl" binding:"required"` // seconds; 0 turns disappearing messages off
 }
 
 // UpdateMessageRequest represents a message update request
@@ -12,25 +36,94 @@ type UpdateMessageRequest struct {
 	Content string `json:"content" binding:"required,max=5000"`
 }
 
-// SendDirectMessageRequest represents a direct message sending request
+// SendDirectMessageRequest represents a direct message sending request.
+// Ciphertext messages leave content empty and carry one envelope per device.
 type SendDirectMessageRequest struct {
+	Content   string               `json:"content,omitempty" binding:"max=5000"`
+	Type      string               `json:"type,omitempty" binding:"omitempty,oneof=text image file ciphertext"` // default: text
+	Envelopes []*DMEnvelopeRequest `json:"envelopes,omitempty" binding:"omitempty,max=100,dive"`
+
+	// AttachmentIDs are uploads, made by the sender, attached to the message
+	AttachmentIDs []string `json:"attachment_ids,omitempty" binding:"omitempty,max=10,dive,uuid"`
+}
+
+// DMEnvelopeRequest is an encrypted message for one device
+type DMEnvelopeRequest struct {
+	DeviceID   string `json:"device_id" binding:"required,uuid"`
+	Type       int    `json:"type" binding:"required,oneof=1 3"` // 1 一般訊息、3 含預金鑰的首則訊息
+	Ciphertext string `json:"ciphertext" binding:"required"`     // Base64
+}
+
+// CreateGroupConversationRequest represents a group conversation creation
+// request; the creator is a participant without being listed
+type CreateGroupConversationRequest struct {
+	Name           string   `json:"name,omitempty" binding:"max=100"`
+	ParticipantIDs []string `json:"participant_ids" binding:"required,min=2,max=9,dive,uuid"`
+}
+
+// AddGroupParticipantsRequest represents users added to a group conversation
+type AddGroupParticipantsRequest struct {
+	UserIDs []string `json:"user_ids" binding:"required,min=1,max=8,dive,uuid"`
+}
+
+// SendGroupMessageRequest represents a group conversation message
+type SendGroupMessageRequest struct {
 	Content string `json:"content" binding:"required,max=5000"`
 	Type    string `json:"type,omitempty" binding:"omitempty,oneof=text image file"` // default: text
 }
 
-// PaginationRequest represents pagination parameters
+// UploadMessageContentRequest holds a message body too large for a
+// WebSocket frame
+type UploadMessageContentRequest struct {
+	Content string `json:"content" binding:"required,max=5000"`
+}
+
+// PaginationRequest represents pagination parameters.
+// Cursor, when valid, takes precedence over Page.
 type PaginationRequest struct {
-	Page  int `form:"page,default=1" binding:"min=1"`
-	Limit int `form:"limit,default=20" binding:"min=1,max=100"`
+	Page   int    `form:"page,default=1" binding:"min=1"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100"`
+	Cursor string `form:"cursor"`
 }
 
 // Offset calculates the offset for database queries
 func (p *PaginationRequest) Offset() int {
+	if p.Cursor != "" {
+		if offset, ok := pagination.DecodeCursor(p.Cursor); ok {
+			return offset
+		}
+	}
 	return (p.Page - 1) * p.Limit
 }
 
+// FetchLimit returns the row count to query, one beyond Limit to detect a next page
+func (p *PaginationRequest) FetchLimit() int {
+	return pagination.FetchLimit(p.Limit)
+}
+
+// MessageCursorQuery pages messages by message ID instead of offset
+type MessageCursorQuery struct {
+	Before string `form:"before" binding:"omitempty,uuid"` // 此訊息之前的一頁
+	After  string `form:"after" binding:"omitempty,uuid"`  // 此訊息之後的訊息
+}
+
+// PollMessagesQuery waits for messages after a message ID
+type PollMessagesQuery struct {
+	AfterID string `form:"after_id" binding:"required,uuid"`          // 此訊息之後的訊息
+	Timeout int    `form:"timeout,default=25" binding:"min=1,max=60"` // 最長等待秒數
+	Limit   int    `form:"limit,default=50" binding:"min=1,max=100"`  // 最多回傳筆數
+}
+
 // SearchRequest represents a search request
 type SearchRequest struct {
 	Query string `form:"q" binding:"required,min=1,max=100"`
 	PaginationRequest
 }
+
+// MessageSearchRequest narrows a message search by sender and time range
+type MessageSearchRequest struct {
+	SearchRequest
+	SenderID string    `form:"sender_id" binding:"omitempty,uuid"`
+	From     time.Time `form:"from"` // RFC3339，包含
+	To       time.Time `form:"to"`   // RFC3339，不包含
+}
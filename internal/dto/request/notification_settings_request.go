@@ -0,0 +1,10 @@
+package request
+
+// UpdateNotificationSettingRequest sets the notification level of one room
+// or direct message conversation. "all" restores the default, "mentions"
+// (rooms only) keeps just mentions and "none" mutes the target.
+type UpdateNotificationSettingRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=room dm"`
+	TargetID   string `json:"target_id" binding:"required,uuid"` // room ID, or the other user's ID for a DM
+	Level      string `json:"level" binding:"required,oneof=all mentions none"`
+}
@@ -0,0 +1,22 @@
+package request
+
+// SubmitReportRequest represents a report about a room message or a user
+type SubmitReportRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=message user"`
+	TargetID   string `json:"target_id" binding:"required,uuid"` // message or user ID
+	Category   string `json:"category" binding:"required,oneof=spam harassment hate sexual violence impersonation other"`
+	Note       string `json:"note,omitempty" binding:"max=2000"`
+}
+
+// ReportQuery represents report list filters
+type ReportQuery struct {
+	Status     string `form:"status" binding:"omitempty,oneof=open reviewing resolved"`
+	TargetType string `form:"target_type" binding:"omitempty,oneof=message user"`
+	Category   string `form:"category" binding:"omitempty,oneof=spam harassment hate sexual violence impersonation other"`
+}
+
+// UpdateReportRequest represents an admin moving a report along
+type UpdateReportRequest struct {
+	Status string `json:"status" binding:"required,oneof=open reviewing resolved"`
+	Note   string `json:"note,omitempty" binding:"max=2000"`
+}
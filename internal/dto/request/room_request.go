@@ -6,6 +6,13 @@ type CreateRoomRequest struct {
 	Description string `json:"description,omitempty" binding:"omitempty,max=500"`
 	Type        string `json:"type,omitempty" binding:"omitempty,oneof=public private"` // default: public
 	MaxMembers  int    `json:"max_members,omitempty" binding:"omitempty,min=2,max=1000"`
+	ReadOnly    bool   `json:"read_only,omitempty"`    // announcement room: only owner/admins may post
+	MessageTTL  int    `json:"message_ttl,omitempty"`  // seconds new messages live; 0 keeps them
+	FeedEnabled bool   `json:"feed_enabled,omitempty"` // public rooms only
+	Language    string `json:"language,omitempty" binding:"omitempty,max=20"`
+	RateLimit   int    `json:"rate_limit,omitempty" binding:"omitempty,min=0"` // messages per member per minute
+
+	ModerationLevel string `json:"moderation_level,omitempty" binding:"omitempty,oneof=off standard strict"` // default: standard
 }
 
 // UpdateRoomRequest represents a room update request
@@ -13,6 +20,13 @@ type UpdateRoomRequest struct {
 	Name        *string `json:"name,omitempty" binding:"omitempty,min=2,max=100"`
 	Description *string `json:"description,omitempty" binding:"omitempty,max=500"`
 	MaxMembers  *int    `json:"max_members,omitempty" binding:"omitempty,min=2,max=1000"`
+	ReadOnly    *bool   `json:"read_only,omitempty"`
+	MessageTTL  *int    `json:"message_ttl,omitempty"` // 0 turns disappearing messages off
+	FeedEnabled *bool   `json:"feed_enabled,omitempty"`
+	Language    *string `json:"language,omitempty" binding:"omitempty,max=20"`
+	RateLimit   *int    `json:"rate_limit,omitempty" binding:"omitempty,min=0"` // 0 restores the global default
+
+	ModerationLevel *string `json:"moderation_level,omitempty" binding:"omitempty,oneof=off standard strict"`
 }
 
 // InviteMemberRequest represents an invite member request
@@ -24,3 +38,39 @@ type InviteMemberRequest struct {
 type UpdateMemberRoleRequest struct {
 	Role string `json:"role" binding:"required,oneof=admin member"`
 }
+
+// MuteMemberRequest represents a request to mute a member for a while
+type MuteMemberRequest struct {
+	Minutes int `json:"minutes" binding:"required,min=1,max=43200"` // up to 30 days
+}
+
+// UpdateRoomPermissionsRequest replaces a room's role permission grants,
+// e.g. {"roles": {"member": {"can_invite": true}}}
+type UpdateRoomPermissionsRequest struct {
+	Roles map[string]map[string]bool `json:"roles" binding:"required"`
+}
+
+// CreateJoinRequestRequest represents a request to join a private room
+type CreateJoinRequestRequest struct {
+	Message string `json:"message,omitempty" binding:"omitempty,max=500"`
+}
+
+// CreateRoomEventRequest represents a request to schedule a room event
+type CreateRoomEventRequest struct {
+	Title       string `json:"title" binding:"required,max=200"`
+	Description string `json:"description,omitempty" binding:"omitempty,max=2000"`
+	Location    string `json:"location,omitempty" binding:"omitempty,max=200"`
+	StartsAt    string `json:"starts_at" binding:"required"` // RFC3339
+	EndsAt      string `json:"ends_at,omitempty"`            // RFC3339，空值表示未指定結束時間
+}
+
+// RSVPRequest represents a member's answer to a room event
+type RSVPRequest struct {
+	Status string `json:"status" binding:"required,oneof=going maybe declined"`
+}
+
+// RoomActivityHeatmapQuery represents activity heatmap query parameters
+type RoomActivityHeatmapQuery struct {
+	Weeks    int    `form:"weeks" binding:"omitempty,min=1,max=12"`
+	TimeZone string `form:"tz" binding:"max=64"` // IANA 時區，例如 Asia/Taipei
+}
@@ -0,0 +1,19 @@
+package request
+
+// CreateWebhookRequest registers an outbound webhook for a room
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url,max=2000"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=message.created member.joined member.left"`
+}
+
+// IncomingWebhookRequest sets the bot identity an incoming webhook posts as
+type IncomingWebhookRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	AvatarURL string `json:"avatar_url,omitempty" binding:"omitempty,url,max=500"`
+}
+
+// PostIncomingWebhookRequest is the body external services post to an
+// incoming webhook
+type PostIncomingWebhookRequest struct {
+	Content string `json:"content" binding:"required,max=5000"`
+}
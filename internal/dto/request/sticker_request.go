@@ -0,0 +1,24 @@
+package request
+
+// StickerRequest represents one sticker of a new pack
+type StickerRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	ImageURL string `json:"image_url" binding:"required,max=500"`
+	Width    int    `json:"width,omitempty" binding:"min=0,max=2048"`
+	Height   int    `json:"height,omitempty" binding:"min=0,max=2048"`
+}
+
+// CreateStickerPackRequest represents a sticker pack creation request
+type CreateStickerPackRequest struct {
+	Name        string           `json:"name" binding:"required,max=100"`
+	Description string           `json:"description,omitempty" binding:"max=500"`
+	Stickers    []StickerRequest `json:"stickers" binding:"required,min=1,max=120,dive"`
+}
+
+// UpdateStickerPackRequest represents a sticker pack update; omitted
+// fields are left unchanged
+type UpdateStickerPackRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,max=100"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=500"`
+	IsActive    *bool   `json:"is_active,omitempty"`
+}
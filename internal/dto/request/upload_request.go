@@ -0,0 +1,25 @@
+package request
+
+// CreateUploadSessionRequest starts a resumable upload
+type CreateUploadSessionRequest struct {
+	Category    string `json:"category" binding:"required,oneof=image file avatar"`
+	FileName    string `json:"file_name" binding:"required,max=255"`
+	ContentType string `json:"content_type" binding:"required,max=255"`
+	Size        int64  `json:"size" binding:"required,min=1"` // total bytes the client will send
+}
+
+// UploadLimitRequest overrides the limits of one upload category; omitted
+// fields are left as they are. A zero size or an empty type list removes
+// the override so the configured value applies again.
+type UploadLimitRequest struct {
+	MaxSize      *int64   `json:"max_size,omitempty" binding:"omitempty,min=0"` // bytes
+	AllowedTypes []string `json:"allowed_types,omitempty" binding:"max=100,dive,max=255"`
+}
+
+// UpdateUploadSettingsRequest changes the upload limits; omitted
+// categories are left as they are
+type UpdateUploadSettingsRequest struct {
+	Image  *UploadLimitRequest `json:"image,omitempty"`
+	File   *UploadLimitRequest `json:"file,omitempty"`
+	Avatar *UploadLimitRequest `json:"avatar,omitempty"`
+}
@@ -0,0 +1,331 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-demo/chat/internal/mail/templates"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// BanResponse represents a ban or suspension
+type BanResponse struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Kind      string `json:"kind"`
+	Reason    string `json:"reason"`
+	BannedBy  string `json:"banned_by,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NewBanResponse creates a ban response from model
+func NewBanResponse(ban *model.UserBan) *BanResponse {
+	resp := &BanResponse{
+		ID:        ban.ID,
+		UserID:    ban.UserID,
+		Kind:      string(ban.Kind()),
+		Reason:    ban.Reason,
+		CreatedAt: ban.CreatedAt.Format(time.RFC3339),
+	}
+	if ban.BannedBy.Valid {
+		resp.BannedBy = ban.BannedBy.String
+	}
+	if ban.ExpiresAt != nil {
+		resp.ExpiresAt = ban.ExpiresAt.Format(time.RFC3339)
+	}
+	if ban.RevokedAt != nil {
+		resp.RevokedAt = ban.RevokedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// AuditLogResponse represents an audit log entry
+type AuditLogResponse struct {
+	ID         string          `json:"id"`
+	ActorID    string          `json:"actor_id,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id,omitempty"`
+	Metadata   json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// NewAuditLogResponse creates an audit log response from model
+func NewAuditLogResponse(entry *model.AuditLog) *AuditLogResponse {
+	resp := &AuditLogResponse{
+		ID:         entry.ID,
+		Action:     string(entry.Action),
+		TargetType: entry.TargetType,
+		CreatedAt:  entry.CreatedAt.Format(time.RFC3339),
+	}
+	if entry.ActorID.Valid {
+		resp.ActorID = entry.ActorID.String
+	}
+	if entry.TargetID.Valid {
+		resp.TargetID = entry.TargetID.String
+	}
+	if len(entry.Metadata) > 0 && string(entry.Metadata) != "{}" {
+		resp.Metadata = json.RawMessage(entry.Metadata)
+	}
+	return resp
+}
+
+// LegalHoldResponse represents a legal hold
+type LegalHoldResponse struct {
+	ID         string `json:"id"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Reason     string `json:"reason"`
+	PlacedBy   string `json:"placed_by,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// NewLegalHoldResponse creates a legal hold response from model
+func NewLegalHoldResponse(hold *model.LegalHold) *LegalHoldResponse {
+	resp := &LegalHoldResponse{
+		ID:         hold.ID,
+		TargetType: string(hold.TargetType),
+		TargetID:   hold.TargetID,
+		Reason:     hold.Reason,
+		CreatedAt:  hold.CreatedAt.Format(time.RFC3339),
+	}
+	if hold.PlacedBy.Valid {
+		resp.PlacedBy = hold.PlacedBy.String
+	}
+	return resp
+}
+
+// IPBanResponse represents an IP ban
+type IPBanResponse struct {
+	ID        string `json:"id"`
+	CIDR      string `json:"cidr"`
+	Reason    string `json:"reason"`
+	Tarpit    bool   `json:"tarpit"`
+	CreatedBy string `json:"created_by,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NewIPBanResponse creates an IP ban response from model
+func NewIPBanResponse(ban *model.IPBan) *IPBanResponse {
+	resp := &IPBanResponse{
+		ID:        ban.ID,
+		CIDR:      ban.CIDR,
+		Reason:    ban.Reason,
+		Tarpit:    ban.Tarpit,
+		CreatedAt: ban.CreatedAt.Format(time.RFC3339),
+	}
+	if ban.CreatedBy.Valid {
+		resp.CreatedBy = ban.CreatedBy.String
+	}
+	if ban.ExpiresAt != nil {
+		resp.ExpiresAt = ban.ExpiresAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// IPAccessRuleResponse represents an IP access rule
+type IPAccessRuleResponse struct {
+	ID        string `json:"id,omitempty"`
+	Scope     string `json:"scope"`
+	Action    string `json:"action"`
+	CIDR      string `json:"cidr,omitempty"`
+	Country   string `json:"country,omitempty"`
+	Note      string `json:"note,omitempty"`
+	Source    string `json:"source"` // "config" or "api"
+	CreatedBy string `json:"created_by,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// NewIPAccessRuleResponse creates an IP access rule response from model
+func NewIPAccessRuleResponse(rule *model.IPAccessRule) *IPAccessRuleResponse {
+	resp := &IPAccessRuleResponse{
+		ID:      rule.ID,
+		Scope:   rule.Scope,
+		Action:  string(rule.Action),
+		CIDR:    rule.CIDR.String,
+		Country: rule.Country.String,
+		Note:    rule.Note,
+		Source:  "api",
+	}
+	if rule.ID == "" {
+		resp.Source = "config"
+		return resp
+	}
+	if rule.CreatedBy.Valid {
+		resp.CreatedBy = rule.CreatedBy.String
+	}
+	resp.CreatedAt = rule.CreatedAt.Format(time.RFC3339)
+	return resp
+}
+
+// IPAccessScopeResult is whether an address may access a scope
+type IPAccessScopeResult struct {
+	Scope   string `json:"scope"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// IPAccessCheckResponse is how the rules treat an address
+type IPAccessCheckResponse struct {
+	IP      string                 `json:"ip"`
+	Country string                 `json:"country,omitempty"`
+	Scopes  []*IPAccessScopeResult `json:"scopes"`
+}
+
+// MailTemplatesResponse lists the available mail templates
+type MailTemplatesResponse struct {
+	Templates     []string `json:"templates"`
+	Locales       []string `json:"locales"`
+	DefaultLocale string   `json:"default_locale"`
+}
+
+// MailPreviewResponse is a rendered mail template
+type MailPreviewResponse struct {
+	Template string `json:"template"`
+	Locale   string `json:"locale"`
+	Subject  string `json:"subject"`
+	HTML     string `json:"html"`
+	Text     string `json:"text"`
+}
+
+// NewMailPreviewResponse creates a mail preview response
+func NewMailPreviewResponse(name string, msg *templates.Message) *MailPreviewResponse {
+	return &MailPreviewResponse{
+		Template: name,
+		Locale:   msg.Locale,
+		Subject:  msg.Subject,
+		HTML:     msg.HTML,
+		Text:     msg.Text,
+	}
+}
+
+// UserImportRowResponse is the outcome of one imported row
+type UserImportRowResponse struct {
+	Row      int    `json:"row"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Status   string `json:"status"` // created, skipped or failed
+	UserID   string `json:"user_id,omitempty"`
+	Invited  bool   `json:"invited"`
+	Error    string `json:"error,omitempty"`
+}
+
+// UserImportResponse summarizes a bulk user import
+type UserImportResponse struct {
+	Total   int                      `json:"total"`
+	Created int                      `json:"created"`
+	Skipped int                      `json:"skipped"`
+	Failed  int                      `json:"failed"`
+	Results []*UserImportRowResponse `json:"results"`
+}
+
+// NewUserImportResponse creates a user import response
+func NewUserImportResponse(summary *service.UserImportSummary) *UserImportResponse {
+	resp := &UserImportResponse{
+		Total:   len(summary.Results),
+		Created: summary.Created,
+		Skipped: summary.Skipped,
+		Failed:  summary.Failed,
+		Results: make([]*UserImportRowResponse, len(summary.Results)),
+	}
+	for i, r := range summary.Results {
+		resp.Results[i] = &UserImportRowResponse{
+			Row:      r.Row,
+			Username: r.Username,
+			Email:    r.Email,
+			Status:   r.Status,
+			UserID:   r.UserID,
+			Invited:  r.Invited,
+			Error:    r.Error,
+		}
+	}
+	return resp
+}
+
+// RoomMergeResponse represents a room merge and its progress
+type RoomMergeResponse struct {
+	ID             string `json:"id"`
+	SourceRoomID   string `json:"source_room_id"`
+	TargetRoomID   string `json:"target_room_id"`
+	IncludeHistory bool   `json:"include_history"`
+	Status         string `json:"status"`
+	MembersMoved   int    `json:"members_moved"`
+	MessagesMoved  int    `json:"messages_moved"`
+	Error          string `json:"error,omitempty"`
+	MergedBy       string `json:"merged_by,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+	CompletedAt    string `json:"completed_at,omitempty"`
+}
+
+// NewRoomMergeResponse creates a room merge response from model
+func NewRoomMergeResponse(merge *model.RoomMerge) *RoomMergeResponse {
+	resp := &RoomMergeResponse{
+		ID:             merge.ID,
+		SourceRoomID:   merge.SourceRoomID,
+		TargetRoomID:   merge.TargetRoomID,
+		IncludeHistory: merge.IncludeHistory,
+		Status:         string(merge.Status),
+		MembersMoved:   merge.MembersMoved,
+		MessagesMoved:  merge.MessagesMoved,
+		Error:          merge.Error.String,
+		MergedBy:       merge.MergedBy.String,
+		CreatedAt:      merge.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      merge.UpdatedAt.Format(time.RFC3339),
+	}
+	if merge.CompletedAt != nil {
+		resp.CompletedAt = merge.CompletedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// SpamFlagResponse represents an account the spam sweep flagged
+type SpamFlagResponse struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"user_id"`
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	Score     int      `json:"score"`
+	Signals   []string `json:"signals"`
+	Action    string   `json:"action"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// NewSpamFlagResponse creates a spam flag response from model
+func NewSpamFlagResponse(flag *model.SpamFlagWithUser) *SpamFlagResponse {
+	return &SpamFlagResponse{
+		ID:        flag.ID,
+		UserID:    flag.UserID,
+		Username:  flag.Username,
+		Email:     flag.Email,
+		Score:     flag.Score,
+		Signals:   flag.Signals,
+		Action:    string(flag.Action),
+		CreatedAt: flag.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// JWTKeyResponse represents a rotated JWT signing key, without its secret
+type JWTKeyResponse struct {
+	ID          string `json:"id"`
+	CreatedBy   string `json:"created_by,omitempty"`
+	ActivatesAt string `json:"activates_at"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// NewJWTKeyResponse creates a JWT key response from model
+func NewJWTKeyResponse(key *model.JWTSigningKey) *JWTKeyResponse {
+	resp := &JWTKeyResponse{
+		ID:          key.ID,
+		ActivatesAt: key.ActivatesAt.Format(time.RFC3339),
+		CreatedAt:   key.CreatedAt.Format(time.RFC3339),
+	}
+	if key.CreatedBy.Valid {
+		resp.CreatedBy = key.CreatedBy.String
+	}
+	return resp
+}
@@ -12,6 +12,7 @@ type TokenResponse struct {
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	TokenType    string    `json:"token_type"`
+	DeviceID     string    `json:"device_id,omitempty"`
 }
 
 // UserResponse represents a user response
@@ -23,7 +24,12 @@ type UserResponse struct {
 	AvatarURL   string `json:"avatar_url"`
 	Status      string `json:"status"`
 	Bio         string `json:"bio"`
+	IsBot       bool   `json:"is_bot,omitempty"`
 	CreatedAt   string `json:"created_at"`
+
+	LastSeenVisibility string `json:"last_seen_visibility,omitempty"` // only in the user's own profile
+	Discoverable       *bool  `json:"discoverable,omitempty"`         // only in the user's own profile
+	DigestFrequency    string `json:"digest_frequency,omitempty"`     // only in the user's own profile
 }
 
 // NewUserResponse creates a user response from model
@@ -35,10 +41,14 @@ func NewUserResponse(user *model.User, includeEmail bool) *UserResponse {
 		AvatarURL:   user.GetAvatarURL(),
 		Status:      string(user.Status),
 		Bio:         user.GetBio(),
+		IsBot:       user.IsBot,
 		CreatedAt:   user.CreatedAt.Format(time.RFC3339),
 	}
 	if includeEmail {
 		resp.Email = user.Email
+		resp.LastSeenVisibility = string(user.LastSeenVisibility)
+		resp.Discoverable = &user.Discoverable
+		resp.DigestFrequency = string(user.DigestFrequency)
 	}
 	return resp
 }
@@ -49,6 +59,13 @@ type AuthResponse struct {
 	Token *TokenResponse `json:"token"`
 }
 
+// SSOInfoResponse tells clients how to sign in
+type SSOInfoResponse struct {
+	Mode       string `json:"mode"`
+	LocalLogin bool   `json:"local_login"`
+	LoginURL   string `json:"login_url,omitempty"`
+}
+
 // ProfileResponse represents user profile response
 type ProfileResponse struct {
 	ID          string `json:"id"`
@@ -57,11 +74,12 @@ type ProfileResponse struct {
 	AvatarURL   string `json:"avatar_url"`
 	Status      string `json:"status"`
 	Bio         string `json:"bio"`
+	LastSeenAt  string `json:"last_seen_at,omitempty"`
 }
 
 // NewProfileResponse creates a profile response from model
 func NewProfileResponse(profile *model.UserProfile) *ProfileResponse {
-	return &ProfileResponse{
+	resp := &ProfileResponse{
 		ID:          profile.ID,
 		Username:    profile.Username,
 		DisplayName: profile.DisplayName,
@@ -69,6 +87,56 @@ func NewProfileResponse(profile *model.UserProfile) *ProfileResponse {
 		Status:      string(profile.Status),
 		Bio:         profile.Bio,
 	}
+	if profile.LastSeenAt != nil {
+		resp.LastSeenAt = profile.LastSeenAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// DiscoveredContactResponse represents a user found among a client's
+// contacts, keyed by the hash the client sent
+type DiscoveredContactResponse struct {
+	EmailHash   string `json:"email_hash"`
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+// NewDiscoveredContactResponse creates a discovered contact response from model
+func NewDiscoveredContactResponse(user *model.User) *DiscoveredContactResponse {
+	return &DiscoveredContactResponse{
+		EmailHash:   user.EmailHash.String,
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.GetDisplayName(),
+		AvatarURL:   user.GetAvatarURL(),
+	}
+}
+
+// FriendSuggestionResponse represents a suggested friend and what they
+// have in common with the viewer
+type FriendSuggestionResponse struct {
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	DisplayName   string `json:"display_name"`
+	AvatarURL     string `json:"avatar_url"`
+	Status        string `json:"status"`
+	MutualFriends int    `json:"mutual_friends"`
+	SharedRooms   int    `json:"shared_rooms"`
+}
+
+// NewFriendSuggestionResponse creates a friend suggestion response from model
+func NewFriendSuggestionResponse(s *model.FriendSuggestion) *FriendSuggestionResponse {
+	return &FriendSuggestionResponse{
+		ID:            s.ID,
+		Username:      s.Username,
+		DisplayName:   s.GetDisplayName(),
+		AvatarURL:     s.GetAvatarURL(),
+		Status:        string(s.Status),
+		MutualFriends: s.MutualFriends,
+		SharedRooms:   s.SharedRooms,
+	}
 }
 
 // FriendResponse represents a friend response
@@ -136,3 +204,82 @@ func NewFriendRequestResponse(f *model.FriendshipWithUser) *FriendRequestRespons
 		RequestedAt: f.CreatedAt.Format(time.RFC3339),
 	}
 }
+
+// RefreshTokenResponse represents one refresh token issued to a device
+type RefreshTokenResponse struct {
+	ID        string     `json:"id"`
+	ParentID  string     `json:"parent_id,omitempty"`
+	IP        string     `json:"ip,omitempty"`
+	Status    string     `json:"status"`
+	IssuedAt  time.Time  `json:"issued_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// DeviceResponse represents a device with its token issuance history
+type DeviceResponse struct {
+	ID         string                  `json:"id"`
+	Name       string                  `json:"name"`
+	UserAgent  string                  `json:"user_agent,omitempty"`
+	LastIP     string                  `json:"last_ip,omitempty"`
+	Current    bool                    `json:"current"`
+	CreatedAt  time.Time               `json:"created_at"`
+	LastSeenAt time.Time               `json:"last_seen_at"`
+	RevokedAt  *time.Time              `json:"revoked_at,omitempty"`
+	Tokens     []*RefreshTokenResponse `json:"tokens"`
+}
+
+// NewDeviceResponse creates a device response from model
+func NewDeviceResponse(device *model.UserDevice, tokens []*model.RefreshTokenRecord, current bool) *DeviceResponse {
+	now := time.Now()
+	history := make([]*RefreshTokenResponse, len(tokens))
+	for i, t := range tokens {
+		history[i] = &RefreshTokenResponse{
+			ID:        t.ID,
+			ParentID:  t.ParentID.String,
+			IP:        t.IP.String,
+			Status:    string(t.Status(now)),
+			IssuedAt:  t.IssuedAt,
+			ExpiresAt: t.ExpiresAt,
+			RotatedAt: t.RotatedAt,
+			RevokedAt: t.RevokedAt,
+		}
+	}
+
+	return &DeviceResponse{
+		ID:         device.ID,
+		Name:       device.Name,
+		UserAgent:  device.UserAgent.String,
+		LastIP:     device.LastIP.String,
+		Current:    current,
+		CreatedAt:  device.CreatedAt,
+		LastSeenAt: device.LastSeenAt,
+		RevokedAt:  device.RevokedAt,
+		Tokens:     history,
+	}
+}
+
+// SessionResponse represents a signed-in device
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// NewSessionResponse creates a session response from model
+func NewSessionResponse(device *model.UserDevice, current bool) *SessionResponse {
+	return &SessionResponse{
+		ID:         device.ID,
+		DeviceName: device.Name,
+		UserAgent:  device.UserAgent.String,
+		IP:         device.LastIP.String,
+		Current:    current,
+		CreatedAt:  device.CreatedAt,
+		LastUsedAt: device.LastSeenAt,
+	}
+}
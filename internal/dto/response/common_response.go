@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/pagination"
 )
 
 // Response represents a standard API response
@@ -12,9 +13,31 @@ type Response struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    *Meta       `json:"meta,omitempty"`
 	Error   *ErrorInfo  `json:"error,omitempty"`
 }
 
+// Meta represents pagination metadata for list responses
+type Meta struct {
+	Limit      int    `json:"limit"`
+	Returned   int    `json:"returned"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NewMeta creates list metadata for a page starting at offset
+func NewMeta(limit, offset, returned int, hasMore bool) *Meta {
+	meta := &Meta{
+		Limit:    limit,
+		Returned: returned,
+		HasMore:  hasMore,
+	}
+	if hasMore {
+		meta.NextCursor = pagination.EncodeCursor(offset + returned)
+	}
+	return meta
+}
+
 // ErrorInfo represents error information
 type ErrorInfo struct {
 	Code    int         `json:"code"`
@@ -30,6 +53,15 @@ func Success(c *gin.Context, data interface{}) {
 	})
 }
 
+// SuccessWithMeta sends a success response with list metadata
+func SuccessWithMeta(c *gin.Context, data interface{}, meta *Meta) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    data,
+		Meta:    meta,
+	})
+}
+
 // SuccessWithMessage sends a success response with a message
 func SuccessWithMessage(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusOK, Response{
@@ -57,10 +89,18 @@ func Error(c *gin.Context, err error) {
 	var appErr *apperrors.AppError
 	if e, ok := err.(*apperrors.AppError); ok {
 		appErr = e
+	} else if e := apperrors.FromContext(err); e != nil {
+		appErr = e
 	} else {
 		appErr = apperrors.ErrInternal
 	}
 
+	// Services collapse repository failures into ErrInternal; if the request
+	// context is done, that failure was caused by the client going away
+	if e := apperrors.FromContext(c.Request.Context().Err()); e != nil {
+		appErr = e
+	}
+
 	c.JSON(appErr.Code, Response{
 		Success: false,
 		Error: &ErrorInfo{
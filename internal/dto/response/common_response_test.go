@@ -0,0 +1,68 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+)
+
+func performErrorRequest(ctx context.Context, err error) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		Error(c, err)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestError_AppError(t *testing.T) {
+	w := performErrorRequest(context.Background(), apperrors.ErrRoomNotFound)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestError_ClientGoneAway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Services report ErrInternal when their query was aborted
+	w := performErrorRequest(ctx, apperrors.ErrInternal)
+
+	if w.Code != apperrors.StatusClientClosedRequest {
+		t.Errorf("Expected status 499, got %d", w.Code)
+	}
+}
+
+func TestError_DeadlineExceeded(t *testing.T) {
+	w := performErrorRequest(context.Background(), context.DeadlineExceeded)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", w.Code)
+	}
+}
+
+func TestNewMeta(t *testing.T) {
+	meta := NewMeta(20, 40, 20, true)
+	if meta.Limit != 20 || meta.Returned != 20 || !meta.HasMore {
+		t.Errorf("Unexpected meta: %+v", meta)
+	}
+	if offset, ok := pagination.DecodeCursor(meta.NextCursor); !ok || offset != 60 {
+		t.Errorf("Expected next cursor at offset 60, got %d", offset)
+	}
+
+	last := NewMeta(20, 60, 5, false)
+	if last.HasMore || last.NextCursor != "" {
+		t.Errorf("Expected no next cursor on the last page: %+v", last)
+	}
+}
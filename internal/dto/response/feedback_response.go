@@ -0,0 +1,50 @@
+package response
+
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// FeedbackResponse represents submitted feedback
+type FeedbackResponse struct {
+	ID            string `json:"id"`
+	UserID        string `json:"user_id,omitempty"`
+	Category      string `json:"category"`
+	Text          string `json:"text"`
+	ScreenshotURL string `json:"screenshot_url,omitempty"`
+	AppVersion    string `json:"app_version,omitempty"`
+	Client        string `json:"client,omitempty"`
+	UserAgent     string `json:"user_agent,omitempty"`
+	Status        string `json:"status"`
+	TriageNote    string `json:"triage_note,omitempty"`
+	TriagedBy     string `json:"triaged_by,omitempty"`
+	TriagedAt     string `json:"triaged_at,omitempty"`
+	ForwardedAt   string `json:"forwarded_at,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// NewFeedbackResponse creates a feedback response from model
+func NewFeedbackResponse(fb *model.Feedback) *FeedbackResponse {
+	resp := &FeedbackResponse{
+		ID:            fb.ID,
+		UserID:        fb.UserID.String,
+		Category:      string(fb.Category),
+		Text:          fb.Text,
+		ScreenshotURL: fb.ScreenshotURL.String,
+		AppVersion:    fb.AppVersion.String,
+		Client:        fb.Client.String,
+		UserAgent:     fb.UserAgent.String,
+		Status:        string(fb.Status),
+		TriageNote:    fb.TriageNote.String,
+		TriagedBy:     fb.TriagedBy.String,
+		CreatedAt:     fb.CreatedAt.Format(time.RFC3339),
+	}
+	if fb.TriagedAt != nil {
+		resp.TriagedAt = fb.TriagedAt.Format(time.RFC3339)
+	}
+	if fb.ForwardedAt != nil {
+		resp.ForwardedAt = fb.ForwardedAt.Format(time.RFC3339)
+	}
+	return resp
+}
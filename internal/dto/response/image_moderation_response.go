@@ -0,0 +1,67 @@
+package response
+
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// ImageModerationSettingsResponse represents the image moderation settings
+type ImageModerationSettingsResponse struct {
+	Enabled        bool    `json:"enabled"`
+	NSFWThreshold  float64 `json:"nsfw_threshold"`
+	BlockThreshold float64 `json:"block_threshold"`
+	UpdatedBy      string  `json:"updated_by,omitempty"`
+	UpdatedAt      string  `json:"updated_at"`
+}
+
+// NewImageModerationSettingsResponse creates a settings response from model
+func NewImageModerationSettingsResponse(s *model.ImageModerationSettings) *ImageModerationSettingsResponse {
+	return &ImageModerationSettingsResponse{
+		Enabled:        s.Enabled,
+		NSFWThreshold:  s.NSFWThreshold,
+		BlockThreshold: s.BlockThreshold,
+		UpdatedBy:      s.UpdatedBy.String,
+		UpdatedAt:      s.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// ImageAssetResponse represents a scanned image
+type ImageAssetResponse struct {
+	ID           string   `json:"id"`
+	UserID       string   `json:"user_id,omitempty"`
+	FileURL      string   `json:"file_url"`
+	ContentType  string   `json:"content_type"`
+	Score        *float64 `json:"score,omitempty"` // missing when detection failed
+	Labels       []string `json:"labels,omitempty"`
+	Verdict      string   `json:"verdict"`
+	ReviewStatus string   `json:"review_status"`
+	ReviewNote   string   `json:"review_note,omitempty"`
+	ReviewedBy   string   `json:"reviewed_by,omitempty"`
+	ReviewedAt   string   `json:"reviewed_at,omitempty"`
+	CreatedAt    string   `json:"created_at"`
+}
+
+// NewImageAssetResponse creates a scanned image response from model
+func NewImageAssetResponse(a *model.ImageAsset) *ImageAssetResponse {
+	resp := &ImageAssetResponse{
+		ID:           a.ID,
+		UserID:       a.UserID.String,
+		FileURL:      a.FileURL,
+		ContentType:  a.ContentType,
+		Labels:       a.LabelList(),
+		Verdict:      string(a.Verdict),
+		ReviewStatus: string(a.ReviewStatus),
+		ReviewNote:   a.ReviewNote.String,
+		ReviewedBy:   a.ReviewedBy.String,
+		CreatedAt:    a.CreatedAt.Format(time.RFC3339),
+	}
+	if a.Score.Valid {
+		score := a.Score.Float64
+		resp.Score = &score
+	}
+	if a.ReviewedAt != nil {
+		resp.ReviewedAt = a.ReviewedAt.Format(time.RFC3339)
+	}
+	return resp
+}
@@ -0,0 +1,58 @@
+package response
+
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// DeviceKeysResponse represents the public keys a device published
+type DeviceKeysResponse struct {
+	DeviceID              string `json:"device_id"`
+	RegistrationID        int    `json:"registration_id"`
+	IdentityKey           string `json:"identity_key"`
+	SignedPrekeyID        int    `json:"signed_prekey_id"`
+	SignedPrekey          string `json:"signed_prekey"`
+	SignedPrekeySignature string `json:"signed_prekey_signature"`
+	UpdatedAt             string `json:"updated_at"`
+}
+
+// NewDeviceKeysResponse creates a device keys response from model
+func NewDeviceKeysResponse(k *model.DeviceKeys) *DeviceKeysResponse {
+	return &DeviceKeysResponse{
+		DeviceID:              k.DeviceID,
+		RegistrationID:        k.RegistrationID,
+		IdentityKey:           k.IdentityKey,
+		SignedPrekeyID:        k.SignedPrekeyID,
+		SignedPrekey:          k.SignedPrekey,
+		SignedPrekeySignature: k.SignedPrekeySignature,
+		UpdatedAt:             k.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// OneTimePrekeyResponse represents a one-time prekey
+type OneTimePrekeyResponse struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey string `json:"public_key"`
+}
+
+// PrekeyBundleResponse is what a client needs to open a session with one
+// device; one_time_prekey is absent once the device ran out
+type PrekeyBundleResponse struct {
+	DeviceKeysResponse
+	OneTimePrekey *OneTimePrekeyResponse `json:"one_time_prekey,omitempty"`
+}
+
+// NewPrekeyBundleResponse creates a prekey bundle response from model
+func NewPrekeyBundleResponse(b *model.PrekeyBundle) *PrekeyBundleResponse {
+	resp := &PrekeyBundleResponse{DeviceKeysResponse: *NewDeviceKeysResponse(&b.DeviceKeys)}
+	if b.OneTimePrekey != nil {
+		resp.OneTimePrekey = &OneTimePrekeyResponse{KeyID: b.OneTimePrekey.KeyID, PublicKey: b.OneTimePrekey.PublicKey}
+	}
+	return resp
+}
+
+// PrekeyCountResponse reports how many one-time prekeys a device has left
+type PrekeyCountResponse struct {
+	Count int `json:"count"`
+}
@@ -0,0 +1,57 @@
+package response
+
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// MessageFlagResponse represents a message queued for moderation review
+type MessageFlagResponse struct {
+	ID         string   `json:"id"`
+	MessageID  string   `json:"message_id"`
+	RoomID     string   `json:"room_id"`
+	UserID     string   `json:"user_id,omitempty"`
+	Username   string   `json:"username,omitempty"`
+	Content    string   `json:"content,omitempty"` // only in the review queue
+	Reason     string   `json:"reason"`
+	Score      *float64 `json:"score,omitempty"` // missing when scoring failed
+	Labels     []string `json:"labels,omitempty"`
+	Status     string   `json:"status"`
+	ReviewNote string   `json:"review_note,omitempty"`
+	ReviewedBy string   `json:"reviewed_by,omitempty"`
+	ReviewedAt string   `json:"reviewed_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// NewMessageFlagResponse creates a flagged message response from model
+func NewMessageFlagResponse(f *model.MessageFlag) *MessageFlagResponse {
+	resp := &MessageFlagResponse{
+		ID:         f.ID,
+		MessageID:  f.MessageID,
+		RoomID:     f.RoomID,
+		UserID:     f.UserID.String,
+		Reason:     string(f.Reason),
+		Labels:     f.LabelList(),
+		Status:     string(f.Status),
+		ReviewNote: f.ReviewNote.String,
+		ReviewedBy: f.ReviewedBy.String,
+		CreatedAt:  f.CreatedAt.Format(time.RFC3339),
+	}
+	if f.Score.Valid {
+		score := f.Score.Float64
+		resp.Score = &score
+	}
+	if f.ReviewedAt != nil {
+		resp.ReviewedAt = f.ReviewedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// NewQueuedMessageFlagResponse creates a review queue entry from model
+func NewQueuedMessageFlagResponse(f *model.MessageFlagWithMessage) *MessageFlagResponse {
+	resp := NewMessageFlagResponse(&f.MessageFlag)
+	resp.Username = f.Username.String
+	resp.Content = f.Content
+	return resp
+}
@@ -14,14 +14,21 @@ type MessageResponse struct {
 	Username    string                `json:"username"`
 	DisplayName string                `json:"display_name"`
 	AvatarURL   string                `json:"avatar_url"`
+	IsBot       bool                  `json:"is_bot,omitempty"` // posted through an incoming webhook
 	Content     string                `json:"content"`
 	Type        string                `json:"type"`
 	ReplyToID   string                `json:"reply_to_id,omitempty"`
 	IsEdited    bool                  `json:"is_edited"`
 	IsDeleted   bool                  `json:"is_deleted"`
+	IsNSFW      bool                  `json:"is_nsfw"` // blur the image until the viewer taps it
 	Attachments []*AttachmentResponse `json:"attachments,omitempty"`
 	CreatedAt   string                `json:"created_at"`
 	UpdatedAt   string                `json:"updated_at"`
+	ExpiresAt   string                `json:"expires_at,omitempty"`          // set in rooms with disappearing messages
+	MergedFrom  string                `json:"merged_from_room_id,omitempty"` // room the message was merged in from
+	Forwarded   *model.ForwardedFrom  `json:"forwarded_from,omitempty"`
+	Embeds      []*model.MessageEmbed `json:"embeds,omitempty"`  // link previews, added shortly after sending
+	Sticker     *StickerResponse      `json:"sticker,omitempty"` // set on sticker messages
 }
 
 // NewMessageResponse creates a message response from model
@@ -41,42 +48,103 @@ func NewMessageResponse(m *model.MessageWithUser) *MessageResponse {
 		replyToID = m.ReplyToID.String
 	}
 
-	return &MessageResponse{
+	resp := &MessageResponse{
 		ID:          m.ID,
 		RoomID:      m.RoomID,
 		UserID:      m.UserID,
 		Username:    m.Username,
 		DisplayName: displayName,
 		AvatarURL:   avatarURL,
+		IsBot:       m.IsBot,
 		Content:     m.Content,
 		Type:        string(m.Type),
 		ReplyToID:   replyToID,
 		IsEdited:    m.IsEdited,
 		IsDeleted:   m.IsDeleted,
+		IsNSFW:      m.IsNSFW,
 		CreatedAt:   m.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:   m.UpdatedAt.Format(time.RFC3339),
+		MergedFrom:  m.MergedFromRoomID.String,
+		Forwarded:   m.GetForwardedFrom(),
+		Embeds:      m.GetEmbeds(),
 	}
+
+	if m.ExpiresAt != nil {
+		resp.ExpiresAt = m.ExpiresAt.Format(time.RFC3339)
+	}
+	if m.Sticker != nil {
+		resp.Sticker = NewStickerResponse(m.Sticker)
+	}
+	resp.Attachments = NewAttachmentResponses(m.Attachments)
+
+	return resp
 }
 
 // AttachmentResponse represents a message attachment response
 type AttachmentResponse struct {
 	ID        string `json:"id"`
+	UploadID  string `json:"upload_id"`
 	FileName  string `json:"file_name"`
 	FileURL   string `json:"file_url"`
 	FileType  string `json:"file_type"`
 	FileSize  int64  `json:"file_size"`
 	CreatedAt string `json:"created_at"`
+
+	// ScanStatus is unscanned, pending, clean or infected; clients hold
+	// rendering pending attachments until upload_scanned says clean
+	ScanStatus string `json:"scan_status"`
 }
 
 // NewAttachmentResponse creates an attachment response from model
-func NewAttachmentResponse(a *model.MessageAttachment) *AttachmentResponse {
+func NewAttachmentResponse(a *model.Attachment) *AttachmentResponse {
 	return &AttachmentResponse{
 		ID:        a.ID,
+		UploadID:  a.UploadID,
 		FileName:  a.FileName,
 		FileURL:   a.FileURL,
-		FileType:  a.FileType,
-		FileSize:  a.FileSize,
+		FileType:  a.ContentType,
+		FileSize:  a.Size,
 		CreatedAt: a.CreatedAt.Format(time.RFC3339),
+
+		ScanStatus: string(a.ScanStatus),
+	}
+}
+
+// NewAttachmentResponses creates attachment responses, nil for none
+func NewAttachmentResponses(attachments []*model.Attachment) []*AttachmentResponse {
+	if len(attachments) == 0 {
+		return nil
+	}
+	resp := make([]*AttachmentResponse, len(attachments))
+	for i, a := range attachments {
+		resp[i] = NewAttachmentResponse(a)
+	}
+	return resp
+}
+
+// ScheduledMessageResponse represents a message waiting to be sent
+type ScheduledMessageResponse struct {
+	ID          string `json:"id"`
+	RoomID      string `json:"room_id"`
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	ReplyToID   string `json:"reply_to_id,omitempty"`
+	Status      string `json:"status"`
+	ScheduledAt string `json:"scheduled_at"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// NewScheduledMessageResponse creates a scheduled message response from model
+func NewScheduledMessageResponse(m *model.ScheduledMessage) *ScheduledMessageResponse {
+	return &ScheduledMessageResponse{
+		ID:          m.ID,
+		RoomID:      m.RoomID,
+		Content:     m.Content,
+		Type:        string(m.Type),
+		ReplyToID:   m.ReplyToID.String,
+		Status:      string(m.Status),
+		ScheduledAt: m.ScheduledAt.Format(time.RFC3339),
+		CreatedAt:   m.CreatedAt.Format(time.RFC3339),
 	}
 }
 
@@ -91,7 +159,23 @@ type DirectMessageResponse struct {
 	Content           string `json:"content"`
 	Type              string `json:"type"`
 	IsRead            bool   `json:"is_read"`
+	IsEdited          bool   `json:"is_edited"`
+	IsNSFW            bool   `json:"is_nsfw"` // blur the image until the viewer taps it
 	CreatedAt         string `json:"created_at"`
+	ExpiresAt         string `json:"expires_at,omitempty"` // set in conversations with disappearing messages
+
+	ForwardedFrom *model.ForwardedFrom  `json:"forwarded_from,omitempty"`
+	Attachments   []*AttachmentResponse `json:"attachments,omitempty"`
+
+	// Ciphertext messages: the envelope addressed to the reading device
+	Envelope *DMEnvelopeResponse `json:"envelope,omitempty"`
+}
+
+// DMEnvelopeResponse is an encrypted message for the reading device
+type DMEnvelopeResponse struct {
+	SenderDeviceID string `json:"sender_device_id"`
+	Type           int    `json:"type"`
+	Ciphertext     string `json:"ciphertext"`
 }
 
 // NewDirectMessageResponse creates a direct message response from model
@@ -106,7 +190,7 @@ func NewDirectMessageResponse(m *model.DirectMessageWithUser) *DirectMessageResp
 		senderAvatarURL = m.SenderAvatarURL.String
 	}
 
-	return &DirectMessageResponse{
+	resp := &DirectMessageResponse{
 		ID:                m.ID,
 		SenderID:          m.SenderID,
 		ReceiverID:        m.ReceiverID,
@@ -116,8 +200,47 @@ func NewDirectMessageResponse(m *model.DirectMessageWithUser) *DirectMessageResp
 		Content:           m.Content,
 		Type:              string(m.Type),
 		IsRead:            m.IsRead,
+		IsEdited:          m.IsEdited,
+		IsNSFW:            m.IsNSFW,
 		CreatedAt:         m.CreatedAt.Format(time.RFC3339),
+		ForwardedFrom:     m.GetForwardedFrom(),
+		Attachments:       NewAttachmentResponses(m.Attachments),
+	}
+
+	if m.ExpiresAt != nil {
+		resp.ExpiresAt = m.ExpiresAt.Format(time.RFC3339)
+	}
+
+	if m.Envelope != nil {
+		resp.Envelope = &DMEnvelopeResponse{
+			SenderDeviceID: m.Envelope.SenderDeviceID,
+			Type:           m.Envelope.Type,
+			Ciphertext:     m.Envelope.Ciphertext,
+		}
+	}
+
+	return resp
+}
+
+// ConversationSettingsResponse represents the shared settings of a DM conversation
+type ConversationSettingsResponse struct {
+	UserID     string `json:"user_id"`     // the other participant
+	MessageTTL int    `json:"message_ttl"` // seconds; 0 keeps messages
+	UpdatedBy  string `json:"updated_by,omitempty"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+}
+
+// NewConversationSettingsResponse creates a conversation settings response from model
+func NewConversationSettingsResponse(otherUserID string, s *model.DirectConversationSettings) *ConversationSettingsResponse {
+	resp := &ConversationSettingsResponse{
+		UserID:     otherUserID,
+		MessageTTL: s.MessageTTL,
+		UpdatedBy:  s.UpdatedBy.String,
 	}
+	if !s.UpdatedAt.IsZero() {
+		resp.UpdatedAt = s.UpdatedAt.Format(time.RFC3339)
+	}
+	return resp
 }
 
 // ConversationResponse represents a conversation response
@@ -130,6 +253,8 @@ type ConversationResponse struct {
 	LastMessage   string `json:"last_message"`
 	LastMessageAt string `json:"last_message_at"`
 	UnreadCount   int    `json:"unread_count"`
+	IsArchived    bool   `json:"is_archived"`
+	IsPinned      bool   `json:"is_pinned"`
 }
 
 // NewConversationResponse creates a conversation response from model
@@ -143,6 +268,26 @@ func NewConversationResponse(c *model.Conversation) *ConversationResponse {
 		LastMessage:   c.LastMessage,
 		LastMessageAt: c.LastMessageAt.Format(time.RFC3339),
 		UnreadCount:   c.UnreadCount,
+		IsArchived:    c.IsArchived,
+		IsPinned:      c.IsPinned,
+	}
+}
+
+// ConversationStateResponse represents the user's own states of a DM conversation
+type ConversationStateResponse struct {
+	UserID     string `json:"user_id"` // the other participant
+	IsArchived bool   `json:"is_archived"`
+	IsHidden   bool   `json:"is_hidden"`
+	IsPinned   bool   `json:"is_pinned"`
+}
+
+// NewConversationStateResponse creates a conversation state response from model
+func NewConversationStateResponse(s *model.ConversationSettings) *ConversationStateResponse {
+	return &ConversationStateResponse{
+		UserID:     s.OtherUserID,
+		IsArchived: s.ArchivedAt != nil,
+		IsHidden:   s.HiddenAt != nil,
+		IsPinned:   s.PinnedAt != nil,
 	}
 }
 
@@ -166,3 +311,127 @@ func NewMessageListResponse(messages []*model.MessageWithUser, total int, hasMor
 		HasMore:  hasMore,
 	}
 }
+
+// DMExportResponse represents a conversation export job
+type DMExportResponse struct {
+	ID           string `json:"id"`
+	UserID       string `json:"user_id"` // the other participant
+	Status       string `json:"status"`  // pending, running, completed or failed
+	MessageCount int    `json:"message_count"`
+	Error        string `json:"error,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	CompletedAt  string `json:"completed_at,omitempty"`
+	ExpiresAt    string `json:"expires_at,omitempty"` // the file is deleted after this
+}
+
+// NewDMExportResponse creates a conversation export response from model
+func NewDMExportResponse(e *model.DMExport) *DMExportResponse {
+	resp := &DMExportResponse{
+		ID:           e.ID,
+		UserID:       e.PeerID,
+		Status:       string(e.Status),
+		MessageCount: e.MessageCount,
+		Error:        e.Error.String,
+		CreatedAt:    e.CreatedAt.Format(time.RFC3339),
+	}
+	if e.CompletedAt != nil {
+		resp.CompletedAt = e.CompletedAt.Format(time.RFC3339)
+	}
+	if e.ExpiresAt != nil {
+		resp.ExpiresAt = e.ExpiresAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// GroupParticipantResponse represents a group conversation participant
+type GroupParticipantResponse struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	JoinedAt    string `json:"joined_at"`
+}
+
+// GroupConversationResponse represents a group conversation with its participants
+type GroupConversationResponse struct {
+	ID           string                      `json:"id"`
+	Name         string                      `json:"name,omitempty"`
+	CreatedBy    string                      `json:"created_by,omitempty"` // may remove other participants
+	Participants []*GroupParticipantResponse `json:"participants"`
+	CreatedAt    string                      `json:"created_at"`
+}
+
+// NewGroupConversationResponse creates a group conversation response from model
+func NewGroupConversationResponse(c *model.GroupConversationDetail) *GroupConversationResponse {
+	participants := make([]*GroupParticipantResponse, len(c.Participants))
+	for i, p := range c.Participants {
+		participants[i] = &GroupParticipantResponse{
+			UserID:      p.UserID,
+			Username:    p.Username,
+			DisplayName: p.GetDisplayName(),
+			AvatarURL:   p.GetAvatarURL(),
+			JoinedAt:    p.JoinedAt.Format(time.RFC3339),
+		}
+	}
+
+	return &GroupConversationResponse{
+		ID:           c.ID,
+		Name:         c.Name.String,
+		CreatedBy:    c.CreatedBy.String,
+		Participants: participants,
+		CreatedAt:    c.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// GroupConversationSummaryResponse represents a group conversation in a user's list
+type GroupConversationSummaryResponse struct {
+	ID               string `json:"id"`
+	Name             string `json:"name,omitempty"`
+	ParticipantCount int    `json:"participant_count"`
+	LastMessage      string `json:"last_message"`
+	LastMessageAt    string `json:"last_message_at,omitempty"`
+	UnreadCount      int    `json:"unread_count"`
+}
+
+// NewGroupConversationSummaryResponse creates a group conversation summary response from model
+func NewGroupConversationSummaryResponse(c *model.GroupConversationSummary) *GroupConversationSummaryResponse {
+	resp := &GroupConversationSummaryResponse{
+		ID:               c.ID,
+		Name:             c.Name.String,
+		ParticipantCount: c.ParticipantCount,
+		LastMessage:      c.LastMessage,
+		UnreadCount:      c.UnreadCount,
+	}
+	if c.LastMessageAt != nil {
+		resp.LastMessageAt = c.LastMessageAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// GroupMessageResponse represents a group conversation message
+type GroupMessageResponse struct {
+	ID                string `json:"id"`
+	ConversationID    string `json:"conversation_id"`
+	SenderID          string `json:"sender_id"`
+	SenderUsername    string `json:"sender_username"`
+	SenderDisplayName string `json:"sender_display_name"`
+	SenderAvatarURL   string `json:"sender_avatar_url"`
+	Content           string `json:"content"`
+	Type              string `json:"type"`
+	CreatedAt         string `json:"created_at"`
+}
+
+// NewGroupMessageResponse creates a group message response from model
+func NewGroupMessageResponse(m *model.GroupMessageWithUser) *GroupMessageResponse {
+	return &GroupMessageResponse{
+		ID:                m.ID,
+		ConversationID:    m.ConversationID,
+		SenderID:          m.SenderID,
+		SenderUsername:    m.SenderUsername,
+		SenderDisplayName: m.GetSenderDisplayName(),
+		SenderAvatarURL:   m.GetSenderAvatarURL(),
+		Content:           m.Content,
+		Type:              string(m.Type),
+		CreatedAt:         m.CreatedAt.Format(time.RFC3339),
+	}
+}
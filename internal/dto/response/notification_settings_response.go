@@ -0,0 +1,26 @@
+package response
+
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// NotificationSettingResponse represents the notification level of one
+// room or direct message conversation
+type NotificationSettingResponse struct {
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Level      string `json:"level"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// NewNotificationSettingResponse creates a setting response from model
+func NewNotificationSettingResponse(p *model.NotificationPreference) *NotificationSettingResponse {
+	return &NotificationSettingResponse{
+		TargetType: string(p.TargetType),
+		TargetID:   p.TargetID,
+		Level:      string(p.Level),
+		UpdatedAt:  p.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,45 @@
+package response
+
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// ReadStateResponse represents how far the user has read one room or
+// conversation
+type ReadStateResponse struct {
+	ID          string `json:"id"`
+	LastReadAt  string `json:"last_read_at,omitempty"`
+	UnreadCount int    `json:"unread_count"`
+}
+
+// ReadStatesResponse represents the user's read state everywhere
+type ReadStatesResponse struct {
+	Rooms  []*ReadStateResponse `json:"rooms"`
+	DMs    []*ReadStateResponse `json:"dms"`
+	Groups []*ReadStateResponse `json:"groups"`
+}
+
+// NewReadStatesResponse creates a read states response from model
+func NewReadStatesResponse(states *model.ReadStates) *ReadStatesResponse {
+	return &ReadStatesResponse{
+		Rooms:  newReadStateResponses(states.Rooms),
+		DMs:    newReadStateResponses(states.DMs),
+		Groups: newReadStateResponses(states.Groups),
+	}
+}
+
+func newReadStateResponses(states []*model.ReadState) []*ReadStateResponse {
+	result := make([]*ReadStateResponse, len(states))
+	for i, s := range states {
+		result[i] = &ReadStateResponse{
+			ID:          s.ID,
+			UnreadCount: s.UnreadCount,
+		}
+		if s.LastReadAt != nil {
+			result[i].LastReadAt = s.LastReadAt.Format(time.RFC3339)
+		}
+	}
+	return result
+}
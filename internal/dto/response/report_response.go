@@ -0,0 +1,80 @@
+package response
+
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// ReportResponse represents a report as its reporter sees it
+type ReportResponse struct {
+	ID         string `json:"id"`
+	TargetType string `json:"target_type"`
+	MessageID  string `json:"message_id,omitempty"`
+	UserID     string `json:"user_id,omitempty"` // the reported user or the message author
+	Category   string `json:"category"`
+	Note       string `json:"note,omitempty"`
+	Status     string `json:"status"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// NewReportResponse creates a report response from model
+func NewReportResponse(r *model.Report) *ReportResponse {
+	return &ReportResponse{
+		ID:         r.ID,
+		TargetType: string(r.TargetType),
+		MessageID:  r.MessageID.String,
+		UserID:     r.ReportedUserID.String,
+		Category:   string(r.Category),
+		Note:       r.Note.String,
+		Status:     string(r.Status),
+		CreatedAt:  r.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// AdminReportResponse represents a report in the triage queue
+type AdminReportResponse struct {
+	ID               string `json:"id"`
+	ReporterID       string `json:"reporter_id,omitempty"`
+	ReporterUsername string `json:"reporter_username,omitempty"`
+	TargetType       string `json:"target_type"`
+	MessageID        string `json:"message_id,omitempty"`
+	RoomID           string `json:"room_id,omitempty"`
+	ReportedUserID   string `json:"reported_user_id,omitempty"`
+	ReportedUsername string `json:"reported_username,omitempty"`
+	MessageContent   string `json:"message_content,omitempty"` // as it was when reported
+	Category         string `json:"category"`
+	Note             string `json:"note,omitempty"`
+	Status           string `json:"status"`
+	ResolutionNote   string `json:"resolution_note,omitempty"`
+	HandledBy        string `json:"handled_by,omitempty"`
+	HandledAt        string `json:"handled_at,omitempty"`
+	CreatedAt        string `json:"created_at"`
+	UpdatedAt        string `json:"updated_at"`
+}
+
+// NewAdminReportResponse creates a triage queue entry from model
+func NewAdminReportResponse(r *model.ReportWithUsers) *AdminReportResponse {
+	resp := &AdminReportResponse{
+		ID:               r.ID,
+		ReporterID:       r.ReporterID.String,
+		ReporterUsername: r.ReporterUsername.String,
+		TargetType:       string(r.TargetType),
+		MessageID:        r.MessageID.String,
+		RoomID:           r.RoomID.String,
+		ReportedUserID:   r.ReportedUserID.String,
+		ReportedUsername: r.ReportedUsername.String,
+		MessageContent:   r.MessageContent.String,
+		Category:         string(r.Category),
+		Note:             r.Note.String,
+		Status:           string(r.Status),
+		ResolutionNote:   r.ResolutionNote.String,
+		HandledBy:        r.HandledBy.String,
+		CreatedAt:        r.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:        r.UpdatedAt.Format(time.RFC3339),
+	}
+	if r.HandledAt != nil {
+		resp.HandledAt = r.HandledAt.Format(time.RFC3339)
+	}
+	return resp
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/service"
 )
 
 // RoomResponse represents a room response
@@ -15,7 +17,14 @@ type RoomResponse struct {
 	OwnerID     string `json:"owner_id"`
 	MaxMembers  int    `json:"max_members"`
 	MemberCount int    `json:"member_count"`
+	ReadOnly    bool   `json:"read_only"`
+	MessageTTL  int    `json:"message_ttl"`
+	Language    string `json:"language"`
+	RateLimit   int    `json:"rate_limit"`
 	CreatedAt   string `json:"created_at"`
+
+	ModerationLevel string `json:"moderation_level"`
+	ArchivedAt      string `json:"archived_at,omitempty"`
 }
 
 // NewRoomResponse creates a room response from model
@@ -25,7 +34,7 @@ func NewRoomResponse(room *model.RoomWithMemberCount) *RoomResponse {
 		description = room.Description.String
 	}
 
-	return &RoomResponse{
+	resp := &RoomResponse{
 		ID:          room.ID,
 		Name:        room.Name,
 		Description: description,
@@ -33,21 +42,43 @@ func NewRoomResponse(room *model.RoomWithMemberCount) *RoomResponse {
 		OwnerID:     room.OwnerID,
 		MaxMembers:  room.MaxMembers,
 		MemberCount: room.MemberCount,
+		ReadOnly:    room.ReadOnly,
+		MessageTTL:  room.MessageTTL,
+		Language:    room.Language,
+		RateLimit:   room.RateLimit,
 		CreatedAt:   room.CreatedAt.Format(time.RFC3339),
+
+		ModerationLevel: string(room.ModerationLevel),
+	}
+
+	if room.ArchivedAt != nil {
+		resp.ArchivedAt = room.ArchivedAt.Format(time.RFC3339)
 	}
+
+	return resp
 }
 
 // RoomDetailResponse represents a detailed room response
 type RoomDetailResponse struct {
-	ID          string           `json:"id"`
-	Name        string           `json:"name"`
-	Description string           `json:"description"`
-	Type        string           `json:"type"`
-	Owner       *ProfileResponse `json:"owner"`
-	MaxMembers  int              `json:"max_members"`
-	MemberCount int              `json:"member_count"`
-	CreatedAt   string           `json:"created_at"`
-	UpdatedAt   string           `json:"updated_at"`
+	ID           string           `json:"id"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	Type         string           `json:"type"`
+	Owner        *ProfileResponse `json:"owner"`
+	MaxMembers   int              `json:"max_members"`
+	MemberCount  int              `json:"member_count"`
+	MessageCount int64            `json:"message_count"`
+	ReadOnly     bool             `json:"read_only"`   // clients should disable input unless the viewer may post
+	MessageTTL   int              `json:"message_ttl"` // seconds new messages live; 0 keeps them
+	FeedEnabled  bool             `json:"feed_enabled"`
+	Language     string           `json:"language"`   // locale of the system messages posted in the room
+	RateLimit    int              `json:"rate_limit"` // messages per member per minute; 0 uses the global default
+	CreatedAt    string           `json:"created_at"`
+	UpdatedAt    string           `json:"updated_at"`
+
+	DeletionScheduledAt string `json:"deletion_scheduled_at,omitempty"`
+	ModerationLevel     string `json:"moderation_level"` // off, standard or strict
+	ArchivedAt          string `json:"archived_at,omitempty"`
 }
 
 // NewRoomDetailResponse creates a detailed room response from model
@@ -58,23 +89,110 @@ func NewRoomDetailResponse(room *model.RoomDetail) *RoomDetailResponse {
 	}
 
 	resp := &RoomDetailResponse{
-		ID:          room.ID,
-		Name:        room.Name,
-		Description: description,
-		Type:        string(room.Type),
-		MaxMembers:  room.MaxMembers,
-		MemberCount: room.MemberCount,
-		CreatedAt:   room.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   room.UpdatedAt.Format(time.RFC3339),
+		ID:           room.ID,
+		Name:         room.Name,
+		Description:  description,
+		Type:         string(room.Type),
+		MaxMembers:   room.MaxMembers,
+		MemberCount:  room.MemberCount,
+		MessageCount: room.MessageCount,
+		ReadOnly:     room.ReadOnly,
+		MessageTTL:   room.MessageTTL,
+		FeedEnabled:  room.FeedEnabled,
+		Language:     room.Language,
+		RateLimit:    room.RateLimit,
+		CreatedAt:    room.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    room.UpdatedAt.Format(time.RFC3339),
+
+		ModerationLevel: string(room.ModerationLevel),
 	}
 
 	if room.Owner != nil {
 		resp.Owner = NewProfileResponse(room.Owner)
 	}
 
+	if room.DeletionScheduledAt != nil {
+		resp.DeletionScheduledAt = room.DeletionScheduledAt.Format(time.RFC3339)
+	}
+	if room.ArchivedAt != nil {
+		resp.ArchivedAt = room.ArchivedAt.Format(time.RFC3339)
+	}
+
 	return resp
 }
 
+// RoomDeletionResponse represents the deletion state of a room
+type RoomDeletionResponse struct {
+	RoomID              string `json:"room_id"`
+	DeletionScheduledAt string `json:"deletion_scheduled_at,omitempty"`
+}
+
+// NewRoomDeletionResponse creates a room deletion response from model
+func NewRoomDeletionResponse(room *model.Room) *RoomDeletionResponse {
+	resp := &RoomDeletionResponse{
+		RoomID: room.ID,
+	}
+
+	if room.DeletionScheduledAt != nil {
+		resp.DeletionScheduledAt = room.DeletionScheduledAt.Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+// RoomArchiveResponse represents the archive state of a room
+type RoomArchiveResponse struct {
+	RoomID     string `json:"room_id"`
+	Archived   bool   `json:"archived"`
+	ArchivedAt string `json:"archived_at,omitempty"`
+}
+
+// NewRoomArchiveResponse creates a room archive response from model
+func NewRoomArchiveResponse(room *model.Room) *RoomArchiveResponse {
+	resp := &RoomArchiveResponse{
+		RoomID:   room.ID,
+		Archived: room.IsArchived(),
+	}
+
+	if room.ArchivedAt != nil {
+		resp.ArchivedAt = room.ArchivedAt.Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+// RoomPermissionsResponse represents the actions a user may perform in a room
+type RoomPermissionsResponse struct {
+	RoomID      string                     `json:"room_id"`
+	Permissions map[string]bool            `json:"permissions,omitempty"`
+	Roles       map[string]map[string]bool `json:"roles,omitempty"`
+}
+
+// NewRoomPermissionsResponse creates a room permissions response
+func NewRoomPermissionsResponse(roomID string, perms map[policy.Action]bool) *RoomPermissionsResponse {
+	permissions := make(map[string]bool, len(perms))
+	for action, allowed := range perms {
+		permissions[string(action)] = allowed
+	}
+
+	return &RoomPermissionsResponse{
+		RoomID:      roomID,
+		Permissions: permissions,
+	}
+}
+
+// WithRoles adds the room's role permission matrix to the response
+func (r *RoomPermissionsResponse) WithRoles(matrix policy.Matrix) *RoomPermissionsResponse {
+	r.Roles = make(map[string]map[string]bool, len(matrix))
+	for role, grants := range matrix {
+		r.Roles[string(role)] = make(map[string]bool, len(grants))
+		for action, allowed := range grants {
+			r.Roles[string(role)][string(action)] = allowed
+		}
+	}
+	return r
+}
+
 // RoomMemberResponse represents a room member response
 type RoomMemberResponse struct {
 	ID          string `json:"id"`
@@ -118,6 +236,110 @@ func NewRoomMemberResponse(m *model.RoomMemberWithUser) *RoomMemberResponse {
 	}
 }
 
+// JoinRequestResponse represents a room join request response
+type JoinRequestResponse struct {
+	ID          string `json:"id"`
+	RoomID      string `json:"room_id"`
+	UserID      string `json:"user_id"`
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Status      string `json:"status"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// NewJoinRequestResponse creates a join request response from model
+func NewJoinRequestResponse(r *model.RoomJoinRequest) *JoinRequestResponse {
+	message := ""
+	if r.Message.Valid {
+		message = r.Message.String
+	}
+
+	return &JoinRequestResponse{
+		ID:        r.ID,
+		RoomID:    r.RoomID,
+		UserID:    r.UserID,
+		Message:   message,
+		Status:    string(r.Status),
+		CreatedAt: r.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// NewJoinRequestWithUserResponse creates a join request response including the requester
+func NewJoinRequestWithUserResponse(r *model.RoomJoinRequestWithUser) *JoinRequestResponse {
+	resp := NewJoinRequestResponse(&r.RoomJoinRequest)
+	resp.Username = r.Username
+	resp.DisplayName = r.Username
+	if r.DisplayName.Valid && r.DisplayName.String != "" {
+		resp.DisplayName = r.DisplayName.String
+	}
+	if r.AvatarURL.Valid {
+		resp.AvatarURL = r.AvatarURL.String
+	}
+	return resp
+}
+
+// RoomEventResponse represents a room event response
+type RoomEventResponse struct {
+	ID            string `json:"id"`
+	RoomID        string `json:"room_id"`
+	CreatedBy     string `json:"created_by,omitempty"`
+	Title         string `json:"title"`
+	Description   string `json:"description,omitempty"`
+	Location      string `json:"location,omitempty"`
+	StartsAt      string `json:"starts_at"`
+	EndsAt        string `json:"ends_at,omitempty"`
+	GoingCount    int    `json:"going_count"`
+	MaybeCount    int    `json:"maybe_count"`
+	DeclinedCount int    `json:"declined_count"`
+	MyRSVP        string `json:"my_rsvp,omitempty"` // the caller's answer, empty when unanswered
+	CreatedAt     string `json:"created_at"`
+}
+
+// NewRoomEventResponse creates a room event response from model
+func NewRoomEventResponse(e *model.RoomEventWithRSVPs) *RoomEventResponse {
+	resp := &RoomEventResponse{
+		ID:            e.ID,
+		RoomID:        e.RoomID,
+		CreatedBy:     e.CreatedBy.String,
+		Title:         e.Title,
+		Description:   e.Description.String,
+		Location:      e.Location.String,
+		StartsAt:      e.StartsAt.Format(time.RFC3339),
+		GoingCount:    e.GoingCount,
+		MaybeCount:    e.MaybeCount,
+		DeclinedCount: e.DeclinedCount,
+		MyRSVP:        e.MyRSVP.String,
+		CreatedAt:     e.CreatedAt.Format(time.RFC3339),
+	}
+	if e.EndsAt.Valid {
+		resp.EndsAt = e.EndsAt.Time.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// RoomMemberMuteResponse represents a member's mute state
+type RoomMemberMuteResponse struct {
+	RoomID     string `json:"room_id"`
+	UserID     string `json:"user_id"`
+	IsMuted    bool   `json:"is_muted"`
+	MutedUntil string `json:"muted_until,omitempty"`
+}
+
+// NewRoomMemberMuteResponse creates a mute response from model
+func NewRoomMemberMuteResponse(m *model.RoomMember) *RoomMemberMuteResponse {
+	resp := &RoomMemberMuteResponse{
+		RoomID:  m.RoomID,
+		UserID:  m.UserID,
+		IsMuted: m.IsMuted,
+	}
+	if m.MutedUntil != nil {
+		resp.MutedUntil = m.MutedUntil.Format(time.RFC3339)
+	}
+	return resp
+}
+
 // RoomListResponse represents a list of rooms
 type RoomListResponse struct {
 	Rooms      []*RoomResponse `json:"rooms"`
@@ -147,3 +369,27 @@ func NewRoomListResponse(rooms []*model.RoomWithMemberCount, total, page, limit
 		TotalPages: totalPages,
 	}
 }
+
+// RoomActivityHeatmapResponse represents a room's message counts by hour of
+// the week. Counts has a row per weekday starting on Sunday, each with 24
+// hourly counts in the requested time zone.
+type RoomActivityHeatmapResponse struct {
+	RoomID   string       `json:"room_id"`
+	Weeks    int          `json:"weeks"`
+	TimeZone string       `json:"timezone"`
+	Since    string       `json:"since"`
+	Total    int64        `json:"total"`
+	Counts   [7][24]int64 `json:"counts"`
+}
+
+// NewRoomActivityHeatmapResponse creates a heatmap response
+func NewRoomActivityHeatmapResponse(h *service.RoomActivityHeatmap) *RoomActivityHeatmapResponse {
+	return &RoomActivityHeatmapResponse{
+		RoomID:   h.RoomID,
+		Weeks:    h.Weeks,
+		TimeZone: h.TimeZone,
+		Since:    h.Since.UTC().Format(time.RFC3339),
+		Total:    h.Total,
+		Counts:   h.Counts,
+	}
+}
@@ -0,0 +1,121 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// WebhookResponse represents a room webhook. Secret is only set in the
+// response to its creation.
+type WebhookResponse struct {
+	ID        string   `json:"id"`
+	RoomID    string   `json:"room_id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Secret    string   `json:"secret,omitempty"`
+	CreatedBy string   `json:"created_by,omitempty"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// NewWebhookResponse creates a webhook response from model, leaving out the secret
+func NewWebhookResponse(w *model.RoomWebhook) *WebhookResponse {
+	return &WebhookResponse{
+		ID:        w.ID,
+		RoomID:    w.RoomID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedBy: w.CreatedBy.String,
+		CreatedAt: w.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// NewCreatedWebhookResponse creates a webhook response that includes the
+// signing secret
+func NewCreatedWebhookResponse(w *model.RoomWebhook) *WebhookResponse {
+	resp := NewWebhookResponse(w)
+	resp.Secret = w.Secret
+	return resp
+}
+
+// WebhookDeliveryResponse represents one delivery in a webhook's log
+type WebhookDeliveryResponse struct {
+	ID             string          `json:"id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  string          `json:"next_attempt_at,omitempty"` // pending deliveries only
+	LastStatusCode *int32          `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    string          `json:"delivered_at,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+}
+
+// NewWebhookDeliveryResponse creates a delivery response from model
+func NewWebhookDeliveryResponse(d *model.RoomWebhookDelivery) *WebhookDeliveryResponse {
+	resp := &WebhookDeliveryResponse{
+		ID:        d.ID,
+		Event:     d.Event,
+		Payload:   json.RawMessage(d.Payload),
+		Status:    string(d.Status),
+		Attempts:  d.Attempts,
+		LastError: d.LastError.String,
+		CreatedAt: d.CreatedAt.Format(time.RFC3339),
+	}
+	if d.Status == model.WebhookDeliveryPending {
+		resp.NextAttemptAt = d.NextAttemptAt.Format(time.RFC3339)
+	}
+	if d.LastStatusCode.Valid {
+		code := d.LastStatusCode.Int32
+		resp.LastStatusCode = &code
+	}
+	if d.DeliveredAt != nil {
+		resp.DeliveredAt = d.DeliveredAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// IncomingWebhookResponse represents an incoming webhook and its bot. Token
+// and URL are only set in the response to its creation.
+type IncomingWebhookResponse struct {
+	ID          string `json:"id"`
+	RoomID      string `json:"room_id"`
+	BotUserID   string `json:"bot_user_id"`
+	BotUsername string `json:"bot_username"`
+	Name        string `json:"name"`
+	AvatarURL   string `json:"avatar_url"`
+	Token       string `json:"token,omitempty"`
+	URL         string `json:"url,omitempty"` // path to post messages to
+	CreatedBy   string `json:"created_by,omitempty"`
+	LastUsedAt  string `json:"last_used_at,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// NewIncomingWebhookResponse creates an incoming webhook response from model
+func NewIncomingWebhookResponse(w *model.IncomingWebhookWithBot) *IncomingWebhookResponse {
+	resp := &IncomingWebhookResponse{
+		ID:          w.ID,
+		RoomID:      w.RoomID,
+		BotUserID:   w.BotUserID,
+		BotUsername: w.BotUsername,
+		Name:        w.GetBotDisplayName(),
+		AvatarURL:   w.BotAvatarURL.String,
+		CreatedBy:   w.CreatedBy.String,
+		CreatedAt:   w.CreatedAt.Format(time.RFC3339),
+	}
+	if w.LastUsedAt.Valid {
+		resp.LastUsedAt = w.LastUsedAt.Time.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// NewCreatedIncomingWebhookResponse creates an incoming webhook response
+// that includes the token and the path to post to
+func NewCreatedIncomingWebhookResponse(w *model.IncomingWebhookWithBot, token, path string) *IncomingWebhookResponse {
+	resp := NewIncomingWebhookResponse(w)
+	resp.Token = token
+	resp.URL = path
+	return resp
+}
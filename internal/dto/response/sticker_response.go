@@ -0,0 +1,57 @@
+package response
+
+import (
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// StickerResponse represents a sticker
+type StickerResponse struct {
+	ID       string `json:"id"`
+	PackID   string `json:"pack_id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+}
+
+// NewStickerResponse creates a sticker response from model
+func NewStickerResponse(s *model.Sticker) *StickerResponse {
+	return &StickerResponse{
+		ID:       s.ID,
+		PackID:   s.PackID,
+		Name:     s.Name,
+		ImageURL: s.ImageURL,
+		Width:    s.Width,
+		Height:   s.Height,
+	}
+}
+
+// StickerPackResponse represents a sticker pack with its stickers
+type StickerPackResponse struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	IsActive    bool               `json:"is_active"`
+	Stickers    []*StickerResponse `json:"stickers"`
+	CreatedAt   string             `json:"created_at"`
+	UpdatedAt   string             `json:"updated_at"`
+}
+
+// NewStickerPackResponse creates a sticker pack response from model
+func NewStickerPackResponse(p *model.StickerPack) *StickerPackResponse {
+	stickers := make([]*StickerResponse, 0, len(p.Stickers))
+	for _, s := range p.Stickers {
+		stickers = append(stickers, NewStickerResponse(s))
+	}
+	return &StickerPackResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		IsActive:    p.IsActive,
+		Stickers:    stickers,
+		CreatedAt:   p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   p.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,135 @@
+package response
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// UploadSessionResponse represents a resumable upload and its progress
+type UploadSessionResponse struct {
+	ID           string  `json:"id"`
+	Category     string  `json:"category"`
+	FileName     string  `json:"file_name"`
+	ContentType  string  `json:"content_type"`
+	TotalSize    int64   `json:"total_size"`
+	ReceivedSize int64   `json:"received_size"` // next chunk starts here
+	Progress     float64 `json:"progress"`      // 0 to 1
+	Completed    bool    `json:"completed"`
+	URL          string  `json:"url,omitempty"`         // set once completed
+	IsNSFW       bool    `json:"is_nsfw,omitempty"`     // set when the completed image should be blurred
+	UploadID     string  `json:"upload_id,omitempty"`   // set once a completed image or file can be attached to messages
+	ScanStatus   string  `json:"scan_status,omitempty"` // antivirus scan of the recorded upload, see AttachmentResponse
+	ExpiresAt    string  `json:"expires_at"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+}
+
+// NewUploadSessionResponse creates an upload session response from model;
+// url is the stored file's address, empty while the upload is in progress
+func NewUploadSessionResponse(s *model.UploadSession, url string) *UploadSessionResponse {
+	return &UploadSessionResponse{
+		ID:           s.ID,
+		Category:     string(s.Category),
+		FileName:     s.FileName,
+		ContentType:  s.ContentType,
+		TotalSize:    s.TotalSize,
+		ReceivedSize: s.ReceivedSize,
+		Progress:     s.Progress(),
+		Completed:    s.IsComplete(),
+		URL:          url,
+		ExpiresAt:    s.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:    s.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    s.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// UploadLimitOverrideResponse is an admin override of one category's
+// limits; missing fields use the configured value
+type UploadLimitOverrideResponse struct {
+	MaxSize      *int64   `json:"max_size,omitempty"`
+	AllowedTypes []string `json:"allowed_types,omitempty"`
+}
+
+// UploadOverridesResponse holds the overrides of every category
+type UploadOverridesResponse struct {
+	Image  UploadLimitOverrideResponse `json:"image"`
+	File   UploadLimitOverrideResponse `json:"file"`
+	Avatar UploadLimitOverrideResponse `json:"avatar"`
+}
+
+// UploadSettingsResponse represents the upload limits in effect and the
+// overrides admins saved
+type UploadSettingsResponse struct {
+	Limits    model.UploadLimits      `json:"limits"`
+	Overrides UploadOverridesResponse `json:"overrides"`
+	UpdatedBy string                  `json:"updated_by,omitempty"`
+	UpdatedAt string                  `json:"updated_at"`
+}
+
+// NewUploadSettingsResponse creates a settings response from the saved
+// overrides and the limits they result in
+func NewUploadSettingsResponse(s *model.UploadSettings, limits model.UploadLimits) *UploadSettingsResponse {
+	override := func(maxSize sql.NullInt64, allowedTypes []string) UploadLimitOverrideResponse {
+		o := UploadLimitOverrideResponse{AllowedTypes: allowedTypes}
+		if maxSize.Valid {
+			o.MaxSize = &maxSize.Int64
+		}
+		return o
+	}
+
+	return &UploadSettingsResponse{
+		Limits: limits,
+		Overrides: UploadOverridesResponse{
+			Image:  override(s.ImageMaxSize, s.ImageAllowedTypes),
+			File:   override(s.FileMaxSize, s.FileAllowedTypes),
+			Avatar: override(s.AvatarMaxSize, s.AvatarAllowedTypes),
+		},
+		UpdatedBy: s.UpdatedBy.String,
+		UpdatedAt: s.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// MetaResponse describes server limits clients should check before
+// sending anything
+type MetaResponse struct {
+	Uploads *model.UploadLimits `json:"uploads"`
+}
+
+// StorageUsageResponse represents the bytes a user stores against the quota
+type StorageUsageResponse struct {
+	Quota     int64                     `json:"quota"`     // bytes, 0 when unlimited
+	Used      int64                     `json:"used"`      // bytes
+	Remaining *int64                    `json:"remaining"` // bytes, null when unlimited
+	Breakdown *StorageBreakdownResponse `json:"breakdown"`
+}
+
+// StorageBreakdownResponse splits the used bytes by upload type
+type StorageBreakdownResponse struct {
+	Image   int64 `json:"image"`
+	File    int64 `json:"file"`
+	Pending int64 `json:"pending"` // resumable uploads in progress, counted at their declared size
+}
+
+// NewStorageUsageResponse creates a storage usage response; quota is zero
+// when unlimited
+func NewStorageUsageResponse(usage *model.StorageUsage, quota int64) *StorageUsageResponse {
+	resp := &StorageUsageResponse{
+		Quota: quota,
+		Used:  usage.Total(),
+		Breakdown: &StorageBreakdownResponse{
+			Image:   usage.Images,
+			File:    usage.Files,
+			Pending: usage.Pending,
+		},
+	}
+	if quota > 0 {
+		remaining := quota - resp.Used
+		if remaining < 0 {
+			remaining = 0
+		}
+		resp.Remaining = &remaining
+	}
+	return resp
+}
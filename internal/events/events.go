@@ -0,0 +1,92 @@
+// Package events publishes domain events to an external bus, so downstream
+// systems such as analytics or search indexing can follow what happens in
+// the chat without polling the database
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Type names a domain event; it is also the last part of the subject the
+// event is published on
+type Type string
+
+const (
+	MessageCreated   Type = "message.created"
+	RoomMemberJoined Type = "room.member.joined"
+	RoomMemberLeft   Type = "room.member.left"
+	UserRegistered   Type = "user.registered"
+)
+
+// DefaultPrefix starts every subject, e.g. chat.events.message.created
+const DefaultPrefix = "chat.events"
+
+// Event is the envelope every event is published in
+type Event struct {
+	ID         string          `json:"id"`
+	Type       Type            `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Source     string          `json:"source,omitempty"` // instance that published it
+	Data       json.RawMessage `json:"data"`
+}
+
+// MessageCreatedData is the data of message.created
+type MessageCreatedData struct {
+	MessageID string    `json:"message_id"`
+	RoomID    string    `json:"room_id"`
+	UserID    string    `json:"user_id"`
+	Type      string    `json:"type"`
+	Content   string    `json:"content"`
+	ReplyToID string    `json:"reply_to_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RoomMemberData is the data of room.member.joined and room.member.left
+type RoomMemberData struct {
+	RoomID  string `json:"room_id"`
+	UserID  string `json:"user_id"`
+	Reason  string `json:"reason"`
+	ActorID string `json:"actor_id,omitempty"` // who invited, approved or kicked the member
+}
+
+// UserRegisteredData is the data of user.registered
+type UserRegisteredData struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Bus delivers encoded events to a broker
+type Bus interface {
+	Publish(ctx context.Context, subject string, payload []byte) error
+	Close() error
+}
+
+// Driver selects the broker events are published to
+type Driver string
+
+const (
+	DriverNone  Driver = "none"
+	DriverRedis Driver = "redis" // one Redis stream per subject
+	DriverNATS  Driver = "nats"  // core NATS subjects
+)
+
+// ParseDriver validates an event bus driver name from configuration
+func ParseDriver(name string) (Driver, error) {
+	switch d := Driver(strings.ToLower(strings.TrimSpace(name))); d {
+	case "", DriverNone:
+		return DriverNone, nil
+	case DriverRedis, DriverNATS:
+		return d, nil
+	case "kafka":
+		// The Kafka wire protocol needs a client library this build does not
+		// ship; NATS and Redis streams both bridge to Kafka with stock connectors
+		return "", fmt.Errorf("kafka is not supported; use nats or redis and bridge to Kafka")
+	default:
+		return "", fmt.Errorf("unknown event bus driver %q (supported: none, redis, nats)", name)
+	}
+}
@@ -0,0 +1,220 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultNATSPort    = "4222"
+	defaultNATSTimeout = 5 * time.Second
+)
+
+// NATSBus publishes events on core NATS subjects. It speaks the text
+// protocol directly: it only ever publishes, so connecting, PUB and
+// answering the server's PINGs is all it needs. The connection is made on
+// first use and again after it breaks. TLS is not supported.
+type NATSBus struct {
+	addr    string
+	user    string
+	pass    string
+	token   string
+	name    string
+	timeout time.Duration
+	logger  *zap.Logger
+
+	mu   sync.Mutex
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// NewNATSBus creates a bus for a nats://[user:pass@|token@]host[:port] URL;
+// name identifies this client to the server
+func NewNATSBus(rawURL, name string, logger *zap.Logger) (*NATSBus, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %w", err)
+	}
+	if u.Scheme != "nats" {
+		return nil, fmt.Errorf("unsupported NATS URL scheme %q (supported: nats)", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("NATS URL has no host")
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = defaultNATSPort
+	}
+	b := &NATSBus{
+		addr:    net.JoinHostPort(u.Hostname(), port),
+		name:    name,
+		timeout: defaultNATSTimeout,
+		logger:  logger,
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			b.user, b.pass = u.User.Username(), pass
+		} else {
+			b.token = u.User.Username()
+		}
+	}
+	return b, nil
+}
+
+// Publish sends an event on subject, reconnecting once if the connection
+// turns out to be broken
+func (b *NATSBus) Publish(ctx context.Context, subject string, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	fresh := b.conn == nil
+	if err := b.publish(ctx, subject, payload); err != nil {
+		if fresh {
+			return err
+		}
+		return b.publish(ctx, subject, payload)
+	}
+	return nil
+}
+
+// publish writes one PUB; the caller holds mu
+func (b *NATSBus) publish(ctx context.Context, subject string, payload []byte) error {
+	if b.conn == nil {
+		if err := b.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(b.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = b.conn.SetWriteDeadline(deadline)
+
+	fmt.Fprintf(b.w, "PUB %s %d\r\n", subject, len(payload))
+	_, _ = b.w.Write(payload)
+	_, _ = b.w.WriteString("\r\n")
+	if err := b.w.Flush(); err != nil {
+		b.closeConn()
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+	return nil
+}
+
+// connect dials the server and completes the CONNECT handshake; the caller
+// holds mu
+func (b *NATSBus) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: b.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(b.timeout))
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to read NATS INFO: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting: %q", strings.TrimSpace(line))
+	}
+
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"lang":     "go",
+		"name":     b.name,
+	}
+	if b.user != "" {
+		options["user"], options["pass"] = b.user, b.pass
+	}
+	if b.token != "" {
+		options["auth_token"] = b.token
+	}
+	connect, _ := json.Marshal(options)
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", connect)
+	if err := w.Flush(); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send NATS CONNECT: %w", err)
+	}
+
+	// The server answers the PING only once it accepted the CONNECT
+	line, err = r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to read NATS handshake: %w", err)
+	}
+	if line = strings.TrimSpace(line); line != "PONG" {
+		conn.Close()
+		return fmt.Errorf("NATS server refused connection: %s", line)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	b.conn, b.w = conn, w
+	go b.readLoop(conn, r)
+	return nil
+}
+
+// readLoop answers the server's keepalive PINGs until conn breaks
+func (b *NATSBus) readLoop(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			b.mu.Lock()
+			if b.conn == conn {
+				b.closeConn()
+			}
+			b.mu.Unlock()
+			return
+		}
+
+		switch line = strings.TrimSpace(line); {
+		case line == "PING":
+			b.mu.Lock()
+			if b.conn == conn {
+				_ = conn.SetWriteDeadline(time.Now().Add(b.timeout))
+				_, _ = b.w.WriteString("PONG\r\n")
+				_ = b.w.Flush()
+			}
+			b.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			// The server closes the connection after reporting an error
+			b.logger.Warn("NATS server reported an error", zap.String("error", line))
+		}
+	}
+}
+
+// closeConn drops the current connection; the caller holds mu
+func (b *NATSBus) closeConn() {
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn, b.w = nil, nil
+	}
+}
+
+// Close flushes and closes the connection
+func (b *NATSBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		return nil
+	}
+	err := b.w.Flush()
+	b.closeConn()
+	return err
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/metrics"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultBufferSize is how many events may wait for the bus before new
+	// ones are dropped
+	DefaultBufferSize = 1024
+
+	// publishTimeout bounds handing one event to the bus
+	publishTimeout = 5 * time.Second
+)
+
+type queuedEvent struct {
+	subject string
+	event   *Event
+	payload []byte
+}
+
+// Publisher queues events and hands them to the bus from one background
+// goroutine, so a slow or unreachable broker never holds up a request.
+// Events are published in order, at most once: when the queue is full or
+// the bus fails, the event is dropped and counted. Consumers that need
+// every record reconcile with the database. Its methods are safe on a nil
+// Publisher, which publishes nothing.
+type Publisher struct {
+	bus    Bus
+	prefix string
+	source string
+	queue  chan *queuedEvent
+	done   chan struct{}
+	logger *zap.Logger
+
+	published *metrics.CounterVec
+	dropped   *metrics.CounterVec
+}
+
+// NewPublisher starts publishing to bus. Subjects are prefix followed by the
+// event type; source identifies this instance in the envelope. A
+// non-positive bufferSize uses DefaultBufferSize.
+func NewPublisher(bus Bus, prefix, source string, bufferSize int, logger *zap.Logger) *Publisher {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	p := &Publisher{
+		bus:    bus,
+		prefix: prefix,
+		source: source,
+		queue:  make(chan *queuedEvent, bufferSize),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+	go p.run()
+	return p
+}
+
+// SetMetrics registers the published and dropped event counters
+func (p *Publisher) SetMetrics(registry *metrics.Registry) {
+	p.published = registry.NewCounterVec("events_published_total", "Domain events handed to the event bus", "type")
+	p.dropped = registry.NewCounterVec("events_dropped_total", "Domain events dropped because the queue was full or the bus failed", "type")
+}
+
+// Publish queues an event whose data is encoded as JSON. It never blocks.
+func (p *Publisher) Publish(eventType Type, data interface{}) {
+	if p == nil {
+		return
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		p.logger.Error("Failed to encode event data", zap.String("type", string(eventType)), zap.Error(err))
+		return
+	}
+	event := &Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Source:     p.source,
+		Data:       raw,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		p.logger.Error("Failed to encode event", zap.String("type", string(eventType)), zap.Error(err))
+		return
+	}
+
+	select {
+	case p.queue <- &queuedEvent{subject: p.Subject(eventType), event: event, payload: payload}:
+	default:
+		p.drop(event, "queue full", nil)
+	}
+}
+
+// Subject returns the subject events of a type are published on
+func (p *Publisher) Subject(eventType Type) string {
+	return p.prefix + "." + string(eventType)
+}
+
+// Close publishes the events still queued and closes the bus. Events
+// published after Close are lost.
+func (p *Publisher) Close() {
+	if p == nil {
+		return
+	}
+	close(p.queue)
+	<-p.done
+
+	if err := p.bus.Close(); err != nil {
+		p.logger.Warn("Failed to close event bus", zap.Error(err))
+	}
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	for queued := range p.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		err := p.bus.Publish(ctx, queued.subject, queued.payload)
+		cancel()
+		if err != nil {
+			p.drop(queued.event, "bus failed", err)
+			continue
+		}
+		if p.published != nil {
+			p.published.WithLabelValues(string(queued.event.Type)).Inc()
+		}
+	}
+}
+
+func (p *Publisher) drop(event *Event, reason string, err error) {
+	p.logger.Warn("Dropped domain event",
+		zap.String("type", string(event.Type)),
+		zap.String("event_id", event.ID),
+		zap.String("reason", reason),
+		zap.Error(err),
+	)
+	if p.dropped != nil {
+		p.dropped.WithLabelValues(string(event.Type)).Inc()
+	}
+}
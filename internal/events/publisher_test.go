@@ -0,0 +1,138 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+type recordedEvent struct {
+	subject string
+	event   Event
+}
+
+type fakeBus struct {
+	mu     sync.Mutex
+	events []recordedEvent
+	err    error
+	block  chan struct{} // when set, Publish waits for it to close
+	closed bool
+}
+
+func (b *fakeBus) Publish(ctx context.Context, subject string, payload []byte) error {
+	if b.block != nil {
+		<-b.block
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+	b.events = append(b.events, recordedEvent{subject: subject, event: event})
+	return nil
+}
+
+func (b *fakeBus) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestParseDriver(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Driver
+		wantErr  bool
+	}{
+		{"", DriverNone, false},
+		{"none", DriverNone, false},
+		{"Redis", DriverRedis, false},
+		{"nats", DriverNATS, false},
+		{"kafka", "", true},
+		{"rabbitmq", "", true},
+	}
+
+	for _, tt := range tests {
+		driver, err := ParseDriver(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDriver(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if driver != tt.expected {
+			t.Errorf("ParseDriver(%q) = %q, want %q", tt.input, driver, tt.expected)
+		}
+	}
+}
+
+func TestPublisher_PublishesInOrder(t *testing.T) {
+	bus := &fakeBus{}
+	publisher := NewPublisher(bus, "", "instance-1", 0, zap.NewNop())
+
+	publisher.Publish(UserRegistered, &UserRegisteredData{UserID: "u1", Username: "alice"})
+	publisher.Publish(RoomMemberJoined, &RoomMemberData{RoomID: "r1", UserID: "u1", Reason: "join"})
+	publisher.Close()
+
+	if !bus.closed {
+		t.Error("Expected Close to close the bus")
+	}
+	if len(bus.events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(bus.events))
+	}
+
+	first := bus.events[0]
+	if first.subject != "chat.events.user.registered" || first.event.Type != UserRegistered {
+		t.Errorf("Expected user.registered first, got %s (%s)", first.subject, first.event.Type)
+	}
+	if first.event.ID == "" || first.event.Source != "instance-1" || first.event.OccurredAt.IsZero() {
+		t.Errorf("Expected a filled envelope, got %+v", first.event)
+	}
+	var data UserRegisteredData
+	if err := json.Unmarshal(first.event.Data, &data); err != nil || data.Username != "alice" {
+		t.Errorf("Expected the event data, got %s (%v)", first.event.Data, err)
+	}
+	if bus.events[1].subject != "chat.events.room.member.joined" {
+		t.Errorf("Expected room.member.joined second, got %s", bus.events[1].subject)
+	}
+}
+
+func TestPublisher_DropsWhenFull(t *testing.T) {
+	bus := &fakeBus{block: make(chan struct{})}
+	publisher := NewPublisher(bus, "test", "", 1, zap.NewNop())
+
+	// The worker holds one event while the bus blocks, the queue one more;
+	// the rest are dropped instead of blocking the caller
+	for i := 0; i < 10; i++ {
+		publisher.Publish(MessageCreated, &MessageCreatedData{MessageID: "m"})
+	}
+	close(bus.block)
+	publisher.Close()
+
+	if n := len(bus.events); n == 0 || n > 2 {
+		t.Errorf("Expected at most 2 events to get through, got %d", n)
+	}
+}
+
+func TestPublisher_BusFailure(t *testing.T) {
+	bus := &fakeBus{err: errors.New("broker down")}
+	publisher := NewPublisher(bus, "test", "", 0, zap.NewNop())
+
+	publisher.Publish(MessageCreated, &MessageCreatedData{MessageID: "m"})
+	publisher.Close()
+
+	if len(bus.events) != 0 {
+		t.Errorf("Expected no events, got %d", len(bus.events))
+	}
+}
+
+func TestPublisher_Nil(t *testing.T) {
+	var publisher *Publisher
+	publisher.Publish(MessageCreated, &MessageCreatedData{})
+	publisher.Close()
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultStreamMaxLen is roughly how many events a Redis stream keeps
+const DefaultStreamMaxLen = 100000
+
+// RedisBus appends events to one Redis stream per subject. Consumers read
+// them with consumer groups; the oldest entries are trimmed once a stream
+// holds about maxLen events.
+type RedisBus struct {
+	redis  *redis.Client
+	maxLen int64
+}
+
+// NewRedisBus creates a bus on redisClient; a non-positive maxLen uses
+// DefaultStreamMaxLen
+func NewRedisBus(redisClient *redis.Client, maxLen int64) *RedisBus {
+	if maxLen <= 0 {
+		maxLen = DefaultStreamMaxLen
+	}
+	return &RedisBus{redis: redisClient, maxLen: maxLen}
+}
+
+// Publish appends an event to the subject's stream
+func (b *RedisBus) Publish(ctx context.Context, subject string, payload []byte) error {
+	if err := b.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: subject,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": payload},
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to append event: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing; the Redis client is shared and closed by its owner
+func (b *RedisBus) Close() error {
+	return nil
+}
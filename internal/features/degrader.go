@@ -0,0 +1,194 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Probe checks a dependency, e.g. by pinging it
+type Probe func(ctx context.Context) error
+
+// ProbeResult is the outcome of the most recent probe of a dependency
+type ProbeResult struct {
+	Name    string        `json:"name"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+	Slow    bool          `json:"slow"`
+}
+
+const (
+	defaultTripAfter    = 3
+	defaultRecoverAfter = 6
+)
+
+// Degrader probes dependencies and degrades the non-critical flags while any
+// of them is slow or failing. To avoid flapping it trips only after
+// consecutive unhealthy checks and restores after a longer healthy streak.
+type Degrader struct {
+	flags        *Set
+	threshold    time.Duration
+	tripAfter    int
+	recoverAfter int
+	logger       *zap.Logger
+
+	mu        sync.Mutex
+	names     []string
+	probes    map[string]Probe
+	unhealthy int
+	healthy   int
+	degraded  bool
+	results   []ProbeResult
+}
+
+// NewDegrader creates a degrader that treats probes slower than threshold as
+// unhealthy
+func NewDegrader(flags *Set, threshold time.Duration, logger *zap.Logger) *Degrader {
+	return &Degrader{
+		flags:        flags,
+		threshold:    threshold,
+		tripAfter:    defaultTripAfter,
+		recoverAfter: defaultRecoverAfter,
+		probes:       make(map[string]Probe),
+		logger:       logger,
+	}
+}
+
+// SetHysteresis sets how many consecutive unhealthy checks trip degradation
+// and how many healthy checks restore the features
+func (d *Degrader) SetHysteresis(tripAfter, recoverAfter int) {
+	if tripAfter > 0 {
+		d.tripAfter = tripAfter
+	}
+	if recoverAfter > 0 {
+		d.recoverAfter = recoverAfter
+	}
+}
+
+// AddProbe registers a dependency probe
+func (d *Degrader) AddProbe(name string, probe Probe) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.probes[name]; !ok {
+		d.names = append(d.names, name)
+		sort.Strings(d.names)
+	}
+	d.probes[name] = probe
+}
+
+// Degraded reports whether non-critical features are currently shed
+func (d *Degrader) Degraded() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.degraded
+}
+
+// Results returns the most recent probe results
+func (d *Degrader) Results() []ProbeResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]ProbeResult(nil), d.results...)
+}
+
+// Status is a point-in-time view of the degradation state
+type Status struct {
+	Degraded bool          `json:"degraded"`
+	Flags    []State       `json:"flags"`
+	Probes   []ProbeResult `json:"probes"`
+}
+
+// Status returns the current flags and the most recent probe results
+func (d *Degrader) Status() *Status {
+	return &Status{
+		Degraded: d.Degraded(),
+		Flags:    d.flags.Snapshot(),
+		Probes:   d.Results(),
+	}
+}
+
+// Check probes every dependency once and updates the flags. It is meant to
+// run as a periodic background job.
+func (d *Degrader) Check(ctx context.Context) error {
+	d.mu.Lock()
+	names := append([]string(nil), d.names...)
+	probes := make([]Probe, len(names))
+	for i, name := range names {
+		probes[i] = d.probes[name]
+	}
+	d.mu.Unlock()
+
+	results := make([]ProbeResult, len(names))
+	var reason string
+	for i, probe := range probes {
+		results[i] = d.probe(ctx, names[i], probe)
+		if results[i].Slow && reason == "" {
+			reason = describe(results[i])
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.results = results
+	var trip, recover bool
+	if reason != "" {
+		d.healthy = 0
+		d.unhealthy++
+		trip = !d.degraded && d.unhealthy >= d.tripAfter
+	} else {
+		d.unhealthy = 0
+		d.healthy++
+		recover = d.degraded && d.healthy >= d.recoverAfter
+	}
+	if trip {
+		d.degraded = true
+	}
+	if recover {
+		d.degraded = false
+	}
+	d.mu.Unlock()
+
+	switch {
+	case trip:
+		d.logger.Warn("Dependencies degraded, shedding non-critical features", zap.String("reason", reason))
+		for _, f := range NonCritical {
+			d.flags.Degrade(f, reason)
+		}
+	case recover:
+		d.logger.Info("Dependencies recovered, restoring non-critical features")
+		for _, f := range NonCritical {
+			d.flags.Restore(f)
+		}
+	}
+	return nil
+}
+
+func (d *Degrader) probe(ctx context.Context, name string, probe Probe) ProbeResult {
+	// Give the probe room to report how slow it is instead of only timing out
+	probeCtx, cancel := context.WithTimeout(ctx, 4*d.threshold)
+	defer cancel()
+
+	start := time.Now()
+	err := probe(probeCtx)
+	result := ProbeResult{
+		Name:    name,
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Slow = err != nil || result.Latency > d.threshold
+	return result
+}
+
+func describe(r ProbeResult) string {
+	if r.Error != "" {
+		return fmt.Sprintf("%s probe failed: %s", r.Name, r.Error)
+	}
+	return fmt.Sprintf("%s latency %s", r.Name, r.Latency.Round(time.Millisecond))
+}
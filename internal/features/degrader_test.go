@@ -0,0 +1,81 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestDegrader_TripsAndRecovers(t *testing.T) {
+	flags := NewSet()
+	d := NewDegrader(flags, 20*time.Millisecond, zap.NewNop())
+	d.SetHysteresis(2, 3)
+
+	var failing bool
+	d.AddProbe("postgres", func(ctx context.Context) error {
+		if failing {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	ctx := context.Background()
+	failing = true
+	_ = d.Check(ctx)
+	if d.Degraded() || !flags.Enabled(FlagSearchIndexing) {
+		t.Fatal("Expected a single unhealthy check not to trip")
+	}
+	_ = d.Check(ctx)
+	if !d.Degraded() {
+		t.Fatal("Expected degrader to trip after consecutive unhealthy checks")
+	}
+	for _, f := range NonCritical {
+		if flags.Enabled(f) {
+			t.Errorf("Expected %s to be degraded", f)
+		}
+	}
+
+	failing = false
+	_ = d.Check(ctx)
+	_ = d.Check(ctx)
+	if !d.Degraded() {
+		t.Fatal("Expected degrader to wait for the recovery streak")
+	}
+	_ = d.Check(ctx)
+	if d.Degraded() {
+		t.Fatal("Expected degrader to recover")
+	}
+	for _, f := range NonCritical {
+		if !flags.Enabled(f) {
+			t.Errorf("Expected %s to be restored", f)
+		}
+	}
+}
+
+func TestDegrader_SlowProbeIsUnhealthy(t *testing.T) {
+	flags := NewSet()
+	d := NewDegrader(flags, 5*time.Millisecond, zap.NewNop())
+	d.SetHysteresis(1, 1)
+	d.AddProbe("redis", func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	_ = d.Check(context.Background())
+	if !d.Degraded() {
+		t.Fatal("Expected slow probe to trip degradation")
+	}
+
+	status := d.Status()
+	if len(status.Probes) != 1 || !status.Probes[0].Slow {
+		t.Errorf("Expected slow probe result, got %+v", status.Probes)
+	}
+	for _, st := range status.Flags {
+		if st.Reason == "" {
+			t.Errorf("Expected degradation reason for %s", st.Flag)
+		}
+	}
+}
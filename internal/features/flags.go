@@ -0,0 +1,174 @@
+package features
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Flag names a feature that can be switched off at runtime
+type Flag string
+
+const (
+	FlagLinkPreviews     Flag = "link_previews"
+	FlagSearchIndexing   Flag = "search_indexing"
+	FlagTypingBroadcasts Flag = "typing_broadcasts"
+)
+
+// NonCritical lists the hot-path features shed when dependencies degrade
+var NonCritical = []Flag{
+	FlagLinkPreviews,
+	FlagSearchIndexing,
+	FlagTypingBroadcasts,
+}
+
+// ParseFlag parses a flag name
+func ParseFlag(name string) (Flag, error) {
+	for _, f := range NonCritical {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown feature flag %q", name)
+}
+
+// State describes the effective state of a flag
+type State struct {
+	Flag       Flag       `json:"flag"`
+	Enabled    bool       `json:"enabled"`
+	Disabled   bool       `json:"disabled,omitempty"` // switched off by configuration
+	Reason     string     `json:"reason,omitempty"`   // why the flag is degraded
+	DegradedAt *time.Time `json:"degraded_at,omitempty"`
+}
+
+type degradation struct {
+	reason string
+	since  time.Time
+}
+
+// Set holds the runtime state of feature flags. A flag is enabled unless it
+// is disabled by configuration or currently degraded. A nil Set reports every
+// flag as enabled.
+type Set struct {
+	mu        sync.RWMutex
+	disabled  map[Flag]bool
+	degraded  map[Flag]degradation
+	listeners map[Flag][]func(enabled bool)
+}
+
+// NewSet creates a flag set with the given flags switched off
+func NewSet(disabled ...Flag) *Set {
+	s := &Set{
+		disabled:  make(map[Flag]bool),
+		degraded:  make(map[Flag]degradation),
+		listeners: make(map[Flag][]func(enabled bool)),
+	}
+	for _, f := range disabled {
+		s.disabled[f] = true
+	}
+	return s
+}
+
+// Enabled reports whether a feature should run
+func (s *Set) Enabled(f Flag) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabledLocked(f)
+}
+
+func (s *Set) enabledLocked(f Flag) bool {
+	if s.disabled[f] {
+		return false
+	}
+	_, degraded := s.degraded[f]
+	return !degraded
+}
+
+// OnChange registers fn to be called whenever the effective state of f
+// changes. Callbacks run synchronously on the goroutine changing the flag.
+func (s *Set) OnChange(f Flag, fn func(enabled bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners[f] = append(s.listeners[f], fn)
+}
+
+// Degrade temporarily switches off f. It returns true if f was newly degraded.
+func (s *Set) Degrade(f Flag, reason string) bool {
+	return s.update(f, func() bool {
+		if _, ok := s.degraded[f]; ok {
+			return false
+		}
+		s.degraded[f] = degradation{reason: reason, since: time.Now()}
+		return true
+	})
+}
+
+// Restore lifts a degradation of f. It returns true if f was degraded.
+func (s *Set) Restore(f Flag) bool {
+	return s.update(f, func() bool {
+		if _, ok := s.degraded[f]; !ok {
+			return false
+		}
+		delete(s.degraded, f)
+		return true
+	})
+}
+
+// update applies change under the lock and notifies listeners outside it if
+// the effective state of f flipped
+func (s *Set) update(f Flag, change func() bool) bool {
+	s.mu.Lock()
+	before := s.enabledLocked(f)
+	changed := change()
+	after := s.enabledLocked(f)
+	listeners := s.listeners[f]
+	s.mu.Unlock()
+
+	if before != after {
+		for _, fn := range listeners {
+			fn(after)
+		}
+	}
+	return changed
+}
+
+// Snapshot returns the state of every known flag, sorted by name
+func (s *Set) Snapshot() []State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[Flag]bool)
+	var flags []Flag
+	for _, group := range [][]Flag{NonCritical, keys(s.disabled), keys(s.degraded)} {
+		for _, f := range group {
+			if !seen[f] {
+				seen[f] = true
+				flags = append(flags, f)
+			}
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i] < flags[j] })
+
+	states := make([]State, len(flags))
+	for i, f := range flags {
+		states[i] = State{Flag: f, Enabled: s.enabledLocked(f), Disabled: s.disabled[f]}
+		if d, ok := s.degraded[f]; ok {
+			since := d.since
+			states[i].Reason = d.reason
+			states[i].DegradedAt = &since
+		}
+	}
+	return states
+}
+
+func keys[V any](m map[Flag]V) []Flag {
+	out := make([]Flag, 0, len(m))
+	for f := range m {
+		out = append(out, f)
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package features
+
+import "testing"
+
+func TestSet_DegradeAndRestore(t *testing.T) {
+	s := NewSet()
+	if !s.Enabled(FlagTypingBroadcasts) {
+		t.Fatal("Expected flags to be enabled by default")
+	}
+
+	var changes []bool
+	s.OnChange(FlagTypingBroadcasts, func(enabled bool) { changes = append(changes, enabled) })
+
+	if !s.Degrade(FlagTypingBroadcasts, "redis latency 900ms") {
+		t.Error("Expected first degrade to report a change")
+	}
+	if s.Degrade(FlagTypingBroadcasts, "again") {
+		t.Error("Expected repeated degrade to be a no-op")
+	}
+	if s.Enabled(FlagTypingBroadcasts) {
+		t.Error("Expected degraded flag to be disabled")
+	}
+
+	if !s.Restore(FlagTypingBroadcasts) {
+		t.Error("Expected restore to report a change")
+	}
+	if !s.Enabled(FlagTypingBroadcasts) {
+		t.Error("Expected restored flag to be enabled")
+	}
+
+	if len(changes) != 2 || changes[0] || !changes[1] {
+		t.Errorf("Expected [false true] change notifications, got %v", changes)
+	}
+}
+
+func TestSet_ConfigDisabledIgnoresRestore(t *testing.T) {
+	s := NewSet(FlagSearchIndexing)
+
+	notified := false
+	s.OnChange(FlagSearchIndexing, func(bool) { notified = true })
+
+	s.Degrade(FlagSearchIndexing, "postgres latency 1s")
+	s.Restore(FlagSearchIndexing)
+	if s.Enabled(FlagSearchIndexing) {
+		t.Error("Expected flag disabled by configuration to stay disabled")
+	}
+	if notified {
+		t.Error("Expected no notification when the effective state did not change")
+	}
+}
+
+func TestSet_NilIsEnabled(t *testing.T) {
+	var s *Set
+	if !s.Enabled(FlagLinkPreviews) {
+		t.Error("Expected nil set to enable every flag")
+	}
+}
+
+func TestSet_Snapshot(t *testing.T) {
+	s := NewSet(FlagLinkPreviews)
+	s.Degrade(FlagTypingBroadcasts, "slow")
+
+	states := s.Snapshot()
+	if len(states) != len(NonCritical) {
+		t.Fatalf("Expected %d states, got %d", len(NonCritical), len(states))
+	}
+	byFlag := make(map[Flag]State)
+	for _, st := range states {
+		byFlag[st.Flag] = st
+	}
+	if st := byFlag[FlagLinkPreviews]; st.Enabled || !st.Disabled {
+		t.Errorf("Unexpected link preview state: %+v", st)
+	}
+	if st := byFlag[FlagTypingBroadcasts]; st.Enabled || st.Reason != "slow" || st.DegradedAt == nil {
+		t.Errorf("Unexpected typing state: %+v", st)
+	}
+	if st := byFlag[FlagSearchIndexing]; !st.Enabled {
+		t.Errorf("Unexpected search indexing state: %+v", st)
+	}
+}
+
+func TestParseFlag(t *testing.T) {
+	if f, err := ParseFlag("search_indexing"); err != nil || f != FlagSearchIndexing {
+		t.Errorf("ParseFlag() = %q, %v", f, err)
+	}
+	if _, err := ParseFlag("unknown"); err == nil {
+		t.Error("Expected error for unknown flag")
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// DeleteAccount godoc
+// @Summary 刪除帳號
+// @Description 確認密碼後刪除當前帳號：個人資料匿名化，好友、封鎖、裝置與聊天室成員資格一併移除，聊天室訊息與私訊依保留政策保留或清除。仍擁有聊天室或受法律保全的帳號無法刪除
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.DeleteAccountRequest true "目前密碼"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/auth/me [delete]
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	var req request.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	if err := h.accountService.DeleteAccount(c.Request.Context(), middleware.GetUserID(c), req.Password); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "帳號已刪除", nil)
+}
+
+// ExportAccount godoc
+// @Summary 下載個人資料
+// @Description 以雜湊鏈 JSON Lines 格式下載當前用戶的個人資料、好友、封鎖、裝置、聊天室訊息與私訊
+// @Tags 認證
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 401 {object} response.Response
+// @Router /api/v1/auth/me/export [get]
+func (h *AccountHandler) ExportAccount(c *gin.Context) {
+	export, err := h.accountService.Export(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="`+export.Filename+`"`)
+	c.Status(http.StatusOK)
+
+	_, _ = export.WriteTo(c.Request.Context(), c.Writer)
+}
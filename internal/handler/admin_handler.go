@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/cluster"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/features"
+	"github.com/go-demo/chat/internal/jobs"
+	"github.com/go-demo/chat/internal/middleware"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/service"
+	"github.com/go-demo/chat/internal/system"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	checker  *system.Checker
+	degrader *features.Degrader
+	registry *cluster.Registry
+	limiters []*middleware.ConcurrencyLimiter
+	jobs     *jobs.Scheduler
+	jwtKeys  *service.JWTKeyService
+	logger   *zap.Logger
+}
+
+func NewAdminHandler(checker *system.Checker, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		checker: checker,
+		logger:  logger,
+	}
+}
+
+// SetDegrader sets the degrader whose state is reported by GetFeatures
+func (h *AdminHandler) SetDegrader(degrader *features.Degrader) {
+	h.degrader = degrader
+}
+
+// SetRegistry sets the instance registry reported by ListInstances
+func (h *AdminHandler) SetRegistry(registry *cluster.Registry) {
+	h.registry = registry
+}
+
+// SetConcurrencyLimiters sets the route concurrency limiters reported by
+// GetConcurrency
+func (h *AdminHandler) SetConcurrencyLimiters(limiters ...*middleware.ConcurrencyLimiter) {
+	h.limiters = limiters
+}
+
+// SetScheduler sets the background job scheduler reported by ListJobs
+func (h *AdminHandler) SetScheduler(scheduler *jobs.Scheduler) {
+	h.jobs = scheduler
+}
+
+// SetJWTKeyService sets the service RotateJWTKey rotates the signing key with
+func (h *AdminHandler) SetJWTKeyService(jwtKeys *service.JWTKeyService) {
+	h.jwtKeys = jwtKeys
+}
+
+// GetSystem godoc
+// @Summary 系統狀態報告
+// @Description 重新執行啟動自我檢查，回報資料庫結構版本、Redis 版本與相依套件版本（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=system.Report}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/system [get]
+func (h *AdminHandler) GetSystem(c *gin.Context) {
+	report := h.checker.Run(c.Request.Context())
+	response.Success(c, report)
+}
+
+// GetFeatures godoc
+// @Summary 功能旗標狀態
+// @Description 回報非關鍵功能的啟用狀態、降級原因與最近一次依賴服務探測結果（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=features.Status}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/features [get]
+func (h *AdminHandler) GetFeatures(c *gin.Context) {
+	if h.degrader == nil {
+		response.Error(c, apperrors.ErrNotFound)
+		return
+	}
+	response.Success(c, h.degrader.Status())
+}
+
+// GetConcurrency godoc
+// @Summary 併發限制狀態
+// @Description 回報本實例搜尋、匯出等高成本路由的每用戶併發上限、目前進行中與排隊的請求數，以及啟動以來放行、排隊、拒絕與逾時的次數（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]middleware.ConcurrencyStats}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/concurrency [get]
+func (h *AdminHandler) GetConcurrency(c *gin.Context) {
+	stats := make([]middleware.ConcurrencyStats, len(h.limiters))
+	for i, limiter := range h.limiters {
+		stats[i] = limiter.Stats()
+	}
+	response.Success(c, stats)
+}
+
+// ListJobs godoc
+// @Summary 背景工作狀態
+// @Description 列出本實例的背景工作，包含啟動以來的執行、失敗與處理項目數，以及最近的執行紀錄（執行 ID、狀態、耗時與錯誤）（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]jobs.JobStatus}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/jobs [get]
+func (h *AdminHandler) ListJobs(c *gin.Context) {
+	if h.jobs == nil {
+		response.Error(c, apperrors.ErrNotFound)
+		return
+	}
+	response.Success(c, h.jobs.Status())
+}
+
+// RunJob godoc
+// @Summary 立即執行背景工作
+// @Description 在本實例立即執行指定的背景工作（例如 room_purge、upload_gc），等待執行結束後回傳執行紀錄；若該工作正在執行，會等前一次結束後再執行（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "工作名稱"
+// @Success 200 {object} response.Response{data=jobs.Run}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/jobs/{name}/run [post]
+func (h *AdminHandler) RunJob(c *gin.Context) {
+	if h.jobs == nil {
+		response.Error(c, apperrors.ErrNotFound)
+		return
+	}
+
+	// The run finishes even if the caller gives up waiting for it
+	run, err := h.jobs.RunNow(context.WithoutCancel(c.Request.Context()), c.Param("name"))
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			response.Error(c, apperrors.New(http.StatusNotFound, "背景工作不存在"))
+			return
+		}
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, run)
+}
+
+// ListInstances godoc
+// @Summary 實例列表
+// @Description 列出已登記的伺服器實例，包含位址、連線數、健康與排空狀態（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]cluster.Instance}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/instances [get]
+func (h *AdminHandler) ListInstances(c *gin.Context) {
+	if h.registry == nil {
+		response.Error(c, apperrors.ErrNotFound)
+		return
+	}
+
+	instances, err := h.registry.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list instances", zap.Error(err))
+		response.Error(c, apperrors.ErrInternal)
+		return
+	}
+
+	response.Success(c, instances)
+}
+
+// DrainInstance godoc
+// @Summary 排空實例
+// @Description 要求指定實例開始排空，實例於下次心跳時生效（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "實例 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/instances/{id}/drain [post]
+func (h *AdminHandler) DrainInstance(c *gin.Context) {
+	h.setDrain(c, true, "已要求實例排空")
+}
+
+// UndrainInstance godoc
+// @Summary 取消排空實例
+// @Description 取消指定實例的排空要求（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "實例 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/instances/{id}/drain [delete]
+func (h *AdminHandler) UndrainInstance(c *gin.Context) {
+	h.setDrain(c, false, "已取消實例排空")
+}
+
+func (h *AdminHandler) setDrain(c *gin.Context, drain bool, message string) {
+	if h.registry == nil {
+		response.Error(c, apperrors.ErrNotFound)
+		return
+	}
+
+	instanceID := c.Param("id")
+	if err := h.registry.SetDrain(c.Request.Context(), instanceID, drain); err != nil {
+		if err == cluster.ErrInstanceNotFound {
+			response.Error(c, apperrors.ErrInstanceNotFound)
+			return
+		}
+		h.logger.Error("Failed to update instance drain",
+			zap.String("instance_id", instanceID),
+			zap.Error(err),
+		)
+		response.Error(c, apperrors.ErrInternal)
+		return
+	}
+
+	h.logger.Info("Instance drain updated",
+		zap.String("instance_id", instanceID),
+		zap.Bool("drain", drain),
+		zap.String("admin_id", middleware.GetUserID(c)),
+	)
+	response.SuccessWithMessage(c, message, nil)
+}
+
+// RotateJWTKey godoc
+// @Summary 輪替 JWT 簽章金鑰
+// @Description 產生新的 JWT 簽章金鑰，於 activates_at 後開始簽發 Token；先前的金鑰在被取代後仍可驗證既有 Token 一個 Refresh Token 效期（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} response.Response{data=response.JWTKeyResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/admin/jwt/rotate [post]
+func (h *AdminHandler) RotateJWTKey(c *gin.Context) {
+	if h.jwtKeys == nil {
+		response.Error(c, apperrors.ErrNotFound)
+		return
+	}
+
+	key, err := h.jwtKeys.Rotate(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewJWTKeyResponse(key))
+}
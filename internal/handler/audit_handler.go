@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditLogs godoc
+// @Summary 稽核紀錄
+// @Description 列出敏感操作的稽核紀錄，可依操作者、動作與時間範圍篩選（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query string false "操作者 ID"
+// @Param action query string false "動作，例如 member.kicked、user.banned"
+// @Param since query string false "起始時間（RFC3339，含）"
+// @Param until query string false "結束時間（RFC3339，不含）"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.AuditLogResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/audit-logs [get]
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	var query request.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "無效的篩選條件")
+		return
+	}
+
+	filter := &repository.AuditFilter{
+		ActorID: query.ActorID,
+		Action:  model.AuditAction(query.Action),
+	}
+
+	var err error
+	if filter.Since, err = parseOptionalTime(query.Since); err != nil {
+		response.BadRequest(c, "無效的起始時間")
+		return
+	}
+	if filter.Until, err = parseOptionalTime(query.Until); err != nil {
+		response.BadRequest(c, "無效的結束時間")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	entries, err := h.auditService.List(c.Request.Context(), filter, req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	entries, hasMore := pagination.Trim(entries, req.Limit)
+
+	entryResponses := make([]*response.AuditLogResponse, len(entries))
+	for i, e := range entries {
+		entryResponses[i] = response.NewAuditLogResponse(e)
+	}
+
+	response.SuccessWithMeta(c, entryResponses, response.NewMeta(req.Limit, req.Offset(), len(entryResponses), hasMore))
+}
+
+// parseOptionalTime parses an RFC3339 timestamp, returning the zero time for ""
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
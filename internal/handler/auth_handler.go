@@ -5,12 +5,14 @@ import (
 	"github.com/go-demo/chat/internal/dto/request"
 	"github.com/go-demo/chat/internal/dto/response"
 	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
 	"github.com/go-demo/chat/internal/pkg/utils"
 	"github.com/go-demo/chat/internal/service"
 )
 
 type AuthHandler struct {
 	authService *service.AuthService
+	ssoService  *service.SSOService
 }
 
 func NewAuthHandler(authService *service.AuthService) *AuthHandler {
@@ -19,6 +21,29 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 	}
 }
 
+// SetSSO routes sign-in through enterprise single sign-on. Registration is
+// closed while it is set, since accounts are provisioned on first login.
+func (h *AuthHandler) SetSSO(ssoService *service.SSOService) {
+	h.ssoService = ssoService
+}
+
+func newTokenResponse(pair *utils.TokenPair) *response.TokenResponse {
+	return &response.TokenResponse{
+		AccessToken:  pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresAt:    pair.ExpiresAt,
+		TokenType:    "Bearer",
+		DeviceID:     pair.DeviceID,
+	}
+}
+
+func clientInfo(c *gin.Context) *service.ClientInfo {
+	return &service.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
 // Register godoc
 // @Summary 用戶註冊
 // @Description 創建新用戶帳號
@@ -28,9 +53,15 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 // @Param request body request.RegisterRequest true "註冊資料"
 // @Success 201 {object} response.Response{data=response.AuthResponse}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 409 {object} response.Response
 // @Router /api/v1/auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
+	if h.ssoService != nil {
+		response.Error(c, service.ErrSSORegistration)
+		return
+	}
+
 	var req request.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, "請求格式錯誤")
@@ -49,9 +80,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	result, err := h.authService.Register(c.Request.Context(), &service.RegisterInput{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: req.Password,
+		Username:   req.Username,
+		Email:      req.Email,
+		Password:   req.Password,
+		DeviceName: req.DeviceName,
+		Client:     clientInfo(c),
 	})
 	if err != nil {
 		response.Error(c, err)
@@ -59,13 +92,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	response.Created(c, &response.AuthResponse{
-		User: response.NewUserResponse(result.User, true),
-		Token: &response.TokenResponse{
-			AccessToken:  result.TokenPair.AccessToken,
-			RefreshToken: result.TokenPair.RefreshToken,
-			ExpiresAt:    result.TokenPair.ExpiresAt,
-			TokenType:    "Bearer",
-		},
+		User:  response.NewUserResponse(result.User, true),
+		Token: newTokenResponse(result.TokenPair),
 	})
 }
 
@@ -79,6 +107,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success 200 {object} response.Response{data=response.AuthResponse}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 503 {object} response.Response
 // @Router /api/v1/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req request.LoginRequest
@@ -87,29 +117,34 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	result, err := h.authService.Login(c.Request.Context(), &service.LoginInput{
-		Username: req.Username,
-		Password: req.Password,
-	})
+	input := &service.LoginInput{
+		Username:   req.Username,
+		Password:   req.Password,
+		DeviceID:   req.DeviceID,
+		DeviceName: req.DeviceName,
+		Client:     clientInfo(c),
+	}
+	var result *service.LoginResult
+	var err error
+	if h.ssoService != nil {
+		result, err = h.ssoService.Login(c.Request.Context(), input)
+	} else {
+		result, err = h.authService.Login(c.Request.Context(), input)
+	}
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
 	response.Success(c, &response.AuthResponse{
-		User: response.NewUserResponse(result.User, true),
-		Token: &response.TokenResponse{
-			AccessToken:  result.TokenPair.AccessToken,
-			RefreshToken: result.TokenPair.RefreshToken,
-			ExpiresAt:    result.TokenPair.ExpiresAt,
-			TokenType:    "Bearer",
-		},
+		User:  response.NewUserResponse(result.User, true),
+		Token: newTokenResponse(result.TokenPair),
 	})
 }
 
 // Logout godoc
 // @Summary 用戶登出
-// @Description 用戶登出
+// @Description 用戶登出，並撤銷目前裝置的 Refresh Token
 // @Tags 認證
 // @Accept json
 // @Produce json
@@ -119,8 +154,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Router /api/v1/auth/logout [post]
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID := middleware.GetUserID(c)
+	deviceID := ""
+	if claims := middleware.GetClaims(c); claims != nil {
+		deviceID = claims.DeviceID
+	}
 
-	if err := h.authService.Logout(c.Request.Context(), userID); err != nil {
+	if err := h.authService.Logout(c.Request.Context(), userID, deviceID); err != nil {
 		response.Error(c, err)
 		return
 	}
@@ -146,18 +185,74 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	tokenPair, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	tokenPair, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken, clientInfo(c))
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
-	response.Success(c, &response.TokenResponse{
-		AccessToken:  tokenPair.AccessToken,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    tokenPair.ExpiresAt,
-		TokenType:    "Bearer",
-	})
+	response.Success(c, newTokenResponse(tokenPair))
+}
+
+// ListDevices godoc
+// @Summary 獲取登入裝置
+// @Description 獲取當前用戶的登入裝置及各裝置的 Refresh Token 簽發紀錄
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]response.DeviceResponse}
+// @Failure 401 {object} response.Response
+// @Router /api/v1/auth/devices [get]
+func (h *AuthHandler) ListDevices(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	currentDeviceID := ""
+	if claims := middleware.GetClaims(c); claims != nil {
+		currentDeviceID = claims.DeviceID
+	}
+
+	sessions, err := h.authService.ListDevices(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	resp := make([]*response.DeviceResponse, len(sessions))
+	for i, s := range sessions {
+		resp[i] = response.NewDeviceResponse(s.Device, s.Tokens, s.Device.ID == currentDeviceID)
+	}
+
+	response.Success(c, resp)
+}
+
+// RevokeDevice godoc
+// @Summary 撤銷登入裝置
+// @Description 撤銷指定裝置，使其 Refresh Token 全部失效並中斷其 WebSocket 連線，不影響其他裝置
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "裝置 ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/auth/devices/{id} [delete]
+func (h *AuthHandler) RevokeDevice(c *gin.Context) {
+	deviceID := c.Param("id")
+	if !utils.ValidateUUID(deviceID) {
+		response.BadRequest(c, "無效的裝置 ID")
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+
+	if err := h.authService.RevokeDevice(c.Request.Context(), userID, deviceID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "裝置已撤銷", nil)
 }
 
 // ChangePassword godoc
@@ -250,6 +345,26 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 			return
 		}
 	}
+	if req.Discoverable != nil {
+		if err := h.authService.SetDiscoverable(c.Request.Context(), userID, *req.Discoverable); err != nil {
+			response.Error(c, err)
+			return
+		}
+	}
+	if req.LastSeenVisibility != nil {
+		visibility := model.LastSeenVisibility(*req.LastSeenVisibility)
+		if err := h.authService.SetLastSeenVisibility(c.Request.Context(), userID, visibility); err != nil {
+			response.Error(c, err)
+			return
+		}
+	}
+	if req.DigestFrequency != nil {
+		frequency := model.DigestFrequency(*req.DigestFrequency)
+		if err := h.authService.SetDigestFrequency(c.Request.Context(), userID, frequency); err != nil {
+			response.Error(c, err)
+			return
+		}
+	}
 
 	// Reload user
 	user, err := h.authService.GetUserByID(c.Request.Context(), userID)
@@ -260,3 +375,64 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 
 	response.Success(c, response.NewUserResponse(user, true))
 }
+
+// ListSessions godoc
+// @Summary 獲取登入工作階段
+// @Description 獲取當前用戶仍有效的登入工作階段，包含裝置、IP 與最後使用時間
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]response.SessionResponse}
+// @Failure 401 {object} response.Response
+// @Router /api/v1/auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	currentDeviceID := ""
+	if claims := middleware.GetClaims(c); claims != nil {
+		currentDeviceID = claims.DeviceID
+	}
+
+	devices, err := h.authService.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	resp := make([]*response.SessionResponse, len(devices))
+	for i, d := range devices {
+		resp[i] = response.NewSessionResponse(d, d.ID == currentDeviceID)
+	}
+
+	response.Success(c, resp)
+}
+
+// RevokeSession godoc
+// @Summary 撤銷登入工作階段
+// @Description 撤銷指定工作階段，該裝置的 WebSocket 連線會被中斷並需重新登入，不影響其他裝置
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "工作階段 ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	sessionID := c.Param("id")
+	if !utils.ValidateUUID(sessionID) {
+		response.BadRequest(c, "無效的工作階段 ID")
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+
+	if err := h.authService.RevokeDevice(c.Request.Context(), userID, sessionID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "工作階段已撤銷", nil)
+}
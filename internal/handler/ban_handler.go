@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type BanHandler struct {
+	banService *service.BanService
+}
+
+func NewBanHandler(banService *service.BanService) *BanHandler {
+	return &BanHandler{
+		banService: banService,
+	}
+}
+
+// BanUser godoc
+// @Summary 停權用戶
+// @Description 封禁或暫時停權用戶，並中斷其 WebSocket 連線（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用戶 ID"
+// @Param request body request.BanUserRequest true "停權資訊"
+// @Success 200 {object} response.Response{data=response.BanResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/users/{id}/ban [post]
+func (h *BanHandler) BanUser(c *gin.Context) {
+	userID := c.Param("id")
+	if !utils.ValidateUUID(userID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	var req request.BanUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			response.BadRequest(c, "無效的停權期間")
+			return
+		}
+		duration = d
+	}
+
+	ban, err := h.banService.Ban(c.Request.Context(), &service.BanInput{
+		UserID:   userID,
+		BannedBy: middleware.GetUserID(c),
+		Reason:   req.Reason,
+		Duration: duration,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "用戶已停權", response.NewBanResponse(ban))
+}
+
+// UnbanUser godoc
+// @Summary 解除停權
+// @Description 解除用戶目前的停權（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用戶 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/users/{id}/ban [delete]
+func (h *BanHandler) UnbanUser(c *gin.Context) {
+	userID := c.Param("id")
+	if !utils.ValidateUUID(userID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	if err := h.banService.Unban(c.Request.Context(), userID, middleware.GetUserID(c)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已解除停權", nil)
+}
+
+// ListUserBans godoc
+// @Summary 用戶停權紀錄
+// @Description 列出用戶的停權歷史（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用戶 ID"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.BanResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/users/{id}/bans [get]
+func (h *BanHandler) ListUserBans(c *gin.Context) {
+	userID := c.Param("id")
+	if !utils.ValidateUUID(userID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	bans, err := h.banService.ListByUserID(c.Request.Context(), userID, req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	bans, hasMore := pagination.Trim(bans, req.Limit)
+
+	banResponses := make([]*response.BanResponse, len(bans))
+	for i, b := range bans {
+		banResponses[i] = response.NewBanResponse(b)
+	}
+
+	response.SuccessWithMeta(c, banResponses, response.NewMeta(req.Limit, req.Offset(), len(banResponses), hasMore))
+}
+
+// ListActiveBans godoc
+// @Summary 停權中用戶列表
+// @Description 列出目前生效中的封禁與停權（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.BanResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/bans [get]
+func (h *BanHandler) ListActiveBans(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	bans, err := h.banService.ListActive(c.Request.Context(), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	bans, hasMore := pagination.Trim(bans, req.Limit)
+
+	banResponses := make([]*response.BanResponse, len(bans))
+	for i, b := range bans {
+		banResponses[i] = response.NewBanResponse(b)
+	}
+
+	response.SuccessWithMeta(c, banResponses, response.NewMeta(req.Limit, req.Offset(), len(banResponses), hasMore))
+}
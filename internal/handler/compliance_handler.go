@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type ComplianceHandler struct {
+	complianceService *service.ComplianceService
+}
+
+func NewComplianceHandler(complianceService *service.ComplianceService) *ComplianceHandler {
+	return &ComplianceHandler{
+		complianceService: complianceService,
+	}
+}
+
+// PlaceLegalHold godoc
+// @Summary 設定法律保全
+// @Description 將聊天室或用戶設為法律保全，保全期間暫停清除其資料（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.PlaceLegalHoldRequest true "保全資訊"
+// @Success 201 {object} response.Response{data=response.LegalHoldResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/admin/legal-holds [post]
+func (h *ComplianceHandler) PlaceLegalHold(c *gin.Context) {
+	var req request.PlaceLegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	hold, err := h.complianceService.PlaceHold(c.Request.Context(), &service.PlaceHoldInput{
+		TargetType: model.LegalHoldTarget(req.TargetType),
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+		PlacedBy:   middleware.GetUserID(c),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewLegalHoldResponse(hold))
+}
+
+// ReleaseLegalHold godoc
+// @Summary 解除法律保全
+// @Description 解除法律保全，恢復一般資料保留規則（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "保全 ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/legal-holds/{id} [delete]
+func (h *ComplianceHandler) ReleaseLegalHold(c *gin.Context) {
+	holdID := c.Param("id")
+	if !utils.ValidateUUID(holdID) {
+		response.BadRequest(c, "無效的保全 ID")
+		return
+	}
+
+	if err := h.complianceService.ReleaseHold(c.Request.Context(), holdID, middleware.GetUserID(c)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已解除法律保全", nil)
+}
+
+// ListLegalHolds godoc
+// @Summary 法律保全列表
+// @Description 列出保全中的聊天室與用戶（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.LegalHoldResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/legal-holds [get]
+func (h *ComplianceHandler) ListLegalHolds(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	holds, err := h.complianceService.ListActiveHolds(c.Request.Context(), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	holds, hasMore := pagination.Trim(holds, req.Limit)
+
+	holdResponses := make([]*response.LegalHoldResponse, len(holds))
+	for i, hold := range holds {
+		holdResponses[i] = response.NewLegalHoldResponse(hold)
+	}
+
+	response.SuccessWithMeta(c, holdResponses, response.NewMeta(req.Limit, req.Offset(), len(holdResponses), hasMore))
+}
+
+// ExportRoom godoc
+// @Summary 匯出聊天室訊息
+// @Description 以雜湊鏈 JSON Lines 格式匯出聊天室所有訊息（含已刪除），供法遵調閱（僅管理員）
+// @Tags 管理
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Success 200 {file} file
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/rooms/{id}/export [get]
+func (h *ComplianceHandler) ExportRoom(c *gin.Context) {
+	roomID := c.Param("id")
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	export, err := h.complianceService.RoomExport(c.Request.Context(), roomID, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	h.stream(c, export)
+}
+
+// ExportUser godoc
+// @Summary 匯出用戶訊息
+// @Description 以雜湊鏈 JSON Lines 格式匯出用戶的聊天室訊息與私訊（含已刪除），供法遵調閱（僅管理員）
+// @Tags 管理
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param id path string true "用戶 ID"
+// @Success 200 {file} file
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/users/{id}/export [get]
+func (h *ComplianceHandler) ExportUser(c *gin.Context) {
+	userID := c.Param("id")
+	if !utils.ValidateUUID(userID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	export, err := h.complianceService.UserExport(c.Request.Context(), userID, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	h.stream(c, export)
+}
+
+// stream writes an export as a download. Once streaming has started the
+// status can no longer change, so a failed export shows up as an archive
+// without a trailer, which fails verification.
+func (h *ComplianceHandler) stream(c *gin.Context, export *service.Export) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="`+export.Filename+`"`)
+	c.Status(http.StatusOK)
+
+	_, _ = export.WriteTo(c.Request.Context(), c.Writer)
+}
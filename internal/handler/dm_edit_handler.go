@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/utils"
+)
+
+// UpdateDirectMessage godoc
+// @Summary 編輯私訊
+// @Description 編輯自己送出的文字私訊，需在送出後的可編輯時間內；雙方會收到 dm_updated 事件
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Param message_id path string true "訊息 ID"
+// @Param request body request.UpdateMessageRequest true "更新內容"
+// @Success 200 {object} response.Response{data=response.DirectMessageResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/v1/dm/{user_id}/messages/{message_id} [put]
+func (h *MessageHandler) UpdateDirectMessage(c *gin.Context) {
+	otherUserID := c.Param("user_id")
+	messageID := c.Param("message_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(otherUserID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+	if !utils.ValidateUUID(messageID) {
+		response.BadRequest(c, "無效的訊息 ID")
+		return
+	}
+
+	var req request.UpdateMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	v := utils.NewValidator()
+	v.ValidateMessageContent("content", req.Content)
+	if v.HasErrors() {
+		response.ValidationError(c, v.Errors())
+		return
+	}
+
+	msg, err := h.dmService.EditMessage(c.Request.Context(), userID, otherUserID, messageID, req.Content)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewDirectMessageResponse(msg))
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// RequestConversationExport godoc
+// @Summary 申請匯出私訊對話
+// @Description 以背景工作匯出自己這一方的私訊對話（JSON，含附件清單），不含自己已刪除的訊息。完成後會收到通知，檔案保留 7 天
+// @Tags 私訊
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Success 201 {object} response.Response{data=response.DMExportResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/dm/{user_id}/export [post]
+func (h *MessageHandler) RequestConversationExport(c *gin.Context) {
+	peerID := c.Param("user_id")
+	if !utils.ValidateUUID(peerID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	export, err := h.dmService.RequestExport(c.Request.Context(), middleware.GetUserID(c), peerID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewDMExportResponse(export))
+}
+
+// GetConversationExport godoc
+// @Summary 查詢私訊對話匯出
+// @Description 查詢私訊對話匯出的處理狀態
+// @Tags 私訊
+// @Produce json
+// @Security BearerAuth
+// @Param export_id path string true "匯出 ID"
+// @Success 200 {object} response.Response{data=response.DMExportResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/exports/{export_id} [get]
+func (h *MessageHandler) GetConversationExport(c *gin.Context) {
+	exportID := c.Param("export_id")
+	if !utils.ValidateUUID(exportID) {
+		response.BadRequest(c, "無效的匯出 ID")
+		return
+	}
+
+	export, err := h.dmService.GetExport(c.Request.Context(), exportID, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewDMExportResponse(export))
+}
+
+// DownloadConversationExport godoc
+// @Summary 下載私訊對話匯出
+// @Description 下載已完成的私訊對話匯出檔
+// @Tags 私訊
+// @Produce json
+// @Security BearerAuth
+// @Param export_id path string true "匯出 ID"
+// @Success 200 {file} file
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/dm/exports/{export_id}/download [get]
+func (h *MessageHandler) DownloadConversationExport(c *gin.Context) {
+	exportID := c.Param("export_id")
+	if !utils.ValidateUUID(exportID) {
+		response.BadRequest(c, "無效的匯出 ID")
+		return
+	}
+
+	export, f, err := h.dmService.OpenExport(c.Request.Context(), exportID, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	defer f.Close()
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+service.DMExportFilename(export)+`"`)
+	c.Status(http.StatusOK)
+	_, _ = io.Copy(c.Writer, f)
+}
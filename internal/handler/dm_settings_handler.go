@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/utils"
+)
+
+// GetConversationSettings godoc
+// @Summary 獲取私訊設定
+// @Description 獲取與指定用戶私訊對話的共用設定，例如閱後即焚的訊息存活時間
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Success 200 {object} response.Response{data=response.ConversationSettingsResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/settings [get]
+func (h *MessageHandler) GetConversationSettings(c *gin.Context) {
+	otherUserID := c.Param("user_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(otherUserID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	settings, err := h.dmService.GetConversationSettings(c.Request.Context(), userID, otherUserID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewConversationSettingsResponse(otherUserID, settings))
+}
+
+// UpdateConversationSettings godoc
+// @Summary 更新私訊設定
+// @Description 設定與指定用戶私訊的閱後即焚時間（秒，0 為關閉），雙方共用，僅影響之後送出的訊息
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Param request body request.UpdateConversationSettingsRequest true "私訊設定"
+// @Success 200 {object} response.Response{data=response.ConversationSettingsResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/settings [put]
+func (h *MessageHandler) UpdateConversationSettings(c *gin.Context) {
+	otherUserID := c.Param("user_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(otherUserID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	var req request.UpdateConversationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	settings, err := h.dmService.SetMessageTTL(c.Request.Context(), userID, otherUserID, *req.MessageTTL)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已更新私訊設定", response.NewConversationSettingsResponse(otherUserID, settings))
+}
+
+// ArchiveConversation godoc
+// @Summary 封存私訊對話
+// @Description 將與指定用戶的對話移至封存列表，只影響自己的對話列表，直到取消封存
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Success 200 {object} response.Response{data=response.ConversationStateResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/archive [post]
+func (h *MessageHandler) ArchiveConversation(c *gin.Context) {
+	h.setConversationState(c, model.ConversationArchived, true)
+}
+
+// UnarchiveConversation godoc
+// @Summary 取消封存私訊對話
+// @Description 將與指定用戶的對話移回一般對話列表
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Success 200 {object} response.Response{data=response.ConversationStateResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/archive [delete]
+func (h *MessageHandler) UnarchiveConversation(c *gin.Context) {
+	h.setConversationState(c, model.ConversationArchived, false)
+}
+
+// HideConversation godoc
+// @Summary 隱藏私訊對話
+// @Description 從對話列表隱藏與指定用戶的對話，對方傳來新訊息時自動重新顯示
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Success 200 {object} response.Response{data=response.ConversationStateResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/hide [post]
+func (h *MessageHandler) HideConversation(c *gin.Context) {
+	h.setConversationState(c, model.ConversationHidden, true)
+}
+
+// UnhideConversation godoc
+// @Summary 取消隱藏私訊對話
+// @Description 讓與指定用戶的對話重新出現在對話列表
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Success 200 {object} response.Response{data=response.ConversationStateResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/hide [delete]
+func (h *MessageHandler) UnhideConversation(c *gin.Context) {
+	h.setConversationState(c, model.ConversationHidden, false)
+}
+
+// PinConversation godoc
+// @Summary 釘選私訊對話
+// @Description 將與指定用戶的對話釘選在對話列表最前面，較晚釘選的排在前面
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Success 200 {object} response.Response{data=response.ConversationStateResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/pin [post]
+func (h *MessageHandler) PinConversation(c *gin.Context) {
+	h.setConversationState(c, model.ConversationPinned, true)
+}
+
+// UnpinConversation godoc
+// @Summary 取消釘選私訊對話
+// @Description 取消釘選與指定用戶的對話
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Success 200 {object} response.Response{data=response.ConversationStateResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/pin [delete]
+func (h *MessageHandler) UnpinConversation(c *gin.Context) {
+	h.setConversationState(c, model.ConversationPinned, false)
+}
+
+func (h *MessageHandler) setConversationState(c *gin.Context, state model.ConversationState, on bool) {
+	otherUserID := c.Param("user_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(otherUserID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	settings, err := h.dmService.SetConversationState(c.Request.Context(), userID, otherUserID, state, on)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewConversationStateResponse(settings))
+}
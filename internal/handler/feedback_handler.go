@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/go-demo/chat/internal/service"
+	"github.com/google/uuid"
+)
+
+// FeedbackSubDir holds screenshots attached to feedback
+const FeedbackSubDir = "feedback"
+
+type FeedbackHandler struct {
+	feedbackService *service.FeedbackService
+	uploadSettings  *service.UploadSettingsService
+	baseURL         string
+}
+
+func NewFeedbackHandler(feedbackService *service.FeedbackService, baseURL string) *FeedbackHandler {
+	_ = os.MkdirAll(filepath.Join(UploadDir, FeedbackSubDir), 0755)
+
+	return &FeedbackHandler{
+		feedbackService: feedbackService,
+		baseURL:         baseURL,
+	}
+}
+
+// SetUploadSettings sets the service whose image limits apply to
+// screenshots. Without it the built-in defaults apply.
+func (h *FeedbackHandler) SetUploadSettings(settings *service.UploadSettingsService) {
+	h.uploadSettings = settings
+}
+
+// SubmitFeedback godoc
+// @Summary 回饋與問題回報
+// @Description 送出問題回報或建議，可附上螢幕截圖（multipart 欄位 screenshot）與用戶端版本
+// @Tags 回饋
+// @Accept json
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param category formData string true "bug、suggestion 或 other"
+// @Param text formData string true "內容"
+// @Param app_version formData string false "應用程式版本"
+// @Param client formData string false "用戶端，例如 web、ios、android"
+// @Param screenshot formData file false "螢幕截圖"
+// @Success 201 {object} response.Response{data=response.FeedbackResponse}
+// @Failure 400 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/v1/feedback [post]
+func (h *FeedbackHandler) SubmitFeedback(c *gin.Context) {
+	var req request.SubmitFeedbackRequest
+	if err := c.ShouldBind(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	var screenshotURL string
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		url, ok := h.saveScreenshot(c)
+		if !ok {
+			return
+		}
+		screenshotURL = url
+	}
+
+	fb, err := h.feedbackService.Submit(c.Request.Context(), &service.SubmitFeedbackInput{
+		UserID:        middleware.GetUserID(c),
+		Category:      model.FeedbackCategory(req.Category),
+		Text:          req.Text,
+		ScreenshotURL: screenshotURL,
+		AppVersion:    req.AppVersion,
+		Client:        req.Client,
+		UserAgent:     truncate(c.Request.UserAgent(), 500),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewFeedbackResponse(fb))
+}
+
+// saveScreenshot stores an attached screenshot and returns its address, or
+// empty when none was attached. It writes the error response itself.
+func (h *FeedbackHandler) saveScreenshot(c *gin.Context) (string, bool) {
+	file, header, err := c.Request.FormFile("screenshot")
+	if err == http.ErrMissingFile {
+		return "", true
+	}
+	if err != nil {
+		response.BadRequest(c, "無法讀取螢幕截圖")
+		return "", false
+	}
+	defer file.Close()
+
+	limits := h.uploadSettings.Limits(c.Request.Context())
+	if status, msg := checkUpload(limits, model.UploadCategoryImage, header.Header.Get("Content-Type"), header.Size); status != 0 {
+		response.ErrorWithStatus(c, status, msg)
+		return "", false
+	}
+
+	filename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), filepath.Ext(header.Filename))
+	out, err := os.Create(filepath.Join(UploadDir, FeedbackSubDir, filename))
+	if err != nil {
+		response.InternalError(c, "儲存檔案失敗")
+		return "", false
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, file); err != nil {
+		response.InternalError(c, "儲存檔案失敗")
+		return "", false
+	}
+
+	return fmt.Sprintf("%s/uploads/%s/%s", h.baseURL, FeedbackSubDir, filename), true
+}
+
+// ListFeedback godoc
+// @Summary 回饋列表
+// @Description 依狀態與類別列出用戶回饋，最新的在前（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "open、in_progress、resolved 或 dismissed"
+// @Param category query string false "bug、suggestion 或 other"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.FeedbackResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/feedback [get]
+func (h *FeedbackHandler) ListFeedback(c *gin.Context) {
+	var query request.FeedbackQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "無效的篩選條件")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	filter := &repository.FeedbackFilter{
+		Status:   model.FeedbackStatus(query.Status),
+		Category: model.FeedbackCategory(query.Category),
+	}
+	items, err := h.feedbackService.List(c.Request.Context(), filter, req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	items, hasMore := pagination.Trim(items, req.Limit)
+
+	result := make([]*response.FeedbackResponse, len(items))
+	for i, fb := range items {
+		result[i] = response.NewFeedbackResponse(fb)
+	}
+
+	response.SuccessWithMeta(c, result, response.NewMeta(req.Limit, req.Offset(), len(result), hasMore))
+}
+
+// GetFeedback godoc
+// @Summary 回饋詳情
+// @Description 取得單筆用戶回饋（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "回饋 ID"
+// @Success 200 {object} response.Response{data=response.FeedbackResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/feedback/{id} [get]
+func (h *FeedbackHandler) GetFeedback(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的回饋 ID")
+		return
+	}
+
+	fb, err := h.feedbackService.Get(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewFeedbackResponse(fb))
+}
+
+// TriageFeedback godoc
+// @Summary 處理回饋
+// @Description 更新用戶回饋的處理狀態並加上備註（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "回饋 ID"
+// @Param request body request.TriageFeedbackRequest true "處理狀態"
+// @Success 200 {object} response.Response{data=response.FeedbackResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/feedback/{id} [patch]
+func (h *FeedbackHandler) TriageFeedback(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的回饋 ID")
+		return
+	}
+
+	var req request.TriageFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	fb, err := h.feedbackService.Triage(c.Request.Context(), id, model.FeedbackStatus(req.Status), req.Note, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewFeedbackResponse(fb))
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type GroupConversationHandler struct {
+	groupService *service.GroupConversationService
+}
+
+func NewGroupConversationHandler(groupService *service.GroupConversationService) *GroupConversationHandler {
+	return &GroupConversationHandler{groupService: groupService}
+}
+
+// CreateConversation godoc
+// @Summary 建立群組對話
+// @Description 與 2 至 9 位用戶建立群組對話（含自己共 3 至 10 人），不屬於任何聊天室；與任一成員有封鎖關係時拒絕
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.CreateGroupConversationRequest true "對話資料"
+// @Success 201 {object} response.Response{data=response.GroupConversationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/v1/group-dms [post]
+func (h *GroupConversationHandler) CreateConversation(c *gin.Context) {
+	var req request.CreateGroupConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	conv, err := h.groupService.Create(c.Request.Context(), &service.CreateGroupConversationInput{
+		CreatorID:      middleware.GetUserID(c),
+		Name:           req.Name,
+		ParticipantIDs: req.ParticipantIDs,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewGroupConversationResponse(conv))
+}
+
+// ListConversations godoc
+// @Summary 群組對話列表
+// @Description 列出參與中的群組對話，依最後訊息時間排序，附最後一則訊息與未讀數量
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.GroupConversationSummaryResponse}
+// @Router /api/v1/group-dms [get]
+func (h *GroupConversationHandler) ListConversations(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	conversations, err := h.groupService.ListConversations(c.Request.Context(), middleware.GetUserID(c), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	conversations, hasMore := pagination.Trim(conversations, req.Limit)
+
+	conversationResponses := make([]*response.GroupConversationSummaryResponse, len(conversations))
+	for i, conv := range conversations {
+		conversationResponses[i] = response.NewGroupConversationSummaryResponse(conv)
+	}
+
+	response.SuccessWithMeta(c, conversationResponses, response.NewMeta(req.Limit, req.Offset(), len(conversationResponses), hasMore))
+}
+
+// GetConversation godoc
+// @Summary 群組對話詳情
+// @Description 獲取群組對話與成員；非成員視為不存在
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "對話 ID"
+// @Success 200 {object} response.Response{data=response.GroupConversationResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/group-dms/{id} [get]
+func (h *GroupConversationHandler) GetConversation(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的對話 ID")
+		return
+	}
+
+	conv, err := h.groupService.Get(c.Request.Context(), id, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewGroupConversationResponse(conv))
+}
+
+// AddParticipants godoc
+// @Summary 新增群組對話成員
+// @Description 任何成員皆可邀請其他用戶加入，總人數不可超過 10 人
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "對話 ID"
+// @Param request body request.AddGroupParticipantsRequest true "新成員"
+// @Success 200 {object} response.Response{data=response.GroupConversationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/v1/group-dms/{id}/participants [post]
+func (h *GroupConversationHandler) AddParticipants(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的對話 ID")
+		return
+	}
+
+	var req request.AddGroupParticipantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	conv, err := h.groupService.AddParticipants(c.Request.Context(), id, middleware.GetUserID(c), req.UserIDs)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewGroupConversationResponse(conv))
+}
+
+// RemoveParticipant godoc
+// @Summary 移除群組對話成員
+// @Description 移除自己即為退出對話；只有建立者可以移除其他成員。最後一位成員退出時對話一併刪除
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "對話 ID"
+// @Param user_id path string true "成員 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/group-dms/{id}/participants/{user_id} [delete]
+func (h *GroupConversationHandler) RemoveParticipant(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的對話 ID")
+		return
+	}
+	targetID := c.Param("user_id")
+	if !utils.ValidateUUID(targetID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	if err := h.groupService.RemoveParticipant(c.Request.Context(), id, middleware.GetUserID(c), targetID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已移除成員", nil)
+}
+
+// SendMessage godoc
+// @Summary 發送群組對話訊息
+// @Description 發送訊息至群組對話，並即時推送給所有成員
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "對話 ID"
+// @Param request body request.SendGroupMessageRequest true "訊息內容"
+// @Success 201 {object} response.Response{data=response.GroupMessageResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/group-dms/{id}/messages [post]
+func (h *GroupConversationHandler) SendMessage(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的對話 ID")
+		return
+	}
+
+	var req request.SendGroupMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	// Validate content
+	v := utils.NewValidator()
+	v.ValidateMessageContent("content", req.Content)
+	if v.HasErrors() {
+		response.ValidationError(c, v.Errors())
+		return
+	}
+
+	msg, err := h.groupService.SendMessage(c.Request.Context(), &service.SendGroupMessageInput{
+		ConversationID: id,
+		SenderID:       middleware.GetUserID(c),
+		Content:        req.Content,
+		Type:           model.MessageType(req.Type),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewGroupMessageResponse(msg))
+}
+
+// GetMessages godoc
+// @Summary 群組對話訊息
+// @Description 獲取群組對話的訊息記錄，每頁依時間先後排列
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "對話 ID"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(50)
+// @Success 200 {object} response.Response{data=[]response.GroupMessageResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/group-dms/{id}/messages [get]
+func (h *GroupConversationHandler) GetMessages(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的對話 ID")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 50}
+	}
+
+	messages, err := h.groupService.ListMessages(c.Request.Context(), id, middleware.GetUserID(c), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	messages, hasMore := pagination.TrimFront(messages, req.Limit)
+
+	messageResponses := make([]*response.GroupMessageResponse, len(messages))
+	for i, m := range messages {
+		messageResponses[i] = response.NewGroupMessageResponse(m)
+	}
+
+	response.SuccessWithMeta(c, messageResponses, response.NewMeta(req.Limit, req.Offset(), len(messageResponses), hasMore))
+}
+
+// MarkAsRead godoc
+// @Summary 標記群組對話已讀
+// @Description 將群組對話目前為止的訊息標記為已讀
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "對話 ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/group-dms/{id}/read [post]
+func (h *GroupConversationHandler) MarkAsRead(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的對話 ID")
+		return
+	}
+
+	if err := h.groupService.MarkAsRead(c.Request.Context(), id, middleware.GetUserID(c)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已標記為已讀", nil)
+}
+
+// GetUnreadCount godoc
+// @Summary 群組對話未讀數量
+// @Description 獲取所有群組對話的未讀訊息總數
+// @Tags 群組對話
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=map[string]int}
+// @Router /api/v1/group-dms/unread [get]
+func (h *GroupConversationHandler) GetUnreadCount(c *gin.Context) {
+	count, err := h.groupService.CountUnread(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"count": count})
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/probe"
+	"github.com/go-demo/chat/internal/system"
+)
+
+// HealthHandler serves the liveness and readiness probes. They answer with
+// plain JSON rather than the API envelope, for load balancers and
+// orchestrators.
+type HealthHandler struct {
+	readiness *system.Readiness
+	prober    *probe.DeliveryProber
+}
+
+func NewHealthHandler(readiness *system.Readiness) *HealthHandler {
+	return &HealthHandler{readiness: readiness}
+}
+
+// SetDeliveryProber includes the message delivery probe in readiness
+// reports. It is informational and never fails readiness on its own.
+func (h *HealthHandler) SetDeliveryProber(prober *probe.DeliveryProber) {
+	h.prober = prober
+}
+
+// Liveness godoc
+// @Summary 存活檢查
+// @Description 程序仍在運作即回傳 200，不檢查相依服務
+// @Tags 系統
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    system.StatusOK,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// Readiness godoc
+// @Summary 就緒檢查
+// @Description 逐一連線 PostgreSQL、Redis 等相依服務並回報各自狀態，任一服務異常時回傳 503
+// @Tags 系統
+// @Produce json
+// @Success 200 {object} system.ReadinessReport
+// @Failure 503 {object} system.ReadinessReport
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.readiness.Check(c.Request.Context())
+
+	body := gin.H{
+		"status":       report.Status,
+		"timestamp":    report.Timestamp,
+		"dependencies": report.Dependencies,
+	}
+	if h.prober != nil {
+		body["delivery_probe"] = h.prober.Status()
+	}
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, body)
+}
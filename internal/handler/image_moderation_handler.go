@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type ImageModerationHandler struct {
+	moderationService *service.ImageModerationService
+}
+
+func NewImageModerationHandler(moderationService *service.ImageModerationService) *ImageModerationHandler {
+	return &ImageModerationHandler{moderationService: moderationService}
+}
+
+// GetSettings godoc
+// @Summary 圖片審核設定
+// @Description 取得上傳圖片的審核開關與門檻（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=response.ImageModerationSettingsResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/moderation/images/settings [get]
+func (h *ImageModerationHandler) GetSettings(c *gin.Context) {
+	settings, err := h.moderationService.GetSettings(c.Request.Context())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewImageModerationSettingsResponse(settings))
+}
+
+// UpdateSettings godoc
+// @Summary 更新圖片審核設定
+// @Description 開關圖片審核並調整門檻：分數達 nsfw_threshold 的圖片模糊顯示並送交審核，達 block_threshold 的圖片直接拒絕（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.UpdateImageModerationRequest true "審核設定"
+// @Success 200 {object} response.Response{data=response.ImageModerationSettingsResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/moderation/images/settings [patch]
+func (h *ImageModerationHandler) UpdateSettings(c *gin.Context) {
+	var req request.UpdateImageModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	settings, err := h.moderationService.UpdateSettings(c.Request.Context(), &service.UpdateImageModerationInput{
+		Enabled:        req.Enabled,
+		NSFWThreshold:  req.NSFWThreshold,
+		BlockThreshold: req.BlockThreshold,
+	}, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewImageModerationSettingsResponse(settings))
+}
+
+// ListQueue godoc
+// @Summary 圖片審核佇列
+// @Description 列出被標記為 NSFW 或無法判定、等待人工審核的圖片，最早上傳的在前（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.ImageAssetResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/moderation/images [get]
+func (h *ImageModerationHandler) ListQueue(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	assets, err := h.moderationService.ListQueue(c.Request.Context(), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	assets, hasMore := pagination.Trim(assets, req.Limit)
+	result := make([]*response.ImageAssetResponse, len(assets))
+	for i, asset := range assets {
+		result[i] = response.NewImageAssetResponse(asset)
+	}
+
+	response.SuccessWithMeta(c, result, response.NewMeta(req.Limit, req.Offset(), len(result), hasMore))
+}
+
+// ReviewImage godoc
+// @Summary 審核圖片
+// @Description 決定圖片的最終判定：clean 取消模糊、nsfw 維持模糊、blocked 刪除檔案，顯示此圖片的訊息一併更新（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "圖片 ID"
+// @Param request body request.ReviewImageRequest true "審核結果"
+// @Success 200 {object} response.Response{data=response.ImageAssetResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/moderation/images/{id} [patch]
+func (h *ImageModerationHandler) ReviewImage(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的圖片 ID")
+		return
+	}
+
+	var req request.ReviewImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	asset, err := h.moderationService.Review(c.Request.Context(), id, model.ImageVerdict(req.Verdict), req.Note, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewImageAssetResponse(asset))
+}
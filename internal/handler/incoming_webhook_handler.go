@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// CreateIncomingWebhook godoc
+// @Summary 新增傳入 Webhook
+// @Description 建立傳入 webhook 與其機器人帳號。外部服務以 POST /api/v1/webhooks/incoming/{token} 發送的訊息會以此機器人的名稱與頭像出現在聊天室。回應中的 token 只會顯示這一次（需要管理聊天室權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param request body request.IncomingWebhookRequest true "機器人身分"
+// @Success 201 {object} response.Response{data=response.IncomingWebhookResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/rooms/{id}/incoming-webhooks [post]
+func (h *RoomHandler) CreateIncomingWebhook(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.IncomingWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	created, err := h.roomService.CreateIncomingWebhook(c.Request.Context(), &service.IncomingWebhookInput{
+		RoomID:    roomID,
+		UserID:    userID,
+		Name:      req.Name,
+		AvatarURL: req.AvatarURL,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewCreatedIncomingWebhookResponse(
+		created.IncomingWebhookWithBot,
+		created.Token,
+		service.IncomingWebhookPathPrefix+created.Token,
+	))
+}
+
+// ListIncomingWebhooks godoc
+// @Summary 傳入 Webhook 列表
+// @Description 列出聊天室的傳入 webhook 與其機器人身分，不含權杖（需要管理聊天室權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Success 200 {object} response.Response{data=[]response.IncomingWebhookResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/incoming-webhooks [get]
+func (h *RoomHandler) ListIncomingWebhooks(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	webhooks, err := h.roomService.ListIncomingWebhooks(c.Request.Context(), roomID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := make([]*response.IncomingWebhookResponse, len(webhooks))
+	for i, w := range webhooks {
+		result[i] = response.NewIncomingWebhookResponse(w)
+	}
+
+	response.Success(c, result)
+}
+
+// UpdateIncomingWebhook godoc
+// @Summary 更新傳入 Webhook
+// @Description 修改機器人的名稱與頭像，已發送的訊息也會顯示新的身分（需要管理聊天室權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param request body request.IncomingWebhookRequest true "機器人身分"
+// @Success 200 {object} response.Response{data=response.IncomingWebhookResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/incoming-webhooks/{webhook_id} [put]
+func (h *RoomHandler) UpdateIncomingWebhook(c *gin.Context) {
+	roomID := c.Param("id")
+	webhookID := c.Param("webhook_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) || !utils.ValidateUUID(webhookID) {
+		response.BadRequest(c, "無效的 ID")
+		return
+	}
+
+	var req request.IncomingWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	webhook, err := h.roomService.UpdateIncomingWebhook(c.Request.Context(), webhookID, &service.IncomingWebhookInput{
+		RoomID:    roomID,
+		UserID:    userID,
+		Name:      req.Name,
+		AvatarURL: req.AvatarURL,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewIncomingWebhookResponse(webhook))
+}
+
+// DeleteIncomingWebhook godoc
+// @Summary 刪除傳入 Webhook
+// @Description 撤銷 webhook 權杖，機器人已發送的訊息會保留（需要管理聊天室權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/incoming-webhooks/{webhook_id} [delete]
+func (h *RoomHandler) DeleteIncomingWebhook(c *gin.Context) {
+	roomID := c.Param("id")
+	webhookID := c.Param("webhook_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) || !utils.ValidateUUID(webhookID) {
+		response.BadRequest(c, "無效的 ID")
+		return
+	}
+
+	if err := h.roomService.DeleteIncomingWebhook(c.Request.Context(), roomID, webhookID, userID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已刪除 Webhook", nil)
+}
+
+// PostIncomingWebhook godoc
+// @Summary 透過傳入 Webhook 發送訊息
+// @Description 以 webhook 的機器人身分在聊天室發送文字訊息，不需要登入，權杖即為授權
+// @Tags 訊息
+// @Accept json
+// @Produce json
+// @Param token path string true "Webhook 權杖"
+// @Param request body request.PostIncomingWebhookRequest true "訊息內容"
+// @Success 201 {object} response.Response{data=response.MessageResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/v1/webhooks/incoming/{token} [post]
+func (h *MessageHandler) PostIncomingWebhook(c *gin.Context) {
+	var req request.PostIncomingWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	v := utils.NewValidator()
+	v.ValidateMessageContent("content", req.Content)
+	if v.HasErrors() {
+		response.ValidationError(c, v.Errors())
+		return
+	}
+
+	msg, err := h.messageService.PostIncomingWebhook(c.Request.Context(), c.Param("token"), req.Content)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	if h.publisher != nil {
+		h.publisher.PublishMessage(msg)
+	}
+
+	response.Created(c, response.NewMessageResponse(msg))
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type IPAccessHandler struct {
+	ipAccessService *service.IPAccessService
+}
+
+func NewIPAccessHandler(ipAccessService *service.IPAccessService) *IPAccessHandler {
+	return &IPAccessHandler{
+		ipAccessService: ipAccessService,
+	}
+}
+
+// ListIPRules godoc
+// @Summary IP 存取規則列表
+// @Description 列出各範圍的 IP 與國家允許、拒絕規則，設定檔中的規則標示為 config 且無法移除（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]response.IPAccessRuleResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/ip-rules [get]
+func (h *IPAccessHandler) ListIPRules(c *gin.Context) {
+	rules, err := h.ipAccessService.List(c.Request.Context())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	ruleResponses := make([]*response.IPAccessRuleResponse, len(rules))
+	for i, rule := range rules {
+		ruleResponses[i] = response.NewIPAccessRuleResponse(rule)
+	}
+	response.Success(c, ruleResponses)
+}
+
+// CreateIPRule godoc
+// @Summary 新增 IP 存取規則
+// @Description 在全站（global）或路由群組（admin、scim、metrics）允許或拒絕 IP 網段或國家，立即套用到所有執行個體。會封鎖自己目前連線的規則將被拒絕（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.IPAccessRuleRequest true "規則"
+// @Success 201 {object} response.Response{data=response.IPAccessRuleResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/v1/admin/ip-rules [post]
+func (h *IPAccessHandler) CreateIPRule(c *gin.Context) {
+	var req request.IPAccessRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	rule, err := h.ipAccessService.Create(c.Request.Context(), &service.IPAccessRuleInput{
+		Scope:        req.Scope,
+		Action:       model.IPAccessAction(req.Action),
+		Target:       req.Target,
+		Note:         req.Note,
+		CreatedBy:    middleware.GetUserID(c),
+		ActorIP:      c.ClientIP(),
+		ActorCountry: middleware.GetClientCountry(c),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewIPAccessRuleResponse(rule))
+}
+
+// DeleteIPRule godoc
+// @Summary 移除 IP 存取規則
+// @Description 移除以 API 新增的規則（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "規則 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/v1/admin/ip-rules/{id} [delete]
+func (h *IPAccessHandler) DeleteIPRule(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的規則 ID")
+		return
+	}
+
+	err := h.ipAccessService.Delete(c.Request.Context(), id, middleware.GetUserID(c), c.ClientIP(), middleware.GetClientCountry(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已移除 IP 存取規則", nil)
+}
+
+// CheckIPRules godoc
+// @Summary 檢查 IP 存取
+// @Description 回報目前的規則對某個 IP 在各範圍是允許或拒絕。未指定國家時以 GeoIP 資料庫查詢（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param ip query string true "IP 位址"
+// @Param country query string false "國碼"
+// @Success 200 {object} response.Response{data=response.IPAccessCheckResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/ip-rules/check [get]
+func (h *IPAccessHandler) CheckIPRules(c *gin.Context) {
+	var query request.IPAccessCheckQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	check, err := h.ipAccessService.Check(query.IP, query.Country)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	resp := &response.IPAccessCheckResponse{IP: check.IP, Country: check.Country}
+	for _, scope := range model.IPAccessScopes {
+		result := &response.IPAccessScopeResult{Scope: scope, Allowed: true}
+		if denial := check.Denials[scope]; denial != nil {
+			result.Allowed = false
+			result.Reason = denial.Reason
+		}
+		resp.Scopes = append(resp.Scopes, result)
+	}
+	response.Success(c, resp)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type IPBanHandler struct {
+	ipBanService *service.IPBanService
+}
+
+func NewIPBanHandler(ipBanService *service.IPBanService) *IPBanHandler {
+	return &IPBanHandler{
+		ipBanService: ipBanService,
+	}
+}
+
+// CreateIPBan godoc
+// @Summary 封鎖 IP
+// @Description 封鎖 IP 位址或網段，可設定期限與拖延回應（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.IPBanRequest true "封鎖資訊"
+// @Success 201 {object} response.Response{data=response.IPBanResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/v1/admin/ip-bans [post]
+func (h *IPBanHandler) CreateIPBan(c *gin.Context) {
+	var req request.IPBanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			response.BadRequest(c, "無效的封鎖期間")
+			return
+		}
+		duration = d
+	}
+
+	ban, err := h.ipBanService.Ban(c.Request.Context(), &service.IPBanInput{
+		CIDR:      req.CIDR,
+		Reason:    req.Reason,
+		Tarpit:    req.Tarpit,
+		Duration:  duration,
+		CreatedBy: middleware.GetUserID(c),
+		ActorIP:   c.ClientIP(),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewIPBanResponse(ban))
+}
+
+// DeleteIPBan godoc
+// @Summary 解除 IP 封鎖
+// @Description 移除 IP 封鎖（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "封鎖 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/ip-bans/{id} [delete]
+func (h *IPBanHandler) DeleteIPBan(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的封鎖 ID")
+		return
+	}
+
+	if err := h.ipBanService.Unban(c.Request.Context(), id, middleware.GetUserID(c)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已解除 IP 封鎖", nil)
+}
+
+// ListIPBans godoc
+// @Summary IP 封鎖列表
+// @Description 列出 IP 封鎖，包含尚未清除的過期項目（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.IPBanResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/ip-bans [get]
+func (h *IPBanHandler) ListIPBans(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	bans, err := h.ipBanService.List(c.Request.Context(), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	bans, hasMore := pagination.Trim(bans, req.Limit)
+
+	banResponses := make([]*response.IPBanResponse, len(bans))
+	for i, b := range bans {
+		banResponses[i] = response.NewIPBanResponse(b)
+	}
+
+	response.SuccessWithMeta(c, banResponses, response.NewMeta(req.Limit, req.Offset(), len(banResponses), hasMore))
+}
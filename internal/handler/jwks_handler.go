@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/pkg/utils"
+)
+
+// JWKSHandler publishes the public signing keys so other services can
+// verify access tokens. Like the health probes it answers with the plain
+// document rather than the API envelope.
+type JWKSHandler struct {
+	jwtManager *utils.JWTManager
+}
+
+func NewJWKSHandler(jwtManager *utils.JWTManager) *JWKSHandler {
+	return &JWKSHandler{jwtManager: jwtManager}
+}
+
+// GetJWKS godoc
+// @Summary JWT 公開金鑰
+// @Description 以 JWKS 格式（RFC 7517）回傳驗證 Token 用的 RSA 與 Ed25519 公開金鑰，包含即將啟用的金鑰；HMAC 金鑰不會公開
+// @Tags 系統
+// @Produce json
+// @Success 200 {object} utils.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// Verifiers may cache briefly; keys are published well before they sign
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwtManager.JWKS())
+}
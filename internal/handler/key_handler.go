@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type KeyHandler struct {
+	keyService *service.KeyService
+}
+
+func NewKeyHandler(keyService *service.KeyService) *KeyHandler {
+	return &KeyHandler{keyService: keyService}
+}
+
+// RegisterKeys godoc
+// @Summary 註冊裝置金鑰
+// @Description 發布目前裝置的身分金鑰、簽章預金鑰與一次性預金鑰，供他人建立端對端加密私訊。金鑰皆為 Base64，伺服器只保存公開金鑰；更換身分金鑰會清除先前上傳的一次性預金鑰。需使用綁定裝置的 Token
+// @Tags 加密私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.RegisterKeysRequest true "裝置金鑰"
+// @Success 200 {object} response.Response{data=response.DeviceKeysResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/keys [put]
+func (h *KeyHandler) RegisterKeys(c *gin.Context) {
+	var req request.RegisterKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	keys, err := h.keyService.RegisterKeys(c.Request.Context(), middleware.GetUserID(c), middleware.GetDeviceID(c), &service.RegisterKeysInput{
+		RegistrationID:        req.RegistrationID,
+		IdentityKey:           req.IdentityKey,
+		SignedPrekeyID:        req.SignedPrekeyID,
+		SignedPrekey:          req.SignedPrekey,
+		SignedPrekeySignature: req.SignedPrekeySignature,
+		OneTimePrekeys:        toOneTimePrekeys(req.OneTimePrekeys),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已註冊裝置金鑰", response.NewDeviceKeysResponse(keys))
+}
+
+// UploadPrekeys godoc
+// @Summary 補充一次性預金鑰
+// @Description 為目前裝置上傳更多一次性預金鑰，一次最多 100 把，已存在的金鑰 ID 會略過
+// @Tags 加密私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.UploadPrekeysRequest true "一次性預金鑰"
+// @Success 200 {object} response.Response{data=response.PrekeyCountResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/keys/prekeys [post]
+func (h *KeyHandler) UploadPrekeys(c *gin.Context) {
+	var req request.UploadPrekeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	count, err := h.keyService.AddPrekeys(c.Request.Context(), middleware.GetUserID(c), middleware.GetDeviceID(c), toOneTimePrekeys(req.OneTimePrekeys))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, &response.PrekeyCountResponse{Count: count})
+}
+
+// CountPrekeys godoc
+// @Summary 查詢剩餘預金鑰
+// @Description 查詢目前裝置剩餘的一次性預金鑰數量，不足時應補充
+// @Tags 加密私訊
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=response.PrekeyCountResponse}
+// @Failure 400 {object} response.Response
+// @Router /api/v1/keys/prekeys/count [get]
+func (h *KeyHandler) CountPrekeys(c *gin.Context) {
+	count, err := h.keyService.CountPrekeys(c.Request.Context(), middleware.GetUserID(c), middleware.GetDeviceID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, &response.PrekeyCountResponse{Count: count})
+}
+
+// GetPrekeyBundles godoc
+// @Summary 取得預金鑰組
+// @Description 取得指定用戶每個裝置的預金鑰組以建立加密工作階段，每組會用掉該裝置一把一次性預金鑰
+// @Tags 加密私訊
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用戶 ID"
+// @Success 200 {object} response.Response{data=[]response.PrekeyBundleResponse}
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/v1/users/{id}/keys [get]
+func (h *KeyHandler) GetPrekeyBundles(c *gin.Context) {
+	userID := c.Param("id")
+	if !utils.ValidateUUID(userID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	bundles, err := h.keyService.GetBundles(c.Request.Context(), middleware.GetUserID(c), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	bundleResponses := make([]*response.PrekeyBundleResponse, len(bundles))
+	for i, b := range bundles {
+		bundleResponses[i] = response.NewPrekeyBundleResponse(b)
+	}
+
+	response.Success(c, bundleResponses)
+}
+
+func toOneTimePrekeys(reqs []*request.OneTimePrekeyRequest) []*model.OneTimePrekey {
+	prekeys := make([]*model.OneTimePrekey, len(reqs))
+	for i, r := range reqs {
+		prekeys[i] = &model.OneTimePrekey{KeyID: r.KeyID, PublicKey: r.PublicKey}
+	}
+	return prekeys
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/mail/templates"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type MailHandler struct {
+	renderer    *templates.Renderer
+	userService *service.UserService
+}
+
+func NewMailHandler(renderer *templates.Renderer, userService *service.UserService) *MailHandler {
+	return &MailHandler{
+		renderer:    renderer,
+		userService: userService,
+	}
+}
+
+// ListTemplates godoc
+// @Summary 信件範本列表
+// @Description 列出可用的信件範本與語系，包含部署覆寫的範本（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=response.MailTemplatesResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/mail/templates [get]
+func (h *MailHandler) ListTemplates(c *gin.Context) {
+	response.Success(c, &response.MailTemplatesResponse{
+		Templates:     h.renderer.Names(),
+		Locales:       h.renderer.Locales(),
+		DefaultLocale: h.renderer.DefaultLocale(),
+	})
+}
+
+// PreviewTemplate godoc
+// @Summary 預覽信件範本
+// @Description 以範例資料與目前管理員的帳號渲染信件範本；format=html 或 text 時直接回傳信件內容（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json,html,plain
+// @Security BearerAuth
+// @Param name path string true "範本名稱"
+// @Param locale query string false "語系，例如 zh-TW、en"
+// @Param format query string false "json（預設）、html 或 text"
+// @Success 200 {object} response.Response{data=response.MailPreviewResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/mail/templates/{name}/preview [get]
+func (h *MailHandler) PreviewTemplate(c *gin.Context) {
+	name := c.Param("name")
+	locale := c.DefaultQuery("locale", h.renderer.DefaultLocale())
+
+	data := &templates.Data{
+		Vars: templates.Sample(name, h.renderer.Site()),
+	}
+	if user, err := h.userService.GetByID(c.Request.Context(), middleware.GetUserID(c)); err == nil {
+		data.User = templates.User{
+			Username:    user.Username,
+			DisplayName: user.GetDisplayName(),
+			Email:       user.Email,
+		}
+	}
+
+	msg, err := h.renderer.Render(name, locale, data)
+	if err != nil {
+		if errors.Is(err, templates.ErrTemplateNotFound) {
+			response.NotFound(c, "信件範本不存在")
+			return
+		}
+		response.BadRequest(c, "信件範本渲染失敗："+err.Error())
+		return
+	}
+
+	switch c.Query("format") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(msg.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(msg.Text))
+	default:
+		response.Success(c, response.NewMailPreviewResponse(name, msg))
+	}
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// ForwardMessage godoc
+// @Summary 轉傳聊天室訊息
+// @Description 將聊天室訊息複製到另一個聊天室（room_id）或私訊（user_id），並附上轉傳來源。需可讀取來源聊天室並可在目標發言
+// @Tags 訊息
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param room_id path string true "來源聊天室 ID"
+// @Param message_id path string true "訊息 ID"
+// @Param request body request.ForwardMessageRequest true "轉傳目標"
+// @Success 201 {object} response.Response{data=response.MessageResponse} "轉傳至私訊時回傳 response.DirectMessageResponse"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{room_id}/messages/{message_id}/forward [post]
+func (h *MessageHandler) ForwardMessage(c *gin.Context) {
+	roomID := c.Param("room_id")
+	messageID := c.Param("message_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+	if !utils.ValidateUUID(messageID) {
+		response.BadRequest(c, "無效的訊息 ID")
+		return
+	}
+
+	req, ok := bindForwardRequest(c)
+	if !ok {
+		return
+	}
+
+	src, err := h.messageService.ForwardSource(c.Request.Context(), roomID, messageID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	h.deliverForward(c, userID, src, req)
+}
+
+// ForwardDirectMessage godoc
+// @Summary 轉傳私訊
+// @Description 將與指定用戶對話中的私訊複製到聊天室（room_id）或另一段私訊（user_id），並附上轉傳來源。需可在目標發言
+// @Tags 私訊
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id path string true "對方用戶 ID"
+// @Param message_id path string true "私訊 ID"
+// @Param request body request.ForwardMessageRequest true "轉傳目標"
+// @Success 201 {object} response.Response{data=response.DirectMessageResponse} "轉傳至聊天室時回傳 response.MessageResponse"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/dm/{user_id}/messages/{message_id}/forward [post]
+func (h *MessageHandler) ForwardDirectMessage(c *gin.Context) {
+	otherUserID := c.Param("user_id")
+	messageID := c.Param("message_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(otherUserID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+	if !utils.ValidateUUID(messageID) {
+		response.BadRequest(c, "無效的訊息 ID")
+		return
+	}
+
+	req, ok := bindForwardRequest(c)
+	if !ok {
+		return
+	}
+
+	src, err := h.dmService.ForwardSource(c.Request.Context(), userID, otherUserID, messageID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	h.deliverForward(c, userID, src, req)
+}
+
+// bindForwardRequest binds the destination, which must be exactly one of a
+// room or a user
+func bindForwardRequest(c *gin.Context) (*request.ForwardMessageRequest, bool) {
+	var req request.ForwardMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return nil, false
+	}
+	if (req.RoomID == "") == (req.UserID == "") {
+		response.BadRequest(c, "請指定一個轉傳目標：room_id 或 user_id")
+		return nil, false
+	}
+	return &req, true
+}
+
+// deliverForward sends the copy through the regular send paths, so the
+// destination's own checks (membership, mutes, blocks) apply
+func (h *MessageHandler) deliverForward(c *gin.Context, userID string, src *service.ForwardSource, req *request.ForwardMessageRequest) {
+	if req.RoomID != "" {
+		msg, err := h.messageService.SendMessage(c.Request.Context(), &service.SendMessageInput{
+			RoomID:        req.RoomID,
+			UserID:        userID,
+			Content:       src.Content,
+			Type:          src.Type,
+			AttachmentIDs: src.AttachmentIDs,
+			ForwardedFrom: src.From,
+		})
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+
+		if h.publisher != nil {
+			h.publisher.PublishMessage(msg)
+		}
+
+		response.Created(c, response.NewMessageResponse(msg))
+		return
+	}
+
+	msg, err := h.dmService.SendMessage(c.Request.Context(), &service.SendDMInput{
+		SenderID:      userID,
+		ReceiverID:    req.UserID,
+		Content:       src.Content,
+		Type:          src.Type,
+		AttachmentIDs: src.AttachmentIDs,
+		ForwardedFrom: src.From,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewDirectMessageResponse(msg))
+}
@@ -1,19 +1,37 @@
 package handler
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-demo/chat/internal/dto/request"
 	"github.com/go-demo/chat/internal/dto/response"
 	"github.com/go-demo/chat/internal/middleware"
 	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/search"
 	"github.com/go-demo/chat/internal/pkg/utils"
 	"github.com/go-demo/chat/internal/service"
 )
 
+// MessagePublisher delivers messages sent over REST to WebSocket clients
+type MessagePublisher interface {
+	PublishMessage(msg *model.MessageWithUser)
+}
+
+// MessageWaiter signals the next message in a room to long-poll requests
+type MessageWaiter interface {
+	WaitForMessage(roomID string) (<-chan struct{}, func())
+}
+
 type MessageHandler struct {
 	messageService *service.MessageService
 	roomService    *service.RoomService
 	dmService      *service.DirectMessageService
+	publisher      MessagePublisher
+	waiter         MessageWaiter
 }
 
 func NewMessageHandler(
@@ -28,16 +46,27 @@ func NewMessageHandler(
 	}
 }
 
+// SetPublisher sets the publisher that pushes REST-sent messages to the room
+func (h *MessageHandler) SetPublisher(publisher MessagePublisher) {
+	h.publisher = publisher
+}
+
+// SetWaiter sets the source of new message signals for long polling;
+// without one, polls return at once
+func (h *MessageHandler) SetWaiter(waiter MessageWaiter) {
+	h.waiter = waiter
+}
+
 // SendMessage godoc
 // @Summary 發送訊息
-// @Description 在聊天室中發送訊息
+// @Description 在聊天室中發送訊息。attachment_ids 可附加自己上傳的圖片或檔案（上傳回應中的 id），最多 10 個
 // @Tags 訊息
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param room_id path string true "聊天室 ID"
 // @Param request body request.SendMessageRequest true "訊息內容"
-// @Success 201 {object} response.Response{data=response.MessageResponse}
+// @Success 201 {object} response.Response{data=response.MessageResponse} "指定 scheduled_at 時回傳 response.ScheduledMessageResponse"
 // @Failure 400 {object} response.Response
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
@@ -71,20 +100,53 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 		msgType = model.MessageTypeImage
 	} else if req.Type == "file" {
 		msgType = model.MessageTypeFile
+	} else if req.Type == "sticker" {
+		msgType = model.MessageTypeSticker
+	}
+
+	input := &service.SendMessageInput{
+		RoomID:        roomID,
+		UserID:        userID,
+		Content:       req.Content,
+		Type:          msgType,
+		ReplyToID:     req.ReplyToID,
+		AttachmentIDs: req.AttachmentIDs,
+	}
+
+	if req.ScheduledAt != "" {
+		// Attachments are linked when a message is stored; an upload could
+		// be removed before a scheduled message is
+		if len(req.AttachmentIDs) > 0 {
+			response.BadRequest(c, "排程訊息不支援附件")
+			return
+		}
+
+		at, err := time.Parse(time.RFC3339, req.ScheduledAt)
+		if err != nil {
+			response.BadRequest(c, "無效的排程時間")
+			return
+		}
+
+		scheduled, err := h.messageService.ScheduleMessage(c.Request.Context(), input, at)
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+
+		response.Created(c, response.NewScheduledMessageResponse(scheduled))
+		return
 	}
 
-	msg, err := h.messageService.SendMessage(c.Request.Context(), &service.SendMessageInput{
-		RoomID:    roomID,
-		UserID:    userID,
-		Content:   req.Content,
-		Type:      msgType,
-		ReplyToID: req.ReplyToID,
-	})
+	msg, err := h.messageService.SendMessage(c.Request.Context(), input)
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
+	if h.publisher != nil {
+		h.publisher.PublishMessage(msg)
+	}
+
 	response.Created(c, response.NewMessageResponse(msg))
 }
 
@@ -98,7 +160,11 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 // @Param room_id path string true "聊天室 ID"
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(50)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Param before query string false "訊息 ID，取得此訊息之前的一頁（不受新訊息影響）"
+// @Param after query string false "訊息 ID，取得此訊息之後的訊息，用於斷線後續傳（僅限成員）"
 // @Success 200 {object} response.Response{data=[]response.MessageResponse}
+// @Failure 400 {object} response.Response
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Router /api/v1/rooms/{room_id}/messages [get]
@@ -116,23 +182,70 @@ func (h *MessageHandler) GetMessages(c *gin.Context) {
 		req = request.PaginationRequest{Page: 1, Limit: 50}
 	}
 
-	messages, err := h.messageService.ListByRoomID(c.Request.Context(), roomID, userID, req.Limit, req.Offset())
+	var cursor request.MessageCursorQuery
+	if err := c.ShouldBindQuery(&cursor); err != nil || (cursor.Before != "" && cursor.After != "") {
+		response.BadRequest(c, "無效的訊息 ID")
+		return
+	}
+	if cursor.Before != "" || cursor.After != "" {
+		h.getMessagesByID(c, roomID, userID, &cursor, req.Limit)
+		return
+	}
+
+	messages, err := h.messageService.ListByRoomID(c.Request.Context(), roomID, userID, req.FetchLimit(), req.Offset())
 	if err != nil {
+		if err == apperrors.ErrRoomNotFound && redirectMergedRoom(c, h.roomService, roomID) {
+			return
+		}
 		response.Error(c, err)
 		return
 	}
+	messages, hasMore := pagination.Trim(messages, req.Limit)
 
 	messageResponses := make([]*response.MessageResponse, len(messages))
 	for i, m := range messages {
 		messageResponses[i] = response.NewMessageResponse(m)
 	}
 
-	response.Success(c, messageResponses)
+	response.SuccessWithMeta(c, messageResponses, response.NewMeta(req.Limit, req.Offset(), len(messageResponses), hasMore))
+}
+
+// getMessagesByID lists the messages before or after a message ID. The
+// meta carries no cursor: the next page starts from the first or last
+// message returned.
+func (h *MessageHandler) getMessagesByID(c *gin.Context, roomID, userID string, cursor *request.MessageCursorQuery, limit int) {
+	var (
+		messages []*model.MessageWithUser
+		hasMore  bool
+		err      error
+	)
+	if cursor.Before != "" {
+		messages, err = h.messageService.ListBefore(c.Request.Context(), roomID, userID, cursor.Before, pagination.FetchLimit(limit))
+		messages, hasMore = pagination.TrimFront(messages, limit)
+	} else {
+		messages, err = h.messageService.ListSince(c.Request.Context(), roomID, userID, cursor.After, pagination.FetchLimit(limit))
+		messages, hasMore = pagination.Trim(messages, limit)
+	}
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	messageResponses := make([]*response.MessageResponse, len(messages))
+	for i, m := range messages {
+		messageResponses[i] = response.NewMessageResponse(m)
+	}
+
+	response.SuccessWithMeta(c, messageResponses, &response.Meta{
+		Limit:    limit,
+		Returned: len(messageResponses),
+		HasMore:  hasMore,
+	})
 }
 
 // UpdateMessage godoc
 // @Summary 編輯訊息
-// @Description 編輯已發送的訊息
+// @Description 編輯已發送的訊息；聊天室會收到 message_updated 事件
 // @Tags 訊息
 // @Accept json
 // @Produce json
@@ -171,7 +284,7 @@ func (h *MessageHandler) UpdateMessage(c *gin.Context) {
 
 // DeleteMessage godoc
 // @Summary 刪除訊息
-// @Description 刪除訊息（自己的訊息或管理員可刪除）
+// @Description 刪除訊息（自己的訊息或管理員可刪除）；聊天室會收到 message_deleted 事件（message_id、room_id）
 // @Tags 訊息
 // @Accept json
 // @Produce json
@@ -201,15 +314,19 @@ func (h *MessageHandler) DeleteMessage(c *gin.Context) {
 
 // SearchMessages godoc
 // @Summary 搜尋訊息
-// @Description 在聊天室中搜尋訊息
+// @Description 在聊天室中搜尋訊息；啟用搜尋引擎時依相關度排序並容許拼字誤差，否則依時間由新到舊
 // @Tags 訊息
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param room_id path string true "聊天室 ID"
 // @Param q query string true "搜尋關鍵字"
+// @Param sender_id query string false "只搜尋此用戶的訊息"
+// @Param from query string false "起始時間（RFC3339，包含）"
+// @Param to query string false "結束時間（RFC3339，不包含）"
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.MessageResponse}
 // @Failure 400 {object} response.Response
 // @Failure 403 {object} response.Response
@@ -223,24 +340,37 @@ func (h *MessageHandler) SearchMessages(c *gin.Context) {
 		return
 	}
 
-	var req request.SearchRequest
+	var req request.MessageSearchRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
 		response.BadRequest(c, "請求格式錯誤")
 		return
 	}
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		response.BadRequest(c, "起始時間必須早於結束時間")
+		return
+	}
 
-	messages, err := h.messageService.Search(c.Request.Context(), roomID, userID, req.Query, req.Limit, req.Offset())
+	messages, err := h.messageService.Search(c.Request.Context(), userID, &search.MessageQuery{
+		RoomID:   roomID,
+		Text:     req.Query,
+		SenderID: req.SenderID,
+		After:    req.From,
+		Before:   req.To,
+		Limit:    req.FetchLimit(),
+		Offset:   req.Offset(),
+	})
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	messages, hasMore := pagination.Trim(messages, req.Limit)
 
 	messageResponses := make([]*response.MessageResponse, len(messages))
 	for i, m := range messages {
 		messageResponses[i] = response.NewMessageResponse(m)
 	}
 
-	response.Success(c, messageResponses)
+	response.SuccessWithMeta(c, messageResponses, response.NewMeta(req.Limit, req.Offset(), len(messageResponses), hasMore))
 }
 
 // MarkAsRead godoc
@@ -273,7 +403,7 @@ func (h *MessageHandler) MarkAsRead(c *gin.Context) {
 
 // SendDirectMessage godoc
 // @Summary 發送私訊
-// @Description 向指定用戶發送私人訊息
+// @Description 向指定用戶發送私人訊息。type 為 ciphertext 時為端對端加密訊息：不含 content，需為對方每個已註冊金鑰的裝置與自己的其他裝置各附一份密文，裝置清單不符時回傳 409 並列出缺少與多餘的裝置
 // @Tags 私訊
 // @Accept json
 // @Produce json
@@ -283,6 +413,7 @@ func (h *MessageHandler) MarkAsRead(c *gin.Context) {
 // @Success 201 {object} response.Response{data=response.DirectMessageResponse}
 // @Failure 400 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Router /api/v1/dm/{user_id} [post]
 func (h *MessageHandler) SendDirectMessage(c *gin.Context) {
@@ -300,28 +431,45 @@ func (h *MessageHandler) SendDirectMessage(c *gin.Context) {
 		return
 	}
 
-	// Validate content
-	v := utils.NewValidator()
-	v.ValidateMessageContent("content", req.Content)
-	if v.HasErrors() {
-		response.ValidationError(c, v.Errors())
-		return
-	}
-
-	// Default type
-	msgType := model.MessageTypeText
-	if req.Type == "image" {
-		msgType = model.MessageTypeImage
-	} else if req.Type == "file" {
-		msgType = model.MessageTypeFile
-	}
-
-	msg, err := h.dmService.SendMessage(c.Request.Context(), &service.SendDMInput{
-		SenderID:   senderID,
-		ReceiverID: receiverID,
-		Content:    req.Content,
-		Type:       msgType,
-	})
+	input := &service.SendDMInput{
+		SenderID:      senderID,
+		ReceiverID:    receiverID,
+		Content:       req.Content,
+		Type:          model.MessageTypeText,
+		AttachmentIDs: req.AttachmentIDs,
+	}
+	if req.Type == "ciphertext" {
+		// The server only relays envelopes; there is no content to validate
+		if len(req.Envelopes) == 0 || req.Content != "" {
+			response.BadRequest(c, "加密訊息需附上各裝置的密文，且不可包含明文內容")
+			return
+		}
+		input.Type = model.MessageTypeCiphertext
+		input.SenderDeviceID = middleware.GetDeviceID(c)
+		for _, env := range req.Envelopes {
+			input.Envelopes = append(input.Envelopes, &model.DMEnvelope{
+				DeviceID:   env.DeviceID,
+				Type:       env.Type,
+				Ciphertext: env.Ciphertext,
+			})
+		}
+	} else {
+		// Validate content
+		v := utils.NewValidator()
+		v.ValidateMessageContent("content", req.Content)
+		if v.HasErrors() {
+			response.ValidationError(c, v.Errors())
+			return
+		}
+
+		if req.Type == "image" {
+			input.Type = model.MessageTypeImage
+		} else if req.Type == "file" {
+			input.Type = model.MessageTypeFile
+		}
+	}
+
+	msg, err := h.dmService.SendMessage(c.Request.Context(), input)
 	if err != nil {
 		response.Error(c, err)
 		return
@@ -332,7 +480,7 @@ func (h *MessageHandler) SendDirectMessage(c *gin.Context) {
 
 // GetConversation godoc
 // @Summary 獲取私訊對話
-// @Description 獲取與指定用戶的私訊對話記錄
+// @Description 獲取與指定用戶的私訊對話記錄；加密訊息附上寄給目前裝置的密文
 // @Tags 私訊
 // @Accept json
 // @Produce json
@@ -340,6 +488,7 @@ func (h *MessageHandler) SendDirectMessage(c *gin.Context) {
 // @Param user_id path string true "對方用戶 ID"
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(50)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.DirectMessageResponse}
 // @Failure 404 {object} response.Response
 // @Router /api/v1/dm/{user_id} [get]
@@ -357,29 +506,36 @@ func (h *MessageHandler) GetConversation(c *gin.Context) {
 		req = request.PaginationRequest{Page: 1, Limit: 50}
 	}
 
-	messages, err := h.dmService.GetConversation(c.Request.Context(), userID, otherUserID, req.Limit, req.Offset())
+	messages, err := h.dmService.GetConversation(c.Request.Context(), userID, otherUserID, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	if err := h.dmService.AttachEnvelopes(c.Request.Context(), middleware.GetDeviceID(c), messages); err != nil {
+		response.Error(c, err)
+		return
+	}
+	messages, hasMore := pagination.Trim(messages, req.Limit)
 
 	messageResponses := make([]*response.DirectMessageResponse, len(messages))
 	for i, m := range messages {
 		messageResponses[i] = response.NewDirectMessageResponse(m)
 	}
 
-	response.Success(c, messageResponses)
+	response.SuccessWithMeta(c, messageResponses, response.NewMeta(req.Limit, req.Offset(), len(messageResponses), hasMore))
 }
 
 // ListConversations godoc
 // @Summary 獲取對話列表
-// @Description 獲取所有私訊對話
+// @Description 獲取私訊對話，釘選的排在最前面；隱藏的對話在收到新訊息前不列出，已封存的對話需以 archived=true 另行列出
 // @Tags 私訊
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param archived query bool false "只列出已封存的對話"
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.ConversationResponse}
 // @Router /api/v1/dm [get]
 func (h *MessageHandler) ListConversations(c *gin.Context) {
@@ -390,18 +546,20 @@ func (h *MessageHandler) ListConversations(c *gin.Context) {
 		req = request.PaginationRequest{Page: 1, Limit: 20}
 	}
 
-	conversations, err := h.dmService.ListConversations(c.Request.Context(), userID, req.Limit, req.Offset())
+	archived, _ := strconv.ParseBool(c.Query("archived"))
+	conversations, err := h.dmService.ListConversations(c.Request.Context(), userID, archived, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	conversations, hasMore := pagination.Trim(conversations, req.Limit)
 
 	conversationResponses := make([]*response.ConversationResponse, len(conversations))
 	for i, c := range conversations {
 		conversationResponses[i] = response.NewConversationResponse(c)
 	}
 
-	response.Success(c, conversationResponses)
+	response.SuccessWithMeta(c, conversationResponses, response.NewMeta(req.Limit, req.Offset(), len(conversationResponses), hasMore))
 }
 
 // MarkDMAsRead godoc
@@ -54,6 +54,7 @@ func setupMessageHandlerTestIsolated(t *testing.T) (*gin.Engine, *service.Messag
 		rooms.PUT("/:room_id/messages/:message_id", handler.UpdateMessage)
 		rooms.DELETE("/:room_id/messages/:message_id", handler.DeleteMessage)
 		rooms.GET("/:room_id/messages/search", handler.SearchMessages)
+		rooms.GET("/:room_id/messages/poll", handler.PollMessages)
 	}
 
 	dm := router.Group("/api/v1/dm")
@@ -155,6 +156,55 @@ func TestMessageHandler_GetMessages(t *testing.T) {
 	}
 }
 
+func TestMessageHandler_PollMessages(t *testing.T) {
+	router, messageService, roomService, _, jwtManager, db, prefix := setupMessageHandlerTestIsolated(t)
+	defer db.Close()
+	defer cleanupMessageHandlerTestByPrefix(t, db, prefix)
+
+	user := createUserForMsgHandlerTestIsolated(t, db, prefix, "alice")
+
+	room, _ := roomService.Create(context.Background(), &service.CreateRoomInput{
+		Name:    prefix + "_Test Room",
+		Type:    model.RoomTypePublic,
+		OwnerID: user.ID,
+	})
+
+	first, _ := messageService.SendMessage(context.Background(), &service.SendMessageInput{
+		RoomID: room.ID, UserID: user.ID, Content: "Message 1", Type: model.MessageTypeText,
+	})
+	second, _ := messageService.SendMessage(context.Background(), &service.SendMessageInput{
+		RoomID: room.ID, UserID: user.ID, Content: "Message 2", Type: model.MessageTypeText,
+	})
+
+	tokenPair, _ := jwtManager.GenerateTokenPair(user.ID, user.Username)
+
+	poll := func(afterID string) (int, []interface{}) {
+		req := httptest.NewRequest("GET", "/api/v1/rooms/"+room.ID+"/messages/poll?timeout=1&after_id="+afterID, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		data, _ := response["data"].([]interface{})
+		return w.Code, data
+	}
+
+	// Messages already stored are returned without waiting
+	if code, data := poll(first.ID); code != http.StatusOK || len(data) != 1 {
+		t.Errorf("Expected 1 message, got %d with %d", code, len(data))
+	}
+
+	// Without a waiter, a poll with nothing new returns at once
+	if code, data := poll(second.ID); code != http.StatusOK || len(data) != 0 {
+		t.Errorf("Expected no messages, got %d with %d", code, len(data))
+	}
+
+	if code, _ := poll("not-a-uuid"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", code)
+	}
+}
+
 func TestMessageHandler_UpdateMessage(t *testing.T) {
 	router, messageService, roomService, _, jwtManager, db, prefix := setupMessageHandlerTestIsolated(t)
 	defer db.Close()
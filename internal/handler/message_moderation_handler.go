@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type MessageModerationHandler struct {
+	moderationService *service.MessageModerationService
+}
+
+func NewMessageModerationHandler(moderationService *service.MessageModerationService) *MessageModerationHandler {
+	return &MessageModerationHandler{moderationService: moderationService}
+}
+
+// ListQueue godoc
+// @Summary 訊息審核佇列
+// @Description 列出被內容審查標記、等待人工審核的訊息，最早標記的在前（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.MessageFlagResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/moderation/messages [get]
+func (h *MessageModerationHandler) ListQueue(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	flags, err := h.moderationService.ListQueue(c.Request.Context(), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	flags, hasMore := pagination.Trim(flags, req.Limit)
+	result := make([]*response.MessageFlagResponse, len(flags))
+	for i, flag := range flags {
+		result[i] = response.NewQueuedMessageFlagResponse(flag)
+	}
+
+	response.SuccessWithMeta(c, result, response.NewMeta(req.Limit, req.Offset(), len(result), hasMore))
+}
+
+// ReviewMessage godoc
+// @Summary 審核訊息
+// @Description 處理被標記的訊息：approve 保留訊息、remove 刪除訊息（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "標記 ID"
+// @Param request body request.ReviewMessageRequest true "審核結果"
+// @Success 200 {object} response.Response{data=response.MessageFlagResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/moderation/messages/{id} [patch]
+func (h *MessageModerationHandler) ReviewMessage(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的標記 ID")
+		return
+	}
+
+	var req request.ReviewMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	flag, err := h.moderationService.Review(c.Request.Context(), id, req.Action, req.Note, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewMessageFlagResponse(flag))
+}
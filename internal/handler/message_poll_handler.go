@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+)
+
+// pollWriteMargin is the time left to write the response after a long poll
+// times out; the wait may outlast the server's write timeout
+const pollWriteMargin = 10 * time.Second
+
+// PollMessages godoc
+// @Summary 長輪詢新訊息
+// @Description 取得 after_id 之後的訊息；若目前沒有新訊息，最多等待 timeout 秒，期間有新訊息即回傳，逾時則回傳空列表。適合無法使用 WebSocket 或 SSE 的簡易整合，下次輪詢以最後一則訊息的 ID 作為 after_id（僅限成員）
+// @Tags 訊息
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param room_id path string true "聊天室 ID"
+// @Param after_id query string true "訊息 ID，取得此訊息之後的訊息"
+// @Param timeout query int false "最長等待秒數（1-60）" default(25)
+// @Param limit query int false "最多回傳筆數" default(50)
+// @Success 200 {object} response.Response{data=[]response.MessageResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{room_id}/messages/poll [get]
+func (h *MessageHandler) PollMessages(c *gin.Context) {
+	roomID := c.Param("room_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var query request.PollMessagesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "無效的訊息 ID 或等待時間")
+		return
+	}
+
+	// Waiting starts before the first read, so a message stored in between
+	// still wakes the request
+	var wake <-chan struct{}
+	if h.waiter != nil {
+		ch, stop := h.waiter.WaitForMessage(roomID)
+		defer stop()
+		wake = ch
+	}
+
+	ctx := c.Request.Context()
+	messages, err := h.messageService.ListSince(ctx, roomID, userID, query.AfterID, pagination.FetchLimit(query.Limit))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	if len(messages) == 0 && wake != nil {
+		timeout := time.Duration(query.Timeout) * time.Second
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout + pollWriteMargin))
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-wake:
+			messages, err = h.messageService.ListSince(ctx, roomID, userID, query.AfterID, pagination.FetchLimit(query.Limit))
+			if err != nil {
+				response.Error(c, err)
+				return
+			}
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+	messages, hasMore := pagination.Trim(messages, query.Limit)
+
+	messageResponses := make([]*response.MessageResponse, len(messages))
+	for i, m := range messages {
+		messageResponses[i] = response.NewMessageResponse(m)
+	}
+
+	response.SuccessWithMeta(c, messageResponses, &response.Meta{
+		Limit:    query.Limit,
+		Returned: len(messageResponses),
+		HasMore:  hasMore,
+	})
+}
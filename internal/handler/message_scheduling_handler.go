@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+)
+
+// ListScheduledMessages godoc
+// @Summary 排程訊息列表
+// @Description 列出自己在聊天室中尚未送出的排程訊息
+// @Tags 訊息
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param room_id path string true "聊天室 ID"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.ScheduledMessageResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{room_id}/messages/scheduled [get]
+func (h *MessageHandler) ListScheduledMessages(c *gin.Context) {
+	roomID := c.Param("room_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	msgs, err := h.messageService.ListScheduledMessages(c.Request.Context(), roomID, userID, req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	msgs, hasMore := pagination.Trim(msgs, req.Limit)
+
+	msgResponses := make([]*response.ScheduledMessageResponse, len(msgs))
+	for i, m := range msgs {
+		msgResponses[i] = response.NewScheduledMessageResponse(m)
+	}
+
+	response.SuccessWithMeta(c, msgResponses, response.NewMeta(req.Limit, req.Offset(), len(msgResponses), hasMore))
+}
+
+// CancelScheduledMessage godoc
+// @Summary 取消排程訊息
+// @Description 取消自己尚未送出的排程訊息
+// @Tags 訊息
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param room_id path string true "聊天室 ID"
+// @Param id path string true "排程訊息 ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{room_id}/messages/scheduled/{id} [delete]
+func (h *MessageHandler) CancelScheduledMessage(c *gin.Context) {
+	roomID := c.Param("room_id")
+	id := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的排程訊息 ID")
+		return
+	}
+
+	if err := h.messageService.CancelScheduledMessage(c.Request.Context(), roomID, id, userID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已取消排程訊息", nil)
+}
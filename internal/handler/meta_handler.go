@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// MetaHandler serves the server limits clients check before sending
+type MetaHandler struct {
+	uploadSettings *service.UploadSettingsService
+}
+
+func NewMetaHandler(uploadSettings *service.UploadSettingsService) *MetaHandler {
+	return &MetaHandler{uploadSettings: uploadSettings}
+}
+
+// GetMeta godoc
+// @Summary 伺服器限制
+// @Description 取得目前生效的限制，例如各類上傳的大小上限（位元組）與允許的 MIME 類型，用戶端可在上傳前先行檢查
+// @Tags 系統
+// @Produce json
+// @Success 200 {object} response.Response{data=response.MetaResponse}
+// @Router /api/v1/meta [get]
+func (h *MetaHandler) GetMeta(c *gin.Context) {
+	response.Success(c, &response.MetaResponse{
+		Uploads: h.uploadSettings.Limits(c.Request.Context()),
+	})
+}
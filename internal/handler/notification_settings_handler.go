@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type NotificationSettingsHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationSettingsHandler(notificationService *service.NotificationService) *NotificationSettingsHandler {
+	return &NotificationSettingsHandler{notificationService: notificationService}
+}
+
+// GetSettings godoc
+// @Summary 獲取通知設定
+// @Description 列出目前用戶調整過通知的聊天室與私訊，未列出的對象維持預設（全部通知）
+// @Tags 用戶
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]response.NotificationSettingResponse}
+// @Router /api/v1/users/me/notification-settings [get]
+func (h *NotificationSettingsHandler) GetSettings(c *gin.Context) {
+	prefs, err := h.notificationService.ListPreferences(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := make([]*response.NotificationSettingResponse, len(prefs))
+	for i, p := range prefs {
+		result[i] = response.NewNotificationSettingResponse(p)
+	}
+
+	response.Success(c, result)
+}
+
+// UpdateSetting godoc
+// @Summary 更新通知設定
+// @Description 設定聊天室或私訊的通知：all 全部通知、mentions 只通知提及（僅限聊天室）、none 靜音
+// @Tags 用戶
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.UpdateNotificationSettingRequest true "通知設定"
+// @Success 200 {object} response.Response{data=response.NotificationSettingResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/users/me/notification-settings [put]
+func (h *NotificationSettingsHandler) UpdateSetting(c *gin.Context) {
+	var req request.UpdateNotificationSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	pref, err := h.notificationService.SetPreference(c.Request.Context(), &service.SetPreferenceInput{
+		UserID:     middleware.GetUserID(c),
+		TargetType: model.NotificationTargetType(req.TargetType),
+		TargetID:   req.TargetID,
+		Level:      model.NotificationLevel(req.Level),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已更新通知設定", response.NewNotificationSettingResponse(pref))
+}
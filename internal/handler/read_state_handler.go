@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type ReadStateHandler struct {
+	readStateService *service.ReadStateService
+}
+
+func NewReadStateHandler(readStateService *service.ReadStateService) *ReadStateHandler {
+	return &ReadStateHandler{readStateService: readStateService}
+}
+
+// GetReadStates godoc
+// @Summary 獲取已讀狀態
+// @Description 一次取得所有聊天室、群組對話的最後已讀時間與未讀數量，以及有未讀訊息的私訊；未列出的私訊沒有未讀。其他裝置標記已讀時會收到 read_state_updated 事件
+// @Tags 用戶
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=response.ReadStatesResponse}
+// @Router /api/v1/users/me/read-state [get]
+func (h *ReadStateHandler) GetReadStates(c *gin.Context) {
+	states, err := h.readStateService.List(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewReadStatesResponse(states))
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type ReportHandler struct {
+	reportService *service.ReportService
+}
+
+func NewReportHandler(reportService *service.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+
+// SubmitReport godoc
+// @Summary 檢舉
+// @Description 檢舉聊天室訊息或用戶，需選擇類別並可附上說明；處理進度以通知告知
+// @Tags 檢舉
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.SubmitReportRequest true "檢舉內容"
+// @Success 201 {object} response.Response{data=response.ReportResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/v1/reports [post]
+func (h *ReportHandler) SubmitReport(c *gin.Context) {
+	var req request.SubmitReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	report, err := h.reportService.Submit(c.Request.Context(), &service.SubmitReportInput{
+		ReporterID: middleware.GetUserID(c),
+		TargetType: model.ReportTargetType(req.TargetType),
+		TargetID:   req.TargetID,
+		Category:   model.ReportCategory(req.Category),
+		Note:       req.Note,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewReportResponse(report))
+}
+
+// ListReports godoc
+// @Summary 檢舉列表
+// @Description 依狀態、對象與類別列出檢舉，最早的在前（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "open、reviewing 或 resolved"
+// @Param target_type query string false "message 或 user"
+// @Param category query string false "spam、harassment、hate、sexual、violence、impersonation 或 other"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.AdminReportResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/reports [get]
+func (h *ReportHandler) ListReports(c *gin.Context) {
+	var query request.ReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "無效的篩選條件")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	filter := &repository.ReportFilter{
+		Status:     model.ReportStatus(query.Status),
+		TargetType: model.ReportTargetType(query.TargetType),
+		Category:   model.ReportCategory(query.Category),
+	}
+	reports, err := h.reportService.List(c.Request.Context(), filter, req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	reports, hasMore := pagination.Trim(reports, req.Limit)
+
+	result := make([]*response.AdminReportResponse, len(reports))
+	for i, r := range reports {
+		result[i] = response.NewAdminReportResponse(r)
+	}
+
+	response.SuccessWithMeta(c, result, response.NewMeta(req.Limit, req.Offset(), len(result), hasMore))
+}
+
+// GetReport godoc
+// @Summary 檢舉詳情
+// @Description 取得單筆檢舉，包含檢舉當下的訊息內容（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "檢舉 ID"
+// @Success 200 {object} response.Response{data=response.AdminReportResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/reports/{id} [get]
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的檢舉 ID")
+		return
+	}
+
+	report, err := h.reportService.Get(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewAdminReportResponse(report))
+}
+
+// UpdateReport godoc
+// @Summary 處理檢舉
+// @Description 變更檢舉狀態並加上內部備註：open 可改為 reviewing 或 resolved，reviewing 可退回 open 或改為 resolved，resolved 為最終狀態；改為 reviewing 或 resolved 時通知檢舉人（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "檢舉 ID"
+// @Param request body request.UpdateReportRequest true "處理狀態"
+// @Success 200 {object} response.Response{data=response.AdminReportResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/admin/reports/{id} [patch]
+func (h *ReportHandler) UpdateReport(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的檢舉 ID")
+		return
+	}
+
+	var req request.UpdateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	report, err := h.reportService.UpdateStatus(c.Request.Context(), id, model.ReportStatus(req.Status), req.Note, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewAdminReportResponse(report))
+}
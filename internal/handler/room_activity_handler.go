@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/utils"
+)
+
+// GetActivityHeatmap godoc
+// @Summary 聊天室活躍時段
+// @Description 依「星期 × 小時」統計最近幾週的訊息數，供顯示聊天室最活躍的時段。counts 第一列為星期日，每列 24 個小時，時間依 tz 指定的時區（預設 UTC）。系統訊息不列入計算
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param weeks query int false "統計週數（預設 4，最多 12）"
+// @Param tz query string false "IANA 時區，例如 Asia/Taipei"
+// @Success 200 {object} response.Response{data=response.RoomActivityHeatmapResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/activity-heatmap [get]
+func (h *RoomHandler) GetActivityHeatmap(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var query request.RoomActivityHeatmapQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "無效的查詢參數")
+		return
+	}
+
+	heatmap, err := h.roomService.GetActivityHeatmap(c.Request.Context(), roomID, userID, query.Weeks, query.TimeZone)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewRoomActivityHeatmapResponse(heatmap))
+}
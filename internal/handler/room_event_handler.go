@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/ical"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// calendarProdID identifies the server in exported calendars
+const calendarProdID = "-//Go Chat//Room Events//EN"
+
+// CreateEvent godoc
+// @Summary 建立聊天室活動
+// @Description 在聊天室建立活動，需有發言權限；建立者自動回覆參加。開始時間需在未來一年內，聊天室成員會收到 room_event_created 事件
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param request body request.CreateRoomEventRequest true "活動資料"
+// @Success 201 {object} response.Response{data=response.RoomEventResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/events [post]
+func (h *RoomHandler) CreateEvent(c *gin.Context) {
+	roomID := c.Param("id")
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.CreateRoomEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	input := &service.CreateRoomEventInput{
+		RoomID:      roomID,
+		UserID:      middleware.GetUserID(c),
+		Title:       req.Title,
+		Description: req.Description,
+		Location:    req.Location,
+	}
+	startsAt, err := time.Parse(time.RFC3339, req.StartsAt)
+	if err != nil {
+		response.BadRequest(c, "無效的開始時間")
+		return
+	}
+	input.StartsAt = startsAt
+	if req.EndsAt != "" {
+		endsAt, err := time.Parse(time.RFC3339, req.EndsAt)
+		if err != nil {
+			response.BadRequest(c, "無效的結束時間")
+			return
+		}
+		input.EndsAt = &endsAt
+	}
+
+	event, err := h.roomService.CreateEvent(c.Request.Context(), input)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewRoomEventResponse(event))
+}
+
+// ListEvents godoc
+// @Summary 聊天室活動列表
+// @Description 列出聊天室尚未結束的活動，依開始時間排序，附回覆人數與自己的回覆
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.RoomEventResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/events [get]
+func (h *RoomHandler) ListEvents(c *gin.Context) {
+	roomID := c.Param("id")
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	events, err := h.roomService.ListEvents(c.Request.Context(), roomID, middleware.GetUserID(c), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	events, hasMore := pagination.Trim(events, req.Limit)
+
+	eventResponses := make([]*response.RoomEventResponse, len(events))
+	for i, e := range events {
+		eventResponses[i] = response.NewRoomEventResponse(e)
+	}
+
+	response.SuccessWithMeta(c, eventResponses, response.NewMeta(req.Limit, req.Offset(), len(eventResponses), hasMore))
+}
+
+// GetEvent godoc
+// @Summary 聊天室活動詳情
+// @Description 獲取聊天室的單一活動
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param event_id path string true "活動 ID"
+// @Success 200 {object} response.Response{data=response.RoomEventResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/events/{event_id} [get]
+func (h *RoomHandler) GetEvent(c *gin.Context) {
+	roomID, eventID, ok := eventParams(c)
+	if !ok {
+		return
+	}
+
+	event, err := h.roomService.GetEvent(c.Request.Context(), roomID, eventID, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewRoomEventResponse(event))
+}
+
+// DeleteEvent godoc
+// @Summary 刪除聊天室活動
+// @Description 活動建立者或可管理聊天室的成員可刪除活動，聊天室成員會收到 room_event_deleted 事件
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param event_id path string true "活動 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/events/{event_id} [delete]
+func (h *RoomHandler) DeleteEvent(c *gin.Context) {
+	roomID, eventID, ok := eventParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.roomService.DeleteEvent(c.Request.Context(), roomID, eventID, middleware.GetUserID(c)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已刪除活動", nil)
+}
+
+// RSVPEvent godoc
+// @Summary 回覆聊天室活動
+// @Description 回覆是否參加尚未結束的活動：going 參加、maybe 可能參加、declined 不參加。回覆參加或可能參加的成員會在開始前收到提醒
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param event_id path string true "活動 ID"
+// @Param request body request.RSVPRequest true "回覆"
+// @Success 200 {object} response.Response{data=response.RoomEventResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/events/{event_id}/rsvp [put]
+func (h *RoomHandler) RSVPEvent(c *gin.Context) {
+	roomID, eventID, ok := eventParams(c)
+	if !ok {
+		return
+	}
+
+	var req request.RSVPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	event, err := h.roomService.RSVP(c.Request.Context(), roomID, eventID, middleware.GetUserID(c), model.RSVPStatus(req.Status))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewRoomEventResponse(event))
+}
+
+// CancelRSVP godoc
+// @Summary 取消活動回覆
+// @Description 撤回自己對活動的回覆
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param event_id path string true "活動 ID"
+// @Success 200 {object} response.Response{data=response.RoomEventResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/events/{event_id}/rsvp [delete]
+func (h *RoomHandler) CancelRSVP(c *gin.Context) {
+	roomID, eventID, ok := eventParams(c)
+	if !ok {
+		return
+	}
+
+	event, err := h.roomService.RSVP(c.Request.Context(), roomID, eventID, middleware.GetUserID(c), "")
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewRoomEventResponse(event))
+}
+
+// GetEventCalendar godoc
+// @Summary 聊天室行事曆訂閱
+// @Description 以 iCalendar 格式輸出聊天室近 30 天與未來的活動。需登入；開放訂閱的公開聊天室任何使用者皆可讀取，其他聊天室僅限成員。依 IP 限流
+// @Tags 聊天室
+// @Produce text/calendar
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Success 200 {file} file
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/v1/rooms/{id}/events.ics [get]
+func (h *RoomHandler) GetEventCalendar(c *gin.Context) {
+	roomID := c.Param("id")
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	calendar, err := h.roomService.GetCalendar(c.Request.Context(), roomID, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, h.buildCalendar(calendar)); err != nil {
+		response.InternalError(c, "產生行事曆失敗")
+		return
+	}
+
+	c.Data(http.StatusOK, ical.ContentType, buf.Bytes())
+}
+
+// buildCalendar maps a room's events onto calendar events, linking each to
+// the room in the web client
+func (h *RoomHandler) buildCalendar(rc *service.RoomCalendar) *ical.Calendar {
+	room := rc.Room
+	roomURL := h.siteURL + "/rooms/" + room.ID
+
+	cal := &ical.Calendar{
+		ProdID:      calendarProdID,
+		Name:        room.Name,
+		Description: room.GetDescription(),
+	}
+	for _, e := range rc.Events {
+		event := &ical.Event{
+			UID:         e.ID,
+			Summary:     e.Title,
+			Description: e.Description.String,
+			Location:    e.Location.String,
+			URL:         roomURL + "?event=" + e.ID,
+			Start:       e.StartsAt,
+			Created:     e.CreatedAt,
+			Updated:     e.UpdatedAt,
+		}
+		if e.EndsAt.Valid {
+			event.End = e.EndsAt.Time
+		}
+		cal.Events = append(cal.Events, event)
+	}
+	return cal
+}
+
+// eventParams validates the room and event IDs of an event route
+func eventParams(c *gin.Context) (roomID, eventID string, ok bool) {
+	roomID = c.Param("id")
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return "", "", false
+	}
+	eventID = c.Param("event_id")
+	if !utils.ValidateUUID(eventID) {
+		response.BadRequest(c, "無效的活動 ID")
+		return "", "", false
+	}
+	return roomID, eventID, true
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/pkg/feed"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// feedTitleLength is how many characters of a message make up an entry title
+const feedTitleLength = 80
+
+// FeedCache stores rendered feeds between requests
+type FeedCache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+}
+
+// SetFeedCache caches rendered room feeds for ttl
+func (h *RoomHandler) SetFeedCache(cache FeedCache, ttl time.Duration) {
+	h.feedCache = cache
+	h.feedCacheTTL = ttl
+}
+
+// SetSiteURL sets the public site address feed links point to
+func (h *RoomHandler) SetSiteURL(siteURL string) {
+	h.siteURL = strings.TrimRight(siteURL, "/")
+}
+
+// GetFeed godoc
+// @Summary 聊天室訂閱
+// @Description 以 RSS 或 Atom 格式輸出公開聊天室的最新訊息（公告聊天室即為公告），需聊天室開放訂閱。不需登入，依 IP 限流並快取
+// @Tags 聊天室
+// @Produce application/rss+xml
+// @Produce application/atom+xml
+// @Param id path string true "聊天室 ID"
+// @Param format query string false "rss（預設）或 atom"
+// @Success 200 {file} file
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/v1/rooms/{id}/feed [get]
+func (h *RoomHandler) GetFeed(c *gin.Context) {
+	roomID := c.Param("id")
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	format, err := feed.ParseFormat(c.Query("format"))
+	if err != nil {
+		response.BadRequest(c, "無效的訂閱格式，請使用 rss 或 atom")
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := "feed:room:" + roomID + ":" + string(format)
+	if h.feedCache != nil {
+		if cached, err := h.feedCache.Get(ctx, key); err == nil {
+			h.writeFeed(c, format, []byte(cached))
+			return
+		}
+	}
+
+	roomFeed, err := h.roomService.GetFeed(ctx, roomID, service.DefaultRoomFeedSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := feed.Write(&buf, format, h.buildFeed(roomFeed)); err != nil {
+		response.InternalError(c, "產生訂閱內容失敗")
+		return
+	}
+
+	if h.feedCache != nil && h.feedCacheTTL > 0 {
+		_ = h.feedCache.Set(ctx, key, buf.String(), h.feedCacheTTL)
+	}
+	h.writeFeed(c, format, buf.Bytes())
+}
+
+func (h *RoomHandler) writeFeed(c *gin.Context, format feed.Format, body []byte) {
+	if h.feedCacheTTL > 0 {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.feedCacheTTL.Seconds())))
+	}
+	c.Data(http.StatusOK, format.ContentType(), body)
+}
+
+// buildFeed maps a room and its messages onto feed entries, linking each
+// entry to its message in the web client
+func (h *RoomHandler) buildFeed(rf *service.RoomFeed) *feed.Feed {
+	room := rf.Room
+	roomURL := h.siteURL + "/rooms/" + room.ID
+
+	f := &feed.Feed{
+		ID:          "urn:uuid:" + room.ID,
+		Title:       room.Name,
+		Description: room.GetDescription(),
+		Link:        roomURL,
+		Updated:     room.UpdatedAt,
+	}
+	for _, msg := range rf.Messages {
+		if msg.CreatedAt.After(f.Updated) {
+			f.Updated = msg.CreatedAt
+		}
+		f.Entries = append(f.Entries, &feed.Entry{
+			ID:        "urn:uuid:" + msg.ID,
+			Title:     feedTitle(msg.Content),
+			Content:   msg.Content,
+			Author:    msg.GetUserDisplayName(),
+			Link:      roomURL + "?message=" + msg.ID,
+			Published: msg.CreatedAt,
+		})
+	}
+	return f
+}
+
+// feedTitle uses the first line of a message, shortened to feedTitleLength
+func feedTitle(content string) string {
+	title := strings.TrimSpace(content)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if utf8.RuneCountInString(title) > feedTitleLength {
+		title = string([]rune(title)[:feedTitleLength]) + "…"
+	}
+	return title
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type memoryFeedCache map[string]string
+
+func (m memoryFeedCache) Get(ctx context.Context, key string) (string, error) {
+	if v, ok := m[key]; ok {
+		return v, nil
+	}
+	return "", errors.New("miss")
+}
+
+func (m memoryFeedCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	m[key] = value.(string)
+	return nil
+}
+
+func TestFeedTitle(t *testing.T) {
+	long := strings.Repeat("公告", 50)
+	tests := map[string]string{
+		"  hello  ":           "hello",
+		"headline\nbody text": "headline",
+		long:                  string([]rune(long)[:feedTitleLength]) + "…",
+	}
+	for content, want := range tests {
+		if got := feedTitle(content); got != want {
+			t.Errorf("feedTitle(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestRoomHandler_GetFeed_Cached(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	roomID := "7f1c1e0c-3f5a-4a55-9d7c-2d7f6b0e8a11"
+	cache := memoryFeedCache{"feed:room:" + roomID + ":atom": "<feed/>"}
+
+	// A cache hit never reaches the service
+	handler := NewRoomHandler(nil)
+	handler.SetFeedCache(cache, time.Minute)
+
+	router := gin.New()
+	router.GET("/api/v1/rooms/:id/feed", handler.GetFeed)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/rooms/"+roomID+"/feed?format=atom", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Body.String() != "<feed/>" {
+		t.Errorf("Expected cached feed, got %q", w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("Unexpected cache control %q", cc)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/rooms/"+roomID+"/feed?format=json", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown format, got %d", w.Code)
+	}
+}
@@ -1,17 +1,27 @@
 package handler
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-demo/chat/internal/dto/request"
 	"github.com/go-demo/chat/internal/dto/response"
 	"github.com/go-demo/chat/internal/middleware"
 	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/pagination"
 	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/policy"
 	"github.com/go-demo/chat/internal/service"
 )
 
 type RoomHandler struct {
 	roomService *service.RoomService
+
+	feedCache    FeedCache
+	feedCacheTTL time.Duration
+	siteURL      string
 }
 
 func NewRoomHandler(roomService *service.RoomService) *RoomHandler {
@@ -60,6 +70,13 @@ func (h *RoomHandler) Create(c *gin.Context) {
 		Type:        roomType,
 		OwnerID:     userID,
 		MaxMembers:  req.MaxMembers,
+		ReadOnly:    req.ReadOnly,
+		MessageTTL:  req.MessageTTL,
+		FeedEnabled: req.FeedEnabled,
+		Language:    req.Language,
+		RateLimit:   req.RateLimit,
+
+		ModerationLevel: model.ModerationLevel(req.ModerationLevel),
 	})
 	if err != nil {
 		response.Error(c, err)
@@ -96,6 +113,9 @@ func (h *RoomHandler) GetByID(c *gin.Context) {
 
 	detail, err := h.roomService.GetByIDWithDetails(c.Request.Context(), roomID)
 	if err != nil {
+		if err == apperrors.ErrRoomNotFound && redirectMergedRoom(c, h.roomService, roomID) {
+			return
+		}
 		response.Error(c, err)
 		return
 	}
@@ -132,12 +152,25 @@ func (h *RoomHandler) Update(c *gin.Context) {
 		return
 	}
 
+	var moderationLevel *model.ModerationLevel
+	if req.ModerationLevel != nil {
+		level := model.ModerationLevel(*req.ModerationLevel)
+		moderationLevel = &level
+	}
+
 	_, err := h.roomService.Update(c.Request.Context(), &service.UpdateRoomInput{
 		RoomID:      roomID,
 		UserID:      userID,
 		Name:        req.Name,
 		Description: req.Description,
 		MaxMembers:  req.MaxMembers,
+		ReadOnly:    req.ReadOnly,
+		MessageTTL:  req.MessageTTL,
+		FeedEnabled: req.FeedEnabled,
+		Language:    req.Language,
+		RateLimit:   req.RateLimit,
+
+		ModerationLevel: moderationLevel,
 	})
 	if err != nil {
 		response.Error(c, err)
@@ -155,15 +188,16 @@ func (h *RoomHandler) Update(c *gin.Context) {
 
 // Delete godoc
 // @Summary 刪除聊天室
-// @Description 刪除聊天室（僅房主可操作）
+// @Description 排定刪除聊天室（僅房主可操作），成員會收到通知，等待期間內可取消
 // @Tags 聊天室
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "聊天室 ID"
-// @Success 204
+// @Success 200 {object} response.Response{data=response.RoomDeletionResponse}
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Router /api/v1/rooms/{id} [delete]
 func (h *RoomHandler) Delete(c *gin.Context) {
 	roomID := c.Param("id")
@@ -174,12 +208,192 @@ func (h *RoomHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.roomService.Delete(c.Request.Context(), roomID, userID); err != nil {
+	room, err := h.roomService.Delete(c.Request.Context(), roomID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "聊天室已排定刪除", response.NewRoomDeletionResponse(room))
+}
+
+// CancelDeletion godoc
+// @Summary 取消刪除聊天室
+// @Description 在等待期間內取消已排定的聊天室刪除（僅房主可操作）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Success 200 {object} response.Response{data=response.RoomDeletionResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/rooms/{id}/deletion/cancel [post]
+func (h *RoomHandler) CancelDeletion(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	room, err := h.roomService.CancelDeletion(c.Request.Context(), roomID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已取消刪除聊天室", response.NewRoomDeletionResponse(room))
+}
+
+// Archive godoc
+// @Summary 封存聊天室
+// @Description 封存聊天室：不再出現在聊天室列表與搜尋中，也無法加入或發送新訊息，成員仍可閱讀歷史訊息（僅房主可操作）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Success 200 {object} response.Response{data=response.RoomArchiveResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/rooms/{id}/archive [post]
+func (h *RoomHandler) Archive(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	room, err := h.roomService.Archive(c.Request.Context(), roomID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "聊天室已封存", response.NewRoomArchiveResponse(room))
+}
+
+// Unarchive godoc
+// @Summary 解除封存聊天室
+// @Description 解除聊天室的封存，恢復列表顯示與發言（僅房主可操作）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Success 200 {object} response.Response{data=response.RoomArchiveResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/rooms/{id}/archive [delete]
+func (h *RoomHandler) Unarchive(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	room, err := h.roomService.Unarchive(c.Request.Context(), roomID, userID)
+	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
-	response.NoContent(c)
+	response.SuccessWithMessage(c, "已解除封存聊天室", response.NewRoomArchiveResponse(room))
+}
+
+// GetPermissions godoc
+// @Summary 獲取聊天室權限
+// @Description 獲取當前用戶在聊天室中可執行的操作，可檢視成員者一併取得各角色的權限設定
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Success 200 {object} response.Response{data=response.RoomPermissionsResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/permissions [get]
+func (h *RoomHandler) GetPermissions(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	perms, err := h.roomService.Permissions(c.Request.Context(), roomID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	resp := response.NewRoomPermissionsResponse(roomID, perms)
+	if perms[policy.CanViewMembers] {
+		matrix, err := h.roomService.RoleMatrix(c.Request.Context(), roomID, userID)
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+		resp.WithRoles(matrix)
+	}
+
+	response.Success(c, resp)
+}
+
+// UpdatePermissions godoc
+// @Summary 設定聊天室角色權限
+// @Description 設定各角色在聊天室中的權限（發言、置頂、邀請、踢出、編輯聊天室），僅房主可操作；未列出的項目沿用預設
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param request body request.UpdateRoomPermissionsRequest true "角色權限"
+// @Success 200 {object} response.Response{data=response.RoomPermissionsResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/permissions [put]
+func (h *RoomHandler) UpdatePermissions(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.UpdateRoomPermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	grants := make(policy.Matrix, len(req.Roles))
+	for role, actions := range req.Roles {
+		grants[model.MemberRole(role)] = make(map[policy.Action]bool, len(actions))
+		for action, allowed := range actions {
+			grants[model.MemberRole(role)][policy.Action(action)] = allowed
+		}
+	}
+
+	matrix, err := h.roomService.UpdateRoleMatrix(c.Request.Context(), roomID, userID, grants)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "聊天室權限已更新", (&response.RoomPermissionsResponse{RoomID: roomID}).WithRoles(matrix))
 }
 
 // ListPublic godoc
@@ -191,6 +405,7 @@ func (h *RoomHandler) Delete(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.RoomResponse}
 // @Router /api/v1/rooms [get]
 func (h *RoomHandler) ListPublic(c *gin.Context) {
@@ -199,29 +414,67 @@ func (h *RoomHandler) ListPublic(c *gin.Context) {
 		req = request.PaginationRequest{Page: 1, Limit: 20}
 	}
 
-	rooms, err := h.roomService.ListPublic(c.Request.Context(), req.Limit, req.Offset())
+	rooms, err := h.roomService.ListPublic(c.Request.Context(), req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	rooms, hasMore := pagination.Trim(rooms, req.Limit)
 
 	roomResponses := make([]*response.RoomResponse, len(rooms))
 	for i, r := range rooms {
 		roomResponses[i] = response.NewRoomResponse(r)
 	}
 
-	response.Success(c, roomResponses)
+	response.SuccessWithMeta(c, roomResponses, response.NewMeta(req.Limit, req.Offset(), len(roomResponses), hasMore))
+}
+
+// ListAllRooms godoc
+// @Summary 列出所有聊天室
+// @Description 列出所有未刪除的聊天室，包含私人與已封存的聊天室，可依類型篩選（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "聊天室類型" Enums(public, private, direct)
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.RoomResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/rooms [get]
+func (h *RoomHandler) ListAllRooms(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	rooms, err := h.roomService.ListAll(c.Request.Context(), model.RoomType(c.Query("type")), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	rooms, hasMore := pagination.Trim(rooms, req.Limit)
+
+	roomResponses := make([]*response.RoomResponse, len(rooms))
+	for i, r := range rooms {
+		roomResponses[i] = response.NewRoomResponse(r)
+	}
+
+	response.SuccessWithMeta(c, roomResponses, response.NewMeta(req.Limit, req.Offset(), len(roomResponses), hasMore))
 }
 
 // ListMyRooms godoc
 // @Summary 獲取我的聊天室
-// @Description 獲取當前用戶加入的聊天室
+// @Description 獲取當前用戶加入的聊天室；已封存的聊天室需以 archived=true 另行列出
 // @Tags 聊天室
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param archived query bool false "只列出已封存的聊天室"
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.RoomResponse}
 // @Router /api/v1/rooms/me [get]
 func (h *RoomHandler) ListMyRooms(c *gin.Context) {
@@ -232,18 +485,24 @@ func (h *RoomHandler) ListMyRooms(c *gin.Context) {
 
 	userID := middleware.GetUserID(c)
 
-	rooms, err := h.roomService.ListByUserID(c.Request.Context(), userID, req.Limit, req.Offset())
+	list := h.roomService.ListByUserID
+	if archived, _ := strconv.ParseBool(c.Query("archived")); archived {
+		list = h.roomService.ListArchivedByUserID
+	}
+
+	rooms, err := list(c.Request.Context(), userID, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	rooms, hasMore := pagination.Trim(rooms, req.Limit)
 
 	roomResponses := make([]*response.RoomResponse, len(rooms))
 	for i, r := range rooms {
 		roomResponses[i] = response.NewRoomResponse(r)
 	}
 
-	response.Success(c, roomResponses)
+	response.SuccessWithMeta(c, roomResponses, response.NewMeta(req.Limit, req.Offset(), len(roomResponses), hasMore))
 }
 
 // Search godoc
@@ -256,6 +515,7 @@ func (h *RoomHandler) ListMyRooms(c *gin.Context) {
 // @Param q query string true "搜尋關鍵字"
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.RoomResponse}
 // @Failure 400 {object} response.Response
 // @Router /api/v1/rooms/search [get]
@@ -266,18 +526,19 @@ func (h *RoomHandler) Search(c *gin.Context) {
 		return
 	}
 
-	rooms, err := h.roomService.Search(c.Request.Context(), req.Query, req.Limit, req.Offset())
+	rooms, err := h.roomService.Search(c.Request.Context(), req.Query, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	rooms, hasMore := pagination.Trim(rooms, req.Limit)
 
 	roomResponses := make([]*response.RoomResponse, len(rooms))
 	for i, r := range rooms {
 		roomResponses[i] = response.NewRoomResponse(r)
 	}
 
-	response.Success(c, roomResponses)
+	response.SuccessWithMeta(c, roomResponses, response.NewMeta(req.Limit, req.Offset(), len(roomResponses), hasMore))
 }
 
 // Join godoc
@@ -416,7 +677,10 @@ func (h *RoomHandler) KickMember(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "聊天室 ID"
-// @Success 200 {object} response.Response{data=[]response.RoomMemberResponse}
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.RoomMemberResponse,meta=response.Meta}
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Router /api/v1/rooms/{id}/members [get]
@@ -429,18 +693,24 @@ func (h *RoomHandler) ListMembers(c *gin.Context) {
 		return
 	}
 
-	members, err := h.roomService.ListMembers(c.Request.Context(), roomID, userID)
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	members, err := h.roomService.ListMembers(c.Request.Context(), roomID, userID, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	members, hasMore := pagination.Trim(members, req.Limit)
 
 	memberResponses := make([]*response.RoomMemberResponse, len(members))
 	for i, m := range members {
 		memberResponses[i] = response.NewRoomMemberResponse(m)
 	}
 
-	response.Success(c, memberResponses)
+	response.SuccessWithMeta(c, memberResponses, response.NewMeta(req.Limit, req.Offset(), len(memberResponses), hasMore))
 }
 
 // PromoteMember godoc
@@ -474,6 +744,77 @@ func (h *RoomHandler) PromoteMember(c *gin.Context) {
 	response.SuccessWithMessage(c, "成員已被提升為管理員", nil)
 }
 
+// MuteMember godoc
+// @Summary 禁言成員
+// @Description 在指定分鐘數內禁止成員發言，到期自動解除（需要禁言權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param user_id path string true "用戶 ID"
+// @Param request body request.MuteMemberRequest true "禁言時間"
+// @Success 200 {object} response.Response{data=response.RoomMemberMuteResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/members/{user_id}/mute [post]
+func (h *RoomHandler) MuteMember(c *gin.Context) {
+	roomID := c.Param("id")
+	targetID := c.Param("user_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) || !utils.ValidateUUID(targetID) {
+		response.BadRequest(c, "無效的 ID")
+		return
+	}
+
+	var req request.MuteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	member, err := h.roomService.MuteMember(c.Request.Context(), roomID, userID, targetID, time.Duration(req.Minutes)*time.Minute)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "成員已被禁言", response.NewRoomMemberMuteResponse(member))
+}
+
+// UnmuteMember godoc
+// @Summary 解除禁言
+// @Description 提前解除成員的禁言（需要禁言權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param user_id path string true "用戶 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/members/{user_id}/mute [delete]
+func (h *RoomHandler) UnmuteMember(c *gin.Context) {
+	roomID := c.Param("id")
+	targetID := c.Param("user_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) || !utils.ValidateUUID(targetID) {
+		response.BadRequest(c, "無效的 ID")
+		return
+	}
+
+	if err := h.roomService.UnmuteMember(c.Request.Context(), roomID, userID, targetID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已解除禁言", nil)
+}
+
 // DemoteMember godoc
 // @Summary 降級管理員為成員
 // @Description 將管理員降級為普通成員（僅房主可操作）
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -52,10 +53,14 @@ func setupRoomHandlerTestIsolated(t *testing.T) (*gin.Engine, *service.RoomServi
 		rooms.GET("/:id", handler.GetByID)
 		rooms.PUT("/:id", handler.Update)
 		rooms.DELETE("/:id", handler.Delete)
+		rooms.POST("/:id/deletion/cancel", handler.CancelDeletion)
+		rooms.POST("/:id/archive", handler.Archive)
+		rooms.DELETE("/:id/archive", handler.Unarchive)
 		rooms.POST("/:id/join", handler.Join)
 		rooms.POST("/:id/leave", handler.Leave)
 		rooms.POST("/:id/invite", handler.InviteMember)
 		rooms.GET("/:id/members", handler.ListMembers)
+		rooms.GET("/:id/permissions", handler.GetPermissions)
 	}
 
 	prefix := repository.GenerateUniquePrefix()
@@ -149,6 +154,29 @@ func TestRoomHandler_ListPublic(t *testing.T) {
 	}
 }
 
+func TestRoomHandler_ListPublic_ClientCanceled(t *testing.T) {
+	router, _, jwtManager, db, prefix := setupRoomHandlerTestIsolated(t)
+	defer db.Close()
+	defer cleanupRoomHandlerTestByPrefix(t, db, prefix)
+
+	user := createUserForRoomHandlerTestIsolated(t, db, prefix, "alice")
+	tokenPair, _ := jwtManager.GenerateTokenPair(user.ID, user.Username)
+
+	// The client disconnects before the handler queries the database
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest("GET", "/api/v1/rooms", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != 499 {
+		t.Errorf("Expected status 499, got %d", w.Code)
+	}
+}
+
 func TestRoomHandler_GetByID(t *testing.T) {
 	router, roomService, jwtManager, db, prefix := setupRoomHandlerTestIsolated(t)
 	defer db.Close()
@@ -228,9 +256,120 @@ func TestRoomHandler_Delete(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	// 204 No Content 是刪除成功的標準 RESTful 回應碼
-	if w.Code != http.StatusNoContent {
-		t.Errorf("Expected status 204, got %d", w.Code)
+	// 刪除為兩階段：先排定刪除，等待期間內可取消
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestRoomHandler_CancelDeletion(t *testing.T) {
+	router, roomService, jwtManager, db, prefix := setupRoomHandlerTestIsolated(t)
+	defer db.Close()
+	defer cleanupRoomHandlerTestByPrefix(t, db, prefix)
+
+	user := createUserForRoomHandlerTestIsolated(t, db, prefix, "alice")
+
+	room, _ := roomService.Create(context.Background(), &service.CreateRoomInput{
+		Name:    prefix + "_To Delete",
+		Type:    model.RoomTypePublic,
+		OwnerID: user.ID,
+	})
+	_, _ = roomService.Delete(context.Background(), room.ID, user.ID)
+
+	tokenPair, _ := jwtManager.GenerateTokenPair(user.ID, user.Username)
+
+	req := httptest.NewRequest("POST", "/api/v1/rooms/"+room.ID+"/deletion/cancel", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Canceling again fails since nothing is scheduled
+	req = httptest.NewRequest("POST", "/api/v1/rooms/"+room.ID+"/deletion/cancel", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+	w = httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+}
+
+func TestRoomHandler_Archive(t *testing.T) {
+	router, roomService, jwtManager, db, prefix := setupRoomHandlerTestIsolated(t)
+	defer db.Close()
+	defer cleanupRoomHandlerTestByPrefix(t, db, prefix)
+
+	user := createUserForRoomHandlerTestIsolated(t, db, prefix, "alice")
+
+	room, _ := roomService.Create(context.Background(), &service.CreateRoomInput{
+		Name:    prefix + "_To Archive",
+		Type:    model.RoomTypePublic,
+		OwnerID: user.ID,
+	})
+
+	tokenPair, _ := jwtManager.GenerateTokenPair(user.ID, user.Username)
+
+	for _, tt := range []struct {
+		method string
+		status int
+	}{
+		{"POST", http.StatusOK},
+		{"POST", http.StatusConflict},
+		{"DELETE", http.StatusOK},
+		{"DELETE", http.StatusConflict},
+	} {
+		req := httptest.NewRequest(tt.method, "/api/v1/rooms/"+room.ID+"/archive", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s archive: expected status %d, got %d: %s", tt.method, tt.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRoomHandler_GetPermissions(t *testing.T) {
+	router, roomService, jwtManager, db, prefix := setupRoomHandlerTestIsolated(t)
+	defer db.Close()
+	defer cleanupRoomHandlerTestByPrefix(t, db, prefix)
+
+	user := createUserForRoomHandlerTestIsolated(t, db, prefix, "alice")
+
+	room, _ := roomService.Create(context.Background(), &service.CreateRoomInput{
+		Name:    prefix + "_Permissions",
+		Type:    model.RoomTypePublic,
+		OwnerID: user.ID,
+	})
+
+	tokenPair, _ := jwtManager.GenerateTokenPair(user.ID, user.Username)
+
+	req := httptest.NewRequest("GET", "/api/v1/rooms/"+room.ID+"/permissions", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data struct {
+			Permissions map[string]bool `json:"permissions"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+	if !resp.Data.Permissions["can_delete_room"] {
+		t.Error("Expected owner to have can_delete_room")
 	}
 }
 
@@ -389,6 +528,103 @@ func TestRoomHandler_ListMyRooms(t *testing.T) {
 	}
 }
 
+func TestRoomHandler_ListMyRooms_PaginationMeta(t *testing.T) {
+	router, roomService, jwtManager, db, prefix := setupRoomHandlerTestIsolated(t)
+	defer db.Close()
+	defer cleanupRoomHandlerTestByPrefix(t, db, prefix)
+
+	user := createUserForRoomHandlerTestIsolated(t, db, prefix, "alice")
+	for i := 1; i <= 3; i++ {
+		_, _ = roomService.Create(context.Background(), &service.CreateRoomInput{Name: fmt.Sprintf("%s_Room %d", prefix, i), Type: model.RoomTypePublic, OwnerID: user.ID})
+	}
+
+	tokenPair, _ := jwtManager.GenerateTokenPair(user.ID, user.Username)
+
+	get := func(url string) (data []interface{}, meta map[string]interface{}) {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return response["data"].([]interface{}), response["meta"].(map[string]interface{})
+	}
+
+	data, meta := get("/api/v1/rooms/me?limit=2")
+	if len(data) != 2 || meta["returned"].(float64) != 2 || meta["has_more"] != true {
+		t.Fatalf("Expected first page of 2 with more, got %d items, meta %v", len(data), meta)
+	}
+
+	cursor, _ := meta["next_cursor"].(string)
+	if cursor == "" {
+		t.Fatal("Expected next_cursor on first page")
+	}
+
+	data, meta = get("/api/v1/rooms/me?limit=2&cursor=" + cursor)
+	if len(data) != 1 || meta["has_more"] != false {
+		t.Errorf("Expected last page of 1 without more, got %d items, meta %v", len(data), meta)
+	}
+	if _, ok := meta["next_cursor"]; ok {
+		t.Error("Expected no next_cursor on last page")
+	}
+}
+
+func TestRoomHandler_ListMembers_PaginationMeta(t *testing.T) {
+	router, roomService, jwtManager, db, prefix := setupRoomHandlerTestIsolated(t)
+	defer db.Close()
+	defer cleanupRoomHandlerTestByPrefix(t, db, prefix)
+
+	owner := createUserForRoomHandlerTestIsolated(t, db, prefix, "owner")
+	room, err := roomService.Create(context.Background(), &service.CreateRoomInput{Name: prefix + "_Members", Type: model.RoomTypePublic, OwnerID: owner.ID})
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	for _, name := range []string{"bob", "carol"} {
+		member := createUserForRoomHandlerTestIsolated(t, db, prefix, name)
+		if err := roomService.Join(context.Background(), room.ID, member.ID); err != nil {
+			t.Fatalf("Failed to join room: %v", err)
+		}
+	}
+
+	tokenPair, _ := jwtManager.GenerateTokenPair(owner.ID, owner.Username)
+
+	get := func(url string) (data []interface{}, meta map[string]interface{}) {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return response["data"].([]interface{}), response["meta"].(map[string]interface{})
+	}
+
+	membersURL := "/api/v1/rooms/" + room.ID + "/members?limit=2"
+	data, meta := get(membersURL)
+	if len(data) != 2 || meta["returned"].(float64) != 2 || meta["has_more"] != true {
+		t.Fatalf("Expected first page of 2 with more, got %d items, meta %v", len(data), meta)
+	}
+
+	cursor, _ := meta["next_cursor"].(string)
+	if cursor == "" {
+		t.Fatal("Expected next_cursor on first page")
+	}
+
+	data, meta = get(membersURL + "&cursor=" + cursor)
+	if len(data) != 1 || meta["has_more"] != false {
+		t.Errorf("Expected last page of 1 without more, got %d items, meta %v", len(data), meta)
+	}
+}
+
 func TestRoomHandler_Unauthorized(t *testing.T) {
 	router, _, _, db, prefix := setupRoomHandlerTestIsolated(t)
 	defer db.Close()
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+)
+
+// CreateJoinRequest godoc
+// @Summary 申請加入聊天室
+// @Description 非成員申請加入私人聊天室，待管理員審核
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param request body request.CreateJoinRequestRequest false "申請資訊"
+// @Success 201 {object} response.Response{data=response.JoinRequestResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/rooms/{id}/join-requests [post]
+func (h *RoomHandler) CreateJoinRequest(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.CreateJoinRequestRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "請求格式錯誤")
+			return
+		}
+	}
+
+	joinRequest, err := h.roomService.RequestJoin(c.Request.Context(), roomID, userID, req.Message)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewJoinRequestResponse(joinRequest))
+}
+
+// ListJoinRequests godoc
+// @Summary 待審核加入申請
+// @Description 列出聊天室待審核的加入申請（需要管理員權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.JoinRequestResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/join-requests [get]
+func (h *RoomHandler) ListJoinRequests(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	requests, err := h.roomService.ListJoinRequests(c.Request.Context(), roomID, userID, req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	requests, hasMore := pagination.Trim(requests, req.Limit)
+
+	requestResponses := make([]*response.JoinRequestResponse, len(requests))
+	for i, r := range requests {
+		requestResponses[i] = response.NewJoinRequestWithUserResponse(r)
+	}
+
+	response.SuccessWithMeta(c, requestResponses, response.NewMeta(req.Limit, req.Offset(), len(requestResponses), hasMore))
+}
+
+// ApproveJoinRequest godoc
+// @Summary 核准加入申請
+// @Description 核准加入申請並將申請者加入聊天室（需要管理員權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param request_id path string true "申請 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/join-requests/{request_id}/approve [post]
+func (h *RoomHandler) ApproveJoinRequest(c *gin.Context) {
+	roomID := c.Param("id")
+	requestID := c.Param("request_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) || !utils.ValidateUUID(requestID) {
+		response.BadRequest(c, "無效的 ID")
+		return
+	}
+
+	if err := h.roomService.ApproveJoinRequest(c.Request.Context(), roomID, requestID, userID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已核准加入申請", nil)
+}
+
+// RejectJoinRequest godoc
+// @Summary 拒絕加入申請
+// @Description 拒絕聊天室加入申請（需要管理員權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param request_id path string true "申請 ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/join-requests/{request_id}/reject [post]
+func (h *RoomHandler) RejectJoinRequest(c *gin.Context) {
+	roomID := c.Param("id")
+	requestID := c.Param("request_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) || !utils.ValidateUUID(requestID) {
+		response.BadRequest(c, "無效的 ID")
+		return
+	}
+
+	if err := h.roomService.RejectJoinRequest(c.Request.Context(), roomID, requestID, userID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已拒絕加入申請", nil)
+}
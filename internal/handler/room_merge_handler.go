@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// MergeRoom godoc
+// @Summary 合併聊天室
+// @Description 將聊天室併入另一個聊天室：成員（去重）與可選的訊息紀錄分批搬移至目標聊天室，完成後舊聊天室連結轉址至目標（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "來源聊天室 ID"
+// @Param request body request.MergeRoomRequest true "合併設定"
+// @Success 201 {object} response.Response{data=response.RoomMergeResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/admin/rooms/{id}/merge [post]
+func (h *RoomHandler) MergeRoom(c *gin.Context) {
+	roomID := c.Param("id")
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.MergeRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	includeHistory := true
+	if req.IncludeHistory != nil {
+		includeHistory = *req.IncludeHistory
+	}
+
+	merge, err := h.roomService.MergeRoom(c.Request.Context(), &service.MergeRoomInput{
+		SourceRoomID:   roomID,
+		TargetRoomID:   req.TargetRoomID,
+		IncludeHistory: includeHistory,
+		MergedBy:       middleware.GetUserID(c),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewRoomMergeResponse(merge))
+}
+
+// ListRoomMerges godoc
+// @Summary 聊天室合併列表
+// @Description 列出聊天室合併作業與進度，最新的在前（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.RoomMergeResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/room-merges [get]
+func (h *RoomHandler) ListRoomMerges(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	merges, err := h.roomService.ListRoomMerges(c.Request.Context(), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	merges, hasMore := pagination.Trim(merges, req.Limit)
+
+	resp := make([]*response.RoomMergeResponse, len(merges))
+	for i, m := range merges {
+		resp[i] = response.NewRoomMergeResponse(m)
+	}
+
+	response.SuccessWithMeta(c, resp, response.NewMeta(req.Limit, req.Offset(), len(resp), hasMore))
+}
+
+// GetRoomMerge godoc
+// @Summary 聊天室合併進度
+// @Description 獲取聊天室合併作業的狀態與已搬移數量（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "合併作業 ID"
+// @Success 200 {object} response.Response{data=response.RoomMergeResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/room-merges/{id} [get]
+func (h *RoomHandler) GetRoomMerge(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的合併作業 ID")
+		return
+	}
+
+	merge, err := h.roomService.GetRoomMerge(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewRoomMergeResponse(merge))
+}
+
+// redirectMergedRoom answers a request for a room that was merged away with
+// a permanent redirect to the same path on the room it was merged into. It
+// reports whether it responded.
+func redirectMergedRoom(c *gin.Context, roomService *service.RoomService, roomID string) bool {
+	targetID, err := roomService.ResolveMergedRoom(c.Request.Context(), roomID)
+	if err != nil {
+		return false
+	}
+
+	location := *c.Request.URL
+	location.Path = strings.Replace(location.Path, "/"+roomID, "/"+targetID, 1)
+	c.Redirect(http.StatusPermanentRedirect, location.RequestURI())
+	return true
+}
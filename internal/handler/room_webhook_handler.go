@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// CreateWebhook godoc
+// @Summary 新增聊天室 Webhook
+// @Description 登記對外 webhook，訂閱的事件（message.created、member.joined、member.left）發生時會以 HTTP POST 送出，失敗時以指數退避重試。回應中的 secret 只會顯示這一次，用於驗證 X-Webhook-Signature：對「X-Webhook-Timestamp.內容」計算的 HMAC-SHA256（需要管理聊天室權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param request body request.CreateWebhookRequest true "Webhook 資訊"
+// @Success 201 {object} response.Response{data=response.WebhookResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/rooms/{id}/webhooks [post]
+func (h *RoomHandler) CreateWebhook(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	var req request.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	webhook, err := h.roomService.CreateWebhook(c.Request.Context(), &service.CreateWebhookInput{
+		RoomID: roomID,
+		UserID: userID,
+		URL:    req.URL,
+		Events: req.Events,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewCreatedWebhookResponse(webhook))
+}
+
+// ListWebhooks godoc
+// @Summary 聊天室 Webhook 列表
+// @Description 列出聊天室登記的 webhook，不含簽章金鑰（需要管理聊天室權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Success 200 {object} response.Response{data=[]response.WebhookResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/webhooks [get]
+func (h *RoomHandler) ListWebhooks(c *gin.Context) {
+	roomID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) {
+		response.BadRequest(c, "無效的聊天室 ID")
+		return
+	}
+
+	webhooks, err := h.roomService.ListWebhooks(c.Request.Context(), roomID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := make([]*response.WebhookResponse, len(webhooks))
+	for i, w := range webhooks {
+		result[i] = response.NewWebhookResponse(w)
+	}
+
+	response.Success(c, result)
+}
+
+// DeleteWebhook godoc
+// @Summary 刪除聊天室 Webhook
+// @Description 刪除 webhook 與其投遞紀錄，尚未送出的事件不再投遞（需要管理聊天室權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/webhooks/{webhook_id} [delete]
+func (h *RoomHandler) DeleteWebhook(c *gin.Context) {
+	roomID := c.Param("id")
+	webhookID := c.Param("webhook_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) || !utils.ValidateUUID(webhookID) {
+		response.BadRequest(c, "無效的 ID")
+		return
+	}
+
+	if err := h.roomService.DeleteWebhook(c.Request.Context(), roomID, webhookID, userID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已刪除 Webhook", nil)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary Webhook 投遞紀錄
+// @Description 列出 webhook 的投遞紀錄，最新的在前，包含狀態、嘗試次數與最近一次的回應狀態碼或錯誤（需要管理聊天室權限）
+// @Tags 聊天室
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "聊天室 ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.WebhookDeliveryResponse}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/rooms/{id}/webhooks/{webhook_id}/deliveries [get]
+func (h *RoomHandler) ListWebhookDeliveries(c *gin.Context) {
+	roomID := c.Param("id")
+	webhookID := c.Param("webhook_id")
+	userID := middleware.GetUserID(c)
+
+	if !utils.ValidateUUID(roomID) || !utils.ValidateUUID(webhookID) {
+		response.BadRequest(c, "無效的 ID")
+		return
+	}
+
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	deliveries, err := h.roomService.ListWebhookDeliveries(c.Request.Context(), roomID, webhookID, userID, req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	deliveries, hasMore := pagination.Trim(deliveries, req.Limit)
+
+	result := make([]*response.WebhookDeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		result[i] = response.NewWebhookDeliveryResponse(d)
+	}
+
+	response.SuccessWithMeta(c, result, response.NewMeta(req.Limit, req.Offset(), len(result), hasMore))
+}
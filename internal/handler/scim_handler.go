@@ -0,0 +1,572 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/scim"
+	"github.com/go-demo/chat/internal/service"
+	"github.com/google/uuid"
+)
+
+// SCIMHandler serves the SCIM 2.0 provisioning API. Responses use the SCIM
+// wire format rather than the usual response envelope.
+type SCIMHandler struct {
+	scimService *service.SCIMService
+	baseURL     string
+}
+
+// NewSCIMHandler creates a SCIM handler. siteURL is used to build the
+// meta.location of resources and may be empty.
+func NewSCIMHandler(scimService *service.SCIMService, siteURL string) *SCIMHandler {
+	return &SCIMHandler{
+		scimService: scimService,
+		baseURL:     strings.TrimRight(siteURL, "/") + "/scim/v2",
+	}
+}
+
+// scimJSON writes v with the SCIM media type
+func scimJSON(c *gin.Context, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	c.Data(status, scim.ContentType, body)
+}
+
+// scimError answers err as a SCIM error
+func scimError(c *gin.Context, err error) {
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) {
+			scimErr = scim.NewError(appErr.Code, "", appErr.Message)
+			if appErr.Code == http.StatusConflict {
+				scimErr.ScimType = scim.ErrorUniqueness
+			}
+		} else {
+			scimErr = scim.NewError(http.StatusInternalServerError, "", apperrors.ErrInternal.Message)
+		}
+	}
+
+	body, _ := json.Marshal(scimErr)
+	c.Data(scimErr.Status, scim.ContentType, body)
+}
+
+// bindSCIM decodes a SCIM request body
+func bindSCIM(c *gin.Context, v interface{}) bool {
+	if err := json.NewDecoder(c.Request.Body).Decode(v); err != nil {
+		scimError(c, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidSyntax, "請求格式錯誤"))
+		return false
+	}
+	return true
+}
+
+// scimID reads the resource ID from the path. IDs that are not UUIDs
+// cannot exist.
+func scimID(c *gin.Context, notFound error) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		scimError(c, notFound)
+		return "", false
+	}
+	return id, true
+}
+
+// scimListQuery reads the filter, startIndex and count query parameters.
+// A missing count is reported as -1 so the service applies its default.
+func scimListQuery(c *gin.Context) (scim.Filter, int, int, bool) {
+	filter, err := scim.ParseFilter(c.Query("filter"))
+	if err != nil {
+		scimError(c, err)
+		return nil, 0, 0, false
+	}
+
+	startIndex, count := 1, -1
+	if v := c.Query("startIndex"); v != "" {
+		if startIndex, err = strconv.Atoi(v); err != nil {
+			scimError(c, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidValue, "startIndex 必須為整數"))
+			return nil, 0, 0, false
+		}
+	}
+	if v := c.Query("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil || count < 0 {
+			scimError(c, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidValue, "count 必須為非負整數"))
+			return nil, 0, 0, false
+		}
+	}
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	return filter, startIndex, count, true
+}
+
+func (h *SCIMHandler) newUser(user *model.SCIMUser, groups []*model.SCIMGroup) *scim.User {
+	active := user.Active
+	out := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          user.ID,
+		ExternalID:  user.ExternalID.String,
+		UserName:    user.Username,
+		DisplayName: user.DisplayName.String,
+		Emails:      []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     h.baseURL + "/Users/" + user.ID,
+		},
+	}
+	if user.DisplayName.Valid {
+		out.Name = &scim.Name{Formatted: user.DisplayName.String}
+	}
+	for _, group := range groups {
+		out.Groups = append(out.Groups, scim.GroupRef{
+			Value:   group.ID,
+			Ref:     h.baseURL + "/Groups/" + group.ID,
+			Display: group.DisplayName,
+		})
+	}
+	return out
+}
+
+func (h *SCIMHandler) newGroup(group *model.SCIMGroup) *scim.Group {
+	out := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID.String,
+		DisplayName: group.DisplayName,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+			Location:     h.baseURL + "/Groups/" + group.ID,
+		},
+	}
+	for _, member := range group.Members {
+		out.Members = append(out.Members, scim.Member{
+			Value:   member.UserID,
+			Ref:     h.baseURL + "/Users/" + member.UserID,
+			Display: member.Username,
+		})
+	}
+	return out
+}
+
+func scimList(c *gin.Context, resources interface{}, total, startIndex, n int) {
+	scimJSON(c, http.StatusOK, &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: n,
+		Resources:    resources,
+	})
+}
+
+// ListUsers godoc
+// @Summary 列出 SCIM 使用者
+// @Description 列出帳號，支援以 userName、emails.value、externalId 或 id 的 eq 篩選，以 and 連接
+// @Tags SCIM
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param filter query string false "篩選條件，例如 userName eq \"alice\""
+// @Param startIndex query int false "起始位置（從 1 起算）"
+// @Param count query int false "每頁筆數（預設 100，最多 200）"
+// @Success 200 {object} scim.ListResponse
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	filter, startIndex, count, ok := scimListQuery(c)
+	if !ok {
+		return
+	}
+
+	users, total, err := h.scimService.ListUsers(c.Request.Context(), filter, startIndex, count)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	resources := make([]*scim.User, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, h.newUser(user, nil))
+	}
+	scimList(c, resources, total, startIndex, len(resources))
+}
+
+// CreateUser godoc
+// @Summary 建立 SCIM 使用者
+// @Description 由身分提供者佈建帳號。密碼為隨機產生，使用者須透過單一登入
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param request body scim.User true "使用者"
+// @Success 201 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var in scim.User
+	if !bindSCIM(c, &in) {
+		return
+	}
+
+	user, err := h.scimService.CreateUser(c.Request.Context(), &in)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	c.Header("Location", h.baseURL+"/Users/"+user.ID)
+	scimJSON(c, http.StatusCreated, h.newUser(user, nil))
+}
+
+// GetUser godoc
+// @Summary 取得 SCIM 使用者
+// @Tags SCIM
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param id path string true "使用者 ID"
+// @Success 200 {object} scim.User
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	id, ok := scimID(c, apperrors.ErrUserNotFound)
+	if !ok {
+		return
+	}
+
+	user, groups, err := h.scimService.GetUser(c.Request.Context(), id)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, h.newUser(user, groups))
+}
+
+// ReplaceUser godoc
+// @Summary 取代 SCIM 使用者
+// @Description 更新顯示名稱、Email、externalId 與啟用狀態。userName 不可變更
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param id path string true "使用者 ID"
+// @Param request body scim.User true "使用者"
+// @Success 200 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Users/{id} [put]
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	id, ok := scimID(c, apperrors.ErrUserNotFound)
+	if !ok {
+		return
+	}
+	var in scim.User
+	if !bindSCIM(c, &in) {
+		return
+	}
+
+	user, err := h.scimService.ReplaceUser(c.Request.Context(), id, &in)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, h.newUser(user, nil))
+}
+
+// PatchUser godoc
+// @Summary 修改 SCIM 使用者
+// @Description 以 PATCH 操作修改帳號，將 active 設為 false 會停用帳號並中斷其連線
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param id path string true "使用者 ID"
+// @Param request body scim.PatchRequest true "PATCH 操作"
+// @Success 200 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	id, ok := scimID(c, apperrors.ErrUserNotFound)
+	if !ok {
+		return
+	}
+	var req scim.PatchRequest
+	if !bindSCIM(c, &req) {
+		return
+	}
+
+	user, err := h.scimService.PatchUser(c.Request.Context(), id, req.Operations)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, h.newUser(user, nil))
+}
+
+// DeleteUser godoc
+// @Summary 移除 SCIM 使用者
+// @Description 停用帳號並自 SCIM 中移除，帳號與其訊息仍保留
+// @Tags SCIM
+// @Security BearerAuth
+// @Param id path string true "使用者 ID"
+// @Success 204
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Users/{id} [delete]
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	id, ok := scimID(c, apperrors.ErrUserNotFound)
+	if !ok {
+		return
+	}
+
+	if err := h.scimService.DeleteUser(c.Request.Context(), id); err != nil {
+		scimError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups godoc
+// @Summary 列出 SCIM 群組
+// @Description 列出群組，支援以 displayName、externalId 或 id 的 eq 篩選
+// @Tags SCIM
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param filter query string false "篩選條件，例如 displayName eq \"engineering\""
+// @Param startIndex query int false "起始位置（從 1 起算）"
+// @Param count query int false "每頁筆數（預設 100，最多 200）"
+// @Param excludedAttributes query string false "設為 members 時不回傳成員"
+// @Success 200 {object} scim.ListResponse
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/Groups [get]
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	filter, startIndex, count, ok := scimListQuery(c)
+	if !ok {
+		return
+	}
+	withMembers := !strings.Contains(strings.ToLower(c.Query("excludedAttributes")), "members")
+
+	groups, total, err := h.scimService.ListGroups(c.Request.Context(), filter, startIndex, count, withMembers)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	resources := make([]*scim.Group, 0, len(groups))
+	for _, group := range groups {
+		resources = append(resources, h.newGroup(group))
+	}
+	scimList(c, resources, total, startIndex, len(resources))
+}
+
+// CreateGroup godoc
+// @Summary 建立 SCIM 群組
+// @Description 建立群組，成員會依 sso.group_rooms 的對應規則加入聊天室
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param request body scim.Group true "群組"
+// @Success 201 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Groups [post]
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var in scim.Group
+	if !bindSCIM(c, &in) {
+		return
+	}
+
+	group, err := h.scimService.CreateGroup(c.Request.Context(), &in)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	c.Header("Location", h.baseURL+"/Groups/"+group.ID)
+	scimJSON(c, http.StatusCreated, h.newGroup(group))
+}
+
+// GetGroup godoc
+// @Summary 取得 SCIM 群組
+// @Tags SCIM
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param id path string true "群組 ID"
+// @Success 200 {object} scim.Group
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [get]
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	id, ok := scimID(c, service.ErrSCIMGroupNotFound)
+	if !ok {
+		return
+	}
+
+	group, err := h.scimService.GetGroup(c.Request.Context(), id)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, h.newGroup(group))
+}
+
+// ReplaceGroup godoc
+// @Summary 取代 SCIM 群組
+// @Description 更新群組名稱與成員，並同步成員的聊天室
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param id path string true "群組 ID"
+// @Param request body scim.Group true "群組"
+// @Success 200 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [put]
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	id, ok := scimID(c, service.ErrSCIMGroupNotFound)
+	if !ok {
+		return
+	}
+	var in scim.Group
+	if !bindSCIM(c, &in) {
+		return
+	}
+
+	group, err := h.scimService.ReplaceGroup(c.Request.Context(), id, &in)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, h.newGroup(group))
+}
+
+// PatchGroup godoc
+// @Summary 修改 SCIM 群組
+// @Description 以 PATCH 操作新增、移除或取代成員，或修改群組名稱
+// @Tags SCIM
+// @Accept application/scim+json
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Param id path string true "群組 ID"
+// @Param request body scim.PatchRequest true "PATCH 操作"
+// @Success 200 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [patch]
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	id, ok := scimID(c, service.ErrSCIMGroupNotFound)
+	if !ok {
+		return
+	}
+	var req scim.PatchRequest
+	if !bindSCIM(c, &req) {
+		return
+	}
+
+	group, err := h.scimService.PatchGroup(c.Request.Context(), id, req.Operations)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, h.newGroup(group))
+}
+
+// DeleteGroup godoc
+// @Summary 刪除 SCIM 群組
+// @Description 刪除群組，並移除成員經由此群組取得的聊天室
+// @Tags SCIM
+// @Security BearerAuth
+// @Param id path string true "群組 ID"
+// @Success 204
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [delete]
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	id, ok := scimID(c, service.ErrSCIMGroupNotFound)
+	if !ok {
+		return
+	}
+
+	if err := h.scimService.DeleteGroup(c.Request.Context(), id); err != nil {
+		scimError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ServiceProviderConfig godoc
+// @Summary SCIM 服務設定
+// @Description 回報支援的 SCIM 功能：PATCH 與篩選，不支援批次、排序與變更密碼
+// @Tags SCIM
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	supported := func(ok bool) gin.H { return gin.H{"supported": ok} }
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{scim.SchemaServiceProviderConfig},
+		"patch":          supported(true),
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": service.SCIMMaxPageSize},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "以 scim.token 設定的權杖驗證",
+			"primary":     true,
+		}},
+		"meta": gin.H{"resourceType": "ServiceProviderConfig", "location": h.baseURL + "/ServiceProviderConfig"},
+	})
+}
+
+// ResourceTypes godoc
+// @Summary SCIM 資源類型
+// @Description 列出支援的資源類型：User 與 Group
+// @Tags SCIM
+// @Produce application/scim+json
+// @Security BearerAuth
+// @Success 200 {object} scim.ListResponse
+// @Router /scim/v2/ResourceTypes [get]
+func (h *SCIMHandler) ResourceTypes(c *gin.Context) {
+	resourceType := func(name, endpoint, schema string) gin.H {
+		return gin.H{
+			"schemas":  []string{scim.SchemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta":     gin.H{"resourceType": "ResourceType", "location": h.baseURL + "/ResourceTypes/" + name},
+		}
+	}
+	types := []gin.H{
+		resourceType("User", "/Users", scim.SchemaUser),
+		resourceType("Group", "/Groups", scim.SchemaGroup),
+	}
+	scimList(c, types, len(types), 1, len(types))
+}
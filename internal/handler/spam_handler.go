@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/pkg/pagination"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type SpamHandler struct {
+	spamService *service.SpamSweepService
+}
+
+func NewSpamHandler(spamService *service.SpamSweepService) *SpamHandler {
+	return &SpamHandler{spamService: spamService}
+}
+
+// ListFlags godoc
+// @Summary 垃圾帳號標記列表
+// @Description 列出垃圾帳號掃描標記或自動停權的帳號、分數與命中的訊號，最新的在前（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "頁碼" default(1)
+// @Param limit query int false "每頁數量" default(20)
+// @Success 200 {object} response.Response{data=[]response.SpamFlagResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/moderation/spam [get]
+func (h *SpamHandler) ListFlags(c *gin.Context) {
+	var req request.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		req = request.PaginationRequest{Page: 1, Limit: 20}
+	}
+
+	flags, err := h.spamService.ListFlags(c.Request.Context(), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	flags, hasMore := pagination.Trim(flags, req.Limit)
+	result := make([]*response.SpamFlagResponse, len(flags))
+	for i, flag := range flags {
+		result[i] = response.NewSpamFlagResponse(flag)
+	}
+
+	response.SuccessWithMeta(c, result, response.NewMeta(req.Limit, req.Offset(), len(result), hasMore))
+}
+
+// GetStats godoc
+// @Summary 垃圾帳號掃描統計
+// @Description 回報累計標記與自動停權的帳號數、近 24 小時標記數，以及最近一次掃描的結果（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=model.SpamStats}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/moderation/spam/stats [get]
+func (h *SpamHandler) GetStats(c *gin.Context) {
+	stats, err := h.spamService.Stats(c.Request.Context())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, stats)
+}
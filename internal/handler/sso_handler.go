@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// SSOHandler serves enterprise single sign-on
+type SSOHandler struct {
+	ssoService  *service.SSOService
+	callbackURL string
+}
+
+// NewSSOHandler creates an SSO handler. SAML sign-ins end with a redirect to
+// callbackURL carrying either a one-time code or an error message.
+func NewSSOHandler(ssoService *service.SSOService, callbackURL string) *SSOHandler {
+	return &SSOHandler{
+		ssoService:  ssoService,
+		callbackURL: callbackURL,
+	}
+}
+
+// GetInfo godoc
+// @Summary 登入方式
+// @Description 回傳目前的登入模式（local、ldap 或 saml）、是否允許本機帳號登入，以及 SAML 登入入口
+// @Tags 認證
+// @Produce json
+// @Success 200 {object} response.Response{data=response.SSOInfoResponse}
+// @Router /api/v1/auth/sso [get]
+func (h *SSOHandler) GetInfo(c *gin.Context) {
+	if h.ssoService == nil {
+		response.Success(c, &response.SSOInfoResponse{Mode: service.SSOModeLocal, LocalLogin: true})
+		return
+	}
+
+	info := &response.SSOInfoResponse{
+		Mode:       h.ssoService.Mode(),
+		LocalLogin: h.ssoService.LocalLogin(),
+	}
+	if info.Mode == service.SSOModeSAML {
+		info.LoginURL = "/api/v1/auth/sso/saml/login"
+	}
+	response.Success(c, info)
+}
+
+// SAMLLogin godoc
+// @Summary SAML 登入
+// @Description 將瀏覽器導向身分提供者進行登入
+// @Tags 認證
+// @Success 302
+// @Failure 404 {object} response.Response
+// @Router /api/v1/auth/sso/saml/login [get]
+func (h *SSOHandler) SAMLLogin(c *gin.Context) {
+	if h.ssoService == nil {
+		response.Error(c, service.ErrSSONotEnabled)
+		return
+	}
+
+	location, err := h.ssoService.SAMLLoginURL(c.Request.Context())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// SAMLMetadata godoc
+// @Summary SAML 服務提供者中繼資料
+// @Description 供身分提供者匯入的 SP 中繼資料
+// @Tags 認證
+// @Produce application/samlmetadata+xml
+// @Success 200 {file} file
+// @Failure 404 {object} response.Response
+// @Router /api/v1/auth/sso/saml/metadata [get]
+func (h *SSOHandler) SAMLMetadata(c *gin.Context) {
+	if h.ssoService == nil {
+		response.Error(c, service.ErrSSONotEnabled)
+		return
+	}
+
+	metadata, err := h.ssoService.SAMLMetadata()
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// SAMLACS godoc
+// @Summary SAML 登入回呼
+// @Description 身分提供者以 HTTP-POST 送回的登入結果。驗證後導向前端回呼頁，網址帶有一次性的 code 或 error
+// @Tags 認證
+// @Accept x-www-form-urlencoded
+// @Param SAMLResponse formData string true "SAML 回應"
+// @Param RelayState formData string true "登入請求狀態"
+// @Success 302
+// @Failure 404 {object} response.Response
+// @Router /api/v1/auth/sso/saml/acs [post]
+func (h *SSOHandler) SAMLACS(c *gin.Context) {
+	if h.ssoService == nil {
+		response.Error(c, service.ErrSSONotEnabled)
+		return
+	}
+
+	code, err := h.ssoService.CompleteSAML(c.Request.Context(), c.PostForm("SAMLResponse"), c.PostForm("RelayState"))
+	query := url.Values{}
+	if err != nil {
+		query.Set("error", apperrors.GetMessage(err))
+	} else {
+		query.Set("code", code)
+	}
+	c.Redirect(http.StatusSeeOther, h.callbackURL+"?"+query.Encode())
+}
+
+// Exchange godoc
+// @Summary 交換登入代碼
+// @Description 以 SAML 登入回呼取得的一次性代碼換取 Token，代碼一分鐘內有效且只能使用一次
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Param request body request.SSOExchangeRequest true "登入代碼"
+// @Success 200 {object} response.Response{data=response.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/auth/sso/exchange [post]
+func (h *SSOHandler) Exchange(c *gin.Context) {
+	if h.ssoService == nil {
+		response.Error(c, service.ErrSSONotEnabled)
+		return
+	}
+
+	var req request.SSOExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	result, err := h.ssoService.ExchangeCode(c.Request.Context(), req.Code, &service.LoginInput{
+		DeviceID:   req.DeviceID,
+		DeviceName: req.DeviceName,
+		Client:     clientInfo(c),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, &response.AuthResponse{
+		User:  response.NewUserResponse(result.User, true),
+		Token: newTokenResponse(result.TokenPair),
+	})
+}
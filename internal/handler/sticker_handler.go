@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+type StickerHandler struct {
+	stickerService *service.StickerService
+}
+
+func NewStickerHandler(stickerService *service.StickerService) *StickerHandler {
+	return &StickerHandler{
+		stickerService: stickerService,
+	}
+}
+
+// ListPacks godoc
+// @Summary 貼圖包列表
+// @Description 列出可使用的貼圖包與其貼圖
+// @Tags 貼圖
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]response.StickerPackResponse}
+// @Router /api/v1/stickers/packs [get]
+func (h *StickerHandler) ListPacks(c *gin.Context) {
+	h.listPacks(c, false)
+}
+
+// GetPack godoc
+// @Summary 取得貼圖包
+// @Description 取得可使用的貼圖包與其貼圖
+// @Tags 貼圖
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "貼圖包 ID"
+// @Success 200 {object} response.Response{data=response.StickerPackResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/stickers/packs/{id} [get]
+func (h *StickerHandler) GetPack(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的貼圖包 ID")
+		return
+	}
+
+	pack, err := h.stickerService.GetPack(c.Request.Context(), id, false)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewStickerPackResponse(pack))
+}
+
+// ListAllPacks godoc
+// @Summary 所有貼圖包
+// @Description 列出所有貼圖包，包含已下架的（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]response.StickerPackResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/stickers/packs [get]
+func (h *StickerHandler) ListAllPacks(c *gin.Context) {
+	h.listPacks(c, true)
+}
+
+func (h *StickerHandler) listPacks(c *gin.Context, includeInactive bool) {
+	packs, err := h.stickerService.ListPacks(c.Request.Context(), includeInactive)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	packResponses := make([]*response.StickerPackResponse, len(packs))
+	for i, p := range packs {
+		packResponses[i] = response.NewStickerPackResponse(p)
+	}
+
+	response.Success(c, packResponses)
+}
+
+// CreatePack godoc
+// @Summary 新增貼圖包
+// @Description 新增貼圖包，貼圖依陣列順序排列（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.CreateStickerPackRequest true "貼圖包資訊"
+// @Success 201 {object} response.Response{data=response.StickerPackResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/stickers/packs [post]
+func (h *StickerHandler) CreatePack(c *gin.Context) {
+	var req request.CreateStickerPackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	input := &service.CreateStickerPackInput{
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   middleware.GetUserID(c),
+	}
+	for _, s := range req.Stickers {
+		input.Stickers = append(input.Stickers, service.StickerInput{
+			Name:     s.Name,
+			ImageURL: s.ImageURL,
+			Width:    s.Width,
+			Height:   s.Height,
+		})
+	}
+
+	pack, err := h.stickerService.CreatePack(c.Request.Context(), input)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, response.NewStickerPackResponse(pack))
+}
+
+// UpdatePack godoc
+// @Summary 更新貼圖包
+// @Description 修改貼圖包名稱、說明或上下架狀態；下架後已送出的貼圖仍會顯示（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "貼圖包 ID"
+// @Param request body request.UpdateStickerPackRequest true "更新內容"
+// @Success 200 {object} response.Response{data=response.StickerPackResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/stickers/packs/{id} [patch]
+func (h *StickerHandler) UpdatePack(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的貼圖包 ID")
+		return
+	}
+
+	var req request.UpdateStickerPackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	pack, err := h.stickerService.UpdatePack(c.Request.Context(), id, &service.UpdateStickerPackInput{
+		Name:        req.Name,
+		Description: req.Description,
+		IsActive:    req.IsActive,
+		UpdatedBy:   middleware.GetUserID(c),
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewStickerPackResponse(pack))
+}
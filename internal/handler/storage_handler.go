@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// SetStorageQuota enables per-user storage quotas on image and file
+// uploads
+func (h *UploadHandler) SetStorageQuota(quota *service.StorageQuotaService) {
+	h.quota = quota
+}
+
+// GetStorageUsage godoc
+// @Summary 取得儲存空間用量
+// @Description 取得目前用戶上傳的圖片與檔案所佔空間、配額與剩餘空間，進行中的可續傳上傳以宣告的大小計入，頭像不計入
+// @Tags 上傳
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=response.StorageUsageResponse}
+// @Router /api/v1/users/me/storage [get]
+func (h *UploadHandler) GetStorageUsage(c *gin.Context) {
+	usage, err := h.quota.Usage(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewStorageUsageResponse(usage, h.quota.Quota()))
+}
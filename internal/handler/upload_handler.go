@@ -1,68 +1,69 @@
 package handler
 
 import (
+	"database/sql"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-demo/chat/internal/dto/response"
 	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/service"
 	"github.com/google/uuid"
 )
 
-const (
-	MaxFileSize     = 10 << 20 // 10 MB
-	MaxImageSize    = 5 << 20  // 5 MB
-	UploadDir       = "./uploads"
-	ImageSubDir     = "images"
-	FileSubDir      = "files"
-	AvatarSubDir    = "avatars"
-)
+const UploadDir = "./uploads"
 
-var allowedImageTypes = map[string]bool{
-	"image/jpeg": true,
-	"image/png":  true,
-	"image/gif":  true,
-	"image/webp": true,
-}
+type UploadHandler struct {
+	baseURL    string
+	settings   *service.UploadSettingsService
+	sessions   *service.UploadSessionService
+	moderation *service.ImageModerationService
 
-var allowedFileTypes = map[string]bool{
-	"application/pdf":    true,
-	"application/msword": true,
-	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
-	"application/vnd.ms-excel": true,
-	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": true,
-	"text/plain":      true,
-	"application/zip": true,
-}
+	// Records images and files so messages can attach them
+	attachments *service.AttachmentService
 
-type UploadHandler struct {
-	baseURL string
+	// Caps the bytes of images and files each user stores
+	quota *service.StorageQuotaService
+
+	// Sets uploaded avatars on the profile and removes the replaced files
+	avatars *service.AvatarService
 }
 
 func NewUploadHandler(baseURL string) *UploadHandler {
-	// Ensure upload directories exist
-	dirs := []string{
-		filepath.Join(UploadDir, ImageSubDir),
-		filepath.Join(UploadDir, FileSubDir),
-		filepath.Join(UploadDir, AvatarSubDir),
-	}
-	for _, dir := range dirs {
-		_ = os.MkdirAll(dir, 0755)
+	h := &UploadHandler{
+		baseURL: baseURL,
 	}
+	h.ensureDirs()
+	return h
+}
 
-	return &UploadHandler{
-		baseURL: baseURL,
+// SetUploadSettings sets the service that resolves the size and type
+// limits. Without it the built-in defaults apply.
+func (h *UploadHandler) SetUploadSettings(settings *service.UploadSettingsService) {
+	h.settings = settings
+	h.ensureDirs()
+}
+
+// ensureDirs creates the category directories uploads are stored under
+func (h *UploadHandler) ensureDirs() {
+	defaults := h.settings.Defaults()
+	for _, limit := range []model.UploadLimit{defaults.Image, defaults.File, defaults.Avatar} {
+		_ = os.MkdirAll(filepath.Join(UploadDir, limit.SubDir), 0755)
 	}
 }
 
 // UploadImage godoc
 // @Summary 上傳圖片
-// @Description 上傳圖片檔案
+// @Description 上傳圖片檔案，大小與格式限制見 GET /api/v1/meta。回傳的 id 可於發送訊息時以 attachment_ids 附加，未附加的圖片會在保留期限後刪除
 // @Tags 上傳
 // @Accept multipart/form-data
 // @Produce json
@@ -70,6 +71,7 @@ func NewUploadHandler(baseURL string) *UploadHandler {
 // @Param file formData file true "圖片檔案"
 // @Success 200 {object} response.Response{data=map[string]string}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response "儲存空間不足，details 含 quota、used 與 requested"
 // @Failure 413 {object} response.Response
 // @Router /api/v1/upload/image [post]
 func (h *UploadHandler) UploadImage(c *gin.Context) {
@@ -80,23 +82,23 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// Check file size
-	if header.Size > MaxImageSize {
-		response.ErrorWithStatus(c, 413, "圖片大小不能超過 5MB")
+	// Check file size and content type
+	limits := h.settings.Limits(c.Request.Context())
+	contentType := header.Header.Get("Content-Type")
+	if status, msg := checkUpload(limits, model.UploadCategoryImage, contentType, header.Size); status != 0 {
+		response.ErrorWithStatus(c, status, msg)
 		return
 	}
-
-	// Check content type
-	contentType := header.Header.Get("Content-Type")
-	if !allowedImageTypes[contentType] {
-		response.BadRequest(c, "不支援的圖片格式，請上傳 JPEG、PNG、GIF 或 WebP 格式")
+	if err := h.quota.Check(c.Request.Context(), middleware.GetUserID(c), model.UploadCategoryImage, header.Size); err != nil {
+		response.Error(c, err)
 		return
 	}
 
 	// Generate unique filename
 	ext := filepath.Ext(header.Filename)
 	filename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
-	filePath := filepath.Join(UploadDir, ImageSubDir, filename)
+	subDir := limits.Image.SubDir
+	filePath := filepath.Join(UploadDir, subDir, filename)
 
 	// Save file
 	if err := h.saveFile(file, filePath); err != nil {
@@ -104,19 +106,40 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 		return
 	}
 
-	fileURL := fmt.Sprintf("%s/uploads/%s/%s", h.baseURL, ImageSubDir, filename)
+	fileURL := fmt.Sprintf("%s/uploads/%s/%s", h.baseURL, subDir, filename)
+
+	nsfw, ok := h.scanImage(c, filePath, fileURL, contentType)
+	if !ok {
+		return
+	}
+
+	upload := &model.Upload{
+		Category:    model.UploadCategoryImage,
+		FileName:    header.Filename,
+		FileURL:     fileURL,
+		FilePath:    filePath,
+		ContentType: contentType,
+		Size:        header.Size,
+	}
+	uploadID, ok := h.recordUpload(c, upload)
+	if !ok {
+		return
+	}
 
 	response.Success(c, gin.H{
-		"url":      fileURL,
-		"filename": header.Filename,
-		"size":     header.Size,
-		"type":     contentType,
+		"id":          uploadID,
+		"url":         fileURL,
+		"filename":    header.Filename,
+		"size":        header.Size,
+		"type":        contentType,
+		"is_nsfw":     nsfw,
+		"scan_status": upload.ScanStatus, // pending until the antivirus scan ends, see upload_scanned
 	})
 }
 
 // UploadFile godoc
 // @Summary 上傳檔案
-// @Description 上傳一般檔案
+// @Description 上傳一般檔案，大小與格式限制見 GET /api/v1/meta。回傳的 id 可於發送訊息時以 attachment_ids 附加，未附加的檔案會在保留期限後刪除
 // @Tags 上傳
 // @Accept multipart/form-data
 // @Produce json
@@ -124,6 +147,7 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 // @Param file formData file true "檔案"
 // @Success 200 {object} response.Response{data=map[string]string}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response "儲存空間不足，details 含 quota、used 與 requested"
 // @Failure 413 {object} response.Response
 // @Router /api/v1/upload/file [post]
 func (h *UploadHandler) UploadFile(c *gin.Context) {
@@ -134,16 +158,15 @@ func (h *UploadHandler) UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// Check file size
-	if header.Size > MaxFileSize {
-		response.ErrorWithStatus(c, 413, "檔案大小不能超過 10MB")
+	// Check file size and content type
+	limits := h.settings.Limits(c.Request.Context())
+	contentType := header.Header.Get("Content-Type")
+	if status, msg := checkUpload(limits, model.UploadCategoryFile, contentType, header.Size); status != 0 {
+		response.ErrorWithStatus(c, status, msg)
 		return
 	}
-
-	// Check content type
-	contentType := header.Header.Get("Content-Type")
-	if !allowedFileTypes[contentType] && !allowedImageTypes[contentType] {
-		response.BadRequest(c, "不支援的檔案格式")
+	if err := h.quota.Check(c.Request.Context(), middleware.GetUserID(c), model.UploadCategoryFile, header.Size); err != nil {
+		response.Error(c, err)
 		return
 	}
 
@@ -154,7 +177,8 @@ func (h *UploadHandler) UploadFile(c *gin.Context) {
 	if len(filename) > 100 {
 		filename = fmt.Sprintf("%s%s", uuid.New().String(), ext)
 	}
-	filePath := filepath.Join(UploadDir, FileSubDir, filename)
+	subDir := limits.File.SubDir
+	filePath := filepath.Join(UploadDir, subDir, filename)
 
 	// Save file
 	if err := h.saveFile(file, filePath); err != nil {
@@ -162,25 +186,52 @@ func (h *UploadHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	fileURL := fmt.Sprintf("%s/uploads/%s/%s", h.baseURL, FileSubDir, filename)
+	fileURL := fmt.Sprintf("%s/uploads/%s/%s", h.baseURL, subDir, filename)
+
+	nsfw := false
+	if limits.Image.Allows(contentType) {
+		var ok bool
+		if nsfw, ok = h.scanImage(c, filePath, fileURL, contentType); !ok {
+			return
+		}
+	}
+
+	upload := &model.Upload{
+		Category:    model.UploadCategoryFile,
+		FileName:    header.Filename,
+		FileURL:     fileURL,
+		FilePath:    filePath,
+		ContentType: contentType,
+		Size:        header.Size,
+	}
+	uploadID, ok := h.recordUpload(c, upload)
+	if !ok {
+		return
+	}
 
 	response.Success(c, gin.H{
-		"url":      fileURL,
-		"filename": header.Filename,
-		"size":     header.Size,
-		"type":     contentType,
+		"id":          uploadID,
+		"url":         fileURL,
+		"filename":    header.Filename,
+		"size":        header.Size,
+		"type":        contentType,
+		"is_nsfw":     nsfw,
+		"scan_status": upload.ScanStatus, // pending until the antivirus scan ends, see upload_scanned
 	})
 }
 
 // UploadAvatar godoc
 // @Summary 上傳頭像
-// @Description 上傳用戶頭像
+// @Description 上傳 JPEG、PNG 或 GIF 頭像並設為目前用戶的頭像，大小限制見 GET /api/v1/meta。可以 crop_x、crop_y 與 crop_size 指定保留的正方形範圍（像素，自左上角起算），未指定時取置中的正方形。頭像會產生 32、64、128 與 256px 四種尺寸，avatar_url 為 256px，原頭像的檔案會一併刪除
 // @Tags 上傳
 // @Accept multipart/form-data
 // @Produce json
 // @Security BearerAuth
 // @Param file formData file true "頭像圖片"
-// @Success 200 {object} response.Response{data=map[string]string}
+// @Param crop_x formData int false "裁切範圍左上角 X"
+// @Param crop_y formData int false "裁切範圍左上角 Y"
+// @Param crop_size formData int false "裁切範圍邊長"
+// @Success 200 {object} response.Response{data=map[string]interface{}}
 // @Failure 400 {object} response.Response
 // @Failure 413 {object} response.Response
 // @Router /api/v1/upload/avatar [post]
@@ -194,40 +245,143 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// Check file size (2MB for avatars)
-	if header.Size > 2<<20 {
-		response.ErrorWithStatus(c, 413, "頭像大小不能超過 2MB")
+	// Check file size and content type
+	limits := h.settings.Limits(c.Request.Context())
+	contentType := header.Header.Get("Content-Type")
+	if status, msg := checkUpload(limits, model.UploadCategoryAvatar, contentType, header.Size); status != 0 {
+		response.ErrorWithStatus(c, status, msg)
 		return
 	}
 
-	// Check content type
-	contentType := header.Header.Get("Content-Type")
-	if !allowedImageTypes[contentType] {
-		response.BadRequest(c, "不支援的圖片格式，請上傳 JPEG、PNG、GIF 或 WebP 格式")
+	crop, ok := avatarCrop(c)
+	if !ok {
+		response.BadRequest(c, "裁切參數須同時提供 crop_x、crop_y 與 crop_size 的整數值")
 		return
 	}
 
-	// Generate filename using user ID
-	ext := filepath.Ext(header.Filename)
-	filename := fmt.Sprintf("%s_%d%s", userID, time.Now().Unix(), ext)
-	filePath := filepath.Join(UploadDir, AvatarSubDir, filename)
+	data, err := io.ReadAll(io.LimitReader(file, header.Size))
+	if err != nil {
+		response.BadRequest(c, "無法讀取檔案")
+		return
+	}
 
-	// Save file
-	if err := h.saveFile(file, filePath); err != nil {
+	subDir := limits.Avatar.SubDir
+	renditions, err := service.RenderAvatar(&service.RenderAvatarInput{
+		UserID:    userID,
+		Image:     data,
+		Crop:      crop,
+		Dir:       filepath.Join(UploadDir, subDir),
+		URLPrefix: fmt.Sprintf("%s/uploads/%s", h.baseURL, subDir),
+	})
+	if err != nil {
+		if apperrors.GetHTTPStatus(err) == http.StatusBadRequest {
+			response.Error(c, err)
+			return
+		}
 		response.InternalError(c, "儲存檔案失敗")
 		return
 	}
 
-	fileURL := fmt.Sprintf("%s/uploads/%s/%s", h.baseURL, AvatarSubDir, filename)
+	nsfw, ok := h.scanImage(c, renditions.Path(), renditions.URL(), renditions.ContentType)
+	if !ok {
+		renditions.Remove()
+		return
+	}
+
+	if h.avatars != nil {
+		if err := h.avatars.Replace(c.Request.Context(), userID, renditions); err != nil {
+			renditions.Remove()
+			response.Error(c, err)
+			return
+		}
+	}
 
+	urls := make(map[string]string, len(renditions.URLs))
+	for size, url := range renditions.URLs {
+		urls[strconv.Itoa(size)] = url
+	}
 	response.Success(c, gin.H{
-		"url":      fileURL,
+		"url":      renditions.URL(),
+		"urls":     urls, // by size in pixels
 		"filename": header.Filename,
 		"size":     header.Size,
-		"type":     contentType,
+		"type":     renditions.ContentType,
+		"is_nsfw":  nsfw,
 	})
 }
 
+// SetAvatars makes uploaded avatars replace the user's avatar right away
+func (h *UploadHandler) SetAvatars(avatars *service.AvatarService) {
+	h.avatars = avatars
+}
+
+// avatarCrop reads the optional crop square of an avatar upload; it reports
+// false when the fields are incomplete or not integers
+func avatarCrop(c *gin.Context) (*service.AvatarCrop, bool) {
+	fields := []string{c.PostForm("crop_x"), c.PostForm("crop_y"), c.PostForm("crop_size")}
+	if fields[0] == "" && fields[1] == "" && fields[2] == "" {
+		return nil, true
+	}
+
+	var values [3]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		values[i] = n
+	}
+	return &service.AvatarCrop{X: values[0], Y: values[1], Size: values[2]}, true
+}
+
+// SetAttachments enables recording uploaded images and files, which
+// messages attach by the returned ID
+func (h *UploadHandler) SetAttachments(attachments *service.AttachmentService) {
+	h.attachments = attachments
+}
+
+// recordUpload records an uploaded image or file for the current user and
+// returns its ID, empty when uploads are not recorded; upload is filled in
+// with the record. It writes the error response itself on failure.
+func (h *UploadHandler) recordUpload(c *gin.Context, upload *model.Upload) (string, bool) {
+	if h.attachments == nil {
+		return "", true
+	}
+
+	upload.UserID = sql.NullString{String: middleware.GetUserID(c), Valid: true}
+	if err := h.attachments.RecordUpload(c.Request.Context(), upload); err != nil {
+		response.Error(c, err)
+		return "", false
+	}
+	return upload.ID, true
+}
+
+// SetImageModeration enables scanning of uploaded images
+func (h *UploadHandler) SetImageModeration(moderation *service.ImageModerationService) {
+	h.moderation = moderation
+}
+
+// scanImage runs a stored image through moderation and reports whether
+// clients should blur it. It writes the error response itself when the
+// image is rejected.
+func (h *UploadHandler) scanImage(c *gin.Context, path, url, contentType string) (bool, bool) {
+	if h.moderation == nil {
+		return false, true
+	}
+
+	asset, err := h.moderation.Scan(c.Request.Context(), &service.ScanImageInput{
+		UserID:      middleware.GetUserID(c),
+		FileURL:     url,
+		FilePath:    path,
+		ContentType: contentType,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return false, false
+	}
+	return asset != nil && asset.IsNSFW(), true
+}
+
 func (h *UploadHandler) saveFile(file io.Reader, path string) error {
 	out, err := os.Create(path)
 	if err != nil {
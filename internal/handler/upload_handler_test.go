@@ -2,12 +2,17 @@ package handler
 
 import (
 	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -279,7 +284,94 @@ func TestUploadHandler_UploadAvatar(t *testing.T) {
 
 	tokenPair, _ := jwtManager.GenerateTokenPair("user-123", "alice")
 
-	// Create a small image
+	body, contentType := createMultipartRequest(t, "file", "avatar.png", testPNG(t, 300, 200), "image/png")
+
+	req := httptest.NewRequest("POST", "/api/v1/upload/avatar", body)
+	req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data struct {
+			URL  string            `json:"url"`
+			URLs map[string]string `json:"urls"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(resp.Data.URLs) != 4 || resp.Data.URL != resp.Data.URLs["256"] {
+		t.Fatalf("Expected four renditions with the largest as url, got %+v", resp.Data)
+	}
+	for size, url := range resp.Data.URLs {
+		f, err := os.Open(filepath.Join(UploadDir, "avatars", filepath.Base(url)))
+		if err != nil {
+			t.Fatalf("Expected the %spx rendition stored: %v", size, err)
+		}
+		config, err := png.DecodeConfig(f)
+		f.Close()
+		if err != nil || strconv.Itoa(config.Width) != size || config.Height != config.Width {
+			t.Errorf("Expected a %spx square, got %+v, %v", size, config, err)
+		}
+	}
+}
+
+func TestUploadHandler_UploadAvatar_Crop(t *testing.T) {
+	router, _, jwtManager := setupUploadHandlerTest(t)
+	defer cleanupUploadTest(t)
+
+	tokenPair, _ := jwtManager.GenerateTokenPair("user-123", "alice")
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   int
+	}{
+		{"Square inside the image", map[string]string{"crop_x": "100", "crop_y": "0", "crop_size": "200"}, http.StatusOK},
+		{"Square outside the image", map[string]string{"crop_x": "150", "crop_y": "0", "crop_size": "200"}, http.StatusBadRequest},
+		{"Incomplete crop", map[string]string{"crop_x": "0"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			for k, v := range tt.fields {
+				_ = writer.WriteField(k, v)
+			}
+			part, _ := writer.CreatePart(map[string][]string{
+				"Content-Disposition": {`form-data; name="file"; filename="avatar.png"`},
+				"Content-Type":        {"image/png"},
+			})
+			_, _ = part.Write(testPNG(t, 300, 200))
+			writer.Close()
+
+			req := httptest.NewRequest("POST", "/api/v1/upload/avatar", body)
+			req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUploadHandler_UploadAvatar_NotAnImage(t *testing.T) {
+	router, _, jwtManager := setupUploadHandlerTest(t)
+	defer cleanupUploadTest(t)
+
+	tokenPair, _ := jwtManager.GenerateTokenPair("user-123", "alice")
+
+	// JPEG magic bytes followed by noise
 	imageContent := []byte{0xFF, 0xD8, 0xFF, 0xE0}
 	for i := 0; i < 500; i++ {
 		imageContent = append(imageContent, byte(i%256))
@@ -294,9 +386,25 @@ func TestUploadHandler_UploadAvatar(t *testing.T) {
 
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an undecodable avatar, got %d", w.Code)
+	}
+}
+
+// testPNG encodes a width by height opaque PNG
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
 	}
+	return buf.Bytes()
 }
 
 func TestUploadHandler_UploadAvatar_TooLarge(t *testing.T) {
@@ -367,9 +475,9 @@ func TestUploadHandler_DirectoryCreation(t *testing.T) {
 
 	// Verify directories exist
 	dirs := []string{
-		filepath.Join(UploadDir, ImageSubDir),
-		filepath.Join(UploadDir, FileSubDir),
-		filepath.Join(UploadDir, AvatarSubDir),
+		filepath.Join(UploadDir, "images"),
+		filepath.Join(UploadDir, "files"),
+		filepath.Join(UploadDir, "avatars"),
 	}
 
 	for _, dir := range dirs {
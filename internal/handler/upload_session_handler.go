@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// UploadOffsetHeader carries the byte offset of a chunk, and of the next
+// expected chunk in responses
+const UploadOffsetHeader = "Upload-Offset"
+
+// SetSessionService enables resumable uploads
+func (h *UploadHandler) SetSessionService(sessions *service.UploadSessionService) {
+	h.sessions = sessions
+}
+
+// CreateUploadSession godoc
+// @Summary 建立可續傳上傳
+// @Description 宣告檔案類別、名稱、類型與大小後分段上傳，中斷後可由已接收的位置續傳
+// @Tags 上傳
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.CreateUploadSessionRequest true "上傳資訊"
+// @Success 201 {object} response.Response{data=response.UploadSessionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response "儲存空間不足，details 含 quota、used 與 requested"
+// @Failure 413 {object} response.Response
+// @Router /api/v1/uploads [post]
+func (h *UploadHandler) CreateUploadSession(c *gin.Context) {
+	var req request.CreateUploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	category := model.UploadCategory(req.Category)
+	limits := h.settings.Limits(c.Request.Context())
+	if status, msg := checkUpload(limits, category, req.ContentType, req.Size); status != 0 {
+		response.ErrorWithStatus(c, status, msg)
+		return
+	}
+	if err := h.quota.Check(c.Request.Context(), middleware.GetUserID(c), category, req.Size); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	session, err := h.sessions.Create(c.Request.Context(), &service.CreateUploadInput{
+		UserID:      middleware.GetUserID(c),
+		Category:    category,
+		FileName:    req.FileName,
+		ContentType: req.ContentType,
+		TotalSize:   req.Size,
+	})
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header(UploadOffsetHeader, "0")
+	response.Created(c, response.NewUploadSessionResponse(session, ""))
+}
+
+// ListPendingUploads godoc
+// @Summary 列出未完成的上傳
+// @Description 列出目前用戶尚未完成且未過期的可續傳上傳，含進度與到期時間，供重新啟動後續傳或放棄
+// @Tags 上傳
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]response.UploadSessionResponse}
+// @Router /api/v1/uploads/pending [get]
+func (h *UploadHandler) ListPendingUploads(c *gin.Context) {
+	sessions, err := h.sessions.ListPending(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := make([]*response.UploadSessionResponse, len(sessions))
+	for i, session := range sessions {
+		result[i] = response.NewUploadSessionResponse(session, "")
+	}
+
+	response.Success(c, result)
+}
+
+// GetUploadSession godoc
+// @Summary 查詢上傳進度
+// @Description 查詢可續傳上傳已接收的位元組數，下一段應由此位置開始
+// @Tags 上傳
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "上傳 ID"
+// @Success 200 {object} response.Response{data=response.UploadSessionResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/v1/uploads/{id} [get]
+func (h *UploadHandler) GetUploadSession(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的上傳 ID")
+		return
+	}
+
+	session, err := h.sessions.Get(c.Request.Context(), id, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header(UploadOffsetHeader, strconv.FormatInt(session.ReceivedSize, 10))
+	response.Success(c, response.NewUploadSessionResponse(session, h.sessionURL(session)))
+}
+
+// UploadChunk godoc
+// @Summary 上傳分段
+// @Description 以請求主體送出一段檔案內容，Upload-Offset 標頭需等於目前已接收的位元組數。收齊後檔案即完成並回傳網址，圖片與檔案另回傳可於發送訊息時附加的 upload_id
+// @Tags 上傳
+// @Accept application/octet-stream
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "上傳 ID"
+// @Param Upload-Offset header int true "此段的起始位置"
+// @Success 200 {object} response.Response{data=response.UploadSessionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response "位置不符，details.offset 為目前進度"
+// @Failure 413 {object} response.Response
+// @Router /api/v1/uploads/{id} [patch]
+func (h *UploadHandler) UploadChunk(c *gin.Context) {
+	id := c.Param("id")
+	userID := middleware.GetUserID(c)
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的上傳 ID")
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader(UploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		response.BadRequest(c, "缺少或無效的 Upload-Offset 標頭")
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := h.sessions.AppendChunk(ctx, id, userID, offset, c.Request.Body)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	nsfw := false
+	if session.ReceivedSize == session.TotalSize {
+		completing := !session.IsComplete()
+		defaults := h.settings.Defaults()
+		subDir, name := uploadTarget(session, &defaults)
+		session, err = h.sessions.Complete(ctx, session, filepath.Join(UploadDir, subDir), name)
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+
+		// Scan once, when the file is first stored
+		if completing && h.settings.Limits(ctx).Image.Allows(session.ContentType) {
+			var ok bool
+			path := filepath.Join(UploadDir, subDir, session.StoredName.String)
+			if nsfw, ok = h.scanImage(c, path, h.sessionURL(session), session.ContentType); !ok {
+				return
+			}
+		}
+	}
+
+	resp := response.NewUploadSessionResponse(session, h.sessionURL(session))
+	resp.IsNSFW = nsfw
+	if resp.URL != "" && session.Category != model.UploadCategoryAvatar {
+		// Recorded under the session ID; recording it again is harmless
+		defaults := h.settings.Defaults()
+		subDir, _ := uploadTarget(session, &defaults)
+		upload := &model.Upload{
+			ID:          session.ID,
+			Category:    session.Category,
+			FileName:    session.FileName,
+			FileURL:     resp.URL,
+			FilePath:    filepath.Join(UploadDir, subDir, session.StoredName.String),
+			ContentType: session.ContentType,
+			Size:        session.TotalSize,
+		}
+		var ok bool
+		if resp.UploadID, ok = h.recordUpload(c, upload); !ok {
+			return
+		}
+		resp.ScanStatus = string(upload.ScanStatus)
+	}
+	c.Header(UploadOffsetHeader, strconv.FormatInt(session.ReceivedSize, 10))
+	response.Success(c, resp)
+}
+
+// AbandonUpload godoc
+// @Summary 放棄上傳
+// @Description 取消可續傳上傳並刪除已接收的內容
+// @Tags 上傳
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "上傳 ID"
+// @Success 204
+// @Failure 404 {object} response.Response
+// @Router /api/v1/uploads/{id} [delete]
+func (h *UploadHandler) AbandonUpload(c *gin.Context) {
+	id := c.Param("id")
+	if !utils.ValidateUUID(id) {
+		response.BadRequest(c, "無效的上傳 ID")
+		return
+	}
+
+	if err := h.sessions.Abandon(c.Request.Context(), id, middleware.GetUserID(c)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// checkUpload applies a category's size and type limits, for the
+// single-request and resumable uploads alike. It returns a zero status when
+// the upload is allowed.
+func checkUpload(limits *model.UploadLimits, category model.UploadCategory, contentType string, size int64) (int, string) {
+	limit := limits.For(category)
+	noun := "檔案"
+	switch category {
+	case model.UploadCategoryImage:
+		noun = "圖片"
+	case model.UploadCategoryAvatar:
+		noun = "頭像"
+	}
+
+	if size > limit.MaxSize {
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("%s大小不能超過 %s", noun, formatUploadSize(limit.MaxSize))
+	}
+	if !limit.Allows(contentType) {
+		if category == model.UploadCategoryImage || category == model.UploadCategoryAvatar {
+			return http.StatusBadRequest, "不支援的圖片格式，請上傳 " + strings.Join(limit.AllowedTypes, "、") + " 格式"
+		}
+		return http.StatusBadRequest, "不支援的檔案格式"
+	}
+	return 0, ""
+}
+
+// formatUploadSize renders a size limit in the largest whole unit
+func formatUploadSize(size int64) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", size>>10)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
+
+// uploadTarget picks the directory and file name a completed upload is
+// stored under, named like the single-request uploads
+func uploadTarget(session *model.UploadSession, limits *model.UploadLimits) (string, string) {
+	ext := filepath.Ext(session.FileName)
+	created := session.CreatedAt.Unix()
+
+	switch session.Category {
+	case model.UploadCategoryImage:
+		return limits.Image.SubDir, fmt.Sprintf("%s_%d%s", session.ID, created, ext)
+	case model.UploadCategoryAvatar:
+		return limits.Avatar.SubDir, fmt.Sprintf("%s_%d%s", session.UserID, created, ext)
+	default:
+		safeName := strings.ReplaceAll(filepath.Base(session.FileName), " ", "_")
+		name := fmt.Sprintf("%s_%s", session.ID[:8], safeName)
+		if len(name) > 100 {
+			name = session.ID + ext
+		}
+		return limits.File.SubDir, name
+	}
+}
+
+// sessionURL returns a completed upload's address, or empty while in progress
+func (h *UploadHandler) sessionURL(session *model.UploadSession) string {
+	if !session.IsComplete() || !session.StoredName.Valid {
+		return ""
+	}
+	defaults := h.settings.Defaults()
+	subDir, _ := uploadTarget(session, &defaults)
+	return fmt.Sprintf("%s/uploads/%s/%s", h.baseURL, subDir, session.StoredName.String)
+}
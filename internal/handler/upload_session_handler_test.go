@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+func TestCheckUpload(t *testing.T) {
+	limits := model.DefaultUploadLimits()
+	tests := []struct {
+		category    model.UploadCategory
+		contentType string
+		size        int64
+		want        int
+	}{
+		{model.UploadCategoryImage, "image/png", limits.Image.MaxSize, 0},
+		{model.UploadCategoryImage, "image/png", limits.Image.MaxSize + 1, http.StatusRequestEntityTooLarge},
+		{model.UploadCategoryImage, "application/pdf", 10, http.StatusBadRequest},
+		{model.UploadCategoryAvatar, "image/jpeg", limits.Avatar.MaxSize + 1, http.StatusRequestEntityTooLarge},
+		{model.UploadCategoryFile, "application/pdf", limits.File.MaxSize, 0},
+		{model.UploadCategoryFile, "image/gif", 10, 0},
+		{model.UploadCategoryFile, "application/x-msdownload", 10, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got, _ := checkUpload(&limits, tt.category, tt.contentType, tt.size); got != tt.want {
+			t.Errorf("checkUpload(%s, %s, %d) = %d, want %d", tt.category, tt.contentType, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestCheckUpload_ConfiguredLimits(t *testing.T) {
+	limits := model.DefaultUploadLimits()
+	limits.Avatar.MaxSize = 512 << 10
+	limits.Avatar.AllowedTypes = []string{"image/png"}
+
+	status, msg := checkUpload(&limits, model.UploadCategoryAvatar, "image/png", 600<<10)
+	if status != http.StatusRequestEntityTooLarge || msg != "頭像大小不能超過 512KB" {
+		t.Errorf("Unexpected result %d %q", status, msg)
+	}
+	status, msg = checkUpload(&limits, model.UploadCategoryAvatar, "image/jpeg", 10)
+	if status != http.StatusBadRequest || !strings.Contains(msg, "image/png") {
+		t.Errorf("Unexpected result %d %q", status, msg)
+	}
+}
+
+func TestUploadTarget(t *testing.T) {
+	session := &model.UploadSession{
+		ID:        "0b5d9a4e-8a3b-4f0e-9c61-3a4f1f2d7c10",
+		UserID:    "5e0c2b7a-1d4f-4c8e-8b2a-6f3e9d1c0a55",
+		FileName:  "../quarterly report.pdf",
+		CreatedAt: time.Unix(1700000000, 0),
+	}
+	limits := model.DefaultUploadLimits()
+	limits.Avatar.SubDir = "profile"
+
+	session.Category = model.UploadCategoryFile
+	if dir, name := uploadTarget(session, &limits); dir != "files" || name != "0b5d9a4e_quarterly_report.pdf" {
+		t.Errorf("Unexpected file target %s/%s", dir, name)
+	}
+
+	session.Category = model.UploadCategoryAvatar
+	if dir, name := uploadTarget(session, &limits); dir != "profile" || name != session.UserID+"_1700000000.pdf" {
+		t.Errorf("Unexpected avatar target %s/%s", dir, name)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// GetUploadSettings godoc
+// @Summary 上傳限制設定
+// @Description 取得各類上傳目前生效的大小與格式限制，以及管理員覆寫的項目（僅管理員）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=response.UploadSettingsResponse}
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/uploads/settings [get]
+func (h *UploadHandler) GetUploadSettings(c *gin.Context) {
+	settings, err := h.settings.GetSettings(c.Request.Context())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewUploadSettingsResponse(settings, settings.Apply(h.settings.Defaults())))
+}
+
+// UpdateUploadSettings godoc
+// @Summary 更新上傳限制設定
+// @Description 覆寫圖片、檔案、頭像的大小上限（位元組）與允許的 MIME 類型，立即生效；大小設為 0 或類型設為空陣列則恢復設定檔的值（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.UpdateUploadSettingsRequest true "上傳限制"
+// @Success 200 {object} response.Response{data=response.UploadSettingsResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/uploads/settings [patch]
+func (h *UploadHandler) UpdateUploadSettings(c *gin.Context) {
+	var req request.UpdateUploadSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	settings, err := h.settings.UpdateSettings(c.Request.Context(), &service.UpdateUploadSettingsInput{
+		Image:  uploadLimitOverride(req.Image),
+		File:   uploadLimitOverride(req.File),
+		Avatar: uploadLimitOverride(req.Avatar),
+	}, middleware.GetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewUploadSettingsResponse(settings, settings.Apply(h.settings.Defaults())))
+}
+
+func uploadLimitOverride(req *request.UploadLimitRequest) *service.UploadLimitOverride {
+	if req == nil {
+		return nil
+	}
+	return &service.UploadLimitOverride{
+		MaxSize:      req.MaxSize,
+		AllowedTypes: req.AllowedTypes,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/go-demo/chat/internal/dto/request"
 	"github.com/go-demo/chat/internal/dto/response"
 	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/pkg/pagination"
 	"github.com/go-demo/chat/internal/pkg/utils"
 	"github.com/go-demo/chat/internal/service"
 )
@@ -21,7 +22,7 @@ func NewUserHandler(userService *service.UserService) *UserHandler {
 
 // GetProfile godoc
 // @Summary 獲取用戶資料
-// @Description 獲取指定用戶的公開資料
+// @Description 獲取指定用戶的公開資料；用戶離線且隱私設定允許時附上最後上線時間 last_seen_at
 // @Tags 用戶
 // @Accept json
 // @Produce json
@@ -38,7 +39,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	profile, err := h.userService.GetProfile(c.Request.Context(), userID)
+	profile, err := h.userService.GetProfile(c.Request.Context(), middleware.GetUserID(c), userID)
 	if err != nil {
 		response.Error(c, err)
 		return
@@ -57,6 +58,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 // @Param q query string true "搜尋關鍵字"
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.ProfileResponse}
 // @Failure 400 {object} response.Response
 // @Router /api/v1/users/search [get]
@@ -67,18 +69,19 @@ func (h *UserHandler) Search(c *gin.Context) {
 		return
 	}
 
-	profiles, err := h.userService.Search(c.Request.Context(), req.Query, req.Limit, req.Offset())
+	profiles, err := h.userService.Search(c.Request.Context(), req.Query, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	profiles, hasMore := pagination.Trim(profiles, req.Limit)
 
 	profileResponses := make([]*response.ProfileResponse, len(profiles))
 	for i, p := range profiles {
 		profileResponses[i] = response.NewProfileResponse(p)
 	}
 
-	response.Success(c, profileResponses)
+	response.SuccessWithMeta(c, profileResponses, response.NewMeta(req.Limit, req.Offset(), len(profileResponses), hasMore))
 }
 
 // BlockUser godoc
@@ -148,6 +151,7 @@ func (h *UserHandler) UnblockUser(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.ProfileResponse}
 // @Router /api/v1/users/blocked [get]
 func (h *UserHandler) ListBlockedUsers(c *gin.Context) {
@@ -158,18 +162,19 @@ func (h *UserHandler) ListBlockedUsers(c *gin.Context) {
 
 	userID := middleware.GetUserID(c)
 
-	profiles, err := h.userService.ListBlockedUsers(c.Request.Context(), userID, req.Limit, req.Offset())
+	profiles, err := h.userService.ListBlockedUsers(c.Request.Context(), userID, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	profiles, hasMore := pagination.Trim(profiles, req.Limit)
 
 	profileResponses := make([]*response.ProfileResponse, len(profiles))
 	for i, p := range profiles {
 		profileResponses[i] = response.NewProfileResponse(p)
 	}
 
-	response.Success(c, profileResponses)
+	response.SuccessWithMeta(c, profileResponses, response.NewMeta(req.Limit, req.Offset(), len(profileResponses), hasMore))
 }
 
 // SendFriendRequest godoc
@@ -297,6 +302,7 @@ func (h *UserHandler) RemoveFriend(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.FriendResponse}
 // @Router /api/v1/users/friends [get]
 func (h *UserHandler) ListFriends(c *gin.Context) {
@@ -307,18 +313,86 @@ func (h *UserHandler) ListFriends(c *gin.Context) {
 
 	userID := middleware.GetUserID(c)
 
-	friends, err := h.userService.ListFriends(c.Request.Context(), userID, req.Limit, req.Offset())
+	friends, err := h.userService.ListFriends(c.Request.Context(), userID, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	friends, hasMore := pagination.Trim(friends, req.Limit)
 
 	friendResponses := make([]*response.FriendResponse, len(friends))
 	for i, f := range friends {
 		friendResponses[i] = response.NewFriendResponse(f)
 	}
 
-	response.Success(c, friendResponses)
+	response.SuccessWithMeta(c, friendResponses, response.NewMeta(req.Limit, req.Offset(), len(friendResponses), hasMore))
+}
+
+// DiscoverContacts godoc
+// @Summary 通訊錄比對
+// @Description 以通訊錄中 email 的 SHA-256 雜湊（去除前後空白並轉小寫後計算，64 字元小寫十六進位）找出已註冊的用戶，每次最多 500 筆；關閉 discoverable 的用戶不會被找到
+// @Tags 好友
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.DiscoverContactsRequest true "email 雜湊"
+// @Success 200 {object} response.Response{data=[]response.DiscoveredContactResponse}
+// @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/v1/users/discover [post]
+func (h *UserHandler) DiscoverContacts(c *gin.Context) {
+	var req request.DiscoverContactsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	users, err := h.userService.DiscoverContacts(c.Request.Context(), middleware.GetUserID(c), req.EmailHashes)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	contactResponses := make([]*response.DiscoveredContactResponse, len(users))
+	for i, u := range users {
+		contactResponses[i] = response.NewDiscoveredContactResponse(u)
+	}
+
+	response.Success(c, contactResponses)
+}
+
+// ListSuggestions godoc
+// @Summary 獲取好友推薦
+// @Description 依共同好友與共同聊天室推薦用戶，共同好友多者優先；已是好友、已送出或收到請求及互相封鎖的用戶不會出現
+// @Tags 好友
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "推薦數量" default(10)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
+// @Success 200 {object} response.Response{data=[]response.FriendSuggestionResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Router /api/v1/users/suggestions [get]
+func (h *UserHandler) ListSuggestions(c *gin.Context) {
+	var req request.FriendSuggestionsQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, "請求格式錯誤")
+		return
+	}
+
+	suggestions, err := h.userService.ListSuggestions(c.Request.Context(), middleware.GetUserID(c), req.FetchLimit(), req.Offset())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	suggestions, hasMore := pagination.Trim(suggestions, req.Limit)
+
+	suggestionResponses := make([]*response.FriendSuggestionResponse, len(suggestions))
+	for i, s := range suggestions {
+		suggestionResponses[i] = response.NewFriendSuggestionResponse(s)
+	}
+
+	response.SuccessWithMeta(c, suggestionResponses, response.NewMeta(req.Limit, req.Offset(), len(suggestionResponses), hasMore))
 }
 
 // ListPendingRequests godoc
@@ -330,6 +404,7 @@ func (h *UserHandler) ListFriends(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.FriendRequestResponse}
 // @Router /api/v1/users/friend-requests/pending [get]
 func (h *UserHandler) ListPendingRequests(c *gin.Context) {
@@ -340,18 +415,19 @@ func (h *UserHandler) ListPendingRequests(c *gin.Context) {
 
 	userID := middleware.GetUserID(c)
 
-	requests, err := h.userService.ListPendingRequests(c.Request.Context(), userID, req.Limit, req.Offset())
+	requests, err := h.userService.ListPendingRequests(c.Request.Context(), userID, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	requests, hasMore := pagination.Trim(requests, req.Limit)
 
 	requestResponses := make([]*response.FriendRequestResponse, len(requests))
 	for i, r := range requests {
 		requestResponses[i] = response.NewFriendRequestResponse(r)
 	}
 
-	response.Success(c, requestResponses)
+	response.SuccessWithMeta(c, requestResponses, response.NewMeta(req.Limit, req.Offset(), len(requestResponses), hasMore))
 }
 
 // ListSentRequests godoc
@@ -363,6 +439,7 @@ func (h *UserHandler) ListPendingRequests(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.FriendRequestResponse}
 // @Router /api/v1/users/friend-requests/sent [get]
 func (h *UserHandler) ListSentRequests(c *gin.Context) {
@@ -373,18 +450,19 @@ func (h *UserHandler) ListSentRequests(c *gin.Context) {
 
 	userID := middleware.GetUserID(c)
 
-	requests, err := h.userService.ListSentRequests(c.Request.Context(), userID, req.Limit, req.Offset())
+	requests, err := h.userService.ListSentRequests(c.Request.Context(), userID, req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	requests, hasMore := pagination.Trim(requests, req.Limit)
 
 	requestResponses := make([]*response.FriendRequestResponse, len(requests))
 	for i, r := range requests {
 		requestResponses[i] = response.NewFriendRequestResponse(r)
 	}
 
-	response.Success(c, requestResponses)
+	response.SuccessWithMeta(c, requestResponses, response.NewMeta(req.Limit, req.Offset(), len(requestResponses), hasMore))
 }
 
 // GetOnlineUsers godoc
@@ -396,6 +474,7 @@ func (h *UserHandler) ListSentRequests(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "頁碼" default(1)
 // @Param limit query int false "每頁數量" default(20)
+// @Param cursor query string false "分頁游標（取自 meta.next_cursor）"
 // @Success 200 {object} response.Response{data=[]response.ProfileResponse}
 // @Router /api/v1/users/online [get]
 func (h *UserHandler) GetOnlineUsers(c *gin.Context) {
@@ -404,16 +483,68 @@ func (h *UserHandler) GetOnlineUsers(c *gin.Context) {
 		req = request.PaginationRequest{Page: 1, Limit: 20}
 	}
 
-	profiles, err := h.userService.GetOnlineUsers(c.Request.Context(), req.Limit, req.Offset())
+	profiles, err := h.userService.GetOnlineUsers(c.Request.Context(), req.FetchLimit(), req.Offset())
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
+	profiles, hasMore := pagination.Trim(profiles, req.Limit)
 
 	profileResponses := make([]*response.ProfileResponse, len(profiles))
 	for i, p := range profiles {
 		profileResponses[i] = response.NewProfileResponse(p)
 	}
 
-	response.Success(c, profileResponses)
+	response.SuccessWithMeta(c, profileResponses, response.NewMeta(req.Limit, req.Offset(), len(profileResponses), hasMore))
+}
+
+// GrantAdmin godoc
+// @Summary 授予管理員權限
+// @Description 將指定用戶設為管理員（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用戶 ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/users/{id}/admin [put]
+func (h *UserHandler) GrantAdmin(c *gin.Context) {
+	h.setAdmin(c, true, "已授予管理員權限")
+}
+
+// RevokeAdmin godoc
+// @Summary 移除管理員權限
+// @Description 移除指定用戶的管理員權限；無法移除自己的權限（僅管理員）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "用戶 ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/v1/admin/users/{id}/admin [delete]
+func (h *UserHandler) RevokeAdmin(c *gin.Context) {
+	h.setAdmin(c, false, "已移除管理員權限")
+}
+
+func (h *UserHandler) setAdmin(c *gin.Context, isAdmin bool, message string) {
+	userID := c.Param("id")
+
+	if !utils.ValidateUUID(userID) {
+		response.BadRequest(c, "無效的用戶 ID")
+		return
+	}
+
+	if err := h.userService.SetAdmin(c.Request.Context(), middleware.GetUserID(c), userID, isAdmin); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, message, nil)
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -272,6 +273,36 @@ func TestUserHandler_ListFriends(t *testing.T) {
 	}
 }
 
+func TestUserHandler_DiscoverContacts_InvalidHashes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewUserHandler(nil)
+	router := gin.New()
+	router.POST("/api/v1/users/discover", handler.DiscoverContacts)
+
+	valid := strings.Repeat("ab", 32)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty list", `{"email_hashes":[]}`},
+		{"too short", `{"email_hashes":["` + valid[:63] + `"]}`},
+		{"not hex", `{"email_hashes":["` + strings.Repeat("g", 64) + `"]}`},
+		{"0x prefix", `{"email_hashes":["0x` + valid[:62] + `"]}`},
+		{"uppercase", `{"email_hashes":["` + strings.ToUpper(valid) + `"]}`},
+		{"one bad among good", `{"email_hashes":["` + valid + `","` + valid[:60] + `"]}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/users/discover", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.name, w.Code)
+		}
+	}
+}
+
 func TestUserHandler_Unauthorized(t *testing.T) {
 	router, _, _, db, prefix := setupUserHandlerTestIsolated(t)
 	defer db.Close()
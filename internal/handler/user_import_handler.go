@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/middleware"
+	"github.com/go-demo/chat/internal/service"
+)
+
+// maxImportBodySize bounds CSV and JSON import bodies
+const maxImportBodySize = 5 << 20
+
+type UserImportHandler struct {
+	importService *service.UserImportService
+}
+
+func NewUserImportHandler(importService *service.UserImportService) *UserImportHandler {
+	return &UserImportHandler{
+		importService: importService,
+	}
+}
+
+// ImportUsers godoc
+// @Summary 批次匯入用戶
+// @Description 以 JSON 或 CSV 批次建立帳號並回傳每列結果，可選擇寄送邀請信（僅管理員）。CSV 需有標題列，包含 username、email，可選 role、password；以 multipart 欄位 file 上傳或直接以 text/csv 送出
+// @Tags 管理
+// @Accept json
+// @Accept mpfd
+// @Accept text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.ImportUsersRequest false "JSON 匯入資料"
+// @Param file formData file false "CSV 檔案"
+// @Param send_invitations query bool false "CSV 匯入時是否寄送邀請信"
+// @Success 200 {object} response.Response{data=response.UserImportResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/users/import [post]
+func (h *UserImportHandler) ImportUsers(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodySize)
+
+	input := &service.UserImportInput{ImportedBy: middleware.GetUserID(c)}
+
+	switch c.ContentType() {
+	case "application/json":
+		var req request.ImportUsersRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "請求格式錯誤")
+			return
+		}
+		input.Invite = req.SendInvitations
+		input.Rows = make([]*service.UserImportRow, len(req.Users))
+		for i, u := range req.Users {
+			input.Rows[i] = &service.UserImportRow{
+				Username: u.Username,
+				Email:    u.Email,
+				Role:     u.Role,
+				Password: u.Password,
+			}
+		}
+
+	case "multipart/form-data":
+		file, err := c.FormFile("file")
+		if err != nil {
+			response.BadRequest(c, "請上傳 CSV 檔案")
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			response.BadRequest(c, "無法讀取上傳的檔案")
+			return
+		}
+		defer f.Close()
+
+		rows, err := service.ParseUserImportCSV(f)
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+		input.Rows = rows
+		input.Invite = sendInvitations(c, c.PostForm("send_invitations"))
+
+	case "text/csv":
+		rows, err := service.ParseUserImportCSV(c.Request.Body)
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+		input.Rows = rows
+		input.Invite = sendInvitations(c, "")
+
+	default:
+		response.BadRequest(c, "僅支援 JSON 或 CSV 格式")
+		return
+	}
+
+	summary, err := h.importService.Import(c.Request.Context(), input)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, response.NewUserImportResponse(summary))
+}
+
+// sendInvitations reads the flag from a form value, falling back to the
+// query string
+func sendInvitations(c *gin.Context, formValue string) bool {
+	if formValue == "" {
+		formValue = c.Query("send_invitations")
+	}
+	invite, _ := strconv.ParseBool(formValue)
+	return invite
+}
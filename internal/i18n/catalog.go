@@ -0,0 +1,103 @@
+// Package i18n holds the text the server generates itself, such as the
+// system messages posted in rooms, in every supported locale
+package i18n
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is used when neither the requested locale nor its base
+// language has the message
+const DefaultLocale = "zh-TW"
+
+// System message keys. Placeholders in braces are filled from params.
+const (
+	SystemMemberJoined  = "system.member_joined"  // {user}
+	SystemMemberLeft    = "system.member_left"    // {user}
+	SystemMemberInvited = "system.member_invited" // {actor} {user}
+	SystemMemberKicked  = "system.member_kicked"  // {actor} {user}
+	SystemMemberMuted   = "system.member_muted"   // {user}
+	SystemMemberUnmuted = "system.member_unmuted" // {user}
+
+	SystemRoomArchived   = "system.room_archived"   // {user}
+	SystemRoomUnarchived = "system.room_unarchived" // {user}
+)
+
+var catalog = map[string]map[string]string{
+	"zh-TW": {
+		SystemMemberJoined:  "{user} 加入了聊天室",
+		SystemMemberLeft:    "{user} 離開了聊天室",
+		SystemMemberInvited: "{actor} 邀請 {user} 加入聊天室",
+		SystemMemberKicked:  "{actor} 將 {user} 移出聊天室",
+		SystemMemberMuted:   "{user} 已被禁言",
+		SystemMemberUnmuted: "{user} 已解除禁言",
+
+		SystemRoomArchived:   "{user} 封存了聊天室",
+		SystemRoomUnarchived: "{user} 解除了聊天室的封存",
+	},
+	"en": {
+		SystemMemberJoined:  "{user} joined the room",
+		SystemMemberLeft:    "{user} left the room",
+		SystemMemberInvited: "{actor} added {user} to the room",
+		SystemMemberKicked:  "{actor} removed {user} from the room",
+		SystemMemberMuted:   "{user} was muted",
+		SystemMemberUnmuted: "{user} is no longer muted",
+
+		SystemRoomArchived:   "{user} archived the room",
+		SystemRoomUnarchived: "{user} unarchived the room",
+	},
+}
+
+// Locales returns the locales with a catalog, sorted
+func Locales() []string {
+	locales := make([]string, 0, len(catalog))
+	for locale := range catalog {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supported checks if a locale, or its base language, has a catalog
+func Supported(locale string) bool {
+	for _, candidate := range candidates(locale) {
+		if _, ok := catalog[candidate]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// T renders a message in the closest available locale: the requested one,
+// then its base language (en-US -> en), then the default locale. Unknown
+// keys render as the key itself.
+func T(locale, key string, params map[string]string) string {
+	text := key
+	for _, candidate := range append(candidates(locale), DefaultLocale) {
+		if msg, ok := catalog[candidate][key]; ok {
+			text = msg
+			break
+		}
+	}
+
+	if len(params) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func candidates(locale string) []string {
+	if locale == "" {
+		return nil
+	}
+	list := []string{locale}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		list = append(list, locale[:i])
+	}
+	return list
+}
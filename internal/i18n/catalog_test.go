@@ -0,0 +1,46 @@
+package i18n
+
+import "testing"
+
+func TestT(t *testing.T) {
+	params := map[string]string{"user": "alice", "actor": "bob"}
+	tests := []struct {
+		locale   string
+		key      string
+		expected string
+	}{
+		{"zh-TW", SystemMemberJoined, "alice 加入了聊天室"},
+		{"en", SystemMemberKicked, "bob removed alice from the room"},
+		{"en-GB", SystemMemberLeft, "alice left the room"},
+		{"fr", SystemMemberMuted, "alice 已被禁言"},
+		{"", SystemMemberInvited, "bob 邀請 alice 加入聊天室"},
+		{"en", "system.unknown", "system.unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := T(tt.locale, tt.key, params); got != tt.expected {
+			t.Errorf("T(%q, %q) = %q, expected %q", tt.locale, tt.key, got, tt.expected)
+		}
+	}
+}
+
+func TestCatalogsHaveTheSameKeys(t *testing.T) {
+	for locale, messages := range catalog {
+		for key := range catalog[DefaultLocale] {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("Locale %s is missing %s", locale, key)
+			}
+		}
+		if len(messages) != len(catalog[DefaultLocale]) {
+			t.Errorf("Locale %s has %d messages, expected %d", locale, len(messages), len(catalog[DefaultLocale]))
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for locale, expected := range map[string]bool{"zh-TW": true, "en": true, "en-US": true, "zh": false, "fr": false, "": false} {
+		if got := Supported(locale); got != expected {
+			t.Errorf("Supported(%q) = %v, expected %v", locale, got, expected)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package ipfilter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// accessChangedChannel tells every instance to reload the access rules
+const accessChangedChannel = "ipfilter:access_changed"
+
+// Reasons a request is refused
+const (
+	ReasonIPDenied      = "ip_denied"
+	ReasonCountryDenied = "country_denied"
+	ReasonNotAllowed    = "not_allowed"
+)
+
+// Denial explains why an AccessList refused a request
+type Denial struct {
+	Scope   string `json:"scope"`
+	Reason  string `json:"reason"`
+	IP      string `json:"ip"`
+	Country string `json:"country,omitempty"`
+}
+
+// RuleStore loads the access rules managed at runtime
+type RuleStore interface {
+	List(ctx context.Context) ([]*model.IPAccessRule, error)
+}
+
+type accessRule struct {
+	rule    *model.IPAccessRule
+	network *net.IPNet // nil for country rules
+}
+
+func (r *accessRule) matches(ip net.IP, country string) bool {
+	if r.network != nil {
+		return r.network.Contains(ip)
+	}
+	return country != "" && r.rule.Country.String == country
+}
+
+// AccessList allows or denies requests by IP range and country, per scope.
+// Within a scope deny rules win; when the scope has allow rules, a request
+// must match one of them. Rules from the config file are fixed; the rest
+// are loaded from the store and reloaded like the Denylist.
+type AccessList struct {
+	store         RuleStore
+	static        []*model.IPAccessRule
+	geo           *GeoDB
+	countryHeader string
+	redis         *redis.Client
+	logger        *zap.Logger
+	mu            sync.RWMutex
+	rules         []*accessRule
+}
+
+// NewAccessList creates an access list holding the static rules; call
+// Reload to add the stored ones
+func NewAccessList(store RuleStore, static []*model.IPAccessRule, redisClient *redis.Client, logger *zap.Logger) *AccessList {
+	a := &AccessList{
+		store:  store,
+		static: static,
+		redis:  redisClient,
+		logger: logger,
+	}
+	a.Replace(nil)
+	return a
+}
+
+// SetGeoDB sets the database used to look up the country of an IP
+func (a *AccessList) SetGeoDB(db *GeoDB) {
+	a.geo = db
+}
+
+// SetCountryHeader trusts a header set by a reverse proxy or CDN, such as
+// CF-IPCountry, for the country of a request. It takes precedence over
+// the GeoDB.
+func (a *AccessList) SetCountryHeader(name string) {
+	a.countryHeader = name
+}
+
+// GeoEnabled reports whether countries can be determined, which country
+// rules need
+func (a *AccessList) GeoEnabled() bool {
+	return a.geo.Len() > 0 || a.countryHeader != ""
+}
+
+// ParseRule parses a static rule written as "scope action target", for
+// example "admin allow 10.0.0.0/8" or "global deny CN"
+func ParseRule(s string) (*model.IPAccessRule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid ip access rule %q: expected scope, action and target", s)
+	}
+	return NewRule(fields[0], model.IPAccessAction(fields[1]), fields[2])
+}
+
+// NewRule validates a rule. target is an IP, a CIDR range or a two-letter
+// country code.
+func NewRule(scope string, action model.IPAccessAction, target string) (*model.IPAccessRule, error) {
+	validScope := false
+	for _, s := range model.IPAccessScopes {
+		validScope = validScope || s == scope
+	}
+	if !validScope {
+		return nil, fmt.Errorf("unknown ip access scope %q", scope)
+	}
+	if action != model.IPAccessAllow && action != model.IPAccessDeny {
+		return nil, fmt.Errorf("unknown ip access action %q", action)
+	}
+
+	rule := &model.IPAccessRule{Scope: scope, Action: action}
+	if network, err := ParseCIDR(target); err == nil {
+		rule.CIDR = sql.NullString{String: network.String(), Valid: true}
+	} else if IsCountryCode(target) {
+		rule.Country = sql.NullString{String: strings.ToUpper(target), Valid: true}
+	} else {
+		return nil, fmt.Errorf("invalid ip access target %q", target)
+	}
+	return rule, nil
+}
+
+// IsCountryCode reports whether s looks like an ISO 3166-1 alpha-2 code
+func IsCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, c := range strings.ToUpper(s) {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Static returns the rules from the config file
+func (a *AccessList) Static() []*model.IPAccessRule {
+	return a.static
+}
+
+func (a *AccessList) compile(rules []*model.IPAccessRule) []*accessRule {
+	compiled := make([]*accessRule, 0, len(rules))
+	for _, rule := range rules {
+		entry := &accessRule{rule: rule}
+		if rule.CIDR.Valid {
+			network, err := ParseCIDR(rule.CIDR.String)
+			if err != nil {
+				a.logger.Warn("Skipping invalid ip access rule", zap.String("id", rule.ID), zap.Error(err))
+				continue
+			}
+			entry.network = network
+		}
+		compiled = append(compiled, entry)
+	}
+	return compiled
+}
+
+// Replace swaps the stored rules. Rules with unparsable ranges are skipped.
+func (a *AccessList) Replace(rules []*model.IPAccessRule) {
+	compiled := a.compile(append(append([]*model.IPAccessRule{}, a.static...), rules...))
+
+	a.mu.Lock()
+	a.rules = compiled
+	a.mu.Unlock()
+}
+
+// Country returns the country of a request from ip, or "" when unknown
+func (a *AccessList) Country(ip string, header http.Header) string {
+	if a.countryHeader != "" && header != nil {
+		if country := strings.ToUpper(strings.TrimSpace(header.Get(a.countryHeader))); IsCountryCode(country) {
+			return country
+		}
+	}
+	return a.geo.Country(ip)
+}
+
+// Check returns why a request from ip in country may not access scope, or
+// nil when it may
+func (a *AccessList) Check(scope, ip, country string) *Denial {
+	a.mu.RLock()
+	rules := a.rules
+	a.mu.RUnlock()
+	return evaluate(rules, scope, ip, country)
+}
+
+// Preview is Check as if add were added and the rule with ID removeID
+// removed. It lets admins see whether a change would lock them out.
+func (a *AccessList) Preview(add *model.IPAccessRule, removeID, scope, ip, country string) *Denial {
+	a.mu.RLock()
+	current := a.rules
+	a.mu.RUnlock()
+
+	rules := make([]*accessRule, 0, len(current)+1)
+	for _, r := range current {
+		if removeID == "" || r.rule.ID != removeID {
+			rules = append(rules, r)
+		}
+	}
+	if add != nil {
+		rules = append(rules, a.compile([]*model.IPAccessRule{add})...)
+	}
+	return evaluate(rules, scope, ip, country)
+}
+
+func evaluate(rules []*accessRule, scope, ip, country string) *Denial {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+
+	hasAllow, allowed := false, false
+	for _, r := range rules {
+		if r.rule.Scope != scope {
+			continue
+		}
+		if r.rule.Action == model.IPAccessDeny {
+			if r.matches(parsed, country) {
+				reason := ReasonIPDenied
+				if r.network == nil {
+					reason = ReasonCountryDenied
+				}
+				return &Denial{Scope: scope, Reason: reason, IP: ip, Country: country}
+			}
+			continue
+		}
+		hasAllow = true
+		allowed = allowed || r.matches(parsed, country)
+	}
+
+	if hasAllow && !allowed {
+		return &Denial{Scope: scope, Reason: ReasonNotAllowed, IP: ip, Country: country}
+	}
+	return nil
+}
+
+// Len returns the number of rules in effect, static ones included
+func (a *AccessList) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.rules)
+}
+
+// Reload loads the stored rules
+func (a *AccessList) Reload(ctx context.Context) error {
+	rules, err := a.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	a.Replace(rules)
+	return nil
+}
+
+// NotifyChanged reloads the local copy and tells the other instances to
+// reload theirs
+func (a *AccessList) NotifyChanged(ctx context.Context) error {
+	if err := a.Reload(ctx); err != nil {
+		return err
+	}
+	if a.redis == nil {
+		return nil
+	}
+	return a.redis.Publish(ctx, accessChangedChannel, "reload").Err()
+}
+
+// Watch reloads the rules whenever another instance publishes a change.
+// It blocks until ctx is canceled.
+func (a *AccessList) Watch(ctx context.Context) {
+	if a.redis == nil {
+		return
+	}
+
+	pubsub := a.redis.Subscribe(ctx, accessChangedChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := a.Reload(ctx); err != nil {
+				a.logger.Warn("Failed to reload ip access rules", zap.Error(err))
+			}
+		}
+	}
+}
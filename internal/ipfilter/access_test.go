@@ -0,0 +1,153 @@
+package ipfilter
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+type fakeRuleStore struct {
+	rules []*model.IPAccessRule
+}
+
+func (s *fakeRuleStore) List(ctx context.Context) ([]*model.IPAccessRule, error) {
+	return s.rules, nil
+}
+
+func mustRule(t *testing.T, s string) *model.IPAccessRule {
+	t.Helper()
+	rule, err := ParseRule(s)
+	if err != nil {
+		t.Fatalf("ParseRule(%q): %v", s, err)
+	}
+	return rule
+}
+
+func TestParseRule(t *testing.T) {
+	rule := mustRule(t, "admin allow 10.1.2.3/8")
+	if rule.Scope != model.IPAccessScopeAdmin || rule.Action != model.IPAccessAllow || rule.CIDR.String != "10.0.0.0/8" {
+		t.Errorf("Unexpected rule %+v", rule)
+	}
+	if rule := mustRule(t, "global deny cn"); rule.Country.String != "CN" || rule.CIDR.Valid {
+		t.Errorf("Expected a country rule, got %+v", rule)
+	}
+
+	for _, bad := range []string{"admin allow", "api allow 10.0.0.0/8", "admin permit 10.0.0.0/8", "global deny CHN"} {
+		if _, err := ParseRule(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestAccessListCheck(t *testing.T) {
+	static := []*model.IPAccessRule{
+		mustRule(t, "admin allow 10.0.0.0/8"),
+		mustRule(t, "admin allow TW"),
+		mustRule(t, "admin deny 10.9.0.0/16"),
+		mustRule(t, "global deny KP"),
+	}
+	list := NewAccessList(&fakeRuleStore{}, static, nil, zap.NewNop())
+
+	tests := []struct {
+		scope, ip, country string
+		reason             string
+	}{
+		{"admin", "10.1.2.3", "", ""},
+		{"admin", "203.0.113.7", "TW", ""},
+		{"admin", "203.0.113.7", "JP", ReasonNotAllowed},
+		{"admin", "203.0.113.7", "", ReasonNotAllowed},
+		{"admin", "10.9.1.1", "", ReasonIPDenied},
+		{"global", "203.0.113.7", "KP", ReasonCountryDenied},
+		{"global", "203.0.113.7", "JP", ""},
+		{"scim", "203.0.113.7", "JP", ""},
+	}
+	for _, tt := range tests {
+		denial := list.Check(tt.scope, tt.ip, tt.country)
+		reason := ""
+		if denial != nil {
+			reason = denial.Reason
+		}
+		if reason != tt.reason {
+			t.Errorf("Check(%s, %s, %q) = %q, want %q", tt.scope, tt.ip, tt.country, reason, tt.reason)
+		}
+	}
+}
+
+func TestAccessListPreview(t *testing.T) {
+	stored := mustRule(t, "admin allow 192.0.2.0/24")
+	stored.ID = "rule-1"
+	list := NewAccessList(&fakeRuleStore{}, nil, nil, zap.NewNop())
+	list.Replace([]*model.IPAccessRule{stored})
+
+	if list.Check("admin", "198.51.100.1", "") == nil {
+		t.Fatal("Expected addresses outside the allowlist to be denied")
+	}
+	if list.Preview(nil, "rule-1", "admin", "198.51.100.1", "") != nil {
+		t.Error("Expected removing the only allow rule to open the scope")
+	}
+	if list.Preview(mustRule(t, "admin allow 198.51.100.0/24"), "", "admin", "198.51.100.1", "") != nil {
+		t.Error("Expected the added rule to allow the address")
+	}
+	if list.Check("admin", "198.51.100.1", "") == nil {
+		t.Error("Expected Preview to leave the rules unchanged")
+	}
+}
+
+func TestAccessListCountry(t *testing.T) {
+	geo, err := ReadGeoDB(strings.NewReader("network,country\n203.0.113.0/24,JP\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	list := NewAccessList(&fakeRuleStore{}, nil, nil, zap.NewNop())
+	list.SetGeoDB(geo)
+	if got := list.Country("203.0.113.7", nil); got != "JP" {
+		t.Errorf("Expected JP from the database, got %q", got)
+	}
+
+	list.SetCountryHeader("CF-IPCountry")
+	header := http.Header{}
+	header.Set("CF-IPCountry", "tw")
+	if got := list.Country("203.0.113.7", header); got != "TW" {
+		t.Errorf("Expected TW from the header, got %q", got)
+	}
+	header.Set("CF-IPCountry", "XX1")
+	if got := list.Country("203.0.113.7", header); got != "JP" {
+		t.Errorf("Expected an invalid header to fall back to the database, got %q", got)
+	}
+}
+
+func TestReadGeoDB(t *testing.T) {
+	data := `# generated
+start_ip,end_ip,country
+1.0.0.0,1.0.0.255,AU
+1.0.1.0,1.0.3.255,CN
+2001:db8::,2001:db8:ffff:ffff:ffff:ffff:ffff:ffff,NL
+5.0.0.0,5.0.0.255,ZZ
+`
+	geo, err := ReadGeoDB(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tests := map[string]string{
+		"1.0.0.0":     "AU",
+		"1.0.2.9":     "CN",
+		"1.0.4.0":     "",
+		"0.255.255.1": "",
+		"2001:db8::1": "NL",
+		"5.0.0.1":     "",
+		"bogus":       "",
+	}
+	for ip, want := range tests {
+		if got := geo.Country(ip); got != want {
+			t.Errorf("Country(%s) = %q, want %q", ip, got, want)
+		}
+	}
+
+	if _, err := ReadGeoDB(strings.NewReader("1.0.0.0/24,AU\nnot-an-ip,CN\n")); err == nil {
+		t.Error("Expected a malformed row to be rejected")
+	}
+}
@@ -0,0 +1,166 @@
+package ipfilter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// changedChannel tells every instance to reload the denylist
+const changedChannel = "ipfilter:changed"
+
+// Store loads the bans currently in effect
+type Store interface {
+	ListActive(ctx context.Context) ([]*model.IPBan, error)
+}
+
+type entry struct {
+	network *net.IPNet
+	ban     *model.IPBan
+}
+
+// Denylist keeps an in-memory copy of the IP bans so requests can be checked
+// without a database round trip. Instances reload it when another instance
+// publishes a change, and on a timer in case a notification was missed.
+type Denylist struct {
+	store   Store
+	redis   *redis.Client
+	logger  *zap.Logger
+	mu      sync.RWMutex
+	entries []entry
+}
+
+// NewDenylist creates an empty denylist; call Reload to populate it
+func NewDenylist(store Store, redisClient *redis.Client, logger *zap.Logger) *Denylist {
+	return &Denylist{
+		store:  store,
+		redis:  redisClient,
+		logger: logger,
+	}
+}
+
+// ParseCIDR parses an IP address or CIDR range. A bare address becomes a
+// single-host range.
+func ParseCIDR(s string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid ip or cidr %q", s)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// Replace swaps the denylist contents. Bans with unparsable ranges are skipped.
+func (d *Denylist) Replace(bans []*model.IPBan) {
+	entries := make([]entry, 0, len(bans))
+	for _, ban := range bans {
+		network, err := ParseCIDR(ban.CIDR)
+		if err != nil {
+			d.logger.Warn("Skipping invalid ip ban", zap.String("id", ban.ID), zap.Error(err))
+			continue
+		}
+		entries = append(entries, entry{network: network, ban: ban})
+	}
+
+	d.mu.Lock()
+	d.entries = entries
+	d.mu.Unlock()
+}
+
+// Match returns the active ban covering ip, or nil
+func (d *Denylist) Match(ip string) *model.IPBan {
+	if d == nil {
+		return nil
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+
+	now := time.Now()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, e := range d.entries {
+		if e.ban.IsActive(now) && e.network.Contains(parsed) {
+			return e.ban
+		}
+	}
+	return nil
+}
+
+// Blocked reports whether requests from ip are refused and whether they
+// should be tarpitted first
+func (d *Denylist) Blocked(ip string) (blocked, tarpit bool) {
+	ban := d.Match(ip)
+	if ban == nil {
+		return false, false
+	}
+	return true, ban.Tarpit
+}
+
+// Len returns the number of loaded bans
+func (d *Denylist) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.entries)
+}
+
+// Reload loads the active bans from the store
+func (d *Denylist) Reload(ctx context.Context) error {
+	bans, err := d.store.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	d.Replace(bans)
+	return nil
+}
+
+// NotifyChanged reloads the local copy and tells the other instances to
+// reload theirs
+func (d *Denylist) NotifyChanged(ctx context.Context) error {
+	if err := d.Reload(ctx); err != nil {
+		return err
+	}
+	if d.redis == nil {
+		return nil
+	}
+	return d.redis.Publish(ctx, changedChannel, "reload").Err()
+}
+
+// Watch reloads the denylist whenever another instance publishes a change.
+// It blocks until ctx is canceled.
+func (d *Denylist) Watch(ctx context.Context) {
+	if d.redis == nil {
+		return
+	}
+
+	pubsub := d.redis.Subscribe(ctx, changedChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := d.Reload(ctx); err != nil {
+				d.logger.Warn("Failed to reload ip denylist", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package ipfilter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	mu   sync.Mutex
+	bans []*model.IPBan
+}
+
+func (s *fakeStore) ListActive(ctx context.Context) ([]*model.IPBan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bans, nil
+}
+
+func (s *fakeStore) set(bans ...*model.IPBan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bans = bans
+}
+
+func setupTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 15})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		t.Skipf("Skipping test, could not connect to test redis: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestParseCIDR(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"203.0.113.7", "203.0.113.7/32", false},
+		{"203.0.113.0/24", "203.0.113.0/24", false},
+		{"203.0.113.9/24", "203.0.113.0/24", false},
+		{"2001:db8::1", "2001:db8::1/128", false},
+		{"2001:db8::/32", "2001:db8::/32", false},
+		{"not-an-ip", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			network, err := ParseCIDR(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if network.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, network.String())
+			}
+		})
+	}
+}
+
+func TestDenylist_Match(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	store := &fakeStore{}
+	store.set(
+		&model.IPBan{ID: "range", CIDR: "203.0.113.0/24", Tarpit: true},
+		&model.IPBan{ID: "host", CIDR: "198.51.100.7/32"},
+		&model.IPBan{ID: "expired", CIDR: "192.0.2.0/24", ExpiresAt: &past},
+		&model.IPBan{ID: "v6", CIDR: "2001:db8::/32"},
+	)
+
+	denylist := NewDenylist(store, nil, zap.NewNop())
+	if err := denylist.Reload(context.Background()); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{"203.0.113.50", "range"},
+		{"198.51.100.7", "host"},
+		{"198.51.100.8", ""},
+		{"192.0.2.1", ""},
+		{"2001:db8::42", "v6"},
+		{"garbage", ""},
+	}
+	for _, tt := range tests {
+		ban := denylist.Match(tt.ip)
+		got := ""
+		if ban != nil {
+			got = ban.ID
+		}
+		if got != tt.expected {
+			t.Errorf("Match(%s) = %q, expected %q", tt.ip, got, tt.expected)
+		}
+	}
+
+	if blocked, tarpit := denylist.Blocked("203.0.113.1"); !blocked || !tarpit {
+		t.Errorf("Expected tarpitted block, got blocked=%v tarpit=%v", blocked, tarpit)
+	}
+	if blocked, _ := denylist.Blocked("10.0.0.1"); blocked {
+		t.Error("Expected unlisted ip to pass")
+	}
+
+	var nilList *Denylist
+	if nilList.Match("203.0.113.1") != nil {
+		t.Error("Expected nil denylist to match nothing")
+	}
+}
+
+func TestDenylist_SyncAcrossInstances(t *testing.T) {
+	client := setupTestRedis(t)
+	store := &fakeStore{}
+
+	a := NewDenylist(store, client, zap.NewNop())
+	b := NewDenylist(store, client, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Watch(ctx)
+	time.Sleep(100 * time.Millisecond) // let the subscription settle
+
+	store.set(&model.IPBan{ID: "1", CIDR: "203.0.113.0/24"})
+	if err := a.NotifyChanged(context.Background()); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+	if a.Len() != 1 {
+		t.Errorf("Expected publishing instance to reload, got %d bans", a.Len())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for b.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected other instance to reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,152 @@
+package ipfilter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+type geoRange struct {
+	start, end netip.Addr
+	country    string
+}
+
+// GeoDB maps IP addresses to countries. It is loaded from a CSV file whose
+// rows are either network,country or first_ip,last_ip,country, the layout
+// of the free DB-IP and IPinfo country downloads.
+type GeoDB struct {
+	ranges []geoRange
+}
+
+// LoadGeoDB reads a country CSV file
+func LoadGeoDB(path string) (*GeoDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geoip database: %w", err)
+	}
+	defer f.Close()
+	return ReadGeoDB(f)
+}
+
+// ReadGeoDB parses country CSV data. A header row is skipped, as are rows
+// without a country.
+func ReadGeoDB(r io.Reader) (*GeoDB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	db := &GeoDB{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid geoip database: %w", err)
+		}
+
+		rng, err := parseGeoRange(record)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("invalid geoip database line %d: %w", line, err)
+		}
+		if rng.country != "" {
+			db.ranges = append(db.ranges, rng)
+		}
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].start.Less(db.ranges[j].start)
+	})
+	return db, nil
+}
+
+func parseGeoRange(record []string) (geoRange, error) {
+	var rng geoRange
+	var country string
+	switch {
+	case len(record) == 2:
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			return rng, err
+		}
+		prefix = prefix.Masked()
+		rng.start, rng.end = prefix.Addr(), lastAddr(prefix)
+		country = record[1]
+	case len(record) >= 3:
+		var err error
+		if rng.start, err = netip.ParseAddr(strings.TrimSpace(record[0])); err != nil {
+			return rng, err
+		}
+		if rng.end, err = netip.ParseAddr(strings.TrimSpace(record[1])); err != nil {
+			return rng, err
+		}
+		country = record[2]
+	default:
+		return rng, fmt.Errorf("expected 2 or 3 fields, got %d", len(record))
+	}
+
+	rng.start, rng.end = as16(rng.start), as16(rng.end)
+	if rng.end.Less(rng.start) {
+		return rng, fmt.Errorf("range ends before it starts")
+	}
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if len(country) == 2 && country != "ZZ" {
+		rng.country = country
+	}
+	return rng, nil
+}
+
+// lastAddr returns the last address of a masked prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().As16()
+	bits := prefix.Bits()
+	if prefix.Addr().Is4() {
+		bits += 96
+	}
+	for i := bits; i < 128; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	return netip.AddrFrom16(b).Unmap()
+}
+
+// as16 puts IPv4 addresses in the IPv4-mapped IPv6 form so both families
+// sort in one table
+func as16(addr netip.Addr) netip.Addr {
+	return netip.AddrFrom16(addr.As16())
+}
+
+// Country returns the country of ip, or "" when it is unknown
+func (db *GeoDB) Country(ip string) string {
+	if db == nil {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = as16(addr)
+
+	// The last range starting at or before addr is the only candidate
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return addr.Less(db.ranges[i].start)
+	}) - 1
+	if i < 0 || db.ranges[i].end.Less(addr) {
+		return ""
+	}
+	return db.ranges[i].country
+}
+
+// Len returns the number of ranges loaded
+func (db *GeoDB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.ranges)
+}
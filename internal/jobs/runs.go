@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/metrics"
+)
+
+// recentRunsPerJob is how many finished runs are kept for each job
+const recentRunsPerJob = 20
+
+// Buckets for job durations, from 10ms to 10 minutes
+var jobDurationBuckets = []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300, 600}
+
+// RunStatus is how a job run ended
+type RunStatus string
+
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunPanicked  RunStatus = "panicked"
+)
+
+// Run is one finished execution of a job
+type Run struct {
+	ID         string    `json:"id"`
+	Job        string    `json:"job"`
+	Status     RunStatus `json:"status"`
+	Items      int64     `json:"items"` // items the job reported processing
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// JobStatus sums up a job's runs since this instance started
+type JobStatus struct {
+	Name       string `json:"name"`
+	Interval   string `json:"interval"`
+	Runs       int64  `json:"runs"`
+	Failures   int64  `json:"failures"`
+	Items      int64  `json:"items"`
+	Running    bool   `json:"running"`
+	LastRun    *Run   `json:"last_run,omitempty"`
+	RecentRuns []*Run `json:"recent_runs"` // newest first
+}
+
+// runState is what a running job reports through its context
+type runState struct {
+	id    string
+	items atomic.Int64
+}
+
+type runKey struct{}
+
+// RunID returns the ID of the job run ctx belongs to, for correlating the
+// job's own logs with the scheduler's
+func RunID(ctx context.Context) string {
+	if state, ok := ctx.Value(runKey{}).(*runState); ok {
+		return state.id
+	}
+	return ""
+}
+
+// AddItems records that the current run processed n more items. Outside a
+// job run it does nothing.
+func AddItems(ctx context.Context, n int) {
+	if state, ok := ctx.Value(runKey{}).(*runState); ok && n > 0 {
+		state.items.Add(int64(n))
+	}
+}
+
+func newRunID() string {
+	raw := make([]byte, 8)
+	_, _ = rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// jobHistory keeps a job's counters and most recent runs
+type jobHistory struct {
+	mu       sync.Mutex
+	runs     int64
+	failures int64
+	items    int64
+	running  bool
+	recent   []*Run // oldest first
+}
+
+func (h *jobHistory) start() {
+	h.mu.Lock()
+	h.running = true
+	h.mu.Unlock()
+}
+
+func (h *jobHistory) finish(run *Run) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.running = false
+	h.runs++
+	if run.Status != RunSucceeded {
+		h.failures++
+	}
+	h.items += run.Items
+	if len(h.recent) == recentRunsPerJob {
+		h.recent = append(h.recent[:0], h.recent[1:]...)
+	}
+	h.recent = append(h.recent, run)
+}
+
+func (h *jobHistory) status(job *Job) JobStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := JobStatus{
+		Name:       job.Name,
+		Interval:   job.Interval.String(),
+		Runs:       h.runs,
+		Failures:   h.failures,
+		Items:      h.items,
+		Running:    h.running,
+		RecentRuns: make([]*Run, 0, len(h.recent)),
+	}
+	for i := len(h.recent) - 1; i >= 0; i-- {
+		status.RecentRuns = append(status.RecentRuns, h.recent[i])
+	}
+	if len(status.RecentRuns) > 0 {
+		status.LastRun = status.RecentRuns[0]
+	}
+	return status
+}
+
+// jobMetrics holds the per-job series; nil unless metrics are enabled
+type jobMetrics struct {
+	runs     *metrics.CounterVec
+	failures *metrics.CounterVec
+	items    *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+func (m *jobMetrics) observe(run *Run) {
+	if m == nil {
+		return
+	}
+	m.runs.WithLabelValues(run.Job).Inc()
+	if run.Status != RunSucceeded {
+		m.failures.WithLabelValues(run.Job).Inc()
+	}
+	m.items.WithLabelValues(run.Job).Add(float64(run.Items))
+	m.duration.WithLabelValues(run.Job).Observe(run.FinishedAt.Sub(run.StartedAt).Seconds())
+}
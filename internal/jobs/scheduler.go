@@ -0,0 +1,176 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrJobNotFound is returned by RunNow for a name no job is registered under
+var ErrJobNotFound = errors.New("job not found")
+
+// Job is a unit of background work run periodically by the Scheduler
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+
+	// mu keeps a run started by RunNow from overlapping a scheduled one
+	mu      sync.Mutex
+	history jobHistory
+}
+
+// Scheduler runs registered jobs on fixed intervals until stopped
+type Scheduler struct {
+	jobs   []*Job
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+
+	// Per-job Prometheus series; nil unless metrics are enabled
+	metrics *jobMetrics
+	logger  *zap.Logger
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+	}
+}
+
+// Register adds a job to the scheduler. Jobs must be registered before Start.
+func (s *Scheduler) Register(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, &Job{
+		Name:     name,
+		Interval: interval,
+		Run:      run,
+	})
+}
+
+// SetMetrics registers the per-job run, failure, item and duration metrics.
+// Call it before Start.
+func (s *Scheduler) SetMetrics(registry *metrics.Registry) {
+	s.metrics = &jobMetrics{
+		runs:     registry.NewCounterVec("job_runs_total", "Background job runs", "job"),
+		failures: registry.NewCounterVec("job_failures_total", "Background job runs that failed or panicked", "job"),
+		items:    registry.NewCounterVec("job_items_processed_total", "Items background jobs reported processing", "job"),
+		duration: registry.NewHistogramVec("job_duration_seconds", "Time a background job run took", jobDurationBuckets, "job"),
+	}
+}
+
+// Status reports every registered job with its recent runs on this instance
+func (s *Scheduler) Status() []JobStatus {
+	statuses := make([]JobStatus, len(s.jobs))
+	for i, job := range s.jobs {
+		statuses[i] = job.history.status(job)
+	}
+	return statuses
+}
+
+// RunNow runs the named job at once, after any run of it in progress, and
+// returns how it went. The run counts in the job's history like any other.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (*Run, error) {
+	for _, job := range s.jobs {
+		if job.Name == name {
+			s.logger.Info("Job run requested", zap.String("job", name))
+			return s.runOnce(ctx, job), nil
+		}
+	}
+	return nil, ErrJobNotFound
+}
+
+// Start launches one goroutine per registered job
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+
+	s.logger.Info("Job scheduler started", zap.Int("jobs", len(s.jobs)))
+}
+
+// Stop cancels all jobs and waits for running ones to finish
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	s.logger.Info("Job scheduler stopped")
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, job)
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, job *Job) *Run {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	state := &runState{id: newRunID()}
+	run := &Run{ID: state.id, Job: job.Name, Status: RunSucceeded, StartedAt: time.Now()}
+	job.history.start()
+
+	defer func() {
+		if r := recover(); r != nil {
+			run.Status = RunPanicked
+			run.Error = fmt.Sprint(r)
+			s.logger.Error("Job panicked",
+				zap.String("job", job.Name),
+				zap.String("run_id", run.ID),
+				zap.Any("panic", r),
+			)
+		}
+		s.finish(job, run, state)
+	}()
+
+	if err := job.Run(context.WithValue(ctx, runKey{}, state)); err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+		s.logger.Error("Job failed",
+			zap.String("job", job.Name),
+			zap.String("run_id", run.ID),
+			zap.Duration("duration", time.Since(run.StartedAt)),
+			zap.Error(err),
+		)
+	}
+	return run
+}
+
+// finish records a run in the job's history and metrics
+func (s *Scheduler) finish(job *Job, run *Run, state *runState) {
+	run.FinishedAt = time.Now()
+	run.DurationMS = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Items = state.items.Load()
+
+	job.history.finish(run)
+	s.metrics.observe(run)
+
+	if run.Status == RunSucceeded {
+		s.logger.Debug("Job finished",
+			zap.String("job", job.Name),
+			zap.String("run_id", run.ID),
+			zap.Duration("duration", run.FinishedAt.Sub(run.StartedAt)),
+			zap.Int64("items", run.Items),
+		)
+	}
+}
@@ -0,0 +1,181 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+func TestScheduler_RunsJobPeriodically(t *testing.T) {
+	scheduler := NewScheduler(zap.NewNop())
+
+	var runs int32
+	scheduler.Register("counter", 10*time.Millisecond, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+
+	scheduler.Start(context.Background())
+	time.Sleep(55 * time.Millisecond)
+	scheduler.Stop()
+
+	if atomic.LoadInt32(&runs) < 2 {
+		t.Errorf("Expected job to run at least twice, got %d", runs)
+	}
+}
+
+func TestScheduler_StopHaltsJobs(t *testing.T) {
+	scheduler := NewScheduler(zap.NewNop())
+
+	var runs int32
+	scheduler.Register("counter", 5*time.Millisecond, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+
+	scheduler.Start(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	scheduler.Stop()
+
+	after := atomic.LoadInt32(&runs)
+	time.Sleep(20 * time.Millisecond)
+
+	if atomic.LoadInt32(&runs) != after {
+		t.Error("Expected no runs after Stop")
+	}
+}
+
+func TestScheduler_SurvivesErrorsAndPanics(t *testing.T) {
+	scheduler := NewScheduler(zap.NewNop())
+
+	var runs int32
+	scheduler.Register("flaky", 5*time.Millisecond, func(ctx context.Context) error {
+		n := atomic.AddInt32(&runs, 1)
+		if n == 1 {
+			panic("boom")
+		}
+		return errors.New("failed")
+	})
+
+	scheduler.Start(context.Background())
+	time.Sleep(30 * time.Millisecond)
+	scheduler.Stop()
+
+	if atomic.LoadInt32(&runs) < 2 {
+		t.Errorf("Expected job to keep running after panic, got %d runs", runs)
+	}
+}
+
+func TestScheduler_RecordsRuns(t *testing.T) {
+	scheduler := NewScheduler(zap.NewNop())
+	registry := metrics.NewRegistry()
+	scheduler.SetMetrics(registry)
+
+	var runIDs []string
+	scheduler.Register("sweep", time.Hour, func(ctx context.Context) error {
+		runIDs = append(runIDs, RunID(ctx))
+		AddItems(ctx, 3)
+		AddItems(ctx, 2)
+		if len(runIDs) == 2 {
+			return errors.New("failed")
+		}
+		if len(runIDs) == 3 {
+			panic("boom")
+		}
+		return nil
+	})
+
+	job := scheduler.jobs[0]
+	for i := 0; i < 3; i++ {
+		scheduler.runOnce(context.Background(), job)
+	}
+
+	status := scheduler.Status()[0]
+	if status.Name != "sweep" || status.Runs != 3 || status.Failures != 2 || status.Items != 15 || status.Running {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if len(status.RecentRuns) != 3 || status.LastRun != status.RecentRuns[0] {
+		t.Fatalf("Expected 3 recent runs newest first, got %+v", status.RecentRuns)
+	}
+	wantStatuses := []RunStatus{RunPanicked, RunFailed, RunSucceeded}
+	for i, run := range status.RecentRuns {
+		if run.Status != wantStatuses[i] {
+			t.Errorf("Expected run %d to be %s, got %s", i, wantStatuses[i], run.Status)
+		}
+		if run.ID == "" || run.ID != runIDs[len(runIDs)-1-i] {
+			t.Errorf("Expected run %d to carry the ID the job saw, got %q", i, run.ID)
+		}
+		if run.Items != 5 {
+			t.Errorf("Expected 5 items, got %d", run.Items)
+		}
+	}
+	if status.RecentRuns[0].Error != "boom" || status.RecentRuns[1].Error != "failed" {
+		t.Errorf("Expected errors to be recorded, got %+v", status.RecentRuns)
+	}
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	for _, want := range []string{
+		`job_runs_total{job="sweep"} 3`,
+		`job_failures_total{job="sweep"} 2`,
+		`job_items_processed_total{job="sweep"} 15`,
+		`job_duration_seconds_count{job="sweep"} 3`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %s in metrics output", want)
+		}
+	}
+}
+
+func TestScheduler_KeepsRecentRuns(t *testing.T) {
+	scheduler := NewScheduler(zap.NewNop())
+	scheduler.Register("noop", time.Hour, func(ctx context.Context) error { return nil })
+
+	for i := 0; i < recentRunsPerJob+5; i++ {
+		scheduler.runOnce(context.Background(), scheduler.jobs[0])
+	}
+
+	status := scheduler.Status()[0]
+	if status.Runs != recentRunsPerJob+5 || len(status.RecentRuns) != recentRunsPerJob {
+		t.Errorf("Expected %d runs and %d kept, got %d and %d", recentRunsPerJob+5, recentRunsPerJob, status.Runs, len(status.RecentRuns))
+	}
+}
+
+func TestAddItems_OutsideRun(t *testing.T) {
+	// Must not panic when a job function is called directly
+	AddItems(context.Background(), 1)
+	if RunID(context.Background()) != "" {
+		t.Error("Expected no run ID outside a run")
+	}
+}
+
+func TestScheduler_RunNow(t *testing.T) {
+	scheduler := NewScheduler(zap.NewNop())
+	scheduler.Register("purge", time.Hour, func(ctx context.Context) error {
+		AddItems(ctx, 3)
+		return nil
+	})
+
+	run, err := scheduler.RunNow(context.Background(), "purge")
+	if err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	if run.Status != RunSucceeded || run.Items != 3 || run.FinishedAt.IsZero() {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+	if status := scheduler.Status()[0]; status.Runs != 1 || status.LastRun.ID != run.ID {
+		t.Errorf("Expected the run in the job's history, got %+v", status)
+	}
+
+	if _, err := scheduler.RunNow(context.Background(), "missing"); err != ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
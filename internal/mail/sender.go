@@ -0,0 +1,135 @@
+// Package mail delivers rendered email. Bodies are produced by the
+// templates subpackage; a Sender only moves them to the recipient.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/go-demo/chat/internal/mail/templates"
+	"go.uber.org/zap"
+)
+
+// Sender delivers a rendered message to a single recipient
+type Sender interface {
+	Send(ctx context.Context, to string, msg *templates.Message) error
+}
+
+// SMTPConfig describes the outgoing mail server
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSender sends mail through an SMTP server, authenticating with PLAIN
+// auth when a username is configured
+type SMTPSender struct {
+	cfg  SMTPConfig
+	addr string
+}
+
+// NewSMTPSender creates an SMTP sender
+func NewSMTPSender(cfg SMTPConfig) *SMTPSender {
+	return &SMTPSender{
+		cfg:  cfg,
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+	}
+}
+
+// Send sends msg to the recipient as multipart/alternative text and HTML
+func (s *SMTPSender) Send(ctx context.Context, to string, msg *templates.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	body, err := encode(s.cfg.From, to, msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	if err := smtp.SendMail(s.addr, auth, s.cfg.From, []string{to}, body); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// LogSender writes mail to the log instead of delivering it. It is used
+// when no SMTP server is configured, e.g. in development.
+type LogSender struct {
+	logger *zap.Logger
+}
+
+// NewLogSender creates a sender that only logs
+func NewLogSender(logger *zap.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+// Send logs the message
+func (s *LogSender) Send(ctx context.Context, to string, msg *templates.Message) error {
+	s.logger.Info("Mail not sent, no SMTP server configured",
+		zap.String("to", to),
+		zap.String("subject", msg.Subject),
+		zap.String("text", msg.Text),
+	)
+	return nil
+}
+
+// encode builds the RFC 5322 message with a text and an HTML part
+func encode(from, to string, msg *templates.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if msg.Locale != "" {
+		fmt.Fprintf(&buf, "Content-Language: %s\r\n", msg.Locale)
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, p := range parts {
+		if p.body == "" {
+			continue
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode mail: %w", err)
+		}
+		if _, err := part.Write([]byte(p.body)); err != nil {
+			return nil, fmt.Errorf("failed to encode mail: %w", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode mail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
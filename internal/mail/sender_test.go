@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+
+	"github.com/go-demo/chat/internal/mail/templates"
+)
+
+func TestEncode(t *testing.T) {
+	raw, err := encode("no-reply@example.com", "bob@example.com", &templates.Message{
+		Locale:  "zh-TW",
+		Subject: "您已受邀加入 Go Chat",
+		HTML:    "<p>你好</p>",
+		Text:    "你好",
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse encoded message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "您已受邀加入 Go Chat" {
+		t.Errorf("Unexpected subject %q (%v)", subject, err)
+	}
+	if msg.Header.Get("To") != "bob@example.com" {
+		t.Errorf("Unexpected recipient %q", msg.Header.Get("To"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Unexpected content type %q (%v)", mediaType, err)
+	}
+
+	var types []string
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+	}
+
+	if len(types) != 2 || types[0] != "text/plain; charset=utf-8" || types[1] != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected parts %v", types)
+	}
+}
@@ -0,0 +1,39 @@
+package templates
+
+// Sample returns example variables for previewing a built-in template.
+// Custom templates get an empty set.
+func Sample(name string, site Site) map[string]interface{} {
+	switch name {
+	case Verification:
+		return map[string]interface{}{
+			"Link":      site.URL + "/verify-email?token=preview",
+			"ExpiresIn": "24h",
+		}
+	case PasswordReset:
+		return map[string]interface{}{
+			"Link":      site.URL + "/reset-password?token=preview",
+			"ExpiresIn": "1h",
+		}
+	case Digest:
+		return map[string]interface{}{
+			"UnreadCount": 15,
+			"Rooms": []map[string]interface{}{
+				{"Name": "general", "Unread": 8},
+				{"Name": "random", "Unread": 4},
+			},
+			"MoreRooms": 0,
+			"DMs": []map[string]interface{}{
+				{"Name": "bob", "Unread": 3},
+			},
+			"FriendRequests": []map[string]interface{}{
+				{"Name": "carol"},
+			},
+		}
+	case Invitation:
+		return map[string]interface{}{
+			"Link":              site.URL + "/login",
+			"TemporaryPassword": "preview-password",
+		}
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,317 @@
+// Package templates renders the localized HTML and plain-text bodies of
+// outgoing email. Templates are embedded in the binary; a deployment can
+// override any of them by placing files with the same layout in a directory:
+//
+//	layout.html.tmpl               shared HTML frame, defines "layout"
+//	<locale>/<name>.subject.tmpl   subject line
+//	<locale>/<name>.html.tmpl      HTML body, defines "body"
+//	<locale>/<name>.txt.tmpl       plain-text body
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed files
+var embedded embed.FS
+
+// DefaultLocale is used when neither the requested locale nor its base
+// language has a template
+const DefaultLocale = "zh-TW"
+
+// Built-in templates
+const (
+	Verification  = "verification"
+	PasswordReset = "password_reset"
+	Digest        = "digest"
+	Invitation    = "invitation"
+)
+
+const layoutFile = "layout.html.tmpl"
+
+// ErrTemplateNotFound is returned when no locale has the requested template
+var ErrTemplateNotFound = errors.New("mail template not found")
+
+// Site describes the deployment sending the mail
+type Site struct {
+	Name string
+	URL  string
+}
+
+// User describes the recipient
+type User struct {
+	Username    string
+	DisplayName string
+	Email       string
+}
+
+// Data is passed to every template. Vars carries template-specific values,
+// e.g. Link and ExpiresIn for verification mail.
+type Data struct {
+	Site Site
+	User User
+	Vars map[string]interface{}
+
+	// Filled in by Render
+	Locale  string
+	Subject string
+}
+
+// Message is a rendered email
+type Message struct {
+	Locale  string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type set struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// Renderer holds the parsed templates of every locale
+type Renderer struct {
+	site          Site
+	defaultLocale string
+	sets          map[string]map[string]*set // locale -> name -> templates
+}
+
+// New parses the embedded templates, overlaid with the files in overrideDir
+// when it is not empty. Parsing happens up front so a broken override fails
+// at startup rather than when the first mail is sent.
+func New(site Site, defaultLocale, overrideDir string) (*Renderer, error) {
+	base, err := fs.Sub(embedded, "files")
+	if err != nil {
+		return nil, err
+	}
+
+	var fsys fs.FS = base
+	if overrideDir != "" {
+		if _, err := os.Stat(overrideDir); err != nil {
+			return nil, fmt.Errorf("mail template dir: %w", err)
+		}
+		fsys = layered{top: os.DirFS(overrideDir), bottom: base}
+	}
+
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+
+	r := &Renderer{
+		site:          site,
+		defaultLocale: defaultLocale,
+		sets:          make(map[string]map[string]*set),
+	}
+	if err := r.load(fsys, base, overrideDir); err != nil {
+		return nil, err
+	}
+	if _, ok := r.sets[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no mail templates for default locale %q", defaultLocale)
+	}
+	return r, nil
+}
+
+func (r *Renderer) load(fsys, base fs.FS, overrideDir string) error {
+	layout, err := fs.ReadFile(fsys, layoutFile)
+	if err != nil {
+		return fmt.Errorf("failed to read mail layout: %w", err)
+	}
+
+	keys := make(map[[2]string]bool)
+	collect := func(src fs.FS) error {
+		matches, err := fs.Glob(src, "*/*.subject.tmpl")
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			locale, file := path.Split(m)
+			keys[[2]string{strings.TrimSuffix(locale, "/"), strings.TrimSuffix(file, ".subject.tmpl")}] = true
+		}
+		return nil
+	}
+	if err := collect(base); err != nil {
+		return err
+	}
+	if overrideDir != "" {
+		if err := collect(os.DirFS(overrideDir)); err != nil {
+			return err
+		}
+	}
+
+	for key := range keys {
+		locale, name := key[0], key[1]
+		s, err := parseSet(fsys, string(layout), locale, name)
+		if err != nil {
+			return err
+		}
+		if r.sets[locale] == nil {
+			r.sets[locale] = make(map[string]*set)
+		}
+		r.sets[locale][name] = s
+	}
+	return nil
+}
+
+func parseSet(fsys fs.FS, layout, locale, name string) (*set, error) {
+	read := func(part string) (string, error) {
+		file := path.Join(locale, name+"."+part+".tmpl")
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read mail template %s: %w", file, err)
+		}
+		return string(b), nil
+	}
+
+	subjectSrc, err := read("subject")
+	if err != nil {
+		return nil, err
+	}
+	htmlSrc, err := read("html")
+	if err != nil {
+		return nil, err
+	}
+	textSrc, err := read("txt")
+	if err != nil {
+		return nil, err
+	}
+
+	id := locale + "/" + name
+	s := &set{}
+	// A missing variable is a bug in the caller; fail instead of mailing "<no value>"
+	if s.subject, err = texttemplate.New(id + ".subject").Option("missingkey=error").Parse(subjectSrc); err != nil {
+		return nil, fmt.Errorf("failed to parse mail template %s subject: %w", id, err)
+	}
+	if s.html, err = htmltemplate.New(id + ".html").Option("missingkey=error").Parse(layout); err != nil {
+		return nil, fmt.Errorf("failed to parse mail layout: %w", err)
+	}
+	if _, err = s.html.Parse(htmlSrc); err != nil {
+		return nil, fmt.Errorf("failed to parse mail template %s html: %w", id, err)
+	}
+	if s.text, err = texttemplate.New(id + ".txt").Option("missingkey=error").Parse(textSrc); err != nil {
+		return nil, fmt.Errorf("failed to parse mail template %s text: %w", id, err)
+	}
+	return s, nil
+}
+
+// Render renders a template in the closest available locale: the requested
+// one, then its base language (en-US -> en), then the default locale.
+func (r *Renderer) Render(name, locale string, data *Data) (*Message, error) {
+	resolved, s := r.lookup(name, locale)
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	d := *data
+	if d.Site == (Site{}) {
+		d.Site = r.site
+	}
+	if d.User.DisplayName == "" {
+		d.User.DisplayName = d.User.Username
+	}
+	d.Locale = resolved
+
+	var buf bytes.Buffer
+	if err := s.subject.Execute(&buf, &d); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	d.Subject = strings.TrimSpace(buf.String())
+
+	msg := &Message{Locale: resolved, Subject: d.Subject}
+
+	buf.Reset()
+	if err := s.html.ExecuteTemplate(&buf, "layout", &d); err != nil {
+		return nil, fmt.Errorf("failed to render %s html: %w", name, err)
+	}
+	msg.HTML = buf.String()
+
+	buf.Reset()
+	if err := s.text.Execute(&buf, &d); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %w", name, err)
+	}
+	msg.Text = buf.String()
+
+	return msg, nil
+}
+
+func (r *Renderer) lookup(name, locale string) (string, *set) {
+	candidates := []string{locale}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	candidates = append(candidates, r.defaultLocale)
+
+	for _, l := range candidates {
+		if s, ok := r.sets[l][name]; ok {
+			return l, s
+		}
+	}
+	return "", nil
+}
+
+// Site returns the site variables templates are rendered with
+func (r *Renderer) Site() Site {
+	return r.site
+}
+
+// DefaultLocale returns the locale used when no better match exists
+func (r *Renderer) DefaultLocale() string {
+	return r.defaultLocale
+}
+
+// Names lists the available templates
+func (r *Renderer) Names() []string {
+	seen := make(map[string]bool)
+	for _, sets := range r.sets {
+		for name := range sets {
+			seen[name] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// Locales lists the locales that have at least one template
+func (r *Renderer) Locales() []string {
+	seen := make(map[string]bool, len(r.sets))
+	for locale := range r.sets {
+		seen[locale] = true
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// layered serves files from top, falling back to bottom
+type layered struct {
+	top    fs.FS
+	bottom fs.FS
+}
+
+func (l layered) Open(name string) (fs.File, error) {
+	f, err := l.top.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return l.bottom.Open(name)
+}
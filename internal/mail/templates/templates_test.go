@@ -0,0 +1,146 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSite = Site{Name: "Go Chat", URL: "https://chat.example.com"}
+
+func newTestRenderer(t *testing.T, overrideDir string) *Renderer {
+	t.Helper()
+	r, err := New(testSite, "", overrideDir)
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	return r
+}
+
+func TestRenderer_BuiltinsRenderWithSamples(t *testing.T) {
+	r := newTestRenderer(t, "")
+
+	for _, locale := range r.Locales() {
+		for _, name := range []string{Verification, PasswordReset, Digest, Invitation} {
+			msg, err := r.Render(name, locale, &Data{
+				User: User{Username: "alice"},
+				Vars: Sample(name, testSite),
+			})
+			if err != nil {
+				t.Fatalf("Render(%s, %s) failed: %v", name, locale, err)
+			}
+			if msg.Locale != locale {
+				t.Errorf("Expected locale %s, got %s", locale, msg.Locale)
+			}
+			if msg.Subject == "" || strings.Contains(msg.Subject, "\n") {
+				t.Errorf("Unexpected subject %q", msg.Subject)
+			}
+			if !strings.Contains(msg.HTML, "<html") || !strings.Contains(msg.HTML, "alice") {
+				t.Errorf("Expected HTML in layout with user name, got %q", msg.HTML)
+			}
+			if !strings.Contains(msg.Text, "alice") || strings.Contains(msg.Text, "<p>") {
+				t.Errorf("Unexpected text body %q", msg.Text)
+			}
+		}
+	}
+}
+
+func TestRenderer_LocaleFallback(t *testing.T) {
+	r := newTestRenderer(t, "")
+	data := &Data{User: User{Username: "bob"}, Vars: Sample(Verification, testSite)}
+
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{"en", "en"},
+		{"en-US", "en"},
+		{"en_GB", "en"},
+		{"fr", DefaultLocale},
+		{"", DefaultLocale},
+	}
+	for _, tt := range tests {
+		msg, err := r.Render(Verification, tt.locale, data)
+		if err != nil {
+			t.Fatalf("Render(%q) failed: %v", tt.locale, err)
+		}
+		if msg.Locale != tt.expected {
+			t.Errorf("Render(%q) used %s, expected %s", tt.locale, msg.Locale, tt.expected)
+		}
+	}
+}
+
+func TestRenderer_EscapesHTML(t *testing.T) {
+	r := newTestRenderer(t, "")
+	msg, err := r.Render(Verification, "en", &Data{
+		User: User{DisplayName: "<script>x</script>"},
+		Vars: Sample(Verification, testSite),
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Error("Expected user values to be escaped in HTML")
+	}
+}
+
+func TestRenderer_Errors(t *testing.T) {
+	r := newTestRenderer(t, "")
+
+	if _, err := r.Render("nope", "en", &Data{}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+	if _, err := r.Render(Verification, "en", &Data{Vars: map[string]interface{}{}}); err == nil {
+		t.Error("Expected missing variables to fail")
+	}
+}
+
+func TestRenderer_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		p := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Replace one part of a built-in and add a new locale
+	write("en/verification.subject.tmpl", "Welcome aboard, {{.User.DisplayName}}")
+	write("ja/verification.subject.tmpl", "メール確認")
+	write("ja/verification.html.tmpl", `{{define "body"}}<p>{{.Vars.Link}}</p>{{end}}`)
+	write("ja/verification.txt.tmpl", "{{.Vars.Link}}")
+
+	r := newTestRenderer(t, dir)
+	data := &Data{User: User{Username: "carol"}, Vars: Sample(Verification, testSite)}
+
+	msg, err := r.Render(Verification, "en", data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if msg.Subject != "Welcome aboard, carol" {
+		t.Errorf("Expected overridden subject, got %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "Please confirm") {
+		t.Error("Expected non-overridden parts to come from the built-ins")
+	}
+
+	msg, err = r.Render(Verification, "ja", data)
+	if err != nil || msg.Locale != "ja" {
+		t.Fatalf("Expected new locale to render, got %v, %v", msg, err)
+	}
+
+	// A locale missing parts fails at startup
+	write("de/verification.subject.tmpl", "Hallo")
+	if _, err := New(testSite, "", dir); err == nil {
+		t.Error("Expected incomplete override to fail")
+	}
+
+	if _, err := New(testSite, "", filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected missing override dir to fail")
+	}
+}
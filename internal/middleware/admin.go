@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+)
+
+// AdminChecker reports whether a user is a system administrator
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID string) (bool, error)
+}
+
+// AdminOnly restricts a route group to system administrators.
+// It must run after Auth so the user ID is available.
+func AdminOnly(checker AdminChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := GetUserID(c)
+		if userID == "" {
+			response.Unauthorized(c, "")
+			c.Abort()
+			return
+		}
+
+		isAdmin, err := checker.IsAdmin(c.Request.Context(), userID)
+		if err != nil {
+			response.Error(c, err)
+			c.Abort()
+			return
+		}
+
+		if !isAdmin {
+			response.Forbidden(c, "需要管理員權限")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
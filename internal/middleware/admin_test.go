@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubAdminChecker struct {
+	admins map[string]bool
+	err    error
+}
+
+func (s *stubAdminChecker) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	return s.admins[userID], s.err
+}
+
+func performAdminRequest(checker AdminChecker, userID string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID != "" {
+			c.Set(UserIDKey, userID)
+		}
+		c.Next()
+	})
+	router.Use(AdminOnly(checker))
+	router.GET("/admin", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminOnly_Admin(t *testing.T) {
+	checker := &stubAdminChecker{admins: map[string]bool{"admin-1": true}}
+
+	w := performAdminRequest(checker, "admin-1")
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestAdminOnly_NonAdmin(t *testing.T) {
+	checker := &stubAdminChecker{admins: map[string]bool{"admin-1": true}}
+
+	w := performAdminRequest(checker, "user-1")
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+func TestAdminOnly_Unauthenticated(t *testing.T) {
+	w := performAdminRequest(&stubAdminChecker{}, "")
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestAdminOnly_CheckerError(t *testing.T) {
+	w := performAdminRequest(&stubAdminChecker{err: errors.New("db down")}, "user-1")
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/anomaly"
+)
+
+// ClientIPContext stores the client address in the request context, where
+// services attribute abuse-prone activity to the sender's network
+func ClientIPContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(anomaly.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -16,8 +17,22 @@ const (
 	ClaimsKey           = "claims"
 )
 
-// Auth creates a JWT authentication middleware
-func Auth(jwtManager *utils.JWTManager) gin.HandlerFunc {
+// AccountChecker rejects authenticated users whose account may not be used,
+// e.g. banned or suspended users
+type AccountChecker interface {
+	CheckAccount(ctx context.Context, userID string) error
+}
+
+// Auth creates a JWT authentication middleware.
+// Optional account checkers run after the token has been validated.
+func Auth(jwtManager *utils.JWTManager, checkers ...AccountChecker) gin.HandlerFunc {
+	return AuthWithCache(jwtManager, nil, checkers...)
+}
+
+// AuthWithCache creates a JWT authentication middleware that remembers
+// tokens which passed validation and the account checks, so their repeat
+// requests skip both. A nil cache validates every request.
+func AuthWithCache(jwtManager *utils.JWTManager, cache *AuthCache, checkers ...AccountChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader(AuthorizationHeader)
 		if authHeader == "" {
@@ -39,15 +54,29 @@ func Auth(jwtManager *utils.JWTManager) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := jwtManager.ValidateAccessToken(token)
-		if err != nil {
-			if err == utils.ErrExpiredToken {
-				response.Unauthorized(c, "Token 已過期")
-			} else {
-				response.Unauthorized(c, "無效的 Token")
+		claims, ok := cache.Get(token)
+		if !ok {
+			var err error
+			claims, err = jwtManager.ValidateAccessToken(token)
+			if err != nil {
+				if err == utils.ErrExpiredToken {
+					response.Unauthorized(c, "Token 已過期")
+				} else {
+					response.Unauthorized(c, "無效的 Token")
+				}
+				c.Abort()
+				return
 			}
-			c.Abort()
-			return
+
+			for _, checker := range checkers {
+				if err := checker.CheckAccount(c.Request.Context(), claims.UserID); err != nil {
+					response.Error(c, err)
+					c.Abort()
+					return
+				}
+			}
+
+			cache.Put(token, claims)
 		}
 
 		// Store user info in context
@@ -123,6 +152,15 @@ func GetClaims(c *gin.Context) *utils.Claims {
 	return claims.(*utils.Claims)
 }
 
+// GetDeviceID retrieves the device the access token was issued to, or ""
+// for tokens not bound to a device
+func GetDeviceID(c *gin.Context) string {
+	if claims := GetClaims(c); claims != nil {
+		return claims.DeviceID
+	}
+	return ""
+}
+
 // IsAuthenticated checks if user is authenticated
 func IsAuthenticated(c *gin.Context) bool {
 	_, exists := c.Get(UserIDKey)
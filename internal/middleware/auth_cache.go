@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/utils"
+)
+
+// DefaultAuthCacheSize bounds how many tokens an AuthCache remembers
+const DefaultAuthCacheSize = 100000
+
+// AuthCache remembers access tokens that passed validation and the account
+// checks, so repeat requests skip JWT parsing and the account lookups.
+// Entries live for the cache TTL but never past the token's expiry, and are
+// keyed by token hash so the cache never holds usable tokens.
+//
+// The cache is per instance: InvalidateUser takes effect immediately here,
+// while other instances notice a ban or deletion within one TTL.
+type AuthCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*authCacheEntry
+	byUser  map[string]map[string]bool // userID -> token hashes
+}
+
+type authCacheEntry struct {
+	claims    *utils.Claims
+	expiresAt time.Time
+}
+
+// NewAuthCache creates a cache keeping validation results for up to ttl.
+// A non-positive maxEntries uses DefaultAuthCacheSize.
+func NewAuthCache(ttl time.Duration, maxEntries int) *AuthCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultAuthCacheSize
+	}
+	return &AuthCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*authCacheEntry),
+		byUser:     make(map[string]map[string]bool),
+	}
+}
+
+// Get returns the claims of a token validated earlier, if still cached
+func (c *AuthCache) Get(token string) (*utils.Claims, bool) {
+	if c == nil {
+		return nil, false
+	}
+	key := authCacheKey(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		c.remove(key, entry.claims.UserID)
+		return nil, false
+	}
+	return entry.claims, true
+}
+
+// Put caches the claims of a token that passed validation. When the cache
+// is full and nothing has expired, the token is simply not cached.
+func (c *AuthCache) Put(token string, claims *utils.Claims) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(c.ttl)
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	if !now.Before(expiresAt) {
+		return
+	}
+	key := authCacheKey(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictExpired(now)
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+
+	c.entries[key] = &authCacheEntry{claims: claims, expiresAt: expiresAt}
+	if c.byUser[claims.UserID] == nil {
+		c.byUser[claims.UserID] = make(map[string]bool)
+	}
+	c.byUser[claims.UserID][key] = true
+}
+
+// InvalidateUser drops every cached token of the user, so their next
+// request is validated and checked again
+func (c *AuthCache) InvalidateUser(userID string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.byUser[userID] {
+		delete(c.entries, key)
+	}
+	delete(c.byUser, userID)
+}
+
+// remove deletes one entry; the caller holds mu
+func (c *AuthCache) remove(key, userID string) {
+	delete(c.entries, key)
+	if keys := c.byUser[userID]; keys != nil {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.byUser, userID)
+		}
+	}
+}
+
+// evictExpired deletes entries past their expiry; the caller holds mu
+func (c *AuthCache) evictExpired(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			c.remove(key, entry.claims.UserID)
+		}
+	}
+}
+
+func authCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
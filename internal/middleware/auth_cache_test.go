@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type countingAccountChecker struct {
+	fakeAccountChecker
+	calls int
+}
+
+func (f *countingAccountChecker) CheckAccount(ctx context.Context, userID string) error {
+	f.calls++
+	return f.fakeAccountChecker.CheckAccount(ctx, userID)
+}
+
+func TestAuthWithCache_SkipsChecksForCachedTokens(t *testing.T) {
+	router := setupTestRouter()
+	jwtManager := createTestJWTManager()
+	cache := NewAuthCache(time.Minute, 10)
+	checker := &countingAccountChecker{fakeAccountChecker: fakeAccountChecker{banned: map[string]bool{}}}
+
+	router.GET("/protected", AuthWithCache(jwtManager, cache, checker), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": GetUserID(c)})
+	})
+
+	tokenPair, _ := jwtManager.GenerateTokenPair("user-123", "testuser")
+	request := func() int {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := request(); code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, got %d", i, code)
+		}
+	}
+	if checker.calls != 1 {
+		t.Errorf("Expected the account to be checked once, got %d", checker.calls)
+	}
+
+	// A ban drops the cached token, so the next request is checked again
+	checker.banned["user-123"] = true
+	cache.InvalidateUser("user-123")
+	if code := request(); code != http.StatusForbidden {
+		t.Errorf("Expected status 403 after invalidation, got %d", code)
+	}
+	if _, ok := cache.Get(tokenPair.AccessToken); ok {
+		t.Error("Expected rejected tokens not to be cached")
+	}
+}
+
+func TestAuthCache_EntriesEndWithToken(t *testing.T) {
+	cache := NewAuthCache(time.Hour, 10)
+
+	claims := &utils.Claims{UserID: "user-123"}
+	// NewNumericDate rounds to seconds, which is too coarse here
+	claims.ExpiresAt = &jwt.NumericDate{Time: time.Now().Add(50 * time.Millisecond)}
+	cache.Put("token", claims)
+	if _, ok := cache.Get("token"); !ok {
+		t.Fatal("Expected the token to be cached")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := cache.Get("token"); ok {
+		t.Error("Expected the entry to expire with the token")
+	}
+
+	expired := &utils.Claims{UserID: "user-123"}
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Second))
+	cache.Put("expired", expired)
+	if _, ok := cache.Get("expired"); ok {
+		t.Error("Expected expired tokens not to be cached")
+	}
+}
+
+func TestAuthCache_Bounded(t *testing.T) {
+	cache := NewAuthCache(time.Minute, 2)
+	for _, token := range []string{"a", "b", "c"} {
+		cache.Put(token, &utils.Claims{UserID: "user-" + token})
+	}
+
+	if _, ok := cache.Get("c"); ok {
+		t.Error("Expected a full cache to skip new tokens")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("Expected earlier tokens to stay cached")
+	}
+}
+
+func TestAuthCache_Nil(t *testing.T) {
+	var cache *AuthCache
+	cache.Put("token", &utils.Claims{UserID: "user-123"})
+	cache.InvalidateUser("user-123")
+	if _, ok := cache.Get("token"); ok {
+		t.Error("Expected a nil cache to never hit")
+	}
+}
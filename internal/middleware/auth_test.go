@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 	"github.com/go-demo/chat/internal/pkg/utils"
 )
 
@@ -151,6 +153,49 @@ func TestOptionalAuth_NoToken(t *testing.T) {
 	}
 }
 
+type fakeAccountChecker struct {
+	banned map[string]bool
+}
+
+func (f *fakeAccountChecker) CheckAccount(ctx context.Context, userID string) error {
+	if f.banned[userID] {
+		return apperrors.ErrUserBanned
+	}
+	return nil
+}
+
+func TestAuth_AccountChecker(t *testing.T) {
+	router := setupTestRouter()
+	jwtManager := createTestJWTManager()
+	checker := &fakeAccountChecker{banned: map[string]bool{"banned-user": true}}
+
+	router.GET("/protected", Auth(jwtManager, checker), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	tests := []struct {
+		userID   string
+		expected int
+	}{
+		{"banned-user", http.StatusForbidden},
+		{"good-user", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		tokenPair, _ := jwtManager.GenerateTokenPair(tt.userID, "testuser")
+
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("User %s: expected status %d, got %d", tt.userID, tt.expected, w.Code)
+		}
+	}
+}
+
 func TestOptionalAuth_ValidToken(t *testing.T) {
 	router := setupTestRouter()
 	jwtManager := createTestJWTManager()
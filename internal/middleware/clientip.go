@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trustedProxyKey marks requests whose peer is one of the trusted proxies
+const trustedProxyKey = "trusted_proxy"
+
+// TrustProxies sets which reverse proxies c.ClientIP believes. The
+// forwarding headers are read only on requests whose peer address is one of
+// proxies; with none, every client is identified by its connection address.
+// gin trusts any peer by default, which would let clients pick the address
+// the IP filter, access rules and rate limits see by sending their own
+// X-Forwarded-For. Empty headers keep gin's X-Forwarded-For and X-Real-IP.
+//
+// It also adds the middleware behind FromTrustedProxy, so call it before
+// registering any other middleware.
+func TrustProxies(router *gin.Engine, proxies, headers []string) error {
+	if len(headers) > 0 {
+		router.RemoteIPHeaders = headers
+	}
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return err
+	}
+
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		networks = append(networks, network)
+	}
+
+	router.Use(func(c *gin.Context) {
+		if ip := net.ParseIP(c.RemoteIP()); ip != nil {
+			for _, network := range networks {
+				if network.Contains(ip) {
+					c.Set(trustedProxyKey, true)
+					break
+				}
+			}
+		}
+		c.Next()
+	})
+	return nil
+}
+
+// FromTrustedProxy reports whether the request reached the server through
+// one of the proxies given to TrustProxies, so headers it sets can be
+// believed
+func FromTrustedProxy(c *gin.Context) bool {
+	return c.GetBool(trustedProxyKey)
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+)
+
+var (
+	// ErrConcurrencyLimit is returned when a user's slots and queue are full
+	ErrConcurrencyLimit = errors.New("too many concurrent requests")
+	// ErrConcurrencyTimeout is returned when no slot freed up in time
+	ErrConcurrencyTimeout = errors.New("timed out waiting for a request slot")
+)
+
+// ConcurrencyLimiter caps how many requests of one route group a user may
+// have in flight on this instance. Requests over the cap wait in a short
+// per-user queue; once the queue is full they fail fast.
+type ConcurrencyLimiter struct {
+	name     string
+	perUser  int
+	queue    int
+	maxWait  time.Duration
+	mu       sync.Mutex
+	users    map[string]*userSlots
+	inFlight atomic.Int64
+	waiting  atomic.Int64
+	admitted atomic.Int64
+	queued   atomic.Int64
+	rejected atomic.Int64
+	timedOut atomic.Int64
+}
+
+// userSlots holds one user's semaphore; refs counts the requests holding or
+// waiting for it so idle users can be forgotten
+type userSlots struct {
+	sem     chan struct{}
+	waiting int
+	refs    int
+}
+
+// NewConcurrencyLimiter creates a limiter allowing perUser requests at once
+// per user, with up to queue more waiting at most maxWait for a slot
+func NewConcurrencyLimiter(name string, perUser, queue int, maxWait time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		name:    name,
+		perUser: perUser,
+		queue:   queue,
+		maxWait: maxWait,
+		users:   make(map[string]*userSlots),
+	}
+}
+
+// Enabled reports whether the limiter caps anything
+func (l *ConcurrencyLimiter) Enabled() bool {
+	return l.perUser > 0
+}
+
+// Acquire takes a slot for key, waiting in the queue if needed. The returned
+// function releases the slot and must be called once the request is done.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	slots, ok := l.users[key]
+	if !ok {
+		slots = &userSlots{sem: make(chan struct{}, l.perUser)}
+		l.users[key] = slots
+	}
+	slots.refs++
+
+	select {
+	case slots.sem <- struct{}{}:
+		l.mu.Unlock()
+		l.admitted.Add(1)
+		return l.admit(key, slots), nil
+	default:
+	}
+
+	if slots.waiting >= l.queue {
+		l.forget(key, slots)
+		l.mu.Unlock()
+		l.rejected.Add(1)
+		return nil, ErrConcurrencyLimit
+	}
+	slots.waiting++
+	l.mu.Unlock()
+
+	l.waiting.Add(1)
+	timer := time.NewTimer(l.maxWait)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case slots.sem <- struct{}{}:
+	case <-timer.C:
+		err = ErrConcurrencyTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	l.waiting.Add(-1)
+
+	l.mu.Lock()
+	slots.waiting--
+	if err != nil {
+		l.forget(key, slots)
+		l.mu.Unlock()
+		if err == ErrConcurrencyTimeout {
+			l.timedOut.Add(1)
+		}
+		return nil, err
+	}
+	l.mu.Unlock()
+
+	l.admitted.Add(1)
+	l.queued.Add(1)
+	return l.admit(key, slots), nil
+}
+
+func (l *ConcurrencyLimiter) admit(key string, slots *userSlots) func() {
+	l.inFlight.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-slots.sem
+			l.inFlight.Add(-1)
+
+			l.mu.Lock()
+			l.forget(key, slots)
+			l.mu.Unlock()
+		})
+	}
+}
+
+// forget drops a reference to the user's slots; callers hold l.mu
+func (l *ConcurrencyLimiter) forget(key string, slots *userSlots) {
+	slots.refs--
+	if slots.refs == 0 {
+		delete(l.users, key)
+	}
+}
+
+// ConcurrencyStats is a point-in-time view of a limiter
+type ConcurrencyStats struct {
+	Name         string        `json:"name"`
+	PerUser      int           `json:"per_user"`
+	QueueSize    int           `json:"queue_size"`
+	QueueTimeout time.Duration `json:"queue_timeout"`
+	ActiveUsers  int           `json:"active_users"`
+	InFlight     int64         `json:"in_flight"`
+	Waiting      int64         `json:"waiting"`
+	Admitted     int64         `json:"admitted"`  // total, including those that queued first
+	Queued       int64         `json:"queued"`    // admitted after waiting for a slot
+	Rejected     int64         `json:"rejected"`  // failed fast because the queue was full
+	TimedOut     int64         `json:"timed_out"` // gave up waiting in the queue
+}
+
+// Stats returns the limiter's current load and counters since start
+func (l *ConcurrencyLimiter) Stats() ConcurrencyStats {
+	l.mu.Lock()
+	activeUsers := len(l.users)
+	l.mu.Unlock()
+
+	return ConcurrencyStats{
+		Name:         l.name,
+		PerUser:      l.perUser,
+		QueueSize:    l.queue,
+		QueueTimeout: l.maxWait,
+		ActiveUsers:  activeUsers,
+		InFlight:     l.inFlight.Load(),
+		Waiting:      l.waiting.Load(),
+		Admitted:     l.admitted.Load(),
+		Queued:       l.queued.Load(),
+		Rejected:     l.rejected.Load(),
+		TimedOut:     l.timedOut.Load(),
+	}
+}
+
+// ConcurrencyLimit limits the routes it wraps with limiter, keyed by user ID
+// or, for anonymous requests, client IP. A disabled limiter lets everything
+// through.
+func ConcurrencyLimit(limiter *ConcurrencyLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limiter.Enabled() {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + c.ClientIP()
+		if userID := GetUserID(c); userID != "" {
+			key = "user:" + userID
+		}
+
+		release, err := limiter.Acquire(c.Request.Context(), key)
+		if err != nil {
+			if c.Request.Context().Err() != nil {
+				// The client went away while queued; nobody reads a response
+				c.Abort()
+				return
+			}
+			c.Header("Retry-After", "1")
+			response.ErrorWithStatus(c, http.StatusTooManyRequests, "同時進行的請求過多，請稍後再試")
+			c.Abort()
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
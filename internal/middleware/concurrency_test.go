@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConcurrencyLimiter_QueuesThenRejects(t *testing.T) {
+	limiter := NewConcurrencyLimiter("search", 1, 1, time.Second)
+	ctx := context.Background()
+
+	release, err := limiter.Acquire(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("Expected the first request to be admitted, got %v", err)
+	}
+
+	// Another user has their own slots
+	other, err := limiter.Acquire(ctx, "user-2")
+	if err != nil {
+		t.Fatalf("Expected another user to be admitted, got %v", err)
+	}
+	other()
+
+	queued := make(chan error, 1)
+	go func() {
+		release, err := limiter.Acquire(ctx, "user-1")
+		if err == nil {
+			release()
+		}
+		queued <- err
+	}()
+
+	// Wait for the second request to enter the queue
+	for limiter.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := limiter.Acquire(ctx, "user-1"); err != ErrConcurrencyLimit {
+		t.Errorf("Expected ErrConcurrencyLimit with a full queue, got %v", err)
+	}
+
+	release()
+	release() // releasing twice is harmless
+	if err := <-queued; err != nil {
+		t.Errorf("Expected the queued request to be admitted, got %v", err)
+	}
+
+	stats := limiter.Stats()
+	if stats.Admitted != 3 || stats.Queued != 1 || stats.Rejected != 1 || stats.InFlight != 0 || stats.ActiveUsers != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestConcurrencyLimiter_QueueTimeout(t *testing.T) {
+	limiter := NewConcurrencyLimiter("export", 1, 1, 10*time.Millisecond)
+
+	release, err := limiter.Acquire(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+	defer release()
+
+	if _, err := limiter.Acquire(context.Background(), "user-1"); err != ErrConcurrencyTimeout {
+		t.Errorf("Expected ErrConcurrencyTimeout, got %v", err)
+	}
+	if stats := limiter.Stats(); stats.TimedOut != 1 || stats.Waiting != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestConcurrencyLimit_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewConcurrencyLimiter("search", 1, 0, time.Second)
+
+	entered := make(chan struct{})
+	done := make(chan struct{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(UserIDKey, "user-1")
+		c.Next()
+	})
+	router.GET("/search", ConcurrencyLimit(limiter), func(c *gin.Context) {
+		if c.Query("slow") != "" {
+			close(entered)
+			<-done
+		}
+		c.String(http.StatusOK, "OK")
+	})
+
+	go router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/search?slow=1", nil))
+	<-entered
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/search", nil))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d", w.Code)
+	}
+
+	close(done)
+	for limiter.Stats().InFlight != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/search", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 once the slot is free, got %d", w.Code)
+	}
+}
+
+func TestConcurrencyLimit_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/export", ConcurrencyLimit(NewConcurrencyLimiter("export", 0, 0, 0)), func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/export", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected a disabled limiter to let requests through, got %d", w.Code)
+	}
+}
@@ -32,11 +32,13 @@ func DefaultCORSConfig() *CORSConfig {
 			"Authorization",
 			"X-Request-ID",
 			"X-Requested-With",
+			"Upload-Offset",
 		},
 		ExposeHeaders: []string{
 			"Content-Length",
 			"Content-Type",
 			"X-Request-ID",
+			"Upload-Offset",
 		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/ipfilter"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+)
+
+// ClientCountryKey holds the country IPAccess resolved for the request
+const ClientCountryKey = "client_country"
+
+// IPAccessChecker decides which requests may reach a scope.
+// It is implemented by ipfilter.AccessList.
+type IPAccessChecker interface {
+	Country(ip string, header http.Header) string
+	Check(scope, ip, country string) *ipfilter.Denial
+}
+
+var ipAccessMessages = map[string]string{
+	ipfilter.ReasonIPDenied:      "您的 IP 位址已被限制存取",
+	ipfilter.ReasonCountryDenied: "您所在的國家或地區無法存取此服務",
+	ipfilter.ReasonNotAllowed:    "您的 IP 位址不在允許存取的範圍內",
+}
+
+// IPAccess refuses requests the access rules of scope do not admit. Use it
+// globally with the global scope and on a route group with that group's
+// scope. The 403 response carries the scope, reason, IP and country in
+// error.details.
+//
+// The client IP comes from the proxies set with TrustProxies, and a country
+// header is only believed on requests those proxies forwarded; anyone else
+// could send their own to escape country rules.
+func IPAccess(checker IPAccessChecker, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		country, ok := c.Get(ClientCountryKey)
+		if !ok {
+			var header http.Header
+			if FromTrustedProxy(c) {
+				header = c.Request.Header
+			}
+			country = checker.Country(ip, header)
+			c.Set(ClientCountryKey, country)
+		}
+
+		if denial := checker.Check(scope, ip, country.(string)); denial != nil {
+			response.Error(c, apperrors.New(http.StatusForbidden, ipAccessMessages[denial.Reason]).WithDetails(denial))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetClientCountry returns the country of the request, or "" when it is
+// unknown or IPAccess did not run
+func GetClientCountry(c *gin.Context) string {
+	country, _ := c.Get(ClientCountryKey)
+	s, _ := country.(string)
+	return s
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/ipfilter"
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+func setupIPAccessRouter(t *testing.T, list *ipfilter.AccessList, proxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := TrustProxies(router, proxies, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	router.Use(IPAccess(list, model.IPAccessScopeGlobal))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, GetClientCountry(c))
+	})
+	admin := router.Group("/admin", IPAccess(list, model.IPAccessScopeAdmin))
+	admin.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestIPAccess(t *testing.T) {
+	var rules []*model.IPAccessRule
+	for _, s := range []string{"global deny 10.0.0.1", "admin allow 192.0.2.0/24", "global deny KP"} {
+		rule, err := ipfilter.ParseRule(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	list := ipfilter.NewAccessList(nil, rules, nil, zap.NewNop())
+	list.SetCountryHeader("CF-IPCountry")
+
+	// The country header is set by the proxy in front of 203.0.113.0/24
+	router := setupIPAccessRouter(t, list, []string{"203.0.113.0/24"})
+
+	tests := []struct {
+		path, remoteAddr, country string
+		expected                  int
+		reason                    string
+	}{
+		{"/test", "203.0.113.7:1234", "", http.StatusOK, ""},
+		{"/test", "10.0.0.1:1234", "", http.StatusForbidden, ipfilter.ReasonIPDenied},
+		{"/test", "203.0.113.7:1234", "KP", http.StatusForbidden, ipfilter.ReasonCountryDenied},
+		{"/admin/test", "192.0.2.10:1234", "", http.StatusOK, ""},
+		{"/admin/test", "203.0.113.7:1234", "", http.StatusForbidden, ipfilter.ReasonNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.country != "" {
+			req.Header.Set("CF-IPCountry", tt.country)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("%s from %s: expected status %d, got %d", tt.path, tt.remoteAddr, tt.expected, w.Code)
+			continue
+		}
+		if tt.reason == "" {
+			continue
+		}
+		var body struct {
+			Error struct {
+				Details ipfilter.Denial `json:"details"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if body.Error.Details.Reason != tt.reason || body.Error.Details.Country != tt.country {
+			t.Errorf("%s from %s: unexpected details %+v", tt.path, tt.remoteAddr, body.Error.Details)
+		}
+	}
+}
+
+func TestIPAccess_SpoofedHeaders(t *testing.T) {
+	var rules []*model.IPAccessRule
+	for _, s := range []string{"global deny 10.0.0.1", "admin allow 192.0.2.0/24", "global deny KP"} {
+		rule, err := ipfilter.ParseRule(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	list := ipfilter.NewAccessList(nil, rules, nil, zap.NewNop())
+	list.SetCountryHeader("CF-IPCountry")
+
+	// No trusted proxies: the headers below are the client's own claims
+	router := setupIPAccessRouter(t, list, nil)
+
+	tests := []struct {
+		name, path, remoteAddr string
+		header                 map[string]string
+		expected               int
+		country                string
+	}{
+		{"denied IP claims another", "/test", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, http.StatusForbidden, ""},
+		{"denied IP via X-Real-IP", "/test", "10.0.0.1:1234", map[string]string{"X-Real-IP": "203.0.113.7"}, http.StatusForbidden, ""},
+		{"outsider claims allowed range", "/admin/test", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "192.0.2.10"}, http.StatusForbidden, ""},
+		{"country header ignored", "/test", "198.51.100.1:1234", map[string]string{"CF-IPCountry": "KP"}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+			if w.Code == http.StatusOK && w.Body.String() != tt.country {
+				t.Errorf("Expected country %q, got %q", tt.country, w.Body.String())
+			}
+		})
+	}
+}
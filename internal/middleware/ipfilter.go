@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+)
+
+// IPBlocker reports whether requests from an IP are refused
+type IPBlocker interface {
+	Blocked(ip string) (blocked, tarpit bool)
+}
+
+// IPFilter refuses requests from denylisted IPs. It runs before Auth so
+// blocked clients never reach token validation. Bans marked for tarpitting
+// hold the request for tarpitDelay before answering, which slows down
+// scanners that retry immediately.
+func IPFilter(blocker IPBlocker, tarpitDelay time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		blocked, tarpit := blocker.Blocked(c.ClientIP())
+		if !blocked {
+			c.Next()
+			return
+		}
+
+		if tarpit && tarpitDelay > 0 {
+			timer := time.NewTimer(tarpitDelay)
+			select {
+			case <-timer.C:
+			case <-c.Request.Context().Done():
+				timer.Stop()
+			}
+		}
+
+		response.Forbidden(c, "您的 IP 位址已被限制存取")
+		c.Abort()
+	}
+}
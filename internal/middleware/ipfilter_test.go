@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeIPBlocker map[string]bool // ip -> tarpit
+
+func (f fakeIPBlocker) Blocked(ip string) (bool, bool) {
+	tarpit, ok := f[ip]
+	return ok, tarpit
+}
+
+func setupIPFilterRouter(blocker IPBlocker, delay time.Duration) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(IPFilter(blocker, delay))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return router
+}
+
+func TestIPFilter(t *testing.T) {
+	router := setupIPFilterRouter(fakeIPBlocker{"10.0.0.1": false, "10.0.0.2": true}, 50*time.Millisecond)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		expected   int
+		slow       bool
+	}{
+		{"allowed", "10.0.0.9:1234", http.StatusOK, false},
+		{"blocked", "10.0.0.1:1234", http.StatusForbidden, false},
+		{"tarpitted", "10.0.0.2:1234", http.StatusForbidden, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+
+			start := time.Now()
+			router.ServeHTTP(w, req)
+			elapsed := time.Since(start)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+			if tt.slow && elapsed < 50*time.Millisecond {
+				t.Errorf("Expected tarpit delay, took %v", elapsed)
+			}
+			if !tt.slow && elapsed >= 50*time.Millisecond {
+				t.Errorf("Expected immediate response, took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestIPFilter_ForwardedFor(t *testing.T) {
+	blocker := fakeIPBlocker{"10.0.0.1": false}
+
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		forwarded  string
+		expected   int
+	}{
+		// Without trusted proxies the header is the client's own claim
+		{"spoofed by banned client", nil, "10.0.0.1:1234", "203.0.113.7", http.StatusForbidden},
+		{"banned address claimed", nil, "203.0.113.7:1234", "10.0.0.1", http.StatusOK},
+		{"untrusted proxy", []string{"192.0.2.1"}, "10.0.0.1:1234", "203.0.113.7", http.StatusForbidden},
+		{"trusted proxy", []string{"192.0.2.0/24"}, "192.0.2.1:1234", "10.0.0.1", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupIPFilterRouter(blocker, 0)
+			if err := TrustProxies(router, tt.proxies, nil); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestTrustProxies_Invalid(t *testing.T) {
+	if err := TrustProxies(gin.New(), []string{"not-an-ip"}, nil); err == nil {
+		t.Error("Expected an error for an invalid proxy address")
+	}
+}
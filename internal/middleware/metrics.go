@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/response"
+	"github.com/go-demo/chat/internal/pkg/metrics"
+)
+
+// Metrics creates a middleware counting and timing requests by route
+// pattern, so /rooms/:id stays one series however many rooms there are
+func Metrics(registry *metrics.Registry) gin.HandlerFunc {
+	requests := registry.NewCounterVec("http_requests_total", "HTTP requests by method, route and status", "method", "route", "status")
+	duration := registry.NewHistogramVec("http_request_duration_seconds", "HTTP request latency by method and route", metrics.DefBuckets, "method", "route")
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		duration.WithLabelValues(c.Request.Method, route).ObserveSince(start)
+	}
+}
+
+// MetricsToken protects the metrics endpoint with a static bearer token.
+// An empty token leaves it open, for scrapers on a trusted network.
+func MetricsToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		given := strings.TrimPrefix(c.GetHeader(AuthorizationHeader), BearerPrefix)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			response.Unauthorized(c, "無效的認證 Token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/pkg/metrics"
+)
+
+func TestMetrics_CountsByRoute(t *testing.T) {
+	router := setupTestRouter()
+	registry := metrics.NewRegistry()
+	router.Use(Metrics(registry))
+	router.GET("/rooms/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/rooms/a", "/rooms/b", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	var b strings.Builder
+	registry.WriteTo(&b)
+	output := b.String()
+	for _, series := range []string{
+		`http_requests_total{method="GET",route="/rooms/:id",status="200"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/rooms/:id"} 2`,
+	} {
+		if !strings.Contains(output, series) {
+			t.Errorf("Expected %s in:\n%s", series, output)
+		}
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/metrics", MetricsToken("secret"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		header   string
+		expected int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.expected {
+			t.Errorf("Header %q: expected status %d, got %d", tt.header, tt.expected, w.Code)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"time"
@@ -61,10 +63,16 @@ func NewRedisRateLimiter(client *redis.Client, requests int, window time.Duratio
 
 // Allow checks if request is allowed using Redis sliding window
 func (l *RedisRateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return l.AllowWithin(ctx, key, l.requests, l.window)
+}
+
+// AllowWithin is Allow with a limit chosen per call, for limits that vary
+// by key such as room rate limits
+func (l *RedisRateLimiter) AllowWithin(ctx context.Context, key string, requests int, window time.Duration) (bool, error) {
 	pipe := l.client.Pipeline()
 
 	now := time.Now().UnixNano()
-	windowStart := now - l.window.Nanoseconds()
+	windowStart := now - window.Nanoseconds()
 
 	// Remove old entries
 	pipe.ZRemRangeByScore(ctx, key, "0", fmt.Sprintf("%d", windowStart))
@@ -79,7 +87,7 @@ func (l *RedisRateLimiter) Allow(ctx context.Context, key string) (bool, error)
 	countCmd := pipe.ZCard(ctx, key)
 
 	// Set expiration
-	pipe.Expire(ctx, key, l.window)
+	pipe.Expire(ctx, key, window)
 
 	_, err := pipe.Exec(ctx)
 	if err != nil {
@@ -91,7 +99,7 @@ func (l *RedisRateLimiter) Allow(ctx context.Context, key string) (bool, error)
 		return false, err
 	}
 
-	return count <= int64(l.requests), nil
+	return count <= int64(requests), nil
 }
 
 // RateLimitConfig represents rate limit configuration
@@ -178,3 +186,72 @@ func MessageRateLimit(client *redis.Client) gin.HandlerFunc {
 	}
 	return RateLimitWithConfig(limiter, config)
 }
+
+// FeedRateLimit creates a per-IP rate limit for public, unauthenticated feeds
+func FeedRateLimit(client *redis.Client, requests int) gin.HandlerFunc {
+	limiter := NewRedisRateLimiter(client, requests, time.Minute)
+	config := &RateLimitConfig{
+		Requests: requests,
+		Window:   time.Minute,
+		KeyFunc: func(c *gin.Context) string {
+			return "ratelimit:feed:" + c.ClientIP()
+		},
+	}
+	return RateLimitWithConfig(limiter, config)
+}
+
+// IncomingWebhookRateLimit creates a per-token limit for incoming webhooks.
+// Keys hold a hash of the token rather than the token itself.
+func IncomingWebhookRateLimit(client *redis.Client) gin.HandlerFunc {
+	limiter := NewRedisRateLimiter(client, 30, time.Minute)
+	config := &RateLimitConfig{
+		Requests: 30,
+		Window:   time.Minute,
+		KeyFunc: func(c *gin.Context) string {
+			sum := sha256.Sum256([]byte(c.Param("token")))
+			return "ratelimit:incoming_webhook:" + hex.EncodeToString(sum[:])
+		},
+	}
+	return RateLimitWithConfig(limiter, config)
+}
+
+// FeedbackRateLimit creates a per-user hourly limit for feedback reports
+func FeedbackRateLimit(client *redis.Client) gin.HandlerFunc {
+	limiter := NewRedisRateLimiter(client, 10, time.Hour)
+	config := &RateLimitConfig{
+		Requests: 10,
+		Window:   time.Hour,
+		KeyFunc: func(c *gin.Context) string {
+			return "ratelimit:feedback:" + GetUserID(c)
+		},
+	}
+	return RateLimitWithConfig(limiter, config)
+}
+
+// DiscoverRateLimit creates a per-user hourly limit for contacts discovery,
+// so email hashes cannot be used to enumerate accounts
+func DiscoverRateLimit(client *redis.Client) gin.HandlerFunc {
+	limiter := NewRedisRateLimiter(client, 10, time.Hour)
+	config := &RateLimitConfig{
+		Requests: 10,
+		Window:   time.Hour,
+		KeyFunc: func(c *gin.Context) string {
+			return "ratelimit:discover:" + GetUserID(c)
+		},
+	}
+	return RateLimitWithConfig(limiter, config)
+}
+
+// ReportRateLimit creates a per-user hourly limit for reports about
+// messages and users
+func ReportRateLimit(client *redis.Client) gin.HandlerFunc {
+	limiter := NewRedisRateLimiter(client, 20, time.Hour)
+	config := &RateLimitConfig{
+		Requests: 20,
+		Window:   time.Hour,
+		KeyFunc: func(c *gin.Context) string {
+			return "ratelimit:report:" + GetUserID(c)
+		},
+	}
+	return RateLimitWithConfig(limiter, config)
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/scim"
+)
+
+// SCIMToken protects the SCIM API with the static bearer token configured
+// in the identity provider. The Bearer scheme is matched case-insensitively,
+// as some providers send it in lower case. Failures are answered as SCIM
+// errors.
+func SCIMToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, given, ok := strings.Cut(c.GetHeader(AuthorizationHeader), " ")
+		if !ok || !strings.EqualFold(scheme, strings.TrimSpace(BearerPrefix)) || token == "" ||
+			subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			body, _ := json.Marshal(scim.NewError(http.StatusUnauthorized, "", "無效的認證 Token"))
+			c.Data(http.StatusUnauthorized, scim.ContentType, body)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/scim"
+)
+
+func TestSCIMToken(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/scim/v2/Users", SCIMToken("secret"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		header   string
+		expected int
+	}{
+		{"", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Basic secret", http.StatusUnauthorized},
+		{"Bearer", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+		{"bearer secret", http.StatusOK},
+		{"BEARER secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/scim/v2/Users", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.expected {
+			t.Errorf("Header %q: expected status %d, got %d", tt.header, tt.expected, w.Code)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("Content-Type") != scim.ContentType {
+			t.Errorf("Expected a SCIM error, got %q", w.Header().Get("Content-Type"))
+		}
+	}
+}
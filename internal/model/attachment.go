@@ -0,0 +1,73 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Upload is an uploaded image or file that messages may attach. Avatars are
+// not recorded.
+type Upload struct {
+	ID          string         `db:"id" json:"id"`
+	UserID      sql.NullString `db:"user_id" json:"user_id,omitempty"` // the uploader, the only one who may attach it
+	Category    UploadCategory `db:"category" json:"category"`
+	FileName    string         `db:"file_name" json:"file_name"`
+	FileURL     string         `db:"file_url" json:"file_url"`
+	FilePath    string         `db:"file_path" json:"-"` // where the file is stored, empty when unknown
+	ContentType string         `db:"content_type" json:"content_type"`
+	Size        int64          `db:"size" json:"size"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+
+	ScanStatus    ScanStatus     `db:"scan_status" json:"scan_status"`
+	ScanSignature sql.NullString `db:"scan_signature" json:"-"` // the malware found, set when infected
+	ScannedAt     sql.NullTime   `db:"scanned_at" json:"scanned_at,omitempty"`
+}
+
+// ScanStatus is how far an upload got through antivirus scanning
+type ScanStatus string
+
+const (
+	// ScanStatusUnscanned marks uploads made while scanning was off
+	ScanStatusUnscanned ScanStatus = "unscanned"
+	// ScanStatusPending marks uploads waiting for the scanner; clients
+	// should hold rendering them until they are clean
+	ScanStatusPending ScanStatus = "pending"
+	ScanStatusClean   ScanStatus = "clean"
+	// ScanStatusInfected marks quarantined uploads, no longer served
+	ScanStatusInfected ScanStatus = "infected"
+)
+
+// IsOwnedBy reports whether userID uploaded the file
+func (u *Upload) IsOwnedBy(userID string) bool {
+	return u.UserID.Valid && u.UserID.String == userID
+}
+
+// StorageUsage is how many bytes of images and files a user stores
+type StorageUsage struct {
+	Images  int64 `db:"images" json:"images"`
+	Files   int64 `db:"files" json:"files"`
+	Pending int64 `db:"pending" json:"pending"` // declared size of resumable uploads in progress
+}
+
+// Total returns the bytes counted against the user's quota
+func (u *StorageUsage) Total() int64 {
+	return u.Images + u.Files + u.Pending
+}
+
+// Attachment links an upload to a room message or a direct message, with
+// the upload's file details
+type Attachment struct {
+	ID              string         `db:"id" json:"id"`
+	UploadID        string         `db:"upload_id" json:"upload_id"`
+	MessageID       sql.NullString `db:"message_id" json:"message_id,omitempty"`
+	DirectMessageID sql.NullString `db:"direct_message_id" json:"direct_message_id,omitempty"`
+	Position        int            `db:"position" json:"position"`
+	CreatedAt       time.Time      `db:"created_at" json:"created_at"`
+
+	FileName    string `db:"file_name" json:"file_name"`
+	FileURL     string `db:"file_url" json:"file_url"`
+	ContentType string `db:"content_type" json:"content_type"`
+	Size        int64  `db:"size" json:"size"`
+
+	ScanStatus ScanStatus `db:"scan_status" json:"scan_status"`
+}
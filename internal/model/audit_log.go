@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// AuditAction identifies a sensitive operation recorded in the audit log
+type AuditAction string
+
+const (
+	AuditActionMemberKicked           AuditAction = "member.kicked"
+	AuditActionMemberPromoted         AuditAction = "member.promoted"
+	AuditActionMemberDemoted          AuditAction = "member.demoted"
+	AuditActionMemberMuted            AuditAction = "member.muted"
+	AuditActionMemberUnmuted          AuditAction = "member.unmuted"
+	AuditActionUserBanned             AuditAction = "user.banned"
+	AuditActionUserUnbanned           AuditAction = "user.unbanned"
+	AuditActionUserImported           AuditAction = "user.imported"
+	AuditActionAdminGranted           AuditAction = "user.admin_granted"
+	AuditActionAdminRevoked           AuditAction = "user.admin_revoked"
+	AuditActionUserProvisioned        AuditAction = "user.sso_provisioned"
+	AuditActionUserDeactivated        AuditAction = "user.deactivated"
+	AuditActionUserReactivated        AuditAction = "user.reactivated"
+	AuditActionIPBanned               AuditAction = "ip.banned"
+	AuditActionIPUnbanned             AuditAction = "ip.unbanned"
+	AuditActionIPRuleAdded            AuditAction = "ip.rule_added"
+	AuditActionIPRuleRemoved          AuditAction = "ip.rule_removed"
+	AuditActionPasswordChanged        AuditAction = "user.password_changed"
+	AuditActionDeviceRevoked          AuditAction = "user.device_revoked"
+	AuditActionAccountDeleted         AuditAction = "user.account_deleted"
+	AuditActionRoomDeletionScheduled  AuditAction = "room.deletion_scheduled"
+	AuditActionRoomDeletionCanceled   AuditAction = "room.deletion_canceled"
+	AuditActionRoomDeleted            AuditAction = "room.deleted"
+	AuditActionRoomPurged             AuditAction = "room.purged"
+	AuditActionRoomMerged             AuditAction = "room.merged"
+	AuditActionRoomArchived           AuditAction = "room.archived"
+	AuditActionRoomUnarchived         AuditAction = "room.unarchived"
+	AuditActionLegalHoldPlaced        AuditAction = "legal_hold.placed"
+	AuditActionLegalHoldReleased      AuditAction = "legal_hold.released"
+	AuditActionComplianceExported     AuditAction = "compliance.exported"
+	AuditActionRoomPermissionsUpdated AuditAction = "room.permissions_updated"
+	AuditActionImageReviewed          AuditAction = "image.reviewed"
+	AuditActionImageModerationUpdated AuditAction = "image.moderation_updated"
+	AuditActionUploadSettingsUpdated  AuditAction = "upload.settings_updated"
+	AuditActionUserSpamFlagged        AuditAction = "user.spam_flagged"
+	AuditActionStickerPackCreated     AuditAction = "sticker_pack.created"
+	AuditActionStickerPackUpdated     AuditAction = "sticker_pack.updated"
+	AuditActionMessageReviewed        AuditAction = "message.reviewed"
+	AuditActionReportUpdated          AuditAction = "report.updated"
+	AuditActionJWTKeyRotated          AuditAction = "jwt.key_rotated"
+)
+
+// Audit target types
+const (
+	AuditTargetUser        = "user"
+	AuditTargetRoom        = "room"
+	AuditTargetIP          = "ip"
+	AuditTargetImage       = "image"
+	AuditTargetUpload      = "upload"
+	AuditTargetStickerPack = "sticker_pack"
+	AuditTargetMessage     = "message"
+	AuditTargetReport      = "report"
+	AuditTargetJWTKey      = "jwt_key"
+)
+
+// AuditLog represents a recorded sensitive action
+type AuditLog struct {
+	ID         string         `db:"id" json:"id"`
+	ActorID    sql.NullString `db:"actor_id" json:"actor_id,omitempty"`
+	Action     AuditAction    `db:"action" json:"action"`
+	TargetType string         `db:"target_type" json:"target_type"`
+	TargetID   sql.NullString `db:"target_id" json:"target_id,omitempty"`
+	Metadata   []byte         `db:"metadata" json:"-"` // JSON object
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
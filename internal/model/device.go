@@ -0,0 +1,60 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserDevice is a client device that holds its own refresh token chain
+type UserDevice struct {
+	ID         string         `db:"id" json:"id"`
+	UserID     string         `db:"user_id" json:"user_id"`
+	Name       string         `db:"name" json:"name"`
+	UserAgent  sql.NullString `db:"user_agent" json:"user_agent,omitempty"`
+	LastIP     sql.NullString `db:"last_ip" json:"last_ip,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	LastSeenAt time.Time      `db:"last_seen_at" json:"last_seen_at"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// IsRevoked checks if the device has been revoked
+func (d *UserDevice) IsRevoked() bool {
+	return d.RevokedAt != nil
+}
+
+// RefreshTokenStatus describes where a refresh token is in its lifecycle
+type RefreshTokenStatus string
+
+const (
+	RefreshTokenActive  RefreshTokenStatus = "active"
+	RefreshTokenRotated RefreshTokenStatus = "rotated"
+	RefreshTokenRevoked RefreshTokenStatus = "revoked"
+	RefreshTokenExpired RefreshTokenStatus = "expired"
+)
+
+// RefreshTokenRecord records the issuance of a refresh token to a device
+type RefreshTokenRecord struct {
+	ID        string         `db:"id" json:"id"`
+	DeviceID  string         `db:"device_id" json:"device_id"`
+	UserID    string         `db:"user_id" json:"user_id"`
+	ParentID  sql.NullString `db:"parent_id" json:"parent_id,omitempty"`
+	IP        sql.NullString `db:"ip" json:"ip,omitempty"`
+	IssuedAt  time.Time      `db:"issued_at" json:"issued_at"`
+	ExpiresAt time.Time      `db:"expires_at" json:"expires_at"`
+	RotatedAt *time.Time     `db:"rotated_at" json:"rotated_at,omitempty"`
+	RevokedAt *time.Time     `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// Status returns the token's lifecycle status at now
+func (t *RefreshTokenRecord) Status(now time.Time) RefreshTokenStatus {
+	switch {
+	case t.RevokedAt != nil:
+		return RefreshTokenRevoked
+	case t.RotatedAt != nil:
+		return RefreshTokenRotated
+	case !now.Before(t.ExpiresAt):
+		return RefreshTokenExpired
+	default:
+		return RefreshTokenActive
+	}
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// Envelope types, as in the Signal protocol
+const (
+	EnvelopeTypeMessage = 1 // a message in an established session
+	EnvelopeTypePrekey  = 3 // the first message, which sets up the session
+)
+
+// DeviceKeys are the public keys a device publishes so others can start an
+// encrypted session with it. Keys are Base64; the server never sees the
+// private halves and does not verify the signature, which clients check
+// against the identity key.
+type DeviceKeys struct {
+	DeviceID              string    `db:"device_id" json:"device_id"`
+	UserID                string    `db:"user_id" json:"user_id"`
+	RegistrationID        int       `db:"registration_id" json:"registration_id"`
+	IdentityKey           string    `db:"identity_key" json:"identity_key"`
+	SignedPrekeyID        int       `db:"signed_prekey_id" json:"signed_prekey_id"`
+	SignedPrekey          string    `db:"signed_prekey" json:"signed_prekey"`
+	SignedPrekeySignature string    `db:"signed_prekey_signature" json:"signed_prekey_signature"`
+	CreatedAt             time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt             time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// OneTimePrekey is a prekey handed out to at most one session initiator
+type OneTimePrekey struct {
+	KeyID     int    `db:"key_id" json:"key_id"`
+	PublicKey string `db:"public_key" json:"public_key"`
+}
+
+// PrekeyBundle is what an initiator needs to open a session with a device.
+// OneTimePrekey is nil once the device ran out; sessions then start from
+// the signed prekey alone.
+type PrekeyBundle struct {
+	DeviceKeys
+	OneTimePrekey *OneTimePrekey
+}
+
+// DMEnvelope is an encrypted direct message addressed to one device
+type DMEnvelope struct {
+	MessageID      string `db:"message_id" json:"message_id"`
+	DeviceID       string `db:"device_id" json:"device_id"`
+	SenderDeviceID string `db:"sender_device_id" json:"sender_device_id"`
+	Type           int    `db:"type" json:"type"`
+	Ciphertext     string `db:"ciphertext" json:"ciphertext"`
+}
@@ -0,0 +1,28 @@
+package model
+
+// DigestRoom is a room with messages the user has not read yet
+type DigestRoom struct {
+	RoomID string `db:"room_id" json:"room_id"`
+	Name   string `db:"name" json:"name"`
+	Unread int    `db:"unread" json:"unread"`
+}
+
+// DigestSender is a user whose direct messages are still unread
+type DigestSender struct {
+	UserID      string `db:"user_id" json:"user_id"`
+	Username    string `db:"username" json:"username"`
+	DisplayName string `db:"display_name" json:"display_name"`
+	Unread      int    `db:"unread" json:"unread"`
+}
+
+// DigestActivity is the unread activity one digest email summarizes
+type DigestActivity struct {
+	Rooms          []*DigestRoom
+	DMs            []*DigestSender
+	FriendRequests []*DigestSender // Unread is unused
+}
+
+// IsEmpty reports whether there is nothing to summarize
+func (a *DigestActivity) IsEmpty() bool {
+	return len(a.Rooms) == 0 && len(a.DMs) == 0 && len(a.FriendRequests) == 0
+}
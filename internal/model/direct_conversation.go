@@ -0,0 +1,39 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DirectConversationSettings are shared by both participants of a DM
+// conversation. A conversation without a stored row uses the zero value.
+type DirectConversationSettings struct {
+	UserLow    string         `db:"user_low" json:"-"`
+	UserHigh   string         `db:"user_high" json:"-"`
+	MessageTTL int            `db:"message_ttl_seconds" json:"message_ttl"` // seconds new messages live; 0 keeps them
+	UpdatedBy  sql.NullString `db:"updated_by" json:"updated_by,omitempty"`
+	UpdatedAt  time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// ConversationState is a state a user can give a DM conversation in their
+// own conversation list
+type ConversationState string
+
+const (
+	ConversationArchived ConversationState = "archived" // listed apart until unarchived
+	ConversationHidden   ConversationState = "hidden"   // shown again when a new message arrives
+	ConversationPinned   ConversationState = "pinned"   // listed first
+)
+
+// ConversationSettings are one user's states of a DM conversation; unlike
+// DirectConversationSettings the other participant does not see them.
+// Each state is set since its time and unset when nil.
+type ConversationSettings struct {
+	UserID      string     `db:"user_id" json:"-"`
+	OtherUserID string     `db:"other_user_id" json:"user_id"`
+	ArchivedAt  *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+	HiddenAt    *time.Time `db:"hidden_at" json:"hidden_at,omitempty"`
+	PinnedAt    *time.Time `db:"pinned_at" json:"pinned_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
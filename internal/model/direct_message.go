@@ -12,10 +12,14 @@ type DirectMessage struct {
 	Content             string      `db:"content" json:"content"`
 	Type                MessageType `db:"type" json:"type"`
 	IsRead              bool        `db:"is_read" json:"is_read"`
+	IsEdited            bool        `db:"is_edited" json:"is_edited"`
 	IsDeletedBySender   bool        `db:"is_deleted_by_sender" json:"-"`
 	IsDeletedByReceiver bool        `db:"is_deleted_by_receiver" json:"-"`
+	IsNSFW              bool        `db:"is_nsfw" json:"is_nsfw"` // clients blur the image until tapped
 	CreatedAt           time.Time   `db:"created_at" json:"created_at"`
 	UpdatedAt           time.Time   `db:"updated_at" json:"updated_at"`
+	ExpiresAt           *time.Time  `db:"expires_at" json:"expires_at,omitempty"`
+	ForwardedFrom       []byte      `db:"forwarded_from" json:"-"` // JSON ForwardedFrom
 }
 
 // DirectMessageWithUser includes sender info
@@ -24,6 +28,12 @@ type DirectMessageWithUser struct {
 	SenderUsername    string         `db:"sender_username" json:"sender_username"`
 	SenderDisplayName sql.NullString `db:"sender_display_name" json:"sender_display_name,omitempty"`
 	SenderAvatarURL   sql.NullString `db:"sender_avatar_url" json:"sender_avatar_url,omitempty"`
+
+	// For ciphertext messages, the envelope addressed to the reading device
+	Envelope *DMEnvelope `db:"-" json:"envelope,omitempty"`
+
+	// Attachments are the uploads attached to the message, in order
+	Attachments []*Attachment `db:"-" json:"attachments,omitempty"`
 }
 
 // GetSenderDisplayName returns sender display_name or username
@@ -52,6 +62,8 @@ type Conversation struct {
 	LastMessage   string    `db:"last_message" json:"last_message"`
 	LastMessageAt time.Time `db:"last_message_at" json:"last_message_at"`
 	UnreadCount   int       `db:"unread_count" json:"unread_count"`
+	IsArchived    bool      `db:"is_archived" json:"is_archived"`
+	IsPinned      bool      `db:"is_pinned" json:"is_pinned"`
 }
 
 // BlockedUser represents a blocked user relationship
@@ -89,6 +101,14 @@ type FriendshipWithUser struct {
 	FriendStatus      UserStatus     `db:"friend_status" json:"friend_status"`
 }
 
+// FriendSuggestion is a user recommended as a friend, with what they
+// have in common with the user
+type FriendSuggestion struct {
+	User
+	MutualFriends int `db:"mutual_friends" json:"mutual_friends"`
+	SharedRooms   int `db:"shared_rooms" json:"shared_rooms"`
+}
+
 // GetFriendDisplayName returns friend display_name or username
 func (f *FriendshipWithUser) GetFriendDisplayName() string {
 	if f.FriendDisplayName.Valid && f.FriendDisplayName.String != "" {
@@ -97,6 +117,23 @@ func (f *FriendshipWithUser) GetFriendDisplayName() string {
 	return f.FriendUsername
 }
 
+// Notification types
+const (
+	NotificationTypeRoomDeleting         = "room_deleting"
+	NotificationTypeRoomDeletionCanceled = "room_deletion_canceled"
+	NotificationTypeMention              = "mention"
+	NotificationTypeReply                = "reply"
+	NotificationTypeReaction             = "reaction"
+	NotificationTypeDirectMessage        = "direct_message"
+	NotificationTypeJoinRequestApproved  = "join_request_approved"
+	NotificationTypeJoinRequestRejected  = "join_request_rejected"
+	NotificationTypeAbuseAlert           = "abuse_alert"
+	NotificationTypeDMExportReady        = "dm_export_ready"
+	NotificationTypeReportUpdated        = "report_updated"
+	NotificationTypeUploadQuarantined    = "upload_quarantined"
+	NotificationTypeRoomEventReminder    = "room_event_reminder"
+)
+
 // Notification represents a user notification
 type Notification struct {
 	ID            string         `db:"id" json:"id"`
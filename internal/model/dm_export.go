@@ -0,0 +1,37 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DMExportStatus is where a conversation export is in the job pipeline
+type DMExportStatus string
+
+const (
+	DMExportStatusPending   DMExportStatus = "pending"
+	DMExportStatusRunning   DMExportStatus = "running"
+	DMExportStatusCompleted DMExportStatus = "completed"
+	DMExportStatusFailed    DMExportStatus = "failed"
+)
+
+// DMExport is a participant's request for a copy of a direct message
+// conversation, produced by a background job
+type DMExport struct {
+	ID           string         `db:"id" json:"id"`
+	UserID       string         `db:"user_id" json:"user_id"`
+	PeerID       string         `db:"peer_id" json:"peer_id"`
+	Status       DMExportStatus `db:"status" json:"status"`
+	StoredName   sql.NullString `db:"stored_name" json:"-"` // file name under the export directory once complete
+	MessageCount int            `db:"message_count" json:"message_count"`
+	Error        sql.NullString `db:"error" json:"error,omitempty"`
+	ClaimedAt    *time.Time     `db:"claimed_at" json:"-"`
+	CompletedAt  *time.Time     `db:"completed_at" json:"completed_at,omitempty"`
+	ExpiresAt    *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+}
+
+// IsReady checks if the export file can be downloaded
+func (e *DMExport) IsReady() bool {
+	return e.Status == DMExportStatusCompleted && e.StoredName.Valid
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// ExternalIdentity links a user at an enterprise identity provider to
+// their local account
+type ExternalIdentity struct {
+	Provider    string    `db:"provider" json:"provider"`
+	Subject     string    `db:"subject" json:"subject"`
+	UserID      string    `db:"user_id" json:"user_id"`
+	LastLoginAt time.Time `db:"last_login_at" json:"last_login_at"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FeedbackCategory classifies in-product feedback
+type FeedbackCategory string
+
+const (
+	FeedbackCategoryBug        FeedbackCategory = "bug"
+	FeedbackCategorySuggestion FeedbackCategory = "suggestion"
+	FeedbackCategoryOther      FeedbackCategory = "other"
+)
+
+// IsValid checks if the category is known
+func (c FeedbackCategory) IsValid() bool {
+	switch c {
+	case FeedbackCategoryBug, FeedbackCategorySuggestion, FeedbackCategoryOther:
+		return true
+	}
+	return false
+}
+
+// FeedbackStatus is where feedback stands in admin triage
+type FeedbackStatus string
+
+const (
+	FeedbackStatusOpen       FeedbackStatus = "open"
+	FeedbackStatusInProgress FeedbackStatus = "in_progress"
+	FeedbackStatusResolved   FeedbackStatus = "resolved"
+	FeedbackStatusDismissed  FeedbackStatus = "dismissed"
+)
+
+// IsValid checks if the status is known
+func (s FeedbackStatus) IsValid() bool {
+	switch s {
+	case FeedbackStatusOpen, FeedbackStatusInProgress, FeedbackStatusResolved, FeedbackStatusDismissed:
+		return true
+	}
+	return false
+}
+
+// Feedback is a bug report or suggestion sent from a client
+type Feedback struct {
+	ID            string           `db:"id" json:"id"`
+	UserID        sql.NullString   `db:"user_id" json:"user_id,omitempty"`
+	Category      FeedbackCategory `db:"category" json:"category"`
+	Text          string           `db:"text" json:"text"`
+	ScreenshotURL sql.NullString   `db:"screenshot_url" json:"screenshot_url,omitempty"`
+	AppVersion    sql.NullString   `db:"app_version" json:"app_version,omitempty"`
+	Client        sql.NullString   `db:"client" json:"client,omitempty"` // e.g. "web", "ios"
+	UserAgent     sql.NullString   `db:"user_agent" json:"user_agent,omitempty"`
+	Status        FeedbackStatus   `db:"status" json:"status"`
+	TriageNote    sql.NullString   `db:"triage_note" json:"triage_note,omitempty"`
+	TriagedBy     sql.NullString   `db:"triaged_by" json:"triaged_by,omitempty"`
+	TriagedAt     *time.Time       `db:"triaged_at" json:"triaged_at,omitempty"`
+	ForwardedAt   *time.Time       `db:"forwarded_at" json:"forwarded_at,omitempty"` // sent to the external tracker
+	CreatedAt     time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time        `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GroupConversation is a direct conversation between three or more users,
+// outside any room
+type GroupConversation struct {
+	ID        string         `db:"id" json:"id"`
+	Name      sql.NullString `db:"name" json:"name,omitempty"`
+	CreatedBy sql.NullString `db:"created_by" json:"created_by,omitempty"` // may remove other participants
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// GroupParticipant is a member of a group conversation with their user info
+type GroupParticipant struct {
+	ConversationID string         `db:"conversation_id" json:"conversation_id"`
+	UserID         string         `db:"user_id" json:"user_id"`
+	JoinedAt       time.Time      `db:"joined_at" json:"joined_at"`
+	LastReadAt     time.Time      `db:"last_read_at" json:"last_read_at"` // messages others sent later are unread
+	Username       string         `db:"username" json:"username"`
+	DisplayName    sql.NullString `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL      sql.NullString `db:"avatar_url" json:"avatar_url,omitempty"`
+}
+
+// GetDisplayName returns display_name or username as fallback
+func (p *GroupParticipant) GetDisplayName() string {
+	if p.DisplayName.Valid && p.DisplayName.String != "" {
+		return p.DisplayName.String
+	}
+	return p.Username
+}
+
+// GetAvatarURL returns avatar_url or empty string
+func (p *GroupParticipant) GetAvatarURL() string {
+	if p.AvatarURL.Valid {
+		return p.AvatarURL.String
+	}
+	return ""
+}
+
+// GroupConversationDetail is a group conversation with its participants
+type GroupConversationDetail struct {
+	GroupConversation
+	Participants []*GroupParticipant `json:"participants"`
+}
+
+// GroupConversationSummary is a group conversation as listed for one of
+// its participants
+type GroupConversationSummary struct {
+	GroupConversation
+	LastMessage      string     `db:"last_message" json:"last_message"`
+	LastMessageAt    *time.Time `db:"last_message_at" json:"last_message_at,omitempty"`
+	UnreadCount      int        `db:"unread_count" json:"unread_count"`
+	ParticipantCount int        `db:"participant_count" json:"participant_count"`
+}
+
+// GroupMessage is a message in a group conversation
+type GroupMessage struct {
+	ID             string      `db:"id" json:"id"`
+	ConversationID string      `db:"conversation_id" json:"conversation_id"`
+	SenderID       string      `db:"sender_id" json:"sender_id"`
+	Content        string      `db:"content" json:"content"`
+	Type           MessageType `db:"type" json:"type"`
+	CreatedAt      time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time   `db:"updated_at" json:"updated_at"`
+}
+
+// GroupMessageWithUser includes sender info
+type GroupMessageWithUser struct {
+	GroupMessage
+	SenderUsername    string         `db:"sender_username" json:"sender_username"`
+	SenderDisplayName sql.NullString `db:"sender_display_name" json:"sender_display_name,omitempty"`
+	SenderAvatarURL   sql.NullString `db:"sender_avatar_url" json:"sender_avatar_url,omitempty"`
+}
+
+// GetSenderDisplayName returns sender display_name or username
+func (m *GroupMessageWithUser) GetSenderDisplayName() string {
+	if m.SenderDisplayName.Valid && m.SenderDisplayName.String != "" {
+		return m.SenderDisplayName.String
+	}
+	return m.SenderUsername
+}
+
+// GetSenderAvatarURL returns sender avatar_url or empty string
+func (m *GroupMessageWithUser) GetSenderAvatarURL() string {
+	if m.SenderAvatarURL.Valid {
+		return m.SenderAvatarURL.String
+	}
+	return ""
+}
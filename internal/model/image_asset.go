@@ -0,0 +1,92 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ImageVerdict is the outcome of scanning an uploaded image
+type ImageVerdict string
+
+const (
+	// ImageVerdictClean images are shown as is
+	ImageVerdictClean ImageVerdict = "clean"
+	// ImageVerdictNSFW images are kept but clients blur them until tapped
+	ImageVerdictNSFW ImageVerdict = "nsfw"
+	// ImageVerdictBlocked images are removed
+	ImageVerdictBlocked ImageVerdict = "blocked"
+)
+
+// IsValid checks if the verdict is known
+func (v ImageVerdict) IsValid() bool {
+	switch v {
+	case ImageVerdictClean, ImageVerdictNSFW, ImageVerdictBlocked:
+		return true
+	}
+	return false
+}
+
+// ImageReviewStatus is where an image stands in the moderation queue
+type ImageReviewStatus string
+
+const (
+	// ImageReviewNone images were clear cut and never queued
+	ImageReviewNone ImageReviewStatus = "none"
+	// ImageReviewPending images wait for an admin
+	ImageReviewPending ImageReviewStatus = "pending"
+	// ImageReviewReviewed images had their verdict set by an admin
+	ImageReviewReviewed ImageReviewStatus = "reviewed"
+)
+
+// ImageAsset is an uploaded image and what moderation made of it
+type ImageAsset struct {
+	ID           string            `db:"id" json:"id"`
+	UserID       sql.NullString    `db:"user_id" json:"user_id,omitempty"`
+	FileURL      string            `db:"file_url" json:"file_url"`
+	FilePath     string            `db:"file_path" json:"-"`
+	ContentType  string            `db:"content_type" json:"content_type"`
+	Score        sql.NullFloat64   `db:"score" json:"score,omitempty"` // NULL when detection failed
+	Labels       string            `db:"labels" json:"-"`              // comma separated
+	Verdict      ImageVerdict      `db:"verdict" json:"verdict"`
+	ReviewStatus ImageReviewStatus `db:"review_status" json:"review_status"`
+	ReviewNote   sql.NullString    `db:"review_note" json:"review_note,omitempty"`
+	ReviewedBy   sql.NullString    `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time        `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time         `db:"created_at" json:"created_at"`
+}
+
+// IsNSFW checks if clients should blur the image
+func (a *ImageAsset) IsNSFW() bool {
+	return a.Verdict == ImageVerdictNSFW || a.Verdict == ImageVerdictBlocked
+}
+
+// LabelList returns the detected categories
+func (a *ImageAsset) LabelList() []string {
+	if a.Labels == "" {
+		return nil
+	}
+	return strings.Split(a.Labels, ",")
+}
+
+// ImageModerationSettings are the admin-controlled image moderation
+// thresholds. Scores are between 0 and 1.
+type ImageModerationSettings struct {
+	Enabled        bool           `db:"enabled" json:"enabled"`
+	NSFWThreshold  float64        `db:"nsfw_threshold" json:"nsfw_threshold"`
+	BlockThreshold float64        `db:"block_threshold" json:"block_threshold"`
+	UpdatedBy      sql.NullString `db:"updated_by" json:"updated_by,omitempty"`
+	UpdatedAt      time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// Verdict classifies a detection score
+func (s *ImageModerationSettings) Verdict(score float64) ImageVerdict {
+	switch {
+	case score >= s.BlockThreshold:
+		return ImageVerdictBlocked
+	case score >= s.NSFWThreshold:
+		return ImageVerdictNSFW
+	default:
+		return ImageVerdictClean
+	}
+}
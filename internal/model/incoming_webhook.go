@@ -0,0 +1,33 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// IncomingWebhook lets an external service post into a room as its bot user
+type IncomingWebhook struct {
+	ID         string         `db:"id" json:"id"`
+	RoomID     string         `db:"room_id" json:"room_id"`
+	BotUserID  string         `db:"bot_user_id" json:"bot_user_id"`
+	TokenHash  string         `db:"token_hash" json:"-"`
+	CreatedBy  sql.NullString `db:"created_by" json:"created_by,omitempty"`
+	LastUsedAt sql.NullTime   `db:"last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+// IncomingWebhookWithBot includes the bot user's identity
+type IncomingWebhookWithBot struct {
+	IncomingWebhook
+	BotUsername    string         `db:"bot_username" json:"bot_username"`
+	BotDisplayName sql.NullString `db:"bot_display_name" json:"bot_display_name,omitempty"`
+	BotAvatarURL   sql.NullString `db:"bot_avatar_url" json:"bot_avatar_url,omitempty"`
+}
+
+// GetBotDisplayName returns the bot's display_name or username
+func (w *IncomingWebhookWithBot) GetBotDisplayName() string {
+	if w.BotDisplayName.Valid && w.BotDisplayName.String != "" {
+		return w.BotDisplayName.String
+	}
+	return w.BotUsername
+}
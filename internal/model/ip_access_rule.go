@@ -0,0 +1,47 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// IPAccessAction decides what happens to requests matching an IPAccessRule
+type IPAccessAction string
+
+const (
+	IPAccessAllow IPAccessAction = "allow"
+	IPAccessDeny  IPAccessAction = "deny"
+)
+
+// Scopes an IPAccessRule applies to: every request, or one route group
+const (
+	IPAccessScopeGlobal  = "global"
+	IPAccessScopeAdmin   = "admin"
+	IPAccessScopeSCIM    = "scim"
+	IPAccessScopeMetrics = "metrics"
+)
+
+// IPAccessScopes lists the valid scopes
+var IPAccessScopes = []string{IPAccessScopeGlobal, IPAccessScopeAdmin, IPAccessScopeSCIM, IPAccessScopeMetrics}
+
+// IPAccessRule allows or denies an IP range or a country within a scope.
+// Exactly one of CIDR and Country is set. Rules from the config file have
+// no ID.
+type IPAccessRule struct {
+	ID        string         `db:"id" json:"id"`
+	Scope     string         `db:"scope" json:"scope"`
+	Action    IPAccessAction `db:"action" json:"action"`
+	CIDR      sql.NullString `db:"cidr" json:"cidr,omitempty"`
+	Country   sql.NullString `db:"country" json:"country,omitempty"` // ISO 3166-1 alpha-2, uppercase
+	Note      string         `db:"note" json:"note"`
+	CreatedBy sql.NullString `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+}
+
+// Target returns the range or country the rule matches
+func (r *IPAccessRule) Target() string {
+	if r.CIDR.Valid {
+		return r.CIDR.String
+	}
+	return r.Country.String
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// IPBan blocks requests from an IP address or CIDR range
+type IPBan struct {
+	ID        string         `db:"id" json:"id"`
+	CIDR      string         `db:"cidr" json:"cidr"`
+	Reason    string         `db:"reason" json:"reason"`
+	Tarpit    bool           `db:"tarpit" json:"tarpit"` // delay responses to slow down scanners
+	CreatedBy sql.NullString `db:"created_by" json:"created_by,omitempty"`
+	ExpiresAt *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+}
+
+// IsActive checks if the ban is in effect at the given time
+func (b *IPBan) IsActive(now time.Time) bool {
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// JWTSigningKey is a rotated secret for signing JWTs. The secret is stored
+// sealed with the key encryption key from the config file.
+type JWTSigningKey struct {
+	ID           string         `db:"id" json:"id"`
+	SealedSecret string         `db:"sealed_secret" json:"-"`
+	CreatedBy    sql.NullString `db:"created_by" json:"created_by,omitempty"`
+	ActivatesAt  time.Time      `db:"activates_at" json:"activates_at"`
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+}
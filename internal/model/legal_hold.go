@@ -0,0 +1,36 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// LegalHoldTarget is the kind of entity placed under legal hold
+type LegalHoldTarget string
+
+const (
+	LegalHoldTargetRoom LegalHoldTarget = "room"
+	LegalHoldTargetUser LegalHoldTarget = "user"
+)
+
+// IsValid checks if the target type is supported
+func (t LegalHoldTarget) IsValid() bool {
+	return t == LegalHoldTargetRoom || t == LegalHoldTargetUser
+}
+
+// LegalHold suspends retention pruning of a room's or user's data
+type LegalHold struct {
+	ID         string          `db:"id" json:"id"`
+	TargetType LegalHoldTarget `db:"target_type" json:"target_type"`
+	TargetID   string          `db:"target_id" json:"target_id"`
+	Reason     string          `db:"reason" json:"reason"`
+	PlacedBy   sql.NullString  `db:"placed_by" json:"placed_by,omitempty"`
+	ReleasedAt *time.Time      `db:"released_at" json:"released_at,omitempty"`
+	ReleasedBy sql.NullString  `db:"released_by" json:"released_by,omitempty"`
+	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
+}
+
+// IsActive checks if the hold is still in effect
+func (h *LegalHold) IsActive() bool {
+	return h.ReleasedAt == nil
+}
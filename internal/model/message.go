@@ -12,6 +12,13 @@ const (
 	MessageTypeImage  MessageType = "image"
 	MessageTypeFile   MessageType = "file"
 	MessageTypeSystem MessageType = "system"
+
+	// The content is the ID of a Sticker
+	MessageTypeSticker MessageType = "sticker"
+
+	// End-to-end encrypted direct message; the server keeps one
+	// DMEnvelope per receiving device and leaves the content empty
+	MessageTypeCiphertext MessageType = "ciphertext"
 )
 
 type Message struct {
@@ -23,8 +30,15 @@ type Message struct {
 	ReplyToID sql.NullString `db:"reply_to_id" json:"reply_to_id,omitempty"`
 	IsEdited  bool           `db:"is_edited" json:"is_edited"`
 	IsDeleted bool           `db:"is_deleted" json:"is_deleted"`
+	IsNSFW    bool           `db:"is_nsfw" json:"is_nsfw"` // clients blur the image until tapped
 	CreatedAt time.Time      `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time      `db:"updated_at" json:"updated_at"`
+	ExpiresAt *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+
+	// Set on messages moved here from another room by a room merge
+	MergedFromRoomID sql.NullString `db:"merged_from_room_id" json:"merged_from_room_id,omitempty"`
+	ForwardedFrom    []byte         `db:"forwarded_from" json:"-"` // JSON ForwardedFrom
+	Embeds           []byte         `db:"embeds" json:"-"`         // JSON []MessageEmbed, filled in after sending
 }
 
 // GetReplyToID returns reply_to_id or empty string
@@ -41,6 +55,13 @@ type MessageWithUser struct {
 	Username    string         `db:"username" json:"username"`
 	DisplayName sql.NullString `db:"display_name" json:"display_name,omitempty"`
 	AvatarURL   sql.NullString `db:"avatar_url" json:"avatar_url,omitempty"`
+	IsBot       bool           `db:"is_bot" json:"is_bot,omitempty"`
+
+	// Sticker is the sticker a sticker message shows, when it still exists
+	Sticker *Sticker `db:"-" json:"sticker,omitempty"`
+
+	// Attachments are the uploads attached to the message, in order
+	Attachments []*Attachment `db:"-" json:"attachments,omitempty"`
 }
 
 // GetUserDisplayName returns display_name or username
@@ -59,20 +80,8 @@ func (m *MessageWithUser) GetUserAvatarURL() string {
 	return ""
 }
 
-// MessageAttachment represents a file attached to a message
-type MessageAttachment struct {
-	ID        string    `db:"id" json:"id"`
-	MessageID string    `db:"message_id" json:"message_id"`
-	FileName  string    `db:"file_name" json:"file_name"`
-	FileURL   string    `db:"file_url" json:"file_url"`
-	FileType  string    `db:"file_type" json:"file_type"`
-	FileSize  int64     `db:"file_size" json:"file_size"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-}
-
-// MessageDetail includes attachments and reply info
+// MessageDetail includes reply info
 type MessageDetail struct {
 	MessageWithUser
-	Attachments []*MessageAttachment `json:"attachments,omitempty"`
-	ReplyTo     *MessageWithUser     `json:"reply_to,omitempty"`
+	ReplyTo *MessageWithUser `json:"reply_to,omitempty"`
 }
@@ -0,0 +1,30 @@
+package model
+
+import "encoding/json"
+
+// EmbedTypeLink is the preview of a link in the message
+const EmbedTypeLink = "link"
+
+// MessageEmbed is rich content shown under a message, such as the Open
+// Graph preview of a link it contains
+type MessageEmbed struct {
+	Type        string `json:"type"`
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// GetEmbeds returns the embeds of the message, nil when it has none or
+// they are unreadable
+func (m *Message) GetEmbeds() []*MessageEmbed {
+	if len(m.Embeds) == 0 {
+		return nil
+	}
+	var embeds []*MessageEmbed
+	if err := json.Unmarshal(m.Embeds, &embeds); err != nil {
+		return nil
+	}
+	return embeds
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ModerationLevel is how strictly a room's messages are moderated
+type ModerationLevel string
+
+const (
+	// ModerationOff rooms skip moderation
+	ModerationOff ModerationLevel = "off"
+	// ModerationStandard rooms mask listed words and flag messages the
+	// moderation API scores high
+	ModerationStandard ModerationLevel = "standard"
+	// ModerationStrict rooms reject listed words and flag messages at lower
+	// scores
+	ModerationStrict ModerationLevel = "strict"
+)
+
+// IsValid checks if the level is known
+func (l ModerationLevel) IsValid() bool {
+	switch l {
+	case ModerationOff, ModerationStandard, ModerationStrict:
+		return true
+	}
+	return false
+}
+
+// MessageFlagReason is why a message was queued for review
+type MessageFlagReason string
+
+const (
+	// MessageFlagClassifier messages scored at or above the flag threshold
+	MessageFlagClassifier MessageFlagReason = "classifier"
+	// MessageFlagScanFailed messages in strict rooms could not be scored
+	MessageFlagScanFailed MessageFlagReason = "scan_failed"
+)
+
+// MessageFlagStatus is where a flagged message stands in the review queue
+type MessageFlagStatus string
+
+const (
+	// MessageFlagPending messages wait for an admin
+	MessageFlagPending MessageFlagStatus = "pending"
+	// MessageFlagApproved messages were found acceptable and kept
+	MessageFlagApproved MessageFlagStatus = "approved"
+	// MessageFlagRemoved messages were deleted by the reviewer
+	MessageFlagRemoved MessageFlagStatus = "removed"
+)
+
+// MessageFlag is a room message queued for moderation review
+type MessageFlag struct {
+	ID         string            `db:"id" json:"id"`
+	MessageID  string            `db:"message_id" json:"message_id"`
+	RoomID     string            `db:"room_id" json:"room_id"`
+	UserID     sql.NullString    `db:"user_id" json:"user_id,omitempty"`
+	Reason     MessageFlagReason `db:"reason" json:"reason"`
+	Score      sql.NullFloat64   `db:"score" json:"score,omitempty"` // NULL when the API failed
+	Labels     string            `db:"labels" json:"-"`              // comma separated
+	Status     MessageFlagStatus `db:"status" json:"status"`
+	ReviewNote sql.NullString    `db:"review_note" json:"review_note,omitempty"`
+	ReviewedBy sql.NullString    `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time        `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time         `db:"created_at" json:"created_at"`
+}
+
+// LabelList returns the detected categories
+func (f *MessageFlag) LabelList() []string {
+	if f.Labels == "" {
+		return nil
+	}
+	return strings.Split(f.Labels, ",")
+}
+
+// MessageFlagWithMessage is a flag with the message it is about, as shown
+// in the review queue
+type MessageFlagWithMessage struct {
+	MessageFlag
+	Content  string         `db:"content" json:"content"`
+	Username sql.NullString `db:"username" json:"username,omitempty"`
+}
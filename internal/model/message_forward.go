@@ -0,0 +1,46 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ForwardSource is the kind of message a forward was copied from
+type ForwardSource string
+
+const (
+	ForwardSourceRoom ForwardSource = "room"
+	ForwardSourceDM   ForwardSource = "dm"
+)
+
+// ForwardedFrom is the provenance stored with a forwarded message
+type ForwardedFrom struct {
+	Source    ForwardSource `json:"source"`
+	MessageID string        `json:"message_id"`
+	RoomID    string        `json:"room_id,omitempty"` // room messages only
+	UserID    string        `json:"user_id"`           // original author
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// decodeForwardedFrom decodes a forwarded_from column, nil when unset or
+// unreadable
+func decodeForwardedFrom(raw []byte) *ForwardedFrom {
+	if len(raw) == 0 {
+		return nil
+	}
+	var from ForwardedFrom
+	if err := json.Unmarshal(raw, &from); err != nil {
+		return nil
+	}
+	return &from
+}
+
+// GetForwardedFrom returns where the message was forwarded from, or nil
+func (m *Message) GetForwardedFrom() *ForwardedFrom {
+	return decodeForwardedFrom(m.ForwardedFrom)
+}
+
+// GetForwardedFrom returns where the message was forwarded from, or nil
+func (m *DirectMessage) GetForwardedFrom() *ForwardedFrom {
+	return decodeForwardedFrom(m.ForwardedFrom)
+}
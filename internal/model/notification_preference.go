@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// NotificationTargetType is what a notification preference applies to
+type NotificationTargetType string
+
+const (
+	NotificationTargetRoom NotificationTargetType = "room"
+	NotificationTargetDM   NotificationTargetType = "dm"
+)
+
+// IsValid checks if the target type is known
+func (t NotificationTargetType) IsValid() bool {
+	return t == NotificationTargetRoom || t == NotificationTargetDM
+}
+
+// NotificationLevel controls which notifications a target may send
+type NotificationLevel string
+
+const (
+	// NotificationLevelAll is the default; it is never stored
+	NotificationLevelAll NotificationLevel = "all"
+	// NotificationLevelMentions only lets mentions through; rooms only
+	NotificationLevelMentions NotificationLevel = "mentions"
+	// NotificationLevelNone mutes the target
+	NotificationLevelNone NotificationLevel = "none"
+)
+
+// IsValidFor checks if the level can be set on the target type
+func (l NotificationLevel) IsValidFor(t NotificationTargetType) bool {
+	switch l {
+	case NotificationLevelAll, NotificationLevelNone:
+		return true
+	case NotificationLevelMentions:
+		return t == NotificationTargetRoom
+	}
+	return false
+}
+
+// Allows checks if a notification of the given type gets through
+func (l NotificationLevel) Allows(notificationType string) bool {
+	switch l {
+	case NotificationLevelNone:
+		return false
+	case NotificationLevelMentions:
+		return notificationType == NotificationTypeMention
+	}
+	return true
+}
+
+// NotificationPreference overrides the notification level of one room or
+// direct message conversation for a user
+type NotificationPreference struct {
+	UserID     string                 `db:"user_id" json:"user_id"`
+	TargetType NotificationTargetType `db:"target_type" json:"target_type"`
+	TargetID   string                 `db:"target_id" json:"target_id"`
+	Level      NotificationLevel      `db:"level" json:"level"`
+	UpdatedAt  time.Time              `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// ReadStateKind is the kind of conversation a read state belongs to
+type ReadStateKind string
+
+const (
+	ReadStateRoom  ReadStateKind = "room"
+	ReadStateDM    ReadStateKind = "dm"
+	ReadStateGroup ReadStateKind = "group"
+)
+
+// ReadState is how far a user has read one room or conversation. ID is the
+// room ID, the other user's ID for direct messages, or the group
+// conversation ID. Direct messages are read per message and have no
+// LastReadAt.
+type ReadState struct {
+	Kind        ReadStateKind `db:"kind" json:"kind"`
+	ID          string        `db:"id" json:"id"`
+	LastReadAt  *time.Time    `db:"last_read_at" json:"last_read_at,omitempty"`
+	UnreadCount int           `db:"unread_count" json:"unread_count"`
+}
+
+// ReadStates is a user's read state across everything they take part in
+type ReadStates struct {
+	Rooms  []*ReadState `json:"rooms"`
+	DMs    []*ReadState `json:"dms"`
+	Groups []*ReadState `json:"groups"`
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ReportTargetType is what a report is about
+type ReportTargetType string
+
+const (
+	ReportTargetMessage ReportTargetType = "message"
+	ReportTargetUser    ReportTargetType = "user"
+)
+
+// IsValid checks if the target type is known
+func (t ReportTargetType) IsValid() bool {
+	return t == ReportTargetMessage || t == ReportTargetUser
+}
+
+// ReportCategory classifies what the reporter objects to
+type ReportCategory string
+
+const (
+	ReportCategorySpam          ReportCategory = "spam"
+	ReportCategoryHarassment    ReportCategory = "harassment"
+	ReportCategoryHate          ReportCategory = "hate"
+	ReportCategorySexual        ReportCategory = "sexual"
+	ReportCategoryViolence      ReportCategory = "violence"
+	ReportCategoryImpersonation ReportCategory = "impersonation"
+	ReportCategoryOther         ReportCategory = "other"
+)
+
+// IsValid checks if the category is known
+func (c ReportCategory) IsValid() bool {
+	switch c {
+	case ReportCategorySpam, ReportCategoryHarassment, ReportCategoryHate, ReportCategorySexual,
+		ReportCategoryViolence, ReportCategoryImpersonation, ReportCategoryOther:
+		return true
+	}
+	return false
+}
+
+// ReportStatus is where a report stands in admin triage
+type ReportStatus string
+
+const (
+	ReportStatusOpen      ReportStatus = "open"
+	ReportStatusReviewing ReportStatus = "reviewing"
+	ReportStatusResolved  ReportStatus = "resolved"
+)
+
+// IsValid checks if the status is known
+func (s ReportStatus) IsValid() bool {
+	switch s {
+	case ReportStatusOpen, ReportStatusReviewing, ReportStatusResolved:
+		return true
+	}
+	return false
+}
+
+// CanMoveTo checks if a report may go from s to next. Open and reviewing
+// reports may move either way or be resolved; resolved reports are final.
+func (s ReportStatus) CanMoveTo(next ReportStatus) bool {
+	switch s {
+	case ReportStatusOpen:
+		return next == ReportStatusReviewing || next == ReportStatusResolved
+	case ReportStatusReviewing:
+		return next == ReportStatusOpen || next == ReportStatusResolved
+	}
+	return false
+}
+
+// Report is a user's complaint about a room message or another user
+type Report struct {
+	ID             string           `db:"id" json:"id"`
+	ReporterID     sql.NullString   `db:"reporter_id" json:"reporter_id,omitempty"`
+	TargetType     ReportTargetType `db:"target_type" json:"target_type"`
+	MessageID      sql.NullString   `db:"message_id" json:"message_id,omitempty"`
+	RoomID         sql.NullString   `db:"room_id" json:"room_id,omitempty"`
+	ReportedUserID sql.NullString   `db:"reported_user_id" json:"reported_user_id,omitempty"` // the user or the message author
+	MessageContent sql.NullString   `db:"message_content" json:"message_content,omitempty"`   // as it was when reported
+	Category       ReportCategory   `db:"category" json:"category"`
+	Note           sql.NullString   `db:"note" json:"note,omitempty"`
+	Status         ReportStatus     `db:"status" json:"status"`
+	ResolutionNote sql.NullString   `db:"resolution_note" json:"resolution_note,omitempty"`
+	HandledBy      sql.NullString   `db:"handled_by" json:"handled_by,omitempty"`
+	HandledAt      *time.Time       `db:"handled_at" json:"handled_at,omitempty"`
+	CreatedAt      time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time        `db:"updated_at" json:"updated_at"`
+}
+
+// ReportWithUsers is a report with the usernames of both parties, as shown
+// in the triage queue
+type ReportWithUsers struct {
+	Report
+	ReporterUsername sql.NullString `db:"reporter_username" json:"reporter_username,omitempty"`
+	ReportedUsername sql.NullString `db:"reported_username" json:"reported_username,omitempty"`
+}
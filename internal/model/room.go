@@ -20,8 +20,27 @@ type Room struct {
 	Type        RoomType       `db:"type" json:"type"`
 	OwnerID     string         `db:"owner_id" json:"owner_id"`
 	MaxMembers  int            `db:"max_members" json:"max_members"`
+	ReadOnly    bool           `db:"read_only" json:"read_only"`             // announcement room: only owner/admins may post
+	MessageTTL  int            `db:"message_ttl_seconds" json:"message_ttl"` // seconds new messages live; 0 keeps them
+	FeedEnabled bool           `db:"feed_enabled" json:"feed_enabled"`       // public RSS/Atom feed of recent messages
+	Language    string         `db:"language" json:"language"`               // locale of the system messages posted in the room
+	RateLimit   int            `db:"message_rate_limit" json:"rate_limit"`   // messages per member per minute; 0 uses the global default
 	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time      `db:"updated_at" json:"updated_at"`
+
+	// Two-phase deletion: scheduled first, soft deleted once the window elapses
+	DeletionScheduledAt *time.Time `db:"deletion_scheduled_at" json:"deletion_scheduled_at,omitempty"`
+	DeletedAt           *time.Time `db:"deleted_at" json:"-"`
+
+	// Set once the room was merged into another; links to it resolve there
+	MergedIntoID sql.NullString `db:"merged_into_id" json:"merged_into_id,omitempty"`
+
+	// How strictly messages are checked; see ModerationLevel
+	ModerationLevel ModerationLevel `db:"moderation_level" json:"moderation_level"`
+
+	// Set while the room is archived: hidden from listings and closed to new
+	// messages, its history still readable by members
+	ArchivedAt *time.Time `db:"archived_at" json:"archived_at,omitempty"`
 }
 
 // GetDescription returns description or empty string
@@ -47,15 +66,60 @@ func (r *Room) IsDirect() bool {
 	return r.Type == RoomTypeDirect
 }
 
-// RoomWithMemberCount includes member count
+// IsAnnouncement checks if only owner/admins may post in the room
+func (r *Room) IsAnnouncement() bool {
+	return r.ReadOnly
+}
+
+// HasFeed checks if the room publishes a syndication feed
+func (r *Room) HasFeed() bool {
+	return r.FeedEnabled && r.IsPublic()
+}
+
+// IsMerged checks if the room was merged into another room
+func (r *Room) IsMerged() bool {
+	return r.MergedIntoID.Valid
+}
+
+// IsArchived checks if the room is archived
+func (r *Room) IsArchived() bool {
+	return r.ArchivedAt != nil
+}
+
+// IsPendingDeletion checks if room is scheduled for deletion
+func (r *Room) IsPendingDeletion() bool {
+	return r.DeletionScheduledAt != nil && r.DeletedAt == nil
+}
+
+// RoomWithMemberCount includes member and message counts
 type RoomWithMemberCount struct {
 	Room
-	MemberCount int `db:"member_count" json:"member_count"`
+	MemberCount  int   `db:"member_count" json:"member_count"`
+	MessageCount int64 `db:"message_count" json:"message_count"`
 }
 
-// RoomDetail includes owner info and member count
+// RoomDetail includes owner info and member and message counts
 type RoomDetail struct {
 	Room
-	MemberCount int          `db:"member_count" json:"member_count"`
-	Owner       *UserProfile `json:"owner,omitempty"`
+	MemberCount  int          `db:"member_count" json:"member_count"`
+	MessageCount int64        `db:"message_count" json:"message_count"`
+	Owner        *UserProfile `json:"owner,omitempty"`
+}
+
+// RoomCounterDrift reports a room whose maintained counters disagreed with
+// a recount
+type RoomCounterDrift struct {
+	RoomID             string `db:"room_id"`
+	MemberCount        int    `db:"member_count"`
+	ActualMemberCount  int    `db:"actual_member_count"`
+	MessageCount       int64  `db:"message_count"`
+	ActualMessageCount int64  `db:"actual_message_count"`
+}
+
+// RoomActivityBucket is a room's message count for one hour of the week.
+// Weekday follows time.Weekday, so 0 is Sunday.
+type RoomActivityBucket struct {
+	Weekday      int   `db:"weekday"`
+	Hour         int   `db:"hour"`
+	MessageCount int64 `db:"message_count"`
 }
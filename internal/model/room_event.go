@@ -0,0 +1,53 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RSVPStatus is a member's answer to a room event
+type RSVPStatus string
+
+const (
+	RSVPGoing    RSVPStatus = "going"
+	RSVPMaybe    RSVPStatus = "maybe"
+	RSVPDeclined RSVPStatus = "declined"
+)
+
+// RoomEvent is a calendar event scheduled in a room
+type RoomEvent struct {
+	ID             string         `db:"id" json:"id"`
+	RoomID         string         `db:"room_id" json:"room_id"`
+	CreatedBy      sql.NullString `db:"created_by" json:"created_by,omitempty"`
+	Title          string         `db:"title" json:"title"`
+	Description    sql.NullString `db:"description" json:"description,omitempty"`
+	Location       sql.NullString `db:"location" json:"location,omitempty"`
+	StartsAt       time.Time      `db:"starts_at" json:"starts_at"`
+	EndsAt         sql.NullTime   `db:"ends_at" json:"ends_at,omitempty"`
+	ReminderSentAt sql.NullTime   `db:"reminder_sent_at" json:"-"`
+	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// EndTime returns when the event ends; events without an end time are
+// treated as ending when they start
+func (e *RoomEvent) EndTime() time.Time {
+	if e.EndsAt.Valid {
+		return e.EndsAt.Time
+	}
+	return e.StartsAt
+}
+
+// HasEnded checks if the event is over
+func (e *RoomEvent) HasEnded() bool {
+	return e.EndTime().Before(time.Now())
+}
+
+// RoomEventWithRSVPs includes the answer counts and the viewer's own answer
+type RoomEventWithRSVPs struct {
+	RoomEvent
+	GoingCount    int            `db:"going_count" json:"going_count"`
+	MaybeCount    int            `db:"maybe_count" json:"maybe_count"`
+	DeclinedCount int            `db:"declined_count" json:"declined_count"`
+	MyRSVP        sql.NullString `db:"my_rsvp" json:"my_rsvp,omitempty"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// JoinRequestStatus is the review state of a room join request
+type JoinRequestStatus string
+
+const (
+	JoinRequestPending  JoinRequestStatus = "pending"
+	JoinRequestApproved JoinRequestStatus = "approved"
+	JoinRequestRejected JoinRequestStatus = "rejected"
+)
+
+// RoomJoinRequest is a non-member's request to join a private room
+type RoomJoinRequest struct {
+	ID         string            `db:"id" json:"id"`
+	RoomID     string            `db:"room_id" json:"room_id"`
+	UserID     string            `db:"user_id" json:"user_id"`
+	Message    sql.NullString    `db:"message" json:"message,omitempty"`
+	Status     JoinRequestStatus `db:"status" json:"status"`
+	ResolvedBy sql.NullString    `db:"resolved_by" json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time        `db:"resolved_at" json:"resolved_at,omitempty"`
+	CreatedAt  time.Time         `db:"created_at" json:"created_at"`
+}
+
+// IsPending checks if the request is awaiting review
+func (r *RoomJoinRequest) IsPending() bool {
+	return r.Status == JoinRequestPending
+}
+
+// RoomJoinRequestWithUser includes the requester's profile
+type RoomJoinRequestWithUser struct {
+	RoomJoinRequest
+	Username    string         `db:"username" json:"username"`
+	DisplayName sql.NullString `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL   sql.NullString `db:"avatar_url" json:"avatar_url,omitempty"`
+}
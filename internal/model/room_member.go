@@ -22,6 +22,14 @@ type RoomMember struct {
 	JoinedAt   time.Time      `db:"joined_at" json:"joined_at"`
 	LastReadAt time.Time      `db:"last_read_at" json:"last_read_at"`
 	IsMuted    bool           `db:"is_muted" json:"is_muted"`
+	MutedUntil *time.Time     `db:"muted_until" json:"muted_until,omitempty"`
+	MutedBy    sql.NullString `db:"muted_by" json:"muted_by,omitempty"`
+}
+
+// IsMutedAt checks if the member is muted at the given time. Mutes past
+// their deadline no longer apply even before the expiry job clears them.
+func (rm *RoomMember) IsMutedAt(now time.Time) bool {
+	return rm.IsMuted && (rm.MutedUntil == nil || rm.MutedUntil.After(now))
 }
 
 // GetNickname returns nickname or empty string
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RoomMergeStatus is the progress of a room merge
+type RoomMergeStatus string
+
+const (
+	RoomMergePending   RoomMergeStatus = "pending"
+	RoomMergeRunning   RoomMergeStatus = "running"
+	RoomMergeCompleted RoomMergeStatus = "completed"
+	RoomMergeFailed    RoomMergeStatus = "failed"
+)
+
+// RoomMerge moves the members, and optionally the history, of a source room
+// into a target room in batches. Progress is stored so an interrupted merge
+// resumes where it stopped.
+type RoomMerge struct {
+	ID             string          `db:"id" json:"id"`
+	SourceRoomID   string          `db:"source_room_id" json:"source_room_id"`
+	TargetRoomID   string          `db:"target_room_id" json:"target_room_id"`
+	IncludeHistory bool            `db:"include_history" json:"include_history"`
+	Status         RoomMergeStatus `db:"status" json:"status"`
+	MembersMoved   int             `db:"members_moved" json:"members_moved"`
+	MessagesMoved  int             `db:"messages_moved" json:"messages_moved"`
+	Error          sql.NullString  `db:"error" json:"error,omitempty"`
+	MergedBy       sql.NullString  `db:"merged_by" json:"merged_by,omitempty"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time       `db:"updated_at" json:"updated_at"`
+	CompletedAt    *time.Time      `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// IsActive checks if the merge still has work left
+func (m *RoomMerge) IsActive() bool {
+	return m.Status == RoomMergePending || m.Status == RoomMergeRunning
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RoomRolePermission overrides whether a role may perform an action in a room
+type RoomRolePermission struct {
+	RoomID    string         `db:"room_id" json:"room_id"`
+	Role      MemberRole     `db:"role" json:"role"`
+	Action    string         `db:"action" json:"action"`
+	Allowed   bool           `db:"allowed" json:"allowed"`
+	UpdatedBy sql.NullString `db:"updated_by" json:"updated_by,omitempty"`
+	UpdatedAt time.Time      `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Room webhook events
+const (
+	WebhookEventMessageCreated = "message.created"
+	WebhookEventMemberJoined   = "member.joined"
+	WebhookEventMemberLeft     = "member.left"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventMessageCreated,
+	WebhookEventMemberJoined,
+	WebhookEventMemberLeft,
+}
+
+// IsWebhookEvent checks if the event is known
+func IsWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// RoomWebhook is an outbound endpoint notified of a room's events
+type RoomWebhook struct {
+	ID        string         `db:"id" json:"id"`
+	RoomID    string         `db:"room_id" json:"room_id"`
+	URL       string         `db:"url" json:"url"`
+	Secret    string         `db:"secret" json:"-"`
+	Events    pq.StringArray `db:"events" json:"events"`
+	CreatedBy sql.NullString `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+}
+
+// WebhookDeliveryStatus is where a delivery stands
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// RoomWebhookDelivery is one event sent, or to be sent, to a webhook
+type RoomWebhookDelivery struct {
+	ID             string                `db:"id" json:"id"`
+	WebhookID      string                `db:"webhook_id" json:"webhook_id"`
+	Event          string                `db:"event" json:"event"`
+	Payload        []byte                `db:"payload" json:"-"` // JSON envelope posted to the webhook
+	Status         WebhookDeliveryStatus `db:"status" json:"status"`
+	Attempts       int                   `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time             `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode sql.NullInt32         `db:"last_status_code" json:"last_status_code,omitempty"`
+	LastError      sql.NullString        `db:"last_error" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time            `db:"delivered_at" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `db:"created_at" json:"created_at"`
+}
+
+// RoomWebhookDeliveryTarget is a claimed delivery with where to send it
+type RoomWebhookDeliveryTarget struct {
+	RoomWebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ScheduledMessageStatus is the delivery state of a scheduled message
+type ScheduledMessageStatus string
+
+const (
+	ScheduledMessagePending  ScheduledMessageStatus = "pending"
+	ScheduledMessageSending  ScheduledMessageStatus = "sending" // claimed by a delivery worker
+	ScheduledMessageSent     ScheduledMessageStatus = "sent"
+	ScheduledMessageFailed   ScheduledMessageStatus = "failed"
+	ScheduledMessageCanceled ScheduledMessageStatus = "canceled"
+)
+
+// ScheduledMessage is a room message held back until ScheduledAt
+type ScheduledMessage struct {
+	ID          string                 `db:"id" json:"id"`
+	RoomID      string                 `db:"room_id" json:"room_id"`
+	UserID      string                 `db:"user_id" json:"user_id"`
+	Content     string                 `db:"content" json:"content"`
+	Type        MessageType            `db:"type" json:"type"`
+	ReplyToID   sql.NullString         `db:"reply_to_id" json:"reply_to_id,omitempty"`
+	ScheduledAt time.Time              `db:"scheduled_at" json:"scheduled_at"`
+	Status      ScheduledMessageStatus `db:"status" json:"status"`
+	MessageID   sql.NullString         `db:"message_id" json:"message_id,omitempty"`
+	Error       sql.NullString         `db:"error" json:"error,omitempty"`
+	ClaimedAt   *time.Time             `db:"claimed_at" json:"-"`
+	CreatedAt   time.Time              `db:"created_at" json:"created_at"`
+	SentAt      *time.Time             `db:"sent_at" json:"sent_at,omitempty"`
+}
+
+// IsPending checks if the message is still waiting to be sent
+func (m *ScheduledMessage) IsPending() bool {
+	return m.Status == ScheduledMessagePending
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SCIMUser is an account as SCIM provisioning sees it. Accounts the
+// identity provider never touched are active and have no external ID.
+type SCIMUser struct {
+	User
+	ExternalID sql.NullString `db:"scim_external_id"`
+	Active     bool           `db:"scim_active"`
+}
+
+// SCIMGroup is a group pushed by the identity provider
+type SCIMGroup struct {
+	ID          string         `db:"id" json:"id"`
+	DisplayName string         `db:"display_name" json:"display_name"`
+	ExternalID  sql.NullString `db:"external_id" json:"external_id,omitempty"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at" json:"updated_at"`
+
+	Members []*SCIMGroupMember `db:"-" json:"members,omitempty"`
+}
+
+// SCIMGroupMember is a member of a SCIMGroup
+type SCIMGroupMember struct {
+	UserID   string `db:"user_id" json:"user_id"`
+	Username string `db:"username" json:"username"`
+}
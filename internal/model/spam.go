@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SpamSignal is an account trait the spam sweep scores
+type SpamSignal string
+
+const (
+	// SpamSignalNoMessages accounts never posted in a room or DM
+	SpamSignalNoMessages SpamSignal = "no_messages"
+	// SpamSignalFriendRequests accounts have many unanswered friend requests out
+	SpamSignalFriendRequests SpamSignal = "mass_friend_requests"
+	// SpamSignalDisposableEmail accounts registered with a throwaway address
+	SpamSignalDisposableEmail SpamSignal = "disposable_email"
+)
+
+// SpamAction is what the sweep did with a high scoring account
+type SpamAction string
+
+const (
+	// SpamActionFlagged accounts are listed for admins to review
+	SpamActionFlagged SpamAction = "flagged"
+	// SpamActionSuspended accounts were also suspended
+	SpamActionSuspended SpamAction = "suspended"
+)
+
+// SpamCandidate is an account the sweep scores, with the activity it looks at
+type SpamCandidate struct {
+	UserID                string    `db:"id"`
+	Email                 string    `db:"email"`
+	CreatedAt             time.Time `db:"created_at"`
+	MessageCount          int       `db:"message_count"`
+	PendingFriendRequests int       `db:"pending_friend_requests"`
+}
+
+// SpamFlag records an account the sweep found suspicious
+type SpamFlag struct {
+	ID        string         `db:"id" json:"id"`
+	UserID    string         `db:"user_id" json:"user_id"`
+	Score     int            `db:"score" json:"score"`
+	Signals   pq.StringArray `db:"signals" json:"signals"`
+	Action    SpamAction     `db:"action" json:"action"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+}
+
+// SpamFlagWithUser is a flag with the account it is about
+type SpamFlagWithUser struct {
+	SpamFlag
+	Username string `db:"username" json:"username"`
+	Email    string `db:"email" json:"email"`
+}
+
+// SpamSweepRun is the outcome of one sweep
+type SpamSweepRun struct {
+	ID         string    `db:"id" json:"id"`
+	StartedAt  time.Time `db:"started_at" json:"started_at"`
+	FinishedAt time.Time `db:"finished_at" json:"finished_at"`
+	Scanned    int       `db:"scanned" json:"scanned"`
+	Flagged    int       `db:"flagged" json:"flagged"` // includes suspended accounts
+	Suspended  int       `db:"suspended" json:"suspended"`
+}
+
+// SpamStats summarizes the sweep for admins
+type SpamStats struct {
+	LastRun        *SpamSweepRun `json:"last_run,omitempty"`
+	TotalFlagged   int           `json:"total_flagged"` // includes suspended accounts
+	TotalSuspended int           `json:"total_suspended"`
+	FlaggedLast24h int           `json:"flagged_last_24h"`
+}
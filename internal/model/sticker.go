@@ -0,0 +1,33 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// StickerPack is a curated set of stickers. Stickers of an inactive pack
+// still render in old messages but cannot be sent.
+type StickerPack struct {
+	ID          string         `db:"id" json:"id"`
+	Name        string         `db:"name" json:"name"`
+	Description string         `db:"description" json:"description"`
+	IsActive    bool           `db:"is_active" json:"is_active"`
+	CreatedBy   sql.NullString `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at" json:"updated_at"`
+
+	Stickers []*Sticker `db:"-" json:"stickers,omitempty"`
+}
+
+// Sticker is one image of a sticker pack. A sticker message carries the
+// sticker ID as its content.
+type Sticker struct {
+	ID        string    `db:"id" json:"id"`
+	PackID    string    `db:"pack_id" json:"pack_id"`
+	Name      string    `db:"name" json:"name"`
+	ImageURL  string    `db:"image_url" json:"image_url"`
+	Width     int       `db:"width" json:"width"`
+	Height    int       `db:"height" json:"height"`
+	Position  int       `db:"position" json:"position"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
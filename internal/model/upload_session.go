@@ -0,0 +1,44 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UploadCategory decides a resumable upload's size limit and destination
+type UploadCategory string
+
+const (
+	UploadCategoryImage  UploadCategory = "image"
+	UploadCategoryFile   UploadCategory = "file"
+	UploadCategoryAvatar UploadCategory = "avatar"
+)
+
+// UploadSession is a resumable upload the client sends in chunks
+type UploadSession struct {
+	ID           string         `db:"id" json:"id"`
+	UserID       string         `db:"user_id" json:"user_id"`
+	Category     UploadCategory `db:"category" json:"category"`
+	FileName     string         `db:"file_name" json:"file_name"`
+	ContentType  string         `db:"content_type" json:"content_type"`
+	TotalSize    int64          `db:"total_size" json:"total_size"`
+	ReceivedSize int64          `db:"received_size" json:"received_size"`
+	StoredName   sql.NullString `db:"stored_name" json:"-"` // file name under the category directory once complete
+	ExpiresAt    time.Time      `db:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time      `db:"updated_at" json:"updated_at"`
+	CompletedAt  *time.Time     `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// IsComplete checks if every byte was received and the file stored
+func (s *UploadSession) IsComplete() bool {
+	return s.CompletedAt != nil
+}
+
+// Progress returns the received fraction between 0 and 1
+func (s *UploadSession) Progress() float64 {
+	if s.TotalSize <= 0 {
+		return 0
+	}
+	return float64(s.ReceivedSize) / float64(s.TotalSize)
+}
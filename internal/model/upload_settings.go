@@ -0,0 +1,95 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UploadLimit bounds the uploads of one category
+type UploadLimit struct {
+	MaxSize      int64    `json:"max_size"` // bytes
+	AllowedTypes []string `json:"allowed_types"`
+	SubDir       string   `json:"-"` // directory under the uploads root
+}
+
+// Allows checks if files of the content type may be uploaded
+func (l *UploadLimit) Allows(contentType string) bool {
+	for _, t := range l.AllowedTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadLimits holds the limits of every upload category
+type UploadLimits struct {
+	Image  UploadLimit `json:"image"`
+	File   UploadLimit `json:"file"`
+	Avatar UploadLimit `json:"avatar"`
+}
+
+// For returns the limits of a category. Unknown categories are treated as
+// general files.
+func (l *UploadLimits) For(category UploadCategory) *UploadLimit {
+	switch category {
+	case UploadCategoryImage:
+		return &l.Image
+	case UploadCategoryAvatar:
+		return &l.Avatar
+	default:
+		return &l.File
+	}
+}
+
+// DefaultUploadLimits returns the limits used when none are configured
+func DefaultUploadLimits() UploadLimits {
+	imageTypes := []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+	fileTypes := []string{
+		"application/pdf",
+		"application/msword",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.ms-excel",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"text/plain",
+		"application/zip",
+	}
+
+	return UploadLimits{
+		Image:  UploadLimit{MaxSize: 5 << 20, AllowedTypes: imageTypes, SubDir: "images"},
+		File:   UploadLimit{MaxSize: 10 << 20, AllowedTypes: append(fileTypes, imageTypes...), SubDir: "files"},
+		Avatar: UploadLimit{MaxSize: 2 << 20, AllowedTypes: imageTypes, SubDir: "avatars"},
+	}
+}
+
+// UploadSettings are the limits admins override at runtime. Null fields
+// keep the configured value.
+type UploadSettings struct {
+	ImageMaxSize       sql.NullInt64  `db:"image_max_size" json:"image_max_size"`
+	ImageAllowedTypes  pq.StringArray `db:"image_allowed_types" json:"image_allowed_types"`
+	FileMaxSize        sql.NullInt64  `db:"file_max_size" json:"file_max_size"`
+	FileAllowedTypes   pq.StringArray `db:"file_allowed_types" json:"file_allowed_types"`
+	AvatarMaxSize      sql.NullInt64  `db:"avatar_max_size" json:"avatar_max_size"`
+	AvatarAllowedTypes pq.StringArray `db:"avatar_allowed_types" json:"avatar_allowed_types"`
+	UpdatedBy          sql.NullString `db:"updated_by" json:"updated_by"`
+	UpdatedAt          time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// Apply returns the configured limits with the overrides in place
+func (s *UploadSettings) Apply(limits UploadLimits) UploadLimits {
+	applyUploadOverride(&limits.Image, s.ImageMaxSize, s.ImageAllowedTypes)
+	applyUploadOverride(&limits.File, s.FileMaxSize, s.FileAllowedTypes)
+	applyUploadOverride(&limits.Avatar, s.AvatarMaxSize, s.AvatarAllowedTypes)
+	return limits
+}
+
+func applyUploadOverride(limit *UploadLimit, maxSize sql.NullInt64, allowedTypes pq.StringArray) {
+	if maxSize.Valid {
+		limit.MaxSize = maxSize.Int64
+	}
+	if allowedTypes != nil {
+		limit.AllowedTypes = allowedTypes
+	}
+}
@@ -14,6 +14,35 @@ const (
 	UserStatusBusy    UserStatus = "busy"
 )
 
+// LastSeenVisibility controls who may see when a user was last online
+type LastSeenVisibility string
+
+const (
+	LastSeenEveryone LastSeenVisibility = "everyone"
+	LastSeenFriends  LastSeenVisibility = "friends"
+	LastSeenNobody   LastSeenVisibility = "nobody"
+)
+
+// DigestFrequency is how often a user is emailed a summary of unread activity
+type DigestFrequency string
+
+const (
+	DigestOff    DigestFrequency = "off"
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+)
+
+// Period returns how much time one digest covers, zero when digests are off
+func (f DigestFrequency) Period() time.Duration {
+	switch f {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
 type User struct {
 	ID           string         `db:"id" json:"id"`
 	Username     string         `db:"username" json:"username"`
@@ -26,6 +55,25 @@ type User struct {
 	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
 	UpdatedAt    time.Time      `db:"updated_at" json:"updated_at"`
 	LastSeenAt   sql.NullTime   `db:"last_seen_at" json:"last_seen_at,omitempty"`
+	IsAdmin      bool           `db:"is_admin" json:"is_admin"`
+	IsBot        bool           `db:"is_bot" json:"is_bot"` // posts through an incoming webhook; cannot log in
+	DeletedAt    sql.NullTime   `db:"deleted_at" json:"-"`
+
+	// Who may see LastSeenAt on the user's profile
+	LastSeenVisibility LastSeenVisibility `db:"last_seen_visibility" json:"last_seen_visibility"`
+	// SHA-256 of the trimmed, lowercased email, maintained by the database
+	EmailHash sql.NullString `db:"email_hash" json:"-"`
+	// Whether contacts discovery may find the user by email hash
+	Discoverable bool `db:"discoverable" json:"discoverable"`
+	// How often unread activity is summarized by email
+	DigestFrequency DigestFrequency `db:"digest_frequency" json:"digest_frequency"`
+	// When the last digest was sent; the next one covers activity after it
+	DigestSentAt sql.NullTime `db:"digest_sent_at" json:"-"`
+}
+
+// IsDeleted reports whether the account was deleted and anonymized
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt.Valid
 }
 
 // GetDisplayName returns display_name or username as fallback
@@ -65,6 +113,27 @@ type UserProfile struct {
 	AvatarURL   string     `json:"avatar_url"`
 	Status      UserStatus `json:"status"`
 	Bio         string     `json:"bio"`
+	LastSeenAt  *time.Time `json:"last_seen_at,omitempty"` // only when the user's privacy setting allows the viewer
+}
+
+// UserDisplay is what other users see next to a user's messages and presence
+type UserDisplay struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	IsBot       bool   `json:"is_bot,omitempty"`
+}
+
+// ToDisplay converts User to UserDisplay
+func (u *User) ToDisplay() *UserDisplay {
+	return &UserDisplay{
+		ID:          u.ID,
+		Username:    u.Username,
+		DisplayName: u.GetDisplayName(),
+		AvatarURL:   u.GetAvatarURL(),
+		IsBot:       u.IsBot,
+	}
 }
 
 // ToProfile converts User to UserProfile
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// BanKind distinguishes permanent bans from time-limited suspensions
+type BanKind string
+
+const (
+	BanKindBan        BanKind = "ban"
+	BanKindSuspension BanKind = "suspension"
+)
+
+// UserBan represents a ban or suspension of a user account
+type UserBan struct {
+	ID        string         `db:"id" json:"id"`
+	UserID    string         `db:"user_id" json:"user_id"`
+	Reason    string         `db:"reason" json:"reason"`
+	BannedBy  sql.NullString `db:"banned_by" json:"banned_by,omitempty"`
+	ExpiresAt *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	RevokedAt *time.Time     `db:"revoked_at" json:"revoked_at,omitempty"`
+	RevokedBy sql.NullString `db:"revoked_by" json:"revoked_by,omitempty"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+}
+
+// Kind returns whether this is a permanent ban or a suspension
+func (b *UserBan) Kind() BanKind {
+	if b.ExpiresAt == nil {
+		return BanKindBan
+	}
+	return BanKindSuspension
+}
+
+// IsActive checks if the ban is in effect at the given time
+func (b *UserBan) IsActive(now time.Time) bool {
+	if b.RevokedAt != nil {
+		return false
+	}
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
@@ -0,0 +1,107 @@
+// Package moderation classifies uploaded images and message text. A
+// Detector or TextClassifier only scores content; what happens at a given
+// score is decided by thresholds in the service layer.
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPTimeout bounds a single call to the detection API
+const DefaultHTTPTimeout = 10 * time.Second
+
+// Image is an image to classify
+type Image struct {
+	ContentType string
+	Body        io.Reader
+}
+
+// Result is a detector's assessment of an image
+type Result struct {
+	// Score is the likelihood between 0 and 1 that the image is explicit or
+	// offensive
+	Score float64 `json:"score"`
+	// Labels name what was detected, e.g. "nudity" or "gore"
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Detector scores images for explicit or offensive content
+type Detector interface {
+	Detect(ctx context.Context, img *Image) (*Result, error)
+}
+
+// StubDetector returns a fixed result without looking at the image. The
+// zero value flags nothing; it is used when no detection API is configured.
+type StubDetector struct {
+	Result Result
+}
+
+// Detect implements Detector
+func (d *StubDetector) Detect(ctx context.Context, img *Image) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result := d.Result
+	return &result, nil
+}
+
+// HTTPDetector sends images to an external detection API. The image is
+// posted as the request body with its content type, and the API answers
+// with a JSON Result.
+type HTTPDetector struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTPDetector creates a detector posting to url, authenticating with a
+// bearer token when apiKey is not empty
+func NewHTTPDetector(url, apiKey string, timeout time.Duration) *HTTPDetector {
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	return &HTTPDetector{
+		url:    url,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Detect implements Detector
+func (d *HTTPDetector) Detect(ctx context.Context, img *Image) (*Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, img.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create detection request: %w", err)
+	}
+	req.Header.Set("Content-Type", img.ContentType)
+	req.Header.Set("Accept", "application/json")
+	if d.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+d.apiKey)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call detection api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, 64<<10)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, body)
+		return nil, fmt.Errorf("detection api returned status %d", resp.StatusCode)
+	}
+
+	var result Result
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode detection result: %w", err)
+	}
+	if result.Score < 0 || result.Score > 1 {
+		return nil, fmt.Errorf("detection api returned score %v outside of [0,1]", result.Score)
+	}
+	return &result, nil
+}
@@ -0,0 +1,82 @@
+package moderation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPDetector_Detect(t *testing.T) {
+	var gotType, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotType = r.Header.Get("Content-Type")
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"score":0.72,"labels":["nudity","suggestive"]}`))
+	}))
+	defer server.Close()
+
+	detector := NewHTTPDetector(server.URL, "secret", 0)
+	result, err := detector.Detect(context.Background(), &Image{
+		ContentType: "image/png",
+		Body:        strings.NewReader("png-bytes"),
+	})
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+
+	if result.Score != 0.72 || len(result.Labels) != 2 || result.Labels[0] != "nudity" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if gotType != "image/png" || gotAuth != "Bearer secret" || gotBody != "png-bytes" {
+		t.Errorf("Unexpected request: type %q, auth %q, body %q", gotType, gotAuth, gotBody)
+	}
+}
+
+func TestHTTPDetector_DetectRejectsBadResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"server error", http.StatusInternalServerError, `{"error":"boom"}`},
+		{"malformed", http.StatusOK, `not json`},
+		{"score out of range", http.StatusOK, `{"score":1.5}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewHTTPDetector(server.URL, "", 0).Detect(context.Background(), &Image{
+				ContentType: "image/jpeg",
+				Body:        strings.NewReader("jpeg-bytes"),
+			})
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestStubDetector_Detect(t *testing.T) {
+	result, err := (&StubDetector{}).Detect(context.Background(), &Image{Body: strings.NewReader("")})
+	if err != nil || result.Score != 0 {
+		t.Errorf("Expected the zero stub to flag nothing, got %+v, %v", result, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&StubDetector{}).Detect(ctx, &Image{}); err == nil {
+		t.Error("Expected a canceled context to fail")
+	}
+}
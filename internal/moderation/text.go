@@ -0,0 +1,229 @@
+package moderation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextClassifier scores message text for abusive or offensive content
+type TextClassifier interface {
+	Classify(ctx context.Context, text string) (*Result, error)
+}
+
+// HTTPTextClassifier sends text to an external moderation API as
+// {"text": "..."}; the API answers with a JSON Result like HTTPDetector's.
+type HTTPTextClassifier struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTPTextClassifier creates a classifier posting to url, authenticating
+// with a bearer token when apiKey is not empty
+func NewHTTPTextClassifier(url, apiKey string, timeout time.Duration) *HTTPTextClassifier {
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	return &HTTPTextClassifier{
+		url:    url,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Classify implements TextClassifier
+func (c *HTTPTextClassifier) Classify(ctx context.Context, text string) (*Result, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode moderation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call moderation api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody := io.LimitReader(resp.Body, 64<<10)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, respBody)
+		return nil, fmt.Errorf("moderation api returned status %d", resp.StatusCode)
+	}
+
+	var result Result
+	if err := json.NewDecoder(respBody).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode moderation result: %w", err)
+	}
+	if result.Score < 0 || result.Score > 1 {
+		return nil, fmt.Errorf("moderation api returned score %v outside of [0,1]", result.Score)
+	}
+	return &result, nil
+}
+
+// WordFilter finds listed words in text, ignoring case. Words written in
+// letters or digits only match whole words, so "ass" does not match
+// "class"; other words, such as Chinese ones, match anywhere. A nil
+// WordFilter matches nothing.
+type WordFilter struct {
+	words []string
+}
+
+// NewWordFilter creates a filter for words; blank entries are ignored
+func NewWordFilter(words []string) *WordFilter {
+	f := &WordFilter{}
+	seen := make(map[string]bool, len(words))
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" || seen[w] {
+			continue
+		}
+		seen[w] = true
+		f.words = append(f.words, w)
+	}
+	return f
+}
+
+// Len returns the number of words in the list
+func (f *WordFilter) Len() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.words)
+}
+
+// Find returns the listed words text contains, in list order
+func (f *WordFilter) Find(text string) []string {
+	if f.Len() == 0 {
+		return nil
+	}
+	lower := strings.ToLower(text)
+
+	var found []string
+	for _, w := range f.words {
+		if len(f.matches(lower, w)) > 0 {
+			found = append(found, w)
+		}
+	}
+	return found
+}
+
+// Mask replaces every listed word in text with asterisks, one per character
+func (f *WordFilter) Mask(text string) string {
+	if f.Len() == 0 {
+		return text
+	}
+	lower := strings.ToLower(text)
+	// Lowercasing may change byte lengths; offsets only line up when it
+	// does not, otherwise mask the lowercased text
+	if len(lower) != len(text) {
+		text = lower
+	}
+
+	masked := []byte(text)
+	for _, w := range f.words {
+		for _, start := range f.matches(lower, w) {
+			for i := start; i < start+len(w); i++ {
+				masked[i] = 0
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(masked); {
+		if masked[i] == 0 {
+			// One asterisk per masked character
+			_, size := utf8.DecodeRuneInString(text[i:])
+			b.WriteByte('*')
+			i += size
+			continue
+		}
+		b.WriteByte(masked[i])
+		i++
+	}
+	return b.String()
+}
+
+// matches returns the byte offsets of w in lower
+func (f *WordFilter) matches(lower, w string) []int {
+	whole := isWord(w)
+
+	var offsets []int
+	for from := 0; from < len(lower); {
+		i := strings.Index(lower[from:], w)
+		if i < 0 {
+			break
+		}
+		start := from + i
+		end := start + len(w)
+		if !whole || (!wordCharBefore(lower, start) && !wordCharAfter(lower, end)) {
+			offsets = append(offsets, start)
+		}
+		from = start + 1
+	}
+	return offsets
+}
+
+// LoadWordList reads a word list file with one word or phrase per line;
+// blank lines and lines starting with # are skipped
+func LoadWordList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read word list: %w", err)
+	}
+	return words, nil
+}
+
+// isWord reports whether w is made of letters and digits that form words
+// with spaces between them, unlike Chinese or Japanese
+func isWord(w string) bool {
+	for _, r := range w {
+		if r > unicode.MaxLatin1 || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ') {
+			return false
+		}
+	}
+	return true
+}
+
+func wordCharBefore(s string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func wordCharAfter(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
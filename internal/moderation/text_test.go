@@ -0,0 +1,92 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHTTPTextClassifier_Classify(t *testing.T) {
+	var got map[string]string
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"score":0.91,"labels":["harassment"]}`))
+	}))
+	defer server.Close()
+
+	result, err := NewHTTPTextClassifier(server.URL, "secret", 0).Classify(context.Background(), "you are awful")
+	if err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+	if result.Score != 0.91 || len(result.Labels) != 1 || result.Labels[0] != "harassment" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if got["text"] != "you are awful" || gotAuth != "Bearer secret" {
+		t.Errorf("Unexpected request: body %v, auth %q", got, gotAuth)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"score":-1}`))
+	}))
+	defer bad.Close()
+	if _, err := NewHTTPTextClassifier(bad.URL, "", 0).Classify(context.Background(), "hi"); err == nil {
+		t.Error("Expected an error for a score out of range")
+	}
+}
+
+func TestWordFilter(t *testing.T) {
+	filter := NewWordFilter([]string{"darn", " ", "Heck", "笨蛋", "darn"})
+
+	tests := []struct {
+		text   string
+		found  []string
+		masked string
+	}{
+		{"hello there", nil, "hello there"},
+		{"Darn it, HECK!", []string{"darn", "heck"}, "**** it, ****!"},
+		{"darning socks in Checkers", nil, "darning socks in Checkers"},
+		{"你這個笨蛋", []string{"笨蛋"}, "你這個**"},
+		{"darn darn", []string{"darn"}, "**** ****"},
+	}
+	for _, tt := range tests {
+		if got := filter.Find(tt.text); !reflect.DeepEqual(got, tt.found) {
+			t.Errorf("Find(%q) = %v, want %v", tt.text, got, tt.found)
+		}
+		if got := filter.Mask(tt.text); got != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.text, got, tt.masked)
+		}
+	}
+
+	var none *WordFilter
+	if none.Find("darn") != nil || none.Mask("darn") != "darn" {
+		t.Error("Expected a nil filter to match nothing")
+	}
+}
+
+func TestLoadWordList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "# 不當用語\nidiot\n\n  笨蛋  \n#skip\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := LoadWordList(path)
+	if err != nil {
+		t.Fatalf("Failed to load word list: %v", err)
+	}
+	if want := []string{"idiot", "笨蛋"}; !reflect.DeepEqual(words, want) {
+		t.Errorf("Expected %v, got %v", want, words)
+	}
+
+	if _, err := LoadWordList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
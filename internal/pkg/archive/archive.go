@@ -0,0 +1,211 @@
+// Package archive writes and verifies tamper-evident JSON Lines archives.
+// Every record carries the hash of its predecessor, so altering, dropping or
+// reordering any line breaks the chain from that point on.
+package archive
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Format identifies the archive layout and hash algorithm
+const Format = "chat-archive/v1+sha256"
+
+// Record kinds written by the archive itself
+const (
+	KindHeader  = "header"
+	KindTrailer = "trailer"
+)
+
+// genesisHash is the previous hash of the first record
+var genesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+var (
+	ErrBrokenChain    = errors.New("archive hash chain is broken")
+	ErrMissingTrailer = errors.New("archive has no trailer")
+)
+
+// Record is a single line of an archive
+type Record struct {
+	Seq      int64           `json:"seq"`
+	Kind     string          `json:"kind"`
+	Data     json.RawMessage `json:"data"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+}
+
+// Trailer summarizes a completed archive
+type Trailer struct {
+	Format   string `json:"format"`
+	Records  int64  `json:"records"` // data records, excluding header and trailer
+	HeadHash string `json:"head_hash"`
+}
+
+// hashed is the portion of a record covered by its hash
+type hashed struct {
+	Seq      int64           `json:"seq"`
+	Kind     string          `json:"kind"`
+	Data     json.RawMessage `json:"data"`
+	PrevHash string          `json:"prev_hash"`
+}
+
+func computeHash(seq int64, kind string, data json.RawMessage, prevHash string) (string, error) {
+	body, err := json.Marshal(&hashed{Seq: seq, Kind: kind, Data: data, PrevHash: prevHash})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Writer appends hash-chained records to an archive
+type Writer struct {
+	w       *bufio.Writer
+	seq     int64
+	prev    string
+	records int64
+	closed  bool
+}
+
+// NewWriter starts an archive, writing header as its first record
+func NewWriter(w io.Writer, header interface{}) (*Writer, error) {
+	aw := &Writer{w: bufio.NewWriter(w), prev: genesisHash}
+	if err := aw.write(KindHeader, header); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// Append writes a data record
+func (w *Writer) Append(kind string, data interface{}) error {
+	if kind == KindHeader || kind == KindTrailer {
+		return fmt.Errorf("archive: reserved record kind %q", kind)
+	}
+	if err := w.write(kind, data); err != nil {
+		return err
+	}
+	w.records++
+	return nil
+}
+
+// Close writes the trailer and flushes the archive. The returned trailer's
+// head hash identifies the archive and can be recorded out of band.
+func (w *Writer) Close() (*Trailer, error) {
+	if w.closed {
+		return nil, errors.New("archive: writer already closed")
+	}
+	w.closed = true
+
+	trailer := &Trailer{Format: Format, Records: w.records, HeadHash: w.prev}
+	if err := w.write(KindTrailer, trailer); err != nil {
+		return nil, err
+	}
+	if err := w.w.Flush(); err != nil {
+		return nil, fmt.Errorf("archive: flush: %w", err)
+	}
+	// The trailer's own hash closes the chain
+	trailer.HeadHash = w.prev
+	return trailer, nil
+}
+
+// Flush writes buffered records to the underlying writer
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) write(kind string, data interface{}) error {
+	if w.closed && kind != KindTrailer {
+		return errors.New("archive: write after close")
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("archive: encode %s: %w", kind, err)
+	}
+	hash, err := computeHash(w.seq, kind, raw, w.prev)
+	if err != nil {
+		return fmt.Errorf("archive: hash %s: %w", kind, err)
+	}
+
+	line, err := json.Marshal(&Record{Seq: w.seq, Kind: kind, Data: raw, PrevHash: w.prev, Hash: hash})
+	if err != nil {
+		return fmt.Errorf("archive: encode record: %w", err)
+	}
+	if _, err := w.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("archive: write: %w", err)
+	}
+
+	w.seq++
+	w.prev = hash
+	return nil
+}
+
+// Verify reads an archive and checks its hash chain and trailer. It returns
+// the trailer with HeadHash set to the hash of the final record.
+func Verify(r io.Reader) (*Trailer, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	prev := genesisHash
+	var seq, records int64
+	var trailer *Trailer
+
+	for scanner.Scan() {
+		if trailer != nil {
+			return nil, fmt.Errorf("%w: record after trailer at seq %d", ErrBrokenChain, seq)
+		}
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("archive: decode seq %d: %w", seq, err)
+		}
+		if rec.Seq != seq || rec.PrevHash != prev {
+			return nil, fmt.Errorf("%w at seq %d", ErrBrokenChain, seq)
+		}
+		if seq == 0 && rec.Kind != KindHeader {
+			return nil, fmt.Errorf("%w: first record is %q, not a header", ErrBrokenChain, rec.Kind)
+		}
+
+		hash, err := computeHash(rec.Seq, rec.Kind, rec.Data, rec.PrevHash)
+		if err != nil {
+			return nil, err
+		}
+		if hash != rec.Hash {
+			return nil, fmt.Errorf("%w: hash mismatch at seq %d", ErrBrokenChain, seq)
+		}
+
+		switch rec.Kind {
+		case KindHeader:
+			if seq != 0 {
+				return nil, fmt.Errorf("%w: unexpected header at seq %d", ErrBrokenChain, seq)
+			}
+		case KindTrailer:
+			trailer = &Trailer{}
+			if err := json.Unmarshal(rec.Data, trailer); err != nil {
+				return nil, fmt.Errorf("archive: decode trailer: %w", err)
+			}
+			if trailer.Records != records || trailer.HeadHash != prev {
+				return nil, fmt.Errorf("%w: trailer does not match records", ErrBrokenChain)
+			}
+		default:
+			records++
+		}
+
+		prev = rec.Hash
+		seq++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("archive: read: %w", err)
+	}
+	if trailer == nil {
+		return nil, ErrMissingTrailer
+	}
+
+	trailer.HeadHash = prev
+	return trailer, nil
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testMessage struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+func writeArchive(t *testing.T, messages ...testMessage) (string, *Trailer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, map[string]string{"room_id": "room-1"})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, m := range messages {
+		if err := w.Append("message", m); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	trailer, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.String(), trailer
+}
+
+func TestArchive_RoundTrip(t *testing.T) {
+	data, written := writeArchive(t,
+		testMessage{ID: "1", Content: "hello"},
+		testMessage{ID: "2", Content: "你好"},
+	)
+
+	if lines := strings.Count(data, "\n"); lines != 4 {
+		t.Fatalf("Expected 4 lines (header, 2 messages, trailer), got %d", lines)
+	}
+
+	verified, err := Verify(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if verified.Records != 2 {
+		t.Errorf("Expected 2 records, got %d", verified.Records)
+	}
+	if verified.HeadHash != written.HeadHash {
+		t.Errorf("Head hash mismatch: wrote %s, verified %s", written.HeadHash, verified.HeadHash)
+	}
+}
+
+func TestArchive_EmptyArchive(t *testing.T) {
+	data, _ := writeArchive(t)
+	trailer, err := Verify(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if trailer.Records != 0 {
+		t.Errorf("Expected 0 records, got %d", trailer.Records)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	data, _ := writeArchive(t,
+		testMessage{ID: "1", Content: "hello"},
+		testMessage{ID: "2", Content: "world"},
+		testMessage{ID: "3", Content: "bye"},
+	)
+	lines := strings.SplitAfter(data, "\n")
+
+	tests := []struct {
+		name    string
+		archive string
+		want    error
+	}{
+		{
+			name:    "edited content",
+			archive: strings.Replace(data, `"world"`, `"w0rld"`, 1),
+			want:    ErrBrokenChain,
+		},
+		{
+			name:    "dropped record",
+			archive: lines[0] + lines[1] + lines[3] + lines[4],
+			want:    ErrBrokenChain,
+		},
+		{
+			name:    "reordered records",
+			archive: lines[0] + lines[2] + lines[1] + lines[3] + lines[4],
+			want:    ErrBrokenChain,
+		},
+		{
+			name:    "truncated",
+			archive: lines[0] + lines[1] + lines[2],
+			want:    ErrMissingTrailer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(tt.archive))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter_RejectsReservedKinds(t *testing.T) {
+	w, err := NewWriter(&bytes.Buffer{}, nil)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.Append(KindTrailer, nil); err == nil {
+		t.Error("Expected error appending a trailer record")
+	}
+}
@@ -0,0 +1,250 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// migrationLockKey serializes migration runs across instances through a
+// PostgreSQL advisory lock
+const migrationLockKey = 7263544301
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// ErrDirtySchema is returned when an earlier migration run failed half way
+var ErrDirtySchema = errors.New("schema is dirty")
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// LoadMigrations reads NNNNNN_name.up.sql and NNNNNN_name.down.sql files,
+// ordered by version
+func LoadMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrationStatus is where the database stands against the known migrations
+type MigrationStatus struct {
+	Version int // 0 when nothing was applied
+	Dirty   bool
+	Latest  int
+	Pending []*Migration
+}
+
+// Migrator applies the migrations and records the level in
+// schema_migrations, the same table the migrate CLI uses, so databases
+// migrated with either stay compatible
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []*Migration
+	logger     *zap.Logger
+}
+
+func NewMigrator(db *sqlx.DB, fsys fs.FS, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// Status reports the applied version and the migrations still to run
+func (m *Migrator) Status(ctx context.Context) (*MigrationStatus, error) {
+	if err := m.ensureTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+	version, dirty, err := m.version(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{Version: version, Dirty: dirty}
+	for _, migration := range m.migrations {
+		status.Latest = migration.Version
+		if migration.Version > version {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	return status, nil
+}
+
+// Up applies every pending migration and returns how many ran
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.locked(ctx, func(conn *sqlx.Conn, version int) error {
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration.Up, migration.Version); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			m.logger.Info("Applied migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts up to steps applied migrations, newest first, and returns
+// how many ran
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.locked(ctx, func(conn *sqlx.Conn, version int) error {
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+			previous := 0
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := m.apply(ctx, conn, migration.Down, previous); err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			m.logger.Info("Reverted migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// locked runs fn on a single connection holding the migration lock, once
+// the schema is known to be clean
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sqlx.Conn, version int) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Advisory locks belong to the session, so lock and unlock on the same
+	// connection the migrations run on
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			m.logger.Warn("Failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	version, dirty, err := m.version(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d, fix the database and reset the dirty flag", ErrDirtySchema, version)
+	}
+	return fn(conn, version)
+}
+
+// apply runs a migration file and records the new version in one
+// transaction, so a failed file leaves the schema where it was
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, script string, version int) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Without arguments the script runs as a simple query, which allows
+	// several statements
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, db sqlx.ExecerContext) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) version(ctx context.Context, db sqlx.QueryerContext) (int, bool, error) {
+	var row struct {
+		Version int  `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	err := sqlx.GetContext(ctx, db, &row, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return row.Version, row.Dirty, nil
+}
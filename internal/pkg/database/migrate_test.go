@@ -0,0 +1,68 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-demo/chat/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000002_rooms.up.sql":   {Data: []byte("CREATE TABLE rooms ();")},
+		"000002_rooms.down.sql": {Data: []byte("DROP TABLE rooms;")},
+		"000001_users.up.sql":   {Data: []byte("CREATE TABLE users ();")},
+		"000001_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"000003_seed.up.sql":    {Data: []byte("INSERT INTO users DEFAULT VALUES;")},
+		"README.md":             {Data: []byte("ignored")},
+	}
+
+	loaded, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	if len(loaded) != 3 {
+		t.Fatalf("Expected 3 migrations, got %d", len(loaded))
+	}
+	if loaded[0].Version != 1 || loaded[0].Name != "users" || loaded[0].Down != "DROP TABLE users;" {
+		t.Errorf("Unexpected first migration: %+v", loaded[0])
+	}
+	if loaded[2].Version != 3 || loaded[2].Down != "" {
+		t.Errorf("Expected the seed migration without a down file, got %+v", loaded[2])
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing up": {
+			"000001_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		},
+		"duplicate version": {
+			"000001_users.up.sql": {Data: []byte("CREATE TABLE users ();")},
+			"000001_rooms.up.sql": {Data: []byte("CREATE TABLE rooms ();")},
+		},
+	}
+	for name, fsys := range tests {
+		if _, err := LoadMigrations(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for i, m := range loaded {
+		if m.Version != i+1 {
+			t.Errorf("Expected version %d, got %d_%s", i+1, m.Version, m.Name)
+		}
+		if m.Down == "" {
+			t.Errorf("Migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
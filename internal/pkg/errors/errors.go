@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -59,15 +60,26 @@ var (
 	ErrInvalidToken    = New(http.StatusUnauthorized, "無效的 Token")
 	ErrTokenExpired    = New(http.StatusUnauthorized, "Token 已過期")
 	ErrInvalidPassword = New(http.StatusUnauthorized, "密碼錯誤")
+	ErrAccountDeleted  = New(http.StatusUnauthorized, "帳號已刪除")
 
 	// 403 Forbidden
 	ErrForbidden        = New(http.StatusForbidden, "禁止存取")
 	ErrPermissionDenied = New(http.StatusForbidden, "權限不足")
+	ErrUserBanned       = New(http.StatusForbidden, "帳號已被停權")
+	ErrMemberMuted      = New(http.StatusForbidden, "您已被禁言，暫時無法發言")
+	ErrRoomReadOnly     = New(http.StatusForbidden, "此聊天室僅限擁有者與管理員發言")
+	ErrRoomArchived     = New(http.StatusForbidden, "聊天室已封存，無法發送新訊息")
 
 	// 404 Not Found
-	ErrNotFound     = New(http.StatusNotFound, "資源不存在")
-	ErrUserNotFound = New(http.StatusNotFound, "用戶不存在")
-	ErrRoomNotFound = New(http.StatusNotFound, "聊天室不存在")
+	ErrNotFound            = New(http.StatusNotFound, "資源不存在")
+	ErrUserNotFound        = New(http.StatusNotFound, "用戶不存在")
+	ErrRoomNotFound        = New(http.StatusNotFound, "聊天室不存在")
+	ErrBanNotFound         = New(http.StatusNotFound, "該用戶目前未被停權")
+	ErrLegalHoldNotFound   = New(http.StatusNotFound, "法律保全不存在或已解除")
+	ErrDeviceNotFound      = New(http.StatusNotFound, "裝置不存在或已撤銷")
+	ErrInstanceNotFound    = New(http.StatusNotFound, "實例不存在或已離線")
+	ErrJoinRequestNotFound = New(http.StatusNotFound, "加入申請不存在或已處理")
+	ErrIPBanNotFound       = New(http.StatusNotFound, "IP 封鎖不存在或已解除")
 
 	// 409 Conflict
 	ErrConflict           = New(http.StatusConflict, "資源衝突")
@@ -77,20 +89,54 @@ var (
 	ErrAlreadyFriend      = New(http.StatusConflict, "已經是好友")
 	ErrAlreadyBlocked     = New(http.StatusConflict, "已經封鎖該用戶")
 	ErrFriendRequestSent  = New(http.StatusConflict, "已發送好友請求")
+	ErrRoomDeletionPending     = New(http.StatusConflict, "聊天室已排定刪除")
+	ErrRoomDeletionNotScheduled = New(http.StatusConflict, "聊天室未排定刪除")
+	ErrRoomAlreadyArchived      = New(http.StatusConflict, "聊天室已封存")
+	ErrRoomNotArchived          = New(http.StatusConflict, "聊天室未封存")
+	ErrLegalHoldExists          = New(http.StatusConflict, "該對象已在法律保全中")
+	ErrJoinRequestExists        = New(http.StatusConflict, "已送出加入申請，請等待審核")
+	ErrAccountOnLegalHold       = New(http.StatusConflict, "帳號受法律保全，暫時無法刪除")
+	ErrAccountOwnsRooms         = New(http.StatusConflict, "請先刪除您擁有的聊天室再刪除帳號")
 
 	// 422 Unprocessable Entity
 	ErrRoomFull         = New(http.StatusUnprocessableEntity, "聊天室已滿")
 	ErrCannotBlockSelf  = New(http.StatusUnprocessableEntity, "無法封鎖自己")
 	ErrCannotMessageSelf = New(http.StatusUnprocessableEntity, "無法給自己發送訊息")
 	ErrUserBlocked      = New(http.StatusUnprocessableEntity, "您已被該用戶封鎖")
+	ErrCannotBanSelf    = New(http.StatusUnprocessableEntity, "無法停權自己")
+	ErrCannotBanOwnIP   = New(http.StatusUnprocessableEntity, "無法封鎖您目前使用的 IP")
+	ErrCannotRevokeOwnAdmin = New(http.StatusUnprocessableEntity, "無法移除自己的管理員權限")
 
 	// 429 Too Many Requests
 	ErrTooManyRequests = New(http.StatusTooManyRequests, "請求過於頻繁，請稍後再試")
 
+	// 499 Client Closed Request
+	ErrRequestCanceled = New(StatusClientClosedRequest, "請求已取消")
+
 	// 500 Internal Server Error
 	ErrInternal = New(http.StatusInternalServerError, "伺服器內部錯誤")
+
+	// 504 Gateway Timeout
+	ErrRequestTimeout = New(http.StatusGatewayTimeout, "請求逾時")
 )
 
+// StatusClientClosedRequest is the non-standard status used when the client
+// goes away before the server finishes handling the request
+const StatusClientClosedRequest = 499
+
+// FromContext maps a context error to an AppError, or nil if err is not
+// caused by cancellation or an expired deadline
+func FromContext(err error) *AppError {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrRequestCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrRequestTimeout
+	default:
+		return nil
+	}
+}
+
 // Is checks if an error is of a specific type
 func Is(err, target error) bool {
 	return errors.Is(err, target)
@@ -0,0 +1,196 @@
+// Package feed renders syndication feeds in RSS 2.0 and Atom 1.0.
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format is a syndication format
+type Format string
+
+const (
+	FormatRSS  Format = "rss"
+	FormatAtom Format = "atom"
+)
+
+// ParseFormat parses a format name; empty means RSS
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatRSS:
+		return FormatRSS, nil
+	case FormatAtom:
+		return FormatAtom, nil
+	default:
+		return "", fmt.Errorf("unknown feed format %q", name)
+	}
+}
+
+// ContentType returns the media type served for the format
+func (f Format) ContentType() string {
+	if f == FormatAtom {
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+// Feed is a format-independent feed
+type Feed struct {
+	ID          string // stable identifier, used as the Atom id
+	Title       string
+	Description string
+	Link        string
+	Updated     time.Time
+	Entries     []*Entry
+}
+
+// Entry is a single feed item
+type Entry struct {
+	ID        string
+	Title     string
+	Content   string
+	Author    string
+	Link      string
+	Published time.Time
+}
+
+// Write renders the feed in the given format
+func Write(w io.Writer, format Format, f *Feed) error {
+	var doc interface{}
+	if format == FormatAtom {
+		doc = newAtom(f)
+	} else {
+		doc = newRSS(f)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode %s feed: %w", format, err)
+	}
+	return enc.Flush()
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"` // dc:creator carries the author name
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description"`
+	Author      string  `xml:"dc:creator,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+func newRSS(f *Feed) *rss {
+	doc := &rss{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Description,
+		},
+	}
+	if !f.Updated.IsZero() {
+		doc.Channel.LastBuildDate = f.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, e := range f.Entries {
+		doc.Channel.Items = append(doc.Channel.Items, &rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			Description: e.Content,
+			Author:      e.Author,
+			GUID:        rssGUID{Value: e.ID},
+			PubDate:     e.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return doc
+}
+
+type atom struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Links    []atomLink   `xml:"link"`
+	Entries  []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+	Links     []atomLink  `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+func newAtom(f *Feed) *atom {
+	doc := &atom{
+		ID:       f.ID,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+	}
+	if f.Link != "" {
+		doc.Links = []atomLink{{Href: f.Link, Rel: "alternate"}}
+	}
+	for _, e := range f.Entries {
+		published := e.Published.UTC().Format(time.RFC3339)
+		entry := &atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Updated:   published,
+			Published: published,
+			Content:   atomContent{Type: "text", Value: e.Content},
+		}
+		if e.Author != "" {
+			entry.Author = &atomAuthor{Name: e.Author}
+		}
+		if e.Link != "" {
+			entry.Links = []atomLink{{Href: e.Link, Rel: "alternate"}}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
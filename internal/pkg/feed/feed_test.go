@@ -0,0 +1,101 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &Feed{
+		ID:          "urn:chat:room:1",
+		Title:       "Announcements",
+		Description: "News & updates",
+		Link:        "https://chat.example.com/rooms/1",
+		Updated:     published,
+		Entries: []*Entry{{
+			ID:        "urn:chat:message:1",
+			Title:     "Release <v2>",
+			Content:   "Release <v2> is out",
+			Author:    "alice",
+			Link:      "https://chat.example.com/rooms/1?message=1",
+			Published: published,
+		}},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"": FormatRSS, "rss": FormatRSS, "atom": FormatAtom} {
+		got, err := ParseFormat(name)
+		if err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("json"); err == nil {
+		t.Error("Expected unknown format to be rejected")
+	}
+}
+
+func TestWrite_RSS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatRSS, testFeed()); err != nil {
+		t.Fatalf("Failed to write feed: %v", err)
+	}
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title   string `xml:"title"`
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Feed is not valid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Version != "2.0" || doc.Channel.Title != "Announcements" {
+		t.Errorf("Unexpected channel: %+v", doc)
+	}
+	if len(doc.Channel.Items) != 1 || doc.Channel.Items[0].Title != "Release <v2>" {
+		t.Fatalf("Unexpected items: %+v", doc.Channel.Items)
+	}
+	if doc.Channel.Items[0].PubDate != "Sun, 01 Mar 2026 12:00:00 +0000" {
+		t.Errorf("Unexpected pubDate %q", doc.Channel.Items[0].PubDate)
+	}
+	if !strings.Contains(buf.String(), "<dc:creator>alice</dc:creator>") {
+		t.Errorf("Expected dc:creator author, got\n%s", buf.String())
+	}
+}
+
+func TestWrite_Atom(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatAtom, testFeed()); err != nil {
+		t.Fatalf("Failed to write feed: %v", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Author  string `xml:"author>name"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Feed is not valid Atom: %v\n%s", err, buf.String())
+	}
+	if doc.ID != "urn:chat:room:1" || doc.Updated != "2026-03-01T12:00:00Z" {
+		t.Errorf("Unexpected feed: %+v", doc)
+	}
+	if len(doc.Entries) != 1 || doc.Entries[0].Author != "alice" || doc.Entries[0].Content != "Release <v2> is out" {
+		t.Errorf("Unexpected entries: %+v", doc.Entries)
+	}
+}
@@ -0,0 +1,137 @@
+// Package ical renders calendars in the iCalendar format (RFC 5545).
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type served for calendars
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLineOctets is the longest content line before it must be folded
+const maxLineOctets = 75
+
+// Calendar is a named list of events
+type Calendar struct {
+	ProdID      string // identifies the product that created the calendar
+	Name        string
+	Description string
+	Events      []*Event
+}
+
+// Event is a single calendar event
+type Event struct {
+	UID         string // globally unique and stable across exports
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time // zero when the event has no end time
+	Created     time.Time
+	Updated     time.Time
+}
+
+// Write renders the calendar with CRLF line endings, folding long lines
+func Write(w io.Writer, c *Calendar) error {
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+
+	lw.line("BEGIN", "VCALENDAR")
+	lw.line("VERSION", "2.0")
+	lw.line("PRODID", c.ProdID)
+	lw.line("CALSCALE", "GREGORIAN")
+	lw.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.Description != "" {
+		lw.line("X-WR-CALDESC", escape(c.Description))
+	}
+
+	for _, e := range c.Events {
+		lw.line("BEGIN", "VEVENT")
+		lw.line("UID", e.UID)
+		lw.line("DTSTAMP", formatTime(e.Updated))
+		lw.line("CREATED", formatTime(e.Created))
+		lw.line("LAST-MODIFIED", formatTime(e.Updated))
+		lw.line("DTSTART", formatTime(e.Start))
+		if !e.End.IsZero() {
+			lw.line("DTEND", formatTime(e.End))
+		}
+		lw.line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			lw.line("LOCATION", escape(e.Location))
+		}
+		if e.URL != "" {
+			lw.line("URL", e.URL)
+		}
+		lw.line("END", "VEVENT")
+	}
+
+	lw.line("END", "VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return bw.Flush()
+}
+
+// lineWriter writes content lines and keeps the first error
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(name, value string) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = lw.w.WriteString(fold(name + ":" + value))
+}
+
+// fold splits a content line into lines of at most maxLineOctets octets,
+// each continuation starting with a space, without splitting a character
+func fold(line string) string {
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the continuation line
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// escape escapes a TEXT value
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// formatTime formats a UTC date-time value
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
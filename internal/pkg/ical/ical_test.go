@@ -0,0 +1,72 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	cal := &Calendar{
+		ProdID: "-//Go Chat//Room Events//EN",
+		Name:   "Announcements",
+		Events: []*Event{{
+			UID:         "1@chat.example.com",
+			Summary:     "Release party; v2, finally",
+			Description: "Line one\nLine two",
+			Start:       start,
+			End:         start.Add(2 * time.Hour),
+			Created:     start,
+			Updated:     start,
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, cal); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Announcements\r\n",
+		"DTSTART:20260301T040000Z\r\n",
+		"DTEND:20260301T060000Z\r\n",
+		`SUMMARY:Release party\; v2\, finally` + "\r\n",
+		`DESCRIPTION:Line one\nLine two` + "\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "LOCATION") {
+		t.Error("Expected empty properties to be left out")
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Error("Expected every line to end with CRLF")
+	}
+}
+
+func TestFold(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("活動", 40)
+	folded := fold(line)
+
+	for _, l := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(l) > maxLineOctets {
+			t.Errorf("Line has %d octets: %q", len(l), l)
+		}
+		if !strings.HasPrefix(l, "DESCRIPTION") && !strings.HasPrefix(l, " ") {
+			t.Errorf("Expected continuation line to start with a space: %q", l)
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != line+"\r\n" {
+		t.Errorf("Unfolding did not restore the line: %q", unfolded)
+	}
+
+	if short := fold("SUMMARY:hi"); short != "SUMMARY:hi\r\n" {
+		t.Errorf("Expected short line unchanged, got %q", short)
+	}
+}
@@ -0,0 +1,65 @@
+package idgen
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Strategy selects how new message IDs are generated
+type Strategy string
+
+const (
+	// StrategyUUID leaves IDs to the database, which assigns random UUIDv4s
+	StrategyUUID Strategy = "uuid"
+	// StrategyUUIDv7 generates time-ordered UUIDv7s, so sorting by ID sorts
+	// by creation time
+	StrategyUUIDv7 Strategy = "uuidv7"
+)
+
+// ParseStrategy validates an ID strategy name from configuration
+func ParseStrategy(name string) (Strategy, error) {
+	switch s := Strategy(strings.ToLower(strings.TrimSpace(name))); s {
+	case "", StrategyUUID:
+		return StrategyUUID, nil
+	case StrategyUUIDv7:
+		return s, nil
+	case "snowflake":
+		// IDs are UUID columns referenced by replies, attachments and
+		// forwards; UUIDv7 gives the same ordering without a schema change
+		return "", fmt.Errorf("snowflake IDs need integer keys; use uuidv7 for time-ordered IDs")
+	default:
+		return "", fmt.Errorf("unknown ID strategy %q (supported: uuid, uuidv7)", name)
+	}
+}
+
+// NewID returns a new ID and the creation time it encodes. It returns ""
+// when the database assigns IDs.
+func (s Strategy) NewID() (string, time.Time, error) {
+	if s != StrategyUUIDv7 {
+		return "", time.Time{}, nil
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate UUIDv7: %w", err)
+	}
+	t, _ := Time(id.String())
+	return id.String(), t, nil
+}
+
+// Time returns the creation time embedded in a UUIDv7, to the millisecond.
+// Other IDs report false, so callers fall back to looking the time up.
+func Time(id string) (time.Time, bool) {
+	u, err := uuid.Parse(id)
+	if err != nil || u.Version() != 7 {
+		return time.Time{}, false
+	}
+
+	var ms [8]byte
+	copy(ms[2:], u[:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(ms[:]))), true
+}
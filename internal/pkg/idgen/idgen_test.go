@@ -0,0 +1,63 @@
+package idgen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Strategy
+		wantErr  bool
+	}{
+		{"", StrategyUUID, false},
+		{"UUID", StrategyUUID, false},
+		{" uuidv7 ", StrategyUUIDv7, false},
+		{"snowflake", "", true},
+		{"ulid", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStrategy(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStrategy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseStrategy(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestNewID(t *testing.T) {
+	if id, _, err := StrategyUUID.NewID(); err != nil || id != "" {
+		t.Errorf("Expected the database to assign UUIDs, got %q, %v", id, err)
+	}
+
+	before := time.Now().Truncate(time.Millisecond)
+	first, createdAt, err := StrategyUUIDv7.NewID()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: %v", err)
+	}
+	if createdAt.Before(before) || createdAt.After(time.Now()) {
+		t.Errorf("Expected the current time, got %v", createdAt)
+	}
+	if got, ok := Time(first); !ok || !got.Equal(createdAt) {
+		t.Errorf("Time(%q) = %v, %v, expected %v", first, got, ok, createdAt)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	second, _, _ := StrategyUUIDv7.NewID()
+	if second <= first {
+		t.Errorf("Expected later IDs to sort after earlier ones: %q <= %q", second, first)
+	}
+}
+
+func TestTime_NotUUIDv7(t *testing.T) {
+	for _, id := range []string{"550e8400-e29b-41d4-a716-446655440000", "not-a-uuid", ""} {
+		if _, ok := Time(id); ok {
+			t.Errorf("Time(%q): expected no embedded time", id)
+		}
+	}
+}
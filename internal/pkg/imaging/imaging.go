@@ -0,0 +1,149 @@
+// Package imaging decodes uploaded images, crops them and scales them down
+// to fixed sizes using only the standard library codecs: JPEG, PNG and GIF
+// (first frame).
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// DefaultMaxPixels caps the width times height of images decoded, so a small
+// file cannot expand into gigabytes of pixels
+const DefaultMaxPixels = 40_000_000
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("image dimensions too large")
+	ErrCropOutOfBounds   = errors.New("crop outside the image")
+)
+
+// Decode reads a JPEG, PNG or GIF image and returns it with its format name.
+// Images with more than maxPixels pixels are refused before decoding.
+func Decode(data []byte, maxPixels int) (image.Image, string, error) {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, "", ErrUnsupportedFormat
+		}
+		return nil, "", err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// CenterSquare returns the largest square centered in the image
+func CenterSquare(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	size := b.Dx()
+	if b.Dy() < size {
+		size = b.Dy()
+	}
+	x := b.Min.X + (b.Dx()-size)/2
+	y := b.Min.Y + (b.Dy()-size)/2
+	return image.Rect(x, y, x+size, y+size)
+}
+
+// Square returns the square of the given side whose top-left corner is at
+// x, y, measured from the image's top-left corner. It must lie within the
+// image.
+func Square(img image.Image, x, y, size int) (image.Rectangle, error) {
+	b := img.Bounds()
+	r := image.Rect(b.Min.X+x, b.Min.Y+y, b.Min.X+x+size, b.Min.Y+y+size)
+	if x < 0 || y < 0 || size <= 0 || !r.In(b) {
+		return image.Rectangle{}, ErrCropOutOfBounds
+	}
+	return r, nil
+}
+
+// Resize scales the area r of img to a width by height image. Each target
+// pixel averages the source pixels it covers, weighted by overlap, which
+// keeps downscaled images smooth.
+func Resize(img image.Image, r image.Rectangle, width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scaleX := float64(r.Dx()) / float64(width)
+	scaleY := float64(r.Dy()) / float64(height)
+
+	for dy := 0; dy < height; dy++ {
+		y0 := float64(r.Min.Y) + float64(dy)*scaleY
+		y1 := y0 + scaleY
+		for dx := 0; dx < width; dx++ {
+			x0 := float64(r.Min.X) + float64(dx)*scaleX
+			x1 := x0 + scaleX
+			dst.SetNRGBA(dx, dy, average(img, x0, y0, x1, y1))
+		}
+	}
+	return dst
+}
+
+// average blends the source pixels overlapping [x0, x1) x [y0, y1) in
+// premultiplied alpha, so transparent pixels do not darken the edges
+func average(img image.Image, x0, y0, x1, y1 float64) color.NRGBA {
+	var r, g, b, a, total float64
+	for y := int(y0); float64(y) < y1; y++ {
+		wy := overlap(float64(y), y0, y1)
+		for x := int(x0); float64(x) < x1; x++ {
+			w := wy * overlap(float64(x), x0, x1)
+			if w <= 0 {
+				continue
+			}
+			pr, pg, pb, pa := img.At(x, y).RGBA()
+			r += float64(pr) * w
+			g += float64(pg) * w
+			b += float64(pb) * w
+			a += float64(pa) * w
+			total += w
+		}
+	}
+	if total == 0 || a == 0 {
+		return color.NRGBA{}
+	}
+	return color.NRGBA{
+		R: uint8(r/a*0xff + 0.5),
+		G: uint8(g/a*0xff + 0.5),
+		B: uint8(b/a*0xff + 0.5),
+		A: uint8(a/total/0x101 + 0.5),
+	}
+}
+
+// overlap returns how much of the pixel starting at p lies within [lo, hi)
+func overlap(p, lo, hi float64) float64 {
+	start, end := p, p+1
+	if lo > start {
+		start = lo
+	}
+	if hi < end {
+		end = hi
+	}
+	if end < start {
+		return 0
+	}
+	return end - start
+}
+
+// Encode writes img in the format Decode reported, JPEG images as JPEG and
+// everything else as PNG, and returns the file extension and content type
+// of what was written
+func Encode(w io.Writer, img image.Image, format string) (string, string, error) {
+	if format == "jpeg" {
+		return ".jpg", "image/jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+	}
+	return ".png", "image/png", png.Encode(w, img)
+}
@@ -0,0 +1,102 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// halves returns a width by height image, red on the left half and blue on
+// the right
+func halves(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	data := encodePNG(t, halves(40, 20))
+
+	img, format, err := Decode(data, 0)
+	if err != nil || format != "png" || img.Bounds().Dx() != 40 {
+		t.Fatalf("Expected a 40px wide png, got %v, %q, %v", img, format, err)
+	}
+	if _, _, err := Decode(data, 799); err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+	if _, _, err := Decode([]byte("RIFF....WEBPVP8 "), 0); err != ErrUnsupportedFormat {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestSquare(t *testing.T) {
+	img := halves(40, 20)
+
+	if r := CenterSquare(img); r != image.Rect(10, 0, 30, 20) {
+		t.Errorf("Expected the centered square, got %v", r)
+	}
+	if r, err := Square(img, 20, 0, 20); err != nil || r != image.Rect(20, 0, 40, 20) {
+		t.Errorf("Expected the right square, got %v, %v", r, err)
+	}
+	for _, c := range [][3]int{{-1, 0, 10}, {25, 0, 20}, {0, 0, 0}} {
+		if _, err := Square(img, c[0], c[1], c[2]); err != ErrCropOutOfBounds {
+			t.Errorf("Expected ErrCropOutOfBounds for %v, got %v", c, err)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	img := halves(40, 20)
+
+	small := Resize(img, image.Rect(0, 0, 40, 20), 4, 2)
+	if got := small.NRGBAAt(0, 0); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Expected red on the left, got %v", got)
+	}
+	if got := small.NRGBAAt(3, 1); got != (color.NRGBA{B: 255, A: 255}) {
+		t.Errorf("Expected blue on the right, got %v", got)
+	}
+
+	// A pixel straddling both halves blends them
+	mixed := Resize(img, image.Rect(10, 0, 30, 20), 1, 1).NRGBAAt(0, 0)
+	if mixed.R < 120 || mixed.R > 135 || mixed.B < 120 || mixed.B > 135 || mixed.A != 255 {
+		t.Errorf("Expected an even blend, got %v", mixed)
+	}
+
+	// Transparent pixels do not darken their neighbours
+	clear := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	clear.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	if got := Resize(clear, clear.Bounds(), 1, 1).NRGBAAt(0, 0); got.R != 255 || got.A < 127 || got.A > 128 {
+		t.Errorf("Expected half-transparent red, got %v", got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	img := halves(4, 4)
+	var buf bytes.Buffer
+
+	if ext, contentType, err := Encode(&buf, img, "jpeg"); err != nil || ext != ".jpg" || contentType != "image/jpeg" {
+		t.Errorf("Expected jpeg, got %q %q %v", ext, contentType, err)
+	}
+	if ext, contentType, err := Encode(&buf, img, "gif"); err != nil || ext != ".png" || contentType != "image/png" {
+		t.Errorf("Expected gif re-encoded as png, got %q %q %v", ext, contentType, err)
+	}
+}
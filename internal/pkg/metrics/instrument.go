@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// InstrumentDB exposes the connection pool statistics of db
+func InstrumentDB(r *Registry, db *sql.DB) {
+	r.NewGaugeFunc("db_open_connections", "Open database connections, in use and idle", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	r.NewGaugeFunc("db_in_use_connections", "Database connections currently in use", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	r.NewGaugeFunc("db_idle_connections", "Idle database connections", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	r.NewGaugeFunc("db_max_open_connections", "Maximum number of open database connections", func() float64 {
+		return float64(db.Stats().MaxOpenConnections)
+	})
+	r.NewCounterFunc("db_wait_count_total", "Connections waited for because the pool was exhausted", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	r.NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for a free connection", func() float64 {
+		return db.Stats().WaitDuration.Seconds()
+	})
+}
+
+// InstrumentRedis counts and times every command sent through client
+func InstrumentRedis(r *Registry, client *redis.Client) {
+	client.AddHook(&redisHook{
+		commands: r.NewCounterVec("redis_commands_total", "Redis commands by command and result", "command", "status"),
+		duration: r.NewHistogramVec("redis_command_duration_seconds", "Redis command latency", DefBuckets, "command"),
+	})
+}
+
+type redisHook struct {
+	commands *CounterVec
+	duration *HistogramVec
+}
+
+func (h *redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.observe(cmd.Name(), start, err)
+		return err
+	}
+}
+
+func (h *redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.observe("pipeline", start, err)
+		return err
+	}
+}
+
+func (h *redisHook) observe(command string, start time.Time, err error) {
+	status := "ok"
+	// A missing key is an answer, not a failure
+	if err != nil && !errors.Is(err, redis.Nil) {
+		status = "error"
+	}
+	h.commands.WithLabelValues(command, status).Inc()
+	h.duration.WithLabelValues(command).ObserveSince(start)
+}
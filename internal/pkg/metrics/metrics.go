@@ -0,0 +1,357 @@
+// Package metrics collects counters, gauges and histograms and exposes them
+// in the Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefBuckets are latency buckets in seconds, from 5ms to 10s
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ContentType is the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type metric interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and renders them for scraping
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register adds a metric. Names are fixed at startup, so a duplicate is a
+// programming error.
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[m.name()] {
+		panic(fmt.Sprintf("metrics: %s registered twice", m.name()))
+	}
+	r.names[m.name()] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo renders every metric in the Prometheus text format, sorted by name
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mu.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler serves the registry to Prometheus scrapers
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// desc is the name, help text and label names shared by a metric's series
+type desc struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+}
+
+func (d *desc) name() string {
+	return d.metricName
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, d.kind)
+}
+
+// seriesKey joins label values into a map key
+func (d *desc) seriesKey(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.metricName, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// Counter is a value that only goes up
+type Counter struct {
+	values []string
+	bits   atomic.Uint64
+}
+
+// Inc adds one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds a non-negative amount
+func (c *Counter) Add(v float64) {
+	for {
+		old := c.bits.Load()
+		if c.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (c *Counter) value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*Counter
+}
+
+// NewCounterVec registers a counter with the given label names
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{
+		desc:   desc{metricName: name, help: help, kind: "counter", labels: labels},
+		series: make(map[string]*Counter),
+	}
+	r.register(v)
+	return v
+}
+
+// WithLabelValues returns the counter for the label values, in the order the
+// labels were declared
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := v.seriesKey(values)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.series[key]
+	if !ok {
+		c = &Counter{values: append([]string(nil), values...)}
+		v.series[key] = c
+	}
+	return c
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+
+	v.mu.Lock()
+	keys := sortedKeys(v.series)
+	for _, key := range keys {
+		c := v.series[key]
+		writeSample(w, v.metricName, v.labels, c.values, "", "", c.value())
+	}
+	v.mu.Unlock()
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	values  []string
+	upper   []float64
+	mu      sync.Mutex
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// Observe records one value
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.upper {
+		if v <= upper {
+			h.buckets[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// ObserveSince records the seconds elapsed since start
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*Histogram
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds
+// and label names
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	v := &HistogramVec{
+		desc:    desc{metricName: name, help: help, kind: "histogram", labels: labels},
+		buckets: sorted,
+		series:  make(map[string]*Histogram),
+	}
+	r.register(v)
+	return v
+}
+
+// NewHistogram registers a histogram without labels
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.NewHistogramVec(name, help, buckets).WithLabelValues()
+}
+
+// WithLabelValues returns the histogram for the label values, in the order
+// the labels were declared
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := v.seriesKey(values)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	h, ok := v.series[key]
+	if !ok {
+		h = &Histogram{
+			values:  append([]string(nil), values...),
+			upper:   v.buckets,
+			buckets: make([]uint64, len(v.buckets)),
+		}
+		v.series[key] = h
+	}
+	return h
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+
+	v.mu.Lock()
+	keys := sortedKeys(v.series)
+	for _, key := range keys {
+		h := v.series[key]
+		h.mu.Lock()
+		for i, upper := range h.upper {
+			writeSample(w, v.metricName+"_bucket", v.labels, h.values, "le", formatFloat(upper), float64(h.buckets[i]))
+		}
+		writeSample(w, v.metricName+"_bucket", v.labels, h.values, "le", "+Inf", float64(h.count))
+		writeSample(w, v.metricName+"_sum", v.labels, h.values, "", "", h.sum)
+		writeSample(w, v.metricName+"_count", v.labels, h.values, "", "", float64(h.count))
+		h.mu.Unlock()
+	}
+	v.mu.Unlock()
+}
+
+// funcMetric reads its single value when scraped
+type funcMetric struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{desc: desc{metricName: name, help: help, kind: "gauge"}, fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is read from fn on every
+// scrape, for totals another component already keeps
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{desc: desc{metricName: name, help: help, kind: "counter"}, fn: fn})
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	m.writeHeader(w)
+	writeSample(w, m.metricName, nil, nil, "", "", m.fn())
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabel(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("Failed to render metrics: %v", err)
+	}
+	return b.String()
+}
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("requests_total", "Requests", "route")
+	latency := r.NewHistogram("latency_seconds", "Latency", []float64{0.5, 0.1})
+	r.NewGaugeFunc("queue_depth", "Queued items", func() float64 { return 3 })
+
+	requests.WithLabelValues("/rooms/:id").Inc()
+	requests.WithLabelValues("/rooms/:id").Add(2)
+	requests.WithLabelValues(`say "hi"`).Inc()
+	latency.Observe(0.05)
+	latency.Observe(0.3)
+
+	expected := `# HELP latency_seconds Latency
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="0.5"} 2
+latency_seconds_bucket{le="+Inf"} 2
+latency_seconds_sum 0.35
+latency_seconds_count 2
+# HELP queue_depth Queued items
+# TYPE queue_depth gauge
+queue_depth 3
+# HELP requests_total Requests
+# TYPE requests_total counter
+requests_total{route="/rooms/:id"} 3
+requests_total{route="say \"hi\""} 1
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("up", "Up", func() float64 { return 1 })
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	r.NewCounterVec("up", "Up")
+}
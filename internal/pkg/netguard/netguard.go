@@ -0,0 +1,32 @@
+// Package netguard keeps outgoing requests made on behalf of users, such
+// as link previews and webhooks, away from the server's own network.
+package netguard
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned when a connection would reach a loopback,
+// private or link-local address
+var ErrPrivateAddress = errors.New("address is loopback, private or link-local")
+
+// IsPrivate reports whether ip is one a user supplied URL must not reach
+func IsPrivate(ip net.IP) bool {
+	return ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+}
+
+// RejectPrivate is a net.Dialer Control function. It runs after DNS
+// resolution, so hostnames pointing at internal services are caught too.
+func RejectPrivate(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if IsPrivate(net.ParseIP(host)) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
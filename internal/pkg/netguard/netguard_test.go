@@ -0,0 +1,33 @@
+package netguard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRejectPrivate(t *testing.T) {
+	tests := []struct {
+		address string
+		private bool
+	}{
+		{"127.0.0.1:80", true},
+		{"[::1]:443", true},
+		{"10.1.2.3:80", true},
+		{"192.168.0.10:8080", true},
+		{"169.254.169.254:80", true},
+		{"0.0.0.0:80", true},
+		{"[fe80::1]:80", true},
+		{"93.184.216.34:443", false},
+		{"[2606:4700::1111]:443", false},
+	}
+
+	for _, tt := range tests {
+		err := RejectPrivate("tcp", tt.address, nil)
+		if tt.private && !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("RejectPrivate(%q) = %v, want ErrPrivateAddress", tt.address, err)
+		}
+		if !tt.private && err != nil {
+			t.Errorf("RejectPrivate(%q) = %v, want nil", tt.address, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+const cursorPrefix = "o:"
+
+// FetchLimit returns the number of rows to request so a following page can be detected.
+// Repositories are queried for one extra row beyond the page size.
+func FetchLimit(limit int) int {
+	return limit + 1
+}
+
+// Trim cuts items fetched with FetchLimit back to the page size and
+// reports whether more items are available
+func Trim[T any](items []T, limit int) ([]T, bool) {
+	if limit >= 0 && len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
+
+// TrimFront is Trim for pages fetched newest first and returned oldest
+// first, where the extra row is the first item
+func TrimFront[T any](items []T, limit int) ([]T, bool) {
+	if limit >= 0 && len(items) > limit {
+		return items[len(items)-limit:], true
+	}
+	return items, false
+}
+
+// EncodeCursor returns an opaque cursor pointing at the given offset
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeCursor returns the offset encoded in a cursor
+func DecodeCursor(cursor string) (int, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+
+	value, ok := strings.CutPrefix(string(data), cursorPrefix)
+	if !ok {
+		return 0, false
+	}
+
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+	return offset, true
+}
@@ -0,0 +1,39 @@
+package pagination
+
+import "testing"
+
+func TestTrim(t *testing.T) {
+	items := []int{1, 2, 3}
+
+	page, hasMore := Trim(items, 2)
+	if len(page) != 2 || !hasMore {
+		t.Errorf("Expected 2 items with more, got %v (%v)", page, hasMore)
+	}
+
+	page, hasMore = Trim(items, 3)
+	if len(page) != 3 || hasMore {
+		t.Errorf("Expected 3 items without more, got %v (%v)", page, hasMore)
+	}
+}
+
+func TestTrimFront(t *testing.T) {
+	page, hasMore := TrimFront([]int{1, 2, 3}, 2)
+	if len(page) != 2 || page[0] != 2 || !hasMore {
+		t.Errorf("Expected the last 2 items with more, got %v (%v)", page, hasMore)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	offset, ok := DecodeCursor(EncodeCursor(40))
+	if !ok || offset != 40 {
+		t.Errorf("Expected offset 40, got %d (%v)", offset, ok)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"", "not base64!", EncodeCursor(-1), "eDox"} {
+		if _, ok := DecodeCursor(cursor); ok {
+			t.Errorf("Expected cursor %q to be rejected", cursor)
+		}
+	}
+}
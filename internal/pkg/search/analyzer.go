@@ -0,0 +1,76 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Analyzer selects how message content is matched against a search query
+type Analyzer string
+
+const (
+	// AnalyzerILike matches substrings with ILIKE; works for any language, backed by a trigram index
+	AnalyzerILike Analyzer = "ilike"
+	// AnalyzerSimple uses the built-in simple text search configuration (no stemming)
+	AnalyzerSimple Analyzer = "simple"
+	// AnalyzerEnglish uses the built-in english configuration with stemming and stop words
+	AnalyzerEnglish Analyzer = "english"
+	// AnalyzerZhparser segments Chinese text with the zhparser extension
+	AnalyzerZhparser Analyzer = "zhparser"
+	// AnalyzerJieba segments Chinese text with the pg_jieba extension
+	AnalyzerJieba Analyzer = "jieba"
+)
+
+// textSearchConfigs maps full-text analyzers to PostgreSQL text search configurations
+var textSearchConfigs = map[Analyzer]string{
+	AnalyzerSimple:   "simple",
+	AnalyzerEnglish:  "english",
+	AnalyzerZhparser: "chinese_zh",
+	AnalyzerJieba:    "jiebacfg",
+}
+
+// ParseAnalyzer validates an analyzer name from configuration
+func ParseAnalyzer(name string) (Analyzer, error) {
+	a := Analyzer(strings.ToLower(strings.TrimSpace(name)))
+	if a == "" || a == AnalyzerILike {
+		return AnalyzerILike, nil
+	}
+	if _, ok := textSearchConfigs[a]; ok {
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown search analyzer %q (supported: ilike, simple, english, zhparser, jieba)", name)
+}
+
+// IsFullText reports whether the analyzer uses PostgreSQL full-text search
+func (a Analyzer) IsFullText() bool {
+	_, ok := textSearchConfigs[a]
+	return ok
+}
+
+// TextSearchConfig returns the PostgreSQL text search configuration, or "" for ILIKE
+func (a Analyzer) TextSearchConfig() string {
+	return textSearchConfigs[a]
+}
+
+// Condition builds the SQL predicate matching a query placeholder.
+// textColumn is used for ILIKE, vectorColumn for full-text analyzers.
+func (a Analyzer) Condition(textColumn, vectorColumn string, placeholder int) string {
+	if cfg := a.TextSearchConfig(); cfg != "" {
+		return fmt.Sprintf("%s @@ plainto_tsquery('%s', $%d)", vectorColumn, cfg, placeholder)
+	}
+	return fmt.Sprintf("%s ILIKE $%d", textColumn, placeholder)
+}
+
+// Arg converts a user query into the argument bound to the Condition placeholder
+func (a Analyzer) Arg(query string) string {
+	if a.IsFullText() {
+		return query
+	}
+	return "%" + escapeLike(query) + "%"
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
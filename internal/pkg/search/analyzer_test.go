@@ -0,0 +1,49 @@
+package search
+
+import "testing"
+
+func TestParseAnalyzer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Analyzer
+		wantErr  bool
+	}{
+		{"", AnalyzerILike, false},
+		{"ILIKE", AnalyzerILike, false},
+		{"english", AnalyzerEnglish, false},
+		{" zhparser ", AnalyzerZhparser, false},
+		{"jieba", AnalyzerJieba, false},
+		{"klingon", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAnalyzer(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAnalyzer(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseAnalyzer(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestAnalyzer_Condition(t *testing.T) {
+	if got := AnalyzerILike.Condition("m.content", "ms.search_vector", 2); got != "m.content ILIKE $2" {
+		t.Errorf("Unexpected ILIKE condition: %s", got)
+	}
+
+	expected := "ms.search_vector @@ plainto_tsquery('chinese_zh', $2)"
+	if got := AnalyzerZhparser.Condition("m.content", "ms.search_vector", 2); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestAnalyzer_Arg(t *testing.T) {
+	if got := AnalyzerILike.Arg("100%_off"); got != `%100\%\_off%` {
+		t.Errorf("Expected escaped pattern, got %s", got)
+	}
+	if got := AnalyzerEnglish.Arg("running fast"); got != "running fast" {
+		t.Errorf("Expected raw query, got %s", got)
+	}
+}
@@ -0,0 +1,264 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultElasticIndex is the index messages are kept in
+	DefaultElasticIndex = "chat-messages"
+
+	defaultElasticTimeout = 5 * time.Second
+
+	// Longest response body quoted in an error
+	maxElasticErrorBody = 500
+)
+
+// ElasticConfig locates an Elasticsearch or OpenSearch cluster
+type ElasticConfig struct {
+	URL      string // e.g. http://localhost:9200
+	Index    string
+	Username string
+	Password string
+	Analyzer string // analyzer for message content, e.g. standard or smartcn
+	Timeout  time.Duration
+}
+
+// MessageDocument is a message as stored in the search index
+type MessageDocument struct {
+	ID        string    `json:"-"`
+	RoomID    string    `json:"room_id"`
+	UserID    string    `json:"user_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ElasticIndex keeps messages in an Elasticsearch or OpenSearch index and
+// searches them with fuzzy matching and relevance ranking. It talks to the
+// REST API, which both engines share for the calls used here.
+type ElasticIndex struct {
+	baseURL  string
+	index    string
+	username string
+	password string
+	analyzer string
+	client   *http.Client
+}
+
+// NewElasticIndex creates an index client; it does not contact the cluster
+func NewElasticIndex(cfg ElasticConfig) (*ElasticIndex, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid search engine URL %q", cfg.URL)
+	}
+	if cfg.Index == "" {
+		cfg.Index = DefaultElasticIndex
+	}
+	if cfg.Analyzer == "" {
+		cfg.Analyzer = "standard"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultElasticTimeout
+	}
+	return &ElasticIndex{
+		baseURL:  strings.TrimRight(cfg.URL, "/"),
+		index:    cfg.Index,
+		username: cfg.Username,
+		password: cfg.Password,
+		analyzer: cfg.Analyzer,
+		client:   &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// EnsureIndex creates the index with its mapping unless it already exists.
+// An existing index keeps its mapping; changing the analyzer needs a new
+// index name.
+func (e *ElasticIndex) EnsureIndex(ctx context.Context) error {
+	status, _, err := e.do(ctx, http.MethodHead, "/"+e.index, "", nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+
+	mapping := map[string]interface{}{
+		"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"room_id":    map[string]string{"type": "keyword"},
+				"user_id":    map[string]string{"type": "keyword"},
+				"content":    map[string]string{"type": "text", "analyzer": e.analyzer},
+				"created_at": map[string]string{"type": "date"},
+			},
+		},
+	}
+	body, _ := json.Marshal(mapping)
+	status, resp, err := e.do(ctx, http.MethodPut, "/"+e.index, "application/json", body)
+	if err != nil {
+		return err
+	}
+	// Another instance may have created it in the meantime
+	if status >= 300 && !bytes.Contains(resp, []byte("resource_already_exists_exception")) {
+		return elasticError("create index", status, resp)
+	}
+	return nil
+}
+
+// Bulk indexes docs and removes the deleted IDs in one request. Removing a
+// document that is not indexed is not an error.
+func (e *ElasticIndex) Bulk(ctx context.Context, docs []*MessageDocument, deleted []string) error {
+	if len(docs) == 0 && len(deleted) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, doc := range docs {
+		_ = enc.Encode(map[string]interface{}{"index": map[string]string{"_id": doc.ID}})
+		if err := enc.Encode(doc); err != nil {
+			return fmt.Errorf("failed to encode document: %w", err)
+		}
+	}
+	for _, id := range deleted {
+		_ = enc.Encode(map[string]interface{}{"delete": map[string]string{"_id": id}})
+	}
+
+	status, resp, err := e.do(ctx, http.MethodPost, "/"+e.index+"/_bulk", "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return elasticError("bulk", status, resp)
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	for _, item := range result.Items {
+		for action, outcome := range item {
+			if outcome.Status < 300 || (action == "delete" && outcome.Status == http.StatusNotFound) {
+				continue
+			}
+			return fmt.Errorf("failed to %s document %s: %s", action, outcome.ID, outcome.Error)
+		}
+	}
+	return nil
+}
+
+// Search returns the IDs of the messages matching q, best match first.
+// Terms match with typo tolerance; filters narrow by room, sender and time.
+func (e *ElasticIndex) Search(ctx context.Context, q *MessageQuery) ([]string, error) {
+	filters := []interface{}{
+		map[string]interface{}{"term": map[string]string{"room_id": q.RoomID}},
+	}
+	if q.SenderID != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]string{"user_id": q.SenderID}})
+	}
+	if !q.After.IsZero() || !q.Before.IsZero() {
+		bounds := map[string]string{}
+		if !q.After.IsZero() {
+			bounds["gte"] = q.After.UTC().Format(time.RFC3339Nano)
+		}
+		if !q.Before.IsZero() {
+			bounds["lt"] = q.Before.UTC().Format(time.RFC3339Nano)
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"created_at": bounds}})
+	}
+
+	request := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"match": map[string]interface{}{
+						"content": map[string]interface{}{
+							"query":     q.Text,
+							"fuzziness": "AUTO",
+							"operator":  "and",
+						},
+					},
+				},
+				"filter": filters,
+			},
+		},
+		"sort":    []interface{}{"_score", map[string]string{"created_at": "desc"}},
+		"from":    q.Offset,
+		"size":    q.Limit,
+		"_source": false,
+	}
+	body, _ := json.Marshal(request)
+
+	status, resp, err := e.do(ctx, http.MethodPost, "/"+e.index+"/_search", "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 300 {
+		return nil, elasticError("search", status, resp)
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+	ids := make([]string, len(result.Hits.Hits))
+	for i, hit := range result.Hits.Hits {
+		ids[i] = hit.ID
+	}
+	return ids, nil
+}
+
+func (e *ElasticIndex) do(ctx context.Context, method, path, contentType string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, e.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if e.username != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("search engine request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read search engine response: %w", err)
+	}
+	return resp.StatusCode, data, nil
+}
+
+func elasticError(op string, status int, body []byte) error {
+	if len(body) > maxElasticErrorBody {
+		body = body[:maxElasticErrorBody]
+	}
+	return fmt.Errorf("search engine %s failed with status %d: %s", op, status, body)
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestElasticIndex_Search(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages/_search" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "elastic" || pass != "secret" {
+			t.Errorf("Expected basic auth, got %q %q", user, pass)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = io.WriteString(w, `{"hits":{"hits":[{"_id":"m2"},{"_id":"m1"}]}}`)
+	}))
+	defer server.Close()
+
+	index, err := NewElasticIndex(ElasticConfig{URL: server.URL + "/", Index: "messages", Username: "elastic", Password: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	ids, err := index.Search(context.Background(), &MessageQuery{
+		RoomID:   "r1",
+		Text:     "helo",
+		SenderID: "u1",
+		After:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Limit:    10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "m2" || ids[1] != "m1" {
+		t.Errorf("Expected hits in ranking order, got %v", ids)
+	}
+
+	body, _ := json.Marshal(got)
+	for _, want := range []string{`"fuzziness":"AUTO"`, `{"term":{"room_id":"r1"}}`, `{"term":{"user_id":"u1"}}`, `"gte":"2024-01-01T00:00:00Z"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected %s in request, got %s", want, body)
+		}
+	}
+	if strings.Contains(string(body), `"lt"`) {
+		t.Errorf("Expected no upper bound, got %s", body)
+	}
+}
+
+func TestElasticIndex_Bulk(t *testing.T) {
+	var lines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+		// Deleting a document that was never indexed is fine
+		_, _ = io.WriteString(w, `{"errors":true,"items":[{"index":{"_id":"m1","status":201}},{"delete":{"_id":"m2","status":404}}]}`)
+	}))
+	defer server.Close()
+
+	index, err := NewElasticIndex(ElasticConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	docs := []*MessageDocument{{ID: "m1", RoomID: "r1", UserID: "u1", Content: "hello"}}
+	if err := index.Bulk(context.Background(), docs, []string{"m2"}); err != nil {
+		t.Fatalf("Failed to bulk index: %v", err)
+	}
+	if len(lines) != 3 || lines[0] != `{"index":{"_id":"m1"}}` || lines[2] != `{"delete":{"_id":"m2"}}` {
+		t.Errorf("Unexpected bulk body %q", lines)
+	}
+}
+
+func TestElasticIndex_BulkItemFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"errors":true,"items":[{"index":{"_id":"m1","status":400,"error":{"type":"mapper_parsing_exception"}}}]}`)
+	}))
+	defer server.Close()
+
+	index, _ := NewElasticIndex(ElasticConfig{URL: server.URL})
+	err := index.Bulk(context.Background(), []*MessageDocument{{ID: "m1"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("Expected the item error, got %v", err)
+	}
+}
+
+func TestNewElasticIndex_InvalidURL(t *testing.T) {
+	for _, raw := range []string{"", "localhost:9200", "ftp://localhost"} {
+		if _, err := NewElasticIndex(ElasticConfig{URL: raw}); err == nil {
+			t.Errorf("Expected error for %q", raw)
+		}
+	}
+}
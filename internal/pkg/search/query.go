@@ -0,0 +1,14 @@
+package search
+
+import "time"
+
+// MessageQuery is a search for messages in one room
+type MessageQuery struct {
+	RoomID   string
+	Text     string
+	SenderID string    // only messages from this user when set
+	After    time.Time // only messages sent at or after this time when set
+	Before   time.Time // only messages sent before this time when set
+	Limit    int
+	Offset   int
+}
@@ -0,0 +1,285 @@
+// Package unfurl fetches web pages and reads their Open Graph metadata, the
+// title, description and image sites publish for link previews.
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-demo/chat/internal/pkg/netguard"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultTimeout     = 5 * time.Second
+	defaultMaxBodySize = 512 << 10
+	defaultUserAgent   = "go-demo-chat-unfurl/1.0"
+
+	// maxRedirects bounds the redirects followed for one page
+	maxRedirects = 5
+
+	// Longest title and description kept, in characters
+	maxTitleLength       = 300
+	maxDescriptionLength = 500
+)
+
+var (
+	ErrNotHTML       = errors.New("page is not HTML")
+	ErrNoMetadata    = errors.New("page has no preview metadata")
+	ErrPrivateTarget = netguard.ErrPrivateAddress
+)
+
+// Config tunes a Fetcher
+type Config struct {
+	Timeout      time.Duration // bounds one page, redirects included
+	MaxBodySize  int64         // bytes of HTML read; metadata lives in the head
+	UserAgent    string
+	AllowPrivate bool // allow loopback, private and link-local addresses
+}
+
+// Preview is what a page says about itself
+type Preview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// Fetcher downloads pages for previews. Unless AllowPrivate is set it cannot
+// reach internal addresses, redirects included, so a link in a message
+// never makes the server call its own network.
+type Fetcher struct {
+	client      *http.Client
+	maxBodySize int64
+	userAgent   string
+}
+
+// NewFetcher creates a fetcher; zero config values use the defaults
+func NewFetcher(cfg Config) *Fetcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultMaxBodySize
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = defaultUserAgent
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivate {
+		dialer.Control = netguard.RejectPrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &Fetcher{
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		maxBodySize: cfg.MaxBodySize,
+		userAgent:   cfg.UserAgent,
+	}
+}
+
+// Fetch downloads an http or https page and returns its preview
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid link %q", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("page returned status %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil ||
+		(mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, ErrNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBodySize), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page: %w", err)
+	}
+	// Relative image paths are relative to where redirects ended up
+	preview := Parse(body, resp.Request.URL)
+	preview.URL = rawURL
+	if preview.Title == "" && preview.Description == "" && preview.ImageURL == "" {
+		return nil, ErrNoMetadata
+	}
+	return preview, nil
+}
+
+// Parse reads the preview of an HTML page located at base. Open Graph tags
+// win over Twitter card tags, which win over <title> and the description
+// meta tag. Reading stops at <body>.
+func Parse(r io.Reader, base *url.URL) *Preview {
+	meta := make(map[string]string)
+	var title string
+
+	z := html.NewTokenizer(r)
+	for done := false; !done; {
+		switch z.Next() {
+		case html.ErrorToken:
+			done = true
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				done = true
+			case "title":
+				if title == "" && z.Next() == html.TextToken {
+					title = string(z.Text())
+				}
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+				var key, content string
+				for more := true; more; {
+					var k, v []byte
+					k, v, more = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(strings.TrimSpace(string(v)))
+					case "content":
+						content = string(v)
+					}
+				}
+				if _, seen := meta[key]; key != "" && !seen {
+					meta[key] = content
+				}
+			}
+		}
+	}
+
+	preview := &Preview{
+		Title:       clean(first(meta["og:title"], meta["twitter:title"], title), maxTitleLength),
+		Description: clean(first(meta["og:description"], meta["twitter:description"], meta["description"]), maxDescriptionLength),
+		SiteName:    clean(meta["og:site_name"], maxTitleLength),
+	}
+	if base != nil {
+		preview.URL = base.String()
+	}
+	image := first(meta["og:image:secure_url"], meta["og:image"], meta["og:image:url"], meta["twitter:image"], meta["twitter:image:src"])
+	if image != "" {
+		preview.ImageURL = resolve(base, image)
+	}
+	return preview
+}
+
+// first returns the first non-blank value
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean collapses whitespace and cuts s to at most limit characters
+func clean(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+// resolve makes ref absolute against base; only http and https images are
+// kept
+func resolve(base *url.URL, ref string) string {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
+
+// linkPattern matches http and https links up to whitespace, quotes, angle
+// brackets or full-width punctuation
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `，。！？、；：「」『』（）【】]+`)
+
+// ExtractURLs returns up to limit distinct links in text, in order.
+// Punctuation trailing a link, as in "see https://example.com.", is left
+// out.
+func ExtractURLs(text string, limit int) []string {
+	var links []string
+	seen := make(map[string]bool)
+	for _, match := range linkPattern.FindAllString(text, -1) {
+		if len(links) >= limit {
+			break
+		}
+		link := trimTrailing(match)
+		u, err := url.Parse(link)
+		if err != nil || u.Host == "" || seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	return links
+}
+
+// trimTrailing drops sentence punctuation after a link, keeping closing
+// parentheses that belong to it
+func trimTrailing(link string) string {
+	for link != "" {
+		last := link[len(link)-1]
+		switch {
+		case strings.IndexByte(".,;:!?*_~", last) >= 0:
+			link = link[:len(link)-1]
+		case last == ')' && strings.Count(link, "(") < strings.Count(link, ")"),
+			last == ']' && strings.Count(link, "[") < strings.Count(link, "]"):
+			link = link[:len(link)-1]
+		default:
+			return link
+		}
+	}
+	return link
+}
@@ -0,0 +1,141 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const ogPage = `<!DOCTYPE html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="  Go 1.22 is released ">
+<meta property="og:description" content="Loop variables, routing patterns and more.">
+<meta property="og:image" content="/images/go.png">
+<meta property="og:site_name" content="The Go Blog">
+</head><body><meta property="og:title" content="ignored"></body></html>`
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://go.dev/blog/go1.22")
+	preview := Parse(strings.NewReader(ogPage), base)
+
+	want := &Preview{
+		URL:         "https://go.dev/blog/go1.22",
+		Title:       "Go 1.22 is released",
+		Description: "Loop variables, routing patterns and more.",
+		ImageURL:    "https://go.dev/images/go.png",
+		SiteName:    "The Go Blog",
+	}
+	if !reflect.DeepEqual(preview, want) {
+		t.Errorf("Expected %+v, got %+v", want, preview)
+	}
+}
+
+func TestParse_Fallbacks(t *testing.T) {
+	page := `<html><head><title>Plain page</title>
+<meta name="description" content="No Open Graph here">
+<meta name="twitter:image" content="javascript:alert(1)">
+</head></html>`
+	preview := Parse(strings.NewReader(page), nil)
+
+	if preview.Title != "Plain page" || preview.Description != "No Open Graph here" {
+		t.Errorf("Expected title and description fallbacks, got %+v", preview)
+	}
+	if preview.ImageURL != "" {
+		t.Errorf("Expected non-http image to be dropped, got %q", preview.ImageURL)
+	}
+}
+
+func TestParse_Truncates(t *testing.T) {
+	page := `<meta property="og:title" content="` + strings.Repeat("長", maxTitleLength+10) + `">`
+	preview := Parse(strings.NewReader(page), nil)
+
+	if n := len([]rune(preview.Title)); n != maxTitleLength {
+		t.Errorf("Expected title cut to %d characters, got %d", maxTitleLength, n)
+	}
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/post", http.StatusMovedPermanently)
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = io.WriteString(w, ogPage)
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/empty":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, "<html><body>hi</body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(Config{AllowPrivate: true})
+
+	preview, err := fetcher.Fetch(context.Background(), server.URL+"/old")
+	if err != nil {
+		t.Fatalf("Failed to fetch: %v", err)
+	}
+	if preview.URL != server.URL+"/old" {
+		t.Errorf("Expected the requested URL, got %q", preview.URL)
+	}
+	if preview.ImageURL != server.URL+"/images/go.png" {
+		t.Errorf("Expected image resolved against the final URL, got %q", preview.ImageURL)
+	}
+
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/image.png"); !errors.Is(err, ErrNotHTML) {
+		t.Errorf("Expected ErrNotHTML, got %v", err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/empty"); !errors.Is(err, ErrNoMetadata) {
+		t.Errorf("Expected ErrNoMetadata, got %v", err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/missing"); err == nil {
+		t.Error("Expected an error for a 404 page")
+	}
+	if _, err := fetcher.Fetch(context.Background(), "ftp://example.com/file"); err == nil {
+		t.Error("Expected an error for a non-http link")
+	}
+}
+
+func TestFetcher_RejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Private address should not be reached")
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher(Config{}).Fetch(context.Background(), server.URL)
+	if !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("Expected ErrPrivateTarget, got %v", err)
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  []string
+	}{
+		{"no links here", 3, nil},
+		{"see https://example.com.", 3, []string{"https://example.com"}},
+		{"看這篇https://example.com/a?b=1，很棒", 3, []string{"https://example.com/a?b=1"}},
+		{"(https://en.wikipedia.org/wiki/Go_(programming_language))", 3, []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"}},
+		{"http://a.com http://b.com http://a.com http://c.com", 2, []string{"http://a.com", "http://b.com"}},
+		{"<https://example.com/x>", 3, []string{"https://example.com/x"}},
+		{"https:// nothing", 3, nil},
+	}
+	for _, tt := range tests {
+		if got := ExtractURLs(tt.text, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExtractURLs(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"time"
+)
+
+// JWK is the public half of a signing key in JSON Web Key form (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys other services verify tokens with: every
+// RSA and Ed25519 key that has not retired, including those about to
+// activate. HMAC secrets are never published, so tokens they sign can only
+// be verified here.
+func (m *JWTManager) JWKS() *JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := &JWKSet{Keys: []JWK{}}
+	now := time.Now()
+	for i, key := range m.keys {
+		if key.PrivateKey == nil || m.retired(i, now) {
+			continue
+		}
+		jwk := JWK{Use: "sig", Alg: key.method().Alg(), Kid: key.ID}
+		switch public := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestJWTManager_JWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	edPublic, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, retiredKey, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+
+	manager := createTestManager()
+	manager.SetStaticKeys([]SigningKey{
+		{ID: "retired", PrivateKey: retiredKey, ActivatesAt: now.Add(-30 * 24 * time.Hour)},
+		{ID: "rsa", PrivateKey: rsaKey, ActivatesAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: "next", PrivateKey: edKey, ActivatesAt: now.Add(time.Hour)},
+	})
+	manager.SetSigningKeys([]SigningKey{{ID: "hmac", Secret: []byte("rotated-secret"), ActivatesAt: now.Add(-time.Hour)}})
+
+	set := manager.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("Expected the rsa and next keys, got %+v", set.Keys)
+	}
+
+	rsaJWK, edJWK := set.Keys[0], set.Keys[1]
+	if rsaJWK.Kid != "rsa" || rsaJWK.Kty != "RSA" || rsaJWK.Alg != "RS256" || rsaJWK.E != "AQAB" || rsaJWK.Use != "sig" {
+		t.Errorf("Unexpected RSA key: %+v", rsaJWK)
+	}
+	if n, _ := base64.RawURLEncoding.DecodeString(rsaJWK.N); len(n) != 256 {
+		t.Errorf("Expected a 2048-bit modulus, got %d bytes", len(n))
+	}
+	if edJWK.Kid != "next" || edJWK.Kty != "OKP" || edJWK.Crv != "Ed25519" || edJWK.Alg != "EdDSA" ||
+		edJWK.X != base64.RawURLEncoding.EncodeToString(edPublic) {
+		t.Errorf("Unexpected Ed25519 key: %+v", edJWK)
+	}
+}
@@ -1,7 +1,13 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,6 +17,7 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrNoSigningKey = errors.New("no jwt signing key is active")
 )
 
 type TokenType string
@@ -25,15 +32,62 @@ type Claims struct {
 	UserID   string    `json:"user_id"`
 	Username string    `json:"username"`
 	Type     TokenType `json:"type"`
+	DeviceID string    `json:"device_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// SigningKey is a rotated JWT secret or key pair. Tokens it signs carry
+// its ID in the kid header.
+type SigningKey struct {
+	ID          string
+	Secret      []byte        // HS256 secret
+	PrivateKey  crypto.Signer // RSA (RS256) or Ed25519 (EdDSA) key, local or in a KMS; used instead of Secret
+	ActivatesAt time.Time     // tokens are signed with it from then on
+}
+
+// method returns the algorithm the key signs with, by its public key so
+// any crypto.Signer works
+func (k SigningKey) method() jwt.SigningMethod {
+	if k.PrivateKey == nil {
+		return jwt.SigningMethodHS256
+	}
+	switch k.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		return signerRS256
+	case ed25519.PublicKey:
+		return signerEdDSA
+	}
+	return nil
+}
+
+// signingKey returns what jwt signs with for the key's method
+func (k SigningKey) signingKey() interface{} {
+	if k.PrivateKey != nil {
+		return k.PrivateKey
+	}
+	return k.Secret
+}
+
+// verificationKey returns what jwt verifies with for the key's method
+func (k SigningKey) verificationKey() interface{} {
+	if k.PrivateKey != nil {
+		return k.PrivateKey.Public()
+	}
+	return k.Secret
+}
+
 // JWTManager handles JWT operations
 type JWTManager struct {
 	secretKey       []byte
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
 	issuer          string
+
+	mu      sync.RWMutex
+	noHMAC  bool
+	static  []SigningKey // from the config file
+	rotated []SigningKey // rotated through the admin API
+	keys    []SigningKey // both, oldest first
 }
 
 // NewJWTManager creates a new JWT manager
@@ -46,43 +100,184 @@ func NewJWTManager(secretKey string, accessTTL, refreshTTL time.Duration, issuer
 	}
 }
 
+// SetSigningKeys replaces the rotated keys. New tokens are signed with the
+// newest key that has activated, or the configured secret before any has.
+// A key that has been superseded, the configured secret included, still
+// verifies tokens for one refresh token TTL, so none it signed is cut short.
+func (m *JWTManager) SetSigningKeys(keys []SigningKey) {
+	m.mu.Lock()
+	m.rotated = keys
+	m.merge()
+	m.mu.Unlock()
+}
+
+// SetStaticKeys sets the keys from the config file. They take turns with
+// the rotated keys by activation time, so rotating through the config file
+// means adding a key that activates later and removing the old one once it
+// has retired.
+func (m *JWTManager) SetStaticKeys(keys []SigningKey) {
+	m.mu.Lock()
+	m.static = keys
+	m.merge()
+	m.mu.Unlock()
+}
+
+// UsesPublicKeys reports whether tokens are meant to be verified with a
+// public key: the config file holds RSA or Ed25519 keys, or HMAC is
+// disabled. A rotated HMAC secret must not take over signing then.
+func (m *JWTManager) UsesPublicKeys() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.noHMAC {
+		return true
+	}
+	for _, key := range m.static {
+		if key.PrivateKey != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// DisableHMAC stops signing and verifying with shared secrets, the
+// configured one and rotated ones alike, so every token can be verified
+// with a public key. Tokens they signed are rejected from then on.
+func (m *JWTManager) DisableHMAC() {
+	m.mu.Lock()
+	m.noHMAC = true
+	m.secretKey = nil
+	m.merge()
+	m.mu.Unlock()
+}
+
+// CanSign reports whether a key is active to sign new tokens with
+func (m *JWTManager) CanSign() bool {
+	key := m.signingKey(time.Now())
+	return key.PrivateKey != nil || len(key.Secret) > 0
+}
+
+// merge sorts the static and rotated keys together. The caller holds mu.
+func (m *JWTManager) merge() {
+	all := append(append([]SigningKey{}, m.static...), m.rotated...)
+	sorted := make([]SigningKey, 0, len(all))
+	for _, key := range all {
+		if !m.noHMAC || key.PrivateKey != nil {
+			sorted = append(sorted, key)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivatesAt.Before(sorted[j].ActivatesAt)
+	})
+	m.keys = sorted
+}
+
+// signingKey returns the key new tokens are signed with; its ID is empty
+// for the configured secret
+func (m *JWTManager) signingKey(now time.Time) SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		if !m.keys[i].ActivatesAt.After(now) {
+			return m.keys[i]
+		}
+	}
+	return SigningKey{Secret: m.secretKey}
+}
+
+// verificationKey returns the key for a token's kid, or false if the key
+// is unknown or was retired more than a refresh token TTL ago. Keys that
+// have not activated yet are accepted, since another instance may already
+// have started signing with them.
+func (m *JWTManager) verificationKey(kid string, now time.Time) (SigningKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if kid == "" {
+		if len(m.secretKey) == 0 || m.retired(-1, now) {
+			return SigningKey{}, false
+		}
+		return SigningKey{Secret: m.secretKey}, true
+	}
+	for i, key := range m.keys {
+		if key.ID == kid {
+			if m.retired(i, now) {
+				return SigningKey{}, false
+			}
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+// retired reports whether the key at index i, or the configured secret for
+// -1, was superseded more than a refresh token TTL ago. The caller holds mu.
+func (m *JWTManager) retired(i int, now time.Time) bool {
+	for _, next := range m.keys[i+1:] {
+		if !next.ActivatesAt.After(now) {
+			return next.ActivatesAt.Before(now.Add(-m.refreshTokenTTL))
+		}
+	}
+	return false
+}
+
 // TokenPair represents access and refresh tokens
 type TokenPair struct {
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	DeviceID     string    `json:"device_id,omitempty"`
+
+	// Refresh token bookkeeping for device-bound token chains
+	RefreshTokenID        string    `json:"-"`
+	RefreshTokenExpiresAt time.Time `json:"-"`
 }
 
 // GenerateTokenPair generates both access and refresh tokens
 func (m *JWTManager) GenerateTokenPair(userID, username string) (*TokenPair, error) {
-	accessToken, accessExp, err := m.generateToken(userID, username, AccessToken, m.accessTokenTTL)
+	return m.GenerateDeviceTokenPair(userID, username, "")
+}
+
+// GenerateDeviceTokenPair generates access and refresh tokens bound to a device
+func (m *JWTManager) GenerateDeviceTokenPair(userID, username, deviceID string) (*TokenPair, error) {
+	accessToken, accessClaims, err := m.generateToken(userID, username, deviceID, AccessToken, m.accessTokenTTL)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, _, err := m.generateToken(userID, username, RefreshToken, m.refreshTokenTTL)
+	refreshToken, refreshClaims, err := m.generateToken(userID, username, deviceID, RefreshToken, m.refreshTokenTTL)
 	if err != nil {
 		return nil, err
 	}
 
 	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    accessExp,
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		ExpiresAt:             accessClaims.ExpiresAt.Time,
+		DeviceID:              deviceID,
+		RefreshTokenID:        refreshClaims.ID,
+		RefreshTokenExpiresAt: refreshClaims.ExpiresAt.Time,
 	}, nil
 }
 
 // GenerateAccessToken generates only an access token
 func (m *JWTManager) GenerateAccessToken(userID, username string) (string, time.Time, error) {
-	return m.generateToken(userID, username, AccessToken, m.accessTokenTTL)
+	token, claims, err := m.generateToken(userID, username, "", AccessToken, m.accessTokenTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, claims.ExpiresAt.Time, nil
 }
 
 // GenerateRefreshToken generates only a refresh token
 func (m *JWTManager) GenerateRefreshToken(userID, username string) (string, time.Time, error) {
-	return m.generateToken(userID, username, RefreshToken, m.refreshTokenTTL)
+	token, claims, err := m.generateToken(userID, username, "", RefreshToken, m.refreshTokenTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, claims.ExpiresAt.Time, nil
 }
 
-func (m *JWTManager) generateToken(userID, username string, tokenType TokenType, ttl time.Duration) (string, time.Time, error) {
+func (m *JWTManager) generateToken(userID, username, deviceID string, tokenType TokenType, ttl time.Duration) (string, *Claims, error) {
 	now := time.Now()
 	expiresAt := now.Add(ttl)
 
@@ -90,6 +285,7 @@ func (m *JWTManager) generateToken(userID, username string, tokenType TokenType,
 		UserID:   userID,
 		Username: username,
 		Type:     tokenType,
+		DeviceID: deviceID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.New().String(),
 			Issuer:    m.issuer,
@@ -100,22 +296,37 @@ func (m *JWTManager) generateToken(userID, username string, tokenType TokenType,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString(m.secretKey)
+	key := m.signingKey(now)
+	if key.PrivateKey == nil && len(key.Secret) == 0 {
+		return "", nil, ErrNoSigningKey
+	}
+	method := key.method()
+	if method == nil {
+		return "", nil, fmt.Errorf("unsupported jwt signing key %s", key.ID)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	signedToken, err := token.SignedString(key.signingKey())
 	if err != nil {
-		return "", time.Time{}, err
+		return "", nil, err
 	}
 
-	return signedToken, expiresAt, nil
+	return signedToken, claims, nil
 }
 
 // ValidateToken validates a token and returns the claims
 func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.verificationKey(kid, time.Now())
+		// The algorithm must be the key's own, so a public key is never
+		// taken for an HMAC secret
+		if !ok || key.method() == nil || token.Method.Alg() != key.method().Alg() {
 			return nil, ErrInvalidToken
 		}
-		return m.secretKey, nil
+		return key.verificationKey(), nil
 	})
 
 	if err != nil {
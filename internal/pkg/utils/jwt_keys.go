@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// minSecretLength is the shortest HMAC secret accepted from a key file
+	minSecretLength = 32
+	// minRSAKeyBits is the smallest RSA key accepted for signing
+	minRSAKeyBits = 2048
+)
+
+// ParseSigningKey loads a static signing key written as
+// "kid path [activates_at]", for example
+// "2026-10 /etc/chat/jwt-2026-10.pem 2026-10-01T00:00:00Z". The file holds
+// either an RSA or Ed25519 private key in PEM form or an HMAC secret, whose
+// surrounding whitespace is ignored. Without an activation time the key
+// signs right away.
+func ParseSigningKey(s string) (SigningKey, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 && len(fields) != 3 {
+		return SigningKey{}, fmt.Errorf("invalid jwt signing key %q: expected kid, path and optional activation time", s)
+	}
+
+	key := SigningKey{ID: fields[0]}
+	data, err := os.ReadFile(fields[1])
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("failed to read jwt signing key %s: %w", key.ID, err)
+	}
+	if bytes.Contains(data, []byte("-----BEGIN ")) {
+		if key.PrivateKey, err = ParsePrivateKeyPEM(data); err != nil {
+			return SigningKey{}, fmt.Errorf("invalid jwt signing key %s: %w", key.ID, err)
+		}
+	} else {
+		key.Secret = bytes.TrimSpace(data)
+		if len(key.Secret) < minSecretLength {
+			return SigningKey{}, fmt.Errorf("jwt signing key %s must be at least %d bytes", key.ID, minSecretLength)
+		}
+	}
+	if len(fields) == 3 {
+		if key.ActivatesAt, err = time.Parse(time.RFC3339, fields[2]); err != nil {
+			return SigningKey{}, fmt.Errorf("invalid activation time of jwt signing key %s: %w", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+// ParsePrivateKeyPEM parses an RSA key in PKCS #1 or PKCS #8 form, or an
+// Ed25519 key in PKCS #8 form
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key)
+}
+
+// ParseSigningKeys loads the static signing keys. IDs must be unique, and
+// only one key may omit its activation time: two keys activating at once
+// would retire the first immediately.
+func ParseSigningKeys(specs []string) ([]SigningKey, error) {
+	keys := make([]SigningKey, 0, len(specs))
+	seen := make(map[string]bool)
+	immediate := 0
+	for _, spec := range specs {
+		key, err := ParseSigningKey(spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("duplicate jwt signing key %s", key.ID)
+		}
+		seen[key.ID] = true
+		if key.ActivatesAt.IsZero() {
+			immediate++
+		}
+		keys = append(keys, key)
+	}
+	if immediate > 1 {
+		return nil, errors.New("only one jwt signing key may omit its activation time")
+	}
+	return keys, nil
+}
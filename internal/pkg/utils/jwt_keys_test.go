@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeKeyFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwt.key")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return path
+}
+
+func writePEMFile(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	return writeKeyFile(t, string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})))
+}
+
+func TestParseSigningKey(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	path := writeKeyFile(t, secret+"\n")
+
+	key, err := ParseSigningKey("2026-10 " + path + " 2026-10-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	if key.ID != "2026-10" || string(key.Secret) != secret || !key.ActivatesAt.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+
+	key, err = ParseSigningKey("now " + path)
+	if err != nil || !key.ActivatesAt.IsZero() {
+		t.Errorf("Expected a key without activation time, got %+v, %v", key, err)
+	}
+
+	for _, spec := range []string{
+		"only-kid",
+		"k " + path + " yesterday",
+		"k " + filepath.Join(t.TempDir(), "missing.key"),
+		"k " + writeKeyFile(t, "too-short"),
+	} {
+		if _, err := ParseSigningKey(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestParseSigningKey_PrivateKeys(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+
+	key, err := ParseSigningKey("rsa " + writePEMFile(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)))
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	if key.method().Alg() != "RS256" || key.Secret != nil {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+
+	key, err = ParseSigningKey("ed " + writePEMFile(t, "PRIVATE KEY", edDER))
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	if key.method().Alg() != "EdDSA" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+
+	// A PEM file that does not hold a usable key is not taken for a secret
+	smallKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	for _, path := range []string{
+		writePEMFile(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(smallKey)),
+		writePEMFile(t, "CERTIFICATE", []byte(strings.Repeat("x", 64))),
+	} {
+		if _, err := ParseSigningKey("k " + path); err == nil {
+			t.Errorf("Expected %s to be rejected", path)
+		}
+	}
+}
+
+func TestParseSigningKeys(t *testing.T) {
+	path := writeKeyFile(t, strings.Repeat("s", 32))
+
+	if _, err := ParseSigningKeys([]string{"a " + path, "b " + path + " 2026-10-01T00:00:00Z"}); err != nil {
+		t.Errorf("Expected keys to parse: %v", err)
+	}
+	if _, err := ParseSigningKeys([]string{"a " + path, "a " + path + " 2026-10-01T00:00:00Z"}); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("Expected duplicate IDs to be rejected, got %v", err)
+	}
+	if _, err := ParseSigningKeys([]string{"a " + path, "b " + path}); err == nil {
+		t.Error("Expected two keys without activation time to be rejected")
+	}
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rand"
+	_ "crypto/sha256" // registers crypto.SHA256
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signerMethod signs through crypto.Signer instead of a concrete private
+// key type, so keys held in a KMS or HSM sign like keys loaded from files.
+// jwt's own RS256 method only signs with an *rsa.PrivateKey. Verification
+// is left to jwt's methods for the algorithm.
+type signerMethod struct {
+	alg  string
+	hash crypto.Hash // 0 for Ed25519, which signs the message itself
+}
+
+var (
+	signerRS256 = &signerMethod{alg: "RS256", hash: crypto.SHA256}
+	signerEdDSA = &signerMethod{alg: "EdDSA"}
+)
+
+func (m *signerMethod) Alg() string {
+	return m.alg
+}
+
+func (m *signerMethod) Verify(signingString string, sig []byte, key interface{}) error {
+	return jwt.GetSigningMethod(m.alg).Verify(signingString, sig, key)
+}
+
+func (m *signerMethod) Sign(signingString string, key interface{}) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, jwt.ErrInvalidKeyType
+	}
+
+	digest := []byte(signingString)
+	if m.hash != 0 {
+		h := m.hash.New()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
+	sig, err := signer.Sign(rand.Reader, digest, m.hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) == 0 {
+		return nil, errors.New("signer returned an empty signature")
+	}
+	return sig, nil
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// opaqueSigner hides the private key behind crypto.Signer, like a key held
+// in a KMS
+type opaqueSigner struct {
+	key   crypto.Signer
+	calls int
+}
+
+func (s *opaqueSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+func (s *opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.calls++
+	return s.key.Sign(rand, digest, opts)
+}
+
+func TestJWTManager_SignsWithCryptoSigner(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, key := range []crypto.Signer{rsaKey, edKey} {
+		signer := &opaqueSigner{key: key}
+		manager := createTestManager()
+		manager.SetStaticKeys([]SigningKey{{ID: "kms-1", PrivateKey: signer}})
+
+		signed, _, err := manager.GenerateAccessToken("user-123", "testuser")
+		if err != nil {
+			t.Fatalf("Failed to sign with %T: %v", key, err)
+		}
+		if signer.calls != 1 {
+			t.Errorf("Expected the signer to be used once, got %d", signer.calls)
+		}
+		if _, err := manager.ValidateAccessToken(signed); err != nil {
+			t.Errorf("Expected token to validate: %v", err)
+		}
+
+		// Downstream services need nothing but the public key
+		claims := &Claims{}
+		_, err = jwt.ParseWithClaims(signed, claims, func(token *jwt.Token) (interface{}, error) {
+			return key.Public(), nil
+		}, jwt.WithValidMethods([]string{"RS256", "EdDSA"}))
+		if err != nil || claims.UserID != "user-123" {
+			t.Errorf("Expected token to verify with the public key: %v", err)
+		}
+	}
+}
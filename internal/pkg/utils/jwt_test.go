@@ -1,8 +1,15 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"testing"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func createTestManager() *JWTManager {
@@ -219,3 +226,160 @@ func TestJWTManager_ClaimsContent(t *testing.T) {
 		t.Error("Expected expires_at to be set")
 	}
 }
+
+func TestJWTManager_SigningKeyRotation(t *testing.T) {
+	manager := createTestManager()
+	now := time.Now()
+
+	legacy, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+
+	// A key that has not activated yet verifies but does not sign
+	manager.SetSigningKeys([]SigningKey{{ID: "k1", Secret: []byte("first-rotated-secret"), ActivatesAt: now.Add(time.Minute)}})
+	pending, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+	if _, err := manager.ValidateAccessToken(pending); err != nil {
+		t.Fatalf("Expected token signed with the configured secret to validate: %v", err)
+	}
+
+	manager.SetSigningKeys([]SigningKey{{ID: "k1", Secret: []byte("first-rotated-secret"), ActivatesAt: now.Add(-time.Minute)}})
+	rotated, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+	for _, token := range []string{legacy, rotated} {
+		if _, err := manager.ValidateAccessToken(token); err != nil {
+			t.Errorf("Expected token to validate after rotation: %v", err)
+		}
+	}
+
+	// A manager without the key rejects what it signed
+	if _, err := createTestManager().ValidateAccessToken(rotated); err != ErrInvalidToken {
+		t.Errorf("Expected ErrInvalidToken for an unknown key, got %v", err)
+	}
+
+	// Once superseded for longer than the refresh TTL, the configured
+	// secret and k1 no longer verify
+	manager.SetSigningKeys([]SigningKey{
+		{ID: "k2", Secret: []byte("second-rotated-secret"), ActivatesAt: now.Add(-8 * 24 * time.Hour)},
+		{ID: "k1", Secret: []byte("first-rotated-secret"), ActivatesAt: now.Add(-9 * 24 * time.Hour)},
+	})
+	if _, err := manager.ValidateAccessToken(legacy); err != ErrInvalidToken {
+		t.Errorf("Expected the retired secret to be rejected, got %v", err)
+	}
+	if _, err := manager.ValidateAccessToken(rotated); err != ErrInvalidToken {
+		t.Errorf("Expected the retired key to be rejected, got %v", err)
+	}
+	current, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+	if _, err := manager.ValidateAccessToken(current); err != nil {
+		t.Errorf("Expected token signed with k2 to validate: %v", err)
+	}
+}
+
+func TestJWTManager_StaticKeys(t *testing.T) {
+	now := time.Now()
+	manager := createTestManager()
+	legacy, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+
+	manager.SetStaticKeys([]SigningKey{{ID: "static-1", Secret: []byte("static-secret"), ActivatesAt: now.Add(-2 * time.Minute)}})
+	static, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+	if key := manager.signingKey(now); key.ID != "static-1" {
+		t.Errorf("Expected static-1 to sign, got %q", key.ID)
+	}
+
+	// A rotated key that activated later takes over, and reloading the
+	// rotated keys leaves the static ones in place
+	manager.SetSigningKeys([]SigningKey{{ID: "k1", Secret: []byte("rotated-secret"), ActivatesAt: now.Add(-time.Minute)}})
+	if key := manager.signingKey(now); key.ID != "k1" {
+		t.Errorf("Expected k1 to sign, got %q", key.ID)
+	}
+	manager.SetSigningKeys(nil)
+	if key := manager.signingKey(now); key.ID != "static-1" {
+		t.Errorf("Expected static-1 to sign again, got %q", key.ID)
+	}
+
+	for _, token := range []string{legacy, static} {
+		if _, err := manager.ValidateAccessToken(token); err != nil {
+			t.Errorf("Expected token to validate: %v", err)
+		}
+	}
+}
+
+func TestJWTManager_AsymmetricStaticKeys(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+
+	for _, key := range []crypto.Signer{rsaKey, edKey} {
+		manager := createTestManager()
+		legacy, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+		manager.SetStaticKeys([]SigningKey{{ID: "static-1", PrivateKey: key, ActivatesAt: now.Add(-time.Minute)}})
+
+		signed, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+		token, _, err := jwt.NewParser().ParseUnverified(signed, &Claims{})
+		if err != nil {
+			t.Fatalf("Failed to parse token: %v", err)
+		}
+		if token.Header["kid"] != "static-1" || token.Method.Alg() == "HS256" {
+			t.Errorf("Expected a token signed with static-1, got %v", token.Header)
+		}
+		for _, tok := range []string{legacy, signed} {
+			if _, err := manager.ValidateAccessToken(tok); err != nil {
+				t.Errorf("Expected token to validate: %v", err)
+			}
+		}
+	}
+}
+
+func TestJWTManager_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	manager := createTestManager()
+	manager.SetStaticKeys([]SigningKey{{ID: "static-1", PrivateKey: rsaKey}})
+
+	// An HS256 token keyed with the public key must not pass as static-1
+	publicDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	claims := &Claims{UserID: "user-123", Type: AccessToken, RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = "static-1"
+	forged, _ := token.SignedString(publicDER)
+
+	if _, err := manager.ValidateAccessToken(forged); err != ErrInvalidToken {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+
+	// Nor may a token signed with the secret claim to be unsigned
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	none, _ := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := manager.ValidateAccessToken(none); err != ErrInvalidToken {
+		t.Errorf("Expected ErrInvalidToken for alg none, got %v", err)
+	}
+}
+
+func TestJWTManager_DisableHMAC(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	now := time.Now()
+
+	manager := createTestManager()
+	legacy, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+	manager.SetSigningKeys([]SigningKey{{ID: "k1", Secret: []byte("rotated-secret"), ActivatesAt: now.Add(-time.Minute)}})
+	rotated, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+
+	manager.DisableHMAC()
+	if manager.CanSign() {
+		t.Error("Expected no key to sign without HMAC and static keys")
+	}
+	if _, _, err := manager.GenerateAccessToken("user-123", "testuser"); err != ErrNoSigningKey {
+		t.Errorf("Expected ErrNoSigningKey, got %v", err)
+	}
+
+	manager.SetStaticKeys([]SigningKey{{ID: "static-1", PrivateKey: edKey, ActivatesAt: now.Add(-2 * time.Minute)}})
+	if !manager.CanSign() {
+		t.Fatal("Expected static-1 to sign")
+	}
+	signed, _, _ := manager.GenerateAccessToken("user-123", "testuser")
+	if _, err := manager.ValidateAccessToken(signed); err != nil {
+		t.Errorf("Expected token signed with static-1 to validate: %v", err)
+	}
+	for _, token := range []string{legacy, rotated} {
+		if _, err := manager.ValidateAccessToken(token); err != ErrInvalidToken {
+			t.Errorf("Expected HMAC token to be rejected, got %v", err)
+		}
+	}
+}
@@ -1,22 +1,53 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const (
-	// DefaultCost is the default bcrypt cost
+	// DefaultCost is the bcrypt cost hashes from before Argon2id used
 	DefaultCost = 12
 )
 
+// Argon2Params are the Argon2id settings of a hash
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP recommendation of 19 MiB and two
+// passes. Raising them makes NeedsRehash report every existing hash, so
+// they are upgraded as users sign in.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// argon2idPrefix tags Argon2id hashes, which use the PHC string format:
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>. bcrypt hashes start with
+// $2a$ or $2b$.
+const argon2idPrefix = "$argon2id$"
+
 var (
 	ErrPasswordTooShort = errors.New("password must be at least 8 characters")
 	ErrPasswordTooLong  = errors.New("password must be at most 72 characters")
 )
 
-// HashPassword hashes a password using bcrypt
+// HashPassword hashes a password using Argon2id
 func HashPassword(password string) (string, error) {
 	if len(password) < 8 {
 		return "", ErrPasswordTooShort
@@ -25,18 +56,77 @@ func HashPassword(password string) (string, error) {
 		return "", ErrPasswordTooLong
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), DefaultCost)
-	if err != nil {
+	p := DefaultArgon2Params
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
 
-	return string(hash), nil
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
-// CheckPassword compares a password with its hash
+// CheckPassword compares a password with its hash, which may be Argon2id
+// or bcrypt
 func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		return err == nil
+	}
+
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+// NeedsRehash reports whether a hash should be replaced after the password
+// was verified: bcrypt hashes and Argon2id hashes with weaker settings than
+// DefaultArgon2Params
+func NeedsRehash(hash string) bool {
+	p, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	want := DefaultArgon2Params
+	return p.Memory < want.Memory || p.Iterations < want.Iterations || p.KeyLength < want.KeyLength
+}
+
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errors.New("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	if p.Iterations == 0 || p.Parallelism == 0 {
+		return p, nil, nil, errors.New("invalid argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errors.New("invalid argon2 key")
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
 }
 
 // ValidatePassword validates password strength
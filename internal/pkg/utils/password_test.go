@@ -3,6 +3,8 @@ package utils
 import (
 	"strings"
 	"testing"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -21,9 +23,16 @@ func TestHashPassword(t *testing.T) {
 		t.Error("Hash should not equal plain password")
 	}
 
-	// bcrypt hash should start with $2a$ or $2b$
-	if !strings.HasPrefix(hash, "$2") {
-		t.Error("Expected bcrypt hash prefix")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("Expected an argon2id hash, got %s", hash)
+	}
+}
+
+func TestHashPassword_UniqueSalt(t *testing.T) {
+	first, _ := HashPassword("securepassword123")
+	second, _ := HashPassword("securepassword123")
+	if first == second {
+		t.Error("Expected hashes of the same password to differ")
 	}
 }
 
@@ -65,6 +74,59 @@ func TestCheckPassword_Wrong(t *testing.T) {
 	}
 }
 
+func TestCheckPassword_Bcrypt(t *testing.T) {
+	// Hashes from before Argon2id keep working
+	hash, err := bcrypt.GenerateFromPassword([]byte("securepassword123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	if !CheckPassword("securepassword123", string(hash)) {
+		t.Error("Expected password check to pass")
+	}
+	if CheckPassword("wrongpassword", string(hash)) {
+		t.Error("Expected password check to fail")
+	}
+}
+
+func TestCheckPassword_TamperedHash(t *testing.T) {
+	hash, _ := HashPassword("securepassword123")
+	parts := strings.Split(hash, "$")
+
+	for _, tampered := range []string{
+		strings.Replace(hash, "t=2", "t=3", 1),
+		strings.Join(append(parts[:5:5], "AAAA"), "$"),
+		strings.Replace(hash, "v=19", "v=16", 1),
+		strings.TrimSuffix(hash, "$"+parts[5]),
+	} {
+		if CheckPassword("securepassword123", tampered) {
+			t.Errorf("Expected password check to fail for %s", tampered)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	current, _ := HashPassword("securepassword123")
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("securepassword123"), bcrypt.MinCost)
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"current", current, false},
+		{"bcrypt", string(legacy), true},
+		{"weaker argon2id", strings.Replace(current, "m=19456", "m=4096", 1), true},
+		{"stronger argon2id", strings.Replace(current, "m=19456", "m=65536", 1), false},
+		{"garbage", "not-a-valid-hash", true},
+	}
+	for _, tt := range tests {
+		if got := NeedsRehash(tt.hash); got != tt.want {
+			t.Errorf("%s: NeedsRehash = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestCheckPassword_InvalidHash(t *testing.T) {
 	if CheckPassword("password", "not-a-valid-hash") {
 		t.Error("Expected password check to fail with invalid hash")
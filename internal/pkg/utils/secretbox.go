@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var ErrSealedSecret = errors.New("sealed secret cannot be opened")
+
+// SecretBox encrypts secrets stored in the database with AES-256-GCM, so
+// read access to the database alone does not reveal them. The key is
+// derived from a passphrase kept in the config file.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a box keyed by the SHA-256 of passphrase
+func NewSecretBox(passphrase string) (*SecretBox, error) {
+	if passphrase == "" {
+		return nil, errors.New("secret box passphrase is empty")
+	}
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts a secret as base64 of the nonce followed by the ciphertext
+func (b *SecretBox) Seal(secret []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, secret, nil)), nil
+}
+
+// Open decrypts a sealed secret. It fails with ErrSealedSecret when the
+// secret was sealed with another passphrase or altered.
+func (b *SecretBox) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return nil, ErrSealedSecret
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrSealedSecret
+	}
+	return secret, nil
+}
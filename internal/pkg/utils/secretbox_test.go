@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSecretBox_SealOpen(t *testing.T) {
+	box, err := NewSecretBox("key-encryption-key")
+	if err != nil {
+		t.Fatalf("Failed to create box: %v", err)
+	}
+
+	sealed, err := box.Seal([]byte("signing-secret"))
+	if err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	if strings.Contains(sealed, "signing-secret") {
+		t.Error("Expected the secret to be encrypted")
+	}
+
+	secret, err := box.Open(sealed)
+	if err != nil || !bytes.Equal(secret, []byte("signing-secret")) {
+		t.Errorf("Expected the secret back, got %q, %v", secret, err)
+	}
+
+	// Sealing twice uses fresh nonces
+	again, _ := box.Seal([]byte("signing-secret"))
+	if again == sealed {
+		t.Error("Expected sealed secrets to differ")
+	}
+}
+
+func TestSecretBox_OpenRejects(t *testing.T) {
+	box, _ := NewSecretBox("key-encryption-key")
+	other, _ := NewSecretBox("another-key")
+	sealed, _ := box.Seal([]byte("signing-secret"))
+
+	tampered := []byte(sealed)
+	tampered[len(tampered)-3] ^= 1
+
+	for name, s := range map[string]string{
+		"other passphrase": "",
+		"tampered":         string(tampered),
+		"not base64":       "%%%",
+		"too short":        "AAAA",
+	} {
+		var err error
+		if name == "other passphrase" {
+			_, err = other.Open(sealed)
+		} else {
+			_, err = box.Open(s)
+		}
+		if err != ErrSealedSecret {
+			t.Errorf("%s: expected ErrSealedSecret, got %v", name, err)
+		}
+	}
+
+	if _, err := NewSecretBox(""); err == nil {
+		t.Error("Expected an empty passphrase to be rejected")
+	}
+}
@@ -0,0 +1,91 @@
+package policy
+
+import "github.com/go-demo/chat/internal/model"
+
+// Matrix grants configurable actions per member role. Owners are not listed:
+// they always hold every configurable action so a room can't lock itself out.
+type Matrix map[model.MemberRole]map[Action]bool
+
+// ConfigurableActions lists the actions a room can grant or revoke per role
+var ConfigurableActions = []Action{
+	CanSend,
+	CanPin,
+	CanInvite,
+	CanKick,
+	CanMute,
+	CanManageRoom,
+}
+
+// ConfigurableRoles lists the roles whose grants a room can change
+var ConfigurableRoles = []model.MemberRole{
+	model.MemberRoleAdmin,
+	model.MemberRoleMember,
+}
+
+// DefaultMatrix returns the built-in grants: admins moderate, members talk
+func DefaultMatrix() Matrix {
+	return Matrix{
+		model.MemberRoleAdmin: {
+			CanSend:       true,
+			CanPin:        true,
+			CanInvite:     true,
+			CanKick:       true,
+			CanMute:       true,
+			CanManageRoom: true,
+		},
+		model.MemberRoleMember: {
+			CanSend: true,
+		},
+	}
+}
+
+// IsConfigurable checks if rooms may change the grant of an action
+func IsConfigurable(action Action) bool {
+	for _, a := range ConfigurableActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// IsConfigurableRole checks if rooms may change the grants of a role
+func IsConfigurableRole(role model.MemberRole) bool {
+	for _, r := range ConfigurableRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the role is granted the action
+func (m Matrix) Allows(role model.MemberRole, action Action) bool {
+	return m[role][action]
+}
+
+// Clone returns a deep copy of the matrix
+func (m Matrix) Clone() Matrix {
+	clone := make(Matrix, len(m))
+	for role, grants := range m {
+		clone[role] = make(map[Action]bool, len(grants))
+		for action, allowed := range grants {
+			clone[role][action] = allowed
+		}
+	}
+	return clone
+}
+
+// Merge returns a copy of the matrix with the overrides applied on top
+func (m Matrix) Merge(overrides Matrix) Matrix {
+	merged := m.Clone()
+	for role, grants := range overrides {
+		if merged[role] == nil {
+			merged[role] = make(map[Action]bool, len(grants))
+		}
+		for action, allowed := range grants {
+			merged[role][action] = allowed
+		}
+	}
+	return merged
+}
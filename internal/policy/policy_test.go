@@ -0,0 +1,256 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+func newTestSubject(roomType model.RoomType, role model.MemberRole, isMember bool) *Subject {
+	room := &model.Room{ID: "room-1", Type: roomType, OwnerID: "owner-1"}
+	s := &Subject{UserID: "user-1", Room: room}
+
+	if role == model.MemberRoleOwner {
+		s.UserID = "owner-1"
+	}
+	if isMember {
+		s.Member = &model.RoomMember{RoomID: room.ID, UserID: s.UserID, Role: role}
+	}
+	return s
+}
+
+func TestEngine_DefaultRules(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		name     string
+		subject  *Subject
+		action   Action
+		expected bool
+	}{
+		{"member can send", newTestSubject(model.RoomTypePublic, model.MemberRoleMember, true), CanSend, true},
+		{"non-member cannot send", newTestSubject(model.RoomTypePublic, "", false), CanSend, false},
+		{"member cannot invite", newTestSubject(model.RoomTypePrivate, model.MemberRoleMember, true), CanInvite, false},
+		{"admin can invite", newTestSubject(model.RoomTypePrivate, model.MemberRoleAdmin, true), CanInvite, true},
+		{"admin can pin", newTestSubject(model.RoomTypePublic, model.MemberRoleAdmin, true), CanPin, true},
+		{"admin can manage room", newTestSubject(model.RoomTypePublic, model.MemberRoleAdmin, true), CanManageRoom, true},
+		{"admin cannot delete room", newTestSubject(model.RoomTypePublic, model.MemberRoleAdmin, true), CanDeleteRoom, false},
+		{"owner can delete room", newTestSubject(model.RoomTypePublic, model.MemberRoleOwner, true), CanDeleteRoom, true},
+		{"owner can manage roles", newTestSubject(model.RoomTypePublic, model.MemberRoleOwner, true), CanManageRoles, true},
+		{"non-member views public members", newTestSubject(model.RoomTypePublic, "", false), CanViewMembers, true},
+		{"non-member cannot view private members", newTestSubject(model.RoomTypePrivate, "", false), CanViewMembers, false},
+		{"non-member reads public history", newTestSubject(model.RoomTypePublic, "", false), CanReadHistory, true},
+		{"non-member cannot access", newTestSubject(model.RoomTypePublic, "", false), CanAccess, false},
+		{"cannot join private room", newTestSubject(model.RoomTypePrivate, "", false), CanJoin, false},
+		{"can join public room", newTestSubject(model.RoomTypePublic, "", false), CanJoin, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := engine.Can(tt.subject, tt.action); got != tt.expected {
+				t.Errorf("Can(%s) = %v, expected %v", tt.action, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEngine_MutedMemberCannotSend(t *testing.T) {
+	engine := New()
+	subject := newTestSubject(model.RoomTypePublic, model.MemberRoleMember, true)
+	subject.Member.IsMuted = true
+
+	if engine.Can(subject, CanSend) {
+		t.Error("Expected muted member to be denied")
+	}
+}
+
+func TestEngine_ExpiredMuteAllowsSend(t *testing.T) {
+	engine := New()
+	subject := newTestSubject(model.RoomTypePublic, model.MemberRoleMember, true)
+	subject.Member.IsMuted = true
+
+	future := time.Now().Add(time.Minute)
+	subject.Member.MutedUntil = &future
+	if engine.Can(subject, CanSend) {
+		t.Error("Expected member muted until later to be denied")
+	}
+
+	past := time.Now().Add(-time.Minute)
+	subject.Member.MutedUntil = &past
+	if !engine.Can(subject, CanSend) {
+		t.Error("Expected expired mute not to apply")
+	}
+}
+
+func TestEngine_ReadOnlyRoomRestrictsSend(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		role     model.MemberRole
+		expected bool
+	}{
+		{model.MemberRoleMember, false},
+		{model.MemberRoleAdmin, true},
+		{model.MemberRoleOwner, true},
+	}
+
+	for _, tt := range tests {
+		subject := newTestSubject(model.RoomTypePublic, tt.role, true)
+		subject.Room.ReadOnly = true
+		if got := engine.Can(subject, CanSend); got != tt.expected {
+			t.Errorf("%s in read-only room: Can(CanSend) = %v, expected %v", tt.role, got, tt.expected)
+		}
+	}
+}
+
+func TestEngine_ArchivedRoom(t *testing.T) {
+	engine := New()
+	archivedAt := time.Now()
+
+	tests := []struct {
+		name     string
+		subject  *Subject
+		action   Action
+		expected bool
+	}{
+		{"owner cannot send", newTestSubject(model.RoomTypePublic, model.MemberRoleOwner, true), CanSend, false},
+		{"member reads history", newTestSubject(model.RoomTypePublic, model.MemberRoleMember, true), CanReadHistory, true},
+		{"non-member cannot read history", newTestSubject(model.RoomTypePublic, "", false), CanReadHistory, false},
+		{"cannot join", newTestSubject(model.RoomTypePublic, "", false), CanJoin, false},
+		{"owner can archive", newTestSubject(model.RoomTypePublic, model.MemberRoleOwner, true), CanArchiveRoom, true},
+		{"admin cannot archive", newTestSubject(model.RoomTypePublic, model.MemberRoleAdmin, true), CanArchiveRoom, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.subject.Room.ArchivedAt = &archivedAt
+			if got := engine.Can(tt.subject, tt.action); got != tt.expected {
+				t.Errorf("Can(%s) = %v, expected %v", tt.action, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEngine_UnknownActionDenied(t *testing.T) {
+	engine := New()
+	subject := newTestSubject(model.RoomTypePublic, model.MemberRoleOwner, true)
+
+	if engine.Can(subject, Action("can_fly")) {
+		t.Error("Expected unknown action to be denied")
+	}
+	if engine.Can(nil, CanSend) {
+		t.Error("Expected nil subject to be denied")
+	}
+}
+
+func TestEngine_SetRule(t *testing.T) {
+	engine := New()
+	subject := newTestSubject(model.RoomTypePublic, model.MemberRoleMember, true)
+
+	engine.SetRule(CanPin, func(s *Subject) bool { return s.IsMember() })
+
+	if !engine.Can(subject, CanPin) {
+		t.Error("Expected overridden rule to allow members to pin")
+	}
+}
+
+func TestEngine_CanActOn(t *testing.T) {
+	engine := New()
+	owner := &model.RoomMember{Role: model.MemberRoleOwner}
+	admin := &model.RoomMember{Role: model.MemberRoleAdmin}
+	member := &model.RoomMember{Role: model.MemberRoleMember}
+
+	if !engine.CanActOn(owner, admin) {
+		t.Error("Expected owner to act on admin")
+	}
+	if !engine.CanActOn(admin, member) {
+		t.Error("Expected admin to act on member")
+	}
+	if engine.CanActOn(admin, admin) {
+		t.Error("Expected admin not to act on admin")
+	}
+	if engine.CanActOn(admin, owner) {
+		t.Error("Expected nobody to act on owner")
+	}
+
+	// Custom roles slot into the hierarchy
+	moderator := &model.RoomMember{Role: model.MemberRole("moderator")}
+	engine.SetRoleRank(moderator.Role, 2)
+	if !engine.CanActOn(moderator, member) {
+		t.Error("Expected custom role to act on member")
+	}
+}
+
+func TestEngine_Permissions(t *testing.T) {
+	engine := New()
+	perms := engine.Permissions(newTestSubject(model.RoomTypePublic, model.MemberRoleMember, true))
+
+	if len(perms) != len(Actions) {
+		t.Errorf("Expected %d permissions, got %d", len(Actions), len(perms))
+	}
+	if !perms[CanSend] || perms[CanDeleteRoom] {
+		t.Errorf("Unexpected permissions: %v", perms)
+	}
+}
+
+func TestEngine_RoomOverrides(t *testing.T) {
+	engine := New()
+
+	member := newTestSubject(model.RoomTypePublic, model.MemberRoleMember, true)
+	member.Overrides = Matrix{model.MemberRoleMember: {CanInvite: true, CanSend: false}}
+	if !engine.Can(member, CanInvite) {
+		t.Error("Expected override to grant invite")
+	}
+	if engine.Can(member, CanSend) {
+		t.Error("Expected override to revoke send")
+	}
+	if engine.Can(member, CanKick) {
+		t.Error("Expected actions without overrides to use defaults")
+	}
+
+	admin := newTestSubject(model.RoomTypePublic, model.MemberRoleAdmin, true)
+	admin.Overrides = Matrix{model.MemberRoleAdmin: {CanKick: false}}
+	if engine.Can(admin, CanKick) {
+		t.Error("Expected override to revoke kick")
+	}
+
+	// Owners keep every configurable action regardless of overrides
+	owner := newTestSubject(model.RoomTypePublic, model.MemberRoleOwner, true)
+	owner.Overrides = Matrix{model.MemberRoleOwner: {CanManageRoom: false}}
+	if !engine.Can(owner, CanManageRoom) {
+		t.Error("Expected owner to keep manage room")
+	}
+}
+
+func TestEngine_SetMatrix(t *testing.T) {
+	engine := New()
+	m := DefaultMatrix()
+	m[model.MemberRoleMember][CanPin] = true
+	engine.SetMatrix(m)
+
+	if !engine.Can(newTestSubject(model.RoomTypePublic, model.MemberRoleMember, true), CanPin) {
+		t.Error("Expected default matrix change to apply")
+	}
+
+	// The engine keeps its own copy
+	m[model.MemberRoleMember][CanPin] = false
+	if !engine.Matrix(nil).Allows(model.MemberRoleMember, CanPin) {
+		t.Error("Expected engine matrix to be isolated from caller")
+	}
+}
+
+func TestMatrix_Merge(t *testing.T) {
+	base := DefaultMatrix()
+	merged := base.Merge(Matrix{model.MemberRoleMember: {CanInvite: true}})
+
+	if !merged.Allows(model.MemberRoleMember, CanInvite) || !merged.Allows(model.MemberRoleMember, CanSend) {
+		t.Errorf("Unexpected merged grants: %v", merged[model.MemberRoleMember])
+	}
+	if base.Allows(model.MemberRoleMember, CanInvite) {
+		t.Error("Expected merge not to modify the base matrix")
+	}
+	if !IsConfigurable(CanKick) || IsConfigurable(CanDeleteRoom) {
+		t.Error("Unexpected configurable actions")
+	}
+}
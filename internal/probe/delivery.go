@@ -0,0 +1,432 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// contentPrefix marks probe messages so they are easy to recognise
+	contentPrefix = "[delivery-probe] "
+
+	// latencySamples is the number of recent probe latencies kept
+	latencySamples = 100
+
+	deviceName = "delivery-prober"
+)
+
+var (
+	errUnauthorized = errors.New("probe credentials rejected")
+	errConflict     = errors.New("conflict")
+)
+
+// Config configures the delivery prober
+type Config struct {
+	BaseURL          string // e.g. http://127.0.0.1:8080
+	Username         string
+	Password         string
+	RoomID           string
+	Timeout          time.Duration // how long to wait for the WS event
+	LatencyThreshold time.Duration // latency above this counts as a breach
+	FailAfter        int           // consecutive breaches before reporting unhealthy
+}
+
+// Status reports the prober's latest results
+type Status struct {
+	Healthy        bool    `json:"healthy"`
+	LastLatencyMs  float64 `json:"last_latency_ms"`
+	P50LatencyMs   float64 `json:"p50_latency_ms"`
+	P95LatencyMs   float64 `json:"p95_latency_ms"`
+	ThresholdMs    float64 `json:"threshold_ms"`
+	Successes      int64   `json:"successes"`
+	Failures       int64   `json:"failures"`
+	ConsecutiveBad int     `json:"consecutive_breaches"`
+	LastError      string  `json:"last_error,omitempty"`
+	LastRunAt      string  `json:"last_run_at,omitempty"`
+}
+
+// DeliveryProber measures end-to-end message delivery by sending a message
+// to a probe room over REST and timing its arrival on a WebSocket connection
+type DeliveryProber struct {
+	cfg    Config
+	http   *http.Client
+	logger *zap.Logger
+
+	// Session state, only touched by Run
+	token    string
+	deviceID string
+	conn     *websocket.Conn
+	received chan wsEvent
+
+	mu             sync.RWMutex
+	samples        []time.Duration
+	lastLatency    time.Duration
+	successes      int64
+	failures       int64
+	consecutiveBad int
+	lastErr        error
+	lastRunAt      time.Time
+}
+
+type wsEvent struct {
+	content string
+	at      time.Time
+}
+
+// NewDeliveryProber creates a prober; call Run periodically
+func NewDeliveryProber(cfg Config, logger *zap.Logger) *DeliveryProber {
+	if cfg.FailAfter <= 0 {
+		cfg.FailAfter = 1
+	}
+	return &DeliveryProber{
+		cfg:    cfg,
+		http:   &http.Client{Timeout: cfg.Timeout},
+		logger: logger,
+	}
+}
+
+// Run performs one probe. It is meant to run as a scheduler job; failures
+// are recorded in Status as well as returned.
+func (p *DeliveryProber) Run(ctx context.Context) error {
+	latency, err := p.probe(ctx)
+	p.record(latency, err)
+	return err
+}
+
+// Healthy reports whether delivery latency is within the SLO
+func (p *DeliveryProber) Healthy() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.consecutiveBad < p.cfg.FailAfter
+}
+
+// Status returns the prober's latest results
+func (p *DeliveryProber) Status() *Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	sorted := make([]time.Duration, len(p.samples))
+	copy(sorted, p.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	status := &Status{
+		Healthy:        p.consecutiveBad < p.cfg.FailAfter,
+		LastLatencyMs:  millis(p.lastLatency),
+		P50LatencyMs:   millis(percentile(sorted, 50)),
+		P95LatencyMs:   millis(percentile(sorted, 95)),
+		ThresholdMs:    millis(p.cfg.LatencyThreshold),
+		Successes:      p.successes,
+		Failures:       p.failures,
+		ConsecutiveBad: p.consecutiveBad,
+	}
+	if p.lastErr != nil {
+		status.LastError = p.lastErr.Error()
+	}
+	if !p.lastRunAt.IsZero() {
+		status.LastRunAt = p.lastRunAt.Format(time.RFC3339)
+	}
+	return status
+}
+
+// Close closes the probe connection
+func (p *DeliveryProber) Close() {
+	if p.conn != nil {
+		_ = p.conn.Close()
+		p.conn = nil
+	}
+}
+
+func (p *DeliveryProber) record(latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastRunAt = time.Now()
+	p.lastErr = err
+	wasHealthy := p.consecutiveBad < p.cfg.FailAfter
+
+	if err != nil {
+		p.failures++
+		p.consecutiveBad++
+	} else {
+		p.successes++
+		p.lastLatency = latency
+		p.samples = append(p.samples, latency)
+		if len(p.samples) > latencySamples {
+			p.samples = p.samples[len(p.samples)-latencySamples:]
+		}
+		if p.cfg.LatencyThreshold > 0 && latency > p.cfg.LatencyThreshold {
+			p.consecutiveBad++
+		} else {
+			p.consecutiveBad = 0
+		}
+	}
+
+	healthy := p.consecutiveBad < p.cfg.FailAfter
+	if wasHealthy && !healthy {
+		p.logger.Warn("Message delivery SLO breached",
+			zap.Duration("latency", latency),
+			zap.Duration("threshold", p.cfg.LatencyThreshold),
+			zap.Error(err),
+		)
+	} else if !wasHealthy && healthy {
+		p.logger.Info("Message delivery SLO recovered", zap.Duration("latency", latency))
+	}
+}
+
+func (p *DeliveryProber) probe(ctx context.Context) (time.Duration, error) {
+	if err := p.ensureConnected(ctx); err != nil {
+		p.Close()
+		return 0, err
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return 0, err
+	}
+	content := contentPrefix + nonce
+
+	// Drop events left over from earlier probes
+	for len(p.received) > 0 {
+		<-p.received
+	}
+
+	sentAt := time.Now()
+	err = p.sendMessage(ctx, content)
+	if err == errUnauthorized {
+		// The access token expired; log in again and retry once
+		p.token = ""
+		if err = p.login(ctx); err == nil {
+			sentAt = time.Now()
+			err = p.sendMessage(ctx, content)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	timer := time.NewTimer(p.cfg.Timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case ev, ok := <-p.received:
+			if !ok {
+				p.Close()
+				return 0, errors.New("probe connection closed before delivery")
+			}
+			if ev.content == content {
+				return ev.at.Sub(sentAt), nil
+			}
+		case <-timer.C:
+			return 0, fmt.Errorf("message not delivered within %s", p.cfg.Timeout)
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func (p *DeliveryProber) ensureConnected(ctx context.Context) error {
+	if p.conn != nil {
+		return nil
+	}
+
+	if p.token == "" {
+		if err := p.login(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Joining is idempotent enough for the probe: an existing membership is fine
+	if _, err := p.post(ctx, "/api/v1/rooms/"+p.cfg.RoomID+"/join", nil); err != nil && err != errConflict {
+		return fmt.Errorf("failed to join probe room: %w", err)
+	}
+
+	wsURL, err := p.wsURL()
+	if err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open probe connection: %w", err)
+	}
+
+	join, _ := json.Marshal(map[string]interface{}{
+		"type":    "join_room",
+		"payload": map[string]string{"room_id": p.cfg.RoomID},
+	})
+	if err := conn.WriteMessage(websocket.TextMessage, join); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to join probe room over websocket: %w", err)
+	}
+
+	p.conn = conn
+	p.received = make(chan wsEvent, 16)
+	go readEvents(conn, p.received)
+	return nil
+}
+
+// readEvents forwards new_message contents until the connection closes
+func readEvents(conn *websocket.Conn, out chan<- wsEvent) {
+	defer close(out)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		at := time.Now()
+
+		var msg struct {
+			Type    string `json:"type"`
+			Payload struct {
+				Content string `json:"content"`
+			} `json:"payload"`
+		}
+		if json.Unmarshal(data, &msg) != nil || msg.Type != "new_message" {
+			continue
+		}
+		if !strings.HasPrefix(msg.Payload.Content, contentPrefix) {
+			continue
+		}
+		select {
+		case out <- wsEvent{content: msg.Payload.Content, at: at}:
+		default:
+		}
+	}
+}
+
+func (p *DeliveryProber) login(ctx context.Context) error {
+	body := map[string]string{
+		"username":    p.cfg.Username,
+		"password":    p.cfg.Password,
+		"device_name": deviceName,
+	}
+	if p.deviceID != "" {
+		body["device_id"] = p.deviceID
+	}
+
+	data, err := p.post(ctx, "/api/v1/auth/login", body)
+	if err != nil {
+		return fmt.Errorf("failed to log in probe user: %w", err)
+	}
+
+	var resp struct {
+		Token struct {
+			AccessToken string `json:"access_token"`
+			DeviceID    string `json:"device_id"`
+		} `json:"token"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || resp.Token.AccessToken == "" {
+		return errors.New("failed to log in probe user: malformed response")
+	}
+	p.token = resp.Token.AccessToken
+	p.deviceID = resp.Token.DeviceID
+	return nil
+}
+
+func (p *DeliveryProber) sendMessage(ctx context.Context, content string) error {
+	_, err := p.post(ctx, "/api/v1/rooms/"+p.cfg.RoomID+"/messages", map[string]string{
+		"content": content,
+	})
+	if err != nil && err != errUnauthorized {
+		return fmt.Errorf("failed to send probe message: %w", err)
+	}
+	return err
+}
+
+// post sends a JSON request and returns the response's data field
+func (p *DeliveryProber) post(ctx context.Context, path string, body interface{}) (json.RawMessage, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(p.cfg.BaseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data  json.RawMessage `json:"data"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&envelope)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, errUnauthorized
+	case resp.StatusCode == http.StatusConflict:
+		return nil, errConflict
+	case resp.StatusCode >= 300:
+		if envelope.Error != nil {
+			return nil, fmt.Errorf("%s: %d %s", path, resp.StatusCode, envelope.Error.Message)
+		}
+		return nil, fmt.Errorf("%s: %d", path, resp.StatusCode)
+	}
+	return envelope.Data, nil
+}
+
+func (p *DeliveryProber) wsURL() (string, error) {
+	u, err := url.Parse(p.cfg.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid probe base URL: %w", err)
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/ws"
+	u.RawQuery = url.Values{"token": {p.token}}.Encode()
+	return u.String(), nil
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func percentile(sorted []time.Duration, pct int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := (len(sorted)*pct + 99) / 100
+	if idx > 0 {
+		idx--
+	}
+	return sorted[idx]
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,196 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// fakeServer implements the endpoints the prober uses and delivers sent
+// messages to the WebSocket connection after delay
+type fakeServer struct {
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	delay   time.Duration
+	drop    bool
+	logins  int
+	expired bool
+}
+
+func (f *fakeServer) handler(t *testing.T) http.Handler {
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v1/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.logins++
+		f.expired = false
+		f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data": map[string]interface{}{
+				"token": map[string]string{"access_token": "token", "device_id": "device-1"},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v1/rooms/room-1/join", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	mux.HandleFunc("/api/v1/rooms/room-1/messages", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		expired, conn, delay, drop := f.expired, f.conn, f.delay, f.drop
+		f.mu.Unlock()
+		if expired {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body struct {
+			Content string `json:"content"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+
+		if drop || conn == nil {
+			return
+		}
+		go func() {
+			time.Sleep(delay)
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type":    "new_message",
+				"payload": map[string]string{"content": body.Content},
+			})
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			_ = conn.WriteMessage(websocket.TextMessage, msg)
+		}()
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade: %v", err)
+			return
+		}
+		f.mu.Lock()
+		f.conn = conn
+		f.mu.Unlock()
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+	})
+	return mux
+}
+
+func newTestProber(t *testing.T, fake *fakeServer) *DeliveryProber {
+	t.Helper()
+	srv := httptest.NewServer(fake.handler(t))
+	t.Cleanup(srv.Close)
+
+	prober := NewDeliveryProber(Config{
+		BaseURL:          srv.URL,
+		Username:         "prober",
+		Password:         "secret",
+		RoomID:           "room-1",
+		Timeout:          200 * time.Millisecond,
+		LatencyThreshold: 50 * time.Millisecond,
+		FailAfter:        2,
+	}, zap.NewNop())
+	t.Cleanup(prober.Close)
+	return prober
+}
+
+func TestDeliveryProber_MeasuresLatency(t *testing.T) {
+	fake := &fakeServer{delay: 10 * time.Millisecond}
+	prober := newTestProber(t, fake)
+
+	if err := prober.Run(context.Background()); err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+
+	status := prober.Status()
+	if !status.Healthy || status.Successes != 1 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if status.LastLatencyMs < 10 {
+		t.Errorf("Expected latency of at least 10ms, got %.2fms", status.LastLatencyMs)
+	}
+}
+
+func TestDeliveryProber_AlarmsAfterConsecutiveBreaches(t *testing.T) {
+	fake := &fakeServer{delay: 100 * time.Millisecond}
+	prober := newTestProber(t, fake)
+	ctx := context.Background()
+
+	_ = prober.Run(ctx)
+	if !prober.Healthy() {
+		t.Error("Expected a single breach to stay healthy")
+	}
+
+	fake.mu.Lock()
+	fake.drop = true
+	fake.mu.Unlock()
+	if err := prober.Run(ctx); err == nil {
+		t.Error("Expected undelivered message to fail the probe")
+	}
+	if prober.Healthy() {
+		t.Error("Expected prober to alarm after consecutive breaches")
+	}
+
+	fake.mu.Lock()
+	fake.drop = false
+	fake.delay = 0
+	fake.mu.Unlock()
+	if err := prober.Run(ctx); err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if !prober.Healthy() {
+		t.Errorf("Expected prober to recover, got %+v", prober.Status())
+	}
+}
+
+func TestDeliveryProber_RelogsInOnExpiredToken(t *testing.T) {
+	fake := &fakeServer{}
+	prober := newTestProber(t, fake)
+	ctx := context.Background()
+
+	if err := prober.Run(ctx); err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+
+	fake.mu.Lock()
+	fake.expired = true
+	fake.mu.Unlock()
+	if err := prober.Run(ctx); err != nil {
+		t.Fatalf("Probe failed after token expiry: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.logins != 2 {
+		t.Errorf("Expected 2 logins, got %d", fake.logins)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if got := percentile(sorted, 50); got != 5 {
+		t.Errorf("Expected p50 of 5, got %d", got)
+	}
+	if got := percentile(sorted, 95); got != 10 {
+		t.Errorf("Expected p95 of 10, got %d", got)
+	}
+	if got := percentile(nil, 95); got != 0 {
+		t.Errorf("Expected 0 for no samples, got %d", got)
+	}
+}
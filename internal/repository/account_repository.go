@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// AnonymizeOptions selects what happens to a deleted account's content
+type AnonymizeOptions struct {
+	// EraseMessages clears the content of the user's room messages; otherwise
+	// they are kept under the anonymized author
+	EraseMessages bool
+	// EraseDirectMessages deletes every direct message the user sent or
+	// received; otherwise the other party keeps them
+	EraseDirectMessages bool
+}
+
+// AnonymizeResult counts what an account deletion touched
+type AnonymizeResult struct {
+	MessagesErased       int64
+	DirectMessagesErased int64
+}
+
+// AccountRepository handles account deletion and the data returned to users
+// who ask for a copy of it
+type AccountRepository struct {
+	db *sqlx.DB
+}
+
+func NewAccountRepository(db *sqlx.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// Anonymize deletes a user's account. The user row is kept so retained
+// messages still reference an author, but everything identifying is
+// scrubbed, the password can no longer match and the user's relationships,
+// memberships, devices and pending scheduled messages are removed.
+// Messages in rooms under an active legal hold are never erased.
+func (r *AccountRepository) Anonymize(ctx context.Context, userID string, opts AnonymizeOptions) (*AnonymizeResult, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	placeholder := "deleted_" + strings.ReplaceAll(userID, "-", "")
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users SET
+			username = $2,
+			email = $3,
+			password_hash = '!',
+			display_name = NULL,
+			avatar_url = NULL,
+			bio = NULL,
+			status = $4,
+			is_admin = FALSE,
+			deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`,
+		userID, placeholder, placeholder+"@deleted.invalid", model.UserStatusOffline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	res := &AnonymizeResult{}
+	if opts.EraseMessages {
+		notHeld := notHeldClause(model.LegalHoldTargetRoom, "messages.room_id")
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM attachments
+			WHERE message_id IN (SELECT id FROM messages WHERE user_id = $1 AND `+notHeld+`)`,
+			userID); err != nil {
+			return nil, fmt.Errorf("failed to delete attachments: %w", err)
+		}
+		result, err := tx.ExecContext(ctx, `
+			UPDATE messages SET content = '', is_deleted = TRUE, updated_at = NOW()
+			WHERE user_id = $1 AND NOT is_deleted AND `+notHeld, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to erase messages: %w", err)
+		}
+		if res.MessagesErased, err = result.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+	}
+	if opts.EraseDirectMessages {
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM direct_messages WHERE sender_id = $1 OR receiver_id = $1`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to erase direct messages: %w", err)
+		}
+		if res.DirectMessagesErased, err = result.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+	}
+
+	cleanup := []struct{ query, what string }{
+		{`DELETE FROM friendships WHERE user_id = $1 OR friend_id = $1`, "friendships"},
+		{`DELETE FROM blocked_users WHERE blocker_id = $1 OR blocked_id = $1`, "blocks"},
+		{`DELETE FROM room_members WHERE user_id = $1`, "room memberships"},
+		{`DELETE FROM room_join_requests WHERE user_id = $1`, "join requests"},
+		{`UPDATE scheduled_messages SET status = 'canceled' WHERE user_id = $1 AND status = 'pending'`, "scheduled messages"},
+		{`DELETE FROM notifications WHERE user_id = $1`, "notifications"},
+		{`DELETE FROM notification_preferences WHERE user_id = $1 OR (target_type = 'dm' AND target_id = $1)`, "notification preferences"},
+		{`DELETE FROM user_devices WHERE user_id = $1`, "devices"},
+	}
+	for _, step := range cleanup {
+		if _, err := tx.ExecContext(ctx, step.query, userID); err != nil {
+			return nil, fmt.Errorf("failed to clean up %s: %w", step.what, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit account deletion: %w", err)
+	}
+	return res, nil
+}
+
+// IsDeleted reports whether a user's account has been deleted
+func (r *AccountRepository) IsDeleted(ctx context.Context, userID string) (bool, error) {
+	var deleted bool
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NOT NULL)`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &deleted, query, userID); err != nil {
+		return false, fmt.Errorf("failed to check account deletion: %w", err)
+	}
+	return deleted, nil
+}
+
+// CountOwnedRooms counts the rooms a user owns that have not been deleted
+func (r *AccountRepository) CountOwnedRooms(ctx context.Context, userID string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM rooms WHERE owner_id = $1 AND deleted_at IS NULL`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count owned rooms: %w", err)
+	}
+	return count, nil
+}
+
+// ListFriendships lists every friendship and friend request involving a user
+func (r *AccountRepository) ListFriendships(ctx context.Context, userID string) ([]*model.Friendship, error) {
+	var friendships []*model.Friendship
+	query := `
+		SELECT * FROM friendships
+		WHERE user_id = $1 OR friend_id = $1
+		ORDER BY created_at`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &friendships, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list friendships: %w", err)
+	}
+	return friendships, nil
+}
+
+// ListBlocks lists the users a user has blocked
+func (r *AccountRepository) ListBlocks(ctx context.Context, userID string) ([]*model.BlockedUser, error) {
+	var blocks []*model.BlockedUser
+	query := `SELECT * FROM blocked_users WHERE blocker_id = $1 ORDER BY created_at`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &blocks, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list blocks: %w", err)
+	}
+	return blocks, nil
+}
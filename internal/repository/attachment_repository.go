@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrUploadNotFound = errors.New("upload not found")
+
+type AttachmentRepository struct {
+	db *sqlx.DB
+}
+
+func NewAttachmentRepository(db *sqlx.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// attachmentColumns selects attachments with their upload's file details
+const attachmentColumns = `
+	SELECT a.*, u.file_name, u.file_url, u.content_type, u.size, u.scan_status
+	FROM attachments a
+	JOIN uploads u ON u.id = a.upload_id`
+
+// CreateUpload records an uploaded file. An upload given an ID keeps it, so
+// a resumable upload is recorded under its session ID. Recording a file URL
+// again returns the existing upload.
+func (r *AttachmentRepository) CreateUpload(ctx context.Context, upload *model.Upload) error {
+	query := `
+		INSERT INTO uploads (id, user_id, category, file_name, file_url, file_path, content_type, size, scan_status)
+		VALUES (COALESCE(NULLIF($1, '')::uuid, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8,
+			COALESCE(NULLIF($9, ''), 'unscanned'))
+		ON CONFLICT (file_url) DO UPDATE SET file_url = EXCLUDED.file_url
+		RETURNING *`
+
+	if err := conn(ctx, r.db).GetContext(ctx, upload, query,
+		upload.ID,
+		upload.UserID,
+		upload.Category,
+		upload.FileName,
+		upload.FileURL,
+		upload.FilePath,
+		upload.ContentType,
+		upload.Size,
+		upload.ScanStatus,
+	); err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	return nil
+}
+
+// GetStorageUsage sums the sizes of a user's recorded uploads by category,
+// and of the resumable images and files still in progress
+func (r *AttachmentRepository) GetStorageUsage(ctx context.Context, userID string) (*model.StorageUsage, error) {
+	query := `
+		SELECT
+			COALESCE((SELECT SUM(size) FROM uploads WHERE user_id = $1 AND category = 'image'), 0) AS images,
+			COALESCE((SELECT SUM(size) FROM uploads WHERE user_id = $1 AND category = 'file'), 0) AS files,
+			COALESCE((SELECT SUM(total_size) FROM upload_sessions
+				WHERE user_id = $1 AND category <> 'avatar' AND completed_at IS NULL AND expires_at > NOW()), 0) AS pending`
+
+	var usage model.StorageUsage
+	if err := conn(ctx, r.db).GetContext(ctx, &usage, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// ListUploadsByIDs gets the uploads with the given IDs; unknown IDs are
+// skipped
+func (r *AttachmentRepository) ListUploadsByIDs(ctx context.Context, ids []string) ([]*model.Upload, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT * FROM uploads WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var uploads []*model.Upload
+	if err := conn(ctx, r.db).SelectContext(ctx, &uploads, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+
+	return uploads, nil
+}
+
+// GetUploadByURL gets the upload stored at a file URL
+func (r *AttachmentRepository) GetUploadByURL(ctx context.Context, url string) (*model.Upload, error) {
+	var upload model.Upload
+	if err := conn(ctx, r.db).GetContext(ctx, &upload, `SELECT * FROM uploads WHERE file_url = $1`, url); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+
+	return &upload, nil
+}
+
+// AttachToMessage attaches uploads to a room message in the given order
+func (r *AttachmentRepository) AttachToMessage(ctx context.Context, messageID string, uploadIDs []string) ([]*model.Attachment, error) {
+	return r.attach(ctx, "message_id", messageID, uploadIDs)
+}
+
+// AttachToDirectMessage attaches uploads to a direct message in the given
+// order
+func (r *AttachmentRepository) AttachToDirectMessage(ctx context.Context, dmID string, uploadIDs []string) ([]*model.Attachment, error) {
+	return r.attach(ctx, "direct_message_id", dmID, uploadIDs)
+}
+
+// attach inserts the attachments of one message; column is message_id or
+// direct_message_id
+func (r *AttachmentRepository) attach(ctx context.Context, column, id string, uploadIDs []string) ([]*model.Attachment, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	insert := `INSERT INTO attachments (upload_id, ` + column + `, position) VALUES ($1, $2, $3)`
+	for i, uploadID := range uploadIDs {
+		if _, err := tx.ExecContext(ctx, insert, uploadID, id, i); err != nil {
+			return nil, fmt.Errorf("failed to create attachment: %w", err)
+		}
+	}
+
+	var attachments []*model.Attachment
+	if err := tx.SelectContext(ctx, &attachments,
+		attachmentColumns+` WHERE a.`+column+` = $1 ORDER BY a.position`, id); err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// ListByMessageIDs lists the attachments of room messages, in order
+func (r *AttachmentRepository) ListByMessageIDs(ctx context.Context, ids []string) ([]*model.Attachment, error) {
+	return r.list(ctx, "message_id", ids)
+}
+
+// ListByDirectMessageIDs lists the attachments of direct messages, in order
+func (r *AttachmentRepository) ListByDirectMessageIDs(ctx context.Context, ids []string) ([]*model.Attachment, error) {
+	return r.list(ctx, "direct_message_id", ids)
+}
+
+func (r *AttachmentRepository) list(ctx context.Context, column string, ids []string) ([]*model.Attachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In(attachmentColumns+` WHERE a.`+column+` IN (?) ORDER BY a.position`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var attachments []*model.Attachment
+	if err := conn(ctx, r.db).SelectContext(ctx, &attachments, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// ListOrphanedUploads lists uploads made before the given time that no
+// message attaches, oldest first
+func (r *AttachmentRepository) ListOrphanedUploads(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error) {
+	query := `
+		SELECT * FROM uploads u
+		WHERE u.created_at < $1
+			AND NOT EXISTS (SELECT 1 FROM attachments a WHERE a.upload_id = u.id)
+		ORDER BY u.created_at
+		LIMIT $2`
+
+	var uploads []*model.Upload
+	if err := conn(ctx, r.db).SelectContext(ctx, &uploads, query, before, limit); err != nil {
+		return nil, fmt.Errorf("failed to list orphaned uploads: %w", err)
+	}
+
+	return uploads, nil
+}
+
+// DeleteOrphanedUpload deletes an upload unless a message attached it in
+// the meantime. It reports whether the upload was deleted.
+func (r *AttachmentRepository) DeleteOrphanedUpload(ctx context.Context, id string) (bool, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM uploads u
+		WHERE u.id = $1 AND NOT EXISTS (SELECT 1 FROM attachments a WHERE a.upload_id = u.id)`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete upload: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// SetScanResult stores the outcome of scanning an upload; filePath is where
+// the file is kept now, which changes when it is quarantined
+func (r *AttachmentRepository) SetScanResult(ctx context.Context, id string, status model.ScanStatus, signature, filePath string) error {
+	query := `
+		UPDATE uploads
+		SET scan_status = $2, scan_signature = NULLIF($3, ''), file_path = $4, scanned_at = NOW()
+		WHERE id = $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, status, signature, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to set scan result: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUploadNotFound
+	}
+	return nil
+}
+
+// ListPendingScans lists uploads made before the given time that are still
+// waiting for the scanner, oldest first
+func (r *AttachmentRepository) ListPendingScans(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error) {
+	query := `
+		SELECT * FROM uploads
+		WHERE scan_status = 'pending' AND created_at < $1
+		ORDER BY created_at
+		LIMIT $2`
+
+	var uploads []*model.Upload
+	if err := conn(ctx, r.db).SelectContext(ctx, &uploads, query, before, limit); err != nil {
+		return nil, fmt.Errorf("failed to list pending scans: %w", err)
+	}
+
+	return uploads, nil
+}
+
+// ListUploadAudience lists the rooms whose messages attach an upload and the
+// users of the direct messages that attach it
+func (r *AttachmentRepository) ListUploadAudience(ctx context.Context, uploadID string) ([]string, []string, error) {
+	var roomIDs []string
+	if err := conn(ctx, r.db).SelectContext(ctx, &roomIDs, `
+		SELECT DISTINCT m.room_id
+		FROM attachments a
+		JOIN messages m ON m.id = a.message_id
+		WHERE a.upload_id = $1`, uploadID); err != nil {
+		return nil, nil, fmt.Errorf("failed to list upload rooms: %w", err)
+	}
+
+	var userIDs []string
+	if err := conn(ctx, r.db).SelectContext(ctx, &userIDs, `
+		SELECT dm.sender_id FROM attachments a
+		JOIN direct_messages dm ON dm.id = a.direct_message_id
+		WHERE a.upload_id = $1
+		UNION
+		SELECT dm.receiver_id FROM attachments a
+		JOIN direct_messages dm ON dm.id = a.direct_message_id
+		WHERE a.upload_id = $1`, uploadID); err != nil {
+		return nil, nil, fmt.Errorf("failed to list upload users: %w", err)
+	}
+
+	return roomIDs, userIDs, nil
+}
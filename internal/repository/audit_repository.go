@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// AuditFilter narrows audit log listings; zero values are ignored
+type AuditFilter struct {
+	ActorID string
+	Action  model.AuditAction
+	Since   time.Time
+	Until   time.Time
+}
+
+// Create stores an audit log entry
+func (r *AuditRepository) Create(ctx context.Context, entry *model.AuditLog) error {
+	metadata := entry.Metadata
+	if len(metadata) == 0 {
+		metadata = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO audit_logs (actor_id, action, target_type, target_id, metadata)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		entry.ActorID,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		metadata,
+	).Scan(&entry.ID, &entry.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	entry.Metadata = metadata
+	return nil
+}
+
+// List lists audit log entries matching the filter, newest first
+func (r *AuditRepository) List(ctx context.Context, filter *AuditFilter, limit, offset int) ([]*model.AuditLog, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.ActorID != "" {
+		addCondition("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		addCondition("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		addCondition("created_at < $%d", filter.Until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT * FROM audit_logs
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+
+	var entries []*model.AuditLog
+	if err := conn(ctx, r.db).SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrBanNotFound = errors.New("ban not found")
+)
+
+type BanRepository struct {
+	db *sqlx.DB
+}
+
+func NewBanRepository(db *sqlx.DB) *BanRepository {
+	return &BanRepository{db: db}
+}
+
+// Create stores a ban, superseding any unrevoked ban of the same user
+func (r *BanRepository) Create(ctx context.Context, ban *model.UserBan) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	supersede := `
+		UPDATE user_bans SET revoked_at = NOW(), revoked_by = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, supersede, ban.UserID, ban.BannedBy); err != nil {
+		return fmt.Errorf("failed to supersede ban: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_bans (user_id, reason, banned_by, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	if err := tx.QueryRowxContext(ctx, query,
+		ban.UserID,
+		ban.Reason,
+		ban.BannedBy,
+		ban.ExpiresAt,
+	).Scan(&ban.ID, &ban.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create ban: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ban: %w", err)
+	}
+	return nil
+}
+
+// GetActiveByUserID retrieves the ban currently in effect for a user
+func (r *BanRepository) GetActiveByUserID(ctx context.Context, userID string) (*model.UserBan, error) {
+	var ban model.UserBan
+	query := `
+		SELECT * FROM user_bans
+		WHERE user_id = $1 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &ban, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBanNotFound
+		}
+		return nil, fmt.Errorf("failed to get active ban: %w", err)
+	}
+
+	return &ban, nil
+}
+
+// Revoke lifts the active ban of a user
+func (r *BanRepository) Revoke(ctx context.Context, userID, revokedBy string) error {
+	query := `
+		UPDATE user_bans SET revoked_at = NOW(), revoked_by = $2
+		WHERE user_id = $1 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, revokedBy)
+	if err != nil {
+		return fmt.Errorf("failed to revoke ban: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrBanNotFound
+	}
+
+	return nil
+}
+
+// ListActive lists bans currently in effect, newest first
+func (r *BanRepository) ListActive(ctx context.Context, limit, offset int) ([]*model.UserBan, error) {
+	query := `
+		SELECT * FROM user_bans
+		WHERE revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	var bans []*model.UserBan
+	if err := conn(ctx, r.db).SelectContext(ctx, &bans, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list active bans: %w", err)
+	}
+
+	return bans, nil
+}
+
+// ListByUserID lists the ban history of a user, newest first
+func (r *BanRepository) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.UserBan, error) {
+	query := `
+		SELECT * FROM user_bans
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	var bans []*model.UserBan
+	if err := conn(ctx, r.db).SelectContext(ctx, &bans, query, userID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+
+	return bans, nil
+}
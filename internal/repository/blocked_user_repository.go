@@ -38,7 +38,7 @@ func (r *BlockedUserRepository) Block(ctx context.Context, blockerID, blockedID
 		RETURNING id`
 
 	var id string
-	err := r.db.QueryRowxContext(ctx, query, blockerID, blockedID).Scan(&id)
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, blockerID, blockedID).Scan(&id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrAlreadyBlocked
@@ -53,7 +53,7 @@ func (r *BlockedUserRepository) Block(ctx context.Context, blockerID, blockedID
 func (r *BlockedUserRepository) Unblock(ctx context.Context, blockerID, blockedID string) error {
 	query := `DELETE FROM blocked_users WHERE blocker_id = $1 AND blocked_id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, blockerID, blockedID)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, blockerID, blockedID)
 	if err != nil {
 		return fmt.Errorf("failed to unblock user: %w", err)
 	}
@@ -74,7 +74,7 @@ func (r *BlockedUserRepository) IsBlocked(ctx context.Context, blockerID, blocke
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM blocked_users WHERE blocker_id = $1 AND blocked_id = $2)`
 
-	if err := r.db.GetContext(ctx, &exists, query, blockerID, blockedID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &exists, query, blockerID, blockedID); err != nil {
 		return false, fmt.Errorf("failed to check if blocked: %w", err)
 	}
 
@@ -91,7 +91,7 @@ func (r *BlockedUserRepository) IsBlockedEither(ctx context.Context, userID1, us
 			   OR (blocker_id = $2 AND blocked_id = $1)
 		)`
 
-	if err := r.db.GetContext(ctx, &exists, query, userID1, userID2); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &exists, query, userID1, userID2); err != nil {
 		return false, fmt.Errorf("failed to check if blocked either: %w", err)
 	}
 
@@ -109,7 +109,7 @@ func (r *BlockedUserRepository) ListBlocked(ctx context.Context, blockerID strin
 		LIMIT $2 OFFSET $3`
 
 	var users []*model.User
-	if err := r.db.SelectContext(ctx, &users, query, blockerID, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, query, blockerID, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list blocked users: %w", err)
 	}
 
@@ -134,7 +134,7 @@ func (r *FriendshipRepository) Create(ctx context.Context, userID, friendID stri
 		RETURNING id`
 
 	var id string
-	err := r.db.QueryRowxContext(ctx, query, userID, friendID).Scan(&id)
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, userID, friendID).Scan(&id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return errors.New("friend request already exists")
@@ -147,7 +147,7 @@ func (r *FriendshipRepository) Create(ctx context.Context, userID, friendID stri
 
 // Accept accepts a friend request
 func (r *FriendshipRepository) Accept(ctx context.Context, userID, friendID string) error {
-	tx, err := r.db.BeginTxx(ctx, nil)
+	tx, err := beginTx(ctx, r.db)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -193,7 +193,7 @@ func (r *FriendshipRepository) Reject(ctx context.Context, userID, friendID stri
 		SET status = 'rejected'
 		WHERE user_id = $1 AND friend_id = $2 AND status = 'pending'`
 
-	result, err := r.db.ExecContext(ctx, query, friendID, userID)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, friendID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to reject friend request: %w", err)
 	}
@@ -215,7 +215,7 @@ func (r *FriendshipRepository) Remove(ctx context.Context, userID, friendID stri
 		DELETE FROM friendships
 		WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)`
 
-	result, err := r.db.ExecContext(ctx, query, userID, friendID)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, friendID)
 	if err != nil {
 		return fmt.Errorf("failed to remove friendship: %w", err)
 	}
@@ -243,7 +243,7 @@ func (r *FriendshipRepository) ListFriends(ctx context.Context, userID string, l
 		LIMIT $2 OFFSET $3`
 
 	var friendships []*model.FriendshipWithUser
-	if err := r.db.SelectContext(ctx, &friendships, query, userID, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &friendships, query, userID, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list friends: %w", err)
 	}
 
@@ -262,7 +262,7 @@ func (r *FriendshipRepository) ListPendingRequests(ctx context.Context, userID s
 		LIMIT $2 OFFSET $3`
 
 	var friendships []*model.FriendshipWithUser
-	if err := r.db.SelectContext(ctx, &friendships, query, userID, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &friendships, query, userID, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list pending requests: %w", err)
 	}
 
@@ -281,13 +281,63 @@ func (r *FriendshipRepository) ListSentRequests(ctx context.Context, userID stri
 		LIMIT $2 OFFSET $3`
 
 	var friendships []*model.FriendshipWithUser
-	if err := r.db.SelectContext(ctx, &friendships, query, userID, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &friendships, query, userID, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list sent requests: %w", err)
 	}
 
 	return friendships, nil
 }
 
+// ListSuggestions recommends users who share friends or rooms with a
+// user, most mutual friends first. Direct message rooms do not count, and
+// users already friends or with a pending request, blocked in either
+// direction, deleted or bots are left out.
+func (r *FriendshipRepository) ListSuggestions(ctx context.Context, userID string, limit, offset int) ([]*model.FriendSuggestion, error) {
+	query := `
+		WITH mutual AS (
+			SELECT theirs.friend_id AS user_id, COUNT(*) AS mutual_friends
+			FROM friendships mine
+			INNER JOIN friendships theirs ON theirs.user_id = mine.friend_id AND theirs.status = 'accepted'
+			WHERE mine.user_id = $1 AND mine.status = 'accepted'
+			GROUP BY theirs.friend_id
+		), shared AS (
+			SELECT other.user_id, COUNT(*) AS shared_rooms
+			FROM room_members mine
+			INNER JOIN rooms r ON r.id = mine.room_id AND r.type <> 'direct'
+			INNER JOIN room_members other ON other.room_id = mine.room_id
+			WHERE mine.user_id = $1
+			GROUP BY other.user_id
+		), candidates AS (
+			SELECT COALESCE(m.user_id, s.user_id) AS user_id,
+				   COALESCE(m.mutual_friends, 0) AS mutual_friends,
+				   COALESCE(s.shared_rooms, 0) AS shared_rooms
+			FROM mutual m
+			FULL OUTER JOIN shared s ON s.user_id = m.user_id
+		)
+		SELECT u.*, c.mutual_friends, c.shared_rooms
+		FROM candidates c
+		INNER JOIN users u ON u.id = c.user_id
+		WHERE c.user_id <> $1
+		  AND u.deleted_at IS NULL AND u.is_bot = FALSE
+		  AND NOT EXISTS (
+			SELECT 1 FROM friendships f
+			WHERE (f.user_id = $1 AND f.friend_id = c.user_id) OR (f.user_id = c.user_id AND f.friend_id = $1)
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM blocked_users b
+			WHERE (b.blocker_id = $1 AND b.blocked_id = c.user_id) OR (b.blocker_id = c.user_id AND b.blocked_id = $1)
+		  )
+		ORDER BY c.mutual_friends DESC, c.shared_rooms DESC, u.username
+		LIMIT $2 OFFSET $3`
+
+	var suggestions []*model.FriendSuggestion
+	if err := conn(ctx, r.db).SelectContext(ctx, &suggestions, query, userID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list friend suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
 // AreFriends checks if two users are friends
 func (r *FriendshipRepository) AreFriends(ctx context.Context, userID, friendID string) (bool, error) {
 	var exists bool
@@ -297,7 +347,7 @@ func (r *FriendshipRepository) AreFriends(ctx context.Context, userID, friendID
 			WHERE user_id = $1 AND friend_id = $2 AND status = 'accepted'
 		)`
 
-	if err := r.db.GetContext(ctx, &exists, query, userID, friendID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &exists, query, userID, friendID); err != nil {
 		return false, fmt.Errorf("failed to check friendship: %w", err)
 	}
 
@@ -309,7 +359,7 @@ func (r *FriendshipRepository) GetFriendship(ctx context.Context, userID, friend
 	var friendship model.Friendship
 	query := `SELECT * FROM friendships WHERE user_id = $1 AND friend_id = $2`
 
-	if err := r.db.GetContext(ctx, &friendship, query, userID, friendID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &friendship, query, userID, friendID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrFriendshipNotFound
 		}
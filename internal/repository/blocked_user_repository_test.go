@@ -497,6 +497,64 @@ func TestFriendshipRepository_AreFriends(t *testing.T) {
 	}
 }
 
+func TestFriendshipRepository_ListSuggestions(t *testing.T) {
+	db, prefix := setupBlockedTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupBlockedTestByPrefix(t, db, prefix)
+
+	user := createTestUserForBlockedIsolated(t, db, prefix, "user")
+	friend := createTestUserForBlockedIsolated(t, db, prefix, "friend")
+	friendOfFriend := createTestUserForBlockedIsolated(t, db, prefix, "fof")
+	roommate := createTestUserForBlockedIsolated(t, db, prefix, "roommate")
+	blocked := createTestUserForBlockedIsolated(t, db, prefix, "blocked")
+	repo := NewFriendshipRepository(db)
+	ctx := context.Background()
+
+	befriend := func(a, b *model.User) {
+		if err := repo.Create(ctx, a.ID, b.ID); err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if err := repo.Accept(ctx, b.ID, a.ID); err != nil {
+			t.Fatalf("Failed to accept request: %v", err)
+		}
+	}
+	befriend(user, friend)
+	befriend(friend, friendOfFriend)
+	befriend(friend, blocked)
+
+	room := CreateIsolatedTestRoom(t, db, prefix, user)
+	for _, member := range []*model.User{user, friendOfFriend, roommate, blocked} {
+		if _, err := db.Exec(`INSERT INTO room_members (room_id, user_id) VALUES ($1, $2)`, room.ID, member.ID); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+	if err := NewBlockedUserRepository(db).Block(ctx, blocked.ID, user.ID); err != nil {
+		t.Fatalf("Failed to block: %v", err)
+	}
+
+	suggestions, err := repo.ListSuggestions(ctx, user.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list suggestions: %v", err)
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("Expected 2 suggestions, got %d", len(suggestions))
+	}
+	if s := suggestions[0]; s.ID != friendOfFriend.ID || s.MutualFriends != 1 || s.SharedRooms != 1 {
+		t.Errorf("Expected the friend of a friend first, got %s (%d, %d)", s.Username, s.MutualFriends, s.SharedRooms)
+	}
+	if s := suggestions[1]; s.ID != roommate.ID || s.MutualFriends != 0 || s.SharedRooms != 1 {
+		t.Errorf("Expected the roommate second, got %s (%d, %d)", s.Username, s.MutualFriends, s.SharedRooms)
+	}
+
+	page, err := repo.ListSuggestions(ctx, user.ID, 10, 1)
+	if err != nil {
+		t.Fatalf("Failed to list suggestions with offset: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != roommate.ID {
+		t.Errorf("Expected only the roommate past the first suggestion, got %d suggestions", len(page))
+	}
+}
+
 func TestFriendshipRepository_GetFriendship(t *testing.T) {
 	db, prefix := setupBlockedTestDBIsolated(t)
 	defer db.Close()
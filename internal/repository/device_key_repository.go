@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrDeviceKeysNotFound = errors.New("device keys not found")
+
+type DeviceKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewDeviceKeyRepository(db *sqlx.DB) *DeviceKeyRepository {
+	return &DeviceKeyRepository{db: db}
+}
+
+// Upsert publishes a device's identity and signed prekey along with
+// one-time prekeys. A new identity key starts over: the one-time prekeys
+// published for the old one are dropped.
+func (r *DeviceKeyRepository) Upsert(ctx context.Context, keys *model.DeviceKeys, prekeys []*model.OneTimePrekey) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var previous sql.NullString
+	if err := tx.GetContext(ctx, &previous,
+		`SELECT identity_key FROM device_keys WHERE device_id = $1 FOR UPDATE`, keys.DeviceID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get device keys: %w", err)
+	}
+
+	query := `
+		INSERT INTO device_keys (device_id, user_id, registration_id, identity_key, signed_prekey_id, signed_prekey, signed_prekey_signature)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (device_id) DO UPDATE SET
+			registration_id = EXCLUDED.registration_id,
+			identity_key = EXCLUDED.identity_key,
+			signed_prekey_id = EXCLUDED.signed_prekey_id,
+			signed_prekey = EXCLUDED.signed_prekey,
+			signed_prekey_signature = EXCLUDED.signed_prekey_signature,
+			updated_at = NOW()
+		RETURNING created_at, updated_at`
+	if err := tx.QueryRowxContext(ctx, query,
+		keys.DeviceID,
+		keys.UserID,
+		keys.RegistrationID,
+		keys.IdentityKey,
+		keys.SignedPrekeyID,
+		keys.SignedPrekey,
+		keys.SignedPrekeySignature,
+	).Scan(&keys.CreatedAt, &keys.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to store device keys: %w", err)
+	}
+
+	if previous.Valid && previous.String != keys.IdentityKey {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM device_one_time_prekeys WHERE device_id = $1`, keys.DeviceID); err != nil {
+			return fmt.Errorf("failed to drop one-time prekeys: %w", err)
+		}
+	}
+	if err := insertPrekeys(ctx, tx, keys.DeviceID, prekeys); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit device keys: %w", err)
+	}
+	return nil
+}
+
+// AddPrekeys publishes more one-time prekeys for a device with keys. Key IDs
+// already published are skipped.
+func (r *DeviceKeyRepository) AddPrekeys(ctx context.Context, deviceID string, prekeys []*model.OneTimePrekey) error {
+	var exists bool
+	if err := conn(ctx, r.db).GetContext(ctx, &exists,
+		`SELECT EXISTS(SELECT 1 FROM device_keys WHERE device_id = $1)`, deviceID); err != nil {
+		return fmt.Errorf("failed to check device keys: %w", err)
+	}
+	if !exists {
+		return ErrDeviceKeysNotFound
+	}
+	return insertPrekeys(ctx, conn(ctx, r.db), deviceID, prekeys)
+}
+
+func insertPrekeys(ctx context.Context, exec sqlx.ExecerContext, deviceID string, prekeys []*model.OneTimePrekey) error {
+	for _, prekey := range prekeys {
+		if _, err := exec.ExecContext(ctx, `
+			INSERT INTO device_one_time_prekeys (device_id, key_id, public_key)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (device_id, key_id) DO NOTHING`,
+			deviceID, prekey.KeyID, prekey.PublicKey); err != nil {
+			return fmt.Errorf("failed to store one-time prekey: %w", err)
+		}
+	}
+	return nil
+}
+
+// CountPrekeys returns how many one-time prekeys a device has left
+func (r *DeviceKeyRepository) CountPrekeys(ctx context.Context, deviceID string) (int, error) {
+	var count int
+	if err := conn(ctx, r.db).GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM device_one_time_prekeys WHERE device_id = $1`, deviceID); err != nil {
+		return 0, fmt.Errorf("failed to count one-time prekeys: %w", err)
+	}
+	return count, nil
+}
+
+// ListDeviceIDs returns the devices of a user that are not revoked and
+// have published keys
+func (r *DeviceKeyRepository) ListDeviceIDs(ctx context.Context, userID string) ([]string, error) {
+	query := `
+		SELECT dk.device_id
+		FROM device_keys dk
+		INNER JOIN user_devices d ON d.id = dk.device_id AND d.revoked_at IS NULL
+		WHERE dk.user_id = $1
+		ORDER BY dk.created_at`
+
+	var ids []string
+	if err := conn(ctx, r.db).SelectContext(ctx, &ids, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list keyed devices: %w", err)
+	}
+	return ids, nil
+}
+
+// ClaimBundles returns a prekey bundle for every keyed device of a user that
+// is not revoked. Each bundle takes one of the device's one-time prekeys,
+// which is deleted so no other initiator gets it.
+func (r *DeviceKeyRepository) ClaimBundles(ctx context.Context, userID string) ([]*model.PrekeyBundle, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var keys []*model.DeviceKeys
+	if err := tx.SelectContext(ctx, &keys, `
+		SELECT dk.*
+		FROM device_keys dk
+		INNER JOIN user_devices d ON d.id = dk.device_id AND d.revoked_at IS NULL
+		WHERE dk.user_id = $1
+		ORDER BY dk.created_at`, userID); err != nil {
+		return nil, fmt.Errorf("failed to list device keys: %w", err)
+	}
+
+	claim := `
+		DELETE FROM device_one_time_prekeys
+		WHERE (device_id, key_id) = (
+			SELECT device_id, key_id FROM device_one_time_prekeys
+			WHERE device_id = $1
+			ORDER BY key_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING key_id, public_key`
+
+	bundles := make([]*model.PrekeyBundle, len(keys))
+	for i, k := range keys {
+		bundles[i] = &model.PrekeyBundle{DeviceKeys: *k}
+
+		var prekey model.OneTimePrekey
+		if err := tx.GetContext(ctx, &prekey, claim, k.DeviceID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to claim one-time prekey: %w", err)
+		}
+		bundles[i].OneTimePrekey = &prekey
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit prekey claims: %w", err)
+	}
+	return bundles, nil
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrDeviceNotFound       = errors.New("device not found")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token already rotated or revoked")
+)
+
+type DeviceRepository struct {
+	db *sqlx.DB
+}
+
+func NewDeviceRepository(db *sqlx.DB) *DeviceRepository {
+	return &DeviceRepository{db: db}
+}
+
+// Create registers a device
+func (r *DeviceRepository) Create(ctx context.Context, device *model.UserDevice) error {
+	query := `
+		INSERT INTO user_devices (user_id, name, user_agent, last_ip)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, last_seen_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		device.UserID,
+		device.Name,
+		device.UserAgent,
+		device.LastIP,
+	).Scan(&device.ID, &device.CreatedAt, &device.LastSeenAt); err != nil {
+		return fmt.Errorf("failed to create device: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a device by ID
+func (r *DeviceRepository) GetByID(ctx context.Context, id string) (*model.UserDevice, error) {
+	var device model.UserDevice
+	query := `SELECT * FROM user_devices WHERE id = $1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &device, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+
+	return &device, nil
+}
+
+// Touch records that a device was just used
+func (r *DeviceRepository) Touch(ctx context.Context, id, ip string) error {
+	query := `
+		UPDATE user_devices
+		SET last_seen_at = NOW(), last_ip = COALESCE(NULLIF($2, ''), last_ip)
+		WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, ip); err != nil {
+		return fmt.Errorf("failed to touch device: %w", err)
+	}
+	return nil
+}
+
+// ListByUserID lists a user's devices, most recently used first
+func (r *DeviceRepository) ListByUserID(ctx context.Context, userID string) ([]*model.UserDevice, error) {
+	query := `
+		SELECT * FROM user_devices
+		WHERE user_id = $1
+		ORDER BY revoked_at IS NOT NULL, last_seen_at DESC`
+
+	var devices []*model.UserDevice
+	if err := conn(ctx, r.db).SelectContext(ctx, &devices, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// Revoke revokes a user's device together with its refresh tokens
+func (r *DeviceRepository) Revoke(ctx context.Context, userID, deviceID string) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE user_devices SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, deviceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke device: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrDeviceNotFound
+	}
+
+	if err := revokeDeviceTokens(ctx, tx, deviceID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit device revocation: %w", err)
+	}
+	return nil
+}
+
+// RevokeTokens revokes every outstanding refresh token of a device, ending
+// its session without revoking the device itself
+func (r *DeviceRepository) RevokeTokens(ctx context.Context, deviceID string) error {
+	return revokeDeviceTokens(ctx, conn(ctx, r.db), deviceID)
+}
+
+func revokeDeviceTokens(ctx context.Context, exec sqlx.ExecerContext, deviceID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE device_id = $1 AND revoked_at IS NULL`
+	if _, err := exec.ExecContext(ctx, query, deviceID); err != nil {
+		return fmt.Errorf("failed to revoke device tokens: %w", err)
+	}
+	return nil
+}
+
+// CreateToken records a newly issued refresh token
+func (r *DeviceRepository) CreateToken(ctx context.Context, token *model.RefreshTokenRecord) error {
+	query := `
+		INSERT INTO refresh_tokens (id, device_id, user_id, parent_id, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING issued_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		token.ID,
+		token.DeviceID,
+		token.UserID,
+		token.ParentID,
+		token.IP,
+		token.ExpiresAt,
+	).Scan(&token.IssuedAt); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// GetToken retrieves a refresh token record by its token ID
+func (r *DeviceRepository) GetToken(ctx context.Context, id string) (*model.RefreshTokenRecord, error) {
+	var token model.RefreshTokenRecord
+	query := `SELECT * FROM refresh_tokens WHERE id = $1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &token, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// RotateToken marks a refresh token as used and records its successor. It
+// fails with ErrRefreshTokenReused if the token was already rotated or revoked.
+func (r *DeviceRepository) RotateToken(ctx context.Context, oldID string, next *model.RefreshTokenRecord) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET rotated_at = NOW()
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL`, oldID)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	query := `
+		INSERT INTO refresh_tokens (id, device_id, user_id, parent_id, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING issued_at`
+	if err := tx.QueryRowxContext(ctx, query,
+		next.ID,
+		next.DeviceID,
+		next.UserID,
+		next.ParentID,
+		next.IP,
+		next.ExpiresAt,
+	).Scan(&next.IssuedAt); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return nil
+}
+
+// ListTokens lists the most recent refresh tokens issued to a device
+func (r *DeviceRepository) ListTokens(ctx context.Context, deviceID string, limit int) ([]*model.RefreshTokenRecord, error) {
+	query := `
+		SELECT * FROM refresh_tokens
+		WHERE device_id = $1
+		ORDER BY issued_at DESC
+		LIMIT $2`
+
+	var tokens []*model.RefreshTokenRecord
+	if err := conn(ctx, r.db).SelectContext(ctx, &tokens, query, deviceID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+
+	return tokens, nil
+}
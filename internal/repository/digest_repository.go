@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// DigestRepository finds users due a digest email and the unread activity
+// their digest summarizes
+type DigestRepository struct {
+	db *sqlx.DB
+}
+
+func NewDigestRepository(db *sqlx.DB) *DigestRepository {
+	return &DigestRepository{db: db}
+}
+
+// ListDue returns up to limit users who opted into digests and whose last
+// one was sent at least a period before asOf, never-sent users first
+func (r *DigestRepository) ListDue(ctx context.Context, asOf time.Time, limit int) ([]*model.User, error) {
+	query := `
+		SELECT * FROM users
+		WHERE digest_frequency <> 'off' AND deleted_at IS NULL AND is_bot = FALSE
+		  AND (digest_sent_at IS NULL OR digest_sent_at <= $1 - CASE digest_frequency
+				WHEN 'weekly' THEN INTERVAL '7 days'
+				ELSE INTERVAL '1 day'
+			END)
+		ORDER BY digest_sent_at NULLS FIRST
+		LIMIT $2`
+
+	var users []*model.User
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, query, asOf, limit); err != nil {
+		return nil, fmt.Errorf("failed to list due digests: %w", err)
+	}
+
+	return users, nil
+}
+
+// GetActivity returns what a user has not read yet that arrived after
+// since: messages in rooms, direct messages grouped by sender and pending
+// friend requests. Rooms and conversations the user muted are left out.
+func (r *DigestRepository) GetActivity(ctx context.Context, userID string, since time.Time) (*model.DigestActivity, error) {
+	activity := &model.DigestActivity{}
+
+	roomQuery := `
+		SELECT r.id as room_id, r.name, COUNT(m.id) as unread
+		FROM room_members rm
+		INNER JOIN rooms r ON r.id = rm.room_id AND r.deleted_at IS NULL
+		INNER JOIN messages m ON m.room_id = rm.room_id
+			AND m.user_id <> rm.user_id
+			AND m.is_deleted = FALSE
+			AND m.created_at > GREATEST(rm.last_read_at, $2)
+		WHERE rm.user_id = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM notification_preferences np
+			WHERE np.user_id = rm.user_id AND np.target_type = 'room'
+			  AND np.target_id = rm.room_id AND np.level = 'none'
+		  )
+		GROUP BY r.id, r.name
+		ORDER BY unread DESC, r.name`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &activity.Rooms, roomQuery, userID, since); err != nil {
+		return nil, fmt.Errorf("failed to list unread rooms: %w", err)
+	}
+
+	dmQuery := `
+		SELECT u.id as user_id, u.username,
+			COALESCE(NULLIF(u.display_name, ''), u.username) as display_name,
+			COUNT(dm.id) as unread
+		FROM direct_messages dm
+		INNER JOIN users u ON u.id = dm.sender_id
+		WHERE dm.receiver_id = $1 AND dm.is_read = FALSE AND dm.is_deleted_by_receiver = FALSE
+		  AND dm.created_at > $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM notification_preferences np
+			WHERE np.user_id = dm.receiver_id AND np.target_type = 'dm'
+			  AND np.target_id = dm.sender_id AND np.level = 'none'
+		  )
+		GROUP BY u.id, u.username, u.display_name
+		ORDER BY unread DESC, u.username`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &activity.DMs, dmQuery, userID, since); err != nil {
+		return nil, fmt.Errorf("failed to list unread direct messages: %w", err)
+	}
+
+	friendQuery := `
+		SELECT u.id as user_id, u.username,
+			COALESCE(NULLIF(u.display_name, ''), u.username) as display_name
+		FROM friendships f
+		INNER JOIN users u ON u.id = f.user_id AND u.deleted_at IS NULL
+		WHERE f.friend_id = $1 AND f.status = 'pending' AND f.created_at > $2
+		ORDER BY f.created_at DESC`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &activity.FriendRequests, friendQuery, userID, since); err != nil {
+		return nil, fmt.Errorf("failed to list pending friend requests: %w", err)
+	}
+
+	return activity, nil
+}
+
+// MarkSent records when a user's digest was sent, or checked and found
+// empty, so the next one starts from there
+func (r *DigestRepository) MarkSent(ctx context.Context, userID string, at time.Time) error {
+	query := `UPDATE users SET digest_sent_at = $2 WHERE id = $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark digest sent: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
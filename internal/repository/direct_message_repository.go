@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-demo/chat/internal/model"
 	"github.com/jmoiron/sqlx"
@@ -24,17 +25,141 @@ func NewDirectMessageRepository(db *sqlx.DB) *DirectMessageRepository {
 
 // Create creates a new direct message
 func (r *DirectMessageRepository) Create(ctx context.Context, msg *model.DirectMessage) error {
-	query := `
-		INSERT INTO direct_messages (sender_id, receiver_id, content, type)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at`
+	return createDirectMessage(ctx, conn(ctx, r.db), msg)
+}
+
+// CreateEncrypted creates an end-to-end encrypted direct message together
+// with its per-device envelopes
+func (r *DirectMessageRepository) CreateEncrypted(ctx context.Context, msg *model.DirectMessage, envelopes []*model.DMEnvelope) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := createDirectMessage(ctx, tx, msg); err != nil {
+		return fmt.Errorf("failed to create direct message: %w", err)
+	}
+	for _, env := range envelopes {
+		env.MessageID = msg.ID
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO direct_message_envelopes (message_id, device_id, sender_device_id, type, ciphertext)
+			VALUES ($1, $2, $3, $4, $5)`,
+			env.MessageID, env.DeviceID, env.SenderDeviceID, env.Type, env.Ciphertext); err != nil {
+			return fmt.Errorf("failed to create message envelope: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit direct message: %w", err)
+	}
+	return nil
+}
+
+// ListEnvelopes returns the envelopes addressed to a device among the given
+// messages
+func (r *DirectMessageRepository) ListEnvelopes(ctx context.Context, deviceID string, messageIDs []string) ([]*model.DMEnvelope, error) {
+	if len(messageIDs) == 0 {
+		return []*model.DMEnvelope{}, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT * FROM direct_message_envelopes
+		WHERE device_id = ? AND message_id IN (?)`, deviceID, messageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var envelopes []*model.DMEnvelope
+	if err := conn(ctx, r.db).SelectContext(ctx, &envelopes, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list message envelopes: %w", err)
+	}
+
+	return envelopes, nil
+}
 
-	return r.db.QueryRowxContext(ctx, query,
+func createDirectMessage(ctx context.Context, q sqlx.QueryerContext, msg *model.DirectMessage) error {
+	query := `
+		INSERT INTO direct_messages (sender_id, receiver_id, content, type, forwarded_from, expires_at)
+		VALUES ($1, $2, $3, $4, $5, (
+			-- Disappearing messages: the conversation's TTL at send time fixes the expiry
+			SELECT CASE WHEN message_ttl_seconds > 0 THEN NOW() + make_interval(secs => message_ttl_seconds) END
+			FROM direct_conversation_settings
+			WHERE user_low = LEAST($1::uuid, $2::uuid) AND user_high = GREATEST($1::uuid, $2::uuid)
+		))
+		RETURNING id, created_at, updated_at, expires_at`
+
+	return q.QueryRowxContext(ctx, query,
 		msg.SenderID,
 		msg.ReceiverID,
 		msg.Content,
 		msg.Type,
-	).Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt)
+		nullJSON(msg.ForwardedFrom),
+	).Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt, &msg.ExpiresAt)
+}
+
+// GetSettings returns the shared settings of the conversation between two
+// users, or defaults when none were stored
+func (r *DirectMessageRepository) GetSettings(ctx context.Context, userID1, userID2 string) (*model.DirectConversationSettings, error) {
+	var settings model.DirectConversationSettings
+	query := `
+		SELECT * FROM direct_conversation_settings
+		WHERE user_low = LEAST($1::uuid, $2::uuid) AND user_high = GREATEST($1::uuid, $2::uuid)`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &settings, query, userID1, userID2); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &model.DirectConversationSettings{}, nil
+		}
+		return nil, fmt.Errorf("failed to get conversation settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// SetMessageTTL sets the message TTL of the conversation between two users
+func (r *DirectMessageRepository) SetMessageTTL(ctx context.Context, userID1, userID2 string, ttlSeconds int, updatedBy string) (*model.DirectConversationSettings, error) {
+	var settings model.DirectConversationSettings
+	query := `
+		INSERT INTO direct_conversation_settings (user_low, user_high, message_ttl_seconds, updated_by)
+		VALUES (LEAST($1::uuid, $2::uuid), GREATEST($1::uuid, $2::uuid), $3, $4)
+		ON CONFLICT (user_low, user_high) DO UPDATE
+		SET message_ttl_seconds = EXCLUDED.message_ttl_seconds,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING *`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &settings, query, userID1, userID2, ttlSeconds, updatedBy); err != nil {
+		return nil, fmt.Errorf("failed to set conversation message ttl: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// ExpireDue deletes up to limit direct messages whose TTL has elapsed for
+// both participants and returns them. Messages of users under legal hold
+// are kept until the hold is released.
+func (r *DirectMessageRepository) ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.DirectMessage, error) {
+	query := `
+		UPDATE direct_messages
+		SET is_deleted_by_sender = true, is_deleted_by_receiver = true, content = '[訊息已刪除]'
+		WHERE id IN (
+			SELECT id FROM direct_messages
+			WHERE expires_at <= $1
+				AND NOT (is_deleted_by_sender AND is_deleted_by_receiver)
+				AND ` + notHeldClause(model.LegalHoldTargetUser, "direct_messages.sender_id") + `
+				AND ` + notHeldClause(model.LegalHoldTargetUser, "direct_messages.receiver_id") + `
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	var messages []*model.DirectMessage
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to expire direct messages: %w", err)
+	}
+
+	return messages, nil
 }
 
 // GetByID retrieves a direct message by ID
@@ -42,7 +167,7 @@ func (r *DirectMessageRepository) GetByID(ctx context.Context, id string) (*mode
 	var msg model.DirectMessage
 	query := `SELECT * FROM direct_messages WHERE id = $1`
 
-	if err := r.db.GetContext(ctx, &msg, query, id); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &msg, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrDirectMessageNotFound
 		}
@@ -61,7 +186,7 @@ func (r *DirectMessageRepository) GetByIDWithUser(ctx context.Context, id string
 		INNER JOIN users u ON dm.sender_id = u.id
 		WHERE dm.id = $1`
 
-	if err := r.db.GetContext(ctx, &msg, query, id); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &msg, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrDirectMessageNotFound
 		}
@@ -71,6 +196,26 @@ func (r *DirectMessageRepository) GetByIDWithUser(ctx context.Context, id string
 	return &msg, nil
 }
 
+// UpdateContent replaces the content of a direct message and marks it edited
+func (r *DirectMessageRepository) UpdateContent(ctx context.Context, id, content string) error {
+	query := `UPDATE direct_messages SET content = $2, is_edited = true WHERE id = $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, content)
+	if err != nil {
+		return fmt.Errorf("failed to update direct message: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrDirectMessageNotFound
+	}
+
+	return nil
+}
+
 // ListConversation retrieves messages between two users
 func (r *DirectMessageRepository) ListConversation(ctx context.Context, userID1, userID2 string, limit, offset int) ([]*model.DirectMessageWithUser, error) {
 	query := `
@@ -86,7 +231,7 @@ func (r *DirectMessageRepository) ListConversation(ctx context.Context, userID1,
 		LIMIT $3 OFFSET $4`
 
 	var messages []*model.DirectMessageWithUser
-	if err := r.db.SelectContext(ctx, &messages, query, userID1, userID2, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, userID1, userID2, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list conversation: %w", err)
 	}
 
@@ -98,8 +243,10 @@ func (r *DirectMessageRepository) ListConversation(ctx context.Context, userID1,
 	return messages, nil
 }
 
-// ListConversations lists all conversations for a user
-func (r *DirectMessageRepository) ListConversations(ctx context.Context, userID string, limit, offset int) ([]*model.Conversation, error) {
+// ListConversations lists the conversations of a user, pinned ones first.
+// Archived conversations are listed only when archived is true; hidden ones
+// are left out until a message newer than the hiding arrives.
+func (r *DirectMessageRepository) ListConversations(ctx context.Context, userID string, archived bool, limit, offset int) ([]*model.Conversation, error) {
 	query := `
 		WITH latest_messages AS (
 			SELECT DISTINCT ON (
@@ -131,21 +278,56 @@ func (r *DirectMessageRepository) ListConversations(ctx context.Context, userID
 			u.status,
 			lm.last_message,
 			lm.last_message_at,
-			COALESCE(uc.unread_count, 0) as unread_count
+			COALESCE(uc.unread_count, 0) as unread_count,
+			cs.archived_at IS NOT NULL as is_archived,
+			cs.pinned_at IS NOT NULL as is_pinned
 		FROM latest_messages lm
 		INNER JOIN users u ON lm.other_user_id = u.id
 		LEFT JOIN unread_counts uc ON u.id = uc.sender_id
-		ORDER BY lm.last_message_at DESC
-		LIMIT $2 OFFSET $3`
+		LEFT JOIN conversation_settings cs ON cs.user_id = $1 AND cs.other_user_id = u.id
+		WHERE (cs.hidden_at IS NULL OR lm.last_message_at > cs.hidden_at)
+			AND (cs.archived_at IS NOT NULL) = $2
+		ORDER BY cs.pinned_at DESC NULLS LAST, lm.last_message_at DESC
+		LIMIT $3 OFFSET $4`
 
 	var conversations []*model.Conversation
-	if err := r.db.SelectContext(ctx, &conversations, query, userID, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &conversations, query, userID, archived, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list conversations: %w", err)
 	}
 
 	return conversations, nil
 }
 
+// conversationStateColumns maps each conversation state to its column
+var conversationStateColumns = map[model.ConversationState]string{
+	model.ConversationArchived: "archived_at",
+	model.ConversationHidden:   "hidden_at",
+	model.ConversationPinned:   "pinned_at",
+}
+
+// SetConversationState sets or clears one of a user's states of the
+// conversation with another user and returns all of them
+func (r *DirectMessageRepository) SetConversationState(ctx context.Context, userID, otherUserID string, state model.ConversationState, on bool) (*model.ConversationSettings, error) {
+	column, ok := conversationStateColumns[state]
+	if !ok {
+		return nil, fmt.Errorf("unknown conversation state %q", state)
+	}
+
+	var settings model.ConversationSettings
+	query := fmt.Sprintf(`
+		INSERT INTO conversation_settings (user_id, other_user_id, %[1]s)
+		VALUES ($1, $2, CASE WHEN $3 THEN NOW() END)
+		ON CONFLICT (user_id, other_user_id) DO UPDATE
+		SET %[1]s = EXCLUDED.%[1]s
+		RETURNING *`, column)
+
+	if err := conn(ctx, r.db).GetContext(ctx, &settings, query, userID, otherUserID, on); err != nil {
+		return nil, fmt.Errorf("failed to set conversation state: %w", err)
+	}
+
+	return &settings, nil
+}
+
 // MarkAsRead marks messages as read
 func (r *DirectMessageRepository) MarkAsRead(ctx context.Context, senderID, receiverID string) error {
 	query := `
@@ -153,7 +335,7 @@ func (r *DirectMessageRepository) MarkAsRead(ctx context.Context, senderID, rece
 		SET is_read = true
 		WHERE sender_id = $1 AND receiver_id = $2 AND is_read = false`
 
-	_, err := r.db.ExecContext(ctx, query, senderID, receiverID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, senderID, receiverID)
 	if err != nil {
 		return fmt.Errorf("failed to mark as read: %w", err)
 	}
@@ -178,7 +360,7 @@ func (r *DirectMessageRepository) DeleteForUser(ctx context.Context, messageID,
 		return fmt.Errorf("user is not part of this conversation")
 	}
 
-	_, err = r.db.ExecContext(ctx, query, messageID)
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, messageID)
 	if err != nil {
 		return fmt.Errorf("failed to delete message for user: %w", err)
 	}
@@ -186,6 +368,47 @@ func (r *DirectMessageRepository) DeleteForUser(ctx context.Context, messageID,
 	return nil
 }
 
+// ListForExport retrieves direct messages sent or received by a user in
+// chronological order after the given position, including deleted ones
+func (r *DirectMessageRepository) ListForExport(ctx context.Context, userID string, after *ExportCursor, limit int) ([]*model.DirectMessage, error) {
+	query := `
+		SELECT * FROM direct_messages
+		WHERE (sender_id = $1 OR receiver_id = $1) AND (created_at, id) > ($2, $3::uuid)
+		ORDER BY created_at, id
+		LIMIT $4`
+
+	var messages []*model.DirectMessage
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, userID, after.CreatedAt, after.ID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list direct messages for export: %w", err)
+	}
+
+	return messages, nil
+}
+
+// ListConversationForExport retrieves the messages between two users that
+// userID has not deleted for themselves, in chronological order after the
+// given position
+func (r *DirectMessageRepository) ListConversationForExport(ctx context.Context, userID, peerID string, after *ExportCursor, limit int) ([]*model.DirectMessageWithUser, error) {
+	query := `
+		SELECT dm.*, u.username as sender_username, u.display_name as sender_display_name, u.avatar_url as sender_avatar_url
+		FROM direct_messages dm
+		INNER JOIN users u ON dm.sender_id = u.id
+		WHERE (
+			(dm.sender_id = $1 AND dm.receiver_id = $2 AND dm.is_deleted_by_sender = false)
+			OR
+			(dm.sender_id = $2 AND dm.receiver_id = $1 AND dm.is_deleted_by_receiver = false)
+		) AND (dm.created_at, dm.id) > ($3, $4::uuid)
+		ORDER BY dm.created_at, dm.id
+		LIMIT $5`
+
+	var messages []*model.DirectMessageWithUser
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, userID, peerID, after.CreatedAt, after.ID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list conversation for export: %w", err)
+	}
+
+	return messages, nil
+}
+
 // CountUnread counts unread messages for a user
 func (r *DirectMessageRepository) CountUnread(ctx context.Context, userID string) (int, error) {
 	var count int
@@ -194,7 +417,7 @@ func (r *DirectMessageRepository) CountUnread(ctx context.Context, userID string
 		FROM direct_messages
 		WHERE receiver_id = $1 AND is_read = false AND is_deleted_by_receiver = false`
 
-	if err := r.db.GetContext(ctx, &count, query, userID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &count, query, userID); err != nil {
 		return 0, fmt.Errorf("failed to count unread: %w", err)
 	}
 
@@ -209,7 +432,7 @@ func (r *DirectMessageRepository) CountUnreadFromUser(ctx context.Context, recei
 		FROM direct_messages
 		WHERE receiver_id = $1 AND sender_id = $2 AND is_read = false AND is_deleted_by_receiver = false`
 
-	if err := r.db.GetContext(ctx, &count, query, receiverID, senderID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &count, query, receiverID, senderID); err != nil {
 		return 0, fmt.Errorf("failed to count unread from user: %w", err)
 	}
 
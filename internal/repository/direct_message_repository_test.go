@@ -152,7 +152,7 @@ func TestDirectMessageRepository_ListConversations(t *testing.T) {
 		t.Fatalf("Failed to create DM: %v", err)
 	}
 
-	conversations, err := repo.ListConversations(ctx, user.ID, 10, 0)
+	conversations, err := repo.ListConversations(ctx, user.ID, false, 10, 0)
 	if err != nil {
 		t.Fatalf("Failed to list conversations: %v", err)
 	}
@@ -162,6 +162,73 @@ func TestDirectMessageRepository_ListConversations(t *testing.T) {
 	}
 }
 
+func TestDirectMessageRepository_SetConversationState(t *testing.T) {
+	db, prefix := setupDMTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupDMTestByPrefix(t, db, prefix)
+
+	user := createTestUserForDMIsolated(t, db, prefix, "dm_state_user")
+	contact1 := createTestUserForDMIsolated(t, db, prefix, "dm_state_contact1")
+	contact2 := createTestUserForDMIsolated(t, db, prefix, "dm_state_contact2")
+	contact3 := createTestUserForDMIsolated(t, db, prefix, "dm_state_contact3")
+	repo := NewDirectMessageRepository(db)
+	ctx := context.Background()
+
+	send := func(from *model.User) {
+		t.Helper()
+		if err := repo.Create(ctx, &model.DirectMessage{
+			SenderID: from.ID, ReceiverID: user.ID, Content: "Hi", Type: model.MessageTypeText,
+		}); err != nil {
+			t.Fatalf("Failed to create DM: %v", err)
+		}
+	}
+	list := func(archived bool) []*model.Conversation {
+		t.Helper()
+		conversations, err := repo.ListConversations(ctx, user.ID, archived, 10, 0)
+		if err != nil {
+			t.Fatalf("Failed to list conversations: %v", err)
+		}
+		return conversations
+	}
+	set := func(other *model.User, state model.ConversationState, on bool) {
+		t.Helper()
+		if _, err := repo.SetConversationState(ctx, user.ID, other.ID, state, on); err != nil {
+			t.Fatalf("Failed to set %s: %v", state, err)
+		}
+	}
+
+	send(contact1)
+	send(contact2)
+	send(contact3)
+
+	// The oldest conversation is pinned to the top
+	set(contact1, model.ConversationPinned, true)
+	conversations := list(false)
+	if len(conversations) != 3 || conversations[0].UserID != contact1.ID || !conversations[0].IsPinned {
+		t.Fatalf("Expected the pinned conversation first, got %+v", conversations)
+	}
+
+	set(contact2, model.ConversationArchived, true)
+	set(contact3, model.ConversationHidden, true)
+	if conversations := list(false); len(conversations) != 1 {
+		t.Errorf("Expected 1 conversation, got %d", len(conversations))
+	}
+	if archived := list(true); len(archived) != 1 || archived[0].UserID != contact2.ID || !archived[0].IsArchived {
+		t.Errorf("Expected the archived conversation, got %+v", archived)
+	}
+
+	// A new message brings a hidden conversation back
+	send(contact3)
+	if conversations := list(false); len(conversations) != 2 {
+		t.Errorf("Expected 2 conversations, got %d", len(conversations))
+	}
+
+	set(contact2, model.ConversationArchived, false)
+	if archived := list(true); len(archived) != 0 {
+		t.Errorf("Expected no archived conversations, got %d", len(archived))
+	}
+}
+
 func TestDirectMessageRepository_MarkAsRead(t *testing.T) {
 	db, prefix := setupDMTestDBIsolated(t)
 	defer db.Close()
@@ -302,3 +369,137 @@ func TestDirectMessageRepository_CountUnreadFromUser(t *testing.T) {
 		t.Errorf("Expected 2 unread messages from sender, got %d", count)
 	}
 }
+
+func TestDirectMessageRepository_ListConversationForExport(t *testing.T) {
+	db, prefix := setupDMTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupDMTestByPrefix(t, db, prefix)
+
+	alice := createTestUserForDMIsolated(t, db, prefix, "dm_alice")
+	bob := createTestUserForDMIsolated(t, db, prefix, "dm_bob")
+	repo := NewDirectMessageRepository(db)
+	ctx := context.Background()
+
+	var ids []string
+	for i, sender := range []*model.User{alice, bob, alice} {
+		receiver := bob
+		if sender == bob {
+			receiver = alice
+		}
+		dm := &model.DirectMessage{SenderID: sender.ID, ReceiverID: receiver.ID, Content: string(rune('a' + i)), Type: model.MessageTypeText}
+		if err := repo.Create(ctx, dm); err != nil {
+			t.Fatalf("Failed to create DM: %v", err)
+		}
+		ids = append(ids, dm.ID)
+	}
+
+	// Alice deletes the message Bob sent her; only her copy loses it
+	if err := repo.DeleteForUser(ctx, ids[1], alice.ID); err != nil {
+		t.Fatalf("Failed to delete DM for user: %v", err)
+	}
+
+	messages, err := repo.ListConversationForExport(ctx, alice.ID, bob.ID, NewExportCursor(), 10)
+	if err != nil {
+		t.Fatalf("Failed to list conversation for export: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != ids[0] || messages[1].ID != ids[2] {
+		t.Errorf("Expected Alice's copy without the deleted message, got %d messages", len(messages))
+	}
+
+	messages, err = repo.ListConversationForExport(ctx, bob.ID, alice.ID, NewExportCursor(), 10)
+	if err != nil {
+		t.Fatalf("Failed to list conversation for export: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Errorf("Expected Bob's copy to keep all 3 messages, got %d", len(messages))
+	}
+
+	// Paging resumes after the cursor
+	cursor := NewExportCursor()
+	cursor.Advance(messages[0].CreatedAt, messages[0].ID)
+	rest, err := repo.ListConversationForExport(ctx, bob.ID, alice.ID, cursor, 10)
+	if err != nil {
+		t.Fatalf("Failed to list conversation for export: %v", err)
+	}
+	if len(rest) != 2 || rest[0].ID != messages[1].ID {
+		t.Errorf("Expected the 2 messages after the cursor, got %d", len(rest))
+	}
+}
+
+func TestDirectMessageRepository_CreateEncrypted(t *testing.T) {
+	db, prefix := setupDMTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupDMTestByPrefix(t, db, prefix)
+
+	sender := createTestUserForDMIsolated(t, db, prefix, "dm_sender")
+	receiver := createTestUserForDMIsolated(t, db, prefix, "dm_receiver")
+	repo := NewDirectMessageRepository(db)
+	deviceRepo := NewDeviceRepository(db)
+	keyRepo := NewDeviceKeyRepository(db)
+	ctx := context.Background()
+
+	senderDevice := &model.UserDevice{UserID: sender.ID, Name: "phone"}
+	receiverDevice := &model.UserDevice{UserID: receiver.ID, Name: "laptop"}
+	for _, device := range []*model.UserDevice{senderDevice, receiverDevice} {
+		if err := deviceRepo.Create(ctx, device); err != nil {
+			t.Fatalf("Failed to create device: %v", err)
+		}
+	}
+
+	keys := &model.DeviceKeys{
+		DeviceID:              receiverDevice.ID,
+		UserID:                receiver.ID,
+		IdentityKey:           "identity",
+		SignedPrekey:          "signed",
+		SignedPrekeySignature: "signature",
+	}
+	prekeys := []*model.OneTimePrekey{{KeyID: 1, PublicKey: "one"}, {KeyID: 2, PublicKey: "two"}}
+	if err := keyRepo.Upsert(ctx, keys, prekeys); err != nil {
+		t.Fatalf("Failed to store device keys: %v", err)
+	}
+
+	// Each bundle hands out a different one-time prekey until they run out
+	for _, want := range []int{1, 2, -1} {
+		bundles, err := keyRepo.ClaimBundles(ctx, receiver.ID)
+		if err != nil {
+			t.Fatalf("Failed to claim bundles: %v", err)
+		}
+		if len(bundles) != 1 || bundles[0].DeviceID != receiverDevice.ID {
+			t.Fatalf("Expected one bundle for the receiver's device, got %d", len(bundles))
+		}
+		got := -1
+		if bundles[0].OneTimePrekey != nil {
+			got = bundles[0].OneTimePrekey.KeyID
+		}
+		if got != want {
+			t.Errorf("Expected one-time prekey %d, got %d", want, got)
+		}
+	}
+
+	dm := &model.DirectMessage{SenderID: sender.ID, ReceiverID: receiver.ID, Type: model.MessageTypeCiphertext}
+	envelopes := []*model.DMEnvelope{{
+		DeviceID:       receiverDevice.ID,
+		SenderDeviceID: senderDevice.ID,
+		Type:           model.EnvelopeTypePrekey,
+		Ciphertext:     "c2VjcmV0",
+	}}
+	if err := repo.CreateEncrypted(ctx, dm, envelopes); err != nil {
+		t.Fatalf("Failed to create encrypted message: %v", err)
+	}
+
+	found, err := repo.ListEnvelopes(ctx, receiverDevice.ID, []string{dm.ID})
+	if err != nil {
+		t.Fatalf("Failed to list envelopes: %v", err)
+	}
+	if len(found) != 1 || found[0].Ciphertext != "c2VjcmV0" || found[0].SenderDeviceID != senderDevice.ID {
+		t.Errorf("Expected the receiver's envelope, got %d", len(found))
+	}
+
+	found, err = repo.ListEnvelopes(ctx, senderDevice.ID, []string{dm.ID})
+	if err != nil {
+		t.Fatalf("Failed to list envelopes: %v", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("Expected no envelope for another device, got %d", len(found))
+	}
+}
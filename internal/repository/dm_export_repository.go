@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDMExportNotFound   = errors.New("dm export not found")
+	ErrDMExportInProgress = errors.New("dm export already in progress")
+)
+
+type DMExportRepository struct {
+	db *sqlx.DB
+}
+
+func NewDMExportRepository(db *sqlx.DB) *DMExportRepository {
+	return &DMExportRepository{db: db}
+}
+
+// Create queues an export. A user may have one export of a conversation
+// pending or running at a time.
+func (r *DMExportRepository) Create(ctx context.Context, export *model.DMExport) error {
+	query := `
+		INSERT INTO dm_exports (user_id, peer_id)
+		VALUES ($1, $2)
+		RETURNING id, status, message_count, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		export.UserID,
+		export.PeerID,
+	).Scan(&export.ID, &export.Status, &export.MessageCount, &export.CreatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrDMExportInProgress
+		}
+		return fmt.Errorf("failed to create dm export: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets an export by ID
+func (r *DMExportRepository) GetByID(ctx context.Context, id string) (*model.DMExport, error) {
+	var export model.DMExport
+	query := `SELECT * FROM dm_exports WHERE id = $1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &export, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDMExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get dm export: %w", err)
+	}
+
+	return &export, nil
+}
+
+// ClaimPending marks up to limit pending exports as running and returns
+// them, oldest first. SKIP LOCKED lets several instances work the queue.
+func (r *DMExportRepository) ClaimPending(ctx context.Context, now time.Time, limit int) ([]*model.DMExport, error) {
+	query := `
+		UPDATE dm_exports
+		SET status = 'running', claimed_at = $1
+		WHERE id IN (
+			SELECT id FROM dm_exports
+			WHERE status = 'pending'
+			ORDER BY created_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	var exports []*model.DMExport
+	if err := conn(ctx, r.db).SelectContext(ctx, &exports, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to claim dm exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+// MarkCompleted records the finished export file
+func (r *DMExportRepository) MarkCompleted(ctx context.Context, id, storedName string, messageCount int, expiresAt time.Time) error {
+	query := `
+		UPDATE dm_exports
+		SET status = 'completed', stored_name = $2, message_count = $3, completed_at = NOW(), expires_at = $4
+		WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, storedName, messageCount, expiresAt); err != nil {
+		return fmt.Errorf("failed to mark dm export completed: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records why an export could not be produced; the record is
+// kept until expiresAt so the user can see what happened
+func (r *DMExportRepository) MarkFailed(ctx context.Context, id, reason string, expiresAt time.Time) error {
+	query := `
+		UPDATE dm_exports
+		SET status = 'failed', error = $2, completed_at = NOW(), expires_at = $3
+		WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, reason, expiresAt); err != nil {
+		return fmt.Errorf("failed to mark dm export failed: %w", err)
+	}
+	return nil
+}
+
+// ReleaseStale returns exports stuck in running, e.g. after a crash
+// mid-export, to the pending queue
+func (r *DMExportRepository) ReleaseStale(ctx context.Context, claimedBefore time.Time) (int64, error) {
+	query := `
+		UPDATE dm_exports
+		SET status = 'pending', claimed_at = NULL
+		WHERE status = 'running' AND claimed_at < $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, claimedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release stale dm exports: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpired removes up to limit expired exports and returns them so
+// their files can be cleaned up
+func (r *DMExportRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) ([]*model.DMExport, error) {
+	query := `
+		DELETE FROM dm_exports
+		WHERE id IN (
+			SELECT id FROM dm_exports
+			WHERE expires_at <= $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	var exports []*model.DMExport
+	if err := conn(ctx, r.db).SelectContext(ctx, &exports, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to delete expired dm exports: %w", err)
+	}
+
+	return exports, nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrFeedbackNotFound = errors.New("feedback not found")
+
+type FeedbackRepository struct {
+	db *sqlx.DB
+}
+
+func NewFeedbackRepository(db *sqlx.DB) *FeedbackRepository {
+	return &FeedbackRepository{db: db}
+}
+
+// FeedbackFilter narrows feedback listings; zero values are ignored
+type FeedbackFilter struct {
+	Status   model.FeedbackStatus
+	Category model.FeedbackCategory
+}
+
+// Create stores new feedback
+func (r *FeedbackRepository) Create(ctx context.Context, fb *model.Feedback) error {
+	query := `
+		INSERT INTO feedback (user_id, category, text, screenshot_url, app_version, client, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, status, created_at, updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		fb.UserID,
+		fb.Category,
+		fb.Text,
+		fb.ScreenshotURL,
+		fb.AppVersion,
+		fb.Client,
+		fb.UserAgent,
+	).Scan(&fb.ID, &fb.Status, &fb.CreatedAt, &fb.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create feedback: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets feedback by ID
+func (r *FeedbackRepository) GetByID(ctx context.Context, id string) (*model.Feedback, error) {
+	var fb model.Feedback
+	if err := conn(ctx, r.db).GetContext(ctx, &fb, `SELECT * FROM feedback WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFeedbackNotFound
+		}
+		return nil, fmt.Errorf("failed to get feedback: %w", err)
+	}
+
+	return &fb, nil
+}
+
+// List lists feedback matching the filter, newest first
+func (r *FeedbackRepository) List(ctx context.Context, filter *FeedbackFilter, limit, offset int) ([]*model.Feedback, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.Status != "" {
+		addCondition("status = $%d", filter.Status)
+	}
+	if filter.Category != "" {
+		addCondition("category = $%d", filter.Category)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT * FROM feedback
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+
+	var items []*model.Feedback
+	if err := conn(ctx, r.db).SelectContext(ctx, &items, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list feedback: %w", err)
+	}
+
+	return items, nil
+}
+
+// Triage sets the status and note and records who triaged the feedback
+func (r *FeedbackRepository) Triage(ctx context.Context, id string, status model.FeedbackStatus, note sql.NullString, triagedBy string) (*model.Feedback, error) {
+	query := `
+		UPDATE feedback
+		SET status = $2, triage_note = $3, triaged_by = $4, triaged_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING *`
+
+	var fb model.Feedback
+	if err := conn(ctx, r.db).GetContext(ctx, &fb, query, id, status, note, triagedBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFeedbackNotFound
+		}
+		return nil, fmt.Errorf("failed to triage feedback: %w", err)
+	}
+
+	return &fb, nil
+}
+
+// ListUnforwarded lists feedback not yet sent to the external tracker,
+// oldest first
+func (r *FeedbackRepository) ListUnforwarded(ctx context.Context, limit int) ([]*model.Feedback, error) {
+	query := `
+		SELECT * FROM feedback
+		WHERE forwarded_at IS NULL
+		ORDER BY created_at
+		LIMIT $1`
+
+	var items []*model.Feedback
+	if err := conn(ctx, r.db).SelectContext(ctx, &items, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to list unforwarded feedback: %w", err)
+	}
+
+	return items, nil
+}
+
+// MarkForwarded records that the feedback reached the external tracker
+func (r *FeedbackRepository) MarkForwarded(ctx context.Context, id string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE feedback SET forwarded_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to mark feedback forwarded: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrGroupConversationNotFound = errors.New("group conversation not found")
+	ErrGroupParticipantNotFound  = errors.New("group participant not found")
+)
+
+type GroupConversationRepository struct {
+	db *sqlx.DB
+}
+
+func NewGroupConversationRepository(db *sqlx.DB) *GroupConversationRepository {
+	return &GroupConversationRepository{db: db}
+}
+
+// Create creates a group conversation with its participants
+func (r *GroupConversationRepository) Create(ctx context.Context, conv *model.GroupConversation, participantIDs []string) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		INSERT INTO group_conversations (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+
+	if err := tx.QueryRowxContext(ctx, query, conv.Name, conv.CreatedBy).Scan(&conv.ID, &conv.CreatedAt, &conv.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create group conversation: %w", err)
+	}
+	if err := addGroupParticipants(ctx, tx, conv.ID, participantIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieves a group conversation by ID
+func (r *GroupConversationRepository) GetByID(ctx context.Context, id string) (*model.GroupConversation, error) {
+	var conv model.GroupConversation
+	if err := conn(ctx, r.db).GetContext(ctx, &conv, `SELECT * FROM group_conversations WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupConversationNotFound
+		}
+		return nil, fmt.Errorf("failed to get group conversation: %w", err)
+	}
+	return &conv, nil
+}
+
+// ListParticipants lists the participants of a group conversation in the
+// order they joined; a conversation that does not exist has none
+func (r *GroupConversationRepository) ListParticipants(ctx context.Context, id string) ([]*model.GroupParticipant, error) {
+	query := `
+		SELECT p.*, u.username, u.display_name, u.avatar_url
+		FROM group_conversation_participants p
+		INNER JOIN users u ON u.id = p.user_id
+		WHERE p.conversation_id = $1
+		ORDER BY p.joined_at, u.username`
+
+	var participants []*model.GroupParticipant
+	if err := conn(ctx, r.db).SelectContext(ctx, &participants, query, id); err != nil {
+		return nil, fmt.Errorf("failed to list group participants: %w", err)
+	}
+
+	return participants, nil
+}
+
+// AddParticipants adds users to a group conversation; users already in it
+// are left as they are
+func (r *GroupConversationRepository) AddParticipants(ctx context.Context, id string, userIDs []string) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := addGroupParticipants(ctx, tx, id, userIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func addGroupParticipants(ctx context.Context, tx queryer, id string, userIDs []string) error {
+	for _, userID := range userIDs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO group_conversation_participants (conversation_id, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT (conversation_id, user_id) DO NOTHING`, id, userID); err != nil {
+			return fmt.Errorf("failed to add group participant: %w", err)
+		}
+	}
+	return nil
+}
+
+// RemoveParticipant removes a user from a group conversation. The
+// conversation and its messages are deleted once nobody is left in it.
+func (r *GroupConversationRepository) RemoveParticipant(ctx context.Context, id, userID string) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM group_conversation_participants
+		WHERE conversation_id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove group participant: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrGroupParticipantNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM group_conversations c
+		WHERE c.id = $1 AND NOT EXISTS (
+			SELECT 1 FROM group_conversation_participants p WHERE p.conversation_id = c.id
+		)`, id); err != nil {
+		return fmt.Errorf("failed to delete empty group conversation: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// CreateMessage creates a message in a group conversation
+func (r *GroupConversationRepository) CreateMessage(ctx context.Context, msg *model.GroupMessage) error {
+	query := `
+		INSERT INTO group_messages (conversation_id, sender_id, content, type)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		msg.ConversationID,
+		msg.SenderID,
+		msg.Content,
+		msg.Type,
+	).Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create group message: %w", err)
+	}
+
+	return nil
+}
+
+// ListMessages retrieves the messages of a group conversation, newest page
+// first, each page in chronological order
+func (r *GroupConversationRepository) ListMessages(ctx context.Context, id string, limit, offset int) ([]*model.GroupMessageWithUser, error) {
+	query := `
+		SELECT m.*, u.username as sender_username, u.display_name as sender_display_name, u.avatar_url as sender_avatar_url
+		FROM group_messages m
+		INNER JOIN users u ON m.sender_id = u.id
+		WHERE m.conversation_id = $1
+		ORDER BY m.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	var messages []*model.GroupMessageWithUser
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, id, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list group messages: %w", err)
+	}
+
+	// Reverse for chronological order
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+// ListForUser lists the group conversations a user takes part in, most
+// recently active first, with their last message and the user's unread count
+func (r *GroupConversationRepository) ListForUser(ctx context.Context, userID string, limit, offset int) ([]*model.GroupConversationSummary, error) {
+	query := `
+		SELECT c.*,
+			COALESCE(lm.content, '') as last_message,
+			lm.created_at as last_message_at,
+			(
+				SELECT COUNT(*) FROM group_messages m
+				WHERE m.conversation_id = c.id AND m.sender_id <> $1 AND m.created_at > p.last_read_at
+			) as unread_count,
+			(
+				SELECT COUNT(*) FROM group_conversation_participants pc
+				WHERE pc.conversation_id = c.id
+			) as participant_count
+		FROM group_conversation_participants p
+		INNER JOIN group_conversations c ON c.id = p.conversation_id
+		LEFT JOIN LATERAL (
+			SELECT content, created_at FROM group_messages m
+			WHERE m.conversation_id = c.id
+			ORDER BY m.created_at DESC
+			LIMIT 1
+		) lm ON TRUE
+		WHERE p.user_id = $1
+		ORDER BY COALESCE(lm.created_at, c.created_at) DESC
+		LIMIT $2 OFFSET $3`
+
+	var conversations []*model.GroupConversationSummary
+	if err := conn(ctx, r.db).SelectContext(ctx, &conversations, query, userID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list group conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// MarkAsRead marks a group conversation read up to now for a participant
+func (r *GroupConversationRepository) MarkAsRead(ctx context.Context, id, userID string) error {
+	query := `
+		UPDATE group_conversation_participants
+		SET last_read_at = NOW()
+		WHERE conversation_id = $1 AND user_id = $2`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, userID); err != nil {
+		return fmt.Errorf("failed to mark group conversation as read: %w", err)
+	}
+
+	return nil
+}
+
+// CountUnread counts the messages others sent in a user's group
+// conversations since the user last read each one
+func (r *GroupConversationRepository) CountUnread(ctx context.Context, userID string) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*)
+		FROM group_messages m
+		INNER JOIN group_conversation_participants p ON p.conversation_id = m.conversation_id AND p.user_id = $1
+		WHERE m.sender_id <> $1 AND m.created_at > p.last_read_at`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count unread group messages: %w", err)
+	}
+
+	return count, nil
+}
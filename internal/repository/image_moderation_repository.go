@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrImageAssetNotFound = errors.New("image asset not found")
+
+// ImageModerationRepository stores image moderation settings, scanned
+// images and the review queue
+type ImageModerationRepository struct {
+	db *sqlx.DB
+}
+
+func NewImageModerationRepository(db *sqlx.DB) *ImageModerationRepository {
+	return &ImageModerationRepository{db: db}
+}
+
+const imageModerationSettingsColumns = `enabled, nsfw_threshold, block_threshold, updated_by, updated_at`
+
+// GetSettings returns the current moderation settings
+func (r *ImageModerationRepository) GetSettings(ctx context.Context) (*model.ImageModerationSettings, error) {
+	var settings model.ImageModerationSettings
+	query := `SELECT ` + imageModerationSettingsColumns + ` FROM image_moderation_settings WHERE id = 1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &settings, query); err != nil {
+		return nil, fmt.Errorf("failed to get image moderation settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// UpdateSettings replaces the moderation settings
+func (r *ImageModerationRepository) UpdateSettings(ctx context.Context, settings *model.ImageModerationSettings) error {
+	query := `
+		UPDATE image_moderation_settings
+		SET enabled = $1, nsfw_threshold = $2, block_threshold = $3, updated_by = $4, updated_at = NOW()
+		WHERE id = 1
+		RETURNING ` + imageModerationSettingsColumns
+
+	if err := conn(ctx, r.db).GetContext(ctx, settings, query,
+		settings.Enabled,
+		settings.NSFWThreshold,
+		settings.BlockThreshold,
+		settings.UpdatedBy,
+	); err != nil {
+		return fmt.Errorf("failed to update image moderation settings: %w", err)
+	}
+
+	return nil
+}
+
+// CreateAsset records a scanned image
+func (r *ImageModerationRepository) CreateAsset(ctx context.Context, asset *model.ImageAsset) error {
+	query := `
+		INSERT INTO image_assets (user_id, file_url, file_path, content_type, score, labels, verdict, review_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		asset.UserID,
+		asset.FileURL,
+		asset.FilePath,
+		asset.ContentType,
+		asset.Score,
+		asset.Labels,
+		asset.Verdict,
+		asset.ReviewStatus,
+	).Scan(&asset.ID, &asset.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create image asset: %w", err)
+	}
+
+	return nil
+}
+
+// GetAsset gets a scanned image by ID
+func (r *ImageModerationRepository) GetAsset(ctx context.Context, id string) (*model.ImageAsset, error) {
+	var asset model.ImageAsset
+	if err := conn(ctx, r.db).GetContext(ctx, &asset, `SELECT * FROM image_assets WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrImageAssetNotFound
+		}
+		return nil, fmt.Errorf("failed to get image asset: %w", err)
+	}
+
+	return &asset, nil
+}
+
+// ListPending lists images waiting for review, oldest first
+func (r *ImageModerationRepository) ListPending(ctx context.Context, limit, offset int) ([]*model.ImageAsset, error) {
+	query := `
+		SELECT * FROM image_assets
+		WHERE review_status = 'pending'
+		ORDER BY created_at
+		LIMIT $1 OFFSET $2`
+
+	var assets []*model.ImageAsset
+	if err := conn(ctx, r.db).SelectContext(ctx, &assets, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list pending images: %w", err)
+	}
+
+	return assets, nil
+}
+
+// Review sets an image's verdict on behalf of an admin and updates the blur
+// flag of every message that shows it. It returns the updated image and how
+// many messages changed.
+func (r *ImageModerationRepository) Review(ctx context.Context, id string, verdict model.ImageVerdict, note sql.NullString, reviewedBy string) (*model.ImageAsset, int64, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var asset model.ImageAsset
+	query := `
+		UPDATE image_assets
+		SET verdict = $2, review_status = 'reviewed', review_note = $3, reviewed_by = $4, reviewed_at = NOW()
+		WHERE id = $1
+		RETURNING *`
+	if err := tx.GetContext(ctx, &asset, query, id, verdict, note, reviewedBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrImageAssetNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to review image: %w", err)
+	}
+
+	var updated int64
+	for _, table := range []string{"messages", "direct_messages"} {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE `+table+` SET is_nsfw = $2
+			WHERE type = 'image' AND content = $1 AND is_nsfw <> $2`,
+			asset.FileURL, asset.IsNSFW())
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to update %s: %w", table, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		updated += rows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit image review: %w", err)
+	}
+	return &asset, updated, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrIncomingWebhookNotFound = errors.New("incoming webhook not found")
+
+// IncomingWebhookRepository stores incoming webhooks and their bot users
+type IncomingWebhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewIncomingWebhookRepository(db *sqlx.DB) *IncomingWebhookRepository {
+	return &IncomingWebhookRepository{db: db}
+}
+
+const incomingWebhookWithBotColumns = `
+	w.*, u.username AS bot_username, u.display_name AS bot_display_name, u.avatar_url AS bot_avatar_url`
+
+// Create creates the webhook's bot user and then the webhook itself. The bot
+// gets an unusable password so it can never log in.
+func (r *IncomingWebhookRepository) Create(ctx context.Context, webhook *model.IncomingWebhook, bot *model.User) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	bot.PasswordHash = "!"
+	bot.Status = model.UserStatusOffline
+	bot.IsBot = true
+	if err := tx.QueryRowxContext(ctx, `
+		INSERT INTO users (username, email, password_hash, display_name, avatar_url, status, is_bot)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE)
+		RETURNING id, created_at, updated_at`,
+		bot.Username,
+		bot.Email,
+		bot.PasswordHash,
+		bot.DisplayName,
+		bot.AvatarURL,
+		bot.Status,
+	).Scan(&bot.ID, &bot.CreatedAt, &bot.UpdatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to create bot user: %w", err)
+	}
+
+	webhook.BotUserID = bot.ID
+	if err := tx.QueryRowxContext(ctx, `
+		INSERT INTO incoming_webhooks (room_id, bot_user_id, token_hash, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		webhook.RoomID,
+		webhook.BotUserID,
+		webhook.TokenHash,
+		webhook.CreatedBy,
+	).Scan(&webhook.ID, &webhook.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create incoming webhook: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets a room's incoming webhook by ID
+func (r *IncomingWebhookRepository) GetByID(ctx context.Context, roomID, id string) (*model.IncomingWebhookWithBot, error) {
+	var webhook model.IncomingWebhookWithBot
+	query := `
+		SELECT ` + incomingWebhookWithBotColumns + `
+		FROM incoming_webhooks w
+		INNER JOIN users u ON u.id = w.bot_user_id
+		WHERE w.id = $1 AND w.room_id = $2`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &webhook, query, id, roomID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get incoming webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+// GetByTokenHash gets an incoming webhook by the hash of its token
+func (r *IncomingWebhookRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.IncomingWebhook, error) {
+	var webhook model.IncomingWebhook
+	query := `SELECT * FROM incoming_webhooks WHERE token_hash = $1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &webhook, query, tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get incoming webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+// ListByRoomID lists a room's incoming webhooks, oldest first
+func (r *IncomingWebhookRepository) ListByRoomID(ctx context.Context, roomID string) ([]*model.IncomingWebhookWithBot, error) {
+	query := `
+		SELECT ` + incomingWebhookWithBotColumns + `
+		FROM incoming_webhooks w
+		INNER JOIN users u ON u.id = w.bot_user_id
+		WHERE w.room_id = $1
+		ORDER BY w.created_at`
+
+	var webhooks []*model.IncomingWebhookWithBot
+	if err := conn(ctx, r.db).SelectContext(ctx, &webhooks, query, roomID); err != nil {
+		return nil, fmt.Errorf("failed to list incoming webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// CountByRoomID counts a room's incoming webhooks
+func (r *IncomingWebhookRepository) CountByRoomID(ctx context.Context, roomID string) (int, error) {
+	var count int
+	if err := conn(ctx, r.db).GetContext(ctx, &count, `SELECT COUNT(*) FROM incoming_webhooks WHERE room_id = $1`, roomID); err != nil {
+		return 0, fmt.Errorf("failed to count incoming webhooks: %w", err)
+	}
+
+	return count, nil
+}
+
+// UpdateBot changes the name and avatar of a webhook's bot. Messages it
+// already posted show the new identity too.
+func (r *IncomingWebhookRepository) UpdateBot(ctx context.Context, roomID, id string, displayName, avatarURL sql.NullString) error {
+	query := `
+		UPDATE users SET display_name = $3, avatar_url = $4
+		WHERE is_bot AND id = (SELECT bot_user_id FROM incoming_webhooks WHERE id = $1 AND room_id = $2)`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, roomID, displayName, avatarURL)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook bot: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrIncomingWebhookNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a room's incoming webhook. Its bot user is kept so the
+// messages it posted keep their author.
+func (r *IncomingWebhookRepository) Delete(ctx context.Context, roomID, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM incoming_webhooks WHERE id = $1 AND room_id = $2`, id, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to delete incoming webhook: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrIncomingWebhookNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed records that the webhook just posted a message
+func (r *IncomingWebhookRepository) TouchLastUsed(ctx context.Context, id string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE incoming_webhooks SET last_used_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to update incoming webhook last used: %w", err)
+	}
+
+	return nil
+}
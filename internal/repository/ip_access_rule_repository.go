@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrIPAccessRuleNotFound = errors.New("ip access rule not found")
+
+type IPAccessRuleRepository struct {
+	db *sqlx.DB
+}
+
+func NewIPAccessRuleRepository(db *sqlx.DB) *IPAccessRuleRepository {
+	return &IPAccessRuleRepository{db: db}
+}
+
+// Create stores an IP access rule
+func (r *IPAccessRuleRepository) Create(ctx context.Context, rule *model.IPAccessRule) error {
+	query := `
+		INSERT INTO ip_access_rules (scope, action, cidr, country, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, cidr::text, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		rule.Scope,
+		rule.Action,
+		rule.CIDR,
+		rule.Country,
+		rule.Note,
+		rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CIDR, &rule.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create ip access rule: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes an IP access rule
+func (r *IPAccessRuleRepository) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM ip_access_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete ip access rule: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrIPAccessRuleNotFound
+	}
+
+	return nil
+}
+
+// List returns every rule by scope, oldest first
+func (r *IPAccessRuleRepository) List(ctx context.Context) ([]*model.IPAccessRule, error) {
+	var rules []*model.IPAccessRule
+	query := `
+		SELECT id, scope, action, cidr::text AS cidr, country, note, created_by, created_at
+		FROM ip_access_rules
+		ORDER BY scope, created_at`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &rules, query); err != nil {
+		return nil, fmt.Errorf("failed to list ip access rules: %w", err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrIPBanNotFound = errors.New("ip ban not found")
+
+type IPBanRepository struct {
+	db *sqlx.DB
+}
+
+func NewIPBanRepository(db *sqlx.DB) *IPBanRepository {
+	return &IPBanRepository{db: db}
+}
+
+// Create stores an IP ban
+func (r *IPBanRepository) Create(ctx context.Context, ban *model.IPBan) error {
+	query := `
+		INSERT INTO ip_bans (cidr, reason, tarpit, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, cidr::text, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		ban.CIDR,
+		ban.Reason,
+		ban.Tarpit,
+		ban.CreatedBy,
+		ban.ExpiresAt,
+	).Scan(&ban.ID, &ban.CIDR, &ban.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create ip ban: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes an IP ban
+func (r *IPBanRepository) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM ip_bans WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete ip ban: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrIPBanNotFound
+	}
+
+	return nil
+}
+
+// List lists IP bans, newest first
+func (r *IPBanRepository) List(ctx context.Context, limit, offset int) ([]*model.IPBan, error) {
+	var bans []*model.IPBan
+	query := `
+		SELECT id, cidr::text AS cidr, reason, tarpit, created_by, expires_at, created_at
+		FROM ip_bans
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &bans, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list ip bans: %w", err)
+	}
+
+	return bans, nil
+}
+
+// ListActive returns every ban in effect
+func (r *IPBanRepository) ListActive(ctx context.Context) ([]*model.IPBan, error) {
+	var bans []*model.IPBan
+	query := `
+		SELECT id, cidr::text AS cidr, reason, tarpit, created_by, expires_at, created_at
+		FROM ip_bans
+		WHERE expires_at IS NULL OR expires_at > NOW()`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &bans, query); err != nil {
+		return nil, fmt.Errorf("failed to list active ip bans: %w", err)
+	}
+
+	return bans, nil
+}
+
+// DeleteExpired removes bans that expired before now
+func (r *IPBanRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM ip_bans WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired ip bans: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrJoinRequestExists   = errors.New("join request already pending")
+)
+
+type JoinRequestRepository struct {
+	db *sqlx.DB
+}
+
+func NewJoinRequestRepository(db *sqlx.DB) *JoinRequestRepository {
+	return &JoinRequestRepository{db: db}
+}
+
+// Create files a pending join request
+func (r *JoinRequestRepository) Create(ctx context.Context, req *model.RoomJoinRequest) error {
+	query := `
+		INSERT INTO room_join_requests (room_id, user_id, message)
+		VALUES ($1, $2, $3)
+		RETURNING id, status, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		req.RoomID,
+		req.UserID,
+		req.Message,
+	).Scan(&req.ID, &req.Status, &req.CreatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrJoinRequestExists
+		}
+		return fmt.Errorf("failed to create join request: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets a join request by ID
+func (r *JoinRequestRepository) GetByID(ctx context.Context, id string) (*model.RoomJoinRequest, error) {
+	var req model.RoomJoinRequest
+	query := `SELECT * FROM room_join_requests WHERE id = $1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &req, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJoinRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to get join request: %w", err)
+	}
+
+	return &req, nil
+}
+
+// ListPending lists a room's pending requests, oldest first
+func (r *JoinRequestRepository) ListPending(ctx context.Context, roomID string, limit, offset int) ([]*model.RoomJoinRequestWithUser, error) {
+	query := `
+		SELECT jr.*, u.username, u.display_name, u.avatar_url
+		FROM room_join_requests jr
+		JOIN users u ON u.id = jr.user_id
+		WHERE jr.room_id = $1 AND jr.status = 'pending'
+		ORDER BY jr.created_at ASC
+		LIMIT $2 OFFSET $3`
+
+	var requests []*model.RoomJoinRequestWithUser
+	if err := conn(ctx, r.db).SelectContext(ctx, &requests, query, roomID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list join requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// Resolve moves a pending request to approved or rejected. It returns
+// ErrJoinRequestNotFound if the request is no longer pending.
+func (r *JoinRequestRepository) Resolve(ctx context.Context, id string, status model.JoinRequestStatus, resolverID string) (*model.RoomJoinRequest, error) {
+	query := `
+		UPDATE room_join_requests
+		SET status = $2, resolved_by = $3, resolved_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING *`
+
+	var req model.RoomJoinRequest
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query, id, status, resolverID).StructScan(&req); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJoinRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to resolve join request: %w", err)
+	}
+
+	return &req, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type JWTKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewJWTKeyRepository(db *sqlx.DB) *JWTKeyRepository {
+	return &JWTKeyRepository{db: db}
+}
+
+// Create stores a signing key
+func (r *JWTKeyRepository) Create(ctx context.Context, key *model.JWTSigningKey) error {
+	query := `
+		INSERT INTO jwt_signing_keys (sealed_secret, created_by, activates_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		key.SealedSecret,
+		key.CreatedBy,
+		key.ActivatesAt,
+	).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create jwt signing key: %w", err)
+	}
+
+	return nil
+}
+
+// List returns every signing key, oldest first. Keys are never deleted:
+// the first one's activation is when the configured secret was retired.
+func (r *JWTKeyRepository) List(ctx context.Context) ([]*model.JWTSigningKey, error) {
+	var keys []*model.JWTSigningKey
+	if err := conn(ctx, r.db).SelectContext(ctx, &keys, `SELECT * FROM jwt_signing_keys ORDER BY activates_at`); err != nil {
+		return nil, fmt.Errorf("failed to list jwt signing keys: %w", err)
+	}
+	return keys, nil
+}
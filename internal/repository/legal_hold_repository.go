@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrLegalHoldNotFound = errors.New("legal hold not found")
+	ErrLegalHoldExists   = errors.New("legal hold already active")
+)
+
+// notHeldClause returns a condition excluding rows whose idColumn is under an
+// active legal hold of the given target type. Retention and purge queries
+// must include it so held data is never pruned.
+func notHeldClause(target model.LegalHoldTarget, idColumn string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM legal_holds lh
+		WHERE lh.target_type = '%s' AND lh.target_id = %s AND lh.released_at IS NULL
+	)`, target, idColumn)
+}
+
+type LegalHoldRepository struct {
+	db *sqlx.DB
+}
+
+func NewLegalHoldRepository(db *sqlx.DB) *LegalHoldRepository {
+	return &LegalHoldRepository{db: db}
+}
+
+// Create places a legal hold
+func (r *LegalHoldRepository) Create(ctx context.Context, hold *model.LegalHold) error {
+	query := `
+		INSERT INTO legal_holds (target_type, target_id, reason, placed_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		hold.TargetType,
+		hold.TargetID,
+		hold.Reason,
+		hold.PlacedBy,
+	).Scan(&hold.ID, &hold.CreatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrLegalHoldExists
+		}
+		return fmt.Errorf("failed to create legal hold: %w", err)
+	}
+
+	return nil
+}
+
+// Release lifts an active legal hold and returns it
+func (r *LegalHoldRepository) Release(ctx context.Context, id, releasedBy string) (*model.LegalHold, error) {
+	var hold model.LegalHold
+	query := `
+		UPDATE legal_holds SET released_at = NOW(), released_by = $2
+		WHERE id = $1 AND released_at IS NULL
+		RETURNING *`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &hold, query, id, releasedBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLegalHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+
+	return &hold, nil
+}
+
+// GetActive retrieves the active hold on a target
+func (r *LegalHoldRepository) GetActive(ctx context.Context, target model.LegalHoldTarget, targetID string) (*model.LegalHold, error) {
+	var hold model.LegalHold
+	query := `
+		SELECT * FROM legal_holds
+		WHERE target_type = $1 AND target_id = $2 AND released_at IS NULL`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &hold, query, target, targetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLegalHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+
+	return &hold, nil
+}
+
+// ListActive lists active legal holds, newest first
+func (r *LegalHoldRepository) ListActive(ctx context.Context, limit, offset int) ([]*model.LegalHold, error) {
+	query := `
+		SELECT * FROM legal_holds
+		WHERE released_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	var holds []*model.LegalHold
+	if err := conn(ctx, r.db).SelectContext(ctx, &holds, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+
+	return holds, nil
+}
+
+// ExportCursor is a keyset position for chronological export listings
+type ExportCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// NewExportCursor returns a cursor positioned before the first row
+func NewExportCursor() *ExportCursor {
+	return &ExportCursor{ID: "00000000-0000-0000-0000-000000000000"}
+}
+
+// Advance moves the cursor past the given row
+func (c *ExportCursor) Advance(createdAt time.Time, id string) {
+	c.CreatedAt = createdAt
+	c.ID = id
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrMessageFlagNotFound = errors.New("message flag not found")
+
+type MessageFlagRepository struct {
+	db *sqlx.DB
+}
+
+func NewMessageFlagRepository(db *sqlx.DB) *MessageFlagRepository {
+	return &MessageFlagRepository{db: db}
+}
+
+// Create queues a message for review
+func (r *MessageFlagRepository) Create(ctx context.Context, flag *model.MessageFlag) error {
+	query := `
+		INSERT INTO message_flags (message_id, room_id, user_id, reason, score, labels)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		flag.MessageID,
+		flag.RoomID,
+		flag.UserID,
+		flag.Reason,
+		flag.Score,
+		flag.Labels,
+	).Scan(&flag.ID, &flag.Status, &flag.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create message flag: %w", err)
+	}
+
+	return nil
+}
+
+// ListPending lists flags waiting for review with their messages, oldest
+// first
+func (r *MessageFlagRepository) ListPending(ctx context.Context, limit, offset int) ([]*model.MessageFlagWithMessage, error) {
+	query := `
+		SELECT f.*, m.content, u.username
+		FROM message_flags f
+		JOIN messages m ON m.id = f.message_id
+		LEFT JOIN users u ON u.id = f.user_id
+		WHERE f.status = 'pending'
+		ORDER BY f.created_at
+		LIMIT $1 OFFSET $2`
+
+	var flags []*model.MessageFlagWithMessage
+	if err := conn(ctx, r.db).SelectContext(ctx, &flags, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list message flags: %w", err)
+	}
+
+	return flags, nil
+}
+
+// Review settles a pending flag; a removed message is soft deleted in the
+// same transaction
+func (r *MessageFlagRepository) Review(ctx context.Context, id string, status model.MessageFlagStatus, note sql.NullString, reviewedBy string) (*model.MessageFlag, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var flag model.MessageFlag
+	query := `
+		UPDATE message_flags
+		SET status = $2, review_note = $3, reviewed_by = $4, reviewed_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING *`
+	if err := tx.GetContext(ctx, &flag, query, id, status, note, reviewedBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMessageFlagNotFound
+		}
+		return nil, fmt.Errorf("failed to review message flag: %w", err)
+	}
+
+	if status == model.MessageFlagRemoved {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE messages SET is_deleted = true, content = '[訊息已刪除]', embeds = NULL
+			WHERE id = $1`, flag.MessageID); err != nil {
+			return nil, fmt.Errorf("failed to delete flagged message: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit message review: %w", err)
+	}
+	return &flag, nil
+}
@@ -5,37 +5,134 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/idgen"
+	"github.com/go-demo/chat/internal/pkg/search"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 var (
 	ErrMessageNotFound = errors.New("message not found")
+
+	// ErrMessageRejected is returned by CreateBatch when Postgres refused
+	// the messages themselves, such as a sender that no longer exists,
+	// rather than failing to run the insert
+	ErrMessageRejected = errors.New("message rejected")
 )
 
 type MessageRepository struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	analyzer search.Analyzer
+	ids      idgen.Strategy
 }
 
 func NewMessageRepository(db *sqlx.DB) *MessageRepository {
-	return &MessageRepository{db: db}
+	return &MessageRepository{db: db, analyzer: search.AnalyzerILike, ids: idgen.StrategyUUID}
+}
+
+// SetSearchAnalyzer sets the analyzer used by Search
+func (r *MessageRepository) SetSearchAnalyzer(analyzer search.Analyzer) {
+	r.analyzer = analyzer
+}
+
+// SetIDStrategy sets how Create generates message IDs. Switching is safe at
+// any time: existing messages keep their IDs and cursors accept both kinds.
+func (r *MessageRepository) SetIDStrategy(strategy idgen.Strategy) {
+	r.ids = strategy
 }
 
-// Create creates a new message
+// Create creates a new message. Time-ordered IDs also fix created_at to the
+// time they encode, so ID order and (created_at, id) order agree.
 func (r *MessageRepository) Create(ctx context.Context, msg *model.Message) error {
-	query := `
-		INSERT INTO messages (room_id, user_id, content, type, reply_to_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at`
+	id, createdAt, err := r.ids.NewID()
+	if err != nil {
+		return err
+	}
 
-	return r.db.QueryRowxContext(ctx, query,
+	query := `
+		INSERT INTO messages (id, created_at, room_id, user_id, content, type, reply_to_id, forwarded_from, expires_at)
+		VALUES (COALESCE($7::uuid, gen_random_uuid()), COALESCE($8::timestamptz, NOW()), $1, $2, $3, $4, $5, $6, (
+			-- Disappearing messages: the room's TTL at send time fixes the expiry
+			SELECT CASE WHEN message_ttl_seconds > 0 THEN NOW() + make_interval(secs => message_ttl_seconds) END
+			FROM rooms WHERE id = $1
+		))
+		RETURNING id, created_at, updated_at, expires_at`
+
+	return conn(ctx, r.db).QueryRowxContext(ctx, query,
 		msg.RoomID,
 		msg.UserID,
 		msg.Content,
 		msg.Type,
 		msg.ReplyToID,
-	).Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt)
+		nullJSON(msg.ForwardedFrom),
+		sql.NullString{String: id, Valid: id != ""},
+		sql.NullTime{Time: createdAt, Valid: id != ""},
+	).Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt, &msg.ExpiresAt)
+}
+
+// messageBatchColumns is the number of parameters CreateBatch binds per message
+const messageBatchColumns = 8
+
+// CreateBatch inserts messages that already carry their ID and creation
+// time, as the write-behind pipeline produces them. Messages whose ID is
+// already stored are skipped, so a batch delivered twice is inserted once;
+// so are messages whose room has since been deleted. It returns the number
+// of rows inserted.
+func (r *MessageRepository) CreateBatch(ctx context.Context, msgs []*model.Message) (int64, error) {
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+
+	values := make([]string, 0, len(msgs))
+	args := make([]interface{}, 0, len(msgs)*messageBatchColumns)
+	for i, msg := range msgs {
+		n := i * messageBatchColumns
+		values = append(values, fmt.Sprintf("($%d::uuid, $%d::timestamptz, $%d::uuid, $%d::uuid, $%d, $%d, $%d::uuid, $%d::jsonb)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8))
+		args = append(args,
+			msg.ID,
+			msg.CreatedAt,
+			msg.RoomID,
+			msg.UserID,
+			msg.Content,
+			msg.Type,
+			msg.ReplyToID,
+			nullJSON(msg.ForwardedFrom),
+		)
+	}
+
+	query := `
+		INSERT INTO messages (id, created_at, room_id, user_id, content, type, reply_to_id, forwarded_from, expires_at)
+		SELECT v.id, v.created_at, v.room_id, v.user_id, v.content, v.type, v.reply_to_id, v.forwarded_from,
+			-- Disappearing messages expire relative to when they were sent, not written
+			CASE WHEN r.message_ttl_seconds > 0 THEN v.created_at + make_interval(secs => r.message_ttl_seconds) END
+		FROM (VALUES ` + strings.Join(values, ", ") + `)
+			AS v(id, created_at, room_id, user_id, content, type, reply_to_id, forwarded_from)
+		INNER JOIN rooms r ON r.id = v.room_id
+		ON CONFLICT (id) DO NOTHING`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		// Data exceptions and constraint violations fail the same way on retry
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23") {
+			return 0, fmt.Errorf("%w: %v", ErrMessageRejected, err)
+		}
+		return 0, fmt.Errorf("failed to insert message batch: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// nullJSON passes an empty JSON column as NULL
+func nullJSON(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return raw
 }
 
 // GetByID retrieves a message by ID
@@ -43,7 +140,7 @@ func (r *MessageRepository) GetByID(ctx context.Context, id string) (*model.Mess
 	var msg model.Message
 	query := `SELECT * FROM messages WHERE id = $1`
 
-	if err := r.db.GetContext(ctx, &msg, query, id); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &msg, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrMessageNotFound
 		}
@@ -57,12 +154,12 @@ func (r *MessageRepository) GetByID(ctx context.Context, id string) (*model.Mess
 func (r *MessageRepository) GetByIDWithUser(ctx context.Context, id string) (*model.MessageWithUser, error) {
 	var msg model.MessageWithUser
 	query := `
-		SELECT m.*, u.username, u.display_name, u.avatar_url
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
 		FROM messages m
 		INNER JOIN users u ON m.user_id = u.id
 		WHERE m.id = $1`
 
-	if err := r.db.GetContext(ctx, &msg, query, id); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &msg, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrMessageNotFound
 		}
@@ -74,9 +171,10 @@ func (r *MessageRepository) GetByIDWithUser(ctx context.Context, id string) (*mo
 
 // Update updates a message content
 func (r *MessageRepository) Update(ctx context.Context, id, content string) error {
-	query := `UPDATE messages SET content = $2, is_edited = true WHERE id = $1 AND is_deleted = false`
+	// Previews of the old links are dropped; new ones are fetched again
+	query := `UPDATE messages SET content = $2, is_edited = true, embeds = NULL WHERE id = $1 AND is_deleted = false`
 
-	result, err := r.db.ExecContext(ctx, query, id, content)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, content)
 	if err != nil {
 		return fmt.Errorf("failed to update message: %w", err)
 	}
@@ -92,11 +190,33 @@ func (r *MessageRepository) Update(ctx context.Context, id, content string) erro
 	return nil
 }
 
+// SetEmbeds stores the embeds generated for a message. It returns
+// ErrMessageNotFound when the message is not stored yet, was deleted, or
+// its content is no longer the content the embeds were made for.
+func (r *MessageRepository) SetEmbeds(ctx context.Context, id, content string, embeds []byte) error {
+	query := `UPDATE messages SET embeds = $3 WHERE id = $1 AND content = $2 AND is_deleted = false`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, content, nullJSON(embeds))
+	if err != nil {
+		return fmt.Errorf("failed to set message embeds: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrMessageNotFound
+	}
+
+	return nil
+}
+
 // SoftDelete marks a message as deleted
 func (r *MessageRepository) SoftDelete(ctx context.Context, id string) error {
-	query := `UPDATE messages SET is_deleted = true, content = '[訊息已刪除]' WHERE id = $1`
+	query := `UPDATE messages SET is_deleted = true, content = '[訊息已刪除]', embeds = NULL WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to soft delete message: %w", err)
 	}
@@ -112,10 +232,36 @@ func (r *MessageRepository) SoftDelete(ctx context.Context, id string) error {
 	return nil
 }
 
+// ExpireDue soft deletes up to limit messages whose TTL has elapsed and
+// returns them. Messages in rooms or from users under legal hold are kept
+// until the hold is released.
+func (r *MessageRepository) ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.Message, error) {
+	query := `
+		UPDATE messages
+		SET is_deleted = true, content = '[訊息已刪除]'
+		WHERE id IN (
+			SELECT id FROM messages
+			WHERE expires_at <= $1 AND is_deleted = false
+				AND ` + notHeldClause(model.LegalHoldTargetRoom, "messages.room_id") + `
+				AND ` + notHeldClause(model.LegalHoldTargetUser, "messages.user_id") + `
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	var messages []*model.Message
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to expire messages: %w", err)
+	}
+
+	return messages, nil
+}
+
 // ListByRoomID retrieves messages for a room (paginated)
 func (r *MessageRepository) ListByRoomID(ctx context.Context, roomID string, limit, offset int) ([]*model.MessageWithUser, error) {
 	query := `
-		SELECT m.*, u.username, u.display_name, u.avatar_url
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
 		FROM messages m
 		INNER JOIN users u ON m.user_id = u.id
 		WHERE m.room_id = $1
@@ -123,7 +269,7 @@ func (r *MessageRepository) ListByRoomID(ctx context.Context, roomID string, lim
 		LIMIT $2 OFFSET $3`
 
 	var messages []*model.MessageWithUser
-	if err := r.db.SelectContext(ctx, &messages, query, roomID, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, roomID, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list messages: %w", err)
 	}
 
@@ -135,32 +281,130 @@ func (r *MessageRepository) ListByRoomID(ctx context.Context, roomID string, lim
 	return messages, nil
 }
 
-// ListByRoomIDSince retrieves messages after a specific time (for real-time sync)
+// ListRecentForFeed retrieves the newest visible messages of a room, newest
+// first. Deleted, expired and system messages are left out.
+func (r *MessageRepository) ListRecentForFeed(ctx context.Context, roomID string, limit int) ([]*model.MessageWithUser, error) {
+	query := `
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
+		FROM messages m
+		INNER JOIN users u ON m.user_id = u.id
+		WHERE m.room_id = $1 AND m.is_deleted = FALSE AND m.type <> 'system'
+			AND (m.expires_at IS NULL OR m.expires_at > NOW())
+		ORDER BY m.created_at DESC
+		LIMIT $2`
+
+	var messages []*model.MessageWithUser
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, roomID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list messages for feed: %w", err)
+	}
+
+	return messages, nil
+}
+
+// ListByRoomIDSince retrieves the messages after the given one in
+// chronological order, to resume from the last message a client has
 func (r *MessageRepository) ListByRoomIDSince(ctx context.Context, roomID string, sinceID string, limit int) ([]*model.MessageWithUser, error) {
+	createdAt, err := r.cursorTime(ctx, sinceID)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT m.*, u.username, u.display_name, u.avatar_url
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
 		FROM messages m
 		INNER JOIN users u ON m.user_id = u.id
-		WHERE m.room_id = $1 AND m.created_at > (
-			SELECT created_at FROM messages WHERE id = $2
-		)
-		ORDER BY m.created_at ASC
-		LIMIT $3`
+		WHERE m.room_id = $1 AND (m.created_at, m.id) > ($2, $3::uuid)
+		ORDER BY m.created_at, m.id
+		LIMIT $4`
 
 	var messages []*model.MessageWithUser
-	if err := r.db.SelectContext(ctx, &messages, query, roomID, sinceID, limit); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, roomID, createdAt, sinceID, limit); err != nil {
 		return nil, fmt.Errorf("failed to list messages since: %w", err)
 	}
 
 	return messages, nil
 }
 
-// CountByRoomID counts messages in a room
+// ListByRoomIDBefore retrieves the page of messages before the given one,
+// in chronological order. Unlike offsets, the page stays put while new
+// messages arrive.
+func (r *MessageRepository) ListByRoomIDBefore(ctx context.Context, roomID string, beforeID string, limit int) ([]*model.MessageWithUser, error) {
+	createdAt, err := r.cursorTime(ctx, beforeID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
+		FROM messages m
+		INNER JOIN users u ON m.user_id = u.id
+		WHERE m.room_id = $1 AND (m.created_at, m.id) < ($2, $3::uuid)
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $4`
+
+	var messages []*model.MessageWithUser
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, roomID, createdAt, beforeID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list messages before: %w", err)
+	}
+
+	// Reverse to get chronological order
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+// cursorTime returns the created_at of the message a cursor ID points at.
+// UUIDv7 IDs carry it, so the message need not exist any more; older random
+// IDs are looked up.
+func (r *MessageRepository) cursorTime(ctx context.Context, id string) (time.Time, error) {
+	if createdAt, ok := idgen.Time(id); ok {
+		return createdAt, nil
+	}
+
+	var createdAt time.Time
+	if err := conn(ctx, r.db).GetContext(ctx, &createdAt, `SELECT created_at FROM messages WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrMessageNotFound
+		}
+		return time.Time{}, fmt.Errorf("failed to get message cursor: %w", err)
+	}
+
+	return createdAt, nil
+}
+
+// ListForExport retrieves messages in chronological order after the given
+// (created_at, id) position, including deleted messages. Exactly one of
+// roomID and userID narrows the listing to a room or an author.
+func (r *MessageRepository) ListForExport(ctx context.Context, roomID, userID string, after *ExportCursor, limit int) ([]*model.MessageWithUser, error) {
+	column, value := "m.room_id", roomID
+	if userID != "" {
+		column, value = "m.user_id", userID
+	}
+
+	query := `
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
+		FROM messages m
+		INNER JOIN users u ON m.user_id = u.id
+		WHERE ` + column + ` = $1 AND (m.created_at, m.id) > ($2, $3::uuid)
+		ORDER BY m.created_at, m.id
+		LIMIT $4`
+
+	var messages []*model.MessageWithUser
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, value, after.CreatedAt, after.ID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list messages for export: %w", err)
+	}
+
+	return messages, nil
+}
+
+// CountByRoomID returns a room's maintained message count
 func (r *MessageRepository) CountByRoomID(ctx context.Context, roomID string) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM messages WHERE room_id = $1`
+	query := `SELECT COALESCE((SELECT message_count FROM room_counters WHERE room_id = $1), 0)`
 
-	if err := r.db.GetContext(ctx, &count, query, roomID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &count, query, roomID); err != nil {
 		return 0, fmt.Errorf("failed to count messages: %w", err)
 	}
 
@@ -176,73 +420,110 @@ func (r *MessageRepository) CountUnreadByRoomID(ctx context.Context, roomID, use
 		INNER JOIN room_members rm ON m.room_id = rm.room_id AND rm.user_id = $2
 		WHERE m.room_id = $1 AND m.created_at > rm.last_read_at AND m.user_id != $2`
 
-	if err := r.db.GetContext(ctx, &count, query, roomID, userID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &count, query, roomID, userID); err != nil {
 		return 0, fmt.Errorf("failed to count unread messages: %w", err)
 	}
 
 	return count, nil
 }
 
-// Search searches messages in a room
-func (r *MessageRepository) Search(ctx context.Context, roomID, query string, limit, offset int) ([]*model.MessageWithUser, error) {
-	searchQuery := `
-		SELECT m.*, u.username, u.display_name, u.avatar_url
+// Search searches messages in a room, newest first, optionally narrowed to
+// one sender and a time range
+func (r *MessageRepository) Search(ctx context.Context, q *search.MessageQuery) ([]*model.MessageWithUser, error) {
+	join := ""
+	if r.analyzer.IsFullText() {
+		join = "INNER JOIN message_search ms ON ms.message_id = m.id"
+	}
+
+	args := []interface{}{q.RoomID, r.analyzer.Arg(q.Text), q.Limit, q.Offset}
+	filters := ""
+	if q.SenderID != "" {
+		args = append(args, q.SenderID)
+		filters += fmt.Sprintf(" AND m.user_id = $%d", len(args))
+	}
+	if !q.After.IsZero() {
+		args = append(args, q.After)
+		filters += fmt.Sprintf(" AND m.created_at >= $%d", len(args))
+	}
+	if !q.Before.IsZero() {
+		args = append(args, q.Before)
+		filters += fmt.Sprintf(" AND m.created_at < $%d", len(args))
+	}
+
+	searchQuery := fmt.Sprintf(`
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
 		FROM messages m
 		INNER JOIN users u ON m.user_id = u.id
-		WHERE m.room_id = $1 AND m.content ILIKE $2 AND m.is_deleted = false
+		%s
+		WHERE m.room_id = $1 AND %s AND m.is_deleted = false%s
 		ORDER BY m.created_at DESC
-		LIMIT $3 OFFSET $4`
+		LIMIT $3 OFFSET $4`, join, r.analyzer.Condition("m.content", "ms.search_vector", 2), filters)
 
 	var messages []*model.MessageWithUser
-	pattern := "%" + query + "%"
 
-	if err := r.db.SelectContext(ctx, &messages, searchQuery, roomID, pattern, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, searchQuery, args...); err != nil {
 		return nil, fmt.Errorf("failed to search messages: %w", err)
 	}
 
 	return messages, nil
 }
 
-// CreateAttachment creates a message attachment
-func (r *MessageRepository) CreateAttachment(ctx context.Context, att *model.MessageAttachment) error {
-	query := `
-		INSERT INTO message_attachments (message_id, file_name, file_url, file_type, file_size)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at`
+// ListByIDs retrieves messages by IDs, deleted ones included, in no
+// particular order
+func (r *MessageRepository) ListByIDs(ctx context.Context, ids []string) ([]*model.Message, error) {
+	if len(ids) == 0 {
+		return []*model.Message{}, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT * FROM messages WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
 
-	return r.db.QueryRowxContext(ctx, query,
-		att.MessageID,
-		att.FileName,
-		att.FileURL,
-		att.FileType,
-		att.FileSize,
-	).Scan(&att.ID, &att.CreatedAt)
+	var messages []*model.Message
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to get messages by ids: %w", err)
+	}
+
+	return messages, nil
 }
 
-// GetAttachmentsByMessageID retrieves attachments for a message
-func (r *MessageRepository) GetAttachmentsByMessageID(ctx context.Context, messageID string) ([]*model.MessageAttachment, error) {
-	query := `SELECT * FROM message_attachments WHERE message_id = $1 ORDER BY created_at`
+// ListByIDsWithUser retrieves the messages of a room that are not deleted
+// by IDs with user info, in no particular order
+func (r *MessageRepository) ListByIDsWithUser(ctx context.Context, roomID string, ids []string) ([]*model.MessageWithUser, error) {
+	if len(ids) == 0 {
+		return []*model.MessageWithUser{}, nil
+	}
 
-	var attachments []*model.MessageAttachment
-	if err := r.db.SelectContext(ctx, &attachments, query, messageID); err != nil {
-		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	query, args, err := sqlx.In(`
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
+		FROM messages m
+		INNER JOIN users u ON m.user_id = u.id
+		WHERE m.room_id = ? AND m.id IN (?) AND m.is_deleted = false`, roomID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
-	return attachments, nil
+	var messages []*model.MessageWithUser
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to get messages with user by ids: %w", err)
+	}
+
+	return messages, nil
 }
 
 // GetLatestByRoomID retrieves the latest message in a room
 func (r *MessageRepository) GetLatestByRoomID(ctx context.Context, roomID string) (*model.MessageWithUser, error) {
 	var msg model.MessageWithUser
 	query := `
-		SELECT m.*, u.username, u.display_name, u.avatar_url
+		SELECT m.*, u.username, u.display_name, u.avatar_url, u.is_bot
 		FROM messages m
 		INNER JOIN users u ON m.user_id = u.id
 		WHERE m.room_id = $1
 		ORDER BY m.created_at DESC
 		LIMIT 1`
 
-	if err := r.db.GetContext(ctx, &msg, query, roomID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &msg, query, roomID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // No messages yet
 		}
@@ -3,9 +3,13 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/idgen"
+	"github.com/go-demo/chat/internal/pkg/search"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
@@ -194,6 +198,49 @@ func TestMessageRepository_SoftDelete(t *testing.T) {
 	}
 }
 
+func TestMessageRepository_SetEmbeds(t *testing.T) {
+	db, prefix := setupMessageTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupMessageTestByPrefix(t, db, prefix)
+
+	user := createTestUserForMessageIsolated(t, db, prefix, "sender")
+	room := createTestRoomIsolated(t, db, prefix, user)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+
+	msg := &model.Message{
+		RoomID:  room.ID,
+		UserID:  user.ID,
+		Content: "Read https://example.com",
+		Type:    model.MessageTypeText,
+	}
+	if err := repo.Create(ctx, msg); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	embeds := []byte(`[{"type":"link","url":"https://example.com","title":"Example"}]`)
+	if err := repo.SetEmbeds(ctx, msg.ID, "stale content", embeds); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound for changed content, got %v", err)
+	}
+	if err := repo.SetEmbeds(ctx, msg.ID, msg.Content, embeds); err != nil {
+		t.Fatalf("Failed to set embeds: %v", err)
+	}
+
+	found, _ := repo.GetByID(ctx, msg.ID)
+	if got := found.GetEmbeds(); len(got) != 1 || got[0].Title != "Example" {
+		t.Errorf("Expected the stored embed, got %+v", got)
+	}
+
+	// Editing drops the previews of the old content
+	if err := repo.Update(ctx, msg.ID, "No links now"); err != nil {
+		t.Fatalf("Failed to update message: %v", err)
+	}
+	found, _ = repo.GetByID(ctx, msg.ID)
+	if found.GetEmbeds() != nil {
+		t.Errorf("Expected embeds cleared on edit, got %s", found.Embeds)
+	}
+}
+
 func TestMessageRepository_ListByRoomID(t *testing.T) {
 	db, prefix := setupMessageTestDBIsolated(t)
 	defer db.Close()
@@ -286,7 +333,7 @@ func TestMessageRepository_Search(t *testing.T) {
 		}
 	}
 
-	results, err := repo.Search(ctx, room.ID, "Golang", 10, 0)
+	results, err := repo.Search(ctx, &search.MessageQuery{RoomID: room.ID, Text: "Golang", Limit: 10})
 	if err != nil {
 		t.Fatalf("Failed to search messages: %v", err)
 	}
@@ -300,6 +347,138 @@ func TestMessageRepository_Search(t *testing.T) {
 	}
 }
 
+func TestMessageRepository_Search_FullText(t *testing.T) {
+	db, prefix := setupMessageTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupMessageTestByPrefix(t, db, prefix)
+
+	user := createTestUserForMessageIsolated(t, db, prefix, "sender")
+	room := createTestRoomIsolated(t, db, prefix, user)
+	repo := NewMessageRepository(db)
+	repo.SetSearchAnalyzer(search.AnalyzerSimple)
+	ctx := context.Background()
+
+	for _, content := range []string{"deploy finished", "100% done", "redeployed"} {
+		msg := &model.Message{
+			RoomID:  room.ID,
+			UserID:  user.ID,
+			Content: content,
+			Type:    model.MessageTypeText,
+		}
+		if err := repo.Create(ctx, msg); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	// Full-text matches whole words, not substrings
+	results, err := repo.Search(ctx, &search.MessageQuery{RoomID: room.ID, Text: "deploy", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to search messages: %v", err)
+	}
+	if len(results) != 1 || results[0].Content != "deploy finished" {
+		t.Errorf("Expected only 'deploy finished', got %d results", len(results))
+	}
+
+	// ILIKE treats wildcards in the query literally
+	repo.SetSearchAnalyzer(search.AnalyzerILike)
+	results, err = repo.Search(ctx, &search.MessageQuery{RoomID: room.ID, Text: "0%", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to search messages: %v", err)
+	}
+	if len(results) != 1 || results[0].Content != "100% done" {
+		t.Errorf("Expected only '100%% done', got %d results", len(results))
+	}
+}
+
+func TestMessageRepository_Search_Filters(t *testing.T) {
+	db, prefix := setupMessageTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupMessageTestByPrefix(t, db, prefix)
+
+	alice := createTestUserForMessageIsolated(t, db, prefix, "alice")
+	bob := createTestUserForMessageIsolated(t, db, prefix, "bob")
+	room := createTestRoomIsolated(t, db, prefix, alice)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+
+	var ids []string
+	for _, sender := range []*model.User{alice, bob, alice} {
+		msg := &model.Message{RoomID: room.ID, UserID: sender.ID, Content: "release notes", Type: model.MessageTypeText}
+		if err := repo.Create(ctx, msg); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		ids = append(ids, msg.ID)
+	}
+	// Spread the messages an hour apart
+	base := time.Now().Add(-3 * time.Hour)
+	for i, id := range ids {
+		if _, err := db.Exec(`UPDATE messages SET created_at = $2 WHERE id = $1`, id, base.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("Failed to backdate message: %v", err)
+		}
+	}
+
+	results, err := repo.Search(ctx, &search.MessageQuery{RoomID: room.ID, Text: "release", SenderID: alice.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to search messages: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != ids[2] || results[1].ID != ids[0] {
+		t.Errorf("Expected alice's two messages newest first, got %d results", len(results))
+	}
+
+	results, err = repo.Search(ctx, &search.MessageQuery{
+		RoomID: room.ID,
+		Text:   "release",
+		After:  base.Add(30 * time.Minute),
+		Before: base.Add(90 * time.Minute),
+		Limit:  10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to search messages: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != ids[1] {
+		t.Errorf("Expected only the message inside the range, got %d results", len(results))
+	}
+}
+
+func TestMessageRepository_ListByIDsWithUser(t *testing.T) {
+	db, prefix := setupMessageTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupMessageTestByPrefix(t, db, prefix)
+
+	user := createTestUserForMessageIsolated(t, db, prefix, "sender")
+	room := createTestRoomIsolated(t, db, prefix, user)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+
+	kept := &model.Message{RoomID: room.ID, UserID: user.ID, Content: "kept", Type: model.MessageTypeText}
+	removed := &model.Message{RoomID: room.ID, UserID: user.ID, Content: "removed", Type: model.MessageTypeText}
+	for _, msg := range []*model.Message{kept, removed} {
+		if err := repo.Create(ctx, msg); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+	if err := repo.SoftDelete(ctx, removed.ID); err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+
+	results, err := repo.ListByIDsWithUser(ctx, room.ID, []string{kept.ID, removed.ID})
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != kept.ID || results[0].Username == "" {
+		t.Errorf("Expected only the kept message with its sender, got %d results", len(results))
+	}
+
+	// The indexer still sees deleted messages so it can drop them
+	all, err := repo.ListByIDs(ctx, []string{kept.ID, removed.ID})
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("Expected both messages, got %d", len(all))
+	}
+}
+
 func TestMessageRepository_CountByRoomID(t *testing.T) {
 	db, prefix := setupMessageTestDBIsolated(t)
 	defer db.Close()
@@ -407,3 +586,115 @@ func TestMessageRepository_MessageTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestMessageRepository_ListByMessageID(t *testing.T) {
+	db, prefix := setupMessageTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupMessageTestByPrefix(t, db, prefix)
+
+	user := createTestUserForMessageIsolated(t, db, prefix, "sender")
+	room := createTestRoomIsolated(t, db, prefix, user)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+
+	// Messages from before the switch keep their random IDs
+	var ids []string
+	for i, strategy := range []idgen.Strategy{idgen.StrategyUUID, idgen.StrategyUUID, idgen.StrategyUUIDv7, idgen.StrategyUUIDv7} {
+		repo.SetIDStrategy(strategy)
+		msg := &model.Message{RoomID: room.ID, UserID: user.ID, Content: "msg", Type: model.MessageTypeText}
+		if err := repo.Create(ctx, msg); err != nil {
+			t.Fatalf("Failed to create message %d: %v", i, err)
+		}
+		if createdAt, ok := idgen.Time(msg.ID); ok != (strategy == idgen.StrategyUUIDv7) || (ok && !createdAt.Equal(msg.CreatedAt)) {
+			t.Errorf("Message %d: expected created_at to match its ID, got %s at %v", i, msg.ID, msg.CreatedAt)
+		}
+		ids = append(ids, msg.ID)
+
+		// UUIDv7 times are whole milliseconds
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	since, err := repo.ListByRoomIDSince(ctx, room.ID, ids[1], 10)
+	if err != nil {
+		t.Fatalf("Failed to list since: %v", err)
+	}
+	if len(since) != 2 || since[0].ID != ids[2] || since[1].ID != ids[3] {
+		t.Errorf("Expected the two newer messages, got %d", len(since))
+	}
+
+	before, err := repo.ListByRoomIDBefore(ctx, room.ID, ids[3], 2)
+	if err != nil {
+		t.Fatalf("Failed to list before: %v", err)
+	}
+	if len(before) != 2 || before[0].ID != ids[1] || before[1].ID != ids[2] {
+		t.Errorf("Expected the two previous messages oldest first, got %d", len(before))
+	}
+
+	// Time-ordered IDs resume even after the message is gone
+	if _, err := db.Exec(`DELETE FROM messages WHERE id = $1`, ids[2]); err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+	since, err = repo.ListByRoomIDSince(ctx, room.ID, ids[2], 10)
+	if err != nil || len(since) != 1 || since[0].ID != ids[3] {
+		t.Errorf("Expected to resume after a deleted message, got %d, %v", len(since), err)
+	}
+
+	if _, err := repo.ListByRoomIDSince(ctx, room.ID, msgNonExistentUUID, 10); err != ErrMessageNotFound {
+		t.Errorf("Expected ErrMessageNotFound for unknown random IDs, got %v", err)
+	}
+}
+
+func TestMessageRepository_CreateBatch(t *testing.T) {
+	db, prefix := setupMessageTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupMessageTestByPrefix(t, db, prefix)
+
+	user := createTestUserForMessageIsolated(t, db, prefix, "sender")
+	room := createTestRoomIsolated(t, db, prefix, user)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+
+	newMessage := func(content string) *model.Message {
+		id, createdAt, err := idgen.StrategyUUIDv7.NewID()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: %v", err)
+		}
+		return &model.Message{ID: id, CreatedAt: createdAt, RoomID: room.ID, UserID: user.ID, Content: content, Type: model.MessageTypeText}
+	}
+
+	first := newMessage("first")
+	// A reply to a message in the same batch
+	reply := newMessage("reply")
+	reply.ReplyToID = sql.NullString{String: first.ID, Valid: true}
+
+	inserted, err := repo.CreateBatch(ctx, []*model.Message{first, reply})
+	if err != nil {
+		t.Fatalf("Failed to create batch: %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("Expected 2 messages inserted, got %d", inserted)
+	}
+
+	stored, err := repo.GetByID(ctx, reply.ID)
+	if err != nil {
+		t.Fatalf("Failed to get message: %v", err)
+	}
+	if !stored.CreatedAt.Equal(reply.CreatedAt) || stored.ReplyToID.String != first.ID {
+		t.Errorf("Expected the message as sent, got %+v", stored)
+	}
+
+	// Delivered again, the batch is skipped
+	inserted, err = repo.CreateBatch(ctx, []*model.Message{first, reply})
+	if err != nil {
+		t.Fatalf("Failed to create batch again: %v", err)
+	}
+	if inserted != 0 {
+		t.Errorf("Expected redelivered messages to be skipped, got %d", inserted)
+	}
+
+	unknownSender := newMessage("ghost")
+	unknownSender.UserID = msgNonExistentUUID
+	if _, err := repo.CreateBatch(ctx, []*model.Message{unknownSender}); !errors.Is(err, ErrMessageRejected) {
+		t.Errorf("Expected ErrMessageRejected, got %v", err)
+	}
+}
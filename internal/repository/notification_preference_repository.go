@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNotificationTargetNotFound = errors.New("notification target not found")
+
+// NotificationPreferenceRepository stores per-room and per-conversation
+// notification levels. Targets without a row use NotificationLevelAll.
+type NotificationPreferenceRepository struct {
+	db *sqlx.DB
+}
+
+func NewNotificationPreferenceRepository(db *sqlx.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+
+// ListByUserID lists a user's preferences, most recently changed first
+func (r *NotificationPreferenceRepository) ListByUserID(ctx context.Context, userID string) ([]*model.NotificationPreference, error) {
+	query := `
+		SELECT * FROM notification_preferences
+		WHERE user_id = $1
+		ORDER BY updated_at DESC`
+
+	var prefs []*model.NotificationPreference
+	if err := conn(ctx, r.db).SelectContext(ctx, &prefs, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list notification preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// GetLevel returns the user's level for a target
+func (r *NotificationPreferenceRepository) GetLevel(ctx context.Context, userID string, targetType model.NotificationTargetType, targetID string) (model.NotificationLevel, error) {
+	var level model.NotificationLevel
+	query := `
+		SELECT level FROM notification_preferences
+		WHERE user_id = $1 AND target_type = $2 AND target_id = $3`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &level, query, userID, targetType, targetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.NotificationLevelAll, nil
+		}
+		return "", fmt.Errorf("failed to get notification level: %w", err)
+	}
+
+	return level, nil
+}
+
+// Set stores a preference. Rooms must be ones the user belongs to and
+// conversations must be with an existing user, otherwise
+// ErrNotificationTargetNotFound is returned.
+func (r *NotificationPreferenceRepository) Set(ctx context.Context, pref *model.NotificationPreference) error {
+	target := `SELECT 1 FROM room_members WHERE room_id = $3 AND user_id = $1`
+	if pref.TargetType == model.NotificationTargetDM {
+		target = `SELECT 1 FROM users WHERE id = $3 AND deleted_at IS NULL`
+	}
+
+	query := `
+		INSERT INTO notification_preferences (user_id, target_type, target_id, level)
+		SELECT $1, $2, $3, $4
+		WHERE EXISTS (` + target + `)
+		ON CONFLICT (user_id, target_type, target_id)
+		DO UPDATE SET level = EXCLUDED.level, updated_at = NOW()
+		RETURNING updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		pref.UserID,
+		pref.TargetType,
+		pref.TargetID,
+		pref.Level,
+	).Scan(&pref.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotificationTargetNotFound
+		}
+		return fmt.Errorf("failed to set notification preference: %w", err)
+	}
+
+	return nil
+}
+
+// Delete resets a target to the default level
+func (r *NotificationPreferenceRepository) Delete(ctx context.Context, userID string, targetType model.NotificationTargetType, targetID string) error {
+	query := `
+		DELETE FROM notification_preferences
+		WHERE user_id = $1 AND target_type = $2 AND target_id = $3`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, targetType, targetID); err != nil {
+		return fmt.Errorf("failed to delete notification preference: %w", err)
+	}
+
+	return nil
+}
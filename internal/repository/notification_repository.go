@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type NotificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewNotificationRepository(db *sqlx.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create creates a new notification
+func (r *NotificationRepository) Create(ctx context.Context, n *model.Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, type, title, content, reference_id, reference_type)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, is_read, created_at`
+
+	return conn(ctx, r.db).QueryRowxContext(ctx, query,
+		n.UserID,
+		n.Type,
+		n.Title,
+		n.Content,
+		n.ReferenceID,
+		n.ReferenceType,
+	).Scan(&n.ID, &n.IsRead, &n.CreatedAt)
+}
+
+// ListByUserID lists notifications for a user, newest first
+func (r *NotificationRepository) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.Notification, error) {
+	query := `
+		SELECT * FROM notifications
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	var notifications []*model.Notification
+	if err := conn(ctx, r.db).SelectContext(ctx, &notifications, query, userID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	return notifications, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReadStateRepository reads how far a user has read each room and
+// conversation, for clients syncing unread badges across devices
+type ReadStateRepository struct {
+	db *sqlx.DB
+}
+
+func NewReadStateRepository(db *sqlx.DB) *ReadStateRepository {
+	return &ReadStateRepository{db: db}
+}
+
+// List returns the user's read state in every room and group conversation
+// they belong to, and in every direct message conversation with unread
+// messages. Conversations missing from DMs have nothing unread.
+func (r *ReadStateRepository) List(ctx context.Context, userID string) (*model.ReadStates, error) {
+	states := &model.ReadStates{
+		Rooms:  []*model.ReadState{},
+		DMs:    []*model.ReadState{},
+		Groups: []*model.ReadState{},
+	}
+
+	roomQuery := `
+		SELECT 'room' as kind, rm.room_id as id, rm.last_read_at,
+			(SELECT COUNT(*) FROM messages m
+			 WHERE m.room_id = rm.room_id AND m.user_id <> rm.user_id
+			   AND m.is_deleted = FALSE AND m.created_at > rm.last_read_at) as unread_count
+		FROM room_members rm
+		INNER JOIN rooms r ON r.id = rm.room_id AND r.deleted_at IS NULL
+		WHERE rm.user_id = $1
+		ORDER BY rm.room_id`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &states.Rooms, roomQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to list room read states: %w", err)
+	}
+
+	dmQuery := `
+		SELECT 'dm' as kind, sender_id as id, NULL as last_read_at, COUNT(*) as unread_count
+		FROM direct_messages
+		WHERE receiver_id = $1 AND is_read = FALSE AND is_deleted_by_receiver = FALSE
+		GROUP BY sender_id
+		ORDER BY sender_id`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &states.DMs, dmQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to list direct message read states: %w", err)
+	}
+
+	groupQuery := `
+		SELECT 'group' as kind, p.conversation_id as id, p.last_read_at,
+			(SELECT COUNT(*) FROM group_messages m
+			 WHERE m.conversation_id = p.conversation_id AND m.sender_id <> p.user_id
+			   AND m.created_at > p.last_read_at) as unread_count
+		FROM group_conversation_participants p
+		WHERE p.user_id = $1
+		ORDER BY p.conversation_id`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &states.Groups, groupQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to list group read states: %w", err)
+	}
+
+	return states, nil
+}
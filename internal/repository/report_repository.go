@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrReportNotFound = errors.New("report not found")
+	ErrReportExists   = errors.New("report already open")
+)
+
+type ReportRepository struct {
+	db *sqlx.DB
+}
+
+func NewReportRepository(db *sqlx.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// ReportFilter narrows report listings; zero values are ignored
+type ReportFilter struct {
+	Status     model.ReportStatus
+	TargetType model.ReportTargetType
+	Category   model.ReportCategory
+}
+
+const reportWithUsersSelect = `
+	SELECT rp.*, ru.username AS reporter_username, tu.username AS reported_username
+	FROM reports rp
+	LEFT JOIN users ru ON ru.id = rp.reporter_id
+	LEFT JOIN users tu ON tu.id = rp.reported_user_id`
+
+// Create files a report. A reporter can only have one unresolved report
+// per message or user.
+func (r *ReportRepository) Create(ctx context.Context, report *model.Report) error {
+	query := `
+		INSERT INTO reports (reporter_id, target_type, message_id, room_id, reported_user_id, message_content, category, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, status, created_at, updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		report.ReporterID,
+		report.TargetType,
+		report.MessageID,
+		report.RoomID,
+		report.ReportedUserID,
+		report.MessageContent,
+		report.Category,
+		report.Note,
+	).Scan(&report.ID, &report.Status, &report.CreatedAt, &report.UpdatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrReportExists
+		}
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets a report by ID with the usernames of both parties
+func (r *ReportRepository) GetByID(ctx context.Context, id string) (*model.ReportWithUsers, error) {
+	var report model.ReportWithUsers
+	if err := conn(ctx, r.db).GetContext(ctx, &report, reportWithUsersSelect+` WHERE rp.id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+
+	return &report, nil
+}
+
+// List lists reports matching the filter, oldest first
+func (r *ReportRepository) List(ctx context.Context, filter *ReportFilter, limit, offset int) ([]*model.ReportWithUsers, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.Status != "" {
+		addCondition("rp.status = $%d", filter.Status)
+	}
+	if filter.TargetType != "" {
+		addCondition("rp.target_type = $%d", filter.TargetType)
+	}
+	if filter.Category != "" {
+		addCondition("rp.category = $%d", filter.Category)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(reportWithUsersSelect+`
+		%s
+		ORDER BY rp.created_at
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+
+	var reports []*model.ReportWithUsers
+	if err := conn(ctx, r.db).SelectContext(ctx, &reports, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// UpdateStatus moves a report from one status to another and records who
+// handled it; a NULL note keeps the previous one. It returns
+// ErrReportNotFound when the report is no longer in status from.
+func (r *ReportRepository) UpdateStatus(ctx context.Context, id string, from, to model.ReportStatus, note sql.NullString, handledBy string) (*model.Report, error) {
+	query := `
+		UPDATE reports
+		SET status = $3, resolution_note = COALESCE($4, resolution_note), handled_by = $5, handled_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = $2
+		RETURNING *`
+
+	var report model.Report
+	if err := conn(ctx, r.db).GetContext(ctx, &report, query, id, from, to, note, handledBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to update report: %w", err)
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrRoomEventNotFound = errors.New("room event not found")
+
+// roomEventColumns selects an event with its RSVP counts and the answer of
+// the user passed as $1
+const roomEventColumns = `
+	e.*,
+	(SELECT COUNT(*) FROM room_event_rsvps r WHERE r.event_id = e.id AND r.status = 'going') as going_count,
+	(SELECT COUNT(*) FROM room_event_rsvps r WHERE r.event_id = e.id AND r.status = 'maybe') as maybe_count,
+	(SELECT COUNT(*) FROM room_event_rsvps r WHERE r.event_id = e.id AND r.status = 'declined') as declined_count,
+	(SELECT r.status FROM room_event_rsvps r WHERE r.event_id = e.id AND r.user_id = $1) as my_rsvp`
+
+type RoomEventRepository struct {
+	db *sqlx.DB
+}
+
+func NewRoomEventRepository(db *sqlx.DB) *RoomEventRepository {
+	return &RoomEventRepository{db: db}
+}
+
+// Create stores an event
+func (r *RoomEventRepository) Create(ctx context.Context, event *model.RoomEvent) error {
+	query := `
+		INSERT INTO room_events (room_id, created_by, title, description, location, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		event.RoomID,
+		event.CreatedBy,
+		event.Title,
+		event.Description,
+		event.Location,
+		event.StartsAt,
+		event.EndsAt,
+	).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create room event: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets an event with its RSVP counts and userID's answer
+func (r *RoomEventRepository) GetByID(ctx context.Context, id, userID string) (*model.RoomEventWithRSVPs, error) {
+	var event model.RoomEventWithRSVPs
+	query := `SELECT ` + roomEventColumns + ` FROM room_events e WHERE e.id = $2`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &event, query, userID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoomEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get room event: %w", err)
+	}
+
+	return &event, nil
+}
+
+// ListUpcoming lists a room's events that have not ended by from, soonest
+// first, with their RSVP counts and userID's answers
+func (r *RoomEventRepository) ListUpcoming(ctx context.Context, roomID, userID string, from time.Time, limit, offset int) ([]*model.RoomEventWithRSVPs, error) {
+	query := `
+		SELECT ` + roomEventColumns + `
+		FROM room_events e
+		WHERE e.room_id = $2 AND COALESCE(e.ends_at, e.starts_at) >= $3
+		ORDER BY e.starts_at ASC, e.id
+		LIMIT $4 OFFSET $5`
+
+	var events []*model.RoomEventWithRSVPs
+	if err := conn(ctx, r.db).SelectContext(ctx, &events, query, userID, roomID, from, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list room events: %w", err)
+	}
+
+	return events, nil
+}
+
+// ListSince lists up to limit of a room's events starting at or after
+// since, soonest first
+func (r *RoomEventRepository) ListSince(ctx context.Context, roomID string, since time.Time, limit int) ([]*model.RoomEvent, error) {
+	query := `
+		SELECT * FROM room_events
+		WHERE room_id = $1 AND starts_at >= $2
+		ORDER BY starts_at ASC, id
+		LIMIT $3`
+
+	var events []*model.RoomEvent
+	if err := conn(ctx, r.db).SelectContext(ctx, &events, query, roomID, since, limit); err != nil {
+		return nil, fmt.Errorf("failed to list room events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Delete removes an event and its RSVPs
+func (r *RoomEventRepository) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM room_events WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete room event: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrRoomEventNotFound
+	}
+
+	return nil
+}
+
+// SetRSVP stores or replaces a user's answer to an event
+func (r *RoomEventRepository) SetRSVP(ctx context.Context, eventID, userID string, status model.RSVPStatus) error {
+	query := `
+		INSERT INTO room_event_rsvps (event_id, user_id, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, user_id) DO UPDATE SET status = EXCLUDED.status`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, eventID, userID, status); err != nil {
+		return fmt.Errorf("failed to set rsvp: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRSVP removes a user's answer to an event, if any
+func (r *RoomEventRepository) DeleteRSVP(ctx context.Context, eventID, userID string) error {
+	query := `DELETE FROM room_event_rsvps WHERE event_id = $1 AND user_id = $2`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, eventID, userID); err != nil {
+		return fmt.Errorf("failed to delete rsvp: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueReminders marks up to limit events starting between now and
+// before as reminded and returns them. Events that started while no
+// worker ran are not reminded.
+func (r *RoomEventRepository) ClaimDueReminders(ctx context.Context, before time.Time, limit int) ([]*model.RoomEvent, error) {
+	query := `
+		UPDATE room_events SET reminder_sent_at = NOW()
+		WHERE id IN (
+			SELECT id FROM room_events
+			WHERE reminder_sent_at IS NULL AND starts_at > NOW() AND starts_at <= $1
+			ORDER BY starts_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	var events []*model.RoomEvent
+	if err := conn(ctx, r.db).SelectContext(ctx, &events, query, before, limit); err != nil {
+		return nil, fmt.Errorf("failed to claim event reminders: %w", err)
+	}
+
+	return events, nil
+}
+
+// ListReminderRecipients returns the members of the event's room who
+// answered going or maybe
+func (r *RoomEventRepository) ListReminderRecipients(ctx context.Context, eventID string) ([]string, error) {
+	query := `
+		SELECT rs.user_id
+		FROM room_event_rsvps rs
+		INNER JOIN room_events e ON e.id = rs.event_id
+		INNER JOIN room_members rm ON rm.room_id = e.room_id AND rm.user_id = rs.user_id
+		WHERE rs.event_id = $1 AND rs.status IN ('going', 'maybe')`
+
+	var userIDs []string
+	if err := conn(ctx, r.db).SelectContext(ctx, &userIDs, query, eventID); err != nil {
+		return nil, fmt.Errorf("failed to list reminder recipients: %w", err)
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrRoomMergeNotFound   = errors.New("room merge not found")
+	ErrRoomMergeInProgress = errors.New("room merge already in progress")
+	ErrRoomMergeHeld       = errors.New("room under legal hold")
+)
+
+type RoomMergeRepository struct {
+	db *sqlx.DB
+}
+
+func NewRoomMergeRepository(db *sqlx.DB) *RoomMergeRepository {
+	return &RoomMergeRepository{db: db}
+}
+
+// Create stores a pending merge. Only one active merge per source room is
+// allowed, and a room under legal hold cannot be merged away.
+func (r *RoomMergeRepository) Create(ctx context.Context, merge *model.RoomMerge) error {
+	query := `
+		INSERT INTO room_merges (source_room_id, target_room_id, include_history, merged_by)
+		SELECT $1, $2, $3, $4
+		WHERE ` + notHeldClause(model.LegalHoldTargetRoom, "$1::uuid") + `
+		RETURNING id, status, created_at, updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		merge.SourceRoomID,
+		merge.TargetRoomID,
+		merge.IncludeHistory,
+		merge.MergedBy,
+	).Scan(&merge.ID, &merge.Status, &merge.CreatedAt, &merge.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoomMergeHeld
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrRoomMergeInProgress
+		}
+		return fmt.Errorf("failed to create room merge: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets a merge by ID
+func (r *RoomMergeRepository) GetByID(ctx context.Context, id string) (*model.RoomMerge, error) {
+	var merge model.RoomMerge
+	query := `SELECT * FROM room_merges WHERE id = $1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &merge, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoomMergeNotFound
+		}
+		return nil, fmt.Errorf("failed to get room merge: %w", err)
+	}
+
+	return &merge, nil
+}
+
+// List lists merges, newest first
+func (r *RoomMergeRepository) List(ctx context.Context, limit, offset int) ([]*model.RoomMerge, error) {
+	query := `
+		SELECT * FROM room_merges
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	var merges []*model.RoomMerge
+	if err := conn(ctx, r.db).SelectContext(ctx, &merges, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list room merges: %w", err)
+	}
+
+	return merges, nil
+}
+
+// ListActiveIDs lists merges with work left, oldest first
+func (r *RoomMergeRepository) ListActiveIDs(ctx context.Context, limit int) ([]string, error) {
+	query := `
+		SELECT id FROM room_merges
+		WHERE status IN ('pending', 'running')
+		ORDER BY created_at
+		LIMIT $1`
+
+	var ids []string
+	if err := conn(ctx, r.db).SelectContext(ctx, &ids, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to list active room merges: %w", err)
+	}
+
+	return ids, nil
+}
+
+// IsMerging checks if a room takes part in an active merge, either side
+func (r *RoomMergeRepository) IsMerging(ctx context.Context, roomID string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM room_merges
+			WHERE (source_room_id = $1 OR target_room_id = $1) AND status IN ('pending', 'running')
+		)`
+
+	var exists bool
+	if err := conn(ctx, r.db).GetContext(ctx, &exists, query, roomID); err != nil {
+		return false, fmt.Errorf("failed to check room merge: %w", err)
+	}
+
+	return exists, nil
+}
+
+// RunBatch advances a merge by one batch in a single transaction:
+// up to limit source members are moved (members already in the target are
+// dropped), then, with history included, up to limit messages are moved and
+// tagged with their source room. Once nothing is left the source room is
+// soft deleted and pointed at the target. It returns nil when another worker
+// holds the merge.
+func (r *RoomMergeRepository) RunBatch(ctx context.Context, id string, limit int) (*model.RoomMerge, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var merge model.RoomMerge
+	lock := `SELECT * FROM room_merges WHERE id = $1 FOR UPDATE SKIP LOCKED`
+	if err := tx.GetContext(ctx, &merge, lock, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock room merge: %w", err)
+	}
+	if !merge.IsActive() {
+		return &merge, nil
+	}
+
+	// A hold placed after the merge started, or a target deleted meanwhile,
+	// stops the merge where it is
+	var check struct {
+		TargetLive bool `db:"target_live"`
+		SourceHeld bool `db:"source_held"`
+	}
+	checkQuery := `
+		SELECT
+			EXISTS(SELECT 1 FROM rooms WHERE id = $2 AND deleted_at IS NULL) AS target_live,
+			NOT ` + notHeldClause(model.LegalHoldTargetRoom, "$1::uuid") + ` AS source_held`
+	if err := tx.GetContext(ctx, &check, checkQuery, merge.SourceRoomID, merge.TargetRoomID); err != nil {
+		return nil, fmt.Errorf("failed to check merged rooms: %w", err)
+	}
+	if !check.TargetLive || check.SourceHeld {
+		reason := "target room deleted"
+		if check.SourceHeld {
+			reason = "source room under legal hold"
+		}
+		fail := `
+			UPDATE room_merges SET status = 'failed', error = $2, updated_at = NOW()
+			WHERE id = $1
+			RETURNING *`
+		if err := tx.GetContext(ctx, &merge, fail, id, reason); err != nil {
+			return nil, fmt.Errorf("failed to fail room merge: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit room merge: %w", err)
+		}
+		return &merge, nil
+	}
+
+	// Newcomers join as plain members; mutes follow them
+	var members struct {
+		Processed int `db:"processed"`
+		Moved     int `db:"moved"`
+	}
+	moveMembers := `
+		WITH batch AS (
+			DELETE FROM room_members
+			WHERE id IN (
+				SELECT id FROM room_members WHERE room_id = $1
+				ORDER BY joined_at, id
+				LIMIT $3
+			)
+			RETURNING user_id, nickname, joined_at, last_read_at, is_muted, muted_until, muted_by
+		), moved AS (
+			INSERT INTO room_members (room_id, user_id, role, nickname, joined_at, last_read_at, is_muted, muted_until, muted_by)
+			SELECT $2, user_id, 'member', nickname, joined_at, last_read_at, is_muted, muted_until, muted_by FROM batch
+			ON CONFLICT (room_id, user_id) DO NOTHING
+			RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM batch) AS processed, (SELECT COUNT(*) FROM moved) AS moved`
+	if err := tx.GetContext(ctx, &members, moveMembers, merge.SourceRoomID, merge.TargetRoomID, limit); err != nil {
+		return nil, fmt.Errorf("failed to move room members: %w", err)
+	}
+
+	var messagesMoved int64
+	if members.Processed < limit && merge.IncludeHistory {
+		moveMessages := `
+			UPDATE messages
+			SET room_id = $2, merged_from_room_id = COALESCE(merged_from_room_id, $1)
+			WHERE id IN (
+				SELECT id FROM messages WHERE room_id = $1
+				ORDER BY created_at, id
+				LIMIT $3
+			)`
+		result, err := tx.ExecContext(ctx, moveMessages, merge.SourceRoomID, merge.TargetRoomID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move messages: %w", err)
+		}
+		messagesMoved, _ = result.RowsAffected()
+	}
+
+	status := model.RoomMergeRunning
+	if members.Processed == 0 && messagesMoved == 0 {
+		if err := r.finalize(ctx, tx, &merge); err != nil {
+			return nil, err
+		}
+		status = model.RoomMergeCompleted
+	}
+
+	progress := `
+		UPDATE room_merges
+		SET status = $2,
+			members_moved = members_moved + $3,
+			messages_moved = messages_moved + $4,
+			updated_at = NOW(),
+			completed_at = CASE WHEN $5 THEN NOW() END
+		WHERE id = $1
+		RETURNING *`
+	completed := status == model.RoomMergeCompleted
+	if err := tx.GetContext(ctx, &merge, progress, id, status, members.Moved, messagesMoved, completed); err != nil {
+		return nil, fmt.Errorf("failed to update room merge: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit room merge: %w", err)
+	}
+
+	return &merge, nil
+}
+
+// finalize retires the source room once it is empty. Pending scheduled
+// messages follow their authors to the target, and the target grows to fit
+// its new members.
+func (r *RoomMergeRepository) finalize(ctx context.Context, tx queryer, merge *model.RoomMerge) error {
+	retire := `
+		UPDATE rooms SET merged_into_id = $2, deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, retire, merge.SourceRoomID, merge.TargetRoomID); err != nil {
+		return fmt.Errorf("failed to retire merged room: %w", err)
+	}
+
+	scheduled := `
+		UPDATE scheduled_messages SET room_id = $2
+		WHERE room_id = $1 AND status = 'pending'`
+	if _, err := tx.ExecContext(ctx, scheduled, merge.SourceRoomID, merge.TargetRoomID); err != nil {
+		return fmt.Errorf("failed to move scheduled messages: %w", err)
+	}
+
+	grow := `
+		UPDATE rooms
+		SET max_members = GREATEST(max_members, (SELECT COUNT(*) FROM room_members WHERE room_id = $1))
+		WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, grow, merge.TargetRoomID); err != nil {
+		return fmt.Errorf("failed to resize merged room: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+type RoomPermissionRepository struct {
+	db *sqlx.DB
+}
+
+func NewRoomPermissionRepository(db *sqlx.DB) *RoomPermissionRepository {
+	return &RoomPermissionRepository{db: db}
+}
+
+// ListByRoom returns a room's permission overrides
+func (r *RoomPermissionRepository) ListByRoom(ctx context.Context, roomID string) ([]*model.RoomRolePermission, error) {
+	var perms []*model.RoomRolePermission
+	query := `
+		SELECT room_id, role, action, allowed, updated_by, updated_at
+		FROM room_role_permissions
+		WHERE room_id = $1
+		ORDER BY role, action`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &perms, query, roomID); err != nil {
+		return nil, fmt.Errorf("failed to list room permissions: %w", err)
+	}
+
+	return perms, nil
+}
+
+// Replace swaps a room's overrides for the given set in one transaction
+func (r *RoomPermissionRepository) Replace(ctx context.Context, roomID, updatedBy string, perms []*model.RoomRolePermission) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_role_permissions WHERE room_id = $1`, roomID); err != nil {
+		return fmt.Errorf("failed to clear room permissions: %w", err)
+	}
+
+	query := `
+		INSERT INTO room_role_permissions (room_id, role, action, allowed, updated_by)
+		VALUES ($1, $2, $3, $4, $5)`
+	updater := sql.NullString{String: updatedBy, Valid: updatedBy != ""}
+	for _, p := range perms {
+		if _, err := tx.ExecContext(ctx, query, roomID, p.Role, p.Action, p.Allowed, updater); err != nil {
+			return fmt.Errorf("failed to save room permission: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-demo/chat/internal/model"
 	"github.com/jmoiron/sqlx"
@@ -16,6 +17,12 @@ var (
 	ErrNotRoomMember     = errors.New("not a room member")
 	ErrAlreadyRoomMember = errors.New("already a room member")
 	ErrRoomFull          = errors.New("room is full")
+
+	ErrRoomDeletionPending      = errors.New("room deletion already scheduled")
+	ErrRoomDeletionNotScheduled = errors.New("room deletion not scheduled")
+
+	ErrRoomAlreadyArchived = errors.New("room already archived")
+	ErrRoomNotArchived     = errors.New("room not archived")
 )
 
 type RoomRepository struct {
@@ -29,25 +36,47 @@ func NewRoomRepository(db *sqlx.DB) *RoomRepository {
 // Create creates a new room
 func (r *RoomRepository) Create(ctx context.Context, room *model.Room) error {
 	query := `
-		INSERT INTO rooms (name, description, type, owner_id, max_members)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at`
+		INSERT INTO rooms (name, description, type, owner_id, max_members, read_only, message_ttl_seconds, feed_enabled, language, message_rate_limit,
+			moderation_level)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE(NULLIF($11, ''), 'standard'))
+		RETURNING id, created_at, updated_at, moderation_level`
 
-	return r.db.QueryRowxContext(ctx, query,
+	return conn(ctx, r.db).QueryRowxContext(ctx, query,
 		room.Name,
 		room.Description,
 		room.Type,
 		room.OwnerID,
 		room.MaxMembers,
-	).Scan(&room.ID, &room.CreatedAt, &room.UpdatedAt)
+		room.ReadOnly,
+		room.MessageTTL,
+		room.FeedEnabled,
+		room.Language,
+		room.RateLimit,
+		room.ModerationLevel,
+	).Scan(&room.ID, &room.CreatedAt, &room.UpdatedAt, &room.ModerationLevel)
 }
 
 // GetByID retrieves a room by ID
 func (r *RoomRepository) GetByID(ctx context.Context, id string) (*model.Room, error) {
+	var room model.Room
+	query := `SELECT * FROM rooms WHERE id = $1 AND deleted_at IS NULL`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &room, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoomNotFound
+		}
+		return nil, fmt.Errorf("failed to get room by id: %w", err)
+	}
+
+	return &room, nil
+}
+
+// GetByIDIncludingDeleted retrieves a room by ID even if it was soft deleted
+func (r *RoomRepository) GetByIDIncludingDeleted(ctx context.Context, id string) (*model.Room, error) {
 	var room model.Room
 	query := `SELECT * FROM rooms WHERE id = $1`
 
-	if err := r.db.GetContext(ctx, &room, query, id); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &room, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRoomNotFound
 		}
@@ -57,17 +86,19 @@ func (r *RoomRepository) GetByID(ctx context.Context, id string) (*model.Room, e
 	return &room, nil
 }
 
+// roomCountColumns selects a room's counters from room_counters joined as rc
+const roomCountColumns = `COALESCE(rc.member_count, 0) as member_count, COALESCE(rc.message_count, 0) as message_count`
+
 // GetByIDWithMemberCount retrieves a room by ID with member count
 func (r *RoomRepository) GetByIDWithMemberCount(ctx context.Context, id string) (*model.RoomWithMemberCount, error) {
 	var room model.RoomWithMemberCount
 	query := `
-		SELECT r.*, COUNT(rm.id) as member_count
+		SELECT r.*, ` + roomCountColumns + `
 		FROM rooms r
-		LEFT JOIN room_members rm ON r.id = rm.room_id
-		WHERE r.id = $1
-		GROUP BY r.id`
+		LEFT JOIN room_counters rc ON r.id = rc.room_id
+		WHERE r.id = $1 AND r.deleted_at IS NULL`
 
-	if err := r.db.GetContext(ctx, &room, query, id); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &room, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrRoomNotFound
 		}
@@ -81,14 +112,21 @@ func (r *RoomRepository) GetByIDWithMemberCount(ctx context.Context, id string)
 func (r *RoomRepository) Update(ctx context.Context, room *model.Room) error {
 	query := `
 		UPDATE rooms
-		SET name = $2, description = $3, max_members = $4
-		WHERE id = $1`
+		SET name = $2, description = $3, max_members = $4, read_only = $5, message_ttl_seconds = $6, feed_enabled = $7, language = $8,
+			message_rate_limit = $9, moderation_level = $10
+		WHERE id = $1 AND deleted_at IS NULL`
 
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := conn(ctx, r.db).ExecContext(ctx, query,
 		room.ID,
 		room.Name,
 		room.Description,
 		room.MaxMembers,
+		room.ReadOnly,
+		room.MessageTTL,
+		room.FeedEnabled,
+		room.Language,
+		room.RateLimit,
+		room.ModerationLevel,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update room: %w", err)
@@ -109,7 +147,7 @@ func (r *RoomRepository) Update(ctx context.Context, room *model.Room) error {
 func (r *RoomRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM rooms WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete room: %w", err)
 	}
@@ -125,59 +163,329 @@ func (r *RoomRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// ScheduleDeletion marks a room for deletion at the given time
+func (r *RoomRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	query := `
+		UPDATE rooms
+		SET deletion_scheduled_at = $2
+		WHERE id = $1 AND deleted_at IS NULL AND deletion_scheduled_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, at)
+	if err != nil {
+		return fmt.Errorf("failed to schedule room deletion: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrRoomDeletionPending
+	}
+
+	return nil
+}
+
+// CancelDeletion clears a pending deletion schedule
+func (r *RoomRepository) CancelDeletion(ctx context.Context, id string) error {
+	query := `
+		UPDATE rooms
+		SET deletion_scheduled_at = NULL
+		WHERE id = $1 AND deleted_at IS NULL AND deletion_scheduled_at IS NOT NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel room deletion: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrRoomDeletionNotScheduled
+	}
+
+	return nil
+}
+
+// Archive archives a room and returns when it was archived
+func (r *RoomRepository) Archive(ctx context.Context, id string) (time.Time, error) {
+	query := `
+		UPDATE rooms
+		SET archived_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND archived_at IS NULL
+		RETURNING archived_at`
+
+	var archivedAt time.Time
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query, id).Scan(&archivedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrRoomAlreadyArchived
+		}
+		return time.Time{}, fmt.Errorf("failed to archive room: %w", err)
+	}
+
+	return archivedAt, nil
+}
+
+// Unarchive reopens an archived room
+func (r *RoomRepository) Unarchive(ctx context.Context, id string) error {
+	query := `
+		UPDATE rooms
+		SET archived_at = NULL
+		WHERE id = $1 AND deleted_at IS NULL AND archived_at IS NOT NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to unarchive room: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrRoomNotArchived
+	}
+
+	return nil
+}
+
+// SoftDeleteDue soft deletes rooms whose deletion window has elapsed and returns them.
+// Rooms under legal hold stay scheduled until the hold is released.
+func (r *RoomRepository) SoftDeleteDue(ctx context.Context, now time.Time, limit int) ([]*model.Room, error) {
+	query := `
+		UPDATE rooms
+		SET deleted_at = $1
+		WHERE id IN (
+			SELECT id FROM rooms
+			WHERE deleted_at IS NULL AND deletion_scheduled_at <= $1
+				AND ` + notHeldClause(model.LegalHoldTargetRoom, "rooms.id") + `
+			ORDER BY deletion_scheduled_at
+			LIMIT $2
+		)
+		RETURNING *`
+
+	var rooms []*model.Room
+	if err := conn(ctx, r.db).SelectContext(ctx, &rooms, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to soft delete rooms: %w", err)
+	}
+
+	return rooms, nil
+}
+
+// ListPurgeable lists soft deleted rooms deleted before the given time.
+// Rooms under legal hold, rooms holding messages of a user under legal hold
+// and merged rooms, whose links still redirect, are kept.
+func (r *RoomRepository) ListPurgeable(ctx context.Context, before time.Time, limit int) ([]*model.Room, error) {
+	query := `
+		SELECT * FROM rooms
+		WHERE deleted_at <= $1 AND merged_into_id IS NULL
+			AND ` + notHeldClause(model.LegalHoldTargetRoom, "rooms.id") + `
+			AND NOT EXISTS (
+				SELECT 1 FROM messages m
+				WHERE m.room_id = rooms.id AND NOT ` + notHeldClause(model.LegalHoldTargetUser, "m.user_id") + `
+			)
+		ORDER BY deleted_at
+		LIMIT $2`
+
+	var rooms []*model.Room
+	if err := conn(ctx, r.db).SelectContext(ctx, &rooms, query, before, limit); err != nil {
+		return nil, fmt.Errorf("failed to list purgeable rooms: %w", err)
+	}
+
+	return rooms, nil
+}
+
+// Purge permanently removes a soft deleted room with its messages and
+// everything else that belongs to it. It returns the URLs of the uploaded
+// files its messages carried that no other message refers to, for the
+// caller to remove once the data is gone.
+func (r *RoomRepository) Purge(ctx context.Context, id string) ([]string, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var locked string
+	if err := tx.GetContext(ctx, &locked,
+		`SELECT id FROM rooms WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoomNotFound
+		}
+		return nil, fmt.Errorf("failed to lock room: %w", err)
+	}
+
+	// Forwarded messages copy the URL, so a file shared with another room
+	// or a direct message stays
+	var files []string
+	if err := tx.SelectContext(ctx, &files, `
+		WITH files AS (
+			SELECT m.content AS url FROM messages m
+			WHERE m.room_id = $1 AND m.type IN ('image', 'file') AND m.is_deleted = false
+			UNION
+			SELECT u.file_url FROM attachments a
+			JOIN uploads u ON u.id = a.upload_id
+			JOIN messages m ON m.id = a.message_id
+			WHERE m.room_id = $1
+		)
+		SELECT url FROM files f
+		WHERE NOT EXISTS (SELECT 1 FROM messages o WHERE o.room_id <> $1 AND o.content = f.url)
+			AND NOT EXISTS (
+				SELECT 1 FROM attachments a
+				JOIN uploads u ON u.id = a.upload_id
+				LEFT JOIN messages o ON o.id = a.message_id
+				WHERE u.file_url = f.url AND (o.room_id <> $1 OR a.direct_message_id IS NOT NULL)
+			)
+			AND NOT EXISTS (SELECT 1 FROM direct_messages d WHERE d.content = f.url)`, id); err != nil {
+		return nil, fmt.Errorf("failed to list room files: %w", err)
+	}
+
+	if len(files) > 0 {
+		query, args, err := sqlx.In(`DELETE FROM image_assets WHERE file_url IN (?)`, files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return nil, fmt.Errorf("failed to delete image assets: %w", err)
+		}
+
+		query, args, err = sqlx.In(`DELETE FROM uploads WHERE file_url IN (?)`, files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return nil, fmt.Errorf("failed to delete uploads: %w", err)
+		}
+	}
+
+	// Messages, members and the rest cascade
+	if _, err := tx.ExecContext(ctx, `DELETE FROM rooms WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to purge room: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit room purge: %w", err)
+	}
+	return files, nil
+}
+
 // ListPublic lists public rooms
 func (r *RoomRepository) ListPublic(ctx context.Context, limit, offset int) ([]*model.RoomWithMemberCount, error) {
 	query := `
-		SELECT r.*, COUNT(rm.id) as member_count
+		SELECT r.*, ` + roomCountColumns + `
 		FROM rooms r
-		LEFT JOIN room_members rm ON r.id = rm.room_id
-		WHERE r.type = 'public'
-		GROUP BY r.id
+		LEFT JOIN room_counters rc ON r.id = rc.room_id
+		WHERE r.type = 'public' AND r.deleted_at IS NULL AND r.archived_at IS NULL
 		ORDER BY r.created_at DESC
 		LIMIT $1 OFFSET $2`
 
 	var rooms []*model.RoomWithMemberCount
-	if err := r.db.SelectContext(ctx, &rooms, query, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &rooms, query, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list public rooms: %w", err)
 	}
 
 	return rooms, nil
 }
 
-// ListByUserID lists rooms that user is a member of
+// ListAll lists every room that has not been deleted, archived ones
+// included, newest first. An empty roomType lists all types.
+func (r *RoomRepository) ListAll(ctx context.Context, roomType model.RoomType, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	query := `
+		SELECT r.*, ` + roomCountColumns + `
+		FROM rooms r
+		LEFT JOIN room_counters rc ON r.id = rc.room_id
+		WHERE r.deleted_at IS NULL AND ($1 = '' OR r.type = $1)
+		ORDER BY r.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	var rooms []*model.RoomWithMemberCount
+	if err := conn(ctx, r.db).SelectContext(ctx, &rooms, query, string(roomType), limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list rooms: %w", err)
+	}
+
+	return rooms, nil
+}
+
+// ListByUserID lists rooms that user is a member of, leaving out archived ones
 func (r *RoomRepository) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error) {
 	query := `
-		SELECT r.*, COUNT(rm2.id) as member_count
+		SELECT r.*, ` + roomCountColumns + `
 		FROM rooms r
 		INNER JOIN room_members rm ON r.id = rm.room_id AND rm.user_id = $1
-		LEFT JOIN room_members rm2 ON r.id = rm2.room_id
-		GROUP BY r.id, rm.joined_at
+		LEFT JOIN room_counters rc ON r.id = rc.room_id
+		WHERE r.deleted_at IS NULL AND r.archived_at IS NULL
 		ORDER BY rm.joined_at DESC
 		LIMIT $2 OFFSET $3`
 
 	var rooms []*model.RoomWithMemberCount
-	if err := r.db.SelectContext(ctx, &rooms, query, userID, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &rooms, query, userID, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list user rooms: %w", err)
 	}
 
 	return rooms, nil
 }
 
+// ListArchivedByUserID lists archived rooms that user is a member of, most
+// recently archived first
+func (r *RoomRepository) ListArchivedByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	query := `
+		SELECT r.*, ` + roomCountColumns + `
+		FROM rooms r
+		INNER JOIN room_members rm ON r.id = rm.room_id AND rm.user_id = $1
+		LEFT JOIN room_counters rc ON r.id = rc.room_id
+		WHERE r.deleted_at IS NULL AND r.archived_at IS NOT NULL
+		ORDER BY r.archived_at DESC
+		LIMIT $2 OFFSET $3`
+
+	var rooms []*model.RoomWithMemberCount
+	if err := conn(ctx, r.db).SelectContext(ctx, &rooms, query, userID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list archived user rooms: %w", err)
+	}
+
+	return rooms, nil
+}
+
+// ListMemberRoomsByIDs returns those of the given rooms the user is a member
+// of, with their counts, in a single query
+func (r *RoomRepository) ListMemberRoomsByIDs(ctx context.Context, userID string, roomIDs []string) ([]*model.RoomWithMemberCount, error) {
+	if len(roomIDs) == 0 {
+		return []*model.RoomWithMemberCount{}, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT r.*, `+roomCountColumns+`
+		FROM rooms r
+		INNER JOIN room_members rm ON r.id = rm.room_id AND rm.user_id = ?
+		LEFT JOIN room_counters rc ON r.id = rc.room_id
+		WHERE r.id IN (?) AND r.deleted_at IS NULL`, userID, roomIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var rooms []*model.RoomWithMemberCount
+	if err := conn(ctx, r.db).SelectContext(ctx, &rooms, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list member rooms: %w", err)
+	}
+
+	return rooms, nil
+}
+
 // Search searches rooms by name
 func (r *RoomRepository) Search(ctx context.Context, query string, limit, offset int) ([]*model.RoomWithMemberCount, error) {
 	searchQuery := `
-		SELECT r.*, COUNT(rm.id) as member_count
+		SELECT r.*, ` + roomCountColumns + `
 		FROM rooms r
-		LEFT JOIN room_members rm ON r.id = rm.room_id
-		WHERE r.type = 'public' AND r.name ILIKE $1
-		GROUP BY r.id
+		LEFT JOIN room_counters rc ON r.id = rc.room_id
+		WHERE r.type = 'public' AND r.deleted_at IS NULL AND r.archived_at IS NULL AND r.name ILIKE $1
 		ORDER BY r.name
 		LIMIT $2 OFFSET $3`
 
 	var rooms []*model.RoomWithMemberCount
 	pattern := "%" + query + "%"
 
-	if err := r.db.SelectContext(ctx, &rooms, searchQuery, pattern, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &rooms, searchQuery, pattern, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to search rooms: %w", err)
 	}
 
@@ -193,13 +501,12 @@ func (r *RoomRepository) AddMember(ctx context.Context, member *model.RoomMember
 	}
 
 	checkQuery := `
-		SELECT r.max_members, COUNT(rm.id) as member_count
+		SELECT r.max_members, COALESCE(rc.member_count, 0) as member_count
 		FROM rooms r
-		LEFT JOIN room_members rm ON r.id = rm.room_id
-		WHERE r.id = $1
-		GROUP BY r.id`
+		LEFT JOIN room_counters rc ON r.id = rc.room_id
+		WHERE r.id = $1 AND r.deleted_at IS NULL`
 
-	if err := r.db.GetContext(ctx, &room, checkQuery, member.RoomID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &room, checkQuery, member.RoomID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrRoomNotFound
 		}
@@ -215,7 +522,7 @@ func (r *RoomRepository) AddMember(ctx context.Context, member *model.RoomMember
 		VALUES ($1, $2, $3, $4)
 		RETURNING id, joined_at, last_read_at`
 
-	err := r.db.QueryRowxContext(ctx, query,
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query,
 		member.RoomID,
 		member.UserID,
 		member.Role,
@@ -237,7 +544,7 @@ func (r *RoomRepository) AddMember(ctx context.Context, member *model.RoomMember
 func (r *RoomRepository) RemoveMember(ctx context.Context, roomID, userID string) error {
 	query := `DELETE FROM room_members WHERE room_id = $1 AND user_id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, roomID, userID)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, roomID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to remove member: %w", err)
 	}
@@ -258,7 +565,7 @@ func (r *RoomRepository) GetMember(ctx context.Context, roomID, userID string) (
 	var member model.RoomMember
 	query := `SELECT * FROM room_members WHERE room_id = $1 AND user_id = $2`
 
-	if err := r.db.GetContext(ctx, &member, query, roomID, userID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &member, query, roomID, userID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotRoomMember
 		}
@@ -269,27 +576,65 @@ func (r *RoomRepository) GetMember(ctx context.Context, roomID, userID string) (
 }
 
 // ListMembers lists all members of a room with user info
-func (r *RoomRepository) ListMembers(ctx context.Context, roomID string) ([]*model.RoomMemberWithUser, error) {
+func (r *RoomRepository) ListMembers(ctx context.Context, roomID string, limit, offset int) ([]*model.RoomMemberWithUser, error) {
 	query := `
 		SELECT rm.*, u.username, u.display_name, u.avatar_url, u.status
 		FROM room_members rm
 		INNER JOIN users u ON rm.user_id = u.id
 		WHERE rm.room_id = $1
-		ORDER BY rm.role, rm.joined_at`
+		ORDER BY rm.role, rm.joined_at, rm.user_id
+		LIMIT $2 OFFSET $3`
 
 	var members []*model.RoomMemberWithUser
-	if err := r.db.SelectContext(ctx, &members, query, roomID); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &members, query, roomID, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to list members: %w", err)
 	}
 
 	return members, nil
 }
 
+// ListMemberIDs lists the user IDs of all members of a room
+func (r *RoomRepository) ListMemberIDs(ctx context.Context, roomID string) ([]string, error) {
+	query := `SELECT user_id FROM room_members WHERE room_id = $1`
+
+	var userIDs []string
+	if err := conn(ctx, r.db).SelectContext(ctx, &userIDs, query, roomID); err != nil {
+		return nil, fmt.Errorf("failed to list member ids: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// ListMemberIDsByUsernames returns the IDs of room members with the given usernames
+func (r *RoomRepository) ListMemberIDsByUsernames(ctx context.Context, roomID string, usernames []string) ([]string, error) {
+	if len(usernames) == 0 {
+		return []string{}, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT rm.user_id
+		FROM room_members rm
+		INNER JOIN users u ON rm.user_id = u.id
+		WHERE rm.room_id = ? AND u.username IN (?)`, roomID, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	query = conn(ctx, r.db).Rebind(query)
+	var userIDs []string
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &userIDs, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list member ids by usernames: %w", err)
+	}
+
+	return userIDs, nil
+}
+
 // UpdateMemberRole updates a member's role
 func (r *RoomRepository) UpdateMemberRole(ctx context.Context, roomID, userID string, role model.MemberRole) error {
 	query := `UPDATE room_members SET role = $3 WHERE room_id = $1 AND user_id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, roomID, userID, role)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, roomID, userID, role)
 	if err != nil {
 		return fmt.Errorf("failed to update member role: %w", err)
 	}
@@ -305,38 +650,211 @@ func (r *RoomRepository) UpdateMemberRole(ctx context.Context, roomID, userID st
 	return nil
 }
 
-// UpdateLastReadAt updates member's last read timestamp
-func (r *RoomRepository) UpdateLastReadAt(ctx context.Context, roomID, userID string) error {
-	query := `UPDATE room_members SET last_read_at = NOW() WHERE room_id = $1 AND user_id = $2`
+// SetMute mutes a member until the given time, or indefinitely when until is
+// nil. It returns the updated member.
+func (r *RoomRepository) SetMute(ctx context.Context, roomID, userID string, until *time.Time, mutedBy string) (*model.RoomMember, error) {
+	query := `
+		UPDATE room_members
+		SET is_muted = TRUE, muted_until = $3, muted_by = $4
+		WHERE room_id = $1 AND user_id = $2
+		RETURNING *`
+
+	var member model.RoomMember
+	if err := conn(ctx, r.db).GetContext(ctx, &member, query, roomID, userID, until, mutedBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotRoomMember
+		}
+		return nil, fmt.Errorf("failed to mute member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// ClearMute lifts a member's mute
+func (r *RoomRepository) ClearMute(ctx context.Context, roomID, userID string) error {
+	query := `
+		UPDATE room_members
+		SET is_muted = FALSE, muted_until = NULL, muted_by = NULL
+		WHERE room_id = $1 AND user_id = $2`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, roomID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unmute member: %w", err)
+	}
 
-	_, err := r.db.ExecContext(ctx, query, roomID, userID)
+	rows, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to update last read at: %w", err)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotRoomMember
 	}
 
 	return nil
 }
 
+// ExpireMutes lifts mutes whose deadline has passed and returns the members
+func (r *RoomRepository) ExpireMutes(ctx context.Context, now time.Time, limit int) ([]*model.RoomMember, error) {
+	query := `
+		UPDATE room_members
+		SET is_muted = FALSE, muted_until = NULL, muted_by = NULL
+		WHERE id IN (
+			SELECT id FROM room_members
+			WHERE is_muted = TRUE AND muted_until <= $1
+			ORDER BY muted_until
+			LIMIT $2
+		)
+		RETURNING *`
+
+	var members []*model.RoomMember
+	if err := conn(ctx, r.db).SelectContext(ctx, &members, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to expire mutes: %w", err)
+	}
+
+	return members, nil
+}
+
+// UpdateLastReadAt updates member's last read timestamp and returns it
+func (r *RoomRepository) UpdateLastReadAt(ctx context.Context, roomID, userID string) (time.Time, error) {
+	var readAt time.Time
+	query := `UPDATE room_members SET last_read_at = NOW() WHERE room_id = $1 AND user_id = $2 RETURNING last_read_at`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &readAt, query, roomID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrNotRoomMember
+		}
+		return time.Time{}, fmt.Errorf("failed to update last read at: %w", err)
+	}
+
+	return readAt, nil
+}
+
 // IsMember checks if user is a member of the room
 func (r *RoomRepository) IsMember(ctx context.Context, roomID, userID string) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM room_members WHERE room_id = $1 AND user_id = $2)`
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM room_members rm
+			INNER JOIN rooms r ON r.id = rm.room_id
+			WHERE rm.room_id = $1 AND rm.user_id = $2 AND r.deleted_at IS NULL
+		)`
 
-	if err := r.db.GetContext(ctx, &exists, query, roomID, userID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &exists, query, roomID, userID); err != nil {
 		return false, fmt.Errorf("failed to check membership: %w", err)
 	}
 
 	return exists, nil
 }
 
-// CountMembers counts room members
+// CountMembers returns a room's maintained member count
 func (r *RoomRepository) CountMembers(ctx context.Context, roomID string) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM room_members WHERE room_id = $1`
+	query := `SELECT COALESCE((SELECT member_count FROM room_counters WHERE room_id = $1), 0)`
 
-	if err := r.db.GetContext(ctx, &count, query, roomID); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &count, query, roomID); err != nil {
 		return 0, fmt.Errorf("failed to count members: %w", err)
 	}
 
 	return count, nil
 }
+
+// ReconcileCounters recounts the members and messages of up to limit rooms
+// whose counters were checked least recently and corrects any drift. It
+// returns how many rooms were checked and those whose counters had drifted.
+// Counter rows being updated by a concurrent write are skipped until the
+// next run.
+func (r *RoomRepository) ReconcileCounters(ctx context.Context, limit int) (int, []*model.RoomCounterDrift, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Rooms whose counter row is missing are picked up first
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO room_counters (room_id)
+		SELECT r.id FROM rooms r
+		WHERE NOT EXISTS (SELECT 1 FROM room_counters rc WHERE rc.room_id = r.id)
+		ON CONFLICT (room_id) DO NOTHING`); err != nil {
+		return 0, nil, fmt.Errorf("failed to create missing room counters: %w", err)
+	}
+
+	var roomIDs []string
+	if err := tx.SelectContext(ctx, &roomIDs, `
+		SELECT room_id FROM room_counters
+		ORDER BY reconciled_at NULLS FIRST
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit); err != nil {
+		return 0, nil, fmt.Errorf("failed to lock room counters: %w", err)
+	}
+	if len(roomIDs) == 0 {
+		return 0, nil, nil
+	}
+
+	// Recount in a separate statement so its snapshot includes every write
+	// committed before the rows were locked; later writes wait on the lock
+	// and apply their increment on top of the recount
+	query, args, err := sqlx.In(`
+		WITH actual AS (
+			SELECT rc.room_id, rc.member_count, rc.message_count,
+				(SELECT COUNT(*) FROM room_members rm WHERE rm.room_id = rc.room_id) AS actual_member_count,
+				(SELECT COUNT(*) FROM messages m WHERE m.room_id = rc.room_id) AS actual_message_count
+			FROM room_counters rc
+			WHERE rc.room_id IN (?)
+		), updated AS (
+			UPDATE room_counters rc
+			SET member_count = a.actual_member_count, message_count = a.actual_message_count, reconciled_at = NOW()
+			FROM actual a
+			WHERE rc.room_id = a.room_id
+			RETURNING a.*
+		)
+		SELECT * FROM updated
+		WHERE member_count <> actual_member_count OR message_count <> actual_message_count`, roomIDs)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to build reconcile query: %w", err)
+	}
+
+	var drifts []*model.RoomCounterDrift
+	if err := tx.SelectContext(ctx, &drifts, tx.Rebind(query), args...); err != nil {
+		return 0, nil, fmt.Errorf("failed to reconcile room counters: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit counter reconciliation: %w", err)
+	}
+	return len(roomIDs), drifts, nil
+}
+
+// ActivityHeatmap sums a room's hourly message counts since the given time
+// by hour of the week, in the given IANA time zone
+func (r *RoomRepository) ActivityHeatmap(ctx context.Context, roomID string, since time.Time, timeZone string) ([]*model.RoomActivityBucket, error) {
+	query := `
+		SELECT EXTRACT(DOW FROM hour AT TIME ZONE $3)::int AS weekday,
+			EXTRACT(HOUR FROM hour AT TIME ZONE $3)::int AS hour,
+			SUM(message_count) AS message_count
+		FROM room_activity_hourly
+		WHERE room_id = $1 AND hour >= $2
+		GROUP BY 1, 2`
+
+	var buckets []*model.RoomActivityBucket
+	if err := conn(ctx, r.db).SelectContext(ctx, &buckets, query, roomID, since, timeZone); err != nil {
+		return nil, fmt.Errorf("failed to get room activity: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// PurgeActivity deletes hourly activity older than the given time and
+// returns how many rows were removed
+func (r *RoomRepository) PurgeActivity(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM room_activity_hourly WHERE hour < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge room activity: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows, nil
+}
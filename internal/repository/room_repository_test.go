@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-demo/chat/internal/model"
 	"github.com/jmoiron/sqlx"
@@ -462,7 +464,7 @@ func TestRoomRepository_ListMembers(t *testing.T) {
 	_ = repo.AddMember(ctx, &model.RoomMember{RoomID: room.ID, UserID: user1.ID, Role: model.MemberRoleOwner})
 	_ = repo.AddMember(ctx, &model.RoomMember{RoomID: room.ID, UserID: user2.ID, Role: model.MemberRoleMember})
 
-	members, err := repo.ListMembers(ctx, room.ID)
+	members, err := repo.ListMembers(ctx, room.ID, 20, 0)
 	if err != nil {
 		t.Fatalf("Failed to list members: %v", err)
 	}
@@ -542,6 +544,37 @@ func TestRoomRepository_IsMember(t *testing.T) {
 	}
 }
 
+func TestRoomRepository_ListMemberRoomsByIDs(t *testing.T) {
+	db, prefix := setupRoomTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupRoomTestByPrefix(t, db, prefix)
+
+	user := createTestUserForRoomIsolated(t, db, prefix, "user")
+	other := createTestUserForRoomIsolated(t, db, prefix, "other")
+	repo := NewRoomRepository(db)
+	ctx := context.Background()
+
+	joined := &model.Room{Name: "Joined", Type: model.RoomTypePublic, OwnerID: user.ID, MaxMembers: 100}
+	notJoined := &model.Room{Name: "Not Joined", Type: model.RoomTypePublic, OwnerID: other.ID, MaxMembers: 100}
+	_ = repo.Create(ctx, joined)
+	_ = repo.Create(ctx, notJoined)
+	_ = repo.AddMember(ctx, &model.RoomMember{RoomID: joined.ID, UserID: user.ID, Role: model.MemberRoleOwner})
+	_ = repo.AddMember(ctx, &model.RoomMember{RoomID: notJoined.ID, UserID: other.ID, Role: model.MemberRoleOwner})
+
+	rooms, err := repo.ListMemberRoomsByIDs(ctx, user.ID, []string{joined.ID, notJoined.ID, roomNonExistentUUID})
+	if err != nil {
+		t.Fatalf("Failed to list member rooms: %v", err)
+	}
+	if len(rooms) != 1 || rooms[0].ID != joined.ID || rooms[0].MemberCount != 1 {
+		t.Errorf("Expected only the joined room with its count, got %+v", rooms)
+	}
+
+	rooms, err = repo.ListMemberRoomsByIDs(ctx, user.ID, nil)
+	if err != nil || len(rooms) != 0 {
+		t.Errorf("Expected no rooms for an empty list, got %v, %v", rooms, err)
+	}
+}
+
 func TestRoomRepository_CountMembers(t *testing.T) {
 	db, prefix := setupRoomTestDBIsolated(t)
 	defer db.Close()
@@ -572,3 +605,144 @@ func TestRoomRepository_CountMembers(t *testing.T) {
 		t.Errorf("Expected 2 members, got %d", count)
 	}
 }
+
+func TestRoomRepository_ContextCanceled(t *testing.T) {
+	db, prefix := setupRoomTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupRoomTestByPrefix(t, db, prefix)
+
+	repo := NewRoomRepository(db)
+
+	// Simulate a client that went away before the query ran
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := repo.ListPublic(ctx, 20, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, roomNonExistentUUID); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRoomRepository_ContextDeadline(t *testing.T) {
+	db, prefix := setupRoomTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupRoomTestByPrefix(t, db, prefix)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// pg_sleep outlives the deadline; the driver must abort the query
+	start := time.Now()
+	_, err := db.ExecContext(ctx, "SELECT pg_sleep(5)")
+	if err == nil {
+		t.Fatal("Expected query to be aborted")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected query to abort promptly, took %v", elapsed)
+	}
+}
+
+func TestRoomRepository_ExpiredContext(t *testing.T) {
+	db, prefix := setupRoomTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupRoomTestByPrefix(t, db, prefix)
+
+	user := createTestUserForRoomIsolated(t, db, prefix, "owner")
+	repo := NewRoomRepository(db)
+
+	room := &model.Room{Name: prefix + "deadline_room", Type: model.RoomTypePublic, OwnerID: user.ID, MaxMembers: 100}
+	if err := repo.Create(context.Background(), room); err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	// The deadline must reach the caller through the repository's wrapping,
+	// whether the method runs a single statement or opens a transaction
+	if _, err := repo.GetByID(ctx, room.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetByID: expected context.DeadlineExceeded, got %v", err)
+	}
+	if _, err := repo.ListMembers(ctx, room.ID, 20, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ListMembers: expected context.DeadlineExceeded, got %v", err)
+	}
+	late := &model.Room{Name: prefix + "late_room", Type: model.RoomTypePublic, OwnerID: user.ID, MaxMembers: 100}
+	if err := repo.Create(ctx, late); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Create: expected context.DeadlineExceeded, got %v", err)
+	}
+	if _, err := repo.Purge(ctx, room.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Purge: expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRoomRepository_Counters(t *testing.T) {
+	db, prefix := setupRoomTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupRoomTestByPrefix(t, db, prefix)
+
+	user1 := createTestUserForRoomIsolated(t, db, prefix, "user1")
+	user2 := createTestUserForRoomIsolated(t, db, prefix, "user2")
+	repo := NewRoomRepository(db)
+	ctx := context.Background()
+
+	room := &model.Room{
+		Name:       "Test Room",
+		Type:       model.RoomTypePublic,
+		OwnerID:    user1.ID,
+		MaxMembers: 100,
+	}
+	if err := repo.Create(ctx, room); err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+
+	_ = repo.AddMember(ctx, &model.RoomMember{RoomID: room.ID, UserID: user1.ID, Role: model.MemberRoleOwner})
+	_ = repo.AddMember(ctx, &model.RoomMember{RoomID: room.ID, UserID: user2.ID, Role: model.MemberRoleMember})
+	msg := &model.Message{RoomID: room.ID, UserID: user1.ID, Content: "hello", Type: model.MessageTypeText}
+	if err := NewMessageRepository(db).Create(ctx, msg); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	_ = repo.RemoveMember(ctx, room.ID, user2.ID)
+
+	got, err := repo.GetByIDWithMemberCount(ctx, room.ID)
+	if err != nil {
+		t.Fatalf("Failed to get room: %v", err)
+	}
+	if got.MemberCount != 1 || got.MessageCount != 1 {
+		t.Errorf("Expected 1 member and 1 message, got %d and %d", got.MemberCount, got.MessageCount)
+	}
+
+	// Simulate drift and let reconciliation correct it
+	if _, err := db.Exec(`UPDATE room_counters SET member_count = 7, message_count = 0, reconciled_at = NULL WHERE room_id = $1`, room.ID); err != nil {
+		t.Fatalf("Failed to corrupt counters: %v", err)
+	}
+
+	found := false
+	for i := 0; i < 100 && !found; i++ {
+		checked, drifts, err := repo.ReconcileCounters(ctx, 500)
+		if err != nil {
+			t.Fatalf("Failed to reconcile counters: %v", err)
+		}
+		for _, d := range drifts {
+			if d.RoomID == room.ID {
+				found = true
+				if d.MemberCount != 7 || d.ActualMemberCount != 1 || d.ActualMessageCount != 1 {
+					t.Errorf("Unexpected drift %+v", d)
+				}
+			}
+		}
+		if checked == 0 {
+			break
+		}
+	}
+	if !found {
+		t.Fatal("Expected the drifted room to be reported")
+	}
+
+	got, _ = repo.GetByIDWithMemberCount(ctx, room.ID)
+	if got.MemberCount != 1 || got.MessageCount != 1 {
+		t.Errorf("Expected counters to be corrected, got %d and %d", got.MemberCount, got.MessageCount)
+	}
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// RoomWebhookRepository stores room webhooks and their delivery log
+type RoomWebhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewRoomWebhookRepository(db *sqlx.DB) *RoomWebhookRepository {
+	return &RoomWebhookRepository{db: db}
+}
+
+// Create registers a webhook
+func (r *RoomWebhookRepository) Create(ctx context.Context, webhook *model.RoomWebhook) error {
+	query := `
+		INSERT INTO room_webhooks (room_id, url, secret, events, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		webhook.RoomID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+		webhook.CreatedBy,
+	).Scan(&webhook.ID, &webhook.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets a room's webhook by ID
+func (r *RoomWebhookRepository) GetByID(ctx context.Context, roomID, id string) (*model.RoomWebhook, error) {
+	var webhook model.RoomWebhook
+	query := `SELECT * FROM room_webhooks WHERE id = $1 AND room_id = $2`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &webhook, query, id, roomID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+// ListByRoomID lists a room's webhooks, oldest first
+func (r *RoomWebhookRepository) ListByRoomID(ctx context.Context, roomID string) ([]*model.RoomWebhook, error) {
+	query := `SELECT * FROM room_webhooks WHERE room_id = $1 ORDER BY created_at`
+
+	var webhooks []*model.RoomWebhook
+	if err := conn(ctx, r.db).SelectContext(ctx, &webhooks, query, roomID); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// CountByRoomID counts a room's webhooks
+func (r *RoomWebhookRepository) CountByRoomID(ctx context.Context, roomID string) (int, error) {
+	var count int
+	if err := conn(ctx, r.db).GetContext(ctx, &count, `SELECT COUNT(*) FROM room_webhooks WHERE room_id = $1`, roomID); err != nil {
+		return 0, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+
+	return count, nil
+}
+
+// Delete removes a room's webhook along with its delivery log
+func (r *RoomWebhookRepository) Delete(ctx context.Context, roomID, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM room_webhooks WHERE id = $1 AND room_id = $2`, id, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// Enqueue queues payload for every webhook of the room subscribed to event
+// and returns how many deliveries were queued
+func (r *RoomWebhookRepository) Enqueue(ctx context.Context, roomID, event string, payload []byte) (int64, error) {
+	query := `
+		INSERT INTO room_webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $2, $3 FROM room_webhooks
+		WHERE room_id = $1 AND $2 = ANY(events)`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, roomID, event, payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// ClaimDue takes up to limit due deliveries and counts the attempt. Claimed
+// deliveries are leased until now+lease, so a delivery abandoned by a
+// crashed instance is retried once the lease runs out.
+func (r *RoomWebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.RoomWebhookDeliveryTarget, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE room_webhook_deliveries
+			SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+			WHERE id IN (
+				SELECT id FROM room_webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT c.*, w.url, w.secret
+		FROM claimed c
+		INNER JOIN room_webhooks w ON w.id = c.webhook_id`
+
+	var deliveries []*model.RoomWebhookDeliveryTarget
+	if err := conn(ctx, r.db).SelectContext(ctx, &deliveries, query, limit, lease.Milliseconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// MarkDelivered records a successful delivery
+func (r *RoomWebhookRepository) MarkDelivered(ctx context.Context, id string, statusCode int) error {
+	query := `
+		UPDATE room_webhook_deliveries
+		SET status = 'delivered', last_status_code = $2, last_error = NULL, delivered_at = NOW()
+		WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, statusCode); err != nil {
+		return fmt.Errorf("failed to mark webhook delivery delivered: %w", err)
+	}
+
+	return nil
+}
+
+// MarkAttemptFailed records a failed attempt. The delivery is retried at
+// retryAt, or given up on when retryAt is nil.
+func (r *RoomWebhookRepository) MarkAttemptFailed(ctx context.Context, id string, statusCode sql.NullInt32, lastError string, retryAt *time.Time) error {
+	query := `
+		UPDATE room_webhook_deliveries
+		SET status = $2, last_status_code = $3, last_error = $4, next_attempt_at = COALESCE($5, next_attempt_at)
+		WHERE id = $1`
+
+	status := model.WebhookDeliveryPending
+	if retryAt == nil {
+		status = model.WebhookDeliveryFailed
+	}
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, status, statusCode, lastError, retryAt); err != nil {
+		return fmt.Errorf("failed to record webhook delivery failure: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeliveries lists a webhook's deliveries, newest first
+func (r *RoomWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit, offset int) ([]*model.RoomWebhookDelivery, error) {
+	query := `
+		SELECT * FROM room_webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	var deliveries []*model.RoomWebhookDelivery
+	if err := conn(ctx, r.db).SelectContext(ctx, &deliveries, query, webhookID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// PurgeSettled deletes delivered and failed deliveries created before the
+// cutoff and returns how many were removed
+func (r *RoomWebhookRepository) PurgeSettled(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM room_webhook_deliveries
+		WHERE status <> 'pending' AND created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook deliveries: %w", err)
+	}
+
+	return result.RowsAffected()
+}
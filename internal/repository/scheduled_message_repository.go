@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+
+type ScheduledMessageRepository struct {
+	db *sqlx.DB
+}
+
+func NewScheduledMessageRepository(db *sqlx.DB) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{db: db}
+}
+
+// Create stores a pending scheduled message
+func (r *ScheduledMessageRepository) Create(ctx context.Context, msg *model.ScheduledMessage) error {
+	query := `
+		INSERT INTO scheduled_messages (room_id, user_id, content, type, reply_to_id, scheduled_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		msg.RoomID,
+		msg.UserID,
+		msg.Content,
+		msg.Type,
+		msg.ReplyToID,
+		msg.ScheduledAt,
+	).Scan(&msg.ID, &msg.Status, &msg.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create scheduled message: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets a scheduled message by ID
+func (r *ScheduledMessageRepository) GetByID(ctx context.Context, id string) (*model.ScheduledMessage, error) {
+	var msg model.ScheduledMessage
+	query := `SELECT * FROM scheduled_messages WHERE id = $1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &msg, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrScheduledMessageNotFound
+		}
+		return nil, fmt.Errorf("failed to get scheduled message: %w", err)
+	}
+
+	return &msg, nil
+}
+
+// ListPending lists a user's pending messages in a room, soonest first
+func (r *ScheduledMessageRepository) ListPending(ctx context.Context, roomID, userID string, limit, offset int) ([]*model.ScheduledMessage, error) {
+	query := `
+		SELECT * FROM scheduled_messages
+		WHERE room_id = $1 AND user_id = $2 AND status = 'pending'
+		ORDER BY scheduled_at ASC
+		LIMIT $3 OFFSET $4`
+
+	var msgs []*model.ScheduledMessage
+	if err := conn(ctx, r.db).SelectContext(ctx, &msgs, query, roomID, userID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled messages: %w", err)
+	}
+
+	return msgs, nil
+}
+
+// Cancel cancels a pending message. It returns ErrScheduledMessageNotFound
+// when the message has already been claimed for delivery.
+func (r *ScheduledMessageRepository) Cancel(ctx context.Context, id string) error {
+	query := `UPDATE scheduled_messages SET status = 'canceled' WHERE id = $1 AND status = 'pending'`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled message: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrScheduledMessageNotFound
+	}
+
+	return nil
+}
+
+// ClaimDue marks up to limit due messages as sending and returns them.
+// SKIP LOCKED lets several instances sweep concurrently without sending
+// the same message twice.
+func (r *ScheduledMessageRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]*model.ScheduledMessage, error) {
+	query := `
+		UPDATE scheduled_messages
+		SET status = 'sending', claimed_at = $1
+		WHERE id IN (
+			SELECT id FROM scheduled_messages
+			WHERE status = 'pending' AND scheduled_at <= $1
+			ORDER BY scheduled_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	var msgs []*model.ScheduledMessage
+	if err := conn(ctx, r.db).SelectContext(ctx, &msgs, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to claim scheduled messages: %w", err)
+	}
+
+	return msgs, nil
+}
+
+// MarkSent records the message created for a scheduled message
+func (r *ScheduledMessageRepository) MarkSent(ctx context.Context, id, messageID string) error {
+	query := `
+		UPDATE scheduled_messages
+		SET status = 'sent', message_id = $2, sent_at = NOW()
+		WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, messageID); err != nil {
+		return fmt.Errorf("failed to mark scheduled message sent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records why a scheduled message could not be sent
+func (r *ScheduledMessageRepository) MarkFailed(ctx context.Context, id, reason string) error {
+	query := `UPDATE scheduled_messages SET status = 'failed', error = $2 WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, reason); err != nil {
+		return fmt.Errorf("failed to mark scheduled message failed: %w", err)
+	}
+	return nil
+}
+
+// ReleaseStale returns messages stuck in sending, e.g. after a crash
+// mid-delivery, to the pending queue
+func (r *ScheduledMessageRepository) ReleaseStale(ctx context.Context, claimedBefore time.Time) (int64, error) {
+	query := `
+		UPDATE scheduled_messages
+		SET status = 'pending', claimed_at = NULL
+		WHERE status = 'sending' AND claimed_at < $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, claimedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release stale scheduled messages: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSCIMGroupNotFound      = errors.New("scim group not found")
+	ErrSCIMGroupAlreadyExists = errors.New("scim group already exists")
+	ErrSCIMExternalIDTaken    = errors.New("scim external id already in use")
+)
+
+// SCIMUserFilter narrows a user listing; empty fields match everything
+type SCIMUserFilter struct {
+	ID         string
+	Username   string
+	Email      string
+	ExternalID string
+}
+
+// SCIMGroupFilter narrows a group listing; empty fields match everything
+type SCIMGroupFilter struct {
+	ID          string
+	DisplayName string
+	ExternalID  string
+}
+
+// SCIMRepository stores the account state and groups SCIM provisioning
+// manages
+type SCIMRepository struct {
+	db *sqlx.DB
+}
+
+func NewSCIMRepository(db *sqlx.DB) *SCIMRepository {
+	return &SCIMRepository{db: db}
+}
+
+// scimUserSelect lists accounts with their provisioning state. Deleted
+// accounts, bots and accounts removed through SCIM are left out.
+const scimUserSelect = `
+	SELECT u.*, s.external_id AS scim_external_id, COALESCE(s.active, TRUE) AS scim_active
+	FROM users u
+	LEFT JOIN scim_users s ON s.user_id = u.id
+	WHERE u.deleted_at IS NULL AND NOT u.is_bot AND s.deleted_at IS NULL`
+
+// ListUsers lists accounts matching filter by creation and counts all matches
+func (r *SCIMRepository) ListUsers(ctx context.Context, filter SCIMUserFilter, limit, offset int) ([]*model.SCIMUser, int, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	add("u.id::text = $%d", filter.ID)
+	add("LOWER(u.username) = LOWER($%d)", filter.Username)
+	add("LOWER(u.email) = LOWER($%d)", filter.Email)
+	add("s.external_id = $%d", filter.ExternalID)
+
+	query := scimUserSelect
+	for _, cond := range where {
+		query += " AND " + cond
+	}
+
+	var total int
+	if err := conn(ctx, r.db).GetContext(ctx, &total, "SELECT COUNT(*) FROM ("+query+") matches", args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scim users: %w", err)
+	}
+
+	users := []*model.SCIMUser{}
+	query += fmt.Sprintf(" ORDER BY u.created_at, u.id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("failed to list scim users: %w", err)
+	}
+	return users, total, nil
+}
+
+// GetUser retrieves an account with its provisioning state
+func (r *SCIMRepository) GetUser(ctx context.Context, userID string) (*model.SCIMUser, error) {
+	var user model.SCIMUser
+	if err := conn(ctx, r.db).GetContext(ctx, &user, scimUserSelect+" AND u.id = $1", userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get scim user: %w", err)
+	}
+	return &user, nil
+}
+
+// SaveUser records the provisioning state of an account, bringing back
+// an account that was removed through SCIM
+func (r *SCIMRepository) SaveUser(ctx context.Context, userID string, externalID sql.NullString, active bool) error {
+	query := `
+		INSERT INTO scim_users (user_id, external_id, active)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET external_id = EXCLUDED.external_id, active = EXCLUDED.active, deleted_at = NULL, updated_at = NOW()`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, externalID, active); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrSCIMExternalIDTaken
+		}
+		return fmt.Errorf("failed to save scim user: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser removes an account from SCIM. The account stays, locked.
+func (r *SCIMRepository) DeleteUser(ctx context.Context, userID string) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO scim_users (user_id, active, deleted_at)
+		VALUES ($1, FALSE, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET active = FALSE, external_id = NULL, deleted_at = NOW(), updated_at = NOW()`, userID); err != nil {
+		return fmt.Errorf("failed to delete scim user: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scim_group_members WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete scim group memberships: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit scim user deletion: %w", err)
+	}
+	return nil
+}
+
+// IsInactive reports whether SCIM deactivated or removed an account
+func (r *SCIMRepository) IsInactive(ctx context.Context, userID string) (bool, error) {
+	var inactive bool
+	query := `SELECT EXISTS(SELECT 1 FROM scim_users WHERE user_id = $1 AND NOT active)`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &inactive, query, userID); err != nil {
+		return false, fmt.Errorf("failed to check scim user: %w", err)
+	}
+	return inactive, nil
+}
+
+// ListGroups lists groups matching filter by name and counts all matches
+func (r *SCIMRepository) ListGroups(ctx context.Context, filter SCIMGroupFilter, limit, offset int) ([]*model.SCIMGroup, int, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	add("id::text = $%d", filter.ID)
+	add("LOWER(display_name) = LOWER($%d)", filter.DisplayName)
+	add("external_id = $%d", filter.ExternalID)
+
+	query := `SELECT * FROM scim_groups`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := conn(ctx, r.db).GetContext(ctx, &total, "SELECT COUNT(*) FROM ("+query+") matches", args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scim groups: %w", err)
+	}
+
+	groups := []*model.SCIMGroup{}
+	query += fmt.Sprintf(" ORDER BY display_name, id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	if err := conn(ctx, r.db).SelectContext(ctx, &groups, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("failed to list scim groups: %w", err)
+	}
+	return groups, total, nil
+}
+
+// GetGroup retrieves a group without its members
+func (r *SCIMRepository) GetGroup(ctx context.Context, id string) (*model.SCIMGroup, error) {
+	var group model.SCIMGroup
+	if err := conn(ctx, r.db).GetContext(ctx, &group, `SELECT * FROM scim_groups WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSCIMGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to get scim group: %w", err)
+	}
+	return &group, nil
+}
+
+// CreateGroup creates a group
+func (r *SCIMRepository) CreateGroup(ctx context.Context, group *model.SCIMGroup) error {
+	query := `
+		INSERT INTO scim_groups (display_name, external_id)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query, group.DisplayName, group.ExternalID).
+		Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrSCIMGroupAlreadyExists
+		}
+		return fmt.Errorf("failed to create scim group: %w", err)
+	}
+	return nil
+}
+
+// UpdateGroup renames a group and sets its external ID
+func (r *SCIMRepository) UpdateGroup(ctx context.Context, group *model.SCIMGroup) error {
+	query := `
+		UPDATE scim_groups SET display_name = $2, external_id = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query, group.ID, group.DisplayName, group.ExternalID).Scan(&group.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSCIMGroupNotFound
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrSCIMGroupAlreadyExists
+		}
+		return fmt.Errorf("failed to update scim group: %w", err)
+	}
+	return nil
+}
+
+// DeleteGroup deletes a group and its memberships
+func (r *SCIMRepository) DeleteGroup(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM scim_groups WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete scim group: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrSCIMGroupNotFound
+	}
+	return nil
+}
+
+// ListGroupMembers lists the members of a group by username
+func (r *SCIMRepository) ListGroupMembers(ctx context.Context, groupID string) ([]*model.SCIMGroupMember, error) {
+	members := []*model.SCIMGroupMember{}
+	query := `
+		SELECT m.user_id, u.username
+		FROM scim_group_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = $1
+		ORDER BY u.username`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &members, query, groupID); err != nil {
+		return nil, fmt.Errorf("failed to list scim group members: %w", err)
+	}
+	return members, nil
+}
+
+// AddGroupMembers adds users to a group, skipping existing members
+func (r *SCIMRepository) AddGroupMembers(ctx context.Context, groupID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	query := `
+		INSERT INTO scim_group_members (group_id, user_id)
+		SELECT $1, unnest($2::uuid[])
+		ON CONFLICT (group_id, user_id) DO NOTHING`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, groupID, pq.Array(userIDs)); err != nil {
+		return fmt.Errorf("failed to add scim group members: %w", err)
+	}
+	return nil
+}
+
+// RemoveGroupMembers removes users from a group
+func (r *SCIMRepository) RemoveGroupMembers(ctx context.Context, groupID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	query := `DELETE FROM scim_group_members WHERE group_id = $1 AND user_id = ANY($2::uuid[])`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, groupID, pq.Array(userIDs)); err != nil {
+		return fmt.Errorf("failed to remove scim group members: %w", err)
+	}
+	return nil
+}
+
+// ListUserGroups lists the groups a user is a member of
+func (r *SCIMRepository) ListUserGroups(ctx context.Context, userID string) ([]*model.SCIMGroup, error) {
+	groups := []*model.SCIMGroup{}
+	query := `
+		SELECT g.*
+		FROM scim_groups g
+		JOIN scim_group_members m ON m.group_id = g.id
+		WHERE m.user_id = $1
+		ORDER BY g.display_name`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &groups, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list scim user groups: %w", err)
+	}
+	return groups, nil
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/pkg/search"
+	"github.com/jmoiron/sqlx"
+)
+
+type SearchRepository struct {
+	db *sqlx.DB
+}
+
+func NewSearchRepository(db *sqlx.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// CurrentConfig returns the text search configuration stored in search_settings
+func (r *SearchRepository) CurrentConfig(ctx context.Context) (string, error) {
+	var cfg string
+	if err := conn(ctx, r.db).GetContext(ctx, &cfg, `SELECT config::text FROM search_settings WHERE id = 1`); err != nil {
+		return "", fmt.Errorf("failed to get search config: %w", err)
+	}
+	return cfg, nil
+}
+
+// ApplyAnalyzer stores the analyzer's text search configuration and rebuilds
+// the message search vectors when it changed. It returns true if a rebuild ran.
+// ILIKE analyzers leave the stored configuration untouched.
+func (r *SearchRepository) ApplyAnalyzer(ctx context.Context, analyzer search.Analyzer) (bool, error) {
+	cfg := analyzer.TextSearchConfig()
+	if cfg == "" {
+		return false, nil
+	}
+
+	var exists bool
+	if err := conn(ctx, r.db).GetContext(ctx, &exists,
+		`SELECT EXISTS(SELECT 1 FROM pg_ts_config WHERE cfgname = $1)`, cfg); err != nil {
+		return false, fmt.Errorf("failed to check text search config: %w", err)
+	}
+	if !exists {
+		return false, fmt.Errorf("text search config %q is not installed for analyzer %q", cfg, analyzer)
+	}
+
+	current, err := r.CurrentConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	if current == cfg {
+		return false, nil
+	}
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE search_settings SET config = $1::regconfig, updated_at = NOW() WHERE id = 1`, cfg); err != nil {
+		return false, fmt.Errorf("failed to update search config: %w", err)
+	}
+
+	rebuild := `
+		INSERT INTO message_search (message_id, search_vector)
+		SELECT id, to_tsvector($1::regconfig, COALESCE(content, '')) FROM messages
+		ON CONFLICT (message_id) DO UPDATE SET search_vector = EXCLUDED.search_vector`
+	if _, err := tx.ExecContext(ctx, rebuild, cfg); err != nil {
+		return false, fmt.Errorf("failed to rebuild search vectors: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit search config: %w", err)
+	}
+	return true, nil
+}
+
+// PauseIndexing stops the message trigger from maintaining search vectors.
+// Messages written while paused are indexed by ResumeIndexing.
+func (r *SearchRepository) PauseIndexing(ctx context.Context) error {
+	query := `
+		UPDATE search_settings
+		SET indexing_enabled = FALSE, indexing_paused_at = NOW(), updated_at = NOW()
+		WHERE id = 1 AND indexing_enabled`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to pause search indexing: %w", err)
+	}
+	return nil
+}
+
+// ResumeIndexing re-enables the message trigger and indexes the messages
+// written or edited while indexing was paused. It returns how many were indexed.
+func (r *SearchRepository) ResumeIndexing(ctx context.Context) (int64, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var state struct {
+		Enabled  bool       `db:"indexing_enabled"`
+		PausedAt *time.Time `db:"indexing_paused_at"`
+	}
+	if err := tx.GetContext(ctx, &state,
+		`SELECT indexing_enabled, indexing_paused_at FROM search_settings WHERE id = 1 FOR UPDATE`); err != nil {
+		return 0, fmt.Errorf("failed to get search indexing state: %w", err)
+	}
+	if state.Enabled {
+		return 0, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE search_settings
+		SET indexing_enabled = TRUE, indexing_paused_at = NULL, updated_at = NOW()
+		WHERE id = 1`); err != nil {
+		return 0, fmt.Errorf("failed to resume search indexing: %w", err)
+	}
+
+	backfill := `
+		INSERT INTO message_search (message_id, search_vector)
+		SELECT m.id, to_tsvector((SELECT config FROM search_settings WHERE id = 1), COALESCE(m.content, ''))
+		FROM messages m
+		WHERE $1::timestamptz IS NULL OR m.created_at >= $1 OR m.updated_at >= $1
+		ON CONFLICT (message_id) DO UPDATE SET search_vector = EXCLUDED.search_vector`
+	result, err := tx.ExecContext(ctx, backfill, state.PausedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill search vectors: %w", err)
+	}
+	indexed, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit search indexing resume: %w", err)
+	}
+	return indexed, nil
+}
+
+// SearchOutboxEntry is a message whose search engine document is out of date
+type SearchOutboxEntry struct {
+	ID        int64  `db:"id"`
+	MessageID string `db:"message_id"`
+}
+
+// SetOutboxEnabled switches recording message changes for an external search
+// engine. Switching on queues every existing message so the index starts
+// complete; switching off drops what was queued. It returns how many
+// messages were queued.
+func (r *SearchRepository) SetOutboxEnabled(ctx context.Context, enabled bool) (int64, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var current bool
+	if err := tx.GetContext(ctx, &current,
+		`SELECT outbox_enabled FROM search_settings WHERE id = 1 FOR UPDATE`); err != nil {
+		return 0, fmt.Errorf("failed to get search outbox state: %w", err)
+	}
+	if current == enabled {
+		return 0, nil
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE search_settings SET outbox_enabled = $1, updated_at = NOW() WHERE id = 1`, enabled); err != nil {
+		return 0, fmt.Errorf("failed to update search outbox state: %w", err)
+	}
+
+	var queued int64
+	if enabled {
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO search_outbox (message_id) SELECT id FROM messages WHERE is_deleted = false`)
+		if err != nil {
+			return 0, fmt.Errorf("failed to backfill search outbox: %w", err)
+		}
+		queued, _ = result.RowsAffected()
+	} else if _, err := tx.ExecContext(ctx, `DELETE FROM search_outbox`); err != nil {
+		return 0, fmt.Errorf("failed to clear search outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit search outbox state: %w", err)
+	}
+	return queued, nil
+}
+
+// ClaimOutbox takes up to limit of the oldest queued message changes.
+// Claimed entries are leased until now+lease, so entries abandoned by a
+// crashed instance are picked up again once the lease runs out.
+func (r *SearchRepository) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*SearchOutboxEntry, error) {
+	query := `
+		UPDATE search_outbox
+		SET claimed_until = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM search_outbox
+			WHERE claimed_until IS NULL OR claimed_until <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, message_id`
+
+	var entries []*SearchOutboxEntry
+	if err := conn(ctx, r.db).SelectContext(ctx, &entries, query, limit, lease.Milliseconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim search outbox entries: %w", err)
+	}
+	return entries, nil
+}
+
+// DeleteOutbox removes processed outbox entries
+func (r *SearchRepository) DeleteOutbox(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`DELETE FROM search_outbox WHERE id IN (?)`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	if _, err := conn(ctx, r.db).ExecContext(ctx, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return fmt.Errorf("failed to delete search outbox entries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// SpamRepository stores what the spam sweep found
+type SpamRepository struct {
+	db *sqlx.DB
+}
+
+func NewSpamRepository(db *sqlx.DB) *SpamRepository {
+	return &SpamRepository{db: db}
+}
+
+// ListCandidates lists accounts created in [from, to) that are not admins,
+// bots, deleted, banned or already flagged, ordered by creation. Pass the
+// last candidate of the previous page as after to continue.
+func (r *SpamRepository) ListCandidates(ctx context.Context, from, to time.Time, after *model.SpamCandidate, limit int) ([]*model.SpamCandidate, error) {
+	afterTime, afterID := from, "00000000-0000-0000-0000-000000000000"
+	if after != nil {
+		afterTime, afterID = after.CreatedAt, after.UserID
+	}
+
+	query := `
+		SELECT u.id, u.email, u.created_at,
+			(SELECT COUNT(*) FROM messages m WHERE m.user_id = u.id)
+				+ (SELECT COUNT(*) FROM direct_messages d WHERE d.sender_id = u.id) AS message_count,
+			(SELECT COUNT(*) FROM friendships f WHERE f.user_id = u.id AND f.status = 'pending') AS pending_friend_requests
+		FROM users u
+		WHERE u.created_at >= $1 AND u.created_at < $2
+			AND (u.created_at, u.id) > ($3, $4::uuid)
+			AND NOT u.is_admin AND NOT u.is_bot AND u.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM spam_flags s WHERE s.user_id = u.id)
+			AND NOT EXISTS (
+				SELECT 1 FROM user_bans b
+				WHERE b.user_id = u.id AND b.revoked_at IS NULL
+					AND (b.expires_at IS NULL OR b.expires_at > NOW())
+			)
+		ORDER BY u.created_at, u.id
+		LIMIT $5`
+
+	var candidates []*model.SpamCandidate
+	if err := conn(ctx, r.db).SelectContext(ctx, &candidates, query, from, to, afterTime, afterID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list spam candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// CreateFlag records a flagged account. It reports false when the account
+// was already flagged.
+func (r *SpamRepository) CreateFlag(ctx context.Context, flag *model.SpamFlag) (bool, error) {
+	query := `
+		INSERT INTO spam_flags (user_id, score, signals, action)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING id, created_at`
+
+	rows, err := conn(ctx, r.db).QueryxContext(ctx, query, flag.UserID, flag.Score, flag.Signals, flag.Action)
+	if err != nil {
+		return false, fmt.Errorf("failed to create spam flag: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+	if err := rows.Scan(&flag.ID, &flag.CreatedAt); err != nil {
+		return false, fmt.Errorf("failed to create spam flag: %w", err)
+	}
+	return true, nil
+}
+
+// ListFlags lists flagged accounts, newest first
+func (r *SpamRepository) ListFlags(ctx context.Context, limit, offset int) ([]*model.SpamFlagWithUser, error) {
+	query := `
+		SELECT s.*, u.username, u.email
+		FROM spam_flags s
+		JOIN users u ON u.id = s.user_id
+		ORDER BY s.created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	var flags []*model.SpamFlagWithUser
+	if err := conn(ctx, r.db).SelectContext(ctx, &flags, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list spam flags: %w", err)
+	}
+
+	return flags, nil
+}
+
+// CreateRun records the outcome of a sweep
+func (r *SpamRepository) CreateRun(ctx context.Context, run *model.SpamSweepRun) error {
+	query := `
+		INSERT INTO spam_sweep_runs (started_at, finished_at, scanned, flagged, suspended)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		run.StartedAt, run.FinishedAt, run.Scanned, run.Flagged, run.Suspended,
+	).Scan(&run.ID); err != nil {
+		return fmt.Errorf("failed to create spam sweep run: %w", err)
+	}
+
+	return nil
+}
+
+// GetStats summarizes the flags and the latest sweep
+func (r *SpamRepository) GetStats(ctx context.Context) (*model.SpamStats, error) {
+	query := `
+		SELECT
+			COUNT(*) AS total_flagged,
+			COUNT(*) FILTER (WHERE action = 'suspended') AS total_suspended,
+			COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '24 hours') AS flagged_last_24h
+		FROM spam_flags`
+
+	var counts struct {
+		TotalFlagged   int `db:"total_flagged"`
+		TotalSuspended int `db:"total_suspended"`
+		FlaggedLast24h int `db:"flagged_last_24h"`
+	}
+	if err := conn(ctx, r.db).GetContext(ctx, &counts, query); err != nil {
+		return nil, fmt.Errorf("failed to count spam flags: %w", err)
+	}
+
+	stats := &model.SpamStats{
+		TotalFlagged:   counts.TotalFlagged,
+		TotalSuspended: counts.TotalSuspended,
+		FlaggedLast24h: counts.FlaggedLast24h,
+	}
+
+	var runs []*model.SpamSweepRun
+	if err := conn(ctx, r.db).SelectContext(ctx, &runs, `SELECT * FROM spam_sweep_runs ORDER BY started_at DESC LIMIT 1`); err != nil {
+		return nil, fmt.Errorf("failed to get last spam sweep run: %w", err)
+	}
+	if len(runs) > 0 {
+		stats.LastRun = runs[0]
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrExternalIdentityNotFound = errors.New("external identity not found")
+
+// SSORepository stores the external identities of enterprise sign-in and
+// the room memberships their group mapping granted
+type SSORepository struct {
+	db *sqlx.DB
+}
+
+func NewSSORepository(db *sqlx.DB) *SSORepository {
+	return &SSORepository{db: db}
+}
+
+// GetIdentity retrieves the identity a provider knows as subject
+func (r *SSORepository) GetIdentity(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error) {
+	var identity model.ExternalIdentity
+	query := `SELECT * FROM external_identities WHERE provider = $1 AND subject = $2`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &identity, query, provider, subject); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrExternalIdentityNotFound
+		}
+		return nil, fmt.Errorf("failed to get external identity: %w", err)
+	}
+
+	return &identity, nil
+}
+
+// CreateIdentity links an external identity to a user
+func (r *SSORepository) CreateIdentity(ctx context.Context, identity *model.ExternalIdentity) error {
+	query := `
+		INSERT INTO external_identities (provider, subject, user_id)
+		VALUES ($1, $2, $3)
+		RETURNING last_login_at, created_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		identity.Provider,
+		identity.Subject,
+		identity.UserID,
+	).Scan(&identity.LastLoginAt, &identity.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create external identity: %w", err)
+	}
+
+	return nil
+}
+
+// TouchIdentity records a sign-in with an external identity
+func (r *SSORepository) TouchIdentity(ctx context.Context, provider, subject string) error {
+	query := `UPDATE external_identities SET last_login_at = NOW() WHERE provider = $1 AND subject = $2`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, provider, subject); err != nil {
+		return fmt.Errorf("failed to touch external identity: %w", err)
+	}
+	return nil
+}
+
+// ListRoomGrants returns the rooms group mapping added a user to
+func (r *SSORepository) ListRoomGrants(ctx context.Context, userID string) ([]string, error) {
+	var roomIDs []string
+	query := `SELECT room_id FROM sso_room_grants WHERE user_id = $1 ORDER BY created_at`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &roomIDs, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list sso room grants: %w", err)
+	}
+	return roomIDs, nil
+}
+
+// AddRoomGrant records that group mapping added a user to a room
+func (r *SSORepository) AddRoomGrant(ctx context.Context, userID, roomID string) error {
+	query := `
+		INSERT INTO sso_room_grants (user_id, room_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, room_id) DO NOTHING`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, roomID); err != nil {
+		return fmt.Errorf("failed to add sso room grant: %w", err)
+	}
+	return nil
+}
+
+// DeleteRoomGrant forgets a room grant
+func (r *SSORepository) DeleteRoomGrant(ctx context.Context, userID, roomID string) error {
+	query := `DELETE FROM sso_room_grants WHERE user_id = $1 AND room_id = $2`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, roomID); err != nil {
+		return fmt.Errorf("failed to delete sso room grant: %w", err)
+	}
+	return nil
+}
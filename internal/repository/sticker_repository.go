@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrStickerPackNotFound = errors.New("sticker pack not found")
+	ErrStickerNotFound     = errors.New("sticker not found")
+)
+
+type StickerRepository struct {
+	db *sqlx.DB
+}
+
+func NewStickerRepository(db *sqlx.DB) *StickerRepository {
+	return &StickerRepository{db: db}
+}
+
+// CreatePack stores a sticker pack together with its stickers
+func (r *StickerRepository) CreatePack(ctx context.Context, pack *model.StickerPack) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		INSERT INTO sticker_packs (name, description, is_active, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	if err := tx.QueryRowxContext(ctx, query,
+		pack.Name,
+		pack.Description,
+		pack.IsActive,
+		pack.CreatedBy,
+	).Scan(&pack.ID, &pack.CreatedAt, &pack.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create sticker pack: %w", err)
+	}
+
+	stickerQuery := `
+		INSERT INTO stickers (pack_id, name, image_url, width, height, position)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	for i, sticker := range pack.Stickers {
+		sticker.PackID = pack.ID
+		sticker.Position = i
+		if err := tx.QueryRowxContext(ctx, stickerQuery,
+			sticker.PackID,
+			sticker.Name,
+			sticker.ImageURL,
+			sticker.Width,
+			sticker.Height,
+			sticker.Position,
+		).Scan(&sticker.ID, &sticker.CreatedAt); err != nil {
+			return fmt.Errorf("failed to create sticker: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sticker pack: %w", err)
+	}
+	return nil
+}
+
+// UpdatePack updates the name, description and availability of a pack
+func (r *StickerRepository) UpdatePack(ctx context.Context, pack *model.StickerPack) error {
+	query := `
+		UPDATE sticker_packs
+		SET name = $2, description = $3, is_active = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		pack.ID,
+		pack.Name,
+		pack.Description,
+		pack.IsActive,
+	).Scan(&pack.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStickerPackNotFound
+		}
+		return fmt.Errorf("failed to update sticker pack: %w", err)
+	}
+
+	return nil
+}
+
+// GetPack retrieves a sticker pack with its stickers
+func (r *StickerRepository) GetPack(ctx context.Context, id string) (*model.StickerPack, error) {
+	var pack model.StickerPack
+	if err := conn(ctx, r.db).GetContext(ctx, &pack, `SELECT * FROM sticker_packs WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStickerPackNotFound
+		}
+		return nil, fmt.Errorf("failed to get sticker pack: %w", err)
+	}
+
+	if err := r.attachStickers(ctx, []*model.StickerPack{&pack}); err != nil {
+		return nil, err
+	}
+	return &pack, nil
+}
+
+// ListPacks lists sticker packs with their stickers, oldest first
+func (r *StickerRepository) ListPacks(ctx context.Context, includeInactive bool) ([]*model.StickerPack, error) {
+	var packs []*model.StickerPack
+	query := `
+		SELECT * FROM sticker_packs
+		WHERE is_active OR $1
+		ORDER BY created_at, id`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &packs, query, includeInactive); err != nil {
+		return nil, fmt.Errorf("failed to list sticker packs: %w", err)
+	}
+
+	if err := r.attachStickers(ctx, packs); err != nil {
+		return nil, err
+	}
+	return packs, nil
+}
+
+// attachStickers loads the stickers of packs in one query
+func (r *StickerRepository) attachStickers(ctx context.Context, packs []*model.StickerPack) error {
+	if len(packs) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*model.StickerPack, len(packs))
+	ids := make([]string, 0, len(packs))
+	for _, pack := range packs {
+		pack.Stickers = []*model.Sticker{}
+		byID[pack.ID] = pack
+		ids = append(ids, pack.ID)
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT * FROM stickers
+		WHERE pack_id IN (?)
+		ORDER BY pack_id, position`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var stickers []*model.Sticker
+	if err := conn(ctx, r.db).SelectContext(ctx, &stickers, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return fmt.Errorf("failed to list stickers: %w", err)
+	}
+
+	for _, sticker := range stickers {
+		if pack, ok := byID[sticker.PackID]; ok {
+			pack.Stickers = append(pack.Stickers, sticker)
+		}
+	}
+	return nil
+}
+
+// GetActiveSticker retrieves a sticker that may be sent, one whose pack is
+// active
+func (r *StickerRepository) GetActiveSticker(ctx context.Context, id string) (*model.Sticker, error) {
+	var sticker model.Sticker
+	query := `
+		SELECT s.* FROM stickers s
+		JOIN sticker_packs p ON p.id = s.pack_id
+		WHERE s.id = $1 AND p.is_active`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &sticker, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStickerNotFound
+		}
+		return nil, fmt.Errorf("failed to get sticker: %w", err)
+	}
+
+	return &sticker, nil
+}
+
+// ListStickersByIDs retrieves stickers by ID whatever the state of their
+// pack; missing IDs are skipped
+func (r *StickerRepository) ListStickersByIDs(ctx context.Context, ids []string) ([]*model.Sticker, error) {
+	if len(ids) == 0 {
+		return []*model.Sticker{}, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT * FROM stickers WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var stickers []*model.Sticker
+	if err := conn(ctx, r.db).SelectContext(ctx, &stickers, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list stickers by ids: %w", err)
+	}
+
+	return stickers, nil
+}
@@ -47,7 +47,7 @@ func CleanupTestDataByPrefix(t *testing.T, db *sqlx.DB, prefix string) {
 	ctx := context.Background()
 
 	// 按照外鍵依賴順序刪除
-	_, _ = db.ExecContext(ctx, "DELETE FROM message_attachments WHERE message_id IN (SELECT id FROM messages WHERE content LIKE $1)", prefix+"%")
+	_, _ = db.ExecContext(ctx, "DELETE FROM attachments WHERE message_id IN (SELECT id FROM messages WHERE content LIKE $1)", prefix+"%")
 	_, _ = db.ExecContext(ctx, "DELETE FROM notifications WHERE user_id IN (SELECT id FROM users WHERE username LIKE $1)", prefix+"%")
 	_, _ = db.ExecContext(ctx, "DELETE FROM direct_messages WHERE sender_id IN (SELECT id FROM users WHERE username LIKE $1)", prefix+"%")
 	_, _ = db.ExecContext(ctx, "DELETE FROM direct_messages WHERE receiver_id IN (SELECT id FROM users WHERE username LIKE $1)", prefix+"%")
@@ -57,6 +57,7 @@ func CleanupTestDataByPrefix(t *testing.T, db *sqlx.DB, prefix string) {
 	_, _ = db.ExecContext(ctx, "DELETE FROM blocked_users WHERE blocked_id IN (SELECT id FROM users WHERE username LIKE $1)", prefix+"%")
 	_, _ = db.ExecContext(ctx, "DELETE FROM friendships WHERE user_id IN (SELECT id FROM users WHERE username LIKE $1)", prefix+"%")
 	_, _ = db.ExecContext(ctx, "DELETE FROM friendships WHERE friend_id IN (SELECT id FROM users WHERE username LIKE $1)", prefix+"%")
+	_, _ = db.ExecContext(ctx, "DELETE FROM legal_holds WHERE placed_by IN (SELECT id FROM users WHERE username LIKE $1)", prefix+"%")
 	_, _ = db.ExecContext(ctx, "DELETE FROM rooms WHERE owner_id IN (SELECT id FROM users WHERE username LIKE $1)", prefix+"%")
 	_, _ = db.ExecContext(ctx, "DELETE FROM rooms WHERE name LIKE $1", prefix+"%")
 	_, _ = db.ExecContext(ctx, "DELETE FROM users WHERE username LIKE $1", prefix+"%")
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// queryer is what repositories run their statements on, either the pool or
+// the transaction carried by the context
+type queryer interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+type txKey struct{}
+
+// conn returns the transaction started by TxManager.WithinTx for ctx, or db
+// when the call is not part of one
+func conn(ctx context.Context, db *sqlx.DB) queryer {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// localTx is the transaction a repository method runs its statements in.
+// When the context already carries one from WithinTx the method joins it,
+// and Commit and Rollback are left to WithinTx.
+type localTx struct {
+	*sqlx.Tx
+	joined bool
+}
+
+func (t *localTx) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *localTx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// beginTx starts a transaction for a repository method that needs several
+// statements to apply together, or joins the one carried by ctx
+func beginTx(ctx context.Context, db *sqlx.DB) (*localTx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return &localTx{Tx: tx, joined: true}, nil
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &localTx{Tx: tx}, nil
+}
+
+// TxManager runs several repository calls as one unit of work
+type TxManager struct {
+	db *sqlx.DB
+}
+
+func NewTxManager(db *sqlx.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithinTx runs fn in a transaction that repositories pick up from the
+// context fn receives. The transaction commits when fn returns nil and
+// rolls back otherwise. A nested call joins the outer transaction.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+func TestTxManager_WithinTx_Commit(t *testing.T) {
+	db, prefix := setupRoomTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupRoomTestByPrefix(t, db, prefix)
+
+	user := createTestUserForRoomIsolated(t, db, prefix, "owner")
+	repo := NewRoomRepository(db)
+	txManager := NewTxManager(db)
+	ctx := context.Background()
+
+	room := &model.Room{Name: prefix + "tx_room", Type: model.RoomTypePublic, OwnerID: user.ID, MaxMembers: 100}
+	err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, room); err != nil {
+			return err
+		}
+		return repo.AddMember(ctx, &model.RoomMember{RoomID: room.ID, UserID: user.ID, Role: model.MemberRoleOwner})
+	})
+	if err != nil {
+		t.Fatalf("WithinTx failed: %v", err)
+	}
+
+	isMember, err := repo.IsMember(ctx, room.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to check membership: %v", err)
+	}
+	if !isMember {
+		t.Error("Expected owner to be a member after commit")
+	}
+}
+
+func TestTxManager_WithinTx_Rollback(t *testing.T) {
+	db, prefix := setupRoomTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupRoomTestByPrefix(t, db, prefix)
+
+	user := createTestUserForRoomIsolated(t, db, prefix, "owner")
+	repo := NewRoomRepository(db)
+	txManager := NewTxManager(db)
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	room := &model.Room{Name: prefix + "tx_room", Type: model.RoomTypePublic, OwnerID: user.ID, MaxMembers: 100}
+	err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, room); err != nil {
+			return err
+		}
+		// A nested unit of work joins the outer transaction
+		return txManager.WithinTx(ctx, func(ctx context.Context) error {
+			if _, err := repo.GetByID(ctx, room.ID); err != nil {
+				return err
+			}
+			return errBoom
+		})
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Expected errBoom, got %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, room.ID); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound after rollback, got %v", err)
+	}
+}
+
+func TestTxManager_WithinTx_RollbackAcrossRepositories(t *testing.T) {
+	db, prefix := setupRoomTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupRoomTestByPrefix(t, db, prefix)
+
+	owner := createTestUserForRoomIsolated(t, db, prefix, "owner")
+	friend := createTestUserForRoomIsolated(t, db, prefix, "friend")
+	roomRepo := NewRoomRepository(db)
+	friendshipRepo := NewFriendshipRepository(db)
+	txManager := NewTxManager(db)
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	room := &model.Room{Name: prefix + "tx_room", Type: model.RoomTypePublic, OwnerID: owner.ID, MaxMembers: 100}
+	err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := roomRepo.Create(ctx, room); err != nil {
+			return err
+		}
+		if err := friendshipRepo.Create(ctx, owner.ID, friend.ID); err != nil {
+			return err
+		}
+		// Accept runs several statements and joins the outer transaction
+		// instead of committing its own
+		if err := friendshipRepo.Accept(ctx, owner.ID, friend.ID); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Expected errBoom, got %v", err)
+	}
+
+	if _, err := roomRepo.GetByID(ctx, room.ID); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound after rollback, got %v", err)
+	}
+	areFriends, err := friendshipRepo.AreFriends(ctx, owner.ID, friend.ID)
+	if err != nil {
+		t.Fatalf("Failed to check friendship: %v", err)
+	}
+	if areFriends {
+		t.Error("Expected the accepted friendship to be rolled back")
+	}
+	if _, err := friendshipRepo.GetFriendship(ctx, owner.ID, friend.ID); err == nil {
+		t.Error("Expected the friend request to be rolled back")
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+	ErrUploadOffsetConflict  = errors.New("upload offset does not match received size")
+)
+
+type UploadSessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewUploadSessionRepository(db *sqlx.DB) *UploadSessionRepository {
+	return &UploadSessionRepository{db: db}
+}
+
+// Create stores a new, empty upload session
+func (r *UploadSessionRepository) Create(ctx context.Context, session *model.UploadSession) error {
+	query := `
+		INSERT INTO upload_sessions (user_id, category, file_name, content_type, total_size, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, received_size, created_at, updated_at`
+
+	if err := conn(ctx, r.db).QueryRowxContext(ctx, query,
+		session.UserID,
+		session.Category,
+		session.FileName,
+		session.ContentType,
+		session.TotalSize,
+		session.ExpiresAt,
+	).Scan(&session.ID, &session.ReceivedSize, &session.CreatedAt, &session.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID gets an upload session that has not expired
+func (r *UploadSessionRepository) GetByID(ctx context.Context, id string) (*model.UploadSession, error) {
+	var session model.UploadSession
+	query := `SELECT * FROM upload_sessions WHERE id = $1 AND expires_at > NOW()`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &session, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUploadSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get upload session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// ListPending lists a user's unfinished, unexpired upload sessions, oldest first
+func (r *UploadSessionRepository) ListPending(ctx context.Context, userID string, limit int) ([]*model.UploadSession, error) {
+	query := `
+		SELECT * FROM upload_sessions
+		WHERE user_id = $1 AND completed_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at ASC
+		LIMIT $2`
+
+	var sessions []*model.UploadSession
+	if err := conn(ctx, r.db).SelectContext(ctx, &sessions, query, userID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list pending upload sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Advance records that the bytes up to received arrived and pushes the
+// expiry out. It only applies when the session is still at offset, so of two
+// clients racing on the same chunk exactly one wins.
+func (r *UploadSessionRepository) Advance(ctx context.Context, id string, offset, received int64, expiresAt time.Time) (*model.UploadSession, error) {
+	query := `
+		UPDATE upload_sessions
+		SET received_size = $3, expires_at = $4, updated_at = NOW()
+		WHERE id = $1 AND received_size = $2 AND completed_at IS NULL
+		RETURNING *`
+
+	var session model.UploadSession
+	if err := conn(ctx, r.db).GetContext(ctx, &session, query, id, offset, received, expiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUploadOffsetConflict
+		}
+		return nil, fmt.Errorf("failed to advance upload session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Complete marks a session complete with the name the file was stored under
+func (r *UploadSessionRepository) Complete(ctx context.Context, id, storedName string) (*model.UploadSession, error) {
+	query := `
+		UPDATE upload_sessions
+		SET stored_name = $2, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND completed_at IS NULL
+		RETURNING *`
+
+	var session model.UploadSession
+	if err := conn(ctx, r.db).GetContext(ctx, &session, query, id, storedName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUploadSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to complete upload session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Delete removes an upload session
+func (r *UploadSessionRepository) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM upload_sessions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete upload session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUploadSessionNotFound
+	}
+
+	return nil
+}
+
+// DeleteExpired removes up to limit expired sessions and returns them so
+// their partial files can be cleaned up
+func (r *UploadSessionRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) ([]*model.UploadSession, error) {
+	query := `
+		DELETE FROM upload_sessions
+		WHERE id IN (
+			SELECT id FROM upload_sessions
+			WHERE expires_at <= $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	var sessions []*model.UploadSession
+	if err := conn(ctx, r.db).SelectContext(ctx, &sessions, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to delete expired upload sessions: %w", err)
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// UploadSettingsRepository stores the upload limits admins override at
+// runtime
+type UploadSettingsRepository struct {
+	db *sqlx.DB
+}
+
+func NewUploadSettingsRepository(db *sqlx.DB) *UploadSettingsRepository {
+	return &UploadSettingsRepository{db: db}
+}
+
+const uploadSettingsColumns = `image_max_size, image_allowed_types, file_max_size, file_allowed_types,
+	avatar_max_size, avatar_allowed_types, updated_by, updated_at`
+
+// Get returns the current overrides
+func (r *UploadSettingsRepository) Get(ctx context.Context) (*model.UploadSettings, error) {
+	var settings model.UploadSettings
+	query := `SELECT ` + uploadSettingsColumns + ` FROM upload_settings WHERE id = 1`
+
+	if err := conn(ctx, r.db).GetContext(ctx, &settings, query); err != nil {
+		return nil, fmt.Errorf("failed to get upload settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// Update replaces the overrides
+func (r *UploadSettingsRepository) Update(ctx context.Context, settings *model.UploadSettings) error {
+	query := `
+		UPDATE upload_settings
+		SET image_max_size = $1, image_allowed_types = $2,
+			file_max_size = $3, file_allowed_types = $4,
+			avatar_max_size = $5, avatar_allowed_types = $6,
+			updated_by = $7, updated_at = NOW()
+		WHERE id = 1
+		RETURNING ` + uploadSettingsColumns
+
+	if err := conn(ctx, r.db).GetContext(ctx, settings, query,
+		settings.ImageMaxSize,
+		settings.ImageAllowedTypes,
+		settings.FileMaxSize,
+		settings.FileAllowedTypes,
+		settings.AvatarMaxSize,
+		settings.AvatarAllowedTypes,
+		settings.UpdatedBy,
+	); err != nil {
+		return fmt.Errorf("failed to update upload settings: %w", err)
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-demo/chat/internal/model"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 var (
@@ -26,11 +27,11 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 	query := `
-		INSERT INTO users (username, email, password_hash, display_name, avatar_url, status, bio)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO users (username, email, password_hash, display_name, avatar_url, status, bio, is_admin)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at, updated_at`
 
-	return r.db.QueryRowxContext(ctx, query,
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query,
 		user.Username,
 		user.Email,
 		user.PasswordHash,
@@ -38,7 +39,16 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 		user.AvatarURL,
 		user.Status,
 		user.Bio,
+		user.IsAdmin,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
 }
 
 // GetByID retrieves a user by ID
@@ -46,7 +56,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*model.User, e
 	var user model.User
 	query := `SELECT * FROM users WHERE id = $1`
 
-	if err := r.db.GetContext(ctx, &user, query, id); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &user, query, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
@@ -61,7 +71,7 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 	var user model.User
 	query := `SELECT * FROM users WHERE username = $1`
 
-	if err := r.db.GetContext(ctx, &user, query, username); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &user, query, username); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
@@ -76,7 +86,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.U
 	var user model.User
 	query := `SELECT * FROM users WHERE email = $1`
 
-	if err := r.db.GetContext(ctx, &user, query, email); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &user, query, email); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
@@ -94,7 +104,7 @@ func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
 		WHERE id = $1
 		RETURNING updated_at`
 
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := conn(ctx, r.db).ExecContext(ctx, query,
 		user.ID,
 		user.DisplayName,
 		user.AvatarURL,
@@ -116,11 +126,32 @@ func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
 	return nil
 }
 
+// UpdateAvatar sets a user's avatar and returns the previous one, empty when
+// the user had none
+func (r *UserRepository) UpdateAvatar(ctx context.Context, userID, avatarURL string) (string, error) {
+	query := `
+		UPDATE users u
+		SET avatar_url = $2
+		FROM (SELECT id, avatar_url FROM users WHERE id = $1 FOR UPDATE) old
+		WHERE u.id = old.id
+		RETURNING COALESCE(old.avatar_url, '')`
+
+	var previous string
+	if err := conn(ctx, r.db).GetContext(ctx, &previous, query, userID, avatarURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to update avatar: %w", err)
+	}
+
+	return previous, nil
+}
+
 // UpdatePassword updates user password
 func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
 	query := `UPDATE users SET password_hash = $2 WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, userID, passwordHash)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, passwordHash)
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
@@ -136,11 +167,22 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHas
 	return nil
 }
 
+// RehashPassword replaces a password hash with an upgraded one, unless the
+// password changed since oldHash was read
+func (r *UserRepository) RehashPassword(ctx context.Context, userID, oldHash, newHash string) error {
+	query := `UPDATE users SET password_hash = $3 WHERE id = $1 AND password_hash = $2`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, oldHash, newHash); err != nil {
+		return fmt.Errorf("failed to rehash password: %w", err)
+	}
+	return nil
+}
+
 // UpdateStatus updates user online status
 func (r *UserRepository) UpdateStatus(ctx context.Context, userID string, status model.UserStatus) error {
 	query := `UPDATE users SET status = $2, last_seen_at = NOW() WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, userID, status)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, status)
 	if err != nil {
 		return fmt.Errorf("failed to update status: %w", err)
 	}
@@ -156,11 +198,145 @@ func (r *UserRepository) UpdateStatus(ctx context.Context, userID string, status
 	return nil
 }
 
+// UpdateLastSeenVisibility sets who may see when a user was last online
+func (r *UserRepository) UpdateLastSeenVisibility(ctx context.Context, userID string, visibility model.LastSeenVisibility) error {
+	query := `UPDATE users SET last_seen_visibility = $2 WHERE id = $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, visibility)
+	if err != nil {
+		return fmt.Errorf("failed to update last seen visibility: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateDiscoverable sets whether contacts discovery may find a user
+func (r *UserRepository) UpdateDiscoverable(ctx context.Context, userID string, discoverable bool) error {
+	query := `UPDATE users SET discoverable = $2 WHERE id = $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, discoverable)
+	if err != nil {
+		return fmt.Errorf("failed to update discoverable: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateEmail changes a user's email address
+func (r *UserRepository) UpdateEmail(ctx context.Context, userID, email string) error {
+	query := `UPDATE users SET email = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, email)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateIsAdmin grants or revokes a user's administrator role
+func (r *UserRepository) UpdateIsAdmin(ctx context.Context, userID string, isAdmin bool) error {
+	query := `UPDATE users SET is_admin = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, isAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to update is_admin: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateDigestFrequency sets how often a user is emailed a digest
+func (r *UserRepository) UpdateDigestFrequency(ctx context.Context, userID string, frequency model.DigestFrequency) error {
+	query := `UPDATE users SET digest_frequency = $2 WHERE id = $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, frequency)
+	if err != nil {
+		return fmt.Errorf("failed to update digest frequency: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// FindDiscoverable returns the users whose email hash is among hashes,
+// leaving out the viewer, users who opted out of discovery, deleted users,
+// bots and users blocked by or blocking the viewer
+func (r *UserRepository) FindDiscoverable(ctx context.Context, viewerID string, hashes []string) ([]*model.User, error) {
+	if len(hashes) == 0 {
+		return []*model.User{}, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT u.* FROM users u
+		WHERE u.email_hash IN (?)
+		  AND u.discoverable = TRUE AND u.deleted_at IS NULL AND u.is_bot = FALSE
+		  AND u.id <> ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM blocked_users b
+			WHERE (b.blocker_id = ? AND b.blocked_id = u.id) OR (b.blocker_id = u.id AND b.blocked_id = ?)
+		  )
+		ORDER BY u.username`, hashes, viewerID, viewerID, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build discovery query: %w", err)
+	}
+
+	var users []*model.User
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, conn(ctx, r.db).Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to find discoverable users: %w", err)
+	}
+
+	return users, nil
+}
+
 // Delete deletes a user
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM users WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -180,14 +356,14 @@ func (r *UserRepository) Delete(ctx context.Context, id string) error {
 func (r *UserRepository) Search(ctx context.Context, query string, limit, offset int) ([]*model.User, error) {
 	searchQuery := `
 		SELECT * FROM users
-		WHERE username ILIKE $1 OR display_name ILIKE $1
+		WHERE (username ILIKE $1 OR display_name ILIKE $1) AND deleted_at IS NULL
 		ORDER BY username
 		LIMIT $2 OFFSET $3`
 
 	var users []*model.User
 	pattern := "%" + query + "%"
 
-	if err := r.db.SelectContext(ctx, &users, searchQuery, pattern, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, searchQuery, pattern, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
 
@@ -199,7 +375,7 @@ func (r *UserRepository) ExistsByUsername(ctx context.Context, username string)
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)`
 
-	if err := r.db.GetContext(ctx, &exists, query, username); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &exists, query, username); err != nil {
 		return false, fmt.Errorf("failed to check username exists: %w", err)
 	}
 
@@ -211,13 +387,42 @@ func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool,
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
 
-	if err := r.db.GetContext(ctx, &exists, query, email); err != nil {
+	if err := conn(ctx, r.db).GetContext(ctx, &exists, query, email); err != nil {
 		return false, fmt.Errorf("failed to check email exists: %w", err)
 	}
 
 	return exists, nil
 }
 
+// FindByUsernamesOrEmails returns the users holding any of the given
+// usernames or emails, used to detect conflicts before a bulk import
+func (r *UserRepository) FindByUsernamesOrEmails(ctx context.Context, usernames, emails []string) ([]*model.User, error) {
+	if len(usernames) == 0 && len(emails) == 0 {
+		return []*model.User{}, nil
+	}
+	// IN () is invalid SQL, so pad empty lists with a value that never matches
+	if len(usernames) == 0 {
+		usernames = []string{""}
+	}
+	if len(emails) == 0 {
+		emails = []string{""}
+	}
+
+	query, args, err := sqlx.In(`SELECT * FROM users WHERE username IN (?) OR email IN (?)`, usernames, emails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	query = conn(ctx, r.db).Rebind(query)
+	var users []*model.User
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to find users by usernames or emails: %w", err)
+	}
+
+	return users, nil
+}
+
 // GetOnlineUsers gets online users (for admin dashboard)
 func (r *UserRepository) GetOnlineUsers(ctx context.Context, limit, offset int) ([]*model.User, error) {
 	query := `
@@ -227,13 +432,22 @@ func (r *UserRepository) GetOnlineUsers(ctx context.Context, limit, offset int)
 		LIMIT $1 OFFSET $2`
 
 	var users []*model.User
-	if err := r.db.SelectContext(ctx, &users, query, limit, offset); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, query, limit, offset); err != nil {
 		return nil, fmt.Errorf("failed to get online users: %w", err)
 	}
 
 	return users, nil
 }
 
+// ListAdminIDs returns the IDs of every administrator
+func (r *UserRepository) ListAdminIDs(ctx context.Context) ([]string, error) {
+	var ids []string
+	if err := conn(ctx, r.db).SelectContext(ctx, &ids, `SELECT id FROM users WHERE is_admin = TRUE AND deleted_at IS NULL ORDER BY created_at`); err != nil {
+		return nil, fmt.Errorf("failed to list admins: %w", err)
+	}
+	return ids, nil
+}
+
 // GetByIDs retrieves multiple users by IDs
 func (r *UserRepository) GetByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
 	if len(ids) == 0 {
@@ -245,10 +459,10 @@ func (r *UserRepository) GetByIDs(ctx context.Context, ids []string) ([]*model.U
 		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
-	query = r.db.Rebind(query)
+	query = conn(ctx, r.db).Rebind(query)
 	var users []*model.User
 
-	if err := r.db.SelectContext(ctx, &users, query, args...); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to get users by ids: %w", err)
 	}
 
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/go-demo/chat/internal/model"
@@ -274,6 +277,39 @@ func TestUserRepository_ExistsByUsername(t *testing.T) {
 	}
 }
 
+func TestUserRepository_FindDiscoverable(t *testing.T) {
+	db, prefix := setupUserTestDBIsolated(t)
+	defer db.Close()
+	defer cleanupUserTestByPrefix(t, db, prefix)
+
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	viewer := CreateIsolatedTestUser(t, db, prefix, "viewer")
+	contact := CreateIsolatedTestUser(t, db, prefix, "contact")
+	hidden := CreateIsolatedTestUser(t, db, prefix, "hidden")
+	if err := repo.UpdateDiscoverable(ctx, hidden.ID, false); err != nil {
+		t.Fatalf("Failed to opt out: %v", err)
+	}
+
+	hashOf := func(email string) string {
+		sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+		return hex.EncodeToString(sum[:])
+	}
+	hashes := []string{hashOf(" " + strings.ToUpper(contact.Email)), hashOf(hidden.Email), hashOf(viewer.Email), hashOf("nobody@example.com")}
+
+	found, err := repo.FindDiscoverable(ctx, viewer.ID, hashes)
+	if err != nil {
+		t.Fatalf("Failed to find users: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != contact.ID {
+		t.Fatalf("Expected only the contact found, got %d users", len(found))
+	}
+	if found[0].EmailHash.String != hashes[0] {
+		t.Errorf("Expected email hash %s, got %s", hashes[0], found[0].EmailHash.String)
+	}
+}
+
 func TestUserRepository_Search(t *testing.T) {
 	db, prefix := setupUserTestDBIsolated(t)
 	defer db.Close()
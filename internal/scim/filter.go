@@ -0,0 +1,197 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Condition is an equality test of a filter, such as userName eq "alice".
+// Attr is lowercased; a sub-attribute is joined with a dot, as in
+// emails.value.
+type Condition struct {
+	Attr  string
+	Value string
+}
+
+// Filter is a conjunction of equality conditions. It covers the filters
+// identity providers send to look up a resource before creating it; other
+// operators are rejected with invalidFilter.
+type Filter []Condition
+
+// Value returns the value attr must equal
+func (f Filter) Value(attr string) (string, bool) {
+	for _, c := range f {
+		if c.Attr == attr {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
+
+// ParseFilter parses the filter query parameter. An empty filter matches
+// everything.
+func ParseFilter(s string) (Filter, error) {
+	p := &filterParser{s: s}
+	p.skipSpace()
+	if p.done() {
+		return nil, nil
+	}
+
+	var f Filter
+	for {
+		c, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, c)
+
+		p.skipSpace()
+		if p.done() {
+			return f, nil
+		}
+		if !strings.EqualFold(p.word(), "and") {
+			return nil, invalidFilter("only and may join conditions")
+		}
+	}
+}
+
+// Path is the path of a PATCH operation, such as active,
+// name.formatted, emails[type eq "work"].value or
+// members[value eq "2819c223"]
+type Path struct {
+	Attr   string
+	Filter *Condition
+	Sub    string
+}
+
+// ParsePath parses a PATCH path. Attribute names are lowercased and the
+// core schema URN prefix is dropped.
+func ParsePath(s string) (*Path, error) {
+	p := &filterParser{s: strings.TrimSpace(s)}
+	attr := p.attrName()
+	if attr == "" {
+		return nil, NewError(http.StatusBadRequest, ErrorInvalidPath, "invalid path "+s)
+	}
+	path := &Path{Attr: attr}
+	if i := strings.IndexByte(attr, '.'); i >= 0 {
+		path.Attr, path.Sub = attr[:i], attr[i+1:]
+	}
+
+	if !p.done() && p.s[p.i] == '[' {
+		if path.Sub != "" {
+			return nil, NewError(http.StatusBadRequest, ErrorInvalidPath, "invalid path "+s)
+		}
+		p.i++
+		c, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.done() || p.s[p.i] != ']' {
+			return nil, NewError(http.StatusBadRequest, ErrorInvalidPath, "invalid path "+s)
+		}
+		p.i++
+		path.Filter = &c
+		if !p.done() && p.s[p.i] == '.' {
+			p.i++
+			path.Sub = p.attrName()
+			if path.Sub == "" {
+				return nil, NewError(http.StatusBadRequest, ErrorInvalidPath, "invalid path "+s)
+			}
+		}
+	}
+
+	if !p.done() {
+		return nil, NewError(http.StatusBadRequest, ErrorInvalidPath, "invalid path "+s)
+	}
+	return path, nil
+}
+
+type filterParser struct {
+	s string
+	i int
+}
+
+func (p *filterParser) done() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *filterParser) skipSpace() {
+	for !p.done() && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
+
+// word reads up to the next space
+func (p *filterParser) word() string {
+	p.skipSpace()
+	start := p.i
+	for !p.done() && p.s[p.i] != ' ' {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// attrName reads an attribute path, dropping a core schema prefix
+func (p *filterParser) attrName() string {
+	p.skipSpace()
+	start := p.i
+	for !p.done() && p.s[p.i] != ' ' && p.s[p.i] != '[' && p.s[p.i] != ']' {
+		p.i++
+	}
+	name := p.s[start:p.i]
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if len(name) > len(schema) && strings.EqualFold(name[:len(schema)+1], schema+":") {
+			name = name[len(schema)+1:]
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// condition reads attrPath eq value
+func (p *filterParser) condition() (Condition, error) {
+	attr := p.attrName()
+	if attr == "" {
+		return Condition{}, invalidFilter("expected an attribute")
+	}
+	if op := p.word(); !strings.EqualFold(op, "eq") {
+		return Condition{}, invalidFilter("unsupported operator " + op)
+	}
+
+	p.skipSpace()
+	if p.done() {
+		return Condition{}, invalidFilter("expected a value")
+	}
+	if p.s[p.i] != '"' {
+		// true, false and numbers compare as their text
+		start := p.i
+		for !p.done() && p.s[p.i] != ' ' && p.s[p.i] != ']' {
+			p.i++
+		}
+		return Condition{Attr: attr, Value: p.s[start:p.i]}, nil
+	}
+
+	start := p.i
+	p.i++
+	for !p.done() && p.s[p.i] != '"' {
+		if p.s[p.i] == '\\' {
+			p.i++
+		}
+		p.i++
+	}
+	if p.done() {
+		return Condition{}, invalidFilter("unterminated string")
+	}
+	p.i++
+
+	var value string
+	if err := json.Unmarshal([]byte(p.s[start:p.i]), &value); err != nil {
+		return Condition{}, invalidFilter("invalid string")
+	}
+	return Condition{Attr: attr, Value: value}, nil
+}
+
+func invalidFilter(detail string) *Error {
+	return NewError(http.StatusBadRequest, ErrorInvalidFilter, detail)
+}
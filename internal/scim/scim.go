@@ -0,0 +1,256 @@
+// Package scim implements the wire format of SCIM 2.0 (RFC 7643 and RFC
+// 7644): the User and Group resources, list responses, PATCH operations,
+// errors and the subset of the filter and path grammar identity providers
+// use when provisioning.
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// Schema URNs
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// Error types of RFC 7644 section 3.12
+const (
+	ErrorInvalidFilter = "invalidFilter"
+	ErrorInvalidSyntax = "invalidSyntax"
+	ErrorInvalidPath   = "invalidPath"
+	ErrorInvalidValue  = "invalidValue"
+	ErrorMutability    = "mutability"
+	ErrorUniqueness    = "uniqueness"
+	ErrorNoTarget      = "noTarget"
+)
+
+// PATCH operations
+const (
+	OpAdd     = "add"
+	OpReplace = "replace"
+	OpRemove  = "remove"
+)
+
+// Error is a SCIM error response
+type Error struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+// NewError creates an error answered with status
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{Status: status, ScimType: scimType, Detail: detail}
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// MarshalJSON encodes the error as RFC 7644 describes, with the status as a
+// string
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail,omitempty"`
+	}{[]string{SchemaError}, strconv.Itoa(e.Status), e.ScimType, e.Detail})
+}
+
+// Meta is the metadata of a resource
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+}
+
+// Name is the name of a user
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// String returns the formatted name, or the given and family names
+func (n *Name) String() string {
+	if n == nil {
+		return ""
+	}
+	if n.Formatted != "" {
+		return n.Formatted
+	}
+	return strings.TrimSpace(n.GivenName + " " + n.FamilyName)
+}
+
+// Email is an email address of a user
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// PrimaryEmail returns the primary address, or the first one when none is
+// marked primary
+func PrimaryEmail(emails []Email) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// GroupRef is a group a user belongs to. It is read-only on users.
+type GroupRef struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// User is the User resource. Attributes the service does not keep, such
+// as password, are accepted and ignored.
+type User struct {
+	Schemas     []string   `json:"schemas"`
+	ID          string     `json:"id,omitempty"`
+	ExternalID  string     `json:"externalId,omitempty"`
+	UserName    string     `json:"userName"`
+	Name        *Name      `json:"name,omitempty"`
+	DisplayName string     `json:"displayName,omitempty"`
+	Emails      []Email    `json:"emails,omitempty"`
+	Active      *bool      `json:"active,omitempty"`
+	Groups      []GroupRef `json:"groups,omitempty"`
+	Meta        *Meta      `json:"meta,omitempty"`
+}
+
+// FullName returns displayName, falling back to name
+func (u *User) FullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Name.String()
+}
+
+// Member is a member of a group
+type Member struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// Group is the Group resource
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// ListResponse is a page of query results
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// PatchRequest is the body of a PATCH request
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is one operation of a PATCH request
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Operation returns the lowercased operation name; some providers send
+// "Replace" rather than "replace"
+func (op *PatchOperation) Operation() string {
+	return strings.ToLower(op.Op)
+}
+
+// String decodes the value as a string
+func (op *PatchOperation) String() (string, error) {
+	var s string
+	if err := json.Unmarshal(op.Value, &s); err != nil {
+		return "", invalidValue("%s expects a string", op.Path)
+	}
+	return s, nil
+}
+
+// Bool decodes the value as a boolean. The strings "true" and "false" are
+// accepted too, as some providers send them.
+func (op *PatchOperation) Bool() (bool, error) {
+	var b bool
+	if err := json.Unmarshal(op.Value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(op.Value, &s); err == nil {
+		if b, err := strconv.ParseBool(strings.ToLower(s)); err == nil {
+			return b, nil
+		}
+	}
+	return false, invalidValue("%s expects a boolean", op.Path)
+}
+
+// Members decodes the value as a list of members; a single member object
+// is accepted too
+func (op *PatchOperation) Members() ([]Member, error) {
+	if len(op.Value) == 0 {
+		return nil, nil
+	}
+	var members []Member
+	if err := json.Unmarshal(op.Value, &members); err == nil {
+		return members, nil
+	}
+	var member Member
+	if err := json.Unmarshal(op.Value, &member); err != nil {
+		return nil, invalidValue("members expects a list of members")
+	}
+	return []Member{member}, nil
+}
+
+// Attributes decodes the value of an operation without a path, an object
+// of attributes to set. Keys are lowercased, as attribute names are case
+// insensitive.
+func (op *PatchOperation) Attributes() (map[string]PatchOperation, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(op.Value, &raw); err != nil {
+		return nil, invalidValue("an operation without a path expects an object")
+	}
+	attrs := make(map[string]PatchOperation, len(raw))
+	for name, value := range raw {
+		attrs[strings.ToLower(name)] = PatchOperation{Op: op.Op, Path: name, Value: value}
+	}
+	return attrs, nil
+}
+
+func invalidValue(format string, args ...interface{}) *Error {
+	return NewError(http.StatusBadRequest, ErrorInvalidValue, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,111 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   Filter
+	}{
+		{"", nil},
+		{`userName eq "alice"`, Filter{{Attr: "username", Value: "alice"}}},
+		{`externalId eq "a \"quoted\" id"`, Filter{{Attr: "externalid", Value: `a "quoted" id`}}},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bob"`, Filter{{Attr: "username", Value: "bob"}}},
+		{`emails.value EQ "a@example.com" and active eq true`, Filter{{Attr: "emails.value", Value: "a@example.com"}, {Attr: "active", Value: "true"}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFilter(tt.filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.filter, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFilter(%q) = %+v, want %+v", tt.filter, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{
+		`userName sw "al"`,
+		`userName eq "alice" or userName eq "bob"`,
+		`userName eq "alice`,
+		`userName eq`,
+		`(userName eq "alice")`,
+	} {
+		_, err := ParseFilter(bad)
+		var scimErr *Error
+		if !errors.As(err, &scimErr) || scimErr.ScimType != ErrorInvalidFilter {
+			t.Errorf("Expected invalidFilter for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want Path
+	}{
+		{"active", Path{Attr: "active"}},
+		{"name.givenName", Path{Attr: "name", Sub: "givenname"}},
+		{`emails[type eq "work"].value`, Path{Attr: "emails", Filter: &Condition{Attr: "type", Value: "work"}, Sub: "value"}},
+		{`members[value eq "2819c223"]`, Path{Attr: "members", Filter: &Condition{Attr: "value", Value: "2819c223"}}},
+		{"urn:ietf:params:scim:schemas:core:2.0:Group:displayName", Path{Attr: "displayname"}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePath(tt.path)
+		if err != nil {
+			t.Errorf("ParsePath(%q): %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("ParsePath(%q) = %+v, want %+v", tt.path, *got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", `members[value eq "x"`, `members[value eq "x"]extra`, `name.given[type eq "x"]`} {
+		if _, err := ParsePath(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPatchOperationValues(t *testing.T) {
+	for _, raw := range []string{`false`, `"False"`} {
+		op := PatchOperation{Op: "Replace", Path: "active", Value: json.RawMessage(raw)}
+		if active, err := op.Bool(); err != nil || active {
+			t.Errorf("Bool(%s) = %v, %v", raw, active, err)
+		}
+	}
+	if _, err := (&PatchOperation{Path: "active", Value: json.RawMessage(`"maybe"`)}).Bool(); err == nil {
+		t.Error("Expected a non-boolean to be rejected")
+	}
+
+	single := PatchOperation{Value: json.RawMessage(`{"value":"u1"}`)}
+	if members, err := single.Members(); err != nil || len(members) != 1 || members[0].Value != "u1" {
+		t.Errorf("Members() = %v, %v", members, err)
+	}
+
+	op := PatchOperation{Op: "replace", Value: json.RawMessage(`{"displayName":"Ops","externalId":"g-1"}`)}
+	attrs, err := op.Attributes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if name, _ := (&PatchOperation{Value: attrs["displayname"].Value}).String(); name != "Ops" {
+		t.Errorf("Expected displayName to be keyed lowercased, got %v", attrs)
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	body, err := json.Marshal(NewError(409, ErrorUniqueness, "userName is taken"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `{"schemas":["urn:ietf:params:scim:api:messages:2.0:Error"],"status":"409","scimType":"uniqueness","detail":"userName is taken"}`
+	if string(body) != want {
+		t.Errorf("Got %s", body)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-demo/chat/internal/anomaly"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AbuseAlertSignatureHeader carries the hex HMAC-SHA256 of an alert webhook
+// body, prefixed with "sha256=", in the same scheme as feedback webhooks
+const AbuseAlertSignatureHeader = "X-Abuse-Alert-Signature"
+
+// AbuseAlertWebhookPayload is the JSON body posted for each alert
+type AbuseAlertWebhookPayload struct {
+	Signal        string `json:"signal"`
+	Key           string `json:"key"`
+	Count         int64  `json:"count"`
+	Threshold     int64  `json:"threshold"`
+	WindowSeconds int64  `json:"window_seconds"`
+	DetectedAt    string `json:"detected_at"`
+}
+
+// AbuseAlertService tells administrators about anomalies through the
+// notification center and, when configured, an external webhook
+type AbuseAlertService struct {
+	userRepo *repository.UserRepository
+	notifier *NotificationService
+	logger   *zap.Logger
+
+	webhookURL    string
+	webhookSecret []byte
+	client        *http.Client
+}
+
+func NewAbuseAlertService(userRepo *repository.UserRepository, notifier *NotificationService, logger *zap.Logger) *AbuseAlertService {
+	return &AbuseAlertService{
+		userRepo: userRepo,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// SetWebhook also posts every alert to url, signing bodies with secret when
+// it is not empty
+func (s *AbuseAlertService) SetWebhook(url, secret string, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultFeedbackWebhookTimeout
+	}
+	s.webhookURL = url
+	s.webhookSecret = nil
+	if secret != "" {
+		s.webhookSecret = []byte(secret)
+	}
+	s.client = &http.Client{Timeout: timeout}
+}
+
+// Alert notifies every administrator. Failures are logged only; the
+// detector has already logged the anomaly itself.
+func (s *AbuseAlertService) Alert(ctx context.Context, alert *anomaly.Alert) {
+	adminIDs, err := s.userRepo.ListAdminIDs(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list admins for abuse alert", zap.Error(err))
+	} else {
+		s.notifier.Notify(ctx, adminIDs, abuseAlertNotification(alert))
+	}
+
+	if s.webhookURL != "" {
+		if err := s.postWebhook(ctx, alert); err != nil {
+			s.logger.Error("Failed to deliver abuse alert webhook",
+				zap.String("signal", string(alert.Signal)),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// abuseAlertNotification describes the alert for the notification center
+func abuseAlertNotification(alert *anomaly.Alert) *NotifyInput {
+	input := &NotifyInput{Type: model.NotificationTypeAbuseAlert}
+
+	var source string
+	switch alert.Signal {
+	case anomaly.SignalRegistration:
+		input.Title = "異常活動警示：註冊數量暴增"
+		source = "全站註冊"
+	case anomaly.SignalFriendRequest:
+		input.Title = "異常活動警示：大量好友邀請"
+		source = "用戶 " + alert.Key + " 的好友邀請"
+		input.ReferenceID = alert.Key
+		input.ReferenceType = "user"
+	case anomaly.SignalMessage:
+		input.Title = "異常活動警示：訊息洗版"
+		source = "來自網段 " + alert.Key + " 的訊息"
+	default:
+		input.Title = "異常活動警示"
+		source = string(alert.Signal) + " " + alert.Key
+	}
+
+	input.Content = fmt.Sprintf("%s在 %s 內達到 %d 次（門檻 %d）",
+		source, formatAlertWindow(alert.Window), alert.Count, alert.Threshold)
+	return input
+}
+
+func formatAlertWindow(window time.Duration) string {
+	if window >= time.Minute && window%time.Minute == 0 {
+		return fmt.Sprintf("%d 分鐘", int64(window/time.Minute))
+	}
+	return window.String()
+}
+
+// postWebhook posts alert to the configured webhook; any 2xx response
+// counts as delivered
+func (s *AbuseAlertService) postWebhook(ctx context.Context, alert *anomaly.Alert) error {
+	body, err := json.Marshal(&AbuseAlertWebhookPayload{
+		Signal:        string(alert.Signal),
+		Key:           alert.Key,
+		Count:         alert.Count,
+		Threshold:     alert.Threshold,
+		WindowSeconds: int64(alert.Window / time.Second),
+		DetectedAt:    alert.DetectedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode abuse alert webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create abuse alert webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.webhookSecret != nil {
+		req.Header.Set(AbuseAlertSignatureHeader, "sha256="+SignFeedbackWebhook(s.webhookSecret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post abuse alert webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("abuse alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/anomaly"
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+func TestAbuseAlertNotification(t *testing.T) {
+	input := abuseAlertNotification(&anomaly.Alert{
+		Signal:    anomaly.SignalFriendRequest,
+		Key:       "550e8400-e29b-41d4-a716-446655440000",
+		Count:     30,
+		Threshold: 30,
+		Window:    10 * time.Minute,
+	})
+	if input.Type != model.NotificationTypeAbuseAlert {
+		t.Errorf("Expected type %q, got %q", model.NotificationTypeAbuseAlert, input.Type)
+	}
+	if input.ReferenceID != "550e8400-e29b-41d4-a716-446655440000" || input.ReferenceType != "user" {
+		t.Errorf("Expected the sender as reference, got %q %q", input.ReferenceType, input.ReferenceID)
+	}
+	if !strings.Contains(input.Content, "10 分鐘") || !strings.Contains(input.Content, "30") {
+		t.Errorf("Unexpected content %q", input.Content)
+	}
+
+	// Networks are not UUIDs and must not be stored as a reference
+	input = abuseAlertNotification(&anomaly.Alert{
+		Signal:    anomaly.SignalMessage,
+		Key:       "203.0.113.0/24",
+		Count:     600,
+		Threshold: 600,
+		Window:    time.Minute,
+	})
+	if input.ReferenceID != "" {
+		t.Errorf("Expected no reference, got %q", input.ReferenceID)
+	}
+	if !strings.Contains(input.Content, "203.0.113.0/24") {
+		t.Errorf("Expected the network in %q", input.Content)
+	}
+}
+
+func TestAbuseAlertService_PostWebhook(t *testing.T) {
+	var got AbuseAlertWebhookPayload
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &got)
+		if want := "sha256=" + SignFeedbackWebhook([]byte("s3cret"), body); r.Header.Get(AbuseAlertSignatureHeader) != want {
+			t.Errorf("Signature %q, want %q", r.Header.Get(AbuseAlertSignatureHeader), want)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	svc := NewAbuseAlertService(nil, nil, zap.NewNop())
+	svc.SetWebhook(server.URL, "s3cret", time.Second)
+
+	alert := &anomaly.Alert{
+		Signal:     anomaly.SignalRegistration,
+		Key:        "site",
+		Count:      50,
+		Threshold:  50,
+		Window:     10 * time.Minute,
+		DetectedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if err := svc.postWebhook(context.Background(), alert); err != nil {
+		t.Fatalf("postWebhook: %v", err)
+	}
+	if got.Signal != "registration" || got.Count != 50 || got.WindowSeconds != 600 || got.DetectedAt != "2024-05-01T12:00:00Z" {
+		t.Errorf("Unexpected payload: %+v", got)
+	}
+
+	status = http.StatusBadGateway
+	if err := svc.postWebhook(context.Background(), alert); err == nil {
+		t.Error("Expected non-2xx response to fail")
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/archive"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Archive record kinds written only by account exports
+const (
+	ExportKindProfile    = "profile"
+	ExportKindFriendship = "friendship"
+	ExportKindBlock      = "block"
+	ExportKindDevice     = "device"
+)
+
+// RetentionMode decides what happens to a deleted account's content
+type RetentionMode string
+
+const (
+	// RetentionKeep keeps the content under the anonymized author
+	RetentionKeep RetentionMode = "keep"
+	// RetentionErase removes the content
+	RetentionErase RetentionMode = "erase"
+)
+
+// ParseRetentionMode parses a configured retention mode
+func ParseRetentionMode(s string) (RetentionMode, error) {
+	switch mode := RetentionMode(s); mode {
+	case RetentionKeep, RetentionErase:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown retention mode %q (expected keep or erase)", s)
+	}
+}
+
+// RetentionPolicy is applied when a user deletes their account
+type RetentionPolicy struct {
+	Messages       RetentionMode
+	DirectMessages RetentionMode
+}
+
+// DefaultRetentionPolicy keeps room history readable for the other members
+// while removing private conversations
+var DefaultRetentionPolicy = RetentionPolicy{
+	Messages:       RetentionKeep,
+	DirectMessages: RetentionErase,
+}
+
+// AccountCheckers runs several account checkers in order, stopping at the
+// first rejection
+type AccountCheckers []AccountChecker
+
+// CheckAccount implements AccountChecker
+func (c AccountCheckers) CheckAccount(ctx context.Context, userID string) error {
+	for _, checker := range c {
+		if err := checker.CheckAccount(ctx, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AccountService lets users delete their account and download their data
+type AccountService struct {
+	accountRepo  *repository.AccountRepository
+	userRepo     *repository.UserRepository
+	holdRepo     *repository.LegalHoldRepository
+	deviceRepo   *repository.DeviceRepository
+	compliance   *ComplianceService
+	policy       RetentionPolicy
+	disconnector UserDisconnector
+	credentials  CredentialCache
+	userCache    *UserCache
+	auditor      *AuditService
+	logger       *zap.Logger
+}
+
+func NewAccountService(
+	accountRepo *repository.AccountRepository,
+	userRepo *repository.UserRepository,
+	holdRepo *repository.LegalHoldRepository,
+	deviceRepo *repository.DeviceRepository,
+	compliance *ComplianceService,
+	logger *zap.Logger,
+) *AccountService {
+	return &AccountService{
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		holdRepo:    holdRepo,
+		deviceRepo:  deviceRepo,
+		compliance:  compliance,
+		policy:      DefaultRetentionPolicy,
+		logger:      logger,
+	}
+}
+
+// SetRetentionPolicy sets what happens to the content of deleted accounts
+func (s *AccountService) SetRetentionPolicy(policy RetentionPolicy) {
+	s.policy = policy
+}
+
+// SetDisconnector sets the component used to drop connections of deleted accounts
+func (s *AccountService) SetDisconnector(disconnector UserDisconnector) {
+	s.disconnector = disconnector
+}
+
+// SetCredentialCache sets the cache whose entries for deleted accounts are dropped
+func (s *AccountService) SetCredentialCache(cache CredentialCache) {
+	s.credentials = cache
+}
+
+// SetUserCache sets the cache of user display data that deletions
+// invalidate
+func (s *AccountService) SetUserCache(cache *UserCache) {
+	s.userCache = cache
+}
+
+// SetAuditor sets the audit service that records account deletions
+func (s *AccountService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// CheckAccount returns ErrAccountDeleted for deleted accounts, so access
+// tokens issued before the deletion stop working
+func (s *AccountService) CheckAccount(ctx context.Context, userID string) error {
+	deleted, err := s.accountRepo.IsDeleted(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to check account deletion", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if deleted {
+		return apperrors.ErrAccountDeleted
+	}
+	return nil
+}
+
+// DeleteAccount anonymizes a user's account after confirming their password.
+// Accounts under legal hold, or that still own rooms, cannot be deleted.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to get user", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if user.IsDeleted() {
+		return apperrors.ErrAccountDeleted
+	}
+
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	if !utils.CheckPassword(password, user.PasswordHash) {
+		return apperrors.ErrInvalidPassword
+	}
+
+	if _, err := s.holdRepo.GetActive(ctx, model.LegalHoldTargetUser, userID); err == nil {
+		return apperrors.ErrAccountOnLegalHold
+	} else if err != repository.ErrLegalHoldNotFound {
+		s.logger.Error("Failed to get legal hold", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	owned, err := s.accountRepo.CountOwnedRooms(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to count owned rooms", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if owned > 0 {
+		return apperrors.ErrAccountOwnsRooms
+	}
+
+	result, err := s.accountRepo.Anonymize(ctx, userID, repository.AnonymizeOptions{
+		EraseMessages:       s.policy.Messages == RetentionErase,
+		EraseDirectMessages: s.policy.DirectMessages == RetentionErase,
+	})
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return apperrors.ErrAccountDeleted
+		}
+		s.logger.Error("Failed to delete account", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("User deleted account",
+		zap.String("user_id", userID),
+		zap.Int64("messages_erased", result.MessagesErased),
+		zap.Int64("direct_messages_erased", result.DirectMessagesErased),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    userID,
+		Action:     model.AuditActionAccountDeleted,
+		TargetType: model.AuditTargetUser,
+		TargetID:   userID,
+		Metadata: map[string]interface{}{
+			"message_retention":      string(s.policy.Messages),
+			"dm_retention":           string(s.policy.DirectMessages),
+			"messages_erased":        result.MessagesErased,
+			"direct_messages_erased": result.DirectMessagesErased,
+		},
+	})
+
+	if s.credentials != nil {
+		s.credentials.InvalidateUser(userID)
+	}
+	s.userCache.Invalidate(ctx, userID)
+	if s.disconnector != nil {
+		s.disconnector.DisconnectUser(userID, apperrors.ErrAccountDeleted.Message)
+	}
+	return nil
+}
+
+// Export prepares an archive of everything stored about a user: their
+// profile, friendships, blocks, devices, room messages and direct messages
+func (s *AccountService) Export(ctx context.Context, userID string) (*Export, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to get user", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if user.IsDeleted() {
+		return nil, apperrors.ErrAccountDeleted
+	}
+
+	// Unlike compliance exports, the header never reveals a legal hold
+	now := time.Now().UTC()
+	return &Export{
+		Filename: fmt.Sprintf("account-%s-%s.jsonl", userID, now.Format("20060102T150405Z")),
+		service:  s.compliance,
+		header: &ExportHeader{
+			Format:      archive.Format,
+			TargetType:  string(model.LegalHoldTargetUser),
+			TargetID:    userID,
+			TargetName:  user.Username,
+			ExportedBy:  userID,
+			GeneratedAt: now.Format(time.RFC3339),
+		},
+		write: func(ctx context.Context, w *archive.Writer) error {
+			if err := s.exportAccount(ctx, w, user); err != nil {
+				return err
+			}
+			if err := s.compliance.exportMessages(ctx, w, "", userID); err != nil {
+				return err
+			}
+			return s.compliance.exportDirectMessages(ctx, w, userID)
+		},
+	}, nil
+}
+
+func (s *AccountService) exportAccount(ctx context.Context, w *archive.Writer, user *model.User) error {
+	if err := w.Append(ExportKindProfile, user); err != nil {
+		return err
+	}
+
+	friendships, err := s.accountRepo.ListFriendships(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, f := range friendships {
+		if err := w.Append(ExportKindFriendship, f); err != nil {
+			return err
+		}
+	}
+
+	blocks, err := s.accountRepo.ListBlocks(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, b := range blocks {
+		if err := w.Append(ExportKindBlock, b); err != nil {
+			return err
+		}
+	}
+
+	devices, err := s.deviceRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		if err := w.Append(ExportKindDevice, d); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/archive"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type stubAccountChecker struct {
+	err   error
+	calls int
+}
+
+func (c *stubAccountChecker) CheckAccount(ctx context.Context, userID string) error {
+	c.calls++
+	return c.err
+}
+
+func TestParseRetentionMode(t *testing.T) {
+	for _, s := range []string{"keep", "erase"} {
+		if mode, err := ParseRetentionMode(s); err != nil || string(mode) != s {
+			t.Errorf("ParseRetentionMode(%q) = %q, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseRetentionMode("purge"); err == nil {
+		t.Error("Expected unknown retention mode to be rejected")
+	}
+}
+
+func TestAccountCheckers(t *testing.T) {
+	first := &stubAccountChecker{err: errors.New("banned")}
+	second := &stubAccountChecker{}
+
+	if err := (AccountCheckers{first, second}).CheckAccount(context.Background(), "u"); err == nil {
+		t.Error("Expected first rejection to be returned")
+	}
+	if second.calls != 0 {
+		t.Error("Expected checking to stop at the first rejection")
+	}
+
+	first.err = nil
+	if err := (AccountCheckers{first, second}).CheckAccount(context.Background(), "u"); err != nil || second.calls != 1 {
+		t.Errorf("Expected every checker to pass, got %v", err)
+	}
+}
+
+func setupTestAccountServiceIsolated(t *testing.T) (*AccountService, *sqlx.DB, string) {
+	t.Helper()
+
+	db, prefix := repository.SetupIsolatedTestDB(t)
+
+	userRepo := repository.NewUserRepository(db)
+	holdRepo := repository.NewLegalHoldRepository(db)
+	compliance := NewComplianceService(
+		holdRepo,
+		repository.NewRoomRepository(db),
+		userRepo,
+		repository.NewMessageRepository(db),
+		repository.NewDirectMessageRepository(db),
+		zap.NewNop(),
+	)
+	accounts := NewAccountService(
+		repository.NewAccountRepository(db),
+		userRepo,
+		holdRepo,
+		repository.NewDeviceRepository(db),
+		compliance,
+		zap.NewNop(),
+	)
+	return accounts, db, prefix
+}
+
+// createTestUserWithPassword creates a user whose password is "password123"
+func createTestUserWithPassword(t *testing.T, db *sqlx.DB, prefix, name string) *model.User {
+	t.Helper()
+
+	user := repository.CreateIsolatedTestUser(t, db, prefix, name)
+	hash, err := utils.HashPassword("password123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if err := repository.NewUserRepository(db).UpdatePassword(context.Background(), user.ID, hash); err != nil {
+		t.Fatalf("Failed to set password: %v", err)
+	}
+	return user
+}
+
+func TestAccountService_DeleteAccount(t *testing.T) {
+	accounts, db, prefix := setupTestAccountServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	owner := repository.CreateIsolatedTestUser(t, db, prefix, "owner")
+	room := repository.CreateIsolatedTestRoom(t, db, prefix, owner)
+	leaver := createTestUserWithPassword(t, db, prefix, "leaver")
+	defer db.Exec(`DELETE FROM users WHERE id = $1`, leaver.ID)
+
+	msg := &model.Message{RoomID: room.ID, UserID: leaver.ID, Content: prefix + "_hello", Type: model.MessageTypeText}
+	if err := repository.NewMessageRepository(db).Create(ctx, msg); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	dm := &model.DirectMessage{SenderID: leaver.ID, ReceiverID: owner.ID, Content: prefix + "_dm", Type: model.MessageTypeText}
+	if err := repository.NewDirectMessageRepository(db).Create(ctx, dm); err != nil {
+		t.Fatalf("Failed to create direct message: %v", err)
+	}
+	if err := repository.NewFriendshipRepository(db).Create(ctx, leaver.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to create friendship: %v", err)
+	}
+
+	if err := accounts.DeleteAccount(ctx, leaver.ID, "wrong-password"); !apperrors.Is(err, apperrors.ErrInvalidPassword) {
+		t.Fatalf("Expected ErrInvalidPassword, got %v", err)
+	}
+	if err := accounts.DeleteAccount(ctx, owner.ID, "password123"); err == nil {
+		t.Error("Expected room owner deletion to be refused")
+	}
+
+	if err := accounts.DeleteAccount(ctx, leaver.ID, "password123"); err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+
+	user, err := repository.NewUserRepository(db).GetByID(ctx, leaver.ID)
+	if err != nil {
+		t.Fatalf("Expected anonymized user row to remain: %v", err)
+	}
+	if !user.IsDeleted() || strings.Contains(user.Username, prefix) || strings.Contains(user.Email, prefix) || user.DisplayName.Valid {
+		t.Errorf("Expected user to be anonymized, got %+v", user)
+	}
+	if utils.CheckPassword("password123", user.PasswordHash) {
+		t.Error("Expected password to no longer match")
+	}
+
+	// Default policy keeps room messages and erases direct messages
+	kept, err := repository.NewMessageRepository(db).GetByID(ctx, msg.ID)
+	if err != nil || kept.Content != msg.Content {
+		t.Errorf("Expected room message to be kept, got %+v, %v", kept, err)
+	}
+	if _, err := repository.NewDirectMessageRepository(db).GetByID(ctx, dm.ID); err == nil {
+		t.Error("Expected direct message to be erased")
+	}
+	if ok, _ := repository.NewFriendshipRepository(db).AreFriends(ctx, owner.ID, leaver.ID); ok {
+		t.Error("Expected friendship to be removed")
+	}
+
+	if err := accounts.CheckAccount(ctx, leaver.ID); !apperrors.Is(err, apperrors.ErrAccountDeleted) {
+		t.Errorf("Expected ErrAccountDeleted, got %v", err)
+	}
+	if err := accounts.CheckAccount(ctx, owner.ID); err != nil {
+		t.Errorf("Expected active account to pass, got %v", err)
+	}
+	if err := accounts.DeleteAccount(ctx, leaver.ID, "password123"); !apperrors.Is(err, apperrors.ErrAccountDeleted) {
+		t.Errorf("Expected second deletion to fail with ErrAccountDeleted, got %v", err)
+	}
+}
+
+func TestAccountService_DeleteAccountUnderLegalHold(t *testing.T) {
+	accounts, db, prefix := setupTestAccountServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	admin := repository.CreateIsolatedTestUser(t, db, prefix, "admin")
+	user := createTestUserWithPassword(t, db, prefix, "held")
+
+	if _, err := accounts.compliance.PlaceHold(ctx, &PlaceHoldInput{
+		TargetType: model.LegalHoldTargetUser,
+		TargetID:   user.ID,
+		Reason:     "investigation",
+		PlacedBy:   admin.ID,
+	}); err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+
+	if err := accounts.DeleteAccount(ctx, user.ID, "password123"); !apperrors.Is(err, apperrors.ErrAccountOnLegalHold) {
+		t.Errorf("Expected ErrAccountOnLegalHold, got %v", err)
+	}
+}
+
+func TestAccountService_Export(t *testing.T) {
+	accounts, db, prefix := setupTestAccountServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "user")
+	friend := repository.CreateIsolatedTestUser(t, db, prefix, "friend")
+	room := repository.CreateIsolatedTestRoom(t, db, prefix, user)
+
+	msg := &model.Message{RoomID: room.ID, UserID: user.ID, Content: prefix + "_hello", Type: model.MessageTypeText}
+	if err := repository.NewMessageRepository(db).Create(ctx, msg); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repository.NewFriendshipRepository(db).Create(ctx, user.ID, friend.ID); err != nil {
+		t.Fatalf("Failed to create friendship: %v", err)
+	}
+
+	export, err := accounts.Export(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to prepare export: %v", err)
+	}
+
+	var buf bytes.Buffer
+	written, err := export.WriteTo(ctx, &buf)
+	if err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+	// profile, friendship and message
+	if written.Records != 3 {
+		t.Errorf("Expected 3 records, got %d", written.Records)
+	}
+	for _, kind := range []string{ExportKindProfile, ExportKindFriendship, ExportKindMessage} {
+		if !strings.Contains(buf.String(), `"kind":"`+kind+`"`) {
+			t.Errorf("Expected a %s record", kind)
+		}
+	}
+	if strings.Contains(buf.String(), "password") {
+		t.Error("Expected the password hash to be left out")
+	}
+
+	if _, err := archive.Verify(strings.NewReader(buf.String())); err != nil {
+		t.Errorf("Export failed verification: %v", err)
+	}
+}
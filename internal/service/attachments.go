@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// MaxAttachments is the number of uploads one message may attach
+const MaxAttachments = 10
+
+var (
+	ErrAttachmentNotFound = apperrors.New(http.StatusBadRequest, "附件不存在或已失效，請重新上傳")
+	ErrAttachmentNotOwned = apperrors.New(http.StatusForbidden, "只能附加自己上傳的檔案")
+	ErrAttachmentInfected = apperrors.New(http.StatusUnprocessableEntity, "檔案含有惡意程式，無法附加")
+)
+
+// AttachmentService records uploaded images and files, checks the uploads
+// new messages attach and deletes the uploads no message attached in time.
+// A nil AttachmentService records nothing and refuses attachments.
+type AttachmentService struct {
+	store     AttachmentStore
+	orphanTTL time.Duration
+	scanner   *UploadScanner
+	logger    *zap.Logger
+}
+
+// NewAttachmentService creates the service; uploads still unattached
+// orphanTTL after they were made are deleted, zero keeps them
+func NewAttachmentService(store AttachmentStore, orphanTTL time.Duration, logger *zap.Logger) *AttachmentService {
+	return &AttachmentService{
+		store:     store,
+		orphanTTL: orphanTTL,
+		logger:    logger,
+	}
+}
+
+// SetScanner makes new uploads wait for an antivirus scan
+func (s *AttachmentService) SetScanner(scanner *UploadScanner) {
+	s.scanner = scanner
+}
+
+// RecordUpload records a stored image or file, queued for scanning when a
+// scanner is set. Recording a file URL again fills upload in with the
+// existing record.
+func (s *AttachmentService) RecordUpload(ctx context.Context, upload *model.Upload) error {
+	if s == nil {
+		return nil
+	}
+	if s.scanner != nil {
+		upload.ScanStatus = model.ScanStatusPending
+	}
+	if err := s.store.CreateUpload(ctx, upload); err != nil {
+		s.logger.Error("Failed to record upload", zap.String("file_url", upload.FileURL), zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	s.scanner.Enqueue(upload)
+	return nil
+}
+
+// AttachInput names the uploads a new message attaches
+type AttachInput struct {
+	UserID    string
+	UploadIDs []string
+
+	// FileURL is the content of an image or file message; an upload stored
+	// there is attached as well
+	FileURL string
+
+	// Forwards carry the original message's attachments, which may have
+	// been uploaded by someone else
+	Forwarded bool
+}
+
+// Resolve checks the uploads a message attaches and returns them in order,
+// without duplicates. Only the uploader may attach an upload, except to
+// forward it. Uploads still being scanned may be attached; infected ones
+// may not.
+func (s *AttachmentService) Resolve(ctx context.Context, input *AttachInput) ([]*model.Upload, error) {
+	if len(input.UploadIDs) > MaxAttachments {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"attachment_ids": fmt.Sprintf("最多只能附加 %d 個檔案", MaxAttachments),
+		})
+	}
+	for _, id := range input.UploadIDs {
+		if !utils.ValidateUUID(id) {
+			return nil, ErrAttachmentNotFound
+		}
+	}
+	if s == nil {
+		if len(input.UploadIDs) > 0 {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, nil
+	}
+
+	var uploads []*model.Upload
+	seen := make(map[string]bool)
+	if input.FileURL != "" {
+		upload, err := s.store.GetUploadByURL(ctx, input.FileURL)
+		switch {
+		case err == nil:
+			uploads = append(uploads, upload)
+			seen[upload.ID] = true
+		case err != repository.ErrUploadNotFound:
+			s.logger.Error("Failed to get upload", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		// Files hosted elsewhere are not recorded and attach nothing
+	}
+
+	if len(input.UploadIDs) > 0 {
+		found, err := s.store.ListUploadsByIDs(ctx, input.UploadIDs)
+		if err != nil {
+			s.logger.Error("Failed to list uploads", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		byID := make(map[string]*model.Upload, len(found))
+		for _, upload := range found {
+			byID[upload.ID] = upload
+		}
+		for _, id := range input.UploadIDs {
+			upload, ok := byID[id]
+			if !ok {
+				return nil, ErrAttachmentNotFound
+			}
+			if !seen[id] {
+				seen[id] = true
+				uploads = append(uploads, upload)
+			}
+		}
+	}
+
+	for _, upload := range uploads {
+		if !input.Forwarded && !upload.IsOwnedBy(input.UserID) {
+			return nil, ErrAttachmentNotOwned
+		}
+		if upload.ScanStatus == model.ScanStatusInfected {
+			return nil, ErrAttachmentInfected
+		}
+	}
+	return uploads, nil
+}
+
+// AttachToMessage links checked uploads to a stored room message. The
+// message is already stored, so a failure is only logged.
+func (s *AttachmentService) AttachToMessage(ctx context.Context, messageID string, uploads []*model.Upload) []*model.Attachment {
+	if s == nil || len(uploads) == 0 {
+		return nil
+	}
+	attachments, err := s.store.AttachToMessage(ctx, messageID, uploadIDs(uploads))
+	if err != nil {
+		s.logger.Error("Failed to attach uploads", zap.String("message_id", messageID), zap.Error(err))
+		return nil
+	}
+	return attachments
+}
+
+// AttachToDirectMessage links checked uploads to a stored direct message.
+// The message is already stored, so a failure is only logged.
+func (s *AttachmentService) AttachToDirectMessage(ctx context.Context, dmID string, uploads []*model.Upload) []*model.Attachment {
+	if s == nil || len(uploads) == 0 {
+		return nil
+	}
+	attachments, err := s.store.AttachToDirectMessage(ctx, dmID, uploadIDs(uploads))
+	if err != nil {
+		s.logger.Error("Failed to attach uploads", zap.String("direct_message_id", dmID), zap.Error(err))
+		return nil
+	}
+	return attachments
+}
+
+// ForMessages loads the attachments of room messages by message ID. A
+// failure is logged and leaves the messages without attachments.
+func (s *AttachmentService) ForMessages(ctx context.Context, ids []string) map[string][]*model.Attachment {
+	if s == nil || len(ids) == 0 {
+		return nil
+	}
+	attachments, err := s.store.ListByMessageIDs(ctx, ids)
+	if err != nil {
+		s.logger.Warn("Failed to load message attachments", zap.Error(err))
+		return nil
+	}
+	byMessage := make(map[string][]*model.Attachment)
+	for _, a := range attachments {
+		byMessage[a.MessageID.String] = append(byMessage[a.MessageID.String], a)
+	}
+	return byMessage
+}
+
+// ForDirectMessages loads the attachments of direct messages by message
+// ID. A failure is logged and leaves the messages without attachments.
+func (s *AttachmentService) ForDirectMessages(ctx context.Context, ids []string) map[string][]*model.Attachment {
+	if s == nil || len(ids) == 0 {
+		return nil
+	}
+	attachments, err := s.store.ListByDirectMessageIDs(ctx, ids)
+	if err != nil {
+		s.logger.Warn("Failed to load direct message attachments", zap.Error(err))
+		return nil
+	}
+	byMessage := make(map[string][]*model.Attachment)
+	for _, a := range attachments {
+		byMessage[a.DirectMessageID.String] = append(byMessage[a.DirectMessageID.String], a)
+	}
+	return byMessage
+}
+
+// CollectGarbage deletes up to limit uploads that no message attached
+// within the orphan TTL, the stored file along with the record
+func (s *AttachmentService) CollectGarbage(ctx context.Context, limit int) (int, error) {
+	if s == nil || s.orphanTTL <= 0 {
+		return 0, nil
+	}
+
+	uploads, err := s.store.ListOrphanedUploads(ctx, time.Now().Add(-s.orphanTTL), limit)
+	if err != nil {
+		s.logger.Error("Failed to list orphaned uploads", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+
+	removed := 0
+	for _, upload := range uploads {
+		if err := checkContext(ctx); err != nil {
+			return removed, err
+		}
+
+		// A message may have attached it since it was listed
+		deleted, err := s.store.DeleteOrphanedUpload(ctx, upload.ID)
+		if err != nil {
+			s.logger.Error("Failed to delete upload", zap.String("upload_id", upload.ID), zap.Error(err))
+			return removed, apperrors.ErrInternal
+		}
+		if !deleted {
+			continue
+		}
+		if upload.FilePath != "" {
+			if err := os.Remove(upload.FilePath); err != nil && !os.IsNotExist(err) {
+				s.logger.Warn("Failed to remove uploaded file", zap.String("path", upload.FilePath), zap.Error(err))
+			}
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		s.logger.Info("Unattached uploads removed", zap.Int("count", removed))
+	}
+	return removed, nil
+}
+
+func uploadIDs(uploads []*model.Upload) []string {
+	ids := make([]string, len(uploads))
+	for i, upload := range uploads {
+		ids[i] = upload.ID
+	}
+	return ids
+}
+
+// attachmentUploadIDs returns the uploads attachments link to, in order
+func attachmentUploadIDs(attachments []*model.Attachment) []string {
+	var ids []string
+	for _, a := range attachments {
+		ids = append(ids, a.UploadID)
+	}
+	return ids
+}
+
+// attachableURL returns the file URL of an image or file message, which
+// may be an upload to attach
+func attachableURL(msgType model.MessageType, content string) string {
+	if msgType == model.MessageTypeImage || msgType == model.MessageTypeFile {
+		return content
+	}
+	return ""
+}
+
+// SetAttachments enables attaching uploads to room messages
+func (s *MessageService) SetAttachments(attachments *AttachmentService) {
+	s.attachments = attachments
+}
+
+// fillAttachments fills in the attachments of messages that are not
+// deleted
+func (s *MessageService) fillAttachments(ctx context.Context, messages ...*model.MessageWithUser) {
+	var ids []string
+	for _, msg := range messages {
+		if !msg.IsDeleted {
+			ids = append(ids, msg.ID)
+		}
+	}
+	byMessage := s.attachments.ForMessages(ctx, ids)
+	for _, msg := range messages {
+		if !msg.IsDeleted {
+			msg.Attachments = byMessage[msg.ID]
+		}
+	}
+}
+
+// SetAttachments enables attaching uploads to direct messages
+func (s *DirectMessageService) SetAttachments(attachments *AttachmentService) {
+	s.attachments = attachments
+}
+
+// fillAttachments fills in the attachments of direct messages
+func (s *DirectMessageService) fillAttachments(ctx context.Context, messages ...*model.DirectMessageWithUser) {
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	byMessage := s.attachments.ForDirectMessages(ctx, ids)
+	for _, msg := range messages {
+		msg.Attachments = byMessage[msg.ID]
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	testUploadID      = "5b1d7c2e-3f4a-4e6b-8c9d-0a1b2c3d4e5f"
+	testOtherUploadID = "9e8d7c6b-5a4f-4e3d-9c2b-1a0f9e8d7c6b"
+	testUploadURL     = "http://localhost:8080/uploads/images/photo.png"
+)
+
+func testUpload(id, userID string) *model.Upload {
+	return &model.Upload{
+		ID:       id,
+		UserID:   sql.NullString{String: userID, Valid: true},
+		Category: model.UploadCategoryImage,
+		FileURL:  testUploadURL,
+	}
+}
+
+func TestAttachmentService_Resolve(t *testing.T) {
+	store := &mockAttachmentStore{
+		ListUploadsByIDsFunc: func(ctx context.Context, ids []string) ([]*model.Upload, error) {
+			return []*model.Upload{testUpload(testUploadID, "alice"), testUpload(testOtherUploadID, "bob")}, nil
+		},
+	}
+	service := NewAttachmentService(store, time.Hour, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("Rejects unknown or malformed uploads", func(t *testing.T) {
+		for _, ids := range [][]string{{"not-a-uuid"}, {"0c1d2e3f-4a5b-4c6d-8e7f-8a9b0c1d2e3f"}} {
+			if _, err := service.Resolve(ctx, &AttachInput{UserID: "alice", UploadIDs: ids}); err != ErrAttachmentNotFound {
+				t.Errorf("Expected ErrAttachmentNotFound for %v, got %v", ids, err)
+			}
+		}
+	})
+
+	t.Run("Rejects too many uploads", func(t *testing.T) {
+		ids := make([]string, MaxAttachments+1)
+		for i := range ids {
+			ids[i] = testUploadID
+		}
+		_, err := service.Resolve(ctx, &AttachInput{UserID: "alice", UploadIDs: ids})
+		if apperrors.GetHTTPStatus(err) != 400 {
+			t.Errorf("Expected a validation error, got %v", err)
+		}
+	})
+
+	t.Run("Only the uploader may attach", func(t *testing.T) {
+		_, err := service.Resolve(ctx, &AttachInput{UserID: "alice", UploadIDs: []string{testUploadID, testOtherUploadID}})
+		if err != ErrAttachmentNotOwned {
+			t.Errorf("Expected ErrAttachmentNotOwned, got %v", err)
+		}
+	})
+
+	t.Run("Forwards carry anyone's uploads", func(t *testing.T) {
+		uploads, err := service.Resolve(ctx, &AttachInput{UserID: "alice", UploadIDs: []string{testOtherUploadID}, Forwarded: true})
+		if err != nil || len(uploads) != 1 || uploads[0].ID != testOtherUploadID {
+			t.Errorf("Expected the forwarded upload, got %v, %v", uploads, err)
+		}
+	})
+
+	t.Run("Attaches the upload a file message points at once", func(t *testing.T) {
+		store.GetUploadByURLFunc = func(ctx context.Context, url string) (*model.Upload, error) {
+			return testUpload(testUploadID, "alice"), nil
+		}
+		defer func() { store.GetUploadByURLFunc = nil }()
+
+		uploads, err := service.Resolve(ctx, &AttachInput{UserID: "alice", UploadIDs: []string{testUploadID}, FileURL: testUploadURL})
+		if err != nil || len(uploads) != 1 {
+			t.Errorf("Expected one upload, got %v, %v", uploads, err)
+		}
+		if _, err := service.Resolve(ctx, &AttachInput{UserID: "bob", FileURL: testUploadURL}); err != ErrAttachmentNotOwned {
+			t.Errorf("Expected ErrAttachmentNotOwned for someone else's file, got %v", err)
+		}
+	})
+
+	t.Run("Infected uploads cannot be attached", func(t *testing.T) {
+		store.ListUploadsByIDsFunc = func(ctx context.Context, ids []string) ([]*model.Upload, error) {
+			infected := testUpload(testUploadID, "alice")
+			infected.ScanStatus = model.ScanStatusInfected
+			return []*model.Upload{infected}, nil
+		}
+		_, err := service.Resolve(ctx, &AttachInput{UserID: "alice", UploadIDs: []string{testUploadID}, Forwarded: true})
+		if err != ErrAttachmentInfected {
+			t.Errorf("Expected ErrAttachmentInfected, got %v", err)
+		}
+	})
+
+	t.Run("Files hosted elsewhere attach nothing", func(t *testing.T) {
+		uploads, err := service.Resolve(ctx, &AttachInput{UserID: "alice", FileURL: "https://example.com/cat.png"})
+		if err != nil || len(uploads) != 0 {
+			t.Errorf("Expected no uploads, got %v, %v", uploads, err)
+		}
+	})
+
+	t.Run("Without the service nothing can be attached", func(t *testing.T) {
+		var disabled *AttachmentService
+		if _, err := disabled.Resolve(ctx, &AttachInput{UserID: "alice", UploadIDs: []string{testUploadID}}); err != ErrAttachmentNotFound {
+			t.Errorf("Expected ErrAttachmentNotFound, got %v", err)
+		}
+		if uploads, err := disabled.Resolve(ctx, &AttachInput{UserID: "alice", FileURL: testUploadURL}); err != nil || uploads != nil {
+			t.Errorf("Expected plain file messages to pass, got %v, %v", uploads, err)
+		}
+	})
+}
+
+func TestAttachmentService_CollectGarbage(t *testing.T) {
+	dir := t.TempDir()
+	orphan := filepath.Join(dir, "orphan.png")
+	attached := filepath.Join(dir, "attached.png")
+	for _, path := range []string{orphan, attached} {
+		if err := os.WriteFile(path, []byte("png"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	var cutoff time.Time
+	store := &mockAttachmentStore{
+		ListOrphanedUploadsFunc: func(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error) {
+			cutoff = before
+			return []*model.Upload{
+				{ID: "orphan", FilePath: orphan},
+				{ID: "attached", FilePath: attached},
+				{ID: "missing", FilePath: filepath.Join(dir, "missing.png")},
+				{ID: "migrated"},
+			}, nil
+		},
+		// A message attached one upload after it was listed
+		DeleteOrphanedUploadFunc: func(ctx context.Context, id string) (bool, error) {
+			return id != "attached", nil
+		},
+	}
+	service := NewAttachmentService(store, 24*time.Hour, zap.NewNop())
+
+	n, err := service.CollectGarbage(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("Failed to collect garbage: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 uploads removed, got %d", n)
+	}
+	if age := time.Since(cutoff); age < 24*time.Hour || age > 25*time.Hour {
+		t.Errorf("Expected uploads older than a day, got cutoff %v ago", age)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("Expected the orphaned file removed")
+	}
+	if _, err := os.Stat(attached); err != nil {
+		t.Errorf("Expected the attached file kept, got %v", err)
+	}
+
+	disabled := NewAttachmentService(store, 0, zap.NewNop())
+	if n, err := disabled.CollectGarbage(context.Background(), 10); n != 0 || err != nil {
+		t.Errorf("Expected nothing removed without a TTL, got %d, %v", n, err)
+	}
+	if store.Calls("ListOrphanedUploads") != 1 {
+		t.Error("Expected no lookup without a TTL")
+	}
+}
+
+func TestMessageService_FillAttachments(t *testing.T) {
+	var requested []string
+	store := &mockAttachmentStore{ListByMessageIDsFunc: func(ctx context.Context, ids []string) ([]*model.Attachment, error) {
+		requested = ids
+		return []*model.Attachment{
+			{ID: "a2", MessageID: sql.NullString{String: "m1", Valid: true}, Position: 0},
+			{ID: "a3", MessageID: sql.NullString{String: "m1", Valid: true}, Position: 1},
+		}, nil
+	}}
+	service := NewMessageService(nil, nil, zap.NewNop())
+	service.SetAttachments(NewAttachmentService(store, time.Hour, zap.NewNop()))
+
+	live := &model.MessageWithUser{Message: model.Message{ID: "m1"}}
+	deleted := &model.MessageWithUser{Message: model.Message{ID: "m2", IsDeleted: true}}
+	service.fillAttachments(context.Background(), live, deleted)
+
+	if len(requested) != 1 || requested[0] != "m1" {
+		t.Errorf("Expected only the live message looked up, got %v", requested)
+	}
+	if len(live.Attachments) != 2 || live.Attachments[0].ID != "a2" {
+		t.Errorf("Expected attachments in order, got %+v", live.Attachments)
+	}
+	if deleted.Attachments != nil {
+		t.Error("Expected no attachments on a deleted message")
+	}
+}
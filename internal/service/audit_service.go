@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+type AuditService struct {
+	auditRepo AuditStore
+	logger    *zap.Logger
+}
+
+func NewAuditService(auditRepo AuditStore, logger *zap.Logger) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+// AuditEntry describes a sensitive action to record
+type AuditEntry struct {
+	ActorID    string // empty for system actions
+	Action     model.AuditAction
+	TargetType string
+	TargetID   string
+	Metadata   map[string]interface{}
+}
+
+// Record stores an audit entry. The action has already happened, so failures
+// are logged rather than returned, and request cancellation is ignored.
+// A nil AuditService records nothing, so callers need not check for one.
+func (s *AuditService) Record(ctx context.Context, entry *AuditEntry) {
+	if s == nil {
+		return
+	}
+
+	log := &model.AuditLog{
+		ActorID:    sql.NullString{String: entry.ActorID, Valid: entry.ActorID != ""},
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   sql.NullString{String: entry.TargetID, Valid: entry.TargetID != ""},
+	}
+
+	if len(entry.Metadata) > 0 {
+		metadata, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			s.logger.Error("Failed to encode audit metadata", zap.String("action", string(entry.Action)), zap.Error(err))
+		} else {
+			log.Metadata = metadata
+		}
+	}
+
+	if err := s.auditRepo.Create(context.WithoutCancel(ctx), log); err != nil {
+		s.logger.Error("Failed to record audit log",
+			zap.String("action", string(entry.Action)),
+			zap.String("actor_id", entry.ActorID),
+			zap.String("target_id", entry.TargetID),
+			zap.Error(err),
+		)
+	}
+}
+
+// List lists audit log entries matching the filter
+func (s *AuditService) List(ctx context.Context, filter *repository.AuditFilter, limit, offset int) ([]*model.AuditLog, error) {
+	entries, err := s.auditRepo.List(ctx, filter, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list audit logs", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return entries, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+func TestAuditService_NilRecordIsNoop(t *testing.T) {
+	var auditor *AuditService
+	auditor.Record(context.Background(), &AuditEntry{Action: model.AuditActionMemberKicked})
+}
+
+func TestAuditService_RecordsBanActions(t *testing.T) {
+	service, db, prefix := setupTestBanServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	admin := repository.CreateIsolatedTestUser(t, db, prefix, "admin")
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "target")
+	defer db.Exec(`DELETE FROM audit_logs WHERE target_id = $1`, user.ID)
+
+	auditor := NewAuditService(repository.NewAuditRepository(db), zap.NewNop())
+	service.SetAuditor(auditor)
+
+	since := time.Now().Add(-time.Minute)
+	if _, err := service.Ban(ctx, &BanInput{UserID: user.ID, BannedBy: admin.ID, Reason: "spam", Duration: time.Hour}); err != nil {
+		t.Fatalf("Failed to ban user: %v", err)
+	}
+	if err := service.Unban(ctx, user.ID, admin.ID); err != nil {
+		t.Fatalf("Failed to unban user: %v", err)
+	}
+
+	entries, err := auditor.List(ctx, &repository.AuditFilter{ActorID: admin.ID, Since: since}, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list audit logs: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(entries))
+	}
+	if entries[0].Action != model.AuditActionUserUnbanned || entries[1].Action != model.AuditActionUserBanned {
+		t.Errorf("Unexpected actions: %s, %s", entries[0].Action, entries[1].Action)
+	}
+	if entries[1].TargetID.String != user.ID {
+		t.Errorf("Expected target %s, got %s", user.ID, entries[1].TargetID.String)
+	}
+
+	entries, err = auditor.List(ctx, &repository.AuditFilter{ActorID: admin.ID, Action: model.AuditActionUserBanned}, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list audit logs: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 ban entry, got %d", len(entries))
+	}
+
+	entries, err = auditor.List(ctx, &repository.AuditFilter{ActorID: admin.ID, Until: since}, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list audit logs: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries before the time range, got %d", len(entries))
+	}
+}
+
+func TestAuditService_Record_Unit(t *testing.T) {
+	store := &mockAuditStore{}
+	var stored *model.AuditLog
+	store.CreateFunc = func(ctx context.Context, entry *model.AuditLog) error {
+		stored = entry
+		return nil
+	}
+	service := NewAuditService(store, zap.NewNop())
+
+	// A canceled request still records the action it performed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	service.Record(ctx, &AuditEntry{
+		Action:     model.AuditActionUserSpamFlagged,
+		TargetType: model.AuditTargetUser,
+		TargetID:   "u1",
+		Metadata:   map[string]interface{}{"score": 70},
+	})
+
+	if stored == nil {
+		t.Fatal("Expected the entry to be stored")
+	}
+	if stored.ActorID.Valid {
+		t.Error("Expected system actions to have no actor")
+	}
+	if string(stored.Metadata) != `{"score":70}` {
+		t.Errorf("Expected encoded metadata, got %s", stored.Metadata)
+	}
+
+	// Failures are logged, not returned
+	store.CreateFunc = func(ctx context.Context, entry *model.AuditLog) error {
+		return errors.New("connection reset")
+	}
+	service.Record(context.Background(), &AuditEntry{Action: model.AuditActionUserBanned})
+	if store.Calls("Create") != 2 {
+		t.Errorf("Expected 2 Create calls, got %d", store.Calls("Create"))
+	}
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultDeviceName is used when a client does not name its device
+	defaultDeviceName = "未命名裝置"
+
+	// deviceTokenHistory is the number of issued tokens reported per device
+	deviceTokenHistory = 10
+)
+
+// DeviceDisconnector drops the realtime connections opened from a device.
+// It is implemented by ws.Hub.
+type DeviceDisconnector interface {
+	DisconnectDevice(userID, deviceID, reason string)
+}
+
+// ClientInfo describes the client a request came from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+func (c *ClientInfo) userAgent() string {
+	if c == nil {
+		return ""
+	}
+	return c.UserAgent
+}
+
+func (c *ClientInfo) ip() string {
+	if c == nil {
+		return ""
+	}
+	return c.IP
+}
+
+// SetDeviceRepository enables device-bound refresh tokens. Without it tokens
+// are issued as before and cannot be revoked per device.
+func (s *AuthService) SetDeviceRepository(deviceRepo *repository.DeviceRepository) {
+	s.deviceRepo = deviceRepo
+}
+
+// SetDeviceDisconnector sets the component used to drop the connections of
+// revoked devices
+func (s *AuthService) SetDeviceDisconnector(disconnector DeviceDisconnector) {
+	s.devices = disconnector
+}
+
+// CheckDevice rejects sessions whose device is unknown, owned by someone
+// else or revoked. Sessions without a device always pass.
+func (s *AuthService) CheckDevice(ctx context.Context, userID, deviceID string) error {
+	if s.deviceRepo == nil || deviceID == "" {
+		return nil
+	}
+
+	device, err := s.deviceRepo.GetByID(ctx, deviceID)
+	if err != nil {
+		if err == repository.ErrDeviceNotFound {
+			return apperrors.ErrDeviceNotFound
+		}
+		s.logger.Error("Failed to get device", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if device.UserID != userID || device.IsRevoked() {
+		return apperrors.ErrDeviceNotFound
+	}
+	return nil
+}
+
+// startSession issues a token pair for the given device, registering a new
+// device when deviceID is empty, unknown, revoked or owned by someone else
+func (s *AuthService) startSession(ctx context.Context, user *model.User, deviceID, deviceName string, client *ClientInfo) (*utils.TokenPair, error) {
+	if s.deviceRepo == nil {
+		tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username)
+		if err != nil {
+			s.logger.Error("Failed to generate token pair", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		return tokenPair, nil
+	}
+
+	device, err := s.resolveDevice(ctx, user.ID, deviceID, deviceName, client)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenPair, err := s.jwtManager.GenerateDeviceTokenPair(user.ID, user.Username, device.ID)
+	if err != nil {
+		s.logger.Error("Failed to generate token pair", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	if err := s.deviceRepo.CreateToken(ctx, &model.RefreshTokenRecord{
+		ID:        tokenPair.RefreshTokenID,
+		DeviceID:  device.ID,
+		UserID:    user.ID,
+		IP:        sql.NullString{String: client.ip(), Valid: client.ip() != ""},
+		ExpiresAt: tokenPair.RefreshTokenExpiresAt,
+	}); err != nil {
+		s.logger.Error("Failed to record refresh token", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return tokenPair, nil
+}
+
+func (s *AuthService) resolveDevice(ctx context.Context, userID, deviceID, deviceName string, client *ClientInfo) (*model.UserDevice, error) {
+	if deviceID != "" && utils.ValidateUUID(deviceID) {
+		device, err := s.deviceRepo.GetByID(ctx, deviceID)
+		if err != nil && err != repository.ErrDeviceNotFound {
+			s.logger.Error("Failed to get device", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		if err == nil && device.UserID == userID && !device.IsRevoked() {
+			if err := s.deviceRepo.Touch(ctx, device.ID, client.ip()); err != nil {
+				s.logger.Warn("Failed to update device last seen", zap.Error(err))
+			}
+			return device, nil
+		}
+	}
+
+	if deviceName == "" {
+		deviceName = defaultDeviceName
+	}
+	device := &model.UserDevice{
+		UserID:    userID,
+		Name:      deviceName,
+		UserAgent: sql.NullString{String: client.userAgent(), Valid: client.userAgent() != ""},
+		LastIP:    sql.NullString{String: client.ip(), Valid: client.ip() != ""},
+	}
+	if err := s.deviceRepo.Create(ctx, device); err != nil {
+		s.logger.Error("Failed to register device", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Device registered",
+		zap.String("user_id", userID),
+		zap.String("device_id", device.ID),
+	)
+	return device, nil
+}
+
+// rotateDeviceToken exchanges a device-bound refresh token for a new pair.
+// Presenting a token that was already rotated means it leaked, so the whole
+// device chain is revoked.
+func (s *AuthService) rotateDeviceToken(ctx context.Context, claims *utils.Claims, client *ClientInfo) (*utils.TokenPair, error) {
+	record, err := s.deviceRepo.GetToken(ctx, claims.ID)
+	if err != nil {
+		if err == repository.ErrRefreshTokenNotFound {
+			return nil, apperrors.ErrInvalidToken
+		}
+		s.logger.Error("Failed to get refresh token", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if record.DeviceID != claims.DeviceID || record.UserID != claims.UserID {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	device, err := s.deviceRepo.GetByID(ctx, claims.DeviceID)
+	if err != nil {
+		if err == repository.ErrDeviceNotFound {
+			return nil, apperrors.ErrInvalidToken
+		}
+		s.logger.Error("Failed to get device", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if device.IsRevoked() {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	tokenPair, err := s.jwtManager.GenerateDeviceTokenPair(claims.UserID, claims.Username, device.ID)
+	if err != nil {
+		s.logger.Error("Failed to generate token pair", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	err = s.deviceRepo.RotateToken(ctx, record.ID, &model.RefreshTokenRecord{
+		ID:        tokenPair.RefreshTokenID,
+		DeviceID:  device.ID,
+		UserID:    claims.UserID,
+		ParentID:  sql.NullString{String: record.ID, Valid: true},
+		IP:        sql.NullString{String: client.ip(), Valid: client.ip() != ""},
+		ExpiresAt: tokenPair.RefreshTokenExpiresAt,
+	})
+	if err == repository.ErrRefreshTokenReused {
+		s.logger.Warn("Refresh token reuse detected, revoking device tokens",
+			zap.String("user_id", claims.UserID),
+			zap.String("device_id", device.ID),
+		)
+		if err := s.deviceRepo.RevokeTokens(context.WithoutCancel(ctx), device.ID); err != nil {
+			s.logger.Error("Failed to revoke device tokens", zap.Error(err))
+		}
+		return nil, apperrors.ErrInvalidToken
+	}
+	if err != nil {
+		s.logger.Error("Failed to rotate refresh token", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	if err := s.deviceRepo.Touch(ctx, device.ID, client.ip()); err != nil {
+		s.logger.Warn("Failed to update device last seen", zap.Error(err))
+	}
+	return tokenPair, nil
+}
+
+// DeviceSession is a device with its recent refresh token issuance history
+type DeviceSession struct {
+	Device *model.UserDevice
+	Tokens []*model.RefreshTokenRecord
+}
+
+// ListDevices lists a user's devices with their recent token history
+func (s *AuthService) ListDevices(ctx context.Context, userID string) ([]*DeviceSession, error) {
+	if s.deviceRepo == nil {
+		return []*DeviceSession{}, nil
+	}
+
+	devices, err := s.deviceRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list devices", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	sessions := make([]*DeviceSession, len(devices))
+	for i, device := range devices {
+		tokens, err := s.deviceRepo.ListTokens(ctx, device.ID, deviceTokenHistory)
+		if err != nil {
+			s.logger.Error("Failed to list refresh tokens", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		sessions[i] = &DeviceSession{Device: device, Tokens: tokens}
+	}
+
+	return sessions, nil
+}
+
+// RevokeDevice revokes one of the user's devices and its refresh token
+// chain, and drops the device's realtime connections. Other devices are
+// unaffected; access tokens already issued to the device stay valid for
+// REST requests until they expire.
+func (s *AuthService) RevokeDevice(ctx context.Context, userID, deviceID string) error {
+	if s.deviceRepo == nil {
+		return apperrors.ErrDeviceNotFound
+	}
+
+	if err := s.deviceRepo.Revoke(ctx, userID, deviceID); err != nil {
+		if err == repository.ErrDeviceNotFound {
+			return apperrors.ErrDeviceNotFound
+		}
+		s.logger.Error("Failed to revoke device", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("Device revoked",
+		zap.String("user_id", userID),
+		zap.String("device_id", deviceID),
+	)
+	if s.devices != nil {
+		s.devices.DisconnectDevice(userID, deviceID, "此裝置已登出")
+	}
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    userID,
+		Action:     model.AuditActionDeviceRevoked,
+		TargetType: model.AuditTargetUser,
+		TargetID:   userID,
+		Metadata:   map[string]interface{}{"device_id": deviceID},
+	})
+	return nil
+}
+
+// ListSessions lists the user's signed-in devices, most recently used first
+func (s *AuthService) ListSessions(ctx context.Context, userID string) ([]*model.UserDevice, error) {
+	if s.deviceRepo == nil {
+		return []*model.UserDevice{}, nil
+	}
+
+	devices, err := s.deviceRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list sessions", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	sessions := make([]*model.UserDevice, 0, len(devices))
+	for _, device := range devices {
+		if !device.IsRevoked() {
+			sessions = append(sessions, device)
+		}
+	}
+	return sessions, nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+type recordingDeviceDisconnector struct {
+	mu        sync.Mutex
+	deviceIDs []string
+}
+
+func (r *recordingDeviceDisconnector) DisconnectDevice(userID, deviceID, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deviceIDs = append(r.deviceIDs, deviceID)
+}
+
+func TestAuthService_DeviceTokens(t *testing.T) {
+	service, db, prefix := setupTestAuthServiceIsolated(t)
+	defer db.Close()
+	defer cleanupAuthTestByPrefix(t, db, prefix)
+
+	service.SetDeviceRepository(repository.NewDeviceRepository(db))
+	disconnector := &recordingDeviceDisconnector{}
+	service.SetDeviceDisconnector(disconnector)
+	ctx := context.Background()
+	client := &ClientInfo{UserAgent: "test-agent", IP: "127.0.0.1"}
+
+	registered, err := service.Register(ctx, &RegisterInput{
+		Username:   prefix + "_testuser",
+		Email:      prefix + "_test@example.com",
+		Password:   "password123",
+		DeviceName: "laptop",
+		Client:     client,
+	})
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	laptop := registered.TokenPair.DeviceID
+	if laptop == "" {
+		t.Fatal("Expected device ID to be issued")
+	}
+
+	phone, err := service.Login(ctx, &LoginInput{
+		Username:   prefix + "_testuser",
+		Password:   "password123",
+		DeviceName: "phone",
+		Client:     client,
+	})
+	if err != nil {
+		t.Fatalf("Failed to login: %v", err)
+	}
+	if phone.TokenPair.DeviceID == laptop {
+		t.Fatal("Expected login without device ID to register a new device")
+	}
+
+	// Rotation keeps the device and invalidates the old token
+	rotated, err := service.RefreshToken(ctx, registered.TokenPair.RefreshToken, client)
+	if err != nil {
+		t.Fatalf("Failed to refresh token: %v", err)
+	}
+	if rotated.DeviceID != laptop {
+		t.Errorf("Expected rotated token on device %s, got %s", laptop, rotated.DeviceID)
+	}
+
+	// Replaying the old token revokes the laptop's whole chain
+	if _, err := service.RefreshToken(ctx, registered.TokenPair.RefreshToken, client); !apperrors.Is(err, apperrors.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken on reuse, got %v", err)
+	}
+	if _, err := service.RefreshToken(ctx, rotated.RefreshToken, client); !apperrors.Is(err, apperrors.ErrInvalidToken) {
+		t.Errorf("Expected rotated token to be revoked after reuse, got %v", err)
+	}
+
+	// Revoking the phone leaves other devices alone
+	relogin, err := service.Login(ctx, &LoginInput{
+		Username: prefix + "_testuser",
+		Password: "password123",
+		DeviceID: laptop,
+		Client:   client,
+	})
+	if err != nil {
+		t.Fatalf("Failed to login: %v", err)
+	}
+	if relogin.TokenPair.DeviceID != laptop {
+		t.Errorf("Expected login to reuse device %s, got %s", laptop, relogin.TokenPair.DeviceID)
+	}
+
+	if err := service.CheckDevice(ctx, registered.User.ID, phone.TokenPair.DeviceID); err != nil {
+		t.Errorf("Expected active device to pass, got %v", err)
+	}
+	if err := service.RevokeDevice(ctx, registered.User.ID, phone.TokenPair.DeviceID); err != nil {
+		t.Fatalf("Failed to revoke device: %v", err)
+	}
+	if len(disconnector.deviceIDs) != 1 || disconnector.deviceIDs[0] != phone.TokenPair.DeviceID {
+		t.Errorf("Expected the revoked device to be disconnected, got %v", disconnector.deviceIDs)
+	}
+	if err := service.CheckDevice(ctx, registered.User.ID, phone.TokenPair.DeviceID); !apperrors.Is(err, apperrors.ErrDeviceNotFound) {
+		t.Errorf("Expected revoked device to be rejected, got %v", err)
+	}
+	if err := service.CheckDevice(ctx, "someone-else", laptop); !apperrors.Is(err, apperrors.ErrDeviceNotFound) {
+		t.Errorf("Expected another user's device to be rejected, got %v", err)
+	}
+	if _, err := service.RefreshToken(ctx, phone.TokenPair.RefreshToken, client); !apperrors.Is(err, apperrors.ErrInvalidToken) {
+		t.Errorf("Expected revoked device token to be rejected, got %v", err)
+	}
+	if _, err := service.RefreshToken(ctx, relogin.TokenPair.RefreshToken, client); err != nil {
+		t.Errorf("Expected other device to keep working, got %v", err)
+	}
+
+	if err := service.RevokeDevice(ctx, registered.User.ID, phone.TokenPair.DeviceID); !apperrors.Is(err, apperrors.ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound on second revoke, got %v", err)
+	}
+
+	sessions, err := service.ListDevices(ctx, registered.User.ID)
+	if err != nil {
+		t.Fatalf("Failed to list devices: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 devices, got %d", len(sessions))
+	}
+	for _, s := range sessions {
+		if len(s.Tokens) == 0 {
+			t.Errorf("Expected token history for device %s", s.Device.ID)
+		}
+	}
+}
+
+func TestAuthService_ListSessions(t *testing.T) {
+	service, db, prefix := setupTestAuthServiceIsolated(t)
+	defer db.Close()
+	defer cleanupAuthTestByPrefix(t, db, prefix)
+
+	service.SetDeviceRepository(repository.NewDeviceRepository(db))
+	ctx := context.Background()
+
+	registered, err := service.Register(ctx, &RegisterInput{
+		Username: prefix + "_testuser",
+		Email:    prefix + "_test@example.com",
+		Password: "password123",
+		Client:   &ClientInfo{UserAgent: "desktop-agent", IP: "10.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	other, err := service.Login(ctx, &LoginInput{
+		Username: prefix + "_testuser",
+		Password: "password123",
+		Client:   &ClientInfo{UserAgent: "mobile-agent", IP: "10.0.0.2"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to login: %v", err)
+	}
+
+	if err := service.RevokeDevice(ctx, registered.User.ID, other.TokenPair.DeviceID); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+
+	sessions, err := service.ListSessions(ctx, registered.User.ID)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("Expected 1 active session, got %d", len(sessions))
+	}
+	if sessions[0].ID != registered.TokenPair.DeviceID {
+		t.Errorf("Expected session %s, got %s", registered.TokenPair.DeviceID, sessions[0].ID)
+	}
+	if sessions[0].UserAgent.String != "desktop-agent" || sessions[0].LastIP.String != "10.0.0.1" {
+		t.Errorf("Unexpected session client info: %+v", sessions[0])
+	}
+}
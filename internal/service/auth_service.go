@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/go-demo/chat/internal/anomaly"
+	"github.com/go-demo/chat/internal/events"
 	"github.com/go-demo/chat/internal/model"
 	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 	"github.com/go-demo/chat/internal/pkg/utils"
@@ -11,13 +13,28 @@ import (
 	"go.uber.org/zap"
 )
 
+// Registrations are watched across the whole site rather than per client
+const registrationAnomalyKey = "site"
+
+// AccountChecker rejects accounts that may not sign in, e.g. banned users
+type AccountChecker interface {
+	CheckAccount(ctx context.Context, userID string) error
+}
+
 type AuthService struct {
-	userRepo   *repository.UserRepository
-	jwtManager *utils.JWTManager
-	logger     *zap.Logger
+	userRepo       AuthUserStore
+	jwtManager     *utils.JWTManager
+	accountChecker AccountChecker
+	auditor        *AuditService
+	deviceRepo     *repository.DeviceRepository
+	devices        DeviceDisconnector
+	anomalies      *anomaly.Detector
+	userCache      *UserCache
+	events         *events.Publisher
+	logger         *zap.Logger
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtManager *utils.JWTManager, logger *zap.Logger) *AuthService {
+func NewAuthService(userRepo AuthUserStore, jwtManager *utils.JWTManager, logger *zap.Logger) *AuthService {
 	return &AuthService{
 		userRepo:   userRepo,
 		jwtManager: jwtManager,
@@ -25,11 +42,49 @@ func NewAuthService(userRepo *repository.UserRepository, jwtManager *utils.JWTMa
 	}
 }
 
+// SetAccountChecker sets the checker consulted on login and token refresh
+func (s *AuthService) SetAccountChecker(checker AccountChecker) {
+	s.accountChecker = checker
+}
+
+// SetAuditor sets the audit service that records password changes
+func (s *AuthService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// SetAnomalyDetector sets the detector watching for registration spikes
+func (s *AuthService) SetAnomalyDetector(detector *anomaly.Detector) {
+	s.anomalies = detector
+}
+
+// SetUserCache sets the cache of user display data that profile changes
+// invalidate
+func (s *AuthService) SetUserCache(cache *UserCache) {
+	s.userCache = cache
+}
+
+// SetEvents sets the publisher user.registered is sent to
+func (s *AuthService) SetEvents(publisher *events.Publisher) {
+	s.events = publisher
+}
+
+// checkAccount runs the account checker if one is configured
+func (s *AuthService) checkAccount(ctx context.Context, userID string) error {
+	if s.accountChecker == nil {
+		return nil
+	}
+	return s.accountChecker.CheckAccount(ctx, userID)
+}
+
 // RegisterInput represents registration input
 type RegisterInput struct {
 	Username string
 	Email    string
 	Password string
+
+	// DeviceName labels the device registered for this session
+	DeviceName string
+	Client     *ClientInfo
 }
 
 // RegisterResult represents registration result
@@ -61,6 +116,9 @@ func (s *AuthService) Register(ctx context.Context, input *RegisterInput) (*Regi
 	}
 
 	// Hash password
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
 	passwordHash, err := utils.HashPassword(input.Password)
 	if err != nil {
 		s.logger.Error("Failed to hash password", zap.Error(err))
@@ -79,12 +137,17 @@ func (s *AuthService) Register(ctx context.Context, input *RegisterInput) (*Regi
 		s.logger.Error("Failed to create user", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
+	s.anomalies.Record(ctx, anomaly.SignalRegistration, registrationAnomalyKey)
+	s.events.Publish(events.UserRegistered, &events.UserRegisteredData{
+		UserID:    user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	})
 
 	// Generate tokens
-	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username)
+	tokenPair, err := s.startSession(ctx, user, "", input.DeviceName, input.Client)
 	if err != nil {
-		s.logger.Error("Failed to generate token pair", zap.Error(err))
-		return nil, apperrors.ErrInternal
+		return nil, err
 	}
 
 	s.logger.Info("User registered",
@@ -102,6 +165,12 @@ func (s *AuthService) Register(ctx context.Context, input *RegisterInput) (*Regi
 type LoginInput struct {
 	Username string
 	Password string
+
+	// DeviceID reuses a previously registered device; a new device is
+	// registered when it is empty or no longer valid
+	DeviceID   string
+	DeviceName string
+	Client     *ClientInfo
 }
 
 // LoginResult represents login result
@@ -123,15 +192,48 @@ func (s *AuthService) Login(ctx context.Context, input *LoginInput) (*LoginResul
 	}
 
 	// Check password
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
 	if !utils.CheckPassword(input.Password, user.PasswordHash) {
 		return nil, apperrors.ErrInvalidPassword
 	}
+	s.upgradePassword(ctx, user, input.Password)
+
+	// Only reveal a ban once the password has been verified
+	return s.signIn(ctx, user, input)
+}
+
+// upgradePassword rehashes a verified password whose hash predates the
+// current algorithm or settings, so bcrypt hashes move to Argon2id as users
+// sign in. Failures are logged only: the old hash keeps working.
+func (s *AuthService) upgradePassword(ctx context.Context, user *model.User, password string) {
+	if !utils.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		s.logger.Warn("Failed to rehash password", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+	if err := s.userRepo.RehashPassword(ctx, user.ID, user.PasswordHash, passwordHash); err != nil {
+		s.logger.Warn("Failed to store rehashed password", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+	user.PasswordHash = passwordHash
+}
+
+// signIn starts a session for a user whose credentials have been verified
+func (s *AuthService) signIn(ctx context.Context, user *model.User, input *LoginInput) (*LoginResult, error) {
+	if err := s.checkAccount(ctx, user.ID); err != nil {
+		return nil, err
+	}
 
 	// Generate tokens
-	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username)
+	tokenPair, err := s.startSession(ctx, user, input.DeviceID, input.DeviceName, input.Client)
 	if err != nil {
-		s.logger.Error("Failed to generate token pair", zap.Error(err))
-		return nil, apperrors.ErrInternal
+		return nil, err
 	}
 
 	// Update status to online
@@ -150,8 +252,9 @@ func (s *AuthService) Login(ctx context.Context, input *LoginInput) (*LoginResul
 	}, nil
 }
 
-// RefreshToken refreshes an access token
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*utils.TokenPair, error) {
+// RefreshToken refreshes an access token. Device-bound refresh tokens are
+// single use and rotate within their device's chain.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client *ClientInfo) (*utils.TokenPair, error) {
 	// Validate refresh token
 	claims, err := s.jwtManager.ValidateRefreshToken(refreshToken)
 	if err != nil {
@@ -161,18 +264,28 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*u
 		return nil, apperrors.ErrInvalidToken
 	}
 
-	// Generate new token pair
-	tokenPair, err := s.jwtManager.GenerateTokenPair(claims.UserID, claims.Username)
-	if err != nil {
-		s.logger.Error("Failed to generate token pair", zap.Error(err))
-		return nil, apperrors.ErrInternal
+	if err := s.checkAccount(ctx, claims.UserID); err != nil {
+		return nil, err
+	}
+
+	if s.deviceRepo != nil && claims.DeviceID != "" {
+		return s.rotateDeviceToken(ctx, claims, client)
 	}
 
-	return tokenPair, nil
+	// Tokens issued before devices were tracked move onto a new device
+	user := &model.User{ID: claims.UserID, Username: claims.Username}
+	return s.startSession(ctx, user, "", "", client)
 }
 
-// Logout logs out a user
-func (s *AuthService) Logout(ctx context.Context, userID string) error {
+// Logout logs out a user and revokes the refresh tokens of the device the
+// session belongs to
+func (s *AuthService) Logout(ctx context.Context, userID, deviceID string) error {
+	if s.deviceRepo != nil && deviceID != "" {
+		if err := s.deviceRepo.RevokeTokens(ctx, deviceID); err != nil {
+			s.logger.Warn("Failed to revoke device tokens on logout", zap.Error(err))
+		}
+	}
+
 	// Update status to offline
 	if err := s.userRepo.UpdateStatus(ctx, userID, model.UserStatusOffline); err != nil {
 		s.logger.Warn("Failed to update user status on logout", zap.Error(err))
@@ -202,6 +315,9 @@ func (s *AuthService) ChangePassword(ctx context.Context, input *ChangePasswordI
 	}
 
 	// Check current password
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
 	if !utils.CheckPassword(input.CurrentPassword, user.PasswordHash) {
 		return apperrors.ErrInvalidPassword
 	}
@@ -214,6 +330,9 @@ func (s *AuthService) ChangePassword(ctx context.Context, input *ChangePasswordI
 	}
 
 	// Hash new password
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
 	passwordHash, err := utils.HashPassword(input.NewPassword)
 	if err != nil {
 		s.logger.Error("Failed to hash password", zap.Error(err))
@@ -227,6 +346,12 @@ func (s *AuthService) ChangePassword(ctx context.Context, input *ChangePasswordI
 	}
 
 	s.logger.Info("User changed password", zap.String("user_id", input.UserID))
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    input.UserID,
+		Action:     model.AuditActionPasswordChanged,
+		TargetType: model.AuditTargetUser,
+		TargetID:   input.UserID,
+	})
 	return nil
 }
 
@@ -288,6 +413,7 @@ func (s *AuthService) UpdateProfile(ctx context.Context, user *model.User) error
 		s.logger.Error("Failed to update user", zap.Error(err))
 		return apperrors.ErrInternal
 	}
+	s.userCache.Invalidate(ctx, existingUser.ID)
 
 	return nil
 }
@@ -303,5 +429,46 @@ func (s *AuthService) SetDisplayName(ctx context.Context, userID, displayName st
 	}
 
 	user.DisplayName = sql.NullString{String: displayName, Valid: displayName != ""}
-	return s.userRepo.Update(ctx, user)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	s.userCache.Invalidate(ctx, userID)
+	return nil
+}
+
+// SetDiscoverable sets whether contacts discovery may find a user
+func (s *AuthService) SetDiscoverable(ctx context.Context, userID string, discoverable bool) error {
+	if err := s.userRepo.UpdateDiscoverable(ctx, userID, discoverable); err != nil {
+		if err == repository.ErrUserNotFound {
+			return apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to update discoverable", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	return nil
+}
+
+// SetLastSeenVisibility sets who may see when a user was last online
+func (s *AuthService) SetLastSeenVisibility(ctx context.Context, userID string, visibility model.LastSeenVisibility) error {
+	if err := s.userRepo.UpdateLastSeenVisibility(ctx, userID, visibility); err != nil {
+		if err == repository.ErrUserNotFound {
+			return apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to update last seen visibility", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	return nil
+}
+
+// SetDigestFrequency sets how often a user is emailed a summary of unread
+// activity
+func (s *AuthService) SetDigestFrequency(ctx context.Context, userID string, frequency model.DigestFrequency) error {
+	if err := s.userRepo.UpdateDigestFrequency(ctx, userID, frequency); err != nil {
+		if err == repository.ErrUserNotFound {
+			return apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to update digest frequency", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	return nil
 }
@@ -6,11 +6,13 @@ import (
 	"time"
 
 	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 	"github.com/go-demo/chat/internal/pkg/utils"
 	"github.com/go-demo/chat/internal/repository"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func setupTestAuthServiceIsolated(t *testing.T) (*AuthService, *sqlx.DB, string) {
@@ -163,6 +165,47 @@ func TestAuthService_Login(t *testing.T) {
 	}
 }
 
+func TestAuthService_Login_RehashesBcrypt(t *testing.T) {
+	service, db, prefix := setupTestAuthServiceIsolated(t)
+	defer db.Close()
+	defer cleanupAuthTestByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	username := prefix + "_testuser"
+
+	registered, err := service.Register(ctx, &RegisterInput{
+		Username: username,
+		Email:    prefix + "_test@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+
+	// Store a hash from before Argon2id
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err := service.userRepo.UpdatePassword(ctx, registered.User.ID, string(legacy)); err != nil {
+		t.Fatalf("Failed to set legacy hash: %v", err)
+	}
+
+	if _, err := service.Login(ctx, &LoginInput{Username: username, Password: "password123"}); err != nil {
+		t.Fatalf("Failed to login with legacy hash: %v", err)
+	}
+
+	user, err := service.userRepo.GetByID(ctx, registered.User.ID)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if utils.NeedsRehash(user.PasswordHash) {
+		t.Errorf("Expected hash to be upgraded, got %s", user.PasswordHash)
+	}
+
+	// The upgraded hash keeps working
+	if _, err := service.Login(ctx, &LoginInput{Username: username, Password: "password123"}); err != nil {
+		t.Fatalf("Failed to login after rehash: %v", err)
+	}
+}
+
 func TestAuthService_Login_WrongPassword(t *testing.T) {
 	service, db, prefix := setupTestAuthServiceIsolated(t)
 	defer db.Close()
@@ -227,7 +270,7 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	}
 
 	// Refresh token
-	newTokenPair, err := service.RefreshToken(ctx, result.TokenPair.RefreshToken)
+	newTokenPair, err := service.RefreshToken(ctx, result.TokenPair.RefreshToken, nil)
 	if err != nil {
 		t.Fatalf("Failed to refresh token: %v", err)
 	}
@@ -248,7 +291,7 @@ func TestAuthService_RefreshToken_Invalid(t *testing.T) {
 
 	ctx := context.Background()
 
-	_, err := service.RefreshToken(ctx, "invalid-token")
+	_, err := service.RefreshToken(ctx, "invalid-token", nil)
 	if err == nil {
 		t.Error("Expected error for invalid refresh token")
 	}
@@ -388,7 +431,7 @@ func TestAuthService_Logout(t *testing.T) {
 		t.Fatalf("Failed to register: %v", err)
 	}
 
-	err = service.Logout(ctx, result.User.ID)
+	err = service.Logout(ctx, result.User.ID, "")
 	if err != nil {
 		t.Fatalf("Failed to logout: %v", err)
 	}
@@ -399,3 +442,86 @@ func TestAuthService_Logout(t *testing.T) {
 		t.Errorf("Expected status offline, got %s", user.Status)
 	}
 }
+
+func newMockAuthService(users *mockAuthUserStore) *AuthService {
+	jwtManager := utils.NewJWTManager("test-secret-key", 15*time.Minute, 7*24*time.Hour, "test")
+	return NewAuthService(users, jwtManager, zap.NewNop())
+}
+
+func TestAuthService_Register_Mocked(t *testing.T) {
+	ctx := context.Background()
+	users := &mockAuthUserStore{}
+	users.ExistsByUsernameFunc = func(ctx context.Context, username string) (bool, error) {
+		return username == "taken", nil
+	}
+	users.CreateFunc = func(ctx context.Context, user *model.User) error {
+		user.ID = "user-1"
+		return nil
+	}
+	service := newMockAuthService(users)
+
+	if _, err := service.Register(ctx, &RegisterInput{Username: "taken", Email: "a@example.com", Password: "password123"}); err != apperrors.ErrUsernameExists {
+		t.Errorf("Expected ErrUsernameExists, got %v", err)
+	}
+	if users.Calls("Create") != 0 {
+		t.Fatal("Expected no user to be created")
+	}
+
+	result, err := service.Register(ctx, &RegisterInput{Username: "alice", Email: "a@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if result.User.ID != "user-1" || result.TokenPair == nil {
+		t.Errorf("Expected the created user and tokens, got %+v", result)
+	}
+	if result.User.PasswordHash == "password123" || !utils.CheckPassword("password123", result.User.PasswordHash) {
+		t.Error("Expected the password to be stored hashed")
+	}
+}
+
+func TestAuthService_Login_Mocked(t *testing.T) {
+	ctx := context.Background()
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	users := &mockAuthUserStore{}
+	users.GetByUsernameFunc = func(ctx context.Context, username string) (*model.User, error) {
+		if username != "alice" {
+			return nil, repository.ErrUserNotFound
+		}
+		return &model.User{ID: "user-1", Username: "alice", PasswordHash: string(legacy)}, nil
+	}
+	users.GetByIDFunc = func(ctx context.Context, id string) (*model.User, error) {
+		return &model.User{ID: id, Username: "alice"}, nil
+	}
+	var rehashed string
+	users.RehashPasswordFunc = func(ctx context.Context, userID, oldHash, newHash string) error {
+		if oldHash != string(legacy) {
+			t.Errorf("Expected the bcrypt hash to be replaced, got %s", oldHash)
+		}
+		rehashed = newHash
+		return nil
+	}
+	service := newMockAuthService(users)
+
+	for _, input := range []*LoginInput{
+		{Username: "bob", Password: "password123"},
+		{Username: "alice", Password: "wrong-password"},
+	} {
+		if _, err := service.Login(ctx, input); err != apperrors.ErrInvalidPassword {
+			t.Errorf("Expected ErrInvalidPassword for %s, got %v", input.Username, err)
+		}
+	}
+	if users.Calls("RehashPassword") != 0 {
+		t.Fatal("Expected no rehash before the password is verified")
+	}
+
+	result, err := service.Login(ctx, &LoginInput{Username: "alice", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Failed to login: %v", err)
+	}
+	if result.TokenPair == nil {
+		t.Error("Expected tokens")
+	}
+	if rehashed == "" || utils.NeedsRehash(rehashed) {
+		t.Errorf("Expected the password to be rehashed with Argon2id, got %q", rehashed)
+	}
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/imaging"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AvatarSizes are the square renditions made of every avatar, in pixels,
+// smallest first; the largest becomes the profile's avatar_url
+var AvatarSizes = []int{32, 64, 128, 256}
+
+var (
+	ErrAvatarUnsupported = apperrors.New(http.StatusBadRequest, "頭像須為 JPEG、PNG 或 GIF 圖片")
+	ErrAvatarTooLarge    = apperrors.New(http.StatusBadRequest, "頭像圖片尺寸過大")
+	ErrAvatarCrop        = apperrors.New(http.StatusBadRequest, "裁切範圍超出圖片")
+)
+
+// avatarRenditionName matches the files RenderAvatar writes:
+// <user>_<nanos>_<size><ext>
+var avatarRenditionName = regexp.MustCompile(`^(.+_\d+)_(\d+)(\.[a-z]+)$`)
+
+// AvatarCrop is the square of the uploaded image to keep, in pixels from
+// its top-left corner
+type AvatarCrop struct {
+	X, Y, Size int
+}
+
+// RenderAvatarInput is an uploaded avatar and where to store it
+type RenderAvatarInput struct {
+	UserID string
+	Image  []byte
+	Crop   *AvatarCrop // nil keeps the centered square
+
+	Dir       string // directory the renditions are written to
+	URLPrefix string // URL Dir is served under
+}
+
+// AvatarRenditions are the stored files of one avatar
+type AvatarRenditions struct {
+	URLs        map[int]string // by size
+	ContentType string
+
+	dir       string
+	urlPrefix string
+	paths     []string
+}
+
+// URL returns the address of the largest rendition
+func (r *AvatarRenditions) URL() string {
+	return r.URLs[AvatarSizes[len(AvatarSizes)-1]]
+}
+
+// Path returns where the largest rendition is stored
+func (r *AvatarRenditions) Path() string {
+	return r.paths[len(r.paths)-1]
+}
+
+// Remove deletes the renditions, for an avatar that was not applied
+func (r *AvatarRenditions) Remove() {
+	for _, p := range r.paths {
+		_ = os.Remove(p)
+	}
+}
+
+// RenderAvatar crops an uploaded image to a square and stores it in every
+// AvatarSizes size. JPEG images stay JPEG; PNG and GIF images, which may be
+// transparent, are stored as PNG.
+func RenderAvatar(input *RenderAvatarInput) (*AvatarRenditions, error) {
+	img, format, err := imaging.Decode(input.Image, imaging.DefaultMaxPixels)
+	switch err {
+	case nil:
+	case imaging.ErrTooLarge:
+		return nil, ErrAvatarTooLarge
+	default:
+		return nil, ErrAvatarUnsupported
+	}
+
+	area := imaging.CenterSquare(img)
+	if input.Crop != nil {
+		if area, err = imaging.Square(img, input.Crop.X, input.Crop.Y, input.Crop.Size); err != nil {
+			return nil, ErrAvatarCrop
+		}
+	}
+
+	r := &AvatarRenditions{
+		URLs:      make(map[int]string, len(AvatarSizes)),
+		dir:       input.Dir,
+		urlPrefix: input.URLPrefix,
+	}
+	stem := fmt.Sprintf("%s_%d", input.UserID, time.Now().UnixNano())
+
+	// Each size is scaled from the next larger one, so only the largest
+	// reads the whole crop
+	var src image.Image = img
+	for i := len(AvatarSizes) - 1; i >= 0; i-- {
+		size := AvatarSizes[i]
+		scaled := imaging.Resize(src, area, size, size)
+		src, area = scaled, scaled.Bounds()
+
+		var buf bytes.Buffer
+		ext, contentType, err := imaging.Encode(&buf, scaled, format)
+		if err != nil {
+			r.Remove()
+			return nil, fmt.Errorf("failed to encode avatar: %w", err)
+		}
+		name := fmt.Sprintf("%s_%d%s", stem, size, ext)
+		p := filepath.Join(input.Dir, name)
+		if err := os.WriteFile(p, buf.Bytes(), 0644); err != nil {
+			r.Remove()
+			return nil, fmt.Errorf("failed to write avatar: %w", err)
+		}
+		r.paths = append([]string{p}, r.paths...)
+		r.URLs[size] = input.URLPrefix + "/" + name
+		r.ContentType = contentType
+	}
+	return r, nil
+}
+
+// AvatarService sets the avatars users upload on their profiles and deletes
+// the files of the avatars they replace
+type AvatarService struct {
+	store  AvatarStore
+	cache  *UserCache
+	logger *zap.Logger
+}
+
+// NewAvatarService creates the service
+func NewAvatarService(store AvatarStore, logger *zap.Logger) *AvatarService {
+	return &AvatarService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// SetCache sets the cache of user display data, which holds avatars
+func (s *AvatarService) SetCache(cache *UserCache) {
+	s.cache = cache
+}
+
+// Replace makes stored renditions the user's avatar in one update and then
+// deletes the files of the previous avatar, if this server stored them. On
+// error the profile is unchanged and the caller removes the renditions.
+func (s *AvatarService) Replace(ctx context.Context, userID string, r *AvatarRenditions) error {
+	previous, err := s.store.UpdateAvatar(ctx, userID, r.URL())
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to update avatar", zap.String("user_id", userID), zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	s.cache.Invalidate(ctx, userID)
+
+	for _, p := range previousAvatarFiles(userID, previous, r) {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("Failed to remove previous avatar", zap.String("path", p), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// previousAvatarFiles returns the stored files of a replaced avatar: every
+// rendition, or the single file of an avatar uploaded before renditions.
+// Avatars hosted elsewhere, or set to someone else's file, are left alone.
+func previousAvatarFiles(userID, previous string, r *AvatarRenditions) []string {
+	if previous == r.URL() || !strings.HasPrefix(previous, r.urlPrefix+"/") {
+		return nil
+	}
+	name := path.Base(previous)
+	if !strings.HasPrefix(name, userID+"_") {
+		return nil
+	}
+
+	m := avatarRenditionName.FindStringSubmatch(name)
+	if m == nil || !isAvatarSize(m[2]) {
+		return []string{filepath.Join(r.dir, name)}
+	}
+	files := make([]string, len(AvatarSizes))
+	for i, size := range AvatarSizes {
+		files[i] = filepath.Join(r.dir, fmt.Sprintf("%s_%d%s", m[1], size, m[3]))
+	}
+	return files
+}
+
+func isAvatarSize(s string) bool {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return false
+	}
+	for _, size := range AvatarSizes {
+		if size == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const testAvatarPrefix = "http://localhost:8080/uploads/avatars"
+
+func renderTestAvatar(t *testing.T, dir, userID string) *AvatarRenditions {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 80, 60))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	r, err := RenderAvatar(&RenderAvatarInput{UserID: userID, Image: buf.Bytes(), Dir: dir, URLPrefix: testAvatarPrefix})
+	if err != nil {
+		t.Fatalf("Failed to render avatar: %v", err)
+	}
+	return r
+}
+
+func TestRenderAvatar_Errors(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 80, 60)))
+
+	if _, err := RenderAvatar(&RenderAvatarInput{UserID: "u1", Image: []byte("GIF89a?"), Dir: dir}); err != ErrAvatarUnsupported {
+		t.Errorf("Expected ErrAvatarUnsupported, got %v", err)
+	}
+	crop := &AvatarCrop{X: 30, Y: 0, Size: 60}
+	if _, err := RenderAvatar(&RenderAvatarInput{UserID: "u1", Image: buf.Bytes(), Crop: crop, Dir: dir}); err != ErrAvatarCrop {
+		t.Errorf("Expected ErrAvatarCrop, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected nothing stored, got %d files", len(entries))
+	}
+}
+
+func TestAvatarService_Replace(t *testing.T) {
+	dir := t.TempDir()
+	old := renderTestAvatar(t, dir, "u1")
+	legacy := filepath.Join(dir, "u1_1700000000.jpg")
+	someoneElses := filepath.Join(dir, "u2_1700000000.jpg")
+	for _, p := range []string{legacy, someoneElses} {
+		if err := os.WriteFile(p, []byte("jpg"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	previous := old.URL()
+	store := &mockAvatarStore{UpdateAvatarFunc: func(ctx context.Context, userID, avatarURL string) (string, error) {
+		p := previous
+		previous = avatarURL
+		return p, nil
+	}}
+	service := NewAvatarService(store, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("Every rendition of the previous avatar is removed", func(t *testing.T) {
+		r := renderTestAvatar(t, dir, "u1")
+		if err := service.Replace(ctx, "u1", r); err != nil {
+			t.Fatalf("Failed to replace avatar: %v", err)
+		}
+		for _, p := range old.paths {
+			if _, err := os.Stat(p); !os.IsNotExist(err) {
+				t.Errorf("Expected %s removed", p)
+			}
+		}
+		for _, p := range r.paths {
+			if _, err := os.Stat(p); err != nil {
+				t.Errorf("Expected %s kept, got %v", p, err)
+			}
+		}
+	})
+
+	t.Run("Avatars uploaded before renditions are removed", func(t *testing.T) {
+		previous = testAvatarPrefix + "/u1_1700000000.jpg"
+		if err := service.Replace(ctx, "u1", renderTestAvatar(t, dir, "u1")); err != nil {
+			t.Fatalf("Failed to replace avatar: %v", err)
+		}
+		if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+			t.Error("Expected the legacy avatar removed")
+		}
+	})
+
+	t.Run("Someone else's file is kept", func(t *testing.T) {
+		previous = testAvatarPrefix + "/u2_1700000000.jpg"
+		if err := service.Replace(ctx, "u1", renderTestAvatar(t, dir, "u1")); err != nil {
+			t.Fatalf("Failed to replace avatar: %v", err)
+		}
+		if _, err := os.Stat(someoneElses); err != nil {
+			t.Errorf("Expected the other user's file kept, got %v", err)
+		}
+	})
+
+	t.Run("A failed update keeps the previous avatar", func(t *testing.T) {
+		current := renderTestAvatar(t, dir, "u1")
+		previous = current.URL()
+		store.UpdateAvatarFunc = func(ctx context.Context, userID, avatarURL string) (string, error) {
+			return "", errors.New("database down")
+		}
+		if err := service.Replace(ctx, "u1", renderTestAvatar(t, dir, "u1")); err != apperrors.ErrInternal {
+			t.Errorf("Expected ErrInternal, got %v", err)
+		}
+		if _, err := os.Stat(current.Path()); err != nil {
+			t.Errorf("Expected the current avatar kept, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// UserDisconnector drops the realtime connections of a user.
+// It is implemented by ws.Hub.
+type UserDisconnector interface {
+	DisconnectUser(userID, reason string)
+}
+
+// CredentialCache remembers users whose credentials were recently checked.
+// It is implemented by middleware.AuthCache.
+type CredentialCache interface {
+	InvalidateUser(userID string)
+}
+
+type BanService struct {
+	banRepo      BanStore
+	userRepo     UserLookup
+	disconnector UserDisconnector
+	credentials  CredentialCache
+	auditor      *AuditService
+	logger       *zap.Logger
+}
+
+func NewBanService(
+	banRepo BanStore,
+	userRepo UserLookup,
+	logger *zap.Logger,
+) *BanService {
+	return &BanService{
+		banRepo:  banRepo,
+		userRepo: userRepo,
+		logger:   logger,
+	}
+}
+
+// SetDisconnector sets the component used to drop connections of banned users
+func (s *BanService) SetDisconnector(disconnector UserDisconnector) {
+	s.disconnector = disconnector
+}
+
+// SetCredentialCache sets the cache whose entries for banned users are dropped
+func (s *BanService) SetCredentialCache(cache CredentialCache) {
+	s.credentials = cache
+}
+
+// SetAuditor sets the audit service that records bans and unbans
+func (s *BanService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// BanInput represents ban input
+type BanInput struct {
+	UserID   string
+	BannedBy string
+	Reason   string
+	Duration time.Duration // zero bans permanently, otherwise suspends for this long
+}
+
+// BanDetails is returned to banned users so clients can explain the lockout
+type BanDetails struct {
+	Kind      model.BanKind `json:"kind"`
+	Reason    string        `json:"reason"`
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+}
+
+// bannedError builds an ErrUserBanned carrying the ban details
+func bannedError(ban *model.UserBan) *apperrors.AppError {
+	return &apperrors.AppError{
+		Code:    apperrors.ErrUserBanned.Code,
+		Message: apperrors.ErrUserBanned.Message,
+		Details: &BanDetails{
+			Kind:      ban.Kind(),
+			Reason:    ban.Reason,
+			ExpiresAt: ban.ExpiresAt,
+		},
+		Err: apperrors.ErrUserBanned,
+	}
+}
+
+// Ban bans or suspends a user and disconnects their realtime sessions
+func (s *BanService) Ban(ctx context.Context, input *BanInput) (*model.UserBan, error) {
+	if input.UserID == input.BannedBy {
+		return nil, apperrors.ErrCannotBanSelf
+	}
+
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to get user", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	// Administrators must be demoted before they can be banned
+	if user.IsAdmin {
+		return nil, apperrors.ErrPermissionDenied
+	}
+
+	ban := &model.UserBan{
+		UserID:   input.UserID,
+		Reason:   input.Reason,
+		BannedBy: sql.NullString{String: input.BannedBy, Valid: input.BannedBy != ""},
+	}
+	if input.Duration > 0 {
+		expiresAt := time.Now().Add(input.Duration)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	if err := s.banRepo.Create(ctx, ban); err != nil {
+		s.logger.Error("Failed to create ban", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("User banned",
+		zap.String("user_id", ban.UserID),
+		zap.String("banned_by", input.BannedBy),
+		zap.String("kind", string(ban.Kind())),
+		zap.String("reason", ban.Reason),
+	)
+
+	metadata := map[string]interface{}{
+		"kind":   string(ban.Kind()),
+		"reason": ban.Reason,
+	}
+	if ban.ExpiresAt != nil {
+		metadata["expires_at"] = ban.ExpiresAt.Format(time.RFC3339)
+	}
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    input.BannedBy,
+		Action:     model.AuditActionUserBanned,
+		TargetType: model.AuditTargetUser,
+		TargetID:   ban.UserID,
+		Metadata:   metadata,
+	})
+
+	if s.credentials != nil {
+		s.credentials.InvalidateUser(ban.UserID)
+	}
+	if s.disconnector != nil {
+		s.disconnector.DisconnectUser(ban.UserID, ban.Reason)
+	}
+
+	return ban, nil
+}
+
+// Unban lifts the active ban of a user
+func (s *BanService) Unban(ctx context.Context, userID, revokedBy string) error {
+	if err := s.banRepo.Revoke(ctx, userID, revokedBy); err != nil {
+		if err == repository.ErrBanNotFound {
+			return apperrors.ErrBanNotFound
+		}
+		s.logger.Error("Failed to revoke ban", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("User unbanned",
+		zap.String("user_id", userID),
+		zap.String("revoked_by", revokedBy),
+	)
+
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    revokedBy,
+		Action:     model.AuditActionUserUnbanned,
+		TargetType: model.AuditTargetUser,
+		TargetID:   userID,
+	})
+	return nil
+}
+
+// GetActiveBan returns the ban in effect for a user, or nil if there is none
+func (s *BanService) GetActiveBan(ctx context.Context, userID string) (*model.UserBan, error) {
+	ban, err := s.banRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBanNotFound) {
+			return nil, nil
+		}
+		s.logger.Error("Failed to get active ban", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return ban, nil
+}
+
+// CheckAccount returns ErrUserBanned if the user is currently banned or suspended
+func (s *BanService) CheckAccount(ctx context.Context, userID string) error {
+	ban, err := s.GetActiveBan(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return bannedError(ban)
+	}
+	return nil
+}
+
+// ListActive lists bans currently in effect
+func (s *BanService) ListActive(ctx context.Context, limit, offset int) ([]*model.UserBan, error) {
+	bans, err := s.banRepo.ListActive(ctx, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list active bans", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return bans, nil
+}
+
+// ListByUserID lists the ban history of a user
+func (s *BanService) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.UserBan, error) {
+	bans, err := s.banRepo.ListByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list bans", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return bans, nil
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type recordingDisconnector struct {
+	mu      sync.Mutex
+	userIDs []string
+}
+
+func (r *recordingDisconnector) DisconnectUser(userID, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.userIDs = append(r.userIDs, userID)
+}
+
+func setupTestBanServiceIsolated(t *testing.T) (*BanService, *sqlx.DB, string) {
+	t.Helper()
+
+	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=chat_test sslmode=disable"
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Skipf("Skipping test, could not connect to test database: %v", err)
+	}
+
+	banRepo := repository.NewBanRepository(db)
+	userRepo := repository.NewUserRepository(db)
+
+	service := NewBanService(banRepo, userRepo, zap.NewNop())
+	prefix := repository.GenerateUniquePrefix()
+	return service, db, prefix
+}
+
+func TestBanService_BanAndUnban(t *testing.T) {
+	service, db, prefix := setupTestBanServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	admin := repository.CreateIsolatedTestUser(t, db, prefix, "admin")
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "spammer")
+
+	disconnector := &recordingDisconnector{}
+	service.SetDisconnector(disconnector)
+
+	ban, err := service.Ban(ctx, &BanInput{UserID: user.ID, BannedBy: admin.ID, Reason: "spam"})
+	if err != nil {
+		t.Fatalf("Failed to ban user: %v", err)
+	}
+	if ban.Kind() != model.BanKindBan {
+		t.Errorf("Expected permanent ban, got %s", ban.Kind())
+	}
+	if len(disconnector.userIDs) != 1 || disconnector.userIDs[0] != user.ID {
+		t.Errorf("Expected banned user to be disconnected, got %v", disconnector.userIDs)
+	}
+
+	err = service.CheckAccount(ctx, user.ID)
+	if !apperrors.Is(err, apperrors.ErrUserBanned) {
+		t.Fatalf("Expected ErrUserBanned, got %v", err)
+	}
+	if details, ok := err.(*apperrors.AppError).Details.(*BanDetails); !ok || details.Reason != "spam" {
+		t.Errorf("Expected ban details with reason, got %+v", err.(*apperrors.AppError).Details)
+	}
+
+	if err := service.Unban(ctx, user.ID, admin.ID); err != nil {
+		t.Fatalf("Failed to unban user: %v", err)
+	}
+	if err := service.CheckAccount(ctx, user.ID); err != nil {
+		t.Errorf("Expected unbanned user to pass, got %v", err)
+	}
+
+	if err := service.Unban(ctx, user.ID, admin.ID); err != apperrors.ErrBanNotFound {
+		t.Errorf("Expected ErrBanNotFound, got %v", err)
+	}
+}
+
+func TestBanService_SuspensionExpires(t *testing.T) {
+	service, db, prefix := setupTestBanServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "suspended")
+
+	ban, err := service.Ban(ctx, &BanInput{UserID: user.ID, Reason: "cool down", Duration: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to suspend user: %v", err)
+	}
+	if ban.Kind() != model.BanKindSuspension {
+		t.Errorf("Expected suspension, got %s", ban.Kind())
+	}
+
+	if err := service.CheckAccount(ctx, user.ID); !apperrors.Is(err, apperrors.ErrUserBanned) {
+		t.Errorf("Expected suspended user to be rejected, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := service.CheckAccount(ctx, user.ID); err != nil {
+		t.Errorf("Expected expired suspension to pass, got %v", err)
+	}
+
+	// An expired suspension does not block a new ban
+	if _, err := service.Ban(ctx, &BanInput{UserID: user.ID, Reason: "again"}); err != nil {
+		t.Errorf("Failed to ban after expired suspension: %v", err)
+	}
+}
+
+func TestBanService_CannotBanSelf(t *testing.T) {
+	service, db, prefix := setupTestBanServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "self")
+
+	_, err := service.Ban(context.Background(), &BanInput{UserID: user.ID, BannedBy: user.ID, Reason: "oops"})
+	if err != apperrors.ErrCannotBanSelf {
+		t.Errorf("Expected ErrCannotBanSelf, got %v", err)
+	}
+}
+
+func TestAuthService_Login_Banned(t *testing.T) {
+	service, db, prefix := setupTestBanServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	jwtManager := utils.NewJWTManager("test-secret", 15*time.Minute, 7*24*time.Hour, "test")
+	authService := NewAuthService(userRepo, jwtManager, zap.NewNop())
+	authService.SetAccountChecker(service)
+
+	username := prefix + "_banned"
+	result, err := authService.Register(ctx, &RegisterInput{
+		Username: username,
+		Email:    prefix + "_banned@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+
+	if _, err := service.Ban(ctx, &BanInput{UserID: result.User.ID, Reason: "abuse"}); err != nil {
+		t.Fatalf("Failed to ban user: %v", err)
+	}
+
+	_, err = authService.Login(ctx, &LoginInput{Username: username, Password: "password123"})
+	if !apperrors.Is(err, apperrors.ErrUserBanned) {
+		t.Errorf("Expected ErrUserBanned on login, got %v", err)
+	}
+
+	_, err = authService.RefreshToken(ctx, result.TokenPair.RefreshToken, nil)
+	if !apperrors.Is(err, apperrors.ErrUserBanned) {
+		t.Errorf("Expected ErrUserBanned on refresh, got %v", err)
+	}
+}
+
+func TestBanService_Ban_Unit(t *testing.T) {
+	ctx := context.Background()
+	storeErr := errors.New("connection reset")
+
+	newService := func(user *model.User, createErr error) (*BanService, *mockBanStore, *recordingDisconnector) {
+		bans := &mockBanStore{CreateFunc: func(ctx context.Context, ban *model.UserBan) error {
+			if createErr != nil {
+				return createErr
+			}
+			ban.ID = "ban-1"
+			return nil
+		}}
+		users := &mockUserLookup{GetByIDFunc: func(ctx context.Context, id string) (*model.User, error) {
+			if user == nil {
+				return nil, repository.ErrUserNotFound
+			}
+			return user, nil
+		}}
+		disconnector := &recordingDisconnector{}
+		service := NewBanService(bans, users, zap.NewNop())
+		service.SetDisconnector(disconnector)
+		return service, bans, disconnector
+	}
+
+	t.Run("Suspends and disconnects", func(t *testing.T) {
+		service, bans, disconnector := newService(&model.User{ID: "u1"}, nil)
+		ban, err := service.Ban(ctx, &BanInput{UserID: "u1", BannedBy: "admin", Reason: "spam", Duration: time.Hour})
+		if err != nil {
+			t.Fatalf("Failed to ban: %v", err)
+		}
+		if ban.Kind() != model.BanKindSuspension || ban.ExpiresAt == nil {
+			t.Errorf("Expected a suspension with an expiry, got %+v", ban)
+		}
+		if bans.Calls("Create") != 1 {
+			t.Errorf("Expected 1 Create call, got %d", bans.Calls("Create"))
+		}
+		if len(disconnector.userIDs) != 1 || disconnector.userIDs[0] != "u1" {
+			t.Errorf("Expected u1 disconnected, got %v", disconnector.userIDs)
+		}
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		service, bans, _ := newService(nil, nil)
+		if _, err := service.Ban(ctx, &BanInput{UserID: "u1", BannedBy: "admin"}); err != apperrors.ErrUserNotFound {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+		if bans.Calls("Create") != 0 {
+			t.Error("Expected no ban to be stored")
+		}
+	})
+
+	t.Run("Administrators cannot be banned", func(t *testing.T) {
+		service, _, _ := newService(&model.User{ID: "u1", IsAdmin: true}, nil)
+		if _, err := service.Ban(ctx, &BanInput{UserID: "u1", BannedBy: "admin"}); err != apperrors.ErrPermissionDenied {
+			t.Errorf("Expected ErrPermissionDenied, got %v", err)
+		}
+	})
+
+	t.Run("Store failure", func(t *testing.T) {
+		service, _, disconnector := newService(&model.User{ID: "u1"}, storeErr)
+		if _, err := service.Ban(ctx, &BanInput{UserID: "u1", BannedBy: "admin"}); err != apperrors.ErrInternal {
+			t.Errorf("Expected ErrInternal, got %v", err)
+		}
+		if len(disconnector.userIDs) != 0 {
+			t.Error("Expected no disconnect when the ban was not stored")
+		}
+	})
+}
+
+func TestBanService_CheckAccount_Unit(t *testing.T) {
+	ctx := context.Background()
+	bans := &mockBanStore{}
+	service := NewBanService(bans, &mockUserLookup{}, zap.NewNop())
+
+	if err := service.CheckAccount(ctx, "u1"); err != nil {
+		t.Errorf("Expected no error without a ban, got %v", err)
+	}
+
+	bans.GetActiveByUserIDFunc = func(ctx context.Context, userID string) (*model.UserBan, error) {
+		return &model.UserBan{UserID: userID, Reason: "spam"}, nil
+	}
+	if err := service.CheckAccount(ctx, "u1"); !apperrors.Is(err, apperrors.ErrUserBanned) {
+		t.Errorf("Expected ErrUserBanned, got %v", err)
+	}
+
+	bans.GetActiveByUserIDFunc = func(ctx context.Context, userID string) (*model.UserBan, error) {
+		return nil, errors.New("connection reset")
+	}
+	if err := service.CheckAccount(ctx, "u1"); err != apperrors.ErrInternal {
+		t.Errorf("Expected ErrInternal, got %v", err)
+	}
+}
@@ -0,0 +1,424 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/archive"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// exportPageSize is the number of rows fetched per query while exporting
+const exportPageSize = 500
+
+// Archive record kinds written by compliance exports
+const (
+	ExportKindMessage       = "message"
+	ExportKindDirectMessage = "direct_message"
+)
+
+type ComplianceService struct {
+	holdRepo    *repository.LegalHoldRepository
+	roomRepo    *repository.RoomRepository
+	userRepo    *repository.UserRepository
+	messageRepo *repository.MessageRepository
+	dmRepo      *repository.DirectMessageRepository
+	auditor     *AuditService
+	logger      *zap.Logger
+}
+
+func NewComplianceService(
+	holdRepo *repository.LegalHoldRepository,
+	roomRepo *repository.RoomRepository,
+	userRepo *repository.UserRepository,
+	messageRepo *repository.MessageRepository,
+	dmRepo *repository.DirectMessageRepository,
+	logger *zap.Logger,
+) *ComplianceService {
+	return &ComplianceService{
+		holdRepo:    holdRepo,
+		roomRepo:    roomRepo,
+		userRepo:    userRepo,
+		messageRepo: messageRepo,
+		dmRepo:      dmRepo,
+		logger:      logger,
+	}
+}
+
+// SetAuditor sets the audit service that records holds and exports
+func (s *ComplianceService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// PlaceHoldInput represents legal hold input
+type PlaceHoldInput struct {
+	TargetType model.LegalHoldTarget
+	TargetID   string
+	Reason     string
+	PlacedBy   string
+}
+
+// PlaceHold places a room or user under legal hold
+func (s *ComplianceService) PlaceHold(ctx context.Context, input *PlaceHoldInput) (*model.LegalHold, error) {
+	if !input.TargetType.IsValid() {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"target_type": "必須為 room 或 user",
+		})
+	}
+	if _, err := s.targetName(ctx, input.TargetType, input.TargetID); err != nil {
+		return nil, err
+	}
+
+	hold := &model.LegalHold{
+		TargetType: input.TargetType,
+		TargetID:   input.TargetID,
+		Reason:     input.Reason,
+		PlacedBy:   sql.NullString{String: input.PlacedBy, Valid: input.PlacedBy != ""},
+	}
+	if err := s.holdRepo.Create(ctx, hold); err != nil {
+		if err == repository.ErrLegalHoldExists {
+			return nil, apperrors.ErrLegalHoldExists
+		}
+		s.logger.Error("Failed to create legal hold", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Legal hold placed",
+		zap.String("hold_id", hold.ID),
+		zap.String("target_type", string(hold.TargetType)),
+		zap.String("target_id", hold.TargetID),
+		zap.String("placed_by", input.PlacedBy),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    input.PlacedBy,
+		Action:     model.AuditActionLegalHoldPlaced,
+		TargetType: string(hold.TargetType),
+		TargetID:   hold.TargetID,
+		Metadata: map[string]interface{}{
+			"hold_id": hold.ID,
+			"reason":  hold.Reason,
+		},
+	})
+
+	return hold, nil
+}
+
+// ReleaseHold lifts an active legal hold
+func (s *ComplianceService) ReleaseHold(ctx context.Context, holdID, releasedBy string) error {
+	hold, err := s.holdRepo.Release(ctx, holdID, releasedBy)
+	if err != nil {
+		if err == repository.ErrLegalHoldNotFound {
+			return apperrors.ErrLegalHoldNotFound
+		}
+		s.logger.Error("Failed to release legal hold", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("Legal hold released",
+		zap.String("hold_id", hold.ID),
+		zap.String("released_by", releasedBy),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    releasedBy,
+		Action:     model.AuditActionLegalHoldReleased,
+		TargetType: string(hold.TargetType),
+		TargetID:   hold.TargetID,
+		Metadata:   map[string]interface{}{"hold_id": hold.ID},
+	})
+
+	return nil
+}
+
+// ListActiveHolds lists legal holds in effect
+func (s *ComplianceService) ListActiveHolds(ctx context.Context, limit, offset int) ([]*model.LegalHold, error) {
+	holds, err := s.holdRepo.ListActive(ctx, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list legal holds", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return holds, nil
+}
+
+// targetName returns the display name of a hold or export target, failing
+// with a not found error if it does not exist
+func (s *ComplianceService) targetName(ctx context.Context, target model.LegalHoldTarget, id string) (string, error) {
+	switch target {
+	case model.LegalHoldTargetRoom:
+		// Soft deleted rooms keep their messages and may still be exported
+		room, err := s.roomRepo.GetByIDIncludingDeleted(ctx, id)
+		if err != nil {
+			if err == repository.ErrRoomNotFound {
+				return "", apperrors.ErrRoomNotFound
+			}
+			return "", apperrors.ErrInternal
+		}
+		return room.Name, nil
+	default:
+		user, err := s.userRepo.GetByID(ctx, id)
+		if err != nil {
+			if err == repository.ErrUserNotFound {
+				return "", apperrors.ErrUserNotFound
+			}
+			return "", apperrors.ErrInternal
+		}
+		return user.Username, nil
+	}
+}
+
+// ExportHeader is the first record of a compliance archive
+type ExportHeader struct {
+	Format      string               `json:"format"`
+	TargetType  string               `json:"target_type"`
+	TargetID    string               `json:"target_id"`
+	TargetName  string               `json:"target_name"`
+	ExportedBy  string               `json:"exported_by"`
+	GeneratedAt string               `json:"generated_at"`
+	LegalHold   *ExportLegalHoldInfo `json:"legal_hold,omitempty"`
+}
+
+// ExportLegalHoldInfo describes the hold in effect when an archive was made
+type ExportLegalHoldInfo struct {
+	ID       string `json:"id"`
+	Reason   string `json:"reason"`
+	PlacedAt string `json:"placed_at"`
+}
+
+// ExportedMessage is a room message record in a compliance archive
+type ExportedMessage struct {
+	ID        string `json:"id"`
+	RoomID    string `json:"room_id"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Content   string `json:"content"`
+	Type      string `json:"type"`
+	ReplyToID string `json:"reply_to_id,omitempty"`
+	IsEdited  bool   `json:"is_edited"`
+	IsDeleted bool   `json:"is_deleted"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ExportedDirectMessage is a direct message record in a compliance archive
+type ExportedDirectMessage struct {
+	ID                  string `json:"id"`
+	SenderID            string `json:"sender_id"`
+	ReceiverID          string `json:"receiver_id"`
+	Content             string `json:"content"`
+	Type                string `json:"type"`
+	IsRead              bool   `json:"is_read"`
+	IsDeletedBySender   bool   `json:"is_deleted_by_sender"`
+	IsDeletedByReceiver bool   `json:"is_deleted_by_receiver"`
+	CreatedAt           string `json:"created_at"`
+	UpdatedAt           string `json:"updated_at"`
+}
+
+func newExportedMessage(m *model.MessageWithUser) *ExportedMessage {
+	return &ExportedMessage{
+		ID:        m.ID,
+		RoomID:    m.RoomID,
+		UserID:    m.UserID,
+		Username:  m.Username,
+		Content:   m.Content,
+		Type:      string(m.Type),
+		ReplyToID: m.GetReplyToID(),
+		IsEdited:  m.IsEdited,
+		IsDeleted: m.IsDeleted,
+		CreatedAt: m.CreatedAt.UTC().Format(time.RFC3339Nano),
+		UpdatedAt: m.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func newExportedDirectMessage(dm *model.DirectMessage) *ExportedDirectMessage {
+	return &ExportedDirectMessage{
+		ID:                  dm.ID,
+		SenderID:            dm.SenderID,
+		ReceiverID:          dm.ReceiverID,
+		Content:             dm.Content,
+		Type:                string(dm.Type),
+		IsRead:              dm.IsRead,
+		IsDeletedBySender:   dm.IsDeletedBySender,
+		IsDeletedByReceiver: dm.IsDeletedByReceiver,
+		CreatedAt:           dm.CreatedAt.UTC().Format(time.RFC3339Nano),
+		UpdatedAt:           dm.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// Export is a prepared compliance export whose target has been validated
+type Export struct {
+	Filename string
+
+	service *ComplianceService
+	header  *ExportHeader
+	write   func(ctx context.Context, w *archive.Writer) error
+}
+
+// RoomExport prepares an archive of every message in a room
+func (s *ComplianceService) RoomExport(ctx context.Context, roomID, exportedBy string) (*Export, error) {
+	header, err := s.exportHeader(ctx, model.LegalHoldTargetRoom, roomID, exportedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{
+		Filename: fmt.Sprintf("room-%s-%s.jsonl", roomID, time.Now().UTC().Format("20060102T150405Z")),
+		service:  s,
+		header:   header,
+		write: func(ctx context.Context, w *archive.Writer) error {
+			return s.exportMessages(ctx, w, roomID, "")
+		},
+	}, nil
+}
+
+// UserExport prepares an archive of a user's room messages and direct messages
+func (s *ComplianceService) UserExport(ctx context.Context, userID, exportedBy string) (*Export, error) {
+	header, err := s.exportHeader(ctx, model.LegalHoldTargetUser, userID, exportedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{
+		Filename: fmt.Sprintf("user-%s-%s.jsonl", userID, time.Now().UTC().Format("20060102T150405Z")),
+		service:  s,
+		header:   header,
+		write: func(ctx context.Context, w *archive.Writer) error {
+			if err := s.exportMessages(ctx, w, "", userID); err != nil {
+				return err
+			}
+			return s.exportDirectMessages(ctx, w, userID)
+		},
+	}, nil
+}
+
+func (s *ComplianceService) exportHeader(ctx context.Context, target model.LegalHoldTarget, id, exportedBy string) (*ExportHeader, error) {
+	name, err := s.targetName(ctx, target, id)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &ExportHeader{
+		Format:      archive.Format,
+		TargetType:  string(target),
+		TargetID:    id,
+		TargetName:  name,
+		ExportedBy:  exportedBy,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	hold, err := s.holdRepo.GetActive(ctx, target, id)
+	switch {
+	case err == nil:
+		header.LegalHold = &ExportLegalHoldInfo{
+			ID:       hold.ID,
+			Reason:   hold.Reason,
+			PlacedAt: hold.CreatedAt.UTC().Format(time.RFC3339),
+		}
+	case err != repository.ErrLegalHoldNotFound:
+		s.logger.Error("Failed to get legal hold", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return header, nil
+}
+
+// WriteTo streams the archive to w and records the export in the audit log.
+// The returned trailer's head hash identifies the archive.
+func (e *Export) WriteTo(ctx context.Context, w io.Writer) (*archive.Trailer, error) {
+	s := e.service
+
+	aw, err := archive.NewWriter(w, e.header)
+	if err != nil {
+		s.logger.Error("Failed to start compliance export", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if err := e.write(ctx, aw); err != nil {
+		s.logger.Error("Compliance export aborted",
+			zap.String("target_type", e.header.TargetType),
+			zap.String("target_id", e.header.TargetID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	trailer, err := aw.Close()
+	if err != nil {
+		s.logger.Error("Failed to finish compliance export", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Compliance export completed",
+		zap.String("target_type", e.header.TargetType),
+		zap.String("target_id", e.header.TargetID),
+		zap.Int64("records", trailer.Records),
+		zap.String("head_hash", trailer.HeadHash),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    e.header.ExportedBy,
+		Action:     model.AuditActionComplianceExported,
+		TargetType: e.header.TargetType,
+		TargetID:   e.header.TargetID,
+		Metadata: map[string]interface{}{
+			"format":    trailer.Format,
+			"records":   trailer.Records,
+			"head_hash": trailer.HeadHash,
+		},
+	})
+
+	return trailer, nil
+}
+
+func (s *ComplianceService) exportMessages(ctx context.Context, w *archive.Writer, roomID, userID string) error {
+	cursor := repository.NewExportCursor()
+	for {
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+
+		messages, err := s.messageRepo.ListForExport(ctx, roomID, userID, cursor, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			if err := w.Append(ExportKindMessage, newExportedMessage(m)); err != nil {
+				return err
+			}
+			cursor.Advance(m.CreatedAt, m.ID)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if len(messages) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+func (s *ComplianceService) exportDirectMessages(ctx context.Context, w *archive.Writer, userID string) error {
+	cursor := repository.NewExportCursor()
+	for {
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+
+		messages, err := s.dmRepo.ListForExport(ctx, userID, cursor, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, dm := range messages {
+			if err := w.Append(ExportKindDirectMessage, newExportedDirectMessage(dm)); err != nil {
+				return err
+			}
+			cursor.Advance(dm.CreatedAt, dm.ID)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if len(messages) < exportPageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/archive"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+func setupTestComplianceServiceIsolated(t *testing.T) (*ComplianceService, *RoomService, *sqlx.DB, string) {
+	t.Helper()
+
+	db, prefix := repository.SetupIsolatedTestDB(t)
+
+	roomRepo := repository.NewRoomRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	messageRepo := repository.NewMessageRepository(db)
+
+	compliance := NewComplianceService(
+		repository.NewLegalHoldRepository(db),
+		roomRepo,
+		userRepo,
+		messageRepo,
+		repository.NewDirectMessageRepository(db),
+		zap.NewNop(),
+	)
+	rooms := NewRoomService(roomRepo, userRepo, messageRepo, zap.NewNop())
+	return compliance, rooms, db, prefix
+}
+
+func TestComplianceService_HoldSuspendsRoomPurge(t *testing.T) {
+	compliance, rooms, db, prefix := setupTestComplianceServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	owner := repository.CreateIsolatedTestUser(t, db, prefix, "owner")
+	room := repository.CreateIsolatedTestRoom(t, db, prefix, owner)
+
+	hold, err := compliance.PlaceHold(ctx, &PlaceHoldInput{
+		TargetType: model.LegalHoldTargetRoom,
+		TargetID:   room.ID,
+		Reason:     "litigation",
+		PlacedBy:   owner.ID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+
+	_, err = compliance.PlaceHold(ctx, &PlaceHoldInput{
+		TargetType: model.LegalHoldTargetRoom,
+		TargetID:   room.ID,
+		Reason:     "again",
+		PlacedBy:   owner.ID,
+	})
+	if !apperrors.Is(err, apperrors.ErrLegalHoldExists) {
+		t.Errorf("Expected ErrLegalHoldExists, got %v", err)
+	}
+
+	rooms.SetDeletionDelay(time.Millisecond)
+	if _, err := rooms.Delete(ctx, room.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to schedule deletion: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := rooms.PurgeScheduledDeletions(ctx, 100); err != nil {
+		t.Fatalf("Failed to purge deletions: %v", err)
+	}
+	if _, err := rooms.GetByID(ctx, room.ID); err != nil {
+		t.Fatalf("Expected held room to survive purge, got %v", err)
+	}
+
+	if err := compliance.ReleaseHold(ctx, hold.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to release hold: %v", err)
+	}
+	if _, err := rooms.PurgeScheduledDeletions(ctx, 100); err != nil {
+		t.Fatalf("Failed to purge deletions: %v", err)
+	}
+	if _, err := rooms.GetByID(ctx, room.ID); err == nil {
+		t.Error("Expected room to be purged once the hold is released")
+	}
+}
+
+func TestComplianceService_RoomExportIsVerifiable(t *testing.T) {
+	compliance, _, db, prefix := setupTestComplianceServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	owner := repository.CreateIsolatedTestUser(t, db, prefix, "owner")
+	room := repository.CreateIsolatedTestRoom(t, db, prefix, owner)
+
+	messageRepo := repository.NewMessageRepository(db)
+	for i := 0; i < 3; i++ {
+		msg := &model.Message{RoomID: room.ID, UserID: owner.ID, Content: prefix + "_message", Type: model.MessageTypeText}
+		if err := messageRepo.Create(ctx, msg); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	export, err := compliance.RoomExport(ctx, room.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to prepare export: %v", err)
+	}
+
+	var buf bytes.Buffer
+	written, err := export.WriteTo(ctx, &buf)
+	if err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+	if written.Records != 3 {
+		t.Errorf("Expected 3 records, got %d", written.Records)
+	}
+
+	verified, err := archive.Verify(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Export failed verification: %v", err)
+	}
+	if verified.HeadHash != written.HeadHash {
+		t.Errorf("Head hash mismatch: %s != %s", verified.HeadHash, written.HeadHash)
+	}
+}
+
+func TestComplianceService_ExportUnknownRoom(t *testing.T) {
+	compliance, _, db, _ := setupTestComplianceServiceIsolated(t)
+	defer db.Close()
+
+	_, err := compliance.RoomExport(context.Background(), "00000000-0000-0000-0000-000000000001", "")
+	if !apperrors.Is(err, apperrors.ErrRoomNotFound) {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+}
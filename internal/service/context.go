@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+)
+
+// checkContext returns an application error if the request context is done,
+// so expensive work is skipped once the client has gone away
+func checkContext(ctx context.Context) error {
+	if e := apperrors.FromContext(ctx.Err()); e != nil {
+		return e
+	}
+	return nil
+}
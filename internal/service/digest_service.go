@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-demo/chat/internal/mail"
+	"github.com/go-demo/chat/internal/mail/templates"
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxDigestRooms caps the rooms listed in one digest; the unread total
+	// still counts all of them
+	maxDigestRooms = 10
+
+	// digestSlack lets a job run that comes slightly early still send a
+	// digest instead of pushing it back a whole job interval
+	digestSlack = 5 * time.Minute
+)
+
+// DigestService emails opted-in users a daily or weekly summary of what
+// they have not read: rooms with unread messages, direct messages and
+// friend requests
+type DigestService struct {
+	store    DigestStore
+	renderer *templates.Renderer
+	sender   mail.Sender
+	logger   *zap.Logger
+}
+
+func NewDigestService(store DigestStore, logger *zap.Logger) *DigestService {
+	return &DigestService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// SetMailer sets the templates and sender used for digest emails
+func (s *DigestService) SetMailer(renderer *templates.Renderer, sender mail.Sender) {
+	s.renderer = renderer
+	s.sender = sender
+}
+
+// SendDue sends the digests of up to limit users that are due and returns
+// how many were sent. A digest covers activity since the previous one, or
+// its period for a user's first. Users with nothing new get no email but
+// start their next period, and a failed send is retried on the next run.
+func (s *DigestService) SendDue(ctx context.Context, limit int) (int, error) {
+	if s.renderer == nil || s.sender == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	users, err := s.store.ListDue(ctx, now.Add(digestSlack), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, user := range users {
+		if err := checkContext(ctx); err != nil {
+			return sent, err
+		}
+
+		since := now.Add(-user.DigestFrequency.Period())
+		if user.DigestSentAt.Valid {
+			since = user.DigestSentAt.Time
+		}
+		activity, err := s.store.GetActivity(ctx, user.ID, since)
+		if err != nil {
+			return sent, err
+		}
+
+		if !activity.IsEmpty() {
+			if err := s.send(ctx, user, activity); err != nil {
+				s.logger.Warn("Failed to send digest", zap.String("user_id", user.ID), zap.Error(err))
+				continue
+			}
+			sent++
+		}
+
+		if err := s.store.MarkSent(ctx, user.ID, now); err != nil {
+			return sent, err
+		}
+	}
+
+	if sent > 0 {
+		s.logger.Info("Digests sent", zap.Int("count", sent))
+	}
+	return sent, nil
+}
+
+func (s *DigestService) send(ctx context.Context, user *model.User, activity *model.DigestActivity) error {
+	msg, err := s.renderer.Render(templates.Digest, s.renderer.DefaultLocale(), &templates.Data{
+		User: templates.User{
+			Username:    user.Username,
+			DisplayName: user.GetDisplayName(),
+			Email:       user.Email,
+		},
+		Vars: digestVars(activity),
+	})
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, user.Email, msg)
+}
+
+// digestVars lays activity out in the variables the digest template uses
+func digestVars(activity *model.DigestActivity) map[string]interface{} {
+	unread := 0
+	rooms := make([]map[string]interface{}, 0, len(activity.Rooms))
+	for _, r := range activity.Rooms {
+		unread += r.Unread
+		if len(rooms) < maxDigestRooms {
+			rooms = append(rooms, map[string]interface{}{"Name": r.Name, "Unread": r.Unread})
+		}
+	}
+
+	dms := make([]map[string]interface{}, len(activity.DMs))
+	for i, d := range activity.DMs {
+		unread += d.Unread
+		dms[i] = map[string]interface{}{"Name": d.DisplayName, "Unread": d.Unread}
+	}
+
+	requests := make([]map[string]interface{}, len(activity.FriendRequests))
+	for i, f := range activity.FriendRequests {
+		requests[i] = map[string]interface{}{"Name": f.DisplayName}
+	}
+
+	return map[string]interface{}{
+		"UnreadCount":    unread,
+		"Rooms":          rooms,
+		"MoreRooms":      len(activity.Rooms) - len(rooms),
+		"DMs":            dms,
+		"FriendRequests": requests,
+	}
+}
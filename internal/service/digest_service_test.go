@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/mail/templates"
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+func newTestDigestService(t *testing.T, store *mockDigestStore) (*DigestService, *recordingSender) {
+	t.Helper()
+	renderer, err := templates.New(templates.Site{Name: "Go Chat", URL: "https://chat.example.com"}, "en", "")
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	sender := &recordingSender{}
+	service := NewDigestService(store, zap.NewNop())
+	service.SetMailer(renderer, sender)
+	return service, sender
+}
+
+func TestDigestService_SendDue(t *testing.T) {
+	ctx := context.Background()
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+
+	alice := &model.User{ID: "alice-id", Username: "alice", Email: "alice@example.com", DigestFrequency: model.DigestDaily}
+	bob := &model.User{
+		ID: "bob-id", Username: "bob", Email: "bob@example.com", DigestFrequency: model.DigestWeekly,
+		DigestSentAt: sql.NullTime{Time: lastWeek, Valid: true},
+	}
+
+	since := make(map[string]time.Time)
+	marked := make(map[string]bool)
+	store := &mockDigestStore{
+		ListDueFunc: func(ctx context.Context, asOf time.Time, limit int) ([]*model.User, error) {
+			return []*model.User{alice, bob}, nil
+		},
+		GetActivityFunc: func(ctx context.Context, userID string, s time.Time) (*model.DigestActivity, error) {
+			since[userID] = s
+			if userID == bob.ID {
+				return &model.DigestActivity{}, nil
+			}
+			return &model.DigestActivity{
+				Rooms:          []*model.DigestRoom{{RoomID: "r1", Name: "general", Unread: 5}},
+				DMs:            []*model.DigestSender{{UserID: "carol-id", Username: "carol", DisplayName: "Carol", Unread: 2}},
+				FriendRequests: []*model.DigestSender{{UserID: "dave-id", Username: "dave", DisplayName: "dave"}},
+			}, nil
+		},
+		MarkSentFunc: func(ctx context.Context, userID string, at time.Time) error {
+			marked[userID] = true
+			return nil
+		},
+	}
+	service, sender := newTestDigestService(t, store)
+
+	sent, err := service.SendDue(ctx, 10)
+	if err != nil {
+		t.Fatalf("SendDue failed: %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("Expected 1 digest sent, got %d", sent)
+	}
+
+	msg := sender.sent[alice.Email]
+	if msg == nil {
+		t.Fatal("Expected a digest for alice")
+	}
+	if !strings.Contains(msg.Subject, "7 unread") {
+		t.Errorf("Expected the subject to count room and DM messages, got %q", msg.Subject)
+	}
+	for _, want := range []string{"general", "Carol", "dave"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("Expected digest text to mention %s, got %q", want, msg.Text)
+		}
+	}
+	if _, ok := sender.sent[bob.Email]; ok {
+		t.Error("Expected no digest for a user without new activity")
+	}
+
+	if d := time.Since(since[alice.ID]); d < 24*time.Hour-time.Minute || d > 24*time.Hour+time.Minute {
+		t.Errorf("Expected a first daily digest to cover the last day, got %v", d)
+	}
+	if !since[bob.ID].Equal(lastWeek) {
+		t.Errorf("Expected the digest to start at the previous one, got %v", since[bob.ID])
+	}
+	if !marked[alice.ID] || !marked[bob.ID] {
+		t.Errorf("Expected both users marked, got %v", marked)
+	}
+}
+
+func TestDigestService_SendDueWithoutMailer(t *testing.T) {
+	store := &mockDigestStore{}
+	service := NewDigestService(store, zap.NewNop())
+
+	sent, err := service.SendDue(context.Background(), 10)
+	if err != nil || sent != 0 {
+		t.Errorf("Expected nothing sent, got %d, %v", sent, err)
+	}
+	if store.Calls("ListDue") != 0 {
+		t.Error("Expected no users listed without a mailer")
+	}
+}
+
+func TestDigestVars_CapsRooms(t *testing.T) {
+	activity := &model.DigestActivity{}
+	for i := 0; i < maxDigestRooms+3; i++ {
+		activity.Rooms = append(activity.Rooms, &model.DigestRoom{Name: "room", Unread: 2})
+	}
+
+	vars := digestVars(activity)
+	if rooms := vars["Rooms"].([]map[string]interface{}); len(rooms) != maxDigestRooms {
+		t.Errorf("Expected %d rooms listed, got %d", maxDigestRooms, len(rooms))
+	}
+	if vars["MoreRooms"] != 3 {
+		t.Errorf("Expected 3 more rooms, got %v", vars["MoreRooms"])
+	}
+	if vars["UnreadCount"] != 2*(maxDigestRooms+3) {
+		t.Errorf("Expected the total to count every room, got %v", vars["UnreadCount"])
+	}
+}
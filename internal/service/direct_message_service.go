@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/go-demo/chat/internal/model"
 	apperrors "github.com/go-demo/chat/internal/pkg/errors"
@@ -10,16 +12,31 @@ import (
 )
 
 type DirectMessageService struct {
-	dmRepo      *repository.DirectMessageRepository
-	userRepo    *repository.UserRepository
-	blockedRepo *repository.BlockedUserRepository
+	dmRepo      DirectMessageStore
+	userRepo    UserBatchLookup
+	blockedRepo BlockLookup
+	notifier    *NotificationService
 	logger      *zap.Logger
+
+	// Conversation exports, see SetExports
+	exportRepo      *repository.DMExportRepository
+	exportDir       string
+	exportRetention time.Duration
+
+	// End-to-end encryption, see SetKeyRepository
+	keyRepo *repository.DeviceKeyRepository
+
+	// Uploads attached to messages; nil refuses attachments
+	attachments *AttachmentService
+
+	// How long senders may edit their messages, see SetEditWindow
+	editWindow time.Duration
 }
 
 func NewDirectMessageService(
-	dmRepo *repository.DirectMessageRepository,
-	userRepo *repository.UserRepository,
-	blockedRepo *repository.BlockedUserRepository,
+	dmRepo DirectMessageStore,
+	userRepo UserBatchLookup,
+	blockedRepo BlockLookup,
 	logger *zap.Logger,
 ) *DirectMessageService {
 	return &DirectMessageService{
@@ -30,12 +47,28 @@ func NewDirectMessageService(
 	}
 }
 
+// SetNotifier sets the notification service used to push DM events
+func (s *DirectMessageService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
 // SendMessageInput represents DM sending input
 type SendDMInput struct {
 	SenderID   string
 	ReceiverID string
 	Content    string
 	Type       model.MessageType
+
+	// AttachmentIDs are the sender's uploads to attach to the message
+	AttachmentIDs []string
+
+	// Set when the message is a forward; stored as its provenance
+	ForwardedFrom *model.ForwardedFrom
+
+	// Ciphertext messages: the sending device and one envelope per device
+	// of the receiver and of the sender's other devices
+	SenderDeviceID string
+	Envelopes      []*model.DMEnvelope
 }
 
 // SendMessage sends a direct message
@@ -66,6 +99,24 @@ func (s *DirectMessageService) SendMessage(ctx context.Context, input *SendDMInp
 	if input.Type == "" {
 		input.Type = model.MessageTypeText
 	}
+	// Stickers are a room feature, forwarding one into a DM is refused
+	if input.Type == model.MessageTypeSticker {
+		return nil, apperrors.New(400, "私訊不支援貼圖")
+	}
+	// The server cannot tie encrypted content to files it stores
+	if input.Type == model.MessageTypeCiphertext && len(input.AttachmentIDs) > 0 {
+		return nil, apperrors.New(400, "加密訊息不支援附件")
+	}
+
+	uploads, err := s.attachments.Resolve(ctx, &AttachInput{
+		UserID:    input.SenderID,
+		UploadIDs: input.AttachmentIDs,
+		FileURL:   attachableURL(input.Type, input.Content),
+		Forwarded: input.ForwardedFrom != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	msg := &model.DirectMessage{
 		SenderID:   input.SenderID,
@@ -74,7 +125,19 @@ func (s *DirectMessageService) SendMessage(ctx context.Context, input *SendDMInp
 		Type:       input.Type,
 	}
 
-	if err := s.dmRepo.Create(ctx, msg); err != nil {
+	if input.ForwardedFrom != nil {
+		from, err := json.Marshal(input.ForwardedFrom)
+		if err != nil {
+			return nil, apperrors.ErrInternal
+		}
+		msg.ForwardedFrom = from
+	}
+
+	if msg.Type == model.MessageTypeCiphertext {
+		if err := s.sendEncrypted(ctx, msg, input); err != nil {
+			return nil, err
+		}
+	} else if err := s.dmRepo.Create(ctx, msg); err != nil {
 		s.logger.Error("Failed to create direct message", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
@@ -85,10 +148,36 @@ func (s *DirectMessageService) SendMessage(ctx context.Context, input *SendDMInp
 		s.logger.Error("Failed to get direct message with user", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
+	msgWithUser.Attachments = s.attachments.AttachToDirectMessage(ctx, msgWithUser.ID, uploads)
+
+	s.notifyReceiver(msgWithUser)
 
 	return msgWithUser, nil
 }
 
+// notifyReceiver queues a notification for a new direct message unless the
+// receiver has muted the sender
+func (s *DirectMessageService) notifyReceiver(msg *model.DirectMessageWithUser) {
+	if s.notifier == nil {
+		return
+	}
+
+	actor := msg.GetSenderDisplayName()
+	input := &NotifyInput{
+		Type:          model.NotificationTypeDirectMessage,
+		Title:         actor + " 傳送了私訊給你",
+		ReferenceID:   msg.SenderID,
+		ReferenceType: "user",
+		SourceType:    model.NotificationTargetDM,
+		SourceID:      msg.SenderID,
+	}
+	if msg.Type == model.MessageTypeText {
+		input.Content = msg.Content
+	}
+	// Keyed by sender so a burst of messages collapses into one notification
+	s.notifier.NotifyBatched(msg.ReceiverID, actor, input)
+}
+
 // GetConversation retrieves messages between two users
 func (s *DirectMessageService) GetConversation(ctx context.Context, userID, otherUserID string, limit, offset int) ([]*model.DirectMessageWithUser, error) {
 	// Check if other user exists
@@ -104,13 +193,15 @@ func (s *DirectMessageService) GetConversation(ctx context.Context, userID, othe
 		s.logger.Error("Failed to list conversation", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
+	s.fillAttachments(ctx, messages...)
 
 	return messages, nil
 }
 
-// ListConversations lists all conversations for a user
-func (s *DirectMessageService) ListConversations(ctx context.Context, userID string, limit, offset int) ([]*model.Conversation, error) {
-	conversations, err := s.dmRepo.ListConversations(ctx, userID, limit, offset)
+// ListConversations lists the conversations of a user, either the archived
+// ones or the others
+func (s *DirectMessageService) ListConversations(ctx context.Context, userID string, archived bool, limit, offset int) ([]*model.Conversation, error) {
+	conversations, err := s.dmRepo.ListConversations(ctx, userID, archived, limit, offset)
 	if err != nil {
 		s.logger.Error("Failed to list conversations", zap.Error(err))
 		return nil, apperrors.ErrInternal
@@ -118,12 +209,35 @@ func (s *DirectMessageService) ListConversations(ctx context.Context, userID str
 	return conversations, nil
 }
 
+// SetConversationState archives, hides or pins the conversation with
+// another user in the user's own list, or undoes it
+func (s *DirectMessageService) SetConversationState(ctx context.Context, userID, otherUserID string, state model.ConversationState, on bool) (*model.ConversationSettings, error) {
+	if userID == otherUserID {
+		return nil, apperrors.ErrCannotMessageSelf
+	}
+	if _, err := s.userRepo.GetByID(ctx, otherUserID); err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	settings, err := s.dmRepo.SetConversationState(ctx, userID, otherUserID, state, on)
+	if err != nil {
+		s.logger.Error("Failed to set conversation state", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return settings, nil
+}
+
 // MarkAsRead marks messages as read
 func (s *DirectMessageService) MarkAsRead(ctx context.Context, userID, senderID string) error {
 	if err := s.dmRepo.MarkAsRead(ctx, senderID, userID); err != nil {
 		s.logger.Error("Failed to mark as read", zap.Error(err))
 		return apperrors.ErrInternal
 	}
+
+	publishReadState(s.notifier, userID, model.ReadStateDM, senderID, time.Now())
 	return nil
 }
 
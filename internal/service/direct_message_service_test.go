@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 	"github.com/go-demo/chat/internal/repository"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -184,7 +185,7 @@ func TestDirectMessageService_ListConversations(t *testing.T) {
 	_, _ = service.SendMessage(ctx, &SendDMInput{SenderID: contact1.ID, ReceiverID: user.ID, Content: "Hi from contact1", Type: model.MessageTypeText})
 	_, _ = service.SendMessage(ctx, &SendDMInput{SenderID: contact2.ID, ReceiverID: user.ID, Content: "Hi from contact2", Type: model.MessageTypeText})
 
-	conversations, err := service.ListConversations(ctx, user.ID, 10, 0)
+	conversations, err := service.ListConversations(ctx, user.ID, false, 10, 0)
 	if err != nil {
 		t.Fatalf("Failed to list conversations: %v", err)
 	}
@@ -409,3 +410,74 @@ func TestDirectMessageService_MessageTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestDirectMessageService_SendMessage_Mocked(t *testing.T) {
+	ctx := context.Background()
+	dms := &mockDirectMessageStore{}
+	dms.CreateFunc = func(ctx context.Context, msg *model.DirectMessage) error {
+		msg.ID = "dm-1"
+		return nil
+	}
+	dms.GetByIDWithUserFunc = func(ctx context.Context, id string) (*model.DirectMessageWithUser, error) {
+		return &model.DirectMessageWithUser{DirectMessage: model.DirectMessage{ID: id, SenderID: "user-1", ReceiverID: "user-2"}}, nil
+	}
+	users := &mockUserBatchLookup{}
+	users.GetByIDFunc = func(ctx context.Context, id string) (*model.User, error) {
+		if id == "ghost" {
+			return nil, repository.ErrUserNotFound
+		}
+		return &model.User{ID: id}, nil
+	}
+	blocks := &mockBlockLookup{}
+	blocks.IsBlockedEitherFunc = func(ctx context.Context, userID1, userID2 string) (bool, error) {
+		return userID2 == "blocker", nil
+	}
+	service := NewDirectMessageService(dms, users, blocks, zap.NewNop())
+
+	for _, tt := range []struct {
+		receiver string
+		msgType  model.MessageType
+		err      error
+	}{
+		{"user-1", model.MessageTypeText, apperrors.ErrCannotMessageSelf},
+		{"ghost", model.MessageTypeText, apperrors.ErrUserNotFound},
+		{"blocker", model.MessageTypeText, apperrors.ErrUserBlocked},
+	} {
+		_, err := service.SendMessage(ctx, &SendDMInput{SenderID: "user-1", ReceiverID: tt.receiver, Content: "hi", Type: tt.msgType})
+		if err != tt.err {
+			t.Errorf("Sending to %s: expected %v, got %v", tt.receiver, tt.err, err)
+		}
+	}
+	if _, err := service.SendMessage(ctx, &SendDMInput{SenderID: "user-1", ReceiverID: "user-2", Content: "x", Type: model.MessageTypeSticker}); err == nil {
+		t.Error("Expected stickers to be refused in direct messages")
+	}
+	if dms.Calls("Create") != 0 {
+		t.Fatal("Expected no direct message to be stored")
+	}
+
+	msg, err := service.SendMessage(ctx, &SendDMInput{SenderID: "user-1", ReceiverID: "user-2", Content: "hi"})
+	if err != nil {
+		t.Fatalf("Failed to send direct message: %v", err)
+	}
+	if msg.ID != "dm-1" || dms.Calls("Create") != 1 {
+		t.Errorf("Expected the direct message to be stored, got %+v", msg)
+	}
+}
+
+func TestDirectMessageService_DeleteMessage_Mocked(t *testing.T) {
+	dms := &mockDirectMessageStore{}
+	dms.DeleteForUserFunc = func(ctx context.Context, messageID, userID string) error {
+		if messageID != "dm-1" {
+			return repository.ErrDirectMessageNotFound
+		}
+		return nil
+	}
+	service := NewDirectMessageService(dms, &mockUserBatchLookup{}, &mockBlockLookup{}, zap.NewNop())
+
+	if err := service.DeleteMessage(context.Background(), "dm-1", "user-1"); err != nil {
+		t.Errorf("Failed to delete direct message: %v", err)
+	}
+	if err := service.DeleteMessage(context.Background(), "dm-2", "user-1"); err != apperrors.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
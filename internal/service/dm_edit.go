@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// DMEventUpdated pushes an edited direct message to both participants
+const DMEventUpdated = "dm_updated"
+
+var (
+	ErrDMEditWindowPassed = apperrors.New(http.StatusForbidden, "訊息已超過可編輯的時間")
+	ErrDMNotEditable      = apperrors.New(http.StatusBadRequest, "僅能編輯文字訊息")
+)
+
+// SetEditWindow sets how long after sending a direct message its sender
+// may still edit it; zero allows edits at any time
+func (s *DirectMessageService) SetEditWindow(window time.Duration) {
+	s.editWindow = window
+}
+
+// EditMessage replaces the content of a text message the user sent to
+// otherUserID and pushes the edited message to both of them
+func (s *DirectMessageService) EditMessage(ctx context.Context, userID, otherUserID, messageID, content string) (*model.DirectMessageWithUser, error) {
+	msg, err := s.dmRepo.GetByIDWithUser(ctx, messageID)
+	if err != nil {
+		if err == repository.ErrDirectMessageNotFound {
+			return nil, apperrors.ErrNotFound
+		}
+		s.logger.Error("Failed to get direct message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	// Messages of other conversations, and ones the sender deleted, are
+	// reported as not found
+	inConversation := (msg.SenderID == userID && msg.ReceiverID == otherUserID) ||
+		(msg.SenderID == otherUserID && msg.ReceiverID == userID)
+	if !inConversation || (msg.SenderID == userID && msg.IsDeletedBySender) {
+		return nil, apperrors.ErrNotFound
+	}
+	if msg.SenderID != userID {
+		return nil, apperrors.ErrPermissionDenied
+	}
+	// Encrypted content cannot be edited by a server that cannot read it,
+	// and images and files are replaced by sending a new message
+	if msg.Type != model.MessageTypeText {
+		return nil, ErrDMNotEditable
+	}
+	if s.editWindow > 0 && time.Since(msg.CreatedAt) > s.editWindow {
+		return nil, ErrDMEditWindowPassed
+	}
+
+	blocked, err := s.blockedRepo.IsBlockedEither(ctx, userID, otherUserID)
+	if err != nil {
+		return nil, apperrors.ErrInternal
+	}
+	if blocked {
+		return nil, apperrors.ErrUserBlocked
+	}
+
+	if err := s.dmRepo.UpdateContent(ctx, messageID, content); err != nil {
+		s.logger.Error("Failed to update direct message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	msg.Content = content
+	msg.IsEdited = true
+	s.fillAttachments(ctx, msg)
+
+	if s.notifier != nil {
+		s.notifier.PublishToUser(msg.ReceiverID, DMEventUpdated, msg)
+		s.notifier.PublishToUser(msg.SenderID, DMEventUpdated, msg)
+	}
+
+	return msg, nil
+}
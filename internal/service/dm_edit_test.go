@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+)
+
+func TestDirectMessageService_EditMessage(t *testing.T) {
+	service, db, prefix := setupTestDMServiceIsolated(t)
+	defer db.Close()
+	defer cleanupDMServiceTestByPrefix(t, db, prefix)
+	service.SetEditWindow(15 * time.Minute)
+
+	ctx := context.Background()
+	alice := createUserForDMServiceTestIsolated(t, db, prefix, "alice")
+	bob := createUserForDMServiceTestIsolated(t, db, prefix, "bob")
+	carol := createUserForDMServiceTestIsolated(t, db, prefix, "carol")
+
+	msg, err := service.SendMessage(ctx, &SendDMInput{
+		SenderID:   alice.ID,
+		ReceiverID: bob.ID,
+		Content:    "helo",
+		Type:       model.MessageTypeText,
+	})
+	if err != nil {
+		t.Fatalf("Failed to send direct message: %v", err)
+	}
+
+	if _, err := service.EditMessage(ctx, bob.ID, alice.ID, msg.ID, "hacked"); err != apperrors.ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied for the receiver, got %v", err)
+	}
+	if _, err := service.EditMessage(ctx, alice.ID, carol.ID, msg.ID, "hello"); err != apperrors.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for another conversation, got %v", err)
+	}
+
+	edited, err := service.EditMessage(ctx, alice.ID, bob.ID, msg.ID, "hello")
+	if err != nil {
+		t.Fatalf("Failed to edit direct message: %v", err)
+	}
+	if edited.Content != "hello" || !edited.IsEdited {
+		t.Errorf("Unexpected edited message: %+v", edited.DirectMessage)
+	}
+
+	messages, err := service.GetConversation(ctx, bob.ID, alice.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to get conversation: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "hello" || !messages[0].IsEdited {
+		t.Errorf("Expected the stored message to be edited, got %+v", messages)
+	}
+
+	if _, err := db.Exec(`UPDATE direct_messages SET created_at = NOW() - INTERVAL '1 hour' WHERE id = $1`, msg.ID); err != nil {
+		t.Fatalf("Failed to backdate message: %v", err)
+	}
+	if _, err := service.EditMessage(ctx, alice.ID, bob.ID, msg.ID, "too late"); err != ErrDMEditWindowPassed {
+		t.Errorf("Expected ErrDMEditWindowPassed, got %v", err)
+	}
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxPrekeysPerUpload caps the one-time prekeys published in one request
+	MaxPrekeysPerUpload = 100
+
+	// Curve25519 public keys are 32 bytes, or 33 with the Signal type byte
+	minPublicKeySize = 32
+	maxPublicKeySize = 33
+	signatureSize    = 64
+
+	// maxCiphertextSize bounds one envelope, decoded
+	maxCiphertextSize = 64 * 1024
+
+	// DMEventEncryptedMessage relays a new encrypted message to the devices
+	// of both participants
+	DMEventEncryptedMessage = "new_encrypted_dm"
+)
+
+var (
+	ErrDeviceNotBound        = apperrors.New(http.StatusBadRequest, "此登入未綁定裝置，請重新登入後再使用加密私訊")
+	ErrInvalidKey            = apperrors.New(http.StatusBadRequest, "無效的金鑰")
+	ErrTooManyPrekeys        = apperrors.New(http.StatusBadRequest, "一次最多上傳 100 把一次性預金鑰")
+	ErrDeviceKeysNotFound    = apperrors.New(http.StatusNotFound, "此裝置尚未註冊金鑰")
+	ErrNoKeyedDevices        = apperrors.New(http.StatusNotFound, "該用戶尚未啟用加密私訊")
+	ErrEncryptionUnavailable = apperrors.New(http.StatusBadRequest, "伺服器未啟用加密私訊")
+	ErrInvalidEnvelope       = apperrors.New(http.StatusBadRequest, "無效的加密訊息")
+)
+
+// KeyService manages the public keys devices publish for end-to-end
+// encrypted direct messages. It follows the Signal flow: each device
+// publishes an identity key, a signed prekey and a stock of one-time
+// prekeys; an initiator fetches a prekey bundle per device of the peer and
+// encrypts one envelope for each. The server only relays public keys and
+// ciphertext.
+type KeyService struct {
+	keyRepo     *repository.DeviceKeyRepository
+	deviceRepo  *repository.DeviceRepository
+	userRepo    *repository.UserRepository
+	blockedRepo *repository.BlockedUserRepository
+	logger      *zap.Logger
+}
+
+func NewKeyService(
+	keyRepo *repository.DeviceKeyRepository,
+	deviceRepo *repository.DeviceRepository,
+	userRepo *repository.UserRepository,
+	blockedRepo *repository.BlockedUserRepository,
+	logger *zap.Logger,
+) *KeyService {
+	return &KeyService{
+		keyRepo:     keyRepo,
+		deviceRepo:  deviceRepo,
+		userRepo:    userRepo,
+		blockedRepo: blockedRepo,
+		logger:      logger,
+	}
+}
+
+// RegisterKeysInput are the keys a device publishes
+type RegisterKeysInput struct {
+	RegistrationID        int
+	IdentityKey           string
+	SignedPrekeyID        int
+	SignedPrekey          string
+	SignedPrekeySignature string
+	OneTimePrekeys        []*model.OneTimePrekey
+}
+
+// RegisterKeys publishes the calling device's keys, replacing what it
+// published before. Registering a new identity key discards the device's
+// remaining one-time prekeys.
+func (s *KeyService) RegisterKeys(ctx context.Context, userID, deviceID string, input *RegisterKeysInput) (*model.DeviceKeys, error) {
+	if err := s.checkDevice(ctx, userID, deviceID); err != nil {
+		return nil, err
+	}
+	if !validKey(input.IdentityKey, minPublicKeySize, maxPublicKeySize) ||
+		!validKey(input.SignedPrekey, minPublicKeySize, maxPublicKeySize) ||
+		!validKey(input.SignedPrekeySignature, signatureSize, signatureSize) {
+		return nil, ErrInvalidKey
+	}
+	if err := validatePrekeys(input.OneTimePrekeys); err != nil {
+		return nil, err
+	}
+
+	keys := &model.DeviceKeys{
+		DeviceID:              deviceID,
+		UserID:                userID,
+		RegistrationID:        input.RegistrationID,
+		IdentityKey:           input.IdentityKey,
+		SignedPrekeyID:        input.SignedPrekeyID,
+		SignedPrekey:          input.SignedPrekey,
+		SignedPrekeySignature: input.SignedPrekeySignature,
+	}
+	if err := s.keyRepo.Upsert(ctx, keys, input.OneTimePrekeys); err != nil {
+		s.logger.Error("Failed to store device keys", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return keys, nil
+}
+
+// AddPrekeys tops up the calling device's one-time prekeys and returns how
+// many it has now
+func (s *KeyService) AddPrekeys(ctx context.Context, userID, deviceID string, prekeys []*model.OneTimePrekey) (int, error) {
+	if err := s.checkDevice(ctx, userID, deviceID); err != nil {
+		return 0, err
+	}
+	if err := validatePrekeys(prekeys); err != nil {
+		return 0, err
+	}
+
+	if err := s.keyRepo.AddPrekeys(ctx, deviceID, prekeys); err != nil {
+		if err == repository.ErrDeviceKeysNotFound {
+			return 0, ErrDeviceKeysNotFound
+		}
+		s.logger.Error("Failed to store one-time prekeys", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+	return s.CountPrekeys(ctx, userID, deviceID)
+}
+
+// CountPrekeys returns how many one-time prekeys the calling device has
+// left, so the client knows when to upload more
+func (s *KeyService) CountPrekeys(ctx context.Context, userID, deviceID string) (int, error) {
+	if err := s.checkDevice(ctx, userID, deviceID); err != nil {
+		return 0, err
+	}
+
+	count, err := s.keyRepo.CountPrekeys(ctx, deviceID)
+	if err != nil {
+		s.logger.Error("Failed to count one-time prekeys", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+	return count, nil
+}
+
+// GetBundles hands out a prekey bundle for every device of a user, each
+// consuming one of the device's one-time prekeys
+func (s *KeyService) GetBundles(ctx context.Context, requesterID, userID string) ([]*model.PrekeyBundle, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+	if requesterID != userID {
+		blocked, err := s.blockedRepo.IsBlockedEither(ctx, requesterID, userID)
+		if err != nil {
+			return nil, apperrors.ErrInternal
+		}
+		if blocked {
+			return nil, apperrors.ErrUserBlocked
+		}
+	}
+
+	bundles, err := s.keyRepo.ClaimBundles(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to claim prekey bundles", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if len(bundles) == 0 {
+		return nil, ErrNoKeyedDevices
+	}
+	return bundles, nil
+}
+
+// checkDevice requires a device-bound token for a device of the user that
+// is still active
+func (s *KeyService) checkDevice(ctx context.Context, userID, deviceID string) error {
+	if deviceID == "" {
+		return ErrDeviceNotBound
+	}
+
+	device, err := s.deviceRepo.GetByID(ctx, deviceID)
+	if err != nil {
+		if err == repository.ErrDeviceNotFound {
+			return apperrors.ErrDeviceNotFound
+		}
+		s.logger.Error("Failed to get device", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if device.UserID != userID || device.IsRevoked() {
+		return apperrors.ErrDeviceNotFound
+	}
+	return nil
+}
+
+func validatePrekeys(prekeys []*model.OneTimePrekey) error {
+	if len(prekeys) > MaxPrekeysPerUpload {
+		return ErrTooManyPrekeys
+	}
+	for _, prekey := range prekeys {
+		if !validKey(prekey.PublicKey, minPublicKeySize, maxPublicKeySize) {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// validKey checks that s is standard Base64 of minSize to maxSize bytes
+func validKey(s string, minSize, maxSize int) bool {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	return err == nil && len(raw) >= minSize && len(raw) <= maxSize
+}
+
+// SetKeyRepository enables end-to-end encrypted direct messages
+func (s *DirectMessageService) SetKeyRepository(repo *repository.DeviceKeyRepository) {
+	s.keyRepo = repo
+}
+
+// DeviceMismatch lists how the envelopes of an encrypted message differ from
+// the devices it must reach. Clients fetch bundles for the missing devices,
+// drop sessions with the stale ones and send again.
+type DeviceMismatch struct {
+	MissingDevices []string `json:"missing_devices,omitempty"`
+	StaleDevices   []string `json:"stale_devices,omitempty"`
+}
+
+// sendEncrypted stores a ciphertext message after checking it carries
+// exactly one envelope for every keyed device of the receiver and every
+// other keyed device of the sender, so no device silently misses it
+func (s *DirectMessageService) sendEncrypted(ctx context.Context, msg *model.DirectMessage, input *SendDMInput) error {
+	if s.keyRepo == nil {
+		return ErrEncryptionUnavailable
+	}
+	if input.SenderDeviceID == "" {
+		return ErrDeviceNotBound
+	}
+
+	expected := make(map[string]bool)
+	for _, userID := range []string{input.ReceiverID, input.SenderID} {
+		ids, err := s.keyRepo.ListDeviceIDs(ctx, userID)
+		if err != nil {
+			s.logger.Error("Failed to list keyed devices", zap.Error(err))
+			return apperrors.ErrInternal
+		}
+		if userID == input.ReceiverID && len(ids) == 0 {
+			return ErrNoKeyedDevices
+		}
+		for _, id := range ids {
+			expected[id] = true
+		}
+	}
+	if !expected[input.SenderDeviceID] {
+		return ErrDeviceKeysNotFound
+	}
+	delete(expected, input.SenderDeviceID)
+
+	var mismatch DeviceMismatch
+	addressed := make(map[string]bool, len(input.Envelopes))
+	for _, env := range input.Envelopes {
+		if addressed[env.DeviceID] ||
+			(env.Type != model.EnvelopeTypeMessage && env.Type != model.EnvelopeTypePrekey) ||
+			!validKey(env.Ciphertext, 1, maxCiphertextSize) {
+			return ErrInvalidEnvelope
+		}
+		addressed[env.DeviceID] = true
+		env.SenderDeviceID = input.SenderDeviceID
+		if !expected[env.DeviceID] {
+			mismatch.StaleDevices = append(mismatch.StaleDevices, env.DeviceID)
+		}
+	}
+	for id := range expected {
+		if !addressed[id] {
+			mismatch.MissingDevices = append(mismatch.MissingDevices, id)
+		}
+	}
+	if len(mismatch.MissingDevices) > 0 || len(mismatch.StaleDevices) > 0 {
+		sort.Strings(mismatch.MissingDevices)
+		sort.Strings(mismatch.StaleDevices)
+		return apperrors.New(http.StatusConflict, "接收裝置已變更，請更新後重新傳送").WithDetails(&mismatch)
+	}
+
+	msg.Content = ""
+	if err := s.dmRepo.CreateEncrypted(ctx, msg, input.Envelopes); err != nil {
+		s.logger.Error("Failed to create encrypted direct message", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	s.relayEncrypted(msg, input)
+	return nil
+}
+
+// EncryptedDMEvent carries a new encrypted message with its envelopes keyed
+// by device ID; each device picks the one addressed to it
+type EncryptedDMEvent struct {
+	MessageID      string                       `json:"message_id"`
+	SenderID       string                       `json:"sender_id"`
+	ReceiverID     string                       `json:"receiver_id"`
+	SenderDeviceID string                       `json:"sender_device_id"`
+	CreatedAt      time.Time                    `json:"created_at"`
+	Envelopes      map[string]*model.DMEnvelope `json:"envelopes"`
+}
+
+// relayEncrypted pushes a stored encrypted message to the online devices of
+// the receiver and the sender's other devices
+func (s *DirectMessageService) relayEncrypted(msg *model.DirectMessage, input *SendDMInput) {
+	if s.notifier == nil {
+		return
+	}
+
+	event := &EncryptedDMEvent{
+		MessageID:      msg.ID,
+		SenderID:       msg.SenderID,
+		ReceiverID:     msg.ReceiverID,
+		SenderDeviceID: input.SenderDeviceID,
+		CreatedAt:      msg.CreatedAt,
+		Envelopes:      make(map[string]*model.DMEnvelope, len(input.Envelopes)),
+	}
+	for _, env := range input.Envelopes {
+		event.Envelopes[env.DeviceID] = env
+	}
+	s.notifier.PublishToUser(msg.ReceiverID, DMEventEncryptedMessage, event)
+	s.notifier.PublishToUser(msg.SenderID, DMEventEncryptedMessage, event)
+}
+
+// AttachEnvelopes sets on each ciphertext message the envelope addressed to
+// the reading device. Messages sent before the device registered its keys
+// have none for it and stay without.
+func (s *DirectMessageService) AttachEnvelopes(ctx context.Context, deviceID string, messages []*model.DirectMessageWithUser) error {
+	if s.keyRepo == nil || deviceID == "" {
+		return nil
+	}
+
+	var ids []string
+	for _, msg := range messages {
+		if msg.Type == model.MessageTypeCiphertext {
+			ids = append(ids, msg.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	envelopes, err := s.dmRepo.ListEnvelopes(ctx, deviceID, ids)
+	if err != nil {
+		s.logger.Error("Failed to list message envelopes", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	byMessage := make(map[string]*model.DMEnvelope, len(envelopes))
+	for _, env := range envelopes {
+		byMessage[env.MessageID] = env
+	}
+	for _, msg := range messages {
+		msg.Envelope = byMessage[msg.ID]
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+func TestValidKey(t *testing.T) {
+	key := func(n int) string {
+		return base64.StdEncoding.EncodeToString(make([]byte, n))
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"curve25519", key(32), true},
+		{"with type byte", key(33), true},
+		{"too short", key(31), false},
+		{"too long", key(34), false},
+		{"not base64", strings.Repeat("!", 44), false},
+		{"url alphabet", strings.Repeat("-", 43) + "=", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := validKey(tt.input, minPublicKeySize, maxPublicKeySize); got != tt.want {
+			t.Errorf("%s: validKey = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePrekeys(t *testing.T) {
+	valid := &model.OneTimePrekey{KeyID: 1, PublicKey: base64.StdEncoding.EncodeToString(make([]byte, 32))}
+
+	if err := validatePrekeys([]*model.OneTimePrekey{valid}); err != nil {
+		t.Errorf("Expected valid prekeys, got %v", err)
+	}
+	if err := validatePrekeys([]*model.OneTimePrekey{valid, {KeyID: 2, PublicKey: "short"}}); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+
+	tooMany := make([]*model.OneTimePrekey, MaxPrekeysPerUpload+1)
+	for i := range tooMany {
+		tooMany[i] = valid
+	}
+	if err := validatePrekeys(tooMany); err != ErrTooManyPrekeys {
+		t.Errorf("Expected ErrTooManyPrekeys, got %v", err)
+	}
+}
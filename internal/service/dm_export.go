@@ -0,0 +1,389 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultDMExportRetention is how long a finished export can be downloaded
+	DefaultDMExportRetention = 7 * 24 * time.Hour
+	// dmExportStaleTimeout returns exports claimed by a worker that died
+	// mid-export to the queue
+	dmExportStaleTimeout = 30 * time.Minute
+)
+
+var (
+	ErrDMExportNotFound   = apperrors.New(http.StatusNotFound, "匯出不存在或已過期")
+	ErrDMExportInProgress = apperrors.New(http.StatusConflict, "此對話已有匯出正在處理")
+	ErrDMExportNotReady   = apperrors.New(http.StatusConflict, "匯出尚未完成")
+	ErrCannotExportSelf   = apperrors.New(http.StatusBadRequest, "無法匯出與自己的對話")
+)
+
+// DMExportParticipant identifies one side of an exported conversation
+type DMExportParticipant struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// ExportedConversationMessage is a message in a participant's conversation
+// export
+type ExportedConversationMessage struct {
+	ID             string               `json:"id"`
+	SenderID       string               `json:"sender_id"`
+	SenderUsername string               `json:"sender_username"`
+	ReceiverID     string               `json:"receiver_id"`
+	Content        string               `json:"content"`
+	Type           string               `json:"type"`
+	IsRead         bool                 `json:"is_read"`
+	ForwardedFrom  *model.ForwardedFrom `json:"forwarded_from,omitempty"`
+	CreatedAt      string               `json:"created_at"`
+	UpdatedAt      string               `json:"updated_at"`
+}
+
+// ExportedAttachment lists a file shared in an exported conversation. The
+// export references attachments by URL rather than embedding them.
+type ExportedAttachment struct {
+	MessageID string `json:"message_id"`
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	FileName  string `json:"file_name"`
+	CreatedAt string `json:"created_at"`
+}
+
+func newExportedConversationMessage(dm *model.DirectMessageWithUser) *ExportedConversationMessage {
+	return &ExportedConversationMessage{
+		ID:             dm.ID,
+		SenderID:       dm.SenderID,
+		SenderUsername: dm.SenderUsername,
+		ReceiverID:     dm.ReceiverID,
+		Content:        dm.Content,
+		Type:           string(dm.Type),
+		IsRead:         dm.IsRead,
+		ForwardedFrom:  dm.GetForwardedFrom(),
+		CreatedAt:      dm.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:      dm.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// newExportedAttachment returns the manifest entry for an image or file
+// message, whose content is the uploaded file's URL, or nil otherwise
+func newExportedAttachment(dm *model.DirectMessageWithUser) *ExportedAttachment {
+	if dm.Type != model.MessageTypeImage && dm.Type != model.MessageTypeFile {
+		return nil
+	}
+	name := dm.Content
+	if u, err := url.Parse(dm.Content); err == nil && u.Path != "" {
+		name = path.Base(u.Path)
+	}
+	return &ExportedAttachment{
+		MessageID: dm.ID,
+		Type:      string(dm.Type),
+		URL:       dm.Content,
+		FileName:  name,
+		CreatedAt: dm.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// SetExports enables conversation exports, written to dir and kept for
+// retention once finished
+func (s *DirectMessageService) SetExports(repo *repository.DMExportRepository, dir string, retention time.Duration) {
+	_ = os.MkdirAll(dir, 0755)
+	if retention <= 0 {
+		retention = DefaultDMExportRetention
+	}
+	s.exportRepo = repo
+	s.exportDir = dir
+	s.exportRetention = retention
+}
+
+// RequestExport queues an export of the user's copy of their conversation
+// with peerID. Messages the user deleted for themselves are left out.
+func (s *DirectMessageService) RequestExport(ctx context.Context, userID, peerID string) (*model.DMExport, error) {
+	if userID == peerID {
+		return nil, ErrCannotExportSelf
+	}
+	if _, err := s.userRepo.GetByID(ctx, peerID); err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to get export peer", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	export := &model.DMExport{UserID: userID, PeerID: peerID}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		if err == repository.ErrDMExportInProgress {
+			return nil, ErrDMExportInProgress
+		}
+		s.logger.Error("Failed to create dm export", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return export, nil
+}
+
+// GetExport returns one of the user's exports
+func (s *DirectMessageService) GetExport(ctx context.Context, id, userID string) (*model.DMExport, error) {
+	export, err := s.exportRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrDMExportNotFound {
+			return nil, ErrDMExportNotFound
+		}
+		s.logger.Error("Failed to get dm export", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if export.UserID != userID {
+		return nil, ErrDMExportNotFound
+	}
+	return export, nil
+}
+
+// OpenExport opens a finished export for download. The caller closes the file.
+func (s *DirectMessageService) OpenExport(ctx context.Context, id, userID string) (*model.DMExport, *os.File, error) {
+	export, err := s.GetExport(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !export.IsReady() {
+		return nil, nil, ErrDMExportNotReady
+	}
+
+	f, err := os.Open(filepath.Join(s.exportDir, export.StoredName.String))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrDMExportNotFound
+		}
+		s.logger.Error("Failed to open dm export", zap.String("export_id", id), zap.Error(err))
+		return nil, nil, apperrors.ErrInternal
+	}
+	return export, f, nil
+}
+
+// DMExportFilename is the name an export is downloaded as
+func DMExportFilename(export *model.DMExport) string {
+	at := export.CreatedAt
+	if export.CompletedAt != nil {
+		at = *export.CompletedAt
+	}
+	return fmt.Sprintf("dm-%s-%s.json", export.PeerID, at.UTC().Format("20060102T150405Z"))
+}
+
+// ProcessExports produces up to limit queued exports and notifies their
+// owners. It returns the number completed.
+func (s *DirectMessageService) ProcessExports(ctx context.Context, limit int) (int, error) {
+	if s.exportRepo == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	if released, err := s.exportRepo.ReleaseStale(ctx, now.Add(-dmExportStaleTimeout)); err != nil {
+		s.logger.Error("Failed to release stale dm exports", zap.Error(err))
+	} else if released > 0 {
+		s.logger.Warn("Released stale dm exports", zap.Int64("count", released))
+	}
+
+	exports, err := s.exportRepo.ClaimPending(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, export := range exports {
+		if err := checkContext(ctx); err != nil {
+			return completed, err
+		}
+
+		count, err := s.produceExport(ctx, export)
+		expiresAt := time.Now().Add(s.exportRetention)
+		if err != nil {
+			s.logger.Error("DM export failed",
+				zap.String("export_id", export.ID),
+				zap.String("user_id", export.UserID),
+				zap.Error(err),
+			)
+			if err := s.exportRepo.MarkFailed(ctx, export.ID, "匯出失敗，請稍後重新申請", expiresAt); err != nil {
+				s.logger.Error("Failed to mark dm export failed", zap.Error(err))
+			}
+			continue
+		}
+
+		if err := s.exportRepo.MarkCompleted(ctx, export.ID, dmExportStoredName(export.ID), count, expiresAt); err != nil {
+			s.logger.Error("Failed to mark dm export completed", zap.Error(err))
+			continue
+		}
+		s.logger.Info("DM conversation exported",
+			zap.String("export_id", export.ID),
+			zap.String("user_id", export.UserID),
+			zap.Int("records", count),
+		)
+		s.notifyExportReady(ctx, export)
+		completed++
+	}
+
+	return completed, nil
+}
+
+// PurgeExports removes up to limit expired exports and their files
+func (s *DirectMessageService) PurgeExports(ctx context.Context, limit int) (int, error) {
+	if s.exportRepo == nil {
+		return 0, nil
+	}
+
+	exports, err := s.exportRepo.DeleteExpired(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+	for _, export := range exports {
+		if !export.StoredName.Valid {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.exportDir, export.StoredName.String)); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("Failed to remove dm export", zap.String("export_id", export.ID), zap.Error(err))
+		}
+	}
+	if len(exports) > 0 {
+		s.logger.Info("Expired dm exports purged", zap.Int("count", len(exports)))
+	}
+	return len(exports), nil
+}
+
+func dmExportStoredName(id string) string {
+	return id + ".json"
+}
+
+// produceExport writes the export to a temporary file and moves it into
+// place once complete, so a download never sees a partial file
+func (s *DirectMessageService) produceExport(ctx context.Context, export *model.DMExport) (int, error) {
+	users, err := s.userRepo.GetByIDs(ctx, []string{export.UserID, export.PeerID})
+	if err != nil {
+		return 0, err
+	}
+	doc := &dmExportDocument{ExportedAt: time.Now().UTC().Format(time.RFC3339)}
+	for _, u := range users {
+		participant := &DMExportParticipant{ID: u.ID, Username: u.Username, DisplayName: u.DisplayName.String}
+		if u.ID == export.UserID {
+			doc.User = participant
+		} else {
+			doc.Peer = participant
+		}
+	}
+	if doc.User == nil || doc.Peer == nil {
+		return 0, fmt.Errorf("participants of dm export %s not found", export.ID)
+	}
+
+	tmp, err := os.CreateTemp(s.exportDir, export.ID+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	count, err := doc.write(ctx, tmp, func(cursor *repository.ExportCursor) ([]*model.DirectMessageWithUser, error) {
+		return s.dmRepo.ListConversationForExport(ctx, export.UserID, export.PeerID, cursor, exportPageSize)
+	})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return count, err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(s.exportDir, dmExportStoredName(export.ID))); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// notifyExportReady tells the owner their export can be downloaded
+func (s *DirectMessageService) notifyExportReady(ctx context.Context, export *model.DMExport) {
+	if s.notifier == nil {
+		return
+	}
+	s.notifier.Notify(ctx, []string{export.UserID}, &NotifyInput{
+		Type:          model.NotificationTypeDMExportReady,
+		Title:         "私訊對話匯出完成",
+		Content:       fmt.Sprintf("匯出檔可在 %d 天內下載", int(s.exportRetention.Hours()/24)),
+		ReferenceID:   export.ID,
+		ReferenceType: "dm_export",
+	})
+}
+
+// dmExportDocument is the JSON file a conversation export produces. It is
+// written a page of messages at a time, with the attachments manifest last.
+type dmExportDocument struct {
+	ExportedAt string
+	User       *DMExportParticipant
+	Peer       *DMExportParticipant
+}
+
+// write streams the document to w, reading messages with fetch, and
+// returns the number of messages written
+func (d *dmExportDocument) write(ctx context.Context, w io.Writer, fetch func(*repository.ExportCursor) ([]*model.DirectMessageWithUser, error)) (int, error) {
+	buf := bufio.NewWriter(w)
+	header, err := json.Marshal(map[string]interface{}{
+		"exported_at": d.ExportedAt,
+		"user":        d.User,
+		"peer":        d.Peer,
+	})
+	if err != nil {
+		return 0, err
+	}
+	// Reopen the header object to append the messages and manifest
+	buf.Write(header[:len(header)-1])
+	buf.WriteString(`,"messages":[`)
+
+	count := 0
+	attachments := []*ExportedAttachment{}
+	cursor := repository.NewExportCursor()
+	for {
+		if err := checkContext(ctx); err != nil {
+			return count, err
+		}
+
+		messages, err := fetch(cursor)
+		if err != nil {
+			return count, err
+		}
+		for _, m := range messages {
+			cursor.Advance(m.CreatedAt, m.ID)
+			raw, err := json.Marshal(newExportedConversationMessage(m))
+			if err != nil {
+				return count, fmt.Errorf("failed to encode message %s: %w", m.ID, err)
+			}
+			if count > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(raw)
+			count++
+			if a := newExportedAttachment(m); a != nil {
+				attachments = append(attachments, a)
+			}
+		}
+		if len(messages) < exportPageSize {
+			break
+		}
+	}
+
+	manifest, err := json.Marshal(attachments)
+	if err != nil {
+		return count, err
+	}
+	buf.WriteString(`],"attachments":`)
+	buf.Write(manifest)
+	fmt.Fprintf(buf, `,"message_count":%d}`+"\n", count)
+	return count, buf.Flush()
+}
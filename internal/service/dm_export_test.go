@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+func newExportTestDM(i int, msgType model.MessageType, content string) *model.DirectMessageWithUser {
+	dm := &model.DirectMessageWithUser{SenderUsername: "alice"}
+	dm.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1)
+	dm.SenderID = "user-alice"
+	dm.ReceiverID = "user-bob"
+	dm.Type = msgType
+	dm.Content = content
+	dm.CreatedAt = time.Date(2024, 5, 1, 12, 0, i, 0, time.UTC)
+	dm.UpdatedAt = dm.CreatedAt
+	return dm
+}
+
+func TestDMExportDocument_Write(t *testing.T) {
+	// More than one page, so the writer has to follow the cursor
+	var all []*model.DirectMessageWithUser
+	for i := 0; i < exportPageSize+2; i++ {
+		all = append(all, newExportTestDM(i, model.MessageTypeText, "hi"))
+	}
+	all[3] = newExportTestDM(3, model.MessageTypeImage, "https://chat.example.com/uploads/images/cat.png")
+	all[exportPageSize+1] = newExportTestDM(exportPageSize+1, model.MessageTypeFile, "https://chat.example.com/uploads/files/report.pdf?v=2")
+
+	calls := 0
+	fetch := func(cursor *repository.ExportCursor) ([]*model.DirectMessageWithUser, error) {
+		calls++
+		start := 0
+		for i, m := range all {
+			if m.ID == cursor.ID {
+				start = i + 1
+			}
+		}
+		end := start + exportPageSize
+		if end > len(all) {
+			end = len(all)
+		}
+		return all[start:end], nil
+	}
+
+	doc := &dmExportDocument{
+		ExportedAt: "2024-05-02T00:00:00Z",
+		User:       &DMExportParticipant{ID: "user-alice", Username: "alice"},
+		Peer:       &DMExportParticipant{ID: "user-bob", Username: "bob", DisplayName: "Bob"},
+	}
+	var buf bytes.Buffer
+	count, err := doc.write(context.Background(), &buf, fetch)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if count != len(all) || calls != 2 {
+		t.Errorf("Expected %d messages in 2 pages, got %d in %d", len(all), count, calls)
+	}
+
+	var got struct {
+		ExportedAt   string                         `json:"exported_at"`
+		Peer         DMExportParticipant            `json:"peer"`
+		Messages     []*ExportedConversationMessage `json:"messages"`
+		Attachments  []*ExportedAttachment          `json:"attachments"`
+		MessageCount int                            `json:"message_count"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Export is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.Peer.Username != "bob" || got.MessageCount != len(all) || len(got.Messages) != len(all) {
+		t.Errorf("Unexpected document: peer %+v, count %d, messages %d", got.Peer, got.MessageCount, len(got.Messages))
+	}
+	if len(got.Attachments) != 2 {
+		t.Fatalf("Expected 2 attachments, got %d", len(got.Attachments))
+	}
+	if got.Attachments[0].FileName != "cat.png" || got.Attachments[1].FileName != "report.pdf" {
+		t.Errorf("Unexpected attachment names: %q, %q", got.Attachments[0].FileName, got.Attachments[1].FileName)
+	}
+}
+
+func TestDMExportDocument_WriteEmpty(t *testing.T) {
+	doc := &dmExportDocument{
+		User: &DMExportParticipant{ID: "user-alice"},
+		Peer: &DMExportParticipant{ID: "user-bob"},
+	}
+	var buf bytes.Buffer
+	_, err := doc.write(context.Background(), &buf, func(*repository.ExportCursor) ([]*model.DirectMessageWithUser, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Export is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if string(got["messages"]) != "[]" || string(got["attachments"]) != "[]" {
+		t.Errorf("Expected empty arrays, got %s and %s", got["messages"], got["attachments"])
+	}
+}
+
+func TestDMExport_IsReady(t *testing.T) {
+	export := &model.DMExport{Status: model.DMExportStatusRunning}
+	if export.IsReady() {
+		t.Error("Expected a running export not to be ready")
+	}
+	export.Status = model.DMExportStatusCompleted
+	export.StoredName.String, export.StoredName.Valid = "x.json", true
+	if !export.IsReady() {
+		t.Error("Expected a completed export to be ready")
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+var ErrFeedbackNotFound = apperrors.New(http.StatusNotFound, "回饋不存在")
+
+// FeedbackForwarder sends feedback on to an external issue tracker
+type FeedbackForwarder interface {
+	Forward(ctx context.Context, fb *model.Feedback) error
+}
+
+type FeedbackService struct {
+	feedbackRepo *repository.FeedbackRepository
+	forwarder    FeedbackForwarder
+	logger       *zap.Logger
+}
+
+func NewFeedbackService(feedbackRepo *repository.FeedbackRepository, logger *zap.Logger) *FeedbackService {
+	return &FeedbackService{
+		feedbackRepo: feedbackRepo,
+		logger:       logger,
+	}
+}
+
+// SetForwarder sets where new feedback is forwarded; without one feedback
+// only stays in the database
+func (s *FeedbackService) SetForwarder(forwarder FeedbackForwarder) {
+	s.forwarder = forwarder
+}
+
+// SubmitFeedbackInput represents feedback sent from a client
+type SubmitFeedbackInput struct {
+	UserID        string
+	Category      model.FeedbackCategory
+	Text          string
+	ScreenshotURL string
+	AppVersion    string
+	Client        string
+	UserAgent     string
+}
+
+// Submit stores feedback. Forwarding happens in the background so a slow
+// tracker never holds up the client.
+func (s *FeedbackService) Submit(ctx context.Context, input *SubmitFeedbackInput) (*model.Feedback, error) {
+	if !input.Category.IsValid() {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"category": "必須為 bug、suggestion 或 other",
+		})
+	}
+	text := strings.TrimSpace(input.Text)
+	if text == "" {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"text": "內容不能為空",
+		})
+	}
+
+	fb := &model.Feedback{
+		UserID:        nullString(input.UserID),
+		Category:      input.Category,
+		Text:          text,
+		ScreenshotURL: nullString(input.ScreenshotURL),
+		AppVersion:    nullString(input.AppVersion),
+		Client:        nullString(input.Client),
+		UserAgent:     nullString(input.UserAgent),
+	}
+	if err := s.feedbackRepo.Create(ctx, fb); err != nil {
+		s.logger.Error("Failed to create feedback", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Feedback submitted",
+		zap.String("feedback_id", fb.ID),
+		zap.String("category", string(fb.Category)),
+		zap.String("user_id", input.UserID),
+	)
+
+	return fb, nil
+}
+
+// Get returns a single piece of feedback
+func (s *FeedbackService) Get(ctx context.Context, id string) (*model.Feedback, error) {
+	fb, err := s.feedbackRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrFeedbackNotFound {
+			return nil, ErrFeedbackNotFound
+		}
+		s.logger.Error("Failed to get feedback", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return fb, nil
+}
+
+// List lists feedback for triage
+func (s *FeedbackService) List(ctx context.Context, filter *repository.FeedbackFilter, limit, offset int) ([]*model.Feedback, error) {
+	items, err := s.feedbackRepo.List(ctx, filter, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list feedback", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return items, nil
+}
+
+// Triage moves feedback to a new status, optionally with a note
+func (s *FeedbackService) Triage(ctx context.Context, id string, status model.FeedbackStatus, note, triagedBy string) (*model.Feedback, error) {
+	if !status.IsValid() {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"status": "必須為 open、in_progress、resolved 或 dismissed",
+		})
+	}
+
+	fb, err := s.feedbackRepo.Triage(ctx, id, status, nullString(strings.TrimSpace(note)), triagedBy)
+	if err != nil {
+		if err == repository.ErrFeedbackNotFound {
+			return nil, ErrFeedbackNotFound
+		}
+		s.logger.Error("Failed to triage feedback", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Feedback triaged",
+		zap.String("feedback_id", id),
+		zap.String("status", string(status)),
+		zap.String("triaged_by", triagedBy),
+	)
+	return fb, nil
+}
+
+// ForwardPending sends up to limit unforwarded feedback to the tracker,
+// oldest first. It stops at the first failure and leaves the rest for the
+// next run, so feedback is retried until the tracker accepts it.
+func (s *FeedbackService) ForwardPending(ctx context.Context, limit int) (int, error) {
+	if s.forwarder == nil {
+		return 0, nil
+	}
+
+	items, err := s.feedbackRepo.ListUnforwarded(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	forwarded := 0
+	for _, fb := range items {
+		if err := checkContext(ctx); err != nil {
+			return forwarded, err
+		}
+		if err := s.forwarder.Forward(ctx, fb); err != nil {
+			s.logger.Warn("Failed to forward feedback", zap.String("feedback_id", fb.ID), zap.Error(err))
+			return forwarded, err
+		}
+		if err := s.feedbackRepo.MarkForwarded(ctx, fb.ID); err != nil {
+			return forwarded, err
+		}
+		forwarded++
+	}
+
+	if forwarded > 0 {
+		s.logger.Info("Feedback forwarded", zap.Int("count", forwarded))
+	}
+	return forwarded, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type recordingForwarder struct {
+	forwarded []string
+	err       error
+}
+
+func (f *recordingForwarder) Forward(ctx context.Context, fb *model.Feedback) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.forwarded = append(f.forwarded, fb.ID)
+	return nil
+}
+
+func setupTestFeedbackService(t *testing.T) (*FeedbackService, *sqlx.DB) {
+	t.Helper()
+
+	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=chat_test sslmode=disable"
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Skipf("Skipping test, could not connect to test database: %v", err)
+	}
+
+	return NewFeedbackService(repository.NewFeedbackRepository(db), zap.NewNop()), db
+}
+
+func TestFeedbackService_SubmitAndTriage(t *testing.T) {
+	service, db := setupTestFeedbackService(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := service.Submit(ctx, &SubmitFeedbackInput{Category: "praise", Text: "nice"}); err == nil {
+		t.Error("Expected unknown category to be rejected")
+	}
+	if _, err := service.Submit(ctx, &SubmitFeedbackInput{Category: model.FeedbackCategoryBug, Text: "   "}); err == nil {
+		t.Error("Expected blank text to be rejected")
+	}
+
+	fb, err := service.Submit(ctx, &SubmitFeedbackInput{
+		Category:   model.FeedbackCategoryBug,
+		Text:       "  Crash when opening settings  ",
+		AppVersion: "1.4.2",
+		Client:     "ios",
+	})
+	if err != nil {
+		t.Fatalf("Failed to submit feedback: %v", err)
+	}
+	defer db.Exec(`DELETE FROM feedback WHERE id = $1`, fb.ID)
+
+	if fb.Status != model.FeedbackStatusOpen || fb.Text != "Crash when opening settings" {
+		t.Errorf("Unexpected feedback: %+v", fb)
+	}
+
+	if _, err := service.Triage(ctx, fb.ID, "closed", "", ""); err == nil {
+		t.Error("Expected unknown status to be rejected")
+	}
+
+	triaged, err := service.Triage(ctx, fb.ID, model.FeedbackStatusInProgress, "Reproduced on 17.2", "")
+	if err != nil {
+		t.Fatalf("Failed to triage feedback: %v", err)
+	}
+	if triaged.Status != model.FeedbackStatusInProgress || triaged.TriageNote.String != "Reproduced on 17.2" || triaged.TriagedAt == nil {
+		t.Errorf("Unexpected triaged feedback: %+v", triaged)
+	}
+
+	items, err := service.List(ctx, &repository.FeedbackFilter{Status: model.FeedbackStatusInProgress}, 100, 0)
+	if err != nil {
+		t.Fatalf("Failed to list feedback: %v", err)
+	}
+	found := false
+	for _, item := range items {
+		if item.Status != model.FeedbackStatusInProgress {
+			t.Errorf("Expected only in-progress feedback, got %s", item.Status)
+		}
+		found = found || item.ID == fb.ID
+	}
+	if !found {
+		t.Error("Expected triaged feedback in filtered list")
+	}
+}
+
+func TestFeedbackService_ForwardPending(t *testing.T) {
+	service, db := setupTestFeedbackService(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Forward anything left over so only this test's feedback is pending
+	drain := &recordingForwarder{}
+	service.SetForwarder(drain)
+	for {
+		n, err := service.ForwardPending(ctx, 100)
+		if err != nil {
+			t.Fatalf("Failed to drain pending feedback: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	fb, err := service.Submit(ctx, &SubmitFeedbackInput{Category: model.FeedbackCategorySuggestion, Text: "Dark mode"})
+	if err != nil {
+		t.Fatalf("Failed to submit feedback: %v", err)
+	}
+	defer db.Exec(`DELETE FROM feedback WHERE id = $1`, fb.ID)
+
+	service.SetForwarder(&recordingForwarder{err: errors.New("tracker down")})
+	if _, err := service.ForwardPending(ctx, 10); err == nil {
+		t.Error("Expected forwarding failure to be reported")
+	}
+
+	forwarder := &recordingForwarder{}
+	service.SetForwarder(forwarder)
+	if n, err := service.ForwardPending(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("Expected 1 forwarded, got %d, %v", n, err)
+	}
+	if len(forwarder.forwarded) != 1 || forwarder.forwarded[0] != fb.ID {
+		t.Errorf("Unexpected forwarded feedback: %v", forwarder.forwarded)
+	}
+	if n, _ := service.ForwardPending(ctx, 10); n != 0 {
+		t.Errorf("Expected feedback to be forwarded once, got %d more", n)
+	}
+}
+
+func TestWebhookFeedbackForwarder(t *testing.T) {
+	var got FeedbackWebhookPayload
+	var signature string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &got)
+		signature = r.Header.Get(FeedbackSignatureHeader)
+		if want := "sha256=" + SignFeedbackWebhook([]byte("s3cret"), body); signature != want {
+			t.Errorf("Signature %q, want %q", signature, want)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	fb := &model.Feedback{
+		ID:        "fb-1",
+		Category:  model.FeedbackCategoryBug,
+		Text:      "Broken",
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	fb.AppVersion.String, fb.AppVersion.Valid = "2.0.0", true
+
+	forwarder := NewWebhookFeedbackForwarder(server.URL, "s3cret", time.Second)
+	if err := forwarder.Forward(context.Background(), fb); err != nil {
+		t.Fatalf("Forward: %v", err)
+	}
+	if got.ID != "fb-1" || got.Category != "bug" || got.AppVersion != "2.0.0" || got.CreatedAt != "2024-05-01T12:00:00Z" {
+		t.Errorf("Unexpected payload: %+v", got)
+	}
+	if signature == "" {
+		t.Error("Expected signed request")
+	}
+
+	status = http.StatusInternalServerError
+	if err := forwarder.Forward(context.Background(), fb); err == nil {
+		t.Error("Expected non-2xx response to fail")
+	}
+}
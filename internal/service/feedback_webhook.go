@@ -0,0 +1,107 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+// FeedbackSignatureHeader carries the hex HMAC-SHA256 of a webhook body,
+// prefixed with "sha256=", when a secret is configured
+const FeedbackSignatureHeader = "X-Feedback-Signature"
+
+// DefaultFeedbackWebhookTimeout bounds a single webhook delivery
+const DefaultFeedbackWebhookTimeout = 10 * time.Second
+
+// FeedbackWebhookPayload is the JSON body posted for each piece of feedback
+type FeedbackWebhookPayload struct {
+	ID            string `json:"id"`
+	Category      string `json:"category"`
+	Text          string `json:"text"`
+	ScreenshotURL string `json:"screenshot_url,omitempty"`
+	AppVersion    string `json:"app_version,omitempty"`
+	Client        string `json:"client,omitempty"`
+	UserAgent     string `json:"user_agent,omitempty"`
+	UserID        string `json:"user_id,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// WebhookFeedbackForwarder posts feedback to an external tracker's webhook.
+// Any 2xx response counts as delivered.
+type WebhookFeedbackForwarder struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookFeedbackForwarder creates a forwarder posting to url, signing
+// bodies with secret when it is not empty
+func NewWebhookFeedbackForwarder(url, secret string, timeout time.Duration) *WebhookFeedbackForwarder {
+	if timeout <= 0 {
+		timeout = DefaultFeedbackWebhookTimeout
+	}
+	f := &WebhookFeedbackForwarder{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+	if secret != "" {
+		f.secret = []byte(secret)
+	}
+	return f
+}
+
+// Forward posts fb to the webhook
+func (f *WebhookFeedbackForwarder) Forward(ctx context.Context, fb *model.Feedback) error {
+	body, err := json.Marshal(&FeedbackWebhookPayload{
+		ID:            fb.ID,
+		Category:      string(fb.Category),
+		Text:          fb.Text,
+		ScreenshotURL: fb.ScreenshotURL.String,
+		AppVersion:    fb.AppVersion.String,
+		Client:        fb.Client.String,
+		UserAgent:     fb.UserAgent.String,
+		UserID:        fb.UserID.String,
+		CreatedAt:     fb.CreatedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode feedback webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create feedback webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.secret != nil {
+		req.Header.Set(FeedbackSignatureHeader, "sha256="+SignFeedbackWebhook(f.secret, body))
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post feedback webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("feedback webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignFeedbackWebhook returns the hex HMAC-SHA256 of body, which receivers
+// compare against the signature header
+func SignFeedbackWebhook(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
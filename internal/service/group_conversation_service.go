@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Group conversations hold at least three participants when created,
+// the creator included, and never more than ten
+const (
+	MinGroupParticipants = 3
+	MaxGroupParticipants = 10
+)
+
+// Realtime events pushed to every participant of a group conversation
+const (
+	GroupEventCreated            = "group_conversation_created"
+	GroupEventMessage            = "new_group_message"
+	GroupEventParticipantsAdded  = "group_participants_added"
+	GroupEventParticipantRemoved = "group_participant_removed"
+)
+
+var (
+	ErrGroupSize                 = apperrors.New(http.StatusBadRequest, "群組對話需有 3 至 10 位成員")
+	ErrGroupConversationNotFound = apperrors.New(http.StatusNotFound, "群組對話不存在")
+	ErrGroupParticipantNotFound  = apperrors.New(http.StatusNotFound, "該用戶不在此群組對話中")
+	ErrGroupRemoveDenied         = apperrors.New(http.StatusForbidden, "只有建立者可以移除其他成員")
+	ErrGroupBotParticipant       = apperrors.New(http.StatusBadRequest, "機器人無法加入群組對話")
+	ErrGroupMessageType          = apperrors.New(http.StatusBadRequest, "群組對話僅支援文字、圖片與檔案訊息")
+)
+
+// GroupConversationService manages multi-party direct conversations. Unlike
+// rooms they have no roles or settings: every participant may write and
+// add others, and only the creator may remove someone else.
+type GroupConversationService struct {
+	store    GroupConversationStore
+	users    UserLookup
+	blocks   BlockLookup
+	notifier *NotificationService
+	logger   *zap.Logger
+}
+
+func NewGroupConversationService(store GroupConversationStore, users UserLookup, blocks BlockLookup, logger *zap.Logger) *GroupConversationService {
+	return &GroupConversationService{
+		store:  store,
+		users:  users,
+		blocks: blocks,
+		logger: logger,
+	}
+}
+
+// SetNotifier sets the notification service used to push group events
+func (s *GroupConversationService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// CreateGroupConversationInput represents a new group conversation
+type CreateGroupConversationInput struct {
+	CreatorID      string
+	Name           string
+	ParticipantIDs []string // others than the creator
+}
+
+// SendGroupMessageInput represents a message sent to a group conversation
+type SendGroupMessageInput struct {
+	ConversationID string
+	SenderID       string
+	Content        string
+	Type           model.MessageType
+}
+
+// GroupParticipantsEvent tells participants who was added to or removed
+// from a group conversation, and by whom
+type GroupParticipantsEvent struct {
+	ConversationID string    `json:"conversation_id"`
+	ActorID        string    `json:"actor_id"`
+	UserIDs        []string  `json:"user_ids"`
+	At             time.Time `json:"at"`
+}
+
+// Create starts a group conversation between the creator and the given
+// users. None of them may have blocked the creator or be blocked by them.
+func (s *GroupConversationService) Create(ctx context.Context, input *CreateGroupConversationInput) (*model.GroupConversationDetail, error) {
+	ids := []string{input.CreatorID}
+	seen := map[string]bool{input.CreatorID: true}
+	for _, id := range input.ParticipantIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < MinGroupParticipants || len(ids) > MaxGroupParticipants {
+		return nil, ErrGroupSize
+	}
+	if err := s.checkInvitees(ctx, input.CreatorID, ids[1:]); err != nil {
+		return nil, err
+	}
+
+	conv := &model.GroupConversation{
+		Name:      nullString(strings.TrimSpace(input.Name)),
+		CreatedBy: nullString(input.CreatorID),
+	}
+	if err := s.store.Create(ctx, conv, ids); err != nil {
+		s.logger.Error("Failed to create group conversation", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	detail, err := s.detail(ctx, conv)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(detail.Participants, GroupEventCreated, detail)
+
+	return detail, nil
+}
+
+// Get retrieves a group conversation the user takes part in
+func (s *GroupConversationService) Get(ctx context.Context, id, userID string) (*model.GroupConversationDetail, error) {
+	if _, err := s.participants(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	conv, err := s.getConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(ctx, conv)
+}
+
+// ListConversations lists the group conversations of a user
+func (s *GroupConversationService) ListConversations(ctx context.Context, userID string, limit, offset int) ([]*model.GroupConversationSummary, error) {
+	conversations, err := s.store.ListForUser(ctx, userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list group conversations", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return conversations, nil
+}
+
+// AddParticipants adds users to a group conversation. Any participant may
+// add others as long as the conversation stays within the size limit.
+func (s *GroupConversationService) AddParticipants(ctx context.Context, id, userID string, userIDs []string) (*model.GroupConversationDetail, error) {
+	participants, err := s.participants(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(participants))
+	for _, p := range participants {
+		seen[p.UserID] = true
+	}
+	var added []string
+	for _, uid := range userIDs {
+		if !seen[uid] {
+			seen[uid] = true
+			added = append(added, uid)
+		}
+	}
+	if len(added) == 0 {
+		return s.Get(ctx, id, userID)
+	}
+	if len(participants)+len(added) > MaxGroupParticipants {
+		return nil, ErrGroupSize
+	}
+	if err := s.checkInvitees(ctx, userID, added); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.AddParticipants(ctx, id, added); err != nil {
+		s.logger.Error("Failed to add group participants", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	conv, err := s.getConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := s.detail(ctx, conv)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(detail.Participants, GroupEventParticipantsAdded, &GroupParticipantsEvent{
+		ConversationID: id,
+		ActorID:        userID,
+		UserIDs:        added,
+		At:             time.Now(),
+	})
+
+	return detail, nil
+}
+
+// RemoveParticipant removes a user from a group conversation. Participants
+// may leave on their own; only the creator may remove someone else. The
+// conversation is deleted when its last participant leaves.
+func (s *GroupConversationService) RemoveParticipant(ctx context.Context, id, userID, targetID string) error {
+	participants, err := s.participants(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !hasGroupParticipant(participants, targetID) {
+		return ErrGroupParticipantNotFound
+	}
+	if targetID != userID {
+		conv, err := s.getConversation(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !conv.CreatedBy.Valid || conv.CreatedBy.String != userID {
+			return ErrGroupRemoveDenied
+		}
+	}
+
+	if err := s.store.RemoveParticipant(ctx, id, targetID); err != nil {
+		if err == repository.ErrGroupParticipantNotFound {
+			return ErrGroupParticipantNotFound
+		}
+		s.logger.Error("Failed to remove group participant", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	// The removed user is told too so their clients drop the conversation
+	s.publish(participants, GroupEventParticipantRemoved, &GroupParticipantsEvent{
+		ConversationID: id,
+		ActorID:        userID,
+		UserIDs:        []string{targetID},
+		At:             time.Now(),
+	})
+
+	return nil
+}
+
+// SendMessage sends a message to a group conversation and pushes it to
+// every participant, the sender's other connections included
+func (s *GroupConversationService) SendMessage(ctx context.Context, input *SendGroupMessageInput) (*model.GroupMessageWithUser, error) {
+	participants, err := s.participants(ctx, input.ConversationID, input.SenderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Type == "" {
+		input.Type = model.MessageTypeText
+	}
+	switch input.Type {
+	case model.MessageTypeText, model.MessageTypeImage, model.MessageTypeFile:
+	default:
+		return nil, ErrGroupMessageType
+	}
+
+	msg := &model.GroupMessage{
+		ConversationID: input.ConversationID,
+		SenderID:       input.SenderID,
+		Content:        input.Content,
+		Type:           input.Type,
+	}
+	if err := s.store.CreateMessage(ctx, msg); err != nil {
+		s.logger.Error("Failed to create group message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	msgWithUser := &model.GroupMessageWithUser{GroupMessage: *msg}
+	for _, p := range participants {
+		if p.UserID == input.SenderID {
+			msgWithUser.SenderUsername = p.Username
+			msgWithUser.SenderDisplayName = p.DisplayName
+			msgWithUser.SenderAvatarURL = p.AvatarURL
+		}
+	}
+	s.publish(participants, GroupEventMessage, msgWithUser)
+
+	return msgWithUser, nil
+}
+
+// ListMessages retrieves the messages of a group conversation the user
+// takes part in
+func (s *GroupConversationService) ListMessages(ctx context.Context, id, userID string, limit, offset int) ([]*model.GroupMessageWithUser, error) {
+	if _, err := s.participants(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.store.ListMessages(ctx, id, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list group messages", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return messages, nil
+}
+
+// MarkAsRead marks a group conversation read for the user
+func (s *GroupConversationService) MarkAsRead(ctx context.Context, id, userID string) error {
+	if _, err := s.participants(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.store.MarkAsRead(ctx, id, userID); err != nil {
+		s.logger.Error("Failed to mark group conversation as read", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	publishReadState(s.notifier, userID, model.ReadStateGroup, id, time.Now())
+	return nil
+}
+
+// CountUnread counts the unread messages across a user's group conversations
+func (s *GroupConversationService) CountUnread(ctx context.Context, userID string) (int, error) {
+	count, err := s.store.CountUnread(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to count unread group messages", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+	return count, nil
+}
+
+// participants lists the participants of a conversation on behalf of one
+// of them. Conversations the user is not in are reported as not found.
+func (s *GroupConversationService) participants(ctx context.Context, id, userID string) ([]*model.GroupParticipant, error) {
+	participants, err := s.store.ListParticipants(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to list group participants", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if !hasGroupParticipant(participants, userID) {
+		return nil, ErrGroupConversationNotFound
+	}
+	return participants, nil
+}
+
+func (s *GroupConversationService) getConversation(ctx context.Context, id string) (*model.GroupConversation, error) {
+	conv, err := s.store.GetByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrGroupConversationNotFound {
+			return nil, ErrGroupConversationNotFound
+		}
+		s.logger.Error("Failed to get group conversation", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return conv, nil
+}
+
+func (s *GroupConversationService) detail(ctx context.Context, conv *model.GroupConversation) (*model.GroupConversationDetail, error) {
+	participants, err := s.store.ListParticipants(ctx, conv.ID)
+	if err != nil {
+		s.logger.Error("Failed to list group participants", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return &model.GroupConversationDetail{GroupConversation: *conv, Participants: participants}, nil
+}
+
+// checkInvitees verifies the users someone brings into a conversation
+// exist, are people rather than bots, and have no block with them
+func (s *GroupConversationService) checkInvitees(ctx context.Context, inviterID string, userIDs []string) error {
+	for _, id := range userIDs {
+		user, err := s.users.GetByID(ctx, id)
+		if err != nil {
+			if err == repository.ErrUserNotFound {
+				return apperrors.ErrUserNotFound
+			}
+			s.logger.Error("Failed to get group participant", zap.Error(err))
+			return apperrors.ErrInternal
+		}
+		if user.IsDeleted() {
+			return apperrors.ErrUserNotFound
+		}
+		if user.IsBot {
+			return ErrGroupBotParticipant
+		}
+
+		blocked, err := s.blocks.IsBlockedEither(ctx, inviterID, id)
+		if err != nil {
+			s.logger.Error("Failed to check block", zap.Error(err))
+			return apperrors.ErrInternal
+		}
+		if blocked {
+			return apperrors.ErrUserBlocked
+		}
+	}
+	return nil
+}
+
+func (s *GroupConversationService) publish(participants []*model.GroupParticipant, eventType string, payload interface{}) {
+	if s.notifier == nil {
+		return
+	}
+	for _, p := range participants {
+		s.notifier.PublishToUser(p.UserID, eventType, payload)
+	}
+}
+
+func hasGroupParticipant(participants []*model.GroupParticipant, userID string) bool {
+	for _, p := range participants {
+		if p.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
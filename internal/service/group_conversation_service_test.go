@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const testGroupID = "5d4c3b2a-1f0e-4d9c-8b7a-6f5e4d3c2b1a"
+
+func groupUsers() *mockUserLookup {
+	return &mockUserLookup{GetByIDFunc: func(ctx context.Context, id string) (*model.User, error) {
+		return &model.User{ID: id, Username: id}, nil
+	}}
+}
+
+// groupStore keeps the participants of one conversation in memory
+func groupStore(creatorID string, participantIDs ...string) *mockGroupConversationStore {
+	participants := func() []*model.GroupParticipant {
+		var list []*model.GroupParticipant
+		for _, id := range participantIDs {
+			list = append(list, &model.GroupParticipant{ConversationID: testGroupID, UserID: id, Username: id})
+		}
+		return list
+	}
+	return &mockGroupConversationStore{
+		CreateFunc: func(ctx context.Context, conv *model.GroupConversation, ids []string) error {
+			conv.ID = testGroupID
+			participantIDs = ids
+			return nil
+		},
+		GetByIDFunc: func(ctx context.Context, id string) (*model.GroupConversation, error) {
+			return &model.GroupConversation{ID: id, CreatedBy: sql.NullString{String: creatorID, Valid: true}}, nil
+		},
+		ListParticipantsFunc: func(ctx context.Context, id string) ([]*model.GroupParticipant, error) {
+			return participants(), nil
+		},
+		AddParticipantsFunc: func(ctx context.Context, id string, ids []string) error {
+			participantIDs = append(participantIDs, ids...)
+			return nil
+		},
+	}
+}
+
+func newTestGroupService(store GroupConversationStore, blocks BlockLookup) (*GroupConversationService, *fakeRealtimePublisher) {
+	publisher := &fakeRealtimePublisher{}
+	notifier := NewNotificationService(nil, zap.NewNop())
+	notifier.SetPublisher(publisher)
+
+	s := NewGroupConversationService(store, groupUsers(), blocks, zap.NewNop())
+	s.SetNotifier(notifier)
+	return s, publisher
+}
+
+func TestGroupConversationService_Create(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Creates with the creator and notifies everyone", func(t *testing.T) {
+		store := groupStore("alice")
+		s, publisher := newTestGroupService(store, &mockBlockLookup{})
+
+		detail, err := s.Create(ctx, &CreateGroupConversationInput{
+			CreatorID:      "alice",
+			Name:           "  trip  ",
+			ParticipantIDs: []string{"bob", "carol", "bob", "alice"},
+		})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if detail.Name.String != "trip" {
+			t.Errorf("Expected trimmed name, got %q", detail.Name.String)
+		}
+		if len(detail.Participants) != 3 {
+			t.Fatalf("Expected 3 participants, got %d", len(detail.Participants))
+		}
+		if len(publisher.userEvents) != 3 {
+			t.Fatalf("Expected 3 events, got %d", len(publisher.userEvents))
+		}
+		for _, e := range publisher.userEvents {
+			if e.eventType != GroupEventCreated {
+				t.Errorf("Expected %s, got %s", GroupEventCreated, e.eventType)
+			}
+		}
+	})
+
+	t.Run("Rejects too few or too many participants", func(t *testing.T) {
+		s, _ := newTestGroupService(groupStore("alice"), &mockBlockLookup{})
+
+		if _, err := s.Create(ctx, &CreateGroupConversationInput{
+			CreatorID:      "alice",
+			ParticipantIDs: []string{"bob", "bob"},
+		}); err != ErrGroupSize {
+			t.Errorf("Expected ErrGroupSize for 2 participants, got %v", err)
+		}
+
+		var many []string
+		for i := 0; i < MaxGroupParticipants; i++ {
+			many = append(many, fmt.Sprintf("user-%d", i))
+		}
+		if _, err := s.Create(ctx, &CreateGroupConversationInput{
+			CreatorID:      "alice",
+			ParticipantIDs: many,
+		}); err != ErrGroupSize {
+			t.Errorf("Expected ErrGroupSize for 11 participants, got %v", err)
+		}
+	})
+
+	t.Run("Rejects blocked users", func(t *testing.T) {
+		store := groupStore("alice")
+		blocks := &mockBlockLookup{IsBlockedEitherFunc: func(ctx context.Context, userID1, userID2 string) (bool, error) {
+			return userID2 == "carol", nil
+		}}
+		s, _ := newTestGroupService(store, blocks)
+
+		_, err := s.Create(ctx, &CreateGroupConversationInput{
+			CreatorID:      "alice",
+			ParticipantIDs: []string{"bob", "carol"},
+		})
+		if err != apperrors.ErrUserBlocked {
+			t.Errorf("Expected ErrUserBlocked, got %v", err)
+		}
+		if store.Calls("Create") != 0 {
+			t.Error("Expected no conversation to be created")
+		}
+	})
+}
+
+func TestGroupConversationService_SendMessage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Delivers to every participant", func(t *testing.T) {
+		s, publisher := newTestGroupService(groupStore("alice", "alice", "bob", "carol"), &mockBlockLookup{})
+
+		msg, err := s.SendMessage(ctx, &SendGroupMessageInput{
+			ConversationID: testGroupID,
+			SenderID:       "bob",
+			Content:        "hello",
+		})
+		if err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+		if msg.Type != model.MessageTypeText || msg.SenderUsername != "bob" {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+		if len(publisher.userEvents) != 3 {
+			t.Fatalf("Expected 3 events, got %d", len(publisher.userEvents))
+		}
+		for _, e := range publisher.userEvents {
+			if e.eventType != GroupEventMessage {
+				t.Errorf("Expected %s, got %s", GroupEventMessage, e.eventType)
+			}
+		}
+	})
+
+	t.Run("Refuses non-participants", func(t *testing.T) {
+		store := groupStore("alice", "alice", "bob", "carol")
+		s, publisher := newTestGroupService(store, &mockBlockLookup{})
+
+		_, err := s.SendMessage(ctx, &SendGroupMessageInput{
+			ConversationID: testGroupID,
+			SenderID:       "mallory",
+			Content:        "hello",
+		})
+		if err != ErrGroupConversationNotFound {
+			t.Errorf("Expected ErrGroupConversationNotFound, got %v", err)
+		}
+		if store.Calls("CreateMessage") != 0 || len(publisher.userEvents) != 0 {
+			t.Error("Expected nothing to be stored or published")
+		}
+	})
+}
+
+func TestGroupConversationService_Participants(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Any participant can add up to the limit", func(t *testing.T) {
+		s, publisher := newTestGroupService(groupStore("alice", "alice", "bob", "carol"), &mockBlockLookup{})
+
+		detail, err := s.AddParticipants(ctx, testGroupID, "bob", []string{"dave", "carol"})
+		if err != nil {
+			t.Fatalf("AddParticipants failed: %v", err)
+		}
+		if len(detail.Participants) != 4 {
+			t.Errorf("Expected 4 participants, got %d", len(detail.Participants))
+		}
+		event := publisher.userEvents[0].payload.(*GroupParticipantsEvent)
+		if len(event.UserIDs) != 1 || event.UserIDs[0] != "dave" {
+			t.Errorf("Expected only dave to be added, got %v", event.UserIDs)
+		}
+
+		var many []string
+		for i := 0; i < MaxGroupParticipants; i++ {
+			many = append(many, fmt.Sprintf("user-%d", i))
+		}
+		if _, err := s.AddParticipants(ctx, testGroupID, "bob", many); err != ErrGroupSize {
+			t.Errorf("Expected ErrGroupSize, got %v", err)
+		}
+	})
+
+	t.Run("Only the creator removes others", func(t *testing.T) {
+		store := groupStore("alice", "alice", "bob", "carol")
+		s, _ := newTestGroupService(store, &mockBlockLookup{})
+
+		if err := s.RemoveParticipant(ctx, testGroupID, "bob", "carol"); err != ErrGroupRemoveDenied {
+			t.Errorf("Expected ErrGroupRemoveDenied, got %v", err)
+		}
+		if err := s.RemoveParticipant(ctx, testGroupID, "bob", "bob"); err != nil {
+			t.Errorf("Expected bob to leave, got %v", err)
+		}
+		if err := s.RemoveParticipant(ctx, testGroupID, "alice", "carol"); err != nil {
+			t.Errorf("Expected alice to remove carol, got %v", err)
+		}
+		if err := s.RemoveParticipant(ctx, testGroupID, "alice", "mallory"); err != ErrGroupParticipantNotFound {
+			t.Errorf("Expected ErrGroupParticipantNotFound, got %v", err)
+		}
+		if store.Calls("RemoveParticipant") != 2 {
+			t.Errorf("Expected 2 removals, got %d", store.Calls("RemoveParticipant"))
+		}
+	})
+}
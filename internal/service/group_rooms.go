@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-demo/chat/internal/sso"
+	"go.uber.org/zap"
+)
+
+// DirectoryRooms adds and removes the room members group mapping manages.
+// It is implemented by RoomService.
+type DirectoryRooms interface {
+	AddDirectoryMember(ctx context.Context, roomID, userID string) (bool, error)
+	RemoveDirectoryMember(ctx context.Context, roomID, userID string) error
+}
+
+// GroupRooms applies the group-to-room mapping rules. Enterprise sign-in
+// and SCIM provisioning both sync through it, so they share one record of
+// the memberships the mapping granted.
+type GroupRooms struct {
+	rules  []sso.GroupRule
+	grants RoomGrantStore
+	rooms  DirectoryRooms
+	logger *zap.Logger
+}
+
+// NewGroupRooms creates a GroupRooms
+func NewGroupRooms(rules []sso.GroupRule, grants RoomGrantStore, rooms DirectoryRooms, logger *zap.Logger) *GroupRooms {
+	return &GroupRooms{
+		rules:  rules,
+		grants: grants,
+		rooms:  rooms,
+		logger: logger,
+	}
+}
+
+// Sync adds the user to the rooms their groups map to and removes them
+// from rooms the mapping added them to before but no longer does. Rooms
+// the user joined on their own are left alone. Failures are logged, they
+// do not fail the sign-in or provisioning request that triggered the sync.
+func (g *GroupRooms) Sync(ctx context.Context, userID string, groups []string) {
+	granted, err := g.grants.ListRoomGrants(ctx, userID)
+	if err != nil {
+		g.logger.Error("Failed to list sso room grants", zap.Error(err))
+		return
+	}
+	want := sso.MapRooms(g.rules, groups)
+
+	wanted := make(map[string]bool, len(want))
+	for _, roomID := range want {
+		wanted[roomID] = true
+	}
+	had := make(map[string]bool, len(granted))
+	for _, roomID := range granted {
+		had[roomID] = true
+	}
+
+	for _, roomID := range want {
+		if had[roomID] {
+			continue
+		}
+		added, err := g.rooms.AddDirectoryMember(ctx, roomID, userID)
+		if err != nil {
+			g.logger.Warn("Failed to add user to mapped room", zap.String("room_id", roomID), zap.Error(err))
+			continue
+		}
+		if !added {
+			continue
+		}
+		if err := g.grants.AddRoomGrant(ctx, userID, roomID); err != nil {
+			g.logger.Error("Failed to record sso room grant", zap.Error(err))
+		}
+	}
+
+	for _, roomID := range granted {
+		if wanted[roomID] {
+			continue
+		}
+		if err := g.rooms.RemoveDirectoryMember(ctx, roomID, userID); err != nil {
+			g.logger.Warn("Failed to remove user from unmapped room", zap.String("room_id", roomID), zap.Error(err))
+			continue
+		}
+		if err := g.grants.DeleteRoomGrant(ctx, userID, roomID); err != nil {
+			g.logger.Error("Failed to delete sso room grant", zap.Error(err))
+		}
+	}
+}
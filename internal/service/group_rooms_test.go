@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/sso"
+	"go.uber.org/zap"
+)
+
+// fakeDirectoryRooms tracks the memberships GroupRooms manages
+type fakeDirectoryRooms struct {
+	mu      sync.Mutex
+	members map[string]bool // room IDs the user is already in
+	missing map[string]bool // room IDs that do not exist
+	added   []string
+	removed []string
+}
+
+func (f *fakeDirectoryRooms) AddDirectoryMember(ctx context.Context, roomID, userID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.missing[roomID] {
+		return false, apperrors.ErrRoomNotFound
+	}
+	if f.members[roomID] {
+		return false, nil
+	}
+	f.added = append(f.added, roomID)
+	return true, nil
+}
+
+func (f *fakeDirectoryRooms) RemoveDirectoryMember(ctx context.Context, roomID, userID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = append(f.removed, roomID)
+	return nil
+}
+
+func TestGroupRooms_Sync(t *testing.T) {
+	store := &mockSSOStore{
+		ListRoomGrantsFunc: func(ctx context.Context, userID string) ([]string, error) {
+			return []string{"room-ops", "room-old", "room-eng"}, nil
+		},
+	}
+	var grantsAdded, grantsDeleted []string
+	store.AddRoomGrantFunc = func(ctx context.Context, userID, roomID string) error {
+		grantsAdded = append(grantsAdded, roomID)
+		return nil
+	}
+	store.DeleteRoomGrantFunc = func(ctx context.Context, userID, roomID string) error {
+		grantsDeleted = append(grantsDeleted, roomID)
+		return nil
+	}
+	rooms := &fakeDirectoryRooms{
+		members: map[string]bool{"room-eng": true, "room-general": true},
+		missing: map[string]bool{"room-gone": true},
+	}
+
+	groupRooms := NewGroupRooms([]sso.GroupRule{
+		{Group: "engineering", RoomIDs: []string{"room-eng", "room-general", "room-new", "room-gone"}},
+		{Group: "ops", RoomIDs: []string{"room-ops"}},
+	}, store, rooms, zap.NewNop())
+
+	groupRooms.Sync(context.Background(), "user-1", []string{"cn=Engineering,ou=groups,dc=example,dc=com"})
+
+	// room-general was joined by hand, so it stays ungranted; room-gone
+	// does not exist
+	if !reflect.DeepEqual(rooms.added, []string{"room-new"}) || !reflect.DeepEqual(grantsAdded, []string{"room-new"}) {
+		t.Errorf("Expected only room-new to be added and granted, got %v and %v", rooms.added, grantsAdded)
+	}
+	if !reflect.DeepEqual(rooms.removed, []string{"room-ops", "room-old"}) || !reflect.DeepEqual(grantsDeleted, []string{"room-ops", "room-old"}) {
+		t.Errorf("Expected the unmapped grants to be removed, got %v and %v", rooms.removed, grantsDeleted)
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/moderation"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ImageLabelScanFailed marks images the detector could not score. They are
+// blurred and queued for review rather than let through unchecked.
+const ImageLabelScanFailed = "scan_failed"
+
+var (
+	ErrImageBlocked       = apperrors.New(http.StatusUnprocessableEntity, "圖片違反內容規範，無法上傳")
+	ErrImageAssetNotFound = apperrors.New(http.StatusNotFound, "圖片不存在")
+)
+
+// ImageModerationService scans uploaded images and lets admins tune the
+// thresholds and work through the images left for review. Images scoring
+// at or above the block threshold are removed; those between the NSFW and
+// block thresholds are kept, blurred and queued.
+type ImageModerationService struct {
+	repo     *repository.ImageModerationRepository
+	detector moderation.Detector
+	auditor  *AuditService
+	logger   *zap.Logger
+}
+
+func NewImageModerationService(repo *repository.ImageModerationRepository, detector moderation.Detector, logger *zap.Logger) *ImageModerationService {
+	return &ImageModerationService{
+		repo:     repo,
+		detector: detector,
+		logger:   logger,
+	}
+}
+
+// SetAuditor sets the audit service that records reviews and settings changes
+func (s *ImageModerationService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// ScanImageInput describes an image that was just stored
+type ScanImageInput struct {
+	UserID      string
+	FileURL     string
+	FilePath    string
+	ContentType string
+}
+
+// Scan classifies a stored image and records the verdict. It returns nil
+// when moderation is switched off, and ErrImageBlocked, after removing the
+// file, when the image must not be shown at all.
+func (s *ImageModerationService) Scan(ctx context.Context, input *ScanImageInput) (*model.ImageAsset, error) {
+	settings, err := s.repo.GetSettings(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get image moderation settings", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if !settings.Enabled {
+		return nil, nil
+	}
+
+	asset := &model.ImageAsset{
+		UserID:       nullString(input.UserID),
+		FileURL:      input.FileURL,
+		FilePath:     input.FilePath,
+		ContentType:  input.ContentType,
+		ReviewStatus: model.ImageReviewNone,
+	}
+
+	result, err := s.detect(ctx, input)
+	if err != nil {
+		if ctxErr := checkContext(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		s.logger.Warn("Image detection failed, queueing for review",
+			zap.String("file_url", input.FileURL),
+			zap.Error(err),
+		)
+		asset.Verdict = model.ImageVerdictNSFW
+		asset.ReviewStatus = model.ImageReviewPending
+		asset.Labels = ImageLabelScanFailed
+	} else {
+		asset.Score = sql.NullFloat64{Float64: result.Score, Valid: true}
+		asset.Labels = joinLabels(result.Labels)
+		asset.Verdict = settings.Verdict(result.Score)
+		if asset.Verdict == model.ImageVerdictNSFW {
+			asset.ReviewStatus = model.ImageReviewPending
+		}
+	}
+
+	if err := s.repo.CreateAsset(ctx, asset); err != nil {
+		s.logger.Error("Failed to record image asset", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	if asset.Verdict == model.ImageVerdictBlocked {
+		s.removeFile(asset)
+		s.logger.Info("Uploaded image blocked",
+			zap.String("asset_id", asset.ID),
+			zap.String("user_id", input.UserID),
+			zap.String("labels", asset.Labels),
+		)
+		return asset, ErrImageBlocked
+	}
+	return asset, nil
+}
+
+func (s *ImageModerationService) detect(ctx context.Context, input *ScanImageInput) (*moderation.Result, error) {
+	f, err := os.Open(input.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return s.detector.Detect(ctx, &moderation.Image{
+		ContentType: input.ContentType,
+		Body:        f,
+	})
+}
+
+// joinLabels stores labels comma separated, so commas inside a label are
+// dropped
+func joinLabels(labels []string) string {
+	cleaned := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l = strings.TrimSpace(strings.ReplaceAll(l, ",", " ")); l != "" {
+			cleaned = append(cleaned, l)
+		}
+	}
+	return strings.Join(cleaned, ",")
+}
+
+func (s *ImageModerationService) removeFile(asset *model.ImageAsset) {
+	if err := os.Remove(asset.FilePath); err != nil && !os.IsNotExist(err) {
+		s.logger.Warn("Failed to remove blocked image", zap.String("asset_id", asset.ID), zap.Error(err))
+	}
+}
+
+// GetSettings returns the moderation settings
+func (s *ImageModerationService) GetSettings(ctx context.Context) (*model.ImageModerationSettings, error) {
+	settings, err := s.repo.GetSettings(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get image moderation settings", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return settings, nil
+}
+
+// UpdateImageModerationInput changes the moderation settings; nil fields
+// are left as they are
+type UpdateImageModerationInput struct {
+	Enabled        *bool
+	NSFWThreshold  *float64
+	BlockThreshold *float64
+}
+
+// UpdateSettings changes the moderation settings. Thresholds must satisfy
+// 0 < nsfw threshold <= block threshold <= 1.
+func (s *ImageModerationService) UpdateSettings(ctx context.Context, input *UpdateImageModerationInput, adminID string) (*model.ImageModerationSettings, error) {
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Enabled != nil {
+		settings.Enabled = *input.Enabled
+	}
+	if input.NSFWThreshold != nil {
+		settings.NSFWThreshold = *input.NSFWThreshold
+	}
+	if input.BlockThreshold != nil {
+		settings.BlockThreshold = *input.BlockThreshold
+	}
+	if settings.NSFWThreshold <= 0 || settings.NSFWThreshold > settings.BlockThreshold || settings.BlockThreshold > 1 {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"nsfw_threshold": "門檻需符合 0 < nsfw_threshold <= block_threshold <= 1",
+		})
+	}
+	settings.UpdatedBy = nullString(adminID)
+
+	if err := s.repo.UpdateSettings(ctx, settings); err != nil {
+		s.logger.Error("Failed to update image moderation settings", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    adminID,
+		Action:     model.AuditActionImageModerationUpdated,
+		TargetType: model.AuditTargetImage,
+		Metadata: map[string]interface{}{
+			"enabled":         settings.Enabled,
+			"nsfw_threshold":  settings.NSFWThreshold,
+			"block_threshold": settings.BlockThreshold,
+		},
+	})
+	return settings, nil
+}
+
+// ListQueue lists images waiting for review, oldest first
+func (s *ImageModerationService) ListQueue(ctx context.Context, limit, offset int) ([]*model.ImageAsset, error) {
+	assets, err := s.repo.ListPending(ctx, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list image review queue", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return assets, nil
+}
+
+// Review settles an image's verdict. Messages showing it are blurred or
+// unblurred to match, and a blocked image's file is removed.
+func (s *ImageModerationService) Review(ctx context.Context, id string, verdict model.ImageVerdict, note, adminID string) (*model.ImageAsset, error) {
+	if !verdict.IsValid() {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"verdict": "必須為 clean、nsfw 或 blocked",
+		})
+	}
+
+	asset, updated, err := s.repo.Review(ctx, id, verdict, nullString(strings.TrimSpace(note)), adminID)
+	if err != nil {
+		if err == repository.ErrImageAssetNotFound {
+			return nil, ErrImageAssetNotFound
+		}
+		s.logger.Error("Failed to review image", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	if verdict == model.ImageVerdictBlocked {
+		s.removeFile(asset)
+	}
+
+	s.logger.Info("Image reviewed",
+		zap.String("asset_id", id),
+		zap.String("verdict", string(verdict)),
+		zap.String("reviewed_by", adminID),
+		zap.Int64("messages_updated", updated),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    adminID,
+		Action:     model.AuditActionImageReviewed,
+		TargetType: model.AuditTargetImage,
+		TargetID:   id,
+		Metadata: map[string]interface{}{
+			"verdict":          string(verdict),
+			"file_url":         asset.FileURL,
+			"messages_updated": updated,
+		},
+	})
+	return asset, nil
+}
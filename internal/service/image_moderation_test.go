@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/moderation"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type failingDetector struct{}
+
+func (failingDetector) Detect(ctx context.Context, img *moderation.Image) (*moderation.Result, error) {
+	return nil, errors.New("detection api unavailable")
+}
+
+func TestImageModerationSettings_Verdict(t *testing.T) {
+	settings := &model.ImageModerationSettings{NSFWThreshold: 0.6, BlockThreshold: 0.9}
+
+	tests := []struct {
+		score float64
+		want  model.ImageVerdict
+	}{
+		{0, model.ImageVerdictClean},
+		{0.59, model.ImageVerdictClean},
+		{0.6, model.ImageVerdictNSFW},
+		{0.89, model.ImageVerdictNSFW},
+		{0.9, model.ImageVerdictBlocked},
+		{1, model.ImageVerdictBlocked},
+	}
+	for _, tt := range tests {
+		if got := settings.Verdict(tt.score); got != tt.want {
+			t.Errorf("Verdict(%v) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestJoinLabels(t *testing.T) {
+	if got := joinLabels([]string{"nudity", " ", "gore, blood"}); got != "nudity,gore  blood" {
+		t.Errorf("Unexpected labels %q", got)
+	}
+	if got := joinLabels(nil); got != "" {
+		t.Errorf("Expected no labels, got %q", got)
+	}
+}
+
+func setupTestImageModerationServiceIsolated(t *testing.T, detector moderation.Detector) (*ImageModerationService, *sqlx.DB, string) {
+	t.Helper()
+
+	db, prefix := repository.SetupIsolatedTestDB(t)
+	svc := NewImageModerationService(repository.NewImageModerationRepository(db), detector, zap.NewNop())
+
+	// Start every test from the default thresholds
+	if _, err := db.Exec(`UPDATE image_moderation_settings SET enabled = TRUE, nsfw_threshold = 0.6, block_threshold = 0.9 WHERE id = 1`); err != nil {
+		t.Fatalf("Failed to reset image moderation settings: %v", err)
+	}
+	return svc, db, prefix
+}
+
+// writeTestImage stores a fake image and returns its path and a unique URL
+func writeTestImage(t *testing.T, prefix, name string) (string, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name+".png")
+	if err := os.WriteFile(path, []byte("png-bytes"), 0644); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	return path, "http://localhost/uploads/images/" + prefix + "_" + name + ".png"
+}
+
+func cleanupImageAssets(db *sqlx.DB, prefix string) {
+	_, _ = db.Exec(`DELETE FROM image_assets WHERE file_url LIKE $1`, "%/"+prefix+"_%")
+}
+
+func TestImageModerationService_Scan(t *testing.T) {
+	detector := &moderation.StubDetector{}
+	svc, db, prefix := setupTestImageModerationServiceIsolated(t, detector)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+	defer cleanupImageAssets(db, prefix)
+
+	ctx := context.Background()
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "uploader")
+
+	path, url := writeTestImage(t, prefix, "clean")
+	asset, err := svc.Scan(ctx, &ScanImageInput{UserID: user.ID, FileURL: url, FilePath: path, ContentType: "image/png"})
+	if err != nil {
+		t.Fatalf("Failed to scan clean image: %v", err)
+	}
+	if asset.Verdict != model.ImageVerdictClean || asset.ReviewStatus != model.ImageReviewNone {
+		t.Errorf("Expected a clean, unqueued image, got %s/%s", asset.Verdict, asset.ReviewStatus)
+	}
+
+	detector.Result = moderation.Result{Score: 0.7, Labels: []string{"suggestive"}}
+	path, url = writeTestImage(t, prefix, "borderline")
+	asset, err = svc.Scan(ctx, &ScanImageInput{UserID: user.ID, FileURL: url, FilePath: path, ContentType: "image/png"})
+	if err != nil {
+		t.Fatalf("Failed to scan borderline image: %v", err)
+	}
+	if !asset.IsNSFW() || asset.ReviewStatus != model.ImageReviewPending {
+		t.Errorf("Expected a blurred, queued image, got %s/%s", asset.Verdict, asset.ReviewStatus)
+	}
+
+	detector.Result = moderation.Result{Score: 0.95, Labels: []string{"nudity"}}
+	path, url = writeTestImage(t, prefix, "explicit")
+	if _, err := svc.Scan(ctx, &ScanImageInput{UserID: user.ID, FileURL: url, FilePath: path, ContentType: "image/png"}); !apperrors.Is(err, ErrImageBlocked) {
+		t.Fatalf("Expected ErrImageBlocked, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the blocked image to be removed")
+	}
+
+	// Switched off, nothing is scanned or recorded
+	disabled := false
+	if _, err := svc.UpdateSettings(ctx, &UpdateImageModerationInput{Enabled: &disabled}, user.ID); err != nil {
+		t.Fatalf("Failed to disable moderation: %v", err)
+	}
+	path, url = writeTestImage(t, prefix, "unscanned")
+	if asset, err := svc.Scan(ctx, &ScanImageInput{UserID: user.ID, FileURL: url, FilePath: path, ContentType: "image/png"}); err != nil || asset != nil {
+		t.Errorf("Expected no scan while disabled, got %+v, %v", asset, err)
+	}
+}
+
+func TestImageModerationService_ScanQueuesDetectorFailures(t *testing.T) {
+	svc, db, prefix := setupTestImageModerationServiceIsolated(t, failingDetector{})
+	defer db.Close()
+	defer cleanupImageAssets(db, prefix)
+
+	path, url := writeTestImage(t, prefix, "unknown")
+	asset, err := svc.Scan(context.Background(), &ScanImageInput{FileURL: url, FilePath: path, ContentType: "image/png"})
+	if err != nil {
+		t.Fatalf("Expected the upload to go through, got %v", err)
+	}
+	if !asset.IsNSFW() || asset.ReviewStatus != model.ImageReviewPending || asset.Labels != ImageLabelScanFailed {
+		t.Errorf("Expected an unscored image to be blurred and queued, got %+v", asset)
+	}
+}
+
+func TestImageModerationService_UpdateSettingsValidates(t *testing.T) {
+	svc, db, prefix := setupTestImageModerationServiceIsolated(t, &moderation.StubDetector{})
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	admin := repository.CreateIsolatedTestUser(t, db, prefix, "admin")
+	high, low := 0.8, 0.5
+	if _, err := svc.UpdateSettings(context.Background(), &UpdateImageModerationInput{NSFWThreshold: &high, BlockThreshold: &low}, admin.ID); !apperrors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected inverted thresholds to be rejected, got %v", err)
+	}
+
+	settings, err := svc.UpdateSettings(context.Background(), &UpdateImageModerationInput{NSFWThreshold: &low}, admin.ID)
+	if err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	if settings.NSFWThreshold != low || settings.BlockThreshold != 0.9 || settings.UpdatedBy.String != admin.ID {
+		t.Errorf("Unexpected settings %+v", settings)
+	}
+}
+
+func TestImageModerationService_Review(t *testing.T) {
+	detector := &moderation.StubDetector{Result: moderation.Result{Score: 0.7}}
+	svc, db, prefix := setupTestImageModerationServiceIsolated(t, detector)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+	defer cleanupImageAssets(db, prefix)
+
+	ctx := context.Background()
+	owner := repository.CreateIsolatedTestUser(t, db, prefix, "owner")
+	room := repository.CreateIsolatedTestRoom(t, db, prefix, owner)
+
+	path, url := writeTestImage(t, prefix, "photo")
+	asset, err := svc.Scan(ctx, &ScanImageInput{UserID: owner.ID, FileURL: url, FilePath: path, ContentType: "image/png"})
+	if err != nil {
+		t.Fatalf("Failed to scan image: %v", err)
+	}
+
+	messages := repository.NewMessageRepository(db)
+	msg := &model.Message{RoomID: room.ID, UserID: owner.ID, Content: url, Type: model.MessageTypeImage}
+	if err := messages.Create(ctx, msg); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if stored, _ := messages.GetByID(ctx, msg.ID); stored == nil || !stored.IsNSFW {
+		t.Fatal("Expected a message showing a flagged image to be blurred")
+	}
+
+	queue, err := svc.ListQueue(ctx, 1000, 0)
+	if err != nil {
+		t.Fatalf("Failed to list queue: %v", err)
+	}
+	queued := false
+	for _, a := range queue {
+		queued = queued || a.ID == asset.ID
+	}
+	if !queued {
+		t.Error("Expected the flagged image in the review queue")
+	}
+
+	reviewed, err := svc.Review(ctx, asset.ID, model.ImageVerdictClean, "harmless", owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to review image: %v", err)
+	}
+	if reviewed.ReviewStatus != model.ImageReviewReviewed || reviewed.ReviewNote.String != "harmless" {
+		t.Errorf("Unexpected review %+v", reviewed)
+	}
+	if stored, _ := messages.GetByID(ctx, msg.ID); stored == nil || stored.IsNSFW {
+		t.Error("Expected the message to be unblurred after review")
+	}
+
+	if _, err := svc.Review(ctx, asset.ID, "maybe", "", owner.ID); !apperrors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected unknown verdict to be rejected, got %v", err)
+	}
+}
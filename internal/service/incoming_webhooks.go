@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxIncomingWebhooks is how many incoming webhooks a room can have
+	MaxIncomingWebhooks = 10
+
+	// IncomingWebhookPathPrefix is where incoming webhook tokens are posted to
+	IncomingWebhookPathPrefix = "/api/v1/webhooks/incoming/"
+
+	maxBotNameLength = 100
+)
+
+var (
+	ErrIncomingWebhookNotFound = apperrors.New(http.StatusNotFound, "Webhook 不存在")
+	ErrTooManyIncomingWebhooks = apperrors.New(http.StatusConflict, "此聊天室的傳入 Webhook 數量已達上限")
+)
+
+// SetIncomingWebhookRepository enables managing incoming webhooks
+func (s *RoomService) SetIncomingWebhookRepository(repo *repository.IncomingWebhookRepository) {
+	s.incomingRepo = repo
+}
+
+// SetIncomingWebhookRepository enables posting through incoming webhooks
+func (s *MessageService) SetIncomingWebhookRepository(repo *repository.IncomingWebhookRepository) {
+	s.incomingRepo = repo
+}
+
+// IncomingWebhookInput describes an incoming webhook's bot identity
+type IncomingWebhookInput struct {
+	RoomID    string
+	UserID    string
+	Name      string
+	AvatarURL string
+}
+
+// CreatedIncomingWebhook carries the token of a new webhook, which is not
+// shown again
+type CreatedIncomingWebhook struct {
+	*model.IncomingWebhookWithBot
+	Token string
+}
+
+// CreateIncomingWebhook creates a webhook and its bot user on behalf of a
+// member allowed to manage the room
+func (s *RoomService) CreateIncomingWebhook(ctx context.Context, input *IncomingWebhookInput) (*CreatedIncomingWebhook, error) {
+	room, err := s.loadIncomingWebhookRoom(ctx, input.RoomID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBotIdentity(input); err != nil {
+		return nil, err
+	}
+
+	count, err := s.incomingRepo.CountByRoomID(ctx, room.ID)
+	if err != nil {
+		s.logger.Error("Failed to count incoming webhooks", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if count >= MaxIncomingWebhooks {
+		return nil, ErrTooManyIncomingWebhooks
+	}
+
+	token, err := generateWebhookSecret()
+	if err != nil {
+		s.logger.Error("Failed to generate webhook token", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	suffix, err := generateBotSuffix()
+	if err != nil {
+		s.logger.Error("Failed to generate bot username", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	bot := &model.User{
+		Username:    "bot-" + suffix,
+		Email:       "bot-" + suffix + "@bots.invalid",
+		DisplayName: nullString(input.Name),
+		AvatarURL:   nullString(input.AvatarURL),
+	}
+	webhook := &model.IncomingWebhook{
+		RoomID:    room.ID,
+		TokenHash: hashIncomingWebhookToken(token),
+		CreatedBy: nullString(input.UserID),
+	}
+	if err := s.incomingRepo.Create(ctx, webhook, bot); err != nil {
+		s.logger.Error("Failed to create incoming webhook", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Incoming webhook created",
+		zap.String("room_id", room.ID),
+		zap.String("webhook_id", webhook.ID),
+		zap.String("bot_user_id", bot.ID),
+		zap.String("created_by", input.UserID),
+	)
+	return &CreatedIncomingWebhook{
+		IncomingWebhookWithBot: &model.IncomingWebhookWithBot{
+			IncomingWebhook: *webhook,
+			BotUsername:     bot.Username,
+			BotDisplayName:  bot.DisplayName,
+			BotAvatarURL:    bot.AvatarURL,
+		},
+		Token: token,
+	}, nil
+}
+
+// ListIncomingWebhooks lists a room's incoming webhooks
+func (s *RoomService) ListIncomingWebhooks(ctx context.Context, roomID, userID string) ([]*model.IncomingWebhookWithBot, error) {
+	if _, err := s.loadIncomingWebhookRoom(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.incomingRepo.ListByRoomID(ctx, roomID)
+	if err != nil {
+		s.logger.Error("Failed to list incoming webhooks", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return webhooks, nil
+}
+
+// UpdateIncomingWebhook changes the name and avatar the webhook posts with
+func (s *RoomService) UpdateIncomingWebhook(ctx context.Context, webhookID string, input *IncomingWebhookInput) (*model.IncomingWebhookWithBot, error) {
+	if _, err := s.loadIncomingWebhookRoom(ctx, input.RoomID, input.UserID); err != nil {
+		return nil, err
+	}
+	if err := validateBotIdentity(input); err != nil {
+		return nil, err
+	}
+
+	if err := s.incomingRepo.UpdateBot(ctx, input.RoomID, webhookID, nullString(input.Name), nullString(input.AvatarURL)); err != nil {
+		if err == repository.ErrIncomingWebhookNotFound {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		s.logger.Error("Failed to update incoming webhook", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	webhook, err := s.incomingRepo.GetByID(ctx, input.RoomID, webhookID)
+	if err != nil {
+		if err == repository.ErrIncomingWebhookNotFound {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		s.logger.Error("Failed to get incoming webhook", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return webhook, nil
+}
+
+// DeleteIncomingWebhook revokes a webhook's token. Messages its bot already
+// posted stay in the room.
+func (s *RoomService) DeleteIncomingWebhook(ctx context.Context, roomID, webhookID, userID string) error {
+	if _, err := s.loadIncomingWebhookRoom(ctx, roomID, userID); err != nil {
+		return err
+	}
+
+	if err := s.incomingRepo.Delete(ctx, roomID, webhookID); err != nil {
+		if err == repository.ErrIncomingWebhookNotFound {
+			return ErrIncomingWebhookNotFound
+		}
+		s.logger.Error("Failed to delete incoming webhook", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("Incoming webhook deleted",
+		zap.String("room_id", roomID),
+		zap.String("webhook_id", webhookID),
+		zap.String("deleted_by", userID),
+	)
+	return nil
+}
+
+// loadIncomingWebhookRoom gets the room and checks the user may manage it
+func (s *RoomService) loadIncomingWebhookRoom(ctx context.Context, roomID, userID string) (*model.Room, error) {
+	if s.incomingRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	return s.loadManagedRoom(ctx, roomID, userID)
+}
+
+// PostIncomingWebhook posts a text message as the bot of the webhook the
+// token belongs to. The bot does not need to be a room member; holding the
+// token is the authorization.
+func (s *MessageService) PostIncomingWebhook(ctx context.Context, token, content string) (*model.MessageWithUser, error) {
+	if s.incomingRepo == nil || token == "" {
+		return nil, ErrIncomingWebhookNotFound
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{"content": "不可為空"})
+	}
+
+	webhook, err := s.incomingRepo.GetByTokenHash(ctx, hashIncomingWebhookToken(token))
+	if err != nil {
+		if err == repository.ErrIncomingWebhookNotFound {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		s.logger.Error("Failed to get incoming webhook", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	msg := &model.Message{
+		RoomID:  webhook.RoomID,
+		UserID:  webhook.BotUserID,
+		Content: content,
+		Type:    model.MessageTypeText,
+	}
+	if err := s.messageRepo.Create(ctx, msg); err != nil {
+		s.logger.Error("Failed to create message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	msgWithUser, err := s.messageRepo.GetByIDWithUser(ctx, msg.ID)
+	if err != nil {
+		s.logger.Error("Failed to get message with user", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	if err := s.incomingRepo.TouchLastUsed(ctx, webhook.ID); err != nil {
+		s.logger.Warn("Failed to update incoming webhook", zap.String("webhook_id", webhook.ID), zap.Error(err))
+	}
+
+	s.notifyRecipients(ctx, msgWithUser)
+	s.emitMessageCreated(ctx, msgWithUser)
+	s.previews.Enqueue(&msgWithUser.Message)
+
+	return msgWithUser, nil
+}
+
+func validateBotIdentity(input *IncomingWebhookInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+
+	details := map[string]string{}
+	if input.Name == "" || utf8.RuneCountInString(input.Name) > maxBotNameLength {
+		details["name"] = "長度需介於 1 至 100 字"
+	}
+	if input.AvatarURL != "" && (len(input.AvatarURL) > 500 || !isWebhookURL(input.AvatarURL)) {
+		details["avatar_url"] = "必須為 http 或 https 網址"
+	}
+	if len(details) > 0 {
+		return apperrors.ErrValidation.WithDetails(details)
+	}
+	return nil
+}
+
+// hashIncomingWebhookToken stores tokens by hash so the table never holds
+// usable values
+func hashIncomingWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateBotSuffix() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
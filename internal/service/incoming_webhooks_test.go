@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+func TestValidateBotIdentity(t *testing.T) {
+	tests := []struct {
+		name  string
+		input IncomingWebhookInput
+		valid bool
+	}{
+		{"name only", IncomingWebhookInput{Name: "CI"}, true},
+		{"with avatar", IncomingWebhookInput{Name: "CI", AvatarURL: "https://example.com/ci.png"}, true},
+		{"blank name", IncomingWebhookInput{Name: "   "}, false},
+		{"long name", IncomingWebhookInput{Name: strings.Repeat("機", 101)}, false},
+		{"bad avatar", IncomingWebhookInput{Name: "CI", AvatarURL: "javascript:alert(1)"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBotIdentity(&tt.input)
+			if (err == nil) != tt.valid {
+				t.Errorf("validateBotIdentity() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestHashIncomingWebhookToken(t *testing.T) {
+	hash := hashIncomingWebhookToken("token")
+	if len(hash) != 64 || hash != hashIncomingWebhookToken("token") {
+		t.Errorf("Expected a stable hex SHA-256, got %q", hash)
+	}
+	if hash == hashIncomingWebhookToken("other") {
+		t.Error("Expected different tokens to hash differently")
+	}
+}
+
+func TestIncomingWebhooks(t *testing.T) {
+	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
+	defer db.Close()
+	defer cleanupMessageServiceTestByPrefix(t, db, prefix)
+
+	repo := repository.NewIncomingWebhookRepository(db)
+	roomService.SetIncomingWebhookRepository(repo)
+	msgService.SetIncomingWebhookRepository(repo)
+
+	ctx := context.Background()
+	owner := createUserForMessageServiceTestIsolated(t, db, prefix, "owner")
+	member := createUserForMessageServiceTestIsolated(t, db, prefix, "member")
+	room := createRoomForMessageServiceTestIsolated(t, db, prefix, owner, roomService)
+	if err := roomService.Join(ctx, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to join: %v", err)
+	}
+
+	if _, err := roomService.CreateIncomingWebhook(ctx, &IncomingWebhookInput{
+		RoomID: room.ID, UserID: member.ID, Name: "CI",
+	}); !apperrors.Is(err, apperrors.ErrPermissionDenied) {
+		t.Errorf("Expected members without manage permission to be denied, got %v", err)
+	}
+
+	created, err := roomService.CreateIncomingWebhook(ctx, &IncomingWebhookInput{
+		RoomID: room.ID, UserID: owner.ID, Name: "CI", AvatarURL: "https://example.com/ci.png",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create incoming webhook: %v", err)
+	}
+	defer db.Exec(`DELETE FROM users WHERE id = $1`, created.BotUserID)
+
+	msg, err := msgService.PostIncomingWebhook(ctx, created.Token, "Build passed")
+	if err != nil {
+		t.Fatalf("Failed to post through webhook: %v", err)
+	}
+	if msg.RoomID != room.ID || msg.UserID != created.BotUserID || !msg.IsBot || msg.GetUserDisplayName() != "CI" {
+		t.Errorf("Expected the message to come from the bot, got %+v", msg)
+	}
+
+	if _, err := msgService.PostIncomingWebhook(ctx, "wrong-token", "Build passed"); !apperrors.Is(err, ErrIncomingWebhookNotFound) {
+		t.Errorf("Expected unknown tokens to be rejected, got %v", err)
+	}
+
+	updated, err := roomService.UpdateIncomingWebhook(ctx, created.ID, &IncomingWebhookInput{
+		RoomID: room.ID, UserID: owner.ID, Name: "Deploy bot",
+	})
+	if err != nil {
+		t.Fatalf("Failed to update incoming webhook: %v", err)
+	}
+	if updated.GetBotDisplayName() != "Deploy bot" || updated.BotAvatarURL.Valid {
+		t.Errorf("Expected the new identity, got %+v", updated)
+	}
+
+	webhooks, err := roomService.ListIncomingWebhooks(ctx, room.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to list incoming webhooks: %v", err)
+	}
+	if len(webhooks) != 1 || !webhooks[0].LastUsedAt.Valid {
+		t.Errorf("Expected one used webhook, got %+v", webhooks)
+	}
+
+	if err := roomService.DeleteIncomingWebhook(ctx, room.ID, created.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to delete incoming webhook: %v", err)
+	}
+	if _, err := msgService.PostIncomingWebhook(ctx, created.Token, "Build passed"); !apperrors.Is(err, ErrIncomingWebhookNotFound) {
+		t.Errorf("Expected the token to be revoked, got %v", err)
+	}
+	if _, err := msgService.GetByID(ctx, msg.ID); err != nil {
+		t.Errorf("Expected the bot's message to be kept, got %v", err)
+	}
+}
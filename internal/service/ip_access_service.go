@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-demo/chat/internal/ipfilter"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrIPAccessRuleNotFound = apperrors.New(http.StatusNotFound, "IP 存取規則不存在")
+	ErrGeoIPNotConfigured   = apperrors.New(http.StatusUnprocessableEntity, "尚未設定 GeoIP 資料庫或國家標頭，無法使用國家規則")
+	ErrIPRuleLocksOut       = apperrors.New(http.StatusUnprocessableEntity, "此變更會封鎖您目前的連線")
+)
+
+// IPAccessControl holds the access rules in effect on every instance.
+// It is implemented by ipfilter.AccessList.
+type IPAccessControl interface {
+	Static() []*model.IPAccessRule
+	GeoEnabled() bool
+	Country(ip string, header http.Header) string
+	Check(scope, ip, country string) *ipfilter.Denial
+	Preview(add *model.IPAccessRule, removeID, scope, ip, country string) *ipfilter.Denial
+	NotifyChanged(ctx context.Context) error
+}
+
+// IPAccessService manages the IP and country allow and deny rules of each
+// scope at runtime
+type IPAccessService struct {
+	store   IPAccessRuleStore
+	access  IPAccessControl
+	auditor *AuditService
+	logger  *zap.Logger
+}
+
+func NewIPAccessService(store IPAccessRuleStore, access IPAccessControl, logger *zap.Logger) *IPAccessService {
+	return &IPAccessService{
+		store:  store,
+		access: access,
+		logger: logger,
+	}
+}
+
+// SetAuditor sets the audit service that records rule changes
+func (s *IPAccessService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// IPAccessRuleInput represents IP access rule input
+type IPAccessRuleInput struct {
+	Scope        string
+	Action       model.IPAccessAction
+	Target       string // IP address, CIDR range or country code
+	Note         string
+	CreatedBy    string
+	ActorIP      string // the admin's own address and country, which
+	ActorCountry string // the rule may not lock out
+}
+
+// lockedOut reports whether the admin API would refuse the actor after
+// adding add or removing removeID. Admin requests pass both the global and
+// the admin scope.
+func (s *IPAccessService) lockedOut(add *model.IPAccessRule, removeID, ip, country string) bool {
+	if net.ParseIP(ip) == nil {
+		return false
+	}
+	for _, scope := range []string{model.IPAccessScopeGlobal, model.IPAccessScopeAdmin} {
+		if s.access.Check(scope, ip, country) == nil && s.access.Preview(add, removeID, scope, ip, country) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Create adds a rule and applies it on every instance
+func (s *IPAccessService) Create(ctx context.Context, input *IPAccessRuleInput) (*model.IPAccessRule, error) {
+	rule, err := ipfilter.NewRule(input.Scope, input.Action, input.Target)
+	if err != nil {
+		return nil, apperrors.New(http.StatusBadRequest, "無效的範圍、動作或 IP 網段、國碼")
+	}
+	if rule.Country.Valid && !s.access.GeoEnabled() {
+		return nil, ErrGeoIPNotConfigured
+	}
+	if network, _ := ipfilter.ParseCIDR(rule.CIDR.String); network != nil && rule.Action == model.IPAccessDeny {
+		ones, bits := network.Mask.Size()
+		if (bits == 32 && ones < minIPv4BanPrefix) || (bits == 128 && ones < minIPv6BanPrefix) {
+			return nil, apperrors.New(http.StatusBadRequest, "封鎖的網段範圍過大")
+		}
+	}
+	if s.lockedOut(rule, "", input.ActorIP, input.ActorCountry) {
+		return nil, ErrIPRuleLocksOut
+	}
+
+	rule.Note = input.Note
+	rule.CreatedBy = sql.NullString{String: input.CreatedBy, Valid: input.CreatedBy != ""}
+	if err := s.store.Create(ctx, rule); err != nil {
+		s.logger.Error("Failed to create ip access rule", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("IP access rule added",
+		zap.String("scope", rule.Scope),
+		zap.String("action", string(rule.Action)),
+		zap.String("target", rule.Target()),
+		zap.String("created_by", input.CreatedBy),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    input.CreatedBy,
+		Action:     model.AuditActionIPRuleAdded,
+		TargetType: model.AuditTargetIP,
+		TargetID:   rule.Target(),
+		Metadata: map[string]interface{}{
+			"rule_id": rule.ID,
+			"scope":   rule.Scope,
+			"action":  rule.Action,
+			"note":    rule.Note,
+		},
+	})
+
+	s.sync(ctx)
+	return rule, nil
+}
+
+// Delete removes a stored rule. Rules from the config file have no ID
+// and cannot be removed.
+func (s *IPAccessService) Delete(ctx context.Context, id, removedBy, actorIP, actorCountry string) error {
+	if s.lockedOut(nil, id, actorIP, actorCountry) {
+		return ErrIPRuleLocksOut
+	}
+
+	if err := s.store.Delete(ctx, id); err != nil {
+		if err == repository.ErrIPAccessRuleNotFound {
+			return ErrIPAccessRuleNotFound
+		}
+		s.logger.Error("Failed to delete ip access rule", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("IP access rule removed",
+		zap.String("rule_id", id),
+		zap.String("removed_by", removedBy),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    removedBy,
+		Action:     model.AuditActionIPRuleRemoved,
+		TargetType: model.AuditTargetIP,
+		TargetID:   id,
+	})
+
+	s.sync(ctx)
+	return nil
+}
+
+// List lists the rules from the config file, then the stored ones
+func (s *IPAccessService) List(ctx context.Context) ([]*model.IPAccessRule, error) {
+	stored, err := s.store.List(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list ip access rules", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return append(append([]*model.IPAccessRule{}, s.access.Static()...), stored...), nil
+}
+
+// IPAccessCheck is how the rules treat an address in every scope
+type IPAccessCheck struct {
+	IP      string
+	Country string
+	Denials map[string]*ipfilter.Denial // by scope; allowed scopes are absent
+}
+
+// Check evaluates the rules in effect for ip. The country is taken from
+// the GeoIP database when country is empty.
+func (s *IPAccessService) Check(ip, country string) (*IPAccessCheck, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, apperrors.New(http.StatusBadRequest, "無效的 IP 位址")
+	}
+	if country == "" {
+		country = s.access.Country(ip, nil)
+	} else if !ipfilter.IsCountryCode(country) {
+		return nil, apperrors.New(http.StatusBadRequest, "無效的國碼")
+	} else {
+		country = strings.ToUpper(country)
+	}
+
+	check := &IPAccessCheck{IP: ip, Country: country, Denials: map[string]*ipfilter.Denial{}}
+	for _, scope := range model.IPAccessScopes {
+		if denial := s.access.Check(scope, ip, country); denial != nil {
+			check.Denials[scope] = denial
+		}
+	}
+	return check, nil
+}
+
+// sync pushes a committed change to every instance. Failures are logged
+// only: the periodic reload catches up.
+func (s *IPAccessService) sync(ctx context.Context) {
+	if err := s.access.NotifyChanged(context.WithoutCancel(ctx)); err != nil {
+		s.logger.Warn("Failed to propagate ip access rule change", zap.Error(err))
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-demo/chat/internal/ipfilter"
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+func setupTestIPAccessService(t *testing.T) (*IPAccessService, *mockIPAccessRuleStore, *ipfilter.AccessList) {
+	t.Helper()
+
+	store := &mockIPAccessRuleStore{}
+	var rules []*model.IPAccessRule
+	store.CreateFunc = func(ctx context.Context, rule *model.IPAccessRule) error {
+		rule.ID = rule.Target()
+		rules = append(rules, rule)
+		return nil
+	}
+	store.DeleteFunc = func(ctx context.Context, id string) error {
+		for i, rule := range rules {
+			if rule.ID == id {
+				rules = append(rules[:i], rules[i+1:]...)
+				break
+			}
+		}
+		return nil
+	}
+	store.ListFunc = func(ctx context.Context) ([]*model.IPAccessRule, error) {
+		return rules, nil
+	}
+
+	access := ipfilter.NewAccessList(store, nil, nil, zap.NewNop())
+	return NewIPAccessService(store, access, zap.NewNop()), store, access
+}
+
+func TestIPAccessService_Create(t *testing.T) {
+	service, store, access := setupTestIPAccessService(t)
+	ctx := context.Background()
+
+	t.Run("Rejects invalid input", func(t *testing.T) {
+		inputs := []*IPAccessRuleInput{
+			{Scope: "api", Action: model.IPAccessAllow, Target: "10.0.0.0/8"},
+			{Scope: "admin", Action: "block", Target: "10.0.0.0/8"},
+			{Scope: "admin", Action: model.IPAccessDeny, Target: "not-an-ip"},
+			{Scope: "global", Action: model.IPAccessDeny, Target: "10.0.0.0/4"},
+		}
+		for _, input := range inputs {
+			if _, err := service.Create(ctx, input); err == nil {
+				t.Errorf("Expected error for %+v", input)
+			}
+		}
+	})
+
+	t.Run("Country rules need geoip", func(t *testing.T) {
+		_, err := service.Create(ctx, &IPAccessRuleInput{Scope: "global", Action: model.IPAccessDeny, Target: "KP"})
+		if err != ErrGeoIPNotConfigured {
+			t.Errorf("Expected ErrGeoIPNotConfigured, got %v", err)
+		}
+	})
+
+	t.Run("Cannot lock out own ip", func(t *testing.T) {
+		inputs := []*IPAccessRuleInput{
+			{Scope: "admin", Action: model.IPAccessAllow, Target: "10.0.0.0/8", ActorIP: "198.51.100.7"},
+			{Scope: "global", Action: model.IPAccessDeny, Target: "198.51.100.0/24", ActorIP: "198.51.100.7"},
+		}
+		for _, input := range inputs {
+			if _, err := service.Create(ctx, input); err != ErrIPRuleLocksOut {
+				t.Errorf("Expected ErrIPRuleLocksOut for %s, got %v", input.Target, err)
+			}
+		}
+		if store.Calls("Create") != 0 {
+			t.Error("Expected no rule to be stored")
+		}
+	})
+
+	t.Run("Applies the rule", func(t *testing.T) {
+		rule, err := service.Create(ctx, &IPAccessRuleInput{Scope: "admin", Action: model.IPAccessAllow, Target: "198.51.100.0/24", ActorIP: "198.51.100.7"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if access.Check("admin", "203.0.113.1", "") == nil {
+			t.Error("Expected addresses outside the allowlist to be denied")
+		}
+
+		// Removing the only allow rule reopens the scope, which is safe
+		if err := service.Delete(ctx, rule.ID, "", "198.51.100.7", ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if access.Check("admin", "203.0.113.1", "") != nil {
+			t.Error("Expected the scope to reopen")
+		}
+	})
+}
+
+func TestIPAccessService_DeleteLocksOut(t *testing.T) {
+	service, store, _ := setupTestIPAccessService(t)
+	ctx := context.Background()
+
+	office, err := service.Create(ctx, &IPAccessRuleInput{Scope: "admin", Action: model.IPAccessAllow, Target: "198.51.100.0/24", ActorIP: "198.51.100.7"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.Create(ctx, &IPAccessRuleInput{Scope: "admin", Action: model.IPAccessAllow, Target: "192.0.2.0/24", ActorIP: "198.51.100.7"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := service.Delete(ctx, office.ID, "", "198.51.100.7", ""); err != ErrIPRuleLocksOut {
+		t.Errorf("Expected ErrIPRuleLocksOut, got %v", err)
+	}
+	if store.Calls("Delete") != 0 {
+		t.Error("Expected the rule to be kept")
+	}
+}
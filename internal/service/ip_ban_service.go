@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"time"
+
+	"github.com/go-demo/chat/internal/ipfilter"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Narrowest prefixes an IP ban may use; anything broader risks locking out
+// large parts of the internet by mistake
+const (
+	minIPv4BanPrefix = 8
+	minIPv6BanPrefix = 32
+)
+
+// IPDenylistSyncer propagates IP ban changes to every instance.
+// It is implemented by ipfilter.Denylist.
+type IPDenylistSyncer interface {
+	NotifyChanged(ctx context.Context) error
+}
+
+type IPBanService struct {
+	ipBanRepo IPBanStore
+	syncer    IPDenylistSyncer
+	auditor   *AuditService
+	logger    *zap.Logger
+}
+
+func NewIPBanService(ipBanRepo IPBanStore, logger *zap.Logger) *IPBanService {
+	return &IPBanService{
+		ipBanRepo: ipBanRepo,
+		logger:    logger,
+	}
+}
+
+// SetSyncer sets the component that reloads the denylist across instances
+func (s *IPBanService) SetSyncer(syncer IPDenylistSyncer) {
+	s.syncer = syncer
+}
+
+// SetAuditor sets the audit service that records IP bans
+func (s *IPBanService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// IPBanInput represents IP ban input
+type IPBanInput struct {
+	CIDR      string // IP address or CIDR range
+	Reason    string
+	Tarpit    bool
+	Duration  time.Duration // zero bans until removed
+	CreatedBy string
+	ActorIP   string // the admin's own address, which may not be banned
+}
+
+// Ban adds an IP or CIDR range to the denylist
+func (s *IPBanService) Ban(ctx context.Context, input *IPBanInput) (*model.IPBan, error) {
+	network, err := ipfilter.ParseCIDR(input.CIDR)
+	if err != nil {
+		return nil, apperrors.New(400, "無效的 IP 位址或網段")
+	}
+
+	ones, bits := network.Mask.Size()
+	if (bits == 32 && ones < minIPv4BanPrefix) || (bits == 128 && ones < minIPv6BanPrefix) {
+		return nil, apperrors.New(400, "封鎖的網段範圍過大")
+	}
+
+	if actorIP := net.ParseIP(input.ActorIP); actorIP != nil && network.Contains(actorIP) {
+		return nil, apperrors.ErrCannotBanOwnIP
+	}
+
+	ban := &model.IPBan{
+		CIDR:      network.String(),
+		Reason:    input.Reason,
+		Tarpit:    input.Tarpit,
+		CreatedBy: sql.NullString{String: input.CreatedBy, Valid: input.CreatedBy != ""},
+	}
+	if input.Duration > 0 {
+		expiresAt := time.Now().Add(input.Duration)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	if err := s.ipBanRepo.Create(ctx, ban); err != nil {
+		s.logger.Error("Failed to create ip ban", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("IP banned",
+		zap.String("cidr", ban.CIDR),
+		zap.String("created_by", input.CreatedBy),
+		zap.Bool("tarpit", ban.Tarpit),
+		zap.String("reason", ban.Reason),
+	)
+
+	metadata := map[string]interface{}{
+		"ban_id": ban.ID,
+		"reason": ban.Reason,
+		"tarpit": ban.Tarpit,
+	}
+	if ban.ExpiresAt != nil {
+		metadata["expires_at"] = ban.ExpiresAt.Format(time.RFC3339)
+	}
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    input.CreatedBy,
+		Action:     model.AuditActionIPBanned,
+		TargetType: model.AuditTargetIP,
+		TargetID:   ban.CIDR,
+		Metadata:   metadata,
+	})
+
+	s.sync(ctx)
+	return ban, nil
+}
+
+// Unban removes an IP ban
+func (s *IPBanService) Unban(ctx context.Context, id, removedBy string) error {
+	if err := s.ipBanRepo.Delete(ctx, id); err != nil {
+		if err == repository.ErrIPBanNotFound {
+			return apperrors.ErrIPBanNotFound
+		}
+		s.logger.Error("Failed to delete ip ban", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("IP ban removed",
+		zap.String("ban_id", id),
+		zap.String("removed_by", removedBy),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    removedBy,
+		Action:     model.AuditActionIPUnbanned,
+		TargetType: model.AuditTargetIP,
+		TargetID:   id,
+	})
+
+	s.sync(ctx)
+	return nil
+}
+
+// List lists IP bans, including expired ones not yet purged
+func (s *IPBanService) List(ctx context.Context, limit, offset int) ([]*model.IPBan, error) {
+	bans, err := s.ipBanRepo.List(ctx, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list ip bans", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return bans, nil
+}
+
+// PurgeExpired deletes expired IP bans
+func (s *IPBanService) PurgeExpired(ctx context.Context) (int64, error) {
+	n, err := s.ipBanRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to purge expired ip bans", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+	if n > 0 {
+		s.logger.Info("Expired ip bans purged", zap.Int64("count", n))
+	}
+	return n, nil
+}
+
+// sync pushes a committed change to every instance. Failures are logged
+// only: the periodic reload catches up.
+func (s *IPBanService) sync(ctx context.Context) {
+	if s.syncer == nil {
+		return
+	}
+	if err := s.syncer.NotifyChanged(context.WithoutCancel(ctx)); err != nil {
+		s.logger.Warn("Failed to propagate ip denylist change", zap.Error(err))
+	}
+}
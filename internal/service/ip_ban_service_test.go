@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type countingSyncer struct {
+	calls int
+}
+
+func (s *countingSyncer) NotifyChanged(ctx context.Context) error {
+	s.calls++
+	return nil
+}
+
+func setupTestIPBanService(t *testing.T) (*IPBanService, *countingSyncer, *sqlx.DB) {
+	t.Helper()
+
+	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=chat_test sslmode=disable"
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Skipf("Skipping test, could not connect to test database: %v", err)
+	}
+
+	syncer := &countingSyncer{}
+	service := NewIPBanService(repository.NewIPBanRepository(db), zap.NewNop())
+	service.SetSyncer(syncer)
+	return service, syncer, db
+}
+
+func TestIPBanService_Ban(t *testing.T) {
+	service, syncer, db := setupTestIPBanService(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	t.Run("Rejects invalid input", func(t *testing.T) {
+		inputs := []*IPBanInput{
+			{CIDR: "not-an-ip", Reason: "test"},
+			{CIDR: "10.0.0.0/4", Reason: "too broad"},
+			{CIDR: "2001:db8::/16", Reason: "too broad"},
+		}
+		for _, input := range inputs {
+			if _, err := service.Ban(ctx, input); err == nil {
+				t.Errorf("Expected error for %s", input.CIDR)
+			}
+		}
+	})
+
+	t.Run("Cannot ban own ip", func(t *testing.T) {
+		_, err := service.Ban(ctx, &IPBanInput{CIDR: "198.51.100.0/24", Reason: "test", ActorIP: "198.51.100.7"})
+		if err != apperrors.ErrCannotBanOwnIP {
+			t.Errorf("Expected ErrCannotBanOwnIP, got %v", err)
+		}
+	})
+
+	ban, err := service.Ban(ctx, &IPBanInput{CIDR: "203.0.113.9/24", Reason: "scanner", Tarpit: true, Duration: time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to ban ip: %v", err)
+	}
+	defer func() { _ = service.Unban(ctx, ban.ID, "") }()
+
+	if ban.CIDR != "203.0.113.0/24" {
+		t.Errorf("Expected normalized range, got %s", ban.CIDR)
+	}
+	if ban.ExpiresAt == nil || !ban.Tarpit {
+		t.Errorf("Unexpected ban: %+v", ban)
+	}
+	if syncer.calls != 1 {
+		t.Errorf("Expected change to be propagated once, got %d", syncer.calls)
+	}
+
+	if err := service.Unban(ctx, ban.ID, ""); err != nil {
+		t.Fatalf("Failed to unban: %v", err)
+	}
+	if err := service.Unban(ctx, ban.ID, ""); err != apperrors.ErrIPBanNotFound {
+		t.Errorf("Expected ErrIPBanNotFound, got %v", err)
+	}
+}
+
+func TestIPBanService_Ban_Unit(t *testing.T) {
+	ctx := context.Background()
+	store := &mockIPBanStore{}
+	service := NewIPBanService(store, zap.NewNop())
+
+	tests := []struct {
+		name  string
+		input IPBanInput
+		err   bool
+	}{
+		{"Invalid address", IPBanInput{CIDR: "not-an-ip"}, true},
+		{"Range too broad", IPBanInput{CIDR: "10.0.0.0/7"}, true},
+		{"Own address", IPBanInput{CIDR: "203.0.113.0/24", ActorIP: "203.0.113.9"}, true},
+		{"Single address", IPBanInput{CIDR: "203.0.113.9", ActorIP: "198.51.100.1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Ban(ctx, &tt.input)
+			if (err != nil) != tt.err {
+				t.Errorf("Expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+	if store.Calls("Create") != 1 {
+		t.Errorf("Expected only the valid ban to be stored, got %d Create calls", store.Calls("Create"))
+	}
+
+	store.DeleteFunc = func(ctx context.Context, id string) error {
+		return repository.ErrIPBanNotFound
+	}
+	if err := service.Unban(ctx, "missing", "admin"); err != apperrors.ErrIPBanNotFound {
+		t.Errorf("Expected ErrIPBanNotFound, got %v", err)
+	}
+}
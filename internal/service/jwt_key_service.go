@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrJWTKeysStatic is returned when tokens are signed with public key
+// algorithms from the config file. A rotated HMAC secret would take over
+// from them, and services verifying tokens through the JWKS could not
+// follow.
+var ErrJWTKeysStatic = apperrors.New(http.StatusConflict, "簽章金鑰由設定檔管理，請改以設定檔輪替")
+
+// jwtKeysChangedChannel tells every instance to reload the signing keys
+const jwtKeysChangedChannel = "jwt:keys_changed"
+
+// JWTKeyService rotates the JWT signing secret. Keys live in the database,
+// sealed with the key encryption key, and every instance keeps them loaded
+// in its JWTManager, reloading when another instance publishes a rotation
+// and on a timer in case it missed one. A new key only starts signing
+// after the activation delay, so every instance can verify its tokens by
+// then.
+type JWTKeyService struct {
+	store           JWTKeyStore
+	jwt             *utils.JWTManager
+	box             *utils.SecretBox
+	activationDelay time.Duration
+	redis           *redis.Client
+	auditor         *AuditService
+	logger          *zap.Logger
+}
+
+// NewJWTKeyService creates a JWTKeyService; without redisClient other
+// instances pick up rotations on their next reload
+func NewJWTKeyService(store JWTKeyStore, jwtManager *utils.JWTManager, box *utils.SecretBox, activationDelay time.Duration, redisClient *redis.Client, logger *zap.Logger) *JWTKeyService {
+	return &JWTKeyService{
+		store:           store,
+		jwt:             jwtManager,
+		box:             box,
+		activationDelay: activationDelay,
+		redis:           redisClient,
+		logger:          logger,
+	}
+}
+
+// SetAuditor sets the audit service that records rotations
+func (s *JWTKeyService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// Reload loads the signing keys into the JWT manager. Keys sealed with
+// another key encryption key are skipped, so tokens they signed no longer
+// validate.
+func (s *JWTKeyService) Reload(ctx context.Context) error {
+	keys, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	signingKeys := make([]utils.SigningKey, 0, len(keys))
+	for _, key := range keys {
+		secret, err := s.box.Open(key.SealedSecret)
+		if err != nil {
+			s.logger.Warn("Skipping jwt signing key that cannot be decrypted", zap.String("key_id", key.ID), zap.Error(err))
+			continue
+		}
+		signingKeys = append(signingKeys, utils.SigningKey{
+			ID:          key.ID,
+			Secret:      secret,
+			ActivatesAt: key.ActivatesAt,
+		})
+	}
+	s.jwt.SetSigningKeys(signingKeys)
+	return nil
+}
+
+// Rotate creates a signing key that takes over once the activation delay
+// has passed. Tokens signed with the previous key stay valid until they
+// expire.
+func (s *JWTKeyService) Rotate(ctx context.Context, actorID string) (*model.JWTSigningKey, error) {
+	if s.jwt.UsesPublicKeys() {
+		return nil, ErrJWTKeysStatic
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		s.logger.Error("Failed to generate jwt secret", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	sealed, err := s.box.Seal(secret)
+	if err != nil {
+		s.logger.Error("Failed to seal jwt secret", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	key := &model.JWTSigningKey{
+		SealedSecret: sealed,
+		CreatedBy:    sql.NullString{String: actorID, Valid: actorID != ""},
+		ActivatesAt:  time.Now().Add(s.activationDelay),
+	}
+	if err := s.store.Create(ctx, key); err != nil {
+		s.logger.Error("Failed to create jwt signing key", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	// The key is stored; instances that miss the reload below pick it up
+	// on their next timed one, well before it activates
+	if err := s.Reload(ctx); err != nil {
+		s.logger.Error("Failed to reload jwt signing keys", zap.Error(err))
+	}
+	if s.redis != nil {
+		if err := s.redis.Publish(ctx, jwtKeysChangedChannel, key.ID).Err(); err != nil {
+			s.logger.Warn("Failed to publish jwt key rotation", zap.Error(err))
+		}
+	}
+
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    actorID,
+		Action:     model.AuditActionJWTKeyRotated,
+		TargetType: model.AuditTargetJWTKey,
+		TargetID:   key.ID,
+		Metadata: map[string]interface{}{
+			"activates_at": key.ActivatesAt.Format(time.RFC3339),
+		},
+	})
+
+	s.logger.Info("JWT signing key rotated",
+		zap.String("key_id", key.ID),
+		zap.Time("activates_at", key.ActivatesAt),
+		zap.String("rotated_by", actorID),
+	)
+	return key, nil
+}
+
+// Watch reloads the keys whenever another instance rotates them. It blocks
+// until ctx is canceled.
+func (s *JWTKeyService) Watch(ctx context.Context) {
+	if s.redis == nil {
+		return
+	}
+
+	pubsub := s.redis.Subscribe(ctx, jwtKeysChangedChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := s.Reload(ctx); err != nil {
+				s.logger.Error("Failed to reload jwt signing keys", zap.Error(err))
+			}
+		}
+	}
+}
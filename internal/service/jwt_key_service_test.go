@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"go.uber.org/zap"
+)
+
+func TestJWTKeyService_Rotate(t *testing.T) {
+	ctx := context.Background()
+	manager := utils.NewJWTManager("configured-secret", 15*time.Minute, 7*24*time.Hour, "test")
+	legacy, _, _ := manager.GenerateAccessToken("user-1", "alice")
+
+	var stored []*model.JWTSigningKey
+	store := &mockJWTKeyStore{
+		CreateFunc: func(ctx context.Context, key *model.JWTSigningKey) error {
+			key.ID = "key-1"
+			key.CreatedAt = time.Now()
+			stored = append(stored, key)
+			return nil
+		},
+		ListFunc: func(ctx context.Context) ([]*model.JWTSigningKey, error) {
+			return stored, nil
+		},
+	}
+	box, _ := utils.NewSecretBox("key-encryption-key")
+	service := NewJWTKeyService(store, manager, box, 0, nil, zap.NewNop())
+
+	key, err := service.Rotate(ctx, "admin-1")
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if key.CreatedBy.String != "admin-1" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+	if secret, err := box.Open(key.SealedSecret); err != nil || len(secret) != 32 {
+		t.Errorf("Expected a sealed 32-byte secret, got %d bytes, %v", len(secret), err)
+	}
+	if store.Calls("List") != 1 {
+		t.Errorf("Expected the keys to be reloaded, got %d lists", store.Calls("List"))
+	}
+
+	// The new key signs at once without an activation delay, and the
+	// configured secret still verifies existing tokens
+	rotated, _, _ := manager.GenerateAccessToken("user-1", "alice")
+	if _, err := utils.NewJWTManager("configured-secret", 15*time.Minute, 7*24*time.Hour, "test").ValidateAccessToken(rotated); err == nil {
+		t.Error("Expected a token signed with the rotated key")
+	}
+	for _, token := range []string{legacy, rotated} {
+		if _, err := manager.ValidateAccessToken(token); err != nil {
+			t.Errorf("Expected token to validate: %v", err)
+		}
+	}
+}
+
+func TestJWTKeyService_RotateStoreError(t *testing.T) {
+	store := &mockJWTKeyStore{
+		CreateFunc: func(ctx context.Context, key *model.JWTSigningKey) error {
+			return context.DeadlineExceeded
+		},
+	}
+	manager := utils.NewJWTManager("configured-secret", time.Minute, time.Hour, "test")
+	box, _ := utils.NewSecretBox("key-encryption-key")
+	service := NewJWTKeyService(store, manager, box, time.Minute, nil, zap.NewNop())
+
+	if _, err := service.Rotate(context.Background(), "admin-1"); err != apperrors.ErrInternal {
+		t.Errorf("Expected ErrInternal, got %v", err)
+	}
+	if store.Calls("List") != 0 {
+		t.Error("Expected no reload after a failed rotation")
+	}
+}
+
+func TestJWTKeyService_RotateWithPublicKeys(t *testing.T) {
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	box, _ := utils.NewSecretBox("key-encryption-key")
+
+	static := utils.NewJWTManager("configured-secret", time.Minute, time.Hour, "test")
+	static.SetStaticKeys([]utils.SigningKey{{ID: "static-1", PrivateKey: private}})
+	noHMAC := utils.NewJWTManager("configured-secret", time.Minute, time.Hour, "test")
+	noHMAC.DisableHMAC()
+
+	for _, manager := range []*utils.JWTManager{static, noHMAC} {
+		store := &mockJWTKeyStore{}
+		service := NewJWTKeyService(store, manager, box, time.Minute, nil, zap.NewNop())
+		if _, err := service.Rotate(context.Background(), "admin-1"); err != ErrJWTKeysStatic {
+			t.Errorf("Expected ErrJWTKeysStatic, got %v", err)
+		}
+		if store.Calls("Create") != 0 {
+			t.Error("Expected no key to be stored")
+		}
+	}
+
+	// Static HMAC keys take turns with rotated ones
+	hmac := utils.NewJWTManager("configured-secret", time.Minute, time.Hour, "test")
+	hmac.SetStaticKeys([]utils.SigningKey{{ID: "static-1", Secret: []byte("static-secret")}})
+	service := NewJWTKeyService(&mockJWTKeyStore{}, hmac, box, time.Minute, nil, zap.NewNop())
+	if _, err := service.Rotate(context.Background(), "admin-1"); err != nil {
+		t.Errorf("Expected rotation next to static HMAC keys, got %v", err)
+	}
+}
+
+func TestJWTKeyService_ReloadSkipsUnreadableKeys(t *testing.T) {
+	box, _ := utils.NewSecretBox("key-encryption-key")
+	other, _ := utils.NewSecretBox("previous-key-encryption-key")
+	readable, _ := box.Seal([]byte("readable-rotated-secret"))
+	unreadable, _ := other.Seal([]byte("unreadable-rotated-secret"))
+
+	store := &mockJWTKeyStore{
+		ListFunc: func(ctx context.Context) ([]*model.JWTSigningKey, error) {
+			return []*model.JWTSigningKey{
+				{ID: "old", SealedSecret: unreadable, ActivatesAt: time.Now().Add(-2 * time.Minute)},
+				{ID: "new", SealedSecret: readable, ActivatesAt: time.Now().Add(-time.Minute)},
+			}, nil
+		},
+	}
+	manager := utils.NewJWTManager("configured-secret", time.Minute, time.Hour, "test")
+	service := NewJWTKeyService(store, manager, box, time.Minute, nil, zap.NewNop())
+
+	if err := service.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	// The readable key signs; a manager holding only that secret verifies
+	token, _, _ := manager.GenerateAccessToken("user-1", "alice")
+	verifier := utils.NewJWTManager("configured-secret", time.Minute, time.Hour, "test")
+	verifier.SetSigningKeys([]utils.SigningKey{{ID: "new", Secret: []byte("readable-rotated-secret")}})
+	if _, err := verifier.ValidateAccessToken(token); err != nil {
+		t.Errorf("Expected the token to be signed with the readable key: %v", err)
+	}
+}
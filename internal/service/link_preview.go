@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/features"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/unfurl"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultLinkPreviewWorkers is how many pages are fetched at once
+	DefaultLinkPreviewWorkers = 4
+
+	// DefaultLinkPreviewQueueSize is how many messages may wait for a
+	// worker before new ones go without previews
+	DefaultLinkPreviewQueueSize = 1024
+
+	// DefaultLinkPreviewsPerMessage caps the links previewed in one message
+	DefaultLinkPreviewsPerMessage = 3
+
+	// DefaultLinkPreviewCacheTTL is how long a fetched preview is reused
+	DefaultLinkPreviewCacheTTL = 24 * time.Hour
+
+	// RoomEventMessageEmbedUpdated tells room clients a message got previews
+	RoomEventMessageEmbedUpdated = "message_embed_updated"
+
+	linkPreviewCachePrefix = "link_preview:"
+
+	// Pages that could not be previewed are not retried for this long
+	linkPreviewFailureTTL = time.Hour
+
+	// linkPreviewJobTimeout bounds fetching every link of one message
+	linkPreviewJobTimeout = 30 * time.Second
+
+	// A write-behind message may not be stored yet when its previews are
+	// ready; they are saved once more after this delay
+	linkPreviewRetryDelay = 2 * time.Second
+)
+
+// LinkFetcher reads the preview of a page.
+// It is implemented by unfurl.Fetcher.
+type LinkFetcher interface {
+	Fetch(ctx context.Context, url string) (*unfurl.Preview, error)
+}
+
+var _ LinkFetcher = (*unfurl.Fetcher)(nil)
+
+// LinkPreviewConfig tunes a LinkPreviewer; zero values use the defaults
+type LinkPreviewConfig struct {
+	Workers       int
+	QueueSize     int
+	MaxPerMessage int
+	CacheTTL      time.Duration
+}
+
+// MessageEmbedUpdatedEvent carries the embeds a message got after it was
+// sent
+type MessageEmbedUpdatedEvent struct {
+	MessageID string                `json:"message_id"`
+	RoomID    string                `json:"room_id"`
+	Embeds    []*model.MessageEmbed `json:"embeds"`
+}
+
+type linkPreviewJob struct {
+	messageID string
+	roomID    string
+	content   string
+	links     []string
+}
+
+// cachedPreview is a preview as kept in Redis; a nil Preview remembers that
+// the page had none
+type cachedPreview struct {
+	Preview *unfurl.Preview `json:"preview,omitempty"`
+}
+
+// LinkPreviewer unfurls the links in new messages in the background. Each
+// page's Open Graph data is cached in Redis and shared by the instances;
+// once a message's previews are stored they are pushed to the room as
+// message_embed_updated. Messages arriving while every worker is busy and
+// the queue is full go without previews. Its methods are safe on a nil
+// LinkPreviewer, which previews nothing.
+type LinkPreviewer struct {
+	fetcher       LinkFetcher
+	store         MessageEmbedStore
+	redis         *redis.Client
+	notifier      *NotificationService
+	flags         *features.Set
+	maxPerMessage int
+	cacheTTL      time.Duration
+	logger        *zap.Logger
+
+	queue   chan *linkPreviewJob
+	workers sync.WaitGroup
+	retries sync.WaitGroup
+}
+
+// NewLinkPreviewer starts the preview workers. Without redisClient every
+// link is fetched each time it is posted.
+func NewLinkPreviewer(fetcher LinkFetcher, store MessageEmbedStore, redisClient *redis.Client, config LinkPreviewConfig, logger *zap.Logger) *LinkPreviewer {
+	if config.Workers <= 0 {
+		config.Workers = DefaultLinkPreviewWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultLinkPreviewQueueSize
+	}
+	if config.MaxPerMessage <= 0 {
+		config.MaxPerMessage = DefaultLinkPreviewsPerMessage
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = DefaultLinkPreviewCacheTTL
+	}
+
+	p := &LinkPreviewer{
+		fetcher:       fetcher,
+		store:         store,
+		redis:         redisClient,
+		maxPerMessage: config.MaxPerMessage,
+		cacheTTL:      config.CacheTTL,
+		logger:        logger,
+		queue:         make(chan *linkPreviewJob, config.QueueSize),
+	}
+	for i := 0; i < config.Workers; i++ {
+		p.workers.Add(1)
+		go p.run()
+	}
+	return p
+}
+
+// SetNotifier sets the service message_embed_updated is pushed through
+func (p *LinkPreviewer) SetNotifier(notifier *NotificationService) {
+	p.notifier = notifier
+}
+
+// SetFeatures makes previews follow the link_previews feature flag
+func (p *LinkPreviewer) SetFeatures(flags *features.Set) {
+	p.flags = flags
+}
+
+// Enqueue queues a message for previews if it is a text message with links.
+// It never blocks.
+func (p *LinkPreviewer) Enqueue(msg *model.Message) {
+	if p == nil || msg.Type != model.MessageTypeText || msg.IsDeleted {
+		return
+	}
+	if !p.flags.Enabled(features.FlagLinkPreviews) {
+		return
+	}
+	links := unfurl.ExtractURLs(msg.Content, p.maxPerMessage)
+	if len(links) == 0 {
+		return
+	}
+
+	select {
+	case p.queue <- &linkPreviewJob{messageID: msg.ID, roomID: msg.RoomID, content: msg.Content, links: links}:
+	default:
+		p.logger.Warn("Link preview queue full, skipping message", zap.String("message_id", msg.ID))
+	}
+}
+
+// Close finishes the queued messages and stops the workers. Messages
+// queued after Close are lost.
+func (p *LinkPreviewer) Close() {
+	if p == nil {
+		return
+	}
+	close(p.queue)
+	p.workers.Wait()
+	p.retries.Wait()
+}
+
+func (p *LinkPreviewer) run() {
+	defer p.workers.Done()
+
+	for job := range p.queue {
+		p.process(job)
+	}
+}
+
+// process previews every link of a message and stores the ones that
+// worked
+func (p *LinkPreviewer) process(job *linkPreviewJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), linkPreviewJobTimeout)
+	defer cancel()
+
+	var embeds []*model.MessageEmbed
+	for _, link := range job.links {
+		preview := p.preview(ctx, link)
+		if preview == nil {
+			continue
+		}
+		embeds = append(embeds, &model.MessageEmbed{
+			Type:        model.EmbedTypeLink,
+			URL:         link,
+			Title:       preview.Title,
+			Description: preview.Description,
+			ImageURL:    preview.ImageURL,
+			SiteName:    preview.SiteName,
+		})
+	}
+	if len(embeds) == 0 {
+		return
+	}
+
+	raw, err := json.Marshal(embeds)
+	if err != nil {
+		p.logger.Error("Failed to encode message embeds", zap.Error(err))
+		return
+	}
+	err = p.store.SetEmbeds(ctx, job.messageID, job.content, raw)
+	if err == repository.ErrMessageNotFound {
+		// Not written yet by the write-behind pipeline, or edited or
+		// deleted meanwhile; only the first case is worth another try
+		p.retries.Add(1)
+		time.AfterFunc(linkPreviewRetryDelay, func() {
+			defer p.retries.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), linkPreviewJobTimeout)
+			defer cancel()
+			p.save(ctx, job, embeds, raw)
+		})
+		return
+	}
+	p.finish(job, embeds, err)
+}
+
+// save is the second attempt to store the embeds of a message
+func (p *LinkPreviewer) save(ctx context.Context, job *linkPreviewJob, embeds []*model.MessageEmbed, raw []byte) {
+	err := p.store.SetEmbeds(ctx, job.messageID, job.content, raw)
+	if err == repository.ErrMessageNotFound {
+		p.logger.Debug("Message gone or changed before its previews were stored", zap.String("message_id", job.messageID))
+		return
+	}
+	p.finish(job, embeds, err)
+}
+
+// finish pushes stored embeds to the room
+func (p *LinkPreviewer) finish(job *linkPreviewJob, embeds []*model.MessageEmbed, err error) {
+	if err != nil {
+		p.logger.Error("Failed to store message embeds", zap.String("message_id", job.messageID), zap.Error(err))
+		return
+	}
+	if p.notifier != nil {
+		p.notifier.PublishToRoom(job.roomID, RoomEventMessageEmbedUpdated, &MessageEmbedUpdatedEvent{
+			MessageID: job.messageID,
+			RoomID:    job.roomID,
+			Embeds:    embeds,
+		})
+	}
+}
+
+// preview returns the preview of a link from the cache or the page itself,
+// nil when the page has none
+func (p *LinkPreviewer) preview(ctx context.Context, link string) *unfurl.Preview {
+	key := linkPreviewCachePrefix + linkHash(link)
+	if p.redis != nil {
+		raw, err := p.redis.Get(ctx, key).Bytes()
+		if err == nil {
+			var cached cachedPreview
+			if err := json.Unmarshal(raw, &cached); err == nil {
+				return cached.Preview
+			}
+		} else if err != redis.Nil {
+			p.logger.Warn("Failed to read link preview cache", zap.Error(err))
+		}
+	}
+
+	preview, err := p.fetcher.Fetch(ctx, link)
+	ttl := p.cacheTTL
+	if err != nil {
+		p.logger.Debug("No preview for link", zap.String("url", link), zap.Error(err))
+		preview = nil
+		if ttl > linkPreviewFailureTTL {
+			ttl = linkPreviewFailureTTL
+		}
+		// A timeout says nothing about the page; try again next time
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	if p.redis != nil {
+		raw, err := json.Marshal(&cachedPreview{Preview: preview})
+		if err == nil {
+			err = p.redis.Set(ctx, key, raw, ttl).Err()
+		}
+		if err != nil {
+			p.logger.Warn("Failed to cache link preview", zap.Error(err))
+		}
+	}
+	return preview
+}
+
+// linkHash keeps cache keys short whatever the length of the link
+func linkHash(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetLinkPreviewer enables link previews for new and edited messages
+func (s *MessageService) SetLinkPreviewer(previewer *LinkPreviewer) {
+	s.previews = previewer
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-demo/chat/internal/features"
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/unfurl"
+	"go.uber.org/zap"
+)
+
+type fakeLinkFetcher struct {
+	mu       sync.Mutex
+	previews map[string]*unfurl.Preview
+	fetched  []string
+}
+
+func (f *fakeLinkFetcher) Fetch(ctx context.Context, url string) (*unfurl.Preview, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetched = append(f.fetched, url)
+	if preview, ok := f.previews[url]; ok {
+		return preview, nil
+	}
+	return nil, unfurl.ErrNoMetadata
+}
+
+type roomEvent struct {
+	roomID    string
+	eventType string
+	payload   interface{}
+}
+
+type userEvent struct {
+	userID    string
+	eventType string
+	payload   interface{}
+}
+
+type fakeRealtimePublisher struct {
+	mu         sync.Mutex
+	roomEvents []roomEvent
+	userEvents []userEvent
+}
+
+func (p *fakeRealtimePublisher) PublishToRoom(roomID, eventType string, payload interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.roomEvents = append(p.roomEvents, roomEvent{roomID, eventType, payload})
+}
+
+func (p *fakeRealtimePublisher) PublishToUser(userID, eventType string, payload interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.userEvents = append(p.userEvents, userEvent{userID, eventType, payload})
+}
+
+func (p *fakeRealtimePublisher) PublishNotification(notification *model.Notification) {}
+
+func TestLinkPreviewer_StoresAndPublishesEmbeds(t *testing.T) {
+	fetcher := &fakeLinkFetcher{previews: map[string]*unfurl.Preview{
+		"https://go.dev/blog": {Title: "The Go Blog", ImageURL: "https://go.dev/logo.png"},
+	}}
+	var stored string
+	store := &mockMessageEmbedStore{SetEmbedsFunc: func(ctx context.Context, id, content string, embeds []byte) error {
+		stored = string(embeds)
+		return nil
+	}}
+	publisher := &fakeRealtimePublisher{}
+	notifier := NewNotificationService(nil, zap.NewNop())
+	notifier.SetPublisher(publisher)
+
+	previewer := NewLinkPreviewer(fetcher, store, nil, LinkPreviewConfig{}, zap.NewNop())
+	previewer.SetNotifier(notifier)
+	previewer.Enqueue(&model.Message{
+		ID:      "m1",
+		RoomID:  "r1",
+		Type:    model.MessageTypeText,
+		Content: "read https://go.dev/blog and https://example.com/nothing.",
+	})
+	previewer.Close()
+
+	if len(fetcher.fetched) != 2 {
+		t.Errorf("Expected both links fetched, got %v", fetcher.fetched)
+	}
+	want := `[{"type":"link","url":"https://go.dev/blog","title":"The Go Blog","image_url":"https://go.dev/logo.png"}]`
+	if stored != want {
+		t.Errorf("Expected embeds %s, got %s", want, stored)
+	}
+
+	if len(publisher.roomEvents) != 1 {
+		t.Fatalf("Expected one room event, got %d", len(publisher.roomEvents))
+	}
+	event := publisher.roomEvents[0]
+	if event.roomID != "r1" || event.eventType != RoomEventMessageEmbedUpdated {
+		t.Errorf("Unexpected event %s to room %s", event.eventType, event.roomID)
+	}
+	if payload := event.payload.(*MessageEmbedUpdatedEvent); payload.MessageID != "m1" || len(payload.Embeds) != 1 {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+}
+
+func TestLinkPreviewer_SkipsMessages(t *testing.T) {
+	fetcher := &fakeLinkFetcher{}
+	store := &mockMessageEmbedStore{}
+	flags := features.NewSet()
+
+	previewer := NewLinkPreviewer(fetcher, store, nil, LinkPreviewConfig{}, zap.NewNop())
+	previewer.SetFeatures(flags)
+
+	previewer.Enqueue(&model.Message{ID: "m1", Type: model.MessageTypeText, Content: "no links"})
+	previewer.Enqueue(&model.Message{ID: "m2", Type: model.MessageTypeImage, Content: "https://example.com/a.png"})
+	previewer.Enqueue(&model.Message{ID: "m3", Type: model.MessageTypeText, Content: "https://example.com", IsDeleted: true})
+	flags.Degrade(features.FlagLinkPreviews, "test")
+	previewer.Enqueue(&model.Message{ID: "m4", Type: model.MessageTypeText, Content: "https://example.com"})
+	previewer.Close()
+
+	if len(fetcher.fetched) != 0 {
+		t.Errorf("Expected nothing fetched, got %v", fetcher.fetched)
+	}
+	if store.Calls("SetEmbeds") != 0 {
+		t.Error("Expected no embeds stored")
+	}
+}
+
+func TestLinkPreviewer_NothingStoredWithoutPreviews(t *testing.T) {
+	store := &mockMessageEmbedStore{SetEmbedsFunc: func(ctx context.Context, id, content string, embeds []byte) error {
+		return errors.New("should not be called")
+	}}
+
+	previewer := NewLinkPreviewer(&fakeLinkFetcher{}, store, nil, LinkPreviewConfig{}, zap.NewNop())
+	previewer.Enqueue(&model.Message{ID: "m1", Type: model.MessageTypeText, Content: "https://example.com"})
+	previewer.Close()
+
+	if store.Calls("SetEmbeds") != 0 {
+		t.Error("Expected no embeds stored for a link without a preview")
+	}
+}
+
+func TestLinkPreviewer_Nil(t *testing.T) {
+	var previewer *LinkPreviewer
+	previewer.Enqueue(&model.Message{Type: model.MessageTypeText, Content: "https://example.com"})
+	previewer.Close()
+}
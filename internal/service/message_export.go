@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// HistoryFormat is an encoding for a member's room history export
+type HistoryFormat string
+
+const (
+	HistoryFormatJSON   HistoryFormat = "json"
+	HistoryFormatCSV    HistoryFormat = "csv"
+	HistoryFormatNDJSON HistoryFormat = "ndjson"
+)
+
+var ErrInvalidHistoryFormat = apperrors.New(http.StatusBadRequest, "無效的匯出格式，請使用 json、csv 或 ndjson")
+
+// ParseHistoryFormat reads a format name, defaulting to JSON when empty
+func ParseHistoryFormat(s string) (HistoryFormat, error) {
+	switch HistoryFormat(s) {
+	case "", HistoryFormatJSON:
+		return HistoryFormatJSON, nil
+	case HistoryFormatCSV:
+		return HistoryFormatCSV, nil
+	case HistoryFormatNDJSON:
+		return HistoryFormatNDJSON, nil
+	}
+	return "", ErrInvalidHistoryFormat
+}
+
+// ContentType returns the MIME type the format is served as
+func (f HistoryFormat) ContentType() string {
+	switch f {
+	case HistoryFormatCSV:
+		return "text/csv; charset=utf-8"
+	case HistoryFormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// historyCSVHeader names the CSV columns, in ExportedMessage field order
+var historyCSVHeader = []string{
+	"id", "room_id", "user_id", "username", "content", "type",
+	"reply_to_id", "is_edited", "created_at", "updated_at",
+}
+
+// HistoryExport is a prepared room history export the caller may read
+type HistoryExport struct {
+	Filename string
+	Format   HistoryFormat
+
+	service *MessageService
+	roomID  string
+	userID  string
+}
+
+// ExportHistory prepares a download of a room's full message history.
+// Unlike browsing, a bulk export is limited to members, and deleted messages
+// are left out.
+func (s *MessageService) ExportHistory(ctx context.Context, roomID, userID string, format HistoryFormat) (*HistoryExport, error) {
+	if err := s.authorize(ctx, roomID, userID, policy.CanAccess); err != nil {
+		return nil, err
+	}
+
+	return &HistoryExport{
+		Filename: fmt.Sprintf("room-%s-%s.%s", roomID, time.Now().UTC().Format("20060102T150405Z"), format),
+		Format:   format,
+		service:  s,
+		roomID:   roomID,
+		userID:   userID,
+	}, nil
+}
+
+// WriteTo streams the history to w a page at a time, flushing after each
+// page so memory use does not grow with the room. It returns the number of
+// messages written.
+func (e *HistoryExport) WriteTo(ctx context.Context, w io.Writer) (int64, error) {
+	s := e.service
+
+	enc := newHistoryEncoder(e.Format, w)
+	count, err := e.write(ctx, enc)
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		s.logger.Error("Room history export aborted",
+			zap.String("room_id", e.roomID),
+			zap.String("user_id", e.userID),
+			zap.Int64("records", count),
+			zap.Error(err),
+		)
+		return count, err
+	}
+
+	s.logger.Info("Room history exported",
+		zap.String("room_id", e.roomID),
+		zap.String("user_id", e.userID),
+		zap.String("format", string(e.Format)),
+		zap.Int64("records", count),
+	)
+	return count, nil
+}
+
+func (e *HistoryExport) write(ctx context.Context, enc *historyEncoder) (int64, error) {
+	var count int64
+	cursor := repository.NewExportCursor()
+	for {
+		if err := checkContext(ctx); err != nil {
+			return count, err
+		}
+
+		messages, err := e.service.messageRepo.ListForExport(ctx, e.roomID, "", cursor, exportPageSize)
+		if err != nil {
+			return count, err
+		}
+		for _, m := range messages {
+			cursor.Advance(m.CreatedAt, m.ID)
+			if m.IsDeleted {
+				continue
+			}
+			if err := enc.Encode(newExportedMessage(m)); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if err := enc.Flush(); err != nil {
+			return count, err
+		}
+		if len(messages) < exportPageSize {
+			return count, nil
+		}
+	}
+}
+
+// historyEncoder writes exported messages in one of the history formats.
+// A JSON export is a single array, so it is only valid once closed.
+type historyEncoder struct {
+	format HistoryFormat
+	dst    io.Writer
+	buf    *bufio.Writer
+	csv    *csv.Writer
+	count  int
+}
+
+func newHistoryEncoder(format HistoryFormat, w io.Writer) *historyEncoder {
+	enc := &historyEncoder{format: format, dst: w, buf: bufio.NewWriter(w)}
+	if format == HistoryFormatCSV {
+		enc.csv = csv.NewWriter(enc.buf)
+	}
+	return enc
+}
+
+// Encode appends one message
+func (e *historyEncoder) Encode(m *ExportedMessage) error {
+	defer func() { e.count++ }()
+
+	switch e.format {
+	case HistoryFormatCSV:
+		if e.count == 0 {
+			if err := e.csv.Write(historyCSVHeader); err != nil {
+				return err
+			}
+		}
+		return e.csv.Write([]string{
+			m.ID, m.RoomID, m.UserID, m.Username, m.Content, m.Type,
+			m.ReplyToID, strconv.FormatBool(m.IsEdited), m.CreatedAt, m.UpdatedAt,
+		})
+	case HistoryFormatNDJSON:
+		return e.writeJSON(m, "", "\n")
+	default:
+		prefix := ","
+		if e.count == 0 {
+			prefix = "["
+		}
+		return e.writeJSON(m, prefix, "")
+	}
+}
+
+func (e *historyEncoder) writeJSON(m *ExportedMessage, prefix, suffix string) error {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode message %s: %w", m.ID, err)
+	}
+	e.buf.WriteString(prefix)
+	e.buf.Write(raw)
+	_, err = e.buf.WriteString(suffix)
+	return err
+}
+
+// Flush pushes buffered messages through to the client
+func (e *historyEncoder) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := e.buf.Flush(); err != nil {
+		return err
+	}
+	if f, ok := e.dst.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// Close finishes the document; an empty history still gets a CSV header
+// or an empty JSON array
+func (e *historyEncoder) Close() error {
+	switch e.format {
+	case HistoryFormatCSV:
+		if e.count == 0 {
+			if err := e.csv.Write(historyCSVHeader); err != nil {
+				return err
+			}
+		}
+	case HistoryFormatJSON:
+		if e.count == 0 {
+			e.buf.WriteString("[")
+		}
+		e.buf.WriteString("]\n")
+	}
+	return e.Flush()
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+func TestParseHistoryFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    HistoryFormat
+		wantErr bool
+	}{
+		{"", HistoryFormatJSON, false},
+		{"json", HistoryFormatJSON, false},
+		{"csv", HistoryFormatCSV, false},
+		{"ndjson", HistoryFormatNDJSON, false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseHistoryFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseHistoryFormat(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestHistoryEncoder(t *testing.T) {
+	messages := []*ExportedMessage{
+		{ID: "1", Username: "alice", Content: "hello, world", Type: "text"},
+		{ID: "2", Username: "bob", Content: "line one\nline two", Type: "text", IsEdited: true},
+	}
+	encode := func(format HistoryFormat, messages []*ExportedMessage) string {
+		var buf bytes.Buffer
+		enc := newHistoryEncoder(format, &buf)
+		for _, m := range messages {
+			if err := enc.Encode(m); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		return buf.String()
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var decoded []ExportedMessage
+		if err := json.Unmarshal([]byte(encode(HistoryFormatJSON, messages)), &decoded); err != nil {
+			t.Fatalf("Expected a JSON array: %v", err)
+		}
+		if len(decoded) != 2 || decoded[1].Content != "line one\nline two" {
+			t.Errorf("Unexpected messages: %+v", decoded)
+		}
+
+		if err := json.Unmarshal([]byte(encode(HistoryFormatJSON, nil)), &decoded); err != nil || len(decoded) != 0 {
+			t.Errorf("Expected an empty array, got %v, %v", decoded, err)
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSuffix(encode(HistoryFormatNDJSON, messages), "\n"), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected 2 lines, got %d", len(lines))
+		}
+		var m ExportedMessage
+		if err := json.Unmarshal([]byte(lines[0]), &m); err != nil || m.ID != "1" {
+			t.Errorf("Unexpected first line %q: %v", lines[0], err)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		records, err := csv.NewReader(strings.NewReader(encode(HistoryFormatCSV, messages))).ReadAll()
+		if err != nil {
+			t.Fatalf("Expected valid CSV: %v", err)
+		}
+		if len(records) != 3 || records[0][0] != "id" {
+			t.Fatalf("Expected header and 2 rows, got %v", records)
+		}
+		if records[1][4] != "hello, world" || records[2][7] != "true" {
+			t.Errorf("Unexpected rows: %v", records[1:])
+		}
+
+		records, _ = csv.NewReader(strings.NewReader(encode(HistoryFormatCSV, nil))).ReadAll()
+		if len(records) != 1 {
+			t.Errorf("Expected only the header for an empty history, got %v", records)
+		}
+	})
+}
+
+func TestMessageService_ExportHistory(t *testing.T) {
+	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
+	defer db.Close()
+	defer cleanupMessageServiceTestByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	owner := createUserForMessageServiceTestIsolated(t, db, prefix, "owner")
+	outsider := createUserForMessageServiceTestIsolated(t, db, prefix, "outsider")
+	room := createRoomForMessageServiceTestIsolated(t, db, prefix, owner, roomService)
+
+	var deleted *model.MessageWithUser
+	for _, content := range []string{"first", "second", "third"} {
+		msg, err := msgService.SendMessage(ctx, &SendMessageInput{
+			RoomID:  room.ID,
+			UserID:  owner.ID,
+			Content: content,
+			Type:    model.MessageTypeText,
+		})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+		if content == "second" {
+			deleted = msg
+		}
+	}
+	if err := msgService.DeleteMessage(ctx, deleted.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+
+	if _, err := msgService.ExportHistory(ctx, room.ID, outsider.ID, HistoryFormatNDJSON); err == nil {
+		t.Error("Expected non-member to be refused")
+	}
+
+	export, err := msgService.ExportHistory(ctx, room.ID, owner.ID, HistoryFormatNDJSON)
+	if err != nil {
+		t.Fatalf("Failed to prepare export: %v", err)
+	}
+
+	var buf bytes.Buffer
+	count, err := export.WriteTo(ctx, &buf)
+	if err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 messages without the deleted one, got %d", count)
+	}
+	if strings.Contains(buf.String(), `"second"`) {
+		t.Error("Expected deleted message to be left out")
+	}
+	if !strings.HasSuffix(export.Filename, ".ndjson") {
+		t.Errorf("Unexpected filename %q", export.Filename)
+	}
+}
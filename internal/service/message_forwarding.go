@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+var ErrMessageNotForwardable = apperrors.New(http.StatusBadRequest, "此訊息無法轉傳")
+
+// ForwardSource is a message the caller may forward: its content, the
+// uploads it attaches and the provenance recorded on the copy
+type ForwardSource struct {
+	Content       string
+	Type          model.MessageType
+	AttachmentIDs []string
+	From          *model.ForwardedFrom
+}
+
+// forwardable checks the message kinds that may be copied elsewhere
+func forwardable(msgType model.MessageType) bool {
+	// Encrypted messages are opaque to the server
+	return msgType != model.MessageTypeSystem && msgType != model.MessageTypeCiphertext
+}
+
+// ForwardSource loads a room message for forwarding. The caller must be
+// able to read the room's history.
+func (s *MessageService) ForwardSource(ctx context.Context, roomID, messageID, userID string) (*ForwardSource, error) {
+	if err := s.authorize(ctx, roomID, userID, policy.CanReadHistory); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		if err == repository.ErrMessageNotFound {
+			return nil, apperrors.ErrNotFound
+		}
+		s.logger.Error("Failed to get message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if msg.RoomID != roomID {
+		return nil, apperrors.ErrNotFound
+	}
+	if msg.IsDeleted || !forwardable(msg.Type) {
+		return nil, ErrMessageNotForwardable
+	}
+
+	return &ForwardSource{
+		Content:       msg.Content,
+		Type:          msg.Type,
+		AttachmentIDs: attachmentUploadIDs(s.attachments.ForMessages(ctx, []string{msg.ID})[msg.ID]),
+		From: &model.ForwardedFrom{
+			Source:    model.ForwardSourceRoom,
+			MessageID: msg.ID,
+			RoomID:    msg.RoomID,
+			UserID:    msg.UserID,
+			CreatedAt: msg.CreatedAt,
+		},
+	}, nil
+}
+
+// ForwardSource loads a direct message from the caller's conversation with
+// otherUserID for forwarding. Messages the caller deleted are not visible.
+func (s *DirectMessageService) ForwardSource(ctx context.Context, userID, otherUserID, messageID string) (*ForwardSource, error) {
+	msg, err := s.dmRepo.GetByID(ctx, messageID)
+	if err != nil {
+		if err == repository.ErrDirectMessageNotFound {
+			return nil, apperrors.ErrNotFound
+		}
+		s.logger.Error("Failed to get direct message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	sent := msg.SenderID == userID && msg.ReceiverID == otherUserID && !msg.IsDeletedBySender
+	received := msg.SenderID == otherUserID && msg.ReceiverID == userID && !msg.IsDeletedByReceiver
+	if !sent && !received {
+		return nil, apperrors.ErrNotFound
+	}
+	if !forwardable(msg.Type) {
+		return nil, ErrMessageNotForwardable
+	}
+
+	return &ForwardSource{
+		Content:       msg.Content,
+		Type:          msg.Type,
+		AttachmentIDs: attachmentUploadIDs(s.attachments.ForDirectMessages(ctx, []string{msg.ID})[msg.ID]),
+		From: &model.ForwardedFrom{
+			Source:    model.ForwardSourceDM,
+			MessageID: msg.ID,
+			UserID:    msg.SenderID,
+			CreatedAt: msg.CreatedAt,
+		},
+	}, nil
+}
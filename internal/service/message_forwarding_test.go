@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+)
+
+func TestMessageService_ForwardSource(t *testing.T) {
+	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
+	defer db.Close()
+	defer cleanupMessageServiceTestByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	author := createUserForMessageServiceTestIsolated(t, db, prefix, "author")
+	outsider := createUserForMessageServiceTestIsolated(t, db, prefix, "outsider")
+	source := createRoomForMessageServiceTestIsolated(t, db, prefix, author, roomService)
+	other := createRoomForMessageServiceTestIsolated(t, db, prefix+"_other", author, roomService)
+
+	original, err := msgService.SendMessage(ctx, &SendMessageInput{
+		RoomID:  source.ID,
+		UserID:  author.ID,
+		Content: "worth sharing",
+		Type:    model.MessageTypeText,
+	})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if _, err := msgService.ForwardSource(ctx, other.ID, original.ID, author.ID); err != apperrors.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for message from another room, got %v", err)
+	}
+	if _, err := msgService.ForwardSource(ctx, source.ID, original.ID, outsider.ID); err == nil {
+		t.Error("Expected non-member to be refused")
+	}
+
+	src, err := msgService.ForwardSource(ctx, source.ID, original.ID, author.ID)
+	if err != nil {
+		t.Fatalf("Failed to load forward source: %v", err)
+	}
+
+	copied, err := msgService.SendMessage(ctx, &SendMessageInput{
+		RoomID:        other.ID,
+		UserID:        author.ID,
+		Content:       src.Content,
+		Type:          src.Type,
+		ForwardedFrom: src.From,
+	})
+	if err != nil {
+		t.Fatalf("Failed to forward message: %v", err)
+	}
+
+	from := copied.GetForwardedFrom()
+	if from == nil {
+		t.Fatal("Expected forwarded message to carry its provenance")
+	}
+	if from.Source != model.ForwardSourceRoom || from.MessageID != original.ID || from.RoomID != source.ID || from.UserID != author.ID {
+		t.Errorf("Unexpected provenance: %+v", from)
+	}
+	if original.GetForwardedFrom() != nil {
+		t.Error("Expected original message to have no provenance")
+	}
+}
+
+func TestDirectMessageService_ForwardSource(t *testing.T) {
+	service, db, prefix := setupTestDMServiceIsolated(t)
+	defer db.Close()
+	defer cleanupDMServiceTestByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	alice := createUserForDMServiceTestIsolated(t, db, prefix, "alice")
+	bob := createUserForDMServiceTestIsolated(t, db, prefix, "bob")
+	carol := createUserForDMServiceTestIsolated(t, db, prefix, "carol")
+
+	original, err := service.SendMessage(ctx, &SendDMInput{
+		SenderID:   alice.ID,
+		ReceiverID: bob.ID,
+		Content:    "pass it on",
+		Type:       model.MessageTypeText,
+	})
+	if err != nil {
+		t.Fatalf("Failed to send direct message: %v", err)
+	}
+
+	// Only the two participants can see the message
+	if _, err := service.ForwardSource(ctx, carol.ID, alice.ID, original.ID); err != apperrors.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for outsider, got %v", err)
+	}
+
+	src, err := service.ForwardSource(ctx, bob.ID, alice.ID, original.ID)
+	if err != nil {
+		t.Fatalf("Failed to load forward source: %v", err)
+	}
+	if src.From.Source != model.ForwardSourceDM || src.From.UserID != alice.ID || src.From.RoomID != "" {
+		t.Errorf("Unexpected provenance: %+v", src.From)
+	}
+
+	copied, err := service.SendMessage(ctx, &SendDMInput{
+		SenderID:      bob.ID,
+		ReceiverID:    carol.ID,
+		Content:       src.Content,
+		Type:          src.Type,
+		ForwardedFrom: src.From,
+	})
+	if err != nil {
+		t.Fatalf("Failed to forward direct message: %v", err)
+	}
+	if from := copied.GetForwardedFrom(); from == nil || from.MessageID != original.ID {
+		t.Errorf("Expected forward to reference %s, got %+v", original.ID, from)
+	}
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/moderation"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMessageProfane      = apperrors.New(http.StatusUnprocessableEntity, "訊息含有不當用語，此聊天室不允許送出")
+	ErrMessageBlocked      = apperrors.New(http.StatusUnprocessableEntity, "訊息違反內容規範，無法送出")
+	ErrMessageFlagNotFound = apperrors.New(http.StatusNotFound, "標記不存在或已審核")
+)
+
+// Review outcomes for flagged messages
+const (
+	MessageReviewApprove = "approve"
+	MessageReviewRemove  = "remove"
+)
+
+// MessageModerationThresholds are the moderation API scores, between 0 and
+// 1, at which a message is queued for review or refused. Strict rooms use
+// their own, lower, pair.
+type MessageModerationThresholds struct {
+	Flag        float64
+	Block       float64
+	StrictFlag  float64
+	StrictBlock float64
+}
+
+// DefaultMessageModerationThresholds apply to zero thresholds
+var DefaultMessageModerationThresholds = MessageModerationThresholds{
+	Flag:        0.8,
+	Block:       0.95,
+	StrictFlag:  0.5,
+	StrictBlock: 0.8,
+}
+
+// MessageModerator checks message text before it is stored, following the
+// room's moderation level. Listed words are masked in standard rooms and
+// refused in strict ones. With a classifier, messages scoring at or above
+// the block threshold are refused and those at or above the flag threshold
+// are sent but queued for review.
+type MessageModerator struct {
+	words      *moderation.WordFilter
+	classifier moderation.TextClassifier
+	thresholds MessageModerationThresholds
+	logger     *zap.Logger
+}
+
+// NewMessageModerator creates a moderator; classifier may be nil to check
+// the word list only
+func NewMessageModerator(words *moderation.WordFilter, classifier moderation.TextClassifier, thresholds MessageModerationThresholds, logger *zap.Logger) *MessageModerator {
+	defaults := DefaultMessageModerationThresholds
+	if thresholds.Flag <= 0 {
+		thresholds.Flag = defaults.Flag
+	}
+	if thresholds.Block <= 0 {
+		thresholds.Block = defaults.Block
+	}
+	if thresholds.StrictFlag <= 0 {
+		thresholds.StrictFlag = defaults.StrictFlag
+	}
+	if thresholds.StrictBlock <= 0 {
+		thresholds.StrictBlock = defaults.StrictBlock
+	}
+	return &MessageModerator{
+		words:      words,
+		classifier: classifier,
+		thresholds: thresholds,
+		logger:     logger,
+	}
+}
+
+// ModerationVerdict is what moderation made of a message
+type ModerationVerdict struct {
+	Content string             // the content to store, listed words masked
+	Flag    *model.MessageFlag // set when the message must be queued for review
+}
+
+// Check moderates content posted in a room with the given level. It returns
+// ErrMessageProfane or ErrMessageBlocked when the message must not be sent.
+// A failing classifier lets the message through, flagged in strict rooms.
+func (m *MessageModerator) Check(ctx context.Context, level model.ModerationLevel, content string) (*ModerationVerdict, error) {
+	verdict := &ModerationVerdict{Content: content}
+	if m == nil || level == model.ModerationOff {
+		return verdict, nil
+	}
+	strict := level == model.ModerationStrict
+
+	if words := m.words.Find(content); len(words) > 0 {
+		if strict {
+			return nil, ErrMessageProfane
+		}
+		verdict.Content = m.words.Mask(content)
+	}
+
+	if m.classifier == nil {
+		return verdict, nil
+	}
+	flagAt, blockAt := m.thresholds.Flag, m.thresholds.Block
+	if strict {
+		flagAt, blockAt = m.thresholds.StrictFlag, m.thresholds.StrictBlock
+	}
+
+	result, err := m.classifier.Classify(ctx, content)
+	if err != nil {
+		if ctxErr := checkContext(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		m.logger.Warn("Message classification failed", zap.Error(err))
+		if strict {
+			verdict.Flag = &model.MessageFlag{Reason: model.MessageFlagScanFailed}
+		}
+		return verdict, nil
+	}
+
+	switch {
+	case result.Score >= blockAt:
+		return nil, ErrMessageBlocked
+	case result.Score >= flagAt:
+		verdict.Flag = &model.MessageFlag{
+			Reason: model.MessageFlagClassifier,
+			Score:  sql.NullFloat64{Float64: result.Score, Valid: true},
+			Labels: joinLabels(result.Labels),
+		}
+	}
+	return verdict, nil
+}
+
+// validateModerationLevel checks a room moderation level
+func validateModerationLevel(level model.ModerationLevel) error {
+	if !level.IsValid() {
+		return apperrors.ErrValidation.WithDetails(map[string]string{
+			"moderation_level": "必須為 off、standard 或 strict",
+		})
+	}
+	return nil
+}
+
+// SetModeration enables moderation of text messages; flags stores the
+// messages queued for review
+func (s *MessageService) SetModeration(moderator *MessageModerator, flags MessageFlagStore) {
+	s.moderator = moderator
+	s.flags = flags
+}
+
+// moderate checks the content of a text message for the room it is posted
+// in
+func (s *MessageService) moderate(ctx context.Context, roomID, content string) (*ModerationVerdict, error) {
+	if s.moderator == nil {
+		return &ModerationVerdict{Content: content}, nil
+	}
+
+	level := model.ModerationStandard
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		s.logger.Warn("Failed to get room moderation level", zap.String("room_id", roomID), zap.Error(err))
+	} else {
+		level = room.ModerationLevel
+	}
+	return s.moderator.Check(ctx, level, content)
+}
+
+// flagMessage queues a stored message for review. The message is already
+// visible, so a failure is only logged.
+func (s *MessageService) flagMessage(ctx context.Context, msg *model.Message, flag *model.MessageFlag) {
+	flag.MessageID = msg.ID
+	flag.RoomID = msg.RoomID
+	flag.UserID = nullString(msg.UserID)
+	if err := s.flags.Create(ctx, flag); err != nil {
+		s.logger.Error("Failed to flag message", zap.String("message_id", msg.ID), zap.Error(err))
+		return
+	}
+
+	s.logger.Info("Message flagged for review",
+		zap.String("message_id", msg.ID),
+		zap.String("room_id", msg.RoomID),
+		zap.String("reason", string(flag.Reason)),
+	)
+}
+
+// MessageModerationService lets admins work through the messages
+// moderation queued for review
+type MessageModerationService struct {
+	flags   MessageFlagStore
+	auditor *AuditService
+	logger  *zap.Logger
+}
+
+func NewMessageModerationService(flags MessageFlagStore, logger *zap.Logger) *MessageModerationService {
+	return &MessageModerationService{
+		flags:  flags,
+		logger: logger,
+	}
+}
+
+// SetAuditor sets the audit service that records reviews
+func (s *MessageModerationService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// ListQueue lists flagged messages waiting for review, oldest first
+func (s *MessageModerationService) ListQueue(ctx context.Context, limit, offset int) ([]*model.MessageFlagWithMessage, error) {
+	flags, err := s.flags.ListPending(ctx, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list message review queue", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return flags, nil
+}
+
+// Review settles a flag: approve keeps the message, remove deletes it
+func (s *MessageModerationService) Review(ctx context.Context, id, action, note, adminID string) (*model.MessageFlag, error) {
+	var status model.MessageFlagStatus
+	switch action {
+	case MessageReviewApprove:
+		status = model.MessageFlagApproved
+	case MessageReviewRemove:
+		status = model.MessageFlagRemoved
+	default:
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"action": "必須為 approve 或 remove",
+		})
+	}
+
+	flag, err := s.flags.Review(ctx, id, status, nullString(strings.TrimSpace(note)), adminID)
+	if err != nil {
+		if err == repository.ErrMessageFlagNotFound {
+			return nil, ErrMessageFlagNotFound
+		}
+		s.logger.Error("Failed to review message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Flagged message reviewed",
+		zap.String("flag_id", id),
+		zap.String("message_id", flag.MessageID),
+		zap.String("status", string(status)),
+		zap.String("reviewed_by", adminID),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    adminID,
+		Action:     model.AuditActionMessageReviewed,
+		TargetType: model.AuditTargetMessage,
+		TargetID:   flag.MessageID,
+		Metadata: map[string]interface{}{
+			"flag_id": flag.ID,
+			"room_id": flag.RoomID,
+			"status":  string(status),
+			"reason":  string(flag.Reason),
+		},
+	})
+	return flag, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/moderation"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type fakeTextClassifier struct {
+	score float64
+	err   error
+}
+
+func (c *fakeTextClassifier) Classify(ctx context.Context, text string) (*moderation.Result, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &moderation.Result{Score: c.score, Labels: []string{"insult"}}, nil
+}
+
+func TestMessageModerator_Check_WordList(t *testing.T) {
+	moderator := NewMessageModerator(moderation.NewWordFilter([]string{"idiot"}), nil, MessageModerationThresholds{}, zap.NewNop())
+	ctx := context.Background()
+
+	verdict, err := moderator.Check(ctx, model.ModerationOff, "you idiot")
+	if err != nil || verdict.Content != "you idiot" {
+		t.Errorf("Expected content untouched when moderation is off, got %+v, %v", verdict, err)
+	}
+
+	verdict, err = moderator.Check(ctx, model.ModerationStandard, "you idiot")
+	if err != nil || verdict.Content != "you *****" || verdict.Flag != nil {
+		t.Errorf("Expected listed word masked, got %+v, %v", verdict, err)
+	}
+
+	if _, err := moderator.Check(ctx, model.ModerationStrict, "you idiot"); err != ErrMessageProfane {
+		t.Errorf("Expected ErrMessageProfane in a strict room, got %v", err)
+	}
+
+	verdict, err = moderator.Check(ctx, model.ModerationStrict, "hello")
+	if err != nil || verdict.Content != "hello" {
+		t.Errorf("Expected clean message passed, got %+v, %v", verdict, err)
+	}
+}
+
+func TestMessageModerator_Check_Classifier(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		level     model.ModerationLevel
+		score     float64
+		err       error
+		wantErr   error
+		wantFlag  model.MessageFlagReason
+		wantScore bool
+	}{
+		{name: "Standard below threshold", level: model.ModerationStandard, score: 0.6},
+		{name: "Standard flagged", level: model.ModerationStandard, score: 0.85, wantFlag: model.MessageFlagClassifier, wantScore: true},
+		{name: "Standard blocked", level: model.ModerationStandard, score: 0.97, wantErr: ErrMessageBlocked},
+		{name: "Strict flagged", level: model.ModerationStrict, score: 0.6, wantFlag: model.MessageFlagClassifier, wantScore: true},
+		{name: "Strict blocked", level: model.ModerationStrict, score: 0.85, wantErr: ErrMessageBlocked},
+		{name: "Standard scan failure", level: model.ModerationStandard, err: errors.New("timeout")},
+		{name: "Strict scan failure", level: model.ModerationStrict, err: errors.New("timeout"), wantFlag: model.MessageFlagScanFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classifier := &fakeTextClassifier{score: tt.score, err: tt.err}
+			moderator := NewMessageModerator(nil, classifier, MessageModerationThresholds{}, zap.NewNop())
+
+			verdict, err := moderator.Check(ctx, tt.level, "message")
+			if err != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if tt.wantFlag == "" {
+				if verdict.Flag != nil {
+					t.Errorf("Expected no flag, got %+v", verdict.Flag)
+				}
+				return
+			}
+			if verdict.Flag == nil || verdict.Flag.Reason != tt.wantFlag {
+				t.Fatalf("Expected %s flag, got %+v", tt.wantFlag, verdict.Flag)
+			}
+			if verdict.Flag.Score.Valid != tt.wantScore {
+				t.Errorf("Expected score recorded %v, got %+v", tt.wantScore, verdict.Flag.Score)
+			}
+		})
+	}
+}
+
+func TestMessageModerator_Check_Nil(t *testing.T) {
+	var moderator *MessageModerator
+	verdict, err := moderator.Check(context.Background(), model.ModerationStrict, "anything")
+	if err != nil || verdict.Content != "anything" {
+		t.Errorf("Expected nil moderator to pass content, got %+v, %v", verdict, err)
+	}
+}
+
+func TestMessageModerationService_Review(t *testing.T) {
+	var gotStatus model.MessageFlagStatus
+	var gotNote sql.NullString
+	store := &mockMessageFlagStore{ReviewFunc: func(ctx context.Context, id string, status model.MessageFlagStatus, note sql.NullString, reviewedBy string) (*model.MessageFlag, error) {
+		gotStatus, gotNote = status, note
+		return &model.MessageFlag{ID: id, MessageID: "msg-1", Status: status}, nil
+	}}
+	service := NewMessageModerationService(store, zap.NewNop())
+	ctx := context.Background()
+
+	flag, err := service.Review(ctx, "flag-1", MessageReviewRemove, "  spam  ", "admin-1")
+	if err != nil {
+		t.Fatalf("Failed to review: %v", err)
+	}
+	if gotStatus != model.MessageFlagRemoved || flag.Status != model.MessageFlagRemoved {
+		t.Errorf("Expected removed, got %s", gotStatus)
+	}
+	if gotNote.String != "spam" {
+		t.Errorf("Expected trimmed note, got %q", gotNote.String)
+	}
+
+	if _, err := service.Review(ctx, "flag-1", "ignore", "", "admin-1"); apperrors.GetHTTPStatus(err) != 400 {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+	if store.Calls("Review") != 1 {
+		t.Error("Expected an unknown action not to reach the store")
+	}
+
+	service = NewMessageModerationService(&mockMessageFlagStore{}, zap.NewNop())
+	if _, err := service.Review(ctx, "flag-1", MessageReviewApprove, "", "admin-1"); err != ErrMessageFlagNotFound {
+		t.Errorf("Expected ErrMessageFlagNotFound, got %v", err)
+	}
+}
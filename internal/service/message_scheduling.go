@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// MinScheduleDelay is how far ahead a message must be scheduled
+	MinScheduleDelay = time.Minute
+	// MaxScheduleDelay is the furthest ahead a message may be scheduled
+	MaxScheduleDelay = 30 * 24 * time.Hour
+
+	// staleClaimTimeout returns messages claimed by a worker that died
+	// mid-delivery to the queue
+	staleClaimTimeout = 5 * time.Minute
+)
+
+var (
+	ErrInvalidScheduleTime      = apperrors.New(400, "排程時間需介於 1 分鐘後至 30 天內")
+	ErrScheduledMessageNotFound = apperrors.New(404, "排程訊息不存在或已送出")
+)
+
+// MessagePublisher broadcasts messages created outside a WebSocket
+// connection to the room's clients. It is implemented by ws.Hub.
+type MessagePublisher interface {
+	PublishMessage(msg *model.MessageWithUser)
+}
+
+// SetScheduledRepository enables scheduled messages
+func (s *MessageService) SetScheduledRepository(repo *repository.ScheduledMessageRepository) {
+	s.scheduledRepo = repo
+}
+
+// SetPublisher sets the publisher that broadcasts scheduled messages once sent
+func (s *MessageService) SetPublisher(publisher MessagePublisher) {
+	s.publisher = publisher
+}
+
+// ScheduleMessage stores a message to be sent at the given time. The sender
+// must be allowed to post now and is checked again on delivery.
+func (s *MessageService) ScheduleMessage(ctx context.Context, input *SendMessageInput, at time.Time) (*model.ScheduledMessage, error) {
+	if s.scheduledRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	now := time.Now()
+	if at.Before(now.Add(MinScheduleDelay)) || at.After(now.Add(MaxScheduleDelay)) {
+		return nil, ErrInvalidScheduleTime
+	}
+
+	if err := s.authorize(ctx, input.RoomID, input.UserID, policy.CanSend); err != nil {
+		if err == apperrors.ErrPermissionDenied {
+			return nil, s.sendDenial(ctx, input.RoomID, input.UserID)
+		}
+		return nil, err
+	}
+
+	if input.Type == "" {
+		input.Type = model.MessageTypeText
+	}
+	if input.Type == model.MessageTypeSticker {
+		if _, err := s.checkSticker(ctx, input.Content); err != nil {
+			return nil, err
+		}
+	}
+	if input.ReplyToID != "" {
+		if _, err := s.replyTarget(ctx, input.RoomID, input.ReplyToID); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := &model.ScheduledMessage{
+		RoomID:      input.RoomID,
+		UserID:      input.UserID,
+		Content:     input.Content,
+		Type:        input.Type,
+		ReplyToID:   sql.NullString{String: input.ReplyToID, Valid: input.ReplyToID != ""},
+		ScheduledAt: at,
+	}
+	if err := s.scheduledRepo.Create(ctx, msg); err != nil {
+		s.logger.Error("Failed to schedule message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return msg, nil
+}
+
+// ListScheduledMessages lists the caller's pending messages in a room
+func (s *MessageService) ListScheduledMessages(ctx context.Context, roomID, userID string, limit, offset int) ([]*model.ScheduledMessage, error) {
+	if s.scheduledRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	if err := s.authorize(ctx, roomID, userID, policy.CanAccess); err != nil {
+		return nil, err
+	}
+
+	msgs, err := s.scheduledRepo.ListPending(ctx, roomID, userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list scheduled messages", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return msgs, nil
+}
+
+// CancelScheduledMessage cancels one of the caller's pending messages
+func (s *MessageService) CancelScheduledMessage(ctx context.Context, roomID, id, userID string) error {
+	if s.scheduledRepo == nil {
+		return apperrors.ErrNotFound
+	}
+
+	msg, err := s.scheduledRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrScheduledMessageNotFound {
+			return ErrScheduledMessageNotFound
+		}
+		s.logger.Error("Failed to get scheduled message", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if msg.RoomID != roomID || msg.UserID != userID || !msg.IsPending() {
+		return ErrScheduledMessageNotFound
+	}
+
+	if err := s.scheduledRepo.Cancel(ctx, id); err != nil {
+		if err == repository.ErrScheduledMessageNotFound {
+			return ErrScheduledMessageNotFound
+		}
+		s.logger.Error("Failed to cancel scheduled message", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	return nil
+}
+
+// DeliverScheduledMessages sends up to limit due messages through the normal
+// send path and broadcasts them. Messages the sender may no longer post,
+// e.g. after leaving or being muted, are marked failed.
+func (s *MessageService) DeliverScheduledMessages(ctx context.Context, limit int) (int, error) {
+	if s.scheduledRepo == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	if released, err := s.scheduledRepo.ReleaseStale(ctx, now.Add(-staleClaimTimeout)); err != nil {
+		s.logger.Error("Failed to release stale scheduled messages", zap.Error(err))
+	} else if released > 0 {
+		s.logger.Warn("Released stale scheduled messages", zap.Int64("count", released))
+	}
+
+	due, err := s.scheduledRepo.ClaimDue(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, scheduled := range due {
+		msg, err := s.SendMessage(ctx, &SendMessageInput{
+			RoomID:    scheduled.RoomID,
+			UserID:    scheduled.UserID,
+			Content:   scheduled.Content,
+			Type:      scheduled.Type,
+			ReplyToID: scheduled.ReplyToID.String,
+		})
+		if err != nil {
+			s.logger.Info("Scheduled message not sent",
+				zap.String("scheduled_message_id", scheduled.ID),
+				zap.Error(err),
+			)
+			if err := s.scheduledRepo.MarkFailed(ctx, scheduled.ID, apperrors.GetMessage(err)); err != nil {
+				s.logger.Error("Failed to mark scheduled message failed", zap.Error(err))
+			}
+			continue
+		}
+
+		if err := s.scheduledRepo.MarkSent(ctx, scheduled.ID, msg.ID); err != nil {
+			s.logger.Error("Failed to mark scheduled message sent", zap.Error(err))
+		}
+		if s.publisher != nil {
+			s.publisher.PublishMessage(msg)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
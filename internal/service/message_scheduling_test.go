@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+type recordingPublisher struct {
+	mu   sync.Mutex
+	msgs []*model.MessageWithUser
+}
+
+func (p *recordingPublisher) PublishMessage(msg *model.MessageWithUser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, msg)
+}
+
+func TestMessageService_ScheduleMessage(t *testing.T) {
+	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
+	defer db.Close()
+	defer cleanupMessageServiceTestByPrefix(t, db, prefix)
+
+	scheduledRepo := repository.NewScheduledMessageRepository(db)
+	msgService.SetScheduledRepository(scheduledRepo)
+	publisher := &recordingPublisher{}
+	msgService.SetPublisher(publisher)
+
+	ctx := context.Background()
+	owner := createUserForMessageServiceTestIsolated(t, db, prefix, "owner")
+	outsider := createUserForMessageServiceTestIsolated(t, db, prefix, "outsider")
+	room := createRoomForMessageServiceTestIsolated(t, db, prefix, owner, roomService)
+
+	input := func(userID string) *SendMessageInput {
+		return &SendMessageInput{RoomID: room.ID, UserID: userID, Content: prefix + " later"}
+	}
+
+	t.Run("Rejects times outside the window", func(t *testing.T) {
+		for _, at := range []time.Time{time.Now(), time.Now().Add(MaxScheduleDelay + time.Hour)} {
+			if _, err := msgService.ScheduleMessage(ctx, input(owner.ID), at); err != ErrInvalidScheduleTime {
+				t.Errorf("Expected ErrInvalidScheduleTime, got %v", err)
+			}
+		}
+	})
+
+	t.Run("Non-member cannot schedule", func(t *testing.T) {
+		if _, err := msgService.ScheduleMessage(ctx, input(outsider.ID), time.Now().Add(time.Hour)); err == nil {
+			t.Error("Expected non-member to be denied")
+		}
+	})
+
+	t.Run("List and cancel", func(t *testing.T) {
+		scheduled, err := msgService.ScheduleMessage(ctx, input(owner.ID), time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to schedule message: %v", err)
+		}
+
+		msgs, err := msgService.ListScheduledMessages(ctx, room.ID, owner.ID, 20, 0)
+		if err != nil || len(msgs) != 1 || msgs[0].ID != scheduled.ID {
+			t.Fatalf("Expected the scheduled message to be listed, got %v (%v)", msgs, err)
+		}
+
+		if err := msgService.CancelScheduledMessage(ctx, room.ID, scheduled.ID, outsider.ID); err != ErrScheduledMessageNotFound {
+			t.Errorf("Expected other users not to cancel, got %v", err)
+		}
+		if err := msgService.CancelScheduledMessage(ctx, room.ID, scheduled.ID, owner.ID); err != nil {
+			t.Fatalf("Failed to cancel: %v", err)
+		}
+		if err := msgService.CancelScheduledMessage(ctx, room.ID, scheduled.ID, owner.ID); err != ErrScheduledMessageNotFound {
+			t.Errorf("Expected second cancel to fail, got %v", err)
+		}
+	})
+
+	t.Run("Delivers due messages", func(t *testing.T) {
+		scheduled, err := msgService.ScheduleMessage(ctx, input(owner.ID), time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to schedule message: %v", err)
+		}
+		if _, err := db.ExecContext(ctx, `UPDATE scheduled_messages SET scheduled_at = NOW() - INTERVAL '1 second' WHERE id = $1`, scheduled.ID); err != nil {
+			t.Fatalf("Failed to make message due: %v", err)
+		}
+
+		if _, err := msgService.DeliverScheduledMessages(ctx, 100); err != nil {
+			t.Fatalf("Failed to deliver: %v", err)
+		}
+
+		delivered, err := scheduledRepo.GetByID(ctx, scheduled.ID)
+		if err != nil {
+			t.Fatalf("Failed to reload scheduled message: %v", err)
+		}
+		if delivered.Status != model.ScheduledMessageSent || !delivered.MessageID.Valid {
+			t.Fatalf("Expected message to be sent, got %s", delivered.Status)
+		}
+
+		found := false
+		for _, msg := range publisher.msgs {
+			if msg.ID == delivered.MessageID.String {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("Expected delivered message to be broadcast")
+		}
+	})
+}
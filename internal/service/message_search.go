@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/search"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSearchSyncBatchSize is how many outbox entries one bulk request
+	// carries
+	DefaultSearchSyncBatchSize = 500
+
+	// searchSyncLease is how long an instance holds the entries it claimed
+	searchSyncLease = time.Minute
+)
+
+// MessageSearchIndex is an external search engine holding a copy of the
+// messages. It is implemented by search.ElasticIndex.
+type MessageSearchIndex interface {
+	Bulk(ctx context.Context, docs []*search.MessageDocument, deleted []string) error
+	Search(ctx context.Context, q *search.MessageQuery) ([]string, error)
+}
+
+var _ MessageSearchIndex = (*search.ElasticIndex)(nil)
+
+// SetSearchIndex makes Search query an external search engine instead of
+// Postgres. The index is kept up to date by a SearchIndexer.
+func (s *MessageService) SetSearchIndex(index MessageSearchIndex) {
+	s.searchIndex = index
+}
+
+// searchEngine runs q against the search engine and loads the matches in
+// its ranking order. Matches deleted since they were indexed are left out.
+func (s *MessageService) searchEngine(ctx context.Context, q *search.MessageQuery) ([]*model.MessageWithUser, error) {
+	ids, err := s.searchIndex.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.messageRepo.ListByIDsWithUser(ctx, q.RoomID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.MessageWithUser, len(found))
+	for _, msg := range found {
+		byID[msg.ID] = msg
+	}
+
+	messages := make([]*model.MessageWithUser, 0, len(found))
+	for _, id := range ids {
+		if msg, ok := byID[id]; ok {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// SearchIndexer copies message changes recorded in the search outbox to
+// the search engine. Messages that are gone or deleted are removed from
+// the index; everything else is (re)indexed with its current content.
+type SearchIndexer struct {
+	searchRepo  *repository.SearchRepository
+	messageRepo *repository.MessageRepository
+	index       MessageSearchIndex
+	batchSize   int
+	logger      *zap.Logger
+}
+
+// NewSearchIndexer creates an indexer; batchSize <= 0 uses the default
+func NewSearchIndexer(searchRepo *repository.SearchRepository, messageRepo *repository.MessageRepository, index MessageSearchIndex, batchSize int, logger *zap.Logger) *SearchIndexer {
+	if batchSize <= 0 {
+		batchSize = DefaultSearchSyncBatchSize
+	}
+	return &SearchIndexer{
+		searchRepo:  searchRepo,
+		messageRepo: messageRepo,
+		index:       index,
+		batchSize:   batchSize,
+		logger:      logger,
+	}
+}
+
+// Sync drains the outbox batch by batch and returns how many entries it
+// processed. A batch the search engine rejects is retried once its lease
+// runs out.
+func (i *SearchIndexer) Sync(ctx context.Context) (int, error) {
+	synced := 0
+	for ctx.Err() == nil {
+		n, err := i.syncBatch(ctx)
+		synced += n
+		if err != nil {
+			return synced, err
+		}
+		if n < i.batchSize {
+			break
+		}
+	}
+	return synced, nil
+}
+
+func (i *SearchIndexer) syncBatch(ctx context.Context) (int, error) {
+	entries, err := i.searchRepo.ClaimOutbox(ctx, i.batchSize, searchSyncLease)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	// A message changed several times is indexed once
+	entryIDs := make([]int64, len(entries))
+	seen := make(map[string]bool, len(entries))
+	var messageIDs []string
+	for n, entry := range entries {
+		entryIDs[n] = entry.ID
+		if !seen[entry.MessageID] {
+			seen[entry.MessageID] = true
+			messageIDs = append(messageIDs, entry.MessageID)
+		}
+	}
+
+	messages, err := i.messageRepo.ListByIDs(ctx, messageIDs)
+	if err != nil {
+		return 0, err
+	}
+	var docs []*search.MessageDocument
+	for _, msg := range messages {
+		if msg.IsDeleted {
+			continue
+		}
+		delete(seen, msg.ID)
+		docs = append(docs, &search.MessageDocument{
+			ID:        msg.ID,
+			RoomID:    msg.RoomID,
+			UserID:    msg.UserID,
+			Content:   msg.Content,
+			CreatedAt: msg.CreatedAt,
+		})
+	}
+	deleted := make([]string, 0, len(seen))
+	for id := range seen {
+		deleted = append(deleted, id)
+	}
+
+	if err := i.index.Bulk(ctx, docs, deleted); err != nil {
+		return 0, fmt.Errorf("failed to sync search index: %w", err)
+	}
+	if err := i.searchRepo.DeleteOutbox(ctx, entryIDs); err != nil {
+		return 0, err
+	}
+
+	i.logger.Debug("Search index synced", zap.Int("indexed", len(docs)), zap.Int("removed", len(deleted)))
+	return len(entries), nil
+}
@@ -3,31 +3,183 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"regexp"
+	"time"
 
+	"github.com/go-demo/chat/internal/anomaly"
+	"github.com/go-demo/chat/internal/events"
 	"github.com/go-demo/chat/internal/model"
 	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/search"
+	"github.com/go-demo/chat/internal/policy"
 	"github.com/go-demo/chat/internal/repository"
 	"go.uber.org/zap"
 )
 
+const (
+	// RoomEventMessageUpdated pushes an edited message to the room
+	RoomEventMessageUpdated = "message_updated"
+	// RoomEventMessageDeleted tells room clients to remove a deleted message
+	RoomEventMessageDeleted = "message_deleted"
+)
+
+// MessageDeletedEvent tells room clients to remove a deleted message
+type MessageDeletedEvent struct {
+	MessageID string `json:"message_id"`
+	RoomID    string `json:"room_id"`
+}
+
 type MessageService struct {
-	messageRepo *repository.MessageRepository
-	roomRepo    *repository.RoomRepository
+	messageRepo MessageStore
+	roomRepo    RoomMemberLookup
+	permRepo    *repository.RoomPermissionRepository
+	policy      *policy.Engine
+	notifier    *NotificationService
+	webhooks    *WebhookDispatcher
 	logger      *zap.Logger
+
+	// Incoming webhooks posting as bot users
+	incomingRepo *repository.IncomingWebhookRepository
+
+	// Scheduled delivery
+	scheduledRepo *repository.ScheduledMessageRepository
+	publisher     MessagePublisher
+
+	anomalies        *anomaly.Detector
+	rateLimiter      MessageRateLimiter
+	defaultRateLimit int
+
+	events *events.Publisher
+
+	// Write-behind persistence; nil inserts every message inline
+	writer *MessageWriter
+	users  *UserService
+
+	// External search engine; nil searches Postgres
+	searchIndex MessageSearchIndex
+
+	// Link previews fetched after sending; nil adds none
+	previews *LinkPreviewer
+
+	// Sticker catalog; nil rejects sticker messages
+	stickers StickerStore
+
+	// Uploads attached to messages; nil refuses attachments
+	attachments *AttachmentService
+
+	// Text moderation; nil sends messages unchecked
+	moderator *MessageModerator
+	flags     MessageFlagStore
 }
 
 func NewMessageService(
-	messageRepo *repository.MessageRepository,
-	roomRepo *repository.RoomRepository,
+	messageRepo MessageStore,
+	roomRepo RoomMemberLookup,
 	logger *zap.Logger,
 ) *MessageService {
 	return &MessageService{
 		messageRepo: messageRepo,
 		roomRepo:    roomRepo,
+		policy:      policy.Default(),
 		logger:      logger,
 	}
 }
 
+// SetPolicy sets the policy engine used for authorization decisions
+func (s *MessageService) SetPolicy(engine *policy.Engine) {
+	s.policy = engine
+}
+
+// SetPermissionRepository enables per-room role permission overrides
+func (s *MessageService) SetPermissionRepository(repo *repository.RoomPermissionRepository) {
+	s.permRepo = repo
+}
+
+// SetNotifier sets the notification service used for mention and reply notifications
+func (s *MessageService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// SetWebhooks sets the dispatcher notifying room webhooks of new messages
+func (s *MessageService) SetWebhooks(dispatcher *WebhookDispatcher) {
+	s.webhooks = dispatcher
+}
+
+// SetAnomalyDetector sets the detector watching for message floods from
+// one network
+func (s *MessageService) SetAnomalyDetector(detector *anomaly.Detector) {
+	s.anomalies = detector
+}
+
+// SetEvents sets the publisher message.created is sent to
+func (s *MessageService) SetEvents(publisher *events.Publisher) {
+	s.events = publisher
+}
+
+// authorize returns ErrPermissionDenied unless the user may perform the action in the room
+func (s *MessageService) authorize(ctx context.Context, roomID, userID string, action policy.Action) error {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return apperrors.ErrRoomNotFound
+		}
+		s.logger.Error("Failed to get room", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	subject, err := loadSubject(ctx, s.roomRepo, s.permRepo, room, userID)
+	if err != nil {
+		s.logger.Error("Failed to load policy subject", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	if !s.policy.Can(subject, action) {
+		return apperrors.ErrPermissionDenied
+	}
+	return nil
+}
+
+// sendDenial explains why a member may not post: an archived room, muted,
+// a read-only announcement room, or simply lacking the send permission
+func (s *MessageService) sendDenial(ctx context.Context, roomID, userID string) error {
+	member, err := s.roomRepo.GetMember(ctx, roomID, userID)
+	if err != nil {
+		return apperrors.ErrPermissionDenied
+	}
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err == nil && room.IsArchived() {
+		return apperrors.ErrRoomArchived
+	}
+	if member.IsMutedAt(time.Now()) {
+		return apperrors.ErrMemberMuted
+	}
+	if err == nil && room.IsAnnouncement() && !member.CanModerate() {
+		return apperrors.ErrRoomReadOnly
+	}
+	return apperrors.ErrPermissionDenied
+}
+
+// ErrInvalidReplyTarget is returned when a message replies to one that is
+// deleted, missing or in another room
+var ErrInvalidReplyTarget = apperrors.New(400, "回覆的訊息不存在或不在此聊天室")
+
+// replyTarget loads the message a new message in roomID replies to
+func (s *MessageService) replyTarget(ctx context.Context, roomID, replyToID string) (*model.Message, error) {
+	parent, err := s.messageRepo.GetByID(ctx, replyToID)
+	if err != nil {
+		if err == repository.ErrMessageNotFound {
+			return nil, ErrInvalidReplyTarget
+		}
+		s.logger.Error("Failed to get replied message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if parent.RoomID != roomID || parent.IsDeleted {
+		return nil, ErrInvalidReplyTarget
+	}
+	return parent, nil
+}
+
 // SendMessageInput represents message sending input
 type SendMessageInput struct {
 	RoomID    string
@@ -35,18 +187,34 @@ type SendMessageInput struct {
 	Content   string
 	Type      model.MessageType
 	ReplyToID string
+
+	// AttachmentIDs are the caller's uploads to attach to the message
+	AttachmentIDs []string
+
+	// Set when the message is a forward; stored as its provenance
+	ForwardedFrom *model.ForwardedFrom
+
+	// WriteBehind lets a text message be stored asynchronously when the
+	// write-behind pipeline is enabled; see SetWriteBehind
+	WriteBehind bool
 }
 
 // SendMessage sends a message to a room
 func (s *MessageService) SendMessage(ctx context.Context, input *SendMessageInput) (*model.MessageWithUser, error) {
-	// Check if user is a member of the room
-	isMember, err := s.roomRepo.IsMember(ctx, input.RoomID, input.UserID)
-	if err != nil {
-		s.logger.Error("Failed to check membership", zap.Error(err))
-		return nil, apperrors.ErrInternal
+	// Members may post unless muted or the room is read-only
+	if err := s.authorize(ctx, input.RoomID, input.UserID, policy.CanSend); err != nil {
+		if err == apperrors.ErrPermissionDenied {
+			return nil, s.sendDenial(ctx, input.RoomID, input.UserID)
+		}
+		return nil, err
 	}
-	if !isMember {
-		return nil, apperrors.ErrPermissionDenied
+	if err := s.checkRoomRate(ctx, input.RoomID, input.UserID); err != nil {
+		return nil, err
+	}
+	if input.ReplyToID != "" {
+		if _, err := s.replyTarget(ctx, input.RoomID, input.ReplyToID); err != nil {
+			return nil, err
+		}
 	}
 
 	// Set default type
@@ -54,6 +222,34 @@ func (s *MessageService) SendMessage(ctx context.Context, input *SendMessageInpu
 		input.Type = model.MessageTypeText
 	}
 
+	var sticker *model.Sticker
+	if input.Type == model.MessageTypeSticker {
+		var err error
+		if sticker, err = s.checkSticker(ctx, input.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	uploads, err := s.attachments.Resolve(ctx, &AttachInput{
+		UserID:    input.UserID,
+		UploadIDs: input.AttachmentIDs,
+		FileURL:   attachableURL(input.Type, input.Content),
+		Forwarded: input.ForwardedFrom != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var flag *model.MessageFlag
+	if input.Type == model.MessageTypeText {
+		verdict, err := s.moderate(ctx, input.RoomID, input.Content)
+		if err != nil {
+			return nil, err
+		}
+		input.Content = verdict.Content
+		flag = verdict.Flag
+	}
+
 	msg := &model.Message{
 		RoomID:  input.RoomID,
 		UserID:  input.UserID,
@@ -65,6 +261,43 @@ func (s *MessageService) SendMessage(ctx context.Context, input *SendMessageInpu
 		msg.ReplyToID = sql.NullString{String: input.ReplyToID, Valid: true}
 	}
 
+	if input.ForwardedFrom != nil {
+		from, err := json.Marshal(input.ForwardedFrom)
+		if err != nil {
+			return nil, apperrors.ErrInternal
+		}
+		msg.ForwardedFrom = from
+	}
+
+	var msgWithUser *model.MessageWithUser
+	// Text can be broadcast before it is stored; images and files are
+	// inserted first so triggers such as NSFW flagging apply to the broadcast.
+	// Flagged text and text with attachments are inserted first too, as the
+	// flag and the attachments refer to it.
+	if input.WriteBehind && s.writer != nil && msg.Type == model.MessageTypeText && flag == nil && len(uploads) == 0 {
+		msgWithUser, err = s.enqueueMessage(ctx, msg)
+	} else {
+		msgWithUser, err = s.createMessage(ctx, msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if flag != nil {
+		s.flagMessage(ctx, &msgWithUser.Message, flag)
+	}
+	msgWithUser.Sticker = sticker
+	msgWithUser.Attachments = s.attachments.AttachToMessage(ctx, msgWithUser.ID, uploads)
+	s.anomalies.Record(ctx, anomaly.SignalMessage, anomaly.NetworkKey(anomaly.ClientIP(ctx)))
+
+	s.notifyRecipients(ctx, msgWithUser)
+	s.emitMessageCreated(ctx, msgWithUser)
+	s.previews.Enqueue(&msgWithUser.Message)
+
+	return msgWithUser, nil
+}
+
+// createMessage inserts a message and reads it back with its sender
+func (s *MessageService) createMessage(ctx context.Context, msg *model.Message) (*model.MessageWithUser, error) {
 	if err := s.messageRepo.Create(ctx, msg); err != nil {
 		s.logger.Error("Failed to create message", zap.Error(err))
 		return nil, apperrors.ErrInternal
@@ -76,10 +309,108 @@ func (s *MessageService) SendMessage(ctx context.Context, input *SendMessageInpu
 		s.logger.Error("Failed to get message with user", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
-
 	return msgWithUser, nil
 }
 
+// emitMessageCreated queues message.created for the event bus and the
+// room's webhooks
+func (s *MessageService) emitMessageCreated(ctx context.Context, msg *model.MessageWithUser) {
+	s.events.Publish(events.MessageCreated, &events.MessageCreatedData{
+		MessageID: msg.ID,
+		RoomID:    msg.RoomID,
+		UserID:    msg.UserID,
+		Type:      string(msg.Type),
+		Content:   msg.Content,
+		ReplyToID: msg.ReplyToID.String,
+		CreatedAt: msg.CreatedAt,
+	})
+
+	if s.webhooks == nil {
+		return
+	}
+	s.webhooks.Emit(ctx, msg.RoomID, model.WebhookEventMessageCreated, &WebhookMessageData{
+		ID:          msg.ID,
+		UserID:      msg.UserID,
+		Username:    msg.Username,
+		DisplayName: msg.GetUserDisplayName(),
+		Content:     msg.Content,
+		Type:        string(msg.Type),
+		ReplyToID:   msg.ReplyToID.String,
+		CreatedAt:   msg.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// mentionPattern matches @username using the same charset as usernames
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@([a-zA-Z0-9_-]{3,50})`)
+
+// parseMentions returns the distinct usernames mentioned in content
+func parseMentions(content string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if name := match[1]; !seen[name] {
+			seen[name] = true
+			usernames = append(usernames, name)
+		}
+	}
+	return usernames
+}
+
+// notifyRecipients queues mention and reply notifications for a new message.
+// Failures are logged only; the message itself has already been stored.
+func (s *MessageService) notifyRecipients(ctx context.Context, msg *model.MessageWithUser) {
+	if s.notifier == nil {
+		return
+	}
+
+	actor := msg.GetUserDisplayName()
+	notified := map[string]bool{msg.UserID: true}
+
+	if usernames := parseMentions(msg.Content); len(usernames) > 0 {
+		userIDs, err := s.roomRepo.ListMemberIDsByUsernames(ctx, msg.RoomID, usernames)
+		if err != nil {
+			s.logger.Warn("Failed to resolve mentions", zap.String("message_id", msg.ID), zap.Error(err))
+		}
+		for _, userID := range userIDs {
+			if notified[userID] {
+				continue
+			}
+			notified[userID] = true
+			// Keyed by room so a burst of mentions collapses into one notification
+			s.notifier.NotifyBatched(userID, actor, &NotifyInput{
+				Type:          model.NotificationTypeMention,
+				Title:         actor + " 提及了你",
+				Content:       msg.Content,
+				ReferenceID:   msg.RoomID,
+				ReferenceType: "room",
+				SourceType:    model.NotificationTargetRoom,
+				SourceID:      msg.RoomID,
+			})
+		}
+	}
+
+	if msg.ReplyToID.Valid {
+		// Only a live message in the same room is notified of replies
+		parent, err := s.replyTarget(ctx, msg.RoomID, msg.ReplyToID.String)
+		if err != nil {
+			s.logger.Warn("Not notifying reply", zap.String("message_id", msg.ID), zap.Error(err))
+			return
+		}
+		if notified[parent.UserID] {
+			return
+		}
+		s.notifier.NotifyBatched(parent.UserID, actor, &NotifyInput{
+			Type:          model.NotificationTypeReply,
+			Title:         actor + " 回覆了你的訊息",
+			Content:       msg.Content,
+			ReferenceID:   parent.ID,
+			ReferenceType: "message",
+			SourceType:    model.NotificationTargetRoom,
+			SourceID:      msg.RoomID,
+		})
+	}
+}
+
 // GetByID retrieves a message by ID
 func (s *MessageService) GetByID(ctx context.Context, id string) (*model.MessageWithUser, error) {
 	msg, err := s.messageRepo.GetByIDWithUser(ctx, id)
@@ -90,6 +421,8 @@ func (s *MessageService) GetByID(ctx context.Context, id string) (*model.Message
 		s.logger.Error("Failed to get message", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
+	s.attachStickers(ctx, msg)
+	s.fillAttachments(ctx, msg)
 	return msg, nil
 }
 
@@ -114,12 +447,37 @@ func (s *MessageService) UpdateMessage(ctx context.Context, messageID, userID, c
 		return nil, apperrors.New(400, "無法編輯已刪除的訊息")
 	}
 
+	if msg.Type == model.MessageTypeSticker {
+		return nil, apperrors.New(400, "貼圖訊息無法編輯")
+	}
+
+	var flag *model.MessageFlag
+	if msg.Type == model.MessageTypeText {
+		verdict, err := s.moderate(ctx, msg.RoomID, content)
+		if err != nil {
+			return nil, err
+		}
+		content = verdict.Content
+		flag = verdict.Flag
+	}
+
 	if err := s.messageRepo.Update(ctx, messageID, content); err != nil {
 		s.logger.Error("Failed to update message", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
 
-	return s.messageRepo.GetByIDWithUser(ctx, messageID)
+	updated, err := s.messageRepo.GetByIDWithUser(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if flag != nil {
+		s.flagMessage(ctx, &updated.Message, flag)
+	}
+	if s.notifier != nil {
+		s.notifier.PublishToRoom(updated.RoomID, RoomEventMessageUpdated, updated)
+	}
+	s.previews.Enqueue(&updated.Message)
+	return updated, nil
 }
 
 // DeleteMessage soft deletes a message
@@ -135,10 +493,8 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID, userID st
 
 	// Check ownership or moderation permission
 	if msg.UserID != userID {
-		// Check if user can moderate
-		member, err := s.roomRepo.GetMember(ctx, msg.RoomID, userID)
-		if err != nil || !member.CanModerate() {
-			return apperrors.ErrPermissionDenied
+		if err := s.authorize(ctx, msg.RoomID, userID, policy.CanModerate); err != nil {
+			return err
 		}
 	}
 
@@ -152,29 +508,21 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID, userID st
 		zap.String("deleted_by", userID),
 	)
 
+	if s.notifier != nil {
+		s.notifier.PublishToRoom(msg.RoomID, RoomEventMessageDeleted, &MessageDeletedEvent{
+			MessageID: messageID,
+			RoomID:    msg.RoomID,
+		})
+	}
+
 	return nil
 }
 
 // ListByRoomID retrieves messages for a room
 func (s *MessageService) ListByRoomID(ctx context.Context, roomID, userID string, limit, offset int) ([]*model.MessageWithUser, error) {
-	// Check if user is a member
-	isMember, err := s.roomRepo.IsMember(ctx, roomID, userID)
-	if err != nil {
-		return nil, apperrors.ErrInternal
-	}
-
-	// For public rooms, allow non-members to view
-	if !isMember {
-		room, err := s.roomRepo.GetByID(ctx, roomID)
-		if err != nil {
-			if err == repository.ErrRoomNotFound {
-				return nil, apperrors.ErrRoomNotFound
-			}
-			return nil, apperrors.ErrInternal
-		}
-		if !room.IsPublic() {
-			return nil, apperrors.ErrPermissionDenied
-		}
+	// Public rooms allow non-members to view
+	if err := s.authorize(ctx, roomID, userID, policy.CanReadHistory); err != nil {
+		return nil, err
 	}
 
 	messages, err := s.messageRepo.ListByRoomID(ctx, roomID, limit, offset)
@@ -183,41 +531,68 @@ func (s *MessageService) ListByRoomID(ctx context.Context, roomID, userID string
 		return nil, apperrors.ErrInternal
 	}
 
+	s.attachStickers(ctx, messages...)
+	s.fillAttachments(ctx, messages...)
 	return messages, nil
 }
 
 // ListSince retrieves messages since a specific message ID
 func (s *MessageService) ListSince(ctx context.Context, roomID, userID, sinceID string, limit int) ([]*model.MessageWithUser, error) {
-	// Check if user is a member
-	isMember, err := s.roomRepo.IsMember(ctx, roomID, userID)
-	if err != nil {
-		return nil, apperrors.ErrInternal
-	}
-	if !isMember {
-		return nil, apperrors.ErrPermissionDenied
+	if err := s.authorize(ctx, roomID, userID, policy.CanAccess); err != nil {
+		return nil, err
 	}
 
 	messages, err := s.messageRepo.ListByRoomIDSince(ctx, roomID, sinceID, limit)
 	if err != nil {
+		if err == repository.ErrMessageNotFound {
+			return nil, apperrors.ErrNotFound
+		}
 		s.logger.Error("Failed to list messages since", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
 
+	s.attachStickers(ctx, messages...)
+	s.fillAttachments(ctx, messages...)
 	return messages, nil
 }
 
-// Search searches messages in a room
-func (s *MessageService) Search(ctx context.Context, roomID, userID, query string, limit, offset int) ([]*model.MessageWithUser, error) {
-	// Check if user is a member
-	isMember, err := s.roomRepo.IsMember(ctx, roomID, userID)
+// ListBefore retrieves the page of messages before a specific message ID
+func (s *MessageService) ListBefore(ctx context.Context, roomID, userID, beforeID string, limit int) ([]*model.MessageWithUser, error) {
+	if err := s.authorize(ctx, roomID, userID, policy.CanReadHistory); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.messageRepo.ListByRoomIDBefore(ctx, roomID, beforeID, limit)
 	if err != nil {
+		if err == repository.ErrMessageNotFound {
+			return nil, apperrors.ErrNotFound
+		}
+		s.logger.Error("Failed to list messages before", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
-	if !isMember {
-		return nil, apperrors.ErrPermissionDenied
+
+	s.attachStickers(ctx, messages...)
+	s.fillAttachments(ctx, messages...)
+	return messages, nil
+}
+
+// Search searches messages in a room. With a search engine set, results
+// are ranked by relevance; otherwise, or when the engine fails, Postgres
+// returns the newest matches first.
+func (s *MessageService) Search(ctx context.Context, userID string, q *search.MessageQuery) ([]*model.MessageWithUser, error) {
+	if err := s.authorize(ctx, q.RoomID, userID, policy.CanAccess); err != nil {
+		return nil, err
+	}
+
+	if s.searchIndex != nil {
+		messages, err := s.searchEngine(ctx, q)
+		if err == nil {
+			return messages, nil
+		}
+		s.logger.Warn("Search engine failed, falling back to Postgres", zap.Error(err))
 	}
 
-	messages, err := s.messageRepo.Search(ctx, roomID, query, limit, offset)
+	messages, err := s.messageRepo.Search(ctx, q)
 	if err != nil {
 		s.logger.Error("Failed to search messages", zap.Error(err))
 		return nil, apperrors.ErrInternal
@@ -234,13 +609,3 @@ func (s *MessageService) CountUnread(ctx context.Context, roomID, userID string)
 	}
 	return count, nil
 }
-
-// CreateAttachment creates a message attachment
-func (s *MessageService) CreateAttachment(ctx context.Context, att *model.MessageAttachment) error {
-	return s.messageRepo.CreateAttachment(ctx, att)
-}
-
-// GetAttachments retrieves attachments for a message
-func (s *MessageService) GetAttachments(ctx context.Context, messageID string) ([]*model.MessageAttachment, error) {
-	return s.messageRepo.GetAttachmentsByMessageID(ctx, messageID)
-}
@@ -2,9 +2,15 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
+	"time"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 
 	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/search"
 	"github.com/go-demo/chat/internal/repository"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -294,7 +300,7 @@ func TestMessageService_Search(t *testing.T) {
 	_, _ = msgService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: user.ID, Content: "Golang is great", Type: model.MessageTypeText})
 	_, _ = msgService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: user.ID, Content: "Testing", Type: model.MessageTypeText})
 
-	results, err := msgService.Search(ctx, room.ID, user.ID, "Golang", 10, 0)
+	results, err := msgService.Search(ctx, user.ID, &search.MessageQuery{RoomID: room.ID, Text: "Golang", Limit: 10})
 	if err != nil {
 		t.Fatalf("Failed to search messages: %v", err)
 	}
@@ -304,6 +310,60 @@ func TestMessageService_Search(t *testing.T) {
 	}
 }
 
+// fakeSearchIndex returns fixed IDs, or fails
+type fakeSearchIndex struct {
+	ids []string
+	err error
+}
+
+func (f *fakeSearchIndex) Bulk(ctx context.Context, docs []*search.MessageDocument, deleted []string) error {
+	return nil
+}
+
+func (f *fakeSearchIndex) Search(ctx context.Context, q *search.MessageQuery) ([]string, error) {
+	return f.ids, f.err
+}
+
+func TestMessageService_Search_Engine(t *testing.T) {
+	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
+	defer db.Close()
+	defer cleanupMessageServiceTestByPrefix(t, db, prefix)
+
+	user := createUserForMessageServiceTestIsolated(t, db, prefix, "sender")
+	ctx := context.Background()
+
+	room := createRoomForMessageServiceTestIsolated(t, db, prefix, user, roomService)
+
+	older, _ := msgService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: user.ID, Content: "Golang", Type: model.MessageTypeText})
+	newer, _ := msgService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: user.ID, Content: "Golang is great", Type: model.MessageTypeText})
+	if older == nil || newer == nil {
+		t.Fatal("Failed to send messages")
+	}
+
+	// The engine's ranking is kept; IDs no longer in Postgres are skipped
+	index := &fakeSearchIndex{ids: []string{older.ID, "00000000-0000-0000-0000-000000000000", newer.ID}}
+	msgService.SetSearchIndex(index)
+	q := &search.MessageQuery{RoomID: room.ID, Text: "Golang", Limit: 10}
+
+	results, err := msgService.Search(ctx, user.ID, q)
+	if err != nil {
+		t.Fatalf("Failed to search messages: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != older.ID || results[1].ID != newer.ID {
+		t.Errorf("Expected the engine's order, got %d results", len(results))
+	}
+
+	// A failing engine falls back to Postgres, newest first
+	index.err = errors.New("cluster unavailable")
+	results, err = msgService.Search(ctx, user.ID, q)
+	if err != nil {
+		t.Fatalf("Failed to search messages: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != newer.ID {
+		t.Errorf("Expected Postgres results newest first, got %d results", len(results))
+	}
+}
+
 func TestMessageService_SendMessage_WithReply(t *testing.T) {
 	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
 	defer db.Close()
@@ -371,3 +431,182 @@ func TestMessageService_SendMessage_MessageTypes(t *testing.T) {
 		}
 	}
 }
+
+// newMockMessageService returns a MessageService on mock stores where
+// userID is a member of roomID
+func newMockMessageService(roomID, userID string) (*MessageService, *mockMessageStore, *mockRoomMemberLookup) {
+	messages := &mockMessageStore{}
+	messages.CreateFunc = func(ctx context.Context, msg *model.Message) error {
+		msg.ID = "msg-new"
+		return nil
+	}
+	messages.GetByIDWithUserFunc = func(ctx context.Context, id string) (*model.MessageWithUser, error) {
+		return &model.MessageWithUser{Message: model.Message{ID: id, RoomID: roomID, UserID: userID, Type: model.MessageTypeText}}, nil
+	}
+
+	rooms := &mockRoomMemberLookup{}
+	rooms.GetByIDFunc = func(ctx context.Context, id string) (*model.Room, error) {
+		if id != roomID {
+			return nil, repository.ErrRoomNotFound
+		}
+		return &model.Room{ID: id, Type: model.RoomTypePublic}, nil
+	}
+	rooms.GetMemberFunc = func(ctx context.Context, id, memberID string) (*model.RoomMember, error) {
+		if id != roomID || memberID != userID {
+			return nil, repository.ErrNotRoomMember
+		}
+		return &model.RoomMember{RoomID: id, UserID: memberID, Role: model.MemberRoleMember}, nil
+	}
+
+	return NewMessageService(messages, rooms, zap.NewNop()), messages, rooms
+}
+
+func TestMessageService_SendMessage_Mocked(t *testing.T) {
+	ctx := context.Background()
+	service, messages, _ := newMockMessageService("room-1", "user-1")
+
+	msg, err := service.SendMessage(ctx, &SendMessageInput{RoomID: "room-1", UserID: "user-1", Content: "hello"})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if msg.ID != "msg-new" || messages.Calls("Create") != 1 {
+		t.Errorf("Expected the message to be stored, got %+v", msg)
+	}
+
+	if _, err := service.SendMessage(ctx, &SendMessageInput{RoomID: "room-1", UserID: "stranger", Content: "hello"}); err != apperrors.ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied for a non-member, got %v", err)
+	}
+	if _, err := service.SendMessage(ctx, &SendMessageInput{RoomID: "room-2", UserID: "user-1", Content: "hello"}); err != apperrors.ErrRoomNotFound {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+	if messages.Calls("Create") != 1 {
+		t.Errorf("Expected rejected messages not to be stored, got %d stored", messages.Calls("Create"))
+	}
+}
+
+func TestMessageService_UpdateMessage_Mocked(t *testing.T) {
+	ctx := context.Background()
+	service, messages, _ := newMockMessageService("room-1", "user-1")
+	messages.GetByIDFunc = func(ctx context.Context, id string) (*model.Message, error) {
+		return &model.Message{ID: id, RoomID: "room-1", UserID: "user-1", Type: model.MessageTypeText}, nil
+	}
+
+	if _, err := service.UpdateMessage(ctx, "msg-1", "user-2", "edited"); err != apperrors.ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied for another user's message, got %v", err)
+	}
+	if messages.Calls("Update") != 0 {
+		t.Error("Expected the message not to be updated")
+	}
+
+	publisher := &fakeRealtimePublisher{}
+	notifier := NewNotificationService(nil, zap.NewNop())
+	notifier.SetPublisher(publisher)
+	service.SetNotifier(notifier)
+
+	var updated string
+	messages.UpdateFunc = func(ctx context.Context, id, content string) error {
+		updated = content
+		return nil
+	}
+	if _, err := service.UpdateMessage(ctx, "msg-1", "user-1", "edited"); err != nil {
+		t.Fatalf("Failed to update message: %v", err)
+	}
+	if updated != "edited" {
+		t.Errorf("Expected content 'edited', got %q", updated)
+	}
+	if len(publisher.roomEvents) != 1 || publisher.roomEvents[0].eventType != RoomEventMessageUpdated {
+		t.Errorf("Expected a message_updated event, got %+v", publisher.roomEvents)
+	}
+}
+
+func TestMessageService_DeleteMessage_Mocked(t *testing.T) {
+	ctx := context.Background()
+	service, messages, _ := newMockMessageService("room-1", "user-1")
+	messages.GetByIDFunc = func(ctx context.Context, id string) (*model.Message, error) {
+		return &model.Message{ID: id, RoomID: "room-1", UserID: "user-1", Type: model.MessageTypeText}, nil
+	}
+	messages.SoftDeleteFunc = func(ctx context.Context, id string) error {
+		return nil
+	}
+
+	publisher := &fakeRealtimePublisher{}
+	notifier := NewNotificationService(nil, zap.NewNop())
+	notifier.SetPublisher(publisher)
+	service.SetNotifier(notifier)
+
+	if err := service.DeleteMessage(ctx, "msg-1", "user-1"); err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+	if len(publisher.roomEvents) != 1 {
+		t.Fatalf("Expected one room event, got %d", len(publisher.roomEvents))
+	}
+	event := publisher.roomEvents[0]
+	deleted, ok := event.payload.(*MessageDeletedEvent)
+	if event.roomID != "room-1" || event.eventType != RoomEventMessageDeleted || !ok || deleted.MessageID != "msg-1" {
+		t.Errorf("Expected a message_deleted event for msg-1, got %+v", event)
+	}
+}
+
+func TestMessageService_SendMessage_ReplyTarget(t *testing.T) {
+	ctx := context.Background()
+	service, messages, _ := newMockMessageService("room-1", "user-1")
+	parents := map[string]*model.Message{
+		"same-room":  {ID: "same-room", RoomID: "room-1", UserID: "author"},
+		"other-room": {ID: "other-room", RoomID: "room-2", UserID: "author"},
+		"deleted":    {ID: "deleted", RoomID: "room-1", UserID: "author", IsDeleted: true},
+	}
+	messages.GetByIDFunc = func(ctx context.Context, id string) (*model.Message, error) {
+		if parent, ok := parents[id]; ok {
+			return parent, nil
+		}
+		return nil, repository.ErrMessageNotFound
+	}
+	var stored *model.Message
+	messages.CreateFunc = func(ctx context.Context, msg *model.Message) error {
+		msg.ID = "msg-new"
+		stored = msg
+		return nil
+	}
+	messages.GetByIDWithUserFunc = func(ctx context.Context, id string) (*model.MessageWithUser, error) {
+		return &model.MessageWithUser{Message: *stored}, nil
+	}
+
+	// A long batch window keeps notifications pending where the test can
+	// see them
+	notifier := NewNotificationService(nil, zap.NewNop())
+	notifier.SetBatchWindow(time.Hour)
+	service.SetNotifier(notifier)
+	pending := func() int {
+		notifier.batchMu.Lock()
+		defer notifier.batchMu.Unlock()
+		return len(notifier.batches)
+	}
+
+	for _, replyTo := range []string{"other-room", "deleted", "missing"} {
+		_, err := service.SendMessage(ctx, &SendMessageInput{RoomID: "room-1", UserID: "user-1", Content: "re", ReplyToID: replyTo})
+		if err != ErrInvalidReplyTarget {
+			t.Errorf("Replying to %s: expected ErrInvalidReplyTarget, got %v", replyTo, err)
+		}
+	}
+	if messages.Calls("Create") != 0 || pending() != 0 {
+		t.Fatalf("Expected no message stored and no notification, got %d stored, %d pending", messages.Calls("Create"), pending())
+	}
+
+	// Stored messages replying across rooms are not notified either
+	service.notifyRecipients(ctx, &model.MessageWithUser{Message: model.Message{
+		ID:        "msg-old",
+		RoomID:    "room-1",
+		UserID:    "user-1",
+		ReplyToID: sql.NullString{String: "other-room", Valid: true},
+	}})
+	if pending() != 0 {
+		t.Errorf("Expected no reply notification for another room's message, got %d", pending())
+	}
+
+	if _, err := service.SendMessage(ctx, &SendMessageInput{RoomID: "room-1", UserID: "user-1", Content: "re", ReplyToID: "same-room"}); err != nil {
+		t.Fatalf("Failed to reply: %v", err)
+	}
+	if pending() != 1 {
+		t.Errorf("Expected the author to be notified of the reply, got %d pending", pending())
+	}
+}
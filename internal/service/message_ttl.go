@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Message TTL bounds, in seconds
+const (
+	MinMessageTTL = 30
+	MaxMessageTTL = 30 * 24 * 60 * 60
+)
+
+// Realtime events for disappearing messages
+const (
+	RoomEventMessageExpired = "message_expired"
+	DMEventMessageExpired   = "dm_expired"
+)
+
+var ErrInvalidMessageTTL = apperrors.New(400, "訊息存活時間需為 0（關閉）或介於 30 秒至 30 天")
+
+// MessageExpiredEvent tells room clients to remove an expired message
+type MessageExpiredEvent struct {
+	MessageID string `json:"message_id"`
+	RoomID    string `json:"room_id"`
+}
+
+// DMExpiredEvent tells both participants to remove an expired direct message
+type DMExpiredEvent struct {
+	MessageID  string `json:"message_id"`
+	SenderID   string `json:"sender_id"`
+	ReceiverID string `json:"receiver_id"`
+}
+
+// validateMessageTTL accepts 0 (off) or a TTL within bounds
+func validateMessageTTL(seconds int) error {
+	if seconds != 0 && (seconds < MinMessageTTL || seconds > MaxMessageTTL) {
+		return ErrInvalidMessageTTL
+	}
+	return nil
+}
+
+// ExpireMessages soft deletes up to limit room messages past their TTL and
+// tells the rooms' clients to drop them
+func (s *MessageService) ExpireMessages(ctx context.Context, limit int) (int, error) {
+	expired, err := s.messageRepo.ExpireDue(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(expired) > 0 {
+		s.logger.Info("Expired messages", zap.Int("count", len(expired)))
+	}
+
+	if s.notifier != nil {
+		for _, msg := range expired {
+			s.notifier.PublishToRoom(msg.RoomID, RoomEventMessageExpired, &MessageExpiredEvent{
+				MessageID: msg.ID,
+				RoomID:    msg.RoomID,
+			})
+		}
+	}
+
+	return len(expired), nil
+}
+
+// ExpireMessages deletes up to limit direct messages past their TTL and
+// tells both participants to drop them
+func (s *DirectMessageService) ExpireMessages(ctx context.Context, limit int) (int, error) {
+	expired, err := s.dmRepo.ExpireDue(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(expired) > 0 {
+		s.logger.Info("Expired direct messages", zap.Int("count", len(expired)))
+	}
+
+	if s.notifier != nil {
+		for _, msg := range expired {
+			event := &DMExpiredEvent{
+				MessageID:  msg.ID,
+				SenderID:   msg.SenderID,
+				ReceiverID: msg.ReceiverID,
+			}
+			s.notifier.PublishToUser(msg.SenderID, DMEventMessageExpired, event)
+			s.notifier.PublishToUser(msg.ReceiverID, DMEventMessageExpired, event)
+		}
+	}
+
+	return len(expired), nil
+}
+
+// GetConversationSettings returns the shared settings of a DM conversation
+func (s *DirectMessageService) GetConversationSettings(ctx context.Context, userID, otherUserID string) (*model.DirectConversationSettings, error) {
+	if _, err := s.userRepo.GetByID(ctx, otherUserID); err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	settings, err := s.dmRepo.GetSettings(ctx, userID, otherUserID)
+	if err != nil {
+		s.logger.Error("Failed to get conversation settings", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return settings, nil
+}
+
+// SetMessageTTL turns disappearing messages on or off for a DM
+// conversation. Either participant may change it; the TTL applies to
+// messages sent afterwards.
+func (s *DirectMessageService) SetMessageTTL(ctx context.Context, userID, otherUserID string, seconds int) (*model.DirectConversationSettings, error) {
+	if userID == otherUserID {
+		return nil, apperrors.ErrCannotMessageSelf
+	}
+	if err := validateMessageTTL(seconds); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, otherUserID); err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	blocked, err := s.blockedRepo.IsBlockedEither(ctx, userID, otherUserID)
+	if err != nil {
+		return nil, apperrors.ErrInternal
+	}
+	if blocked {
+		return nil, apperrors.ErrUserBlocked
+	}
+
+	settings, err := s.dmRepo.SetMessageTTL(ctx, userID, otherUserID, seconds, userID)
+	if err != nil {
+		s.logger.Error("Failed to set conversation message ttl", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return settings, nil
+}
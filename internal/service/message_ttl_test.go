@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+func TestValidateMessageTTL(t *testing.T) {
+	tests := []struct {
+		seconds int
+		valid   bool
+	}{
+		{0, true},
+		{MinMessageTTL, true},
+		{MaxMessageTTL, true},
+		{MinMessageTTL - 1, false},
+		{MaxMessageTTL + 1, false},
+		{-1, false},
+	}
+
+	for _, tt := range tests {
+		if err := validateMessageTTL(tt.seconds); (err == nil) != tt.valid {
+			t.Errorf("validateMessageTTL(%d) = %v, expected valid %v", tt.seconds, err, tt.valid)
+		}
+	}
+}
+
+func TestMessageService_ExpireMessages(t *testing.T) {
+	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
+	defer db.Close()
+	defer cleanupMessageServiceTestByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	owner := createUserForMessageServiceTestIsolated(t, db, prefix, "owner")
+	room := createRoomForMessageServiceTestIsolated(t, db, prefix, owner, roomService)
+
+	ttl := 60
+	if _, err := roomService.Update(ctx, &UpdateRoomInput{RoomID: room.ID, UserID: owner.ID, MessageTTL: &ttl}); err != nil {
+		t.Fatalf("Failed to set message ttl: %v", err)
+	}
+
+	msg, err := msgService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: owner.ID, Content: "vanishing"})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if msg.ExpiresAt == nil {
+		t.Fatal("Expected message in a TTL room to have an expiry")
+	}
+
+	if _, err := db.Exec(`UPDATE messages SET expires_at = NOW() - INTERVAL '1 second' WHERE id = $1`, msg.ID); err != nil {
+		t.Fatalf("Failed to backdate expiry: %v", err)
+	}
+
+	if _, err := msgService.ExpireMessages(ctx, 100); err != nil {
+		t.Fatalf("Failed to expire messages: %v", err)
+	}
+
+	var deleted bool
+	if err := db.Get(&deleted, `SELECT is_deleted FROM messages WHERE id = $1`, msg.ID); err != nil {
+		t.Fatalf("Failed to load message: %v", err)
+	}
+	if !deleted {
+		t.Error("Expected expired message to be deleted")
+	}
+}
+
+func TestDirectMessageService_SetMessageTTL(t *testing.T) {
+	service, db, prefix := setupTestDMServiceIsolated(t)
+	defer db.Close()
+	defer cleanupDMServiceTestByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	alice := createUserForDMServiceTestIsolated(t, db, prefix, "alice")
+	bob := createUserForDMServiceTestIsolated(t, db, prefix, "bob")
+	defer db.Exec(`DELETE FROM direct_conversation_settings WHERE user_low = $1 OR user_high = $1`, alice.ID)
+
+	if _, err := service.SetMessageTTL(ctx, alice.ID, bob.ID, 5); err != ErrInvalidMessageTTL {
+		t.Errorf("Expected ErrInvalidMessageTTL, got %v", err)
+	}
+
+	if _, err := service.SetMessageTTL(ctx, alice.ID, bob.ID, 3600); err != nil {
+		t.Fatalf("Failed to set message ttl: %v", err)
+	}
+
+	// Settings are shared, so bob sees what alice set
+	settings, err := service.GetConversationSettings(ctx, bob.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to get settings: %v", err)
+	}
+	if settings.MessageTTL != 3600 || settings.UpdatedBy.String != alice.ID {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+
+	msg, err := service.SendMessage(ctx, &SendDMInput{
+		SenderID:   bob.ID,
+		ReceiverID: alice.ID,
+		Content:    "vanishing",
+		Type:       model.MessageTypeText,
+	})
+	if err != nil {
+		t.Fatalf("Failed to send direct message: %v", err)
+	}
+	if msg.ExpiresAt == nil {
+		t.Fatal("Expected direct message to have an expiry")
+	}
+
+	if _, err := db.Exec(`UPDATE direct_messages SET expires_at = NOW() - INTERVAL '1 second' WHERE id = $1`, msg.ID); err != nil {
+		t.Fatalf("Failed to backdate expiry: %v", err)
+	}
+	if n, err := service.ExpireMessages(ctx, 100); err != nil || n < 1 {
+		t.Fatalf("Expected the message to expire, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/idgen"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// messageWriteStream holds messages accepted but not yet in Postgres
+	messageWriteStream = "messages:write"
+	// messageWriteDeadStream keeps messages Postgres refused, with the error
+	messageWriteDeadStream = "messages:write:dead"
+	messageWriteGroup      = "message-writers"
+
+	// DefaultWriteBatchSize is how many messages one insert writes at most
+	DefaultWriteBatchSize = 200
+	// maxWriteBatchSize keeps a batch under Postgres' bind parameter limit
+	maxWriteBatchSize = 1000
+	// DefaultWriteFlushInterval is how long a partial batch waits for more messages
+	DefaultWriteFlushInterval = 100 * time.Millisecond
+	// DefaultWriteClaimAfter is how long a message read by a writer stays
+	// unacknowledged before another writer takes it over
+	DefaultWriteClaimAfter = 30 * time.Second
+
+	writeRetryDelay = time.Second
+	writeTimeout    = 10 * time.Second
+)
+
+// WriteBehindConfig tunes a MessageWriter; zero values use the defaults
+type WriteBehindConfig struct {
+	BatchSize     int
+	FlushInterval time.Duration
+
+	// AtLeastOnce keeps each message in the stream until it is inserted,
+	// and hands messages of a writer that died mid-batch to another one.
+	// Without it a message is dropped from the stream once read, so a
+	// crash or an outage loses the batch in flight.
+	AtLeastOnce bool
+	ClaimAfter  time.Duration
+
+	// Consumer names this writer in the consumer group; it should be stable
+	// across restarts so the instance resumes its own unfinished batch
+	Consumer string
+}
+
+// MessageWriter persists messages write-behind. The send path appends each
+// message to a Redis stream and returns, so it can be broadcast without
+// waiting on Postgres; a worker reads the stream through a consumer group
+// and inserts the messages in batches. Inserts skip IDs already stored, so
+// a message delivered twice is written once.
+type MessageWriter struct {
+	redis  *redis.Client
+	repo   *repository.MessageRepository
+	config WriteBehindConfig
+	logger *zap.Logger
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewMessageWriter creates a writer; call Start to run its worker
+func NewMessageWriter(redisClient *redis.Client, repo *repository.MessageRepository, config WriteBehindConfig, logger *zap.Logger) *MessageWriter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultWriteBatchSize
+	}
+	if config.BatchSize > maxWriteBatchSize {
+		config.BatchSize = maxWriteBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultWriteFlushInterval
+	}
+	if config.ClaimAfter <= 0 {
+		config.ClaimAfter = DefaultWriteClaimAfter
+	}
+	return &MessageWriter{
+		redis:  redisClient,
+		repo:   repo,
+		config: config,
+		logger: logger,
+	}
+}
+
+// pendingMessage is a message as it travels through the stream
+type pendingMessage struct {
+	ID            string            `json:"id"`
+	RoomID        string            `json:"room_id"`
+	UserID        string            `json:"user_id"`
+	Content       string            `json:"content"`
+	Type          model.MessageType `json:"type"`
+	ReplyToID     string            `json:"reply_to_id,omitempty"`
+	ForwardedFrom json.RawMessage   `json:"forwarded_from,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// Enqueue appends a message to the stream. The message must already carry
+// its ID and creation time; once Enqueue returns, the writers insert it.
+func (w *MessageWriter) Enqueue(ctx context.Context, msg *model.Message) error {
+	data, err := json.Marshal(&pendingMessage{
+		ID:            msg.ID,
+		RoomID:        msg.RoomID,
+		UserID:        msg.UserID,
+		Content:       msg.Content,
+		Type:          msg.Type,
+		ReplyToID:     msg.ReplyToID.String,
+		ForwardedFrom: msg.ForwardedFrom,
+		CreatedAt:     msg.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	if err := w.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: messageWriteStream,
+		Values: map[string]interface{}{"message": data},
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue message: %w", err)
+	}
+	return nil
+}
+
+// Start creates the consumer group if needed and launches the worker
+func (w *MessageWriter) Start(ctx context.Context) error {
+	err := w.redis.XGroupCreateMkStream(ctx, messageWriteStream, messageWriteGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create message writer group: %w", err)
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.wg.Add(1)
+	go w.loop(ctx)
+
+	w.logger.Info("Message writer started",
+		zap.String("consumer", w.config.Consumer),
+		zap.Int("batch_size", w.config.BatchSize),
+		zap.Bool("at_least_once", w.config.AtLeastOnce),
+	)
+	return nil
+}
+
+// Stop stops reading the stream and waits for the batch in flight to be
+// written. Messages still in the stream are written after the next start.
+func (w *MessageWriter) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+
+	w.logger.Info("Message writer stopped")
+}
+
+func (w *MessageWriter) loop(ctx context.Context) {
+	defer w.wg.Done()
+
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		if w.config.AtLeastOnce && time.Since(lastClaim) >= w.config.ClaimAfter {
+			w.claim(ctx)
+			lastClaim = time.Now()
+		}
+
+		entries, err := w.read(ctx)
+		if len(entries) > 0 {
+			w.write(entries)
+		}
+		if err != nil && ctx.Err() == nil {
+			w.logger.Error("Failed to read message stream", zap.Error(err))
+			w.wait(ctx, writeRetryDelay)
+		}
+	}
+}
+
+// read collects up to a batch of new messages, waiting at most the flush
+// interval after the first one for the batch to fill
+func (w *MessageWriter) read(ctx context.Context) ([]redis.XMessage, error) {
+	var entries []redis.XMessage
+	var deadline time.Time
+
+	for len(entries) < w.config.BatchSize {
+		block := w.config.FlushInterval
+		if !deadline.IsZero() {
+			block = time.Until(deadline)
+			if block < time.Millisecond {
+				break
+			}
+		}
+
+		streams, err := w.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    messageWriteGroup,
+			Consumer: w.config.Consumer,
+			Streams:  []string{messageWriteStream, ">"},
+			Count:    int64(w.config.BatchSize - len(entries)),
+			Block:    block,
+			NoAck:    !w.config.AtLeastOnce,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				if len(entries) > 0 {
+					break
+				}
+				return nil, nil
+			}
+			return entries, err
+		}
+
+		for _, stream := range streams {
+			entries = append(entries, stream.Messages...)
+		}
+		if deadline.IsZero() && len(entries) > 0 {
+			deadline = time.Now().Add(w.config.FlushInterval)
+		}
+	}
+	return entries, nil
+}
+
+// claim takes over messages another writer read but never acknowledged
+func (w *MessageWriter) claim(ctx context.Context) {
+	start := "0-0"
+	for {
+		entries, next, err := w.redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   messageWriteStream,
+			Group:    messageWriteGroup,
+			Consumer: w.config.Consumer,
+			MinIdle:  w.config.ClaimAfter,
+			Start:    start,
+			Count:    int64(w.config.BatchSize),
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Error("Failed to claim pending messages", zap.Error(err))
+			}
+			return
+		}
+		if len(entries) > 0 {
+			w.logger.Info("Claimed pending messages", zap.Int("count", len(entries)))
+			w.write(entries)
+		}
+		if next == "0-0" || ctx.Err() != nil {
+			return
+		}
+		start = next
+	}
+}
+
+// write inserts a batch and removes the written messages from the stream.
+// It runs to completion even while stopping, so a batch read is not
+// abandoned halfway.
+func (w *MessageWriter) write(entries []redis.XMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	msgs := make([]*model.Message, 0, len(entries))
+	decoded := make([]redis.XMessage, 0, len(entries))
+	var done []string
+	for _, entry := range entries {
+		msg, err := decodePendingMessage(entry)
+		if err != nil {
+			w.deadLetter(ctx, entry, err)
+			done = append(done, entry.ID)
+			continue
+		}
+		msgs = append(msgs, msg)
+		decoded = append(decoded, entry)
+	}
+
+	_, err := w.repo.CreateBatch(ctx, msgs)
+	if err == nil {
+		for _, entry := range decoded {
+			done = append(done, entry.ID)
+		}
+		w.ack(ctx, done)
+		return
+	}
+	if !errors.Is(err, repository.ErrMessageRejected) {
+		w.retryLater(ctx, done, len(msgs), err)
+		return
+	}
+
+	// One bad message fails the whole insert; write them one by one so the
+	// rest still go through
+	var retry int
+	var retryErr error
+	for i, msg := range msgs {
+		_, err := w.repo.CreateBatch(ctx, []*model.Message{msg})
+		switch {
+		case err == nil:
+			done = append(done, decoded[i].ID)
+		case errors.Is(err, repository.ErrMessageRejected):
+			w.deadLetter(ctx, decoded[i], err)
+			done = append(done, decoded[i].ID)
+		default:
+			retry++
+			retryErr = err
+		}
+	}
+	if retry > 0 {
+		w.retryLater(ctx, done, retry, retryErr)
+		return
+	}
+	w.ack(ctx, done)
+}
+
+// retryLater leaves a batch Postgres could not take in the stream, where
+// at-least-once delivery hands it out again
+func (w *MessageWriter) retryLater(ctx context.Context, done []string, count int, err error) {
+	w.ack(ctx, done)
+	if w.config.AtLeastOnce {
+		w.logger.Warn("Failed to write message batch, will retry", zap.Int("count", count), zap.Error(err))
+		return
+	}
+	w.logger.Error("Failed to write message batch, messages lost", zap.Int("count", count), zap.Error(err))
+}
+
+// deadLetter moves a message Postgres refused aside for an operator to
+// inspect, so it does not block the stream
+func (w *MessageWriter) deadLetter(ctx context.Context, entry redis.XMessage, cause error) {
+	w.logger.Error("Dropping unwritable message",
+		zap.String("entry_id", entry.ID),
+		zap.Error(cause),
+	)
+	if err := w.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: messageWriteDeadStream,
+		Values: map[string]interface{}{"message": entry.Values["message"], "error": cause.Error()},
+	}).Err(); err != nil {
+		w.logger.Error("Failed to dead-letter message", zap.String("entry_id", entry.ID), zap.Error(err))
+	}
+}
+
+// ack removes written messages from the stream and the group's pending list
+func (w *MessageWriter) ack(ctx context.Context, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	pipe := w.redis.Pipeline()
+	if w.config.AtLeastOnce {
+		pipe.XAck(ctx, messageWriteStream, messageWriteGroup, ids...)
+	}
+	pipe.XDel(ctx, messageWriteStream, ids...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		w.logger.Warn("Failed to acknowledge written messages", zap.Int("count", len(ids)), zap.Error(err))
+	}
+}
+
+func (w *MessageWriter) wait(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func decodePendingMessage(entry redis.XMessage) (*model.Message, error) {
+	raw, ok := entry.Values["message"].(string)
+	if !ok {
+		return nil, errors.New("stream entry has no message")
+	}
+
+	var pending pendingMessage
+	if err := json.Unmarshal([]byte(raw), &pending); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	msg := &model.Message{
+		ID:        pending.ID,
+		RoomID:    pending.RoomID,
+		UserID:    pending.UserID,
+		Content:   pending.Content,
+		Type:      pending.Type,
+		CreatedAt: pending.CreatedAt,
+	}
+	if pending.ReplyToID != "" {
+		msg.ReplyToID = sql.NullString{String: pending.ReplyToID, Valid: true}
+	}
+	if len(pending.ForwardedFrom) > 0 {
+		msg.ForwardedFrom = pending.ForwardedFrom
+	}
+	return msg, nil
+}
+
+// SetWriteBehind stores messages sent with SendMessageInput.WriteBehind
+// through the writer instead of inserting them before the broadcast. users
+// supplies the sender's name and avatar the insert would have joined in.
+func (s *MessageService) SetWriteBehind(writer *MessageWriter, users *UserService) {
+	s.writer = writer
+	s.users = users
+}
+
+// enqueueMessage hands a message to the writer and returns it as it will be
+// stored. The ID is generated here, time-ordered whatever the configured
+// strategy, so the message can be referenced before it is written.
+func (s *MessageService) enqueueMessage(ctx context.Context, msg *model.Message) (*model.MessageWithUser, error) {
+	sender, err := s.users.GetDisplay(ctx, msg.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, createdAt, err := idgen.StrategyUUIDv7.NewID()
+	if err != nil {
+		s.logger.Error("Failed to generate message ID", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	msg.ID = id
+	msg.CreatedAt = createdAt
+	msg.UpdatedAt = createdAt
+
+	if err := s.writer.Enqueue(ctx, msg); err != nil {
+		s.logger.Error("Failed to enqueue message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return &model.MessageWithUser{
+		Message:     *msg,
+		Username:    sender.Username,
+		DisplayName: sql.NullString{String: sender.DisplayName, Valid: sender.DisplayName != ""},
+		AvatarURL:   sql.NullString{String: sender.AvatarURL, Valid: sender.AvatarURL != ""},
+		IsBot:       sender.IsBot,
+	}, nil
+}
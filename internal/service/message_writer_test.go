@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestNewMessageWriter_Defaults(t *testing.T) {
+	writer := NewMessageWriter(nil, nil, WriteBehindConfig{}, zap.NewNop())
+	if writer.config.BatchSize != DefaultWriteBatchSize {
+		t.Errorf("Expected default batch size, got %d", writer.config.BatchSize)
+	}
+	if writer.config.FlushInterval != DefaultWriteFlushInterval || writer.config.ClaimAfter != DefaultWriteClaimAfter {
+		t.Errorf("Expected default intervals, got %+v", writer.config)
+	}
+
+	writer = NewMessageWriter(nil, nil, WriteBehindConfig{BatchSize: 50000}, zap.NewNop())
+	if writer.config.BatchSize != maxWriteBatchSize {
+		t.Errorf("Expected batch size capped at %d, got %d", maxWriteBatchSize, writer.config.BatchSize)
+	}
+}
+
+func TestMessageWriter_EnqueueRoundTrip(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 15})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		t.Skipf("Skipping test, could not connect to test redis: %v", err)
+	}
+	defer client.Close()
+	client.Del(ctx, messageWriteStream)
+	defer client.Del(context.Background(), messageWriteStream)
+
+	writer := NewMessageWriter(client, nil, WriteBehindConfig{}, zap.NewNop())
+	sent := &model.Message{
+		ID:            "0190a1b2-c3d4-7e5f-8a9b-0c1d2e3f4a5b",
+		RoomID:        "room-1",
+		UserID:        "user-1",
+		Content:       "hello",
+		Type:          model.MessageTypeText,
+		ReplyToID:     sql.NullString{String: "parent-1", Valid: true},
+		ForwardedFrom: []byte(`{"room_id":"room-0"}`),
+		CreatedAt:     time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if err := writer.Enqueue(ctx, sent); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+
+	entries, err := client.XRange(ctx, messageWriteStream, "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one stream entry, got %d (%v)", len(entries), err)
+	}
+	got, err := decodePendingMessage(entries[0])
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if got.ID != sent.ID || got.Content != sent.Content || got.ReplyToID != sent.ReplyToID ||
+		string(got.ForwardedFrom) != string(sent.ForwardedFrom) || !got.CreatedAt.Equal(sent.CreatedAt) {
+		t.Errorf("Expected the message as enqueued, got %+v", got)
+	}
+}
+
+func TestDecodePendingMessage_Malformed(t *testing.T) {
+	if _, err := decodePendingMessage(redis.XMessage{ID: "1-0", Values: map[string]interface{}{}}); err == nil {
+		t.Error("Expected an error for an entry without a message")
+	}
+	if _, err := decodePendingMessage(redis.XMessage{ID: "1-0", Values: map[string]interface{}{"message": "{"}}); err == nil {
+		t.Error("Expected an error for malformed JSON")
+	}
+}
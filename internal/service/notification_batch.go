@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+)
+
+const (
+	// DefaultNotificationBatchWindow is how long triggers are coalesced before delivery
+	DefaultNotificationBatchWindow = 10 * time.Second
+
+	// Actor names listed in a batched notification before summarizing the rest
+	maxBatchActors = 3
+
+	// Timeout for delivering a flushed batch
+	batchDeliveryTimeout = 5 * time.Second
+)
+
+// Titles used when several triggers were coalesced into one notification
+var batchTitles = map[string]string{
+	model.NotificationTypeMention:       "%d 則新的提及",
+	model.NotificationTypeReply:         "%d 則新的回覆",
+	model.NotificationTypeReaction:      "%d 個新的反應",
+	model.NotificationTypeDirectMessage: "%d 則新的私訊",
+}
+
+// notificationBatch accumulates triggers for one user and reference
+type notificationBatch struct {
+	userID string
+	input  NotifyInput
+	count  int
+	actors []string
+	timer  *time.Timer
+}
+
+// SetBatchWindow sets the coalescing window; zero or negative disables batching
+func (s *NotificationService) SetBatchWindow(window time.Duration) {
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
+	s.batchWindow = window
+}
+
+// NotifyBatched queues a notification for userID. Triggers with the same type and
+// reference arriving within the batch window are delivered as one notification.
+// Triggers from a room or conversation the user has muted are dropped.
+func (s *NotificationService) NotifyBatched(userID, actor string, input *NotifyInput) {
+	if !s.allows(userID, input) {
+		return
+	}
+
+	s.batchMu.Lock()
+
+	if s.batchWindow <= 0 {
+		s.batchMu.Unlock()
+		s.deliver(userID, input)
+		return
+	}
+
+	key := batchKey(userID, input)
+	batch, ok := s.batches[key]
+	if !ok {
+		batch = &notificationBatch{userID: userID, input: *input}
+		s.batches[key] = batch
+		batch.timer = time.AfterFunc(s.batchWindow, func() {
+			s.flushBatch(key)
+		})
+	}
+	batch.count++
+	if actor != "" && !containsString(batch.actors, actor) {
+		batch.actors = append(batch.actors, actor)
+	}
+
+	s.batchMu.Unlock()
+}
+
+// Flush delivers every pending batch immediately, e.g. on shutdown
+func (s *NotificationService) Flush() {
+	s.batchMu.Lock()
+	keys := make([]string, 0, len(s.batches))
+	for key, batch := range s.batches {
+		batch.timer.Stop()
+		keys = append(keys, key)
+	}
+	s.batchMu.Unlock()
+
+	for _, key := range keys {
+		s.flushBatch(key)
+	}
+}
+
+// PendingBatches returns the number of batches waiting for their window to close
+func (s *NotificationService) PendingBatches() int {
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
+	return len(s.batches)
+}
+
+func (s *NotificationService) flushBatch(key string) {
+	s.batchMu.Lock()
+	batch, ok := s.batches[key]
+	if ok {
+		delete(s.batches, key)
+	}
+	s.batchMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	input := batch.input
+	if batch.count > 1 {
+		if title, ok := batchTitles[input.Type]; ok {
+			input.Title = fmt.Sprintf(title, batch.count)
+		}
+		input.Content = summarizeActors(batch.actors)
+	}
+	s.deliver(batch.userID, &input)
+}
+
+func (s *NotificationService) deliver(userID string, input *NotifyInput) {
+	ctx, cancel := context.WithTimeout(context.Background(), batchDeliveryTimeout)
+	defer cancel()
+
+	s.Notify(ctx, []string{userID}, input)
+}
+
+func batchKey(userID string, input *NotifyInput) string {
+	return userID + "|" + input.Type + "|" + input.ReferenceType + "|" + input.ReferenceID
+}
+
+// summarizeActors renders "alice、bob、carol 等 5 人"
+func summarizeActors(actors []string) string {
+	if len(actors) <= maxBatchActors {
+		return strings.Join(actors, "、")
+	}
+	return fmt.Sprintf("%s 等 %d 人", strings.Join(actors[:maxBatchActors], "、"), len(actors))
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []string
+	}{
+		{"hello @alice and @bob_2", []string{"alice", "bob_2"}},
+		{"@alice @alice again", []string{"alice"}},
+		{"mail me at me@example.com", nil},
+		{"@ab is too short", nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseMentions(tt.content); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("parseMentions(%q) = %v, expected %v", tt.content, got, tt.expected)
+		}
+	}
+}
+
+func TestSummarizeActors(t *testing.T) {
+	if got := summarizeActors([]string{"alice", "bob"}); got != "alice、bob" {
+		t.Errorf("Unexpected summary: %s", got)
+	}
+	if got := summarizeActors([]string{"a", "b", "c", "d", "e"}); got != "a、b、c 等 5 人" {
+		t.Errorf("Unexpected summary: %s", got)
+	}
+}
+
+func setupNotificationServiceIsolated(t *testing.T) (*NotificationService, *repository.NotificationRepository, *sqlx.DB, string) {
+	t.Helper()
+
+	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=chat_test sslmode=disable"
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Skipf("Skipping test, could not connect to test database: %v", err)
+	}
+
+	repo := repository.NewNotificationRepository(db)
+	return NewNotificationService(repo, zap.NewNop()), repo, db, repository.GenerateUniquePrefix()
+}
+
+func TestNotificationService_NotifyBatched_Coalesces(t *testing.T) {
+	svc, repo, db, prefix := setupNotificationServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "target")
+	svc.SetBatchWindow(time.Hour)
+
+	for i := 0; i < 4; i++ {
+		svc.NotifyBatched(user.ID, fmt.Sprintf("user%d", i%2), &NotifyInput{
+			Type:          model.NotificationTypeMention,
+			Title:         "someone 提及了你",
+			ReferenceID:   "room-1",
+			ReferenceType: "room",
+		})
+	}
+
+	if svc.PendingBatches() != 1 {
+		t.Fatalf("Expected 1 pending batch, got %d", svc.PendingBatches())
+	}
+
+	svc.Flush()
+
+	notifications, err := repo.ListByUserID(context.Background(), user.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list notifications: %v", err)
+	}
+	if len(notifications) != 1 {
+		t.Fatalf("Expected 1 batched notification, got %d", len(notifications))
+	}
+	if notifications[0].Title != "4 則新的提及" {
+		t.Errorf("Expected batched title, got %s", notifications[0].Title)
+	}
+	if notifications[0].Content.String != "user0、user1" {
+		t.Errorf("Expected actor summary, got %s", notifications[0].Content.String)
+	}
+}
+
+func TestNotificationService_NotifyBatched_WindowExpires(t *testing.T) {
+	svc, repo, db, prefix := setupNotificationServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "target")
+	svc.SetBatchWindow(20 * time.Millisecond)
+
+	svc.NotifyBatched(user.ID, "alice", &NotifyInput{
+		Type:        model.NotificationTypeReply,
+		Title:       "alice 回覆了你的訊息",
+		ReferenceID: "msg-1",
+	})
+
+	var notifications []*model.Notification
+	deadline := time.Now().Add(2 * time.Second)
+	for len(notifications) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		var err error
+		notifications, err = repo.ListByUserID(context.Background(), user.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("Failed to list notifications: %v", err)
+		}
+	}
+
+	if len(notifications) != 1 || notifications[0].Title != "alice 回覆了你的訊息" {
+		t.Errorf("Expected the single notification unchanged, got %d", len(notifications))
+	}
+}
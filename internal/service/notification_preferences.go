@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+var ErrNotificationTargetNotFound = apperrors.New(http.StatusNotFound, "找不到要設定的聊天室或用戶")
+
+// Timeout for looking up a recipient's preference before queueing a trigger
+const preferenceLookupTimeout = 2 * time.Second
+
+// SetPreferenceRepository sets the repository holding users' notification
+// preferences; without it every trigger is delivered
+func (s *NotificationService) SetPreferenceRepository(repo *repository.NotificationPreferenceRepository) {
+	s.preferenceRepo = repo
+}
+
+// ListPreferences lists the rooms and conversations a user has changed from
+// the default level
+func (s *NotificationService) ListPreferences(ctx context.Context, userID string) ([]*model.NotificationPreference, error) {
+	prefs, err := s.preferenceRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list notification preferences", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return prefs, nil
+}
+
+// SetPreferenceInput changes the notification level of one target
+type SetPreferenceInput struct {
+	UserID     string
+	TargetType model.NotificationTargetType
+	TargetID   string
+	Level      model.NotificationLevel
+}
+
+// SetPreference stores a user's level for a room they belong to or a
+// conversation partner. Setting NotificationLevelAll removes the override.
+func (s *NotificationService) SetPreference(ctx context.Context, input *SetPreferenceInput) (*model.NotificationPreference, error) {
+	if !input.TargetType.IsValid() {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"target_type": "必須為 room 或 dm",
+		})
+	}
+	if !input.Level.IsValidFor(input.TargetType) {
+		detail := "必須為 all、mentions 或 none"
+		if input.TargetType == model.NotificationTargetDM {
+			detail = "私訊必須為 all 或 none"
+		}
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{"level": detail})
+	}
+	if input.TargetType == model.NotificationTargetDM && input.TargetID == input.UserID {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"target_id": "不能設定與自己的私訊",
+		})
+	}
+
+	pref := &model.NotificationPreference{
+		UserID:     input.UserID,
+		TargetType: input.TargetType,
+		TargetID:   input.TargetID,
+		Level:      input.Level,
+	}
+
+	if input.Level == model.NotificationLevelAll {
+		if err := s.preferenceRepo.Delete(ctx, input.UserID, input.TargetType, input.TargetID); err != nil {
+			s.logger.Error("Failed to reset notification preference", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		pref.UpdatedAt = time.Now()
+		return pref, nil
+	}
+
+	if err := s.preferenceRepo.Set(ctx, pref); err != nil {
+		if err == repository.ErrNotificationTargetNotFound {
+			return nil, ErrNotificationTargetNotFound
+		}
+		s.logger.Error("Failed to set notification preference", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return pref, nil
+}
+
+// allows checks the recipient's preference for the room or conversation a
+// trigger came from. Lookup failures let the notification through.
+func (s *NotificationService) allows(userID string, input *NotifyInput) bool {
+	if s.preferenceRepo == nil || input.SourceID == "" {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), preferenceLookupTimeout)
+	defer cancel()
+
+	level, err := s.preferenceRepo.GetLevel(ctx, userID, input.SourceType, input.SourceID)
+	if err != nil {
+		s.logger.Warn("Failed to get notification preference",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return true
+	}
+	return level.Allows(input.Type)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+func TestNotificationLevel_Allows(t *testing.T) {
+	tests := []struct {
+		level            model.NotificationLevel
+		notificationType string
+		want             bool
+	}{
+		{model.NotificationLevelAll, model.NotificationTypeReply, true},
+		{model.NotificationLevelMentions, model.NotificationTypeMention, true},
+		{model.NotificationLevelMentions, model.NotificationTypeReply, false},
+		{model.NotificationLevelNone, model.NotificationTypeMention, false},
+		{model.NotificationLevelNone, model.NotificationTypeDirectMessage, false},
+	}
+	for _, tt := range tests {
+		if got := tt.level.Allows(tt.notificationType); got != tt.want {
+			t.Errorf("%s.Allows(%s) = %v, want %v", tt.level, tt.notificationType, got, tt.want)
+		}
+	}
+
+	if model.NotificationLevelMentions.IsValidFor(model.NotificationTargetDM) {
+		t.Error("Expected mentions-only to be rejected for direct messages")
+	}
+}
+
+func TestNotificationService_Preferences(t *testing.T) {
+	svc, repo, db, prefix := setupNotificationServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	svc.SetPreferenceRepository(repository.NewNotificationPreferenceRepository(db))
+	ctx := context.Background()
+
+	owner := repository.CreateIsolatedTestUser(t, db, prefix, "owner")
+	member := repository.CreateIsolatedTestUser(t, db, prefix, "member")
+	room := repository.CreateIsolatedTestRoom(t, db, prefix, owner)
+	if err := repository.NewRoomRepository(db).AddMember(ctx, &model.RoomMember{RoomID: room.ID, UserID: member.ID, Role: model.MemberRoleMember}); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	// Only rooms the user belongs to can be set
+	other := repository.CreateIsolatedTestRoom(t, db, prefix, owner)
+	if _, err := svc.SetPreference(ctx, &SetPreferenceInput{
+		UserID: member.ID, TargetType: model.NotificationTargetRoom, TargetID: other.ID, Level: model.NotificationLevelNone,
+	}); !apperrors.Is(err, ErrNotificationTargetNotFound) {
+		t.Errorf("Expected ErrNotificationTargetNotFound, got %v", err)
+	}
+
+	if _, err := svc.SetPreference(ctx, &SetPreferenceInput{
+		UserID: member.ID, TargetType: model.NotificationTargetRoom, TargetID: room.ID, Level: model.NotificationLevelMentions,
+	}); err != nil {
+		t.Fatalf("Failed to set room preference: %v", err)
+	}
+	if _, err := svc.SetPreference(ctx, &SetPreferenceInput{
+		UserID: member.ID, TargetType: model.NotificationTargetDM, TargetID: owner.ID, Level: model.NotificationLevelNone,
+	}); err != nil {
+		t.Fatalf("Failed to set DM preference: %v", err)
+	}
+
+	prefs, err := svc.ListPreferences(ctx, member.ID)
+	if err != nil {
+		t.Fatalf("Failed to list preferences: %v", err)
+	}
+	if len(prefs) != 2 {
+		t.Fatalf("Expected 2 preferences, got %d", len(prefs))
+	}
+
+	fromRoom := func(notificationType string) *NotifyInput {
+		return &NotifyInput{
+			Type:        notificationType,
+			Title:       notificationType,
+			ReferenceID: room.ID,
+			SourceType:  model.NotificationTargetRoom,
+			SourceID:    room.ID,
+		}
+	}
+	svc.NotifyBatched(member.ID, "owner", fromRoom(model.NotificationTypeMention))
+	svc.NotifyBatched(member.ID, "owner", fromRoom(model.NotificationTypeReply))
+	svc.NotifyBatched(member.ID, "owner", &NotifyInput{
+		Type:       model.NotificationTypeDirectMessage,
+		Title:      "dm",
+		SourceType: model.NotificationTargetDM,
+		SourceID:   owner.ID,
+	})
+
+	notifications, err := repo.ListByUserID(ctx, member.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list notifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].Type != model.NotificationTypeMention {
+		t.Fatalf("Expected only the mention to get through, got %+v", notifications)
+	}
+
+	// Back to the default, the muted conversation notifies again
+	svc.SetBatchWindow(time.Hour)
+	if _, err := svc.SetPreference(ctx, &SetPreferenceInput{
+		UserID: member.ID, TargetType: model.NotificationTargetDM, TargetID: owner.ID, Level: model.NotificationLevelAll,
+	}); err != nil {
+		t.Fatalf("Failed to reset DM preference: %v", err)
+	}
+	svc.NotifyBatched(member.ID, "owner", &NotifyInput{
+		Type:       model.NotificationTypeDirectMessage,
+		Title:      "dm",
+		SourceType: model.NotificationTargetDM,
+		SourceID:   owner.ID,
+	})
+	if svc.PendingBatches() != 1 {
+		t.Errorf("Expected the DM to be queued after unmuting, got %d batches", svc.PendingBatches())
+	}
+}
+
+func TestNotificationService_SetPreferenceValidates(t *testing.T) {
+	svc, _, db, prefix := setupNotificationServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	svc.SetPreferenceRepository(repository.NewNotificationPreferenceRepository(db))
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "user")
+
+	tests := []*SetPreferenceInput{
+		{UserID: user.ID, TargetType: "channel", TargetID: user.ID, Level: model.NotificationLevelNone},
+		{UserID: user.ID, TargetType: model.NotificationTargetDM, TargetID: user.ID, Level: model.NotificationLevelNone},
+		{UserID: user.ID, TargetType: model.NotificationTargetRoom, TargetID: user.ID, Level: "loud"},
+	}
+	for _, input := range tests {
+		if _, err := svc.SetPreference(context.Background(), input); !apperrors.Is(err, apperrors.ErrValidation) {
+			t.Errorf("Expected %+v to be rejected, got %v", input, err)
+		}
+	}
+}
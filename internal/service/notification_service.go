@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// RealtimePublisher pushes server-initiated events to connected clients.
+// It is implemented by ws.Hub and injected once the hub has been created.
+type RealtimePublisher interface {
+	PublishToRoom(roomID, eventType string, payload interface{})
+	PublishToUser(userID, eventType string, payload interface{})
+	PublishNotification(notification *model.Notification)
+}
+
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	preferenceRepo   *repository.NotificationPreferenceRepository
+	publisher        RealtimePublisher
+	logger           *zap.Logger
+
+	// Coalescing of high-frequency triggers, see NotifyBatched
+	batchWindow time.Duration
+	batchMu     sync.Mutex
+	batches     map[string]*notificationBatch
+}
+
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	logger *zap.Logger,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		logger:           logger,
+		batches:          make(map[string]*notificationBatch),
+	}
+}
+
+// SetPublisher sets the realtime publisher used to push events
+func (s *NotificationService) SetPublisher(publisher RealtimePublisher) {
+	s.publisher = publisher
+}
+
+// NotifyInput represents notification input
+type NotifyInput struct {
+	Type          string
+	Title         string
+	Content       string
+	ReferenceID   string
+	ReferenceType string
+
+	// Room or conversation partner the trigger came from. When set,
+	// NotifyBatched honours the recipient's preference for it.
+	SourceType model.NotificationTargetType
+	SourceID   string
+}
+
+// Notify stores a notification for each user and pushes it to online clients
+func (s *NotificationService) Notify(ctx context.Context, userIDs []string, input *NotifyInput) {
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		n := &model.Notification{
+			UserID:        userID,
+			Type:          input.Type,
+			Title:         input.Title,
+			Content:       sql.NullString{String: input.Content, Valid: input.Content != ""},
+			ReferenceID:   sql.NullString{String: input.ReferenceID, Valid: input.ReferenceID != ""},
+			ReferenceType: sql.NullString{String: input.ReferenceType, Valid: input.ReferenceType != ""},
+		}
+
+		if err := s.notificationRepo.Create(ctx, n); err != nil {
+			s.logger.Error("Failed to create notification",
+				zap.String("user_id", userID),
+				zap.String("type", input.Type),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if s.publisher != nil {
+			s.publisher.PublishNotification(n)
+		}
+	}
+}
+
+// PublishToRoom pushes an event to clients subscribed to a room
+func (s *NotificationService) PublishToRoom(roomID, eventType string, payload interface{}) {
+	if s.publisher != nil {
+		s.publisher.PublishToRoom(roomID, eventType, payload)
+	}
+}
+
+// PublishToUser pushes a realtime event to every connection of a user
+func (s *NotificationService) PublishToUser(userID, eventType string, payload interface{}) {
+	if s.publisher != nil {
+		s.publisher.PublishToUser(userID, eventType, payload)
+	}
+}
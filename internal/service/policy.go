@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+// loadSubject builds the policy subject for a user acting on a room
+func loadSubject(ctx context.Context, members MemberLookup, permRepo *repository.RoomPermissionRepository, room *model.Room, userID string) (*policy.Subject, error) {
+	member, err := members.GetMember(ctx, room.ID, userID)
+	if err != nil && err != repository.ErrNotRoomMember {
+		return nil, err
+	}
+
+	overrides, err := loadOverrides(ctx, permRepo, room.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy.Subject{
+		UserID:    userID,
+		Room:      room,
+		Member:    member,
+		Overrides: overrides,
+	}, nil
+}
+
+// loadOverrides reads a room's changes to the default role matrix. Without
+// a permission repository every room uses the defaults.
+func loadOverrides(ctx context.Context, permRepo *repository.RoomPermissionRepository, roomID string) (policy.Matrix, error) {
+	if permRepo == nil {
+		return nil, nil
+	}
+
+	perms, err := permRepo.ListByRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if len(perms) == 0 {
+		return nil, nil
+	}
+
+	overrides := make(policy.Matrix)
+	for _, p := range perms {
+		if overrides[p.Role] == nil {
+			overrides[p.Role] = make(map[policy.Action]bool)
+		}
+		overrides[p.Role][policy.Action(p.Action)] = p.Allowed
+	}
+	return overrides, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ReadStateEventUpdated tells every connection of a user that they read a
+// room or conversation, so their other devices can clear its badge
+const ReadStateEventUpdated = "read_state_updated"
+
+// ReadStateEvent is the payload of read_state_updated events. Reading
+// always brings the unread count to zero.
+type ReadStateEvent struct {
+	Kind        model.ReadStateKind `json:"kind"`
+	ID          string              `json:"id"`
+	LastReadAt  string              `json:"last_read_at"`
+	UnreadCount int                 `json:"unread_count"`
+}
+
+// publishReadState pushes a read_state_updated event to all of userID's
+// connections
+func publishReadState(notifier *NotificationService, userID string, kind model.ReadStateKind, id string, readAt time.Time) {
+	if notifier == nil {
+		return
+	}
+	notifier.PublishToUser(userID, ReadStateEventUpdated, &ReadStateEvent{
+		Kind:       kind,
+		ID:         id,
+		LastReadAt: readAt.Format(time.RFC3339),
+	})
+}
+
+// ReadStateService serves a user's read state across rooms, direct
+// messages and group conversations in one call, for clients that come
+// online and need every badge at once
+type ReadStateService struct {
+	store  ReadStateStore
+	logger *zap.Logger
+}
+
+func NewReadStateService(store ReadStateStore, logger *zap.Logger) *ReadStateService {
+	return &ReadStateService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// List returns the user's read states
+func (s *ReadStateService) List(ctx context.Context, userID string) (*model.ReadStates, error) {
+	states, err := s.store.List(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list read states", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return states, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+func TestGroupConversationService_MarkAsReadSyncsDevices(t *testing.T) {
+	ctx := context.Background()
+	s, publisher := newTestGroupService(groupStore("alice", "alice", "bob", "carol"), &mockBlockLookup{})
+
+	if err := s.MarkAsRead(ctx, testGroupID, "bob"); err != nil {
+		t.Fatalf("Failed to mark as read: %v", err)
+	}
+
+	if len(publisher.userEvents) != 1 {
+		t.Fatalf("Expected one event, got %+v", publisher.userEvents)
+	}
+	e := publisher.userEvents[0]
+	if e.userID != "bob" || e.eventType != ReadStateEventUpdated {
+		t.Errorf("Expected read_state_updated for bob only, got %+v", e)
+	}
+	payload := e.payload.(*ReadStateEvent)
+	if payload.Kind != model.ReadStateGroup || payload.ID != testGroupID || payload.UnreadCount != 0 || payload.LastReadAt == "" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+
+	publisher.userEvents = nil
+	if err := s.MarkAsRead(ctx, testGroupID, "mallory"); err != ErrGroupConversationNotFound {
+		t.Errorf("Expected ErrGroupConversationNotFound for a non-participant, got %v", err)
+	}
+	if len(publisher.userEvents) != 0 {
+		t.Errorf("Expected no event for a failed read, got %+v", publisher.userEvents)
+	}
+}
+
+func TestReadStateService_List(t *testing.T) {
+	ctx := context.Background()
+
+	store := &mockReadStateStore{ListFunc: func(ctx context.Context, userID string) (*model.ReadStates, error) {
+		return nil, errors.New("boom")
+	}}
+	if _, err := NewReadStateService(store, zap.NewNop()).List(ctx, "alice"); err != apperrors.ErrInternal {
+		t.Errorf("Expected ErrInternal, got %v", err)
+	}
+}
+
+func TestRoomService_UpdateLastReadSyncsDevices(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	publisher := &fakeRealtimePublisher{}
+	notifier := NewNotificationService(nil, zap.NewNop())
+	notifier.SetPublisher(publisher)
+	service.SetNotifier(notifier)
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	outsider := createUserForRoomServiceTestIsolated(t, db, prefix, "outsider")
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+	ctx := context.Background()
+
+	if err := service.UpdateLastRead(ctx, room.ID, outsider.ID); err != apperrors.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a non-member, got %v", err)
+	}
+	if len(publisher.userEvents) != 0 {
+		t.Errorf("Expected no event for a non-member, got %+v", publisher.userEvents)
+	}
+
+	if err := service.UpdateLastRead(ctx, room.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to update last read: %v", err)
+	}
+	if len(publisher.userEvents) != 1 || publisher.userEvents[0].userID != owner.ID {
+		t.Fatalf("Expected one event for the owner, got %+v", publisher.userEvents)
+	}
+	payload := publisher.userEvents[0].payload.(*ReadStateEvent)
+	if payload.Kind != model.ReadStateRoom || payload.ID != room.ID {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+
+	states, err := NewReadStateService(repository.NewReadStateRepository(db), zap.NewNop()).List(ctx, owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to list read states: %v", err)
+	}
+	if len(states.Rooms) != 1 || states.Rooms[0].ID != room.ID || states.Rooms[0].UnreadCount != 0 || states.Rooms[0].LastReadAt == nil {
+		t.Errorf("Unexpected room read states: %+v", states.Rooms)
+	}
+}
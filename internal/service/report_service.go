@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrReportNotFound       = apperrors.New(http.StatusNotFound, "檢舉不存在")
+	ErrReportExists         = apperrors.New(http.StatusConflict, "您已檢舉過此對象，正在處理中")
+	ErrReportTransition     = apperrors.New(http.StatusConflict, "檢舉目前的狀態無法如此變更")
+	ErrCannotReportSelf     = apperrors.New(http.StatusUnprocessableEntity, "無法檢舉自己")
+	ErrMessageNotReportable = apperrors.New(http.StatusBadRequest, "此訊息無法檢舉")
+)
+
+// ReportMessageSource loads a room message the reporter can see.
+// It is implemented by MessageService.
+type ReportMessageSource interface {
+	ReportTarget(ctx context.Context, messageID, userID string) (*model.Message, error)
+}
+
+// ReportTarget loads a room message for reporting. Messages in rooms whose
+// history the user cannot read are reported as not found.
+func (s *MessageService) ReportTarget(ctx context.Context, messageID, userID string) (*model.Message, error) {
+	msg, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		if err == repository.ErrMessageNotFound {
+			return nil, apperrors.ErrNotFound
+		}
+		s.logger.Error("Failed to get message", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if err := s.authorize(ctx, msg.RoomID, userID, policy.CanReadHistory); err != nil {
+		if err == apperrors.ErrPermissionDenied || err == apperrors.ErrRoomNotFound {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	if msg.IsDeleted || msg.Type == model.MessageTypeSystem {
+		return nil, ErrMessageNotReportable
+	}
+	return msg, nil
+}
+
+type ReportService struct {
+	reports  ReportStore
+	messages ReportMessageSource
+	users    UserLookup
+	notifier *NotificationService
+	auditor  *AuditService
+	logger   *zap.Logger
+}
+
+func NewReportService(reports ReportStore, messages ReportMessageSource, users UserLookup, logger *zap.Logger) *ReportService {
+	return &ReportService{
+		reports:  reports,
+		messages: messages,
+		users:    users,
+		logger:   logger,
+	}
+}
+
+// SetNotifier sets the notification service that tells reporters about
+// progress on their reports
+func (s *ReportService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// SetAuditor sets the audit service that records status changes
+func (s *ReportService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// SubmitReportInput represents a report filed by a user
+type SubmitReportInput struct {
+	ReporterID string
+	TargetType model.ReportTargetType
+	TargetID   string // message or user ID
+	Category   model.ReportCategory
+	Note       string
+}
+
+// Submit files a report about a message the reporter can see or about
+// another user
+func (s *ReportService) Submit(ctx context.Context, input *SubmitReportInput) (*model.Report, error) {
+	if !input.TargetType.IsValid() {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"target_type": "必須為 message 或 user",
+		})
+	}
+	if !input.Category.IsValid() {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"category": "必須為 spam、harassment、hate、sexual、violence、impersonation 或 other",
+		})
+	}
+	if !utils.ValidateUUID(input.TargetID) {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"target_id": "無效的 ID",
+		})
+	}
+
+	report := &model.Report{
+		ReporterID: nullString(input.ReporterID),
+		TargetType: input.TargetType,
+		Category:   input.Category,
+		Note:       nullString(strings.TrimSpace(input.Note)),
+	}
+
+	switch input.TargetType {
+	case model.ReportTargetMessage:
+		msg, err := s.messages.ReportTarget(ctx, input.TargetID, input.ReporterID)
+		if err != nil {
+			return nil, err
+		}
+		if msg.UserID == input.ReporterID {
+			return nil, ErrCannotReportSelf
+		}
+		report.MessageID = nullString(msg.ID)
+		report.RoomID = nullString(msg.RoomID)
+		report.ReportedUserID = nullString(msg.UserID)
+		report.MessageContent = nullString(msg.Content)
+	case model.ReportTargetUser:
+		if input.TargetID == input.ReporterID {
+			return nil, ErrCannotReportSelf
+		}
+		if _, err := s.users.GetByID(ctx, input.TargetID); err != nil {
+			if err == repository.ErrUserNotFound {
+				return nil, apperrors.ErrUserNotFound
+			}
+			s.logger.Error("Failed to get reported user", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		report.ReportedUserID = nullString(input.TargetID)
+	}
+
+	if err := s.reports.Create(ctx, report); err != nil {
+		if err == repository.ErrReportExists {
+			return nil, ErrReportExists
+		}
+		s.logger.Error("Failed to create report", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Report filed",
+		zap.String("report_id", report.ID),
+		zap.String("target_type", string(report.TargetType)),
+		zap.String("category", string(report.Category)),
+		zap.String("reporter_id", input.ReporterID),
+	)
+
+	return report, nil
+}
+
+// Get returns a single report
+func (s *ReportService) Get(ctx context.Context, id string) (*model.ReportWithUsers, error) {
+	report, err := s.reports.GetByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrReportNotFound {
+			return nil, ErrReportNotFound
+		}
+		s.logger.Error("Failed to get report", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return report, nil
+}
+
+// List lists reports for triage, oldest first
+func (s *ReportService) List(ctx context.Context, filter *repository.ReportFilter, limit, offset int) ([]*model.ReportWithUsers, error) {
+	reports, err := s.reports.List(ctx, filter, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list reports", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return reports, nil
+}
+
+// UpdateStatus moves a report to a new status, optionally with a note kept
+// for other admins, and tells the reporter when it is picked up or resolved
+func (s *ReportService) UpdateStatus(ctx context.Context, id string, status model.ReportStatus, note, adminID string) (*model.ReportWithUsers, error) {
+	if !status.IsValid() {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{
+			"status": "必須為 open、reviewing 或 resolved",
+		})
+	}
+
+	report, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from := report.Status
+	if !from.CanMoveTo(status) {
+		return nil, ErrReportTransition
+	}
+
+	updated, err := s.reports.UpdateStatus(ctx, id, from, status, nullString(strings.TrimSpace(note)), adminID)
+	if err != nil {
+		// Another admin moved the report first
+		if err == repository.ErrReportNotFound {
+			return nil, ErrReportTransition
+		}
+		s.logger.Error("Failed to update report", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	report.Report = *updated
+
+	s.logger.Info("Report status changed",
+		zap.String("report_id", id),
+		zap.String("from", string(from)),
+		zap.String("to", string(status)),
+		zap.String("handled_by", adminID),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    adminID,
+		Action:     model.AuditActionReportUpdated,
+		TargetType: model.AuditTargetReport,
+		TargetID:   id,
+		Metadata: map[string]interface{}{
+			"from": string(from),
+			"to":   string(status),
+		},
+	})
+	s.notifyReporter(ctx, &report.Report)
+
+	return report, nil
+}
+
+// notifyReporter tells the reporter their report is being looked at or
+// has been dealt with. The admin's note is internal and not included.
+func (s *ReportService) notifyReporter(ctx context.Context, report *model.Report) {
+	if s.notifier == nil || !report.ReporterID.Valid {
+		return
+	}
+
+	var title string
+	switch report.Status {
+	case model.ReportStatusReviewing:
+		title = "您的檢舉正在處理中"
+	case model.ReportStatusResolved:
+		title = "您的檢舉已處理完成，感謝您的回報"
+	default:
+		return
+	}
+	// The change is committed; the reporter must hear about it even if the
+	// admin's client disconnects
+	s.notifier.Notify(context.WithoutCancel(ctx), []string{report.ReporterID.String}, &NotifyInput{
+		Type:          model.NotificationTypeReportUpdated,
+		Title:         title,
+		ReferenceID:   report.ID,
+		ReferenceType: "report",
+	})
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	testReporterID = "0b6f3c1e-2d4a-4f7b-9e8c-5a1d2b3c4d5e"
+	testAuthorID   = "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+	testMessageID  = "3c2b1a09-8f7e-4d6c-9b5a-4e3d2c1b0a9f"
+)
+
+type fakeReportMessages struct {
+	msg *model.Message
+	err error
+}
+
+func (f *fakeReportMessages) ReportTarget(ctx context.Context, messageID, userID string) (*model.Message, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.msg, nil
+}
+
+func TestReportService_Submit(t *testing.T) {
+	ctx := context.Background()
+	messages := &fakeReportMessages{msg: &model.Message{
+		ID:      testMessageID,
+		RoomID:  "room-1",
+		UserID:  testAuthorID,
+		Content: "buy followers now",
+	}}
+	users := &mockUserLookup{GetByIDFunc: func(ctx context.Context, id string) (*model.User, error) {
+		return &model.User{ID: id}, nil
+	}}
+
+	t.Run("Reports a message with a snapshot", func(t *testing.T) {
+		var stored *model.Report
+		store := &mockReportStore{CreateFunc: func(ctx context.Context, report *model.Report) error {
+			stored = report
+			report.Status = model.ReportStatusOpen
+			return nil
+		}}
+		service := NewReportService(store, messages, users, zap.NewNop())
+
+		report, err := service.Submit(ctx, &SubmitReportInput{
+			ReporterID: testReporterID,
+			TargetType: model.ReportTargetMessage,
+			TargetID:   testMessageID,
+			Category:   model.ReportCategorySpam,
+			Note:       "  bot  ",
+		})
+		if err != nil {
+			t.Fatalf("Failed to submit report: %v", err)
+		}
+		if report != stored || stored.ReportedUserID.String != testAuthorID || stored.RoomID.String != "room-1" {
+			t.Errorf("Unexpected report %+v", stored)
+		}
+		if stored.MessageContent.String != "buy followers now" || stored.Note.String != "bot" {
+			t.Errorf("Expected content snapshot and trimmed note, got %+v", stored)
+		}
+	})
+
+	t.Run("Rejects invalid input", func(t *testing.T) {
+		store := &mockReportStore{}
+		service := NewReportService(store, messages, users, zap.NewNop())
+		inputs := []*SubmitReportInput{
+			{ReporterID: testReporterID, TargetType: "room", TargetID: testMessageID, Category: model.ReportCategorySpam},
+			{ReporterID: testReporterID, TargetType: model.ReportTargetUser, TargetID: testAuthorID, Category: "rude"},
+			{ReporterID: testReporterID, TargetType: model.ReportTargetUser, TargetID: "not-a-uuid", Category: model.ReportCategorySpam},
+		}
+		for _, input := range inputs {
+			if _, err := service.Submit(ctx, input); apperrors.GetHTTPStatus(err) != 400 {
+				t.Errorf("Expected validation error for %+v, got %v", input, err)
+			}
+		}
+		if store.Calls("Create") != 0 {
+			t.Error("Expected nothing stored")
+		}
+	})
+
+	t.Run("Cannot report self", func(t *testing.T) {
+		service := NewReportService(&mockReportStore{}, messages, users, zap.NewNop())
+		if _, err := service.Submit(ctx, &SubmitReportInput{
+			ReporterID: testAuthorID, TargetType: model.ReportTargetMessage, TargetID: testMessageID, Category: model.ReportCategorySpam,
+		}); err != ErrCannotReportSelf {
+			t.Errorf("Expected ErrCannotReportSelf for own message, got %v", err)
+		}
+		if _, err := service.Submit(ctx, &SubmitReportInput{
+			ReporterID: testReporterID, TargetType: model.ReportTargetUser, TargetID: testReporterID, Category: model.ReportCategorySpam,
+		}); err != ErrCannotReportSelf {
+			t.Errorf("Expected ErrCannotReportSelf, got %v", err)
+		}
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		service := NewReportService(&mockReportStore{}, messages, &mockUserLookup{}, zap.NewNop())
+		if _, err := service.Submit(ctx, &SubmitReportInput{
+			ReporterID: testReporterID, TargetType: model.ReportTargetUser, TargetID: testAuthorID, Category: model.ReportCategoryHarassment,
+		}); err != apperrors.ErrUserNotFound {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Duplicate open report", func(t *testing.T) {
+		store := &mockReportStore{CreateFunc: func(ctx context.Context, report *model.Report) error {
+			return repository.ErrReportExists
+		}}
+		service := NewReportService(store, messages, users, zap.NewNop())
+		if _, err := service.Submit(ctx, &SubmitReportInput{
+			ReporterID: testReporterID, TargetType: model.ReportTargetUser, TargetID: testAuthorID, Category: model.ReportCategoryHarassment,
+		}); err != ErrReportExists {
+			t.Errorf("Expected ErrReportExists, got %v", err)
+		}
+	})
+}
+
+func TestReportService_UpdateStatus(t *testing.T) {
+	ctx := context.Background()
+	newStore := func(status model.ReportStatus) *mockReportStore {
+		return &mockReportStore{
+			GetByIDFunc: func(ctx context.Context, id string) (*model.ReportWithUsers, error) {
+				return &model.ReportWithUsers{
+					Report:           model.Report{ID: id, Status: status},
+					ReporterUsername: sql.NullString{String: "alice", Valid: true},
+				}, nil
+			},
+			UpdateStatusFunc: func(ctx context.Context, id string, from, to model.ReportStatus, note sql.NullString, handledBy string) (*model.Report, error) {
+				if from != status {
+					t.Errorf("Expected update from %s, got %s", status, from)
+				}
+				return &model.Report{ID: id, Status: to, ResolutionNote: note}, nil
+			},
+		}
+	}
+
+	tests := []struct {
+		from, to model.ReportStatus
+		allowed  bool
+	}{
+		{model.ReportStatusOpen, model.ReportStatusReviewing, true},
+		{model.ReportStatusOpen, model.ReportStatusResolved, true},
+		{model.ReportStatusReviewing, model.ReportStatusOpen, true},
+		{model.ReportStatusReviewing, model.ReportStatusResolved, true},
+		{model.ReportStatusOpen, model.ReportStatusOpen, false},
+		{model.ReportStatusResolved, model.ReportStatusOpen, false},
+		{model.ReportStatusResolved, model.ReportStatusReviewing, false},
+	}
+	for _, tt := range tests {
+		store := newStore(tt.from)
+		service := NewReportService(store, nil, nil, zap.NewNop())
+
+		report, err := service.UpdateStatus(ctx, "report-1", tt.to, " checked ", "admin-1")
+		if !tt.allowed {
+			if err != ErrReportTransition {
+				t.Errorf("%s -> %s: expected ErrReportTransition, got %v", tt.from, tt.to, err)
+			}
+			if store.Calls("UpdateStatus") != 0 {
+				t.Errorf("%s -> %s: expected no update", tt.from, tt.to)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s -> %s: %v", tt.from, tt.to, err)
+		}
+		if report.Status != tt.to || report.ResolutionNote.String != "checked" || report.ReporterUsername.String != "alice" {
+			t.Errorf("%s -> %s: unexpected report %+v", tt.from, tt.to, report)
+		}
+	}
+
+	t.Run("Concurrent change", func(t *testing.T) {
+		store := newStore(model.ReportStatusOpen)
+		store.UpdateStatusFunc = nil
+		service := NewReportService(store, nil, nil, zap.NewNop())
+		if _, err := service.UpdateStatus(ctx, "report-1", model.ReportStatusResolved, "", "admin-1"); err != ErrReportTransition {
+			t.Errorf("Expected ErrReportTransition, got %v", err)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		service := NewReportService(&mockReportStore{}, nil, nil, zap.NewNop())
+		if _, err := service.UpdateStatus(ctx, "report-1", model.ReportStatusResolved, "", "admin-1"); err != ErrReportNotFound {
+			t.Errorf("Expected ErrReportNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Activity heatmap bounds, in weeks. Hourly activity older than the maximum
+// is purged.
+const (
+	DefaultActivityHeatmapWeeks = 4
+	MaxActivityHeatmapWeeks     = 12
+)
+
+// RoomActivityHeatmap is a room's message count per hour of the week over
+// the last few weeks. Counts is indexed by weekday (0 is Sunday) and hour in
+// TimeZone.
+type RoomActivityHeatmap struct {
+	RoomID   string
+	Weeks    int
+	TimeZone string
+	Since    time.Time
+	Total    int64
+	Counts   [7][24]int64
+}
+
+// GetActivityHeatmap buckets a room's messages of the last weeks by hour of
+// the week in the given IANA time zone, UTC when empty. Anyone who may read
+// the room's history may see it.
+func (s *RoomService) GetActivityHeatmap(ctx context.Context, roomID, userID string, weeks int, timeZone string) (*RoomActivityHeatmap, error) {
+	if weeks <= 0 {
+		weeks = DefaultActivityHeatmapWeeks
+	}
+	if weeks > MaxActivityHeatmapWeeks {
+		weeks = MaxActivityHeatmapWeeks
+	}
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	// Local is the server's zone, which the database does not know
+	if _, err := time.LoadLocation(timeZone); err != nil || timeZone == "Local" {
+		return nil, apperrors.ErrValidation.WithDetails(map[string]string{"tz": "無效的時區"})
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		s.logger.Error("Failed to get room", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if err := s.authorize(ctx, room, userID, policy.CanReadHistory); err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Truncate(time.Hour).Add(-time.Duration(weeks) * 7 * 24 * time.Hour)
+	buckets, err := s.roomRepo.ActivityHeatmap(ctx, roomID, since, timeZone)
+	if err != nil {
+		s.logger.Error("Failed to get room activity", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return newRoomActivityHeatmap(roomID, weeks, timeZone, since, buckets), nil
+}
+
+// PurgeActivity drops hourly activity too old to show up in any heatmap
+func (s *RoomService) PurgeActivity(ctx context.Context) (int64, error) {
+	before := time.Now().Truncate(time.Hour).Add(-MaxActivityHeatmapWeeks * 7 * 24 * time.Hour)
+	purged, err := s.roomRepo.PurgeActivity(ctx, before)
+	if err != nil {
+		s.logger.Error("Failed to purge room activity", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+	if purged > 0 {
+		s.logger.Info("Purged room activity", zap.Int64("rows", purged))
+	}
+	return purged, nil
+}
+
+func newRoomActivityHeatmap(roomID string, weeks int, timeZone string, since time.Time, buckets []*model.RoomActivityBucket) *RoomActivityHeatmap {
+	heatmap := &RoomActivityHeatmap{
+		RoomID:   roomID,
+		Weeks:    weeks,
+		TimeZone: timeZone,
+		Since:    since,
+	}
+	for _, b := range buckets {
+		if b.Weekday < 0 || b.Weekday > 6 || b.Hour < 0 || b.Hour > 23 {
+			continue
+		}
+		heatmap.Counts[b.Weekday][b.Hour] += b.MessageCount
+		heatmap.Total += b.MessageCount
+	}
+	return heatmap
+}
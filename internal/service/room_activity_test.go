@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+func TestNewRoomActivityHeatmap(t *testing.T) {
+	heatmap := newRoomActivityHeatmap("room", 4, "UTC", time.Now(), []*model.RoomActivityBucket{
+		{Weekday: 0, Hour: 9, MessageCount: 3},
+		{Weekday: 6, Hour: 23, MessageCount: 2},
+		{Weekday: 7, Hour: 0, MessageCount: 5},
+	})
+
+	if heatmap.Counts[0][9] != 3 || heatmap.Counts[6][23] != 2 {
+		t.Errorf("Expected counts in their buckets, got %v", heatmap.Counts)
+	}
+	if heatmap.Total != 5 {
+		t.Errorf("Expected out-of-range buckets to be ignored, got total %d", heatmap.Total)
+	}
+}
+
+func TestRoomService_GetActivityHeatmap_InvalidTimeZone(t *testing.T) {
+	service := &RoomService{}
+	for _, tz := range []string{"Mars/Olympus", "Local"} {
+		if _, err := service.GetActivityHeatmap(context.Background(), "room", "user", 4, tz); !apperrors.Is(err, apperrors.ErrValidation) {
+			t.Errorf("Time zone %q: expected a validation error, got %v", tz, err)
+		}
+	}
+}
+
+func TestRoomService_GetActivityHeatmap(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	messageRepo := repository.NewMessageRepository(db)
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	outsider := createUserForRoomServiceTestIsolated(t, db, prefix, "outsider")
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePrivate)
+
+	for _, msgType := range []model.MessageType{model.MessageTypeText, model.MessageTypeText, model.MessageTypeSystem} {
+		if err := messageRepo.Create(ctx, &model.Message{RoomID: room.ID, UserID: owner.ID, Content: "hi", Type: msgType}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	if _, err := service.GetActivityHeatmap(ctx, room.ID, outsider.ID, 0, ""); !apperrors.Is(err, apperrors.ErrPermissionDenied) {
+		t.Errorf("Expected outsiders of a private room to be denied, got %v", err)
+	}
+
+	heatmap, err := service.GetActivityHeatmap(ctx, room.ID, owner.ID, 0, "Asia/Taipei")
+	if err != nil {
+		t.Fatalf("Failed to get heatmap: %v", err)
+	}
+	if heatmap.Weeks != DefaultActivityHeatmapWeeks || heatmap.Total != 2 {
+		t.Fatalf("Expected 2 messages over the default weeks, got %+v", heatmap)
+	}
+
+	now := time.Now().In(time.FixedZone("CST", 8*60*60))
+	if heatmap.Counts[now.Weekday()][now.Hour()] != 2 {
+		t.Errorf("Expected the messages in the current local hour, got %v", heatmap.Counts)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-demo/chat/internal/i18n"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Room archive events pushed to room subscribers
+const (
+	RoomEventArchived   = "room_archived"
+	RoomEventUnarchived = "room_unarchived"
+)
+
+var ErrRoomArchiveDirect = apperrors.New(http.StatusBadRequest, "私訊聊天室無法封存")
+
+// RoomArchiveEvent is the payload of room archive events
+type RoomArchiveEvent struct {
+	RoomID     string `json:"room_id"`
+	ArchivedAt string `json:"archived_at,omitempty"`
+}
+
+// Archive archives a room (owner only). The room drops out of listings and
+// takes no new members or messages; members keep access to its history.
+func (s *RoomService) Archive(ctx context.Context, roomID, userID string) (*model.Room, error) {
+	room, err := s.archivableRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if room.IsPendingDeletion() {
+		return nil, apperrors.ErrRoomDeletionPending
+	}
+
+	archivedAt, err := s.roomRepo.Archive(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomAlreadyArchived {
+			return nil, apperrors.ErrRoomAlreadyArchived
+		}
+		s.logger.Error("Failed to archive room", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	room.ArchivedAt = &archivedAt
+
+	s.logger.Info("Room archived",
+		zap.String("room_id", roomID),
+		zap.String("archived_by", userID),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    userID,
+		Action:     model.AuditActionRoomArchived,
+		TargetType: model.AuditTargetRoom,
+		TargetID:   roomID,
+		Metadata:   map[string]interface{}{"room_name": room.Name},
+	})
+
+	if s.notifier != nil {
+		s.notifier.PublishToRoom(roomID, RoomEventArchived, &RoomArchiveEvent{
+			RoomID:     roomID,
+			ArchivedAt: archivedAt.Format(time.RFC3339),
+		})
+	}
+	s.postSystemMessage(ctx, room, i18n.SystemRoomArchived, userID, "")
+
+	return room, nil
+}
+
+// Unarchive reopens an archived room (owner only)
+func (s *RoomService) Unarchive(ctx context.Context, roomID, userID string) (*model.Room, error) {
+	room, err := s.archivableRoom(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roomRepo.Unarchive(ctx, roomID); err != nil {
+		if err == repository.ErrRoomNotArchived {
+			return nil, apperrors.ErrRoomNotArchived
+		}
+		s.logger.Error("Failed to unarchive room", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	room.ArchivedAt = nil
+
+	s.logger.Info("Room unarchived",
+		zap.String("room_id", roomID),
+		zap.String("unarchived_by", userID),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    userID,
+		Action:     model.AuditActionRoomUnarchived,
+		TargetType: model.AuditTargetRoom,
+		TargetID:   roomID,
+		Metadata:   map[string]interface{}{"room_name": room.Name},
+	})
+
+	if s.notifier != nil {
+		s.notifier.PublishToRoom(roomID, RoomEventUnarchived, &RoomArchiveEvent{RoomID: roomID})
+	}
+	s.postSystemMessage(ctx, room, i18n.SystemRoomUnarchived, userID, "")
+
+	return room, nil
+}
+
+// ListArchivedByUserID lists archived rooms that user is a member of
+func (s *RoomService) ListArchivedByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	rooms, err := s.roomRepo.ListArchivedByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list archived user rooms", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return rooms, nil
+}
+
+// archivableRoom loads a room the user may archive or unarchive
+func (s *RoomService) archivableRoom(ctx context.Context, roomID, userID string) (*model.Room, error) {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+	if err := s.authorize(ctx, room, userID, policy.CanArchiveRoom); err != nil {
+		return nil, err
+	}
+	if room.IsDirect() {
+		return nil, ErrRoomArchiveDirect
+	}
+	return room, nil
+}
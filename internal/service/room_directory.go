@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-demo/chat/internal/i18n"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AddDirectoryMember adds a user to a room their directory groups map them
+// to. The mapping stands in for an invitation, so private rooms need none.
+// It reports false if the user already was a member.
+func (s *RoomService) AddDirectoryMember(ctx context.Context, roomID, userID string) (bool, error) {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return false, apperrors.ErrRoomNotFound
+		}
+		return false, apperrors.ErrInternal
+	}
+	if room.IsArchived() {
+		return false, apperrors.ErrRoomArchived
+	}
+
+	member := &model.RoomMember{
+		RoomID: roomID,
+		UserID: userID,
+		Role:   model.MemberRoleMember,
+	}
+	if err := s.roomRepo.AddMember(ctx, member); err != nil {
+		if err == repository.ErrAlreadyRoomMember {
+			return false, nil
+		}
+		if err == repository.ErrRoomFull {
+			return false, apperrors.ErrRoomFull
+		}
+		s.logger.Error("Failed to add directory member", zap.Error(err))
+		return false, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Directory group added user to room",
+		zap.String("room_id", roomID),
+		zap.String("user_id", userID),
+	)
+	s.emitMemberEvent(ctx, roomID, model.WebhookEventMemberJoined, userID, WebhookMemberReasonDirectory, "")
+	s.postSystemMessage(ctx, room, i18n.SystemMemberJoined, userID, "")
+	return true, nil
+}
+
+// RemoveDirectoryMember removes a user from a room their directory groups
+// no longer map them to. An owner is never removed.
+func (s *RoomService) RemoveDirectoryMember(ctx context.Context, roomID, userID string) error {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil
+		}
+		return apperrors.ErrInternal
+	}
+	if room.OwnerID == userID {
+		return nil
+	}
+
+	if err := s.roomRepo.RemoveMember(ctx, roomID, userID); err != nil {
+		if err == repository.ErrNotRoomMember {
+			return nil
+		}
+		s.logger.Error("Failed to remove directory member", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("Directory group removed user from room",
+		zap.String("room_id", roomID),
+		zap.String("user_id", userID),
+	)
+	s.emitMemberEvent(ctx, roomID, model.WebhookEventMemberLeft, userID, WebhookMemberReasonDirectory, "")
+	s.postSystemMessage(ctx, room, i18n.SystemMemberLeft, userID, "")
+	return nil
+}
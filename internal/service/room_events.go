@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxRoomEventLead is the furthest ahead an event may start
+	MaxRoomEventLead = 365 * 24 * time.Hour
+
+	// roomCalendarLookback keeps recently past events in the iCal export so
+	// calendars do not drop them the moment they start
+	roomCalendarLookback = 30 * 24 * time.Hour
+	// maxRoomCalendarEvents caps the events in one iCal export
+	maxRoomCalendarEvents = 500
+)
+
+// Room event realtime events pushed to room subscribers
+const (
+	RoomEventCreated     = "room_event_created"
+	RoomEventRemoved     = "room_event_deleted"
+	RoomEventRSVPUpdated = "room_event_rsvp_updated"
+)
+
+var (
+	ErrRoomEventNotFound    = apperrors.New(http.StatusNotFound, "活動不存在")
+	ErrRoomEventTime        = apperrors.New(http.StatusBadRequest, "活動需在未來一年內開始，且結束時間需晚於開始時間")
+	ErrRoomEventEnded       = apperrors.New(http.StatusBadRequest, "活動已結束")
+	ErrRoomCalendarNotFound = apperrors.New(http.StatusNotFound, "此聊天室未開放行事曆訂閱")
+)
+
+// CreateRoomEventInput is an event to schedule in a room
+type CreateRoomEventInput struct {
+	RoomID      string
+	UserID      string
+	Title       string
+	Description string
+	Location    string
+	StartsAt    time.Time
+	EndsAt      *time.Time
+}
+
+// RoomEventRSVPEvent is the payload of room_event_rsvp_updated
+type RoomEventRSVPEvent struct {
+	RoomID        string `json:"room_id"`
+	EventID       string `json:"event_id"`
+	UserID        string `json:"user_id"`
+	Status        string `json:"status,omitempty"` // empty when the answer was withdrawn
+	GoingCount    int    `json:"going_count"`
+	MaybeCount    int    `json:"maybe_count"`
+	DeclinedCount int    `json:"declined_count"`
+}
+
+// RoomCalendar is a room and the events its iCal export lists
+type RoomCalendar struct {
+	Room   *model.Room
+	Events []*model.RoomEvent
+}
+
+// SetEventRepository enables room events
+func (s *RoomService) SetEventRepository(repo *repository.RoomEventRepository) {
+	s.eventRepo = repo
+}
+
+// CreateEvent schedules an event in a room. Members who may post can
+// create events; the creator is counted as going.
+func (s *RoomService) CreateEvent(ctx context.Context, input *CreateRoomEventInput) (*model.RoomEventWithRSVPs, error) {
+	if s.eventRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	now := time.Now()
+	if !input.StartsAt.After(now) || input.StartsAt.After(now.Add(MaxRoomEventLead)) {
+		return nil, ErrRoomEventTime
+	}
+	if input.EndsAt != nil && !input.EndsAt.After(input.StartsAt) {
+		return nil, ErrRoomEventTime
+	}
+
+	room, err := s.loadRoom(ctx, input.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, room, input.UserID, policy.CanSend); err != nil {
+		return nil, err
+	}
+
+	event := &model.RoomEvent{
+		RoomID:      input.RoomID,
+		CreatedBy:   sql.NullString{String: input.UserID, Valid: true},
+		Title:       input.Title,
+		Description: sql.NullString{String: input.Description, Valid: input.Description != ""},
+		Location:    sql.NullString{String: input.Location, Valid: input.Location != ""},
+		StartsAt:    input.StartsAt,
+	}
+	if input.EndsAt != nil {
+		event.EndsAt = sql.NullTime{Time: *input.EndsAt, Valid: true}
+	}
+
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		if err := s.eventRepo.Create(ctx, event); err != nil {
+			return err
+		}
+		return s.eventRepo.SetRSVP(ctx, event.ID, input.UserID, model.RSVPGoing)
+	})
+	if err != nil {
+		s.logger.Error("Failed to create room event", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	created, err := s.getEvent(ctx, room.ID, event.ID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.notifier != nil {
+		// Room subscribers share the payload, so it carries no viewer's answer
+		s.notifier.PublishToRoom(room.ID, RoomEventCreated, &created.RoomEvent)
+	}
+
+	return created, nil
+}
+
+// ListEvents lists a room's events that have not ended yet, soonest first
+func (s *RoomService) ListEvents(ctx context.Context, roomID, userID string, limit, offset int) ([]*model.RoomEventWithRSVPs, error) {
+	if s.eventRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	room, err := s.loadRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, room, userID, policy.CanAccess); err != nil {
+		return nil, err
+	}
+
+	events, err := s.eventRepo.ListUpcoming(ctx, roomID, userID, time.Now(), limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list room events", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return events, nil
+}
+
+// GetEvent gets one of a room's events
+func (s *RoomService) GetEvent(ctx context.Context, roomID, eventID, userID string) (*model.RoomEventWithRSVPs, error) {
+	if s.eventRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	room, err := s.loadRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, room, userID, policy.CanAccess); err != nil {
+		return nil, err
+	}
+
+	return s.getEvent(ctx, roomID, eventID, userID)
+}
+
+// DeleteEvent removes an event. Its creator and members who may manage the
+// room can delete it.
+func (s *RoomService) DeleteEvent(ctx context.Context, roomID, eventID, userID string) error {
+	if s.eventRepo == nil {
+		return apperrors.ErrNotFound
+	}
+
+	room, err := s.loadRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(ctx, room, userID, policy.CanAccess); err != nil {
+		return err
+	}
+
+	event, err := s.getEvent(ctx, roomID, eventID, userID)
+	if err != nil {
+		return err
+	}
+	if event.CreatedBy.String != userID {
+		if err := s.authorize(ctx, room, userID, policy.CanManageRoom); err != nil {
+			return err
+		}
+	}
+
+	if err := s.eventRepo.Delete(ctx, eventID); err != nil {
+		if err == repository.ErrRoomEventNotFound {
+			return ErrRoomEventNotFound
+		}
+		s.logger.Error("Failed to delete room event", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	if s.notifier != nil {
+		s.notifier.PublishToRoom(roomID, RoomEventRemoved, map[string]string{"room_id": roomID, "event_id": eventID})
+	}
+
+	return nil
+}
+
+// RSVP records a member's answer to an event that has not ended. An empty
+// status withdraws the answer.
+func (s *RoomService) RSVP(ctx context.Context, roomID, eventID, userID string, status model.RSVPStatus) (*model.RoomEventWithRSVPs, error) {
+	if s.eventRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	room, err := s.loadRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, room, userID, policy.CanAccess); err != nil {
+		return nil, err
+	}
+
+	event, err := s.getEvent(ctx, roomID, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if event.HasEnded() {
+		return nil, ErrRoomEventEnded
+	}
+
+	if status == "" {
+		err = s.eventRepo.DeleteRSVP(ctx, eventID, userID)
+	} else {
+		err = s.eventRepo.SetRSVP(ctx, eventID, userID, status)
+	}
+	if err != nil {
+		s.logger.Error("Failed to update rsvp", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	updated, err := s.getEvent(ctx, roomID, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.notifier != nil {
+		s.notifier.PublishToRoom(roomID, RoomEventRSVPUpdated, &RoomEventRSVPEvent{
+			RoomID:        roomID,
+			EventID:       eventID,
+			UserID:        userID,
+			Status:        string(status),
+			GoingCount:    updated.GoingCount,
+			MaybeCount:    updated.MaybeCount,
+			DeclinedCount: updated.DeclinedCount,
+		})
+	}
+
+	return updated, nil
+}
+
+// GetCalendar loads the events of a room's iCal export: the last 30 days
+// and everything ahead. Rooms that publish a feed are readable by any
+// signed-in user; other rooms only by members, and are otherwise reported
+// as not found.
+func (s *RoomService) GetCalendar(ctx context.Context, roomID, userID string) (*RoomCalendar, error) {
+	if s.eventRepo == nil {
+		return nil, ErrRoomCalendarNotFound
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, ErrRoomCalendarNotFound
+		}
+		s.logger.Error("Failed to get room", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if !room.HasFeed() {
+		if userID == "" {
+			return nil, ErrRoomCalendarNotFound
+		}
+		if err := s.authorize(ctx, room, userID, policy.CanAccess); err != nil {
+			if err == apperrors.ErrPermissionDenied {
+				return nil, ErrRoomCalendarNotFound
+			}
+			return nil, err
+		}
+	}
+
+	events, err := s.eventRepo.ListSince(ctx, roomID, time.Now().Add(-roomCalendarLookback), maxRoomCalendarEvents)
+	if err != nil {
+		s.logger.Error("Failed to list room events for calendar", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return &RoomCalendar{Room: room, Events: events}, nil
+}
+
+// SendEventReminders notifies the members who answered going or maybe of
+// up to limit events starting within lead, and returns how many events
+// were reminded
+func (s *RoomService) SendEventReminders(ctx context.Context, lead time.Duration, limit int) (int, error) {
+	if s.eventRepo == nil || s.notifier == nil {
+		return 0, nil
+	}
+
+	events, err := s.eventRepo.ClaimDueReminders(ctx, time.Now().Add(lead), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		userIDs, err := s.eventRepo.ListReminderRecipients(ctx, event.ID)
+		if err != nil {
+			s.logger.Error("Failed to list event reminder recipients",
+				zap.String("event_id", event.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		s.notifier.Notify(ctx, userIDs, &NotifyInput{
+			Type:          model.NotificationTypeRoomEventReminder,
+			Title:         "活動「" + event.Title + "」即將開始",
+			Content:       event.StartsAt.Format(time.RFC3339),
+			ReferenceID:   event.ID,
+			ReferenceType: "room_event",
+		})
+	}
+
+	return len(events), nil
+}
+
+// loadRoom gets a room, mapping a missing one to ErrRoomNotFound
+func (s *RoomService) loadRoom(ctx context.Context, roomID string) (*model.Room, error) {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		s.logger.Error("Failed to get room", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return room, nil
+}
+
+// getEvent gets an event of the room, reporting events of other rooms as
+// not found
+func (s *RoomService) getEvent(ctx context.Context, roomID, eventID, userID string) (*model.RoomEventWithRSVPs, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID, userID)
+	if err != nil {
+		if err == repository.ErrRoomEventNotFound {
+			return nil, ErrRoomEventNotFound
+		}
+		s.logger.Error("Failed to get room event", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if event.RoomID != roomID {
+		return nil, ErrRoomEventNotFound
+	}
+	return event, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+func TestRoomService_Events(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+	service.SetEventRepository(repository.NewRoomEventRepository(db))
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	alice := createUserForRoomServiceTestIsolated(t, db, prefix, "alice")
+	outsider := createUserForRoomServiceTestIsolated(t, db, prefix, "outsider")
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePrivate)
+	ctx := context.Background()
+
+	if err := service.InviteMember(ctx, room.ID, owner.ID, alice.ID); err != nil {
+		t.Fatalf("Failed to invite member: %v", err)
+	}
+
+	startsAt := time.Now().Add(time.Hour)
+	endsAt := startsAt.Add(time.Hour)
+
+	t.Run("Rejects events in the past", func(t *testing.T) {
+		_, err := service.CreateEvent(ctx, &CreateRoomEventInput{
+			RoomID: room.ID, UserID: owner.ID, Title: "late", StartsAt: time.Now().Add(-time.Minute),
+		})
+		if err != ErrRoomEventTime {
+			t.Errorf("Expected ErrRoomEventTime, got %v", err)
+		}
+	})
+
+	t.Run("Rejects non-members", func(t *testing.T) {
+		_, err := service.CreateEvent(ctx, &CreateRoomEventInput{
+			RoomID: room.ID, UserID: outsider.ID, Title: "party", StartsAt: startsAt,
+		})
+		if err != apperrors.ErrPermissionDenied {
+			t.Errorf("Expected ErrPermissionDenied, got %v", err)
+		}
+	})
+
+	event, err := service.CreateEvent(ctx, &CreateRoomEventInput{
+		RoomID:   room.ID,
+		UserID:   owner.ID,
+		Title:    "Release party",
+		Location: "Online",
+		StartsAt: startsAt,
+		EndsAt:   &endsAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if event.GoingCount != 1 || event.MyRSVP.String != string(model.RSVPGoing) {
+		t.Errorf("Expected the creator to be going, got %+v", event)
+	}
+
+	updated, err := service.RSVP(ctx, room.ID, event.ID, alice.ID, model.RSVPMaybe)
+	if err != nil {
+		t.Fatalf("Failed to RSVP: %v", err)
+	}
+	if updated.GoingCount != 1 || updated.MaybeCount != 1 || updated.MyRSVP.String != string(model.RSVPMaybe) {
+		t.Errorf("Unexpected counts after RSVP: %+v", updated)
+	}
+
+	events, err := service.ListEvents(ctx, room.ID, alice.ID, 20, 0)
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != event.ID {
+		t.Fatalf("Expected the event to be listed, got %+v", events)
+	}
+
+	t.Run("Private calendar needs membership", func(t *testing.T) {
+		if _, err := service.GetCalendar(ctx, room.ID, ""); err != ErrRoomCalendarNotFound {
+			t.Errorf("Expected ErrRoomCalendarNotFound without a user, got %v", err)
+		}
+		if _, err := service.GetCalendar(ctx, room.ID, outsider.ID); err != ErrRoomCalendarNotFound {
+			t.Errorf("Expected ErrRoomCalendarNotFound for a non-member, got %v", err)
+		}
+		calendar, err := service.GetCalendar(ctx, room.ID, alice.ID)
+		if err != nil {
+			t.Fatalf("Failed to get calendar: %v", err)
+		}
+		if len(calendar.Events) != 1 {
+			t.Errorf("Expected 1 calendar event, got %d", len(calendar.Events))
+		}
+	})
+
+	t.Run("Reminds members who answered", func(t *testing.T) {
+		recipients, err := service.eventRepo.ListReminderRecipients(ctx, event.ID)
+		if err != nil {
+			t.Fatalf("Failed to list reminder recipients: %v", err)
+		}
+		if len(recipients) != 2 {
+			t.Errorf("Expected 2 recipients, got %v", recipients)
+		}
+
+		claimed, err := service.eventRepo.ClaimDueReminders(ctx, startsAt.Add(time.Minute), 100)
+		if err != nil {
+			t.Fatalf("Failed to claim reminders: %v", err)
+		}
+		found := false
+		for _, e := range claimed {
+			found = found || e.ID == event.ID
+		}
+		if !found {
+			t.Error("Expected the event to be claimed for its reminder")
+		}
+		again, _ := service.eventRepo.ClaimDueReminders(ctx, startsAt.Add(time.Minute), 100)
+		for _, e := range again {
+			if e.ID == event.ID {
+				t.Error("Expected the reminder to be claimed only once")
+			}
+		}
+	})
+
+	if err := service.DeleteEvent(ctx, room.ID, event.ID, alice.ID); err != apperrors.ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied for a member who did not create the event, got %v", err)
+	}
+	if err := service.DeleteEvent(ctx, room.ID, event.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+	if _, err := service.GetEvent(ctx, room.ID, event.ID, owner.ID); err != ErrRoomEventNotFound {
+		t.Errorf("Expected ErrRoomEventNotFound after deletion, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Feed size bounds
+const (
+	DefaultRoomFeedSize = 50
+	MaxRoomFeedSize     = 200
+)
+
+var (
+	ErrRoomFeedNotPublic = apperrors.New(http.StatusBadRequest, "僅公開聊天室可開放訂閱")
+	// Unknown rooms answer the same way, so the feed does not reveal which rooms exist
+	ErrRoomFeedUnavailable = apperrors.New(http.StatusNotFound, "此聊天室未開放訂閱")
+)
+
+// RoomFeed is a room and its most recent messages, newest first
+type RoomFeed struct {
+	Room     *model.Room
+	Messages []*model.MessageWithUser
+}
+
+// GetFeed loads a room's feed. It needs no caller: only public rooms that
+// opted in publish one.
+func (s *RoomService) GetFeed(ctx context.Context, roomID string, limit int) (*RoomFeed, error) {
+	if limit <= 0 {
+		limit = DefaultRoomFeedSize
+	}
+	if limit > MaxRoomFeedSize {
+		limit = MaxRoomFeedSize
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, ErrRoomFeedUnavailable
+		}
+		s.logger.Error("Failed to get room", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if !room.HasFeed() {
+		return nil, ErrRoomFeedUnavailable
+	}
+
+	messages, err := s.messageRepo.ListRecentForFeed(ctx, roomID, limit)
+	if err != nil {
+		s.logger.Error("Failed to list messages for feed", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return &RoomFeed{Room: room, Messages: messages}, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+func TestRoomService_GetFeed(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	messageRepo := repository.NewMessageRepository(db)
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	public := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+	private := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePrivate)
+
+	if _, err := service.GetFeed(ctx, public.ID, 0); err != ErrRoomFeedUnavailable {
+		t.Errorf("Expected ErrRoomFeedUnavailable before opting in, got %v", err)
+	}
+
+	enabled := true
+	if _, err := service.Update(ctx, &UpdateRoomInput{RoomID: private.ID, UserID: owner.ID, FeedEnabled: &enabled}); err != ErrRoomFeedNotPublic {
+		t.Errorf("Expected ErrRoomFeedNotPublic, got %v", err)
+	}
+	if _, err := service.Update(ctx, &UpdateRoomInput{RoomID: public.ID, UserID: owner.ID, FeedEnabled: &enabled}); err != nil {
+		t.Fatalf("Failed to enable feed: %v", err)
+	}
+
+	for _, content := range []string{"first", "second", "removed"} {
+		if err := messageRepo.Create(ctx, &model.Message{RoomID: public.ID, UserID: owner.ID, Content: content, Type: model.MessageTypeText}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE messages SET is_deleted = TRUE WHERE room_id = $1 AND content = 'removed'`, public.ID); err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+
+	feed, err := service.GetFeed(ctx, public.ID, 0)
+	if err != nil {
+		t.Fatalf("Failed to get feed: %v", err)
+	}
+	if len(feed.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(feed.Messages))
+	}
+	if feed.Messages[0].Content != "second" {
+		t.Errorf("Expected newest message first, got %q", feed.Messages[0].Content)
+	}
+}
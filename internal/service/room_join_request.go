@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/go-demo/chat/internal/i18n"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// SetJoinRequestRepository enables join requests for private rooms
+func (s *RoomService) SetJoinRequestRepository(repo *repository.JoinRequestRepository) {
+	s.joinRequestRepo = repo
+}
+
+// RequestJoin files a request to join a private room for moderator review
+func (s *RoomService) RequestJoin(ctx context.Context, roomID, userID, message string) (*model.RoomJoinRequest, error) {
+	if s.joinRequestRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	// Rooms that can be joined directly don't need a request
+	if s.policy.Can(&policy.Subject{UserID: userID, Room: room}, policy.CanJoin) {
+		return nil, apperrors.New(400, "此聊天室可直接加入，無需申請")
+	}
+	if !room.IsPrivate() {
+		return nil, apperrors.ErrPermissionDenied
+	}
+
+	isMember, err := s.roomRepo.IsMember(ctx, roomID, userID)
+	if err != nil {
+		s.logger.Error("Failed to check membership", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if isMember {
+		return nil, apperrors.ErrAlreadyRoomMember
+	}
+
+	req := &model.RoomJoinRequest{
+		RoomID:  roomID,
+		UserID:  userID,
+		Message: sql.NullString{String: message, Valid: message != ""},
+	}
+	if err := s.joinRequestRepo.Create(ctx, req); err != nil {
+		if err == repository.ErrJoinRequestExists {
+			return nil, apperrors.ErrJoinRequestExists
+		}
+		s.logger.Error("Failed to create join request", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Join request filed",
+		zap.String("room_id", roomID),
+		zap.String("user_id", userID),
+	)
+
+	return req, nil
+}
+
+// ListJoinRequests lists a room's pending join requests for its moderators
+func (s *RoomService) ListJoinRequests(ctx context.Context, roomID, userID string, limit, offset int) ([]*model.RoomJoinRequestWithUser, error) {
+	if s.joinRequestRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	if err := s.authorize(ctx, room, userID, policy.CanInvite); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.joinRequestRepo.ListPending(ctx, roomID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list join requests", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return requests, nil
+}
+
+// ApproveJoinRequest adds the requester to the room and notifies them
+func (s *RoomService) ApproveJoinRequest(ctx context.Context, roomID, requestID, userID string) error {
+	room, req, err := s.loadJoinRequest(ctx, roomID, requestID, userID)
+	if err != nil {
+		return err
+	}
+
+	member := &model.RoomMember{
+		RoomID: roomID,
+		UserID: req.UserID,
+		Role:   model.MemberRoleMember,
+	}
+	// An invite may have raced the request; the requester is in either way
+	if err := s.roomRepo.AddMember(ctx, member); err != nil && err != repository.ErrAlreadyRoomMember {
+		if err == repository.ErrRoomFull {
+			return apperrors.ErrRoomFull
+		}
+		s.logger.Error("Failed to add member from join request", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	return s.resolveJoinRequest(ctx, room, req, model.JoinRequestApproved, userID)
+}
+
+// RejectJoinRequest declines a join request and notifies the requester
+func (s *RoomService) RejectJoinRequest(ctx context.Context, roomID, requestID, userID string) error {
+	room, req, err := s.loadJoinRequest(ctx, roomID, requestID, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.resolveJoinRequest(ctx, room, req, model.JoinRequestRejected, userID)
+}
+
+// loadJoinRequest loads a pending request of the room after checking that
+// the user may review it
+func (s *RoomService) loadJoinRequest(ctx context.Context, roomID, requestID, userID string) (*model.Room, *model.RoomJoinRequest, error) {
+	if s.joinRequestRepo == nil {
+		return nil, nil, apperrors.ErrNotFound
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, nil, apperrors.ErrRoomNotFound
+		}
+		return nil, nil, apperrors.ErrInternal
+	}
+
+	if err := s.authorize(ctx, room, userID, policy.CanInvite); err != nil {
+		return nil, nil, err
+	}
+
+	req, err := s.joinRequestRepo.GetByID(ctx, requestID)
+	if err != nil {
+		if err == repository.ErrJoinRequestNotFound {
+			return nil, nil, apperrors.ErrJoinRequestNotFound
+		}
+		s.logger.Error("Failed to get join request", zap.Error(err))
+		return nil, nil, apperrors.ErrInternal
+	}
+	if req.RoomID != roomID || !req.IsPending() {
+		return nil, nil, apperrors.ErrJoinRequestNotFound
+	}
+
+	return room, req, nil
+}
+
+func (s *RoomService) resolveJoinRequest(ctx context.Context, room *model.Room, req *model.RoomJoinRequest, status model.JoinRequestStatus, resolverID string) error {
+	if _, err := s.joinRequestRepo.Resolve(ctx, req.ID, status, resolverID); err != nil {
+		if err == repository.ErrJoinRequestNotFound {
+			return apperrors.ErrJoinRequestNotFound
+		}
+		s.logger.Error("Failed to resolve join request", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("Join request resolved",
+		zap.String("room_id", room.ID),
+		zap.String("request_id", req.ID),
+		zap.String("status", string(status)),
+		zap.String("resolved_by", resolverID),
+	)
+
+	if status == model.JoinRequestApproved {
+		s.emitMemberEvent(ctx, room.ID, model.WebhookEventMemberJoined, req.UserID, WebhookMemberReasonJoinRequest, resolverID)
+		s.postSystemMessage(ctx, room, i18n.SystemMemberJoined, req.UserID, "")
+	}
+
+	if s.notifier != nil {
+		input := &NotifyInput{
+			Type:          model.NotificationTypeJoinRequestApproved,
+			Title:         fmt.Sprintf("您已加入聊天室「%s」", room.Name),
+			ReferenceID:   room.ID,
+			ReferenceType: "room",
+		}
+		if status == model.JoinRequestRejected {
+			input.Type = model.NotificationTypeJoinRequestRejected
+			input.Title = fmt.Sprintf("您加入聊天室「%s」的申請未獲核准", room.Name)
+		}
+		// The decision is committed; the requester must hear about it even if
+		// the reviewing client disconnects
+		s.notifier.Notify(context.WithoutCancel(ctx), []string{req.UserID}, input)
+	}
+
+	return nil
+}
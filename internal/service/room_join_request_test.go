@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+func TestRoomService_JoinRequests(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+	service.SetJoinRequestRepository(repository.NewJoinRequestRepository(db))
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	alice := createUserForRoomServiceTestIsolated(t, db, prefix, "alice")
+	bob := createUserForRoomServiceTestIsolated(t, db, prefix, "bob")
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePrivate)
+	ctx := context.Background()
+
+	t.Run("Public room rejects requests", func(t *testing.T) {
+		public := createRoomForRoomServiceTestIsolated(t, service, prefix+"_pub", owner, model.RoomTypePublic)
+		if _, err := service.RequestJoin(ctx, public.ID, alice.ID, ""); err == nil {
+			t.Error("Expected error for public room")
+		}
+	})
+
+	aliceReq, err := service.RequestJoin(ctx, room.ID, alice.ID, "hello")
+	if err != nil {
+		t.Fatalf("Failed to request join: %v", err)
+	}
+	if _, err := service.RequestJoin(ctx, room.ID, alice.ID, ""); err != apperrors.ErrJoinRequestExists {
+		t.Errorf("Expected ErrJoinRequestExists, got %v", err)
+	}
+	bobReq, err := service.RequestJoin(ctx, room.ID, bob.ID, "")
+	if err != nil {
+		t.Fatalf("Failed to request join: %v", err)
+	}
+
+	t.Run("Non-moderator cannot list", func(t *testing.T) {
+		if _, err := service.ListJoinRequests(ctx, room.ID, alice.ID, 20, 0); err == nil {
+			t.Error("Expected permission error")
+		}
+	})
+
+	pending, err := service.ListJoinRequests(ctx, room.ID, owner.ID, 20, 0)
+	if err != nil {
+		t.Fatalf("Failed to list join requests: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending requests, got %d", len(pending))
+	}
+
+	if err := service.ApproveJoinRequest(ctx, room.ID, aliceReq.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to approve: %v", err)
+	}
+	isMember, _ := service.roomRepo.IsMember(ctx, room.ID, alice.ID)
+	if !isMember {
+		t.Error("Expected approved requester to be a member")
+	}
+	if err := service.ApproveJoinRequest(ctx, room.ID, aliceReq.ID, owner.ID); err != apperrors.ErrJoinRequestNotFound {
+		t.Errorf("Expected resolved request to be gone, got %v", err)
+	}
+
+	if err := service.RejectJoinRequest(ctx, room.ID, bobReq.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to reject: %v", err)
+	}
+	isMember, _ = service.roomRepo.IsMember(ctx, room.ID, bob.ID)
+	if isMember {
+		t.Error("Expected rejected requester not to be a member")
+	}
+
+	// A rejected user may ask again
+	if _, err := service.RequestJoin(ctx, room.ID, bob.ID, ""); err != nil {
+		t.Errorf("Expected new request after rejection, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// RoomMergeBatchSize bounds the members and messages moved per transaction
+const RoomMergeBatchSize = 500
+
+// roomMergeBatchesPerRun bounds how many batches one merge advances per
+// scheduler run so a large merge does not starve the others
+const roomMergeBatchesPerRun = 20
+
+// maxMergeRedirects bounds how many merges a link follows
+const maxMergeRedirects = 8
+
+// RoomEventMerged tells subscribers of both rooms that the source room now
+// lives on in the target
+const RoomEventMerged = "room_merged"
+
+var (
+	ErrRoomMergeSameRoom   = apperrors.New(http.StatusBadRequest, "無法將聊天室併入自身")
+	ErrRoomMergeDirect     = apperrors.New(http.StatusBadRequest, "私訊聊天室無法合併")
+	ErrRoomMergeInProgress = apperrors.New(http.StatusConflict, "聊天室正在進行合併")
+	ErrRoomMergeHeld       = apperrors.New(http.StatusConflict, "聊天室在法律保全中，無法合併")
+	ErrRoomMergeNotFound   = apperrors.New(http.StatusNotFound, "合併作業不存在")
+)
+
+// MergeRoomInput describes a merge requested by an admin
+type MergeRoomInput struct {
+	SourceRoomID   string
+	TargetRoomID   string
+	IncludeHistory bool
+	MergedBy       string
+}
+
+// RoomMergedEvent is the payload of RoomEventMerged
+type RoomMergedEvent struct {
+	MergeID      string `json:"merge_id"`
+	RoomID       string `json:"room_id"`
+	MergedIntoID string `json:"merged_into_id"`
+}
+
+// SetMergeRepository enables admin room merges
+func (s *RoomService) SetMergeRepository(repo *repository.RoomMergeRepository) {
+	s.mergeRepo = repo
+}
+
+// MergeRoom queues a merge of the source room into the target. The work is
+// done in batches by ProcessRoomMerges; the returned merge reports progress.
+func (s *RoomService) MergeRoom(ctx context.Context, input *MergeRoomInput) (*model.RoomMerge, error) {
+	if input.SourceRoomID == input.TargetRoomID {
+		return nil, ErrRoomMergeSameRoom
+	}
+
+	source, err := s.mergeableRoom(ctx, input.SourceRoomID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.mergeableRoom(ctx, input.TargetRoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	merge := &model.RoomMerge{
+		SourceRoomID:   source.ID,
+		TargetRoomID:   target.ID,
+		IncludeHistory: input.IncludeHistory,
+		MergedBy:       sql.NullString{String: input.MergedBy, Valid: input.MergedBy != ""},
+	}
+	if err := s.mergeRepo.Create(ctx, merge); err != nil {
+		switch err {
+		case repository.ErrRoomMergeInProgress:
+			return nil, ErrRoomMergeInProgress
+		case repository.ErrRoomMergeHeld:
+			return nil, ErrRoomMergeHeld
+		}
+		s.logger.Error("Failed to create room merge", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Room merge queued",
+		zap.String("merge_id", merge.ID),
+		zap.String("source_room_id", source.ID),
+		zap.String("target_room_id", target.ID),
+		zap.String("merged_by", input.MergedBy),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    input.MergedBy,
+		Action:     model.AuditActionRoomMerged,
+		TargetType: model.AuditTargetRoom,
+		TargetID:   source.ID,
+		Metadata: map[string]interface{}{
+			"merge_id":        merge.ID,
+			"room_name":       source.Name,
+			"target_room_id":  target.ID,
+			"target_name":     target.Name,
+			"include_history": input.IncludeHistory,
+		},
+	})
+
+	return merge, nil
+}
+
+// mergeableRoom loads a room that may take part in a new merge
+func (s *RoomService) mergeableRoom(ctx context.Context, id string) (*model.Room, error) {
+	room, err := s.roomRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		s.logger.Error("Failed to get room", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if room.IsDirect() {
+		return nil, ErrRoomMergeDirect
+	}
+	if room.IsPendingDeletion() {
+		return nil, apperrors.ErrRoomDeletionPending
+	}
+
+	// Chained merges would move data out from under a running batch
+	merging, err := s.mergeRepo.IsMerging(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to check room merge", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if merging {
+		return nil, ErrRoomMergeInProgress
+	}
+
+	return room, nil
+}
+
+// GetRoomMerge returns a merge and its progress
+func (s *RoomService) GetRoomMerge(ctx context.Context, id string) (*model.RoomMerge, error) {
+	merge, err := s.mergeRepo.GetByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrRoomMergeNotFound {
+			return nil, ErrRoomMergeNotFound
+		}
+		s.logger.Error("Failed to get room merge", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return merge, nil
+}
+
+// ListRoomMerges lists merges, newest first
+func (s *RoomService) ListRoomMerges(ctx context.Context, limit, offset int) ([]*model.RoomMerge, error) {
+	merges, err := s.mergeRepo.List(ctx, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list room merges", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return merges, nil
+}
+
+// ProcessRoomMerges advances every active merge by a bounded number of
+// batches. Progress is committed per batch, so a merge interrupted by a
+// restart resumes on the next run, possibly on another instance.
+func (s *RoomService) ProcessRoomMerges(ctx context.Context) (int, error) {
+	if s.mergeRepo == nil {
+		return 0, nil
+	}
+
+	ids, err := s.mergeRepo.ListActiveIDs(ctx, 10)
+	if err != nil {
+		s.logger.Error("Failed to list active room merges", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+
+	completed := 0
+	for _, id := range ids {
+		for i := 0; i < roomMergeBatchesPerRun; i++ {
+			if err := checkContext(ctx); err != nil {
+				return completed, err
+			}
+
+			merge, err := s.mergeRepo.RunBatch(ctx, id, RoomMergeBatchSize)
+			if err != nil {
+				s.logger.Error("Failed to run room merge batch", zap.String("merge_id", id), zap.Error(err))
+				break
+			}
+			// Held by another instance
+			if merge == nil {
+				break
+			}
+			if merge.IsActive() {
+				continue
+			}
+
+			if merge.Status == model.RoomMergeCompleted {
+				completed++
+				s.finishMerge(merge)
+			} else {
+				s.logger.Warn("Room merge failed",
+					zap.String("merge_id", merge.ID),
+					zap.String("error", merge.Error.String),
+				)
+			}
+			break
+		}
+	}
+
+	return completed, nil
+}
+
+func (s *RoomService) finishMerge(merge *model.RoomMerge) {
+	s.logger.Info("Room merge completed",
+		zap.String("merge_id", merge.ID),
+		zap.String("source_room_id", merge.SourceRoomID),
+		zap.String("target_room_id", merge.TargetRoomID),
+		zap.Int("members_moved", merge.MembersMoved),
+		zap.Int("messages_moved", merge.MessagesMoved),
+	)
+
+	if s.notifier != nil {
+		event := &RoomMergedEvent{
+			MergeID:      merge.ID,
+			RoomID:       merge.SourceRoomID,
+			MergedIntoID: merge.TargetRoomID,
+		}
+		s.notifier.PublishToRoom(merge.SourceRoomID, RoomEventMerged, event)
+		s.notifier.PublishToRoom(merge.TargetRoomID, RoomEventMerged, event)
+	}
+}
+
+// ResolveMergedRoom follows merges from a room that no longer exists to the
+// room it lives on in. It returns apperrors.ErrRoomNotFound when the room
+// was not merged.
+func (s *RoomService) ResolveMergedRoom(ctx context.Context, roomID string) (string, error) {
+	id := roomID
+	for i := 0; i < maxMergeRedirects; i++ {
+		room, err := s.roomRepo.GetByIDIncludingDeleted(ctx, id)
+		if err != nil {
+			if err == repository.ErrRoomNotFound {
+				return "", apperrors.ErrRoomNotFound
+			}
+			s.logger.Error("Failed to get room", zap.Error(err))
+			return "", apperrors.ErrInternal
+		}
+		if room.DeletedAt == nil {
+			if id == roomID {
+				return "", apperrors.ErrRoomNotFound
+			}
+			return id, nil
+		}
+		if !room.IsMerged() {
+			return "", apperrors.ErrRoomNotFound
+		}
+		id = room.MergedIntoID.String
+	}
+	return "", apperrors.ErrRoomNotFound
+}
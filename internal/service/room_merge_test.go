@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+func TestRoomService_MergeRoom(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	service.SetMergeRepository(repository.NewRoomMergeRepository(db))
+	roomRepo := repository.NewRoomRepository(db)
+	messageRepo := repository.NewMessageRepository(db)
+
+	ctx := context.Background()
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	both := createUserForRoomServiceTestIsolated(t, db, prefix, "both")
+	newcomer := createUserForRoomServiceTestIsolated(t, db, prefix, "newcomer")
+	source := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+	target := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+
+	for _, m := range []*model.RoomMember{
+		{RoomID: source.ID, UserID: both.ID, Role: model.MemberRoleAdmin},
+		{RoomID: target.ID, UserID: both.ID, Role: model.MemberRoleMember},
+		{RoomID: source.ID, UserID: newcomer.ID, Role: model.MemberRoleMember},
+	} {
+		if err := roomRepo.AddMember(ctx, m); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+	for _, content := range []string{prefix + " first", prefix + " second"} {
+		if err := messageRepo.Create(ctx, &model.Message{RoomID: source.ID, UserID: owner.ID, Content: content, Type: model.MessageTypeText}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	if _, err := service.MergeRoom(ctx, &MergeRoomInput{SourceRoomID: source.ID, TargetRoomID: source.ID}); err != ErrRoomMergeSameRoom {
+		t.Errorf("Expected ErrRoomMergeSameRoom, got %v", err)
+	}
+
+	merge, err := service.MergeRoom(ctx, &MergeRoomInput{
+		SourceRoomID:   source.ID,
+		TargetRoomID:   target.ID,
+		IncludeHistory: true,
+		MergedBy:       owner.ID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to queue merge: %v", err)
+	}
+	if merge.Status != model.RoomMergePending {
+		t.Errorf("Expected pending merge, got %s", merge.Status)
+	}
+
+	if _, err := service.MergeRoom(ctx, &MergeRoomInput{SourceRoomID: target.ID, TargetRoomID: source.ID}); err != ErrRoomMergeInProgress {
+		t.Errorf("Expected ErrRoomMergeInProgress, got %v", err)
+	}
+
+	if _, err := service.ProcessRoomMerges(ctx); err != nil {
+		t.Fatalf("Failed to process merges: %v", err)
+	}
+
+	merge, err = service.GetRoomMerge(ctx, merge.ID)
+	if err != nil {
+		t.Fatalf("Failed to get merge: %v", err)
+	}
+	if merge.Status != model.RoomMergeCompleted {
+		t.Fatalf("Expected completed merge, got %s (%s)", merge.Status, merge.Error.String)
+	}
+	// Owner and the shared member were already in the target
+	if merge.MembersMoved != 1 || merge.MessagesMoved != 2 {
+		t.Errorf("Expected 1 member and 2 messages moved, got %d/%d", merge.MembersMoved, merge.MessagesMoved)
+	}
+
+	member, err := roomRepo.GetMember(ctx, target.ID, both.ID)
+	if err != nil {
+		t.Fatalf("Failed to get member: %v", err)
+	}
+	if member.Role != model.MemberRoleMember {
+		t.Errorf("Expected existing target role to be kept, got %s", member.Role)
+	}
+	if ok, _ := roomRepo.IsMember(ctx, target.ID, newcomer.ID); !ok {
+		t.Error("Expected newcomer to be moved to the target")
+	}
+
+	var merged int
+	if err := db.Get(&merged, `SELECT COUNT(*) FROM messages WHERE room_id = $1 AND merged_from_room_id = $2`, target.ID, source.ID); err != nil {
+		t.Fatalf("Failed to count merged messages: %v", err)
+	}
+	if merged != 2 {
+		t.Errorf("Expected 2 messages tagged with their source room, got %d", merged)
+	}
+
+	if _, err := service.GetByID(ctx, source.ID); err != apperrors.ErrRoomNotFound {
+		t.Errorf("Expected merged room to be gone, got %v", err)
+	}
+	resolved, err := service.ResolveMergedRoom(ctx, source.ID)
+	if err != nil || resolved != target.ID {
+		t.Errorf("Expected merged room to resolve to %s, got %q, %v", target.ID, resolved, err)
+	}
+	if _, err := service.ResolveMergedRoom(ctx, target.ID); err != apperrors.ErrRoomNotFound {
+		t.Errorf("Expected a live room not to resolve, got %v", err)
+	}
+}
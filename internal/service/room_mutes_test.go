@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+func TestRoomService_MuteMember(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	member := createUserForRoomServiceTestIsolated(t, db, prefix, "member")
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+	ctx := context.Background()
+
+	if err := service.Join(ctx, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to join room: %v", err)
+	}
+
+	messageService := NewMessageService(repository.NewMessageRepository(db), repository.NewRoomRepository(db), zap.NewNop())
+
+	t.Run("Member cannot mute", func(t *testing.T) {
+		if _, err := service.MuteMember(ctx, room.ID, member.ID, owner.ID, time.Minute); err != apperrors.ErrPermissionDenied {
+			t.Errorf("Expected ErrPermissionDenied, got %v", err)
+		}
+	})
+
+	t.Run("Rejects out of range durations", func(t *testing.T) {
+		if _, err := service.MuteMember(ctx, room.ID, owner.ID, member.ID, 0); err == nil {
+			t.Error("Expected error for zero duration")
+		}
+	})
+
+	muted, err := service.MuteMember(ctx, room.ID, owner.ID, member.ID, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to mute member: %v", err)
+	}
+	if !muted.IsMuted || muted.MutedUntil == nil {
+		t.Fatalf("Expected mute with deadline, got %+v", muted)
+	}
+
+	_, err = messageService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: member.ID, Content: "hi"})
+	if err != apperrors.ErrMemberMuted {
+		t.Errorf("Expected ErrMemberMuted, got %v", err)
+	}
+
+	// Backdate the mute so the sweep picks it up
+	if _, err := db.ExecContext(ctx, `UPDATE room_members SET muted_until = NOW() - INTERVAL '1 second' WHERE room_id = $1 AND user_id = $2`, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to backdate mute: %v", err)
+	}
+	if _, err := service.ExpireMutes(ctx, 500); err != nil {
+		t.Fatalf("Failed to expire mutes: %v", err)
+	}
+
+	m, err := service.GetMember(ctx, room.ID, member.ID)
+	if err != nil {
+		t.Fatalf("Failed to get member: %v", err)
+	}
+	if m.IsMuted {
+		t.Error("Expected mute to be lifted")
+	}
+	if _, err := messageService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: member.ID, Content: prefix + " hi"}); err != nil {
+		t.Errorf("Expected unmuted member to send, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// RoomEventPermissionsUpdated tells room subscribers to refetch their permissions
+const RoomEventPermissionsUpdated = "room_permissions_updated"
+
+// Errors returned when a role matrix update touches something owners may
+// not configure
+var (
+	ErrUnconfigurableRole   = apperrors.New(http.StatusBadRequest, "只能設定管理員與一般成員的權限")
+	ErrUnconfigurableAction = apperrors.New(http.StatusBadRequest, "包含無法設定的權限項目")
+)
+
+// RoomPermissionsEvent is the payload of RoomEventPermissionsUpdated
+type RoomPermissionsEvent struct {
+	RoomID string        `json:"room_id"`
+	Roles  policy.Matrix `json:"roles"`
+}
+
+// SetPermissionRepository enables per-room role permission overrides
+func (s *RoomService) SetPermissionRepository(repo *repository.RoomPermissionRepository) {
+	s.permRepo = repo
+}
+
+// RoleMatrix returns the effective role permission matrix of a room
+func (s *RoomService) RoleMatrix(ctx context.Context, roomID, userID string) (policy.Matrix, error) {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	if err := s.authorize(ctx, room, userID, policy.CanViewMembers); err != nil {
+		return nil, err
+	}
+
+	overrides, err := loadOverrides(ctx, s.permRepo, room.ID)
+	if err != nil {
+		s.logger.Error("Failed to load room permissions", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return s.policy.Matrix(overrides), nil
+}
+
+// UpdateRoleMatrix replaces a room's role grants. Roles and actions left out
+// fall back to the defaults.
+func (s *RoomService) UpdateRoleMatrix(ctx context.Context, roomID, userID string, grants policy.Matrix) (policy.Matrix, error) {
+	if s.permRepo == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	if err := s.authorize(ctx, room, userID, policy.CanManageRoles); err != nil {
+		return nil, err
+	}
+
+	var perms []*model.RoomRolePermission
+	for role, actions := range grants {
+		if !policy.IsConfigurableRole(role) {
+			return nil, ErrUnconfigurableRole
+		}
+		for action, allowed := range actions {
+			if !policy.IsConfigurable(action) {
+				return nil, ErrUnconfigurableAction
+			}
+			perms = append(perms, &model.RoomRolePermission{
+				RoomID:  roomID,
+				Role:    role,
+				Action:  string(action),
+				Allowed: allowed,
+			})
+		}
+	}
+	sort.Slice(perms, func(i, j int) bool {
+		if perms[i].Role != perms[j].Role {
+			return perms[i].Role < perms[j].Role
+		}
+		return perms[i].Action < perms[j].Action
+	})
+
+	if err := s.permRepo.Replace(ctx, roomID, userID, perms); err != nil {
+		s.logger.Error("Failed to save room permissions", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	matrix := s.policy.Matrix(grants)
+
+	s.logger.Info("Room permissions updated",
+		zap.String("room_id", roomID),
+		zap.String("updated_by", userID),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    userID,
+		Action:     model.AuditActionRoomPermissionsUpdated,
+		TargetType: model.AuditTargetRoom,
+		TargetID:   roomID,
+		Metadata: map[string]interface{}{
+			"roles": matrix,
+		},
+	})
+
+	if s.notifier != nil {
+		s.notifier.PublishToRoom(roomID, RoomEventPermissionsUpdated, &RoomPermissionsEvent{
+			RoomID: roomID,
+			Roles:  matrix,
+		})
+	}
+
+	return matrix, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// SetRetention enables permanently removing soft deleted rooms once they
+// have been deleted for retention. Files uploaded to the room are those
+// served from uploadDir at baseURL + "/uploads/".
+func (s *RoomService) SetRetention(retention time.Duration, uploadDir, baseURL string) {
+	s.retention = retention
+	s.uploadDir = uploadDir
+	s.uploadURL = baseURL + "/uploads/"
+}
+
+// PurgeDeletedRooms permanently removes up to limit rooms whose retention
+// has elapsed, with their messages and the files uploaded to them. Rooms
+// under legal hold wait until the hold is released.
+func (s *RoomService) PurgeDeletedRooms(ctx context.Context, limit int) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	rooms, err := s.roomRepo.ListPurgeable(ctx, time.Now().Add(-s.retention), limit)
+	if err != nil {
+		s.logger.Error("Failed to list purgeable rooms", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+
+	purged := 0
+	for _, room := range rooms {
+		if err := checkContext(ctx); err != nil {
+			return purged, err
+		}
+
+		files, err := s.roomRepo.Purge(ctx, room.ID)
+		if err != nil {
+			// Another instance got there first
+			if err == repository.ErrRoomNotFound {
+				continue
+			}
+			s.logger.Error("Failed to purge room", zap.String("room_id", room.ID), zap.Error(err))
+			return purged, apperrors.ErrInternal
+		}
+		purged++
+
+		removed := s.removeUploads(room.ID, files)
+		s.logger.Info("Room purged",
+			zap.String("room_id", room.ID),
+			zap.Int("files_removed", removed),
+		)
+		s.auditor.Record(ctx, &AuditEntry{
+			Action:     model.AuditActionRoomPurged,
+			TargetType: model.AuditTargetRoom,
+			TargetID:   room.ID,
+			Metadata: map[string]interface{}{
+				"room_name":     room.Name,
+				"files_removed": removed,
+			},
+		})
+	}
+
+	return purged, nil
+}
+
+// removeUploads deletes the uploaded files behind urls. The room is already
+// gone, so failures are only logged.
+func (s *RoomService) removeUploads(roomID string, urls []string) int {
+	removed := 0
+	for _, url := range urls {
+		p, ok := uploadPath(s.uploadDir, s.uploadURL, url)
+		if !ok {
+			continue
+		}
+		if err := os.Remove(p); err != nil {
+			if !os.IsNotExist(err) {
+				s.logger.Warn("Failed to remove room upload", zap.String("room_id", roomID), zap.String("path", p), zap.Error(err))
+			}
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// uploadPath maps the URL of an uploaded file to its path under dir. URLs
+// not starting with prefix, such as external images, have no local file.
+func uploadPath(dir, prefix, url string) (string, bool) {
+	if dir == "" || !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+
+	rel := url[len(prefix):]
+	if j := strings.IndexAny(rel, "?#"); j >= 0 {
+		rel = rel[:j]
+	}
+	// Cleaning from the root drops any ".." climbing out of dir
+	rel = strings.TrimPrefix(path.Clean("/"+rel), "/")
+	if rel == "" {
+		return "", false
+	}
+	return filepath.Join(dir, filepath.FromSlash(rel)), true
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+func TestUploadPath(t *testing.T) {
+	const prefix = "http://localhost:8080/uploads/"
+
+	tests := []struct {
+		url  string
+		path string
+		ok   bool
+	}{
+		{prefix + "images/a.png", filepath.Join("uploads", "images", "a.png"), true},
+		{prefix + "files/report.pdf?download=1", filepath.Join("uploads", "files", "report.pdf"), true},
+		{prefix + "../config.yaml", filepath.Join("uploads", "config.yaml"), true},
+		{prefix + "images/../../../etc/passwd", filepath.Join("uploads", "etc", "passwd"), true},
+		{prefix, "", false},
+		{"https://cdn.example.com/uploads/images/a.png", "", false},
+		{"hello", "", false},
+	}
+
+	for _, tt := range tests {
+		path, ok := uploadPath("uploads", prefix, tt.url)
+		if ok != tt.ok || path != tt.path {
+			t.Errorf("uploadPath(%q) = %q, %v, expected %q, %v", tt.url, path, ok, tt.path, tt.ok)
+		}
+	}
+}
+
+func TestRoomService_PurgeDeletedRooms_Disabled(t *testing.T) {
+	service := NewRoomService(nil, nil, nil, zap.NewNop())
+
+	n, err := service.PurgeDeletedRooms(context.Background(), 10)
+	if err != nil || n != 0 {
+		t.Errorf("Expected nothing purged without a retention, got %d, %v", n, err)
+	}
+}
+
+func TestRoomService_PurgeDeletedRooms(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	ctx := context.Background()
+
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "images", prefix+".png")
+	if err := os.WriteFile(file, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	messageRepo := repository.NewMessageRepository(db)
+	if err := messageRepo.Create(ctx, &model.Message{
+		RoomID:  room.ID,
+		UserID:  owner.ID,
+		Content: "http://localhost:8080/uploads/images/" + prefix + ".png",
+		Type:    model.MessageTypeImage,
+	}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	service.SetDeletionDelay(time.Nanosecond)
+	if _, err := service.Delete(ctx, room.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to delete room: %v", err)
+	}
+	if _, err := service.PurgeScheduledDeletions(ctx, 100); err != nil {
+		t.Fatalf("Failed to soft delete room: %v", err)
+	}
+
+	service.SetRetention(time.Hour, dir, "http://localhost:8080")
+	if _, err := service.PurgeDeletedRooms(ctx, 100); err != nil {
+		t.Fatalf("Failed to purge rooms: %v", err)
+	}
+	if _, err := service.roomRepo.GetByIDIncludingDeleted(ctx, room.ID); err != nil {
+		t.Fatalf("Expected room kept during retention, got %v", err)
+	}
+
+	service.SetRetention(time.Nanosecond, dir, "http://localhost:8080")
+	if _, err := service.PurgeDeletedRooms(ctx, 100); err != nil {
+		t.Fatalf("Failed to purge rooms: %v", err)
+	}
+	if _, err := service.roomRepo.GetByIDIncludingDeleted(ctx, room.ID); err != repository.ErrRoomNotFound {
+		t.Errorf("Expected room purged, got %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("Expected uploaded file removed, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// RoomRateWindow is the window a room's rate limit counts messages in
+const RoomRateWindow = time.Minute
+
+// RateLimitBounds are the per-member message rates, per minute, owners may
+// choose for their rooms. Max is the global default, so a room can only be
+// made stricter; 0 leaves it unbounded.
+type RateLimitBounds struct {
+	Min int
+	Max int
+}
+
+// DefaultRateLimitBounds apply until SetRateLimitBounds is called
+var DefaultRateLimitBounds = RateLimitBounds{Min: 1, Max: 60}
+
+// ErrRoomRateLimited is returned when a member posts faster than the room
+// allows
+var ErrRoomRateLimited = apperrors.New(429, "此聊天室限制發言頻率，請稍後再試")
+
+// MessageRateLimiter counts requests per key in a sliding window.
+// It is implemented by middleware.RedisRateLimiter.
+type MessageRateLimiter interface {
+	AllowWithin(ctx context.Context, key string, requests int, window time.Duration) (bool, error)
+}
+
+// SetRateLimitBounds sets the range owners may pick a room rate limit from
+func (s *RoomService) SetRateLimitBounds(bounds RateLimitBounds) {
+	s.rateBounds = bounds
+}
+
+// validateRateLimit checks a room rate limit against the admin bounds; 0
+// clears the override
+func (s *RoomService) validateRateLimit(limit int) error {
+	if limit == 0 || (limit >= s.rateBounds.Min && (s.rateBounds.Max == 0 || limit <= s.rateBounds.Max)) {
+		return nil
+	}
+	if s.rateBounds.Max == 0 {
+		return apperrors.ErrValidation.WithDetails(map[string]string{
+			"rate_limit": fmt.Sprintf("每分鐘發言數不能低於 %d，0 表示沿用全站預設", s.rateBounds.Min),
+		})
+	}
+	return apperrors.ErrValidation.WithDetails(map[string]string{
+		"rate_limit": fmt.Sprintf("每分鐘發言數需介於 %d 至 %d 之間，0 表示沿用全站預設", s.rateBounds.Min, s.rateBounds.Max),
+	})
+}
+
+// SetRateLimiter enables rate limits in the send path: each room's own
+// limit, or defaultLimit messages per member per minute for rooms without
+// one. A zero defaultLimit only limits rooms that set their own.
+func (s *MessageService) SetRateLimiter(limiter MessageRateLimiter, defaultLimit int) {
+	s.rateLimiter = limiter
+	s.defaultRateLimit = defaultLimit
+}
+
+// checkRoomRate returns ErrRoomRateLimited when a member exceeds the room's
+// rate limit. Owners and admins are exempt, and a limiter failure lets the
+// message through.
+func (s *MessageService) checkRoomRate(ctx context.Context, roomID, userID string) error {
+	if s.rateLimiter == nil {
+		return nil
+	}
+
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		return nil
+	}
+	limit := room.RateLimit
+	if limit == 0 {
+		limit = s.defaultRateLimit
+	}
+	if limit == 0 {
+		return nil
+	}
+	member, err := s.roomRepo.GetMember(ctx, roomID, userID)
+	if err == nil && member.CanModerate() {
+		return nil
+	}
+
+	allowed, err := s.rateLimiter.AllowWithin(ctx, roomRateKey(roomID, userID), limit, RoomRateWindow)
+	if err != nil {
+		s.logger.Warn("Failed to check room rate limit", zap.String("room_id", roomID), zap.Error(err))
+		return nil
+	}
+	if !allowed {
+		return ErrRoomRateLimited
+	}
+	return nil
+}
+
+func roomRateKey(roomID, userID string) string {
+	return "ratelimit:room:" + roomID + ":" + userID
+}
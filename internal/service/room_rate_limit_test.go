@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+)
+
+// countingRateLimiter allows requests per key up to the limit, ignoring
+// the window
+type countingRateLimiter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (l *countingRateLimiter) AllowWithin(ctx context.Context, key string, requests int, window time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts == nil {
+		l.counts = make(map[string]int)
+	}
+	l.counts[key]++
+	return l.counts[key] <= requests, nil
+}
+
+func TestRoomService_ValidateRateLimit(t *testing.T) {
+	service := &RoomService{rateBounds: RateLimitBounds{Min: 2, Max: 30}}
+
+	for _, limit := range []int{0, 2, 30} {
+		if err := service.validateRateLimit(limit); err != nil {
+			t.Errorf("Expected %d to be allowed, got %v", limit, err)
+		}
+	}
+	for _, limit := range []int{1, 31} {
+		if err := service.validateRateLimit(limit); !apperrors.Is(err, apperrors.ErrValidation) {
+			t.Errorf("Expected %d to be rejected, got %v", limit, err)
+		}
+	}
+
+	service.rateBounds.Max = 0
+	if err := service.validateRateLimit(1000); err != nil {
+		t.Errorf("Expected no upper bound without a global default, got %v", err)
+	}
+}
+
+func TestMessageService_RoomRateLimit(t *testing.T) {
+	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
+	defer db.Close()
+	defer cleanupMessageServiceTestByPrefix(t, db, prefix)
+
+	msgService.SetRateLimiter(&countingRateLimiter{}, 0)
+	owner := createUserForMessageServiceTestIsolated(t, db, prefix, "owner")
+	member := createUserForMessageServiceTestIsolated(t, db, prefix, "member")
+	room := createRoomForMessageServiceTestIsolated(t, db, prefix, owner, roomService)
+	ctx := context.Background()
+
+	if err := roomService.Join(ctx, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to join room: %v", err)
+	}
+	limit := 2
+	if _, err := roomService.Update(ctx, &UpdateRoomInput{RoomID: room.ID, UserID: owner.ID, RateLimit: &limit}); err != nil {
+		t.Fatalf("Failed to set rate limit: %v", err)
+	}
+
+	send := func(userID string) error {
+		_, err := msgService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: userID, Content: prefix + "_hello"})
+		return err
+	}
+	for i := 0; i < limit; i++ {
+		if err := send(member.ID); err != nil {
+			t.Fatalf("Expected message %d to be sent, got %v", i+1, err)
+		}
+	}
+	if err := send(member.ID); err != ErrRoomRateLimited {
+		t.Errorf("Expected ErrRoomRateLimited, got %v", err)
+	}
+
+	// The owner is exempt
+	for i := 0; i <= limit; i++ {
+		if err := send(owner.ID); err != nil {
+			t.Fatalf("Expected the owner to be exempt, got %v", err)
+		}
+	}
+}
@@ -3,31 +3,110 @@ package service
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
+	"github.com/go-demo/chat/internal/events"
+	"github.com/go-demo/chat/internal/i18n"
 	"github.com/go-demo/chat/internal/model"
 	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
 	"github.com/go-demo/chat/internal/repository"
 	"go.uber.org/zap"
 )
 
+// DefaultRoomDeletionDelay is the window during which a scheduled deletion can be canceled
+const DefaultRoomDeletionDelay = 24 * time.Hour
+
+// Room lifecycle events pushed to room subscribers
+const (
+	RoomEventDeleting         = "room_deleting"
+	RoomEventDeletionCanceled = "room_deletion_canceled"
+	RoomEventDeleted          = "room_deleted"
+)
+
+// RoomDeletionEvent is the payload of room deletion events
+type RoomDeletionEvent struct {
+	RoomID      string `json:"room_id"`
+	RoomName    string `json:"room_name"`
+	ScheduledAt string `json:"scheduled_at,omitempty"`
+}
+
 type RoomService struct {
-	roomRepo    *repository.RoomRepository
-	userRepo    *repository.UserRepository
-	messageRepo *repository.MessageRepository
-	logger      *zap.Logger
+	roomRepo      RoomStore
+	userRepo      UserLookup
+	messageRepo   RoomMessageStore
+	notifier      *NotificationService
+	publisher     MessagePublisher
+	rateBounds    RateLimitBounds
+	policy        *policy.Engine
+	auditor       *AuditService
+	tx            Transactor
+	deletionDelay time.Duration
+	retention     time.Duration
+	uploadDir     string
+	uploadURL     string
+
+	joinRequestRepo *repository.JoinRequestRepository
+	permRepo        *repository.RoomPermissionRepository
+	mergeRepo       *repository.RoomMergeRepository
+	webhooks        *WebhookDispatcher
+	incomingRepo    *repository.IncomingWebhookRepository
+	eventRepo       *repository.RoomEventRepository
+	events          *events.Publisher
+	logger          *zap.Logger
 }
 
 func NewRoomService(
-	roomRepo *repository.RoomRepository,
-	userRepo *repository.UserRepository,
-	messageRepo *repository.MessageRepository,
+	roomRepo RoomStore,
+	userRepo UserLookup,
+	messageRepo RoomMessageStore,
 	logger *zap.Logger,
 ) *RoomService {
 	return &RoomService{
-		roomRepo:    roomRepo,
-		userRepo:    userRepo,
-		messageRepo: messageRepo,
-		logger:      logger,
+		roomRepo:      roomRepo,
+		userRepo:      userRepo,
+		messageRepo:   messageRepo,
+		policy:        policy.Default(),
+		deletionDelay: DefaultRoomDeletionDelay,
+		rateBounds:    DefaultRateLimitBounds,
+		logger:        logger,
+	}
+}
+
+// SetNotifier sets the notification service used for room events
+func (s *RoomService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// SetPolicy sets the policy engine used for authorization decisions
+func (s *RoomService) SetPolicy(engine *policy.Engine) {
+	s.policy = engine
+}
+
+// SetAuditor sets the audit service that records moderation and deletions
+func (s *RoomService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// SetTransactor sets the transaction manager that makes multi-step writes
+// such as creating a room with its owner atomic
+func (s *RoomService) SetTransactor(tx Transactor) {
+	s.tx = tx
+}
+
+// withinTx runs fn in a transaction, or directly when no transactor is set
+func (s *RoomService) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.WithinTx(ctx, fn)
+}
+
+// SetDeletionDelay sets how long a scheduled deletion waits before it is applied
+func (s *RoomService) SetDeletionDelay(delay time.Duration) {
+	if delay > 0 {
+		s.deletionDelay = delay
 	}
 }
 
@@ -38,6 +117,13 @@ type CreateRoomInput struct {
 	Type        model.RoomType
 	OwnerID     string
 	MaxMembers  int
+	ReadOnly    bool
+	MessageTTL  int // seconds; 0 keeps messages
+	FeedEnabled bool
+	Language    string // system message locale; default i18n.DefaultLocale
+	RateLimit   int    // messages per member per minute; 0 uses the global default
+
+	ModerationLevel model.ModerationLevel // default model.ModerationStandard
 }
 
 // Create creates a new room
@@ -46,34 +132,66 @@ func (s *RoomService) Create(ctx context.Context, input *CreateRoomInput) (*mode
 	if input.MaxMembers <= 0 {
 		input.MaxMembers = 100
 	}
+	if err := validateMessageTTL(input.MessageTTL); err != nil {
+		return nil, err
+	}
+	if input.FeedEnabled && input.Type != model.RoomTypePublic {
+		return nil, ErrRoomFeedNotPublic
+	}
+	if input.Language == "" {
+		input.Language = i18n.DefaultLocale
+	}
+	if err := validateRoomLanguage(input.Language); err != nil {
+		return nil, err
+	}
+	if err := s.validateRateLimit(input.RateLimit); err != nil {
+		return nil, err
+	}
+	if input.ModerationLevel == "" {
+		input.ModerationLevel = model.ModerationStandard
+	}
+	if err := validateModerationLevel(input.ModerationLevel); err != nil {
+		return nil, err
+	}
 
 	room := &model.Room{
-		Name:       input.Name,
-		Type:       input.Type,
-		OwnerID:    input.OwnerID,
-		MaxMembers: input.MaxMembers,
+		Name:        input.Name,
+		Type:        input.Type,
+		OwnerID:     input.OwnerID,
+		MaxMembers:  input.MaxMembers,
+		ReadOnly:    input.ReadOnly,
+		MessageTTL:  input.MessageTTL,
+		FeedEnabled: input.FeedEnabled,
+		Language:    input.Language,
+		RateLimit:   input.RateLimit,
+
+		ModerationLevel: input.ModerationLevel,
 	}
 
 	if input.Description != "" {
 		room.Description = sql.NullString{String: input.Description, Valid: true}
 	}
 
-	if err := s.roomRepo.Create(ctx, room); err != nil {
-		s.logger.Error("Failed to create room", zap.Error(err))
-		return nil, apperrors.ErrInternal
-	}
-
-	// Add owner as member with owner role
-	member := &model.RoomMember{
-		RoomID: room.ID,
-		UserID: input.OwnerID,
-		Role:   model.MemberRoleOwner,
-	}
+	// The room and its owner membership are written together, so a failure
+	// never leaves a room without an owner
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		if err := s.roomRepo.Create(ctx, room); err != nil {
+			return fmt.Errorf("create room: %w", err)
+		}
 
-	if err := s.roomRepo.AddMember(ctx, member); err != nil {
-		s.logger.Error("Failed to add owner as member", zap.Error(err))
-		// Delete the room if we can't add the owner
-		_ = s.roomRepo.Delete(ctx, room.ID)
+		// Add owner as member with owner role
+		member := &model.RoomMember{
+			RoomID: room.ID,
+			UserID: input.OwnerID,
+			Role:   model.MemberRoleOwner,
+		}
+		if err := s.roomRepo.AddMember(ctx, member); err != nil {
+			return fmt.Errorf("add owner as member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to create room", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
 
@@ -116,8 +234,9 @@ func (s *RoomService) GetByIDWithDetails(ctx context.Context, id string) (*model
 	}
 
 	detail := &model.RoomDetail{
-		Room:        room.Room,
-		MemberCount: room.MemberCount,
+		Room:         room.Room,
+		MemberCount:  room.MemberCount,
+		MessageCount: room.MessageCount,
 	}
 
 	if owner != nil {
@@ -134,6 +253,13 @@ type UpdateRoomInput struct {
 	Name        *string
 	Description *string
 	MaxMembers  *int
+	ReadOnly    *bool
+	MessageTTL  *int // seconds; 0 turns disappearing messages off
+	FeedEnabled *bool
+	Language    *string
+	RateLimit   *int // 0 restores the global default
+
+	ModerationLevel *model.ModerationLevel
 }
 
 // Update updates a room
@@ -146,13 +272,8 @@ func (s *RoomService) Update(ctx context.Context, input *UpdateRoomInput) (*mode
 		return nil, apperrors.ErrInternal
 	}
 
-	// Check permission (only owner can update)
-	if room.OwnerID != input.UserID {
-		// Check if user is admin
-		member, err := s.roomRepo.GetMember(ctx, input.RoomID, input.UserID)
-		if err != nil || !member.CanModerate() {
-			return nil, apperrors.ErrPermissionDenied
-		}
+	if err := s.authorize(ctx, room, input.UserID, policy.CanManageRoom); err != nil {
+		return nil, err
 	}
 
 	// Update fields
@@ -165,6 +286,39 @@ func (s *RoomService) Update(ctx context.Context, input *UpdateRoomInput) (*mode
 	if input.MaxMembers != nil && *input.MaxMembers > 0 {
 		room.MaxMembers = *input.MaxMembers
 	}
+	if input.ReadOnly != nil {
+		room.ReadOnly = *input.ReadOnly
+	}
+	if input.MessageTTL != nil {
+		if err := validateMessageTTL(*input.MessageTTL); err != nil {
+			return nil, err
+		}
+		room.MessageTTL = *input.MessageTTL
+	}
+	if input.FeedEnabled != nil {
+		if *input.FeedEnabled && !room.IsPublic() {
+			return nil, ErrRoomFeedNotPublic
+		}
+		room.FeedEnabled = *input.FeedEnabled
+	}
+	if input.Language != nil {
+		if err := validateRoomLanguage(*input.Language); err != nil {
+			return nil, err
+		}
+		room.Language = *input.Language
+	}
+	if input.RateLimit != nil {
+		if err := s.validateRateLimit(*input.RateLimit); err != nil {
+			return nil, err
+		}
+		room.RateLimit = *input.RateLimit
+	}
+	if input.ModerationLevel != nil {
+		if err := validateModerationLevel(*input.ModerationLevel); err != nil {
+			return nil, err
+		}
+		room.ModerationLevel = *input.ModerationLevel
+	}
 
 	if err := s.roomRepo.Update(ctx, room); err != nil {
 		s.logger.Error("Failed to update room", zap.Error(err))
@@ -174,32 +328,180 @@ func (s *RoomService) Update(ctx context.Context, input *UpdateRoomInput) (*mode
 	return room, nil
 }
 
-// Delete deletes a room
-func (s *RoomService) Delete(ctx context.Context, roomID, userID string) error {
+// Delete schedules a room for deletion. Members are notified and the room is
+// soft deleted once the deletion delay elapses, leaving time to export history.
+func (s *RoomService) Delete(ctx context.Context, roomID, userID string) (*model.Room, error) {
 	room, err := s.roomRepo.GetByID(ctx, roomID)
 	if err != nil {
 		if err == repository.ErrRoomNotFound {
-			return apperrors.ErrRoomNotFound
+			return nil, apperrors.ErrRoomNotFound
 		}
-		return apperrors.ErrInternal
+		return nil, apperrors.ErrInternal
 	}
 
-	// Only owner can delete
-	if room.OwnerID != userID {
-		return apperrors.ErrPermissionDenied
+	if err := s.authorize(ctx, room, userID, policy.CanDeleteRoom); err != nil {
+		return nil, err
 	}
 
-	if err := s.roomRepo.Delete(ctx, roomID); err != nil {
-		s.logger.Error("Failed to delete room", zap.Error(err))
-		return apperrors.ErrInternal
+	scheduledAt := time.Now().Add(s.deletionDelay)
+	if err := s.roomRepo.ScheduleDeletion(ctx, roomID, scheduledAt); err != nil {
+		if err == repository.ErrRoomDeletionPending {
+			return nil, apperrors.ErrRoomDeletionPending
+		}
+		s.logger.Error("Failed to schedule room deletion", zap.Error(err))
+		return nil, apperrors.ErrInternal
 	}
+	room.DeletionScheduledAt = &scheduledAt
 
-	s.logger.Info("Room deleted",
+	s.logger.Info("Room deletion scheduled",
 		zap.String("room_id", roomID),
 		zap.String("deleted_by", userID),
+		zap.Time("scheduled_at", scheduledAt),
 	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    userID,
+		Action:     model.AuditActionRoomDeletionScheduled,
+		TargetType: model.AuditTargetRoom,
+		TargetID:   roomID,
+		Metadata: map[string]interface{}{
+			"room_name":    room.Name,
+			"scheduled_at": scheduledAt.Format(time.RFC3339),
+		},
+	})
+
+	s.notifyDeletion(ctx, room, RoomEventDeleting, &NotifyInput{
+		Type:          model.NotificationTypeRoomDeleting,
+		Title:         "聊天室「" + room.Name + "」即將被刪除",
+		Content:       "聊天室將於 " + scheduledAt.Format(time.RFC3339) + " 刪除，請在此之前匯出需要保留的訊息",
+		ReferenceID:   room.ID,
+		ReferenceType: "room",
+	})
 
-	return nil
+	return room, nil
+}
+
+// CancelDeletion cancels a pending room deletion (owner only)
+func (s *RoomService) CancelDeletion(ctx context.Context, roomID, userID string) (*model.Room, error) {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	if err := s.authorize(ctx, room, userID, policy.CanDeleteRoom); err != nil {
+		return nil, err
+	}
+
+	if err := s.roomRepo.CancelDeletion(ctx, roomID); err != nil {
+		if err == repository.ErrRoomDeletionNotScheduled {
+			return nil, apperrors.ErrRoomDeletionNotScheduled
+		}
+		s.logger.Error("Failed to cancel room deletion", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	room.DeletionScheduledAt = nil
+
+	s.logger.Info("Room deletion canceled",
+		zap.String("room_id", roomID),
+		zap.String("canceled_by", userID),
+	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    userID,
+		Action:     model.AuditActionRoomDeletionCanceled,
+		TargetType: model.AuditTargetRoom,
+		TargetID:   roomID,
+		Metadata:   map[string]interface{}{"room_name": room.Name},
+	})
+
+	s.notifyDeletion(ctx, room, RoomEventDeletionCanceled, &NotifyInput{
+		Type:          model.NotificationTypeRoomDeletionCanceled,
+		Title:         "聊天室「" + room.Name + "」已取消刪除",
+		ReferenceID:   room.ID,
+		ReferenceType: "room",
+	})
+
+	return room, nil
+}
+
+// PurgeScheduledDeletions soft deletes rooms whose deletion window has elapsed
+func (s *RoomService) PurgeScheduledDeletions(ctx context.Context, limit int) (int, error) {
+	rooms, err := s.roomRepo.SoftDeleteDue(ctx, time.Now(), limit)
+	if err != nil {
+		s.logger.Error("Failed to purge scheduled room deletions", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+
+	for _, room := range rooms {
+		if err := checkContext(ctx); err != nil {
+			return len(rooms), err
+		}
+
+		s.logger.Info("Room deleted", zap.String("room_id", room.ID))
+		s.auditor.Record(ctx, &AuditEntry{
+			Action:     model.AuditActionRoomDeleted,
+			TargetType: model.AuditTargetRoom,
+			TargetID:   room.ID,
+			Metadata:   map[string]interface{}{"room_name": room.Name},
+		})
+		if s.notifier != nil {
+			s.notifier.PublishToRoom(room.ID, RoomEventDeleted, &RoomDeletionEvent{
+				RoomID:   room.ID,
+				RoomName: room.Name,
+			})
+		}
+	}
+
+	return len(rooms), nil
+}
+
+// ReconcileCounters checks the maintained member and message counts of up
+// to limit rooms against a recount, correcting and logging any drift
+func (s *RoomService) ReconcileCounters(ctx context.Context, limit int) (int, error) {
+	checked, drifts, err := s.roomRepo.ReconcileCounters(ctx, limit)
+	if err != nil {
+		s.logger.Error("Failed to reconcile room counters", zap.Error(err))
+		return 0, apperrors.ErrInternal
+	}
+
+	for _, d := range drifts {
+		s.logger.Warn("Room counters drifted",
+			zap.String("room_id", d.RoomID),
+			zap.Int("member_count", d.MemberCount),
+			zap.Int("actual_member_count", d.ActualMemberCount),
+			zap.Int64("message_count", d.MessageCount),
+			zap.Int64("actual_message_count", d.ActualMessageCount),
+		)
+	}
+
+	return checked, nil
+}
+
+func (s *RoomService) notifyDeletion(ctx context.Context, room *model.Room, eventType string, input *NotifyInput) {
+	if s.notifier == nil {
+		return
+	}
+
+	// The state change is already committed; members must hear about it even
+	// if the requesting client disconnects mid-way
+	ctx = context.WithoutCancel(ctx)
+
+	event := &RoomDeletionEvent{
+		RoomID:   room.ID,
+		RoomName: room.Name,
+	}
+	if room.DeletionScheduledAt != nil {
+		event.ScheduledAt = room.DeletionScheduledAt.Format(time.RFC3339)
+	}
+	s.notifier.PublishToRoom(room.ID, eventType, event)
+
+	memberIDs, err := s.roomRepo.ListMemberIDs(ctx, room.ID)
+	if err != nil {
+		s.logger.Warn("Failed to list room members for notification", zap.Error(err))
+		return
+	}
+	s.notifier.Notify(ctx, memberIDs, input)
 }
 
 // ListPublic lists public rooms
@@ -212,6 +514,22 @@ func (s *RoomService) ListPublic(ctx context.Context, limit, offset int) ([]*mod
 	return rooms, nil
 }
 
+// ListAll lists every room for administrators, optionally of one type
+func (s *RoomService) ListAll(ctx context.Context, roomType model.RoomType, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	switch roomType {
+	case "", model.RoomTypePublic, model.RoomTypePrivate, model.RoomTypeDirect:
+	default:
+		return nil, apperrors.New(400, "無效的聊天室類型")
+	}
+
+	rooms, err := s.roomRepo.ListAll(ctx, roomType, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list rooms", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return rooms, nil
+}
+
 // ListByUserID lists rooms that user is a member of
 func (s *RoomService) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error) {
 	rooms, err := s.roomRepo.ListByUserID(ctx, userID, limit, offset)
@@ -242,8 +560,8 @@ func (s *RoomService) Join(ctx context.Context, roomID, userID string) error {
 		return apperrors.ErrInternal
 	}
 
-	// Check if room is private (need invite)
-	if room.IsPrivate() {
+	// Private rooms need an invite
+	if !s.policy.Can(&policy.Subject{UserID: userID, Room: room}, policy.CanJoin) {
 		return apperrors.ErrPermissionDenied
 	}
 
@@ -268,6 +586,8 @@ func (s *RoomService) Join(ctx context.Context, roomID, userID string) error {
 		zap.String("room_id", roomID),
 		zap.String("user_id", userID),
 	)
+	s.emitMemberEvent(ctx, roomID, model.WebhookEventMemberJoined, userID, WebhookMemberReasonJoin, "")
+	s.postSystemMessage(ctx, room, i18n.SystemMemberJoined, userID, "")
 
 	return nil
 }
@@ -299,23 +619,24 @@ func (s *RoomService) Leave(ctx context.Context, roomID, userID string) error {
 		zap.String("room_id", roomID),
 		zap.String("user_id", userID),
 	)
+	s.emitMemberEvent(ctx, roomID, model.WebhookEventMemberLeft, userID, WebhookMemberReasonLeave, "")
+	s.postSystemMessage(ctx, room, i18n.SystemMemberLeft, userID, "")
 
 	return nil
 }
 
 // InviteMember invites a user to a private room
 func (s *RoomService) InviteMember(ctx context.Context, roomID, inviterID, inviteeID string) error {
-	// Check if inviter can moderate
-	member, err := s.roomRepo.GetMember(ctx, roomID, inviterID)
+	room, err := s.roomRepo.GetByID(ctx, roomID)
 	if err != nil {
-		if err == repository.ErrNotRoomMember {
-			return apperrors.ErrPermissionDenied
+		if err == repository.ErrRoomNotFound {
+			return apperrors.ErrRoomNotFound
 		}
 		return apperrors.ErrInternal
 	}
 
-	if !member.CanModerate() {
-		return apperrors.ErrPermissionDenied
+	if err := s.authorize(ctx, room, inviterID, policy.CanInvite); err != nil {
+		return err
 	}
 
 	// Check if invitee exists
@@ -341,22 +662,27 @@ func (s *RoomService) InviteMember(ctx context.Context, roomID, inviterID, invit
 		}
 		return apperrors.ErrInternal
 	}
+	s.emitMemberEvent(ctx, roomID, model.WebhookEventMemberJoined, inviteeID, WebhookMemberReasonInvite, inviterID)
+	s.postSystemMessage(ctx, room, i18n.SystemMemberInvited, inviteeID, inviterID)
 
 	return nil
 }
 
 // KickMember removes a member from a room
 func (s *RoomService) KickMember(ctx context.Context, roomID, kickerID, targetID string) error {
-	// Check if kicker can moderate
-	kicker, err := s.roomRepo.GetMember(ctx, roomID, kickerID)
+	room, err := s.roomRepo.GetByID(ctx, roomID)
 	if err != nil {
-		if err == repository.ErrNotRoomMember {
-			return apperrors.ErrPermissionDenied
+		if err == repository.ErrRoomNotFound {
+			return apperrors.ErrRoomNotFound
 		}
 		return apperrors.ErrInternal
 	}
 
-	if !kicker.CanModerate() {
+	kicker, err := loadSubject(ctx, s.roomRepo, s.permRepo, room, kickerID)
+	if err != nil {
+		return apperrors.ErrInternal
+	}
+	if !s.policy.Can(kicker, policy.CanKick) {
 		return apperrors.ErrPermissionDenied
 	}
 
@@ -370,10 +696,7 @@ func (s *RoomService) KickMember(ctx context.Context, roomID, kickerID, targetID
 	}
 
 	// Cannot kick owner or same/higher role
-	if target.IsOwner() {
-		return apperrors.ErrPermissionDenied
-	}
-	if kicker.Role == model.MemberRoleAdmin && target.Role == model.MemberRoleAdmin {
+	if !s.policy.CanActOn(kicker.Member, target) {
 		return apperrors.ErrPermissionDenied
 	}
 
@@ -386,6 +709,15 @@ func (s *RoomService) KickMember(ctx context.Context, roomID, kickerID, targetID
 		zap.String("kicked_by", kickerID),
 		zap.String("target", targetID),
 	)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    kickerID,
+		Action:     model.AuditActionMemberKicked,
+		TargetType: model.AuditTargetUser,
+		TargetID:   targetID,
+		Metadata:   map[string]interface{}{"room_id": roomID},
+	})
+	s.emitMemberEvent(ctx, roomID, model.WebhookEventMemberLeft, targetID, WebhookMemberReasonKick, kickerID)
+	s.postSystemMessage(ctx, room, i18n.SystemMemberKicked, targetID, kickerID)
 
 	return nil
 }
@@ -397,9 +729,8 @@ func (s *RoomService) PromoteMember(ctx context.Context, roomID, promoterID, tar
 		return apperrors.ErrRoomNotFound
 	}
 
-	// Only owner can promote
-	if room.OwnerID != promoterID {
-		return apperrors.ErrPermissionDenied
+	if err := s.authorize(ctx, room, promoterID, policy.CanManageRoles); err != nil {
+		return err
 	}
 
 	if err := s.roomRepo.UpdateMemberRole(ctx, roomID, targetID, model.MemberRoleAdmin); err != nil {
@@ -409,6 +740,17 @@ func (s *RoomService) PromoteMember(ctx context.Context, roomID, promoterID, tar
 		return apperrors.ErrInternal
 	}
 
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    promoterID,
+		Action:     model.AuditActionMemberPromoted,
+		TargetType: model.AuditTargetUser,
+		TargetID:   targetID,
+		Metadata: map[string]interface{}{
+			"room_id": roomID,
+			"role":    string(model.MemberRoleAdmin),
+		},
+	})
+
 	return nil
 }
 
@@ -419,9 +761,8 @@ func (s *RoomService) DemoteMember(ctx context.Context, roomID, demoterID, targe
 		return apperrors.ErrRoomNotFound
 	}
 
-	// Only owner can demote
-	if room.OwnerID != demoterID {
-		return apperrors.ErrPermissionDenied
+	if err := s.authorize(ctx, room, demoterID, policy.CanManageRoles); err != nil {
+		return err
 	}
 
 	if err := s.roomRepo.UpdateMemberRole(ctx, roomID, targetID, model.MemberRoleMember); err != nil {
@@ -431,12 +772,22 @@ func (s *RoomService) DemoteMember(ctx context.Context, roomID, demoterID, targe
 		return apperrors.ErrInternal
 	}
 
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    demoterID,
+		Action:     model.AuditActionMemberDemoted,
+		TargetType: model.AuditTargetUser,
+		TargetID:   targetID,
+		Metadata: map[string]interface{}{
+			"room_id": roomID,
+			"role":    string(model.MemberRoleMember),
+		},
+	})
+
 	return nil
 }
 
 // ListMembers lists all members of a room
-func (s *RoomService) ListMembers(ctx context.Context, roomID, userID string) ([]*model.RoomMemberWithUser, error) {
-	// Check if user is a member (for private rooms)
+func (s *RoomService) ListMembers(ctx context.Context, roomID, userID string, limit, offset int) ([]*model.RoomMemberWithUser, error) {
 	room, err := s.roomRepo.GetByID(ctx, roomID)
 	if err != nil {
 		if err == repository.ErrRoomNotFound {
@@ -445,17 +796,12 @@ func (s *RoomService) ListMembers(ctx context.Context, roomID, userID string) ([
 		return nil, apperrors.ErrInternal
 	}
 
-	if room.IsPrivate() {
-		isMember, err := s.roomRepo.IsMember(ctx, roomID, userID)
-		if err != nil {
-			return nil, apperrors.ErrInternal
-		}
-		if !isMember {
-			return nil, apperrors.ErrPermissionDenied
-		}
+	// Private rooms only expose members to members
+	if err := s.authorize(ctx, room, userID, policy.CanViewMembers); err != nil {
+		return nil, err
 	}
 
-	members, err := s.roomRepo.ListMembers(ctx, roomID)
+	members, err := s.roomRepo.ListMembers(ctx, roomID, limit, offset)
 	if err != nil {
 		s.logger.Error("Failed to list members", zap.Error(err))
 		return nil, apperrors.ErrInternal
@@ -464,6 +810,75 @@ func (s *RoomService) ListMembers(ctx context.Context, roomID, userID string) ([
 	return members, nil
 }
 
+// Can reports whether a user may perform an action in a room
+func (s *RoomService) Can(ctx context.Context, roomID, userID string, action policy.Action) (bool, error) {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return false, apperrors.ErrRoomNotFound
+		}
+		return false, apperrors.ErrInternal
+	}
+
+	subject, err := loadSubject(ctx, s.roomRepo, s.permRepo, room, userID)
+	if err != nil {
+		s.logger.Error("Failed to load policy subject", zap.Error(err))
+		return false, apperrors.ErrInternal
+	}
+
+	return s.policy.Can(subject, action), nil
+}
+
+// Permissions evaluates every policy action for a user in a room
+func (s *RoomService) Permissions(ctx context.Context, roomID, userID string) (map[policy.Action]bool, error) {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	subject, err := loadSubject(ctx, s.roomRepo, s.permRepo, room, userID)
+	if err != nil {
+		s.logger.Error("Failed to load policy subject", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	return s.policy.Permissions(subject), nil
+}
+
+// authorize returns ErrPermissionDenied unless the user may perform the action
+func (s *RoomService) authorize(ctx context.Context, room *model.Room, userID string, action policy.Action) error {
+	subject, err := loadSubject(ctx, s.roomRepo, s.permRepo, room, userID)
+	if err != nil {
+		s.logger.Error("Failed to load policy subject", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	if !s.policy.Can(subject, action) {
+		return apperrors.ErrPermissionDenied
+	}
+	return nil
+}
+
+// AccessibleRooms returns the given rooms whose realtime events the user may
+// subscribe to, keyed by ID. Access is membership (see policy.CanAccess), so
+// all rooms are checked with one query instead of one per room.
+func (s *RoomService) AccessibleRooms(ctx context.Context, roomIDs []string, userID string) (map[string]*model.RoomWithMemberCount, error) {
+	rooms, err := s.roomRepo.ListMemberRoomsByIDs(ctx, userID, roomIDs)
+	if err != nil {
+		s.logger.Error("Failed to list member rooms", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	accessible := make(map[string]*model.RoomWithMemberCount, len(rooms))
+	for _, room := range rooms {
+		accessible[room.ID] = room
+	}
+	return accessible, nil
+}
+
 // IsMember checks if user is a member of a room
 func (s *RoomService) IsMember(ctx context.Context, roomID, userID string) (bool, error) {
 	return s.roomRepo.IsMember(ctx, roomID, userID)
@@ -483,5 +898,15 @@ func (s *RoomService) GetMember(ctx context.Context, roomID, userID string) (*mo
 
 // UpdateLastRead updates the last read timestamp for a member
 func (s *RoomService) UpdateLastRead(ctx context.Context, roomID, userID string) error {
-	return s.roomRepo.UpdateLastReadAt(ctx, roomID, userID)
+	readAt, err := s.roomRepo.UpdateLastReadAt(ctx, roomID, userID)
+	if err != nil {
+		if err == repository.ErrNotRoomMember {
+			return apperrors.ErrNotFound
+		}
+		s.logger.Error("Failed to update last read", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	publishReadState(s.notifier, userID, model.ReadStateRoom, roomID, readAt)
+	return nil
 }
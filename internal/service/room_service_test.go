@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 	"github.com/go-demo/chat/internal/repository"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -26,6 +28,7 @@ func setupTestRoomServiceIsolated(t *testing.T) (*RoomService, *sqlx.DB, string)
 	logger := zap.NewNop()
 
 	service := NewRoomService(roomRepo, userRepo, messageRepo, logger)
+	service.SetTransactor(repository.NewTxManager(db))
 	prefix := repository.GenerateUniquePrefix()
 	return service, db, prefix
 }
@@ -215,14 +218,26 @@ func TestRoomService_Delete(t *testing.T) {
 
 	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
 
-	err := service.Delete(ctx, room.ID, owner.ID)
+	scheduled, err := service.Delete(ctx, room.ID, owner.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete room: %v", err)
 	}
+	if scheduled.DeletionScheduledAt == nil {
+		t.Fatal("Expected deletion to be scheduled")
+	}
 
-	_, err = service.GetByID(ctx, room.ID)
-	if err == nil {
-		t.Error("Expected room to be deleted")
+	// Room stays available during the deletion window
+	found, err := service.GetByID(ctx, room.ID)
+	if err != nil {
+		t.Fatalf("Expected room to exist until deletion window elapses: %v", err)
+	}
+	if !found.IsPendingDeletion() {
+		t.Error("Expected room to be pending deletion")
+	}
+
+	// Scheduling again is a conflict
+	if _, err := service.Delete(ctx, room.ID, owner.ID); err == nil {
+		t.Error("Expected error when deletion is already scheduled")
 	}
 }
 
@@ -237,12 +252,128 @@ func TestRoomService_Delete_NoPermission(t *testing.T) {
 
 	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
 
-	err := service.Delete(ctx, room.ID, otherUser.ID)
+	_, err := service.Delete(ctx, room.ID, otherUser.ID)
 	if err == nil {
 		t.Error("Expected permission denied error")
 	}
 }
 
+func TestRoomService_CancelDeletion(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	ctx := context.Background()
+
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+
+	if _, err := service.CancelDeletion(ctx, room.ID, owner.ID); err == nil {
+		t.Error("Expected error when deletion is not scheduled")
+	}
+
+	if _, err := service.Delete(ctx, room.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to delete room: %v", err)
+	}
+
+	if _, err := service.CancelDeletion(ctx, room.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to cancel deletion: %v", err)
+	}
+
+	found, err := service.GetByID(ctx, room.ID)
+	if err != nil {
+		t.Fatalf("Failed to get room: %v", err)
+	}
+	if found.IsPendingDeletion() {
+		t.Error("Expected deletion to be canceled")
+	}
+}
+
+func TestRoomService_Archive(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	member := createUserForRoomServiceTestIsolated(t, db, prefix, "member")
+	ctx := context.Background()
+
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+	if err := service.Join(ctx, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to join room: %v", err)
+	}
+
+	if _, err := service.Archive(ctx, room.ID, member.ID); err == nil {
+		t.Error("Expected only the owner to archive")
+	}
+
+	archived, err := service.Archive(ctx, room.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to archive room: %v", err)
+	}
+	if !archived.IsArchived() {
+		t.Fatal("Expected room to be archived")
+	}
+	if _, err := service.Archive(ctx, room.ID, owner.ID); err == nil {
+		t.Error("Expected error when the room is already archived")
+	}
+
+	// Hidden from listings, still listed among the member's archived rooms
+	rooms, err := service.ListByUserID(ctx, member.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list rooms: %v", err)
+	}
+	if len(rooms) != 0 {
+		t.Errorf("Expected archived room hidden, got %d rooms", len(rooms))
+	}
+	rooms, err = service.ListArchivedByUserID(ctx, member.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list archived rooms: %v", err)
+	}
+	if len(rooms) != 1 || rooms[0].ID != room.ID {
+		t.Errorf("Expected the archived room listed, got %d rooms", len(rooms))
+	}
+
+	if _, err := service.Unarchive(ctx, room.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to unarchive room: %v", err)
+	}
+	found, err := service.GetByID(ctx, room.ID)
+	if err != nil {
+		t.Fatalf("Failed to get room: %v", err)
+	}
+	if found.IsArchived() {
+		t.Error("Expected room to be unarchived")
+	}
+	if _, err := service.Unarchive(ctx, room.ID, owner.ID); err == nil {
+		t.Error("Expected error when the room is not archived")
+	}
+}
+
+func TestRoomService_PurgeScheduledDeletions(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	ctx := context.Background()
+
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+
+	service.SetDeletionDelay(time.Millisecond)
+	if _, err := service.Delete(ctx, room.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to delete room: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := service.PurgeScheduledDeletions(ctx, 100); err != nil {
+		t.Fatalf("Failed to purge deletions: %v", err)
+	}
+
+	if _, err := service.GetByID(ctx, room.ID); err == nil {
+		t.Error("Expected room to be deleted")
+	}
+}
+
 func TestRoomService_ListPublic(t *testing.T) {
 	service, db, prefix := setupTestRoomServiceIsolated(t)
 	defer db.Close()
@@ -523,7 +654,7 @@ func TestRoomService_ListMembers(t *testing.T) {
 
 	_ = service.Join(ctx, room.ID, member.ID)
 
-	members, err := service.ListMembers(ctx, room.ID, owner.ID)
+	members, err := service.ListMembers(ctx, room.ID, owner.ID, 20, 0)
 	if err != nil {
 		t.Fatalf("Failed to list members: %v", err)
 	}
@@ -557,3 +688,99 @@ func TestRoomService_IsMember(t *testing.T) {
 		t.Error("Expected to be a member")
 	}
 }
+
+// newMockRoomService returns a RoomService on mock stores holding the room
+// and its members
+func newMockRoomService(room *model.Room, members ...*model.RoomMember) (*RoomService, *mockRoomStore, *mockRoomMessageStore) {
+	rooms := &mockRoomStore{}
+	rooms.GetByIDFunc = func(ctx context.Context, id string) (*model.Room, error) {
+		if id != room.ID {
+			return nil, repository.ErrRoomNotFound
+		}
+		return room, nil
+	}
+	rooms.GetMemberFunc = func(ctx context.Context, roomID, userID string) (*model.RoomMember, error) {
+		for _, m := range members {
+			if m.RoomID == roomID && m.UserID == userID {
+				return m, nil
+			}
+		}
+		return nil, repository.ErrNotRoomMember
+	}
+
+	messages := &mockRoomMessageStore{}
+	messages.GetByIDWithUserFunc = func(ctx context.Context, id string) (*model.MessageWithUser, error) {
+		return &model.MessageWithUser{Message: model.Message{ID: id, RoomID: room.ID}}, nil
+	}
+
+	return NewRoomService(rooms, &mockUserLookup{}, messages, zap.NewNop()), rooms, messages
+}
+
+func TestRoomService_Join_Mocked(t *testing.T) {
+	ctx := context.Background()
+
+	public := &model.Room{ID: "room-1", Type: model.RoomTypePublic, OwnerID: "owner"}
+	service, rooms, messages := newMockRoomService(public)
+	publisher := &recordingPublisher{}
+	service.SetMessagePublisher(publisher)
+	var joined *model.RoomMember
+	rooms.AddMemberFunc = func(ctx context.Context, member *model.RoomMember) error {
+		joined = member
+		return nil
+	}
+	if err := service.Join(ctx, "room-1", "user-1"); err != nil {
+		t.Fatalf("Failed to join room: %v", err)
+	}
+	if joined == nil || joined.UserID != "user-1" || joined.Role != model.MemberRoleMember {
+		t.Errorf("Expected user-1 to join as a member, got %+v", joined)
+	}
+	if messages.Calls("Create") != 1 || len(publisher.msgs) != 1 {
+		t.Error("Expected a system message announcing the join")
+	}
+
+	rooms.AddMemberFunc = func(ctx context.Context, member *model.RoomMember) error {
+		return repository.ErrRoomFull
+	}
+	if err := service.Join(ctx, "room-1", "user-2"); err != apperrors.ErrRoomFull {
+		t.Errorf("Expected ErrRoomFull, got %v", err)
+	}
+
+	private := &model.Room{ID: "room-2", Type: model.RoomTypePrivate, OwnerID: "owner"}
+	service, rooms, _ = newMockRoomService(private)
+	if err := service.Join(ctx, "room-2", "user-1"); err != apperrors.ErrPermissionDenied {
+		t.Errorf("Expected ErrPermissionDenied joining a private room, got %v", err)
+	}
+	if rooms.Calls("AddMember") != 0 {
+		t.Error("Expected no member to be added to the private room")
+	}
+}
+
+func TestRoomService_KickMember_Mocked(t *testing.T) {
+	ctx := context.Background()
+	room := &model.Room{ID: "room-1", Type: model.RoomTypePublic, OwnerID: "owner"}
+	service, rooms, _ := newMockRoomService(room,
+		&model.RoomMember{RoomID: "room-1", UserID: "owner", Role: model.MemberRoleOwner},
+		&model.RoomMember{RoomID: "room-1", UserID: "admin", Role: model.MemberRoleAdmin},
+		&model.RoomMember{RoomID: "room-1", UserID: "member", Role: model.MemberRoleMember},
+	)
+
+	if err := service.KickMember(ctx, "room-1", "member", "admin"); err != apperrors.ErrPermissionDenied {
+		t.Errorf("Expected a member not to kick an admin, got %v", err)
+	}
+	if err := service.KickMember(ctx, "room-1", "admin", "owner"); err != apperrors.ErrPermissionDenied {
+		t.Errorf("Expected an admin not to kick the owner, got %v", err)
+	}
+	if rooms.Calls("RemoveMember") != 0 {
+		t.Fatal("Expected no member to be removed")
+	}
+
+	if err := service.KickMember(ctx, "room-1", "admin", "member"); err != nil {
+		t.Fatalf("Failed to kick member: %v", err)
+	}
+	if rooms.Calls("RemoveMember") != 1 {
+		t.Error("Expected the member to be removed")
+	}
+	if err := service.KickMember(ctx, "room-1", "admin", "stranger"); err != apperrors.ErrNotFound {
+		t.Errorf("Expected ErrNotFound kicking a non-member, got %v", err)
+	}
+}
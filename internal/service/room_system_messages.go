@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-demo/chat/internal/i18n"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// SetMessagePublisher enables the system messages posted when members
+// join, leave or are moderated; publisher broadcasts them to the room
+func (s *RoomService) SetMessagePublisher(publisher MessagePublisher) {
+	s.publisher = publisher
+}
+
+// validateRoomLanguage checks that system messages can be rendered in the
+// language
+func validateRoomLanguage(language string) error {
+	if !i18n.Supported(language) {
+		return apperrors.ErrValidation.WithDetails(map[string]string{
+			"language": "不支援的語言，可用：" + strings.Join(i18n.Locales(), "、"),
+		})
+	}
+	return nil
+}
+
+// postSystemMessage records a notice in the room's language, authored by
+// the member it is about, and broadcasts it. The action it reports has
+// already happened, so failures are only logged.
+func (s *RoomService) postSystemMessage(ctx context.Context, room *model.Room, key, userID, actorID string) {
+	if s.publisher == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	params := map[string]string{"user": s.systemMessageName(ctx, userID)}
+	if actorID != "" {
+		params["actor"] = s.systemMessageName(ctx, actorID)
+	}
+	msg := &model.Message{
+		RoomID:  room.ID,
+		UserID:  userID,
+		Content: i18n.T(room.Language, key, params),
+		Type:    model.MessageTypeSystem,
+	}
+	if err := s.messageRepo.Create(ctx, msg); err != nil {
+		s.logger.Warn("Failed to post system message", zap.String("room_id", room.ID), zap.String("key", key), zap.Error(err))
+		return
+	}
+
+	withUser, err := s.messageRepo.GetByIDWithUser(ctx, msg.ID)
+	if err != nil {
+		s.logger.Warn("Failed to load system message", zap.String("message_id", msg.ID), zap.Error(err))
+		return
+	}
+	s.publisher.PublishMessage(withUser)
+}
+
+func (s *RoomService) systemMessageName(ctx context.Context, userID string) string {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return userID
+	}
+	return user.GetDisplayName()
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+)
+
+func TestRoomService_Language(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	room := createRoomForRoomServiceTestIsolated(t, service, prefix, owner, model.RoomTypePublic)
+	ctx := context.Background()
+
+	if room.Language != "zh-TW" {
+		t.Errorf("Expected default language zh-TW, got %q", room.Language)
+	}
+
+	unsupported := "xx"
+	if _, err := service.Update(ctx, &UpdateRoomInput{RoomID: room.ID, UserID: owner.ID, Language: &unsupported}); !apperrors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+
+	english := "en-US"
+	updated, err := service.Update(ctx, &UpdateRoomInput{RoomID: room.ID, UserID: owner.ID, Language: &english})
+	if err != nil {
+		t.Fatalf("Failed to update language: %v", err)
+	}
+	if updated.Language != "en-US" {
+		t.Errorf("Expected language en-US, got %q", updated.Language)
+	}
+}
+
+func TestRoomService_SystemMessages(t *testing.T) {
+	service, db, prefix := setupTestRoomServiceIsolated(t)
+	defer db.Close()
+	defer cleanupRoomServiceTestByPrefix(t, db, prefix)
+
+	publisher := &recordingPublisher{}
+	service.SetMessagePublisher(publisher)
+
+	owner := createUserForRoomServiceTestIsolated(t, db, prefix, "owner")
+	member := createUserForRoomServiceTestIsolated(t, db, prefix, "member")
+	ctx := context.Background()
+
+	room, err := service.Create(ctx, &CreateRoomInput{
+		Name:     prefix + "_english_room",
+		Type:     model.RoomTypePublic,
+		OwnerID:  owner.ID,
+		Language: "en",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+
+	if err := service.Join(ctx, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to join room: %v", err)
+	}
+	if err := service.Leave(ctx, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to leave room: %v", err)
+	}
+
+	if len(publisher.msgs) != 2 {
+		t.Fatalf("Expected 2 system messages, got %d", len(publisher.msgs))
+	}
+	want := []string{
+		member.GetDisplayName() + " joined the room",
+		member.GetDisplayName() + " left the room",
+	}
+	for i, msg := range publisher.msgs {
+		if msg.Type != model.MessageTypeSystem {
+			t.Errorf("Expected system message, got %s", msg.Type)
+		}
+		if msg.Content != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], msg.Content)
+		}
+	}
+}
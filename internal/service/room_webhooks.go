@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-demo/chat/internal/events"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/policy"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// MaxRoomWebhooks is how many webhooks a room can register
+const MaxRoomWebhooks = 10
+
+var (
+	ErrWebhookNotFound     = apperrors.New(http.StatusNotFound, "Webhook 不存在")
+	ErrTooManyRoomWebhooks = apperrors.New(http.StatusConflict, "此聊天室的 Webhook 數量已達上限")
+)
+
+// SetWebhooks enables room webhooks; without a dispatcher the webhook API
+// returns not found and no events are queued
+func (s *RoomService) SetWebhooks(dispatcher *WebhookDispatcher) {
+	s.webhooks = dispatcher
+}
+
+// SetEvents sets the publisher room.member.joined and room.member.left are
+// sent to
+func (s *RoomService) SetEvents(publisher *events.Publisher) {
+	s.events = publisher
+}
+
+// CreateWebhookInput registers a webhook
+type CreateWebhookInput struct {
+	RoomID string
+	UserID string
+	URL    string
+	Events []string
+}
+
+// CreateWebhook registers a webhook on behalf of a member allowed to manage
+// the room. The returned webhook carries its signing secret, which is not
+// shown again.
+func (s *RoomService) CreateWebhook(ctx context.Context, input *CreateWebhookInput) (*model.RoomWebhook, error) {
+	room, err := s.loadWebhookRoom(ctx, input.RoomID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{}
+	if !isWebhookURL(input.URL) {
+		details["url"] = "必須為 http 或 https 網址"
+	}
+	events := make([]string, 0, len(input.Events))
+	for _, event := range input.Events {
+		if !model.IsWebhookEvent(event) {
+			details["events"] = "必須為 " + strings.Join(model.WebhookEvents, "、") + " 之一"
+			break
+		}
+		if !containsString(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 && details["events"] == "" {
+		details["events"] = "至少需訂閱一個事件"
+	}
+	if len(details) > 0 {
+		return nil, apperrors.ErrValidation.WithDetails(details)
+	}
+
+	count, err := s.webhooks.repo.CountByRoomID(ctx, room.ID)
+	if err != nil {
+		s.logger.Error("Failed to count webhooks", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if count >= MaxRoomWebhooks {
+		return nil, ErrTooManyRoomWebhooks
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		s.logger.Error("Failed to generate webhook secret", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	webhook := &model.RoomWebhook{
+		RoomID:    room.ID,
+		URL:       input.URL,
+		Secret:    secret,
+		Events:    events,
+		CreatedBy: nullString(input.UserID),
+	}
+	if err := s.webhooks.repo.Create(ctx, webhook); err != nil {
+		s.logger.Error("Failed to create webhook", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("Room webhook registered",
+		zap.String("room_id", room.ID),
+		zap.String("webhook_id", webhook.ID),
+		zap.String("created_by", input.UserID),
+	)
+	return webhook, nil
+}
+
+// ListWebhooks lists a room's webhooks
+func (s *RoomService) ListWebhooks(ctx context.Context, roomID, userID string) ([]*model.RoomWebhook, error) {
+	if _, err := s.loadWebhookRoom(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhooks.repo.ListByRoomID(ctx, roomID)
+	if err != nil {
+		s.logger.Error("Failed to list webhooks", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (s *RoomService) DeleteWebhook(ctx context.Context, roomID, webhookID, userID string) error {
+	if _, err := s.loadWebhookRoom(ctx, roomID, userID); err != nil {
+		return err
+	}
+
+	if err := s.webhooks.repo.Delete(ctx, roomID, webhookID); err != nil {
+		if err == repository.ErrWebhookNotFound {
+			return ErrWebhookNotFound
+		}
+		s.logger.Error("Failed to delete webhook", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.logger.Info("Room webhook deleted",
+		zap.String("room_id", roomID),
+		zap.String("webhook_id", webhookID),
+		zap.String("deleted_by", userID),
+	)
+	return nil
+}
+
+// ListWebhookDeliveries lists a webhook's deliveries, newest first
+func (s *RoomService) ListWebhookDeliveries(ctx context.Context, roomID, webhookID, userID string, limit, offset int) ([]*model.RoomWebhookDelivery, error) {
+	if _, err := s.loadWebhookRoom(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.webhooks.repo.GetByID(ctx, roomID, webhookID); err != nil {
+		if err == repository.ErrWebhookNotFound {
+			return nil, ErrWebhookNotFound
+		}
+		s.logger.Error("Failed to get webhook", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	deliveries, err := s.webhooks.repo.ListDeliveries(ctx, webhookID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list webhook deliveries", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return deliveries, nil
+}
+
+// loadWebhookRoom gets the room and checks the user may manage it
+func (s *RoomService) loadWebhookRoom(ctx context.Context, roomID, userID string) (*model.Room, error) {
+	if s.webhooks == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	return s.loadManagedRoom(ctx, roomID, userID)
+}
+
+// loadManagedRoom gets the room and checks the user may manage it
+func (s *RoomService) loadManagedRoom(ctx context.Context, roomID, userID string) (*model.Room, error) {
+	room, err := s.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if err == repository.ErrRoomNotFound {
+			return nil, apperrors.ErrRoomNotFound
+		}
+		return nil, apperrors.ErrInternal
+	}
+
+	if err := s.authorize(ctx, room, userID, policy.CanManageRoom); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// emitMemberEvent queues a membership event for the event bus and the
+// room's webhooks
+func (s *RoomService) emitMemberEvent(ctx context.Context, roomID, event, userID, reason, actorID string) {
+	busEvent := events.RoomMemberJoined
+	if event == model.WebhookEventMemberLeft {
+		busEvent = events.RoomMemberLeft
+	}
+	s.events.Publish(busEvent, &events.RoomMemberData{
+		RoomID:  roomID,
+		UserID:  userID,
+		Reason:  reason,
+		ActorID: actorID,
+	})
+
+	if s.webhooks == nil {
+		return
+	}
+	s.webhooks.Emit(ctx, roomID, event, &WebhookMemberData{
+		UserID:  userID,
+		Reason:  reason,
+		ActorID: actorID,
+	})
+}
+
+func isWebhookURL(raw string) bool {
+	if len(raw) > 2000 {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/netguard"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestIsWebhookURL(t *testing.T) {
+	for raw, want := range map[string]bool{
+		"https://example.com/hooks/chat": true,
+		"http://example.com:8080":        true,
+		"ftp://example.com":              false,
+		"https://user:pw@example.com":    false,
+		"/relative":                      false,
+	} {
+		if got := isWebhookURL(raw); got != want {
+			t.Errorf("isWebhookURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func testDeliveryTarget(url string) *model.RoomWebhookDeliveryTarget {
+	return &model.RoomWebhookDeliveryTarget{
+		RoomWebhookDelivery: model.RoomWebhookDelivery{
+			ID:      "delivery-1",
+			Event:   model.WebhookEventMessageCreated,
+			Payload: []byte(`{"event":"message.created"}`),
+		},
+		URL:    url,
+		Secret: "secret",
+	}
+}
+
+func TestWebhookDispatcher_PostSignsRequests(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dispatcher := NewWebhookDispatcher(nil, time.Second, 0, true, zap.NewNop())
+	status, err := dispatcher.post(context.Background(), testDeliveryTarget(server.URL))
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Expected delivery to succeed, got %d, %v", status, err)
+	}
+
+	timestamp := got.Header.Get(WebhookTimestampHeader)
+	if want := "sha256=" + SignRoomWebhook([]byte("secret"), timestamp, body); got.Header.Get(WebhookSignatureHeader) != want {
+		t.Errorf("Unexpected signature %q", got.Header.Get(WebhookSignatureHeader))
+	}
+	if got.Header.Get(WebhookEventHeader) != model.WebhookEventMessageCreated || got.Header.Get(WebhookDeliveryHeader) != "delivery-1" {
+		t.Errorf("Unexpected headers %v", got.Header)
+	}
+}
+
+func TestWebhookDispatcher_PostRejectsPrivateTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the request to be blocked")
+	}))
+	defer server.Close()
+
+	dispatcher := NewWebhookDispatcher(nil, time.Second, 0, false, zap.NewNop())
+	if _, err := dispatcher.post(context.Background(), testDeliveryTarget(server.URL)); !errors.Is(err, netguard.ErrPrivateAddress) {
+		t.Errorf("Expected ErrPrivateAddress, got %v", err)
+	}
+}
+
+func TestRoomService_Webhooks(t *testing.T) {
+	msgService, roomService, db, prefix := setupTestMessageServiceIsolated(t)
+	defer db.Close()
+	defer cleanupMessageServiceTestByPrefix(t, db, prefix)
+
+	var mu sync.Mutex
+	var events []string
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var payload WebhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		events = append(events, payload.Event)
+	}))
+	defer server.Close()
+
+	repo := repository.NewRoomWebhookRepository(db)
+	dispatcher := NewWebhookDispatcher(repo, time.Second, 2, true, zap.NewNop())
+	roomService.SetWebhooks(dispatcher)
+	msgService.SetWebhooks(dispatcher)
+
+	ctx := context.Background()
+	owner := createUserForMessageServiceTestIsolated(t, db, prefix, "owner")
+	member := createUserForMessageServiceTestIsolated(t, db, prefix, "member")
+	room := createRoomForMessageServiceTestIsolated(t, db, prefix, owner, roomService)
+
+	if _, err := roomService.CreateWebhook(ctx, &CreateWebhookInput{
+		RoomID: room.ID, UserID: member.ID, URL: server.URL, Events: []string{model.WebhookEventMemberJoined},
+	}); !apperrors.Is(err, apperrors.ErrPermissionDenied) {
+		t.Errorf("Expected non-members to be denied, got %v", err)
+	}
+	if _, err := roomService.CreateWebhook(ctx, &CreateWebhookInput{
+		RoomID: room.ID, UserID: owner.ID, URL: server.URL, Events: []string{"message.deleted"},
+	}); !apperrors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected unknown events to be rejected, got %v", err)
+	}
+
+	webhook, err := roomService.CreateWebhook(ctx, &CreateWebhookInput{
+		RoomID: room.ID,
+		UserID: owner.ID,
+		URL:    server.URL,
+		Events: []string{model.WebhookEventMemberJoined, model.WebhookEventMessageCreated},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	if len(webhook.Secret) != 64 {
+		t.Errorf("Expected a generated secret, got %q", webhook.Secret)
+	}
+
+	if err := roomService.Join(ctx, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to join: %v", err)
+	}
+	if _, err := msgService.SendMessage(ctx, &SendMessageInput{RoomID: room.ID, UserID: member.ID, Content: "hi", Type: model.MessageTypeText}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	// Not subscribed
+	if err := roomService.Leave(ctx, room.ID, member.ID); err != nil {
+		t.Fatalf("Failed to leave: %v", err)
+	}
+
+	// The first attempt fails and is scheduled for a retry
+	if _, err := dispatcher.DeliverDue(ctx, 100); err != nil {
+		t.Fatalf("Failed to deliver: %v", err)
+	}
+	deliveries, err := roomService.ListWebhookDeliveries(ctx, room.ID, webhook.ID, owner.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", len(deliveries))
+	}
+	for _, d := range deliveries {
+		if d.Status != model.WebhookDeliveryPending || d.Attempts != 1 || d.LastStatusCode.Int32 != http.StatusBadGateway {
+			t.Errorf("Expected a pending retry, got %+v", d)
+		}
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if _, err := db.Exec(`UPDATE room_webhook_deliveries SET next_attempt_at = NOW() WHERE webhook_id = $1`, webhook.ID); err != nil {
+		t.Fatalf("Failed to make deliveries due: %v", err)
+	}
+	if _, err := dispatcher.DeliverDue(ctx, 100); err != nil {
+		t.Fatalf("Failed to deliver: %v", err)
+	}
+
+	deliveries, _ = roomService.ListWebhookDeliveries(ctx, room.ID, webhook.ID, owner.ID, 10, 0)
+	for _, d := range deliveries {
+		if d.Status != model.WebhookDeliveryDelivered || d.Attempts != 2 {
+			t.Errorf("Expected delivered on the second attempt, got %+v", d)
+		}
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected the webhook to receive 2 events, got %v", events)
+	}
+
+	if err := roomService.DeleteWebhook(ctx, room.ID, webhook.ID, owner.ID); err != nil {
+		t.Fatalf("Failed to delete webhook: %v", err)
+	}
+	if _, err := roomService.ListWebhookDeliveries(ctx, room.ID, webhook.ID, owner.ID, 10, 0); !apperrors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound after deletion, got %v", err)
+	}
+}
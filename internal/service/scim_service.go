@@ -0,0 +1,836 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-demo/chat/internal/events"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/go-demo/chat/internal/scim"
+	"go.uber.org/zap"
+)
+
+// SCIM list paging
+const (
+	SCIMDefaultPageSize = 100
+	SCIMMaxPageSize     = 200
+)
+
+var (
+	ErrAccountDeactivated  = apperrors.New(http.StatusForbidden, "帳號已由組織停用")
+	ErrSCIMGroupNotFound   = apperrors.New(http.StatusNotFound, "群組不存在")
+	ErrSCIMGroupExists     = apperrors.New(http.StatusConflict, "群組名稱已存在")
+	ErrSCIMExternalIDTaken = apperrors.New(http.StatusConflict, "externalId 已被其他帳號使用")
+)
+
+// SCIMService lets an identity provider provision and deprovision accounts
+// and push groups over SCIM 2.0. Group membership is synced into rooms
+// through the group-to-room mapping rules.
+type SCIMService struct {
+	store        SCIMStore
+	userRepo     *repository.UserRepository
+	auth         *AuthService
+	groupRooms   *GroupRooms
+	disconnector UserDisconnector
+	credentials  CredentialCache
+	auditor      *AuditService
+	logger       *zap.Logger
+}
+
+// NewSCIMService creates a SCIMService. groupRooms may be nil when no
+// mapping rules are configured.
+func NewSCIMService(store SCIMStore, userRepo *repository.UserRepository, auth *AuthService, groupRooms *GroupRooms, logger *zap.Logger) *SCIMService {
+	return &SCIMService{
+		store:      store,
+		userRepo:   userRepo,
+		auth:       auth,
+		groupRooms: groupRooms,
+		logger:     logger,
+	}
+}
+
+// SetDisconnector sets the component used to drop connections of
+// deactivated accounts
+func (s *SCIMService) SetDisconnector(disconnector UserDisconnector) {
+	s.disconnector = disconnector
+}
+
+// SetCredentialCache sets the cache whose entries for deactivated accounts
+// are dropped
+func (s *SCIMService) SetCredentialCache(cache CredentialCache) {
+	s.credentials = cache
+}
+
+// SetAuditor sets the audit service that records provisioning
+func (s *SCIMService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// CheckAccount returns ErrAccountDeactivated for accounts the identity
+// provider deactivated or removed
+func (s *SCIMService) CheckAccount(ctx context.Context, userID string) error {
+	inactive, err := s.store.IsInactive(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to check scim account", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if inactive {
+		return ErrAccountDeactivated
+	}
+	return nil
+}
+
+// scimPage turns a 1-based start index and count into a limit and offset
+func scimPage(startIndex, count int) (int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = SCIMDefaultPageSize
+	}
+	if count > SCIMMaxPageSize {
+		count = SCIMMaxPageSize
+	}
+	return count, startIndex - 1
+}
+
+// ListUsers lists the accounts matching filter
+func (s *SCIMService) ListUsers(ctx context.Context, filter scim.Filter, startIndex, count int) ([]*model.SCIMUser, int, error) {
+	var f repository.SCIMUserFilter
+	for _, c := range filter {
+		switch c.Attr {
+		case "id":
+			f.ID = c.Value
+		case "username":
+			f.Username = c.Value
+		case "emails", "emails.value":
+			f.Email = c.Value
+		case "externalid":
+			f.ExternalID = c.Value
+		default:
+			return nil, 0, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidFilter, "不支援以 "+c.Attr+" 篩選")
+		}
+	}
+
+	limit, offset := scimPage(startIndex, count)
+	users, total, err := s.store.ListUsers(ctx, f, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list scim users", zap.Error(err))
+		return nil, 0, apperrors.ErrInternal
+	}
+	return users, total, nil
+}
+
+// GetUser returns an account with the groups it belongs to
+func (s *SCIMService) GetUser(ctx context.Context, userID string) (*model.SCIMUser, []*model.SCIMGroup, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups, err := s.store.ListUserGroups(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list scim user groups", zap.Error(err))
+		return nil, nil, apperrors.ErrInternal
+	}
+	return user, groups, nil
+}
+
+func (s *SCIMService) getUser(ctx context.Context, userID string) (*model.SCIMUser, error) {
+	user, err := s.store.GetUser(ctx, userID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to get scim user", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return user, nil
+}
+
+// scimUserState is the part of an account SCIM can change
+type scimUserState struct {
+	displayName string
+	email       string
+	externalID  string
+	active      bool
+}
+
+func invalidSCIMValue(errs utils.ValidationErrors) error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrorInvalidValue, errs.Error())
+}
+
+// CreateUser provisions an account. Its password is random and never
+// handed out; the user signs in through the identity provider.
+func (s *SCIMService) CreateUser(ctx context.Context, in *scim.User) (*model.SCIMUser, error) {
+	username := strings.TrimSpace(in.UserName)
+	email := strings.TrimSpace(scim.PrimaryEmail(in.Emails))
+
+	v := utils.NewValidator()
+	v.ValidateUsername("userName", username)
+	v.ValidateEmail("emails", email)
+	if v.HasErrors() {
+		return nil, invalidSCIMValue(v.Errors())
+	}
+
+	if in.ExternalID != "" {
+		if _, total, err := s.store.ListUsers(ctx, repository.SCIMUserFilter{ExternalID: in.ExternalID}, 1, 0); err != nil {
+			s.logger.Error("Failed to look up external id", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		} else if total > 0 {
+			return nil, ErrSCIMExternalIDTaken
+		}
+	}
+	if exists, err := s.userRepo.ExistsByUsername(ctx, username); err != nil {
+		s.logger.Error("Failed to check username", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	} else if exists {
+		return nil, apperrors.ErrUsernameExists
+	}
+	if exists, err := s.userRepo.ExistsByEmail(ctx, email); err != nil {
+		s.logger.Error("Failed to check email", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	} else if exists {
+		return nil, apperrors.ErrEmailExists
+	}
+
+	password, err := randomToken()
+	if err != nil {
+		s.logger.Error("Failed to generate password", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		s.logger.Error("Failed to hash password", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	name := in.FullName()
+	user := &model.User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: passwordHash,
+		DisplayName:  sql.NullString{String: name, Valid: name != ""},
+		Status:       model.UserStatusOffline,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if err == repository.ErrUserAlreadyExists {
+			return nil, apperrors.ErrConflict
+		}
+		s.logger.Error("Failed to create scim user", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	active := in.Active == nil || *in.Active
+	externalID := sql.NullString{String: in.ExternalID, Valid: in.ExternalID != ""}
+	if err := s.store.SaveUser(ctx, user.ID, externalID, active); err != nil {
+		s.logger.Error("Failed to save scim user", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.auth.events.Publish(events.UserRegistered, &events.UserRegisteredData{
+		UserID:    user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	})
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    user.ID,
+		Action:     model.AuditActionUserProvisioned,
+		TargetType: model.AuditTargetUser,
+		TargetID:   user.ID,
+		Metadata: map[string]interface{}{
+			"provider": "scim",
+			"subject":  in.ExternalID,
+			"account":  "created",
+		},
+	})
+	s.logger.Info("SCIM user provisioned", zap.String("user_id", user.ID), zap.String("username", user.Username))
+
+	return s.getUser(ctx, user.ID)
+}
+
+// ReplaceUser sets the attributes of an account. The username cannot
+// change.
+func (s *SCIMService) ReplaceUser(ctx context.Context, userID string, in *scim.User) (*model.SCIMUser, error) {
+	current, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if in.UserName != "" && !strings.EqualFold(strings.TrimSpace(in.UserName), current.Username) {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrorMutability, "userName 無法變更")
+	}
+
+	return s.applyUser(ctx, current, scimUserState{
+		displayName: in.FullName(),
+		email:       strings.TrimSpace(scim.PrimaryEmail(in.Emails)),
+		externalID:  in.ExternalID,
+		active:      in.Active == nil || *in.Active,
+	})
+}
+
+// PatchUser applies PATCH operations to an account. Attributes the service
+// does not keep are ignored.
+func (s *SCIMService) PatchUser(ctx context.Context, userID string, ops []scim.PatchOperation) (*model.SCIMUser, error) {
+	current, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	next := scimUserState{
+		displayName: current.DisplayName.String,
+		email:       current.Email,
+		externalID:  current.ExternalID.String,
+		active:      current.Active,
+	}
+	err = forEachPatch(ops, func(path *scim.Path, op *scim.PatchOperation) error {
+		return patchUser(&next, current, path, op)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.applyUser(ctx, current, next)
+}
+
+// forEachPatch calls fn for every attribute the operations touch, splitting
+// operations without a path into one call per attribute
+func forEachPatch(ops []scim.PatchOperation, fn func(path *scim.Path, op *scim.PatchOperation) error) error {
+	for i := range ops {
+		op := &ops[i]
+		switch op.Operation() {
+		case scim.OpAdd, scim.OpReplace, scim.OpRemove:
+		default:
+			return scim.NewError(http.StatusBadRequest, scim.ErrorInvalidSyntax, "不支援的 PATCH 操作 "+op.Op)
+		}
+
+		if op.Path != "" {
+			path, err := scim.ParsePath(op.Path)
+			if err != nil {
+				return err
+			}
+			if err := fn(path, op); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op.Operation() == scim.OpRemove {
+			return scim.NewError(http.StatusBadRequest, scim.ErrorNoTarget, "remove 需要 path")
+		}
+		attrs, err := op.Attributes()
+		if err != nil {
+			return err
+		}
+		for name := range attrs {
+			attrOp := attrs[name]
+			path, err := scim.ParsePath(attrOp.Path)
+			if err != nil {
+				return err
+			}
+			if err := fn(path, &attrOp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func patchUser(next *scimUserState, current *model.SCIMUser, path *scim.Path, op *scim.PatchOperation) error {
+	remove := op.Operation() == scim.OpRemove
+
+	switch path.Attr {
+	case "active":
+		if remove {
+			return nil
+		}
+		active, err := op.Bool()
+		if err != nil {
+			return err
+		}
+		next.active = active
+	case "displayname":
+		if remove {
+			next.displayName = ""
+			return nil
+		}
+		name, err := op.String()
+		if err != nil {
+			return err
+		}
+		next.displayName = name
+	case "name":
+		if remove {
+			return nil
+		}
+		// Only a formatted name is kept
+		switch path.Sub {
+		case "":
+			var name scim.Name
+			if err := decodePatchValue(op, &name); err != nil {
+				return err
+			}
+			if formatted := name.String(); formatted != "" {
+				next.displayName = formatted
+			}
+		case "formatted":
+			name, err := op.String()
+			if err != nil {
+				return err
+			}
+			next.displayName = name
+		}
+	case "externalid":
+		if remove {
+			next.externalID = ""
+			return nil
+		}
+		externalID, err := op.String()
+		if err != nil {
+			return err
+		}
+		next.externalID = externalID
+	case "username":
+		if remove {
+			return scim.NewError(http.StatusBadRequest, scim.ErrorMutability, "userName 無法變更")
+		}
+		username, err := op.String()
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(strings.TrimSpace(username), current.Username) {
+			return scim.NewError(http.StatusBadRequest, scim.ErrorMutability, "userName 無法變更")
+		}
+	case "emails":
+		// An account always has an email, so removals are ignored
+		if remove {
+			return nil
+		}
+		if path.Sub == "value" {
+			email, err := op.String()
+			if err != nil {
+				return err
+			}
+			next.email = strings.TrimSpace(email)
+			return nil
+		}
+		var emails []scim.Email
+		if err := decodePatchValue(op, &emails); err != nil {
+			var email scim.Email
+			if decodePatchValue(op, &email) != nil {
+				return err
+			}
+			emails = []scim.Email{email}
+		}
+		if email := strings.TrimSpace(scim.PrimaryEmail(emails)); email != "" {
+			next.email = email
+		}
+	}
+	return nil
+}
+
+func decodePatchValue(op *scim.PatchOperation, v interface{}) error {
+	if err := json.Unmarshal(op.Value, v); err != nil {
+		return scim.NewError(http.StatusBadRequest, scim.ErrorInvalidValue, op.Path+" 的值格式錯誤")
+	}
+	return nil
+}
+
+// applyUser saves the changes from current to next
+func (s *SCIMService) applyUser(ctx context.Context, current *model.SCIMUser, next scimUserState) (*model.SCIMUser, error) {
+	userID := current.ID
+
+	if next.email != "" && !strings.EqualFold(next.email, current.Email) {
+		v := utils.NewValidator()
+		v.ValidateEmail("emails", next.email)
+		if v.HasErrors() {
+			return nil, invalidSCIMValue(v.Errors())
+		}
+		if err := s.userRepo.UpdateEmail(ctx, userID, next.email); err != nil {
+			if err == repository.ErrUserAlreadyExists {
+				return nil, apperrors.ErrEmailExists
+			}
+			s.logger.Error("Failed to update email", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+	}
+
+	if next.displayName != current.DisplayName.String {
+		if err := s.auth.SetDisplayName(ctx, userID, next.displayName); err != nil {
+			s.logger.Error("Failed to update display name", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+	}
+
+	if next.externalID != current.ExternalID.String || next.active != current.Active {
+		externalID := sql.NullString{String: next.externalID, Valid: next.externalID != ""}
+		if err := s.store.SaveUser(ctx, userID, externalID, next.active); err != nil {
+			if err == repository.ErrSCIMExternalIDTaken {
+				return nil, ErrSCIMExternalIDTaken
+			}
+			s.logger.Error("Failed to save scim user", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+	}
+
+	switch {
+	case current.Active && !next.active:
+		s.deactivated(ctx, userID, false)
+	case !current.Active && next.active:
+		s.auditor.Record(ctx, &AuditEntry{
+			Action:     model.AuditActionUserReactivated,
+			TargetType: model.AuditTargetUser,
+			TargetID:   userID,
+			Metadata:   map[string]interface{}{"provider": "scim"},
+		})
+		s.logger.Info("SCIM user reactivated", zap.String("user_id", userID))
+	}
+
+	return s.getUser(ctx, userID)
+}
+
+// deactivated signs a deactivated account out everywhere
+func (s *SCIMService) deactivated(ctx context.Context, userID string, deleted bool) {
+	if s.credentials != nil {
+		s.credentials.InvalidateUser(userID)
+	}
+	if s.disconnector != nil {
+		s.disconnector.DisconnectUser(userID, ErrAccountDeactivated.Message)
+	}
+
+	s.auditor.Record(ctx, &AuditEntry{
+		Action:     model.AuditActionUserDeactivated,
+		TargetType: model.AuditTargetUser,
+		TargetID:   userID,
+		Metadata: map[string]interface{}{
+			"provider": "scim",
+			"deleted":  deleted,
+		},
+	})
+	s.logger.Info("SCIM user deactivated", zap.String("user_id", userID), zap.Bool("deleted", deleted))
+}
+
+// DeleteUser removes an account from SCIM. The account is locked rather
+// than erased, so its messages stay readable; it leaves its SCIM groups
+// and the rooms they granted.
+func (s *SCIMService) DeleteUser(ctx context.Context, userID string) error {
+	current, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteUser(ctx, userID); err != nil {
+		s.logger.Error("Failed to delete scim user", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if current.Active {
+		s.deactivated(ctx, userID, true)
+	}
+	s.syncUsers(ctx, []string{userID})
+	return nil
+}
+
+// ListGroups lists the groups matching filter, with their members unless
+// withMembers is false
+func (s *SCIMService) ListGroups(ctx context.Context, filter scim.Filter, startIndex, count int, withMembers bool) ([]*model.SCIMGroup, int, error) {
+	var f repository.SCIMGroupFilter
+	for _, c := range filter {
+		switch c.Attr {
+		case "id":
+			f.ID = c.Value
+		case "displayname":
+			f.DisplayName = c.Value
+		case "externalid":
+			f.ExternalID = c.Value
+		default:
+			return nil, 0, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidFilter, "不支援以 "+c.Attr+" 篩選")
+		}
+	}
+
+	limit, offset := scimPage(startIndex, count)
+	groups, total, err := s.store.ListGroups(ctx, f, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list scim groups", zap.Error(err))
+		return nil, 0, apperrors.ErrInternal
+	}
+	if withMembers {
+		for _, group := range groups {
+			if group.Members, err = s.store.ListGroupMembers(ctx, group.ID); err != nil {
+				s.logger.Error("Failed to list scim group members", zap.Error(err))
+				return nil, 0, apperrors.ErrInternal
+			}
+		}
+	}
+	return groups, total, nil
+}
+
+// GetGroup returns a group with its members
+func (s *SCIMService) GetGroup(ctx context.Context, id string) (*model.SCIMGroup, error) {
+	group, err := s.store.GetGroup(ctx, id)
+	if err != nil {
+		if err == repository.ErrSCIMGroupNotFound {
+			return nil, ErrSCIMGroupNotFound
+		}
+		s.logger.Error("Failed to get scim group", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if group.Members, err = s.store.ListGroupMembers(ctx, id); err != nil {
+		s.logger.Error("Failed to list scim group members", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return group, nil
+}
+
+// memberIDs resolves group members to the IDs of existing accounts
+func (s *SCIMService) memberIDs(ctx context.Context, members []scim.Member) ([]string, error) {
+	seen := make(map[string]bool, len(members))
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		if seen[m.Value] {
+			continue
+		}
+		seen[m.Value] = true
+
+		if !utils.ValidateUUID(m.Value) {
+			return nil, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidValue, "無效的成員 "+m.Value)
+		}
+		if _, err := s.store.GetUser(ctx, m.Value); err != nil {
+			if err == repository.ErrUserNotFound {
+				return nil, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidValue, "成員不存在 "+m.Value)
+			}
+			s.logger.Error("Failed to get scim user", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		ids = append(ids, m.Value)
+	}
+	return ids, nil
+}
+
+// CreateGroup creates a group and syncs its members' rooms
+func (s *SCIMService) CreateGroup(ctx context.Context, in *scim.Group) (*model.SCIMGroup, error) {
+	name := strings.TrimSpace(in.DisplayName)
+	if name == "" {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidValue, "displayName 為必填")
+	}
+	memberIDs, err := s.memberIDs(ctx, in.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &model.SCIMGroup{
+		DisplayName: name,
+		ExternalID:  sql.NullString{String: in.ExternalID, Valid: in.ExternalID != ""},
+	}
+	if err := s.store.CreateGroup(ctx, group); err != nil {
+		if err == repository.ErrSCIMGroupAlreadyExists {
+			return nil, ErrSCIMGroupExists
+		}
+		s.logger.Error("Failed to create scim group", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if err := s.store.AddGroupMembers(ctx, group.ID, memberIDs); err != nil {
+		s.logger.Error("Failed to add scim group members", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.logger.Info("SCIM group created", zap.String("group_id", group.ID), zap.String("name", name), zap.Int("members", len(memberIDs)))
+	s.syncUsers(ctx, memberIDs)
+	return s.GetGroup(ctx, group.ID)
+}
+
+// ReplaceGroup sets the name and members of a group
+func (s *SCIMService) ReplaceGroup(ctx context.Context, id string, in *scim.Group) (*model.SCIMGroup, error) {
+	group, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	memberIDs, err := s.memberIDs(ctx, in.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]bool, len(memberIDs))
+	for _, userID := range memberIDs {
+		members[userID] = true
+	}
+	return s.saveGroup(ctx, group, strings.TrimSpace(in.DisplayName), in.ExternalID, members)
+}
+
+// PatchGroup applies PATCH operations to a group
+func (s *SCIMService) PatchGroup(ctx context.Context, id string, ops []scim.PatchOperation) (*model.SCIMGroup, error) {
+	group, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name, externalID := group.DisplayName, group.ExternalID.String
+	members := make(map[string]bool, len(group.Members))
+	for _, m := range group.Members {
+		members[m.UserID] = true
+	}
+
+	err = forEachPatch(ops, func(path *scim.Path, op *scim.PatchOperation) error {
+		remove := op.Operation() == scim.OpRemove
+		switch path.Attr {
+		case "displayname":
+			if remove {
+				return scim.NewError(http.StatusBadRequest, scim.ErrorMutability, "displayName 為必填")
+			}
+			value, err := op.String()
+			if err != nil {
+				return err
+			}
+			name = strings.TrimSpace(value)
+		case "externalid":
+			if remove {
+				externalID = ""
+				return nil
+			}
+			value, err := op.String()
+			if err != nil {
+				return err
+			}
+			externalID = value
+		case "members":
+			if remove && path.Filter != nil {
+				if path.Filter.Attr != "value" {
+					return scim.NewError(http.StatusBadRequest, scim.ErrorInvalidFilter, "成員只能依 value 篩選")
+				}
+				delete(members, path.Filter.Value)
+				return nil
+			}
+			value, err := op.Members()
+			if err != nil {
+				return err
+			}
+			if remove {
+				if len(value) == 0 {
+					members = map[string]bool{}
+				}
+				for _, m := range value {
+					delete(members, m.Value)
+				}
+				return nil
+			}
+			ids, err := s.memberIDs(ctx, value)
+			if err != nil {
+				return err
+			}
+			if op.Operation() == scim.OpReplace {
+				members = map[string]bool{}
+			}
+			for _, userID := range ids {
+				members[userID] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.saveGroup(ctx, group, name, externalID, members)
+}
+
+// saveGroup stores the new name and members of group and syncs the rooms
+// of everyone whose groups changed
+func (s *SCIMService) saveGroup(ctx context.Context, group *model.SCIMGroup, name, externalID string, members map[string]bool) (*model.SCIMGroup, error) {
+	if name == "" {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrorInvalidValue, "displayName 為必填")
+	}
+
+	renamed := !strings.EqualFold(name, group.DisplayName)
+	if name != group.DisplayName || externalID != group.ExternalID.String {
+		updated := &model.SCIMGroup{
+			ID:          group.ID,
+			DisplayName: name,
+			ExternalID:  sql.NullString{String: externalID, Valid: externalID != ""},
+		}
+		if err := s.store.UpdateGroup(ctx, updated); err != nil {
+			switch err {
+			case repository.ErrSCIMGroupNotFound:
+				return nil, ErrSCIMGroupNotFound
+			case repository.ErrSCIMGroupAlreadyExists:
+				return nil, ErrSCIMGroupExists
+			}
+			s.logger.Error("Failed to update scim group", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+	}
+
+	var added, removed, affected []string
+	had := make(map[string]bool, len(group.Members))
+	for _, m := range group.Members {
+		had[m.UserID] = true
+		if !members[m.UserID] {
+			removed = append(removed, m.UserID)
+		}
+		if renamed || !members[m.UserID] {
+			affected = append(affected, m.UserID)
+		}
+	}
+	for userID := range members {
+		if !had[userID] {
+			added = append(added, userID)
+			affected = append(affected, userID)
+		}
+	}
+
+	if err := s.store.AddGroupMembers(ctx, group.ID, added); err != nil {
+		s.logger.Error("Failed to add scim group members", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if err := s.store.RemoveGroupMembers(ctx, group.ID, removed); err != nil {
+		s.logger.Error("Failed to remove scim group members", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.syncUsers(ctx, affected)
+	return s.GetGroup(ctx, group.ID)
+}
+
+// DeleteGroup deletes a group and syncs its former members' rooms
+func (s *SCIMService) DeleteGroup(ctx context.Context, id string) error {
+	group, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteGroup(ctx, id); err != nil {
+		if err == repository.ErrSCIMGroupNotFound {
+			return ErrSCIMGroupNotFound
+		}
+		s.logger.Error("Failed to delete scim group", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	userIDs := make([]string, len(group.Members))
+	for i, m := range group.Members {
+		userIDs[i] = m.UserID
+	}
+	s.logger.Info("SCIM group deleted", zap.String("group_id", id), zap.String("name", group.DisplayName))
+	s.syncUsers(ctx, userIDs)
+	return nil
+}
+
+// syncUsers brings the mapped rooms of users in line with their SCIM
+// groups
+func (s *SCIMService) syncUsers(ctx context.Context, userIDs []string) {
+	if s.groupRooms == nil {
+		return
+	}
+	for _, userID := range userIDs {
+		groups, err := s.store.ListUserGroups(ctx, userID)
+		if err != nil {
+			s.logger.Error("Failed to list scim user groups", zap.String("user_id", userID), zap.Error(err))
+			continue
+		}
+		names := make([]string, len(groups))
+		for i, group := range groups {
+			names[i] = group.DisplayName
+		}
+		s.groupRooms.Sync(ctx, userID, names)
+	}
+}
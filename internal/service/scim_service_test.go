@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/scim"
+	"github.com/go-demo/chat/internal/sso"
+	"go.uber.org/zap"
+)
+
+const (
+	scimAlice = "5b9f3a4e-0c1d-4e2f-8a3b-1c2d3e4f5a6b"
+	scimBob   = "6c0a4b5f-1d2e-4f3a-9b4c-2d3e4f5a6b7c"
+	scimCarol = "7d1b5c6a-2e3f-4a4b-8c5d-3e4f5a6b7c8d"
+)
+
+func scimTestUser(id, username string) *model.SCIMUser {
+	return &model.SCIMUser{
+		User: model.User{
+			ID:          id,
+			Username:    username,
+			Email:       username + "@example.com",
+			DisplayName: sql.NullString{String: username, Valid: true},
+		},
+		Active: true,
+	}
+}
+
+type recordingCredentialCache struct {
+	userIDs []string
+}
+
+func (r *recordingCredentialCache) InvalidateUser(userID string) {
+	r.userIDs = append(r.userIDs, userID)
+}
+
+func TestSCIMService_PatchUserDeactivates(t *testing.T) {
+	var saved *bool
+	store := &mockSCIMStore{
+		GetUserFunc: func(ctx context.Context, userID string) (*model.SCIMUser, error) {
+			user := scimTestUser(scimAlice, "alice")
+			if saved != nil {
+				user.Active = *saved
+			}
+			return user, nil
+		},
+		SaveUserFunc: func(ctx context.Context, userID string, externalID sql.NullString, active bool) error {
+			saved = &active
+			return nil
+		},
+	}
+	svc := NewSCIMService(store, nil, nil, nil, zap.NewNop())
+	credentials := &recordingCredentialCache{}
+	disconnector := &recordingDisconnector{}
+	svc.SetCredentialCache(credentials)
+	svc.SetDisconnector(disconnector)
+
+	// Azure AD sends the boolean as a string
+	user, err := svc.PatchUser(context.Background(), scimAlice, []scim.PatchOperation{
+		{Op: "Replace", Path: "active", Value: json.RawMessage(`"False"`)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user.Active || saved == nil || *saved {
+		t.Fatal("Expected the account to be saved inactive")
+	}
+	if len(credentials.userIDs) != 1 || len(disconnector.userIDs) != 1 {
+		t.Errorf("Expected the account to be signed out, got %v and %v", credentials.userIDs, disconnector.userIDs)
+	}
+
+	store.IsInactiveFunc = func(ctx context.Context, userID string) (bool, error) { return true, nil }
+	if err := svc.CheckAccount(context.Background(), scimAlice); err != ErrAccountDeactivated {
+		t.Errorf("Expected ErrAccountDeactivated, got %v", err)
+	}
+}
+
+func TestSCIMService_PatchUserKeepsUsername(t *testing.T) {
+	store := &mockSCIMStore{
+		GetUserFunc: func(ctx context.Context, userID string) (*model.SCIMUser, error) {
+			return scimTestUser(scimAlice, "alice"), nil
+		},
+	}
+	svc := NewSCIMService(store, nil, nil, nil, zap.NewNop())
+
+	// Providers resend the username unchanged, in any case
+	if _, err := svc.PatchUser(context.Background(), scimAlice, []scim.PatchOperation{
+		{Op: "replace", Value: json.RawMessage(`{"userName":"Alice"}`)},
+	}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	_, err := svc.PatchUser(context.Background(), scimAlice, []scim.PatchOperation{
+		{Op: "replace", Path: "userName", Value: json.RawMessage(`"mallory"`)},
+	})
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) || scimErr.ScimType != scim.ErrorMutability {
+		t.Errorf("Expected a mutability error, got %v", err)
+	}
+	if store.Calls("SaveUser") != 0 {
+		t.Error("Expected nothing to be saved")
+	}
+}
+
+func TestSCIMService_PatchGroupSyncsRooms(t *testing.T) {
+	members := map[string]bool{scimAlice: true, scimBob: true}
+	var added, removed []string
+	store := &mockSCIMStore{
+		GetUserFunc: func(ctx context.Context, userID string) (*model.SCIMUser, error) {
+			return scimTestUser(userID, "user"), nil
+		},
+		GetGroupFunc: func(ctx context.Context, id string) (*model.SCIMGroup, error) {
+			return &model.SCIMGroup{ID: id, DisplayName: "engineering"}, nil
+		},
+		ListGroupMembersFunc: func(ctx context.Context, groupID string) ([]*model.SCIMGroupMember, error) {
+			var list []*model.SCIMGroupMember
+			for userID := range members {
+				list = append(list, &model.SCIMGroupMember{UserID: userID})
+			}
+			return list, nil
+		},
+		AddGroupMembersFunc: func(ctx context.Context, groupID string, userIDs []string) error {
+			added = append(added, userIDs...)
+			for _, userID := range userIDs {
+				members[userID] = true
+			}
+			return nil
+		},
+		RemoveGroupMembersFunc: func(ctx context.Context, groupID string, userIDs []string) error {
+			removed = append(removed, userIDs...)
+			for _, userID := range userIDs {
+				delete(members, userID)
+			}
+			return nil
+		},
+		ListUserGroupsFunc: func(ctx context.Context, userID string) ([]*model.SCIMGroup, error) {
+			if members[userID] {
+				return []*model.SCIMGroup{{DisplayName: "engineering"}}, nil
+			}
+			return nil, nil
+		},
+	}
+	grants := &mockSSOStore{
+		ListRoomGrantsFunc: func(ctx context.Context, userID string) ([]string, error) {
+			if userID == scimAlice {
+				return []string{"room-eng"}, nil
+			}
+			return nil, nil
+		},
+	}
+	rooms := &fakeDirectoryRooms{}
+	groupRooms := NewGroupRooms([]sso.GroupRule{{Group: "engineering", RoomIDs: []string{"room-eng"}}}, grants, rooms, zap.NewNop())
+	svc := NewSCIMService(store, nil, nil, groupRooms, zap.NewNop())
+
+	group, err := svc.PatchGroup(context.Background(), "group-1", []scim.PatchOperation{
+		{Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"` + scimCarol + `"}]`)},
+		{Op: "remove", Path: `members[value eq "` + scimAlice + `"]`},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(added, []string{scimCarol}) || !reflect.DeepEqual(removed, []string{scimAlice}) {
+		t.Errorf("Expected carol added and alice removed, got %v and %v", added, removed)
+	}
+	if len(group.Members) != 2 {
+		t.Errorf("Expected two members, got %d", len(group.Members))
+	}
+	// Bob's groups did not change, so only alice and carol are synced
+	if !reflect.DeepEqual(rooms.added, []string{"room-eng"}) || !reflect.DeepEqual(rooms.removed, []string{"room-eng"}) {
+		t.Errorf("Expected carol to join and alice to leave room-eng, got %v and %v", rooms.added, rooms.removed)
+	}
+	if n := grants.Calls("ListRoomGrants"); n != 2 {
+		t.Errorf("Expected two users to be synced, got %d", n)
+	}
+}
+
+func TestSCIMService_PatchGroupRejectsUnknownMembers(t *testing.T) {
+	store := &mockSCIMStore{
+		GetGroupFunc: func(ctx context.Context, id string) (*model.SCIMGroup, error) {
+			return &model.SCIMGroup{ID: id, DisplayName: "engineering"}, nil
+		},
+	}
+	svc := NewSCIMService(store, nil, nil, nil, zap.NewNop())
+
+	for _, value := range []string{`[{"value":"not-a-uuid"}]`, `[{"value":"` + scimCarol + `"}]`} {
+		_, err := svc.PatchGroup(context.Background(), "group-1", []scim.PatchOperation{
+			{Op: "add", Path: "members", Value: json.RawMessage(value)},
+		})
+		var scimErr *scim.Error
+		if !errors.As(err, &scimErr) || scimErr.ScimType != scim.ErrorInvalidValue {
+			t.Errorf("Expected invalidValue for %s, got %v", value, err)
+		}
+	}
+	if store.Calls("AddGroupMembers") != 0 {
+		t.Error("Expected no members to be added")
+	}
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Points each spam signal adds to an account's score
+const (
+	spamWeightNoMessages      = 30
+	spamWeightFriendRequests  = 50
+	spamWeightDisposableEmail = 40
+)
+
+// SpamSuspensionReason is the ban reason of accounts the sweep suspends
+const SpamSuspensionReason = "系統偵測為垃圾帳號"
+
+// SpamPolicy decides which accounts the sweep scores and what it does with
+// high scores
+type SpamPolicy struct {
+	MinAccountAge          time.Duration // newer accounts have not had time to post yet
+	MaxAccountAge          time.Duration // older accounts are not scored
+	FriendRequestThreshold int           // unanswered friend requests out that count as mass requests; 0 disables the signal
+	DisposableDomains      []string      // matches the domain and its subdomains
+	FlagScore              int           // accounts scoring at least this are flagged
+	SuspendScore           int           // accounts scoring at least this are also suspended; 0 never suspends
+	SuspendDuration        time.Duration // zero suspends permanently
+}
+
+// SpamSweepService periodically scores recent accounts and flags or
+// suspends the ones that look like spam
+type SpamSweepService struct {
+	repo       SpamStore
+	banService *BanService
+	auditor    *AuditService
+	policy     SpamPolicy
+	domains    map[string]bool
+	logger     *zap.Logger
+}
+
+func NewSpamSweepService(repo SpamStore, banService *BanService, policy SpamPolicy, logger *zap.Logger) *SpamSweepService {
+	domains := make(map[string]bool, len(policy.DisposableDomains))
+	for _, domain := range policy.DisposableDomains {
+		domains[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+	return &SpamSweepService{
+		repo:       repo,
+		banService: banService,
+		policy:     policy,
+		domains:    domains,
+		logger:     logger,
+	}
+}
+
+// SetAuditor sets the audit service that records flagged accounts
+func (s *SpamSweepService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// Score adds up the signals an account shows
+func (s *SpamSweepService) Score(candidate *model.SpamCandidate) (int, []string) {
+	score := 0
+	var signals []string
+	if candidate.MessageCount == 0 {
+		score += spamWeightNoMessages
+		signals = append(signals, string(model.SpamSignalNoMessages))
+	}
+	if s.policy.FriendRequestThreshold > 0 && candidate.PendingFriendRequests >= s.policy.FriendRequestThreshold {
+		score += spamWeightFriendRequests
+		signals = append(signals, string(model.SpamSignalFriendRequests))
+	}
+	if s.isDisposable(candidate.Email) {
+		score += spamWeightDisposableEmail
+		signals = append(signals, string(model.SpamSignalDisposableEmail))
+	}
+	return score, signals
+}
+
+// isDisposable checks the email's domain, and its parent domains, against
+// the disposable domain list
+func (s *SpamSweepService) isDisposable(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for domain != "" {
+		if s.domains[domain] {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
+
+// Sweep scores every eligible account, batchSize at a time, flags the
+// ones at or above the flag score and suspends the ones at or above the
+// suspend score. The run is recorded for the admin stats.
+func (s *SpamSweepService) Sweep(ctx context.Context, batchSize int) (*model.SpamSweepRun, error) {
+	run := &model.SpamSweepRun{StartedAt: time.Now()}
+	from := run.StartedAt.Add(-s.policy.MaxAccountAge)
+	to := run.StartedAt.Add(-s.policy.MinAccountAge)
+
+	var after *model.SpamCandidate
+	for {
+		candidates, err := s.repo.ListCandidates(ctx, from, to, after, batchSize)
+		if err != nil {
+			s.logger.Error("Failed to list spam candidates", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+
+		for _, candidate := range candidates {
+			run.Scanned++
+			switch s.handle(ctx, candidate) {
+			case model.SpamActionSuspended:
+				run.Suspended++
+				run.Flagged++
+			case model.SpamActionFlagged:
+				run.Flagged++
+			}
+		}
+
+		if len(candidates) < batchSize {
+			break
+		}
+		after = candidates[len(candidates)-1]
+	}
+
+	run.FinishedAt = time.Now()
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		s.logger.Error("Failed to record spam sweep run", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	if run.Flagged > 0 {
+		s.logger.Info("Spam sweep flagged accounts",
+			zap.Int("scanned", run.Scanned),
+			zap.Int("flagged", run.Flagged),
+			zap.Int("suspended", run.Suspended),
+		)
+	}
+	return run, nil
+}
+
+// handle scores one account and acts on it, returning what was done. A
+// failure only skips the account, which the next sweep retries.
+func (s *SpamSweepService) handle(ctx context.Context, candidate *model.SpamCandidate) model.SpamAction {
+	score, signals := s.Score(candidate)
+	if score < s.policy.FlagScore {
+		return ""
+	}
+
+	flag := &model.SpamFlag{
+		UserID:  candidate.UserID,
+		Score:   score,
+		Signals: signals,
+		Action:  model.SpamActionFlagged,
+	}
+	if s.policy.SuspendScore > 0 && score >= s.policy.SuspendScore {
+		if _, err := s.banService.Ban(ctx, &BanInput{
+			UserID:   candidate.UserID,
+			Reason:   SpamSuspensionReason,
+			Duration: s.policy.SuspendDuration,
+		}); err != nil {
+			s.logger.Warn("Failed to suspend spam account", zap.String("user_id", candidate.UserID), zap.Error(err))
+			return ""
+		}
+		flag.Action = model.SpamActionSuspended
+	}
+
+	created, err := s.repo.CreateFlag(ctx, flag)
+	if err != nil {
+		s.logger.Warn("Failed to flag spam account", zap.String("user_id", candidate.UserID), zap.Error(err))
+		return ""
+	}
+	if !created {
+		// Another instance flagged it in the meantime
+		return ""
+	}
+
+	s.auditor.Record(ctx, &AuditEntry{
+		Action:     model.AuditActionUserSpamFlagged,
+		TargetType: model.AuditTargetUser,
+		TargetID:   candidate.UserID,
+		Metadata: map[string]interface{}{
+			"score":   score,
+			"signals": signals,
+			"action":  string(flag.Action),
+		},
+	})
+	return flag.Action
+}
+
+// ListFlags lists the accounts the sweep flagged, newest first
+func (s *SpamSweepService) ListFlags(ctx context.Context, limit, offset int) ([]*model.SpamFlagWithUser, error) {
+	flags, err := s.repo.ListFlags(ctx, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list spam flags", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return flags, nil
+}
+
+// Stats summarizes the flags and the latest sweep
+func (s *SpamSweepService) Stats(ctx context.Context) (*model.SpamStats, error) {
+	stats, err := s.repo.GetStats(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get spam stats", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return stats, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+func TestSpamSweepService_Score(t *testing.T) {
+	service := NewSpamSweepService(&mockSpamStore{}, nil, SpamPolicy{
+		FriendRequestThreshold: 10,
+		DisposableDomains:      []string{"Mailinator.com "},
+	}, zap.NewNop())
+
+	tests := []struct {
+		name      string
+		candidate model.SpamCandidate
+		score     int
+		signals   []string
+	}{
+		{"active account", model.SpamCandidate{Email: "a@example.com", MessageCount: 3}, 0, nil},
+		{"no messages", model.SpamCandidate{Email: "a@example.com"}, spamWeightNoMessages, []string{"no_messages"}},
+		{"disposable subdomain", model.SpamCandidate{Email: "a@x.MAILINATOR.com", MessageCount: 1}, spamWeightDisposableEmail, []string{"disposable_email"}},
+		{"lookalike domain", model.SpamCandidate{Email: "a@notmailinator.com", MessageCount: 1}, 0, nil},
+		{
+			"every signal",
+			model.SpamCandidate{Email: "a@mailinator.com", PendingFriendRequests: 10},
+			spamWeightNoMessages + spamWeightFriendRequests + spamWeightDisposableEmail,
+			[]string{"no_messages", "mass_friend_requests", "disposable_email"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, signals := service.Score(&tt.candidate)
+			if score != tt.score {
+				t.Errorf("Expected score %d, got %d", tt.score, score)
+			}
+			if !reflect.DeepEqual(signals, tt.signals) {
+				t.Errorf("Expected signals %v, got %v", tt.signals, signals)
+			}
+		})
+	}
+}
+
+func TestSpamSweepService_Sweep(t *testing.T) {
+	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=chat_test sslmode=disable"
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Skipf("Skipping test, could not connect to test database: %v", err)
+	}
+	defer db.Close()
+
+	prefix := repository.GenerateUniquePrefix()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	userRepo := repository.NewUserRepository(db)
+	banService := NewBanService(repository.NewBanRepository(db), userRepo, zap.NewNop())
+	// Only the accounts below are old enough to fall in the window
+	service := NewSpamSweepService(repository.NewSpamRepository(db), banService, SpamPolicy{
+		MinAccountAge:          99 * 365 * 24 * time.Hour,
+		MaxAccountAge:          101 * 365 * 24 * time.Hour,
+		FriendRequestThreshold: 2,
+		DisposableDomains:      []string{"mailinator.com"},
+		FlagScore:              60,
+		SuspendScore:           100,
+		SuspendDuration:        time.Hour,
+	}, zap.NewNop())
+	ctx := context.Background()
+
+	createdAt := time.Now().Add(-100 * 365 * 24 * time.Hour)
+	account := func(name, email string) *model.User {
+		user := repository.CreateIsolatedTestUser(t, db, prefix, name)
+		if _, err := db.Exec(`UPDATE users SET email = $1, created_at = $2 WHERE id = $3`, email, createdAt, user.ID); err != nil {
+			t.Fatalf("Failed to age user: %v", err)
+		}
+		return user
+	}
+	quiet := account("quiet", prefix+"_quiet@example.com")
+	throwaway := account("throwaway", prefix+"_throwaway@mailinator.com")
+	spammer := account("spammer", prefix+"_spammer@mailinator.com")
+	for _, target := range []*model.User{quiet, throwaway} {
+		if _, err := db.Exec(`INSERT INTO friendships (user_id, friend_id, status) VALUES ($1, $2, 'pending')`, spammer.ID, target.ID); err != nil {
+			t.Fatalf("Failed to create friend request: %v", err)
+		}
+	}
+
+	run, err := service.Sweep(ctx, 1)
+	if err != nil {
+		t.Fatalf("Failed to sweep: %v", err)
+	}
+	defer db.Exec(`DELETE FROM spam_sweep_runs WHERE id = $1`, run.ID)
+
+	if run.Scanned != 3 || run.Flagged != 2 || run.Suspended != 1 {
+		t.Errorf("Expected 3 scanned, 2 flagged, 1 suspended, got %+v", run)
+	}
+
+	flags := make(map[string]model.SpamAction)
+	rows, err := db.Queryx(`SELECT user_id, action FROM spam_flags WHERE user_id IN ($1, $2, $3)`, quiet.ID, throwaway.ID, spammer.ID)
+	if err != nil {
+		t.Fatalf("Failed to read flags: %v", err)
+	}
+	for rows.Next() {
+		var userID string
+		var action model.SpamAction
+		if err := rows.Scan(&userID, &action); err != nil {
+			t.Fatalf("Failed to scan flag: %v", err)
+		}
+		flags[userID] = action
+	}
+	rows.Close()
+
+	if _, ok := flags[quiet.ID]; ok {
+		t.Error("Expected quiet account not to be flagged")
+	}
+	if flags[throwaway.ID] != model.SpamActionFlagged {
+		t.Errorf("Expected throwaway account flagged, got %q", flags[throwaway.ID])
+	}
+	if flags[spammer.ID] != model.SpamActionSuspended {
+		t.Errorf("Expected spammer suspended, got %q", flags[spammer.ID])
+	}
+	if err := banService.CheckAccount(ctx, spammer.ID); err == nil {
+		t.Error("Expected spammer to be banned")
+	}
+
+	// Flagged accounts are not scored again
+	again, err := service.Sweep(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to sweep again: %v", err)
+	}
+	defer db.Exec(`DELETE FROM spam_sweep_runs WHERE id = $1`, again.ID)
+	if again.Scanned != 1 || again.Flagged != 0 {
+		t.Errorf("Expected only the quiet account scanned, got %+v", again)
+	}
+}
+
+func TestSpamSweepService_Sweep_Unit(t *testing.T) {
+	ctx := context.Background()
+	pages := [][]*model.SpamCandidate{
+		{
+			{UserID: "active", Email: "a@example.com", MessageCount: 5},
+			{UserID: "throwaway", Email: "b@mailinator.com"},
+		},
+		{
+			{UserID: "spammer", Email: "c@mailinator.com", PendingFriendRequests: 3},
+		},
+	}
+	store := &mockSpamStore{}
+	store.ListCandidatesFunc = func(ctx context.Context, from, to time.Time, after *model.SpamCandidate, limit int) ([]*model.SpamCandidate, error) {
+		if after == nil {
+			return pages[0], nil
+		}
+		if after.UserID != "throwaway" {
+			t.Errorf("Expected the next page after the last candidate, got %s", after.UserID)
+		}
+		return pages[1], nil
+	}
+	flags := make(map[string]*model.SpamFlag)
+	store.CreateFlagFunc = func(ctx context.Context, flag *model.SpamFlag) (bool, error) {
+		flags[flag.UserID] = flag
+		return true, nil
+	}
+
+	bans := &mockBanStore{}
+	users := &mockUserLookup{GetByIDFunc: func(ctx context.Context, id string) (*model.User, error) {
+		return &model.User{ID: id}, nil
+	}}
+	service := NewSpamSweepService(store, NewBanService(bans, users, zap.NewNop()), SpamPolicy{
+		FriendRequestThreshold: 2,
+		DisposableDomains:      []string{"mailinator.com"},
+		FlagScore:              60,
+		SuspendScore:           100,
+	}, zap.NewNop())
+
+	run, err := service.Sweep(ctx, 2)
+	if err != nil {
+		t.Fatalf("Failed to sweep: %v", err)
+	}
+	if run.Scanned != 3 || run.Flagged != 2 || run.Suspended != 1 {
+		t.Errorf("Expected 3 scanned, 2 flagged, 1 suspended, got %+v", run)
+	}
+	if store.Calls("ListCandidates") != 2 || store.Calls("CreateRun") != 1 {
+		t.Errorf("Expected 2 pages and 1 recorded run, got %d and %d", store.Calls("ListCandidates"), store.Calls("CreateRun"))
+	}
+	if flags["throwaway"] == nil || flags["throwaway"].Action != model.SpamActionFlagged {
+		t.Errorf("Expected throwaway flagged, got %+v", flags["throwaway"])
+	}
+	if flags["spammer"] == nil || flags["spammer"].Action != model.SpamActionSuspended {
+		t.Errorf("Expected spammer suspended, got %+v", flags["spammer"])
+	}
+	if bans.Calls("Create") != 1 {
+		t.Errorf("Expected 1 ban, got %d", bans.Calls("Create"))
+	}
+}
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-demo/chat/internal/events"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/go-demo/chat/internal/sso"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Enterprise sign-in modes
+const (
+	SSOModeLocal = "local"
+	SSOModeLDAP  = "ldap"
+	SSOModeSAML  = "saml"
+)
+
+const (
+	// ssoRequestTTL is how long a SAML sign-in may take at the IdP
+	ssoRequestTTL = 10 * time.Minute
+	// ssoCodeTTL is how long the callback page has to exchange its code
+	ssoCodeTTL = time.Minute
+
+	ssoRequestKeyPrefix   = "sso:saml:request:"
+	ssoAssertionKeyPrefix = "sso:saml:assertion:"
+	ssoCodeKeyPrefix      = "sso:code:"
+)
+
+var (
+	ErrSSONotEnabled      = apperrors.New(http.StatusNotFound, "未啟用單一登入")
+	ErrSSORequired        = apperrors.New(http.StatusForbidden, "請使用單一登入")
+	ErrSSORegistration    = apperrors.New(http.StatusForbidden, "已啟用單一登入，帳號會在首次登入時自動建立")
+	ErrSSOUnavailable     = apperrors.New(http.StatusServiceUnavailable, "身分提供者暫時無法使用，請稍後再試")
+	ErrSSORequestExpired  = apperrors.New(http.StatusBadRequest, "單一登入請求已失效，請重新登入")
+	ErrSSOAssertion       = apperrors.New(http.StatusUnauthorized, "單一登入驗證失敗")
+	ErrSSOCodeInvalid     = apperrors.New(http.StatusUnauthorized, "登入代碼無效或已過期")
+	ErrSSOAccountConflict = apperrors.New(http.StatusConflict, "身分提供者的帳號與既有使用者衝突，請聯絡管理員")
+	ErrSSOProfileInvalid  = apperrors.New(http.StatusUnprocessableEntity, "身分提供者提供的使用者名稱或電子郵件無效，請聯絡管理員")
+)
+
+// SSOConfig configures enterprise sign-in
+type SSOConfig struct {
+	// Mode is SSOModeLDAP or SSOModeSAML; the authenticator for it must be
+	// set
+	Mode string
+	LDAP *sso.LDAPAuthenticator
+	SAML *sso.SAMLServiceProvider
+	// LocalLogin keeps password sign-in working for accounts that exist
+	// only locally, such as a break-glass administrator
+	LocalLogin bool
+	// LinkByEmail links a first sign-in to an existing account with the
+	// same email instead of refusing it
+	LinkByEmail bool
+	GroupRules  []sso.GroupRule
+}
+
+// SSOService signs users in through an enterprise identity provider. A
+// user's first sign-in creates their account, and every sign-in syncs the
+// rooms their groups map to.
+type SSOService struct {
+	cfg        SSOConfig
+	store      SSOStore
+	userRepo   *repository.UserRepository
+	auth       *AuthService
+	groupRooms *GroupRooms
+	redis      *redis.Client
+	auditor    *AuditService
+	logger     *zap.Logger
+}
+
+// NewSSOService creates an SSOService
+func NewSSOService(cfg SSOConfig, store SSOStore, userRepo *repository.UserRepository, auth *AuthService, rooms DirectoryRooms, redisClient *redis.Client, logger *zap.Logger) *SSOService {
+	return &SSOService{
+		cfg:        cfg,
+		store:      store,
+		userRepo:   userRepo,
+		auth:       auth,
+		groupRooms: NewGroupRooms(cfg.GroupRules, store, rooms, logger),
+		redis:      redisClient,
+		logger:     logger,
+	}
+}
+
+// SetAuditor sets the audit service that records provisioned accounts
+func (s *SSOService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// Mode returns the sign-in mode
+func (s *SSOService) Mode() string {
+	return s.cfg.Mode
+}
+
+// LocalLogin reports whether local accounts may still sign in with a
+// password
+func (s *SSOService) LocalLogin() bool {
+	return s.cfg.LocalLogin
+}
+
+// Login signs in with a username and password. In LDAP mode they are
+// checked against the directory; local accounts are only tried when the
+// directory does not know the user and local login is enabled.
+func (s *SSOService) Login(ctx context.Context, input *LoginInput) (*LoginResult, error) {
+	if s.cfg.Mode != SSOModeLDAP {
+		if s.cfg.LocalLogin {
+			return s.auth.Login(ctx, input)
+		}
+		return nil, ErrSSORequired
+	}
+
+	identity, err := s.cfg.LDAP.Authenticate(ctx, input.Username, input.Password)
+	if err != nil {
+		if errors.Is(err, sso.ErrInvalidCredentials) {
+			if s.cfg.LocalLogin {
+				return s.auth.Login(ctx, input)
+			}
+			return nil, apperrors.ErrInvalidPassword
+		}
+		if err := checkContext(ctx); err != nil {
+			return nil, err
+		}
+		s.logger.Error("LDAP authentication failed", zap.Error(err))
+		return nil, ErrSSOUnavailable
+	}
+
+	user, err := s.provision(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	return s.auth.signIn(ctx, user, input)
+}
+
+// SAMLLoginURL starts a SAML sign-in and returns the IdP URL to send the
+// browser to
+func (s *SSOService) SAMLLoginURL(ctx context.Context) (string, error) {
+	if s.cfg.Mode != SSOModeSAML {
+		return "", ErrSSONotEnabled
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		s.logger.Error("Failed to generate sso state", zap.Error(err))
+		return "", apperrors.ErrInternal
+	}
+	redirectURL, requestID, err := s.cfg.SAML.AuthnRequestURL(state, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to build SAML request", zap.Error(err))
+		return "", apperrors.ErrInternal
+	}
+	if err := s.redis.Set(ctx, ssoRequestKeyPrefix+state, requestID, ssoRequestTTL).Err(); err != nil {
+		s.logger.Error("Failed to store SAML request", zap.Error(err))
+		return "", apperrors.ErrInternal
+	}
+	return redirectURL, nil
+}
+
+// SAMLMetadata returns the service provider metadata
+func (s *SSOService) SAMLMetadata() ([]byte, error) {
+	if s.cfg.Mode != SSOModeSAML {
+		return nil, ErrSSONotEnabled
+	}
+	return s.cfg.SAML.Metadata(), nil
+}
+
+// CompleteSAML verifies the response the IdP posted for the sign-in with
+// relayState and returns a one-time code that ExchangeCode trades for
+// tokens. Tokens are not handed to the browser in the redirect itself.
+func (s *SSOService) CompleteSAML(ctx context.Context, samlResponse, relayState string) (string, error) {
+	if s.cfg.Mode != SSOModeSAML {
+		return "", ErrSSONotEnabled
+	}
+	if relayState == "" {
+		return "", ErrSSORequestExpired
+	}
+
+	requestID, err := s.redis.GetDel(ctx, ssoRequestKeyPrefix+relayState).Result()
+	if err == redis.Nil {
+		return "", ErrSSORequestExpired
+	}
+	if err != nil {
+		s.logger.Error("Failed to load SAML request", zap.Error(err))
+		return "", apperrors.ErrInternal
+	}
+
+	assertion, err := s.cfg.SAML.ParseResponse(samlResponse, requestID, time.Now())
+	if err != nil {
+		s.logger.Warn("Rejected SAML response", zap.Error(err))
+		return "", ErrSSOAssertion
+	}
+
+	// An assertion is good for one sign-in; remember it until it expires
+	ttl := time.Until(assertion.NotOnOrAfter)
+	if ttl < time.Minute {
+		ttl = time.Minute
+	}
+	fresh, err := s.redis.SetNX(ctx, ssoAssertionKeyPrefix+assertion.ID, 1, ttl).Result()
+	if err != nil {
+		s.logger.Error("Failed to record SAML assertion", zap.Error(err))
+		return "", apperrors.ErrInternal
+	}
+	if !fresh {
+		s.logger.Warn("Rejected replayed SAML assertion", zap.String("assertion_id", assertion.ID))
+		return "", ErrSSOAssertion
+	}
+
+	user, err := s.provision(ctx, assertion.Identity)
+	if err != nil {
+		return "", err
+	}
+	if err := s.auth.checkAccount(ctx, user.ID); err != nil {
+		return "", err
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		s.logger.Error("Failed to generate sso code", zap.Error(err))
+		return "", apperrors.ErrInternal
+	}
+	if err := s.redis.Set(ctx, ssoCodeKeyPrefix+code, user.ID, ssoCodeTTL).Err(); err != nil {
+		s.logger.Error("Failed to store sso code", zap.Error(err))
+		return "", apperrors.ErrInternal
+	}
+	return code, nil
+}
+
+// ExchangeCode trades a code from CompleteSAML for a session. Each code
+// works once.
+func (s *SSOService) ExchangeCode(ctx context.Context, code string, input *LoginInput) (*LoginResult, error) {
+	if s.cfg.Mode != SSOModeSAML {
+		return nil, ErrSSONotEnabled
+	}
+	if code == "" {
+		return nil, ErrSSOCodeInvalid
+	}
+
+	userID, err := s.redis.GetDel(ctx, ssoCodeKeyPrefix+code).Result()
+	if err == redis.Nil {
+		return nil, ErrSSOCodeInvalid
+	}
+	if err != nil {
+		s.logger.Error("Failed to load sso code", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			return nil, ErrSSOCodeInvalid
+		}
+		s.logger.Error("Failed to get user", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return s.auth.signIn(ctx, user, input)
+}
+
+// provision returns the account of an identity, creating it on first
+// sign-in, and brings its display name and rooms in line with the IdP
+func (s *SSOService) provision(ctx context.Context, identity *sso.Identity) (*model.User, error) {
+	var user *model.User
+	linked, err := s.store.GetIdentity(ctx, identity.Provider, identity.Subject)
+	switch {
+	case err == nil:
+		user, err = s.userRepo.GetByID(ctx, linked.UserID)
+		if err != nil {
+			s.logger.Error("Failed to get sso user", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		if err := s.store.TouchIdentity(ctx, identity.Provider, identity.Subject); err != nil {
+			s.logger.Warn("Failed to record sso login", zap.Error(err))
+		}
+		if identity.DisplayName != "" && identity.DisplayName != user.DisplayName.String {
+			if err := s.auth.SetDisplayName(ctx, user.ID, identity.DisplayName); err != nil {
+				s.logger.Warn("Failed to sync display name", zap.Error(err))
+			} else {
+				user.DisplayName = sql.NullString{String: identity.DisplayName, Valid: true}
+			}
+		}
+	case err == repository.ErrExternalIdentityNotFound:
+		if user, err = s.firstLogin(ctx, identity); err != nil {
+			return nil, err
+		}
+	default:
+		s.logger.Error("Failed to get external identity", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.groupRooms.Sync(ctx, user.ID, identity.Groups)
+	return user, nil
+}
+
+// firstLogin links an identity to an account, creating one unless
+// LinkByEmail finds an existing account with its email
+func (s *SSOService) firstLogin(ctx context.Context, identity *sso.Identity) (*model.User, error) {
+	if s.cfg.LinkByEmail && identity.Email != "" {
+		user, err := s.userRepo.GetByEmail(ctx, identity.Email)
+		switch {
+		case err == nil:
+			if err := s.link(ctx, identity, user, "linked"); err != nil {
+				return nil, err
+			}
+			return user, nil
+		case err != repository.ErrUserNotFound:
+			s.logger.Error("Failed to get user by email", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+	}
+
+	v := utils.NewValidator()
+	v.ValidateUsername("username", identity.Username)
+	v.ValidateEmail("email", identity.Email)
+	if v.HasErrors() {
+		s.logger.Warn("SSO identity cannot be provisioned",
+			zap.String("provider", identity.Provider),
+			zap.String("subject", identity.Subject),
+			zap.Any("errors", v.Errors()),
+		)
+		return nil, ErrSSOProfileInvalid
+	}
+
+	// The account only signs in through the IdP, so its password is random
+	// and never handed out
+	password, err := randomToken()
+	if err != nil {
+		s.logger.Error("Failed to generate password", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		s.logger.Error("Failed to hash password", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	user := &model.User{
+		Username:     identity.Username,
+		Email:        identity.Email,
+		PasswordHash: passwordHash,
+		DisplayName:  sql.NullString{String: identity.DisplayName, Valid: identity.DisplayName != ""},
+		Status:       model.UserStatusOffline,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if err == repository.ErrUserAlreadyExists {
+			s.logger.Warn("SSO identity conflicts with an existing user",
+				zap.String("provider", identity.Provider),
+				zap.String("subject", identity.Subject),
+				zap.String("username", identity.Username),
+			)
+			return nil, ErrSSOAccountConflict
+		}
+		s.logger.Error("Failed to create sso user", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	s.auth.events.Publish(events.UserRegistered, &events.UserRegisteredData{
+		UserID:    user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	})
+
+	if err := s.link(ctx, identity, user, "created"); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *SSOService) link(ctx context.Context, identity *sso.Identity, user *model.User, how string) error {
+	if err := s.store.CreateIdentity(ctx, &model.ExternalIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		UserID:   user.ID,
+	}); err != nil {
+		s.logger.Error("Failed to link external identity", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    user.ID,
+		Action:     model.AuditActionUserProvisioned,
+		TargetType: model.AuditTargetUser,
+		TargetID:   user.ID,
+		Metadata: map[string]interface{}{
+			"provider": identity.Provider,
+			"subject":  identity.Subject,
+			"account":  how,
+		},
+	})
+	s.logger.Info("SSO identity linked",
+		zap.String("user_id", user.ID),
+		zap.String("provider", identity.Provider),
+		zap.String("account", how),
+	)
+	return nil
+}
+
+// randomToken returns 32 random bytes, base64url encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSSOService_LoginWithoutPasswords(t *testing.T) {
+	svc := NewSSOService(SSOConfig{Mode: SSOModeSAML}, &mockSSOStore{}, nil, nil, &fakeDirectoryRooms{}, nil, zap.NewNop())
+
+	if _, err := svc.Login(context.Background(), &LoginInput{Username: "alice", Password: "secret"}); err != ErrSSORequired {
+		t.Errorf("Expected ErrSSORequired, got %v", err)
+	}
+	if _, err := svc.ExchangeCode(context.Background(), "", &LoginInput{}); err != ErrSSOCodeInvalid {
+		t.Errorf("Expected ErrSSOCodeInvalid for an empty code, got %v", err)
+	}
+	if _, err := NewSSOService(SSOConfig{Mode: SSOModeLDAP}, nil, nil, nil, nil, nil, zap.NewNop()).SAMLLoginURL(context.Background()); err != ErrSSONotEnabled {
+		t.Errorf("Expected ErrSSONotEnabled outside SAML mode, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// MaxStickersPerPack caps the stickers in one pack
+const MaxStickersPerPack = 120
+
+// ErrInvalidSticker rejects sticker messages whose sticker cannot be sent
+var ErrInvalidSticker = apperrors.New(400, "貼圖不存在或已下架")
+
+// StickerService manages the curated sticker packs
+type StickerService struct {
+	stickerRepo StickerStore
+	auditor     *AuditService
+	logger      *zap.Logger
+}
+
+func NewStickerService(stickerRepo StickerStore, logger *zap.Logger) *StickerService {
+	return &StickerService{
+		stickerRepo: stickerRepo,
+		logger:      logger,
+	}
+}
+
+// SetAuditor sets the audit service that records sticker pack changes
+func (s *StickerService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// ListPacks lists the packs users can send stickers from; admins may
+// include inactive ones
+func (s *StickerService) ListPacks(ctx context.Context, includeInactive bool) ([]*model.StickerPack, error) {
+	packs, err := s.stickerRepo.ListPacks(ctx, includeInactive)
+	if err != nil {
+		s.logger.Error("Failed to list sticker packs", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return packs, nil
+}
+
+// GetPack retrieves a pack; inactive packs are only visible to admins
+func (s *StickerService) GetPack(ctx context.Context, id string, includeInactive bool) (*model.StickerPack, error) {
+	pack, err := s.stickerRepo.GetPack(ctx, id)
+	if err != nil {
+		if err == repository.ErrStickerPackNotFound {
+			return nil, apperrors.ErrNotFound
+		}
+		s.logger.Error("Failed to get sticker pack", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if !pack.IsActive && !includeInactive {
+		return nil, apperrors.ErrNotFound
+	}
+	return pack, nil
+}
+
+// StickerInput represents one sticker of a new pack
+type StickerInput struct {
+	Name     string
+	ImageURL string
+	Width    int
+	Height   int
+}
+
+// CreateStickerPackInput represents sticker pack creation input
+type CreateStickerPackInput struct {
+	Name        string
+	Description string
+	Stickers    []StickerInput // in display order
+	CreatedBy   string
+}
+
+// CreatePack adds an active sticker pack
+func (s *StickerService) CreatePack(ctx context.Context, input *CreateStickerPackInput) (*model.StickerPack, error) {
+	if len(input.Stickers) == 0 || len(input.Stickers) > MaxStickersPerPack {
+		return nil, apperrors.New(400, "貼圖包須包含 1 到 120 張貼圖")
+	}
+
+	pack := &model.StickerPack{
+		Name:        strings.TrimSpace(input.Name),
+		Description: strings.TrimSpace(input.Description),
+		IsActive:    true,
+		CreatedBy:   nullString(input.CreatedBy),
+	}
+	if pack.Name == "" {
+		return nil, apperrors.New(400, "貼圖包名稱不能為空")
+	}
+	for _, in := range input.Stickers {
+		if !validImageURL(in.ImageURL) {
+			return nil, apperrors.New(400, "無效的貼圖網址")
+		}
+		pack.Stickers = append(pack.Stickers, &model.Sticker{
+			Name:     strings.TrimSpace(in.Name),
+			ImageURL: in.ImageURL,
+			Width:    in.Width,
+			Height:   in.Height,
+		})
+	}
+
+	if err := s.stickerRepo.CreatePack(ctx, pack); err != nil {
+		s.logger.Error("Failed to create sticker pack", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    input.CreatedBy,
+		Action:     model.AuditActionStickerPackCreated,
+		TargetType: model.AuditTargetStickerPack,
+		TargetID:   pack.ID,
+		Metadata: map[string]interface{}{
+			"name":     pack.Name,
+			"stickers": len(pack.Stickers),
+		},
+	})
+	return pack, nil
+}
+
+// UpdateStickerPackInput represents a sticker pack update; nil fields are
+// left unchanged
+type UpdateStickerPackInput struct {
+	Name        *string
+	Description *string
+	IsActive    *bool
+	UpdatedBy   string
+}
+
+// UpdatePack renames a pack or takes it on or off sale. Messages already
+// sent keep showing stickers of an inactive pack.
+func (s *StickerService) UpdatePack(ctx context.Context, id string, input *UpdateStickerPackInput) (*model.StickerPack, error) {
+	pack, err := s.GetPack(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return nil, apperrors.New(400, "貼圖包名稱不能為空")
+		}
+		pack.Name = name
+	}
+	if input.Description != nil {
+		pack.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.IsActive != nil {
+		pack.IsActive = *input.IsActive
+	}
+
+	if err := s.stickerRepo.UpdatePack(ctx, pack); err != nil {
+		if err == repository.ErrStickerPackNotFound {
+			return nil, apperrors.ErrNotFound
+		}
+		s.logger.Error("Failed to update sticker pack", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    input.UpdatedBy,
+		Action:     model.AuditActionStickerPackUpdated,
+		TargetType: model.AuditTargetStickerPack,
+		TargetID:   pack.ID,
+		Metadata: map[string]interface{}{
+			"name":      pack.Name,
+			"is_active": pack.IsActive,
+		},
+	})
+	return pack, nil
+}
+
+// validImageURL accepts absolute http and https URLs
+func validImageURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// SetStickerRepository enables sticker messages
+func (s *MessageService) SetStickerRepository(repo StickerStore) {
+	s.stickers = repo
+}
+
+// checkSticker makes sure a sticker message names a sticker that can be
+// sent and returns it
+func (s *MessageService) checkSticker(ctx context.Context, id string) (*model.Sticker, error) {
+	if s.stickers == nil || !utils.ValidateUUID(id) {
+		return nil, ErrInvalidSticker
+	}
+
+	sticker, err := s.stickers.GetActiveSticker(ctx, id)
+	if err != nil {
+		if err == repository.ErrStickerNotFound {
+			return nil, ErrInvalidSticker
+		}
+		s.logger.Error("Failed to get sticker", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return sticker, nil
+}
+
+// attachStickers fills in the sticker of each sticker message. A failure
+// only costs clients the rendering, so it is logged and ignored.
+func (s *MessageService) attachStickers(ctx context.Context, messages ...*model.MessageWithUser) {
+	if s.stickers == nil {
+		return
+	}
+
+	var ids []string
+	for _, msg := range messages {
+		if msg.Type == model.MessageTypeSticker && !msg.IsDeleted && utils.ValidateUUID(msg.Content) {
+			ids = append(ids, msg.Content)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	stickers, err := s.stickers.ListStickersByIDs(ctx, ids)
+	if err != nil {
+		s.logger.Warn("Failed to load message stickers", zap.Error(err))
+		return
+	}
+	byID := make(map[string]*model.Sticker, len(stickers))
+	for _, sticker := range stickers {
+		byID[sticker.ID] = sticker
+	}
+	for _, msg := range messages {
+		if msg.Type == model.MessageTypeSticker {
+			msg.Sticker = byID[msg.Content]
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const testStickerID = "7f2c5d3e-8a61-4b0e-9c3f-2d9b6a1e4f50"
+
+func TestStickerService_CreatePack(t *testing.T) {
+	store := &mockStickerStore{CreatePackFunc: func(ctx context.Context, pack *model.StickerPack) error {
+		pack.ID = "pack-1"
+		return nil
+	}}
+	service := NewStickerService(store, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("Rejects invalid input", func(t *testing.T) {
+		inputs := []*CreateStickerPackInput{
+			{Name: "Cats"},
+			{Name: "  ", Stickers: []StickerInput{{Name: "a", ImageURL: "https://cdn.example.com/a.png"}}},
+			{Name: "Cats", Stickers: []StickerInput{{Name: "a", ImageURL: "javascript:alert(1)"}}},
+			{Name: "Cats", Stickers: []StickerInput{{Name: "a", ImageURL: "/a.png"}}},
+		}
+		for _, input := range inputs {
+			if _, err := service.CreatePack(ctx, input); err == nil {
+				t.Errorf("Expected error for %+v", input)
+			}
+		}
+		if store.Calls("CreatePack") != 0 {
+			t.Error("Expected nothing stored")
+		}
+	})
+
+	t.Run("Creates an active pack", func(t *testing.T) {
+		pack, err := service.CreatePack(ctx, &CreateStickerPackInput{
+			Name: " Cats ",
+			Stickers: []StickerInput{
+				{Name: "wave", ImageURL: "https://cdn.example.com/wave.png", Width: 128, Height: 128},
+				{Name: "nap", ImageURL: "https://cdn.example.com/nap.png"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create pack: %v", err)
+		}
+		if pack.Name != "Cats" || !pack.IsActive || len(pack.Stickers) != 2 {
+			t.Errorf("Unexpected pack %+v", pack)
+		}
+	})
+}
+
+func TestStickerService_GetPack_HidesInactive(t *testing.T) {
+	store := &mockStickerStore{GetPackFunc: func(ctx context.Context, id string) (*model.StickerPack, error) {
+		return &model.StickerPack{ID: id, Name: "Retired"}, nil
+	}}
+	service := NewStickerService(store, zap.NewNop())
+
+	if _, err := service.GetPack(context.Background(), "pack-1", false); err != apperrors.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := service.GetPack(context.Background(), "pack-1", true); err != nil {
+		t.Errorf("Expected admins to see inactive packs, got %v", err)
+	}
+}
+
+func TestStickerService_UpdatePack(t *testing.T) {
+	store := &mockStickerStore{GetPackFunc: func(ctx context.Context, id string) (*model.StickerPack, error) {
+		return &model.StickerPack{ID: id, Name: "Cats", IsActive: true}, nil
+	}}
+	service := NewStickerService(store, zap.NewNop())
+
+	inactive := false
+	pack, err := service.UpdatePack(context.Background(), "pack-1", &UpdateStickerPackInput{IsActive: &inactive})
+	if err != nil {
+		t.Fatalf("Failed to update pack: %v", err)
+	}
+	if pack.IsActive || pack.Name != "Cats" {
+		t.Errorf("Expected only availability changed, got %+v", pack)
+	}
+
+	blank := " "
+	if _, err := service.UpdatePack(context.Background(), "pack-1", &UpdateStickerPackInput{Name: &blank}); err == nil {
+		t.Error("Expected error for a blank name")
+	}
+}
+
+func TestMessageService_CheckSticker(t *testing.T) {
+	store := &mockStickerStore{GetActiveStickerFunc: func(ctx context.Context, id string) (*model.Sticker, error) {
+		return &model.Sticker{ID: id, ImageURL: "https://cdn.example.com/wave.png"}, nil
+	}}
+	service := NewMessageService(nil, nil, zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := service.checkSticker(ctx, testStickerID); err != ErrInvalidSticker {
+		t.Errorf("Expected ErrInvalidSticker without a sticker catalog, got %v", err)
+	}
+
+	service.SetStickerRepository(store)
+	if _, err := service.checkSticker(ctx, "not-a-uuid"); err != ErrInvalidSticker {
+		t.Errorf("Expected ErrInvalidSticker, got %v", err)
+	}
+	sticker, err := service.checkSticker(ctx, testStickerID)
+	if err != nil || sticker.ID != testStickerID {
+		t.Errorf("Expected sticker %s, got %+v, %v", testStickerID, sticker, err)
+	}
+
+	service.SetStickerRepository(&mockStickerStore{})
+	if _, err := service.checkSticker(ctx, testStickerID); err != ErrInvalidSticker {
+		t.Errorf("Expected ErrInvalidSticker for an inactive sticker, got %v", err)
+	}
+}
+
+func TestMessageService_AttachStickers(t *testing.T) {
+	var requested []string
+	store := &mockStickerStore{ListStickersByIDsFunc: func(ctx context.Context, ids []string) ([]*model.Sticker, error) {
+		requested = ids
+		return []*model.Sticker{{ID: testStickerID, Name: "wave"}}, nil
+	}}
+	service := NewMessageService(nil, nil, zap.NewNop())
+	service.SetStickerRepository(store)
+
+	sticker := &model.MessageWithUser{Message: model.Message{Type: model.MessageTypeSticker, Content: testStickerID}}
+	text := &model.MessageWithUser{Message: model.Message{Type: model.MessageTypeText, Content: testStickerID}}
+	service.attachStickers(context.Background(), sticker, text)
+
+	if len(requested) != 1 {
+		t.Errorf("Expected only the sticker message looked up, got %v", requested)
+	}
+	if sticker.Sticker == nil || sticker.Sticker.Name != "wave" {
+		t.Errorf("Expected sticker attached, got %+v", sticker.Sticker)
+	}
+	if text.Sticker != nil {
+		t.Error("Expected no sticker on a text message")
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// storageQuotaExceeded tells the client how much room is left
+func storageQuotaExceeded(quota, used, requested int64) error {
+	return apperrors.New(http.StatusForbidden, "儲存空間不足，無法上傳此檔案").
+		WithDetails(map[string]int64{"quota": quota, "used": used, "requested": requested})
+}
+
+// StorageQuotaService caps the bytes of images and files each user stores.
+// Resumable uploads in progress count with their declared size; avatars do
+// not count. Concurrent uploads may overshoot the quota by the size of the
+// uploads checked at the same time. A nil StorageQuotaService allows every
+// upload.
+type StorageQuotaService struct {
+	store  StorageUsageStore
+	quota  int64
+	logger *zap.Logger
+}
+
+// NewStorageQuotaService creates the service; quota is in bytes per user,
+// zero allows unlimited storage
+func NewStorageQuotaService(store StorageUsageStore, quota int64, logger *zap.Logger) *StorageQuotaService {
+	return &StorageQuotaService{
+		store:  store,
+		quota:  quota,
+		logger: logger,
+	}
+}
+
+// Quota returns the bytes each user may store, zero when unlimited
+func (s *StorageQuotaService) Quota() int64 {
+	if s == nil || s.quota < 0 {
+		return 0
+	}
+	return s.quota
+}
+
+// Usage returns the bytes a user stores by category
+func (s *StorageQuotaService) Usage(ctx context.Context, userID string) (*model.StorageUsage, error) {
+	if s == nil {
+		return &model.StorageUsage{}, nil
+	}
+	usage, err := s.store.GetStorageUsage(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get storage usage", zap.String("user_id", userID), zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return usage, nil
+}
+
+// Check returns a 403 error, with the quota, the bytes used and the bytes
+// requested as details, when storing size more bytes would exceed the
+// user's quota. Avatars are not checked.
+func (s *StorageQuotaService) Check(ctx context.Context, userID string, category model.UploadCategory, size int64) error {
+	if s.Quota() == 0 || category == model.UploadCategoryAvatar {
+		return nil
+	}
+	usage, err := s.Usage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if used := usage.Total(); used+size > s.quota {
+		return storageQuotaExceeded(s.quota, used, size)
+	}
+	return nil
+}
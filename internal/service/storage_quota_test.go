@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func TestStorageQuotaService_Check(t *testing.T) {
+	store := &mockStorageUsageStore{GetStorageUsageFunc: func(ctx context.Context, userID string) (*model.StorageUsage, error) {
+		return &model.StorageUsage{Images: 300, Files: 500, Pending: 100}, nil
+	}}
+	service := NewStorageQuotaService(store, 1000, zap.NewNop())
+	ctx := context.Background()
+
+	if err := service.Check(ctx, "alice", model.UploadCategoryFile, 100); err != nil {
+		t.Errorf("Expected an upload filling the quota exactly to pass, got %v", err)
+	}
+
+	err := service.Check(ctx, "alice", model.UploadCategoryImage, 101)
+	if apperrors.GetHTTPStatus(err) != 403 {
+		t.Fatalf("Expected a 403, got %v", err)
+	}
+	var appErr *apperrors.AppError
+	if !apperrors.As(err, &appErr) {
+		t.Fatalf("Expected an AppError, got %T", err)
+	}
+	details, _ := appErr.Details.(map[string]int64)
+	if details["quota"] != 1000 || details["used"] != 900 || details["requested"] != 101 {
+		t.Errorf("Unexpected details %v", appErr.Details)
+	}
+
+	if err := service.Check(ctx, "alice", model.UploadCategoryAvatar, 5000); err != nil {
+		t.Errorf("Expected avatars not to count, got %v", err)
+	}
+	if store.Calls("GetStorageUsage") != 2 {
+		t.Errorf("Expected usage looked up for images and files only, got %d", store.Calls("GetStorageUsage"))
+	}
+}
+
+func TestStorageQuotaService_Unlimited(t *testing.T) {
+	store := &mockStorageUsageStore{GetStorageUsageFunc: func(ctx context.Context, userID string) (*model.StorageUsage, error) {
+		return nil, errors.New("database down")
+	}}
+	ctx := context.Background()
+
+	if err := NewStorageQuotaService(store, 0, zap.NewNop()).Check(ctx, "alice", model.UploadCategoryFile, 1<<40); err != nil {
+		t.Errorf("Expected no quota to allow any upload, got %v", err)
+	}
+	var disabled *StorageQuotaService
+	if err := disabled.Check(ctx, "alice", model.UploadCategoryFile, 1<<40); err != nil {
+		t.Errorf("Expected a nil service to allow any upload, got %v", err)
+	}
+	if store.Calls("GetStorageUsage") != 0 {
+		t.Error("Expected no lookup without a quota")
+	}
+
+	if err := NewStorageQuotaService(store, 1000, zap.NewNop()).Check(ctx, "alice", model.UploadCategoryFile, 1); err != apperrors.ErrInternal {
+		t.Errorf("Expected ErrInternal when usage cannot be read, got %v", err)
+	}
+}
@@ -0,0 +1,424 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/search"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+// The stores below are the repository methods a service uses. Services
+// accept them instead of the concrete repositories so they can be unit
+// tested with the fakes in stores_mock_test.go, without a database.
+
+// UserLookup loads users by ID.
+// It is implemented by repository.UserRepository.
+type UserLookup interface {
+	GetByID(ctx context.Context, id string) (*model.User, error)
+}
+
+// AvatarStore sets user avatars.
+// It is implemented by repository.UserRepository.
+type AvatarStore interface {
+	UpdateAvatar(ctx context.Context, userID, avatarURL string) (string, error)
+}
+
+// UserBatchLookup loads users by ID, one or many at a time.
+// It is implemented by repository.UserRepository.
+type UserBatchLookup interface {
+	UserLookup
+	GetByIDs(ctx context.Context, ids []string) ([]*model.User, error)
+}
+
+// RoomStore stores rooms and their members.
+// It is implemented by repository.RoomRepository.
+type RoomStore interface {
+	Create(ctx context.Context, room *model.Room) error
+	GetByID(ctx context.Context, id string) (*model.Room, error)
+	GetByIDIncludingDeleted(ctx context.Context, id string) (*model.Room, error)
+	GetByIDWithMemberCount(ctx context.Context, id string) (*model.RoomWithMemberCount, error)
+	Update(ctx context.Context, room *model.Room) error
+	Archive(ctx context.Context, id string) (time.Time, error)
+	Unarchive(ctx context.Context, id string) error
+	ScheduleDeletion(ctx context.Context, id string, at time.Time) error
+	CancelDeletion(ctx context.Context, id string) error
+	SoftDeleteDue(ctx context.Context, now time.Time, limit int) ([]*model.Room, error)
+	ListPurgeable(ctx context.Context, before time.Time, limit int) ([]*model.Room, error)
+	Purge(ctx context.Context, id string) ([]string, error)
+	PurgeActivity(ctx context.Context, before time.Time) (int64, error)
+	ReconcileCounters(ctx context.Context, limit int) (int, []*model.RoomCounterDrift, error)
+	ListAll(ctx context.Context, roomType model.RoomType, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ListPublic(ctx context.Context, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ListArchivedByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ListMemberRoomsByIDs(ctx context.Context, userID string, roomIDs []string) ([]*model.RoomWithMemberCount, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ActivityHeatmap(ctx context.Context, roomID string, since time.Time, timeZone string) ([]*model.RoomActivityBucket, error)
+	AddMember(ctx context.Context, member *model.RoomMember) error
+	RemoveMember(ctx context.Context, roomID, userID string) error
+	GetMember(ctx context.Context, roomID, userID string) (*model.RoomMember, error)
+	IsMember(ctx context.Context, roomID, userID string) (bool, error)
+	ListMembers(ctx context.Context, roomID string, limit, offset int) ([]*model.RoomMemberWithUser, error)
+	ListMemberIDs(ctx context.Context, roomID string) ([]string, error)
+	UpdateMemberRole(ctx context.Context, roomID, userID string, role model.MemberRole) error
+	UpdateLastReadAt(ctx context.Context, roomID, userID string) (time.Time, error)
+	SetMute(ctx context.Context, roomID, userID string, until *time.Time, mutedBy string) (*model.RoomMember, error)
+	ClearMute(ctx context.Context, roomID, userID string) error
+	ExpireMutes(ctx context.Context, now time.Time, limit int) ([]*model.RoomMember, error)
+}
+
+// MemberLookup loads a user's membership of a room.
+// It is implemented by repository.RoomRepository.
+type MemberLookup interface {
+	GetMember(ctx context.Context, roomID, userID string) (*model.RoomMember, error)
+}
+
+// RoomMemberLookup loads the rooms messages are sent to and their members.
+// It is implemented by repository.RoomRepository.
+type RoomMemberLookup interface {
+	MemberLookup
+	GetByID(ctx context.Context, id string) (*model.Room, error)
+	ListMemberIDsByUsernames(ctx context.Context, roomID string, usernames []string) ([]string, error)
+}
+
+// MessageStore stores room messages.
+// It is implemented by repository.MessageRepository.
+type MessageStore interface {
+	Create(ctx context.Context, msg *model.Message) error
+	GetByID(ctx context.Context, id string) (*model.Message, error)
+	GetByIDWithUser(ctx context.Context, id string) (*model.MessageWithUser, error)
+	ListByIDsWithUser(ctx context.Context, roomID string, ids []string) ([]*model.MessageWithUser, error)
+	ListByRoomID(ctx context.Context, roomID string, limit, offset int) ([]*model.MessageWithUser, error)
+	ListByRoomIDBefore(ctx context.Context, roomID string, beforeID string, limit int) ([]*model.MessageWithUser, error)
+	ListByRoomIDSince(ctx context.Context, roomID string, sinceID string, limit int) ([]*model.MessageWithUser, error)
+	ListForExport(ctx context.Context, roomID, userID string, after *repository.ExportCursor, limit int) ([]*model.MessageWithUser, error)
+	Search(ctx context.Context, q *search.MessageQuery) ([]*model.MessageWithUser, error)
+	Update(ctx context.Context, id, content string) error
+	SoftDelete(ctx context.Context, id string) error
+	CountUnreadByRoomID(ctx context.Context, roomID, userID string) (int, error)
+	ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.Message, error)
+}
+
+// RoomMessageStore posts the system messages of a room and reads its feed.
+// It is implemented by repository.MessageRepository.
+type RoomMessageStore interface {
+	Create(ctx context.Context, msg *model.Message) error
+	GetByIDWithUser(ctx context.Context, id string) (*model.MessageWithUser, error)
+	ListRecentForFeed(ctx context.Context, roomID string, limit int) ([]*model.MessageWithUser, error)
+}
+
+// AuthUserStore stores the accounts users register and sign in with.
+// It is implemented by repository.UserRepository.
+type AuthUserStore interface {
+	Create(ctx context.Context, user *model.User) error
+	GetByID(ctx context.Context, id string) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	ExistsByUsername(ctx context.Context, username string) (bool, error)
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	Update(ctx context.Context, user *model.User) error
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	RehashPassword(ctx context.Context, userID, oldHash, newHash string) error
+	UpdateStatus(ctx context.Context, userID string, status model.UserStatus) error
+	UpdateDiscoverable(ctx context.Context, userID string, discoverable bool) error
+	UpdateLastSeenVisibility(ctx context.Context, userID string, visibility model.LastSeenVisibility) error
+	UpdateDigestFrequency(ctx context.Context, userID string, frequency model.DigestFrequency) error
+}
+
+// UserStore reads and updates user profiles.
+// It is implemented by repository.UserRepository.
+type UserStore interface {
+	GetByID(ctx context.Context, id string) (*model.User, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*model.User, error)
+	Update(ctx context.Context, user *model.User) error
+	UpdateStatus(ctx context.Context, userID string, status model.UserStatus) error
+	UpdateIsAdmin(ctx context.Context, userID string, isAdmin bool) error
+	GetOnlineUsers(ctx context.Context, limit, offset int) ([]*model.User, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]*model.User, error)
+	FindDiscoverable(ctx context.Context, viewerID string, hashes []string) ([]*model.User, error)
+}
+
+// BlockStore stores the users each user has blocked.
+// It is implemented by repository.BlockedUserRepository.
+type BlockStore interface {
+	Block(ctx context.Context, blockerID, blockedID string) error
+	Unblock(ctx context.Context, blockerID, blockedID string) error
+	IsBlocked(ctx context.Context, blockerID, blockedID string) (bool, error)
+	IsBlockedEither(ctx context.Context, userID1, userID2 string) (bool, error)
+	ListBlocked(ctx context.Context, blockerID string, limit, offset int) ([]*model.User, error)
+}
+
+// FriendshipStore stores friendships and friend requests.
+// It is implemented by repository.FriendshipRepository.
+type FriendshipStore interface {
+	Create(ctx context.Context, userID, friendID string) error
+	Accept(ctx context.Context, userID, friendID string) error
+	Reject(ctx context.Context, userID, friendID string) error
+	Remove(ctx context.Context, userID, friendID string) error
+	AreFriends(ctx context.Context, userID, friendID string) (bool, error)
+	ListFriends(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error)
+	ListPendingRequests(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error)
+	ListSentRequests(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error)
+	ListSuggestions(ctx context.Context, userID string, limit, offset int) ([]*model.FriendSuggestion, error)
+}
+
+// DirectMessageStore stores direct messages and conversation settings.
+// It is implemented by repository.DirectMessageRepository.
+type DirectMessageStore interface {
+	Create(ctx context.Context, msg *model.DirectMessage) error
+	CreateEncrypted(ctx context.Context, msg *model.DirectMessage, envelopes []*model.DMEnvelope) error
+	GetByID(ctx context.Context, id string) (*model.DirectMessage, error)
+	GetByIDWithUser(ctx context.Context, id string) (*model.DirectMessageWithUser, error)
+	UpdateContent(ctx context.Context, id, content string) error
+	DeleteForUser(ctx context.Context, messageID, userID string) error
+	ListConversation(ctx context.Context, userID1, userID2 string, limit, offset int) ([]*model.DirectMessageWithUser, error)
+	ListConversationForExport(ctx context.Context, userID, peerID string, after *repository.ExportCursor, limit int) ([]*model.DirectMessageWithUser, error)
+	ListConversations(ctx context.Context, userID string, archived bool, limit, offset int) ([]*model.Conversation, error)
+	ListEnvelopes(ctx context.Context, deviceID string, messageIDs []string) ([]*model.DMEnvelope, error)
+	MarkAsRead(ctx context.Context, senderID, receiverID string) error
+	CountUnread(ctx context.Context, userID string) (int, error)
+	CountUnreadFromUser(ctx context.Context, receiverID, senderID string) (int, error)
+	GetSettings(ctx context.Context, userID1, userID2 string) (*model.DirectConversationSettings, error)
+	SetConversationState(ctx context.Context, userID, otherUserID string, state model.ConversationState, on bool) (*model.ConversationSettings, error)
+	SetMessageTTL(ctx context.Context, userID1, userID2 string, ttlSeconds int, updatedBy string) (*model.DirectConversationSettings, error)
+	ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.DirectMessage, error)
+}
+
+// BanStore stores user bans.
+// It is implemented by repository.BanRepository.
+type BanStore interface {
+	Create(ctx context.Context, ban *model.UserBan) error
+	GetActiveByUserID(ctx context.Context, userID string) (*model.UserBan, error)
+	Revoke(ctx context.Context, userID, revokedBy string) error
+	ListActive(ctx context.Context, limit, offset int) ([]*model.UserBan, error)
+	ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.UserBan, error)
+}
+
+// AuditStore stores audit log entries.
+// It is implemented by repository.AuditRepository.
+type AuditStore interface {
+	Create(ctx context.Context, entry *model.AuditLog) error
+	List(ctx context.Context, filter *repository.AuditFilter, limit, offset int) ([]*model.AuditLog, error)
+}
+
+// IPBanStore stores IP bans.
+// It is implemented by repository.IPBanRepository.
+type IPBanStore interface {
+	Create(ctx context.Context, ban *model.IPBan) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, limit, offset int) ([]*model.IPBan, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// IPAccessRuleStore stores IP access rules.
+// It is implemented by repository.IPAccessRuleRepository.
+type IPAccessRuleStore interface {
+	Create(ctx context.Context, rule *model.IPAccessRule) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]*model.IPAccessRule, error)
+}
+
+// RoomGrantStore stores the room memberships group mapping granted.
+// It is implemented by repository.SSORepository.
+type RoomGrantStore interface {
+	ListRoomGrants(ctx context.Context, userID string) ([]string, error)
+	AddRoomGrant(ctx context.Context, userID, roomID string) error
+	DeleteRoomGrant(ctx context.Context, userID, roomID string) error
+}
+
+// SSOStore stores external identities and the room memberships group
+// mapping granted.
+// It is implemented by repository.SSORepository.
+type SSOStore interface {
+	RoomGrantStore
+	GetIdentity(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error)
+	CreateIdentity(ctx context.Context, identity *model.ExternalIdentity) error
+	TouchIdentity(ctx context.Context, provider, subject string) error
+}
+
+// SCIMStore stores the account state and groups SCIM provisioning manages.
+// It is implemented by repository.SCIMRepository.
+type SCIMStore interface {
+	ListUsers(ctx context.Context, filter repository.SCIMUserFilter, limit, offset int) ([]*model.SCIMUser, int, error)
+	GetUser(ctx context.Context, userID string) (*model.SCIMUser, error)
+	SaveUser(ctx context.Context, userID string, externalID sql.NullString, active bool) error
+	DeleteUser(ctx context.Context, userID string) error
+	IsInactive(ctx context.Context, userID string) (bool, error)
+	ListGroups(ctx context.Context, filter repository.SCIMGroupFilter, limit, offset int) ([]*model.SCIMGroup, int, error)
+	GetGroup(ctx context.Context, id string) (*model.SCIMGroup, error)
+	CreateGroup(ctx context.Context, group *model.SCIMGroup) error
+	UpdateGroup(ctx context.Context, group *model.SCIMGroup) error
+	DeleteGroup(ctx context.Context, id string) error
+	ListGroupMembers(ctx context.Context, groupID string) ([]*model.SCIMGroupMember, error)
+	AddGroupMembers(ctx context.Context, groupID string, userIDs []string) error
+	RemoveGroupMembers(ctx context.Context, groupID string, userIDs []string) error
+	ListUserGroups(ctx context.Context, userID string) ([]*model.SCIMGroup, error)
+}
+
+// JWTKeyStore stores rotated JWT signing keys.
+// It is implemented by repository.JWTKeyRepository.
+type JWTKeyStore interface {
+	Create(ctx context.Context, key *model.JWTSigningKey) error
+	List(ctx context.Context) ([]*model.JWTSigningKey, error)
+}
+
+// UploadSettingsStore stores the upload limit overrides.
+// It is implemented by repository.UploadSettingsRepository.
+type UploadSettingsStore interface {
+	Get(ctx context.Context) (*model.UploadSettings, error)
+	Update(ctx context.Context, settings *model.UploadSettings) error
+}
+
+// SpamStore stores what the spam sweep found.
+// It is implemented by repository.SpamRepository.
+type SpamStore interface {
+	ListCandidates(ctx context.Context, from, to time.Time, after *model.SpamCandidate, limit int) ([]*model.SpamCandidate, error)
+	CreateFlag(ctx context.Context, flag *model.SpamFlag) (bool, error)
+	ListFlags(ctx context.Context, limit, offset int) ([]*model.SpamFlagWithUser, error)
+	CreateRun(ctx context.Context, run *model.SpamSweepRun) error
+	GetStats(ctx context.Context) (*model.SpamStats, error)
+}
+
+// MessageEmbedStore stores the embeds generated for messages.
+// It is implemented by repository.MessageRepository.
+type MessageEmbedStore interface {
+	SetEmbeds(ctx context.Context, id, content string, embeds []byte) error
+}
+
+// StickerStore stores sticker packs and their stickers.
+// It is implemented by repository.StickerRepository.
+type StickerStore interface {
+	CreatePack(ctx context.Context, pack *model.StickerPack) error
+	UpdatePack(ctx context.Context, pack *model.StickerPack) error
+	GetPack(ctx context.Context, id string) (*model.StickerPack, error)
+	ListPacks(ctx context.Context, includeInactive bool) ([]*model.StickerPack, error)
+	GetActiveSticker(ctx context.Context, id string) (*model.Sticker, error)
+	ListStickersByIDs(ctx context.Context, ids []string) ([]*model.Sticker, error)
+}
+
+// AttachmentStore stores uploaded files and the messages attaching them.
+// It is implemented by repository.AttachmentRepository.
+type AttachmentStore interface {
+	CreateUpload(ctx context.Context, upload *model.Upload) error
+	ListUploadsByIDs(ctx context.Context, ids []string) ([]*model.Upload, error)
+	GetUploadByURL(ctx context.Context, url string) (*model.Upload, error)
+	AttachToMessage(ctx context.Context, messageID string, uploadIDs []string) ([]*model.Attachment, error)
+	AttachToDirectMessage(ctx context.Context, dmID string, uploadIDs []string) ([]*model.Attachment, error)
+	ListByMessageIDs(ctx context.Context, ids []string) ([]*model.Attachment, error)
+	ListByDirectMessageIDs(ctx context.Context, ids []string) ([]*model.Attachment, error)
+	ListOrphanedUploads(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error)
+	DeleteOrphanedUpload(ctx context.Context, id string) (bool, error)
+}
+
+// UploadScanStore stores antivirus scan results of uploads.
+// It is implemented by repository.AttachmentRepository.
+type UploadScanStore interface {
+	SetScanResult(ctx context.Context, id string, status model.ScanStatus, signature, filePath string) error
+	ListPendingScans(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error)
+	ListUploadAudience(ctx context.Context, uploadID string) ([]string, []string, error)
+}
+
+// StorageUsageStore sums the bytes users store.
+// It is implemented by repository.AttachmentRepository.
+type StorageUsageStore interface {
+	GetStorageUsage(ctx context.Context, userID string) (*model.StorageUsage, error)
+}
+
+// MessageFlagStore stores the messages moderation queued for review.
+// It is implemented by repository.MessageFlagRepository.
+type MessageFlagStore interface {
+	Create(ctx context.Context, flag *model.MessageFlag) error
+	ListPending(ctx context.Context, limit, offset int) ([]*model.MessageFlagWithMessage, error)
+	Review(ctx context.Context, id string, status model.MessageFlagStatus, note sql.NullString, reviewedBy string) (*model.MessageFlag, error)
+}
+
+// ReportStore stores user reports.
+// It is implemented by repository.ReportRepository.
+type ReportStore interface {
+	Create(ctx context.Context, report *model.Report) error
+	GetByID(ctx context.Context, id string) (*model.ReportWithUsers, error)
+	List(ctx context.Context, filter *repository.ReportFilter, limit, offset int) ([]*model.ReportWithUsers, error)
+	UpdateStatus(ctx context.Context, id string, from, to model.ReportStatus, note sql.NullString, handledBy string) (*model.Report, error)
+}
+
+// BlockLookup tells whether two users have blocked each other.
+// It is implemented by repository.BlockedUserRepository.
+type BlockLookup interface {
+	IsBlockedEither(ctx context.Context, userID1, userID2 string) (bool, error)
+}
+
+// GroupConversationStore stores group conversations, their participants
+// and messages.
+// It is implemented by repository.GroupConversationRepository.
+type GroupConversationStore interface {
+	Create(ctx context.Context, conv *model.GroupConversation, participantIDs []string) error
+	GetByID(ctx context.Context, id string) (*model.GroupConversation, error)
+	ListParticipants(ctx context.Context, id string) ([]*model.GroupParticipant, error)
+	AddParticipants(ctx context.Context, id string, userIDs []string) error
+	RemoveParticipant(ctx context.Context, id, userID string) error
+	CreateMessage(ctx context.Context, msg *model.GroupMessage) error
+	ListMessages(ctx context.Context, id string, limit, offset int) ([]*model.GroupMessageWithUser, error)
+	ListForUser(ctx context.Context, userID string, limit, offset int) ([]*model.GroupConversationSummary, error)
+	MarkAsRead(ctx context.Context, id, userID string) error
+	CountUnread(ctx context.Context, userID string) (int, error)
+}
+
+// DigestStore finds users due a digest email and their unread activity.
+// It is implemented by repository.DigestRepository.
+type DigestStore interface {
+	ListDue(ctx context.Context, asOf time.Time, limit int) ([]*model.User, error)
+	GetActivity(ctx context.Context, userID string, since time.Time) (*model.DigestActivity, error)
+	MarkSent(ctx context.Context, userID string, at time.Time) error
+}
+
+// ReadStateStore reads a user's read state across rooms and conversations.
+// It is implemented by repository.ReadStateRepository.
+type ReadStateStore interface {
+	List(ctx context.Context, userID string) (*model.ReadStates, error)
+}
+
+// Transactor runs fn as one unit of work; the repository calls made with
+// the context fn receives commit or roll back together.
+// It is implemented by repository.TxManager.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var (
+	_ Transactor          = (*repository.TxManager)(nil)
+	_ UserLookup          = (*repository.UserRepository)(nil)
+	_ AvatarStore         = (*repository.UserRepository)(nil)
+	_ UserBatchLookup     = (*repository.UserRepository)(nil)
+	_ AuthUserStore       = (*repository.UserRepository)(nil)
+	_ UserStore           = (*repository.UserRepository)(nil)
+	_ RoomStore           = (*repository.RoomRepository)(nil)
+	_ RoomMemberLookup    = (*repository.RoomRepository)(nil)
+	_ MessageStore        = (*repository.MessageRepository)(nil)
+	_ RoomMessageStore    = (*repository.MessageRepository)(nil)
+	_ BlockStore          = (*repository.BlockedUserRepository)(nil)
+	_ FriendshipStore     = (*repository.FriendshipRepository)(nil)
+	_ DirectMessageStore  = (*repository.DirectMessageRepository)(nil)
+	_ BanStore            = (*repository.BanRepository)(nil)
+	_ AuditStore          = (*repository.AuditRepository)(nil)
+	_ IPBanStore          = (*repository.IPBanRepository)(nil)
+	_ JWTKeyStore         = (*repository.JWTKeyRepository)(nil)
+	_ IPAccessRuleStore   = (*repository.IPAccessRuleRepository)(nil)
+	_ SSOStore            = (*repository.SSORepository)(nil)
+	_ SCIMStore           = (*repository.SCIMRepository)(nil)
+	_ UploadSettingsStore = (*repository.UploadSettingsRepository)(nil)
+	_ SpamStore           = (*repository.SpamRepository)(nil)
+	_ MessageEmbedStore   = (*repository.MessageRepository)(nil)
+	_ StickerStore        = (*repository.StickerRepository)(nil)
+	_ MessageFlagStore    = (*repository.MessageFlagRepository)(nil)
+	_ ReportStore         = (*repository.ReportRepository)(nil)
+	_ AttachmentStore     = (*repository.AttachmentRepository)(nil)
+	_ UploadScanStore     = (*repository.AttachmentRepository)(nil)
+	_ StorageUsageStore   = (*repository.AttachmentRepository)(nil)
+	_ BlockLookup         = (*repository.BlockedUserRepository)(nil)
+
+	_ GroupConversationStore = (*repository.GroupConversationRepository)(nil)
+	_ DigestStore            = (*repository.DigestRepository)(nil)
+	_ ReadStateStore         = (*repository.ReadStateRepository)(nil)
+)
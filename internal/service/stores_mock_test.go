@@ -0,0 +1,1883 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/search"
+	"github.com/go-demo/chat/internal/repository"
+)
+
+// Mock stores for unit tests. Each method calls the matching Func field
+// when it is set and returns zero values otherwise, and every call is
+// recorded by method name.
+
+type mockCalls struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (m *mockCalls) record(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, method)
+}
+
+// Calls counts the calls of a method
+func (m *mockCalls) Calls(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, c := range m.calls {
+		if c == method {
+			n++
+		}
+	}
+	return n
+}
+
+type mockUserLookup struct {
+	mockCalls
+	GetByIDFunc func(ctx context.Context, id string) (*model.User, error)
+}
+
+func (m *mockUserLookup) GetByID(ctx context.Context, id string) (*model.User, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+type mockBanStore struct {
+	mockCalls
+	CreateFunc            func(ctx context.Context, ban *model.UserBan) error
+	GetActiveByUserIDFunc func(ctx context.Context, userID string) (*model.UserBan, error)
+	RevokeFunc            func(ctx context.Context, userID, revokedBy string) error
+	ListActiveFunc        func(ctx context.Context, limit, offset int) ([]*model.UserBan, error)
+	ListByUserIDFunc      func(ctx context.Context, userID string, limit, offset int) ([]*model.UserBan, error)
+}
+
+func (m *mockBanStore) Create(ctx context.Context, ban *model.UserBan) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, ban)
+}
+
+func (m *mockBanStore) GetActiveByUserID(ctx context.Context, userID string) (*model.UserBan, error) {
+	m.record("GetActiveByUserID")
+	if m.GetActiveByUserIDFunc == nil {
+		return nil, repository.ErrBanNotFound
+	}
+	return m.GetActiveByUserIDFunc(ctx, userID)
+}
+
+func (m *mockBanStore) Revoke(ctx context.Context, userID, revokedBy string) error {
+	m.record("Revoke")
+	if m.RevokeFunc == nil {
+		return nil
+	}
+	return m.RevokeFunc(ctx, userID, revokedBy)
+}
+
+func (m *mockBanStore) ListActive(ctx context.Context, limit, offset int) ([]*model.UserBan, error) {
+	m.record("ListActive")
+	if m.ListActiveFunc == nil {
+		return nil, nil
+	}
+	return m.ListActiveFunc(ctx, limit, offset)
+}
+
+func (m *mockBanStore) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.UserBan, error) {
+	m.record("ListByUserID")
+	if m.ListByUserIDFunc == nil {
+		return nil, nil
+	}
+	return m.ListByUserIDFunc(ctx, userID, limit, offset)
+}
+
+type mockAuditStore struct {
+	mockCalls
+	CreateFunc func(ctx context.Context, entry *model.AuditLog) error
+	ListFunc   func(ctx context.Context, filter *repository.AuditFilter, limit, offset int) ([]*model.AuditLog, error)
+}
+
+func (m *mockAuditStore) Create(ctx context.Context, entry *model.AuditLog) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, entry)
+}
+
+func (m *mockAuditStore) List(ctx context.Context, filter *repository.AuditFilter, limit, offset int) ([]*model.AuditLog, error) {
+	m.record("List")
+	if m.ListFunc == nil {
+		return nil, nil
+	}
+	return m.ListFunc(ctx, filter, limit, offset)
+}
+
+type mockIPBanStore struct {
+	mockCalls
+	CreateFunc        func(ctx context.Context, ban *model.IPBan) error
+	DeleteFunc        func(ctx context.Context, id string) error
+	ListFunc          func(ctx context.Context, limit, offset int) ([]*model.IPBan, error)
+	DeleteExpiredFunc func(ctx context.Context, now time.Time) (int64, error)
+}
+
+func (m *mockIPBanStore) Create(ctx context.Context, ban *model.IPBan) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, ban)
+}
+
+func (m *mockIPBanStore) Delete(ctx context.Context, id string) error {
+	m.record("Delete")
+	if m.DeleteFunc == nil {
+		return nil
+	}
+	return m.DeleteFunc(ctx, id)
+}
+
+func (m *mockIPBanStore) List(ctx context.Context, limit, offset int) ([]*model.IPBan, error) {
+	m.record("List")
+	if m.ListFunc == nil {
+		return nil, nil
+	}
+	return m.ListFunc(ctx, limit, offset)
+}
+
+func (m *mockIPBanStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	m.record("DeleteExpired")
+	if m.DeleteExpiredFunc == nil {
+		return 0, nil
+	}
+	return m.DeleteExpiredFunc(ctx, now)
+}
+
+type mockIPAccessRuleStore struct {
+	mockCalls
+	CreateFunc func(ctx context.Context, rule *model.IPAccessRule) error
+	DeleteFunc func(ctx context.Context, id string) error
+	ListFunc   func(ctx context.Context) ([]*model.IPAccessRule, error)
+}
+
+func (m *mockIPAccessRuleStore) Create(ctx context.Context, rule *model.IPAccessRule) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, rule)
+}
+
+func (m *mockIPAccessRuleStore) Delete(ctx context.Context, id string) error {
+	m.record("Delete")
+	if m.DeleteFunc == nil {
+		return nil
+	}
+	return m.DeleteFunc(ctx, id)
+}
+
+func (m *mockIPAccessRuleStore) List(ctx context.Context) ([]*model.IPAccessRule, error) {
+	m.record("List")
+	if m.ListFunc == nil {
+		return nil, nil
+	}
+	return m.ListFunc(ctx)
+}
+
+type mockJWTKeyStore struct {
+	mockCalls
+	CreateFunc func(ctx context.Context, key *model.JWTSigningKey) error
+	ListFunc   func(ctx context.Context) ([]*model.JWTSigningKey, error)
+}
+
+func (m *mockJWTKeyStore) Create(ctx context.Context, key *model.JWTSigningKey) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, key)
+}
+
+func (m *mockJWTKeyStore) List(ctx context.Context) ([]*model.JWTSigningKey, error) {
+	m.record("List")
+	if m.ListFunc == nil {
+		return nil, nil
+	}
+	return m.ListFunc(ctx)
+}
+
+type mockSSOStore struct {
+	mockCalls
+	GetIdentityFunc     func(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error)
+	CreateIdentityFunc  func(ctx context.Context, identity *model.ExternalIdentity) error
+	ListRoomGrantsFunc  func(ctx context.Context, userID string) ([]string, error)
+	AddRoomGrantFunc    func(ctx context.Context, userID, roomID string) error
+	DeleteRoomGrantFunc func(ctx context.Context, userID, roomID string) error
+}
+
+func (m *mockSSOStore) GetIdentity(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error) {
+	m.record("GetIdentity")
+	if m.GetIdentityFunc == nil {
+		return nil, repository.ErrExternalIdentityNotFound
+	}
+	return m.GetIdentityFunc(ctx, provider, subject)
+}
+
+func (m *mockSSOStore) CreateIdentity(ctx context.Context, identity *model.ExternalIdentity) error {
+	m.record("CreateIdentity")
+	if m.CreateIdentityFunc == nil {
+		return nil
+	}
+	return m.CreateIdentityFunc(ctx, identity)
+}
+
+func (m *mockSSOStore) TouchIdentity(ctx context.Context, provider, subject string) error {
+	m.record("TouchIdentity")
+	return nil
+}
+
+func (m *mockSSOStore) ListRoomGrants(ctx context.Context, userID string) ([]string, error) {
+	m.record("ListRoomGrants")
+	if m.ListRoomGrantsFunc == nil {
+		return nil, nil
+	}
+	return m.ListRoomGrantsFunc(ctx, userID)
+}
+
+func (m *mockSSOStore) AddRoomGrant(ctx context.Context, userID, roomID string) error {
+	m.record("AddRoomGrant")
+	if m.AddRoomGrantFunc == nil {
+		return nil
+	}
+	return m.AddRoomGrantFunc(ctx, userID, roomID)
+}
+
+func (m *mockSSOStore) DeleteRoomGrant(ctx context.Context, userID, roomID string) error {
+	m.record("DeleteRoomGrant")
+	if m.DeleteRoomGrantFunc == nil {
+		return nil
+	}
+	return m.DeleteRoomGrantFunc(ctx, userID, roomID)
+}
+
+type mockSCIMStore struct {
+	mockCalls
+	ListUsersFunc          func(ctx context.Context, filter repository.SCIMUserFilter, limit, offset int) ([]*model.SCIMUser, int, error)
+	GetUserFunc            func(ctx context.Context, userID string) (*model.SCIMUser, error)
+	SaveUserFunc           func(ctx context.Context, userID string, externalID sql.NullString, active bool) error
+	DeleteUserFunc         func(ctx context.Context, userID string) error
+	IsInactiveFunc         func(ctx context.Context, userID string) (bool, error)
+	ListGroupsFunc         func(ctx context.Context, filter repository.SCIMGroupFilter, limit, offset int) ([]*model.SCIMGroup, int, error)
+	GetGroupFunc           func(ctx context.Context, id string) (*model.SCIMGroup, error)
+	CreateGroupFunc        func(ctx context.Context, group *model.SCIMGroup) error
+	UpdateGroupFunc        func(ctx context.Context, group *model.SCIMGroup) error
+	DeleteGroupFunc        func(ctx context.Context, id string) error
+	ListGroupMembersFunc   func(ctx context.Context, groupID string) ([]*model.SCIMGroupMember, error)
+	AddGroupMembersFunc    func(ctx context.Context, groupID string, userIDs []string) error
+	RemoveGroupMembersFunc func(ctx context.Context, groupID string, userIDs []string) error
+	ListUserGroupsFunc     func(ctx context.Context, userID string) ([]*model.SCIMGroup, error)
+}
+
+func (m *mockSCIMStore) ListUsers(ctx context.Context, filter repository.SCIMUserFilter, limit, offset int) ([]*model.SCIMUser, int, error) {
+	m.record("ListUsers")
+	if m.ListUsersFunc == nil {
+		return nil, 0, nil
+	}
+	return m.ListUsersFunc(ctx, filter, limit, offset)
+}
+
+func (m *mockSCIMStore) GetUser(ctx context.Context, userID string) (*model.SCIMUser, error) {
+	m.record("GetUser")
+	if m.GetUserFunc == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return m.GetUserFunc(ctx, userID)
+}
+
+func (m *mockSCIMStore) SaveUser(ctx context.Context, userID string, externalID sql.NullString, active bool) error {
+	m.record("SaveUser")
+	if m.SaveUserFunc == nil {
+		return nil
+	}
+	return m.SaveUserFunc(ctx, userID, externalID, active)
+}
+
+func (m *mockSCIMStore) DeleteUser(ctx context.Context, userID string) error {
+	m.record("DeleteUser")
+	if m.DeleteUserFunc == nil {
+		return nil
+	}
+	return m.DeleteUserFunc(ctx, userID)
+}
+
+func (m *mockSCIMStore) IsInactive(ctx context.Context, userID string) (bool, error) {
+	m.record("IsInactive")
+	if m.IsInactiveFunc == nil {
+		return false, nil
+	}
+	return m.IsInactiveFunc(ctx, userID)
+}
+
+func (m *mockSCIMStore) ListGroups(ctx context.Context, filter repository.SCIMGroupFilter, limit, offset int) ([]*model.SCIMGroup, int, error) {
+	m.record("ListGroups")
+	if m.ListGroupsFunc == nil {
+		return nil, 0, nil
+	}
+	return m.ListGroupsFunc(ctx, filter, limit, offset)
+}
+
+func (m *mockSCIMStore) GetGroup(ctx context.Context, id string) (*model.SCIMGroup, error) {
+	m.record("GetGroup")
+	if m.GetGroupFunc == nil {
+		return nil, repository.ErrSCIMGroupNotFound
+	}
+	return m.GetGroupFunc(ctx, id)
+}
+
+func (m *mockSCIMStore) CreateGroup(ctx context.Context, group *model.SCIMGroup) error {
+	m.record("CreateGroup")
+	if m.CreateGroupFunc == nil {
+		return nil
+	}
+	return m.CreateGroupFunc(ctx, group)
+}
+
+func (m *mockSCIMStore) UpdateGroup(ctx context.Context, group *model.SCIMGroup) error {
+	m.record("UpdateGroup")
+	if m.UpdateGroupFunc == nil {
+		return nil
+	}
+	return m.UpdateGroupFunc(ctx, group)
+}
+
+func (m *mockSCIMStore) DeleteGroup(ctx context.Context, id string) error {
+	m.record("DeleteGroup")
+	if m.DeleteGroupFunc == nil {
+		return nil
+	}
+	return m.DeleteGroupFunc(ctx, id)
+}
+
+func (m *mockSCIMStore) ListGroupMembers(ctx context.Context, groupID string) ([]*model.SCIMGroupMember, error) {
+	m.record("ListGroupMembers")
+	if m.ListGroupMembersFunc == nil {
+		return nil, nil
+	}
+	return m.ListGroupMembersFunc(ctx, groupID)
+}
+
+func (m *mockSCIMStore) AddGroupMembers(ctx context.Context, groupID string, userIDs []string) error {
+	m.record("AddGroupMembers")
+	if m.AddGroupMembersFunc == nil {
+		return nil
+	}
+	return m.AddGroupMembersFunc(ctx, groupID, userIDs)
+}
+
+func (m *mockSCIMStore) RemoveGroupMembers(ctx context.Context, groupID string, userIDs []string) error {
+	m.record("RemoveGroupMembers")
+	if m.RemoveGroupMembersFunc == nil {
+		return nil
+	}
+	return m.RemoveGroupMembersFunc(ctx, groupID, userIDs)
+}
+
+func (m *mockSCIMStore) ListUserGroups(ctx context.Context, userID string) ([]*model.SCIMGroup, error) {
+	m.record("ListUserGroups")
+	if m.ListUserGroupsFunc == nil {
+		return nil, nil
+	}
+	return m.ListUserGroupsFunc(ctx, userID)
+}
+
+type mockUploadSettingsStore struct {
+	mockCalls
+	GetFunc    func(ctx context.Context) (*model.UploadSettings, error)
+	UpdateFunc func(ctx context.Context, settings *model.UploadSettings) error
+}
+
+func (m *mockUploadSettingsStore) Get(ctx context.Context) (*model.UploadSettings, error) {
+	m.record("Get")
+	if m.GetFunc == nil {
+		return &model.UploadSettings{}, nil
+	}
+	return m.GetFunc(ctx)
+}
+
+func (m *mockUploadSettingsStore) Update(ctx context.Context, settings *model.UploadSettings) error {
+	m.record("Update")
+	if m.UpdateFunc == nil {
+		return nil
+	}
+	return m.UpdateFunc(ctx, settings)
+}
+
+type mockSpamStore struct {
+	mockCalls
+	ListCandidatesFunc func(ctx context.Context, from, to time.Time, after *model.SpamCandidate, limit int) ([]*model.SpamCandidate, error)
+	CreateFlagFunc     func(ctx context.Context, flag *model.SpamFlag) (bool, error)
+	ListFlagsFunc      func(ctx context.Context, limit, offset int) ([]*model.SpamFlagWithUser, error)
+	CreateRunFunc      func(ctx context.Context, run *model.SpamSweepRun) error
+	GetStatsFunc       func(ctx context.Context) (*model.SpamStats, error)
+}
+
+func (m *mockSpamStore) ListCandidates(ctx context.Context, from, to time.Time, after *model.SpamCandidate, limit int) ([]*model.SpamCandidate, error) {
+	m.record("ListCandidates")
+	if m.ListCandidatesFunc == nil {
+		return nil, nil
+	}
+	return m.ListCandidatesFunc(ctx, from, to, after, limit)
+}
+
+func (m *mockSpamStore) CreateFlag(ctx context.Context, flag *model.SpamFlag) (bool, error) {
+	m.record("CreateFlag")
+	if m.CreateFlagFunc == nil {
+		return true, nil
+	}
+	return m.CreateFlagFunc(ctx, flag)
+}
+
+func (m *mockSpamStore) ListFlags(ctx context.Context, limit, offset int) ([]*model.SpamFlagWithUser, error) {
+	m.record("ListFlags")
+	if m.ListFlagsFunc == nil {
+		return nil, nil
+	}
+	return m.ListFlagsFunc(ctx, limit, offset)
+}
+
+func (m *mockSpamStore) CreateRun(ctx context.Context, run *model.SpamSweepRun) error {
+	m.record("CreateRun")
+	if m.CreateRunFunc == nil {
+		return nil
+	}
+	return m.CreateRunFunc(ctx, run)
+}
+
+func (m *mockSpamStore) GetStats(ctx context.Context) (*model.SpamStats, error) {
+	m.record("GetStats")
+	if m.GetStatsFunc == nil {
+		return &model.SpamStats{}, nil
+	}
+	return m.GetStatsFunc(ctx)
+}
+
+type mockMessageEmbedStore struct {
+	mockCalls
+	SetEmbedsFunc func(ctx context.Context, id, content string, embeds []byte) error
+}
+
+func (m *mockMessageEmbedStore) SetEmbeds(ctx context.Context, id, content string, embeds []byte) error {
+	m.record("SetEmbeds")
+	if m.SetEmbedsFunc == nil {
+		return nil
+	}
+	return m.SetEmbedsFunc(ctx, id, content, embeds)
+}
+
+type mockStickerStore struct {
+	mockCalls
+	CreatePackFunc        func(ctx context.Context, pack *model.StickerPack) error
+	UpdatePackFunc        func(ctx context.Context, pack *model.StickerPack) error
+	GetPackFunc           func(ctx context.Context, id string) (*model.StickerPack, error)
+	ListPacksFunc         func(ctx context.Context, includeInactive bool) ([]*model.StickerPack, error)
+	GetActiveStickerFunc  func(ctx context.Context, id string) (*model.Sticker, error)
+	ListStickersByIDsFunc func(ctx context.Context, ids []string) ([]*model.Sticker, error)
+}
+
+func (m *mockStickerStore) CreatePack(ctx context.Context, pack *model.StickerPack) error {
+	m.record("CreatePack")
+	if m.CreatePackFunc == nil {
+		return nil
+	}
+	return m.CreatePackFunc(ctx, pack)
+}
+
+func (m *mockStickerStore) UpdatePack(ctx context.Context, pack *model.StickerPack) error {
+	m.record("UpdatePack")
+	if m.UpdatePackFunc == nil {
+		return nil
+	}
+	return m.UpdatePackFunc(ctx, pack)
+}
+
+func (m *mockStickerStore) GetPack(ctx context.Context, id string) (*model.StickerPack, error) {
+	m.record("GetPack")
+	if m.GetPackFunc == nil {
+		return nil, repository.ErrStickerPackNotFound
+	}
+	return m.GetPackFunc(ctx, id)
+}
+
+func (m *mockStickerStore) ListPacks(ctx context.Context, includeInactive bool) ([]*model.StickerPack, error) {
+	m.record("ListPacks")
+	if m.ListPacksFunc == nil {
+		return nil, nil
+	}
+	return m.ListPacksFunc(ctx, includeInactive)
+}
+
+func (m *mockStickerStore) GetActiveSticker(ctx context.Context, id string) (*model.Sticker, error) {
+	m.record("GetActiveSticker")
+	if m.GetActiveStickerFunc == nil {
+		return nil, repository.ErrStickerNotFound
+	}
+	return m.GetActiveStickerFunc(ctx, id)
+}
+
+func (m *mockStickerStore) ListStickersByIDs(ctx context.Context, ids []string) ([]*model.Sticker, error) {
+	m.record("ListStickersByIDs")
+	if m.ListStickersByIDsFunc == nil {
+		return nil, nil
+	}
+	return m.ListStickersByIDsFunc(ctx, ids)
+}
+
+type mockMessageFlagStore struct {
+	mockCalls
+	CreateFunc      func(ctx context.Context, flag *model.MessageFlag) error
+	ListPendingFunc func(ctx context.Context, limit, offset int) ([]*model.MessageFlagWithMessage, error)
+	ReviewFunc      func(ctx context.Context, id string, status model.MessageFlagStatus, note sql.NullString, reviewedBy string) (*model.MessageFlag, error)
+}
+
+func (m *mockMessageFlagStore) Create(ctx context.Context, flag *model.MessageFlag) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, flag)
+}
+
+func (m *mockMessageFlagStore) ListPending(ctx context.Context, limit, offset int) ([]*model.MessageFlagWithMessage, error) {
+	m.record("ListPending")
+	if m.ListPendingFunc == nil {
+		return nil, nil
+	}
+	return m.ListPendingFunc(ctx, limit, offset)
+}
+
+func (m *mockMessageFlagStore) Review(ctx context.Context, id string, status model.MessageFlagStatus, note sql.NullString, reviewedBy string) (*model.MessageFlag, error) {
+	m.record("Review")
+	if m.ReviewFunc == nil {
+		return nil, repository.ErrMessageFlagNotFound
+	}
+	return m.ReviewFunc(ctx, id, status, note, reviewedBy)
+}
+
+type mockReportStore struct {
+	mockCalls
+	CreateFunc       func(ctx context.Context, report *model.Report) error
+	GetByIDFunc      func(ctx context.Context, id string) (*model.ReportWithUsers, error)
+	ListFunc         func(ctx context.Context, filter *repository.ReportFilter, limit, offset int) ([]*model.ReportWithUsers, error)
+	UpdateStatusFunc func(ctx context.Context, id string, from, to model.ReportStatus, note sql.NullString, handledBy string) (*model.Report, error)
+}
+
+func (m *mockReportStore) Create(ctx context.Context, report *model.Report) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, report)
+}
+
+func (m *mockReportStore) GetByID(ctx context.Context, id string) (*model.ReportWithUsers, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrReportNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockReportStore) List(ctx context.Context, filter *repository.ReportFilter, limit, offset int) ([]*model.ReportWithUsers, error) {
+	m.record("List")
+	if m.ListFunc == nil {
+		return nil, nil
+	}
+	return m.ListFunc(ctx, filter, limit, offset)
+}
+
+func (m *mockReportStore) UpdateStatus(ctx context.Context, id string, from, to model.ReportStatus, note sql.NullString, handledBy string) (*model.Report, error) {
+	m.record("UpdateStatus")
+	if m.UpdateStatusFunc == nil {
+		return nil, repository.ErrReportNotFound
+	}
+	return m.UpdateStatusFunc(ctx, id, from, to, note, handledBy)
+}
+
+type mockAttachmentStore struct {
+	mockCalls
+	CreateUploadFunc           func(ctx context.Context, upload *model.Upload) error
+	ListUploadsByIDsFunc       func(ctx context.Context, ids []string) ([]*model.Upload, error)
+	GetUploadByURLFunc         func(ctx context.Context, url string) (*model.Upload, error)
+	AttachToMessageFunc        func(ctx context.Context, messageID string, uploadIDs []string) ([]*model.Attachment, error)
+	AttachToDirectMessageFunc  func(ctx context.Context, dmID string, uploadIDs []string) ([]*model.Attachment, error)
+	ListByMessageIDsFunc       func(ctx context.Context, ids []string) ([]*model.Attachment, error)
+	ListByDirectMessageIDsFunc func(ctx context.Context, ids []string) ([]*model.Attachment, error)
+	ListOrphanedUploadsFunc    func(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error)
+	DeleteOrphanedUploadFunc   func(ctx context.Context, id string) (bool, error)
+}
+
+func (m *mockAttachmentStore) CreateUpload(ctx context.Context, upload *model.Upload) error {
+	m.record("CreateUpload")
+	if m.CreateUploadFunc == nil {
+		return nil
+	}
+	return m.CreateUploadFunc(ctx, upload)
+}
+
+func (m *mockAttachmentStore) ListUploadsByIDs(ctx context.Context, ids []string) ([]*model.Upload, error) {
+	m.record("ListUploadsByIDs")
+	if m.ListUploadsByIDsFunc == nil {
+		return nil, nil
+	}
+	return m.ListUploadsByIDsFunc(ctx, ids)
+}
+
+func (m *mockAttachmentStore) GetUploadByURL(ctx context.Context, url string) (*model.Upload, error) {
+	m.record("GetUploadByURL")
+	if m.GetUploadByURLFunc == nil {
+		return nil, repository.ErrUploadNotFound
+	}
+	return m.GetUploadByURLFunc(ctx, url)
+}
+
+func (m *mockAttachmentStore) AttachToMessage(ctx context.Context, messageID string, uploadIDs []string) ([]*model.Attachment, error) {
+	m.record("AttachToMessage")
+	if m.AttachToMessageFunc == nil {
+		return nil, nil
+	}
+	return m.AttachToMessageFunc(ctx, messageID, uploadIDs)
+}
+
+func (m *mockAttachmentStore) AttachToDirectMessage(ctx context.Context, dmID string, uploadIDs []string) ([]*model.Attachment, error) {
+	m.record("AttachToDirectMessage")
+	if m.AttachToDirectMessageFunc == nil {
+		return nil, nil
+	}
+	return m.AttachToDirectMessageFunc(ctx, dmID, uploadIDs)
+}
+
+func (m *mockAttachmentStore) ListByMessageIDs(ctx context.Context, ids []string) ([]*model.Attachment, error) {
+	m.record("ListByMessageIDs")
+	if m.ListByMessageIDsFunc == nil {
+		return nil, nil
+	}
+	return m.ListByMessageIDsFunc(ctx, ids)
+}
+
+func (m *mockAttachmentStore) ListByDirectMessageIDs(ctx context.Context, ids []string) ([]*model.Attachment, error) {
+	m.record("ListByDirectMessageIDs")
+	if m.ListByDirectMessageIDsFunc == nil {
+		return nil, nil
+	}
+	return m.ListByDirectMessageIDsFunc(ctx, ids)
+}
+
+func (m *mockAttachmentStore) ListOrphanedUploads(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error) {
+	m.record("ListOrphanedUploads")
+	if m.ListOrphanedUploadsFunc == nil {
+		return nil, nil
+	}
+	return m.ListOrphanedUploadsFunc(ctx, before, limit)
+}
+
+func (m *mockAttachmentStore) DeleteOrphanedUpload(ctx context.Context, id string) (bool, error) {
+	m.record("DeleteOrphanedUpload")
+	if m.DeleteOrphanedUploadFunc == nil {
+		return false, nil
+	}
+	return m.DeleteOrphanedUploadFunc(ctx, id)
+}
+
+type mockUploadScanStore struct {
+	mockCalls
+	SetScanResultFunc      func(ctx context.Context, id string, status model.ScanStatus, signature, filePath string) error
+	ListPendingScansFunc   func(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error)
+	ListUploadAudienceFunc func(ctx context.Context, uploadID string) ([]string, []string, error)
+}
+
+func (m *mockUploadScanStore) SetScanResult(ctx context.Context, id string, status model.ScanStatus, signature, filePath string) error {
+	m.record("SetScanResult")
+	if m.SetScanResultFunc == nil {
+		return nil
+	}
+	return m.SetScanResultFunc(ctx, id, status, signature, filePath)
+}
+
+func (m *mockUploadScanStore) ListPendingScans(ctx context.Context, before time.Time, limit int) ([]*model.Upload, error) {
+	m.record("ListPendingScans")
+	if m.ListPendingScansFunc == nil {
+		return nil, nil
+	}
+	return m.ListPendingScansFunc(ctx, before, limit)
+}
+
+func (m *mockUploadScanStore) ListUploadAudience(ctx context.Context, uploadID string) ([]string, []string, error) {
+	m.record("ListUploadAudience")
+	if m.ListUploadAudienceFunc == nil {
+		return nil, nil, nil
+	}
+	return m.ListUploadAudienceFunc(ctx, uploadID)
+}
+
+type mockStorageUsageStore struct {
+	mockCalls
+	GetStorageUsageFunc func(ctx context.Context, userID string) (*model.StorageUsage, error)
+}
+
+func (m *mockStorageUsageStore) GetStorageUsage(ctx context.Context, userID string) (*model.StorageUsage, error) {
+	m.record("GetStorageUsage")
+	if m.GetStorageUsageFunc == nil {
+		return &model.StorageUsage{}, nil
+	}
+	return m.GetStorageUsageFunc(ctx, userID)
+}
+
+type mockAvatarStore struct {
+	mockCalls
+	UpdateAvatarFunc func(ctx context.Context, userID, avatarURL string) (string, error)
+}
+
+func (m *mockAvatarStore) UpdateAvatar(ctx context.Context, userID, avatarURL string) (string, error) {
+	m.record("UpdateAvatar")
+	if m.UpdateAvatarFunc == nil {
+		return "", nil
+	}
+	return m.UpdateAvatarFunc(ctx, userID, avatarURL)
+}
+
+type mockBlockLookup struct {
+	mockCalls
+	IsBlockedEitherFunc func(ctx context.Context, userID1, userID2 string) (bool, error)
+}
+
+func (m *mockBlockLookup) IsBlockedEither(ctx context.Context, userID1, userID2 string) (bool, error) {
+	m.record("IsBlockedEither")
+	if m.IsBlockedEitherFunc == nil {
+		return false, nil
+	}
+	return m.IsBlockedEitherFunc(ctx, userID1, userID2)
+}
+
+type mockGroupConversationStore struct {
+	mockCalls
+	CreateFunc            func(ctx context.Context, conv *model.GroupConversation, participantIDs []string) error
+	GetByIDFunc           func(ctx context.Context, id string) (*model.GroupConversation, error)
+	ListParticipantsFunc  func(ctx context.Context, id string) ([]*model.GroupParticipant, error)
+	AddParticipantsFunc   func(ctx context.Context, id string, userIDs []string) error
+	RemoveParticipantFunc func(ctx context.Context, id, userID string) error
+	CreateMessageFunc     func(ctx context.Context, msg *model.GroupMessage) error
+	ListMessagesFunc      func(ctx context.Context, id string, limit, offset int) ([]*model.GroupMessageWithUser, error)
+	ListForUserFunc       func(ctx context.Context, userID string, limit, offset int) ([]*model.GroupConversationSummary, error)
+	MarkAsReadFunc        func(ctx context.Context, id, userID string) error
+	CountUnreadFunc       func(ctx context.Context, userID string) (int, error)
+}
+
+func (m *mockGroupConversationStore) Create(ctx context.Context, conv *model.GroupConversation, participantIDs []string) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, conv, participantIDs)
+}
+
+func (m *mockGroupConversationStore) GetByID(ctx context.Context, id string) (*model.GroupConversation, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrGroupConversationNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockGroupConversationStore) ListParticipants(ctx context.Context, id string) ([]*model.GroupParticipant, error) {
+	m.record("ListParticipants")
+	if m.ListParticipantsFunc == nil {
+		return nil, nil
+	}
+	return m.ListParticipantsFunc(ctx, id)
+}
+
+func (m *mockGroupConversationStore) AddParticipants(ctx context.Context, id string, userIDs []string) error {
+	m.record("AddParticipants")
+	if m.AddParticipantsFunc == nil {
+		return nil
+	}
+	return m.AddParticipantsFunc(ctx, id, userIDs)
+}
+
+func (m *mockGroupConversationStore) RemoveParticipant(ctx context.Context, id, userID string) error {
+	m.record("RemoveParticipant")
+	if m.RemoveParticipantFunc == nil {
+		return nil
+	}
+	return m.RemoveParticipantFunc(ctx, id, userID)
+}
+
+func (m *mockGroupConversationStore) CreateMessage(ctx context.Context, msg *model.GroupMessage) error {
+	m.record("CreateMessage")
+	if m.CreateMessageFunc == nil {
+		return nil
+	}
+	return m.CreateMessageFunc(ctx, msg)
+}
+
+func (m *mockGroupConversationStore) ListMessages(ctx context.Context, id string, limit, offset int) ([]*model.GroupMessageWithUser, error) {
+	m.record("ListMessages")
+	if m.ListMessagesFunc == nil {
+		return nil, nil
+	}
+	return m.ListMessagesFunc(ctx, id, limit, offset)
+}
+
+func (m *mockGroupConversationStore) ListForUser(ctx context.Context, userID string, limit, offset int) ([]*model.GroupConversationSummary, error) {
+	m.record("ListForUser")
+	if m.ListForUserFunc == nil {
+		return nil, nil
+	}
+	return m.ListForUserFunc(ctx, userID, limit, offset)
+}
+
+func (m *mockGroupConversationStore) MarkAsRead(ctx context.Context, id, userID string) error {
+	m.record("MarkAsRead")
+	if m.MarkAsReadFunc == nil {
+		return nil
+	}
+	return m.MarkAsReadFunc(ctx, id, userID)
+}
+
+func (m *mockGroupConversationStore) CountUnread(ctx context.Context, userID string) (int, error) {
+	m.record("CountUnread")
+	if m.CountUnreadFunc == nil {
+		return 0, nil
+	}
+	return m.CountUnreadFunc(ctx, userID)
+}
+
+type mockDigestStore struct {
+	mockCalls
+	ListDueFunc     func(ctx context.Context, asOf time.Time, limit int) ([]*model.User, error)
+	GetActivityFunc func(ctx context.Context, userID string, since time.Time) (*model.DigestActivity, error)
+	MarkSentFunc    func(ctx context.Context, userID string, at time.Time) error
+}
+
+func (m *mockDigestStore) ListDue(ctx context.Context, asOf time.Time, limit int) ([]*model.User, error) {
+	m.record("ListDue")
+	if m.ListDueFunc == nil {
+		return nil, nil
+	}
+	return m.ListDueFunc(ctx, asOf, limit)
+}
+
+func (m *mockDigestStore) GetActivity(ctx context.Context, userID string, since time.Time) (*model.DigestActivity, error) {
+	m.record("GetActivity")
+	if m.GetActivityFunc == nil {
+		return &model.DigestActivity{}, nil
+	}
+	return m.GetActivityFunc(ctx, userID, since)
+}
+
+func (m *mockDigestStore) MarkSent(ctx context.Context, userID string, at time.Time) error {
+	m.record("MarkSent")
+	if m.MarkSentFunc == nil {
+		return nil
+	}
+	return m.MarkSentFunc(ctx, userID, at)
+}
+
+type mockReadStateStore struct {
+	mockCalls
+	ListFunc func(ctx context.Context, userID string) (*model.ReadStates, error)
+}
+
+func (m *mockReadStateStore) List(ctx context.Context, userID string) (*model.ReadStates, error) {
+	m.record("List")
+	if m.ListFunc == nil {
+		return &model.ReadStates{}, nil
+	}
+	return m.ListFunc(ctx, userID)
+}
+
+type mockRoomStore struct {
+	mockCalls
+	CreateFunc                  func(ctx context.Context, room *model.Room) error
+	GetByIDFunc                 func(ctx context.Context, id string) (*model.Room, error)
+	GetByIDIncludingDeletedFunc func(ctx context.Context, id string) (*model.Room, error)
+	GetByIDWithMemberCountFunc  func(ctx context.Context, id string) (*model.RoomWithMemberCount, error)
+	UpdateFunc                  func(ctx context.Context, room *model.Room) error
+	ArchiveFunc                 func(ctx context.Context, id string) (time.Time, error)
+	UnarchiveFunc               func(ctx context.Context, id string) error
+	ScheduleDeletionFunc        func(ctx context.Context, id string, at time.Time) error
+	CancelDeletionFunc          func(ctx context.Context, id string) error
+	SoftDeleteDueFunc           func(ctx context.Context, now time.Time, limit int) ([]*model.Room, error)
+	ListPurgeableFunc           func(ctx context.Context, before time.Time, limit int) ([]*model.Room, error)
+	PurgeFunc                   func(ctx context.Context, id string) ([]string, error)
+	PurgeActivityFunc           func(ctx context.Context, before time.Time) (int64, error)
+	ReconcileCountersFunc       func(ctx context.Context, limit int) (int, []*model.RoomCounterDrift, error)
+	ListAllFunc                 func(ctx context.Context, roomType model.RoomType, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ListPublicFunc              func(ctx context.Context, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ListByUserIDFunc            func(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ListArchivedByUserIDFunc    func(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ListMemberRoomsByIDsFunc    func(ctx context.Context, userID string, roomIDs []string) ([]*model.RoomWithMemberCount, error)
+	SearchFunc                  func(ctx context.Context, query string, limit, offset int) ([]*model.RoomWithMemberCount, error)
+	ActivityHeatmapFunc         func(ctx context.Context, roomID string, since time.Time, timeZone string) ([]*model.RoomActivityBucket, error)
+	AddMemberFunc               func(ctx context.Context, member *model.RoomMember) error
+	RemoveMemberFunc            func(ctx context.Context, roomID, userID string) error
+	GetMemberFunc               func(ctx context.Context, roomID, userID string) (*model.RoomMember, error)
+	IsMemberFunc                func(ctx context.Context, roomID, userID string) (bool, error)
+	ListMembersFunc             func(ctx context.Context, roomID string, limit, offset int) ([]*model.RoomMemberWithUser, error)
+	ListMemberIDsFunc           func(ctx context.Context, roomID string) ([]string, error)
+	UpdateMemberRoleFunc        func(ctx context.Context, roomID, userID string, role model.MemberRole) error
+	UpdateLastReadAtFunc        func(ctx context.Context, roomID, userID string) (time.Time, error)
+	SetMuteFunc                 func(ctx context.Context, roomID, userID string, until *time.Time, mutedBy string) (*model.RoomMember, error)
+	ClearMuteFunc               func(ctx context.Context, roomID, userID string) error
+	ExpireMutesFunc             func(ctx context.Context, now time.Time, limit int) ([]*model.RoomMember, error)
+}
+
+func (m *mockRoomStore) Create(ctx context.Context, room *model.Room) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, room)
+}
+
+func (m *mockRoomStore) GetByID(ctx context.Context, id string) (*model.Room, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrRoomNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockRoomStore) GetByIDIncludingDeleted(ctx context.Context, id string) (*model.Room, error) {
+	m.record("GetByIDIncludingDeleted")
+	if m.GetByIDIncludingDeletedFunc == nil {
+		return nil, repository.ErrRoomNotFound
+	}
+	return m.GetByIDIncludingDeletedFunc(ctx, id)
+}
+
+func (m *mockRoomStore) GetByIDWithMemberCount(ctx context.Context, id string) (*model.RoomWithMemberCount, error) {
+	m.record("GetByIDWithMemberCount")
+	if m.GetByIDWithMemberCountFunc == nil {
+		return nil, repository.ErrRoomNotFound
+	}
+	return m.GetByIDWithMemberCountFunc(ctx, id)
+}
+
+func (m *mockRoomStore) Update(ctx context.Context, room *model.Room) error {
+	m.record("Update")
+	if m.UpdateFunc == nil {
+		return nil
+	}
+	return m.UpdateFunc(ctx, room)
+}
+
+func (m *mockRoomStore) Archive(ctx context.Context, id string) (time.Time, error) {
+	m.record("Archive")
+	if m.ArchiveFunc == nil {
+		return time.Time{}, nil
+	}
+	return m.ArchiveFunc(ctx, id)
+}
+
+func (m *mockRoomStore) Unarchive(ctx context.Context, id string) error {
+	m.record("Unarchive")
+	if m.UnarchiveFunc == nil {
+		return nil
+	}
+	return m.UnarchiveFunc(ctx, id)
+}
+
+func (m *mockRoomStore) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	m.record("ScheduleDeletion")
+	if m.ScheduleDeletionFunc == nil {
+		return nil
+	}
+	return m.ScheduleDeletionFunc(ctx, id, at)
+}
+
+func (m *mockRoomStore) CancelDeletion(ctx context.Context, id string) error {
+	m.record("CancelDeletion")
+	if m.CancelDeletionFunc == nil {
+		return nil
+	}
+	return m.CancelDeletionFunc(ctx, id)
+}
+
+func (m *mockRoomStore) SoftDeleteDue(ctx context.Context, now time.Time, limit int) ([]*model.Room, error) {
+	m.record("SoftDeleteDue")
+	if m.SoftDeleteDueFunc == nil {
+		return nil, nil
+	}
+	return m.SoftDeleteDueFunc(ctx, now, limit)
+}
+
+func (m *mockRoomStore) ListPurgeable(ctx context.Context, before time.Time, limit int) ([]*model.Room, error) {
+	m.record("ListPurgeable")
+	if m.ListPurgeableFunc == nil {
+		return nil, nil
+	}
+	return m.ListPurgeableFunc(ctx, before, limit)
+}
+
+func (m *mockRoomStore) Purge(ctx context.Context, id string) ([]string, error) {
+	m.record("Purge")
+	if m.PurgeFunc == nil {
+		return nil, nil
+	}
+	return m.PurgeFunc(ctx, id)
+}
+
+func (m *mockRoomStore) PurgeActivity(ctx context.Context, before time.Time) (int64, error) {
+	m.record("PurgeActivity")
+	if m.PurgeActivityFunc == nil {
+		return 0, nil
+	}
+	return m.PurgeActivityFunc(ctx, before)
+}
+
+func (m *mockRoomStore) ReconcileCounters(ctx context.Context, limit int) (int, []*model.RoomCounterDrift, error) {
+	m.record("ReconcileCounters")
+	if m.ReconcileCountersFunc == nil {
+		return 0, nil, nil
+	}
+	return m.ReconcileCountersFunc(ctx, limit)
+}
+
+func (m *mockRoomStore) ListAll(ctx context.Context, roomType model.RoomType, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	m.record("ListAll")
+	if m.ListAllFunc == nil {
+		return nil, nil
+	}
+	return m.ListAllFunc(ctx, roomType, limit, offset)
+}
+
+func (m *mockRoomStore) ListPublic(ctx context.Context, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	m.record("ListPublic")
+	if m.ListPublicFunc == nil {
+		return nil, nil
+	}
+	return m.ListPublicFunc(ctx, limit, offset)
+}
+
+func (m *mockRoomStore) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	m.record("ListByUserID")
+	if m.ListByUserIDFunc == nil {
+		return nil, nil
+	}
+	return m.ListByUserIDFunc(ctx, userID, limit, offset)
+}
+
+func (m *mockRoomStore) ListArchivedByUserID(ctx context.Context, userID string, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	m.record("ListArchivedByUserID")
+	if m.ListArchivedByUserIDFunc == nil {
+		return nil, nil
+	}
+	return m.ListArchivedByUserIDFunc(ctx, userID, limit, offset)
+}
+
+func (m *mockRoomStore) ListMemberRoomsByIDs(ctx context.Context, userID string, roomIDs []string) ([]*model.RoomWithMemberCount, error) {
+	m.record("ListMemberRoomsByIDs")
+	if m.ListMemberRoomsByIDsFunc == nil {
+		return nil, nil
+	}
+	return m.ListMemberRoomsByIDsFunc(ctx, userID, roomIDs)
+}
+
+func (m *mockRoomStore) Search(ctx context.Context, query string, limit, offset int) ([]*model.RoomWithMemberCount, error) {
+	m.record("Search")
+	if m.SearchFunc == nil {
+		return nil, nil
+	}
+	return m.SearchFunc(ctx, query, limit, offset)
+}
+
+func (m *mockRoomStore) ActivityHeatmap(ctx context.Context, roomID string, since time.Time, timeZone string) ([]*model.RoomActivityBucket, error) {
+	m.record("ActivityHeatmap")
+	if m.ActivityHeatmapFunc == nil {
+		return nil, nil
+	}
+	return m.ActivityHeatmapFunc(ctx, roomID, since, timeZone)
+}
+
+func (m *mockRoomStore) AddMember(ctx context.Context, member *model.RoomMember) error {
+	m.record("AddMember")
+	if m.AddMemberFunc == nil {
+		return nil
+	}
+	return m.AddMemberFunc(ctx, member)
+}
+
+func (m *mockRoomStore) RemoveMember(ctx context.Context, roomID, userID string) error {
+	m.record("RemoveMember")
+	if m.RemoveMemberFunc == nil {
+		return nil
+	}
+	return m.RemoveMemberFunc(ctx, roomID, userID)
+}
+
+func (m *mockRoomStore) GetMember(ctx context.Context, roomID, userID string) (*model.RoomMember, error) {
+	m.record("GetMember")
+	if m.GetMemberFunc == nil {
+		return nil, repository.ErrNotRoomMember
+	}
+	return m.GetMemberFunc(ctx, roomID, userID)
+}
+
+func (m *mockRoomStore) IsMember(ctx context.Context, roomID, userID string) (bool, error) {
+	m.record("IsMember")
+	if m.IsMemberFunc == nil {
+		return false, nil
+	}
+	return m.IsMemberFunc(ctx, roomID, userID)
+}
+
+func (m *mockRoomStore) ListMembers(ctx context.Context, roomID string, limit, offset int) ([]*model.RoomMemberWithUser, error) {
+	m.record("ListMembers")
+	if m.ListMembersFunc == nil {
+		return nil, nil
+	}
+	return m.ListMembersFunc(ctx, roomID, limit, offset)
+}
+
+func (m *mockRoomStore) ListMemberIDs(ctx context.Context, roomID string) ([]string, error) {
+	m.record("ListMemberIDs")
+	if m.ListMemberIDsFunc == nil {
+		return nil, nil
+	}
+	return m.ListMemberIDsFunc(ctx, roomID)
+}
+
+func (m *mockRoomStore) UpdateMemberRole(ctx context.Context, roomID, userID string, role model.MemberRole) error {
+	m.record("UpdateMemberRole")
+	if m.UpdateMemberRoleFunc == nil {
+		return nil
+	}
+	return m.UpdateMemberRoleFunc(ctx, roomID, userID, role)
+}
+
+func (m *mockRoomStore) UpdateLastReadAt(ctx context.Context, roomID, userID string) (time.Time, error) {
+	m.record("UpdateLastReadAt")
+	if m.UpdateLastReadAtFunc == nil {
+		return time.Time{}, nil
+	}
+	return m.UpdateLastReadAtFunc(ctx, roomID, userID)
+}
+
+func (m *mockRoomStore) SetMute(ctx context.Context, roomID, userID string, until *time.Time, mutedBy string) (*model.RoomMember, error) {
+	m.record("SetMute")
+	if m.SetMuteFunc == nil {
+		return nil, nil
+	}
+	return m.SetMuteFunc(ctx, roomID, userID, until, mutedBy)
+}
+
+func (m *mockRoomStore) ClearMute(ctx context.Context, roomID, userID string) error {
+	m.record("ClearMute")
+	if m.ClearMuteFunc == nil {
+		return nil
+	}
+	return m.ClearMuteFunc(ctx, roomID, userID)
+}
+
+func (m *mockRoomStore) ExpireMutes(ctx context.Context, now time.Time, limit int) ([]*model.RoomMember, error) {
+	m.record("ExpireMutes")
+	if m.ExpireMutesFunc == nil {
+		return nil, nil
+	}
+	return m.ExpireMutesFunc(ctx, now, limit)
+}
+
+type mockRoomMemberLookup struct {
+	mockCalls
+	GetMemberFunc                func(ctx context.Context, roomID, userID string) (*model.RoomMember, error)
+	GetByIDFunc                  func(ctx context.Context, id string) (*model.Room, error)
+	ListMemberIDsByUsernamesFunc func(ctx context.Context, roomID string, usernames []string) ([]string, error)
+}
+
+func (m *mockRoomMemberLookup) GetMember(ctx context.Context, roomID, userID string) (*model.RoomMember, error) {
+	m.record("GetMember")
+	if m.GetMemberFunc == nil {
+		return nil, repository.ErrNotRoomMember
+	}
+	return m.GetMemberFunc(ctx, roomID, userID)
+}
+
+func (m *mockRoomMemberLookup) GetByID(ctx context.Context, id string) (*model.Room, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrRoomNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockRoomMemberLookup) ListMemberIDsByUsernames(ctx context.Context, roomID string, usernames []string) ([]string, error) {
+	m.record("ListMemberIDsByUsernames")
+	if m.ListMemberIDsByUsernamesFunc == nil {
+		return nil, nil
+	}
+	return m.ListMemberIDsByUsernamesFunc(ctx, roomID, usernames)
+}
+
+type mockMessageStore struct {
+	mockCalls
+	CreateFunc              func(ctx context.Context, msg *model.Message) error
+	GetByIDFunc             func(ctx context.Context, id string) (*model.Message, error)
+	GetByIDWithUserFunc     func(ctx context.Context, id string) (*model.MessageWithUser, error)
+	ListByIDsWithUserFunc   func(ctx context.Context, roomID string, ids []string) ([]*model.MessageWithUser, error)
+	ListByRoomIDFunc        func(ctx context.Context, roomID string, limit, offset int) ([]*model.MessageWithUser, error)
+	ListByRoomIDBeforeFunc  func(ctx context.Context, roomID string, beforeID string, limit int) ([]*model.MessageWithUser, error)
+	ListByRoomIDSinceFunc   func(ctx context.Context, roomID string, sinceID string, limit int) ([]*model.MessageWithUser, error)
+	ListForExportFunc       func(ctx context.Context, roomID, userID string, after *repository.ExportCursor, limit int) ([]*model.MessageWithUser, error)
+	SearchFunc              func(ctx context.Context, q *search.MessageQuery) ([]*model.MessageWithUser, error)
+	UpdateFunc              func(ctx context.Context, id, content string) error
+	SoftDeleteFunc          func(ctx context.Context, id string) error
+	CountUnreadByRoomIDFunc func(ctx context.Context, roomID, userID string) (int, error)
+	ExpireDueFunc           func(ctx context.Context, now time.Time, limit int) ([]*model.Message, error)
+}
+
+func (m *mockMessageStore) Create(ctx context.Context, msg *model.Message) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, msg)
+}
+
+func (m *mockMessageStore) GetByID(ctx context.Context, id string) (*model.Message, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrMessageNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockMessageStore) GetByIDWithUser(ctx context.Context, id string) (*model.MessageWithUser, error) {
+	m.record("GetByIDWithUser")
+	if m.GetByIDWithUserFunc == nil {
+		return nil, repository.ErrMessageNotFound
+	}
+	return m.GetByIDWithUserFunc(ctx, id)
+}
+
+func (m *mockMessageStore) ListByIDsWithUser(ctx context.Context, roomID string, ids []string) ([]*model.MessageWithUser, error) {
+	m.record("ListByIDsWithUser")
+	if m.ListByIDsWithUserFunc == nil {
+		return nil, nil
+	}
+	return m.ListByIDsWithUserFunc(ctx, roomID, ids)
+}
+
+func (m *mockMessageStore) ListByRoomID(ctx context.Context, roomID string, limit, offset int) ([]*model.MessageWithUser, error) {
+	m.record("ListByRoomID")
+	if m.ListByRoomIDFunc == nil {
+		return nil, nil
+	}
+	return m.ListByRoomIDFunc(ctx, roomID, limit, offset)
+}
+
+func (m *mockMessageStore) ListByRoomIDBefore(ctx context.Context, roomID string, beforeID string, limit int) ([]*model.MessageWithUser, error) {
+	m.record("ListByRoomIDBefore")
+	if m.ListByRoomIDBeforeFunc == nil {
+		return nil, nil
+	}
+	return m.ListByRoomIDBeforeFunc(ctx, roomID, beforeID, limit)
+}
+
+func (m *mockMessageStore) ListByRoomIDSince(ctx context.Context, roomID string, sinceID string, limit int) ([]*model.MessageWithUser, error) {
+	m.record("ListByRoomIDSince")
+	if m.ListByRoomIDSinceFunc == nil {
+		return nil, nil
+	}
+	return m.ListByRoomIDSinceFunc(ctx, roomID, sinceID, limit)
+}
+
+func (m *mockMessageStore) ListForExport(ctx context.Context, roomID, userID string, after *repository.ExportCursor, limit int) ([]*model.MessageWithUser, error) {
+	m.record("ListForExport")
+	if m.ListForExportFunc == nil {
+		return nil, nil
+	}
+	return m.ListForExportFunc(ctx, roomID, userID, after, limit)
+}
+
+func (m *mockMessageStore) Search(ctx context.Context, q *search.MessageQuery) ([]*model.MessageWithUser, error) {
+	m.record("Search")
+	if m.SearchFunc == nil {
+		return nil, nil
+	}
+	return m.SearchFunc(ctx, q)
+}
+
+func (m *mockMessageStore) Update(ctx context.Context, id, content string) error {
+	m.record("Update")
+	if m.UpdateFunc == nil {
+		return nil
+	}
+	return m.UpdateFunc(ctx, id, content)
+}
+
+func (m *mockMessageStore) SoftDelete(ctx context.Context, id string) error {
+	m.record("SoftDelete")
+	if m.SoftDeleteFunc == nil {
+		return nil
+	}
+	return m.SoftDeleteFunc(ctx, id)
+}
+
+func (m *mockMessageStore) CountUnreadByRoomID(ctx context.Context, roomID, userID string) (int, error) {
+	m.record("CountUnreadByRoomID")
+	if m.CountUnreadByRoomIDFunc == nil {
+		return 0, nil
+	}
+	return m.CountUnreadByRoomIDFunc(ctx, roomID, userID)
+}
+
+func (m *mockMessageStore) ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.Message, error) {
+	m.record("ExpireDue")
+	if m.ExpireDueFunc == nil {
+		return nil, nil
+	}
+	return m.ExpireDueFunc(ctx, now, limit)
+}
+
+type mockRoomMessageStore struct {
+	mockCalls
+	CreateFunc            func(ctx context.Context, msg *model.Message) error
+	GetByIDWithUserFunc   func(ctx context.Context, id string) (*model.MessageWithUser, error)
+	ListRecentForFeedFunc func(ctx context.Context, roomID string, limit int) ([]*model.MessageWithUser, error)
+}
+
+func (m *mockRoomMessageStore) Create(ctx context.Context, msg *model.Message) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, msg)
+}
+
+func (m *mockRoomMessageStore) GetByIDWithUser(ctx context.Context, id string) (*model.MessageWithUser, error) {
+	m.record("GetByIDWithUser")
+	if m.GetByIDWithUserFunc == nil {
+		return nil, repository.ErrMessageNotFound
+	}
+	return m.GetByIDWithUserFunc(ctx, id)
+}
+
+func (m *mockRoomMessageStore) ListRecentForFeed(ctx context.Context, roomID string, limit int) ([]*model.MessageWithUser, error) {
+	m.record("ListRecentForFeed")
+	if m.ListRecentForFeedFunc == nil {
+		return nil, nil
+	}
+	return m.ListRecentForFeedFunc(ctx, roomID, limit)
+}
+
+type mockAuthUserStore struct {
+	mockCalls
+	CreateFunc                   func(ctx context.Context, user *model.User) error
+	GetByIDFunc                  func(ctx context.Context, id string) (*model.User, error)
+	GetByUsernameFunc            func(ctx context.Context, username string) (*model.User, error)
+	ExistsByUsernameFunc         func(ctx context.Context, username string) (bool, error)
+	ExistsByEmailFunc            func(ctx context.Context, email string) (bool, error)
+	UpdateFunc                   func(ctx context.Context, user *model.User) error
+	UpdatePasswordFunc           func(ctx context.Context, userID, passwordHash string) error
+	RehashPasswordFunc           func(ctx context.Context, userID, oldHash, newHash string) error
+	UpdateStatusFunc             func(ctx context.Context, userID string, status model.UserStatus) error
+	UpdateDiscoverableFunc       func(ctx context.Context, userID string, discoverable bool) error
+	UpdateLastSeenVisibilityFunc func(ctx context.Context, userID string, visibility model.LastSeenVisibility) error
+	UpdateDigestFrequencyFunc    func(ctx context.Context, userID string, frequency model.DigestFrequency) error
+}
+
+func (m *mockAuthUserStore) Create(ctx context.Context, user *model.User) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, user)
+}
+
+func (m *mockAuthUserStore) GetByID(ctx context.Context, id string) (*model.User, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockAuthUserStore) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	m.record("GetByUsername")
+	if m.GetByUsernameFunc == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return m.GetByUsernameFunc(ctx, username)
+}
+
+func (m *mockAuthUserStore) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	m.record("ExistsByUsername")
+	if m.ExistsByUsernameFunc == nil {
+		return false, nil
+	}
+	return m.ExistsByUsernameFunc(ctx, username)
+}
+
+func (m *mockAuthUserStore) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	m.record("ExistsByEmail")
+	if m.ExistsByEmailFunc == nil {
+		return false, nil
+	}
+	return m.ExistsByEmailFunc(ctx, email)
+}
+
+func (m *mockAuthUserStore) Update(ctx context.Context, user *model.User) error {
+	m.record("Update")
+	if m.UpdateFunc == nil {
+		return nil
+	}
+	return m.UpdateFunc(ctx, user)
+}
+
+func (m *mockAuthUserStore) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	m.record("UpdatePassword")
+	if m.UpdatePasswordFunc == nil {
+		return nil
+	}
+	return m.UpdatePasswordFunc(ctx, userID, passwordHash)
+}
+
+func (m *mockAuthUserStore) RehashPassword(ctx context.Context, userID, oldHash, newHash string) error {
+	m.record("RehashPassword")
+	if m.RehashPasswordFunc == nil {
+		return nil
+	}
+	return m.RehashPasswordFunc(ctx, userID, oldHash, newHash)
+}
+
+func (m *mockAuthUserStore) UpdateStatus(ctx context.Context, userID string, status model.UserStatus) error {
+	m.record("UpdateStatus")
+	if m.UpdateStatusFunc == nil {
+		return nil
+	}
+	return m.UpdateStatusFunc(ctx, userID, status)
+}
+
+func (m *mockAuthUserStore) UpdateDiscoverable(ctx context.Context, userID string, discoverable bool) error {
+	m.record("UpdateDiscoverable")
+	if m.UpdateDiscoverableFunc == nil {
+		return nil
+	}
+	return m.UpdateDiscoverableFunc(ctx, userID, discoverable)
+}
+
+func (m *mockAuthUserStore) UpdateLastSeenVisibility(ctx context.Context, userID string, visibility model.LastSeenVisibility) error {
+	m.record("UpdateLastSeenVisibility")
+	if m.UpdateLastSeenVisibilityFunc == nil {
+		return nil
+	}
+	return m.UpdateLastSeenVisibilityFunc(ctx, userID, visibility)
+}
+
+func (m *mockAuthUserStore) UpdateDigestFrequency(ctx context.Context, userID string, frequency model.DigestFrequency) error {
+	m.record("UpdateDigestFrequency")
+	if m.UpdateDigestFrequencyFunc == nil {
+		return nil
+	}
+	return m.UpdateDigestFrequencyFunc(ctx, userID, frequency)
+}
+
+type mockUserStore struct {
+	mockCalls
+	GetByIDFunc          func(ctx context.Context, id string) (*model.User, error)
+	GetByIDsFunc         func(ctx context.Context, ids []string) ([]*model.User, error)
+	UpdateFunc           func(ctx context.Context, user *model.User) error
+	UpdateStatusFunc     func(ctx context.Context, userID string, status model.UserStatus) error
+	UpdateIsAdminFunc    func(ctx context.Context, userID string, isAdmin bool) error
+	GetOnlineUsersFunc   func(ctx context.Context, limit, offset int) ([]*model.User, error)
+	SearchFunc           func(ctx context.Context, query string, limit, offset int) ([]*model.User, error)
+	FindDiscoverableFunc func(ctx context.Context, viewerID string, hashes []string) ([]*model.User, error)
+}
+
+func (m *mockUserStore) GetByID(ctx context.Context, id string) (*model.User, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockUserStore) GetByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
+	m.record("GetByIDs")
+	if m.GetByIDsFunc == nil {
+		return nil, nil
+	}
+	return m.GetByIDsFunc(ctx, ids)
+}
+
+func (m *mockUserStore) Update(ctx context.Context, user *model.User) error {
+	m.record("Update")
+	if m.UpdateFunc == nil {
+		return nil
+	}
+	return m.UpdateFunc(ctx, user)
+}
+
+func (m *mockUserStore) UpdateStatus(ctx context.Context, userID string, status model.UserStatus) error {
+	m.record("UpdateStatus")
+	if m.UpdateStatusFunc == nil {
+		return nil
+	}
+	return m.UpdateStatusFunc(ctx, userID, status)
+}
+
+func (m *mockUserStore) UpdateIsAdmin(ctx context.Context, userID string, isAdmin bool) error {
+	m.record("UpdateIsAdmin")
+	if m.UpdateIsAdminFunc == nil {
+		return nil
+	}
+	return m.UpdateIsAdminFunc(ctx, userID, isAdmin)
+}
+
+func (m *mockUserStore) GetOnlineUsers(ctx context.Context, limit, offset int) ([]*model.User, error) {
+	m.record("GetOnlineUsers")
+	if m.GetOnlineUsersFunc == nil {
+		return nil, nil
+	}
+	return m.GetOnlineUsersFunc(ctx, limit, offset)
+}
+
+func (m *mockUserStore) Search(ctx context.Context, query string, limit, offset int) ([]*model.User, error) {
+	m.record("Search")
+	if m.SearchFunc == nil {
+		return nil, nil
+	}
+	return m.SearchFunc(ctx, query, limit, offset)
+}
+
+func (m *mockUserStore) FindDiscoverable(ctx context.Context, viewerID string, hashes []string) ([]*model.User, error) {
+	m.record("FindDiscoverable")
+	if m.FindDiscoverableFunc == nil {
+		return nil, nil
+	}
+	return m.FindDiscoverableFunc(ctx, viewerID, hashes)
+}
+
+type mockBlockStore struct {
+	mockCalls
+	BlockFunc           func(ctx context.Context, blockerID, blockedID string) error
+	UnblockFunc         func(ctx context.Context, blockerID, blockedID string) error
+	IsBlockedFunc       func(ctx context.Context, blockerID, blockedID string) (bool, error)
+	IsBlockedEitherFunc func(ctx context.Context, userID1, userID2 string) (bool, error)
+	ListBlockedFunc     func(ctx context.Context, blockerID string, limit, offset int) ([]*model.User, error)
+}
+
+func (m *mockBlockStore) Block(ctx context.Context, blockerID, blockedID string) error {
+	m.record("Block")
+	if m.BlockFunc == nil {
+		return nil
+	}
+	return m.BlockFunc(ctx, blockerID, blockedID)
+}
+
+func (m *mockBlockStore) Unblock(ctx context.Context, blockerID, blockedID string) error {
+	m.record("Unblock")
+	if m.UnblockFunc == nil {
+		return nil
+	}
+	return m.UnblockFunc(ctx, blockerID, blockedID)
+}
+
+func (m *mockBlockStore) IsBlocked(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	m.record("IsBlocked")
+	if m.IsBlockedFunc == nil {
+		return false, nil
+	}
+	return m.IsBlockedFunc(ctx, blockerID, blockedID)
+}
+
+func (m *mockBlockStore) IsBlockedEither(ctx context.Context, userID1, userID2 string) (bool, error) {
+	m.record("IsBlockedEither")
+	if m.IsBlockedEitherFunc == nil {
+		return false, nil
+	}
+	return m.IsBlockedEitherFunc(ctx, userID1, userID2)
+}
+
+func (m *mockBlockStore) ListBlocked(ctx context.Context, blockerID string, limit, offset int) ([]*model.User, error) {
+	m.record("ListBlocked")
+	if m.ListBlockedFunc == nil {
+		return nil, nil
+	}
+	return m.ListBlockedFunc(ctx, blockerID, limit, offset)
+}
+
+type mockFriendshipStore struct {
+	mockCalls
+	CreateFunc              func(ctx context.Context, userID, friendID string) error
+	AcceptFunc              func(ctx context.Context, userID, friendID string) error
+	RejectFunc              func(ctx context.Context, userID, friendID string) error
+	RemoveFunc              func(ctx context.Context, userID, friendID string) error
+	AreFriendsFunc          func(ctx context.Context, userID, friendID string) (bool, error)
+	ListFriendsFunc         func(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error)
+	ListPendingRequestsFunc func(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error)
+	ListSentRequestsFunc    func(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error)
+	ListSuggestionsFunc     func(ctx context.Context, userID string, limit, offset int) ([]*model.FriendSuggestion, error)
+}
+
+func (m *mockFriendshipStore) Create(ctx context.Context, userID, friendID string) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, userID, friendID)
+}
+
+func (m *mockFriendshipStore) Accept(ctx context.Context, userID, friendID string) error {
+	m.record("Accept")
+	if m.AcceptFunc == nil {
+		return nil
+	}
+	return m.AcceptFunc(ctx, userID, friendID)
+}
+
+func (m *mockFriendshipStore) Reject(ctx context.Context, userID, friendID string) error {
+	m.record("Reject")
+	if m.RejectFunc == nil {
+		return nil
+	}
+	return m.RejectFunc(ctx, userID, friendID)
+}
+
+func (m *mockFriendshipStore) Remove(ctx context.Context, userID, friendID string) error {
+	m.record("Remove")
+	if m.RemoveFunc == nil {
+		return nil
+	}
+	return m.RemoveFunc(ctx, userID, friendID)
+}
+
+func (m *mockFriendshipStore) AreFriends(ctx context.Context, userID, friendID string) (bool, error) {
+	m.record("AreFriends")
+	if m.AreFriendsFunc == nil {
+		return false, nil
+	}
+	return m.AreFriendsFunc(ctx, userID, friendID)
+}
+
+func (m *mockFriendshipStore) ListFriends(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error) {
+	m.record("ListFriends")
+	if m.ListFriendsFunc == nil {
+		return nil, nil
+	}
+	return m.ListFriendsFunc(ctx, userID, limit, offset)
+}
+
+func (m *mockFriendshipStore) ListPendingRequests(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error) {
+	m.record("ListPendingRequests")
+	if m.ListPendingRequestsFunc == nil {
+		return nil, nil
+	}
+	return m.ListPendingRequestsFunc(ctx, userID, limit, offset)
+}
+
+func (m *mockFriendshipStore) ListSentRequests(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error) {
+	m.record("ListSentRequests")
+	if m.ListSentRequestsFunc == nil {
+		return nil, nil
+	}
+	return m.ListSentRequestsFunc(ctx, userID, limit, offset)
+}
+
+func (m *mockFriendshipStore) ListSuggestions(ctx context.Context, userID string, limit, offset int) ([]*model.FriendSuggestion, error) {
+	m.record("ListSuggestions")
+	if m.ListSuggestionsFunc == nil {
+		return nil, nil
+	}
+	return m.ListSuggestionsFunc(ctx, userID, limit, offset)
+}
+
+type mockDirectMessageStore struct {
+	mockCalls
+	CreateFunc                    func(ctx context.Context, msg *model.DirectMessage) error
+	CreateEncryptedFunc           func(ctx context.Context, msg *model.DirectMessage, envelopes []*model.DMEnvelope) error
+	GetByIDFunc                   func(ctx context.Context, id string) (*model.DirectMessage, error)
+	GetByIDWithUserFunc           func(ctx context.Context, id string) (*model.DirectMessageWithUser, error)
+	UpdateContentFunc             func(ctx context.Context, id, content string) error
+	DeleteForUserFunc             func(ctx context.Context, messageID, userID string) error
+	ListConversationFunc          func(ctx context.Context, userID1, userID2 string, limit, offset int) ([]*model.DirectMessageWithUser, error)
+	ListConversationForExportFunc func(ctx context.Context, userID, peerID string, after *repository.ExportCursor, limit int) ([]*model.DirectMessageWithUser, error)
+	ListConversationsFunc         func(ctx context.Context, userID string, archived bool, limit, offset int) ([]*model.Conversation, error)
+	ListEnvelopesFunc             func(ctx context.Context, deviceID string, messageIDs []string) ([]*model.DMEnvelope, error)
+	MarkAsReadFunc                func(ctx context.Context, senderID, receiverID string) error
+	CountUnreadFunc               func(ctx context.Context, userID string) (int, error)
+	CountUnreadFromUserFunc       func(ctx context.Context, receiverID, senderID string) (int, error)
+	GetSettingsFunc               func(ctx context.Context, userID1, userID2 string) (*model.DirectConversationSettings, error)
+	SetConversationStateFunc      func(ctx context.Context, userID, otherUserID string, state model.ConversationState, on bool) (*model.ConversationSettings, error)
+	SetMessageTTLFunc             func(ctx context.Context, userID1, userID2 string, ttlSeconds int, updatedBy string) (*model.DirectConversationSettings, error)
+	ExpireDueFunc                 func(ctx context.Context, now time.Time, limit int) ([]*model.DirectMessage, error)
+}
+
+func (m *mockDirectMessageStore) Create(ctx context.Context, msg *model.DirectMessage) error {
+	m.record("Create")
+	if m.CreateFunc == nil {
+		return nil
+	}
+	return m.CreateFunc(ctx, msg)
+}
+
+func (m *mockDirectMessageStore) CreateEncrypted(ctx context.Context, msg *model.DirectMessage, envelopes []*model.DMEnvelope) error {
+	m.record("CreateEncrypted")
+	if m.CreateEncryptedFunc == nil {
+		return nil
+	}
+	return m.CreateEncryptedFunc(ctx, msg, envelopes)
+}
+
+func (m *mockDirectMessageStore) GetByID(ctx context.Context, id string) (*model.DirectMessage, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrDirectMessageNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockDirectMessageStore) GetByIDWithUser(ctx context.Context, id string) (*model.DirectMessageWithUser, error) {
+	m.record("GetByIDWithUser")
+	if m.GetByIDWithUserFunc == nil {
+		return nil, repository.ErrDirectMessageNotFound
+	}
+	return m.GetByIDWithUserFunc(ctx, id)
+}
+
+func (m *mockDirectMessageStore) UpdateContent(ctx context.Context, id, content string) error {
+	m.record("UpdateContent")
+	if m.UpdateContentFunc == nil {
+		return nil
+	}
+	return m.UpdateContentFunc(ctx, id, content)
+}
+
+func (m *mockDirectMessageStore) DeleteForUser(ctx context.Context, messageID, userID string) error {
+	m.record("DeleteForUser")
+	if m.DeleteForUserFunc == nil {
+		return nil
+	}
+	return m.DeleteForUserFunc(ctx, messageID, userID)
+}
+
+func (m *mockDirectMessageStore) ListConversation(ctx context.Context, userID1, userID2 string, limit, offset int) ([]*model.DirectMessageWithUser, error) {
+	m.record("ListConversation")
+	if m.ListConversationFunc == nil {
+		return nil, nil
+	}
+	return m.ListConversationFunc(ctx, userID1, userID2, limit, offset)
+}
+
+func (m *mockDirectMessageStore) ListConversationForExport(ctx context.Context, userID, peerID string, after *repository.ExportCursor, limit int) ([]*model.DirectMessageWithUser, error) {
+	m.record("ListConversationForExport")
+	if m.ListConversationForExportFunc == nil {
+		return nil, nil
+	}
+	return m.ListConversationForExportFunc(ctx, userID, peerID, after, limit)
+}
+
+func (m *mockDirectMessageStore) ListConversations(ctx context.Context, userID string, archived bool, limit, offset int) ([]*model.Conversation, error) {
+	m.record("ListConversations")
+	if m.ListConversationsFunc == nil {
+		return nil, nil
+	}
+	return m.ListConversationsFunc(ctx, userID, archived, limit, offset)
+}
+
+func (m *mockDirectMessageStore) ListEnvelopes(ctx context.Context, deviceID string, messageIDs []string) ([]*model.DMEnvelope, error) {
+	m.record("ListEnvelopes")
+	if m.ListEnvelopesFunc == nil {
+		return nil, nil
+	}
+	return m.ListEnvelopesFunc(ctx, deviceID, messageIDs)
+}
+
+func (m *mockDirectMessageStore) MarkAsRead(ctx context.Context, senderID, receiverID string) error {
+	m.record("MarkAsRead")
+	if m.MarkAsReadFunc == nil {
+		return nil
+	}
+	return m.MarkAsReadFunc(ctx, senderID, receiverID)
+}
+
+func (m *mockDirectMessageStore) CountUnread(ctx context.Context, userID string) (int, error) {
+	m.record("CountUnread")
+	if m.CountUnreadFunc == nil {
+		return 0, nil
+	}
+	return m.CountUnreadFunc(ctx, userID)
+}
+
+func (m *mockDirectMessageStore) CountUnreadFromUser(ctx context.Context, receiverID, senderID string) (int, error) {
+	m.record("CountUnreadFromUser")
+	if m.CountUnreadFromUserFunc == nil {
+		return 0, nil
+	}
+	return m.CountUnreadFromUserFunc(ctx, receiverID, senderID)
+}
+
+func (m *mockDirectMessageStore) GetSettings(ctx context.Context, userID1, userID2 string) (*model.DirectConversationSettings, error) {
+	m.record("GetSettings")
+	if m.GetSettingsFunc == nil {
+		return nil, nil
+	}
+	return m.GetSettingsFunc(ctx, userID1, userID2)
+}
+
+func (m *mockDirectMessageStore) SetConversationState(ctx context.Context, userID, otherUserID string, state model.ConversationState, on bool) (*model.ConversationSettings, error) {
+	m.record("SetConversationState")
+	if m.SetConversationStateFunc == nil {
+		return nil, nil
+	}
+	return m.SetConversationStateFunc(ctx, userID, otherUserID, state, on)
+}
+
+func (m *mockDirectMessageStore) SetMessageTTL(ctx context.Context, userID1, userID2 string, ttlSeconds int, updatedBy string) (*model.DirectConversationSettings, error) {
+	m.record("SetMessageTTL")
+	if m.SetMessageTTLFunc == nil {
+		return nil, nil
+	}
+	return m.SetMessageTTLFunc(ctx, userID1, userID2, ttlSeconds, updatedBy)
+}
+
+func (m *mockDirectMessageStore) ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.DirectMessage, error) {
+	m.record("ExpireDue")
+	if m.ExpireDueFunc == nil {
+		return nil, nil
+	}
+	return m.ExpireDueFunc(ctx, now, limit)
+}
+
+type mockUserBatchLookup struct {
+	mockCalls
+	GetByIDFunc  func(ctx context.Context, id string) (*model.User, error)
+	GetByIDsFunc func(ctx context.Context, ids []string) ([]*model.User, error)
+}
+
+func (m *mockUserBatchLookup) GetByID(ctx context.Context, id string) (*model.User, error) {
+	m.record("GetByID")
+	if m.GetByIDFunc == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *mockUserBatchLookup) GetByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
+	m.record("GetByIDs")
+	if m.GetByIDsFunc == nil {
+		return nil, nil
+	}
+	return m.GetByIDsFunc(ctx, ids)
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/antivirus"
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultUploadScanWorkers is how many uploads are scanned at once
+	DefaultUploadScanWorkers = 2
+
+	// DefaultUploadScanQueueSize is how many uploads may wait for a worker;
+	// uploads arriving while it is full are picked up by RetryPending
+	DefaultUploadScanQueueSize = 256
+
+	// EventUploadScanned tells the uploader, and the rooms and conversations
+	// attaching an upload, that its scan finished
+	EventUploadScanned = "upload_scanned"
+
+	// uploadScanJobTimeout bounds scanning one upload
+	uploadScanJobTimeout = 2 * time.Minute
+
+	// Uploads still pending this long were dropped from the queue, lost in
+	// a restart or failed to scan, and are queued again
+	uploadScanRetryAfter = 5 * time.Minute
+)
+
+// UploadScanConfig tunes an UploadScanner; zero values use the defaults
+type UploadScanConfig struct {
+	Workers   int
+	QueueSize int
+
+	// QuarantineDir receives infected files, outside the served uploads
+	// directory. Without it infected files are deleted.
+	QuarantineDir string
+}
+
+// UploadScannedEvent carries the outcome of scanning an upload
+type UploadScannedEvent struct {
+	UploadID   string           `json:"upload_id"`
+	ScanStatus model.ScanStatus `json:"scan_status"`
+}
+
+// UploadScanner scans recorded uploads for malware in the background.
+// Infected files are moved to the quarantine directory, so they are no
+// longer served, and the uploader is notified; every outcome is pushed as
+// upload_scanned. A scanner failure leaves the upload pending for
+// RetryPending. Its methods are safe on a nil UploadScanner, which scans
+// nothing.
+type UploadScanner struct {
+	scanner       antivirus.Scanner
+	store         UploadScanStore
+	notifier      *NotificationService
+	quarantineDir string
+	logger        *zap.Logger
+
+	queue   chan *model.Upload
+	workers sync.WaitGroup
+}
+
+// NewUploadScanner starts the scan workers
+func NewUploadScanner(scanner antivirus.Scanner, store UploadScanStore, config UploadScanConfig, logger *zap.Logger) *UploadScanner {
+	if config.Workers <= 0 {
+		config.Workers = DefaultUploadScanWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultUploadScanQueueSize
+	}
+
+	s := &UploadScanner{
+		scanner:       scanner,
+		store:         store,
+		quarantineDir: config.QuarantineDir,
+		logger:        logger,
+		queue:         make(chan *model.Upload, config.QueueSize),
+	}
+	for i := 0; i < config.Workers; i++ {
+		s.workers.Add(1)
+		go s.run()
+	}
+	return s
+}
+
+// SetNotifier sets the service quarantine notices and upload_scanned are
+// sent through
+func (s *UploadScanner) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// Enqueue queues a pending upload for scanning. It never blocks.
+func (s *UploadScanner) Enqueue(upload *model.Upload) {
+	if s == nil || upload.ScanStatus != model.ScanStatusPending {
+		return
+	}
+
+	queued := *upload
+	select {
+	case s.queue <- &queued:
+	default:
+		s.logger.Warn("Upload scan queue full, deferring upload", zap.String("upload_id", upload.ID))
+	}
+}
+
+// RetryPending queues up to limit uploads that have been pending for a
+// while again and returns how many were queued
+func (s *UploadScanner) RetryPending(ctx context.Context, limit int) (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+
+	uploads, err := s.store.ListPendingScans(ctx, time.Now().Add(-uploadScanRetryAfter), limit)
+	if err != nil {
+		s.logger.Error("Failed to list pending upload scans", zap.Error(err))
+		return 0, err
+	}
+	for _, upload := range uploads {
+		s.Enqueue(upload)
+	}
+	return len(uploads), nil
+}
+
+// Close finishes the queued uploads and stops the workers. Uploads queued
+// after Close stay pending until the next start.
+func (s *UploadScanner) Close() {
+	if s == nil {
+		return
+	}
+	close(s.queue)
+	s.workers.Wait()
+}
+
+func (s *UploadScanner) run() {
+	defer s.workers.Done()
+
+	for upload := range s.queue {
+		s.process(upload)
+	}
+}
+
+// process scans one upload and stores the verdict
+func (s *UploadScanner) process(upload *model.Upload) {
+	ctx, cancel := context.WithTimeout(context.Background(), uploadScanJobTimeout)
+	defer cancel()
+
+	logger := s.logger.With(zap.String("upload_id", upload.ID))
+
+	file, err := os.Open(upload.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Removed before it was scanned; nothing is left to serve
+			logger.Warn("Uploaded file missing, skipping scan", zap.String("path", upload.FilePath))
+			if err := s.store.SetScanResult(ctx, upload.ID, model.ScanStatusUnscanned, "", upload.FilePath); err != nil {
+				logger.Error("Failed to store scan result", zap.Error(err))
+			}
+			return
+		}
+		logger.Error("Failed to open uploaded file", zap.Error(err))
+		return
+	}
+	result, err := s.scanner.Scan(ctx, file)
+	file.Close()
+	if err != nil {
+		logger.Warn("Failed to scan upload, will retry", zap.Error(err))
+		return
+	}
+
+	status, filePath := model.ScanStatusClean, upload.FilePath
+	if result.Infected {
+		status, filePath = model.ScanStatusInfected, s.quarantine(upload)
+		logger.Warn("Infected upload quarantined",
+			zap.String("signature", result.Signature),
+			zap.String("quarantined_at", filePath),
+		)
+	}
+	if err := s.store.SetScanResult(ctx, upload.ID, status, result.Signature, filePath); err != nil {
+		logger.Error("Failed to store scan result", zap.Error(err))
+		return
+	}
+
+	s.publish(ctx, upload, status)
+	if result.Infected && s.notifier != nil && upload.UserID.Valid {
+		s.notifier.Notify(ctx, []string{upload.UserID.String}, &NotifyInput{
+			Type:          model.NotificationTypeUploadQuarantined,
+			Title:         fmt.Sprintf("你上傳的檔案「%s」含有惡意程式，已被隔離", upload.FileName),
+			ReferenceID:   upload.ID,
+			ReferenceType: "upload",
+		})
+	}
+}
+
+// quarantine moves an infected file out of the uploads directory and
+// returns where it is kept now, empty when it was deleted instead
+func (s *UploadScanner) quarantine(upload *model.Upload) string {
+	if s.quarantineDir != "" {
+		target := filepath.Join(s.quarantineDir, upload.ID+filepath.Ext(upload.FilePath))
+		err := os.MkdirAll(s.quarantineDir, 0700)
+		if err == nil {
+			if err = os.Rename(upload.FilePath, target); err == nil {
+				return target
+			}
+		}
+		s.logger.Warn("Failed to quarantine upload, deleting it", zap.String("upload_id", upload.ID), zap.Error(err))
+	}
+
+	if err := os.Remove(upload.FilePath); err != nil && !os.IsNotExist(err) {
+		s.logger.Error("Failed to remove infected upload", zap.String("path", upload.FilePath), zap.Error(err))
+	}
+	return ""
+}
+
+// publish pushes upload_scanned to the uploader and wherever the upload is
+// attached already
+func (s *UploadScanner) publish(ctx context.Context, upload *model.Upload, status model.ScanStatus) {
+	if s.notifier == nil {
+		return
+	}
+	event := &UploadScannedEvent{UploadID: upload.ID, ScanStatus: status}
+
+	roomIDs, userIDs, err := s.store.ListUploadAudience(ctx, upload.ID)
+	if err != nil {
+		s.logger.Warn("Failed to list where upload is attached", zap.String("upload_id", upload.ID), zap.Error(err))
+	}
+	for _, roomID := range roomIDs {
+		s.notifier.PublishToRoom(roomID, EventUploadScanned, event)
+	}
+
+	seen := make(map[string]bool)
+	if upload.UserID.Valid {
+		userIDs = append(userIDs, upload.UserID.String)
+	}
+	for _, userID := range userIDs {
+		if !seen[userID] {
+			seen[userID] = true
+			s.notifier.PublishToUser(userID, EventUploadScanned, event)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-demo/chat/internal/antivirus"
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+// fakeAntivirus flags files containing "EICAR"
+type fakeAntivirus struct {
+	err error
+}
+
+func (f *fakeAntivirus) Scan(ctx context.Context, r io.Reader) (*antivirus.Result, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(string(data), "EICAR") {
+		return &antivirus.Result{Infected: true, Signature: "Eicar-Signature"}, nil
+	}
+	return &antivirus.Result{}, nil
+}
+
+type scanResult struct {
+	status    model.ScanStatus
+	signature string
+	filePath  string
+}
+
+func newTestUploadScanner(t *testing.T, scanner antivirus.Scanner, results *[]scanResult) (*UploadScanner, *fakeRealtimePublisher) {
+	t.Helper()
+	store := &mockUploadScanStore{
+		SetScanResultFunc: func(ctx context.Context, id string, status model.ScanStatus, signature, filePath string) error {
+			*results = append(*results, scanResult{status, signature, filePath})
+			return nil
+		},
+		ListUploadAudienceFunc: func(ctx context.Context, uploadID string) ([]string, []string, error) {
+			return []string{"r1"}, nil, nil
+		},
+	}
+	publisher := &fakeRealtimePublisher{}
+	notifier := NewNotificationService(nil, zap.NewNop())
+	notifier.SetPublisher(publisher)
+
+	s := NewUploadScanner(scanner, store, UploadScanConfig{
+		Workers:       1,
+		QuarantineDir: filepath.Join(t.TempDir(), "quarantine"),
+	}, zap.NewNop())
+	s.SetNotifier(notifier)
+	return s, publisher
+}
+
+func writeUpload(t *testing.T, contents string) *model.Upload {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	return &model.Upload{ID: testUploadID, FilePath: path, ScanStatus: model.ScanStatusPending}
+}
+
+func TestUploadScanner_Clean(t *testing.T) {
+	var results []scanResult
+	s, publisher := newTestUploadScanner(t, &fakeAntivirus{}, &results)
+	defer s.Close()
+	upload := writeUpload(t, "quarterly numbers")
+
+	s.process(upload)
+
+	if len(results) != 1 || results[0].status != model.ScanStatusClean || results[0].filePath != upload.FilePath {
+		t.Fatalf("Expected the upload stored clean in place, got %+v", results)
+	}
+	if len(publisher.roomEvents) != 1 || publisher.roomEvents[0].eventType != EventUploadScanned {
+		t.Fatalf("Expected upload_scanned pushed to the room, got %+v", publisher.roomEvents)
+	}
+	if event := publisher.roomEvents[0].payload.(*UploadScannedEvent); event.ScanStatus != model.ScanStatusClean {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
+
+func TestUploadScanner_QuarantinesInfected(t *testing.T) {
+	var results []scanResult
+	s, _ := newTestUploadScanner(t, &fakeAntivirus{}, &results)
+	defer s.Close()
+	upload := writeUpload(t, "X5O!P%@AP EICAR")
+
+	s.process(upload)
+
+	if len(results) != 1 || results[0].status != model.ScanStatusInfected || results[0].signature != "Eicar-Signature" {
+		t.Fatalf("Expected the upload stored infected, got %+v", results)
+	}
+	if _, err := os.Stat(upload.FilePath); !os.IsNotExist(err) {
+		t.Error("Expected the file removed from the uploads directory")
+	}
+	quarantined := results[0].filePath
+	if filepath.Dir(quarantined) != s.quarantineDir {
+		t.Fatalf("Expected the file moved to quarantine, got %q", quarantined)
+	}
+	if _, err := os.Stat(quarantined); err != nil {
+		t.Errorf("Expected the quarantined file kept, got %v", err)
+	}
+}
+
+func TestUploadScanner_ScannerFailureLeavesPending(t *testing.T) {
+	var results []scanResult
+	s, publisher := newTestUploadScanner(t, &fakeAntivirus{err: errors.New("clamd down")}, &results)
+	defer s.Close()
+
+	s.process(writeUpload(t, "EICAR"))
+
+	if len(results) != 0 || len(publisher.roomEvents) != 0 {
+		t.Errorf("Expected nothing stored or pushed, got %+v", results)
+	}
+}
+
+func TestUploadScanner_Enqueue(t *testing.T) {
+	var results []scanResult
+	s, _ := newTestUploadScanner(t, &fakeAntivirus{}, &results)
+
+	unscanned := writeUpload(t, "old")
+	unscanned.ScanStatus = model.ScanStatusUnscanned
+	s.Enqueue(unscanned)
+	s.Enqueue(writeUpload(t, "new"))
+	s.Close()
+
+	if len(results) != 1 || results[0].status != model.ScanStatusClean {
+		t.Errorf("Expected only the pending upload scanned, got %+v", results)
+	}
+
+	var disabled *UploadScanner
+	disabled.Enqueue(writeUpload(t, "new"))
+	disabled.Close()
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultUploadSessionTTL is how long an upload may sit idle before it is
+	// discarded; every chunk restarts the clock
+	DefaultUploadSessionTTL = 24 * time.Hour
+	// MaxPendingUploads caps the pending uploads listed per user
+	MaxPendingUploads = 50
+)
+
+var (
+	ErrUploadSessionNotFound = apperrors.New(http.StatusNotFound, "上傳工作不存在或已過期")
+	ErrUploadChunkTooLarge   = apperrors.New(http.StatusRequestEntityTooLarge, "上傳內容超過宣告的檔案大小")
+)
+
+// uploadOffsetMismatch tells the client where to resume from
+func uploadOffsetMismatch(received int64) error {
+	return apperrors.New(http.StatusConflict, "上傳位置不符，請由目前進度續傳").
+		WithDetails(map[string]int64{"offset": received})
+}
+
+// UploadSessionService manages resumable uploads. Chunks are written to a
+// partial file named after the session and moved into place once complete.
+type UploadSessionService struct {
+	repo       *repository.UploadSessionRepository
+	partialDir string
+	ttl        time.Duration
+	logger     *zap.Logger
+}
+
+func NewUploadSessionService(repo *repository.UploadSessionRepository, partialDir string, logger *zap.Logger) *UploadSessionService {
+	_ = os.MkdirAll(partialDir, 0755)
+
+	return &UploadSessionService{
+		repo:       repo,
+		partialDir: partialDir,
+		ttl:        DefaultUploadSessionTTL,
+		logger:     logger,
+	}
+}
+
+// SetSessionTTL sets how long an idle upload is kept
+func (s *UploadSessionService) SetSessionTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.ttl = ttl
+	}
+}
+
+// CreateUploadInput describes a file about to be uploaded in chunks. The
+// caller has already checked the category's size and type limits.
+type CreateUploadInput struct {
+	UserID      string
+	Category    model.UploadCategory
+	FileName    string
+	ContentType string
+	TotalSize   int64
+}
+
+// Create starts a resumable upload
+func (s *UploadSessionService) Create(ctx context.Context, input *CreateUploadInput) (*model.UploadSession, error) {
+	session := &model.UploadSession{
+		UserID:      input.UserID,
+		Category:    input.Category,
+		FileName:    input.FileName,
+		ContentType: input.ContentType,
+		TotalSize:   input.TotalSize,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	if err := s.repo.Create(ctx, session); err != nil {
+		s.logger.Error("Failed to create upload session", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return session, nil
+}
+
+// Get returns one of the user's upload sessions
+func (s *UploadSessionService) Get(ctx context.Context, id, userID string) (*model.UploadSession, error) {
+	session, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrUploadSessionNotFound {
+			return nil, ErrUploadSessionNotFound
+		}
+		s.logger.Error("Failed to get upload session", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if session.UserID != userID {
+		return nil, ErrUploadSessionNotFound
+	}
+	return session, nil
+}
+
+// ListPending lists the user's unfinished uploads so a restarted client can
+// resume or abandon them
+func (s *UploadSessionService) ListPending(ctx context.Context, userID string) ([]*model.UploadSession, error) {
+	sessions, err := s.repo.ListPending(ctx, userID, MaxPendingUploads)
+	if err != nil {
+		s.logger.Error("Failed to list pending uploads", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return sessions, nil
+}
+
+// AppendChunk writes the bytes from r at offset, which must equal what the
+// server has received so far. A chunk may be empty, which lets a client
+// re-trigger completion after the last chunk's response was lost.
+func (s *UploadSessionService) AppendChunk(ctx context.Context, id, userID string, offset int64, r io.Reader) (*model.UploadSession, error) {
+	session, err := s.Get(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsComplete() {
+		return session, nil
+	}
+	if offset != session.ReceivedSize {
+		return nil, uploadOffsetMismatch(session.ReceivedSize)
+	}
+
+	written, err := s.writeAt(id, offset, io.LimitReader(r, session.TotalSize-offset+1))
+	if err != nil {
+		s.logger.Error("Failed to write upload chunk", zap.String("upload_id", id), zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	if offset+written > session.TotalSize {
+		_ = os.Truncate(s.partialPath(id), offset)
+		return nil, ErrUploadChunkTooLarge
+	}
+
+	advanced, err := s.repo.Advance(ctx, id, offset, offset+written, time.Now().Add(s.ttl))
+	if err != nil {
+		if err == repository.ErrUploadOffsetConflict {
+			// Another request moved the session on first
+			current, getErr := s.Get(ctx, id, userID)
+			if getErr != nil {
+				return nil, getErr
+			}
+			return nil, uploadOffsetMismatch(current.ReceivedSize)
+		}
+		s.logger.Error("Failed to advance upload session", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return advanced, nil
+}
+
+// Complete moves a fully received upload to dir/name and marks the session
+// complete
+func (s *UploadSessionService) Complete(ctx context.Context, session *model.UploadSession, dir, name string) (*model.UploadSession, error) {
+	if session.IsComplete() {
+		return session, nil
+	}
+	if session.ReceivedSize != session.TotalSize {
+		return nil, uploadOffsetMismatch(session.ReceivedSize)
+	}
+
+	if err := moveFile(s.partialPath(session.ID), filepath.Join(dir, name)); err != nil {
+		s.logger.Error("Failed to store completed upload", zap.String("upload_id", session.ID), zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	completed, err := s.repo.Complete(ctx, session.ID, name)
+	if err != nil {
+		if err == repository.ErrUploadSessionNotFound {
+			return nil, ErrUploadSessionNotFound
+		}
+		s.logger.Error("Failed to complete upload session", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return completed, nil
+}
+
+// Abandon discards an upload and whatever was received of it
+func (s *UploadSessionService) Abandon(ctx context.Context, id, userID string) error {
+	session, err := s.Get(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if err == repository.ErrUploadSessionNotFound {
+			return ErrUploadSessionNotFound
+		}
+		s.logger.Error("Failed to delete upload session", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	if !session.IsComplete() {
+		s.removePartial(id)
+	}
+	return nil
+}
+
+// PurgeExpired deletes up to limit expired sessions and their partial
+// files. Completed files stay where they are.
+func (s *UploadSessionService) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	sessions, err := s.repo.DeleteExpired(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+	for _, session := range sessions {
+		if !session.IsComplete() {
+			s.removePartial(session.ID)
+		}
+	}
+	if len(sessions) > 0 {
+		s.logger.Info("Expired upload sessions purged", zap.Int("count", len(sessions)))
+	}
+	return len(sessions), nil
+}
+
+func (s *UploadSessionService) partialPath(id string) string {
+	return filepath.Join(s.partialDir, id)
+}
+
+func (s *UploadSessionService) removePartial(id string) {
+	if err := os.Remove(s.partialPath(id)); err != nil && !os.IsNotExist(err) {
+		s.logger.Warn("Failed to remove partial upload", zap.String("upload_id", id), zap.Error(err))
+	}
+}
+
+// writeAt copies r into the partial file starting at offset
+func (s *UploadSessionService) writeAt(id string, offset int64, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.partialPath(id), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.Copy(f, r)
+}
+
+// moveFile renames src to dst, copying when they are on different devices
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy upload: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+func setupTestUploadSessionServiceIsolated(t *testing.T) (*UploadSessionService, *sqlx.DB, string) {
+	t.Helper()
+
+	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=chat_test sslmode=disable"
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Skipf("Skipping test, could not connect to test database: %v", err)
+	}
+
+	service := NewUploadSessionService(repository.NewUploadSessionRepository(db), t.TempDir(), zap.NewNop())
+	return service, db, repository.GenerateUniquePrefix()
+}
+
+func TestUploadSessionService_Resume(t *testing.T) {
+	service, db, prefix := setupTestUploadSessionServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "uploader")
+	other := repository.CreateIsolatedTestUser(t, db, prefix, "other")
+
+	session, err := service.Create(ctx, &CreateUploadInput{
+		UserID:      user.ID,
+		Category:    model.UploadCategoryFile,
+		FileName:    "notes.txt",
+		ContentType: "text/plain",
+		TotalSize:   11,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create upload session: %v", err)
+	}
+
+	if _, err := service.AppendChunk(ctx, session.ID, user.ID, 0, strings.NewReader("hello ")); err != nil {
+		t.Fatalf("Failed to append chunk: %v", err)
+	}
+
+	// After a restart the client finds the upload and where to resume
+	pending, err := service.ListPending(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to list pending uploads: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ReceivedSize != 6 {
+		t.Fatalf("Expected one pending upload at offset 6, got %+v", pending)
+	}
+	if others, _ := service.ListPending(ctx, other.ID); len(others) != 0 {
+		t.Errorf("Expected no pending uploads for another user, got %d", len(others))
+	}
+
+	_, err = service.AppendChunk(ctx, session.ID, user.ID, 0, strings.NewReader("again"))
+	if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Code != 409 {
+		t.Errorf("Expected offset conflict, got %v", err)
+	}
+	if _, err := service.AppendChunk(ctx, session.ID, user.ID, 6, strings.NewReader("world!!")); err != ErrUploadChunkTooLarge {
+		t.Errorf("Expected ErrUploadChunkTooLarge, got %v", err)
+	}
+
+	session, err = service.AppendChunk(ctx, session.ID, user.ID, 6, strings.NewReader("world"))
+	if err != nil {
+		t.Fatalf("Failed to append last chunk: %v", err)
+	}
+
+	dir := t.TempDir()
+	session, err = service.Complete(ctx, session, dir, "notes.txt")
+	if err != nil {
+		t.Fatalf("Failed to complete upload: %v", err)
+	}
+	if !session.IsComplete() {
+		t.Error("Expected session to be complete")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	if err != nil || string(data) != "hello world" {
+		t.Errorf("Expected stored file to read %q, got %q (%v)", "hello world", data, err)
+	}
+	if pending, _ := service.ListPending(ctx, user.ID); len(pending) != 0 {
+		t.Errorf("Expected completed upload to leave the pending list, got %d", len(pending))
+	}
+}
+
+func TestUploadSessionService_Abandon(t *testing.T) {
+	service, db, prefix := setupTestUploadSessionServiceIsolated(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	user := repository.CreateIsolatedTestUser(t, db, prefix, "uploader")
+	other := repository.CreateIsolatedTestUser(t, db, prefix, "other")
+
+	session, err := service.Create(ctx, &CreateUploadInput{
+		UserID:      user.ID,
+		Category:    model.UploadCategoryImage,
+		FileName:    "photo.png",
+		ContentType: "image/png",
+		TotalSize:   4,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create upload session: %v", err)
+	}
+	if _, err := service.AppendChunk(ctx, session.ID, user.ID, 0, strings.NewReader("ab")); err != nil {
+		t.Fatalf("Failed to append chunk: %v", err)
+	}
+
+	if err := service.Abandon(ctx, session.ID, other.ID); err != ErrUploadSessionNotFound {
+		t.Errorf("Expected ErrUploadSessionNotFound for another user, got %v", err)
+	}
+	if err := service.Abandon(ctx, session.ID, user.ID); err != nil {
+		t.Fatalf("Failed to abandon upload: %v", err)
+	}
+	if _, err := os.Stat(service.partialPath(session.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected partial file to be removed, got %v", err)
+	}
+	if _, err := service.Get(ctx, session.ID, user.ID); err != ErrUploadSessionNotFound {
+		t.Errorf("Expected ErrUploadSessionNotFound after abandoning, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"mime"
+	"strings"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// UploadSettingsService resolves the upload limits in effect: the
+// configured limits, with the overrides admins saved at runtime on top
+type UploadSettingsService struct {
+	repo     UploadSettingsStore
+	defaults model.UploadLimits
+	auditor  *AuditService
+	logger   *zap.Logger
+}
+
+func NewUploadSettingsService(repo UploadSettingsStore, defaults model.UploadLimits, logger *zap.Logger) *UploadSettingsService {
+	return &UploadSettingsService{
+		repo:     repo,
+		defaults: defaults,
+		logger:   logger,
+	}
+}
+
+// SetAuditor sets the audit service that records settings changes
+func (s *UploadSettingsService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// Defaults returns the configured limits, before any override
+func (s *UploadSettingsService) Defaults() model.UploadLimits {
+	if s == nil {
+		return model.DefaultUploadLimits()
+	}
+	return s.defaults
+}
+
+// Limits returns the limits in effect. When the overrides cannot be read
+// the configured limits apply, so uploads keep working.
+func (s *UploadSettingsService) Limits(ctx context.Context) *model.UploadLimits {
+	limits := s.Defaults()
+	if s == nil || s.repo == nil {
+		return &limits
+	}
+
+	settings, err := s.repo.Get(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to get upload settings, using configured limits", zap.Error(err))
+		return &limits
+	}
+	limits = settings.Apply(limits)
+	return &limits
+}
+
+// GetSettings returns the overrides admins saved
+func (s *UploadSettingsService) GetSettings(ctx context.Context) (*model.UploadSettings, error) {
+	settings, err := s.repo.Get(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get upload settings", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return settings, nil
+}
+
+// UploadLimitOverride changes the limits of one category. A nil field is
+// left as it is; a zero size or an empty type list removes the override
+// so the configured value applies again.
+type UploadLimitOverride struct {
+	MaxSize      *int64
+	AllowedTypes []string
+}
+
+// UpdateUploadSettingsInput changes the upload limits; nil categories are
+// left as they are
+type UpdateUploadSettingsInput struct {
+	Image  *UploadLimitOverride
+	File   *UploadLimitOverride
+	Avatar *UploadLimitOverride
+}
+
+// UpdateSettings saves new overrides
+func (s *UploadSettingsService) UpdateSettings(ctx context.Context, input *UpdateUploadSettingsInput, adminID string) (*model.UploadSettings, error) {
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make(map[string]string)
+	overrides := []struct {
+		name         string
+		override     *UploadLimitOverride
+		maxSize      *sql.NullInt64
+		allowedTypes *[]string
+	}{
+		{"image", input.Image, &settings.ImageMaxSize, (*[]string)(&settings.ImageAllowedTypes)},
+		{"file", input.File, &settings.FileMaxSize, (*[]string)(&settings.FileAllowedTypes)},
+		{"avatar", input.Avatar, &settings.AvatarMaxSize, (*[]string)(&settings.AvatarAllowedTypes)},
+	}
+	for _, o := range overrides {
+		if o.override == nil {
+			continue
+		}
+		if size := o.override.MaxSize; size != nil {
+			switch {
+			case *size < 0:
+				details[o.name+".max_size"] = "大小上限不能為負數"
+			case *size == 0:
+				*o.maxSize = sql.NullInt64{}
+			default:
+				*o.maxSize = sql.NullInt64{Int64: *size, Valid: true}
+			}
+		}
+		if o.override.AllowedTypes != nil {
+			types, ok := normalizeContentTypes(o.override.AllowedTypes)
+			if !ok {
+				details[o.name+".allowed_types"] = "無效的 MIME 類型"
+				continue
+			}
+			*o.allowedTypes = types
+		}
+	}
+	if len(details) > 0 {
+		return nil, apperrors.ErrValidation.WithDetails(details)
+	}
+	settings.UpdatedBy = nullString(adminID)
+
+	if err := s.repo.Update(ctx, settings); err != nil {
+		s.logger.Error("Failed to update upload settings", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+
+	limits := settings.Apply(s.defaults)
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    adminID,
+		Action:     model.AuditActionUploadSettingsUpdated,
+		TargetType: model.AuditTargetUpload,
+		Metadata: map[string]interface{}{
+			"image":  limits.Image,
+			"file":   limits.File,
+			"avatar": limits.Avatar,
+		},
+	})
+	return settings, nil
+}
+
+// normalizeContentTypes lower-cases and de-duplicates MIME types. An empty
+// list normalizes to nil, which clears the override.
+func normalizeContentTypes(types []string) ([]string, bool) {
+	if len(types) == 0 {
+		return nil, true
+	}
+
+	seen := make(map[string]bool, len(types))
+	normalized := make([]string, 0, len(types))
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		mediaType, params, err := mime.ParseMediaType(t)
+		if err != nil || mediaType != t || len(params) > 0 || strings.Count(t, "/") != 1 {
+			return nil, false
+		}
+		if !seen[t] {
+			seen[t] = true
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized, true
+}
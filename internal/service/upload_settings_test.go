@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+func TestUploadSettings_Apply(t *testing.T) {
+	settings := &model.UploadSettings{
+		ImageMaxSize:       sql.NullInt64{Int64: 8 << 20, Valid: true},
+		AvatarAllowedTypes: pq.StringArray{"image/png"},
+	}
+	limits := settings.Apply(model.DefaultUploadLimits())
+
+	if limits.Image.MaxSize != 8<<20 || len(limits.Image.AllowedTypes) != 4 {
+		t.Errorf("Unexpected image limits: %+v", limits.Image)
+	}
+	if limits.Avatar.MaxSize != 2<<20 || !limits.Avatar.Allows("image/png") || limits.Avatar.Allows("image/jpeg") {
+		t.Errorf("Unexpected avatar limits: %+v", limits.Avatar)
+	}
+	if limits.File.SubDir != "files" || !limits.File.Allows("application/pdf") {
+		t.Errorf("Expected file limits untouched, got %+v", limits.File)
+	}
+}
+
+func TestUploadSettingsService_NilUsesDefaults(t *testing.T) {
+	var svc *UploadSettingsService
+	limits := svc.Limits(context.Background())
+	if limits.Image.MaxSize != 5<<20 || limits.Avatar.SubDir != "avatars" {
+		t.Errorf("Expected default limits, got %+v", limits)
+	}
+}
+
+func TestNormalizeContentTypes(t *testing.T) {
+	types, ok := normalizeContentTypes([]string{" Image/PNG ", "image/png", "application/pdf"})
+	if !ok || len(types) != 2 || types[0] != "image/png" || types[1] != "application/pdf" {
+		t.Errorf("Unexpected result %v %v", types, ok)
+	}
+
+	if types, ok := normalizeContentTypes([]string{}); !ok || types != nil {
+		t.Errorf("Expected an empty list to clear the override, got %v %v", types, ok)
+	}
+
+	for _, invalid := range []string{"png", "image/png; q=1", "image/*/x", ""} {
+		if _, ok := normalizeContentTypes([]string{invalid}); ok {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestUploadSettingsService_Limits_Unit(t *testing.T) {
+	ctx := context.Background()
+	defaults := model.DefaultUploadLimits()
+	store := &mockUploadSettingsStore{}
+	service := NewUploadSettingsService(store, defaults, zap.NewNop())
+
+	store.GetFunc = func(ctx context.Context) (*model.UploadSettings, error) {
+		return &model.UploadSettings{ImageMaxSize: sql.NullInt64{Int64: 1024, Valid: true}}, nil
+	}
+	if limits := service.Limits(ctx); limits.Image.MaxSize != 1024 || limits.File.MaxSize != defaults.File.MaxSize {
+		t.Errorf("Expected the image override on top of the defaults, got %+v", limits)
+	}
+
+	store.GetFunc = func(ctx context.Context) (*model.UploadSettings, error) {
+		return nil, errors.New("connection reset")
+	}
+	if limits := service.Limits(ctx); limits.Image.MaxSize != defaults.Image.MaxSize {
+		t.Errorf("Expected the defaults when overrides cannot be read, got %+v", limits)
+	}
+}
+
+func TestUploadSettingsService_UpdateSettings_Unit(t *testing.T) {
+	ctx := context.Background()
+	store := &mockUploadSettingsStore{}
+	service := NewUploadSettingsService(store, model.DefaultUploadLimits(), zap.NewNop())
+
+	negative := int64(-1)
+	_, err := service.UpdateSettings(ctx, &UpdateUploadSettingsInput{Image: &UploadLimitOverride{MaxSize: &negative}}, "admin")
+	if !apperrors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+	if store.Calls("Update") != 0 {
+		t.Error("Expected invalid settings not to be saved")
+	}
+
+	var saved *model.UploadSettings
+	store.UpdateFunc = func(ctx context.Context, settings *model.UploadSettings) error {
+		saved = settings
+		return nil
+	}
+	size := int64(2048)
+	if _, err := service.UpdateSettings(ctx, &UpdateUploadSettingsInput{
+		File: &UploadLimitOverride{MaxSize: &size, AllowedTypes: []string{"Text/Plain", "text/plain"}},
+	}, "admin"); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	if saved == nil || saved.FileMaxSize.Int64 != 2048 || len(saved.FileAllowedTypes) != 1 || saved.UpdatedBy.String != "admin" {
+		t.Errorf("Unexpected saved settings %+v", saved)
+	}
+}
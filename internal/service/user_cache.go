@@ -0,0 +1,162 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DefaultUserCacheSize bounds how many users a UserCache remembers
+const DefaultUserCacheSize = 10000
+
+// userChangedChannel carries the ID of a user whose display data changed
+const userChangedChannel = "users:changed"
+
+// UserCache keeps the display data of recently seen users in memory, so
+// broadcasts do not look the sender up for every message. When full, the
+// least recently used user is evicted. Entries expire after the TTL; a
+// profile change drops the entry here right away and, through Redis, on
+// the other instances.
+type UserCache struct {
+	ttl        time.Duration
+	maxEntries int
+	redis      *redis.Client
+	logger     *zap.Logger
+
+	mu      sync.Mutex
+	order   *list.List // front is the most recently used
+	entries map[string]*list.Element
+}
+
+type userCacheEntry struct {
+	user      *model.UserDisplay
+	expiresAt time.Time
+}
+
+// NewUserCache creates a cache keeping display data for up to ttl. A
+// non-positive maxEntries uses DefaultUserCacheSize; without redisClient
+// changes are only dropped on this instance.
+func NewUserCache(ttl time.Duration, maxEntries int, redisClient *redis.Client, logger *zap.Logger) *UserCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultUserCacheSize
+	}
+	return &UserCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		redis:      redisClient,
+		logger:     logger,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached display data of a user
+func (c *UserCache) Get(id string) (*model.UserDisplay, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*userCacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.user, true
+}
+
+// Put caches a user's display data
+func (c *UserCache) Put(user *model.UserDisplay) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	entry := &userCacheEntry{user: user, expiresAt: time.Now().Add(c.ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[user.ID]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	if c.order.Len() >= c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	c.entries[user.ID] = c.order.PushFront(entry)
+}
+
+// Len returns the number of cached users
+func (c *UserCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Invalidate drops a user whose display data changed, here and on the
+// other instances
+func (c *UserCache) Invalidate(ctx context.Context, userID string) {
+	if c == nil {
+		return
+	}
+	c.drop(userID)
+	if c.redis == nil {
+		return
+	}
+	if err := c.redis.Publish(ctx, userChangedChannel, userID).Err(); err != nil {
+		c.logger.Warn("Failed to publish user change", zap.String("user_id", userID), zap.Error(err))
+	}
+}
+
+// Watch drops users whose change another instance published. It blocks
+// until ctx is canceled.
+func (c *UserCache) Watch(ctx context.Context) {
+	if c == nil || c.redis == nil {
+		return
+	}
+
+	pubsub := c.redis.Subscribe(ctx, userChangedChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.drop(msg.Payload)
+		}
+	}
+}
+
+func (c *UserCache) drop(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[userID]; ok {
+		c.remove(elem)
+	}
+}
+
+// remove deletes one entry; the caller holds mu
+func (c *UserCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*userCacheEntry).user.ID)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"go.uber.org/zap"
+)
+
+func TestUserCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewUserCache(time.Minute, 2, nil, zap.NewNop())
+
+	cache.Put(&model.UserDisplay{ID: "a", Username: "alice"})
+	cache.Put(&model.UserDisplay{ID: "b", Username: "bob"})
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+
+	// b is now the least recently used
+	cache.Put(&model.UserDisplay{ID: "c", Username: "carol"})
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("Expected a to stay cached")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached users, got %d", cache.Len())
+	}
+}
+
+func TestUserCache_Expiry(t *testing.T) {
+	cache := NewUserCache(time.Minute, 0, nil, zap.NewNop())
+	cache.Put(&model.UserDisplay{ID: "a", Username: "alice"})
+
+	elem := cache.entries["a"]
+	elem.Value.(*userCacheEntry).expiresAt = time.Now().Add(-time.Second)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected expired entry to be missed")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected expired entry to be removed, got %d", cache.Len())
+	}
+}
+
+func TestUserCache_PutReplaces(t *testing.T) {
+	cache := NewUserCache(time.Minute, 0, nil, zap.NewNop())
+	cache.Put(&model.UserDisplay{ID: "a", DisplayName: "Alice"})
+	cache.Put(&model.UserDisplay{ID: "a", DisplayName: "Alicia"})
+
+	user, ok := cache.Get("a")
+	if !ok || user.DisplayName != "Alicia" {
+		t.Errorf("Expected replaced entry, got %+v", user)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 cached user, got %d", cache.Len())
+	}
+}
+
+func TestUserCache_Invalidate(t *testing.T) {
+	cache := NewUserCache(time.Minute, 0, nil, zap.NewNop())
+	cache.Put(&model.UserDisplay{ID: "a", Username: "alice"})
+
+	cache.Invalidate(context.Background(), "a")
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected invalidated entry to be missed")
+	}
+}
+
+func TestUserCache_DisabledAndNil(t *testing.T) {
+	disabled := NewUserCache(0, 0, nil, zap.NewNop())
+	disabled.Put(&model.UserDisplay{ID: "a"})
+	if disabled.Len() != 0 {
+		t.Error("Expected a zero TTL to disable caching")
+	}
+
+	var cache *UserCache
+	cache.Put(&model.UserDisplay{ID: "a"})
+	cache.Invalidate(context.Background(), "a")
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected nil cache to miss")
+	}
+}
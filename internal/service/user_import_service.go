@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	netmail "net/mail"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/go-demo/chat/internal/mail"
+	"github.com/go-demo/chat/internal/mail/templates"
+	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxUserImportRows caps a single import request
+	MaxUserImportRows = 1000
+
+	// userImportBatchSize is how many rows are checked and created together
+	userImportBatchSize = 100
+)
+
+// Import roles
+const (
+	ImportRoleUser  = "user"
+	ImportRoleAdmin = "admin"
+)
+
+// Per-row import outcomes
+const (
+	UserImportCreated = "created"
+	UserImportSkipped = "skipped" // username or email already taken
+	UserImportFailed  = "failed"
+)
+
+var (
+	ErrImportEmpty   = apperrors.New(400, "匯入資料沒有任何用戶")
+	ErrImportTooMany = apperrors.New(400, fmt.Sprintf("單次最多匯入 %d 位用戶", MaxUserImportRows))
+	ErrImportCSV     = apperrors.New(400, "CSV 格式錯誤，需包含 username 與 email 欄位")
+)
+
+type UserImportService struct {
+	userRepo *repository.UserRepository
+	renderer *templates.Renderer
+	sender   mail.Sender
+	auditor  *AuditService
+	logger   *zap.Logger
+}
+
+func NewUserImportService(userRepo *repository.UserRepository, logger *zap.Logger) *UserImportService {
+	return &UserImportService{
+		userRepo: userRepo,
+		logger:   logger,
+	}
+}
+
+// SetMailer sets the templates and sender used for invitation emails
+func (s *UserImportService) SetMailer(renderer *templates.Renderer, sender mail.Sender) {
+	s.renderer = renderer
+	s.sender = sender
+}
+
+// SetAuditor sets the audit service that records imported accounts
+func (s *UserImportService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
+// UserImportRow is one account to create
+type UserImportRow struct {
+	Username string
+	Email    string
+	Role     string // user (default) or admin
+	Password string // initial password; empty generates one, which requires an invitation
+}
+
+// UserImportInput represents bulk import input
+type UserImportInput struct {
+	Rows       []*UserImportRow
+	Invite     bool // email every created user their credentials
+	ImportedBy string
+}
+
+// UserImportResult is the outcome of a single row
+type UserImportResult struct {
+	Row      int // 1-based position in the input
+	Username string
+	Email    string
+	Status   string
+	UserID   string
+	Invited  bool
+	Error    string
+}
+
+// UserImportSummary is the outcome of an import
+type UserImportSummary struct {
+	Created int
+	Skipped int
+	Failed  int
+	Results []*UserImportResult
+}
+
+// pendingImport is a validated row waiting to be created
+type pendingImport struct {
+	result   *UserImportResult
+	row      *UserImportRow
+	password string
+	hash     string
+}
+
+// ParseUserImportCSV reads rows from CSV with a header line. The username
+// and email columns are required; role and password are optional and
+// unknown columns are ignored.
+func ParseUserImportCSV(r io.Reader) ([]*UserImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrImportCSV
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, ErrImportCSV
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, ErrImportCSV
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []*UserImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrImportCSV
+		}
+		if len(rows) == MaxUserImportRows {
+			return nil, ErrImportTooMany
+		}
+		rows = append(rows, &UserImportRow{
+			Username: field(record, "username"),
+			Email:    field(record, "email"),
+			Role:     field(record, "role"),
+			Password: field(record, "password"),
+		})
+	}
+
+	return rows, nil
+}
+
+// Import creates accounts in batches. Rows are independent: a bad or
+// conflicting row is reported in its result and does not stop the others.
+func (s *UserImportService) Import(ctx context.Context, input *UserImportInput) (*UserImportSummary, error) {
+	if len(input.Rows) == 0 {
+		return nil, ErrImportEmpty
+	}
+	if len(input.Rows) > MaxUserImportRows {
+		return nil, ErrImportTooMany
+	}
+
+	invite := input.Invite && s.sender != nil && s.renderer != nil
+	summary := &UserImportSummary{Results: make([]*UserImportResult, len(input.Rows))}
+
+	// Duplicates within the file itself are caught here; the batches only
+	// check against existing accounts
+	seenUsernames := make(map[string]bool, len(input.Rows))
+	seenEmails := make(map[string]bool, len(input.Rows))
+
+	var pending []*pendingImport
+	for i, row := range input.Rows {
+		row.Username = strings.TrimSpace(row.Username)
+		row.Email = strings.ToLower(strings.TrimSpace(row.Email))
+		row.Role = strings.ToLower(strings.TrimSpace(row.Role))
+
+		result := &UserImportResult{Row: i + 1, Username: row.Username, Email: row.Email}
+		summary.Results[i] = result
+
+		if msg := validateImportRow(row, invite); msg != "" {
+			result.Status, result.Error = UserImportFailed, msg
+			continue
+		}
+		if seenUsernames[row.Username] || seenEmails[row.Email] {
+			result.Status, result.Error = UserImportSkipped, "與匯入資料中的其他列重複"
+			continue
+		}
+		seenUsernames[row.Username] = true
+		seenEmails[row.Email] = true
+
+		pending = append(pending, &pendingImport{result: result, row: row, password: row.Password})
+	}
+
+	for start := 0; start < len(pending); start += userImportBatchSize {
+		if err := checkContext(ctx); err != nil {
+			return nil, err
+		}
+
+		end := start + userImportBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		if err := s.importBatch(ctx, pending[start:end], input, invite); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, result := range summary.Results {
+		switch result.Status {
+		case UserImportCreated:
+			summary.Created++
+		case UserImportSkipped:
+			summary.Skipped++
+		default:
+			summary.Failed++
+		}
+	}
+
+	s.logger.Info("Users imported",
+		zap.String("imported_by", input.ImportedBy),
+		zap.Int("created", summary.Created),
+		zap.Int("skipped", summary.Skipped),
+		zap.Int("failed", summary.Failed),
+	)
+
+	return summary, nil
+}
+
+// validateImportRow returns a user-facing reason the row is unusable, or ""
+func validateImportRow(row *UserImportRow, invite bool) string {
+	if len(row.Username) < 3 || len(row.Username) > 50 {
+		return "使用者名稱長度需為 3 到 50 個字元"
+	}
+	if addr, err := netmail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+		return "無效的電子郵件"
+	}
+	switch row.Role {
+	case "", ImportRoleUser, ImportRoleAdmin:
+	default:
+		return "無效的角色，僅支援 user 或 admin"
+	}
+	if row.Password == "" {
+		if !invite {
+			return "需提供初始密碼或寄送邀請信"
+		}
+		return ""
+	}
+	if err := utils.ValidatePassword(row.Password); err != nil {
+		return "密碼長度需為 8 到 72 個字元"
+	}
+	return ""
+}
+
+// importBatch creates one batch of validated rows
+func (s *UserImportService) importBatch(ctx context.Context, batch []*pendingImport, input *UserImportInput, invite bool) error {
+	usernames := make([]string, len(batch))
+	emails := make([]string, len(batch))
+	for i, p := range batch {
+		usernames[i] = p.row.Username
+		emails[i] = p.row.Email
+	}
+
+	existing, err := s.userRepo.FindByUsernamesOrEmails(ctx, usernames, emails)
+	if err != nil {
+		s.logger.Error("Failed to check existing users", zap.Error(err))
+		return apperrors.ErrInternal
+	}
+	takenUsernames := make(map[string]bool, len(existing))
+	takenEmails := make(map[string]bool, len(existing))
+	for _, u := range existing {
+		takenUsernames[u.Username] = true
+		takenEmails[strings.ToLower(u.Email)] = true
+	}
+
+	var toCreate []*pendingImport
+	for _, p := range batch {
+		switch {
+		case takenUsernames[p.row.Username]:
+			p.result.Status, p.result.Error = UserImportSkipped, "使用者名稱已存在"
+		case takenEmails[p.row.Email]:
+			p.result.Status, p.result.Error = UserImportSkipped, "電子郵件已存在"
+		default:
+			toCreate = append(toCreate, p)
+		}
+	}
+
+	s.hashPasswords(toCreate)
+
+	for _, p := range toCreate {
+		if p.hash == "" {
+			p.result.Status, p.result.Error = UserImportFailed, "建立帳號失敗"
+			continue
+		}
+
+		user := &model.User{
+			Username:     p.row.Username,
+			Email:        p.row.Email,
+			PasswordHash: p.hash,
+			Status:       model.UserStatusOffline,
+			IsAdmin:      p.row.Role == ImportRoleAdmin,
+		}
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			if errors.Is(err, repository.ErrUserAlreadyExists) {
+				p.result.Status, p.result.Error = UserImportSkipped, "使用者名稱或電子郵件已存在"
+				continue
+			}
+			s.logger.Error("Failed to create imported user", zap.String("username", user.Username), zap.Error(err))
+			p.result.Status, p.result.Error = UserImportFailed, "建立帳號失敗"
+			continue
+		}
+
+		p.result.Status = UserImportCreated
+		p.result.UserID = user.ID
+
+		if invite {
+			if err := s.sendInvitation(ctx, user, p.password); err != nil {
+				s.logger.Warn("Failed to send invitation", zap.String("user_id", user.ID), zap.Error(err))
+				p.result.Error = "帳號已建立，但邀請信寄送失敗"
+			} else {
+				p.result.Invited = true
+			}
+		}
+
+		s.auditor.Record(ctx, &AuditEntry{
+			ActorID:    input.ImportedBy,
+			Action:     model.AuditActionUserImported,
+			TargetType: model.AuditTargetUser,
+			TargetID:   user.ID,
+			Metadata: map[string]interface{}{
+				"role":    roleName(user.IsAdmin),
+				"invited": p.result.Invited,
+			},
+		})
+	}
+
+	return nil
+}
+
+// hashPasswords fills in the password hash of each row, generating temporary
+// passwords where none was given. Hashing is deliberately slow, so rows are
+// hashed in parallel; rows that fail keep an empty hash.
+func (s *UserImportService) hashPasswords(batch []*pendingImport) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	for _, p := range batch {
+		if p.password == "" {
+			password, err := temporaryPassword()
+			if err != nil {
+				s.logger.Error("Failed to generate password", zap.Error(err))
+				continue
+			}
+			p.password = password
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(p *pendingImport) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			hash, err := utils.HashPassword(p.password)
+			if err != nil {
+				s.logger.Error("Failed to hash password", zap.Error(err))
+				return
+			}
+			p.hash = hash
+		}(p)
+	}
+
+	wg.Wait()
+}
+
+func (s *UserImportService) sendInvitation(ctx context.Context, user *model.User, password string) error {
+	site := s.renderer.Site()
+	msg, err := s.renderer.Render(templates.Invitation, s.renderer.DefaultLocale(), &templates.Data{
+		User: templates.User{
+			Username:    user.Username,
+			DisplayName: user.GetDisplayName(),
+			Email:       user.Email,
+		},
+		Vars: map[string]interface{}{
+			"Link":              site.URL + "/login",
+			"TemporaryPassword": password,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, user.Email, msg)
+}
+
+// temporaryPassword returns a random 16 character password
+func temporaryPassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func roleName(isAdmin bool) string {
+	if isAdmin {
+		return ImportRoleAdmin
+	}
+	return ImportRoleUser
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-demo/chat/internal/mail/templates"
+	"github.com/go-demo/chat/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type recordingSender struct {
+	mu   sync.Mutex
+	sent map[string]*templates.Message
+}
+
+func (s *recordingSender) Send(ctx context.Context, to string, msg *templates.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sent == nil {
+		s.sent = make(map[string]*templates.Message)
+	}
+	s.sent[to] = msg
+	return nil
+}
+
+func setupTestUserImportService(t *testing.T) (*UserImportService, *sqlx.DB, string) {
+	t.Helper()
+
+	dsn := "host=localhost port=5432 user=postgres password=postgres dbname=chat_test sslmode=disable"
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Skipf("Skipping test, could not connect to test database: %v", err)
+	}
+
+	service := NewUserImportService(repository.NewUserRepository(db), zap.NewNop())
+	prefix := repository.GenerateUniquePrefix()
+	return service, db, prefix
+}
+
+func TestParseUserImportCSV(t *testing.T) {
+	t.Run("Reads columns by header", func(t *testing.T) {
+		csv := "\ufeffEmail,Username,Role,Extra\nalice@example.com,alice,admin,x\nbob@example.com, bob\n"
+		rows, err := ParseUserImportCSV(strings.NewReader(csv))
+		if err != nil {
+			t.Fatalf("Failed to parse CSV: %v", err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("Expected 2 rows, got %d", len(rows))
+		}
+		if rows[0].Username != "alice" || rows[0].Email != "alice@example.com" || rows[0].Role != "admin" {
+			t.Errorf("Unexpected first row: %+v", rows[0])
+		}
+		if rows[1].Username != "bob" || rows[1].Role != "" || rows[1].Password != "" {
+			t.Errorf("Unexpected second row: %+v", rows[1])
+		}
+	})
+
+	t.Run("Requires username and email columns", func(t *testing.T) {
+		if _, err := ParseUserImportCSV(strings.NewReader("username,role\nalice,user\n")); err != ErrImportCSV {
+			t.Errorf("Expected ErrImportCSV, got %v", err)
+		}
+	})
+
+	t.Run("Rejects too many rows", func(t *testing.T) {
+		var b strings.Builder
+		b.WriteString("username,email\n")
+		for i := 0; i <= MaxUserImportRows; i++ {
+			b.WriteString("user,user@example.com\n")
+		}
+		if _, err := ParseUserImportCSV(strings.NewReader(b.String())); err != ErrImportTooMany {
+			t.Errorf("Expected ErrImportTooMany, got %v", err)
+		}
+	})
+}
+
+func TestValidateImportRow(t *testing.T) {
+	tests := []struct {
+		name   string
+		row    *UserImportRow
+		invite bool
+		valid  bool
+	}{
+		{"password given", &UserImportRow{Username: "alice", Email: "alice@example.com", Password: "password123"}, false, true},
+		{"invite without password", &UserImportRow{Username: "alice", Email: "alice@example.com"}, true, true},
+		{"no password and no invite", &UserImportRow{Username: "alice", Email: "alice@example.com"}, false, false},
+		{"short username", &UserImportRow{Username: "al", Email: "alice@example.com", Password: "password123"}, false, false},
+		{"invalid email", &UserImportRow{Username: "alice", Email: "Alice <alice@example.com>", Password: "password123"}, false, false},
+		{"unknown role", &UserImportRow{Username: "alice", Email: "alice@example.com", Role: "owner", Password: "password123"}, false, false},
+		{"short password", &UserImportRow{Username: "alice", Email: "alice@example.com", Password: "short"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateImportRow(tt.row, tt.invite) == ""; got != tt.valid {
+				t.Errorf("validateImportRow() valid = %v, expected %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestUserImportService_Import(t *testing.T) {
+	service, db, prefix := setupTestUserImportService(t)
+	defer db.Close()
+	defer repository.CleanupTestDataByPrefix(t, db, prefix)
+
+	ctx := context.Background()
+	existing := repository.CreateIsolatedTestUser(t, db, prefix, "existing")
+
+	renderer, err := templates.New(templates.Site{Name: "Test", URL: "http://localhost"}, "en", "")
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	sender := &recordingSender{}
+	service.SetMailer(renderer, sender)
+
+	summary, err := service.Import(ctx, &UserImportInput{
+		Rows: []*UserImportRow{
+			{Username: prefix + "_alice", Email: prefix + "_alice@example.com", Password: "password123"},
+			{Username: prefix + "_bob", Email: prefix + "_bob@example.com", Role: "admin"},
+			{Username: existing.Username, Email: prefix + "_taken@example.com", Password: "password123"},
+			{Username: prefix + "_alice", Email: prefix + "_dup@example.com", Password: "password123"},
+			{Username: prefix + "_bad", Email: "not-an-email", Password: "password123"},
+		},
+		Invite: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to import users: %v", err)
+	}
+
+	if summary.Created != 2 || summary.Skipped != 2 || summary.Failed != 1 {
+		t.Errorf("Expected 2 created, 2 skipped, 1 failed, got %d/%d/%d", summary.Created, summary.Skipped, summary.Failed)
+	}
+
+	expected := []string{UserImportCreated, UserImportCreated, UserImportSkipped, UserImportSkipped, UserImportFailed}
+	for i, result := range summary.Results {
+		if result.Row != i+1 {
+			t.Errorf("Expected row %d, got %d", i+1, result.Row)
+		}
+		if result.Status != expected[i] {
+			t.Errorf("Row %d: expected %s, got %s (%s)", i+1, expected[i], result.Status, result.Error)
+		}
+	}
+
+	bob, err := repository.NewUserRepository(db).GetByID(ctx, summary.Results[1].UserID)
+	if err != nil {
+		t.Fatalf("Failed to load imported user: %v", err)
+	}
+	if !bob.IsAdmin {
+		t.Error("Expected imported admin to have admin rights")
+	}
+
+	msg := sender.sent[prefix+"_bob@example.com"]
+	if msg == nil {
+		t.Fatal("Expected an invitation for the user without a password")
+	}
+	if !strings.Contains(msg.Text, bob.Username) {
+		t.Error("Expected invitation to contain the username")
+	}
+	if !summary.Results[0].Invited || !summary.Results[1].Invited {
+		t.Error("Expected created users to be marked as invited")
+	}
+}
+
+func TestUserImportService_RequiresPasswordWithoutInvite(t *testing.T) {
+	service := NewUserImportService(nil, zap.NewNop())
+
+	// Invitations are silently off without a mailer, so rows need passwords
+	summary, err := service.Import(context.Background(), &UserImportInput{
+		Rows:   []*UserImportRow{{Username: "alice", Email: "alice@example.com"}},
+		Invite: true,
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if summary.Failed != 1 || summary.Results[0].Status != UserImportFailed {
+		t.Errorf("Expected row to fail, got %+v", summary.Results[0])
+	}
+}
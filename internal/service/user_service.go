@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/go-demo/chat/internal/anomaly"
 	"github.com/go-demo/chat/internal/model"
 	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 	"github.com/go-demo/chat/internal/repository"
@@ -11,16 +12,19 @@ import (
 )
 
 type UserService struct {
-	userRepo       *repository.UserRepository
-	blockedRepo    *repository.BlockedUserRepository
-	friendshipRepo *repository.FriendshipRepository
+	userRepo       UserStore
+	blockedRepo    BlockStore
+	friendshipRepo FriendshipStore
+	anomalies      *anomaly.Detector
+	cache          *UserCache
+	auditor        *AuditService
 	logger         *zap.Logger
 }
 
 func NewUserService(
-	userRepo *repository.UserRepository,
-	blockedRepo *repository.BlockedUserRepository,
-	friendshipRepo *repository.FriendshipRepository,
+	userRepo UserStore,
+	blockedRepo BlockStore,
+	friendshipRepo FriendshipStore,
 	logger *zap.Logger,
 ) *UserService {
 	return &UserService{
@@ -31,6 +35,21 @@ func NewUserService(
 	}
 }
 
+// SetAnomalyDetector sets the detector watching for mass friend requests
+func (s *UserService) SetAnomalyDetector(detector *anomaly.Detector) {
+	s.anomalies = detector
+}
+
+// SetCache sets the cache of user display data
+func (s *UserService) SetCache(cache *UserCache) {
+	s.cache = cache
+}
+
+// SetAuditor sets the audit service that records administrator changes
+func (s *UserService) SetAuditor(auditor *AuditService) {
+	s.auditor = auditor
+}
+
 // GetByID retrieves a user by ID
 func (s *UserService) GetByID(ctx context.Context, id string) (*model.User, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
@@ -44,13 +63,165 @@ func (s *UserService) GetByID(ctx context.Context, id string) (*model.User, erro
 	return user, nil
 }
 
-// GetProfile retrieves a user's public profile
-func (s *UserService) GetProfile(ctx context.Context, id string) (*model.UserProfile, error) {
+// GetDisplay returns the display data of a user, from the cache when possible
+func (s *UserService) GetDisplay(ctx context.Context, id string) (*model.UserDisplay, error) {
+	users, err := s.GetDisplays(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	user, ok := users[id]
+	if !ok {
+		return nil, apperrors.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// GetDisplays returns the display data of several users keyed by ID. Users
+// not cached are loaded in a single query; unknown IDs are left out.
+func (s *UserService) GetDisplays(ctx context.Context, ids []string) (map[string]*model.UserDisplay, error) {
+	users := make(map[string]*model.UserDisplay, len(ids))
+	missing := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, seen := users[id]; seen {
+			continue
+		}
+		if user, ok := s.cache.Get(id); ok {
+			users[id] = user
+			continue
+		}
+		users[id] = nil
+		missing = append(missing, id)
+	}
+
+	if len(missing) > 0 {
+		loaded, err := s.userRepo.GetByIDs(ctx, missing)
+		if err != nil {
+			s.logger.Error("Failed to get users", zap.Error(err))
+			return nil, apperrors.ErrInternal
+		}
+		for _, user := range loaded {
+			display := user.ToDisplay()
+			s.cache.Put(display)
+			users[user.ID] = display
+		}
+	}
+
+	for id, user := range users {
+		if user == nil {
+			delete(users, id)
+		}
+	}
+	return users, nil
+}
+
+// IsAdmin checks if a user is a system administrator
+func (s *UserService) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	user, err := s.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
+
+// SetAdmin grants or revokes a user's administrator role. Admins cannot
+// revoke their own role, so there is always at least one left.
+func (s *UserService) SetAdmin(ctx context.Context, actorID, userID string, isAdmin bool) error {
+	if !isAdmin && actorID == userID {
+		return apperrors.ErrCannotRevokeOwnAdmin
+	}
+
+	if err := s.userRepo.UpdateIsAdmin(ctx, userID, isAdmin); err != nil {
+		if err == repository.ErrUserNotFound {
+			return apperrors.ErrUserNotFound
+		}
+		s.logger.Error("Failed to update admin role", zap.String("user_id", userID), zap.Error(err))
+		return apperrors.ErrInternal
+	}
+
+	action := model.AuditActionAdminGranted
+	if !isAdmin {
+		action = model.AuditActionAdminRevoked
+	}
+	s.auditor.Record(ctx, &AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: model.AuditTargetUser,
+		TargetID:   userID,
+	})
+
+	s.logger.Info("Admin role updated",
+		zap.String("user_id", userID),
+		zap.Bool("is_admin", isAdmin),
+		zap.String("updated_by", actorID),
+	)
+	return nil
+}
+
+// GetProfile retrieves a user's public profile as seen by viewerID. The
+// time the user was last online is included while they are not, if their
+// privacy setting shows it to the viewer.
+func (s *UserService) GetProfile(ctx context.Context, viewerID, id string) (*model.UserProfile, error) {
 	user, err := s.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return user.ToProfile(), nil
+
+	profile := user.ToProfile()
+	if !user.LastSeenAt.Valid || user.IsOnline() {
+		return profile, nil
+	}
+	visible, err := s.lastSeenVisibleTo(ctx, user, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visible {
+		lastSeen := user.LastSeenAt.Time
+		profile.LastSeenAt = &lastSeen
+	}
+	return profile, nil
+}
+
+// lastSeenVisibleTo reports whether a user's privacy setting lets viewerID
+// see when they were last online
+func (s *UserService) lastSeenVisibleTo(ctx context.Context, user *model.User, viewerID string) (bool, error) {
+	if viewerID == user.ID {
+		return true, nil
+	}
+
+	switch user.LastSeenVisibility {
+	case model.LastSeenNobody:
+		return false, nil
+	case model.LastSeenFriends:
+		areFriends, err := s.friendshipRepo.AreFriends(ctx, user.ID, viewerID)
+		if err != nil {
+			s.logger.Error("Failed to check friendship", zap.Error(err))
+			return false, apperrors.ErrInternal
+		}
+		return areFriends, nil
+	default:
+		return true, nil
+	}
+}
+
+// DiscoverContacts finds the users among a client's contacts, given as
+// lowercase hex SHA-256 hashes of their trimmed, lowercased emails, so raw
+// addresses never leave the device. Users who opted out are not found.
+func (s *UserService) DiscoverContacts(ctx context.Context, userID string, emailHashes []string) ([]*model.User, error) {
+	hashes := make([]string, 0, len(emailHashes))
+	seen := make(map[string]bool, len(emailHashes))
+	for _, hash := range emailHashes {
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+
+	users, err := s.userRepo.FindDiscoverable(ctx, userID, hashes)
+	if err != nil {
+		s.logger.Error("Failed to discover contacts", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return users, nil
 }
 
 // UpdateProfileInput represents profile update input
@@ -87,6 +258,7 @@ func (s *UserService) UpdateProfile(ctx context.Context, input *UpdateProfileInp
 		s.logger.Error("Failed to update user", zap.Error(err))
 		return nil, apperrors.ErrInternal
 	}
+	s.cache.Invalidate(ctx, user.ID)
 
 	return user, nil
 }
@@ -232,6 +404,7 @@ func (s *UserService) SendFriendRequest(ctx context.Context, userID, friendID st
 		s.logger.Error("Failed to create friend request", zap.Error(err))
 		return apperrors.ErrInternal
 	}
+	s.anomalies.Record(ctx, anomaly.SignalFriendRequest, userID)
 
 	return nil
 }
@@ -282,6 +455,17 @@ func (s *UserService) ListFriends(ctx context.Context, userID string, limit, off
 	return friends, nil
 }
 
+// ListSuggestions recommends users to befriend based on mutual friends
+// and shared rooms
+func (s *UserService) ListSuggestions(ctx context.Context, userID string, limit, offset int) ([]*model.FriendSuggestion, error) {
+	suggestions, err := s.friendshipRepo.ListSuggestions(ctx, userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list friend suggestions", zap.Error(err))
+		return nil, apperrors.ErrInternal
+	}
+	return suggestions, nil
+}
+
 // ListPendingRequests lists pending friend requests
 func (s *UserService) ListPendingRequests(ctx context.Context, userID string, limit, offset int) ([]*model.FriendshipWithUser, error) {
 	requests, err := s.friendshipRepo.ListPendingRequests(ctx, userID, limit, offset)
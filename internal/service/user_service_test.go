@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 	"github.com/go-demo/chat/internal/repository"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -71,6 +73,38 @@ func TestUserService_GetByID_NotFound(t *testing.T) {
 	}
 }
 
+func TestUserService_SetAdmin(t *testing.T) {
+	service, db, prefix := setupTestUserServiceIsolated(t)
+	defer db.Close()
+	defer cleanupUserServiceTestByPrefix(t, db, prefix)
+
+	admin := createUserForServiceTestIsolated(t, db, prefix, "admin")
+	user := createUserForServiceTestIsolated(t, db, prefix, "user")
+	ctx := context.Background()
+
+	if err := service.SetAdmin(ctx, admin.ID, user.ID, true); err != nil {
+		t.Fatalf("Failed to grant admin: %v", err)
+	}
+	if isAdmin, _ := service.IsAdmin(ctx, user.ID); !isAdmin {
+		t.Error("Expected user to be an admin")
+	}
+
+	if err := service.SetAdmin(ctx, user.ID, user.ID, false); err != apperrors.ErrCannotRevokeOwnAdmin {
+		t.Errorf("Expected ErrCannotRevokeOwnAdmin, got %v", err)
+	}
+
+	if err := service.SetAdmin(ctx, admin.ID, user.ID, false); err != nil {
+		t.Fatalf("Failed to revoke admin: %v", err)
+	}
+	if isAdmin, _ := service.IsAdmin(ctx, user.ID); isAdmin {
+		t.Error("Expected admin role to be revoked")
+	}
+
+	if err := service.SetAdmin(ctx, admin.ID, "00000000-0000-0000-0000-000000000000", true); err != apperrors.ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
 func TestUserService_GetProfile(t *testing.T) {
 	service, db, prefix := setupTestUserServiceIsolated(t)
 	defer db.Close()
@@ -79,7 +113,7 @@ func TestUserService_GetProfile(t *testing.T) {
 	user := createUserForServiceTestIsolated(t, db, prefix, "testuser")
 	ctx := context.Background()
 
-	profile, err := service.GetProfile(ctx, user.ID)
+	profile, err := service.GetProfile(ctx, user.ID, user.ID)
 	if err != nil {
 		t.Fatalf("Failed to get profile: %v", err)
 	}
@@ -89,6 +123,55 @@ func TestUserService_GetProfile(t *testing.T) {
 	}
 }
 
+func TestUserService_GetProfile_LastSeenVisibility(t *testing.T) {
+	service, db, prefix := setupTestUserServiceIsolated(t)
+	defer db.Close()
+	defer cleanupUserServiceTestByPrefix(t, db, prefix)
+
+	user := createUserForServiceTestIsolated(t, db, prefix, "user")
+	friend := createUserForServiceTestIsolated(t, db, prefix, "friend")
+	stranger := createUserForServiceTestIsolated(t, db, prefix, "stranger")
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+
+	_ = service.SendFriendRequest(ctx, user.ID, friend.ID)
+	_ = service.AcceptFriendRequest(ctx, friend.ID, user.ID)
+	if err := service.UpdateStatus(ctx, user.ID, model.UserStatusOffline); err != nil {
+		t.Fatalf("Failed to update status: %v", err)
+	}
+
+	tests := []struct {
+		visibility model.LastSeenVisibility
+		viewer     string
+		visible    bool
+	}{
+		{model.LastSeenEveryone, stranger.ID, true},
+		{model.LastSeenFriends, friend.ID, true},
+		{model.LastSeenFriends, stranger.ID, false},
+		{model.LastSeenNobody, friend.ID, false},
+		{model.LastSeenNobody, user.ID, true},
+	}
+	for _, tt := range tests {
+		if err := userRepo.UpdateLastSeenVisibility(ctx, user.ID, tt.visibility); err != nil {
+			t.Fatalf("Failed to update visibility: %v", err)
+		}
+		profile, err := service.GetProfile(ctx, tt.viewer, user.ID)
+		if err != nil {
+			t.Fatalf("Failed to get profile: %v", err)
+		}
+		if (profile.LastSeenAt != nil) != tt.visible {
+			t.Errorf("%s to %s: expected visible %v, got %v", tt.visibility, tt.viewer, tt.visible, profile.LastSeenAt)
+		}
+	}
+
+	// Hidden while the user is online
+	_ = service.UpdateStatus(ctx, user.ID, model.UserStatusOnline)
+	_ = userRepo.UpdateLastSeenVisibility(ctx, user.ID, model.LastSeenEveryone)
+	if profile, _ := service.GetProfile(ctx, stranger.ID, user.ID); profile.LastSeenAt != nil {
+		t.Error("Expected no last seen time while online")
+	}
+}
+
 func TestUserService_UpdateProfile(t *testing.T) {
 	service, db, prefix := setupTestUserServiceIsolated(t)
 	defer db.Close()
@@ -475,3 +558,105 @@ func TestUserService_GetOnlineUsers(t *testing.T) {
 		t.Error("Expected to find user1 in online users")
 	}
 }
+
+func TestUserService_GetDisplays(t *testing.T) {
+	service, db, prefix := setupTestUserServiceIsolated(t)
+	defer db.Close()
+	defer cleanupUserServiceTestByPrefix(t, db, prefix)
+
+	cache := NewUserCache(time.Minute, 0, nil, zap.NewNop())
+	service.SetCache(cache)
+	alice := createUserForServiceTestIsolated(t, db, prefix, "alice")
+	bob := createUserForServiceTestIsolated(t, db, prefix, "bob")
+	ctx := context.Background()
+
+	users, err := service.GetDisplays(ctx, []string{alice.ID, bob.ID, alice.ID, "00000000-0000-0000-0000-000000000000"})
+	if err != nil {
+		t.Fatalf("Failed to get displays: %v", err)
+	}
+	if len(users) != 2 || users[alice.ID].Username != alice.Username || users[bob.ID].Username != bob.Username {
+		t.Errorf("Expected alice and bob, got %+v", users)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected both users cached, got %d", cache.Len())
+	}
+
+	// A profile change is visible on the next lookup
+	displayName := "Alice Liddell"
+	if _, err := service.UpdateProfile(ctx, &UpdateProfileInput{UserID: alice.ID, DisplayName: &displayName}); err != nil {
+		t.Fatalf("Failed to update profile: %v", err)
+	}
+	user, err := service.GetDisplay(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to get display: %v", err)
+	}
+	if user.DisplayName != displayName {
+		t.Errorf("Expected display name %q, got %q", displayName, user.DisplayName)
+	}
+
+	if _, err := service.GetDisplay(ctx, "00000000-0000-0000-0000-000000000000"); !apperrors.Is(err, apperrors.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestUserService_BlockUser_Mocked(t *testing.T) {
+	ctx := context.Background()
+	users := &mockUserStore{}
+	users.GetByIDFunc = func(ctx context.Context, id string) (*model.User, error) {
+		return &model.User{ID: id}, nil
+	}
+	blocks := &mockBlockStore{}
+	friendships := &mockFriendshipStore{}
+	service := NewUserService(users, blocks, friendships, zap.NewNop())
+
+	if err := service.BlockUser(ctx, "user-1", "user-1"); err != apperrors.ErrCannotBlockSelf {
+		t.Errorf("Expected ErrCannotBlockSelf, got %v", err)
+	}
+	if err := service.BlockUser(ctx, "user-1", "user-2"); err != nil {
+		t.Fatalf("Failed to block user: %v", err)
+	}
+	if blocks.Calls("Block") != 1 || friendships.Calls("Remove") != 1 {
+		t.Error("Expected the block to be stored and the friendship removed")
+	}
+
+	blocks.BlockFunc = func(ctx context.Context, blockerID, blockedID string) error {
+		return repository.ErrAlreadyBlocked
+	}
+	if err := service.BlockUser(ctx, "user-1", "user-2"); err != apperrors.ErrAlreadyBlocked {
+		t.Errorf("Expected ErrAlreadyBlocked, got %v", err)
+	}
+}
+
+func TestUserService_SendFriendRequest_Mocked(t *testing.T) {
+	ctx := context.Background()
+	users := &mockUserStore{}
+	users.GetByIDFunc = func(ctx context.Context, id string) (*model.User, error) {
+		return &model.User{ID: id}, nil
+	}
+	blocks := &mockBlockStore{}
+	blocks.IsBlockedEitherFunc = func(ctx context.Context, userID1, userID2 string) (bool, error) {
+		return userID2 == "blocker", nil
+	}
+	friendships := &mockFriendshipStore{}
+	friendships.AreFriendsFunc = func(ctx context.Context, userID, friendID string) (bool, error) {
+		return friendID == "friend", nil
+	}
+	service := NewUserService(users, blocks, friendships, zap.NewNop())
+
+	if err := service.SendFriendRequest(ctx, "user-1", "blocker"); err != apperrors.ErrUserBlocked {
+		t.Errorf("Expected ErrUserBlocked, got %v", err)
+	}
+	if err := service.SendFriendRequest(ctx, "user-1", "friend"); err != apperrors.ErrAlreadyFriend {
+		t.Errorf("Expected ErrAlreadyFriend, got %v", err)
+	}
+	if friendships.Calls("Create") != 0 {
+		t.Fatal("Expected no friend request to be stored")
+	}
+
+	if err := service.SendFriendRequest(ctx, "user-1", "user-2"); err != nil {
+		t.Fatalf("Failed to send friend request: %v", err)
+	}
+	if friendships.Calls("Create") != 1 {
+		t.Error("Expected the friend request to be stored")
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-demo/chat/internal/model"
+	"github.com/go-demo/chat/internal/pkg/netguard"
+	"github.com/go-demo/chat/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Headers sent with every room webhook delivery. The signature is the hex
+// HMAC-SHA256 of "<timestamp>.<body>" under the webhook's secret, prefixed
+// with "sha256=", so receivers can reject both forgeries and replays.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// DefaultWebhookTimeout bounds a single delivery attempt
+	DefaultWebhookTimeout = 10 * time.Second
+
+	// DefaultWebhookMaxAttempts is how many times a delivery is tried before it fails
+	DefaultWebhookMaxAttempts = 8
+
+	// Delay before the first retry; it doubles with every failed attempt
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+
+	// Deliveries sent at once by DeliverDue
+	webhookDeliveryWorkers = 8
+
+	// Longest error message kept in the delivery log
+	maxWebhookErrorLength = 500
+)
+
+// WebhookPayload is the JSON body posted to room webhooks
+type WebhookPayload struct {
+	Event      string      `json:"event"`
+	RoomID     string      `json:"room_id"`
+	OccurredAt string      `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// WebhookMessageData is the data of message.created
+type WebhookMessageData struct {
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	ReplyToID   string `json:"reply_to_id,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// Reasons given in member events
+const (
+	WebhookMemberReasonJoin        = "join"
+	WebhookMemberReasonInvite      = "invite"
+	WebhookMemberReasonJoinRequest = "join_request"
+	WebhookMemberReasonLeave       = "leave"
+	WebhookMemberReasonKick        = "kick"
+	WebhookMemberReasonDirectory   = "directory" // added or removed by SSO group mapping
+)
+
+// WebhookMemberData is the data of member.joined and member.left
+type WebhookMemberData struct {
+	UserID  string `json:"user_id"`
+	Reason  string `json:"reason"`
+	ActorID string `json:"actor_id,omitempty"` // who invited, approved or kicked the member
+}
+
+// WebhookDispatcher queues room events for the room's webhooks and delivers
+// them in the background, retrying failures with exponential backoff.
+// Deliveries are not ordered.
+type WebhookDispatcher struct {
+	repo        *repository.RoomWebhookRepository
+	client      *http.Client
+	timeout     time.Duration
+	maxAttempts int
+	logger      *zap.Logger
+}
+
+// NewWebhookDispatcher creates a dispatcher. Unless allowPrivate is set,
+// webhooks cannot reach loopback, private or link-local addresses.
+func NewWebhookDispatcher(repo *repository.RoomWebhookRepository, timeout time.Duration, maxAttempts int, allowPrivate bool, logger *zap.Logger) *WebhookDispatcher {
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultWebhookMaxAttempts
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = netguard.RejectPrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &WebhookDispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// A redirect could point anywhere; receivers must answer directly
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		timeout:     timeout,
+		maxAttempts: maxAttempts,
+		logger:      logger,
+	}
+}
+
+// Emit queues an event for the room's webhooks. Failures are logged only;
+// the event itself has already happened.
+func (d *WebhookDispatcher) Emit(ctx context.Context, roomID, event string, data interface{}) {
+	body, err := json.Marshal(&WebhookPayload{
+		Event:      event,
+		RoomID:     roomID,
+		OccurredAt: time.Now().UTC().Format(time.RFC3339),
+		Data:       data,
+	})
+	if err != nil {
+		d.logger.Error("Failed to encode webhook payload", zap.String("event", event), zap.Error(err))
+		return
+	}
+
+	if _, err := d.repo.Enqueue(context.WithoutCancel(ctx), roomID, event, body); err != nil {
+		d.logger.Error("Failed to queue webhook deliveries",
+			zap.String("room_id", roomID),
+			zap.String("event", event),
+			zap.Error(err),
+		)
+	}
+}
+
+// DeliverDue sends up to limit due deliveries and returns how many
+// succeeded. It is meant to run as a periodic background job.
+func (d *WebhookDispatcher) DeliverDue(ctx context.Context, limit int) (int, error) {
+	// The lease outlasts an attempt so a live delivery is never claimed twice
+	deliveries, err := d.repo.ClaimDue(ctx, limit, 2*d.timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+	)
+	sem := make(chan struct{}, webhookDeliveryWorkers)
+	for _, delivery := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery *model.RoomWebhookDeliveryTarget) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if d.deliver(ctx, delivery) {
+				mu.Lock()
+				delivered++
+				mu.Unlock()
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return delivered, ctx.Err()
+}
+
+// deliver posts one delivery and records the outcome
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *model.RoomWebhookDeliveryTarget) bool {
+	statusCode, err := d.post(ctx, delivery)
+	if err == nil {
+		if err := d.repo.MarkDelivered(context.WithoutCancel(ctx), delivery.ID, statusCode); err != nil {
+			d.logger.Error("Failed to record webhook delivery", zap.String("delivery_id", delivery.ID), zap.Error(err))
+		}
+		return true
+	}
+
+	var retryAt *time.Time
+	if delivery.Attempts < d.maxAttempts {
+		next := time.Now().Add(webhookBackoff(delivery.Attempts))
+		retryAt = &next
+	}
+
+	message := err.Error()
+	if len(message) > maxWebhookErrorLength {
+		message = message[:maxWebhookErrorLength]
+	}
+	code := sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0}
+	if err := d.repo.MarkAttemptFailed(context.WithoutCancel(ctx), delivery.ID, code, message, retryAt); err != nil {
+		d.logger.Error("Failed to record webhook delivery failure", zap.String("delivery_id", delivery.ID), zap.Error(err))
+	}
+
+	fields := []zap.Field{
+		zap.String("delivery_id", delivery.ID),
+		zap.String("webhook_id", delivery.WebhookID),
+		zap.Int("attempts", delivery.Attempts),
+		zap.Error(err),
+	}
+	if retryAt == nil {
+		d.logger.Warn("Webhook delivery failed permanently", fields...)
+	} else {
+		d.logger.Debug("Webhook delivery failed, will retry", fields...)
+	}
+	return false
+}
+
+// post sends the delivery and returns the response status, if any. Any 2xx
+// response counts as delivered.
+func (d *WebhookDispatcher) post(ctx context.Context, delivery *model.RoomWebhookDeliveryTarget) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignRoomWebhook([]byte(delivery.Secret), timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// PurgeDeliveries removes finished deliveries older than retention
+func (d *WebhookDispatcher) PurgeDeliveries(ctx context.Context, retention time.Duration) (int64, error) {
+	purged, err := d.repo.PurgeSettled(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		d.logger.Info("Purged webhook deliveries", zap.Int64("count", purged))
+	}
+	return purged, nil
+}
+
+// webhookBackoff returns the wait after the given number of failed attempts
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// SignRoomWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>",
+// which receivers compare against the signature header
+func SignRoomWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,211 @@
+package sso
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The subset of ASN.1 BER that LDAP messages need: definite lengths,
+// single byte tags and non-negative integers.
+
+const (
+	berClassUniversal   = 0x00
+	berClassApplication = 0x40
+	berClassContext     = 0x80
+	berConstructed      = 0x20
+
+	berTagBoolean     = 0x01
+	berTagInteger     = 0x02
+	berTagOctetString = 0x04
+	berTagEnumerated  = 0x0a
+	berTagSequence    = 0x10 | berConstructed
+	berTagSet         = 0x11 | berConstructed
+)
+
+// maxBERPacketSize bounds a message read from the server, so a broken or
+// hostile one cannot make us allocate without limit
+const maxBERPacketSize = 4 << 20
+
+var errBERMalformed = errors.New("malformed BER packet")
+
+// berPacket is a decoded BER element
+type berPacket struct {
+	tag      byte // identifier octet: class, constructed bit and number
+	value    []byte
+	children []*berPacket
+}
+
+func (p *berPacket) constructed() bool {
+	return p.tag&berConstructed != 0
+}
+
+// child returns the i-th child or nil
+func (p *berPacket) child(i int) *berPacket {
+	if i < 0 || i >= len(p.children) {
+		return nil
+	}
+	return p.children[i]
+}
+
+// int decodes an INTEGER or ENUMERATED value
+func (p *berPacket) int() (int, error) {
+	if len(p.value) == 0 || len(p.value) > 4 {
+		return 0, errBERMalformed
+	}
+	v := 0
+	if p.value[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, b := range p.value {
+		v = v<<8 | int(b)
+	}
+	return v, nil
+}
+
+func (p *berPacket) string() string {
+	return string(p.value)
+}
+
+// berEncode encodes one element with the given identifier octet
+func berEncode(tag byte, content []byte) []byte {
+	out := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	default:
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		out = append(out, 0x80|byte(len(length)))
+		out = append(out, length...)
+	}
+	return append(out, content...)
+}
+
+// berConstruct encodes a constructed element from encoded children
+func berConstruct(tag byte, children ...[]byte) []byte {
+	var content []byte
+	for _, c := range children {
+		content = append(content, c...)
+	}
+	return berEncode(tag|berConstructed, content)
+}
+
+func berInt(tag byte, v int) []byte {
+	// Minimal two's complement encoding
+	var content []byte
+	for {
+		content = append([]byte{byte(v)}, content...)
+		if (v < 0x80 && v >= -0x80) || len(content) == 4 {
+			break
+		}
+		v >>= 8
+	}
+	return berEncode(tag, content)
+}
+
+func berString(tag byte, s string) []byte {
+	return berEncode(tag, []byte(s))
+}
+
+func berBool(v bool) []byte {
+	if v {
+		return berEncode(berTagBoolean, []byte{0xff})
+	}
+	return berEncode(berTagBoolean, []byte{0x00})
+}
+
+// berRead reads one element from r
+func berRead(r *bufio.Reader) (*berPacket, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag&0x1f == 0x1f {
+		return nil, fmt.Errorf("%w: multi-byte tag", errBERMalformed)
+	}
+
+	length, err := berReadLength(r)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return berParse(tag, content)
+}
+
+func berReadLength(r io.ByteReader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b < 0x80 {
+		return int(b), nil
+	}
+
+	n := int(b & 0x7f)
+	if n == 0 || n > 4 {
+		// 0x80 is the indefinite form, which LDAP does not allow
+		return 0, fmt.Errorf("%w: unsupported length", errBERMalformed)
+	}
+	length := 0
+	for i := 0; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxBERPacketSize {
+		return 0, fmt.Errorf("%w: %d byte packet", errBERMalformed, length)
+	}
+	return length, nil
+}
+
+// berParse decodes an element's content, recursing into constructed ones
+func berParse(tag byte, content []byte) (*berPacket, error) {
+	p := &berPacket{tag: tag, value: content}
+	if !p.constructed() {
+		return p, nil
+	}
+
+	for rest := content; len(rest) > 0; {
+		childTag := rest[0]
+		if childTag&0x1f == 0x1f {
+			return nil, fmt.Errorf("%w: multi-byte tag", errBERMalformed)
+		}
+		br := &sliceByteReader{b: rest[1:]}
+		length, err := berReadLength(br)
+		if err != nil {
+			return nil, errBERMalformed
+		}
+		if length > len(br.b) {
+			return nil, fmt.Errorf("%w: truncated element", errBERMalformed)
+		}
+		child, err := berParse(childTag, br.b[:length])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		rest = br.b[length:]
+	}
+	return p, nil
+}
+
+type sliceByteReader struct {
+	b []byte
+}
+
+func (r *sliceByteReader) ReadByte() (byte, error) {
+	if len(r.b) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	c := r.b[0]
+	r.b = r.b[1:]
+	return c, nil
+}
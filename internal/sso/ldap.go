@@ -0,0 +1,464 @@
+package sso
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultLDAPTimeout bounds one authentication, connecting included
+const DefaultLDAPTimeout = 10 * time.Second
+
+// LDAP protocol operations (RFC 4511), as identifier octets
+const (
+	ldapBindRequest      = berClassApplication | berConstructed | 0
+	ldapBindResponse     = berClassApplication | berConstructed | 1
+	ldapUnbindRequest    = berClassApplication | 2
+	ldapSearchRequest    = berClassApplication | berConstructed | 3
+	ldapSearchEntry      = berClassApplication | berConstructed | 4
+	ldapSearchDone       = berClassApplication | berConstructed | 5
+	ldapSearchReference  = berClassApplication | berConstructed | 19
+	ldapExtendedRequest  = berClassApplication | berConstructed | 23
+	ldapExtendedResponse = berClassApplication | berConstructed | 24
+
+	ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+	ldapResultSuccess           = 0
+	ldapResultSizeLimitExceeded = 4
+	ldapResultNoSuchObject      = 32
+	ldapResultInvalidCred       = 49
+
+	ldapScopeBase    = 0
+	ldapScopeSubtree = 2
+)
+
+// LDAPConfig configures an LDAPAuthenticator
+type LDAPConfig struct {
+	// URL is ldap://host[:389] or ldaps://host[:636]
+	URL string
+	// StartTLS upgrades an ldap:// connection before binding
+	StartTLS bool
+
+	// UserDNTemplate builds the user's DN from the username, e.g.
+	// uid=%s,ou=people,dc=example,dc=com. Without it the user is found by
+	// searching BaseDN with UserFilter, bound as BindDN if that is set.
+	UserDNTemplate string
+	BindDN         string
+	BindPassword   string
+	BaseDN         string
+	// UserFilter finds the user's entry, %s standing for the username
+	UserFilter string
+
+	UsernameAttribute    string
+	EmailAttribute       string
+	DisplayNameAttribute string
+	// GroupAttribute lists the user's groups, e.g. memberOf
+	GroupAttribute string
+
+	Timeout time.Duration
+	// TLSConfig overrides the TLS settings; ServerName defaults to the
+	// URL's host
+	TLSConfig *tls.Config
+}
+
+// LDAPAuthenticator authenticates users by binding to a directory as them
+type LDAPAuthenticator struct {
+	cfg     LDAPConfig
+	address string
+	tls     *tls.Config
+	ldaps   bool
+	filter  *ldapFilter
+}
+
+// NewLDAPAuthenticator validates cfg and creates an authenticator
+func NewLDAPAuthenticator(cfg LDAPConfig) (*LDAPAuthenticator, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url: %w", err)
+	}
+
+	a := &LDAPAuthenticator{cfg: cfg, address: u.Host}
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			a.address = net.JoinHostPort(u.Hostname(), "389")
+		}
+	case "ldaps":
+		a.ldaps = true
+		if u.Port() == "" {
+			a.address = net.JoinHostPort(u.Hostname(), "636")
+		}
+	default:
+		return nil, fmt.Errorf("ldap url must be ldap:// or ldaps://, got %q", cfg.URL)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("ldap url %q has no host", cfg.URL)
+	}
+	if a.ldaps && cfg.StartTLS {
+		return nil, errors.New("ldap start_tls only applies to ldap:// urls")
+	}
+
+	if cfg.TLSConfig != nil {
+		a.tls = cfg.TLSConfig.Clone()
+	} else {
+		a.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if a.tls.ServerName == "" {
+		a.tls.ServerName = u.Hostname()
+	}
+
+	if cfg.UserDNTemplate == "" {
+		if cfg.BaseDN == "" || cfg.UserFilter == "" {
+			return nil, errors.New("ldap needs either a user dn template or a base dn and user filter")
+		}
+		if a.filter, err = parseLDAPFilter(cfg.UserFilter); err != nil {
+			return nil, err
+		}
+	} else if strings.Count(cfg.UserDNTemplate, filterPlaceholder) != 1 {
+		return nil, errors.New("ldap user dn template must contain %s once")
+	}
+
+	if a.cfg.Timeout <= 0 {
+		a.cfg.Timeout = DefaultLDAPTimeout
+	}
+	return a, nil
+}
+
+// Authenticate binds as the user and returns their directory entry as an
+// Identity. A wrong password or unknown user is ErrInvalidCredentials.
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	// An empty password makes a simple bind unauthenticated, which servers
+	// accept without checking anything
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.Timeout)
+	defer cancel()
+
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	var entry *ldapEntry
+	if a.cfg.UserDNTemplate != "" {
+		dn := strings.Replace(a.cfg.UserDNTemplate, filterPlaceholder, escapeDN(username), 1)
+		if err := conn.bind(dn, password); err != nil {
+			return nil, err
+		}
+		entry, err = conn.searchOne(dn, ldapScopeBase, &ldapFilter{tag: filterPresent, attr: "objectClass"}, "", a.attributes())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if a.cfg.BindDN != "" {
+			if err := conn.bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+				if errors.Is(err, ErrInvalidCredentials) {
+					return nil, errors.New("ldap service account bind rejected")
+				}
+				return nil, err
+			}
+		}
+		entry, err = conn.searchOne(a.cfg.BaseDN, ldapScopeSubtree, a.filter, username, a.attributes())
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.bind(entry.dn, password); err != nil {
+			return nil, err
+		}
+	}
+
+	identity := &Identity{
+		Provider:    ProviderLDAP,
+		Subject:     strings.ToLower(entry.dn),
+		Username:    entry.first(a.cfg.UsernameAttribute),
+		Email:       entry.first(a.cfg.EmailAttribute),
+		DisplayName: entry.first(a.cfg.DisplayNameAttribute),
+		Groups:      entry.values(a.cfg.GroupAttribute),
+	}
+	if identity.Username == "" {
+		identity.Username = username
+	}
+	return identity, nil
+}
+
+func (a *LDAPAuthenticator) attributes() []string {
+	var attrs []string
+	for _, attr := range []string{a.cfg.UsernameAttribute, a.cfg.EmailAttribute, a.cfg.DisplayNameAttribute, a.cfg.GroupAttribute} {
+		if attr != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+func (a *LDAPAuthenticator) connect(ctx context.Context) (*ldapConn, error) {
+	var dialer net.Dialer
+	raw, err := dialer.DialContext(ctx, "tcp", a.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = raw.SetDeadline(deadline)
+	}
+
+	conn := newLDAPConn(raw)
+	if a.ldaps {
+		if err := conn.upgradeTLS(ctx, a.tls); err != nil {
+			raw.Close()
+			return nil, err
+		}
+	} else if a.cfg.StartTLS {
+		if err := conn.startTLS(ctx, a.tls); err != nil {
+			raw.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// ldapConn is one connection; requests are sent one at a time
+type ldapConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	lastID int
+}
+
+func newLDAPConn(conn net.Conn) *ldapConn {
+	return &ldapConn{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *ldapConn) close() {
+	_, _ = c.send(berEncode(ldapUnbindRequest, nil))
+	c.conn.Close()
+}
+
+func (c *ldapConn) send(op []byte) (int, error) {
+	c.lastID++
+	msg := berConstruct(berTagSequence, berInt(berTagInteger, c.lastID), op)
+	if _, err := c.conn.Write(msg); err != nil {
+		return 0, fmt.Errorf("ldap write failed: %w", err)
+	}
+	return c.lastID, nil
+}
+
+// read returns the protocol operation of the next message for id
+func (c *ldapConn) read(id int) (*berPacket, error) {
+	for {
+		msg, err := berRead(c.r)
+		if err != nil {
+			return nil, fmt.Errorf("ldap read failed: %w", err)
+		}
+		if msg.tag != berTagSequence || len(msg.children) < 2 {
+			return nil, errBERMalformed
+		}
+		msgID, err := msg.children[0].int()
+		if err != nil {
+			return nil, err
+		}
+		// Message 0 is an unsolicited notification, typically the server
+		// disconnecting
+		if msgID == 0 {
+			return nil, errors.New("ldap server closed the connection")
+		}
+		if msgID == id {
+			return msg.children[1], nil
+		}
+	}
+}
+
+// result decodes an LDAPResult's code and diagnostic message
+func ldapResult(op *berPacket) (int, string, error) {
+	if len(op.children) < 3 {
+		return 0, "", errBERMalformed
+	}
+	code, err := op.children[0].int()
+	if err != nil {
+		return 0, "", err
+	}
+	return code, op.children[2].string(), nil
+}
+
+func (c *ldapConn) bind(dn, password string) error {
+	id, err := c.send(berConstruct(ldapBindRequest,
+		berInt(berTagInteger, 3),
+		berString(berTagOctetString, dn),
+		berString(berClassContext|0, password),
+	))
+	if err != nil {
+		return err
+	}
+	op, err := c.read(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != ldapBindResponse {
+		return fmt.Errorf("ldap: unexpected response to bind")
+	}
+
+	code, msg, err := ldapResult(op)
+	switch {
+	case err != nil:
+		return err
+	case code == ldapResultInvalidCred:
+		return ErrInvalidCredentials
+	case code != ldapResultSuccess:
+		return fmt.Errorf("ldap bind failed: result %d: %s", code, msg)
+	}
+	return nil
+}
+
+func (c *ldapConn) startTLS(ctx context.Context, config *tls.Config) error {
+	id, err := c.send(berConstruct(ldapExtendedRequest, berString(berClassContext|0, ldapStartTLSOID)))
+	if err != nil {
+		return err
+	}
+	op, err := c.read(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != ldapExtendedResponse {
+		return fmt.Errorf("ldap: unexpected response to StartTLS")
+	}
+	code, msg, err := ldapResult(op)
+	if err != nil {
+		return err
+	}
+	if code != ldapResultSuccess {
+		return fmt.Errorf("ldap StartTLS failed: result %d: %s", code, msg)
+	}
+	return c.upgradeTLS(ctx, config)
+}
+
+func (c *ldapConn) upgradeTLS(ctx context.Context, config *tls.Config) error {
+	tlsConn := tls.Client(c.conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("ldap TLS handshake failed: %w", err)
+	}
+	c.conn = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// ldapEntry is a search result entry
+type ldapEntry struct {
+	dn    string
+	attrs map[string][]string // by lower-cased attribute name
+}
+
+func (e *ldapEntry) values(attr string) []string {
+	if attr == "" {
+		return nil
+	}
+	return e.attrs[strings.ToLower(attr)]
+}
+
+func (e *ldapEntry) first(attr string) string {
+	if values := e.values(attr); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// searchOne searches for exactly one entry. None is ErrInvalidCredentials,
+// since that is what a mistyped username looks like.
+func (c *ldapConn) searchOne(base string, scope int, filter *ldapFilter, username string, attrs []string) (*ldapEntry, error) {
+	encodedAttrs := make([][]byte, len(attrs))
+	for i, attr := range attrs {
+		encodedAttrs[i] = berString(berTagOctetString, attr)
+	}
+	id, err := c.send(berConstruct(ldapSearchRequest,
+		berString(berTagOctetString, base),
+		berInt(berTagEnumerated, scope),
+		berInt(berTagEnumerated, 0), // never dereference aliases
+		berInt(berTagInteger, 2),    // two entries are enough to tell it is ambiguous
+		berInt(berTagInteger, 0),
+		berBool(false),
+		filter.encode(username),
+		berConstruct(berTagSequence, encodedAttrs...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*ldapEntry
+	for {
+		op, err := c.read(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.tag {
+		case ldapSearchEntry:
+			entry, err := parseLDAPEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case ldapSearchReference:
+			// Referrals to other servers are not followed
+		case ldapSearchDone:
+			code, msg, err := ldapResult(op)
+			switch {
+			case err != nil:
+				return nil, err
+			case len(entries) > 1:
+				return nil, fmt.Errorf("ldap search matched more than one entry")
+			case code == ldapResultNoSuchObject:
+				return nil, ErrInvalidCredentials
+			case code != ldapResultSuccess && !(code == ldapResultSizeLimitExceeded && len(entries) == 1):
+				return nil, fmt.Errorf("ldap search failed: result %d: %s", code, msg)
+			case len(entries) == 0:
+				return nil, ErrInvalidCredentials
+			}
+			return entries[0], nil
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response to search")
+		}
+	}
+}
+
+func parseLDAPEntry(op *berPacket) (*ldapEntry, error) {
+	if len(op.children) < 2 {
+		return nil, errBERMalformed
+	}
+	entry := &ldapEntry{dn: op.children[0].string(), attrs: make(map[string][]string)}
+	for _, attr := range op.children[1].children {
+		if len(attr.children) < 2 {
+			return nil, errBERMalformed
+		}
+		name := strings.ToLower(attr.children[0].string())
+		for _, v := range attr.children[1].children {
+			entry.attrs[name] = append(entry.attrs[name], v.string())
+		}
+	}
+	return entry, nil
+}
+
+// escapeDN escapes a value for use in a DN (RFC 4514 section 2.4)
+func escapeDN(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case strings.IndexByte(`,+"\<>;=`, c) >= 0,
+			i == 0 && (c == ' ' || c == '#'),
+			i == len(v)-1 && c == ' ':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == 0:
+			b.WriteString(`\00`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,206 @@
+package sso
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// filterPlaceholder in a user filter stands for the username
+const filterPlaceholder = "%s"
+
+// ldapFilter is a parsed RFC 4515 search filter. The username is
+// substituted into its assertion values when it is encoded, never into the
+// filter text, so a username cannot change the filter's structure.
+type ldapFilter struct {
+	tag      byte // the Filter CHOICE's context tag
+	children []*ldapFilter
+	attr     string
+	value    string
+	// substrings holds the initial, any and final parts of a substrings
+	// filter; an empty initial or final part is left out
+	substrings []string
+}
+
+// Filter CHOICE tags (RFC 4511 section 4.5.1)
+const (
+	filterAnd            = berClassContext | berConstructed | 0
+	filterOr             = berClassContext | berConstructed | 1
+	filterNot            = berClassContext | berConstructed | 2
+	filterEquality       = berClassContext | berConstructed | 3
+	filterSubstrings     = berClassContext | berConstructed | 4
+	filterGreaterOrEqual = berClassContext | berConstructed | 5
+	filterLessOrEqual    = berClassContext | berConstructed | 6
+	filterPresent        = berClassContext | 7
+	filterApprox         = berClassContext | berConstructed | 8
+)
+
+// parseLDAPFilter parses a filter such as (&(objectClass=person)(uid=%s))
+func parseLDAPFilter(s string) (*ldapFilter, error) {
+	f, rest, err := parseFilterAt(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("ldap filter %q: %w", s, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("ldap filter %q: unexpected %q", s, rest)
+	}
+	return f, nil
+}
+
+func parseFilterAt(s string) (*ldapFilter, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("expected '('")
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", fmt.Errorf("unterminated filter")
+	}
+
+	switch s[0] {
+	case '&', '|', '!':
+		f := &ldapFilter{tag: filterAnd}
+		switch s[0] {
+		case '|':
+			f.tag = filterOr
+		case '!':
+			f.tag = filterNot
+		}
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilterAt(s)
+			if err != nil {
+				return nil, "", err
+			}
+			f.children = append(f.children, child)
+			s = rest
+		}
+		if !strings.HasPrefix(s, ")") {
+			return nil, "", fmt.Errorf("expected ')'")
+		}
+		if len(f.children) == 0 || (f.tag == filterNot && len(f.children) != 1) {
+			return nil, "", fmt.Errorf("wrong number of operands")
+		}
+		return f, s[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("expected ')'")
+	}
+	f, err := parseFilterItem(s[:end])
+	if err != nil {
+		return nil, "", err
+	}
+	return f, s[end+1:], nil
+}
+
+func parseFilterItem(item string) (*ldapFilter, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("invalid item %q", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+
+	f := &ldapFilter{tag: filterEquality}
+	switch attr[len(attr)-1] {
+	case '>':
+		f.tag, attr = filterGreaterOrEqual, attr[:len(attr)-1]
+	case '<':
+		f.tag, attr = filterLessOrEqual, attr[:len(attr)-1]
+	case '~':
+		f.tag, attr = filterApprox, attr[:len(attr)-1]
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("invalid item %q", item)
+	}
+	f.attr = attr
+
+	if f.tag == filterEquality && strings.Contains(value, "*") {
+		if value == "*" {
+			f.tag = filterPresent
+			return f, nil
+		}
+		f.tag = filterSubstrings
+		for _, part := range strings.Split(value, "*") {
+			part, err := unescapeFilterValue(part)
+			if err != nil {
+				return nil, err
+			}
+			f.substrings = append(f.substrings, part)
+		}
+		return f, nil
+	}
+
+	var err error
+	f.value, err = unescapeFilterValue(value)
+	return f, err
+}
+
+// unescapeFilterValue decodes the \XX escapes of an assertion value
+func unescapeFilterValue(v string) (string, error) {
+	if !strings.Contains(v, `\`) {
+		return v, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' {
+			b.WriteByte(v[i])
+			continue
+		}
+		if i+2 >= len(v) {
+			return "", fmt.Errorf("truncated escape in %q", v)
+		}
+		decoded, err := hex.DecodeString(v[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", v)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// encode encodes the filter with username in place of the placeholder
+func (f *ldapFilter) encode(username string) []byte {
+	substitute := func(s string) string {
+		return strings.ReplaceAll(s, filterPlaceholder, username)
+	}
+
+	switch f.tag {
+	case filterAnd, filterOr:
+		children := make([][]byte, len(f.children))
+		for i, child := range f.children {
+			children[i] = child.encode(username)
+		}
+		return berConstruct(f.tag, children...)
+	case filterNot:
+		return berConstruct(f.tag, f.children[0].encode(username))
+	case filterPresent:
+		return berString(f.tag, f.attr)
+	case filterSubstrings:
+		var parts [][]byte
+		last := len(f.substrings) - 1
+		for i, part := range f.substrings {
+			if part == "" {
+				continue
+			}
+			tag := byte(berClassContext | 1) // any
+			switch i {
+			case 0:
+				tag = berClassContext | 0 // initial
+			case last:
+				tag = berClassContext | 2 // final
+			}
+			parts = append(parts, berString(tag, substitute(part)))
+		}
+		return berConstruct(f.tag,
+			berString(berTagOctetString, f.attr),
+			berConstruct(berTagSequence, parts...),
+		)
+	default:
+		return berConstruct(f.tag,
+			berString(berTagOctetString, f.attr),
+			berString(berTagOctetString, substitute(f.value)),
+		)
+	}
+}
@@ -0,0 +1,279 @@
+package sso
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDirectory is a minimal LDAP server holding one user. It records the
+// username each search asked for.
+type fakeDirectory struct {
+	addr string
+
+	mu       sync.Mutex
+	searched []string
+}
+
+const (
+	fakeServiceDN       = "cn=svc,dc=example,dc=com"
+	fakeServicePassword = "svc-secret"
+	fakeUserDN          = "uid=alice,ou=people,dc=example,dc=com"
+	fakeUserPassword    = "alice-secret"
+)
+
+func newFakeDirectory(t *testing.T) *fakeDirectory {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	d := &fakeDirectory{addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	return d
+}
+
+func (d *fakeDirectory) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := berRead(r)
+		if err != nil {
+			return
+		}
+		id, _ := msg.child(0).int()
+		op := msg.child(1)
+
+		reply := func(ops ...[]byte) {
+			for _, op := range ops {
+				_, _ = conn.Write(berConstruct(berTagSequence, berInt(berTagInteger, id), op))
+			}
+		}
+		result := func(tag byte, code int) []byte {
+			return berConstruct(tag, berInt(berTagEnumerated, code), berString(berTagOctetString, ""), berString(berTagOctetString, ""))
+		}
+
+		switch op.tag {
+		case ldapBindRequest:
+			dn, password := op.child(1).string(), op.child(2).string()
+			code := ldapResultInvalidCred
+			if (dn == fakeServiceDN && password == fakeServicePassword) || (dn == fakeUserDN && password == fakeUserPassword) {
+				code = ldapResultSuccess
+			}
+			reply(result(ldapBindResponse, code))
+		case ldapSearchRequest:
+			username := equalityValue(op.child(6), "uid")
+			d.mu.Lock()
+			d.searched = append(d.searched, username)
+			d.mu.Unlock()
+			if username != "alice" && op.child(0).string() != fakeUserDN {
+				reply(result(ldapSearchDone, ldapResultSuccess))
+				continue
+			}
+			attr := func(name string, values ...string) []byte {
+				encoded := make([][]byte, len(values))
+				for i, v := range values {
+					encoded[i] = berString(berTagOctetString, v)
+				}
+				return berConstruct(berTagSequence, berString(berTagOctetString, name), berConstruct(berTagSet, encoded...))
+			}
+			reply(
+				berConstruct(ldapSearchEntry,
+					berString(berTagOctetString, fakeUserDN),
+					berConstruct(berTagSequence,
+						attr("uid", "alice"),
+						attr("mail", "alice@example.com"),
+						attr("displayName", "Alice Liddell"),
+						attr("memberOf", "cn=engineering,ou=groups,dc=example,dc=com", "cn=staff,ou=groups,dc=example,dc=com"),
+					),
+				),
+				result(ldapSearchDone, ldapResultSuccess),
+			)
+		case ldapUnbindRequest:
+			return
+		}
+	}
+}
+
+// equalityValue finds the value an equality filter asserts for attr
+func equalityValue(f *berPacket, attr string) string {
+	if f.tag == filterEquality && f.child(0).string() == attr {
+		return f.child(1).string()
+	}
+	for _, c := range f.children {
+		if v := equalityValue(c, attr); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func newTestLDAPAuthenticator(t *testing.T, d *fakeDirectory, cfg LDAPConfig) *LDAPAuthenticator {
+	t.Helper()
+	cfg.URL = "ldap://" + d.addr
+	cfg.UsernameAttribute = "uid"
+	cfg.EmailAttribute = "mail"
+	cfg.DisplayNameAttribute = "displayName"
+	cfg.GroupAttribute = "memberOf"
+	a, err := NewLDAPAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	return a
+}
+
+func TestLDAPAuthenticator_SearchAndBind(t *testing.T) {
+	d := newFakeDirectory(t)
+	a := newTestLDAPAuthenticator(t, d, LDAPConfig{
+		BindDN:       fakeServiceDN,
+		BindPassword: fakeServicePassword,
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(&(objectClass=person)(uid=%s))",
+	})
+
+	identity, err := a.Authenticate(context.Background(), "alice", fakeUserPassword)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if identity.Provider != ProviderLDAP || identity.Subject != fakeUserDN {
+		t.Errorf("Unexpected subject %s/%s", identity.Provider, identity.Subject)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" || identity.DisplayName != "Alice Liddell" {
+		t.Errorf("Unexpected attributes %+v", identity)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[0] != "cn=engineering,ou=groups,dc=example,dc=com" {
+		t.Errorf("Unexpected groups %v", identity.Groups)
+	}
+
+	if _, err := a.Authenticate(context.Background(), "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for a wrong password, got %v", err)
+	}
+	if _, err := a.Authenticate(context.Background(), "bob", "whatever"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for an unknown user, got %v", err)
+	}
+	if _, err := a.Authenticate(context.Background(), "alice", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected an empty password to be refused, got %v", err)
+	}
+}
+
+func TestLDAPAuthenticator_UsernameIsNotFilterSyntax(t *testing.T) {
+	d := newFakeDirectory(t)
+	a := newTestLDAPAuthenticator(t, d, LDAPConfig{
+		BaseDN:     "dc=example,dc=com",
+		UserFilter: "(uid=%s)",
+	})
+
+	injected := "*)(uid=alice"
+	if _, err := a.Authenticate(context.Background(), injected, "whatever"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.searched) != 1 || d.searched[0] != injected {
+		t.Errorf("Expected the username to be searched for literally, got %q", d.searched)
+	}
+}
+
+func TestLDAPAuthenticator_DNTemplate(t *testing.T) {
+	d := newFakeDirectory(t)
+	a := newTestLDAPAuthenticator(t, d, LDAPConfig{
+		UserDNTemplate: "uid=%s,ou=people,dc=example,dc=com",
+	})
+
+	identity, err := a.Authenticate(context.Background(), "alice", fakeUserPassword)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if identity.Email != "alice@example.com" {
+		t.Errorf("Expected the entry to be read after binding, got %+v", identity)
+	}
+	if _, err := a.Authenticate(context.Background(), "alice,ou=admins", fakeUserPassword); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected the escaped DN to be rejected, got %v", err)
+	}
+}
+
+func TestNewLDAPAuthenticator_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  LDAPConfig
+	}{
+		{"scheme", LDAPConfig{URL: "http://ldap.example.com", UserDNTemplate: "uid=%s"}},
+		{"start tls over ldaps", LDAPConfig{URL: "ldaps://ldap.example.com", StartTLS: true, UserDNTemplate: "uid=%s"}},
+		{"no user lookup", LDAPConfig{URL: "ldap://ldap.example.com"}},
+		{"bad filter", LDAPConfig{URL: "ldap://ldap.example.com", BaseDN: "dc=example", UserFilter: "(uid=%s"}},
+		{"template without placeholder", LDAPConfig{URL: "ldap://ldap.example.com", UserDNTemplate: "uid=alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLDAPAuthenticator(tt.cfg); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestLDAPFilterEncoding(t *testing.T) {
+	f, err := parseLDAPFilter(`(&(objectClass=person)(!(locked=TRUE))(|(uid=%s)(mail=%s@*))(cn=a\2ab))`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	encoded := f.encode("bob")
+	p, err := berRead(bufio.NewReader(strings.NewReader(string(encoded))))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if p.tag != filterAnd || len(p.children) != 4 {
+		t.Fatalf("Expected an and of four, got %#x with %d", p.tag, len(p.children))
+	}
+	if p.child(1).tag != filterNot || p.child(1).child(0).child(1).string() != "TRUE" {
+		t.Error("Unexpected not filter")
+	}
+	or := p.child(2)
+	if or.tag != filterOr || or.child(0).child(1).string() != "bob" {
+		t.Error("Expected the username in the equality match")
+	}
+	substrings := or.child(1)
+	if substrings.tag != filterSubstrings || substrings.child(1).child(0).string() != "bob@" || substrings.child(1).child(0).tag != berClassContext|0 {
+		t.Error("Expected an initial substring")
+	}
+	if p.child(3).child(1).string() != "a*b" {
+		t.Errorf("Expected the escape to be decoded, got %q", p.child(3).child(1).string())
+	}
+
+	for _, bad := range []string{"uid=%s", "(uid=%s", "(!(a=1)(b=2))", "(=x)", `(cn=a\2)`} {
+		if _, err := parseLDAPFilter(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestEscapeDN(t *testing.T) {
+	tests := map[string]string{
+		"alice":      "alice",
+		"a,b+c=d":    `a\,b\+c\=d`,
+		" #lead":     `\ #lead`,
+		"#hash":      `\#hash`,
+		"trail ":     `trail\ `,
+		`back\slash`: `back\\slash`,
+	}
+	for in, want := range tests {
+		if got := escapeDN(in); got != want {
+			t.Errorf("escapeDN(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package sso
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	nsSAMLAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsSAMLProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+
+	samlStatusSuccess     = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlBearer            = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	samlBindingPOST       = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlNameIDUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+)
+
+// DefaultSAMLClockSkew is how far the IdP's clock may be off from ours
+const DefaultSAMLClockSkew = 2 * time.Minute
+
+// MaxSAMLResponseSize bounds the encoded SAMLResponse form value
+const MaxSAMLResponseSize = 256 << 10
+
+// SAMLConfig configures a SAMLServiceProvider
+type SAMLConfig struct {
+	// EntityID identifies this service provider to the IdP
+	EntityID string
+	// ACSURL is where the IdP posts its response
+	ACSURL string
+	// IdPSSOURL is the IdP's HTTP-Redirect single sign-on endpoint
+	IdPSSOURL string
+	// IdPEntityID is the expected issuer; empty accepts any issuer signed
+	// with IdPCertificate
+	IdPEntityID    string
+	IdPCertificate *x509.Certificate
+
+	UsernameAttribute    string
+	EmailAttribute       string
+	DisplayNameAttribute string
+	GroupsAttribute      string
+
+	ClockSkew time.Duration
+}
+
+// SAMLServiceProvider runs the service provider side of SP-initiated SAML
+// 2.0 web browser SSO: it redirects to the IdP with an AuthnRequest and
+// verifies the signed response the IdP posts back. Encrypted assertions
+// are not supported.
+type SAMLServiceProvider struct {
+	cfg SAMLConfig
+}
+
+// NewSAMLServiceProvider validates cfg and creates a service provider
+func NewSAMLServiceProvider(cfg SAMLConfig) (*SAMLServiceProvider, error) {
+	switch {
+	case cfg.EntityID == "":
+		return nil, errors.New("saml needs the service provider entity id")
+	case cfg.ACSURL == "":
+		return nil, errors.New("saml needs the assertion consumer service url")
+	case cfg.IdPSSOURL == "":
+		return nil, errors.New("saml needs the IdP single sign-on url")
+	case cfg.IdPCertificate == nil:
+		return nil, errors.New("saml needs the IdP signing certificate")
+	}
+	if _, err := url.Parse(cfg.IdPSSOURL); err != nil {
+		return nil, fmt.Errorf("invalid IdP single sign-on url: %w", err)
+	}
+	if cfg.ClockSkew <= 0 {
+		cfg.ClockSkew = DefaultSAMLClockSkew
+	}
+	return &SAMLServiceProvider{cfg: cfg}, nil
+}
+
+// ParseCertificate parses a certificate given as PEM or as the bare base64
+// DER found in IdP metadata
+func ParseCertificate(s string) (*x509.Certificate, error) {
+	var der []byte
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := decodeBase64(s)
+		if err != nil {
+			return nil, errors.New("certificate is neither PEM nor base64")
+		}
+		der = decoded
+	}
+	return x509.ParseCertificate(der)
+}
+
+// AuthnRequestURL returns the IdP URL to send the browser to and the ID of
+// the request in it, which the response must answer
+func (sp *SAMLServiceProvider) AuthnRequestURL(relayState string, now time.Time) (string, string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	id := "_" + hex.EncodeToString(idBytes)
+
+	request := fmt.Sprintf(`<samlp:AuthnRequest xmlns:samlp="%s" xmlns:saml="%s" ID="%s" Version="2.0" IssueInstant="%s" Destination="%s" AssertionConsumerServiceURL="%s" ProtocolBinding="%s"><saml:Issuer>%s</saml:Issuer><samlp:NameIDPolicy Format="%s" AllowCreate="true"/></samlp:AuthnRequest>`,
+		nsSAMLProtocol, nsSAMLAssertion, id,
+		now.UTC().Format(time.RFC3339),
+		xmlEscape(sp.cfg.IdPSSOURL), xmlEscape(sp.cfg.ACSURL), samlBindingPOST,
+		xmlEscape(sp.cfg.EntityID), samlNameIDUnspecified,
+	)
+
+	// HTTP-Redirect binding: raw DEFLATE, then base64
+	var deflated bytes.Buffer
+	w, err := flate.NewWriter(&deflated, flate.BestCompression)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := w.Write([]byte(request)); err != nil {
+		return "", "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+
+	u, err := url.Parse(sp.cfg.IdPSSOURL)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	if relayState != "" {
+		q.Set("RelayState", relayState)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), id, nil
+}
+
+// Metadata returns the service provider metadata to register with the IdP
+func (sp *SAMLServiceProvider) Metadata() []byte {
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="%s">
+  <md:SPSSODescriptor AuthnRequestsSigned="false" WantAssertionsSigned="true" protocolSupportEnumeration="%s">
+    <md:NameIDFormat>%s</md:NameIDFormat>
+    <md:AssertionConsumerService Binding="%s" Location="%s" index="0" isDefault="true"/>
+  </md:SPSSODescriptor>
+</md:EntityDescriptor>
+`, xmlEscape(sp.cfg.EntityID), nsSAMLProtocol, samlNameIDUnspecified, samlBindingPOST, xmlEscape(sp.cfg.ACSURL)))
+}
+
+// SAMLAssertion is a verified assertion
+type SAMLAssertion struct {
+	// ID identifies the assertion, so a replay can be refused until
+	// NotOnOrAfter
+	ID           string
+	NotOnOrAfter time.Time
+	Identity     *Identity
+}
+
+// ParseResponse verifies a base64 SAMLResponse answering the request with
+// requestID and returns its assertion. Unsolicited responses are refused.
+func (sp *SAMLServiceProvider) ParseResponse(encoded, requestID string, now time.Time) (*SAMLAssertion, error) {
+	if len(encoded) > MaxSAMLResponseSize {
+		return nil, fmt.Errorf("%w: response too large", ErrInvalidAssertion)
+	}
+	data, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid encoding", ErrInvalidAssertion)
+	}
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+
+	assertion, err := sp.verify(root, requestID, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+	return assertion, nil
+}
+
+func (sp *SAMLServiceProvider) verify(response *xmlNode, requestID string, now time.Time) (*SAMLAssertion, error) {
+	if !response.is(nsSAMLProtocol, "Response") {
+		return nil, errors.New("not a SAML response")
+	}
+	if dest := response.attr("Destination"); dest != "" && dest != sp.cfg.ACSURL {
+		return nil, fmt.Errorf("response is for %q", dest)
+	}
+	if requestID == "" || response.attr("InResponseTo") != requestID {
+		return nil, errors.New("response does not answer our request")
+	}
+
+	var statusCode string
+	if status := response.element(nsSAMLProtocol, "Status"); status != nil {
+		if code := status.element(nsSAMLProtocol, "StatusCode"); code != nil {
+			statusCode = code.attr("Value")
+		}
+	}
+	if statusCode != samlStatusSuccess {
+		return nil, fmt.Errorf("IdP returned status %q", statusCode)
+	}
+	if err := sp.checkIssuer(response, false); err != nil {
+		return nil, err
+	}
+
+	if len(response.elements(nsSAMLAssertion, "EncryptedAssertion")) > 0 {
+		return nil, errors.New("encrypted assertions are not supported")
+	}
+	assertion := response.element(nsSAMLAssertion, "Assertion")
+	if assertion == nil {
+		return nil, errors.New("response must contain exactly one assertion")
+	}
+
+	// Either the response or the assertion may be signed; every signature
+	// present must verify, and at least one must cover the assertion
+	responseErr := verifySignature(response, sp.cfg.IdPCertificate)
+	if responseErr != nil && responseErr != errNotSigned {
+		return nil, fmt.Errorf("response signature: %v", responseErr)
+	}
+	assertionErr := verifySignature(assertion, sp.cfg.IdPCertificate)
+	if assertionErr != nil && assertionErr != errNotSigned {
+		return nil, fmt.Errorf("assertion signature: %v", assertionErr)
+	}
+	if responseErr == errNotSigned && assertionErr == errNotSigned {
+		return nil, errors.New("neither the response nor the assertion is signed")
+	}
+
+	if err := sp.checkIssuer(assertion, true); err != nil {
+		return nil, err
+	}
+
+	subject := assertion.element(nsSAMLAssertion, "Subject")
+	if subject == nil {
+		return nil, errors.New("assertion has no subject")
+	}
+	nameID := subject.element(nsSAMLAssertion, "NameID")
+	if nameID == nil || strings.TrimSpace(nameID.textContent()) == "" {
+		return nil, errors.New("assertion has no NameID")
+	}
+	notOnOrAfter, err := sp.checkSubjectConfirmation(subject, requestID, now)
+	if err != nil {
+		return nil, err
+	}
+	if conditionsEnd, err := sp.checkConditions(assertion, now); err != nil {
+		return nil, err
+	} else if !conditionsEnd.IsZero() && conditionsEnd.After(notOnOrAfter) {
+		notOnOrAfter = conditionsEnd
+	}
+
+	attrs := samlAttributes(assertion)
+	identity := &Identity{
+		Provider:    ProviderSAML,
+		Subject:     strings.TrimSpace(nameID.textContent()),
+		Username:    first(attrs[sp.cfg.UsernameAttribute]),
+		Email:       first(attrs[sp.cfg.EmailAttribute]),
+		DisplayName: first(attrs[sp.cfg.DisplayNameAttribute]),
+		Groups:      attrs[sp.cfg.GroupsAttribute],
+	}
+	if identity.Email == "" && strings.Contains(identity.Subject, "@") {
+		identity.Email = identity.Subject
+	}
+	if identity.Username == "" {
+		identity.Username, _, _ = strings.Cut(identity.Subject, "@")
+	}
+
+	id := assertion.attr("ID")
+	if id == "" {
+		return nil, errors.New("assertion has no ID")
+	}
+	return &SAMLAssertion{ID: id, NotOnOrAfter: notOnOrAfter, Identity: identity}, nil
+}
+
+// checkIssuer checks el's Issuer against the configured IdP. An assertion
+// must name its issuer; on the response it is optional.
+func (sp *SAMLServiceProvider) checkIssuer(el *xmlNode, required bool) error {
+	issuer := el.element(nsSAMLAssertion, "Issuer")
+	if issuer == nil {
+		if required {
+			return errors.New("assertion has no issuer")
+		}
+		return nil
+	}
+	if got := strings.TrimSpace(issuer.textContent()); sp.cfg.IdPEntityID != "" && got != sp.cfg.IdPEntityID {
+		return fmt.Errorf("unexpected issuer %q", got)
+	}
+	return nil
+}
+
+// checkSubjectConfirmation requires a bearer confirmation for our ACS and
+// request that has not expired, and returns when it expires
+func (sp *SAMLServiceProvider) checkSubjectConfirmation(subject *xmlNode, requestID string, now time.Time) (time.Time, error) {
+	for _, confirmation := range subject.elements(nsSAMLAssertion, "SubjectConfirmation") {
+		if confirmation.attr("Method") != samlBearer {
+			continue
+		}
+		data := confirmation.element(nsSAMLAssertion, "SubjectConfirmationData")
+		if data == nil || data.attr("Recipient") != sp.cfg.ACSURL || data.attr("InResponseTo") != requestID {
+			continue
+		}
+		notOnOrAfter, err := parseSAMLTime(data.attr("NotOnOrAfter"))
+		if err != nil || notOnOrAfter.IsZero() || !now.Before(notOnOrAfter.Add(sp.cfg.ClockSkew)) {
+			continue
+		}
+		if notBefore, err := parseSAMLTime(data.attr("NotBefore")); err != nil || now.Add(sp.cfg.ClockSkew).Before(notBefore) {
+			continue
+		}
+		return notOnOrAfter, nil
+	}
+	return time.Time{}, errors.New("no valid bearer subject confirmation")
+}
+
+// checkConditions checks the validity window and audience, and returns
+// when the conditions expire, or zero if they do not say
+func (sp *SAMLServiceProvider) checkConditions(assertion *xmlNode, now time.Time) (time.Time, error) {
+	conditions := assertion.element(nsSAMLAssertion, "Conditions")
+	if conditions == nil {
+		return time.Time{}, errors.New("assertion has no conditions")
+	}
+
+	notBefore, err := parseSAMLTime(conditions.attr("NotBefore"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if now.Add(sp.cfg.ClockSkew).Before(notBefore) {
+		return time.Time{}, errors.New("assertion is not yet valid")
+	}
+	notOnOrAfter, err := parseSAMLTime(conditions.attr("NotOnOrAfter"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !notOnOrAfter.IsZero() && !now.Before(notOnOrAfter.Add(sp.cfg.ClockSkew)) {
+		return time.Time{}, errors.New("assertion has expired")
+	}
+
+	// Every audience restriction must name us
+	restrictions := conditions.elements(nsSAMLAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return time.Time{}, errors.New("assertion has no audience restriction")
+	}
+	for _, restriction := range restrictions {
+		found := false
+		for _, audience := range restriction.elements(nsSAMLAssertion, "Audience") {
+			if strings.TrimSpace(audience.textContent()) == sp.cfg.EntityID {
+				found = true
+			}
+		}
+		if !found {
+			return time.Time{}, errors.New("assertion is not for this service provider")
+		}
+	}
+	return notOnOrAfter, nil
+}
+
+// samlAttributes collects attribute values by Name and by FriendlyName
+func samlAttributes(assertion *xmlNode) map[string][]string {
+	attrs := make(map[string][]string)
+	for _, statement := range assertion.elements(nsSAMLAssertion, "AttributeStatement") {
+		for _, attr := range statement.elements(nsSAMLAssertion, "Attribute") {
+			var values []string
+			for _, v := range attr.elements(nsSAMLAssertion, "AttributeValue") {
+				values = append(values, strings.TrimSpace(v.textContent()))
+			}
+			for _, name := range []string{attr.attr("Name"), attr.attr("FriendlyName")} {
+				if name != "" {
+					attrs[name] = append(attrs[name], values...)
+				}
+			}
+		}
+	}
+	return attrs
+}
+
+func parseSAMLTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return t, nil
+}
+
+func first(values []string) string {
+	if len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
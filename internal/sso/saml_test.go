@@ -0,0 +1,298 @@
+package sso
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testACSURL    = "https://chat.example.com/api/v1/auth/sso/saml/acs"
+	testSPEntity  = "https://chat.example.com/saml"
+	testIdPEntity = "https://idp.example.com"
+	testRequestID = "_request1"
+)
+
+var testNow = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestIdPKey(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp"},
+		NotBefore:    testNow.Add(-time.Hour),
+		NotAfter:     testNow.Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return key, cert
+}
+
+func newTestSP(t *testing.T, cert *x509.Certificate) *SAMLServiceProvider {
+	t.Helper()
+	sp, err := NewSAMLServiceProvider(SAMLConfig{
+		EntityID:          testSPEntity,
+		ACSURL:            testACSURL,
+		IdPSSOURL:         "https://idp.example.com/sso?tenant=1",
+		IdPEntityID:       testIdPEntity,
+		IdPCertificate:    cert,
+		UsernameAttribute: "uid",
+		EmailAttribute:    "mail",
+		GroupsAttribute:   "groups",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service provider: %v", err)
+	}
+	return sp
+}
+
+// testAssertion returns an assertion with the given audience, signed with
+// key unless key is nil
+func testAssertion(t *testing.T, key *rsa.PrivateKey, id, audience string) string {
+	t.Helper()
+	assertion := fmt.Sprintf(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="%s" Version="2.0" IssueInstant="2026-01-02T03:04:00Z">
+  <saml:Issuer>%s</saml:Issuer>SIGNATURE
+  <saml:Subject>
+    <saml:NameID>alice@example.com</saml:NameID>
+    <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+      <saml:SubjectConfirmationData InResponseTo="%s" Recipient="%s" NotOnOrAfter="2026-01-02T03:09:05Z"/>
+    </saml:SubjectConfirmation>
+  </saml:Subject>
+  <saml:Conditions NotBefore="2026-01-02T03:03:05Z" NotOnOrAfter="2026-01-02T03:14:05.000Z">
+    <saml:AudienceRestriction><saml:Audience>%s</saml:Audience></saml:AudienceRestriction>
+  </saml:Conditions>
+  <saml:AttributeStatement>
+    <saml:Attribute Name="urn:oid:0.9.2342.19200300.100.1.1" FriendlyName="uid"><saml:AttributeValue>alice</saml:AttributeValue></saml:Attribute>
+    <saml:Attribute Name="groups">
+      <saml:AttributeValue>engineering</saml:AttributeValue>
+      <saml:AttributeValue>staff &amp; friends</saml:AttributeValue>
+    </saml:Attribute>
+  </saml:AttributeStatement>
+</saml:Assertion>`, id, testIdPEntity, testRequestID, testACSURL, audience)
+
+	if key == nil {
+		return strings.Replace(assertion, "SIGNATURE", "", 1)
+	}
+	return sign(t, key, assertion, id)
+}
+
+// sign fills the SIGNATURE placeholder of an element with an enveloped
+// signature over it
+func sign(t *testing.T, key *rsa.PrivateKey, element, id string) string {
+	t.Helper()
+	unsigned, err := parseXML([]byte(strings.Replace(element, "SIGNATURE", "", 1)))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	digest := sha256.Sum256(canonicalize(unsigned, nil, nil))
+
+	signedInfo := fmt.Sprintf(`<ds:SignedInfo><ds:CanonicalizationMethod Algorithm="%s"/><ds:SignatureMethod Algorithm="%s"/><ds:Reference URI="#%s"><ds:Transforms><ds:Transform Algorithm="%s"/><ds:Transform Algorithm="%s"/></ds:Transforms><ds:DigestMethod Algorithm="%s"/><ds:DigestValue>%s</ds:DigestValue></ds:Reference></ds:SignedInfo>`,
+		algExcC14N, algRSASHA256, id, algEnveloped, algExcC14N, algSHA256, base64.StdEncoding.EncodeToString(digest[:]))
+	sig := `<ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` + signedInfo + `<ds:SignatureValue>VALUE</ds:SignatureValue></ds:Signature>`
+
+	doc, err := parseXML([]byte(strings.Replace(element, "SIGNATURE", sig, 1)))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	hashed := sha256.Sum256(canonicalize(signature(doc).element(nsDSig, "SignedInfo"), nil, nil))
+	value, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	// Wrap the value over lines as IdPs do
+	encoded := base64.StdEncoding.EncodeToString(value)
+	wrapped := encoded[:64] + "\n" + encoded[64:]
+	return strings.Replace(element, "SIGNATURE", strings.Replace(sig, "VALUE", wrapped, 1), 1)
+}
+
+func testResponse(assertions string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_response1" InResponseTo="%s" Destination="%s" Version="2.0" IssueInstant="2026-01-02T03:04:00Z">SIGNATURE
+  <saml:Issuer xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">%s</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+  %s
+</samlp:Response>`, testRequestID, testACSURL, testIdPEntity, assertions)
+}
+
+func encodeResponse(response string) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Replace(response, "SIGNATURE", "", 1)))
+}
+
+func TestSAMLParseResponse_SignedAssertion(t *testing.T) {
+	key, cert := newTestIdPKey(t)
+	sp := newTestSP(t, cert)
+
+	response := testResponse(testAssertion(t, key, "_assertion1", testSPEntity))
+	assertion, err := sp.ParseResponse(encodeResponse(response), testRequestID, testNow)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if assertion.ID != "_assertion1" || !assertion.NotOnOrAfter.Equal(time.Date(2026, 1, 2, 3, 14, 5, 0, time.UTC)) {
+		t.Errorf("Unexpected assertion %s until %s", assertion.ID, assertion.NotOnOrAfter)
+	}
+	identity := assertion.Identity
+	if identity.Provider != ProviderSAML || identity.Subject != "alice@example.com" {
+		t.Errorf("Unexpected subject %s/%s", identity.Provider, identity.Subject)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" {
+		t.Errorf("Expected the username by friendly name and the email from the NameID, got %+v", identity)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[1] != "staff & friends" {
+		t.Errorf("Unexpected groups %q", identity.Groups)
+	}
+}
+
+func TestSAMLParseResponse_SignedResponse(t *testing.T) {
+	key, cert := newTestIdPKey(t)
+	sp := newTestSP(t, cert)
+
+	response := sign(t, key, testResponse(testAssertion(t, nil, "_assertion1", testSPEntity)), "_response1")
+	if _, err := sp.ParseResponse(base64.StdEncoding.EncodeToString([]byte(response)), testRequestID, testNow); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestSAMLParseResponse_Rejects(t *testing.T) {
+	key, cert := newTestIdPKey(t)
+	otherKey, _ := newTestIdPKey(t)
+	sp := newTestSP(t, cert)
+	signed := testAssertion(t, key, "_assertion1", testSPEntity)
+
+	tests := []struct {
+		name      string
+		response  string
+		requestID string
+		now       time.Time
+	}{
+		{"unsigned", testResponse(testAssertion(t, nil, "_assertion1", testSPEntity)), testRequestID, testNow},
+		{"wrong key", testResponse(testAssertion(t, otherKey, "_assertion1", testSPEntity)), testRequestID, testNow},
+		{"tampered", testResponse(strings.Replace(signed, "<saml:NameID>alice@", "<saml:NameID>admin@", 1)), testRequestID, testNow},
+		{"wrong audience", testResponse(testAssertion(t, key, "_assertion1", "https://other.example.com")), testRequestID, testNow},
+		{"other request", testResponse(signed), "_request2", testNow},
+		{"no request", testResponse(signed), "", testNow},
+		{"expired", testResponse(signed), testRequestID, testNow.Add(time.Hour)},
+		{"not yet valid", testResponse(signed), testRequestID, testNow.Add(-time.Hour)},
+		{"two assertions", testResponse(signed + testAssertion(t, nil, "_assertion2", testSPEntity)), testRequestID, testNow},
+		{"doctype", strings.Replace(testResponse(signed), "?>", "?><!DOCTYPE x>", 1), testRequestID, testNow},
+		{"failed status", strings.Replace(testResponse(signed), ":status:Success", ":status:Responder", 1), testRequestID, testNow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sp.ParseResponse(encodeResponse(tt.response), tt.requestID, tt.now)
+			if !errors.Is(err, ErrInvalidAssertion) {
+				t.Errorf("Expected ErrInvalidAssertion, got %v", err)
+			}
+		})
+	}
+}
+
+// A signed assertion moved elsewhere in the document must not vouch for an
+// unsigned one in its place
+func TestSAMLParseResponse_SignatureWrapping(t *testing.T) {
+	key, cert := newTestIdPKey(t)
+	sp := newTestSP(t, cert)
+
+	signed := testAssertion(t, key, "_assertion1", testSPEntity)
+	forged := strings.Replace(testAssertion(t, nil, "_assertion1", testSPEntity), "alice@example.com", "admin@example.com", 1)
+	// Hide the signed original inside the forged assertion's extensions
+	wrapped := strings.Replace(forged, "</saml:Assertion>", "<saml:Advice>"+signed+"</saml:Advice></saml:Assertion>", 1)
+
+	if _, err := sp.ParseResponse(encodeResponse(testResponse(wrapped)), testRequestID, testNow); !errors.Is(err, ErrInvalidAssertion) {
+		t.Errorf("Expected ErrInvalidAssertion, got %v", err)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	doc, err := parseXML([]byte(`<a:root xmlns:a="urn:a" xmlns:b="urn:b" xmlns="urn:d" xmlns:unused="urn:u"><a:child  z="2" b:y="1"
+  attr='say "hi"'><b:x>t&amp;&lt;&gt;<![CDATA[<raw>]]></b:x><!-- comment --><y/><a:z xmlns:a="urn:a2"/></a:child></a:root>`))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	want := `<a:child xmlns:a="urn:a" xmlns:b="urn:b" attr="say &quot;hi&quot;" z="2" b:y="1"><b:x>t&amp;&lt;&gt;&lt;raw&gt;</b:x><y xmlns="urn:d"></y><a:z xmlns:a="urn:a2"></a:z></a:child>`
+	if got := string(canonicalize(doc.children[0], nil, nil)); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	wantInclusive := `<a:child xmlns="urn:d" xmlns:a="urn:a" xmlns:b="urn:b" xmlns:unused="urn:u" attr="say &quot;hi&quot;" z="2" b:y="1">`
+	if got := string(canonicalize(doc.children[0], []string{"#default", "unused"}, nil)); !strings.HasPrefix(got, wantInclusive) {
+		t.Errorf("Expected the inclusive prefixes to be rendered, got %s", got)
+	}
+}
+
+func TestSAMLAuthnRequestURL(t *testing.T) {
+	_, cert := newTestIdPKey(t)
+	sp := newTestSP(t, cert)
+
+	redirect, id, err := sp.AuthnRequestURL("state-1", testNow)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatalf("Invalid URL: %v", err)
+	}
+	q := u.Query()
+	if u.Host != "idp.example.com" || q.Get("tenant") != "1" || q.Get("RelayState") != "state-1" {
+		t.Errorf("Unexpected redirect %s", redirect)
+	}
+
+	deflated, err := base64.StdEncoding.DecodeString(q.Get("SAMLRequest"))
+	if err != nil {
+		t.Fatalf("Invalid encoding: %v", err)
+	}
+	request, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		t.Fatalf("Invalid deflate: %v", err)
+	}
+	parsed, err := parseXML(request)
+	if err != nil {
+		t.Fatalf("Invalid XML: %v", err)
+	}
+	if !parsed.is(nsSAMLProtocol, "AuthnRequest") || parsed.attr("ID") != id || parsed.attr("AssertionConsumerServiceURL") != testACSURL {
+		t.Errorf("Unexpected request %s", request)
+	}
+}
+
+func TestParseCertificate(t *testing.T) {
+	_, cert := newTestIdPKey(t)
+	pemCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	bare := base64.StdEncoding.EncodeToString(cert.Raw)
+
+	for _, s := range []string{pemCert, bare[:40] + "\n  " + bare[40:]} {
+		parsed, err := ParseCertificate(s)
+		if err != nil || !parsed.Equal(cert) {
+			t.Errorf("Failed to parse certificate: %v", err)
+		}
+	}
+	if _, err := ParseCertificate("not a certificate"); err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -0,0 +1,113 @@
+// Package sso authenticates users against an enterprise identity provider:
+// an LDAP directory, by binding as the user, or a SAML 2.0 IdP, by
+// verifying the assertion it posts back. It only establishes who the user
+// is; provisioning accounts and room memberships is left to the service
+// layer.
+package sso
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Providers name where an Identity came from
+const (
+	ProviderLDAP = "ldap"
+	ProviderSAML = "saml"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the directory rejects the
+	// username or password
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInvalidAssertion is returned for a SAML response that fails
+	// verification; the wrapped error says why
+	ErrInvalidAssertion = errors.New("invalid SAML assertion")
+)
+
+// Identity is a user as the identity provider knows them
+type Identity struct {
+	Provider string
+	// Subject identifies the user at the provider and does not change when
+	// their attributes do: the entry DN for LDAP, the NameID for SAML
+	Subject     string
+	Username    string
+	Email       string
+	DisplayName string
+	Groups      []string
+}
+
+// GroupRule grants membership of rooms to the members of a group
+type GroupRule struct {
+	Group   string
+	RoomIDs []string
+}
+
+// ParseGroupRules parses rules of the form "group=roomID,roomID". The group
+// may itself contain '=', as LDAP DNs do, so the rule splits at the last
+// one.
+func ParseGroupRules(rules []string) ([]GroupRule, error) {
+	var parsed []GroupRule
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		i := strings.LastIndex(rule, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("group rule %q: want group=roomID,...", rule)
+		}
+
+		r := GroupRule{Group: strings.TrimSpace(rule[:i])}
+		for _, id := range strings.Split(rule[i+1:], ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				r.RoomIDs = append(r.RoomIDs, id)
+			}
+		}
+		if len(r.RoomIDs) == 0 {
+			return nil, fmt.Errorf("group rule %q: no rooms", rule)
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// MapRooms returns the rooms the groups grant, without duplicates. Groups
+// match case-insensitively, and a rule naming a bare group also matches an
+// LDAP group DN whose CN it is.
+func MapRooms(rules []GroupRule, groups []string) []string {
+	var roomIDs []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if !memberOf(rule.Group, groups) {
+			continue
+		}
+		for _, id := range rule.RoomIDs {
+			if !seen[id] {
+				seen[id] = true
+				roomIDs = append(roomIDs, id)
+			}
+		}
+	}
+	return roomIDs
+}
+
+func memberOf(group string, groups []string) bool {
+	for _, g := range groups {
+		if strings.EqualFold(g, group) || strings.EqualFold(groupCN(g), group) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupCN returns the value of a DN's leading CN, or "" if it has none
+func groupCN(dn string) string {
+	rdn, _, _ := strings.Cut(dn, ",")
+	name, value, ok := strings.Cut(rdn, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(name), "cn") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,57 @@
+package sso
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGroupRules(t *testing.T) {
+	rules, err := ParseGroupRules([]string{
+		"engineering=room-1, room-2",
+		" cn=ops,ou=groups,dc=example,dc=com=room-3 ",
+		"",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []GroupRule{
+		{Group: "engineering", RoomIDs: []string{"room-1", "room-2"}},
+		{Group: "cn=ops,ou=groups,dc=example,dc=com", RoomIDs: []string{"room-3"}},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("Expected %+v, got %+v", want, rules)
+	}
+
+	for _, bad := range []string{"engineering", "=room-1", "engineering="} {
+		if _, err := ParseGroupRules([]string{bad}); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestMapRooms(t *testing.T) {
+	rules := []GroupRule{
+		{Group: "Engineering", RoomIDs: []string{"room-1", "room-2"}},
+		{Group: "cn=ops,ou=groups,dc=example,dc=com", RoomIDs: []string{"room-2", "room-3"}},
+		{Group: "sales", RoomIDs: []string{"room-4"}},
+	}
+
+	tests := []struct {
+		name   string
+		groups []string
+		want   []string
+	}{
+		{"no groups", nil, nil},
+		{"case-insensitive name", []string{"engineering"}, []string{"room-1", "room-2"}},
+		{"CN of a group DN", []string{"CN=Engineering,OU=Groups,DC=example,DC=com"}, []string{"room-1", "room-2"}},
+		{"full DN without duplicates", []string{"engineering", "cn=ops,ou=groups,dc=example,dc=com"}, []string{"room-1", "room-2", "room-3"}},
+		{"unmapped group", []string{"marketing"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapRooms(rules, tt.groups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
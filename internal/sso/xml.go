@@ -0,0 +1,312 @@
+package sso
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A minimal DOM that keeps what encoding/xml's Token throws away but XML
+// signatures depend on: namespace prefixes and where each namespace is
+// declared. Signed SAML responses are verified over this tree and their
+// contents are read from the same nodes, so what is read is what was
+// signed.
+
+const nsXML = "http://www.w3.org/XML/1998/namespace"
+
+// maxXMLDepth bounds element nesting; SAML responses are a few levels deep
+const maxXMLDepth = 64
+
+type xmlAttr struct {
+	prefix, local, value string
+}
+
+type xmlNode struct {
+	parent *xmlNode
+	// A text node has only text set
+	text   string
+	isText bool
+
+	prefix, local string
+	// nsDecls are the namespaces declared on this element by prefix, ""
+	// being the default namespace
+	nsDecls  map[string]string
+	attrs    []xmlAttr
+	children []*xmlNode
+}
+
+// parseXML parses a document into its root element. Document type
+// declarations and processing instructions inside the root are rejected.
+func parseXML(data []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+
+	var root, current *xmlNode
+	depth := 0
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if current == nil && root != nil {
+				return nil, errors.New("more than one root element")
+			}
+			if depth++; depth > maxXMLDepth {
+				return nil, errors.New("elements nested too deeply")
+			}
+			n := &xmlNode{parent: current, prefix: t.Name.Space, local: t.Name.Local, nsDecls: make(map[string]string)}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					n.nsDecls[""] = a.Value
+				case a.Name.Space == "xmlns":
+					n.nsDecls[a.Name.Local] = a.Value
+				default:
+					n.attrs = append(n.attrs, xmlAttr{prefix: a.Name.Space, local: a.Name.Local, value: a.Value})
+				}
+			}
+			if err := n.checkPrefixes(); err != nil {
+				return nil, err
+			}
+			if current == nil {
+				root = n
+			} else {
+				current.children = append(current.children, n)
+			}
+			current = n
+		case xml.EndElement:
+			if current == nil || t.Name.Space != current.prefix || t.Name.Local != current.local {
+				return nil, fmt.Errorf("unexpected end element %s", t.Name.Local)
+			}
+			current = current.parent
+			depth--
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, &xmlNode{parent: current, text: string(t), isText: true})
+			}
+		case xml.Directive:
+			return nil, errors.New("document type declarations are not allowed")
+		case xml.ProcInst:
+			if root != nil {
+				return nil, errors.New("processing instructions are not allowed")
+			}
+		}
+	}
+	if root == nil || current != nil {
+		return nil, errors.New("incomplete document")
+	}
+	return root, nil
+}
+
+// checkPrefixes rejects prefixes that are not declared in scope
+func (n *xmlNode) checkPrefixes() error {
+	if _, ok := n.lookupNS(n.prefix); !ok && n.prefix != "" {
+		return fmt.Errorf("undeclared namespace prefix %q", n.prefix)
+	}
+	for _, a := range n.attrs {
+		if _, ok := n.lookupNS(a.prefix); !ok && a.prefix != "" {
+			return fmt.Errorf("undeclared namespace prefix %q", a.prefix)
+		}
+	}
+	return nil
+}
+
+// lookupNS returns the namespace a prefix is bound to in n's scope
+func (n *xmlNode) lookupNS(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for e := n; e != nil; e = e.parent {
+		if uri, ok := e.nsDecls[prefix]; ok {
+			return uri, true
+		}
+	}
+	return "", false
+}
+
+// is reports whether n is the element local in namespace ns
+func (n *xmlNode) is(ns, local string) bool {
+	if n.isText || n.local != local {
+		return false
+	}
+	uri, _ := n.lookupNS(n.prefix)
+	return uri == ns
+}
+
+// attr returns the value of an unqualified attribute
+func (n *xmlNode) attr(local string) string {
+	for _, a := range n.attrs {
+		if a.prefix == "" && a.local == local {
+			return a.value
+		}
+	}
+	return ""
+}
+
+// elements returns n's child elements local in namespace ns
+func (n *xmlNode) elements(ns, local string) []*xmlNode {
+	var found []*xmlNode
+	for _, c := range n.children {
+		if c.is(ns, local) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// element returns n's only child element local in namespace ns, or nil if
+// there is none or more than one
+func (n *xmlNode) element(ns, local string) *xmlNode {
+	if found := n.elements(ns, local); len(found) == 1 {
+		return found[0]
+	}
+	return nil
+}
+
+// textContent returns the text of n and its descendants
+func (n *xmlNode) textContent() string {
+	if n.isText {
+		return n.text
+	}
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(c.textContent())
+	}
+	return b.String()
+}
+
+// canonicalize serializes n with Exclusive XML Canonicalization, without
+// comments (https://www.w3.org/TR/xml-exc-c14n/). inclusive lists the
+// prefixes treated as by inclusive canonicalization, "#default" for the
+// default namespace; exclude is left out, as the enveloped signature
+// transform requires.
+func canonicalize(n *xmlNode, inclusive []string, exclude *xmlNode) []byte {
+	var b bytes.Buffer
+	writeCanonical(&b, n, map[string]string{}, inclusive, exclude)
+	return b.Bytes()
+}
+
+func writeCanonical(b *bytes.Buffer, n *xmlNode, rendered map[string]string, inclusive []string, exclude *xmlNode) {
+	if n.isText {
+		b.WriteString(escapeCanonicalText(n.text))
+		return
+	}
+
+	// The namespaces visibly utilized by the element and its attributes,
+	// plus the inclusive ones in scope
+	utilized := []string{n.prefix}
+	for _, a := range n.attrs {
+		if a.prefix != "" {
+			utilized = append(utilized, a.prefix)
+		}
+	}
+	for _, p := range inclusive {
+		if p == "#default" {
+			p = ""
+		}
+		if _, ok := n.lookupNS(p); ok {
+			utilized = append(utilized, p)
+		}
+	}
+
+	decls := make(map[string]string)
+	for _, p := range utilized {
+		if p == "xml" {
+			continue
+		}
+		uri, _ := n.lookupNS(p)
+		prev, ok := rendered[p]
+		if p == "" && !ok {
+			// No output ancestor has a default namespace, so an empty one
+			// needs no xmlns=""
+			prev, ok = "", true
+		}
+		if !ok || prev != uri {
+			decls[p] = uri
+		}
+	}
+
+	scope := rendered
+	if len(decls) > 0 {
+		scope = make(map[string]string, len(rendered)+len(decls))
+		for p, uri := range rendered {
+			scope[p] = uri
+		}
+		for p, uri := range decls {
+			scope[p] = uri
+		}
+	}
+
+	name := n.local
+	if n.prefix != "" {
+		name = n.prefix + ":" + n.local
+	}
+	b.WriteString("<" + name)
+
+	prefixes := make([]string, 0, len(decls))
+	for p := range decls {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	for _, p := range prefixes {
+		if p == "" {
+			b.WriteString(` xmlns="` + escapeCanonicalAttr(decls[p]) + `"`)
+		} else {
+			b.WriteString(" xmlns:" + p + `="` + escapeCanonicalAttr(decls[p]) + `"`)
+		}
+	}
+
+	attrs := make([]xmlAttr, len(n.attrs))
+	copy(attrs, n.attrs)
+	attrNS := func(a xmlAttr) string {
+		if a.prefix == "" {
+			return ""
+		}
+		uri, _ := n.lookupNS(a.prefix)
+		return uri
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		if nsI, nsJ := attrNS(attrs[i]), attrNS(attrs[j]); nsI != nsJ {
+			return nsI < nsJ
+		}
+		return attrs[i].local < attrs[j].local
+	})
+	for _, a := range attrs {
+		b.WriteString(" ")
+		if a.prefix != "" {
+			b.WriteString(a.prefix + ":")
+		}
+		b.WriteString(a.local + `="` + escapeCanonicalAttr(a.value) + `"`)
+	}
+	b.WriteString(">")
+
+	for _, c := range n.children {
+		if c != exclude {
+			writeCanonical(b, c, scope, inclusive, exclude)
+		}
+	}
+	b.WriteString("</" + name + ">")
+}
+
+var (
+	canonicalTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	canonicalAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeCanonicalText(s string) string {
+	return canonicalTextEscaper.Replace(s)
+}
+
+func escapeCanonicalAttr(s string) string {
+	return canonicalAttrEscaper.Replace(s)
+}
@@ -0,0 +1,135 @@
+package sso
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// XML signature algorithms. Only what SAML IdPs sign with today is
+// accepted: exclusive canonicalization, SHA-256 and RSA.
+const (
+	nsDSig       = "http://www.w3.org/2000/09/xmldsig#"
+	nsExcC14N    = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algExcC14N   = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algRSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algSHA256    = "http://www.w3.org/2001/04/xmlenc#sha256"
+)
+
+// errNotSigned is returned for an element without a signature of its own
+var errNotSigned = errors.New("element is not signed")
+
+// signature returns the ds:Signature child of el, or nil
+func signature(el *xmlNode) *xmlNode {
+	return el.element(nsDSig, "Signature")
+}
+
+// verifySignature verifies the enveloped signature of el against cert. The
+// signature must be a child of el and reference el itself by its ID, so it
+// cannot vouch for some other element of the document.
+func verifySignature(el *xmlNode, cert *x509.Certificate) error {
+	if len(el.elements(nsDSig, "Signature")) == 0 {
+		return errNotSigned
+	}
+	sig := signature(el)
+	if sig == nil {
+		return errors.New("more than one signature")
+	}
+
+	signedInfo := sig.element(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return errors.New("signature has no SignedInfo")
+	}
+	c14n := signedInfo.element(nsDSig, "CanonicalizationMethod")
+	if c14n == nil || c14n.attr("Algorithm") != algExcC14N {
+		return errors.New("unsupported canonicalization method")
+	}
+	method := signedInfo.element(nsDSig, "SignatureMethod")
+	if method == nil || method.attr("Algorithm") != algRSASHA256 {
+		return errors.New("unsupported signature method")
+	}
+
+	ref := signedInfo.element(nsDSig, "Reference")
+	if ref == nil {
+		return errors.New("signature must have exactly one reference")
+	}
+	id := el.attr("ID")
+	if id == "" || ref.attr("URI") != "#"+id {
+		return errors.New("signature does not reference the signed element")
+	}
+
+	var enveloped, excC14N bool
+	var refInclusive []string
+	if transforms := ref.element(nsDSig, "Transforms"); transforms != nil {
+		for _, t := range transforms.elements(nsDSig, "Transform") {
+			switch t.attr("Algorithm") {
+			case algEnveloped:
+				enveloped = true
+			case algExcC14N:
+				excC14N = true
+				refInclusive = inclusivePrefixes(t)
+			default:
+				return fmt.Errorf("unsupported transform %q", t.attr("Algorithm"))
+			}
+		}
+	}
+	if !enveloped || !excC14N {
+		return errors.New("signature must use the enveloped and exclusive canonicalization transforms")
+	}
+
+	digestMethod := ref.element(nsDSig, "DigestMethod")
+	if digestMethod == nil || digestMethod.attr("Algorithm") != algSHA256 {
+		return errors.New("unsupported digest method")
+	}
+	digestValue := ref.element(nsDSig, "DigestValue")
+	if digestValue == nil {
+		return errors.New("reference has no digest")
+	}
+	wantDigest, err := decodeBase64(digestValue.textContent())
+	if err != nil {
+		return errors.New("invalid digest encoding")
+	}
+	digest := sha256.Sum256(canonicalize(el, refInclusive, sig))
+	if subtle.ConstantTimeCompare(digest[:], wantDigest) != 1 {
+		return errors.New("digest mismatch")
+	}
+
+	sigValue := sig.element(nsDSig, "SignatureValue")
+	if sigValue == nil {
+		return errors.New("signature has no value")
+	}
+	sigBytes, err := decodeBase64(sigValue.textContent())
+	if err != nil {
+		return errors.New("invalid signature encoding")
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("IdP certificate does not have an RSA key")
+	}
+	hashed := sha256.Sum256(canonicalize(signedInfo, inclusivePrefixes(c14n), nil))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], sigBytes); err != nil {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// inclusivePrefixes returns the InclusiveNamespaces PrefixList of an
+// exclusive canonicalization method or transform
+func inclusivePrefixes(method *xmlNode) []string {
+	if list := method.element(nsExcC14N, "InclusiveNamespaces"); list != nil {
+		return strings.Fields(list.attr("PrefixList"))
+	}
+	return nil
+}
+
+// decodeBase64 decodes base64 that may be wrapped over several lines
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
@@ -0,0 +1,105 @@
+package system
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultReadinessTimeout bounds each dependency ping
+const DefaultReadinessTimeout = 2 * time.Second
+
+// DependencyStatus is the outcome of pinging one dependency
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadinessReport is the result of pinging every dependency
+type ReadinessReport struct {
+	Status       string                       `json:"status"`
+	Timestamp    string                       `json:"timestamp"`
+	Dependencies map[string]*DependencyStatus `json:"dependencies"`
+}
+
+// Ready reports whether every dependency answered
+func (r *ReadinessReport) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type dependency struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// Readiness pings the dependencies the server cannot serve traffic without
+type Readiness struct {
+	timeout      time.Duration
+	dependencies []dependency
+}
+
+// NewReadiness creates a readiness check giving each dependency timeout to
+// answer
+func NewReadiness(timeout time.Duration) *Readiness {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+	return &Readiness{timeout: timeout}
+}
+
+// Add registers a dependency. Call it before serving traffic.
+func (r *Readiness) Add(name string, check func(ctx context.Context) error) {
+	r.dependencies = append(r.dependencies, dependency{name: name, check: check})
+}
+
+// Check pings every dependency concurrently, so a slow one costs at most
+// one timeout
+func (r *Readiness) Check(ctx context.Context) *ReadinessReport {
+	report := &ReadinessReport{
+		Status:       StatusOK,
+		Timestamp:    time.Now().Format(time.RFC3339),
+		Dependencies: make(map[string]*DependencyStatus, len(r.dependencies)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, dep := range r.dependencies {
+		wg.Add(1)
+		go func(dep dependency) {
+			defer wg.Done()
+			status := r.ping(ctx, dep)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Dependencies[dep.name] = status
+			if status.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(dep)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (r *Readiness) ping(ctx context.Context, dep dependency) *DependencyStatus {
+	pingCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dep.check(pingCtx)
+	status := &DependencyStatus{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err == nil && pingCtx.Err() != nil {
+		// A check that ignores its context still fails once the time is up
+		err = pingCtx.Err()
+	}
+	if err != nil {
+		status.Status = StatusFail
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -0,0 +1,70 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadiness_AllUp(t *testing.T) {
+	r := NewReadiness(time.Second)
+	r.Add("postgres", func(context.Context) error { return nil })
+	r.Add("redis", func(context.Context) error { return nil })
+
+	report := r.Check(context.Background())
+	if !report.Ready() {
+		t.Fatalf("Expected ready, got %+v", report)
+	}
+	if len(report.Dependencies) != 2 || report.Dependencies["redis"].Status != StatusOK {
+		t.Errorf("Expected both dependencies reported ok, got %+v", report.Dependencies)
+	}
+}
+
+func TestReadiness_DependencyDown(t *testing.T) {
+	r := NewReadiness(time.Second)
+	r.Add("postgres", func(context.Context) error { return nil })
+	r.Add("redis", func(context.Context) error { return errors.New("connection refused") })
+
+	report := r.Check(context.Background())
+	if report.Ready() || report.Status != StatusFail {
+		t.Fatalf("Expected not ready, got %s", report.Status)
+	}
+	if dep := report.Dependencies["redis"]; dep.Status != StatusFail || dep.Error != "connection refused" {
+		t.Errorf("Unexpected redis status: %+v", dep)
+	}
+	if dep := report.Dependencies["postgres"]; dep.Status != StatusOK {
+		t.Errorf("Expected postgres ok, got %+v", dep)
+	}
+}
+
+func TestReadiness_Timeout(t *testing.T) {
+	r := NewReadiness(20 * time.Millisecond)
+	r.Add("postgres", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	r.Add("redis", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := r.Check(context.Background())
+	if report.Ready() {
+		t.Fatal("Expected a hanging dependency to fail readiness")
+	}
+	// Dependencies are pinged concurrently
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected checks to run concurrently, took %v", elapsed)
+	}
+	if dep := report.Dependencies["postgres"]; dep.Error == "" {
+		t.Errorf("Expected a timeout error, got %+v", dep)
+	}
+}
+
+func TestReadiness_NoDependencies(t *testing.T) {
+	if report := NewReadiness(0).Check(context.Background()); !report.Ready() {
+		t.Errorf("Expected ready without dependencies, got %s", report.Status)
+	}
+}
@@ -0,0 +1,323 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+)
+
+// ExpectedSchemaVersion is the migration level this binary was built against.
+// Bump it together with every new file in migrations/.
+const ExpectedSchemaVersion = 53
+
+const (
+	// gen_random_uuid() is built in from PostgreSQL 13
+	minPostgresVersionNum = 130000
+	minRedisVersion       = "6.2"
+)
+
+// Dependencies whose versions are included in the startup report
+var reportedModules = []string{
+	"github.com/gin-gonic/gin",
+	"github.com/gorilla/websocket",
+	"github.com/jmoiron/sqlx",
+	"github.com/lib/pq",
+	"github.com/redis/go-redis/v9",
+	"github.com/golang-jwt/jwt/v5",
+}
+
+// Check status values
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// CheckResult is the outcome of a single startup check
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// DatabaseInfo describes the connected PostgreSQL server
+type DatabaseInfo struct {
+	Version               string `json:"version"`
+	SchemaVersion         int    `json:"schema_version"`
+	ExpectedSchemaVersion int    `json:"expected_schema_version"`
+	Dirty                 bool   `json:"dirty"`
+}
+
+// RedisInfo describes the connected Redis server
+type RedisInfo struct {
+	Version string   `json:"version"`
+	Modules []string `json:"modules"`
+}
+
+// Report is the structured startup report
+type Report struct {
+	Version      string            `json:"version"`
+	GoVersion    string            `json:"go_version"`
+	StartedAt    time.Time         `json:"started_at"`
+	Uptime       string            `json:"uptime"`
+	Database     DatabaseInfo      `json:"database"`
+	Redis        RedisInfo         `json:"redis"`
+	Dependencies map[string]string `json:"dependencies"`
+	Checks       []CheckResult     `json:"checks"`
+}
+
+// OK reports whether every check passed (warnings are allowed)
+func (r *Report) OK() bool {
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Err joins all failed checks into a single error, or returns nil
+func (r *Report) Err() error {
+	var errs []error
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			errs = append(errs, fmt.Errorf("%s: %s", check.Name, check.Message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Report) add(name, status, message string) {
+	r.Checks = append(r.Checks, CheckResult{Name: name, Status: status, Message: message})
+}
+
+// Checker verifies that runtime dependencies match what the binary expects
+type Checker struct {
+	db              *sqlx.DB
+	redis           *redis.Client
+	version         string
+	requiredModules []string
+	startedAt       time.Time
+}
+
+// NewChecker creates a new Checker
+func NewChecker(db *sqlx.DB, redisClient *redis.Client, version string, requiredModules []string) *Checker {
+	return &Checker{
+		db:              db,
+		redis:           redisClient,
+		version:         version,
+		requiredModules: requiredModules,
+		startedAt:       time.Now(),
+	}
+}
+
+// Run executes all checks and builds a report
+func (c *Checker) Run(ctx context.Context) *Report {
+	report := &Report{
+		Version:      c.version,
+		GoVersion:    runtime.Version(),
+		StartedAt:    c.startedAt,
+		Uptime:       time.Since(c.startedAt).Round(time.Second).String(),
+		Dependencies: dependencyVersions(),
+	}
+
+	c.checkPostgres(ctx, report)
+	c.checkSchema(ctx, report)
+	c.checkRedis(ctx, report)
+
+	return report
+}
+
+func (c *Checker) checkPostgres(ctx context.Context, report *Report) {
+	var versionNum int
+	if err := c.db.GetContext(ctx, &versionNum, "SELECT current_setting('server_version_num')::int"); err != nil {
+		report.add("postgres", StatusFail, fmt.Sprintf("failed to query server version: %v", err))
+		return
+	}
+
+	_ = c.db.GetContext(ctx, &report.Database.Version, "SHOW server_version")
+
+	if versionNum < minPostgresVersionNum {
+		report.add("postgres", StatusFail, fmt.Sprintf(
+			"PostgreSQL %s is not supported, version 13 or newer is required", report.Database.Version))
+		return
+	}
+	report.add("postgres", StatusOK, "")
+}
+
+func (c *Checker) checkSchema(ctx context.Context, report *Report) {
+	report.Database.ExpectedSchemaVersion = ExpectedSchemaVersion
+
+	var row struct {
+		Version int  `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	err := c.db.GetContext(ctx, &row, "SELECT version, dirty FROM schema_migrations LIMIT 1")
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			report.add("schema", StatusFail, "no migrations applied, run `make migrate-up`")
+			return
+		}
+		report.add("schema", StatusFail, fmt.Sprintf(
+			"failed to read schema_migrations (have migrations been run?): %v", err))
+		return
+	}
+
+	report.Database.SchemaVersion = row.Version
+	report.Database.Dirty = row.Dirty
+
+	switch {
+	case row.Dirty:
+		report.add("schema", StatusFail, fmt.Sprintf(
+			"migration %d is dirty, fix the database and force the version before starting", row.Version))
+	case row.Version < ExpectedSchemaVersion:
+		report.add("schema", StatusFail, fmt.Sprintf(
+			"schema version %d is older than required %d, run `make migrate-up`", row.Version, ExpectedSchemaVersion))
+	case row.Version > ExpectedSchemaVersion:
+		// Newer schema is expected during rolling deploys
+		report.add("schema", StatusWarn, fmt.Sprintf(
+			"schema version %d is newer than this binary (%d)", row.Version, ExpectedSchemaVersion))
+	default:
+		report.add("schema", StatusOK, "")
+	}
+}
+
+func (c *Checker) checkRedis(ctx context.Context, report *Report) {
+	info, err := c.redis.Info(ctx, "server").Result()
+	if err != nil {
+		report.add("redis", StatusFail, fmt.Sprintf("failed to query server info: %v", err))
+		return
+	}
+
+	report.Redis.Version = parseRedisInfo(info)["redis_version"]
+	if compareVersions(report.Redis.Version, minRedisVersion) < 0 {
+		report.add("redis", StatusFail, fmt.Sprintf(
+			"Redis %s is not supported, version %s or newer is required", report.Redis.Version, minRedisVersion))
+		return
+	}
+	report.add("redis", StatusOK, "")
+
+	modules, err := c.listRedisModules(ctx)
+	if err != nil {
+		if len(c.requiredModules) > 0 {
+			report.add("redis_modules", StatusFail, fmt.Sprintf("failed to list modules: %v", err))
+		}
+		return
+	}
+	report.Redis.Modules = modules
+
+	if missing := missingModules(c.requiredModules, modules); len(missing) > 0 {
+		report.add("redis_modules", StatusFail, "missing required modules: "+strings.Join(missing, ", "))
+		return
+	}
+	if len(c.requiredModules) > 0 {
+		report.add("redis_modules", StatusOK, "")
+	}
+}
+
+func (c *Checker) listRedisModules(ctx context.Context) ([]string, error) {
+	result, err := c.redis.Do(ctx, "MODULE", "LIST").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	modules := []string{}
+	entries, _ := result.([]interface{})
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case map[interface{}]interface{}: // RESP3
+			if name, ok := e["name"].(string); ok {
+				modules = append(modules, name)
+			}
+		case []interface{}: // RESP2: flat key/value list
+			for i := 0; i+1 < len(e); i += 2 {
+				if key, _ := e[i].(string); key == "name" {
+					if name, ok := e[i+1].(string); ok {
+						modules = append(modules, name)
+					}
+				}
+			}
+		}
+	}
+	return modules, nil
+}
+
+func parseRedisInfo(info string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func missingModules(required, loaded []string) []string {
+	have := make(map[string]bool, len(loaded))
+	for _, name := range loaded {
+		have[strings.ToLower(name)] = true
+	}
+
+	var missing []string
+	for _, name := range required {
+		if !have[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func dependencyVersions() map[string]string {
+	versions := make(map[string]string)
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versions
+	}
+
+	wanted := make(map[string]bool, len(reportedModules))
+	for _, path := range reportedModules {
+		wanted[path] = true
+	}
+	for _, dep := range info.Deps {
+		if wanted[dep.Path] {
+			versions[dep.Path] = dep.Version
+		}
+	}
+	return versions
+}
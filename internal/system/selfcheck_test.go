@@ -0,0 +1,89 @@
+package system
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestExpectedSchemaVersion_MatchesMigrations(t *testing.T) {
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Skipf("Skipping test, migrations directory not found: %v", err)
+	}
+
+	pattern := regexp.MustCompile(`^(\d+)_`)
+	latest := 0
+	for _, file := range files {
+		match := pattern.FindStringSubmatch(filepath.Base(file))
+		if match == nil {
+			continue
+		}
+		if v, _ := strconv.Atoi(match[1]); v > latest {
+			latest = v
+		}
+	}
+
+	if latest != ExpectedSchemaVersion {
+		t.Errorf("ExpectedSchemaVersion is %d but latest migration is %d", ExpectedSchemaVersion, latest)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"7.2.4", "6.2", 1},
+		{"6.2", "6.2.0", 0},
+		{"6.0.16", "6.2", -1},
+		{"10.0", "9.9", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestParseRedisInfo(t *testing.T) {
+	info := "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n"
+
+	values := parseRedisInfo(info)
+
+	if values["redis_version"] != "7.2.4" {
+		t.Errorf("Expected 7.2.4, got %q", values["redis_version"])
+	}
+	if values["redis_mode"] != "standalone" {
+		t.Errorf("Expected standalone, got %q", values["redis_mode"])
+	}
+}
+
+func TestMissingModules(t *testing.T) {
+	missing := missingModules([]string{"search", "ReJSON"}, []string{"rejson"})
+
+	if len(missing) != 1 || missing[0] != "search" {
+		t.Errorf("Expected [search], got %v", missing)
+	}
+}
+
+func TestReport_Err(t *testing.T) {
+	report := &Report{}
+	report.add("postgres", StatusOK, "")
+	report.add("schema", StatusWarn, "newer")
+
+	if !report.OK() || report.Err() != nil {
+		t.Error("Expected warnings not to fail the report")
+	}
+
+	report.add("redis", StatusFail, "unreachable")
+
+	if report.OK() {
+		t.Error("Expected report to fail")
+	}
+	if err := report.Err(); err == nil || err.Error() != "redis: unreachable" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package ws
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-demo/chat/internal/features"
+)
+
+// ProtocolVersion is advertised to clients that negotiate capabilities.
+// Version 3 added the join_rooms frame; version 4 the msgpack encoding;
+// version 5 the resume frame; version 6 room presence.
+const ProtocolVersion = 6
+
+// Frame encodings. JSON travels in text frames; MessagePack in binary
+// frames, several messages in a frame simply following one another.
+const (
+	EncodingJSON    = "json"
+	EncodingMsgpack = "msgpack"
+)
+
+// minClientPayload is the smallest max_payload a client may declare;
+// anything lower could not carry an ordinary chat message
+const minClientPayload = 1024
+
+// controlEvents are delivered whatever a client declares, since a client
+// cannot work without them
+var controlEvents = map[MessageType]bool{
+	MessageTypeWelcome:         true,
+	MessageTypeError:           true,
+	MessageTypeAck:             true,
+	MessageTypePong:            true,
+	MessageTypeAccountBanned:   true,
+	MessageTypeSessionRevoked:  true,
+	MessageTypeReconnect:       true,
+	MessageTypeReconnectTicket: true,
+	MessageTypeRoomsJoined:     true,
+	MessageTypeResumed:         true,
+	MessageTypeRoomPresence:    true,
+}
+
+// legacyEvents are the events clients received before capabilities were
+// negotiated. A client that declares nothing gets only these, so events
+// added later never reach a client that cannot parse them.
+var legacyEvents = map[MessageType]bool{
+	MessageTypeRoomJoined:             true,
+	MessageTypeRoomLeft:               true,
+	MessageTypeNewMessage:             true,
+	MessageTypeUserTyping:             true,
+	MessageTypeUserStopTyping:         true,
+	MessageTypeUserOnline:             true,
+	MessageTypeUserOffline:            true,
+	MessageTypeNewDM:                  true,
+	MessageTypeDMRead:                 true,
+	MessageTypeNotification:           true,
+	MessageTypeRoomDeleting:           true,
+	MessageTypeRoomDeletionCanceled:   true,
+	MessageTypeRoomDeleted:            true,
+	MessageTypeRoomMerged:             true,
+	MessageTypeMessageExpired:         true,
+	MessageTypeDMExpired:              true,
+	MessageTypeMemberMuted:            true,
+	MessageTypeMemberUnmuted:          true,
+	MessageTypeRoomPermissionsUpdated: true,
+}
+
+// Capabilities is what a client declared in the WebSocket handshake
+type Capabilities struct {
+	// Negotiated is false for clients that declared nothing
+	Negotiated bool
+	// Events the client handles; nil means the legacy set
+	Events map[MessageType]bool
+	// Encoding chosen from the client's list
+	Encoding string
+	// MaxPayload is the largest frame the client accepts; zero is unlimited
+	MaxPayload int
+}
+
+// ParseCapabilities reads capabilities from the handshake query:
+//
+//	events=new_message,user_typing  event types the client handles
+//	encodings=msgpack,json           frame encodings, in order of preference
+//	max_payload=65536                largest frame in bytes
+//
+// Unknown event names are ignored so newer clients can talk to older servers.
+func ParseCapabilities(query url.Values) (*Capabilities, error) {
+	caps := &Capabilities{Encoding: EncodingJSON}
+
+	if raw, ok := query["events"]; ok {
+		caps.Negotiated = true
+		caps.Events = make(map[MessageType]bool)
+		for _, name := range splitList(raw) {
+			caps.Events[MessageType(name)] = true
+		}
+	}
+
+	if raw, ok := query["encodings"]; ok {
+		caps.Negotiated = true
+		caps.Encoding = ""
+		caps.Encoding = SelectEncoding(splitList(raw))
+		if caps.Encoding == "" {
+			return nil, fmt.Errorf("no supported encoding in %q (supported: %s)", strings.Join(raw, ","), strings.Join(supportedEncodings(), ", "))
+		}
+	}
+
+	if raw := query.Get("max_payload"); raw != "" {
+		caps.Negotiated = true
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < minClientPayload {
+			return nil, fmt.Errorf("max_payload must be a number of at least %d", minClientPayload)
+		}
+		caps.MaxPayload = n
+	}
+
+	return caps, nil
+}
+
+// SelectEncoding returns the first of the offered encodings the server
+// speaks, or "" when there is none. WebSocket subprotocols offered in the
+// handshake are encoding names too.
+func SelectEncoding(offered []string) string {
+	for _, name := range offered {
+		if _, ok := encoders[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+func supportedEncodings() []string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Accepts reports whether an event should be sent to the client
+func (c *Capabilities) Accepts(t MessageType) bool {
+	if controlEvents[t] {
+		return true
+	}
+	if c.Events == nil {
+		return legacyEvents[t]
+	}
+	return c.Events[t]
+}
+
+// AcceptedEvents lists the non-control events the client will receive
+func (c *Capabilities) AcceptedEvents() []MessageType {
+	set := c.Events
+	if set == nil {
+		set = legacyEvents
+	}
+
+	events := make([]MessageType, 0, len(set))
+	for t := range set {
+		if !controlEvents[t] {
+			events = append(events, t)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+	return events
+}
+
+// newWelcomePayload describes the negotiated session and the server's
+// enabled features
+func newWelcomePayload(caps *Capabilities, flags *features.Set, maxMessageSize int) *WelcomePayload {
+	enabled := []string{}
+	for _, f := range features.NonCritical {
+		if flags.Enabled(f) {
+			enabled = append(enabled, string(f))
+		}
+	}
+
+	return &WelcomePayload{
+		ProtocolVersion: ProtocolVersion,
+		Features:        enabled,
+		Events:          caps.AcceptedEvents(),
+		Encoding:        caps.Encoding,
+		MaxPayload:      caps.MaxPayload,
+		MaxMessageSize:  maxMessageSize,
+	}
+}
+
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,181 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-demo/chat/internal/features"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	t.Run("legacy client", func(t *testing.T) {
+		caps, err := ParseCapabilities(url.Values{"token": {"x"}})
+		if err != nil {
+			t.Fatalf("ParseCapabilities: %v", err)
+		}
+		if caps.Negotiated || caps.Events != nil || caps.Encoding != EncodingJSON || caps.MaxPayload != 0 {
+			t.Errorf("Unexpected legacy capabilities: %+v", caps)
+		}
+	})
+
+	t.Run("declared", func(t *testing.T) {
+		caps, err := ParseCapabilities(url.Values{
+			"events":      {"new_message, user_typing", "message_reaction"},
+			"encodings":   {"cbor,json"},
+			"max_payload": {"65536"},
+		})
+		if err != nil {
+			t.Fatalf("ParseCapabilities: %v", err)
+		}
+		if !caps.Negotiated || caps.Encoding != EncodingJSON || caps.MaxPayload != 65536 {
+			t.Errorf("Unexpected capabilities: %+v", caps)
+		}
+		if len(caps.Events) != 3 || !caps.Events[MessageTypeUserTyping] {
+			t.Errorf("Unexpected events: %v", caps.Events)
+		}
+	})
+
+	t.Run("msgpack preferred", func(t *testing.T) {
+		caps, err := ParseCapabilities(url.Values{"encodings": {"cbor,msgpack,json"}})
+		if err != nil {
+			t.Fatalf("ParseCapabilities: %v", err)
+		}
+		if caps.Encoding != EncodingMsgpack {
+			t.Errorf("Expected msgpack, got %q", caps.Encoding)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, query := range []url.Values{
+			{"encodings": {"cbor"}},
+			{"max_payload": {"abc"}},
+			{"max_payload": {"512"}},
+		} {
+			if _, err := ParseCapabilities(query); err == nil {
+				t.Errorf("Expected %v to be rejected", query)
+			}
+		}
+	})
+}
+
+func TestCapabilities_Accepts(t *testing.T) {
+	legacy := &Capabilities{}
+	if !legacy.Accepts(MessageTypeNewMessage) {
+		t.Error("Expected legacy client to receive new messages")
+	}
+	if legacy.Accepts("message_reaction") {
+		t.Error("Expected legacy client to skip events it predates")
+	}
+
+	declared := &Capabilities{Negotiated: true, Events: map[MessageType]bool{MessageTypeNewMessage: true}}
+	if declared.Accepts(MessageTypeUserTyping) {
+		t.Error("Expected undeclared event to be skipped")
+	}
+	if !declared.Accepts(MessageTypeError) || !declared.Accepts(MessageTypeAck) {
+		t.Error("Expected control events to always be delivered")
+	}
+
+	for _, e := range declared.AcceptedEvents() {
+		if controlEvents[e] {
+			t.Errorf("Expected control event %s not to be listed", e)
+		}
+	}
+}
+
+func TestNewWelcomePayload(t *testing.T) {
+	flags := features.NewSet(features.FlagTypingBroadcasts)
+	caps := &Capabilities{
+		Negotiated: true,
+		Events:     map[MessageType]bool{MessageTypeNewMessage: true, MessageTypeUserTyping: true},
+		Encoding:   EncodingJSON,
+		MaxPayload: 4096,
+	}
+
+	payload := newWelcomePayload(caps, flags, defaultMaxMessageSize)
+	if payload.ProtocolVersion != ProtocolVersion || payload.MaxPayload != 4096 || payload.MaxMessageSize != defaultMaxMessageSize {
+		t.Errorf("Unexpected welcome: %+v", payload)
+	}
+	for _, f := range payload.Features {
+		if f == string(features.FlagTypingBroadcasts) {
+			t.Error("Expected disabled flag not to be advertised")
+		}
+	}
+	if len(payload.Features) != len(features.NonCritical)-1 {
+		t.Errorf("Expected other flags to be advertised, got %v", payload.Features)
+	}
+	if len(payload.Events) != 2 || payload.Events[0] != MessageTypeNewMessage {
+		t.Errorf("Expected sorted declared events, got %v", payload.Events)
+	}
+}
+
+func TestHub_BroadcastSkipsUndeclaredEvents(t *testing.T) {
+	hub := createTestHub()
+	legacy := createMockClient("user-1", "alice")
+	modern := createMockClient("user-2", "bob")
+	modern.SetCapabilities(&Capabilities{
+		Negotiated: true,
+		Events:     map[MessageType]bool{MessageTypeNewMessage: true, "message_reaction": true},
+	})
+	legacy.SetCapabilities(&Capabilities{})
+
+	hub.rooms["room-1"] = map[*Client]bool{legacy: true, modern: true}
+
+	reaction, _ := NewMessage("message_reaction", map[string]string{"emoji": "+1"})
+	hub.broadcastToRoom(&BroadcastMessage{RoomID: "room-1", Message: reaction})
+
+	if len(legacy.send) != 0 {
+		t.Error("Expected legacy client to skip the reaction event")
+	}
+	if len(modern.send) != 1 {
+		t.Fatalf("Expected declaring client to receive the reaction event, got %d", len(modern.send))
+	}
+
+	typing, _ := NewMessage(MessageTypeUserTyping, &UserTypingPayload{RoomID: "room-1"})
+	modern.SendMessage(typing)
+	if len(modern.send) != 1 {
+		t.Error("Expected undeclared direct event to be skipped")
+	}
+}
+
+func TestClient_MaxPayload(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+	client.SetCapabilities(&Capabilities{MaxPayload: minClientPayload})
+
+	small, _ := NewMessage(MessageTypeNewMessage, &NewMessagePayload{Content: "hi"})
+	large, _ := NewMessage(MessageTypeNewMessage, &NewMessagePayload{Content: strings.Repeat("x", 2*minClientPayload)})
+	client.SendMessage(small)
+	client.SendMessage(large)
+
+	if len(client.send) != 1 {
+		t.Fatalf("Expected only the small message to be queued, got %d", len(client.send))
+	}
+	var got Message
+	if err := json.Unmarshal(<-client.send, &got); err != nil || got.Type != MessageTypeNewMessage {
+		t.Errorf("Unexpected queued message: %+v, %v", got, err)
+	}
+	if hub.oversizedMessages.Load() != 1 {
+		t.Errorf("Expected 1 oversized message, got %d", hub.oversizedMessages.Load())
+	}
+}
+
+func TestSelectEncoding(t *testing.T) {
+	tests := []struct {
+		offered []string
+		want    string
+	}{
+		{[]string{"msgpack", "json"}, EncodingMsgpack},
+		{[]string{"json", "msgpack"}, EncodingJSON},
+		{[]string{"chat.v2", "msgpack"}, EncodingMsgpack},
+		{[]string{"chat.v2"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := SelectEncoding(tt.offered); got != tt.want {
+			t.Errorf("SelectEncoding(%v) = %q, want %q", tt.offered, got, tt.want)
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-demo/chat/internal/anomaly"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
@@ -13,19 +19,51 @@ const (
 	// Time allowed to write a message to the peer
 	writeWait = 10 * time.Second
 
-	// Time allowed to read the next pong message from the peer
+	// Default time a peer may stay silent before the idle reaper closes it
 	pongWait = 60 * time.Second
 
-	// Send pings to peer with this period. Must be less than pongWait
+	// Default period of pings to the peer. Must be less than pongWait
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 4096
+	// Maximum message size allowed from peer unless configured otherwise
+	defaultMaxMessageSize = 4096
 
-	// Send buffer size
+	// Most rooms a join_rooms frame may name; 100 IDs fit in the default
+	// max message size
+	maxJoinRoomsBatch = 100
+
+	// Default send buffer size
 	sendBufferSize = 256
+
+	// Log a drop warning once every this many dropped messages per client
+	dropLogInterval = 100
+
+	// Recent write durations kept for latency percentiles
+	clientLatencySamples = 128
+	hubLatencySamples    = 4096
+)
+
+// SlowConsumerPolicy decides what happens when a client's send buffer is full
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerDropOldest discards the oldest queued message to make room
+	SlowConsumerDropOldest SlowConsumerPolicy = "drop_oldest"
+	// SlowConsumerDisconnect evicts the client so it can reconnect and resync
+	SlowConsumerDisconnect SlowConsumerPolicy = "disconnect"
 )
 
+// ParseSlowConsumerPolicy validates a policy name from configuration
+func ParseSlowConsumerPolicy(name string) (SlowConsumerPolicy, error) {
+	switch SlowConsumerPolicy(name) {
+	case "", SlowConsumerDropOldest:
+		return SlowConsumerDropOldest, nil
+	case SlowConsumerDisconnect:
+		return SlowConsumerDisconnect, nil
+	}
+	return "", fmt.Errorf("unknown slow consumer policy %q (supported: drop_oldest, disconnect)", name)
+}
+
 // Client represents a WebSocket client connection
 type Client struct {
 	hub      *Hub
@@ -36,19 +74,111 @@ type Client struct {
 	rooms    map[string]bool // Subscribed rooms
 	mu       sync.RWMutex
 	logger   *zap.Logger
+
+	// Canceled when the connection closes so in-flight work is abandoned
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Guards send against writes after Close
+	sendMu  sync.Mutex
+	closed  bool
+	dropped atomic.Int64
+	evicted atomic.Bool
+
+	// Recent write durations
+	latency *latencyWindow
+
+	// When the peer last sent a frame or pong, in Unix nanoseconds
+	lastSeen atomic.Int64
+
+	// Declared in the handshake; nil accepts every event
+	caps *Capabilities
+
+	// Device and access token expiry the connection was authenticated with
+	deviceID         string
+	sessionExpiresAt time.Time
 }
 
 // NewClient creates a new client
 func NewClient(hub *Hub, conn *websocket.Conn, userID, username string, logger *zap.Logger) *Client {
-	return &Client{
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bufferSize := sendBufferSize
+	if hub != nil && hub.sendBufferSize > 0 {
+		bufferSize = hub.sendBufferSize
+	}
+
+	c := &Client{
 		hub:      hub,
 		conn:     conn,
-		send:     make(chan []byte, sendBufferSize),
+		send:     make(chan []byte, bufferSize),
 		userID:   userID,
 		username: username,
 		rooms:    make(map[string]bool),
 		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		latency:  newLatencyWindow(clientLatencySamples),
+	}
+	c.touch()
+	return c
+}
+
+// Context returns a context that is canceled when the client disconnects
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WriteLatency returns the given percentile (0-100) of recent write durations
+func (c *Client) WriteLatency(percentile float64) time.Duration {
+	if c.latency == nil {
+		return 0
+	}
+	return c.latency.Percentiles(percentile)[0]
+}
+
+// SetCapabilities records what the client declared in the handshake. It
+// must be called before the client is registered.
+func (c *Client) SetCapabilities(caps *Capabilities) {
+	c.caps = caps
+}
+
+// SetClientIP records the address the connection came from, so work done
+// for the client can be attributed to its network. It must be called
+// before the client is registered.
+func (c *Client) SetClientIP(ip string) {
+	c.ctx = anomaly.WithClientIP(c.ctx, ip)
+}
+
+// SetSession records the device the client signed in from and when its
+// access token expires, which bound the reconnect tickets issued to it. It
+// must be called before the client is registered.
+func (c *Client) SetSession(deviceID string, expiresAt time.Time) {
+	c.deviceID = deviceID
+	c.sessionExpiresAt = expiresAt
+}
+
+// Accepts reports whether an event should be sent to the client
+func (c *Client) Accepts(t MessageType) bool {
+	return c.caps == nil || c.caps.Accepts(t)
+}
+
+// encoding is the frame encoding negotiated by the client
+func (c *Client) encoding() string {
+	if c.caps == nil || c.caps.Encoding == "" {
+		return EncodingJSON
+	}
+	return c.caps.Encoding
+}
+
+func (c *Client) maxPayload() int {
+	if c.caps == nil {
+		return 0
 	}
+	return c.caps.MaxPayload
 }
 
 // GetUserID returns client's user ID
@@ -101,16 +231,29 @@ func (c *Client) ReadPump() {
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
-	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	// The hub's idle reaper closes the connection if the peer goes silent
+	c.conn.SetReadLimit(int64(c.hub.readLimit()))
 	c.conn.SetPongHandler(func(string) error {
-		_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.touch()
 		return nil
 	})
 
 	for {
-		_, data, err := c.conn.ReadMessage()
+		frameType, data, err := c.conn.ReadMessage()
 		if err != nil {
+			// The connection has already been closed with 1009; large
+			// bodies belong in POST /api/v1/ws/content
+			if errors.Is(err, websocket.ErrReadLimit) {
+				c.hub.oversizedFrames.Add(1)
+				c.logger.Warn("WebSocket frame exceeds max message size",
+					zap.String("user_id", c.userID),
+					zap.Int("max_message_size", c.hub.readLimit()),
+				)
+			}
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				c.hub.clientCloses.Add(1)
+			}
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				c.logger.Warn("WebSocket read error",
 					zap.String("user_id", c.userID),
@@ -119,9 +262,16 @@ func (c *Client) ReadPump() {
 			}
 			break
 		}
+		c.touch()
 
+		// Binary frames carry MessagePack whatever encoding was negotiated
 		var msg Message
-		if err := json.Unmarshal(data, &msg); err != nil {
+		if frameType == websocket.BinaryMessage {
+			err = decodeMsgpack(data, &msg)
+		} else {
+			err = json.Unmarshal(data, &msg)
+		}
+		if err != nil {
 			c.logger.Warn("Failed to parse message",
 				zap.String("user_id", c.userID),
 				zap.Error(err),
@@ -136,7 +286,7 @@ func (c *Client) ReadPump() {
 
 // WritePump pumps messages from the hub to the WebSocket connection
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.pingInterval())
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -145,44 +295,118 @@ func (c *Client) WritePump() {
 	for {
 		select {
 		case message, ok := <-c.send:
-			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout()))
 			if !ok {
 				// Hub closed the channel
 				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			_, _ = w.Write(message)
-
-			// Add queued messages to the current WebSocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				_, _ = w.Write([]byte{'\n'})
-				_, _ = w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
-				return
+			// A batch that would exceed the client's max payload leaves
+			// the rest for the next frame
+			for message != nil {
+				start := time.Now()
+				next, err := c.writeBatch(message)
+				if err != nil {
+					c.handleWriteError(err)
+					return
+				}
+				c.recordWriteLatency(time.Since(start))
+				message = next
 			}
 
 		case <-ticker.C:
-			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout()))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.handleWriteError(err)
 				return
 			}
 		}
 	}
 }
 
+// writeBatch writes a message plus everything already queued as one frame.
+// It returns a queued message that did not fit within the client's max
+// payload, which starts the next frame.
+func (c *Client) writeBatch(message []byte) ([]byte, error) {
+	frameType, separator := c.framing()
+	w, err := c.conn.NextWriter(frameType)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = w.Write(message)
+
+	// Add queued messages to the current WebSocket message
+	limit := c.maxPayload()
+	size := len(message)
+	var next []byte
+	n := len(c.send)
+	for i := 0; i < n; i++ {
+		queued := <-c.send
+		if limit > 0 && size+len(separator)+len(queued) > limit {
+			next = queued
+			break
+		}
+		size += len(separator) + len(queued)
+		_, _ = w.Write(separator)
+		_, _ = w.Write(queued)
+	}
+
+	return next, w.Close()
+}
+
+// framing returns the frame type for the client's encoding and what goes
+// between batched messages. MessagePack values need no separator.
+func (c *Client) framing() (int, []byte) {
+	if c.encoding() == EncodingMsgpack {
+		return websocket.BinaryMessage, nil
+	}
+	return websocket.TextMessage, newline
+}
+
+func (c *Client) writeTimeout() time.Duration {
+	if c.hub != nil && c.hub.writeTimeout > 0 {
+		return c.hub.writeTimeout
+	}
+	return writeWait
+}
+
+func (c *Client) recordWriteLatency(d time.Duration) {
+	if c.latency != nil {
+		c.latency.Record(d)
+	}
+	if c.hub != nil && c.hub.writeLatency != nil {
+		c.hub.writeLatency.Record(d)
+	}
+	if c.hub != nil && c.hub.metrics != nil {
+		c.hub.metrics.writeDuration.Observe(d.Seconds())
+	}
+}
+
+// handleWriteError logs write deadline misses; the caller then drops the connection
+func (c *Client) handleWriteError(err error) {
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return
+	}
+
+	if c.hub != nil {
+		c.hub.writeTimeouts.Add(1)
+	}
+	c.logger.Warn("WebSocket write timed out, disconnecting client",
+		zap.String("user_id", c.userID),
+		zap.Duration("timeout", c.writeTimeout()),
+		zap.Int("queued", len(c.send)),
+	)
+}
+
 // handleMessage handles incoming messages based on type
 func (c *Client) handleMessage(msg *Message) {
 	switch msg.Type {
 	case MessageTypeJoinRoom:
 		c.handleJoinRoom(msg)
+	case MessageTypeJoinRooms:
+		c.handleJoinRooms(msg)
 	case MessageTypeLeaveRoom:
 		c.handleLeaveRoom(msg)
 	case MessageTypeSendMessage:
@@ -197,6 +421,12 @@ func (c *Client) handleMessage(msg *Message) {
 		c.handlePing(msg)
 	case MessageTypeMarkRead:
 		c.handleMarkRead(msg)
+	case MessageTypeRequestTicket:
+		c.handleRequestTicket(msg)
+	case MessageTypeResume:
+		c.handleResume(msg)
+	case MessageTypeRoomPresence:
+		c.handleRoomPresence(msg)
 	default:
 		c.sendError(400, "未知的訊息類型")
 	}
@@ -212,6 +442,44 @@ func (c *Client) handleJoinRoom(msg *Message) {
 	c.hub.JoinRoom(c, payload.RoomID)
 }
 
+func (c *Client) handleJoinRooms(msg *Message) {
+	var payload JoinRoomsPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		c.sendError(400, "無效的請求參數")
+		return
+	}
+	if len(payload.RoomIDs) == 0 || len(payload.RoomIDs) > maxJoinRoomsBatch {
+		c.sendError(400, fmt.Sprintf("一次可加入 1 至 %d 個聊天室", maxJoinRoomsBatch))
+		return
+	}
+
+	c.hub.JoinRooms(c, payload.RoomIDs, msg.RequestID)
+}
+
+func (c *Client) handleResume(msg *Message) {
+	var payload ResumePayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		c.sendError(400, "無效的請求參數")
+		return
+	}
+	if len(payload.Rooms) == 0 || len(payload.Rooms) > maxJoinRoomsBatch {
+		c.sendError(400, fmt.Sprintf("一次可續傳 1 至 %d 個聊天室", maxJoinRoomsBatch))
+		return
+	}
+
+	c.hub.Resume(c, payload.Rooms, msg.RequestID)
+}
+
+func (c *Client) handleRoomPresence(msg *Message) {
+	var payload RoomPresenceQueryPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		c.sendError(400, "無效的請求參數")
+		return
+	}
+
+	c.hub.RoomPresence(c, payload.RoomID, msg.RequestID)
+}
+
 func (c *Client) handleLeaveRoom(msg *Message) {
 	var payload LeaveRoomPayload
 	if err := msg.ParsePayload(&payload); err != nil {
@@ -274,27 +542,130 @@ func (c *Client) handleMarkRead(msg *Message) {
 	c.hub.MarkAsRead(c, payload)
 }
 
-// SendMessage sends a message to the client
+func (c *Client) handleRequestTicket(msg *Message) {
+	c.hub.IssueReconnectTicket(c, msg.RequestID)
+}
+
+// SendMessage sends a message to the client, unless the client did not
+// declare the event type
 func (c *Client) SendMessage(msg *Message) {
-	data, err := json.Marshal(msg)
+	if !c.Accepts(msg.Type) {
+		return
+	}
+
+	data, err := msg.Encode(c.encoding())
 	if err != nil {
-		c.logger.Error("Failed to marshal message",
+		c.logger.Error("Failed to encode message",
 			zap.String("user_id", c.userID),
 			zap.Error(err),
 		)
 		return
 	}
 
+	c.enqueue(data)
+}
+
+// Dropped returns the number of messages dropped for this client
+func (c *Client) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// enqueue adds data to the send buffer, applying the slow consumer policy when it is full
+func (c *Client) enqueue(data []byte) {
+	if limit := c.maxPayload(); limit > 0 && len(data) > limit {
+		c.recordOversized(len(data))
+		return
+	}
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.closed {
+		return
+	}
+
 	select {
 	case c.send <- data:
+		return
+	default:
+	}
+
+	// Buffer is full, the client is not reading fast enough
+	if c.slowConsumerPolicy() == SlowConsumerDisconnect {
+		c.recordDrop()
+		c.evict()
+		return
+	}
+
+	select {
+	case <-c.send:
+		c.recordDrop()
 	default:
-		// Channel is full, client is slow
-		c.logger.Warn("Client send buffer full",
+	}
+	select {
+	case c.send <- data:
+	default:
+		c.recordDrop()
+	}
+}
+
+func (c *Client) slowConsumerPolicy() SlowConsumerPolicy {
+	if c.hub != nil && c.hub.slowConsumerPolicy != "" {
+		return c.hub.slowConsumerPolicy
+	}
+	return SlowConsumerDropOldest
+}
+
+func (c *Client) recordDrop() {
+	n := c.dropped.Add(1)
+	if c.hub != nil {
+		c.hub.droppedMessages.Add(1)
+	}
+
+	if n == 1 || n%dropLogInterval == 0 {
+		c.logger.Warn("Client send buffer full, dropping messages",
 			zap.String("user_id", c.userID),
+			zap.Int64("dropped", n),
 		)
 	}
 }
 
+// recordOversized counts a message skipped for exceeding the client's max payload
+func (c *Client) recordOversized(size int) {
+	if c.hub != nil {
+		c.hub.oversizedMessages.Add(1)
+	}
+	c.logger.Debug("Skipping message larger than client max payload",
+		zap.String("user_id", c.userID),
+		zap.Int("size", size),
+		zap.Int("max_payload", c.maxPayload()),
+	)
+}
+
+// evict disconnects a slow consumer once; the hub closes the connection on unregister
+func (c *Client) evict() {
+	if !c.evicted.CompareAndSwap(false, true) {
+		return
+	}
+
+	c.logger.Warn("Evicting slow consumer",
+		zap.String("user_id", c.userID),
+		zap.String("username", c.username),
+		zap.Int("queued", len(c.send)),
+		zap.Int64("dropped", c.dropped.Load()),
+	)
+
+	if c.hub == nil {
+		return
+	}
+	c.hub.slowConsumerEvictions.Add(1)
+
+	// Unregister asynchronously: enqueue may be running on the hub goroutine
+	go func() {
+		c.hub.unregister <- c
+	}()
+}
+
 // sendError sends an error message to the client
 func (c *Client) sendError(code int, message string) {
 	errMsg, _ := NewErrorMessage(code, message)
@@ -303,5 +674,14 @@ func (c *Client) sendError(code int, message string) {
 
 // Close closes the client connection
 func (c *Client) Close() {
-	close(c.send)
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
 }
@@ -3,6 +3,7 @@ package ws
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -163,6 +164,92 @@ func TestClient_SendMessage_BufferFull(t *testing.T) {
 	}
 }
 
+func TestClient_SendMessage_DropOldest(t *testing.T) {
+	hub := createTestHub()
+	client := &Client{
+		hub:      hub,
+		send:     make(chan []byte, 2),
+		userID:   "user-123",
+		username: "alice",
+		rooms:    make(map[string]bool),
+		logger:   zap.NewNop(),
+	}
+
+	for _, id := range []string{"msg-1", "msg-2", "msg-3"} {
+		msg, _ := NewMessage(MessageTypeNewMessage, &NewMessagePayload{ID: id})
+		client.SendMessage(msg)
+	}
+
+	if client.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", client.Dropped())
+	}
+	if stats := hub.GetStats(); stats["dropped_messages"] != 1 {
+		t.Errorf("Expected dropped_messages 1, got %d", stats["dropped_messages"])
+	}
+
+	// The oldest message is discarded, newer ones are kept in order
+	for _, expected := range []string{"msg-2", "msg-3"} {
+		var received Message
+		_ = json.Unmarshal(<-client.send, &received)
+		var payload NewMessagePayload
+		_ = received.ParsePayload(&payload)
+		if payload.ID != expected {
+			t.Errorf("Expected %s, got %s", expected, payload.ID)
+		}
+	}
+}
+
+func TestClient_SendMessage_DisconnectSlowConsumer(t *testing.T) {
+	hub := createTestHub()
+	hub.SetSendQueue(1, SlowConsumerDisconnect)
+	client := NewClient(hub, nil, "user-123", "alice", zap.NewNop())
+
+	msg, _ := NewMessage(MessageTypeNewMessage, &NewMessagePayload{Content: "Test"})
+	client.SendMessage(msg)
+	client.SendMessage(msg)
+	client.SendMessage(msg)
+
+	select {
+	case evicted := <-hub.unregister:
+		if evicted != client {
+			t.Error("Expected the slow client to be unregistered")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected slow consumer to be evicted")
+	}
+
+	select {
+	case <-hub.unregister:
+		t.Error("Expected eviction to happen only once")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if stats := hub.GetStats(); stats["slow_consumer_evictions"] != 1 {
+		t.Errorf("Expected slow_consumer_evictions 1, got %d", stats["slow_consumer_evictions"])
+	}
+}
+
+func TestClient_SendAfterClose(t *testing.T) {
+	client := NewClient(nil, nil, "user-123", "alice", zap.NewNop())
+	client.Close()
+	client.Close()
+
+	msg, _ := NewMessage(MessageTypePong, nil)
+	client.SendMessage(msg) // must not panic on the closed channel
+}
+
+func TestParseSlowConsumerPolicy(t *testing.T) {
+	if p, err := ParseSlowConsumerPolicy(""); err != nil || p != SlowConsumerDropOldest {
+		t.Errorf("Expected drop_oldest default, got %q (%v)", p, err)
+	}
+	if p, err := ParseSlowConsumerPolicy("disconnect"); err != nil || p != SlowConsumerDisconnect {
+		t.Errorf("Expected disconnect, got %q (%v)", p, err)
+	}
+	if _, err := ParseSlowConsumerPolicy("block"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
 func TestClient_MultipleRooms(t *testing.T) {
 	client := createTestClient("user-123", "alice")
 
@@ -308,3 +395,82 @@ func TestClient_HandleMessage_Types(t *testing.T) {
 		}()
 	}
 }
+
+func TestClient_HandleJoinRooms_RejectsBadBatch(t *testing.T) {
+	tooMany := make([]string, maxJoinRoomsBatch+1)
+	for i := range tooMany {
+		tooMany[i] = "00000000-0000-0000-0000-000000000000"
+	}
+	payload, _ := json.Marshal(&JoinRoomsPayload{RoomIDs: tooMany})
+
+	for name, raw := range map[string]json.RawMessage{
+		"empty":    json.RawMessage(`{"room_ids":[]}`),
+		"too many": payload,
+		"invalid":  json.RawMessage(`{"room_ids":"room-1"}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// No hub: the batch must be rejected before it is needed
+			client := createTestClient("user-123", "alice")
+			client.handleMessage(&Message{Type: MessageTypeJoinRooms, Payload: raw})
+
+			select {
+			case data := <-client.send:
+				var received Message
+				var errPayload ErrorPayload
+				if err := json.Unmarshal(data, &received); err != nil {
+					t.Fatalf("Failed to unmarshal received message: %v", err)
+				}
+				_ = received.ParsePayload(&errPayload)
+				if received.Type != MessageTypeError || errPayload.Code != 400 {
+					t.Errorf("Expected a 400 error, got %s %+v", received.Type, errPayload)
+				}
+			default:
+				t.Error("Expected an error in send channel")
+			}
+		})
+	}
+}
+
+func TestClient_HandleResume_RejectsBadBatch(t *testing.T) {
+	for name, raw := range map[string]json.RawMessage{
+		"empty":   json.RawMessage(`{"rooms":[]}`),
+		"invalid": json.RawMessage(`{"rooms":"room-1"}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// No hub: the batch must be rejected before it is needed
+			client := createTestClient("user-123", "alice")
+			client.handleMessage(&Message{Type: MessageTypeResume, Payload: raw})
+
+			var received Message
+			var errPayload ErrorPayload
+			_ = json.Unmarshal(<-client.send, &received)
+			_ = received.ParsePayload(&errPayload)
+			if received.Type != MessageTypeError || errPayload.Code != 400 {
+				t.Errorf("Expected a 400 error, got %s %+v", received.Type, errPayload)
+			}
+		})
+	}
+}
+
+func TestClient_CloseCancelsContext(t *testing.T) {
+	client := NewClient(nil, nil, "user-123", "alice", zap.NewNop())
+
+	ctx := client.Context()
+	if ctx.Err() != nil {
+		t.Fatal("Expected context to be active before close")
+	}
+
+	client.Close()
+
+	if ctx.Err() == nil {
+		t.Error("Expected context to be canceled after close")
+	}
+}
+
+func TestClient_ContextWithoutCancel(t *testing.T) {
+	client := createTestClient("user-123", "alice")
+
+	if client.Context() == nil {
+		t.Error("Expected non-nil context")
+	}
+}
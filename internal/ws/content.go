@@ -0,0 +1,84 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultContentRefTTL is how long an uploaded message body can be
+	// referenced before it is discarded
+	DefaultContentRefTTL = 5 * time.Minute
+
+	contentKeyPrefix = "ws:content:"
+	contentRefBytes  = 16
+)
+
+// ErrInvalidContentRef is returned for unknown, expired or already used
+// content references
+var ErrInvalidContentRef = errors.New("invalid content reference")
+
+// ContentStore holds message bodies too large for a WebSocket frame. The
+// client uploads the body over REST and sends a frame that references it,
+// so the realtime path only ever reads small frames. Bodies live in Redis
+// so any instance can resolve a reference issued by another.
+type ContentStore struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// NewContentStore creates a content store; a non-positive ttl uses
+// DefaultContentRefTTL
+func NewContentStore(redisClient *redis.Client, ttl time.Duration) *ContentStore {
+	if ttl <= 0 {
+		ttl = DefaultContentRefTTL
+	}
+	return &ContentStore{
+		redis: redisClient,
+		ttl:   ttl,
+	}
+}
+
+// Put stores a body for the user and returns the reference to send instead
+func (s *ContentStore) Put(ctx context.Context, userID, content string) (string, time.Time, error) {
+	raw := make([]byte, contentRefBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate content reference: %w", err)
+	}
+	ref := hex.EncodeToString(raw)
+
+	expiresAt := time.Now().Add(s.ttl)
+	if err := s.redis.Set(ctx, contentKey(userID, ref), content, s.ttl).Err(); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to store content: %w", err)
+	}
+
+	return ref, expiresAt, nil
+}
+
+// Take returns and removes a body the user uploaded. A reference can be
+// used at most once, and only by the user who uploaded it.
+func (s *ContentStore) Take(ctx context.Context, userID, ref string) (string, error) {
+	if len(ref) != hex.EncodedLen(contentRefBytes) {
+		return "", ErrInvalidContentRef
+	}
+
+	content, err := s.redis.GetDel(ctx, contentKey(userID, ref)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrInvalidContentRef
+		}
+		return "", fmt.Errorf("failed to take content: %w", err)
+	}
+	return content, nil
+}
+
+// contentKey scopes references to their uploader
+func contentKey(userID, ref string) string {
+	return contentKeyPrefix + userID + ":" + ref
+}
@@ -0,0 +1,119 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func setupTestContentStore(t *testing.T) *ContentStore {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 15})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		t.Skipf("Skipping test, could not connect to test redis: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewContentStore(client, time.Minute)
+}
+
+func TestContentStore_SingleUse(t *testing.T) {
+	store := setupTestContentStore(t)
+	ctx := context.Background()
+	body := strings.Repeat("長", 5000)
+
+	ref, expiresAt, err := store.Put(ctx, "user-1", body)
+	if err != nil {
+		t.Fatalf("Failed to store content: %v", err)
+	}
+	if time.Until(expiresAt) <= 0 {
+		t.Error("Expected content to expire in the future")
+	}
+
+	if _, err := store.Take(ctx, "user-2", ref); err != ErrInvalidContentRef {
+		t.Errorf("Expected ErrInvalidContentRef for another user, got %v", err)
+	}
+
+	content, err := store.Take(ctx, "user-1", ref)
+	if err != nil {
+		t.Fatalf("Failed to take content: %v", err)
+	}
+	if content != body {
+		t.Error("Expected the uploaded body back")
+	}
+
+	if _, err := store.Take(ctx, "user-1", ref); err != ErrInvalidContentRef {
+		t.Errorf("Expected ErrInvalidContentRef on second use, got %v", err)
+	}
+}
+
+func TestContentStore_MalformedRef(t *testing.T) {
+	store := NewContentStore(nil, 0)
+
+	if store.ttl != DefaultContentRefTTL {
+		t.Errorf("Expected default TTL, got %v", store.ttl)
+	}
+	// Rejected before Redis is consulted
+	if _, err := store.Take(context.Background(), "user-1", "short"); err != ErrInvalidContentRef {
+		t.Errorf("Expected ErrInvalidContentRef, got %v", err)
+	}
+}
+
+func TestHub_ResolveContent(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-1", "alice")
+	ctx := context.Background()
+
+	content, ok := hub.resolveContent(ctx, client, "hello", "")
+	if !ok || content != "hello" {
+		t.Errorf("Expected inline content to pass through, got %q, %v", content, ok)
+	}
+
+	// Without a content store references are refused
+	if _, ok := hub.resolveContent(ctx, client, "", strings.Repeat("a", 32)); ok {
+		t.Error("Expected reference to be refused without a content store")
+	}
+
+	// Content and a reference together are ambiguous
+	hub.SetContents(NewContentStore(nil, 0))
+	if _, ok := hub.resolveContent(ctx, client, "hello", strings.Repeat("a", 32)); ok {
+		t.Error("Expected content with a reference to be refused")
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case data := <-client.send:
+			var msg Message
+			if err := json.Unmarshal(data, &msg); err != nil || msg.Type != MessageTypeError {
+				t.Errorf("Expected error frame, got %s", data)
+			}
+		default:
+			t.Fatal("Expected an error frame")
+		}
+	}
+}
+
+func TestHub_SetMaxMessageSize(t *testing.T) {
+	hub := createTestHub()
+	if hub.readLimit() != defaultMaxMessageSize {
+		t.Errorf("Expected default read limit, got %d", hub.readLimit())
+	}
+
+	hub.SetMaxMessageSize(64 << 10)
+	if hub.readLimit() != 64<<10 {
+		t.Errorf("Expected configured read limit, got %d", hub.readLimit())
+	}
+
+	hub.SetMaxMessageSize(100)
+	if hub.readLimit() != minClientPayload {
+		t.Errorf("Expected read limit raised to %d, got %d", minClientPayload, hub.readLimit())
+	}
+}
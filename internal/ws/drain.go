@@ -0,0 +1,134 @@
+package ws
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReconnectReasonDraining tells clients the instance is going away
+const ReconnectReasonDraining = "draining"
+
+// Drain stops the hub from accepting new connections and asks existing
+// clients to reconnect, spreading the requests randomly over window so the
+// rest of the fleet absorbs them gradually. Calling Drain while draining is
+// a no-op.
+func (h *Hub) Drain(window time.Duration) {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+
+	if h.draining.Load() {
+		return
+	}
+	h.draining.Store(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.drainCancel = cancel
+
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	h.logger.Warn("Draining hub",
+		zap.Int("connections", len(clients)),
+		zap.Duration("window", window),
+	)
+
+	go h.migrateClients(ctx, clients, window)
+}
+
+// Undrain accepts connections again and stops asking clients to reconnect
+func (h *Hub) Undrain() {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+
+	if !h.draining.Load() {
+		return
+	}
+	h.draining.Store(false)
+	if h.drainCancel != nil {
+		h.drainCancel()
+		h.drainCancel = nil
+	}
+
+	h.logger.Info("Hub drain canceled")
+}
+
+// Draining reports whether the hub is refusing new connections
+func (h *Hub) Draining() bool {
+	return h.draining.Load()
+}
+
+type scheduledReconnect struct {
+	client *Client
+	at     time.Duration
+}
+
+func (h *Hub) migrateClients(ctx context.Context, clients []*Client, window time.Duration) {
+	schedule := make([]scheduledReconnect, len(clients))
+	for i, client := range clients {
+		var at time.Duration
+		if window > 0 {
+			at = time.Duration(rand.Int63n(int64(window)))
+		}
+		schedule[i] = scheduledReconnect{client: client, at: at}
+	}
+	sort.Slice(schedule, func(i, j int) bool { return schedule[i].at < schedule[j].at })
+
+	start := time.Now()
+	for _, s := range schedule {
+		if wait := s.at - time.Since(start); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+
+		h.requestReconnect(s.client)
+	}
+
+	h.logger.Info("Hub drain complete", zap.Int("migrated", len(schedule)))
+}
+
+// requestReconnect tells a client to reconnect, handing it a reconnect
+// ticket when available, then closes its connection
+func (h *Hub) requestReconnect(client *Client) {
+	h.mu.RLock()
+	_, connected := h.clients[client]
+	h.mu.RUnlock()
+	if !connected {
+		return
+	}
+
+	payload := &ReconnectPayload{Reason: ReconnectReasonDraining}
+	if h.tickets != nil {
+		ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
+		ticket, expiresAt, err := h.issueTicket(ctx, client)
+		cancel()
+		if err != nil {
+			h.logger.Warn("Failed to issue reconnect ticket for drain",
+				zap.String("user_id", client.userID),
+				zap.Error(err),
+			)
+		} else {
+			payload.Ticket = ticket
+			payload.ExpiresAt = expiresAt.Format(time.RFC3339)
+		}
+	}
+
+	msg, _ := NewMessage(MessageTypeReconnect, payload)
+	// Queued before the send channel is closed, so it is written ahead of the close frame
+	client.SendMessage(msg)
+	h.unregister <- client
+}
@@ -0,0 +1,98 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestHub_DrainAsksClientsToReconnect(t *testing.T) {
+	hub := createTestHub()
+
+	clients := []*Client{
+		createMockClient("user-1", "alice"),
+		createMockClient("user-2", "bob"),
+	}
+	for _, c := range clients {
+		c.hub = hub
+		hub.clients[c] = true
+	}
+
+	hub.Drain(0)
+	if !hub.Draining() {
+		t.Fatal("Expected hub to be draining")
+	}
+
+	unregistered := map[*Client]bool{}
+	for range clients {
+		select {
+		case c := <-hub.unregister:
+			unregistered[c] = true
+		case <-time.After(time.Second):
+			t.Fatal("Expected every client to be migrated")
+		}
+	}
+
+	for _, c := range clients {
+		if !unregistered[c] {
+			t.Errorf("Expected %s to be unregistered", c.userID)
+		}
+		var msg Message
+		_ = json.Unmarshal(<-c.send, &msg)
+		if msg.Type != MessageTypeReconnect {
+			t.Errorf("Expected reconnect message, got %s", msg.Type)
+		}
+		var payload ReconnectPayload
+		if err := msg.ParsePayload(&payload); err != nil {
+			t.Fatalf("Failed to parse payload: %v", err)
+		}
+		if payload.Reason != ReconnectReasonDraining {
+			t.Errorf("Expected reason %q, got %q", ReconnectReasonDraining, payload.Reason)
+		}
+	}
+}
+
+func TestHub_UndrainStopsMigration(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+	hub.clients[client] = true
+
+	hub.Drain(time.Hour)
+	hub.Undrain()
+
+	if hub.Draining() {
+		t.Error("Expected hub to accept connections again")
+	}
+	select {
+	case <-hub.unregister:
+		t.Error("Expected no client to be migrated after undrain")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHandler_ServeWSRejectsWhileDraining(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := createTestHub()
+	hub.Drain(0)
+
+	handler := NewHandler(hub, nil, zap.NewNop())
+	router := gin.New()
+	router.GET("/ws", handler.ServeWS)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ws?ticket=abc", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+}
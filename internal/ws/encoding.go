@@ -0,0 +1,141 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Scratch buffers larger than this are not returned to the pool, so one
+// unusually large event does not pin its buffer forever
+const maxPooledBufferSize = 64 << 10
+
+// encodeBufferPool holds scratch buffers for building outbound frames
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// newline separates messages batched into one frame
+var newline = []byte{'\n'}
+
+// encoders turn a message into a frame, one per supported encoding
+var encoders = map[string]func(*Message) ([]byte, error){
+	EncodingJSON:    encodeJSON,
+	EncodingMsgpack: encodeMsgpack,
+}
+
+// encodedFrames caches a message's frames by encoding. JSON, which nearly
+// every client uses, skips the map.
+type encodedFrames struct {
+	mu     sync.Mutex
+	json   []byte
+	frames map[string][]byte
+}
+
+func (f *encodedFrames) get(encoding string) ([]byte, bool) {
+	if encoding == EncodingJSON {
+		return f.json, f.json != nil
+	}
+	data, ok := f.frames[encoding]
+	return data, ok
+}
+
+func (f *encodedFrames) put(encoding string, data []byte) {
+	if encoding == EncodingJSON {
+		f.json = data
+		return
+	}
+	if f.frames == nil {
+		f.frames = make(map[string][]byte, 1)
+	}
+	f.frames[encoding] = data
+}
+
+// Encode returns the message encoded for the given encoding. Messages built
+// by NewMessage encode once per encoding and share the result between every
+// recipient, so they must not be modified after they are first sent.
+func (m *Message) Encode(encoding string) ([]byte, error) {
+	if m.encoded == nil {
+		return encodeWith(m, encoding)
+	}
+
+	m.encoded.mu.Lock()
+	defer m.encoded.mu.Unlock()
+
+	if data, ok := m.encoded.get(encoding); ok {
+		return data, nil
+	}
+	data, err := encodeWith(m, encoding)
+	if err != nil {
+		return nil, err
+	}
+	m.encoded.put(encoding, data)
+	return data, nil
+}
+
+func encodeWith(m *Message, encoding string) ([]byte, error) {
+	encode, ok := encoders[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	return encode(m)
+}
+
+// encodeJSON writes the same bytes as json.Marshal(m) without reflecting
+// over the envelope. The payload is copied verbatim rather than compacted
+// and HTML-escaped again, since it already came out of json.Marshal in
+// NewMessage or off the wire from another instance.
+func encodeJSON(m *Message) ([]byte, error) {
+	year := m.Timestamp.Year()
+	if year < 0 || year >= 10000 {
+		return nil, fmt.Errorf("timestamp year %d outside of range [0,9999]", year)
+	}
+
+	bp := encodeBufferPool.Get().(*[]byte)
+	b := (*bp)[:0]
+
+	b = append(b, `{"type":`...)
+	b = appendJSONString(b, string(m.Type))
+	if len(m.Payload) > 0 {
+		b = append(b, `,"payload":`...)
+		b = append(b, m.Payload...)
+	}
+	b = append(b, `,"timestamp":"`...)
+	b = m.Timestamp.AppendFormat(b, time.RFC3339Nano)
+	b = append(b, '"')
+	if m.RequestID != "" {
+		b = append(b, `,"request_id":`...)
+		b = appendJSONString(b, m.RequestID)
+	}
+	b = append(b, '}')
+
+	// The frame outlives the scratch buffer, so copy it out at its exact size
+	data := make([]byte, len(b))
+	copy(data, b)
+
+	if cap(b) <= maxPooledBufferSize {
+		*bp = b
+		encodeBufferPool.Put(bp)
+	}
+	return data, nil
+}
+
+// appendJSONString appends s as a JSON string. Plain ASCII is copied as is;
+// anything json.Marshal would escape goes through json.Marshal so the
+// output stays byte for byte identical.
+func appendJSONString(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			quoted, _ := json.Marshal(s)
+			return append(b, quoted...)
+		}
+	}
+	b = append(b, '"')
+	b = append(b, s...)
+	return append(b, '"')
+}
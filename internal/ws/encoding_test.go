@@ -0,0 +1,301 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+func TestMessage_EncodeMatchesMarshal(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.FixedZone("CST", 8*3600))
+	payload, _ := json.Marshal(map[string]interface{}{"content": "<b>hi</b>", "n": 1})
+
+	tests := []struct {
+		name string
+		msg  *Message
+	}{
+		{"payload", &Message{Type: MessageTypeNewMessage, Payload: payload, Timestamp: ts}},
+		{"no payload", &Message{Type: MessageTypePong, Timestamp: ts}},
+		{"request id", &Message{Type: MessageTypeAck, Payload: payload, Timestamp: ts, RequestID: "req-42"}},
+		{"escaped request id", &Message{Type: MessageTypeAck, Timestamp: ts, RequestID: "a\"b\\c<d>&\n é"}},
+		{"utc", &Message{Type: MessageTypeUserTyping, Payload: payload, Timestamp: ts.UTC()}},
+		{"zero time", &Message{Type: MessageTypeError}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json.Marshal(tt.msg)
+			if err != nil {
+				t.Fatalf("json.Marshal: %v", err)
+			}
+			got, err := tt.msg.Encode(EncodingJSON)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("Encode mismatch\n got: %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestMessage_EncodeCachesFrame(t *testing.T) {
+	msg, err := NewMessage(MessageTypeNewMessage, &NewMessagePayload{Content: "hello"})
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+
+	first, err := msg.Encode(EncodingJSON)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	second, _ := msg.Encode(EncodingJSON)
+	if &first[0] != &second[0] {
+		t.Error("Expected the encoded frame to be shared")
+	}
+
+	if _, err := msg.Encode("cbor"); err == nil {
+		t.Error("Expected unsupported encoding to fail")
+	}
+
+	// Messages read from a peer carry no cache but still encode
+	var peer Message
+	if err := json.Unmarshal(first, &peer); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	again, err := peer.Encode(EncodingJSON)
+	if err != nil || string(again) != string(first) {
+		t.Errorf("Expected round trip to encode identically, got %s, %v", again, err)
+	}
+}
+
+func TestMessage_EncodeMsgpack(t *testing.T) {
+	msg, err := NewMessage(MessageTypeNewMessage, map[string]interface{}{"content": "hello", "count": 3, "ratio": 0.5})
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+	msg.RequestID = "req-1"
+
+	data, err := msg.Encode(EncodingMsgpack)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	again, _ := msg.Encode(EncodingMsgpack)
+	if &data[0] != &again[0] {
+		t.Error("Expected the encoded frame to be shared")
+	}
+
+	var frame map[string]interface{}
+	if err := codec.NewDecoderBytes(data, msgpackHandle).Decode(&frame); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	payload, _ := frame["payload"].(map[string]interface{})
+	if frame["type"] != "new_message" || frame["request_id"] != "req-1" || payload["content"] != "hello" {
+		t.Errorf("Unexpected frame %v", frame)
+	}
+	if _, ok := payload["count"].(float64); ok {
+		t.Errorf("Expected whole numbers encoded as integers, got %T", payload["count"])
+	}
+
+	var decoded Message
+	if err := decodeMsgpack(data, &decoded); err != nil {
+		t.Fatalf("decodeMsgpack: %v", err)
+	}
+	var got, want map[string]interface{}
+	_ = json.Unmarshal(decoded.Payload, &got)
+	_ = json.Unmarshal(msg.Payload, &want)
+	if decoded.Type != msg.Type || decoded.RequestID != "req-1" || !decoded.Timestamp.Equal(msg.Timestamp) || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected a round trip, got %+v with payload %s", decoded, decoded.Payload)
+	}
+}
+
+func TestClient_WriteBatchMsgpack(t *testing.T) {
+	// A compressed connection, as permessage-deflate negotiates it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		up := websocket.Upgrader{EnableCompression: true}
+		conn, err := up.Upgrade(w, r, http.Header{"Sec-Websocket-Protocol": {EncodingMsgpack}})
+		if err != nil {
+			return
+		}
+		client := createMockClient("user-1", "alice")
+		client.conn = conn
+		client.caps = &Capabilities{Negotiated: true, Encoding: EncodingMsgpack}
+
+		for _, content := range []string{"one", "two"} {
+			msg, _ := NewMessage(MessageTypeNewMessage, map[string]string{"content": content})
+			client.SendMessage(msg)
+		}
+		if _, err := client.writeBatch(<-client.send); err != nil {
+			t.Errorf("writeBatch: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	dialer := websocket.Dialer{EnableCompression: true, Subprotocols: []string{"msgpack", "json"}}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != EncodingMsgpack || !strings.Contains(resp.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate") {
+		t.Errorf("Expected msgpack and compression negotiated, got %q, %v", conn.Subprotocol(), resp.Header)
+	}
+
+	frameType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if frameType != websocket.BinaryMessage {
+		t.Errorf("Expected a binary frame, got %d", frameType)
+	}
+
+	// Batched messages follow one another without a separator
+	dec := codec.NewDecoderBytes(data, msgpackHandle)
+	var contents []string
+	for i := 0; i < 2; i++ {
+		var frame msgpackFrame
+		if err := dec.Decode(&frame); err != nil {
+			t.Fatalf("Decode message %d: %v", i, err)
+		}
+		payload, _ := frame.Payload.(map[string]interface{})
+		contents = append(contents, fmt.Sprint(payload["content"]))
+	}
+	if strings.Join(contents, ",") != "one,two" {
+		t.Errorf("Expected both messages in one frame, got %v", contents)
+	}
+}
+
+func TestMessage_EncodeRejectsOutOfRangeTimestamp(t *testing.T) {
+	msg := &Message{Type: MessageTypePong, Timestamp: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if _, err := msg.Encode(EncodingJSON); err == nil {
+		t.Error("Expected out of range timestamp to fail like json.Marshal")
+	}
+}
+
+func TestPartitionByEncoding(t *testing.T) {
+	plain := createMockClient("user-1", "alice")
+	other := createMockClient("user-2", "bob")
+	other.SetCapabilities(&Capabilities{Encoding: "other"})
+	declared := createMockClient("user-3", "carol")
+	declared.SetCapabilities(&Capabilities{Negotiated: true, Encoding: EncodingJSON})
+
+	clients := []*Client{other, plain, declared}
+	if n := partitionByEncoding(clients, EncodingJSON); n != 2 {
+		t.Fatalf("Expected 2 JSON clients, got %d", n)
+	}
+	if clients[2] != other {
+		t.Error("Expected the other encoding to be moved to the back")
+	}
+}
+
+const benchmarkRoomSize = 100
+
+func newBenchmarkPayload() *NewMessagePayload {
+	return &NewMessagePayload{
+		ID:          "6f1c9a52-1b1e-4a55-9d8e-2b51f3f1a0d4",
+		RoomID:      "0c7d2f3e-8a6b-4c1d-9e2f-3a4b5c6d7e8f",
+		UserID:      "9a8b7c6d-5e4f-3a2b-1c0d-e9f8a7b6c5d4",
+		Username:    "alice",
+		DisplayName: "Alice",
+		Content:     "The quick brown fox jumps over the lazy dog",
+		Type:        "text",
+		CreatedAt:   "2024-03-01T12:30:45Z",
+	}
+}
+
+// BenchmarkRoomEventEncoding compares encoding one event for a room of
+// recipients: json.Marshal per recipient and once per event (before) against
+// the cached frame (after)
+func BenchmarkRoomEventEncoding(b *testing.B) {
+	payload := newBenchmarkPayload()
+
+	b.Run("marshal_per_recipient", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			msg, _ := NewMessage(MessageTypeNewMessage, payload)
+			for j := 0; j < benchmarkRoomSize; j++ {
+				if _, err := json.Marshal(msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("marshal_once", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			msg, _ := NewMessage(MessageTypeNewMessage, payload)
+			if _, err := json.Marshal(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("encode_cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			msg, _ := NewMessage(MessageTypeNewMessage, payload)
+			for j := 0; j < benchmarkRoomSize; j++ {
+				if _, err := msg.Encode(EncodingJSON); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkMessageEnvelope measures encoding the envelope around an already
+// encoded payload
+func BenchmarkMessageEnvelope(b *testing.B) {
+	msg, _ := NewMessage(MessageTypeNewMessage, newBenchmarkPayload())
+	msg.RequestID = "req-42"
+
+	b.Run("json_marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("encode_json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := encodeJSON(msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkHub_BroadcastToRoom(b *testing.B) {
+	for _, size := range []int{10, benchmarkRoomSize, 1000} {
+		b.Run(fmt.Sprintf("clients=%d", size), func(b *testing.B) {
+			hub := createTestHub()
+			clients := make(map[*Client]bool, size)
+			for i := 0; i < size; i++ {
+				clients[createMockClient(fmt.Sprintf("user-%d", i), "user")] = true
+			}
+			hub.rooms["room-1"] = clients
+			payload := newBenchmarkPayload()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				msg, _ := NewMessage(MessageTypeNewMessage, payload)
+				hub.broadcastToRoom(&BroadcastMessage{RoomID: "room-1", Message: msg})
+				for client := range clients {
+					<-client.send
+				}
+			}
+		})
+	}
+}
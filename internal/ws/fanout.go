@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Clients handed to a single fan-out job
+const fanoutChunkSize = 64
+
+// fanoutJob delivers one encoded message to a batch of clients
+type fanoutJob struct {
+	clients []*Client
+	data    []byte
+}
+
+// fanoutPool delivers broadcasts from a fixed set of workers so the hub
+// goroutine never iterates large rooms itself
+type fanoutPool struct {
+	workers int
+	jobs    chan fanoutJob
+	once    sync.Once
+}
+
+func newFanoutPool(workers int) *fanoutPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &fanoutPool{
+		workers: workers,
+		jobs:    make(chan fanoutJob, workers*16),
+	}
+}
+
+// start launches the workers; calling it more than once has no effect
+func (p *fanoutPool) start() {
+	p.once.Do(func() {
+		for i := 0; i < p.workers; i++ {
+			go p.work()
+		}
+	})
+}
+
+func (p *fanoutPool) work() {
+	for job := range p.jobs {
+		for _, client := range job.clients {
+			client.enqueue(job.data)
+		}
+	}
+}
+
+// submit splits clients into chunks and queues them for delivery
+func (p *fanoutPool) submit(clients []*Client, data []byte) {
+	for len(clients) > 0 {
+		n := fanoutChunkSize
+		if len(clients) < n {
+			n = len(clients)
+		}
+		p.jobs <- fanoutJob{clients: clients[:n], data: data}
+		clients = clients[n:]
+	}
+}
@@ -0,0 +1,84 @@
+package ws
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHub_BroadcastToRoom_FanoutPool(t *testing.T) {
+	hub := createTestHub()
+	hub.SetFanoutWorkers(4)
+	hub.fanout.start()
+
+	sender := createMockClient("user-0", "sender")
+	sender.hub = hub
+
+	roomID := "room-1"
+	hub.rooms[roomID] = map[*Client]bool{sender: true}
+
+	// Enough clients to span several fan-out chunks
+	var clients []*Client
+	for i := 0; i < fanoutChunkSize*3+1; i++ {
+		client := createMockClient("user-x", "bob")
+		client.hub = hub
+		hub.rooms[roomID][client] = true
+		clients = append(clients, client)
+	}
+
+	msg, _ := NewMessage(MessageTypeNewMessage, &NewMessagePayload{RoomID: roomID, Content: "Hello!"})
+	hub.broadcastToRoom(&BroadcastMessage{RoomID: roomID, Message: msg, Sender: sender})
+
+	for i, client := range clients {
+		select {
+		case <-client.send:
+		case <-time.After(time.Second):
+			t.Fatalf("Client %d did not receive broadcast", i)
+		}
+	}
+
+	select {
+	case <-sender.send:
+		t.Error("Sender should not receive its own broadcast")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClient_HandleWriteError_CountsTimeouts(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+
+	client.handleWriteError(errors.New("broken pipe"))
+	client.handleWriteError(timeoutError{})
+
+	if stats := hub.GetStats(); stats["write_timeouts"] != 1 {
+		t.Errorf("Expected write_timeouts 1, got %d", stats["write_timeouts"])
+	}
+}
+
+func TestHub_GetStats_WriteLatency(t *testing.T) {
+	hub := createTestHub()
+	hub.writeLatency = newLatencyWindow(hubLatencySamples)
+
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+	client.latency = newLatencyWindow(clientLatencySamples)
+	hub.clients[client] = true
+
+	client.recordWriteLatency(2 * time.Millisecond)
+
+	stats := hub.GetStats()
+	if stats["write_latency_p99_us"] != 2000 {
+		t.Errorf("Expected write_latency_p99_us 2000, got %d", stats["write_latency_p99_us"])
+	}
+	if stats["slowest_client_write_p99_us"] != 2000 {
+		t.Errorf("Expected slowest_client_write_p99_us 2000, got %d", stats["slowest_client_write_p99_us"])
+	}
+}
@@ -0,0 +1,182 @@
+package ws
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons a message is refused by flood control
+const (
+	FloodReasonRate      = "rate"      // too many messages per second
+	FloodReasonDuplicate = "duplicate" // the same text repeated too often
+	FloodReasonMuted     = "muted"     // the sender is muted for flooding
+)
+
+// Strikes older than this are forgotten, so an occasional burst never adds
+// up to a mute
+const floodStrikeDecay = time.Minute
+
+// How often state of users who went quiet is dropped
+const floodSweepInterval = time.Minute
+
+// FloodPolicy configures flood control for messages sent over WebSocket.
+// A zero limit disables that check.
+type FloodPolicy struct {
+	MaxPerSecond    int           // messages a user may send per second
+	DuplicateLimit  int           // identical text messages allowed within DuplicateWindow
+	DuplicateWindow time.Duration // how long sent text counts as a duplicate
+	Warnings        int           // refused messages warned about before the sender is muted
+	MuteDuration    time.Duration // how long a muted sender's messages are refused; 0 never mutes
+}
+
+// floodVerdict explains why a message was refused
+type floodVerdict struct {
+	Reason       string
+	WarningsLeft int       // warnings before the sender is muted
+	MutedUntil   time.Time // set when the sender is muted
+}
+
+// floodControl tracks what each user recently sent. All of a user's
+// connections on this instance share one budget, so opening more
+// connections does not raise it.
+type floodControl struct {
+	policy FloodPolicy
+	now    func() time.Time
+
+	mu        sync.Mutex
+	users     map[string]*floodState
+	lastSweep time.Time
+}
+
+type floodState struct {
+	sent       []time.Time  // sends within the last second
+	texts      []floodEntry // text sent within the duplicate window
+	strikes    int
+	lastStrike time.Time
+	mutedUntil time.Time
+}
+
+type floodEntry struct {
+	sum uint64
+	at  time.Time
+}
+
+// newFloodControl returns nil when the policy checks nothing
+func newFloodControl(policy FloodPolicy) *floodControl {
+	if policy.MaxPerSecond <= 0 && (policy.DuplicateLimit <= 0 || policy.DuplicateWindow <= 0) {
+		return nil
+	}
+	return &floodControl{
+		policy: policy,
+		now:    time.Now,
+		users:  make(map[string]*floodState),
+	}
+}
+
+// Check records a message the user is about to send and returns nil if it
+// may go out. text is the message text, or empty for images, files and
+// stickers, which are only rate limited. A refused message counts as a
+// strike; once the strikes exceed the policy's warnings the user is muted.
+func (f *floodControl) Check(userID, text string) *floodVerdict {
+	if f == nil {
+		return nil
+	}
+	now := f.now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sweep(now)
+
+	st := f.users[userID]
+	if st == nil {
+		st = &floodState{}
+		f.users[userID] = st
+	}
+	if now.Before(st.mutedUntil) {
+		return &floodVerdict{Reason: FloodReasonMuted, MutedUntil: st.mutedUntil}
+	}
+	st.prune(now, f.policy.DuplicateWindow)
+
+	var sum uint64
+	if text = strings.ToLower(strings.TrimSpace(text)); text != "" {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(text))
+		sum = h.Sum64()
+	}
+
+	reason := ""
+	switch {
+	case f.policy.MaxPerSecond > 0 && len(st.sent) >= f.policy.MaxPerSecond:
+		reason = FloodReasonRate
+	case text != "" && f.policy.DuplicateLimit > 0 && st.count(sum) >= f.policy.DuplicateLimit:
+		reason = FloodReasonDuplicate
+	}
+	if reason == "" {
+		st.sent = append(st.sent, now)
+		if text != "" && f.policy.DuplicateLimit > 0 {
+			st.texts = append(st.texts, floodEntry{sum: sum, at: now})
+		}
+		return nil
+	}
+
+	if now.Sub(st.lastStrike) > floodStrikeDecay {
+		st.strikes = 0
+	}
+	st.strikes++
+	st.lastStrike = now
+
+	verdict := &floodVerdict{Reason: reason, WarningsLeft: f.policy.Warnings - st.strikes}
+	if verdict.WarningsLeft < 0 {
+		verdict.WarningsLeft = 0
+		if f.policy.MuteDuration > 0 {
+			st.strikes = 0
+			st.mutedUntil = now.Add(f.policy.MuteDuration)
+			verdict.MutedUntil = st.mutedUntil
+		}
+	}
+	return verdict
+}
+
+// prune forgets sends older than a second and text older than window
+func (st *floodState) prune(now time.Time, window time.Duration) {
+	i := 0
+	for i < len(st.sent) && now.Sub(st.sent[i]) >= time.Second {
+		i++
+	}
+	st.sent = st.sent[i:]
+
+	i = 0
+	for i < len(st.texts) && now.Sub(st.texts[i].at) >= window {
+		i++
+	}
+	st.texts = st.texts[i:]
+}
+
+// count returns how often text with the given hash was sent recently
+func (st *floodState) count(sum uint64) int {
+	n := 0
+	for _, e := range st.texts {
+		if e.sum == sum {
+			n++
+		}
+	}
+	return n
+}
+
+// sweep drops users with nothing left to remember. It must be called with
+// f.mu held.
+func (f *floodControl) sweep(now time.Time) {
+	if now.Sub(f.lastSweep) < floodSweepInterval {
+		return
+	}
+	f.lastSweep = now
+
+	for userID, st := range f.users {
+		st.prune(now, f.policy.DuplicateWindow)
+		if len(st.sent) == 0 && len(st.texts) == 0 && !now.Before(st.mutedUntil) && now.Sub(st.lastStrike) > floodStrikeDecay {
+			delete(f.users, userID)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestFloodControl(policy FloodPolicy) (*floodControl, *time.Time) {
+	f := newFloodControl(policy)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	return f, &now
+}
+
+func TestNewFloodControl_Disabled(t *testing.T) {
+	if f := newFloodControl(FloodPolicy{}); f != nil {
+		t.Error("Expected nil flood control for an empty policy")
+	}
+	var f *floodControl
+	if v := f.Check("user-1", "hi"); v != nil {
+		t.Errorf("Expected nil flood control to allow everything, got %+v", v)
+	}
+}
+
+func TestFloodControl_Rate(t *testing.T) {
+	f, now := newTestFloodControl(FloodPolicy{MaxPerSecond: 3, Warnings: 5})
+
+	for i := 0; i < 3; i++ {
+		if v := f.Check("user-1", ""); v != nil {
+			t.Fatalf("Expected message %d allowed, got %+v", i, v)
+		}
+	}
+	v := f.Check("user-1", "")
+	if v == nil || v.Reason != FloodReasonRate {
+		t.Fatalf("Expected rate verdict, got %+v", v)
+	}
+	if f.Check("user-2", "") != nil {
+		t.Error("Expected other users unaffected")
+	}
+
+	*now = now.Add(time.Second)
+	if v := f.Check("user-1", ""); v != nil {
+		t.Errorf("Expected budget back after a second, got %+v", v)
+	}
+}
+
+func TestFloodControl_Duplicate(t *testing.T) {
+	f, now := newTestFloodControl(FloodPolicy{DuplicateLimit: 2, DuplicateWindow: 30 * time.Second, Warnings: 5})
+
+	f.Check("user-1", "Buy now")
+	*now = now.Add(2 * time.Second)
+	f.Check("user-1", "buy now ")
+	*now = now.Add(2 * time.Second)
+
+	if v := f.Check("user-1", "BUY NOW"); v == nil || v.Reason != FloodReasonDuplicate {
+		t.Fatalf("Expected duplicate verdict, got %+v", v)
+	}
+	if v := f.Check("user-1", "something else"); v != nil {
+		t.Errorf("Expected different text allowed, got %+v", v)
+	}
+	if v := f.Check("user-1", ""); v != nil {
+		t.Errorf("Expected non-text messages exempt from duplicate checks, got %+v", v)
+	}
+
+	*now = now.Add(30 * time.Second)
+	if v := f.Check("user-1", "buy now"); v != nil {
+		t.Errorf("Expected text allowed once the window passed, got %+v", v)
+	}
+}
+
+func TestFloodControl_WarnsThenMutes(t *testing.T) {
+	f, now := newTestFloodControl(FloodPolicy{MaxPerSecond: 1, Warnings: 2, MuteDuration: time.Minute})
+
+	f.Check("user-1", "")
+	for want := 1; want >= 0; want-- {
+		v := f.Check("user-1", "")
+		if v == nil || v.WarningsLeft != want || !v.MutedUntil.IsZero() {
+			t.Fatalf("Expected warning with %d left, got %+v", want, v)
+		}
+	}
+
+	v := f.Check("user-1", "")
+	if v == nil || v.MutedUntil != now.Add(time.Minute) {
+		t.Fatalf("Expected mute, got %+v", v)
+	}
+
+	*now = now.Add(30 * time.Second)
+	if v := f.Check("user-1", ""); v == nil || v.Reason != FloodReasonMuted {
+		t.Errorf("Expected muted verdict, got %+v", v)
+	}
+
+	*now = now.Add(31 * time.Second)
+	if v := f.Check("user-1", ""); v != nil {
+		t.Errorf("Expected user allowed after the mute, got %+v", v)
+	}
+}
+
+func TestFloodControl_StrikesDecay(t *testing.T) {
+	f, now := newTestFloodControl(FloodPolicy{MaxPerSecond: 1, Warnings: 1, MuteDuration: time.Minute})
+
+	f.Check("user-1", "")
+	if v := f.Check("user-1", ""); v == nil || v.WarningsLeft != 0 {
+		t.Fatalf("Expected last warning, got %+v", v)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	f.Check("user-1", "")
+	if v := f.Check("user-1", ""); v == nil || !v.MutedUntil.IsZero() {
+		t.Errorf("Expected a fresh warning after a quiet spell, got %+v", v)
+	}
+}
+
+func TestFloodControl_Sweep(t *testing.T) {
+	f, now := newTestFloodControl(FloodPolicy{MaxPerSecond: 5})
+
+	f.Check("user-1", "")
+	*now = now.Add(2 * time.Minute)
+	f.Check("user-2", "")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.users["user-1"]; ok {
+		t.Error("Expected idle user swept")
+	}
+	if _, ok := f.users["user-2"]; !ok {
+		t.Error("Expected active user kept")
+	}
+}
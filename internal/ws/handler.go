@@ -1,10 +1,16 @@
 package ws
 
 import (
+	"compress/flate"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-demo/chat/internal/dto/request"
+	"github.com/go-demo/chat/internal/middleware"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
 	"github.com/go-demo/chat/internal/pkg/utils"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
@@ -22,9 +28,13 @@ var upgrader = websocket.Upgrader{
 
 // Handler handles WebSocket connections
 type Handler struct {
-	hub        *Hub
-	jwtManager *utils.JWTManager
-	logger     *zap.Logger
+	hub            *Hub
+	jwtManager     *utils.JWTManager
+	accountChecker middleware.AccountChecker
+	logger         *zap.Logger
+
+	upgrader         websocket.Upgrader
+	compressionLevel int
 }
 
 // NewHandler creates a new WebSocket handler
@@ -33,18 +43,137 @@ func NewHandler(hub *Hub, jwtManager *utils.JWTManager, logger *zap.Logger) *Han
 		hub:        hub,
 		jwtManager: jwtManager,
 		logger:     logger,
+		upgrader:   upgrader,
 	}
 }
 
+// SetCompression enables permessage-deflate for clients that offer it, at a
+// flate level from 1 (fastest) to 9 (smallest); 0 keeps the default
+func (h *Handler) SetCompression(enabled bool, level int) error {
+	if enabled && level != 0 && (level < flate.BestSpeed || level > flate.BestCompression) {
+		return fmt.Errorf("invalid compression level %d (supported: %d-%d)", level, flate.BestSpeed, flate.BestCompression)
+	}
+	h.upgrader.EnableCompression = enabled
+	h.compressionLevel = level
+	return nil
+}
+
+// SetAccountChecker sets the checker that rejects banned users before upgrading
+func (h *Handler) SetAccountChecker(checker middleware.AccountChecker) {
+	h.accountChecker = checker
+}
+
 // ServeWS handles WebSocket connection requests
 // @Summary WebSocket 連線
-// @Description 建立 WebSocket 連線進行即時通訊
+// @Description 建立 WebSocket 連線進行即時通訊。宣告 events、encodings 或 max_payload 的用戶端會先收到 welcome 事件，內含協定版本、已啟用的功能與協商結果
 // @Tags WebSocket
-// @Param token query string true "JWT Token"
+// @Param token query string false "JWT Token"
+// @Param ticket query string false "重連票證（單次使用，可取代 JWT Token）"
+// @Param events query string false "用戶端可處理的事件類型，以逗號分隔；未宣告時僅收到舊版事件"
+// @Param encodings query string false "支援的編碼，依偏好排序（json 或 msgpack）；亦可改以 Sec-WebSocket-Protocol 子協定宣告"
+// @Param max_payload query int false "用戶端可接收的最大訊框位元組數（至少 1024）"
 // @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 503 {object} map[string]string
 // @Router /ws [get]
 func (h *Handler) ServeWS(c *gin.Context) {
+	// A draining instance sends clients elsewhere; checked before a ticket is consumed
+	if h.hub.Draining() {
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "此節點維護中，請重新連線"})
+		return
+	}
+
+	caps, err := ParseCapabilities(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的用戶端能力宣告：" + err.Error()})
+		return
+	}
+
+	session, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	if h.accountChecker != nil {
+		if err := h.accountChecker.CheckAccount(c.Request.Context(), session.UserID); err != nil {
+			c.JSON(apperrors.GetHTTPStatus(err), gin.H{"error": apperrors.GetMessage(err)})
+			return
+		}
+	}
+
+	// An encoding offered as a subprotocol wins over the query
+	var header http.Header
+	if encoding := SelectEncoding(websocket.Subprotocols(c.Request)); encoding != "" {
+		caps.Encoding = encoding
+		caps.Negotiated = true
+		header = http.Header{"Sec-Websocket-Protocol": {encoding}}
+	}
+
+	// Upgrade connection
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, header)
+	if err != nil {
+		h.logger.Error("Failed to upgrade WebSocket",
+			zap.Error(err),
+		)
+		return
+	}
+
+	if h.compressionLevel != 0 {
+		_ = conn.SetCompressionLevel(h.compressionLevel)
+	}
+
+	// Create client
+	client := NewClient(h.hub, conn, session.UserID, session.Username, h.logger)
+	client.SetCapabilities(caps)
+	client.SetClientIP(c.ClientIP())
+	client.SetSession(session.DeviceID, session.ExpiresAt)
+
+	// Register client
+	h.hub.register <- client
+
+	// Start client pumps
+	go client.WritePump()
+	go client.ReadPump()
+}
+
+// authenticate resolves the session from a reconnect ticket or a JWT and
+// writes the error response when neither is valid or the session's device
+// was revoked
+func (h *Handler) authenticate(c *gin.Context) (*Ticket, bool) {
+	session, ok := h.resolveSession(c)
+	if !ok {
+		return nil, false
+	}
+
+	if h.hub.devices != nil && session.DeviceID != "" {
+		if err := h.hub.devices.CheckDevice(c.Request.Context(), session.UserID, session.DeviceID); err != nil {
+			if apperrors.Is(err, apperrors.ErrDeviceNotFound) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "此裝置已登出，請重新登入"})
+			} else {
+				c.JSON(apperrors.GetHTTPStatus(err), gin.H{"error": apperrors.GetMessage(err)})
+			}
+			return nil, false
+		}
+	}
+	return session, true
+}
+
+// resolveSession reads the session from a reconnect ticket or a JWT
+func (h *Handler) resolveSession(c *gin.Context) (*Ticket, bool) {
+	if value := c.Query("ticket"); value != "" && h.hub.tickets != nil {
+		ticket, err := h.hub.tickets.Redeem(c.Request.Context(), value)
+		if err != nil {
+			if err != ErrInvalidTicket {
+				h.logger.Error("Failed to redeem reconnect ticket", zap.Error(err))
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "無效或已使用的重連票證"})
+			return nil, false
+		}
+		return ticket, true
+	}
+
 	// Get token from query parameter or header
 	token := c.Query("token")
 	if token == "" {
@@ -56,7 +185,7 @@ func (h *Handler) ServeWS(c *gin.Context) {
 
 	if token == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少認證 Token"})
-		return
+		return nil, false
 	}
 
 	// Validate token
@@ -66,27 +195,57 @@ func (h *Handler) ServeWS(c *gin.Context) {
 			zap.Error(err),
 		)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "無效的 Token"})
-		return
+		return nil, false
 	}
 
-	// Upgrade connection
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		h.logger.Error("Failed to upgrade WebSocket",
-			zap.Error(err),
-		)
+	session := &Ticket{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		DeviceID: claims.DeviceID,
+	}
+	if claims.ExpiresAt != nil {
+		session.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return session, true
+}
+
+// UploadContent stores a message body too large for a WebSocket frame
+// @Summary 上傳大型訊息內容
+// @Description 內容超過 WebSocket 訊框上限（welcome 事件的 max_message_size）時，先以此端點上傳，再於 send_message 或 send_dm 以 content_ref 取代 content。參考僅限上傳者使用一次，逾時失效
+// @Tags WebSocket
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.UploadMessageContentRequest true "訊息內容"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/ws/content [post]
+func (h *Handler) UploadContent(c *gin.Context) {
+	if h.hub.contents == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未啟用此功能"})
 		return
 	}
 
-	// Create client
-	client := NewClient(h.hub, conn, claims.UserID, claims.Username, h.logger)
+	var req request.UploadMessageContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求參數"})
+		return
+	}
 
-	// Register client
-	h.hub.register <- client
+	ref, expiresAt, err := h.hub.contents.Put(c.Request.Context(), middleware.GetUserID(c), req.Content)
+	if err != nil {
+		h.logger.Error("Failed to store uploaded content", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "伺服器錯誤"})
+		return
+	}
 
-	// Start client pumps
-	go client.WritePump()
-	go client.ReadPump()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"content_ref": ref,
+			"expires_at":  expiresAt.UTC().Format(time.RFC3339),
+		},
+	})
 }
 
 // GetStats returns WebSocket hub statistics
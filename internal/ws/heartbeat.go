@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SetHeartbeat sets how often connections are pinged and how long one may
+// go without a pong, or any other frame, before the idle reaper closes it.
+// Zero keeps the default for either; the interval must be shorter than the
+// timeout, or a healthy client could be reaped between two pings.
+func (h *Hub) SetHeartbeat(pingInterval, pongTimeout time.Duration) error {
+	if pingInterval <= 0 {
+		pingInterval = pingPeriod
+	}
+	if pongTimeout <= 0 {
+		pongTimeout = pongWait
+	}
+	if pingInterval >= pongTimeout {
+		return fmt.Errorf("ping interval %v must be shorter than pong timeout %v", pingInterval, pongTimeout)
+	}
+	h.pingInterval = pingInterval
+	h.pongTimeout = pongTimeout
+	return nil
+}
+
+func (c *Client) pingInterval() time.Duration {
+	if c.hub != nil && c.hub.pingInterval > 0 {
+		return c.hub.pingInterval
+	}
+	return pingPeriod
+}
+
+func (c *Client) pongTimeout() time.Duration {
+	if c.hub != nil && c.hub.pongTimeout > 0 {
+		return c.hub.pongTimeout
+	}
+	return pongWait
+}
+
+// touch records that the peer was heard from
+func (c *Client) touch() {
+	c.lastSeen.Store(time.Now().UnixNano())
+}
+
+// idleFor returns how long the peer has been silent
+func (c *Client) idleFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, c.lastSeen.Load()))
+}
+
+// runReaper closes idle connections until the process exits, checking a
+// few times per pong timeout
+func (h *Hub) runReaper() {
+	ticker := time.NewTicker(h.reapInterval())
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		h.reapIdle(now)
+	}
+}
+
+func (h *Hub) reapInterval() time.Duration {
+	timeout := h.pongTimeout
+	if timeout <= 0 {
+		timeout = pongWait
+	}
+	return timeout / 4
+}
+
+// reapIdle closes the WebSocket connections silent for longer than the pong
+// timeout and returns how many it closed. Closing the connection ends the
+// client's read pump, which unregisters it. Event streams have no pongs and
+// end when a write fails instead.
+func (h *Hub) reapIdle(now time.Time) int {
+	h.mu.RLock()
+	var idle []*Client
+	for client := range h.clients {
+		if client.conn != nil && client.idleFor(now) > client.pongTimeout() {
+			idle = append(idle, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range idle {
+		h.idleReaped.Add(1)
+		h.logger.Info("Closing idle WebSocket connection",
+			zap.String("user_id", client.userID),
+			zap.Duration("idle", client.idleFor(now)),
+		)
+		_ = client.conn.Close()
+	}
+	return len(idle)
+}
+
+// countIdle returns how many WebSocket connections have missed at least
+// one ping, without having been reaped yet. The caller holds h.mu.
+func (h *Hub) countIdle(now time.Time) int {
+	n := 0
+	for client := range h.clients {
+		if client.conn != nil && client.idleFor(now) > client.pingInterval() {
+			n++
+		}
+	}
+	return n
+}
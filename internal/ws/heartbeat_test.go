@@ -0,0 +1,93 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHub_SetHeartbeat(t *testing.T) {
+	hub := createTestHub()
+
+	if err := hub.SetHeartbeat(30*time.Second, 30*time.Second); err == nil {
+		t.Error("Expected a ping interval as long as the timeout to be refused")
+	}
+	if err := hub.SetHeartbeat(0, 20*time.Second); err == nil {
+		t.Error("Expected the default ping interval to be refused with a shorter timeout")
+	}
+	if err := hub.SetHeartbeat(10*time.Second, 0); err != nil {
+		t.Fatalf("SetHeartbeat: %v", err)
+	}
+
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+	if client.pingInterval() != 10*time.Second || client.pongTimeout() != pongWait {
+		t.Errorf("Unexpected heartbeat %v / %v", client.pingInterval(), client.pongTimeout())
+	}
+	if hub.reapInterval() != pongWait/4 {
+		t.Errorf("Expected reaping every quarter timeout, got %v", hub.reapInterval())
+	}
+}
+
+// dialTestConn returns the server side of a live WebSocket connection and
+// the client side that observes it
+func dialTestConn(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conns <- conn
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { _ = peer.Close() })
+	return <-conns, peer
+}
+
+func TestHub_ReapIdle(t *testing.T) {
+	hub := createTestHub()
+	if err := hub.SetHeartbeat(time.Second, 3*time.Second); err != nil {
+		t.Fatalf("SetHeartbeat: %v", err)
+	}
+
+	conn, peer := dialTestConn(t)
+	silent := NewClient(hub, conn, "user-1", "alice", hub.logger)
+	active := NewClient(hub, conn, "user-2", "bob", hub.logger)
+	stream := NewClient(hub, nil, "user-3", "carol", hub.logger)
+	for _, client := range []*Client{silent, active, stream} {
+		hub.clients[client] = true
+	}
+
+	now := time.Now()
+	silent.lastSeen.Store(now.Add(-5 * time.Second).UnixNano())
+	active.lastSeen.Store(now.Add(-2 * time.Second).UnixNano())
+	stream.lastSeen.Store(now.Add(-time.Hour).UnixNano())
+
+	stats := hub.GetStats()
+	if stats["idle_connections"] != 2 {
+		t.Errorf("Expected 2 connections past a ping, got %d", stats["idle_connections"])
+	}
+
+	if n := hub.reapIdle(now); n != 1 {
+		t.Fatalf("Expected only the silent connection reaped, got %d", n)
+	}
+	if hub.GetStats()["idle_reaped"] != 1 {
+		t.Errorf("Expected idle_reaped 1, got %d", hub.GetStats()["idle_reaped"])
+	}
+
+	_ = peer.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := peer.ReadMessage(); err == nil {
+		t.Error("Expected the reaped connection closed")
+	}
+}
@@ -3,10 +3,17 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-demo/chat/internal/features"
 	"github.com/go-demo/chat/internal/model"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/go-demo/chat/internal/pkg/utils"
+	"github.com/go-demo/chat/internal/policy"
 	"github.com/go-demo/chat/internal/service"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -30,6 +37,9 @@ type Hub struct {
 	// Clients by user: userID -> clients (supports multiple connections)
 	users map[string]map[*Client]bool
 
+	// Connections per user in each room: roomID -> userID -> count
+	presence map[string]map[string]int
+
 	// Register requests from clients
 	register chan *Client
 
@@ -54,10 +64,74 @@ type Hub struct {
 	// Redis for Pub/Sub (horizontal scaling)
 	redis *redis.Client
 
+	// Per-client send queue settings
+	sendBufferSize     int
+	slowConsumerPolicy SlowConsumerPolicy
+
+	// Backpressure counters
+	droppedMessages       atomic.Int64
+	slowConsumerEvictions atomic.Int64
+	oversizedMessages     atomic.Int64
+	oversizedFrames       atomic.Int64
+
+	// Largest frame read from a client; 0 uses defaultMaxMessageSize
+	maxMessageSize int
+
+	// Heartbeat settings; 0 uses pingPeriod and pongWait
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+
+	// Connections closed by the idle reaper and by the peer
+	idleReaped   atomic.Int64
+	clientCloses atomic.Int64
+
+	// Broadcast delivery and write deadline settings
+	fanout        *fanoutPool
+	writeTimeout  time.Duration
+	writeLatency  *latencyWindow
+	writeTimeouts atomic.Int64
+
+	// Prometheus histograms; nil unless metrics are enabled
+	metrics *hubMetrics
+
+	// Feature flags for non-critical traffic such as typing indicators
+	features *features.Set
+
+	// Single-use tickets for reconnecting without the JWT
+	tickets *TicketStore
+
+	// Rejects sessions of revoked devices; nil skips the check
+	devices DeviceChecker
+
+	// Message bodies uploaded over REST for sending by reference
+	contents *ContentStore
+
+	// Long-poll requests waiting for a room's next message
+	waiters roomWaiters
+
+	// Recent messages per room for clients that resume; nil disables replay
+	replay *ReplayBuffer
+
+	// Flood control for messages sent over WebSocket; nil disables it
+	flood         *floodControl
+	floodWarnings atomic.Int64
+	floodMutes    atomic.Int64
+
+	// Drain state for rolling deploys
+	draining    atomic.Bool
+	drainMu     sync.Mutex
+	drainCancel context.CancelFunc
+
 	// Logger
 	logger *zap.Logger
 }
 
+// DeviceChecker rejects sessions whose device was revoked or is unknown.
+// It is implemented by service.AuthService.
+type DeviceChecker interface {
+	CheckDevice(ctx context.Context, userID, deviceID string) error
+}
+
 // DirectMessageBroadcast represents a DM to send
 type DirectMessageBroadcast struct {
 	ReceiverID string
@@ -77,6 +151,7 @@ func NewHub(
 		clients:        make(map[*Client]bool),
 		rooms:          make(map[string]map[*Client]bool),
 		users:          make(map[string]map[*Client]bool),
+		presence:       make(map[string]map[string]int),
 		register:       make(chan *Client),
 		unregister:     make(chan *Client),
 		broadcast:      make(chan *BroadcastMessage, 256),
@@ -86,15 +161,93 @@ func NewHub(
 		dmService:      dmService,
 		userService:    userService,
 		redis:          redisClient,
+		fanout:         newFanoutPool(0),
+		writeLatency:   newLatencyWindow(hubLatencySamples),
 		logger:         logger,
 	}
 }
 
+// SetSendQueue configures the per-client send buffer size and slow consumer policy.
+// It must be called before clients connect.
+func (h *Hub) SetSendQueue(bufferSize int, policy SlowConsumerPolicy) {
+	h.sendBufferSize = bufferSize
+	h.slowConsumerPolicy = policy
+}
+
+// SetFanoutWorkers sets the number of broadcast workers (0 uses one per CPU).
+// It must be called before Run.
+func (h *Hub) SetFanoutWorkers(workers int) {
+	h.fanout = newFanoutPool(workers)
+}
+
+// SetWriteTimeout sets the deadline for a single write to a client.
+// Clients that miss it are disconnected.
+func (h *Hub) SetWriteTimeout(timeout time.Duration) {
+	h.writeTimeout = timeout
+}
+
+// SetMaxMessageSize sets the largest frame accepted from a client; a client
+// sending a larger one is disconnected. Sizes below 1024 bytes are raised
+// to it. It must be called before clients connect.
+func (h *Hub) SetMaxMessageSize(size int) {
+	if size < minClientPayload {
+		size = minClientPayload
+	}
+	h.maxMessageSize = size
+}
+
+// readLimit returns the largest frame accepted from a client
+func (h *Hub) readLimit() int {
+	if h == nil || h.maxMessageSize <= 0 {
+		return defaultMaxMessageSize
+	}
+	return h.maxMessageSize
+}
+
+// SetContents enables sending message bodies by reference
+func (h *Hub) SetContents(contents *ContentStore) {
+	h.contents = contents
+}
+
+// SetFeatures sets the feature flags consulted before sending non-critical
+// events such as typing indicators
+func (h *Hub) SetFeatures(flags *features.Set) {
+	h.features = flags
+}
+
+// SetFloodPolicy enables flood control for messages sent over WebSocket.
+// It must be called before clients connect.
+func (h *Hub) SetFloodPolicy(policy FloodPolicy) {
+	h.flood = newFloodControl(policy)
+}
+
+// SetReplay sets the buffer of recent messages replayed to clients that resume
+func (h *Hub) SetReplay(replay *ReplayBuffer) {
+	h.replay = replay
+}
+
+// SetTickets enables reconnect tickets
+func (h *Hub) SetTickets(tickets *TicketStore) {
+	h.tickets = tickets
+}
+
+// SetDeviceChecker sets the checker that refuses connections and reconnect
+// tickets for revoked devices
+func (h *Hub) SetDeviceChecker(devices DeviceChecker) {
+	h.devices = devices
+}
+
 // Run starts the hub
 func (h *Hub) Run() {
+	if h.fanout != nil {
+		h.fanout.start()
+	}
+
 	// Start Redis subscriber in goroutine
 	go h.subscribeRedis()
 
+	go h.runReaper()
+
 	for {
 		select {
 		case client := <-h.register:
@@ -130,6 +283,14 @@ func (h *Hub) registerClient(client *Client) {
 		zap.Int("total_clients", len(h.clients)),
 	)
 
+	// Clients that negotiated learn what this server offers; legacy
+	// clients would not know the welcome event
+	if client.caps != nil && client.caps.Negotiated {
+		if welcome, err := NewMessage(MessageTypeWelcome, newWelcomePayload(client.caps, h.features, h.readLimit())); err == nil {
+			client.SendMessage(welcome)
+		}
+	}
+
 	// Update user status
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -160,12 +321,10 @@ func (h *Hub) unregisterClient(client *Client) {
 	}
 
 	// Remove from all rooms
+	var left []*BroadcastMessage
 	for roomID := range client.rooms {
-		if roomClients, ok := h.rooms[roomID]; ok {
-			delete(roomClients, client)
-			if len(roomClients) == 0 {
-				delete(h.rooms, roomID)
-			}
+		if h.removeFromRoomLocked(client, roomID) {
+			left = append(left, presenceBroadcast(client, roomID, false))
 		}
 	}
 
@@ -173,6 +332,11 @@ func (h *Hub) unregisterClient(client *Client) {
 
 	client.Close()
 
+	// Already on the hub goroutine, so delivered directly rather than queued
+	for _, bm := range left {
+		h.broadcastToRoom(bm)
+	}
+
 	h.logger.Info("Client disconnected",
 		zap.String("user_id", client.userID),
 		zap.String("username", client.username),
@@ -198,29 +362,29 @@ func (h *Hub) unregisterClient(client *Client) {
 
 // JoinRoom adds a client to a room
 func (h *Hub) JoinRoom(client *Client, roomID string) {
-	// Check if user is member of the room
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Check if user may subscribe to the room
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
 	defer cancel()
 
-	isMember, err := h.roomService.IsMember(ctx, roomID, client.userID)
+	allowed, err := h.roomService.Can(ctx, roomID, client.userID, policy.CanAccess)
 	if err != nil {
-		client.sendError(500, "伺服器錯誤")
+		client.sendError(apperrors.GetHTTPStatus(err), apperrors.GetMessage(err))
 		return
 	}
 
-	if !isMember {
+	if !allowed {
 		client.sendError(403, "您不是該聊天室的成員")
 		return
 	}
 
 	h.mu.Lock()
-	if h.rooms[roomID] == nil {
-		h.rooms[roomID] = make(map[*Client]bool)
-	}
-	h.rooms[roomID][client] = true
+	entered := h.addToRoomLocked(client, roomID)
 	h.mu.Unlock()
 
 	client.JoinRoom(roomID)
+	if entered {
+		h.broadcast <- presenceBroadcast(client, roomID, true)
+	}
 
 	// Get room info
 	room, err := h.roomService.GetByIDWithDetails(ctx, roomID)
@@ -242,18 +406,87 @@ func (h *Hub) JoinRoom(client *Client, roomID string) {
 	)
 }
 
-// LeaveRoom removes a client from a room
-func (h *Hub) LeaveRoom(client *Client, roomID string) {
+// JoinRooms subscribes a client to several rooms at once. Membership of all
+// rooms is checked with a single query, and the client gets one
+// rooms_joined frame with a result per room instead of a room_joined each.
+func (h *Hub) JoinRooms(client *Client, roomIDs []string, requestID string) {
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
+	defer cancel()
+
+	seen := make(map[string]bool, len(roomIDs))
+	valid := make([]string, 0, len(roomIDs))
+	for _, roomID := range roomIDs {
+		if !seen[roomID] && utils.ValidateUUID(roomID) {
+			valid = append(valid, roomID)
+		}
+		seen[roomID] = true
+	}
+
+	rooms, err := h.roomService.AccessibleRooms(ctx, valid, client.userID)
+	if err != nil {
+		client.sendError(apperrors.GetHTTPStatus(err), apperrors.GetMessage(err))
+		return
+	}
+
 	h.mu.Lock()
-	if roomClients, ok := h.rooms[roomID]; ok {
-		delete(roomClients, client)
-		if len(roomClients) == 0 {
-			delete(h.rooms, roomID)
+	var entered []string
+	for roomID := range rooms {
+		if h.addToRoomLocked(client, roomID) {
+			entered = append(entered, roomID)
 		}
 	}
 	h.mu.Unlock()
 
+	results := make([]JoinRoomResult, 0, len(roomIDs))
+	reported := make(map[string]bool, len(roomIDs))
+	for _, roomID := range roomIDs {
+		if reported[roomID] {
+			continue
+		}
+		reported[roomID] = true
+
+		room, ok := rooms[roomID]
+		switch {
+		case ok:
+			client.JoinRoom(roomID)
+			results = append(results, JoinRoomResult{
+				RoomID:      roomID,
+				Joined:      true,
+				RoomName:    room.Name,
+				MemberCount: room.MemberCount,
+			})
+		case !utils.ValidateUUID(roomID):
+			results = append(results, JoinRoomResult{RoomID: roomID, Code: 400, Error: "無效的聊天室 ID"})
+		default:
+			results = append(results, JoinRoomResult{RoomID: roomID, Code: 403, Error: "您不是該聊天室的成員"})
+		}
+	}
+
+	joinedMsg, _ := NewMessage(MessageTypeRoomsJoined, &RoomsJoinedPayload{Results: results})
+	joinedMsg.RequestID = requestID
+	client.SendMessage(joinedMsg)
+
+	for _, roomID := range entered {
+		h.broadcast <- presenceBroadcast(client, roomID, true)
+	}
+
+	h.logger.Debug("Client joined rooms",
+		zap.String("user_id", client.userID),
+		zap.Int("requested", len(reported)),
+		zap.Int("joined", len(rooms)),
+	)
+}
+
+// LeaveRoom removes a client from a room
+func (h *Hub) LeaveRoom(client *Client, roomID string) {
+	h.mu.Lock()
+	left := h.removeFromRoomLocked(client, roomID)
+	h.mu.Unlock()
+
 	client.LeaveRoom(roomID)
+	if left {
+		h.broadcast <- presenceBroadcast(client, roomID, false)
+	}
 
 	// Send room left confirmation
 	leftMsg, _ := NewMessage(MessageTypeRoomLeft, &LeaveRoomPayload{RoomID: roomID})
@@ -272,13 +505,11 @@ func (h *Hub) SendMessage(client *Client, payload SendMessagePayload, requestID
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
 	defer cancel()
 
-	// Get user info for broadcast
-	user, err := h.userService.GetByID(ctx, client.userID)
-	if err != nil {
-		client.sendError(500, "伺服器錯誤")
+	content, ok := h.resolveContent(ctx, client, payload.Content, payload.ContentRef)
+	if !ok {
 		return
 	}
 
@@ -288,16 +519,53 @@ func (h *Hub) SendMessage(client *Client, payload SendMessagePayload, requestID
 		msgType = model.MessageTypeImage
 	} else if payload.Type == "file" {
 		msgType = model.MessageTypeFile
+	} else if payload.Type == "sticker" {
+		msgType = model.MessageTypeSticker
+	}
+
+	if !h.checkFlood(client, msgType, content) {
+		return
 	}
 
 	msg, err := h.messageService.SendMessage(ctx, &service.SendMessageInput{
 		RoomID:    payload.RoomID,
 		UserID:    client.userID,
-		Content:   payload.Content,
+		Content:   content,
 		Type:      msgType,
 		ReplyToID: payload.ReplyToID,
+
+		AttachmentIDs: payload.AttachmentIDs,
+
+		// The broadcast does not wait for Postgres when write-behind is on
+		WriteBehind: true,
 	})
 	if err != nil {
+		if apperrors.Is(err, apperrors.ErrMemberMuted) || apperrors.Is(err, apperrors.ErrRoomReadOnly) ||
+			apperrors.Is(err, apperrors.ErrRoomArchived) {
+			client.sendError(403, apperrors.GetMessage(err))
+			return
+		}
+		if apperrors.Is(err, apperrors.ErrPermissionDenied) {
+			client.sendError(403, "您沒有在該聊天室發言的權限")
+			return
+		}
+		if apperrors.Is(err, service.ErrRoomRateLimited) {
+			client.sendError(429, apperrors.GetMessage(err))
+			return
+		}
+		if apperrors.Is(err, service.ErrInvalidSticker) || apperrors.Is(err, service.ErrAttachmentNotFound) {
+			client.sendError(400, apperrors.GetMessage(err))
+			return
+		}
+		if apperrors.Is(err, service.ErrAttachmentNotOwned) {
+			client.sendError(403, apperrors.GetMessage(err))
+			return
+		}
+		if apperrors.Is(err, service.ErrMessageProfane) || apperrors.Is(err, service.ErrMessageBlocked) ||
+			apperrors.Is(err, service.ErrAttachmentInfected) {
+			client.sendError(422, apperrors.GetMessage(err))
+			return
+		}
 		client.sendError(500, "發送訊息失敗")
 		return
 	}
@@ -311,38 +579,180 @@ func (h *Hub) SendMessage(client *Client, payload SendMessagePayload, requestID
 	client.SendMessage(ackMsg)
 
 	// Broadcast to room
-	broadcastPayload := &NewMessagePayload{
+	broadcastMsg, _ := newChatMessage(msg)
+	h.recordReplay(ctx, payload.RoomID, msg.ID, broadcastMsg)
+
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  payload.RoomID,
+		Message: broadcastMsg,
+		Sender:  client,
+	}
+
+	// Publish to Redis for horizontal scaling
+	h.publishToRedis("room:"+payload.RoomID, broadcastMsg)
+}
+
+// checkFlood reports whether the client's user may send a message. A
+// refused message gets a flood_warning, for clients that handle it, and an
+// error saying why.
+func (h *Hub) checkFlood(client *Client, msgType model.MessageType, content string) bool {
+	text := ""
+	if msgType == model.MessageTypeText {
+		text = content
+	}
+	verdict := h.flood.Check(client.userID, text)
+	if verdict == nil {
+		return true
+	}
+
+	warning := &FloodWarningPayload{Reason: verdict.Reason, WarningsLeft: verdict.WarningsLeft}
+	if !verdict.MutedUntil.IsZero() {
+		warning.MutedUntil = verdict.MutedUntil.Format(time.RFC3339)
+	}
+	warningMsg, _ := NewMessage(MessageTypeFloodWarning, warning)
+
+	client.SendMessage(warningMsg)
+
+	wait := int(math.Ceil(time.Until(verdict.MutedUntil).Seconds()))
+	switch {
+	case verdict.Reason == FloodReasonMuted:
+		client.sendError(429, fmt.Sprintf("您因洗版已被暫時禁言，請於 %d 秒後再試", wait))
+	case !verdict.MutedUntil.IsZero():
+		h.floodMutes.Add(1)
+		h.logger.Warn("User muted for flooding",
+			zap.String("user_id", client.userID),
+			zap.String("reason", verdict.Reason),
+			zap.Time("muted_until", verdict.MutedUntil),
+		)
+		client.sendError(429, fmt.Sprintf("您發送訊息過於頻繁，已被暫時禁言 %d 秒", wait))
+	default:
+		h.floodWarnings.Add(1)
+		if verdict.Reason == FloodReasonDuplicate {
+			client.sendError(429, "請勿重複發送相同的訊息，持續洗版將被暫時禁言")
+		} else {
+			client.sendError(429, "發送訊息過於頻繁，持續洗版將被暫時禁言")
+		}
+	}
+	return false
+}
+
+// PublishMessage broadcasts a message that was sent outside the WebSocket
+// connection, such as through the REST API, to the room's clients
+func (h *Hub) PublishMessage(msg *model.MessageWithUser) {
+	broadcastMsg, _ := newChatMessage(msg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	h.recordReplay(ctx, msg.RoomID, msg.ID, broadcastMsg)
+	cancel()
+
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  msg.RoomID,
+		Message: broadcastMsg,
+	}
+	h.publishToRedis("room:"+msg.RoomID, broadcastMsg)
+}
+
+// Payloads built for every chat message and keystroke are pooled. NewMessage
+// encodes a payload straight away, so it can be reused as soon as it returns.
+var (
+	newMessagePayloadPool = sync.Pool{New: func() interface{} { return new(NewMessagePayload) }}
+	typingPayloadPool     = sync.Pool{New: func() interface{} { return new(UserTypingPayload) }}
+)
+
+// newChatMessage builds the new_message event for a stored message
+func newChatMessage(msg *model.MessageWithUser) (*Message, error) {
+	payload := newMessagePayloadPool.Get().(*NewMessagePayload)
+	defer func() {
+		*payload = NewMessagePayload{}
+		newMessagePayloadPool.Put(payload)
+	}()
+
+	*payload = NewMessagePayload{
 		ID:          msg.ID,
 		RoomID:      msg.RoomID,
 		UserID:      msg.UserID,
-		Username:    user.Username,
-		DisplayName: user.GetDisplayName(),
-		AvatarURL:   user.GetAvatarURL(),
+		Username:    msg.Username,
+		DisplayName: msg.GetUserDisplayName(),
+		AvatarURL:   msg.GetUserAvatarURL(),
+		IsBot:       msg.IsBot,
 		Content:     msg.Content,
 		Type:        string(msg.Type),
 		ReplyToID:   msg.GetReplyToID(),
+		IsNSFW:      msg.IsNSFW,
 		CreatedAt:   msg.CreatedAt.Format(time.RFC3339),
+
+		ForwardedFrom: msg.ForwardedFrom,
+		Attachments:   newAttachmentPayloads(msg.Attachments),
 	}
+	if sticker := msg.Sticker; sticker != nil {
+		payload.Sticker = &StickerPayload{
+			ID:       sticker.ID,
+			PackID:   sticker.PackID,
+			Name:     sticker.Name,
+			ImageURL: sticker.ImageURL,
+			Width:    sticker.Width,
+			Height:   sticker.Height,
+		}
+	}
+	return NewMessage(MessageTypeNewMessage, payload)
+}
 
-	broadcastMsg, _ := NewMessage(MessageTypeNewMessage, broadcastPayload)
+// newAttachmentPayloads describes attachments, nil for none
+func newAttachmentPayloads(attachments []*model.Attachment) []*AttachmentPayload {
+	if len(attachments) == 0 {
+		return nil
+	}
+	payloads := make([]*AttachmentPayload, len(attachments))
+	for i, a := range attachments {
+		payloads[i] = &AttachmentPayload{
+			ID:          a.ID,
+			UploadID:    a.UploadID,
+			FileName:    a.FileName,
+			FileURL:     a.FileURL,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			ScanStatus:  string(a.ScanStatus),
+		}
+	}
+	return payloads
+}
 
-	h.broadcast <- &BroadcastMessage{
-		RoomID:  payload.RoomID,
-		Message: broadcastMsg,
-		Sender:  client,
+// resolveContent returns the body to send: the frame's content, or the
+// body uploaded under contentRef. It sends the error to the client itself.
+func (h *Hub) resolveContent(ctx context.Context, client *Client, content, contentRef string) (string, bool) {
+	if contentRef == "" {
+		return content, true
+	}
+	if content != "" || h.contents == nil {
+		client.sendError(400, "無效的請求參數")
+		return "", false
 	}
 
-	// Publish to Redis for horizontal scaling
-	h.publishToRedis("room:"+payload.RoomID, broadcastMsg)
+	body, err := h.contents.Take(ctx, client.userID, contentRef)
+	if err != nil {
+		if err != ErrInvalidContentRef {
+			h.logger.Error("Failed to take uploaded content", zap.Error(err))
+			client.sendError(500, "發送訊息失敗")
+			return "", false
+		}
+		client.sendError(400, "內容參考無效、已使用或已過期")
+		return "", false
+	}
+	return body, true
 }
 
 // SendDirectMessage sends a direct message
 func (h *Hub) SendDirectMessage(client *Client, payload SendDMPayload, requestID string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
 	defer cancel()
 
+	content, ok := h.resolveContent(ctx, client, payload.Content, payload.ContentRef)
+	if !ok {
+		return
+	}
+
 	// Get sender info
-	sender, err := h.userService.GetByID(ctx, client.userID)
+	sender, err := h.userService.GetDisplay(ctx, client.userID)
 	if err != nil {
 		client.sendError(500, "伺服器錯誤")
 		return
@@ -356,13 +766,23 @@ func (h *Hub) SendDirectMessage(client *Client, payload SendDMPayload, requestID
 		msgType = model.MessageTypeFile
 	}
 
+	if !h.checkFlood(client, msgType, content) {
+		return
+	}
+
 	dm, err := h.dmService.SendMessage(ctx, &service.SendDMInput{
-		SenderID:   client.userID,
-		ReceiverID: payload.ReceiverID,
-		Content:    payload.Content,
-		Type:       msgType,
+		SenderID:      client.userID,
+		ReceiverID:    payload.ReceiverID,
+		Content:       content,
+		Type:          msgType,
+		AttachmentIDs: payload.AttachmentIDs,
 	})
 	if err != nil {
+		if apperrors.Is(err, service.ErrAttachmentNotFound) || apperrors.Is(err, service.ErrAttachmentNotOwned) ||
+			apperrors.Is(err, service.ErrAttachmentInfected) {
+			client.sendError(apperrors.GetHTTPStatus(err), apperrors.GetMessage(err))
+			return
+		}
 		client.sendError(500, "發送訊息失敗")
 		return
 	}
@@ -380,11 +800,13 @@ func (h *Hub) SendDirectMessage(client *Client, payload SendDMPayload, requestID
 		ID:                dm.ID,
 		SenderID:          dm.SenderID,
 		SenderUsername:    sender.Username,
-		SenderDisplayName: sender.GetDisplayName(),
-		SenderAvatarURL:   sender.GetAvatarURL(),
+		SenderDisplayName: sender.DisplayName,
+		SenderAvatarURL:   sender.AvatarURL,
 		Content:           dm.Content,
 		Type:              string(dm.Type),
+		IsNSFW:            dm.IsNSFW,
 		CreatedAt:         dm.CreatedAt.Format(time.RFC3339),
+		Attachments:       newAttachmentPayloads(dm.Attachments),
 	}
 
 	dmMsg, _ := NewMessage(MessageTypeNewDM, dmPayload)
@@ -403,19 +825,26 @@ func (h *Hub) SendDirectMessage(client *Client, payload SendDMPayload, requestID
 
 // BroadcastTyping broadcasts typing indicator
 func (h *Hub) BroadcastTyping(client *Client, roomID string, isTyping bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if !h.features.Enabled(features.FlagTypingBroadcasts) {
+		return
+	}
+
+	if !client.IsInRoom(roomID) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
 	defer cancel()
 
-	user, err := h.userService.GetByID(ctx, client.userID)
-	if err != nil {
+	// Typing is a prelude to sending; members who may not post stay silent
+	allowed, err := h.roomService.Can(ctx, roomID, client.userID, policy.CanSend)
+	if err != nil || !allowed {
 		return
 	}
 
-	payload := &UserTypingPayload{
-		RoomID:      roomID,
-		UserID:      client.userID,
-		Username:    user.Username,
-		DisplayName: user.GetDisplayName(),
+	user, err := h.userService.GetDisplay(ctx, client.userID)
+	if err != nil {
+		return
 	}
 
 	var msgType MessageType
@@ -425,7 +854,16 @@ func (h *Hub) BroadcastTyping(client *Client, roomID string, isTyping bool) {
 		msgType = MessageTypeUserStopTyping
 	}
 
+	payload := typingPayloadPool.Get().(*UserTypingPayload)
+	*payload = UserTypingPayload{
+		RoomID:      roomID,
+		UserID:      client.userID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+	}
 	msg, _ := NewMessage(msgType, payload)
+	*payload = UserTypingPayload{}
+	typingPayloadPool.Put(payload)
 
 	h.broadcast <- &BroadcastMessage{
 		RoomID:  roomID,
@@ -434,9 +872,63 @@ func (h *Hub) BroadcastTyping(client *Client, roomID string, isTyping bool) {
 	}
 }
 
+// IssueReconnectTicket sends the client a single-use ticket it can present
+// instead of its JWT when the connection drops
+func (h *Hub) IssueReconnectTicket(client *Client, requestID string) {
+	if h.tickets == nil {
+		client.sendError(503, "重連票證功能未啟用")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
+	defer cancel()
+
+	ticket, expiresAt, err := h.issueTicket(ctx, client)
+	if err == ErrSessionExpired {
+		client.sendError(401, "登入已過期，請重新登入")
+		return
+	}
+	if apperrors.Is(err, apperrors.ErrDeviceNotFound) {
+		client.sendError(401, "此裝置已登出，請重新登入")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to issue reconnect ticket",
+			zap.String("user_id", client.userID),
+			zap.Error(err),
+		)
+		client.sendError(500, "產生重連票證失敗")
+		return
+	}
+
+	ticketMsg, _ := NewMessage(MessageTypeReconnectTicket, &ReconnectTicketPayload{
+		Ticket:    ticket,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	})
+	ticketMsg.RequestID = requestID
+	client.SendMessage(ticketMsg)
+}
+
+// issueTicket issues a reconnect ticket bound to the client's device and
+// access token expiry. It fails with ErrSessionExpired once the token has
+// expired and with the checker's error when the device was revoked.
+func (h *Hub) issueTicket(ctx context.Context, client *Client) (string, time.Time, error) {
+	if h.devices != nil && client.deviceID != "" {
+		if err := h.devices.CheckDevice(ctx, client.userID, client.deviceID); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	return h.tickets.Issue(ctx, &Ticket{
+		UserID:    client.userID,
+		Username:  client.username,
+		DeviceID:  client.deviceID,
+		ExpiresAt: client.sessionExpiresAt,
+	})
+}
+
 // MarkAsRead handles mark as read
 func (h *Hub) MarkAsRead(client *Client, payload MarkReadPayload) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
 	defer cancel()
 
 	if payload.RoomID != "" {
@@ -461,23 +953,74 @@ func (h *Hub) MarkAsRead(client *Client, payload MarkReadPayload) {
 }
 
 func (h *Hub) broadcastToRoom(bm *BroadcastMessage) {
+	if h.metrics != nil {
+		defer h.metrics.broadcastDuration.ObserveSince(time.Now())
+	}
+
+	if bm.Message.Type == MessageTypeNewMessage {
+		h.waiters.wake(bm.RoomID)
+	}
+
 	h.mu.RLock()
-	clients := h.rooms[bm.RoomID]
+	clients := make([]*Client, 0, len(h.rooms[bm.RoomID]))
+	for client := range h.rooms[bm.RoomID] {
+		// Skip the sending connection (it already has an acknowledgement);
+		// other devices of the same user still receive the message
+		if bm.Sender != nil && client == bm.Sender {
+			continue
+		}
+		// Clients that cannot handle the event are left out instead of broken
+		if !client.Accepts(bm.Message.Type) {
+			continue
+		}
+		clients = append(clients, client)
+	}
 	h.mu.RUnlock()
 
-	for client := range clients {
-		// Skip sender for certain message types (they already have acknowledgement)
-		if bm.Sender != nil && client == bm.Sender {
-			// Still send to other devices of the same user
-			if client.userID == bm.Sender.userID && client != bm.Sender {
-				client.SendMessage(bm.Message)
+	if len(clients) == 0 {
+		return
+	}
+
+	// Encode once per encoding in use rather than once per recipient
+	for len(clients) > 0 {
+		encoding := clients[0].encoding()
+		n := partitionByEncoding(clients, encoding)
+		group := clients[:n]
+		clients = clients[n:]
+
+		data, err := bm.Message.Encode(encoding)
+		if err != nil {
+			h.logger.Error("Failed to encode broadcast message",
+				zap.String("room_id", bm.RoomID),
+				zap.String("encoding", encoding),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if h.fanout == nil {
+			for _, client := range group {
+				client.enqueue(data)
 			}
 			continue
 		}
-		client.SendMessage(bm.Message)
+		h.fanout.submit(group, data)
 	}
 }
 
+// partitionByEncoding moves the clients using encoding to the front of
+// clients and returns how many there are
+func partitionByEncoding(clients []*Client, encoding string) int {
+	n := 0
+	for i, client := range clients {
+		if client.encoding() == encoding {
+			clients[n], clients[i] = clients[i], clients[n]
+			n++
+		}
+	}
+	return n
+}
+
 func (h *Hub) sendToUser(userID string, msg *Message) {
 	h.mu.RLock()
 	clients := h.users[userID]
@@ -497,7 +1040,7 @@ func (h *Hub) broadcastUserStatus(client *Client, online bool) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	user, err := h.userService.GetByID(ctx, client.userID)
+	user, err := h.userService.GetDisplay(ctx, client.userID)
 	if err != nil {
 		return
 	}
@@ -505,7 +1048,7 @@ func (h *Hub) broadcastUserStatus(client *Client, online bool) {
 	payload := &UserStatusPayload{
 		UserID:      client.userID,
 		Username:    user.Username,
-		DisplayName: user.GetDisplayName(),
+		DisplayName: user.DisplayName,
 		Status:      status,
 	}
 
@@ -528,13 +1071,76 @@ func (h *Hub) broadcastUserStatus(client *Client, online bool) {
 	}
 }
 
+// PublishToRoom broadcasts a server event to all clients in a room
+func (h *Hub) PublishToRoom(roomID, eventType string, payload interface{}) {
+	msg, err := NewMessage(MessageType(eventType), payload)
+	if err != nil {
+		h.logger.Error("Failed to encode room event", zap.String("type", eventType), zap.Error(err))
+		return
+	}
+
+	if replayedEvents[msg.Type] {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		h.recordReplay(ctx, roomID, "", msg)
+		cancel()
+	}
+
+	h.broadcast <- &BroadcastMessage{
+		RoomID:  roomID,
+		Message: msg,
+	}
+
+	h.publishToRedis("room:"+roomID, msg)
+}
+
+// PublishToUser sends an arbitrary event to every connection of a user.
+// It implements service.RealtimePublisher.
+func (h *Hub) PublishToUser(userID, eventType string, payload interface{}) {
+	msg, err := NewMessage(MessageType(eventType), payload)
+	if err != nil {
+		h.logger.Error("Failed to encode user event", zap.String("type", eventType), zap.Error(err))
+		return
+	}
+
+	h.directMessage <- &DirectMessageBroadcast{
+		ReceiverID: userID,
+		Message:    msg,
+	}
+
+	h.publishToRedis("dm:"+userID, msg)
+}
+
+// PublishNotification pushes a stored notification to the user's clients
+func (h *Hub) PublishNotification(n *model.Notification) {
+	payload := &NotificationPayload{
+		ID:            n.ID,
+		Type:          n.Type,
+		Title:         n.Title,
+		Content:       n.Content.String,
+		ReferenceID:   n.ReferenceID.String,
+		ReferenceType: n.ReferenceType.String,
+		CreatedAt:     n.CreatedAt.Format(time.RFC3339),
+	}
+
+	msg, err := NewMessage(MessageTypeNotification, payload)
+	if err != nil {
+		return
+	}
+
+	h.directMessage <- &DirectMessageBroadcast{
+		ReceiverID: n.UserID,
+		Message:    msg,
+	}
+}
+
 // Redis Pub/Sub for horizontal scaling
 func (h *Hub) publishToRedis(channel string, msg *Message) {
 	if h.redis == nil {
 		return
 	}
 
-	data, err := json.Marshal(msg)
+	// Other instances decode the message again, so it always travels as JSON
+	data, err := msg.Encode(EncodingJSON)
 	if err != nil {
 		return
 	}
@@ -564,6 +1170,13 @@ func (h *Hub) subscribeRedis() {
 	}
 }
 
+// ConnectionCount returns the number of open connections on this instance
+func (h *Hub) ConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
 // GetOnlineUsers returns online user IDs
 func (h *Hub) GetOnlineUsers() []string {
 	h.mu.RLock()
@@ -590,14 +1203,115 @@ func (h *Hub) GetRoomClients(roomID string) int {
 	return len(h.rooms[roomID])
 }
 
+// DisconnectUser notifies and disconnects every connection of a user.
+// It implements service.UserDisconnector.
+func (h *Hub) DisconnectUser(userID, reason string) {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.users[userID]))
+	for client := range h.users[userID] {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	if len(clients) == 0 {
+		return
+	}
+
+	msg, _ := NewMessage(MessageTypeAccountBanned, &AccountBannedPayload{Reason: reason})
+	for _, client := range clients {
+		// Queued before the send channel is closed, so it is written ahead of the close frame
+		client.SendMessage(msg)
+	}
+
+	go func() {
+		for _, client := range clients {
+			h.unregister <- client
+		}
+	}()
+
+	h.logger.Info("Disconnected user",
+		zap.String("user_id", userID),
+		zap.Int("connections", len(clients)),
+	)
+}
+
+// DisconnectDevice notifies and disconnects the connections a user opened
+// from one device, leaving their other devices connected.
+// It implements service.DeviceDisconnector.
+func (h *Hub) DisconnectDevice(userID, deviceID, reason string) {
+	h.mu.RLock()
+	var clients []*Client
+	for client := range h.users[userID] {
+		if client.deviceID == deviceID {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	if len(clients) == 0 {
+		return
+	}
+
+	msg, _ := NewMessage(MessageTypeSessionRevoked, &SessionRevokedPayload{DeviceID: deviceID, Reason: reason})
+	for _, client := range clients {
+		// Queued before the send channel is closed, so it is written ahead of the close frame
+		client.SendMessage(msg)
+	}
+
+	go func() {
+		for _, client := range clients {
+			h.unregister <- client
+		}
+	}()
+
+	h.logger.Info("Disconnected device",
+		zap.String("user_id", userID),
+		zap.String("device_id", deviceID),
+		zap.Int("connections", len(clients)),
+	)
+}
+
 // GetStats returns hub statistics
 func (h *Hub) GetStats() map[string]int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	return map[string]int{
+	stats := map[string]int{
 		"total_clients":  len(h.clients),
 		"online_users":   len(h.users),
 		"active_rooms":   len(h.rooms),
+
+		"dropped_messages":        int(h.droppedMessages.Load()),
+		"slow_consumer_evictions": int(h.slowConsumerEvictions.Load()),
+		"oversized_messages":      int(h.oversizedMessages.Load()),
+		"oversized_frames":        int(h.oversizedFrames.Load()),
+		"write_timeouts":          int(h.writeTimeouts.Load()),
+
+		"flood_warnings": int(h.floodWarnings.Load()),
+		"flood_mutes":    int(h.floodMutes.Load()),
+
+		"long_poll_waiters": h.waiters.count(),
+
+		"idle_connections": h.countIdle(time.Now()),
+		"idle_reaped":      int(h.idleReaped.Load()),
+		"client_closes":    int(h.clientCloses.Load()),
+	}
+
+	if h.writeLatency != nil {
+		p := h.writeLatency.Percentiles(50, 95, 99)
+		stats["write_latency_p50_us"] = int(p[0].Microseconds())
+		stats["write_latency_p95_us"] = int(p[1].Microseconds())
+		stats["write_latency_p99_us"] = int(p[2].Microseconds())
 	}
+
+	// Tail latency of the slowest connection helps spot a single bad client
+	var slowest time.Duration
+	for client := range h.clients {
+		if p99 := client.WriteLatency(99); p99 > slowest {
+			slowest = p99
+		}
+	}
+	stats["slowest_client_write_p99_us"] = int(slowest.Microseconds())
+
+	return stats
 }
@@ -1,9 +1,11 @@
 package ws
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
+	"github.com/go-demo/chat/internal/model"
 	"go.uber.org/zap"
 )
 
@@ -357,3 +359,127 @@ func TestHub_SendToUser(t *testing.T) {
 		t.Error("Client did not receive message")
 	}
 }
+
+func TestHub_PublishToRoom(t *testing.T) {
+	hub := createTestHub()
+
+	hub.PublishToRoom("room-1", string(MessageTypeRoomDeleting), map[string]string{"room_id": "room-1"})
+
+	select {
+	case bm := <-hub.broadcast:
+		if bm.RoomID != "room-1" {
+			t.Errorf("Expected room-1, got %s", bm.RoomID)
+		}
+		if bm.Message.Type != MessageTypeRoomDeleting {
+			t.Errorf("Expected type %s, got %s", MessageTypeRoomDeleting, bm.Message.Type)
+		}
+		if bm.Sender != nil {
+			t.Error("Expected system message without sender")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected broadcast message")
+	}
+}
+
+func TestHub_PublishNotification(t *testing.T) {
+	hub := createTestHub()
+
+	hub.PublishNotification(&model.Notification{
+		ID:     "notification-1",
+		UserID: "user-1",
+		Type:   model.NotificationTypeRoomDeleting,
+		Title:  "test",
+	})
+
+	select {
+	case dm := <-hub.directMessage:
+		if dm.ReceiverID != "user-1" {
+			t.Errorf("Expected user-1, got %s", dm.ReceiverID)
+		}
+		var payload NotificationPayload
+		if err := dm.Message.ParsePayload(&payload); err != nil {
+			t.Fatalf("Failed to parse payload: %v", err)
+		}
+		if payload.ID != "notification-1" || payload.Type != model.NotificationTypeRoomDeleting {
+			t.Errorf("Unexpected payload: %+v", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected notification message")
+	}
+}
+
+func TestHub_DisconnectUser(t *testing.T) {
+	hub := createTestHub()
+
+	device1 := createMockClient("user-1", "alice")
+	device2 := createMockClient("user-1", "alice")
+	other := createMockClient("user-2", "bob")
+	for _, c := range []*Client{device1, device2, other} {
+		c.hub = hub
+		hub.clients[c] = true
+	}
+	hub.users["user-1"] = map[*Client]bool{device1: true, device2: true}
+	hub.users["user-2"] = map[*Client]bool{other: true}
+
+	hub.DisconnectUser("user-1", "spam")
+
+	for _, c := range []*Client{device1, device2} {
+		var msg Message
+		_ = json.Unmarshal(<-c.send, &msg)
+		if msg.Type != MessageTypeAccountBanned {
+			t.Errorf("Expected account_banned message, got %s", msg.Type)
+		}
+	}
+
+	unregistered := map[*Client]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case c := <-hub.unregister:
+			unregistered[c] = true
+		case <-time.After(time.Second):
+			t.Fatal("Expected both connections to be unregistered")
+		}
+	}
+	if !unregistered[device1] || !unregistered[device2] {
+		t.Error("Expected both devices of the banned user to be unregistered")
+	}
+
+	if len(other.send) != 0 {
+		t.Error("Other users should not be affected")
+	}
+}
+
+func TestHub_DisconnectDevice(t *testing.T) {
+	hub := createTestHub()
+
+	phone := createMockClient("user-1", "alice")
+	phone.SetSession("phone", time.Time{})
+	laptop := createMockClient("user-1", "alice")
+	laptop.SetSession("laptop", time.Time{})
+	for _, c := range []*Client{phone, laptop} {
+		c.hub = hub
+		hub.clients[c] = true
+	}
+	hub.users["user-1"] = map[*Client]bool{phone: true, laptop: true}
+
+	hub.DisconnectDevice("user-1", "phone", "revoked")
+
+	var msg Message
+	_ = json.Unmarshal(<-phone.send, &msg)
+	if msg.Type != MessageTypeSessionRevoked {
+		t.Errorf("Expected session_revoked message, got %s", msg.Type)
+	}
+
+	select {
+	case c := <-hub.unregister:
+		if c != phone {
+			t.Error("Expected only the revoked device to be unregistered")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the revoked device to be unregistered")
+	}
+
+	if len(laptop.send) != 0 {
+		t.Error("Other devices should not be affected")
+	}
+}
@@ -0,0 +1,63 @@
+package ws
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow keeps the most recent write durations for percentile queries
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+// Record adds a sample, overwriting the oldest once the window is full
+func (w *latencyWindow) Record(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples[w.next] = d
+	w.next++
+	if w.next == len(w.samples) {
+		w.next = 0
+		w.full = true
+	}
+}
+
+// Percentiles returns the requested percentiles (0-100) of the recorded samples.
+// All values are zero when nothing has been recorded.
+func (w *latencyWindow) Percentiles(ps ...float64) []time.Duration {
+	w.mu.Lock()
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	w.mu.Unlock()
+
+	result := make([]time.Duration, len(ps))
+	if n == 0 {
+		return result
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for i, p := range ps {
+		idx := int(p / 100 * float64(n-1))
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= n {
+			idx = n - 1
+		}
+		result[i] = sorted[idx]
+	}
+	return result
+}
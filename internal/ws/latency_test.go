@@ -0,0 +1,45 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyWindow_Empty(t *testing.T) {
+	w := newLatencyWindow(8)
+
+	p := w.Percentiles(50, 99)
+	if p[0] != 0 || p[1] != 0 {
+		t.Errorf("Expected zero percentiles, got %v", p)
+	}
+}
+
+func TestLatencyWindow_Percentiles(t *testing.T) {
+	w := newLatencyWindow(100)
+	for i := 1; i <= 100; i++ {
+		w.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	p := w.Percentiles(50, 99, 100)
+	if p[0] != 50*time.Millisecond {
+		t.Errorf("Expected p50 50ms, got %v", p[0])
+	}
+	if p[1] != 99*time.Millisecond {
+		t.Errorf("Expected p99 99ms, got %v", p[1])
+	}
+	if p[2] != 100*time.Millisecond {
+		t.Errorf("Expected p100 100ms, got %v", p[2])
+	}
+}
+
+func TestLatencyWindow_OverwritesOldest(t *testing.T) {
+	w := newLatencyWindow(4)
+	w.Record(time.Second)
+	for i := 0; i < 4; i++ {
+		w.Record(time.Millisecond)
+	}
+
+	if max := w.Percentiles(100)[0]; max != time.Millisecond {
+		t.Errorf("Expected old sample to be evicted, got max %v", max)
+	}
+}
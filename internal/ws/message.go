@@ -11,15 +11,20 @@ type MessageType string
 const (
 	// Client -> Server messages
 	MessageTypeJoinRoom     MessageType = "join_room"
+	MessageTypeJoinRooms    MessageType = "join_rooms" // several rooms in one frame
 	MessageTypeLeaveRoom    MessageType = "leave_room"
 	MessageTypeSendMessage  MessageType = "send_message"
 	MessageTypeTyping       MessageType = "typing"
 	MessageTypeStopTyping   MessageType = "stop_typing"
 	MessageTypePing         MessageType = "ping"
 	MessageTypeMarkRead     MessageType = "mark_read"
+	MessageTypeRequestTicket MessageType = "request_reconnect_ticket"
+	MessageTypeResume       MessageType = "resume" // replay messages missed while disconnected
+	MessageTypeRoomPresence MessageType = "room_presence" // members connected to a room; the reply has the same type
 
 	// Server -> Client messages
 	MessageTypeRoomJoined   MessageType = "room_joined"
+	MessageTypeRoomsJoined  MessageType = "rooms_joined" // reply to join_rooms
 	MessageTypeRoomLeft     MessageType = "room_left"
 	MessageTypeNewMessage   MessageType = "new_message"
 	MessageTypeUserTyping   MessageType = "user_typing"
@@ -34,9 +39,69 @@ const (
 	MessageTypeSendDM       MessageType = "send_dm"
 	MessageTypeNewDM        MessageType = "new_dm"
 	MessageTypeDMRead       MessageType = "dm_read"
+	MessageTypeDMUpdated    MessageType = "dm_updated" // the sender edited a message
 
 	// Notification types
 	MessageTypeNotification MessageType = "notification"
+
+	// Read state types
+	MessageTypeReadStateUpdated MessageType = "read_state_updated" // another device read a room or conversation
+
+	// Room lifecycle types
+	MessageTypeRoomDeleting         MessageType = "room_deleting"
+	MessageTypeRoomDeletionCanceled MessageType = "room_deletion_canceled"
+	MessageTypeRoomDeleted          MessageType = "room_deleted"
+	MessageTypeRoomMerged           MessageType = "room_merged"
+
+	// Room moderation types
+	MessageTypeMemberMuted            MessageType = "member_muted"
+	MessageTypeMemberUnmuted          MessageType = "member_unmuted"
+	MessageTypeRoomPermissionsUpdated MessageType = "room_permissions_updated"
+
+	// Message change types
+	MessageTypeMessageUpdated MessageType = "message_updated" // the sender edited a message
+	MessageTypeMessageDeleted MessageType = "message_deleted"
+
+	// Disappearing message types
+	MessageTypeMessageExpired MessageType = "message_expired"
+	MessageTypeDMExpired      MessageType = "dm_expired"
+
+	// Link preview types
+	MessageTypeMessageEmbedUpdated MessageType = "message_embed_updated"
+
+	// Antivirus types
+	MessageTypeUploadScanned MessageType = "upload_scanned"
+
+	// Encrypted direct message types
+	MessageTypeNewEncryptedDM MessageType = "new_encrypted_dm"
+
+	// Group conversation types
+	MessageTypeGroupConversationCreated MessageType = "group_conversation_created"
+	MessageTypeNewGroupMessage          MessageType = "new_group_message"
+	MessageTypeGroupParticipantsAdded   MessageType = "group_participants_added"
+	MessageTypeGroupParticipantRemoved  MessageType = "group_participant_removed"
+
+	// Room event types
+	MessageTypeRoomEventCreated     MessageType = "room_event_created"
+	MessageTypeRoomEventDeleted     MessageType = "room_event_deleted"
+	MessageTypeRoomEventRSVPUpdated MessageType = "room_event_rsvp_updated"
+
+	// Account types
+	MessageTypeAccountBanned  MessageType = "account_banned"
+	MessageTypeSessionRevoked MessageType = "session_revoked"
+
+	// Flood control types
+	MessageTypeFloodWarning MessageType = "flood_warning"
+
+	// Connection types
+	MessageTypeReconnectTicket MessageType = "reconnect_ticket"
+	MessageTypeReconnect       MessageType = "reconnect"
+	MessageTypeWelcome         MessageType = "welcome" // only sent to clients that negotiated capabilities
+	MessageTypeResumed         MessageType = "resumed" // reply to resume
+
+	// Room presence types
+	MessageTypeMemberEntered MessageType = "member_entered" // a member's first connection joined the room
+	MessageTypeMemberLeft    MessageType = "member_left"    // a member's last connection left the room
 )
 
 // Message represents a WebSocket message
@@ -45,6 +110,9 @@ type Message struct {
 	Payload   json.RawMessage `json:"payload,omitempty"`
 	Timestamp time.Time       `json:"timestamp"`
 	RequestID string          `json:"request_id,omitempty"`
+
+	// encoded caches outbound frames; nil for messages read from a peer
+	encoded *encodedFrames
 }
 
 // JoinRoomPayload represents join room payload
@@ -52,6 +120,11 @@ type JoinRoomPayload struct {
 	RoomID string `json:"room_id"`
 }
 
+// JoinRoomsPayload subscribes to several rooms at once
+type JoinRoomsPayload struct {
+	RoomIDs []string `json:"room_ids"`
+}
+
 // LeaveRoomPayload represents leave room payload
 type LeaveRoomPayload struct {
 	RoomID string `json:"room_id"`
@@ -61,8 +134,15 @@ type LeaveRoomPayload struct {
 type SendMessagePayload struct {
 	RoomID    string `json:"room_id"`
 	Content   string `json:"content"`
-	Type      string `json:"type,omitempty"` // text, image, file
+	Type      string `json:"type,omitempty"` // text, image, file, sticker (content is the sticker ID)
 	ReplyToID string `json:"reply_to_id,omitempty"`
+
+	// AttachmentIDs are uploads, made by the sender, attached to the message
+	AttachmentIDs []string `json:"attachment_ids,omitempty"`
+
+	// ContentRef replaces Content with a body uploaded through
+	// POST /api/v1/ws/content, for bodies larger than a frame allows
+	ContentRef string `json:"content_ref,omitempty"`
 }
 
 // TypingPayload represents typing indicator payload
@@ -75,6 +155,9 @@ type SendDMPayload struct {
 	ReceiverID string `json:"receiver_id"`
 	Content    string `json:"content"`
 	Type       string `json:"type,omitempty"`
+	ContentRef string `json:"content_ref,omitempty"` // see SendMessagePayload
+
+	AttachmentIDs []string `json:"attachment_ids,omitempty"` // see SendMessagePayload
 }
 
 // MarkReadPayload represents mark as read payload
@@ -99,10 +182,57 @@ type NewMessagePayload struct {
 	Username    string `json:"username"`
 	DisplayName string `json:"display_name"`
 	AvatarURL   string `json:"avatar_url"`
+	IsBot       bool   `json:"is_bot,omitempty"` // posted through an incoming webhook
 	Content     string `json:"content"`
 	Type        string `json:"type"`
 	ReplyToID   string `json:"reply_to_id,omitempty"`
+	IsNSFW      bool   `json:"is_nsfw,omitempty"` // blur the image until the viewer taps it
 	CreatedAt   string `json:"created_at"`
+
+	ForwardedFrom json.RawMessage `json:"forwarded_from,omitempty"`
+
+	// Sticker is set on sticker messages so clients can draw the sticker
+	// instead of treating it as an image
+	Sticker *StickerPayload `json:"sticker,omitempty"`
+
+	Attachments []*AttachmentPayload `json:"attachments,omitempty"`
+}
+
+// StickerPayload describes the sticker of a sticker message
+type StickerPayload struct {
+	ID       string `json:"id"`
+	PackID   string `json:"pack_id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+}
+
+// AttachmentPayload describes an upload attached to a message
+type AttachmentPayload struct {
+	ID          string `json:"id"`
+	UploadID    string `json:"upload_id"`
+	FileName    string `json:"file_name"`
+	FileURL     string `json:"file_url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	ScanStatus  string `json:"scan_status"` // pending attachments are not rendered until upload_scanned says clean
+}
+
+// RoomsJoinedPayload answers join_rooms with one result per requested room
+type RoomsJoinedPayload struct {
+	Results []JoinRoomResult `json:"results"`
+}
+
+// JoinRoomResult is the outcome of joining one room; on failure Error
+// explains why and the room fields are empty
+type JoinRoomResult struct {
+	RoomID      string `json:"room_id"`
+	Joined      bool   `json:"joined"`
+	RoomName    string `json:"room_name,omitempty"`
+	MemberCount int    `json:"member_count,omitempty"`
+	Code        int    `json:"code,omitempty"`
+	Error       string `json:"error,omitempty"`
 }
 
 // UserTypingPayload represents user typing broadcast
@@ -130,7 +260,10 @@ type NewDMPayload struct {
 	SenderAvatarURL   string `json:"sender_avatar_url"`
 	Content           string `json:"content"`
 	Type              string `json:"type"`
+	IsNSFW            bool   `json:"is_nsfw,omitempty"` // blur the image until the viewer taps it
 	CreatedAt         string `json:"created_at"`
+
+	Attachments []*AttachmentPayload `json:"attachments,omitempty"`
 }
 
 // DMReadPayload represents DM read notification
@@ -157,6 +290,101 @@ type NotificationPayload struct {
 	CreatedAt     string `json:"created_at"`
 }
 
+// AccountBannedPayload is sent right before a banned user is disconnected
+type AccountBannedPayload struct {
+	Reason string `json:"reason"`
+}
+
+// SessionRevokedPayload is sent right before the connections of a revoked
+// device are closed
+type SessionRevokedPayload struct {
+	DeviceID string `json:"device_id"`
+	Reason   string `json:"reason"`
+}
+
+// ResumePayload names the last message the client saw in each room
+type ResumePayload struct {
+	Rooms []ResumeRoom `json:"rooms"`
+}
+
+// ResumeRoom is a room to catch up on
+type ResumeRoom struct {
+	RoomID        string `json:"room_id"`
+	LastMessageID string `json:"last_message_id"`
+}
+
+// ResumedPayload answers resume with one result per room, after the
+// replayed new_message events
+type ResumedPayload struct {
+	Results []ResumeResult `json:"results"`
+}
+
+// ResumeResult says how many messages were replayed in a room. When
+// Complete is false older messages were missed too and the client fetches
+// them with GET /api/v1/rooms/{room_id}/messages?after=.
+type ResumeResult struct {
+	RoomID   string `json:"room_id"`
+	Replayed int    `json:"replayed"`
+	Complete bool   `json:"complete"`
+	Code     int    `json:"code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RoomPresenceQueryPayload asks which members are connected to a room
+type RoomPresenceQueryPayload struct {
+	RoomID string `json:"room_id"`
+}
+
+// RoomPresencePayload lists the members connected to a room
+type RoomPresencePayload struct {
+	RoomID  string          `json:"room_id"`
+	Members []PresentMember `json:"members"`
+}
+
+// PresentMember is a member connected to a room
+type PresentMember struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// MemberPresencePayload announces a member entering or leaving a room
+type MemberPresencePayload struct {
+	RoomID   string `json:"room_id"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// ReconnectTicketPayload carries a single-use ticket for the next connection
+type ReconnectTicketPayload struct {
+	Ticket    string `json:"ticket"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// ReconnectPayload asks the client to reconnect, optionally with a ticket
+type ReconnectPayload struct {
+	Reason    string `json:"reason"`
+	Ticket    string `json:"ticket,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// WelcomePayload is the server's answer to a capability handshake
+type WelcomePayload struct {
+	ProtocolVersion int           `json:"protocol_version"`
+	Features        []string      `json:"features"` // enabled feature flags
+	Events          []MessageType `json:"events"`   // events this connection will receive
+	Encoding        string        `json:"encoding"`
+	MaxPayload      int           `json:"max_payload,omitempty"` // largest frame sent to the client
+	MaxMessageSize  int           `json:"max_message_size"`      // largest message the server accepts
+}
+
+// FloodWarningPayload tells a sender their message was refused for
+// flooding, and whether they are now muted
+type FloodWarningPayload struct {
+	Reason       string `json:"reason"`                // rate, duplicate or muted
+	WarningsLeft int    `json:"warnings_left"`         // refused messages left before a mute
+	MutedUntil   string `json:"muted_until,omitempty"` // RFC3339, while muted
+}
+
 // AckPayload represents acknowledgement
 type AckPayload struct {
 	RequestID string `json:"request_id"`
@@ -175,6 +403,7 @@ func NewMessage(msgType MessageType, payload interface{}) (*Message, error) {
 		Type:      msgType,
 		Payload:   payloadBytes,
 		Timestamp: time.Now(),
+		encoded:   &encodedFrames{},
 	}, nil
 }
 
@@ -0,0 +1,78 @@
+package ws
+
+import (
+	"github.com/go-demo/chat/internal/pkg/metrics"
+)
+
+// Buckets for per-message work, from 100µs to 1s
+var hubLatencyBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, 1}
+
+// hubMetrics holds the histograms the hub observes directly; everything
+// else is read from the hub's own state when scraped
+type hubMetrics struct {
+	broadcastDuration *metrics.Histogram
+	writeDuration     *metrics.Histogram
+}
+
+// SetMetrics registers the hub's connection, queue and latency metrics.
+// Call it before Run.
+func (h *Hub) SetMetrics(registry *metrics.Registry) {
+	h.metrics = &hubMetrics{
+		broadcastDuration: registry.NewHistogram("ws_broadcast_duration_seconds",
+			"Time to encode a room broadcast and queue it for every recipient", hubLatencyBuckets),
+		writeDuration: registry.NewHistogram("ws_write_duration_seconds",
+			"Time to write one frame to a WebSocket connection", hubLatencyBuckets),
+	}
+
+	registry.NewGaugeFunc("ws_connections", "Open WebSocket connections", func() float64 {
+		return float64(h.ConnectionCount())
+	})
+	registry.NewGaugeFunc("ws_online_users", "Users with at least one open connection", func() float64 {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		return float64(len(h.users))
+	})
+	registry.NewGaugeFunc("ws_active_rooms", "Rooms with at least one subscribed connection", func() float64 {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		return float64(len(h.rooms))
+	})
+
+	registry.NewGaugeFunc("ws_broadcast_queue_depth", "Room broadcasts waiting for the hub", func() float64 {
+		return float64(len(h.broadcast))
+	})
+	registry.NewGaugeFunc("ws_direct_queue_depth", "Direct messages waiting for the hub", func() float64 {
+		return float64(len(h.directMessage))
+	})
+	registry.NewGaugeFunc("ws_fanout_queue_depth", "Broadcast batches waiting for a fan-out worker", func() float64 {
+		if h.fanout == nil {
+			return 0
+		}
+		return float64(len(h.fanout.jobs))
+	})
+	registry.NewGaugeFunc("ws_send_queue_depth", "Frames queued on all connections, waiting to be written", func() float64 {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		queued := 0
+		for client := range h.clients {
+			queued += len(client.send)
+		}
+		return float64(queued)
+	})
+
+	registry.NewCounterFunc("ws_dropped_messages_total", "Frames dropped because a connection's queue was full", func() float64 {
+		return float64(h.droppedMessages.Load())
+	})
+	registry.NewCounterFunc("ws_slow_consumer_evictions_total", "Connections closed for not keeping up", func() float64 {
+		return float64(h.slowConsumerEvictions.Load())
+	})
+	registry.NewCounterFunc("ws_write_timeouts_total", "Connections closed after missing the write deadline", func() float64 {
+		return float64(h.writeTimeouts.Load())
+	})
+	registry.NewCounterFunc("ws_oversized_messages_total", "Incoming frames rejected for their size", func() float64 {
+		return float64(h.oversizedMessages.Load())
+	})
+	registry.NewCounterFunc("ws_oversized_frames_total", "Connections closed for sending a frame over the max message size", func() float64 {
+		return float64(h.oversizedFrames.Load())
+	})
+}
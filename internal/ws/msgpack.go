@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/ugorji/go/codec"
+)
+
+// msgpackHandle writes maps with sorted keys so a message always encodes to
+// the same bytes, and reads strings and maps into plain Go types that
+// encoding/json can marshal
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.Canonical = true
+	h.WriteExt = true // str8 and bin types
+	h.RawToString = true
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}()
+
+// payloadJSONHandle reads payloads with whole numbers as integers, which
+// then encode as MessagePack integers rather than floats
+var payloadJSONHandle = func() *codec.JsonHandle {
+	h := &codec.JsonHandle{}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}()
+
+// msgpackFrame is a message as a MessagePack map, with the same keys as
+// the JSON frame and the payload as a nested map rather than text
+type msgpackFrame struct {
+	Type      string      `codec:"type"`
+	Payload   interface{} `codec:"payload,omitempty"`
+	Timestamp string      `codec:"timestamp,omitempty"`
+	RequestID string      `codec:"request_id,omitempty"`
+}
+
+// encodeMsgpack converts the JSON payload, so the MessagePack frame carries
+// the same fields a JSON client would see
+func encodeMsgpack(m *Message) ([]byte, error) {
+	frame := msgpackFrame{
+		Type:      string(m.Type),
+		Timestamp: m.Timestamp.Format(time.RFC3339Nano),
+		RequestID: m.RequestID,
+	}
+	if len(m.Payload) > 0 {
+		if err := codec.NewDecoderBytes(m.Payload, payloadJSONHandle).Decode(&frame.Payload); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	if err := codec.NewEncoderBytes(&data, msgpackHandle).Encode(&frame); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeMsgpack reads a message a client sent as a binary frame. The
+// payload is turned back into JSON for the handlers that parse it.
+func decodeMsgpack(data []byte, m *Message) error {
+	var frame msgpackFrame
+	if err := codec.NewDecoderBytes(data, msgpackHandle).Decode(&frame); err != nil {
+		return err
+	}
+
+	*m = Message{Type: MessageType(frame.Type), RequestID: frame.RequestID}
+	if frame.Payload != nil {
+		payload, err := json.Marshal(frame.Payload)
+		if err != nil {
+			return err
+		}
+		m.Payload = payload
+	}
+	if frame.Timestamp != "" {
+		m.Timestamp, _ = time.Parse(time.RFC3339Nano, frame.Timestamp)
+	}
+	return nil
+}
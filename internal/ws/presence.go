@@ -0,0 +1,100 @@
+package ws
+
+import (
+	"sort"
+)
+
+// addToRoomLocked subscribes a client to a room and reports whether its
+// user was not in the room on any other connection. The caller holds h.mu.
+func (h *Hub) addToRoomLocked(client *Client, roomID string) bool {
+	if h.rooms[roomID] == nil {
+		h.rooms[roomID] = make(map[*Client]bool)
+	}
+	if h.rooms[roomID][client] {
+		return false
+	}
+	h.rooms[roomID][client] = true
+
+	if h.presence == nil {
+		h.presence = make(map[string]map[string]int)
+	}
+	if h.presence[roomID] == nil {
+		h.presence[roomID] = make(map[string]int)
+	}
+	h.presence[roomID][client.userID]++
+	return h.presence[roomID][client.userID] == 1
+}
+
+// removeFromRoomLocked unsubscribes a client from a room and reports
+// whether that was its user's last connection in the room. The caller
+// holds h.mu.
+func (h *Hub) removeFromRoomLocked(client *Client, roomID string) bool {
+	roomClients, ok := h.rooms[roomID]
+	if !ok || !roomClients[client] {
+		return false
+	}
+	delete(roomClients, client)
+	if len(roomClients) == 0 {
+		delete(h.rooms, roomID)
+	}
+
+	users := h.presence[roomID]
+	if users[client.userID] > 1 {
+		users[client.userID]--
+		return false
+	}
+	delete(users, client.userID)
+	if len(users) == 0 {
+		delete(h.presence, roomID)
+	}
+	return true
+}
+
+// presenceBroadcast builds the member_entered or member_left event for a
+// client's user. The client itself is skipped: it knows where it is.
+func presenceBroadcast(client *Client, roomID string, entered bool) *BroadcastMessage {
+	msgType := MessageTypeMemberLeft
+	if entered {
+		msgType = MessageTypeMemberEntered
+	}
+	msg, _ := NewMessage(msgType, &MemberPresencePayload{
+		RoomID:   roomID,
+		UserID:   client.userID,
+		Username: client.username,
+	})
+	return &BroadcastMessage{
+		RoomID:  roomID,
+		Message: msg,
+		Sender:  client,
+	}
+}
+
+// RoomPresence answers a room_presence query with the members connected to
+// this instance and subscribed to the room, one entry per user
+func (h *Hub) RoomPresence(client *Client, roomID, requestID string) {
+	if !client.IsInRoom(roomID) {
+		client.sendError(403, "您尚未加入該聊天室")
+		return
+	}
+
+	h.mu.RLock()
+	seen := make(map[string]bool, len(h.presence[roomID]))
+	members := make([]PresentMember, 0, len(h.presence[roomID]))
+	for member := range h.rooms[roomID] {
+		if seen[member.userID] {
+			continue
+		}
+		seen[member.userID] = true
+		members = append(members, PresentMember{UserID: member.userID, Username: member.username})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(members, func(i, j int) bool { return members[i].Username < members[j].Username })
+
+	presenceMsg, _ := NewMessage(MessageTypeRoomPresence, &RoomPresencePayload{
+		RoomID:  roomID,
+		Members: members,
+	})
+	presenceMsg.RequestID = requestID
+	client.SendMessage(presenceMsg)
+}
@@ -0,0 +1,93 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHub_PresenceCounting(t *testing.T) {
+	hub := createTestHub()
+	phone := createMockClient("user-1", "alice")
+	laptop := createMockClient("user-1", "alice")
+
+	if !hub.addToRoomLocked(phone, "room-1") {
+		t.Error("Expected the first connection to enter the room")
+	}
+	if hub.addToRoomLocked(laptop, "room-1") {
+		t.Error("Expected a second connection not to enter again")
+	}
+	if hub.addToRoomLocked(laptop, "room-1") {
+		t.Error("Expected a repeated join not to count twice")
+	}
+
+	if hub.removeFromRoomLocked(phone, "room-1") {
+		t.Error("Expected the user to stay while another connection remains")
+	}
+	if !hub.removeFromRoomLocked(laptop, "room-1") {
+		t.Error("Expected the last connection to leave the room")
+	}
+	if hub.removeFromRoomLocked(laptop, "room-1") {
+		t.Error("Expected leaving twice to be ignored")
+	}
+	if len(hub.rooms) != 0 || len(hub.presence) != 0 {
+		t.Errorf("Expected empty rooms cleaned up, got %v / %v", hub.rooms, hub.presence)
+	}
+}
+
+func TestHub_RoomPresence(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-2", "bob")
+	client.hub = hub
+	for _, c := range []*Client{client, createMockClient("user-1", "alice"), createMockClient("user-1", "alice")} {
+		hub.addToRoomLocked(c, "room-1")
+		c.JoinRoom("room-1")
+	}
+
+	hub.RoomPresence(client, "room-1", "req-1")
+
+	var received Message
+	if err := json.Unmarshal(<-client.send, &received); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	var payload RoomPresencePayload
+	_ = received.ParsePayload(&payload)
+	if received.Type != MessageTypeRoomPresence || received.RequestID != "req-1" {
+		t.Fatalf("Unexpected reply %s %q", received.Type, received.RequestID)
+	}
+	if len(payload.Members) != 2 || payload.Members[0].Username != "alice" || payload.Members[1].Username != "bob" {
+		t.Errorf("Expected alice and bob once each, got %+v", payload.Members)
+	}
+
+	hub.RoomPresence(client, "room-2", "req-2")
+	if err := json.Unmarshal(<-client.send, &received); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if received.Type != MessageTypeError {
+		t.Errorf("Expected a room not joined refused, got %s", received.Type)
+	}
+}
+
+func TestHub_LeaveRoomAnnouncesLastConnection(t *testing.T) {
+	hub := createTestHub()
+	phone := createMockClient("user-1", "alice")
+	laptop := createMockClient("user-1", "alice")
+	for _, c := range []*Client{phone, laptop} {
+		hub.addToRoomLocked(c, "room-1")
+		c.JoinRoom("room-1")
+	}
+
+	hub.LeaveRoom(phone, "room-1")
+	select {
+	case bm := <-hub.broadcast:
+		t.Fatalf("Expected no member_left while a connection remains, got %s", bm.Message.Type)
+	default:
+	}
+
+	hub.LeaveRoom(laptop, "room-1")
+	bm := <-hub.broadcast
+	var payload MemberPresencePayload
+	_ = bm.Message.ParsePayload(&payload)
+	if bm.Message.Type != MessageTypeMemberLeft || bm.Sender != laptop || payload.UserID != "user-1" || payload.RoomID != "room-1" {
+		t.Errorf("Unexpected broadcast %s %+v", bm.Message.Type, payload)
+	}
+}
@@ -0,0 +1,171 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultReplaySize is how many recent messages each room keeps for replay
+	DefaultReplaySize = 200
+	// DefaultReplayTTL is how long a quiet room's recent messages are kept
+	DefaultReplayTTL = 5 * time.Minute
+
+	replayKeyPrefix = "ws:replay:room:"
+)
+
+// replayedEvents change messages already sent to a room. They are buffered
+// alongside new_message, so a client that resumes also learns that a
+// message it saw, or is about to be replayed, was since edited or removed.
+var replayedEvents = map[MessageType]bool{
+	MessageTypeMessageUpdated:      true,
+	MessageTypeMessageDeleted:      true,
+	MessageTypeMessageExpired:      true,
+	MessageTypeMessageEmbedUpdated: true,
+}
+
+// replayEntry is a stored frame. New messages carry their ID, which clients
+// resume from; events that change a message carry none.
+type replayEntry struct {
+	ID    string          `json:"id,omitempty"`
+	Frame json.RawMessage `json:"frame"`
+}
+
+// ReplayBuffer keeps each room's most recent new_message events, and the
+// events that changed those messages, in a capped Redis list, so a client
+// that reconnects on any instance can catch up on what it missed without
+// refetching over REST
+type ReplayBuffer struct {
+	redis *redis.Client
+	size  int
+	ttl   time.Duration
+}
+
+// NewReplayBuffer creates a replay buffer; non-positive values use
+// DefaultReplaySize and DefaultReplayTTL
+func NewReplayBuffer(redisClient *redis.Client, size int, ttl time.Duration) *ReplayBuffer {
+	if size <= 0 {
+		size = DefaultReplaySize
+	}
+	if ttl <= 0 {
+		ttl = DefaultReplayTTL
+	}
+	return &ReplayBuffer{
+		redis: redisClient,
+		size:  size,
+		ttl:   ttl,
+	}
+}
+
+// Append records a room's new message, or with an empty messageID an event
+// changing one, dropping the oldest beyond the buffer size
+func (b *ReplayBuffer) Append(ctx context.Context, roomID, messageID string, msg *Message) error {
+	frame, err := msg.Encode(EncodingJSON)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&replayEntry{ID: messageID, Frame: frame})
+	if err != nil {
+		return err
+	}
+
+	key := replayKeyPrefix + roomID
+	pipe := b.redis.TxPipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, int64(-b.size), -1)
+	pipe.Expire(ctx, key, b.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append replay entry: %w", err)
+	}
+	return nil
+}
+
+// Since returns the room's messages and message changes after
+// lastMessageID, oldest first. It reports false when lastMessageID is no
+// longer in the buffer, in which case the messages missed cannot all be
+// replayed.
+func (b *ReplayBuffer) Since(ctx context.Context, roomID, lastMessageID string) ([]*Message, bool, error) {
+	entries, err := b.redis.LRange(ctx, replayKeyPrefix+roomID, 0, -1).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read replay entries: %w", err)
+	}
+
+	var missed []*Message
+	found := false
+	for _, raw := range entries {
+		var entry replayEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			continue
+		}
+		if !found {
+			found = entry.ID != "" && entry.ID == lastMessageID
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal(entry.Frame, &msg); err != nil {
+			continue
+		}
+		missed = append(missed, &msg)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return missed, true, nil
+}
+
+// recordReplay keeps a room's new message, or an event changing one, for
+// clients that resume
+func (h *Hub) recordReplay(ctx context.Context, roomID, messageID string, msg *Message) {
+	if h.replay == nil || msg == nil {
+		return
+	}
+	if err := h.replay.Append(ctx, roomID, messageID, msg); err != nil {
+		h.logger.Warn("Failed to record message for replay",
+			zap.String("room_id", roomID),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+	}
+}
+
+// Resume replays the messages a reconnected client missed in the rooms it
+// has joined again, then answers with a resumed frame saying, per room,
+// whether the replay is complete. Rooms whose last seen message has left
+// the buffer must be caught up over REST.
+func (h *Hub) Resume(client *Client, rooms []ResumeRoom, requestID string) {
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
+	defer cancel()
+
+	results := make([]ResumeResult, 0, len(rooms))
+	for _, room := range rooms {
+		result := ResumeResult{RoomID: room.RoomID}
+		switch {
+		case !client.IsInRoom(room.RoomID):
+			result.Code = 403
+			result.Error = "您尚未加入該聊天室"
+		case h.replay != nil:
+			missed, complete, err := h.replay.Since(ctx, room.RoomID, room.LastMessageID)
+			if err != nil {
+				h.logger.Warn("Failed to read messages for replay",
+					zap.String("room_id", room.RoomID),
+					zap.Error(err),
+				)
+			}
+			for _, msg := range missed {
+				client.SendMessage(msg)
+			}
+			result.Replayed = len(missed)
+			result.Complete = complete
+		}
+		results = append(results, result)
+	}
+
+	resumedMsg, _ := NewMessage(MessageTypeResumed, &ResumedPayload{Results: results})
+	resumedMsg.RequestID = requestID
+	client.SendMessage(resumedMsg)
+}
@@ -0,0 +1,137 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func setupTestReplayBuffer(t *testing.T, size int) *ReplayBuffer {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 15})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		t.Skipf("Skipping test, could not connect to test redis: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewReplayBuffer(client, size, time.Minute)
+}
+
+func TestReplayBuffer_Since(t *testing.T) {
+	buffer := setupTestReplayBuffer(t, 3)
+	ctx := context.Background()
+	roomID := fmt.Sprintf("replay-test-%d", time.Now().UnixNano())
+	defer buffer.redis.Del(ctx, replayKeyPrefix+roomID)
+
+	for i := 1; i <= 4; i++ {
+		msg, _ := NewMessage(MessageTypeNewMessage, map[string]string{"id": fmt.Sprintf("m%d", i)})
+		if err := buffer.Append(ctx, roomID, fmt.Sprintf("m%d", i), msg); err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+	}
+
+	missed, complete, err := buffer.Since(ctx, roomID, "m2")
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if !complete || len(missed) != 2 {
+		t.Fatalf("Expected 2 messages replayed completely, got %d, %v", len(missed), complete)
+	}
+	var payload map[string]string
+	_ = missed[0].ParsePayload(&payload)
+	if missed[0].Type != MessageTypeNewMessage || payload["id"] != "m3" {
+		t.Errorf("Expected m3 first, got %s %v", missed[0].Type, payload)
+	}
+
+	if missed, complete, _ := buffer.Since(ctx, roomID, "m4"); !complete || len(missed) != 0 {
+		t.Errorf("Expected nothing missed after the newest message, got %d, %v", len(missed), complete)
+	}
+
+	// The oldest message fell out of the buffer
+	if _, complete, _ := buffer.Since(ctx, roomID, "m1"); complete {
+		t.Error("Expected an incomplete replay for a message no longer buffered")
+	}
+}
+
+func TestHub_Resume(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+	client.JoinRoom("room-1")
+
+	hub.Resume(client, []ResumeRoom{
+		{RoomID: "room-1", LastMessageID: "m1"},
+		{RoomID: "room-2", LastMessageID: "m1"},
+	}, "req-1")
+
+	var received Message
+	if err := json.Unmarshal(<-client.send, &received); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	var payload ResumedPayload
+	_ = received.ParsePayload(&payload)
+	if received.Type != MessageTypeResumed || received.RequestID != "req-1" || len(payload.Results) != 2 {
+		t.Fatalf("Unexpected reply %s %+v", received.Type, payload)
+	}
+
+	// Without a replay buffer nothing can be replayed
+	if r := payload.Results[0]; r.Complete || r.Code != 0 {
+		t.Errorf("Expected an incomplete replay, got %+v", r)
+	}
+	if r := payload.Results[1]; r.Code != 403 {
+		t.Errorf("Expected a room not joined refused, got %+v", r)
+	}
+}
+
+func TestHub_ResumeAfterDelete(t *testing.T) {
+	buffer := setupTestReplayBuffer(t, 10)
+	ctx := context.Background()
+	roomID := fmt.Sprintf("replay-test-%d", time.Now().UnixNano())
+	defer buffer.redis.Del(ctx, replayKeyPrefix+roomID)
+
+	hub := createTestHub()
+	hub.SetReplay(buffer)
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+	client.JoinRoom(roomID)
+
+	for _, id := range []string{"m1", "m2"} {
+		msg, _ := NewMessage(MessageTypeNewMessage, map[string]string{"id": id})
+		if err := buffer.Append(ctx, roomID, id, msg); err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+	}
+	// Deleted while the client was away; the event is buffered with the messages
+	hub.PublishToRoom(roomID, string(MessageTypeMessageDeleted), map[string]string{"message_id": "m1", "room_id": roomID})
+	<-hub.broadcast
+
+	hub.Resume(client, []ResumeRoom{{RoomID: roomID, LastMessageID: "m1"}}, "req-1")
+
+	var types []MessageType
+	for i := 0; i < 3; i++ {
+		var received Message
+		if err := json.Unmarshal(<-client.send, &received); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		types = append(types, received.Type)
+	}
+	want := []MessageType{MessageTypeNewMessage, MessageTypeMessageDeleted, MessageTypeResumed}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, types)
+		}
+	}
+
+	// Change events are not positions a client can resume from
+	if _, complete, _ := buffer.Since(ctx, roomID, ""); complete {
+		t.Error("Expected an empty last message ID not to match a change event")
+	}
+}
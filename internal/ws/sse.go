@@ -0,0 +1,159 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+)
+
+// sseMaxRooms caps the rooms an event stream subscribes to on connect
+const sseMaxRooms = 500
+
+// ServeSSE streams the events a WebSocket client would receive as
+// Server-Sent Events, for clients whose network blocks WebSocket
+// @Summary 即時事件串流（SSE）
+// @Description 無法使用 WebSocket 的用戶端（例如經過企業代理）改以 Server-Sent Events 接收即時事件。連線後自動訂閱所有已加入的聊天室，每個事件以一個 data 欄位送出，內容與 WebSocket 訊框相同；之後加入的聊天室需重新連線才會訂閱。此串流只能接收，發送訊息請使用 REST API
+// @Tags WebSocket
+// @Produce text/event-stream
+// @Param token query string false "JWT Token（EventSource 無法設定標頭時使用）"
+// @Param ticket query string false "重連票證（單次使用，可取代 JWT Token）"
+// @Param events query string false "用戶端可處理的事件類型，以逗號分隔；未宣告時僅收到舊版事件"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/events [get]
+func (h *Handler) ServeSSE(c *gin.Context) {
+	if h.hub.Draining() {
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "此節點維護中，請重新連線"})
+		return
+	}
+
+	caps, err := ParseCapabilities(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的用戶端能力宣告：" + err.Error()})
+		return
+	}
+	if caps.Encoding != EncodingJSON {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "事件串流僅支援 json 編碼"})
+		return
+	}
+
+	session, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	if h.accountChecker != nil {
+		if err := h.accountChecker.CheckAccount(c.Request.Context(), session.UserID); err != nil {
+			c.JSON(apperrors.GetHTTPStatus(err), gin.H{"error": apperrors.GetMessage(err)})
+			return
+		}
+	}
+
+	// A client without a connection: the hub routes to it like any other,
+	// and the stream below writes what it queues
+	client := NewClient(h.hub, nil, session.UserID, session.Username, h.logger)
+	client.SetCapabilities(caps)
+	client.SetClientIP(c.ClientIP())
+	client.SetSession(session.DeviceID, session.ExpiresAt)
+
+	h.hub.register <- client
+	h.hub.joinMemberRooms(client)
+
+	h.hub.streamEvents(c, client)
+}
+
+// joinMemberRooms subscribes a client to the rooms its user is a member of,
+// for clients that cannot send join_room
+func (h *Hub) joinMemberRooms(client *Client) {
+	ctx, cancel := context.WithTimeout(client.Context(), 5*time.Second)
+	rooms, err := h.roomService.ListByUserID(ctx, client.userID, sseMaxRooms, 0)
+	cancel()
+	if err != nil {
+		client.sendError(apperrors.GetHTTPStatus(err), apperrors.GetMessage(err))
+		return
+	}
+
+	roomIDs := make([]string, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = room.ID
+	}
+	h.JoinRooms(client, roomIDs, "")
+}
+
+// streamEvents writes the messages queued for a client as SSE events until
+// the request ends or the hub drops the client, with a comment line as a
+// heartbeat so proxies keep the response open
+func (h *Hub) streamEvents(c *gin.Context, client *Client) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx would otherwise buffer the stream
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	ticker := time.NewTicker(client.pingInterval())
+	defer ticker.Stop()
+
+	write := func(data []byte) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(client.writeTimeout()))
+		if _, err := c.Writer.Write(data); err != nil {
+			client.handleWriteError(err)
+			return false
+		}
+		_ = rc.Flush()
+		return true
+	}
+
+	// Send the headers now so the client sees the stream open
+	c.Writer.WriteHeaderNow()
+	_ = rc.Flush()
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			if !ok {
+				// The hub unregistered the client
+				return
+			}
+			start := time.Now()
+			if !write(sseEvent(message)) {
+				h.unregister <- client
+				return
+			}
+			client.recordWriteLatency(time.Since(start))
+
+		case <-ticker.C:
+			if !write(sseHeartbeat) {
+				h.unregister <- client
+				return
+			}
+
+		case <-c.Request.Context().Done():
+			h.unregister <- client
+			return
+		}
+	}
+}
+
+// sseHeartbeat is a comment line, which EventSource ignores
+var sseHeartbeat = []byte(": ping\n\n")
+
+// sseEvent frames one queued message as an SSE event, each line of it as a
+// data field
+func sseEvent(message []byte) []byte {
+	var buf bytes.Buffer
+	for _, line := range bytes.Split(message, newline) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newSSEContext(ctx context.Context) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/events", nil).WithContext(ctx)
+	return c, w
+}
+
+func TestHub_StreamEvents(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+
+	msg, _ := NewMessage(MessageTypeNewMessage, map[string]string{"content": "hello"})
+	client.SendMessage(msg)
+	client.SendMessage(msg)
+	// The hub closes the queue when it drops the client
+	client.Close()
+
+	c, w := newSSEContext(context.Background())
+	hub.streamEvents(c, client)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+	body := w.Body.String()
+	if n := strings.Count(body, "data: {"); n != 2 {
+		t.Errorf("Expected 2 events, got %d in %q", n, body)
+	}
+	if !strings.Contains(body, `"type":"new_message"`) || !strings.HasSuffix(body, "}\n\n") {
+		t.Errorf("Unexpected stream %q", body)
+	}
+}
+
+func TestHub_StreamEvents_UnregistersOnDisconnect(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := newSSEContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		hub.streamEvents(c, client)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case unregistered := <-hub.unregister:
+		if unregistered != client {
+			t.Error("Expected the streaming client unregistered")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the client unregistered when the request ends")
+	}
+	<-done
+}
+
+func TestSSEEvent(t *testing.T) {
+	got := string(sseEvent([]byte("{\"a\":1}\n{\"b\":2}")))
+	want := "data: {\"a\":1}\ndata: {\"b\":2}\n\n"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
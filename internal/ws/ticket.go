@@ -0,0 +1,131 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultTicketTTL is how long a reconnect ticket stays redeemable
+	DefaultTicketTTL = time.Minute
+
+	ticketKeyPrefix = "ws:ticket:"
+	ticketBytes     = 32
+)
+
+var (
+	// ErrInvalidTicket is returned for unknown, expired or already used tickets
+	ErrInvalidTicket = errors.New("invalid reconnect ticket")
+	// ErrSessionExpired is returned when the access token a connection was
+	// opened with has expired, so no further ticket may be issued for it
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// Ticket identifies the session a reconnect ticket was issued to. A ticket
+// carries the expiry of the access token the session started with, so
+// chaining tickets never keeps a session alive past that token.
+type Ticket struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	DeviceID  string    `json:"device_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// expired reports whether the session's access token has expired; a zero
+// ExpiresAt never expires
+func (t *Ticket) expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// TicketStore issues short-lived, single-use reconnect tickets backed by
+// Redis so any instance can redeem a ticket issued by another
+type TicketStore struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// NewTicketStore creates a ticket store; a non-positive ttl uses DefaultTicketTTL
+func NewTicketStore(redisClient *redis.Client, ttl time.Duration) *TicketStore {
+	if ttl <= 0 {
+		ttl = DefaultTicketTTL
+	}
+	return &TicketStore{
+		redis: redisClient,
+		ttl:   ttl,
+	}
+}
+
+// TTL returns how long issued tickets remain valid
+func (s *TicketStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue creates a ticket for the given session and returns its opaque value.
+// The ticket never outlives the session's ExpiresAt; ErrSessionExpired is
+// returned once that has passed.
+func (s *TicketStore) Issue(ctx context.Context, ticket *Ticket) (string, time.Time, error) {
+	now := time.Now()
+	if ticket.expired(now) {
+		return "", time.Time{}, ErrSessionExpired
+	}
+	ttl := s.ttl
+	if !ticket.ExpiresAt.IsZero() && ticket.ExpiresAt.Sub(now) < ttl {
+		ttl = ticket.ExpiresAt.Sub(now)
+	}
+
+	raw := make([]byte, ticketBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate ticket: %w", err)
+	}
+	value := hex.EncodeToString(raw)
+
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	expiresAt := now.Add(ttl)
+	if err := s.redis.Set(ctx, ticketKey(value), data, ttl).Err(); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to store ticket: %w", err)
+	}
+
+	return value, expiresAt, nil
+}
+
+// Redeem consumes a ticket. A ticket can be redeemed at most once.
+func (s *TicketStore) Redeem(ctx context.Context, value string) (*Ticket, error) {
+	if len(value) != hex.EncodedLen(ticketBytes) {
+		return nil, ErrInvalidTicket
+	}
+
+	data, err := s.redis.GetDel(ctx, ticketKey(value)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrInvalidTicket
+		}
+		return nil, fmt.Errorf("failed to redeem ticket: %w", err)
+	}
+
+	var ticket Ticket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ticket: %w", err)
+	}
+	if ticket.expired(time.Now()) {
+		return nil, ErrInvalidTicket
+	}
+	return &ticket, nil
+}
+
+// ticketKey stores tickets by hash so the keyspace never holds usable values
+func ticketKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return ticketKeyPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	apperrors "github.com/go-demo/chat/internal/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupTestTicketStore(t *testing.T) *TicketStore {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 15})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		t.Skipf("Skipping test, could not connect to test redis: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewTicketStore(client, time.Minute)
+}
+
+func TestTicketStore_SingleUse(t *testing.T) {
+	store := setupTestTicketStore(t)
+	ctx := context.Background()
+
+	value, expiresAt, err := store.Issue(ctx, &Ticket{UserID: "user-1", Username: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to issue ticket: %v", err)
+	}
+	if time.Until(expiresAt) <= 0 {
+		t.Error("Expected ticket to expire in the future")
+	}
+
+	ticket, err := store.Redeem(ctx, value)
+	if err != nil {
+		t.Fatalf("Failed to redeem ticket: %v", err)
+	}
+	if ticket.UserID != "user-1" || ticket.Username != "alice" {
+		t.Errorf("Unexpected ticket: %+v", ticket)
+	}
+
+	if _, err := store.Redeem(ctx, value); err != ErrInvalidTicket {
+		t.Errorf("Expected ErrInvalidTicket on second use, got %v", err)
+	}
+}
+
+func TestTicketStore_SessionExpiry(t *testing.T) {
+	store := setupTestTicketStore(t)
+	ctx := context.Background()
+
+	// A ticket never outlives the access token the session started with
+	sessionEnd := time.Now().Add(2 * time.Second)
+	value, expiresAt, err := store.Issue(ctx, &Ticket{UserID: "user-1", Username: "alice", ExpiresAt: sessionEnd})
+	if err != nil {
+		t.Fatalf("Failed to issue ticket: %v", err)
+	}
+	if expiresAt.After(sessionEnd) {
+		t.Errorf("Expected ticket to expire by %v, got %v", sessionEnd, expiresAt)
+	}
+
+	ticket, err := store.Redeem(ctx, value)
+	if err != nil {
+		t.Fatalf("Failed to redeem ticket: %v", err)
+	}
+	if !ticket.ExpiresAt.Equal(sessionEnd) {
+		t.Errorf("Expected redeemed ticket to carry the session expiry, got %v", ticket.ExpiresAt)
+	}
+}
+
+func TestTicketStore_IssueExpiredSession(t *testing.T) {
+	// Refused before Redis is consulted
+	store := NewTicketStore(redis.NewClient(&redis.Options{Addr: "localhost:0"}), 0)
+
+	_, _, err := store.Issue(context.Background(), &Ticket{UserID: "user-1", ExpiresAt: time.Now().Add(-time.Second)})
+	if err != ErrSessionExpired {
+		t.Errorf("Expected ErrSessionExpired, got %v", err)
+	}
+}
+
+func TestTicketStore_RedeemMalformed(t *testing.T) {
+	// Malformed values are rejected before Redis is consulted
+	store := NewTicketStore(redis.NewClient(&redis.Options{Addr: "localhost:0"}), 0)
+
+	if store.TTL() != DefaultTicketTTL {
+		t.Errorf("Expected default TTL %v, got %v", DefaultTicketTTL, store.TTL())
+	}
+	if _, err := store.Redeem(context.Background(), "not-a-ticket"); err != ErrInvalidTicket {
+		t.Errorf("Expected ErrInvalidTicket, got %v", err)
+	}
+}
+
+func TestHub_IssueReconnectTicket_Disabled(t *testing.T) {
+	hub := createTestHub()
+	client := createMockClient("user-1", "alice")
+	client.hub = hub
+
+	hub.IssueReconnectTicket(client, "req-1")
+
+	var msg Message
+	_ = json.Unmarshal(<-client.send, &msg)
+	if msg.Type != MessageTypeError {
+		t.Errorf("Expected error message without a ticket store, got %s", msg.Type)
+	}
+}
+
+type fakeDeviceChecker struct {
+	revoked map[string]bool
+}
+
+func (f *fakeDeviceChecker) CheckDevice(ctx context.Context, userID, deviceID string) error {
+	if f.revoked[deviceID] {
+		return apperrors.ErrDeviceNotFound
+	}
+	return nil
+}
+
+func TestHub_IssueReconnectTicket_EndedSession(t *testing.T) {
+	hub := createTestHub()
+	hub.SetTickets(NewTicketStore(redis.NewClient(&redis.Options{Addr: "localhost:0"}), 0))
+	hub.SetDeviceChecker(&fakeDeviceChecker{revoked: map[string]bool{"device-1": true}})
+
+	tests := []struct {
+		name      string
+		deviceID  string
+		expiresAt time.Time
+	}{
+		{"revoked device", "device-1", time.Now().Add(time.Hour)},
+		{"expired access token", "device-2", time.Now().Add(-time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := createMockClient("user-1", "alice")
+			client.hub = hub
+			client.SetSession(tt.deviceID, tt.expiresAt)
+
+			hub.IssueReconnectTicket(client, "req-1")
+
+			var msg Message
+			_ = json.Unmarshal(<-client.send, &msg)
+			var payload ErrorPayload
+			_ = json.Unmarshal(msg.Payload, &payload)
+			if msg.Type != MessageTypeError || payload.Code != 401 {
+				t.Errorf("Expected a 401 error, got %s %+v", msg.Type, payload)
+			}
+		})
+	}
+}
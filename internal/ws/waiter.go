@@ -0,0 +1,64 @@
+package ws
+
+import "sync"
+
+// roomWaiters holds the long-poll requests waiting for a room's next message
+type roomWaiters struct {
+	mu    sync.Mutex
+	rooms map[string]map[chan struct{}]struct{}
+}
+
+func (w *roomWaiters) add(roomID string) (chan struct{}, func()) {
+	ch := make(chan struct{})
+
+	w.mu.Lock()
+	if w.rooms == nil {
+		w.rooms = make(map[string]map[chan struct{}]struct{})
+	}
+	if w.rooms[roomID] == nil {
+		w.rooms[roomID] = make(map[chan struct{}]struct{})
+	}
+	w.rooms[roomID][ch] = struct{}{}
+	w.mu.Unlock()
+
+	return ch, func() { w.remove(roomID, ch) }
+}
+
+func (w *roomWaiters) remove(roomID string, ch chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.rooms[roomID], ch)
+	if len(w.rooms[roomID]) == 0 {
+		delete(w.rooms, roomID)
+	}
+}
+
+// wake releases every request waiting on a room; each waits only once
+func (w *roomWaiters) wake(roomID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for ch := range w.rooms[roomID] {
+		close(ch)
+	}
+	delete(w.rooms, roomID)
+}
+
+func (w *roomWaiters) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+	for _, waiting := range w.rooms {
+		n += len(waiting)
+	}
+	return n
+}
+
+// WaitForMessage returns a channel closed when the next new_message event
+// is broadcast to a room on this instance, and a function that stops
+// waiting, which must be called when the caller gives up
+func (h *Hub) WaitForMessage(roomID string) (<-chan struct{}, func()) {
+	return h.waiters.add(roomID)
+}
@@ -0,0 +1,58 @@
+package ws
+
+import "testing"
+
+func TestHub_WaitForMessage(t *testing.T) {
+	hub := createTestHub()
+
+	wake, stop := hub.WaitForMessage("room-1")
+	defer stop()
+	other, stopOther := hub.WaitForMessage("room-2")
+	defer stopOther()
+
+	typing, _ := NewMessage(MessageTypeUserTyping, &UserTypingPayload{RoomID: "room-1"})
+	hub.broadcastToRoom(&BroadcastMessage{RoomID: "room-1", Message: typing})
+	select {
+	case <-wake:
+		t.Fatal("Expected only new messages to wake the room")
+	default:
+	}
+
+	msg, _ := NewMessage(MessageTypeNewMessage, map[string]string{"room_id": "room-1"})
+	hub.broadcastToRoom(&BroadcastMessage{RoomID: "room-1", Message: msg})
+	select {
+	case <-wake:
+	default:
+		t.Fatal("Expected the waiter woken without clients in the room")
+	}
+	select {
+	case <-other:
+		t.Error("Expected other rooms to keep waiting")
+	default:
+	}
+
+	if n := hub.GetStats()["long_poll_waiters"]; n != 1 {
+		t.Errorf("Expected 1 waiter left, got %d", n)
+	}
+}
+
+func TestHub_WaitForMessage_Stop(t *testing.T) {
+	hub := createTestHub()
+
+	_, stop := hub.WaitForMessage("room-1")
+	kept, stopKept := hub.WaitForMessage("room-1")
+	defer stopKept()
+	stop()
+	stop()
+
+	if n := hub.waiters.count(); n != 1 {
+		t.Fatalf("Expected 1 waiter, got %d", n)
+	}
+	msg, _ := NewMessage(MessageTypeNewMessage, map[string]string{"room_id": "room-1"})
+	hub.broadcastToRoom(&BroadcastMessage{RoomID: "room-1", Message: msg})
+	select {
+	case <-kept:
+	default:
+		t.Error("Expected the remaining waiter woken")
+	}
+}
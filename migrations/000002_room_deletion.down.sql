@@ -0,0 +1,6 @@
+-- 刪除索引
+DROP INDEX IF EXISTS idx_rooms_deletion_scheduled_at;
+
+-- 刪除欄位
+ALTER TABLE rooms DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE rooms DROP COLUMN IF EXISTS deletion_scheduled_at;
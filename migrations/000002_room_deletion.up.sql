@@ -0,0 +1,7 @@
+-- 聊天室兩階段刪除：排定刪除時間與軟刪除時間
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- 排定刪除索引（供背景工作掃描到期的聊天室）
+CREATE INDEX IF NOT EXISTS idx_rooms_deletion_scheduled_at ON rooms(deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL;
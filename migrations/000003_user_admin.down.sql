@@ -0,0 +1,2 @@
+-- 刪除系統管理員標記
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
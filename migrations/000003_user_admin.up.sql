@@ -0,0 +1,2 @@
+-- 系統管理員標記
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,13 @@
+-- 刪除索引
+DROP INDEX IF EXISTS idx_messages_content_trgm;
+
+-- 刪除觸發器與函數
+DROP TRIGGER IF EXISTS update_messages_search_vector ON messages;
+DROP FUNCTION IF EXISTS update_message_search_vector();
+
+-- 刪除表
+DROP TABLE IF EXISTS message_search;
+DROP TABLE IF EXISTS search_settings;
+
+-- 中文分詞設定（擴充套件保留，可能被其他資料庫物件使用）
+DROP TEXT SEARCH CONFIGURATION IF EXISTS chinese_zh;
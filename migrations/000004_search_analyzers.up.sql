@@ -0,0 +1,58 @@
+-- 全文檢索設定（單列表，由應用程式依設定檔同步）
+CREATE TABLE IF NOT EXISTS search_settings (
+    id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    config REGCONFIG NOT NULL DEFAULT 'simple',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO search_settings (id, config) VALUES (1, 'simple') ON CONFLICT (id) DO NOTHING;
+
+-- 中文分詞（僅在伺服器安裝對應擴充套件時建立）
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'zhparser') THEN
+        CREATE EXTENSION IF NOT EXISTS zhparser;
+        IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'chinese_zh') THEN
+            CREATE TEXT SEARCH CONFIGURATION chinese_zh (PARSER = zhparser);
+            ALTER TEXT SEARCH CONFIGURATION chinese_zh ADD MAPPING FOR n, v, a, i, e, l WITH simple;
+        END IF;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'pg_jieba') THEN
+        CREATE EXTENSION IF NOT EXISTS pg_jieba; -- 提供 jiebacfg
+    END IF;
+END
+$$;
+
+-- 訊息全文索引表（獨立於 messages，避免影響既有查詢欄位）
+CREATE TABLE IF NOT EXISTS message_search (
+    message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    search_vector TSVECTOR NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_search_vector ON message_search USING GIN (search_vector);
+
+-- 訊息寫入時依目前設定產生 tsvector
+CREATE OR REPLACE FUNCTION update_message_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO message_search (message_id, search_vector)
+    VALUES (NEW.id, to_tsvector((SELECT config FROM search_settings WHERE id = 1), COALESCE(NEW.content, '')))
+    ON CONFLICT (message_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_messages_search_vector
+    AFTER INSERT OR UPDATE OF content ON messages
+    FOR EACH ROW
+    EXECUTE FUNCTION update_message_search_vector();
+
+-- 既有訊息回填
+INSERT INTO message_search (message_id, search_vector)
+SELECT id, to_tsvector('simple', COALESCE(content, '')) FROM messages
+ON CONFLICT (message_id) DO NOTHING;
+
+-- ILIKE 搜尋（預設，CJK 友善）使用三元組索引
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_messages_content_trgm ON messages USING GIN (content gin_trgm_ops);
@@ -0,0 +1,2 @@
+-- 刪除用戶停權紀錄
+DROP TABLE IF EXISTS user_bans;
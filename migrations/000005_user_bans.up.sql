@@ -0,0 +1,16 @@
+-- 用戶停權紀錄（expires_at 為空表示永久封禁，否則為暫時停權）
+CREATE TABLE IF NOT EXISTS user_bans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    banned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_bans_user_id ON user_bans(user_id, created_at DESC);
+
+-- 每位用戶同時只會有一筆未撤銷的停權
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_bans_active ON user_bans(user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,2 @@
+-- 刪除稽核紀錄
+DROP TABLE IF EXISTS audit_logs;
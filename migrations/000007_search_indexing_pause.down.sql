@@ -0,0 +1,14 @@
+-- 還原為一律同步建立索引
+CREATE OR REPLACE FUNCTION update_message_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO message_search (message_id, search_vector)
+    VALUES (NEW.id, to_tsvector((SELECT config FROM search_settings WHERE id = 1), COALESCE(NEW.content, '')))
+    ON CONFLICT (message_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+ALTER TABLE search_settings
+    DROP COLUMN IF EXISTS indexing_paused_at,
+    DROP COLUMN IF EXISTS indexing_enabled;
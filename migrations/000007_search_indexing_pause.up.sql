@@ -0,0 +1,21 @@
+-- 依賴服務降級時可暫停寫入路徑上的全文索引，恢復後補建
+ALTER TABLE search_settings
+    ADD COLUMN IF NOT EXISTS indexing_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS indexing_paused_at TIMESTAMP WITH TIME ZONE;
+
+CREATE OR REPLACE FUNCTION update_message_search_vector()
+RETURNS TRIGGER AS $$
+DECLARE
+    settings search_settings%ROWTYPE;
+BEGIN
+    SELECT * INTO settings FROM search_settings WHERE id = 1;
+    IF NOT settings.indexing_enabled THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO message_search (message_id, search_vector)
+    VALUES (NEW.id, to_tsvector(settings.config, COALESCE(NEW.content, '')))
+    ON CONFLICT (message_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
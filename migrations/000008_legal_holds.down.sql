@@ -0,0 +1,2 @@
+-- 刪除法律保全
+DROP TABLE IF EXISTS legal_holds;
@@ -0,0 +1,15 @@
+-- 法律保全：保全期間暫停對應聊天室／用戶資料的清除
+CREATE TABLE IF NOT EXISTS legal_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('room', 'user')),
+    target_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    placed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    released_at TIMESTAMP WITH TIME ZONE, -- NULL 表示保全中
+    released_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 同一對象同時只能有一筆保全中的紀錄
+CREATE UNIQUE INDEX IF NOT EXISTS idx_legal_holds_active
+    ON legal_holds(target_type, target_id) WHERE released_at IS NULL;
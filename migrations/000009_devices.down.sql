@@ -0,0 +1,3 @@
+-- 刪除裝置與 Refresh Token 紀錄
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS user_devices;
@@ -0,0 +1,2 @@
+-- 刪除聊天室加入申請
+DROP TABLE IF EXISTS room_join_requests;
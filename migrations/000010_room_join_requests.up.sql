@@ -0,0 +1,17 @@
+-- 私人聊天室加入申請：非成員提出申請，由房主或管理員審核
+CREATE TABLE IF NOT EXISTS room_join_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 同一用戶對同一聊天室同時只能有一筆待審核申請
+CREATE UNIQUE INDEX IF NOT EXISTS idx_room_join_requests_pending
+    ON room_join_requests(room_id, user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_room_join_requests_room
+    ON room_join_requests(room_id, created_at) WHERE status = 'pending';
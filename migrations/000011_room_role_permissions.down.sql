@@ -0,0 +1,2 @@
+-- 刪除聊天室角色權限覆寫
+DROP TABLE IF EXISTS room_role_permissions;
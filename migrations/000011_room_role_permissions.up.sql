@@ -0,0 +1,10 @@
+-- 聊天室角色權限覆寫：未列出的項目沿用系統預設權限矩陣
+CREATE TABLE IF NOT EXISTS room_role_permissions (
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (room_id, role, action)
+);
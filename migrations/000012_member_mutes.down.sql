@@ -0,0 +1,4 @@
+-- 移除成員禁言期限
+DROP INDEX IF EXISTS idx_room_members_muted_until;
+ALTER TABLE room_members DROP COLUMN IF EXISTS muted_by;
+ALTER TABLE room_members DROP COLUMN IF EXISTS muted_until;
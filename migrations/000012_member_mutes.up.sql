@@ -0,0 +1,7 @@
+-- 成員禁言期限：muted_until 為 NULL 時表示無限期禁言
+ALTER TABLE room_members ADD COLUMN IF NOT EXISTS muted_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE room_members ADD COLUMN IF NOT EXISTS muted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- 背景工作依到期時間解除禁言
+CREATE INDEX IF NOT EXISTS idx_room_members_muted_until
+    ON room_members(muted_until) WHERE is_muted = TRUE AND muted_until IS NOT NULL;
@@ -0,0 +1,2 @@
+-- 刪除 IP 軟封鎖
+DROP TABLE IF EXISTS ip_bans;
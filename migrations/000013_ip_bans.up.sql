@@ -0,0 +1,12 @@
+-- IP 軟封鎖：管理員封鎖的 IP 或網段，expires_at 為 NULL 時表示永久封鎖
+CREATE TABLE IF NOT EXISTS ip_bans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cidr CIDR NOT NULL,
+    reason TEXT NOT NULL,
+    tarpit BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_bans_expires_at ON ip_bans(expires_at) WHERE expires_at IS NOT NULL;
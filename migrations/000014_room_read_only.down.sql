@@ -0,0 +1,2 @@
+-- 移除公告聊天室設定
+ALTER TABLE rooms DROP COLUMN IF EXISTS read_only;
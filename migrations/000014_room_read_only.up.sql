@@ -0,0 +1,2 @@
+-- 公告聊天室：啟用後僅擁有者與管理員可發言
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS read_only BOOLEAN NOT NULL DEFAULT FALSE;
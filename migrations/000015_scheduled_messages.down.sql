@@ -0,0 +1,2 @@
+-- 移除排程訊息
+DROP TABLE IF EXISTS scheduled_messages;
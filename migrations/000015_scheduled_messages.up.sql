@@ -0,0 +1,22 @@
+-- 排程訊息：到期後由背景工作透過一般發送流程送出
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'text',
+    reply_to_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sending', 'sent', 'failed', 'canceled')),
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    error TEXT,
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+-- 背景工作依排程時間取出待發送訊息
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due
+    ON scheduled_messages(scheduled_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_room_user
+    ON scheduled_messages(room_id, user_id, scheduled_at) WHERE status = 'pending';
@@ -0,0 +1,7 @@
+-- 移除限時訊息設定
+DROP INDEX IF EXISTS idx_direct_messages_expires_at;
+DROP INDEX IF EXISTS idx_messages_expires_at;
+ALTER TABLE direct_messages DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS expires_at;
+DROP TABLE IF EXISTS direct_conversation_settings;
+ALTER TABLE rooms DROP COLUMN IF EXISTS message_ttl_seconds;
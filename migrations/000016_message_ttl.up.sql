@@ -0,0 +1,22 @@
+-- 限時訊息：聊天室與私訊對話可設定訊息存活秒數，0 表示不啟用
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS message_ttl_seconds INT NOT NULL DEFAULT 0;
+
+-- 私訊對話設定，以兩位用戶 ID 排序後作為主鍵，雙方共用
+CREATE TABLE IF NOT EXISTS direct_conversation_settings (
+    user_low UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_high UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_ttl_seconds INT NOT NULL DEFAULT 0,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_low, user_high),
+    CHECK (user_low < user_high)
+);
+
+-- 訊息建立時依設定寫入到期時間，背景工作到期後將其軟刪除
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE direct_messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_messages_expires_at
+    ON messages(expires_at) WHERE expires_at IS NOT NULL AND is_deleted = FALSE;
+CREATE INDEX IF NOT EXISTS idx_direct_messages_expires_at
+    ON direct_messages(expires_at) WHERE expires_at IS NOT NULL;
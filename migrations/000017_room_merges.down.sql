@@ -0,0 +1,4 @@
+-- 移除聊天室合併
+ALTER TABLE messages DROP COLUMN IF EXISTS merged_from_room_id;
+ALTER TABLE rooms DROP COLUMN IF EXISTS merged_into_id;
+DROP TABLE IF EXISTS room_merges;
@@ -0,0 +1,28 @@
+-- 聊天室合併：管理員將來源聊天室併入目標聊天室，由背景工作分批搬移，可中斷後續跑
+CREATE TABLE IF NOT EXISTS room_merges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    source_room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    target_room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    include_history BOOLEAN NOT NULL DEFAULT TRUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    members_moved INT NOT NULL DEFAULT 0,
+    messages_moved INT NOT NULL DEFAULT 0,
+    error TEXT,
+    merged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CHECK (source_room_id <> target_room_id)
+);
+
+-- 同一聊天室同時只能有一筆進行中的合併
+CREATE UNIQUE INDEX IF NOT EXISTS idx_room_merges_active_source
+    ON room_merges(source_room_id) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_room_merges_active
+    ON room_merges(created_at) WHERE status IN ('pending', 'running');
+
+-- 合併完成後舊聊天室指向新聊天室，讓舊連結可轉址
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS merged_into_id UUID REFERENCES rooms(id) ON DELETE SET NULL;
+
+-- 搬移過來的訊息記錄原聊天室
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS merged_from_room_id UUID REFERENCES rooms(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+-- 移除訊息轉傳來源
+ALTER TABLE direct_messages DROP COLUMN IF EXISTS forwarded_from;
+ALTER TABLE messages DROP COLUMN IF EXISTS forwarded_from;
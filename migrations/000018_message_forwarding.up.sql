@@ -0,0 +1,3 @@
+-- 訊息轉傳：記錄轉傳來源（來源種類、訊息、聊天室與原作者）
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded_from JSONB;
+ALTER TABLE direct_messages ADD COLUMN IF NOT EXISTS forwarded_from JSONB;
@@ -0,0 +1,2 @@
+-- 移除聊天室訂閱設定
+ALTER TABLE rooms DROP COLUMN IF EXISTS feed_enabled;
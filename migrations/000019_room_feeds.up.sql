@@ -0,0 +1,2 @@
+-- 聊天室 RSS/Atom 訂閱：僅公開聊天室可啟用
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS feed_enabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- 移除可續傳上傳
+DROP TABLE IF EXISTS upload_sessions;
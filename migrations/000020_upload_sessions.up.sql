@@ -0,0 +1,21 @@
+-- 可續傳上傳：用戶端分段送出檔案，中斷後可查詢進度並從斷點繼續
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(20) NOT NULL CHECK (category IN ('image', 'file', 'avatar')),
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    total_size BIGINT NOT NULL CHECK (total_size > 0),
+    received_size BIGINT NOT NULL DEFAULT 0,
+    stored_name VARCHAR(255),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- 列出用戶未完成的上傳
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_pending
+    ON upload_sessions(user_id, created_at) WHERE completed_at IS NULL;
+-- 背景工作清除過期的上傳
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at ON upload_sessions(expires_at);
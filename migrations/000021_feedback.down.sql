@@ -0,0 +1,2 @@
+-- 移除用戶回饋
+DROP TABLE IF EXISTS feedback;
@@ -0,0 +1,24 @@
+-- 用戶回饋與問題回報，管理員可分類處理，並可轉送至外部追蹤系統
+CREATE TABLE IF NOT EXISTS feedback (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    category VARCHAR(20) NOT NULL CHECK (category IN ('bug', 'suggestion', 'other')),
+    text TEXT NOT NULL,
+    screenshot_url VARCHAR(500),
+    app_version VARCHAR(50),
+    client VARCHAR(100),
+    user_agent VARCHAR(500),
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'in_progress', 'resolved', 'dismissed')),
+    triage_note TEXT,
+    triaged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    triaged_at TIMESTAMP WITH TIME ZONE,
+    forwarded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 管理員依狀態列出待處理的回饋
+CREATE INDEX IF NOT EXISTS idx_feedback_status_created_at ON feedback(status, created_at DESC);
+-- 背景工作轉送尚未送出的回饋
+CREATE INDEX IF NOT EXISTS idx_feedback_unforwarded ON feedback(created_at) WHERE forwarded_at IS NULL;
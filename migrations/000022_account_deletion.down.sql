@@ -0,0 +1,2 @@
+-- 移除帳號刪除標記
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,2 @@
+-- 帳號刪除：刪除後保留匿名化的用戶列，讓保留下來的訊息仍有作者
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
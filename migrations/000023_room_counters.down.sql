@@ -0,0 +1,8 @@
+-- 移除聊天室計數器
+DROP TRIGGER IF EXISTS update_messages_room_count ON messages;
+DROP TRIGGER IF EXISTS update_room_members_count ON room_members;
+DROP TRIGGER IF EXISTS create_rooms_counters ON rooms;
+DROP FUNCTION IF EXISTS update_room_message_count();
+DROP FUNCTION IF EXISTS update_room_member_count();
+DROP FUNCTION IF EXISTS create_room_counters();
+DROP TABLE IF EXISTS room_counters;
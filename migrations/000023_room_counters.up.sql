@@ -0,0 +1,77 @@
+-- 聊天室計數器：成員數與訊息數由觸發器增量維護，避免每次查詢都 COUNT(*)
+-- 獨立成表，計數變動不會更新 rooms.updated_at
+CREATE TABLE IF NOT EXISTS room_counters (
+    room_id UUID PRIMARY KEY REFERENCES rooms(id) ON DELETE CASCADE,
+    member_count INTEGER NOT NULL DEFAULT 0,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    reconciled_at TIMESTAMP WITH TIME ZONE
+);
+
+-- 定期校正依 reconciled_at 由舊到新挑選
+CREATE INDEX IF NOT EXISTS idx_room_counters_reconciled_at ON room_counters(reconciled_at NULLS FIRST);
+
+-- 建立聊天室時一併建立計數列
+CREATE OR REPLACE FUNCTION create_room_counters()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO room_counters (room_id) VALUES (NEW.id) ON CONFLICT (room_id) DO NOTHING;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER create_rooms_counters
+    AFTER INSERT ON rooms
+    FOR EACH ROW
+    EXECUTE FUNCTION create_room_counters();
+
+-- 只更新既有的計數列：聊天室刪除時連帶刪除的成員與訊息不需要再計數
+CREATE OR REPLACE FUNCTION update_room_member_count()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.room_id = OLD.room_id THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE room_counters SET member_count = member_count + 1 WHERE room_id = NEW.room_id;
+    END IF;
+    IF TG_OP IN ('DELETE', 'UPDATE') THEN
+        UPDATE room_counters SET member_count = member_count - 1 WHERE room_id = OLD.room_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_room_members_count
+    AFTER INSERT OR DELETE OR UPDATE OF room_id ON room_members
+    FOR EACH ROW
+    EXECUTE FUNCTION update_room_member_count();
+
+CREATE OR REPLACE FUNCTION update_room_message_count()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.room_id = OLD.room_id THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE room_counters SET message_count = message_count + 1 WHERE room_id = NEW.room_id;
+    END IF;
+    IF TG_OP IN ('DELETE', 'UPDATE') THEN
+        UPDATE room_counters SET message_count = message_count - 1 WHERE room_id = OLD.room_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_messages_room_count
+    AFTER INSERT OR DELETE OR UPDATE OF room_id ON messages
+    FOR EACH ROW
+    EXECUTE FUNCTION update_room_message_count();
+
+-- 既有聊天室回填
+INSERT INTO room_counters (room_id, member_count, message_count, reconciled_at)
+SELECT r.id,
+    (SELECT COUNT(*) FROM room_members rm WHERE rm.room_id = r.id),
+    (SELECT COUNT(*) FROM messages m WHERE m.room_id = r.id),
+    NOW()
+FROM rooms r
+ON CONFLICT (room_id) DO NOTHING;
@@ -0,0 +1,8 @@
+-- 移除圖片審核
+DROP TRIGGER IF EXISTS set_direct_messages_nsfw ON direct_messages;
+DROP TRIGGER IF EXISTS set_messages_nsfw ON messages;
+DROP FUNCTION IF EXISTS set_message_nsfw();
+ALTER TABLE direct_messages DROP COLUMN IF EXISTS is_nsfw;
+ALTER TABLE messages DROP COLUMN IF EXISTS is_nsfw;
+DROP TABLE IF EXISTS image_assets;
+DROP TABLE IF EXISTS image_moderation_settings;
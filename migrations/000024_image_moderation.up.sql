@@ -0,0 +1,64 @@
+-- 圖片審核設定（單列表，由管理員調整）
+-- 分數介於 nsfw_threshold 與 block_threshold 之間的圖片標記為 NSFW 並送交人工審核，
+-- 達到 block_threshold 的圖片直接拒絕
+CREATE TABLE IF NOT EXISTS image_moderation_settings (
+    id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    nsfw_threshold DOUBLE PRECISION NOT NULL DEFAULT 0.6,
+    block_threshold DOUBLE PRECISION NOT NULL DEFAULT 0.9,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (nsfw_threshold > 0 AND nsfw_threshold <= block_threshold AND block_threshold <= 1)
+);
+
+INSERT INTO image_moderation_settings (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+-- 已掃描的上傳圖片與判定結果
+CREATE TABLE IF NOT EXISTS image_assets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    file_url TEXT NOT NULL UNIQUE,
+    file_path TEXT NOT NULL, -- 伺服器上的儲存位置，封鎖時用來刪除檔案
+    content_type VARCHAR(255) NOT NULL,
+    score DOUBLE PRECISION, -- 偵測失敗時為 NULL
+    labels TEXT NOT NULL DEFAULT '', -- 以逗號分隔的偵測類別
+    verdict VARCHAR(20) NOT NULL CHECK (verdict IN ('clean', 'nsfw', 'blocked')),
+    review_status VARCHAR(20) NOT NULL DEFAULT 'none' CHECK (review_status IN ('none', 'pending', 'reviewed')),
+    review_note TEXT,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 審核佇列：待審的圖片依上傳先後排列
+CREATE INDEX IF NOT EXISTS idx_image_assets_pending
+    ON image_assets(created_at) WHERE review_status = 'pending';
+
+-- 訊息上的模糊標記，用戶端據此先模糊顯示圖片
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS is_nsfw BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE direct_messages ADD COLUMN IF NOT EXISTS is_nsfw BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 圖片訊息寫入時依圖片判定設定模糊標記
+CREATE OR REPLACE FUNCTION set_message_nsfw()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.type = 'image' THEN
+        NEW.is_nsfw := EXISTS (
+            SELECT 1 FROM image_assets WHERE file_url = NEW.content AND verdict IN ('nsfw', 'blocked')
+        );
+    ELSE
+        NEW.is_nsfw := FALSE;
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER set_messages_nsfw
+    BEFORE INSERT OR UPDATE OF content, type ON messages
+    FOR EACH ROW
+    EXECUTE FUNCTION set_message_nsfw();
+
+CREATE TRIGGER set_direct_messages_nsfw
+    BEFORE INSERT OR UPDATE OF content, type ON direct_messages
+    FOR EACH ROW
+    EXECUTE FUNCTION set_message_nsfw();
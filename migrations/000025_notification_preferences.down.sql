@@ -0,0 +1,5 @@
+-- 移除通知偏好
+DROP TRIGGER IF EXISTS delete_dm_notification_preferences ON users;
+DROP TRIGGER IF EXISTS delete_room_notification_preferences ON rooms;
+DROP FUNCTION IF EXISTS delete_notification_preferences();
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,32 @@
+-- 用戶的通知偏好（依聊天室或私訊對象設定）
+-- 未設定的對象維持預設：所有提及、回覆與私訊都會通知
+-- level: mentions = 只通知提及（僅限聊天室），none = 靜音
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type VARCHAR(10) NOT NULL CHECK (target_type IN ('room', 'dm')),
+    target_id UUID NOT NULL, -- 聊天室 ID 或私訊對象的用戶 ID
+    level VARCHAR(10) NOT NULL CHECK (level IN ('mentions', 'none')),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, target_type, target_id),
+    CHECK (target_type = 'room' OR level = 'none')
+);
+
+-- 聊天室或用戶刪除時一併移除指向它的偏好
+CREATE OR REPLACE FUNCTION delete_notification_preferences()
+RETURNS TRIGGER AS $$
+BEGIN
+    DELETE FROM notification_preferences
+    WHERE target_type = TG_ARGV[0] AND target_id = OLD.id;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS delete_room_notification_preferences ON rooms;
+CREATE TRIGGER delete_room_notification_preferences
+    AFTER DELETE ON rooms
+    FOR EACH ROW EXECUTE FUNCTION delete_notification_preferences('room');
+
+DROP TRIGGER IF EXISTS delete_dm_notification_preferences ON users;
+CREATE TRIGGER delete_dm_notification_preferences
+    AFTER DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION delete_notification_preferences('dm');
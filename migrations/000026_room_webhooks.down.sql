@@ -0,0 +1,3 @@
+-- 移除聊天室 webhook
+DROP TABLE IF EXISTS room_webhook_deliveries;
+DROP TABLE IF EXISTS room_webhooks;
@@ -0,0 +1,34 @@
+-- 聊天室對外 webhook：由聊天室管理員登記，新訊息與成員異動時以簽章的 HTTP POST 通知
+CREATE TABLE IF NOT EXISTS room_webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL, -- HMAC-SHA256 簽章金鑰，僅於建立時回傳
+    events TEXT[] NOT NULL, -- 訂閱的事件：message.created, member.joined, member.left
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_webhooks_room_id ON room_webhooks(room_id);
+
+-- 每次事件對每個 webhook 的投遞紀錄，失敗時以指數退避重試
+CREATE TABLE IF NOT EXISTS room_webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES room_webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(), -- 投遞中時為租約到期時間
+    last_status_code INT, -- 最近一次回應的 HTTP 狀態碼，連線失敗時為 NULL
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 背景工作取出到期的投遞
+CREATE INDEX IF NOT EXISTS idx_room_webhook_deliveries_due ON room_webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+-- 依 webhook 查詢投遞紀錄
+CREATE INDEX IF NOT EXISTS idx_room_webhook_deliveries_webhook ON room_webhook_deliveries(webhook_id, created_at DESC);
+-- 清除超過保留期限的已結束投遞
+CREATE INDEX IF NOT EXISTS idx_room_webhook_deliveries_settled ON room_webhook_deliveries(created_at) WHERE status <> 'pending';
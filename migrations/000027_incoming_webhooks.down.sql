@@ -0,0 +1,3 @@
+-- 移除傳入 webhook 與機器人標記
+DROP TABLE IF EXISTS incoming_webhooks;
+ALTER TABLE users DROP COLUMN IF EXISTS is_bot;
@@ -0,0 +1,15 @@
+-- 機器人帳號：由傳入 webhook 建立，無法登入
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 傳入 webhook：持有權杖的外部服務可用機器人身分在聊天室發送訊息
+CREATE TABLE IF NOT EXISTS incoming_webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    bot_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL, -- 權杖的 SHA-256，權杖本身僅於建立時回傳
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_webhooks_room_id ON incoming_webhooks(room_id);
@@ -0,0 +1,4 @@
+-- 移除聊天室活躍度彙總
+DROP TRIGGER IF EXISTS record_messages_room_activity ON messages;
+DROP FUNCTION IF EXISTS record_room_activity();
+DROP TABLE IF EXISTS room_activity_hourly;
@@ -0,0 +1,36 @@
+-- 聊天室每小時訊息數彙總，供「最活躍時段」熱度圖使用，避免每次查詢都掃描訊息表
+-- 只累加不扣減：訊息刪除後，當時的活躍度仍然算數
+CREATE TABLE IF NOT EXISTS room_activity_hourly (
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    message_count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (room_id, hour)
+);
+
+-- 定期清除超過保留期間的彙總
+CREATE INDEX IF NOT EXISTS idx_room_activity_hourly_hour ON room_activity_hourly(hour);
+
+-- 系統訊息（加入、離開等）不算活躍度
+CREATE OR REPLACE FUNCTION record_room_activity()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO room_activity_hourly (room_id, hour, message_count)
+    VALUES (NEW.room_id, date_trunc('hour', NEW.created_at), 1)
+    ON CONFLICT (room_id, hour) DO UPDATE SET message_count = room_activity_hourly.message_count + 1;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER record_messages_room_activity
+    AFTER INSERT ON messages
+    FOR EACH ROW
+    WHEN (NEW.type <> 'system')
+    EXECUTE FUNCTION record_room_activity();
+
+-- 回填最近 12 週（熱度圖可查詢的上限）
+INSERT INTO room_activity_hourly (room_id, hour, message_count)
+SELECT room_id, date_trunc('hour', created_at), COUNT(*)
+FROM messages
+WHERE type <> 'system' AND created_at >= NOW() - INTERVAL '12 weeks'
+GROUP BY room_id, date_trunc('hour', created_at)
+ON CONFLICT (room_id, hour) DO NOTHING;
@@ -0,0 +1,2 @@
+-- 移除私訊對話匯出
+DROP TABLE IF EXISTS dm_exports;
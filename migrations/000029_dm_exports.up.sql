@@ -0,0 +1,23 @@
+-- 私訊對話匯出：參與者申請後由背景工作產生檔案，完成後通知下載
+CREATE TABLE IF NOT EXISTS dm_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    peer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    stored_name VARCHAR(255),
+    message_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 同一對話同時只能有一個進行中的匯出
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dm_exports_in_progress
+    ON dm_exports(user_id, peer_id) WHERE status IN ('pending', 'running');
+-- 背景工作領取待處理的匯出
+CREATE INDEX IF NOT EXISTS idx_dm_exports_pending ON dm_exports(created_at) WHERE status = 'pending';
+-- 背景工作清除過期的匯出檔
+CREATE INDEX IF NOT EXISTS idx_dm_exports_expires_at ON dm_exports(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- 移除上傳限制設定
+DROP TABLE IF EXISTS upload_settings;
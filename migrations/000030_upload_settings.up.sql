@@ -0,0 +1,15 @@
+-- 上傳限制設定（單列表，由管理員調整）
+-- 欄位為 NULL 時沿用設定檔中的值
+CREATE TABLE IF NOT EXISTS upload_settings (
+    id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    image_max_size BIGINT CHECK (image_max_size > 0),
+    image_allowed_types TEXT[],
+    file_max_size BIGINT CHECK (file_max_size > 0),
+    file_allowed_types TEXT[],
+    avatar_max_size BIGINT CHECK (avatar_max_size > 0),
+    avatar_allowed_types TEXT[],
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO upload_settings (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
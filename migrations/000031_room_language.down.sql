@@ -0,0 +1,2 @@
+-- 移除聊天室語言
+ALTER TABLE rooms DROP COLUMN IF EXISTS language;
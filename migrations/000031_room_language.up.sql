@@ -0,0 +1,2 @@
+-- 聊天室語言：伺服器產生的系統訊息以此語言呈現
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS language VARCHAR(20) NOT NULL DEFAULT 'zh-TW';
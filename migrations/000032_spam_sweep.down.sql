@@ -0,0 +1,3 @@
+-- 移除垃圾帳號掃描
+DROP TABLE IF EXISTS spam_sweep_runs;
+DROP TABLE IF EXISTS spam_flags;
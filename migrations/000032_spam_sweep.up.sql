@@ -0,0 +1,23 @@
+-- 垃圾帳號掃描標記的帳號，每位用戶只標記一次，之後的掃描會略過
+CREATE TABLE IF NOT EXISTS spam_flags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    score INT NOT NULL,
+    signals TEXT[] NOT NULL DEFAULT '{}', -- 命中的訊號，例如 no_messages、disposable_email
+    action VARCHAR(20) NOT NULL CHECK (action IN ('flagged', 'suspended')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_spam_flags_created_at ON spam_flags(created_at DESC);
+
+-- 每次掃描的結果，供管理員統計使用
+CREATE TABLE IF NOT EXISTS spam_sweep_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    scanned INT NOT NULL DEFAULT 0,
+    flagged INT NOT NULL DEFAULT 0,
+    suspended INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_spam_sweep_runs_started_at ON spam_sweep_runs(started_at DESC);
@@ -0,0 +1,2 @@
+-- 移除聊天室發言頻率上限
+ALTER TABLE rooms DROP COLUMN IF EXISTS message_rate_limit;
@@ -0,0 +1,2 @@
+-- 聊天室發言頻率上限：每位成員每分鐘可發送的訊息數，0 表示沿用全站預設
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS message_rate_limit INT NOT NULL DEFAULT 0 CHECK (message_rate_limit >= 0);
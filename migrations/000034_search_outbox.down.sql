@@ -0,0 +1,6 @@
+-- 移除搜尋引擎 outbox
+DROP TRIGGER IF EXISTS enqueue_messages_search_outbox ON messages;
+DROP FUNCTION IF EXISTS enqueue_search_outbox();
+DROP TABLE IF EXISTS search_outbox;
+
+ALTER TABLE search_settings DROP COLUMN IF EXISTS outbox_enabled;
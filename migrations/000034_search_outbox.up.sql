@@ -0,0 +1,31 @@
+-- 外部搜尋引擎（Elasticsearch / OpenSearch）啟用時，訊息異動記入 outbox，由背景工作非同步同步到索引
+ALTER TABLE search_settings
+    ADD COLUMN IF NOT EXISTS outbox_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS search_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    message_id UUID NOT NULL, -- 不設外鍵：訊息刪除後仍需從索引移除
+    claimed_until TIMESTAMP WITH TIME ZONE, -- 處理中的租約，到期未完成則由其他實例接手
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION enqueue_search_outbox()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NOT (SELECT outbox_enabled FROM search_settings WHERE id = 1) THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO search_outbox (message_id) VALUES (OLD.id);
+    ELSE
+        INSERT INTO search_outbox (message_id) VALUES (NEW.id);
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER enqueue_messages_search_outbox
+    AFTER INSERT OR UPDATE OF content, is_deleted, room_id OR DELETE ON messages
+    FOR EACH ROW
+    EXECUTE FUNCTION enqueue_search_outbox();
@@ -0,0 +1,6 @@
+-- 移除端對端加密私訊
+DROP TABLE IF EXISTS direct_message_envelopes;
+DROP TABLE IF EXISTS device_one_time_prekeys;
+DROP TABLE IF EXISTS device_keys;
+
+DELETE FROM direct_messages WHERE type = 'ciphertext';
@@ -0,0 +1,35 @@
+-- 端對端加密私訊：每個裝置的公開金鑰（身分金鑰與簽章預金鑰），伺服器只保存公開部分
+CREATE TABLE IF NOT EXISTS device_keys (
+    device_id UUID PRIMARY KEY REFERENCES user_devices(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    registration_id INTEGER NOT NULL,
+    identity_key TEXT NOT NULL, -- Base64
+    signed_prekey_id INTEGER NOT NULL,
+    signed_prekey TEXT NOT NULL,
+    signed_prekey_signature TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_keys_user_id ON device_keys(user_id);
+
+-- 一次性預金鑰，取得預金鑰組時領取並刪除
+CREATE TABLE IF NOT EXISTS device_one_time_prekeys (
+    device_id UUID NOT NULL REFERENCES device_keys(device_id) ON DELETE CASCADE,
+    key_id INTEGER NOT NULL,
+    public_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (device_id, key_id)
+);
+
+-- 加密私訊的內容不經伺服器解讀，每個接收裝置各有一份密文
+CREATE TABLE IF NOT EXISTS direct_message_envelopes (
+    message_id UUID NOT NULL REFERENCES direct_messages(id) ON DELETE CASCADE,
+    device_id UUID NOT NULL REFERENCES user_devices(id) ON DELETE CASCADE,
+    sender_device_id UUID NOT NULL,
+    type SMALLINT NOT NULL, -- 1 一般訊息、3 含預金鑰的首則訊息
+    ciphertext TEXT NOT NULL, -- Base64
+    PRIMARY KEY (message_id, device_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_direct_message_envelopes_device_id ON direct_message_envelopes(device_id);
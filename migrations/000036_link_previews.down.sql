@@ -0,0 +1,2 @@
+-- 移除連結預覽
+ALTER TABLE messages DROP COLUMN IF EXISTS embeds;
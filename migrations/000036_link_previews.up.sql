@@ -0,0 +1,2 @@
+-- 連結預覽：訊息中網址的 Open Graph 資訊（標題、描述、圖片），由背景工作抓取後寫入
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS embeds JSONB;
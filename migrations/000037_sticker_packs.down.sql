@@ -0,0 +1,6 @@
+-- 移除貼圖包
+UPDATE messages SET type = 'text', content = '[貼圖]' WHERE type = 'sticker';
+UPDATE scheduled_messages SET type = 'text', content = '[貼圖]' WHERE type = 'sticker';
+
+DROP TABLE IF EXISTS stickers;
+DROP TABLE IF EXISTS sticker_packs;
@@ -0,0 +1,24 @@
+-- 貼圖包：由管理員維護的精選貼圖，下架的貼圖包不再提供新訊息使用
+CREATE TABLE IF NOT EXISTS sticker_packs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 貼圖訊息的 content 為貼圖 ID
+CREATE TABLE IF NOT EXISTS stickers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pack_id UUID NOT NULL REFERENCES sticker_packs(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    image_url VARCHAR(500) NOT NULL,
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stickers_pack_id ON stickers(pack_id, position);
@@ -0,0 +1,3 @@
+-- 移除訊息內容審查
+DROP TABLE IF EXISTS message_flags;
+ALTER TABLE rooms DROP COLUMN IF EXISTS moderation_level;
@@ -0,0 +1,22 @@
+-- 聊天室內容審查等級：off 不檢查、standard 遮蔽不當用語、strict 拒絕含不當用語的訊息並以較低門檻標記
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS moderation_level VARCHAR(10) NOT NULL DEFAULT 'standard'
+    CHECK (moderation_level IN ('off', 'standard', 'strict'));
+
+-- 被審查 API 標記、等待管理員審核的訊息
+CREATE TABLE IF NOT EXISTS message_flags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason VARCHAR(20) NOT NULL, -- classifier, scan_failed
+    score DOUBLE PRECISION, -- 審查 API 無法判定時為 NULL
+    labels TEXT NOT NULL DEFAULT '', -- 以逗號分隔
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'removed')),
+    review_note TEXT,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_flags_pending ON message_flags(created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_message_flags_message_id ON message_flags(message_id);
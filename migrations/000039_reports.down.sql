@@ -0,0 +1,2 @@
+-- 移除用戶檢舉
+DROP TABLE IF EXISTS reports;
@@ -0,0 +1,27 @@
+-- 用戶對訊息或其他用戶的檢舉，由管理員分派處理
+CREATE TABLE IF NOT EXISTS reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reporter_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    target_type VARCHAR(10) NOT NULL CHECK (target_type IN ('message', 'user')),
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    room_id UUID REFERENCES rooms(id) ON DELETE SET NULL,
+    reported_user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- 被檢舉的用戶或訊息作者
+    message_content TEXT, -- 檢舉當下的訊息內容，訊息之後被編輯或刪除仍可查證
+    category VARCHAR(20) NOT NULL
+        CHECK (category IN ('spam', 'harassment', 'hate', 'sexual', 'violence', 'impersonation', 'other')),
+    note TEXT,
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'reviewing', 'resolved')),
+    resolution_note TEXT,
+    handled_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    handled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 管理員依狀態列出待處理的檢舉
+CREATE INDEX IF NOT EXISTS idx_reports_status_created_at ON reports(status, created_at);
+-- 同一用戶對同一訊息或用戶只能有一筆未結案的檢舉
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_message ON reports(reporter_id, message_id)
+    WHERE target_type = 'message' AND status <> 'resolved';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_user ON reports(reporter_id, reported_user_id)
+    WHERE target_type = 'user' AND status <> 'resolved';
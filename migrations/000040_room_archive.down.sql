@@ -0,0 +1,2 @@
+-- 移除聊天室封存
+ALTER TABLE rooms DROP COLUMN IF EXISTS archived_at;
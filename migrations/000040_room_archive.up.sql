@@ -0,0 +1,2 @@
+-- 聊天室封存：封存後不再出現在列表中、無法發送新訊息，成員仍可閱讀歷史訊息，房主可解除封存
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
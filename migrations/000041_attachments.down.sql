@@ -0,0 +1,21 @@
+-- 移除附件與上傳紀錄，還原舊的訊息附件表
+CREATE TABLE IF NOT EXISTS message_attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    file_url VARCHAR(500) NOT NULL,
+    file_type VARCHAR(100),
+    file_size BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_attachments_message_id ON message_attachments(message_id);
+
+INSERT INTO message_attachments (message_id, file_name, file_url, file_type, file_size, created_at)
+SELECT a.message_id, u.file_name, u.file_url, u.content_type, u.size, a.created_at
+FROM attachments a
+JOIN uploads u ON u.id = a.upload_id
+WHERE a.message_id IS NOT NULL;
+
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS uploads;
@@ -0,0 +1,47 @@
+-- 上傳的檔案：圖片與一般檔案（不含頭像），未被任何訊息附加的檔案會在保留期限後清除
+CREATE TABLE IF NOT EXISTS uploads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    category VARCHAR(20) NOT NULL CHECK (category IN ('image', 'file')),
+    file_name VARCHAR(255) NOT NULL,
+    file_url TEXT NOT NULL UNIQUE,
+    file_path TEXT NOT NULL, -- 伺服器上的儲存位置，清除時用來刪除檔案
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id);
+CREATE INDEX IF NOT EXISTS idx_uploads_created_at ON uploads(created_at);
+
+-- 附件：上傳的檔案與聊天室訊息或私訊的關聯，同一檔案可附加於多則訊息（例如轉傳）
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    upload_id UUID NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES messages(id) ON DELETE CASCADE,
+    direct_message_id UUID REFERENCES direct_messages(id) ON DELETE CASCADE,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((message_id IS NULL) <> (direct_message_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_upload_id ON attachments(upload_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attachments_message
+    ON attachments(message_id, upload_id) WHERE message_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attachments_direct_message
+    ON attachments(direct_message_id, upload_id) WHERE direct_message_id IS NOT NULL;
+
+-- 舊的訊息附件表改存於 uploads 與 attachments，原檔案位置未知，file_path 留空
+INSERT INTO uploads (user_id, category, file_name, file_url, file_path, content_type, size, created_at)
+SELECT DISTINCT ON (a.file_url) m.user_id, 'file', a.file_name, a.file_url, '', COALESCE(a.file_type, ''), COALESCE(a.file_size, 0), a.created_at
+FROM message_attachments a
+JOIN messages m ON m.id = a.message_id
+ORDER BY a.file_url, a.created_at
+ON CONFLICT (file_url) DO NOTHING;
+
+INSERT INTO attachments (upload_id, message_id, created_at)
+SELECT DISTINCT ON (a.message_id, u.id) u.id, a.message_id, a.created_at
+FROM message_attachments a
+JOIN uploads u ON u.file_url = a.file_url;
+
+DROP TABLE IF EXISTS message_attachments;
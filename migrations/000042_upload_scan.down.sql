@@ -0,0 +1,7 @@
+-- 移除上傳檔案的病毒掃描狀態
+DROP INDEX IF EXISTS idx_uploads_scan_pending;
+
+ALTER TABLE uploads
+    DROP COLUMN IF EXISTS scanned_at,
+    DROP COLUMN IF EXISTS scan_signature,
+    DROP COLUMN IF EXISTS scan_status;
@@ -0,0 +1,9 @@
+-- 上傳檔案的病毒掃描狀態：unscanned（未啟用掃描或舊檔案）、pending（等待掃描）、clean、infected（已隔離）
+ALTER TABLE uploads
+    ADD COLUMN IF NOT EXISTS scan_status VARCHAR(20) NOT NULL DEFAULT 'unscanned'
+        CHECK (scan_status IN ('unscanned', 'pending', 'clean', 'infected')),
+    ADD COLUMN IF NOT EXISTS scan_signature TEXT, -- 偵測到的惡意程式名稱
+    ADD COLUMN IF NOT EXISTS scanned_at TIMESTAMP WITH TIME ZONE;
+
+-- 重新排入掃描時只查詢等待中的檔案
+CREATE INDEX IF NOT EXISTS idx_uploads_scan_pending ON uploads(created_at) WHERE scan_status = 'pending';
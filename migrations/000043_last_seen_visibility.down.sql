@@ -0,0 +1,3 @@
+-- 移除最後上線時間的隱私設定
+ALTER TABLE users
+    DROP COLUMN IF EXISTS last_seen_visibility;
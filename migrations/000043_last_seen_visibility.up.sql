@@ -0,0 +1,4 @@
+-- 誰可以在個人資料看到用戶的最後上線時間：everyone（所有人）、friends（僅好友）、nobody（不公開）
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS last_seen_visibility VARCHAR(10) NOT NULL DEFAULT 'everyone'
+        CHECK (last_seen_visibility IN ('everyone', 'friends', 'nobody'));
@@ -0,0 +1,9 @@
+-- 移除通訊錄比對
+DROP INDEX IF EXISTS idx_users_email_hash;
+
+DROP TRIGGER IF EXISTS update_users_email_hash ON users;
+DROP FUNCTION IF EXISTS update_user_email_hash();
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS discoverable,
+    DROP COLUMN IF EXISTS email_hash;
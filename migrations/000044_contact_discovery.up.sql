@@ -0,0 +1,23 @@
+-- 通訊錄比對：以 email 的 SHA-256（去除前後空白並轉小寫後計算，十六進位）搜尋用戶，不需傳送原始 email
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_hash CHAR(64),
+    ADD COLUMN IF NOT EXISTS discoverable BOOLEAN NOT NULL DEFAULT TRUE; -- 用戶可關閉，不被通訊錄比對找到
+
+-- email 寫入或變更時重新計算雜湊
+CREATE OR REPLACE FUNCTION update_user_email_hash()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.email_hash = encode(sha256(convert_to(lower(btrim(NEW.email)), 'UTF8')), 'hex');
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_users_email_hash
+    BEFORE INSERT OR UPDATE OF email ON users
+    FOR EACH ROW
+    EXECUTE FUNCTION update_user_email_hash();
+
+-- 既有用戶回填
+UPDATE users SET email_hash = encode(sha256(convert_to(lower(btrim(email)), 'UTF8')), 'hex');
+
+CREATE INDEX IF NOT EXISTS idx_users_email_hash ON users(email_hash) WHERE discoverable = TRUE AND deleted_at IS NULL;
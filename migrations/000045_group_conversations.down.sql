@@ -0,0 +1,5 @@
+-- 移除群組對話
+DROP TABLE IF EXISTS group_messages;
+DROP TABLE IF EXISTS group_conversation_participants;
+DROP TRIGGER IF EXISTS update_group_conversations_updated_at ON group_conversations;
+DROP TABLE IF EXISTS group_conversations;
@@ -0,0 +1,37 @@
+-- 群組對話：3 至 10 人的多人私訊，不屬於任何聊天室
+CREATE TABLE IF NOT EXISTS group_conversations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100), -- 未命名時用戶端以成員名稱顯示
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL, -- 建立者可移除其他成員
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_group_conversations_updated_at
+    BEFORE UPDATE ON group_conversations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- 群組對話成員，last_read_at 之後他人傳送的訊息即為未讀
+CREATE TABLE IF NOT EXISTS group_conversation_participants (
+    conversation_id UUID NOT NULL REFERENCES group_conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_read_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_participants_user_id ON group_conversation_participants(user_id);
+
+-- 群組對話訊息
+CREATE TABLE IF NOT EXISTS group_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID NOT NULL REFERENCES group_conversations(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    type VARCHAR(20) DEFAULT 'text', -- text, image, file
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_messages_conversation ON group_messages(conversation_id, created_at DESC);
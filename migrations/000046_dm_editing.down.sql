@@ -0,0 +1,2 @@
+-- 移除私訊的編輯標記
+ALTER TABLE direct_messages DROP COLUMN IF EXISTS is_edited;
@@ -0,0 +1,2 @@
+-- 私訊是否被寄件者編輯過
+ALTER TABLE direct_messages ADD COLUMN IF NOT EXISTS is_edited BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- 移除私訊對話的個人狀態
+DROP TABLE IF EXISTS conversation_settings;
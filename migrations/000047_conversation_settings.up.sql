@@ -0,0 +1,19 @@
+-- 私訊對話的個人狀態，只影響該用戶自己的對話列表
+-- archived_at：封存，移至封存列表直到取消
+-- hidden_at：隱藏，對方在此之後傳來新訊息時自動重新顯示
+-- pinned_at：釘選，排在列表最前面，較晚釘選的在前
+CREATE TABLE IF NOT EXISTS conversation_settings (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    other_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    archived_at TIMESTAMP WITH TIME ZONE,
+    hidden_at TIMESTAMP WITH TIME ZONE,
+    pinned_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, other_user_id)
+);
+
+CREATE TRIGGER update_conversation_settings_updated_at
+    BEFORE UPDATE ON conversation_settings
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,6 @@
+-- 移除未讀摘要信設定
+DROP INDEX IF EXISTS idx_users_digest;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS digest_sent_at,
+    DROP COLUMN IF EXISTS digest_frequency;
@@ -0,0 +1,9 @@
+-- 未讀摘要信：用戶自行選擇 off（不寄送）、daily（每日）或 weekly（每週）
+-- digest_sent_at 記錄上次寄送（或檢查後無新動態）的時間，下次摘要只包含此後的動態
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS digest_frequency VARCHAR(10) NOT NULL DEFAULT 'off'
+        CHECK (digest_frequency IN ('off', 'daily', 'weekly')),
+    ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_digest ON users(digest_sent_at NULLS FIRST)
+    WHERE digest_frequency <> 'off' AND deleted_at IS NULL;
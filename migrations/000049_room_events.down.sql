@@ -0,0 +1,3 @@
+-- 移除聊天室活動
+DROP TABLE IF EXISTS room_event_rsvps;
+DROP TABLE IF EXISTS room_events;
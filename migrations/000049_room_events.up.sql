@@ -0,0 +1,41 @@
+-- 聊天室活動：成員建立的行事曆活動，可回覆是否參加，開始前提醒並提供 iCal 訂閱
+CREATE TABLE IF NOT EXISTS room_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL, -- 建立者可刪除活動
+    title VARCHAR(200) NOT NULL,
+    description TEXT,
+    location VARCHAR(200),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE, -- 空值表示未指定結束時間
+    reminder_sent_at TIMESTAMP WITH TIME ZONE, -- 已送出開始前提醒的時間
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_events_room_id ON room_events(room_id, starts_at);
+-- 背景提醒只掃描尚未提醒的活動
+CREATE INDEX IF NOT EXISTS idx_room_events_reminder ON room_events(starts_at) WHERE reminder_sent_at IS NULL;
+
+CREATE TRIGGER update_room_events_updated_at
+    BEFORE UPDATE ON room_events
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- 活動回覆：going（參加）、maybe（可能參加）、declined（不參加）
+CREATE TABLE IF NOT EXISTS room_event_rsvps (
+    event_id UUID NOT NULL REFERENCES room_events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('going', 'maybe', 'declined')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_event_rsvps_user_id ON room_event_rsvps(user_id);
+
+CREATE TRIGGER update_room_event_rsvps_updated_at
+    BEFORE UPDATE ON room_event_rsvps
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,3 @@
+-- 移除企業單一登入的身分對應與群組授予的聊天室紀錄
+DROP TABLE IF EXISTS sso_room_grants;
+DROP TABLE IF EXISTS external_identities;
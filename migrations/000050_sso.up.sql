@@ -0,0 +1,20 @@
+-- 企業單一登入：身分提供者（LDAP / SAML）的使用者與本地帳號的對應，首次登入時自動建立帳號
+CREATE TABLE IF NOT EXISTS external_identities (
+    provider VARCHAR(20) NOT NULL,
+    -- LDAP 為使用者條目的 DN，SAML 為 NameID
+    subject TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_login_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_identities_user_id ON external_identities(user_id);
+
+-- 依群組對應規則加入的聊天室；使用者離開群組時只移除這些成員資格，不影響自行加入的聊天室
+CREATE TABLE IF NOT EXISTS sso_room_grants (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, room_id)
+);
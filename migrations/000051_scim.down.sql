@@ -0,0 +1,4 @@
+-- 移除 SCIM 佈建的帳號狀態與群組
+DROP TABLE IF EXISTS scim_group_members;
+DROP TABLE IF EXISTS scim_groups;
+DROP TABLE IF EXISTS scim_users;
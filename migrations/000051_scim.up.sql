@@ -0,0 +1,32 @@
+-- SCIM 佈建：身分提供者管理的帳號狀態；沒有紀錄的帳號視為啟用
+CREATE TABLE IF NOT EXISTS scim_users (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    external_id VARCHAR(255),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    -- 經由 SCIM 刪除：帳號保留但停用，且不再出現在 SCIM 查詢中
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scim_users_external_id ON scim_users(external_id) WHERE external_id IS NOT NULL;
+
+-- 身分提供者推送的群組，依群組對應規則同步成員至聊天室
+CREATE TABLE IF NOT EXISTS scim_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    display_name VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scim_groups_display_name ON scim_groups(LOWER(display_name));
+
+CREATE TABLE IF NOT EXISTS scim_group_members (
+    group_id UUID NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_group_members_user_id ON scim_group_members(user_id);
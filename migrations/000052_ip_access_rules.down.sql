@@ -0,0 +1,2 @@
+-- 移除 IP 存取規則
+DROP TABLE IF EXISTS ip_access_rules;
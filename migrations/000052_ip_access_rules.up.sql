@@ -0,0 +1,13 @@
+-- IP 存取規則：在全站（global）或特定路由群組允許或拒絕 IP 網段或國家
+-- 範圍內有允許規則時，只有符合允許規則的來源可以存取；拒絕規則優先
+CREATE TABLE IF NOT EXISTS ip_access_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scope VARCHAR(20) NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('allow', 'deny')),
+    cidr CIDR,
+    country CHAR(2),
+    note TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((cidr IS NULL) <> (country IS NULL))
+);
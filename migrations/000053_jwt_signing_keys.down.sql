@@ -0,0 +1,2 @@
+-- 移除 JWT 簽章金鑰，回到僅使用設定檔中的 JWT 密鑰
+DROP TABLE IF EXISTS jwt_signing_keys;
@@ -0,0 +1,12 @@
+-- JWT 簽章金鑰：管理員輪替後以最新且已生效的金鑰簽發 Token，舊金鑰在被取代後保留一個 Refresh Token 效期以驗證既有 Token
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- 以 jwt.key_encryption_key 加密（AES-256-GCM），僅能讀取資料庫者無法偽造 Token
+    sealed_secret TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- 生效前先讓所有實例載入，避免其他實例無法驗證新金鑰簽發的 Token
+    activates_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_jwt_signing_keys_activates_at ON jwt_signing_keys(activates_at);
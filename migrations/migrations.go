@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migrations so they ship with the binary
+package migrations
+
+import "embed"
+
+// FS holds the NNNNNN_name.up.sql and NNNNNN_name.down.sql files
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,145 @@
+package chatclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Admin calls need a signed-in administrator; the server answers 403 to
+// anyone else.
+
+// NewUser is an account for CreateUsers to create
+type NewUser struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`     // user (default) or admin
+	Password string `json:"password,omitempty"` // empty generates one, which takes an invitation
+}
+
+// UserImportResult is the outcome of creating one account
+type UserImportResult struct {
+	Row      int    `json:"row"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Status   string `json:"status"` // created, skipped or failed
+	UserID   string `json:"user_id,omitempty"`
+	Invited  bool   `json:"invited"`
+	Error    string `json:"error,omitempty"`
+}
+
+// UserImport summarizes CreateUsers
+type UserImport struct {
+	Total   int                 `json:"total"`
+	Created int                 `json:"created"`
+	Skipped int                 `json:"skipped"`
+	Failed  int                 `json:"failed"`
+	Results []*UserImportResult `json:"results"`
+}
+
+// JobRun is one run of a background job
+type JobRun struct {
+	ID         string    `json:"id"`
+	Job        string    `json:"job"`
+	Status     string    `json:"status"`
+	Items      int64     `json:"items"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Job is a background job as reported by the instance that answered
+type Job struct {
+	Name     string  `json:"name"`
+	Interval string  `json:"interval"`
+	Runs     int64   `json:"runs"`
+	Failures int64   `json:"failures"`
+	Items    int64   `json:"items"`
+	Running  bool    `json:"running"`
+	LastRun  *JobRun `json:"last_run,omitempty"`
+}
+
+// JWTKey is a rotated JWT signing key
+type JWTKey struct {
+	ID          string `json:"id"`
+	CreatedBy   string `json:"created_by,omitempty"`
+	ActivatesAt string `json:"activates_at"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// CreateUsers creates accounts, optionally emailing each their credentials.
+// Rows are independent; check each result's Status.
+func (c *Client) CreateUsers(ctx context.Context, users []*NewUser, sendInvitations bool) (*UserImport, error) {
+	body := map[string]interface{}{
+		"users":            users,
+		"send_invitations": sendInvitations,
+	}
+
+	var result UserImport
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/admin/users/import", nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetAdmin grants or revokes a user's administrator role
+func (c *Client) SetAdmin(ctx context.Context, userID string, isAdmin bool) error {
+	method := http.MethodPut
+	if !isAdmin {
+		method = http.MethodDelete
+	}
+	_, err := c.do(ctx, method, "/api/v1/admin/users/"+url.PathEscape(userID)+"/admin", nil, nil, nil)
+	return err
+}
+
+// ListAllRooms lists every room, private and archived ones included. An
+// empty roomType lists all types.
+func (c *Client) ListAllRooms(ctx context.Context, roomType string, page Page) ([]*Room, *Meta, error) {
+	q := page.values()
+	if roomType != "" {
+		q.Set("type", roomType)
+	}
+
+	var rooms []*Room
+	meta, err := c.do(ctx, http.MethodGet, "/api/v1/admin/rooms", q, nil, &rooms)
+	return rooms, meta, err
+}
+
+// ListJobs lists the background jobs of the instance that answers
+func (c *Client) ListJobs(ctx context.Context) ([]*Job, error) {
+	var jobs []*Job
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/admin/jobs", nil, nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RunJob runs a background job at once and waits for it to finish. A run
+// that failed is reported in its Status and Error, not as an error.
+func (c *Client) RunJob(ctx context.Context, name string) (*JobRun, error) {
+	var run JobRun
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/admin/jobs/"+url.PathEscape(name)+"/run", nil, nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// RotateJWTKey creates a JWT signing key that takes over at its ActivatesAt
+func (c *Client) RotateJWTKey(ctx context.Context) (*JWTKey, error) {
+	var key JWTKey
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/admin/jwt/rotate", nil, nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// WSStats returns the WebSocket counters of the instance that answers
+func (c *Client) WSStats(ctx context.Context) (map[string]int, error) {
+	var stats map[string]int
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/ws/stats", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
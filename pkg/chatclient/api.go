@@ -0,0 +1,141 @@
+package chatclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (p Page) values() url.Values {
+	q := url.Values{}
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	} else if p.Page > 0 {
+		q.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return q
+}
+
+// SearchUsers finds users whose username or display name contains query
+func (c *Client) SearchUsers(ctx context.Context, query string, page Page) ([]*User, *Meta, error) {
+	q := page.values()
+	q.Set("q", query)
+
+	var users []*User
+	meta, err := c.do(ctx, http.MethodGet, "/api/v1/users/search", q, nil, &users)
+	return users, meta, err
+}
+
+// ListPublicRooms lists public rooms
+func (c *Client) ListPublicRooms(ctx context.Context, page Page) ([]*Room, *Meta, error) {
+	var rooms []*Room
+	meta, err := c.do(ctx, http.MethodGet, "/api/v1/rooms", page.values(), nil, &rooms)
+	return rooms, meta, err
+}
+
+// ListMyRooms lists the rooms the user is a member of
+func (c *Client) ListMyRooms(ctx context.Context, page Page) ([]*Room, *Meta, error) {
+	var rooms []*Room
+	meta, err := c.do(ctx, http.MethodGet, "/api/v1/rooms/me", page.values(), nil, &rooms)
+	return rooms, meta, err
+}
+
+// GetRoom returns a room
+func (c *Client) GetRoom(ctx context.Context, roomID string) (*Room, error) {
+	var room Room
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/rooms/"+url.PathEscape(roomID), nil, nil, &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// CreateRoom creates a room owned by the user
+func (c *Client) CreateRoom(ctx context.Context, input *CreateRoomInput) (*Room, error) {
+	var room Room
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/rooms", nil, input, &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// JoinRoom makes the user a member of a public room. Receiving its
+// messages over the WebSocket also takes Conn.JoinRooms.
+func (c *Client) JoinRoom(ctx context.Context, roomID string) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/v1/rooms/"+url.PathEscape(roomID)+"/join", nil, nil, nil)
+	return err
+}
+
+// LeaveRoom gives up the user's membership of a room
+func (c *Client) LeaveRoom(ctx context.Context, roomID string) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/v1/rooms/"+url.PathEscape(roomID)+"/leave", nil, nil, nil)
+	return err
+}
+
+// ListMessages returns a page of a room's messages, oldest first
+func (c *Client) ListMessages(ctx context.Context, roomID string, page Page) ([]*Message, *Meta, error) {
+	var messages []*Message
+	meta, err := c.do(ctx, http.MethodGet, "/api/v1/rooms/"+url.PathEscape(roomID)+"/messages", page.values(), nil, &messages)
+	return messages, meta, err
+}
+
+// ListMessagesAfter returns the messages posted in a room after
+// messageID, for catching up past what a WebSocket resume could replay
+func (c *Client) ListMessagesAfter(ctx context.Context, roomID, messageID string, limit int) ([]*Message, *Meta, error) {
+	q := Page{Limit: limit}.values()
+	q.Set("after", messageID)
+
+	var messages []*Message
+	meta, err := c.do(ctx, http.MethodGet, "/api/v1/rooms/"+url.PathEscape(roomID)+"/messages", q, nil, &messages)
+	return messages, meta, err
+}
+
+// SendMessage posts a message to a room
+func (c *Client) SendMessage(ctx context.Context, roomID string, input *SendMessageInput) (*Message, error) {
+	var msg Message
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/rooms/"+url.PathEscape(roomID)+"/messages", nil, input, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// MarkRoomRead marks a room read up to now
+func (c *Client) MarkRoomRead(ctx context.Context, roomID string) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/v1/rooms/"+url.PathEscape(roomID)+"/messages/read", nil, nil, nil)
+	return err
+}
+
+// ListDirectMessages returns a page of the conversation with another user
+func (c *Client) ListDirectMessages(ctx context.Context, userID string, page Page) ([]*DirectMessage, *Meta, error) {
+	var messages []*DirectMessage
+	meta, err := c.do(ctx, http.MethodGet, "/api/v1/dm/"+url.PathEscape(userID), page.values(), nil, &messages)
+	return messages, meta, err
+}
+
+// SendDirectMessage sends a text message to another user
+func (c *Client) SendDirectMessage(ctx context.Context, userID, content string) (*DirectMessage, error) {
+	var msg DirectMessage
+	body := map[string]string{"content": content}
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/dm/"+url.PathEscape(userID), nil, body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// MarkDirectMessagesRead marks the messages another user sent read
+func (c *Client) MarkDirectMessagesRead(ctx context.Context, userID string) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/v1/dm/"+url.PathEscape(userID)+"/read", nil, nil, nil)
+	return err
+}
+
+// ReadStates returns the user's read state across rooms and conversations
+func (c *Client) ReadStates(ctx context.Context) (*ReadStates, error) {
+	var states ReadStates
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/users/me/read-state", nil, nil, &states); err != nil {
+		return nil, err
+	}
+	return &states, nil
+}
@@ -0,0 +1,237 @@
+// Package chatclient is a Go client for the chat server: typed calls for
+// the REST API and a managed WebSocket connection that reconnects, rejoins
+// its rooms and replays missed messages on its own.
+//
+//	c := chatclient.New("https://chat.example.com")
+//	if _, err := c.Login(ctx, "alice", "secret"); err != nil { ... }
+//	conn := c.WebSocket()
+//	conn.On(chatclient.EventNewMessage, func(e *chatclient.Event) { ... })
+//	if err := conn.Connect(ctx); err != nil { ... }
+//	conn.JoinRooms(ctx, roomID)
+package chatclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client calls the REST API. It keeps the tokens of the last login and
+// refreshes the access token once when a request is rejected as expired.
+// A Client is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu    sync.RWMutex
+	token *Token
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for REST calls and the
+// WebSocket handshake
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken starts the client with tokens obtained earlier
+func WithToken(token *Token) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New creates a client for the server at baseURL, e.g. https://chat.example.com
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the current tokens, nil before logging in
+func (c *Client) Token() *Token {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the tokens, e.g. with ones restored from storage
+func (c *Client) SetToken(token *Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+func (c *Client) accessToken() string {
+	if token := c.Token(); token != nil {
+		return token.AccessToken
+	}
+	return ""
+}
+
+// APIError is a request the server refused
+type APIError struct {
+	StatusCode int
+	Message    string
+	Details    json.RawMessage
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("chatclient: %d %s", e.StatusCode, e.Message)
+}
+
+// envelope is the body of every REST response
+type envelope struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Meta    *Meta           `json:"meta"`
+	Error   *struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Details json.RawMessage `json:"details"`
+	} `json:"error"`
+}
+
+// do sends a request and decodes the data of the response into out, which
+// may be nil. A 401 is retried once after refreshing the access token.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (*Meta, error) {
+	meta, err := c.send(ctx, method, path, query, body, out)
+	if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode == http.StatusUnauthorized {
+		if token := c.Token(); token != nil && token.RefreshToken != "" {
+			if _, refreshErr := c.Refresh(ctx); refreshErr == nil {
+				return c.send(ctx, method, path, query, body, out)
+			}
+		}
+	}
+	return meta, err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body, out interface{}) (*Meta, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := c.accessToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil && err != io.EOF {
+		if resp.StatusCode >= http.StatusBadRequest {
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+		}
+		return nil, fmt.Errorf("chatclient: decode %s %s: %w", method, path, err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest || !env.Success {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: env.Message}
+		if env.Error != nil {
+			apiErr.Message = env.Error.Message
+			apiErr.Details = env.Error.Details
+		}
+		return nil, apiErr
+	}
+
+	if out != nil && len(env.Data) > 0 {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			return nil, fmt.Errorf("chatclient: decode %s %s: %w", method, path, err)
+		}
+	}
+	return env.Meta, nil
+}
+
+// Register creates an account and keeps its tokens
+func (c *Client) Register(ctx context.Context, username, email, password string) (*User, error) {
+	var auth authResult
+	body := map[string]string{"username": username, "email": email, "password": password}
+	if _, err := c.send(ctx, http.MethodPost, "/api/v1/auth/register", nil, body, &auth); err != nil {
+		return nil, err
+	}
+	c.SetToken(auth.Token)
+	return auth.User, nil
+}
+
+// Login signs in and keeps the tokens
+func (c *Client) Login(ctx context.Context, username, password string) (*User, error) {
+	var auth authResult
+	body := map[string]string{"username": username, "password": password}
+	if _, err := c.send(ctx, http.MethodPost, "/api/v1/auth/login", nil, body, &auth); err != nil {
+		return nil, err
+	}
+	c.SetToken(auth.Token)
+	return auth.User, nil
+}
+
+// Refresh exchanges the refresh token for new tokens
+func (c *Client) Refresh(ctx context.Context) (*Token, error) {
+	current := c.Token()
+	if current == nil || current.RefreshToken == "" {
+		return nil, &APIError{StatusCode: http.StatusUnauthorized, Message: "no refresh token"}
+	}
+
+	var token Token
+	body := map[string]string{"refresh_token": current.RefreshToken}
+	if _, err := c.send(ctx, http.MethodPost, "/api/v1/auth/refresh", nil, body, &token); err != nil {
+		return nil, err
+	}
+	c.SetToken(&token)
+	return &token, nil
+}
+
+// Logout revokes the current device's refresh token and forgets the tokens
+func (c *Client) Logout(ctx context.Context) error {
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/auth/logout", nil, nil, nil); err != nil {
+		return err
+	}
+	c.SetToken(nil)
+	return nil
+}
+
+// Me returns the signed-in user
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/auth/me", nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,118 @@
+package chatclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestClient_LoginAndRefresh(t *testing.T) {
+	access := "first"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data": map[string]interface{}{
+				"user":  map[string]string{"id": "u1", "username": "alice"},
+				"token": map[string]string{"access_token": access, "refresh_token": "r1"},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["refresh_token"] != "r1" {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false})
+			return
+		}
+		access = "second"
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"access_token": access, "refresh_token": "r2"},
+		})
+	})
+	mux.HandleFunc("/api/v1/auth/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer second" {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"success": false,
+				"error":   map[string]interface{}{"code": 401, "message": "Token 已過期"},
+			})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"id": "u1", "username": "alice"},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := New(srv.URL + "/")
+
+	user, err := c.Login(ctx, "alice", "secret")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if user.ID != "u1" || c.Token().AccessToken != "first" {
+		t.Fatalf("Unexpected login result: %+v, %+v", user, c.Token())
+	}
+
+	// The expired access token is refreshed and the call retried
+	me, err := c.Me(ctx)
+	if err != nil {
+		t.Fatalf("Me failed: %v", err)
+	}
+	if me.Username != "alice" || c.Token().RefreshToken != "r2" {
+		t.Errorf("Expected refreshed tokens, got %+v", c.Token())
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   map[string]interface{}{"code": 404, "message": "聊天室不存在"},
+		})
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL, WithToken(&Token{AccessToken: "t"})).GetRoom(context.Background(), "r1")
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("Expected *APIError, got %T %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "聊天室不存在" {
+		t.Errorf("Unexpected error: %+v", apiErr)
+	}
+}
+
+func TestClient_ListMessagesPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/rooms/r1/messages" || r.URL.Query().Get("cursor") != "abc" || r.URL.Query().Get("limit") != "2" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    []map[string]string{{"id": "m1"}, {"id": "m2"}},
+			"meta":    map[string]interface{}{"limit": 2, "returned": 2, "has_more": true, "next_cursor": "def"},
+		})
+	}))
+	defer srv.Close()
+
+	messages, meta, err := New(srv.URL).ListMessages(context.Background(), "r1", Page{Page: 3, Limit: 2, Cursor: "abc"})
+	if err != nil {
+		t.Fatalf("ListMessages failed: %v", err)
+	}
+	if len(messages) != 2 || messages[1].ID != "m2" || !meta.HasMore || meta.NextCursor != "def" {
+		t.Errorf("Unexpected page: %+v, %+v", messages, meta)
+	}
+}
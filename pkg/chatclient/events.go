@@ -0,0 +1,151 @@
+package chatclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event types the server sends. Conn declares the ones it has handlers for
+// in the handshake, since the server only sends newer events to clients
+// that ask for them; see WithEvents.
+const (
+	EventWelcome          = "welcome"
+	EventError            = "error"
+	EventAck              = "ack"
+	EventReconnect        = "reconnect"
+	EventAccountBanned    = "account_banned"
+	EventSessionRevoked   = "session_revoked"
+	EventRoomJoined       = "room_joined"
+	EventRoomsJoined      = "rooms_joined"
+	EventRoomLeft         = "room_left"
+	EventResumed          = "resumed"
+	EventNewMessage       = "new_message"
+	EventUserTyping       = "user_typing"
+	EventUserStopTyping   = "user_stop_typing"
+	EventUserOnline       = "user_online"
+	EventUserOffline      = "user_offline"
+	EventNewDM            = "new_dm"
+	EventDMRead           = "dm_read"
+	EventNotification     = "notification"
+	EventReadStateUpdated = "read_state_updated"
+)
+
+// controlEvents reach every connection whatever it declares
+var controlEvents = map[string]bool{
+	EventWelcome:        true,
+	EventError:          true,
+	EventAck:            true,
+	"pong":              true,
+	EventAccountBanned:  true,
+	EventSessionRevoked: true,
+	EventReconnect:      true,
+	"reconnect_ticket":  true,
+	EventRoomsJoined:    true,
+	EventResumed:        true,
+	"room_presence":     true,
+}
+
+// Event is a message received from the server
+type Event struct {
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// Decode unmarshals the payload into v
+func (e *Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// ServerError is an error event, e.g. the answer to a message the server
+// refused
+type ServerError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("chatclient: websocket error %d: %s", e.Code, e.Message)
+}
+
+// NewMessageEvent is the payload of new_message events
+type NewMessageEvent struct {
+	ID          string        `json:"id"`
+	RoomID      string        `json:"room_id"`
+	UserID      string        `json:"user_id"`
+	Username    string        `json:"username"`
+	DisplayName string        `json:"display_name"`
+	AvatarURL   string        `json:"avatar_url"`
+	IsBot       bool          `json:"is_bot,omitempty"`
+	Content     string        `json:"content"`
+	Type        string        `json:"type"`
+	ReplyToID   string        `json:"reply_to_id,omitempty"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+	CreatedAt   string        `json:"created_at"`
+}
+
+// NewDMEvent is the payload of new_dm events
+type NewDMEvent struct {
+	ID                string `json:"id"`
+	SenderID          string `json:"sender_id"`
+	SenderUsername    string `json:"sender_username"`
+	SenderDisplayName string `json:"sender_display_name"`
+	Content           string `json:"content"`
+	Type              string `json:"type"`
+	CreatedAt         string `json:"created_at"`
+}
+
+// ReadStateEvent is the payload of read_state_updated events
+type ReadStateEvent struct {
+	Kind        string `json:"kind"` // room, dm or group
+	ID          string `json:"id"`
+	LastReadAt  string `json:"last_read_at"`
+	UnreadCount int    `json:"unread_count"`
+}
+
+// JoinResult is the outcome of joining one room over the WebSocket
+type JoinResult struct {
+	RoomID      string `json:"room_id"`
+	Joined      bool   `json:"joined"`
+	RoomName    string `json:"room_name,omitempty"`
+	MemberCount int    `json:"member_count,omitempty"`
+	Code        int    `json:"code,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ResumeResult says how many messages the server replayed in a room after
+// a reconnect. When Complete is false older messages were missed too; fetch
+// them with Client.ListMessagesAfter.
+type ResumeResult struct {
+	RoomID   string `json:"room_id"`
+	Replayed int    `json:"replayed"`
+	Complete bool   `json:"complete"`
+	Code     int    `json:"code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type joinedPayload struct {
+	Results []*JoinResult `json:"results"`
+}
+
+type resumedPayload struct {
+	Results []*ResumeResult `json:"results"`
+}
+
+type ackPayload struct {
+	RequestID string `json:"request_id"`
+	Success   bool   `json:"success"`
+	MessageID string `json:"message_id,omitempty"`
+}
+
+type reconnectPayload struct {
+	Reason string `json:"reason"`
+	Ticket string `json:"ticket,omitempty"`
+}
+
+type resumeRoom struct {
+	RoomID        string `json:"room_id"`
+	LastMessageID string `json:"last_message_id"`
+}
@@ -0,0 +1,148 @@
+package chatclient
+
+import "time"
+
+// Token is an access and refresh token pair
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	TokenType    string    `json:"token_type"`
+	DeviceID     string    `json:"device_id,omitempty"`
+}
+
+type authResult struct {
+	User  *User  `json:"user"`
+	Token *Token `json:"token"`
+}
+
+// Meta describes a page of a list
+type Meta struct {
+	Limit      int    `json:"limit"`
+	Returned   int    `json:"returned"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Page selects a page of a list; the zero value is the server's default
+// first page. Cursor, taken from Meta.NextCursor, wins over Page.
+type Page struct {
+	Page   int
+	Limit  int
+	Cursor string
+}
+
+// User is a user profile; Email is only set on the signed-in user
+type User struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email,omitempty"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	Status      string `json:"status"`
+	Bio         string `json:"bio"`
+	IsBot       bool   `json:"is_bot,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// Room is a chat room
+type Room struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	Type            string `json:"type"`
+	OwnerID         string `json:"owner_id"`
+	MaxMembers      int    `json:"max_members"`
+	MemberCount     int    `json:"member_count"`
+	ReadOnly        bool   `json:"read_only"`
+	MessageTTL      int    `json:"message_ttl"`
+	Language        string `json:"language"`
+	RateLimit       int    `json:"rate_limit"`
+	ModerationLevel string `json:"moderation_level"`
+	ArchivedAt      string `json:"archived_at,omitempty"`
+	CreatedAt       string `json:"created_at"`
+}
+
+// CreateRoomInput describes a new room; zero fields take server defaults
+type CreateRoomInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"` // public or private
+	MaxMembers  int    `json:"max_members,omitempty"`
+	ReadOnly    bool   `json:"read_only,omitempty"`
+	MessageTTL  int    `json:"message_ttl,omitempty"`
+	Language    string `json:"language,omitempty"`
+}
+
+// Attachment is an upload attached to a message
+type Attachment struct {
+	ID         string `json:"id"`
+	UploadID   string `json:"upload_id"`
+	FileName   string `json:"file_name"`
+	FileURL    string `json:"file_url"`
+	FileType   string `json:"file_type"`
+	FileSize   int64  `json:"file_size"`
+	ScanStatus string `json:"scan_status"`
+}
+
+// Message is a room message
+type Message struct {
+	ID          string        `json:"id"`
+	RoomID      string        `json:"room_id"`
+	UserID      string        `json:"user_id"`
+	Username    string        `json:"username"`
+	DisplayName string        `json:"display_name"`
+	AvatarURL   string        `json:"avatar_url"`
+	IsBot       bool          `json:"is_bot,omitempty"`
+	Content     string        `json:"content"`
+	Type        string        `json:"type"`
+	ReplyToID   string        `json:"reply_to_id,omitempty"`
+	IsEdited    bool          `json:"is_edited"`
+	IsDeleted   bool          `json:"is_deleted"`
+	IsNSFW      bool          `json:"is_nsfw"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+	CreatedAt   string        `json:"created_at"`
+	UpdatedAt   string        `json:"updated_at"`
+	ExpiresAt   string        `json:"expires_at,omitempty"`
+}
+
+// SendMessageInput is a message to send; Type defaults to text
+type SendMessageInput struct {
+	Content       string   `json:"content"`
+	Type          string   `json:"type,omitempty"`
+	ReplyToID     string   `json:"reply_to_id,omitempty"`
+	AttachmentIDs []string `json:"attachment_ids,omitempty"`
+}
+
+// DirectMessage is a direct message
+type DirectMessage struct {
+	ID                string        `json:"id"`
+	SenderID          string        `json:"sender_id"`
+	ReceiverID        string        `json:"receiver_id"`
+	SenderUsername    string        `json:"sender_username"`
+	SenderDisplayName string        `json:"sender_display_name"`
+	SenderAvatarURL   string        `json:"sender_avatar_url"`
+	Content           string        `json:"content"`
+	Type              string        `json:"type"`
+	IsRead            bool          `json:"is_read"`
+	IsEdited          bool          `json:"is_edited"`
+	IsNSFW            bool          `json:"is_nsfw"`
+	Attachments       []*Attachment `json:"attachments,omitempty"`
+	CreatedAt         string        `json:"created_at"`
+	ExpiresAt         string        `json:"expires_at,omitempty"`
+}
+
+// ReadState is how far the user has read one room or conversation
+type ReadState struct {
+	ID          string `json:"id"`
+	LastReadAt  string `json:"last_read_at,omitempty"`
+	UnreadCount int    `json:"unread_count"`
+}
+
+// ReadStates is the user's read state everywhere. DMs lists only
+// conversations with unread messages, keyed by the other user's ID.
+type ReadStates struct {
+	Rooms  []*ReadState `json:"rooms"`
+	DMs    []*ReadState `json:"dms"`
+	Groups []*ReadState `json:"groups"`
+}
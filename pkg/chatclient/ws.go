@@ -0,0 +1,735 @@
+package chatclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// maxRoomsPerFrame is the most rooms the server joins or resumes in
+	// one frame
+	maxRoomsPerFrame = 100
+
+	writeTimeout = 10 * time.Second
+	// restoreTimeout bounds rejoining and resuming rooms after a reconnect
+	restoreTimeout = 30 * time.Second
+)
+
+var (
+	// ErrNotConnected is returned for messages sent while the connection
+	// is down; a reconnect may be in progress
+	ErrNotConnected = errors.New("chatclient: websocket not connected")
+	// ErrClosed is returned once Close has been called
+	ErrClosed = errors.New("chatclient: websocket closed")
+	// ErrBanned ends the connection when the server bans the account
+	ErrBanned = errors.New("chatclient: account banned")
+	// ErrSessionRevoked ends the connection when the device is signed out
+	ErrSessionRevoked = errors.New("chatclient: session revoked")
+)
+
+// Conn is a managed WebSocket connection. After Connect it reconnects on
+// its own with exponential backoff, rejoins the rooms joined through it
+// and asks the server to replay the messages missed in each. Handlers run
+// one at a time on the connection's goroutine and must not block; calls
+// that wait for a reply (JoinRooms, SendMessage, SendDirectMessage) must
+// not be made from a handler.
+type Conn struct {
+	client     *Client
+	dialer     *websocket.Dialer
+	events     []string
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	readWait   time.Duration
+
+	handlerMu     sync.RWMutex
+	handlers      map[string][]func(*Event)
+	stateHandlers []func(connected bool, err error)
+
+	mu      sync.Mutex
+	ws      *websocket.Conn
+	started bool
+	closed  bool
+	ticket  string            // single-use ticket for the next dial
+	rooms   map[string]string // joined room ID -> last message ID seen
+	pending []*pendingRequest // awaiting a reply, oldest first
+	nextID  uint64
+
+	writeMu sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+type pendingRequest struct {
+	id    string
+	reply chan requestResult
+}
+
+type requestResult struct {
+	event *Event
+	err   error
+}
+
+// ConnOption configures a Conn
+type ConnOption func(*Conn)
+
+// WithEvents declares extra event types to receive, beyond the package's
+// Event constants and the types that have handlers
+func WithEvents(events ...string) ConnOption {
+	return func(c *Conn) {
+		c.events = append(c.events, events...)
+	}
+}
+
+// WithBackoff sets the delay before the first reconnect attempt and the
+// cap it doubles up to; the defaults are 500ms and 30s
+func WithBackoff(min, max time.Duration) ConnOption {
+	return func(c *Conn) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+// WithReadTimeout sets how long the connection may stay silent, server
+// pings included, before it is considered dead; the default is 2 minutes
+func WithReadTimeout(d time.Duration) ConnOption {
+	return func(c *Conn) {
+		c.readWait = d
+	}
+}
+
+// WebSocket creates a managed WebSocket connection that authenticates
+// with the client's tokens. Register handlers, then call Connect.
+func (c *Client) WebSocket(opts ...ConnOption) *Conn {
+	dialer := *websocket.DefaultDialer
+	if t, ok := c.httpClient.Transport.(*http.Transport); ok && t.TLSClientConfig != nil {
+		dialer.TLSClientConfig = t.TLSClientConfig
+	}
+
+	conn := &Conn{
+		client:     c,
+		dialer:     &dialer,
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		readWait:   2 * time.Minute,
+		handlers:   make(map[string][]func(*Event)),
+		rooms:      make(map[string]string),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(conn)
+	}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	return conn
+}
+
+// On registers a handler for an event type
+func (c *Conn) On(eventType string, fn func(*Event)) {
+	c.handlerMu.Lock()
+	defer c.handlerMu.Unlock()
+	c.handlers[eventType] = append(c.handlers[eventType], fn)
+}
+
+// OnStateChange registers a handler called when the connection comes up
+// or goes down; err says why it went down, nil after Close
+func (c *Conn) OnStateChange(fn func(connected bool, err error)) {
+	c.handlerMu.Lock()
+	defer c.handlerMu.Unlock()
+	c.stateHandlers = append(c.stateHandlers, fn)
+}
+
+// Connect opens the connection and keeps it open until Close. Only the
+// first attempt's failure is returned; later drops are retried.
+func (c *Conn) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	if c.started {
+		c.mu.Unlock()
+		return errors.New("chatclient: websocket already connected")
+	}
+	c.started = true
+	c.mu.Unlock()
+
+	ws, err := c.dial(ctx)
+	if err != nil {
+		c.mu.Lock()
+		if c.closed {
+			// Close is waiting for a run that will never start
+			close(c.done)
+		} else {
+			c.started = false
+		}
+		c.mu.Unlock()
+		return err
+	}
+
+	// Usable as soon as Connect returns
+	c.mu.Lock()
+	c.ws = ws
+	c.mu.Unlock()
+
+	go c.run(ws)
+	return nil
+}
+
+// Close closes the connection and stops reconnecting
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	started := c.started
+	ws := c.ws
+	c.mu.Unlock()
+
+	c.cancel()
+	if ws != nil {
+		_ = ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		_ = ws.Close()
+	}
+	if started {
+		<-c.done
+	}
+	return nil
+}
+
+// Connected reports whether the connection is currently up
+func (c *Conn) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws != nil
+}
+
+// JoinRooms subscribes to rooms the user is a member of. Rooms joined
+// successfully are rejoined after every reconnect until LeaveRoom.
+func (c *Conn) JoinRooms(ctx context.Context, roomIDs ...string) ([]*JoinResult, error) {
+	var results []*JoinResult
+	for start := 0; start < len(roomIDs); start += maxRoomsPerFrame {
+		end := start + maxRoomsPerFrame
+		if end > len(roomIDs) {
+			end = len(roomIDs)
+		}
+
+		batch, err := c.joinRooms(ctx, roomIDs[start:end])
+		if err != nil {
+			return results, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+func (c *Conn) joinRooms(ctx context.Context, roomIDs []string) ([]*JoinResult, error) {
+	event, err := c.request(ctx, "join_rooms", map[string]interface{}{"room_ids": roomIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	var payload joinedPayload
+	if err := event.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	for _, r := range payload.Results {
+		if r.Joined {
+			if _, ok := c.rooms[r.RoomID]; !ok {
+				c.rooms[r.RoomID] = ""
+			}
+		} else {
+			delete(c.rooms, r.RoomID)
+		}
+	}
+	c.mu.Unlock()
+
+	return payload.Results, nil
+}
+
+// LeaveRoom unsubscribes from a room
+func (c *Conn) LeaveRoom(roomID string) error {
+	c.mu.Lock()
+	delete(c.rooms, roomID)
+	c.mu.Unlock()
+
+	return c.Send("leave_room", map[string]string{"room_id": roomID})
+}
+
+// SendMessage posts a text message to a joined room and returns its ID
+// once the server has stored it
+func (c *Conn) SendMessage(ctx context.Context, roomID, content string) (string, error) {
+	event, err := c.request(ctx, "send_message", map[string]string{"room_id": roomID, "content": content})
+	if err != nil {
+		return "", err
+	}
+
+	var ack ackPayload
+	if err := event.Decode(&ack); err != nil {
+		return "", err
+	}
+
+	// The server does not echo the message to the connection that sent it
+	c.seen(roomID, ack.MessageID)
+	return ack.MessageID, nil
+}
+
+// SendDirectMessage sends a text message to another user and returns its ID
+func (c *Conn) SendDirectMessage(ctx context.Context, userID, content string) (string, error) {
+	event, err := c.request(ctx, "send_dm", map[string]string{"receiver_id": userID, "content": content})
+	if err != nil {
+		return "", err
+	}
+
+	var ack ackPayload
+	if err := event.Decode(&ack); err != nil {
+		return "", err
+	}
+	return ack.MessageID, nil
+}
+
+// Typing tells a room the user is typing
+func (c *Conn) Typing(roomID string) error {
+	return c.Send("typing", map[string]string{"room_id": roomID})
+}
+
+// MarkRoomRead marks a room read; the user's other devices get a
+// read_state_updated event
+func (c *Conn) MarkRoomRead(roomID string) error {
+	return c.Send("mark_read", map[string]string{"room_id": roomID})
+}
+
+// Send writes a message of any type without waiting for a reply
+func (c *Conn) Send(msgType string, payload interface{}) error {
+	return c.write(msgType, payload, "")
+}
+
+// request sends a message and waits for the reply carrying its request
+// ID. The server handles a connection's messages in order and its error
+// events carry no request ID, so an error answers the oldest request still
+// waiting.
+func (c *Conn) request(ctx context.Context, msgType string, payload interface{}) (*Event, error) {
+	c.mu.Lock()
+	if c.ws == nil {
+		closed := c.closed
+		c.mu.Unlock()
+		if closed {
+			return nil, ErrClosed
+		}
+		return nil, ErrNotConnected
+	}
+	c.nextID++
+	p := &pendingRequest{id: "c" + strconv.FormatUint(c.nextID, 10), reply: make(chan requestResult, 1)}
+	c.pending = append(c.pending, p)
+	c.mu.Unlock()
+
+	if err := c.write(msgType, payload, p.id); err != nil {
+		c.removePending(p)
+		return nil, err
+	}
+
+	select {
+	case r := <-p.reply:
+		return r.event, r.err
+	case <-ctx.Done():
+		c.removePending(p)
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Conn) removePending(p *pendingRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, q := range c.pending {
+		if q == p {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// resolve completes the request an event answers, if any
+func (c *Conn) resolve(event *Event) {
+	requestID := event.RequestID
+	if event.Type == EventAck {
+		var ack ackPayload
+		if err := event.Decode(&ack); err == nil {
+			requestID = ack.RequestID
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if event.Type == EventError && requestID == "" {
+		if len(c.pending) == 0 {
+			return
+		}
+		var serverErr ServerError
+		_ = event.Decode(&serverErr)
+		p := c.pending[0]
+		c.pending = c.pending[1:]
+		p.reply <- requestResult{err: &serverErr}
+		return
+	}
+
+	if requestID == "" {
+		return
+	}
+	for i, p := range c.pending {
+		if p.id == requestID {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			p.reply <- requestResult{event: event}
+			return
+		}
+	}
+}
+
+func (c *Conn) write(msgType string, payload interface{}, requestID string) error {
+	frame := map[string]interface{}{"type": msgType}
+	if payload != nil {
+		frame["payload"] = payload
+	}
+	if requestID != "" {
+		frame["request_id"] = requestID
+	}
+
+	c.mu.Lock()
+	ws := c.ws
+	closed := c.closed
+	c.mu.Unlock()
+	if ws == nil {
+		if closed {
+			return ErrClosed
+		}
+		return ErrNotConnected
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return ws.WriteJSON(frame)
+}
+
+// seen records the newest message the connection has seen in a room
+func (c *Conn) seen(roomID, messageID string) {
+	if messageID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.rooms[roomID]; ok {
+		c.rooms[roomID] = messageID
+	}
+}
+
+// run reads from the connection and reconnects whenever it drops, until
+// Close or a ban
+func (c *Conn) run(ws *websocket.Conn) {
+	defer close(c.done)
+
+	for {
+		c.mu.Lock()
+		if c.closed {
+			// Closed while this connection was being made
+			c.mu.Unlock()
+			_ = ws.Close()
+			return
+		}
+		c.ws = ws
+		c.mu.Unlock()
+		c.notifyState(true, nil)
+
+		go c.restore()
+		err := c.read(ws)
+		_ = ws.Close()
+
+		c.mu.Lock()
+		c.ws = nil
+		pending := c.pending
+		c.pending = nil
+		closed := c.closed
+		c.mu.Unlock()
+		for _, p := range pending {
+			p.reply <- requestResult{err: ErrNotConnected}
+		}
+
+		if closed {
+			c.notifyState(false, nil)
+			return
+		}
+		c.notifyState(false, err)
+		if err == ErrBanned || err == ErrSessionRevoked {
+			return
+		}
+
+		if ws = c.redial(); ws == nil {
+			return
+		}
+	}
+}
+
+// redial retries until a connection is made or the Conn is closed. A
+// server that asked the client to move on handed over a ticket, which is
+// used at once.
+func (c *Conn) redial() *websocket.Conn {
+	backoff := c.minBackoff
+	c.mu.Lock()
+	wait := c.ticket == ""
+	c.mu.Unlock()
+
+	for {
+		if wait {
+			select {
+			case <-time.After(backoff):
+			case <-c.ctx.Done():
+				return nil
+			}
+			if backoff *= 2; backoff > c.maxBackoff {
+				backoff = c.maxBackoff
+			}
+		}
+		wait = true
+
+		ws, err := c.dial(c.ctx)
+		if err == nil {
+			return ws
+		}
+		if c.ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// restore rejoins the tracked rooms on a fresh connection and asks for
+// the messages missed in the ones that had seen any
+func (c *Conn) restore() {
+	c.mu.Lock()
+	roomIDs := make([]string, 0, len(c.rooms))
+	for id := range c.rooms {
+		roomIDs = append(roomIDs, id)
+	}
+	c.mu.Unlock()
+	if len(roomIDs) == 0 {
+		return
+	}
+	sort.Strings(roomIDs)
+
+	ctx, cancel := context.WithTimeout(c.ctx, restoreTimeout)
+	defer cancel()
+
+	if _, err := c.JoinRooms(ctx, roomIDs...); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	var rooms []resumeRoom
+	for _, id := range roomIDs {
+		if last := c.rooms[id]; last != "" {
+			rooms = append(rooms, resumeRoom{RoomID: id, LastMessageID: last})
+		}
+	}
+	c.mu.Unlock()
+
+	for start := 0; start < len(rooms); start += maxRoomsPerFrame {
+		end := start + maxRoomsPerFrame
+		if end > len(rooms) {
+			end = len(rooms)
+		}
+		if _, err := c.request(ctx, "resume", map[string]interface{}{"rooms": rooms[start:end]}); err != nil {
+			return
+		}
+	}
+}
+
+// read dispatches events until the connection fails
+func (c *Conn) read(ws *websocket.Conn) error {
+	_ = ws.SetReadDeadline(time.Now().Add(c.readWait))
+	ws.SetPingHandler(func(data string) error {
+		_ = ws.SetReadDeadline(time.Now().Add(c.readWait))
+		return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeTimeout))
+	})
+
+	for {
+		_, r, err := ws.NextReader()
+		if err != nil {
+			return err
+		}
+		_ = ws.SetReadDeadline(time.Now().Add(c.readWait))
+
+		// A frame may carry several messages, one after another
+		dec := json.NewDecoder(r)
+		for {
+			var event Event
+			if err := dec.Decode(&event); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return err
+			}
+			if err := c.handle(ws, &event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Conn) handle(ws *websocket.Conn, event *Event) error {
+	switch event.Type {
+	case EventNewMessage:
+		var msg struct {
+			ID     string `json:"id"`
+			RoomID string `json:"room_id"`
+		}
+		if err := event.Decode(&msg); err == nil {
+			c.seen(msg.RoomID, msg.ID)
+		}
+	case EventReconnect:
+		// The server is going away; the next dial uses its ticket
+		var payload reconnectPayload
+		if err := event.Decode(&payload); err == nil && payload.Ticket != "" {
+			c.mu.Lock()
+			c.ticket = payload.Ticket
+			c.mu.Unlock()
+		}
+	}
+
+	c.resolve(event)
+	c.dispatch(event)
+
+	switch event.Type {
+	case EventReconnect:
+		return errors.New("chatclient: server asked to reconnect")
+	case EventAccountBanned:
+		return ErrBanned
+	case EventSessionRevoked:
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+func (c *Conn) dispatch(event *Event) {
+	c.handlerMu.RLock()
+	handlers := c.handlers[event.Type]
+	c.handlerMu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(event)
+	}
+}
+
+func (c *Conn) notifyState(connected bool, err error) {
+	c.handlerMu.RLock()
+	handlers := c.stateHandlers
+	c.handlerMu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(connected, err)
+	}
+}
+
+// dial opens one connection, with the pending ticket if there is one and
+// the access token otherwise. A handshake rejected as unauthorized is
+// retried once after refreshing the token.
+func (c *Conn) dial(ctx context.Context) (*websocket.Conn, error) {
+	ws, resp, err := c.dialOnce(ctx)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusUnauthorized {
+		if _, refreshErr := c.client.Refresh(ctx); refreshErr == nil {
+			ws, _, err = c.dialOnce(ctx)
+		}
+	}
+	return ws, err
+}
+
+func (c *Conn) dialOnce(ctx context.Context) (*websocket.Conn, *http.Response, error) {
+	target, err := url.Parse(c.client.baseURL + "/ws")
+	if err != nil {
+		return nil, nil, err
+	}
+	switch target.Scheme {
+	case "https":
+		target.Scheme = "wss"
+	case "http":
+		target.Scheme = "ws"
+	}
+
+	q := url.Values{}
+	q.Set("events", strings.Join(c.declaredEvents(), ","))
+	q.Set("encodings", "json")
+
+	c.mu.Lock()
+	ticket := c.ticket
+	c.ticket = ""
+	c.mu.Unlock()
+
+	header := http.Header{}
+	if ticket != "" {
+		q.Set("ticket", ticket)
+	} else if token := c.client.accessToken(); token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	target.RawQuery = q.Encode()
+
+	ws, resp, err := c.dialer.DialContext(ctx, target.String(), header)
+	if err != nil {
+		if resp == nil {
+			return nil, nil, err
+		}
+		// A refused handshake answers with {"error": "..."}
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if resp.Body != nil {
+			if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+				apiErr.Message = body.Error
+			}
+			resp.Body.Close()
+		}
+		return nil, resp, apiErr
+	}
+	return ws, resp, nil
+}
+
+// declaredEvents lists the event types to ask for in the handshake: the
+// package's events, those given to WithEvents and those with handlers
+func (c *Conn) declaredEvents() []string {
+	set := map[string]bool{
+		EventRoomJoined: true, EventRoomLeft: true, EventNewMessage: true,
+		EventUserTyping: true, EventUserStopTyping: true, EventUserOnline: true,
+		EventUserOffline: true, EventNewDM: true, EventDMRead: true,
+		EventNotification: true, EventReadStateUpdated: true,
+	}
+	for _, e := range c.events {
+		set[e] = true
+	}
+	c.handlerMu.RLock()
+	for e := range c.handlers {
+		set[e] = true
+	}
+	c.handlerMu.RUnlock()
+
+	events := make([]string, 0, len(set))
+	for e := range set {
+		if !controlEvents[e] {
+			events = append(events, e)
+		}
+	}
+	sort.Strings(events)
+	return events
+}
@@ -0,0 +1,254 @@
+package chatclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type frame struct {
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+type serverConn struct {
+	*websocket.Conn
+	request *http.Request
+}
+
+// fakeWSServer hands each accepted WebSocket connection to the test
+func fakeWSServer(t *testing.T) (*httptest.Server, chan *serverConn) {
+	t.Helper()
+	conns := make(chan *serverConn, 4)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- &serverConn{Conn: ws, request: r}
+	}))
+	return srv, conns
+}
+
+func (s *serverConn) expect(t *testing.T, msgType string) frame {
+	t.Helper()
+	_ = s.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var f frame
+	if err := s.ReadJSON(&f); err != nil {
+		t.Fatalf("Failed to read %s: %v", msgType, err)
+	}
+	if f.Type != msgType {
+		t.Fatalf("Expected %s, got %s %s", msgType, f.Type, f.Payload)
+	}
+	return f
+}
+
+func (s *serverConn) send(t *testing.T, msgType string, payload interface{}, requestID string) {
+	t.Helper()
+	data, _ := json.Marshal(payload)
+	if err := s.WriteJSON(frame{Type: msgType, Payload: data, RequestID: requestID}); err != nil {
+		t.Fatalf("Failed to send %s: %v", msgType, err)
+	}
+}
+
+func accept(t *testing.T, conns chan *serverConn) *serverConn {
+	t.Helper()
+	select {
+	case c := <-conns:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a connection")
+		return nil
+	}
+}
+
+func TestConn_ReconnectRejoinsAndResumes(t *testing.T) {
+	srv, conns := fakeWSServer(t)
+	defer srv.Close()
+
+	client := New(srv.URL, WithToken(&Token{AccessToken: "tok"}))
+	conn := client.WebSocket(WithBackoff(10*time.Millisecond, 50*time.Millisecond))
+	received := make(chan *Event, 4)
+	conn.On(EventNewMessage, func(e *Event) { received <- e })
+	conn.On("room_event_created", func(e *Event) {})
+
+	ctx := context.Background()
+	if err := conn.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer conn.Close()
+
+	first := accept(t, conns)
+	if got := first.request.Header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("Expected the access token, got %q", got)
+	}
+	events := first.request.URL.Query().Get("events")
+	if !strings.Contains(events, "read_state_updated") || !strings.Contains(events, "room_event_created") || strings.Contains(events, "ack") {
+		t.Errorf("Unexpected declared events %q", events)
+	}
+
+	joined := make(chan []*JoinResult, 1)
+	go func() {
+		results, err := conn.JoinRooms(ctx, "r1", "r2")
+		if err != nil {
+			t.Errorf("JoinRooms failed: %v", err)
+		}
+		joined <- results
+	}()
+	f := first.expect(t, "join_rooms")
+	first.send(t, EventRoomsJoined, map[string]interface{}{"results": []map[string]interface{}{
+		{"room_id": "r1", "joined": true},
+		{"room_id": "r2", "joined": false, "code": 403},
+	}}, f.RequestID)
+	if results := <-joined; len(results) != 2 || !results[0].Joined || results[1].Joined {
+		t.Fatalf("Unexpected join results %+v", results)
+	}
+
+	first.send(t, EventNewMessage, map[string]string{"id": "m1", "room_id": "r1", "content": "hi"}, "")
+	select {
+	case e := <-received:
+		var msg NewMessageEvent
+		if err := e.Decode(&msg); err != nil || msg.Content != "hi" {
+			t.Errorf("Unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for new_message")
+	}
+
+	// Drop the connection; the client comes back, rejoins only r1 and
+	// resumes after the last message it saw
+	first.Close()
+	second := accept(t, conns)
+	f = second.expect(t, "join_rooms")
+	if !strings.Contains(string(f.Payload), `["r1"]`) {
+		t.Errorf("Expected only r1 to be rejoined, got %s", f.Payload)
+	}
+	second.send(t, EventRoomsJoined, map[string]interface{}{"results": []map[string]interface{}{
+		{"room_id": "r1", "joined": true},
+	}}, f.RequestID)
+	f = second.expect(t, "resume")
+	if !strings.Contains(string(f.Payload), `"last_message_id":"m1"`) {
+		t.Errorf("Expected resume after m1, got %s", f.Payload)
+	}
+	second.send(t, EventResumed, map[string]interface{}{"results": []map[string]interface{}{
+		{"room_id": "r1", "replayed": 0, "complete": true},
+	}}, f.RequestID)
+}
+
+func TestConn_SendMessage(t *testing.T) {
+	srv, conns := fakeWSServer(t)
+	defer srv.Close()
+
+	conn := New(srv.URL, WithToken(&Token{AccessToken: "tok"})).WebSocket()
+	ctx := context.Background()
+	if err := conn.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer conn.Close()
+	server := accept(t, conns)
+
+	type result struct {
+		id  string
+		err error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		id, err := conn.SendMessage(ctx, "r1", "hello")
+		done <- result{id, err}
+	}()
+	f := server.expect(t, "send_message")
+	server.send(t, EventAck, map[string]interface{}{"request_id": f.RequestID, "success": true, "message_id": "m9"}, "")
+	if r := <-done; r.err != nil || r.id != "m9" {
+		t.Errorf("Expected m9, got %+v", r)
+	}
+
+	// Error events carry no request ID and answer the oldest request
+	go func() {
+		id, err := conn.SendMessage(ctx, "r1", "again")
+		done <- result{id, err}
+	}()
+	server.expect(t, "send_message")
+	server.send(t, EventError, map[string]interface{}{"code": 403, "message": "您尚未加入該聊天室"}, "")
+	r := <-done
+	if serverErr, ok := r.err.(*ServerError); !ok || serverErr.Code != 403 {
+		t.Errorf("Expected a 403 ServerError, got %v", r.err)
+	}
+}
+
+func TestConn_ReconnectWithTicket(t *testing.T) {
+	srv, conns := fakeWSServer(t)
+	defer srv.Close()
+
+	conn := New(srv.URL, WithToken(&Token{AccessToken: "tok"})).WebSocket(WithBackoff(time.Hour, time.Hour))
+	if err := conn.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer conn.Close()
+
+	first := accept(t, conns)
+	first.send(t, EventReconnect, map[string]string{"reason": "draining", "ticket": "tk1"}, "")
+
+	// The ticket skips the backoff and replaces the token
+	second := accept(t, conns)
+	if got := second.request.URL.Query().Get("ticket"); got != "tk1" {
+		t.Errorf("Expected ticket tk1, got %q", got)
+	}
+	if got := second.request.Header.Get("Authorization"); got != "" {
+		t.Errorf("Expected no token with a ticket, got %q", got)
+	}
+}
+
+func TestConn_BanStopsReconnecting(t *testing.T) {
+	testStopsReconnecting(t, EventAccountBanned, ErrBanned)
+}
+
+func TestConn_SessionRevokedStopsReconnecting(t *testing.T) {
+	testStopsReconnecting(t, EventSessionRevoked, ErrSessionRevoked)
+}
+
+func testStopsReconnecting(t *testing.T, eventType string, want error) {
+	t.Helper()
+	srv, conns := fakeWSServer(t)
+	defer srv.Close()
+
+	conn := New(srv.URL, WithToken(&Token{AccessToken: "tok"})).WebSocket(WithBackoff(time.Millisecond, time.Millisecond))
+	states := make(chan error, 4)
+	conn.OnStateChange(func(connected bool, err error) {
+		if !connected {
+			states <- err
+		}
+	})
+	if err := conn.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer conn.Close()
+
+	server := accept(t, conns)
+	server.send(t, eventType, map[string]string{"reason": "spam"}, "")
+
+	select {
+	case err := <-states:
+		if err != want {
+			t.Errorf("Expected %v, got %v", want, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the disconnect")
+	}
+	select {
+	case <-conns:
+		t.Error("Expected no reconnect after " + eventType)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := conn.SendMessage(context.Background(), "r1", "hi"); err != ErrNotConnected {
+		t.Errorf("Expected ErrNotConnected, got %v", err)
+	}
+}